@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/core/compare"
+	"github.com/benedoc-inc/pdfer/forms"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// The exported C functions are thin wrappers around the handlers below, which
+// take a JSON request and return a JSON response. PDFs travel as base64 strings,
+// the encoding encoding/json uses for byte slices.
+
+// request holds the fields used by all operations; each uses a subset
+type request struct {
+	PDF        []byte          `json:"pdf"`
+	Password   string          `json:"password,omitempty"`
+	Data       types.FormData  `json:"data,omitempty"`        // fill: field values
+	Lock       types.FieldLock `json:"lock,omitempty"`        // fill: fields to make read-only
+	AttachData bool            `json:"attach_data,omitempty"` // fill: embed the data as an attachment
+	PDF2       []byte          `json:"pdf2,omitempty"`        // compare: the second document
+	Password2  string          `json:"password2,omitempty"`   // compare: password of the second document
+	Verbose    bool            `json:"verbose,omitempty"`
+}
+
+// response is the envelope returned by all operations. Error is set instead of
+// the result when the operation fails.
+type response struct {
+	Error  string      `json:"error,omitempty"`
+	Result interface{} `json:"result,omitempty"`
+}
+
+// fillResult is the result of a fill
+type fillResult struct {
+	PDF []byte `json:"pdf"`
+}
+
+// schemaResult is the result of extracting a form schema
+type schemaResult struct {
+	Type   forms.FormType         `json:"type"`
+	Schema *types.FormSchema      `json:"schema"`
+	Values map[string]interface{} `json:"values,omitempty"`
+}
+
+// pageText is the plain text of one page
+type pageText struct {
+	PageNumber int    `json:"page_number"`
+	Text       string `json:"text"`
+}
+
+// handler runs one operation on a decoded request
+type handler func(req *request) (interface{}, error)
+
+// handle decodes a request, runs the operation and encodes its response
+func handle(input []byte, h handler) []byte {
+	var req request
+	var result interface{}
+	err := json.Unmarshal(input, &req)
+	if err != nil {
+		err = fmt.Errorf("invalid request: %w", err)
+	} else if len(req.PDF) == 0 {
+		err = fmt.Errorf("invalid request: no pdf")
+	} else {
+		result, err = h(&req)
+	}
+
+	resp := response{Result: result}
+	if err != nil {
+		resp = response{Error: err.Error()}
+	}
+	out, err := json.Marshal(resp)
+	if err != nil {
+		out, _ = json.Marshal(response{Error: fmt.Sprintf("failed to encode response: %v", err)})
+	}
+	return out
+}
+
+// fill fills a form and returns the filled PDF
+func fill(req *request) (interface{}, error) {
+	filled, err := forms.FillWithOptions(req.PDF, req.Data, forms.FillOptions{
+		Password:   []byte(req.Password),
+		Verbose:    req.Verbose,
+		Lock:       req.Lock,
+		AttachData: req.AttachData,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fillResult{PDF: filled}, nil
+}
+
+// extractSchema returns the form type, schema and current values
+func extractSchema(req *request) (interface{}, error) {
+	form, err := forms.Extract(req.PDF, []byte(req.Password), req.Verbose)
+	if err != nil {
+		return nil, err
+	}
+	return schemaResult{Type: form.Type(), Schema: form.Schema(), Values: form.GetValues()}, nil
+}
+
+// extractText returns the text of each page, one line per text baseline
+func extractText(req *request) (interface{}, error) {
+	doc, err := extract.ExtractContent(req.PDF, []byte(req.Password), req.Verbose)
+	if err != nil {
+		return nil, err
+	}
+	pages := make([]pageText, 0, len(doc.Pages))
+	for _, page := range doc.Pages {
+		pages = append(pages, pageText{PageNumber: page.PageNumber, Text: joinText(page.Text)})
+	}
+	return pages, nil
+}
+
+// compareDocuments compares pdf with pdf2
+func compareDocuments(req *request) (interface{}, error) {
+	if len(req.PDF2) == 0 {
+		return nil, fmt.Errorf("invalid request: no pdf2")
+	}
+	return compare.ComparePDFs(req.PDF, req.PDF2, []byte(req.Password), []byte(req.Password2), req.Verbose)
+}
+
+// joinText joins text elements in content order, starting a new line when the
+// baseline moves by more than half the font size
+func joinText(elements []types.TextElement) string {
+	var sb strings.Builder
+	for i, el := range elements {
+		if i > 0 {
+			prev := elements[i-1]
+			if math.Abs(el.Y-prev.Y) > math.Max(prev.FontSize, 1)/2 {
+				sb.WriteByte('\n')
+			} else if el.X > prev.X+prev.Width+0.1 && !strings.HasSuffix(prev.Text, " ") {
+				sb.WriteByte(' ')
+			}
+		}
+		sb.WriteString(el.Text)
+	}
+	return sb.String()
+}
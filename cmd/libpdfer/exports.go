@@ -0,0 +1,39 @@
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import "unsafe"
+
+//export pdfer_fill
+func pdfer_fill(request *C.char) *C.char {
+	return call(request, fill)
+}
+
+//export pdfer_extract_schema
+func pdfer_extract_schema(request *C.char) *C.char {
+	return call(request, extractSchema)
+}
+
+//export pdfer_extract_text
+func pdfer_extract_text(request *C.char) *C.char {
+	return call(request, extractText)
+}
+
+//export pdfer_compare
+func pdfer_compare(request *C.char) *C.char {
+	return call(request, compareDocuments)
+}
+
+//export pdfer_free
+func pdfer_free(response *C.char) {
+	C.free(unsafe.Pointer(response))
+}
+
+// call runs a handler on a C request string and returns the response in memory
+// allocated with malloc, so that it outlives the call and can be freed from C
+func call(request *C.char, h handler) *C.char {
+	return C.CString(string(handle([]byte(C.GoString(request)), h)))
+}
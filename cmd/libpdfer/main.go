@@ -0,0 +1,32 @@
+// Command libpdfer builds pdfer as a C shared library, so that services in
+// Python, Node, Java and other languages can call it in-process instead of
+// running the CLI.
+//
+// Build:
+//
+//	go build -buildmode=c-shared -o libpdfer.so ./cmd/libpdfer
+//
+// which also writes libpdfer.h. Every function takes a NUL-terminated JSON
+// request and returns a NUL-terminated JSON response that the caller must
+// release with pdfer_free:
+//
+//	char *pdfer_fill(const char *request);           // {"pdf", "password", "data", "lock", "attach_data"} -> {"result": {"pdf"}}
+//	char *pdfer_extract_schema(const char *request); // {"pdf", "password"} -> {"result": {"type", "schema", "values"}}
+//	char *pdfer_extract_text(const char *request);   // {"pdf", "password"} -> {"result": [{"page_number", "text"}]}
+//	char *pdfer_compare(const char *request);        // {"pdf", "pdf2", "password", "password2"} -> {"result": {...}}
+//	void pdfer_free(char *response);
+//
+// PDFs are base64 encoded. A failed call returns {"error": "..."}.
+//
+// From Python:
+//
+//	lib = ctypes.CDLL("./libpdfer.so")
+//	lib.pdfer_fill.restype = ctypes.c_void_p
+//	req = json.dumps({"pdf": base64.b64encode(pdf).decode(), "data": {"name": "Jane"}})
+//	ptr = lib.pdfer_fill(req.encode())
+//	resp = json.loads(ctypes.string_at(ptr))
+//	lib.pdfer_free(ctypes.c_void_p(ptr))
+package main
+
+// main is required for a c-shared build but never runs
+func main() {}
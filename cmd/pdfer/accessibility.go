@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/core/parse"
+)
+
+// runAccessibility handles "pdfer accessibility": reports untagged content,
+// figures without alt text, a missing language, fields without tooltips and
+// tab order problems, and exits with status 1 when any are found
+func runAccessibility(args []string) {
+	fs := flag.NewFlagSet("accessibility", flag.ExitOnError)
+	var (
+		inputPDF   = fs.String("input", "", "Path to input PDF file, or - for standard input")
+		password   = fs.String("password", "", "Password if the PDF is encrypted")
+		jsonOutput = fs.Bool("json", false, "Print the report as JSON")
+		verbose    = fs.Bool("verbose", false, "Enable verbose logging")
+	)
+	fs.Parse(args)
+	inputArg(fs, inputPDF)
+
+	if *inputPDF == "" {
+		log.Fatal("Error: -input flag is required")
+	}
+
+	pdfBytes, err := readFile(*inputPDF)
+	if err != nil {
+		log.Fatalf("Error reading PDF: %v", err)
+	}
+	pdf, err := parse.OpenWithOptions(pdfBytes, parse.ParseOptions{
+		Password: []byte(*password),
+		Verbose:  *verbose,
+	})
+	if err != nil {
+		log.Fatalf("Error parsing PDF: %v", err)
+	}
+
+	report, err := extract.AnalyzeAccessibility(pdfBytes, pdf, *verbose)
+	if err != nil {
+		log.Fatalf("Error analyzing PDF: %v", err)
+	}
+
+	if *jsonOutput {
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatalf("Error encoding report: %v", err)
+		}
+		fmt.Println(string(out))
+	} else {
+		for _, issue := range report.Issues {
+			where := "document"
+			if issue.PageNumber > 0 {
+				where = fmt.Sprintf("page %d", issue.PageNumber)
+			}
+			fmt.Printf("FAIL\t%s\t%s\t%s\n", where, issue.Check, issue.Message)
+		}
+		if report.Passed {
+			fmt.Println("Accessibility check passed")
+		} else {
+			fmt.Printf("Accessibility check failed: %d issues\n", len(report.Issues))
+		}
+	}
+
+	if !report.Passed {
+		os.Exit(1)
+	}
+}
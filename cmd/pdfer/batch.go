@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/forms"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// runBatch handles "pdfer batch": fills a form once per row of a CSV or Excel
+// file and writes one PDF per row, exiting with status 1 when any row fails
+func runBatch(args []string) {
+	fs := flag.NewFlagSet("batch", flag.ExitOnError)
+	var (
+		inputPDF    = fs.String("input", "", "Path to the form PDF to fill, or - for standard input")
+		dataFile    = fs.String("data", "", "Path to a CSV or XLSX file with one fill per row, or - for standard input")
+		mappingJSON = fs.String("mapping", "", "Path to a JSON object mapping column headers to field names (default: headers are field names)")
+		sheet       = fs.String("sheet", "", "Sheet of an XLSX file to read (default: the first)")
+		outputDir   = fs.String("output-dir", ".", "Directory to write the filled PDFs to")
+		nameColumn  = fs.String("name-column", "", "Column whose value names each output file, or each bookmark with -combine (default: row-N.pdf)")
+		combine     = fs.String("combine", "", "Write all filled copies to this one PDF, or - for standard output, instead of one file per row")
+		flatten     = fs.Bool("flatten", false, "Flatten each filled copy of a -combine output")
+		password    = fs.String("password", "", "Password if the PDF is encrypted")
+		lock        = fs.String("lock", "", "Make fields read-only after filling: filled or all")
+		appearances = fs.Bool("appearances", false, "Rebuild the appearance streams of filled AcroForm fields")
+		verbose     = fs.Bool("verbose", false, "Enable verbose logging")
+	)
+	fs.Parse(args)
+	inputArg(fs, inputPDF)
+
+	if *inputPDF == "" || *dataFile == "" {
+		log.Fatal("Error: -input and -data flags are required")
+	}
+	if err := checkStdin(*inputPDF, *dataFile); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	fieldLock := types.FieldLock(*lock)
+	if fieldLock != types.LockNone && fieldLock != types.LockFilled && fieldLock != types.LockAll {
+		log.Fatalf("Error: invalid -lock %q: want filled or all", *lock)
+	}
+	if *flatten && *combine == "" {
+		log.Fatal("Error: -flatten requires -combine")
+	}
+
+	var mapping map[string]string
+	if *mappingJSON != "" {
+		mappingBytes, err := readFile(*mappingJSON)
+		if err != nil {
+			log.Fatalf("Error reading mapping: %v", err)
+		}
+		if err := json.Unmarshal(mappingBytes, &mapping); err != nil {
+			log.Fatalf("Error parsing mapping: %v", err)
+		}
+	}
+
+	// A name column the mapping leaves out is read under a key no field has, and
+	// dropped from the rows before filling
+	nameKey, dropName := *nameColumn, false
+	if *nameColumn != "" && mapping != nil {
+		if field, ok := mapping[*nameColumn]; ok {
+			nameKey = field
+		} else {
+			nameKey, dropName = "\x00name", true
+			mapping[*nameColumn] = nameKey
+		}
+	}
+
+	dataBytes, err := readFile(*dataFile)
+	if err != nil {
+		log.Fatalf("Error reading data: %v", err)
+	}
+	rows, err := forms.ReadRows(dataBytes, *sheet, mapping)
+	if err != nil {
+		log.Fatalf("Error reading rows: %v", err)
+	}
+	names := make([]string, len(rows))
+	if *nameColumn != "" {
+		for i, row := range rows {
+			if value, ok := row[nameKey]; ok {
+				names[i] = fmt.Sprint(value)
+			}
+			if dropName {
+				delete(row, nameKey)
+			}
+		}
+	}
+
+	pdfBytes, err := readFile(*inputPDF)
+	if err != nil {
+		log.Fatalf("Error reading PDF: %v", err)
+	}
+	tmpl, err := forms.NewTemplate(pdfBytes, []byte(*password), *verbose)
+	if err != nil {
+		log.Fatalf("Error loading form: %v", err)
+	}
+	opts := forms.FillOptions{Verbose: *verbose, Lock: fieldLock, Appearances: *appearances}
+
+	if *combine != "" {
+		merged, err := tmpl.MergeRows(rows, forms.MergeOptions{
+			FillOptions: opts,
+			Flatten:     *flatten,
+			Title:       func(row int, _ types.FormData) string { return names[row] },
+		})
+		if err != nil {
+			log.Fatalf("Error merging rows: %v", err)
+		}
+		if err := writeOutput(*combine, merged); err != nil {
+			log.Fatalf("Error writing PDF: %v", err)
+		}
+		fmt.Fprintf(statusWriter(*combine), "Merged %d rows into %s\n", len(rows), *combine)
+		return
+	}
+	if err := os.MkdirAll(*outputDir, 0755); err != nil {
+		log.Fatalf("Error creating output directory: %v", err)
+	}
+
+	written, failed := 0, 0
+	used := make(map[string]bool, len(rows))
+	err = tmpl.FillRows(rows, opts, func(row int, filled []byte, err error) error {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Row %d: %v\n", row+1, err)
+			failed++
+			return nil
+		}
+		name := outputFileName(names[row], fmt.Sprintf("row-%d", row+1))
+		for base, n := name, 2; used[name]; n++ {
+			name = fmt.Sprintf("%s-%d", base, n)
+		}
+		used[name] = true
+		path := filepath.Join(*outputDir, name+".pdf")
+		if err := os.WriteFile(path, filled, 0644); err != nil {
+			return fmt.Errorf("row %d: %w", row+1, err)
+		}
+		written++
+		if *verbose {
+			log.Printf("Row %d: wrote %s", row+1, path)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Error writing PDF: %v", err)
+	}
+
+	fmt.Printf("Filled %d of %d rows into %s\n", written, len(rows), *outputDir)
+	if failed > 0 {
+		fmt.Printf("%d rows failed\n", failed)
+		os.Exit(1)
+	}
+}
+
+// outputFileName makes a cell value safe to use as a file name, or returns
+// fallback when nothing usable is left
+func outputFileName(value, fallback string) string {
+	name := strings.Map(func(r rune) rune {
+		if r < ' ' || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(value))
+	if name == "" || name == "." || name == ".." {
+		return fallback
+	}
+	return name
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/benedoc-inc/pdfer"
+)
+
+// runCheck handles "pdfer check": evaluates a PDF against a JSON policy and exits
+// with status 1 when any rule fails
+func runCheck(args []string) {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	var (
+		inputPDF   = fs.String("input", "", "Path to input PDF file, or - for standard input")
+		policyJSON = fs.String("policy", "", "Path to JSON policy file")
+		password   = fs.String("password", "", "Password if the PDF is encrypted")
+		jsonOutput = fs.Bool("json", false, "Print the report as JSON")
+		verbose    = fs.Bool("verbose", false, "Enable verbose logging")
+	)
+	fs.Parse(args)
+	inputArg(fs, inputPDF)
+
+	if *inputPDF == "" || *policyJSON == "" {
+		log.Fatal("Error: -input and -policy flags are required")
+	}
+	if err := checkStdin(*inputPDF, *policyJSON); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	policyBytes, err := readFile(*policyJSON)
+	if err != nil {
+		log.Fatalf("Error reading policy: %v", err)
+	}
+	policy, err := pdfer.ParsePolicy(policyBytes)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	pdfBytes, err := readFile(*inputPDF)
+	if err != nil {
+		log.Fatalf("Error reading PDF: %v", err)
+	}
+
+	report, err := pdfer.CheckPolicy(pdfBytes, []byte(*password), policy, *verbose)
+	if err != nil {
+		log.Fatalf("Error checking PDF: %v", err)
+	}
+
+	if *jsonOutput {
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatalf("Error encoding report: %v", err)
+		}
+		fmt.Println(string(out))
+	} else {
+		for _, result := range report.Results {
+			status := "PASS"
+			if !result.Passed {
+				status = "FAIL"
+			}
+			fmt.Printf("%s\t%s\t%s\n", status, result.Rule, result.Message)
+		}
+		if report.Passed {
+			fmt.Println("Policy check passed")
+		} else {
+			fmt.Printf("Policy check failed: %d of %d rules\n", len(report.Failed()), len(report.Results))
+		}
+	}
+
+	if !report.Passed {
+		os.Exit(1)
+	}
+}
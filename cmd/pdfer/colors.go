@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/benedoc-inc/pdfer/core/manipulate"
+)
+
+// runConvertColors handles "pdfer convert-colors": converts page content and
+// images to CMYK or grayscale, optionally embedding an ICC output intent
+func runConvertColors(args []string) {
+	fs := flag.NewFlagSet("convert-colors", flag.ExitOnError)
+	var (
+		inputPDF  = fs.String("input", "", "Path to input PDF file, or - for standard input")
+		outputPDF = fs.String("output", "", "Path to output PDF file, or - for standard output")
+		password  = fs.String("password", "", "Password if the PDF is encrypted")
+		target    = fs.String("target", "cmyk", "Color space to convert to: cmyk or gray")
+		profile   = fs.String("icc", "", "Path to an ICC profile of the output device, embedded as the output intent")
+		condition = fs.String("output-condition", "", "Output condition identifier of the output intent, e.g. FOGRA39")
+		quality   = fs.Int("jpeg-quality", 90, "Quality of JPEG images re-encoded in gray, 1-100")
+		verbose   = fs.Bool("verbose", false, "Enable verbose logging")
+	)
+	fs.Parse(args)
+	inputArg(fs, inputPDF)
+
+	if *inputPDF == "" || *outputPDF == "" {
+		log.Fatal("Error: -input and -output flags are required")
+	}
+
+	pdfBytes, err := readFile(*inputPDF)
+	if err != nil {
+		log.Fatalf("Error reading PDF: %v", err)
+	}
+	opts := manipulate.ColorConversionOptions{
+		Target:          manipulate.ColorTarget(*target),
+		OutputCondition: *condition,
+		JPEGQuality:     *quality,
+	}
+	if *profile != "" {
+		if opts.ICCProfile, err = readFile(*profile); err != nil {
+			log.Fatalf("Error reading ICC profile: %v", err)
+		}
+	}
+
+	out, err := manipulate.ConvertColors(pdfBytes, []byte(*password), opts, *verbose)
+	if err != nil {
+		log.Fatalf("Error converting colors: %v", err)
+	}
+
+	if err := writeOutput(*outputPDF, out); err != nil {
+		log.Fatalf("Error writing PDF: %v", err)
+	}
+
+	status := statusWriter(*outputPDF)
+	fmt.Fprintf(status, "Successfully converted PDF to %s\n", *target)
+	fmt.Fprintf(status, "Input:  %s\n", *inputPDF)
+	fmt.Fprintf(status, "Output: %s\n", *outputPDF)
+}
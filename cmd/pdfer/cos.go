@@ -0,0 +1,614 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+var (
+	cosObjHeader  = regexp.MustCompile(`^\d+\s+(\d+)\s+obj\b`)
+	cosRefPattern = regexp.MustCompile(`(\d+)\s+(\d+)\s+R\b`)
+	cosKeyRef     = regexp.MustCompile(`/([^\s/<>\[\]()]+)\s+(\d+)\s+\d+\s+R\b`)
+	cosLength     = regexp.MustCompile(`/Length\s+(\d+)(\s+\d+\s+R)?`)
+	cosFilter     = regexp.MustCompile(`/Filter\s*(\[[^\]]*\]|/[^\s/<>\[\]()]+)`)
+	cosPageType   = regexp.MustCompile(`/Type\s*/(Pages|Page)\b`)
+	cosKids       = regexp.MustCompile(`/Kids\s*\[([^\]]*)\]`)
+	cosCount      = regexp.MustCompile(`/Count\s+(-?\d+)`)
+	cosMediaBox   = regexp.MustCompile(`/MediaBox\s*\[([^\]]*)\]`)
+	cosPagesRef   = regexp.MustCompile(`/Pages\s+(\d+)\s+\d+\s+R`)
+)
+
+// cosFilters are the filters that "pdfer cos" decodes; image filters are left
+// alone, since their output isn't useful in a dump
+var cosFilters = map[string]bool{
+	"FlateDecode": true, "ASCIIHexDecode": true, "ASCII85Decode": true, "RunLengthDecode": true,
+}
+
+// cosInspector dumps the objects of a parsed PDF
+type cosInspector struct {
+	pdf     *parse.PDF
+	out     io.Writer
+	raw     bool // Print stream data as stored instead of decoding it
+	maxData int  // Stream bytes to print, 0 for all
+}
+
+// runCos handles "pdfer cos": dumps objects by number (resolved, decrypted and
+// with their streams decoded), the trailer, the cross-reference chain or the
+// page tree, or browses objects interactively with -i
+func runCos(args []string) {
+	fs := flag.NewFlagSet("cos", flag.ExitOnError)
+	var (
+		inputPDF    = fs.String("input", "", "Path to input PDF file, or - for standard input")
+		password    = fs.String("password", "", "Password if the PDF is encrypted")
+		objects     = fs.String("obj", "", "Comma-separated object numbers to dump (also read from the arguments after the input)")
+		pages       = fs.Bool("pages", false, "Print the page tree")
+		xref        = fs.Bool("xref", false, "Print the cross-reference sections of the /Prev chain")
+		interactive = fs.Bool("i", false, "Browse objects interactively, following references by key")
+		raw         = fs.Bool("raw", false, "Print stream data as stored instead of decoding it")
+		data        = fs.Bool("data", false, "Write only the decoded stream data of the object to standard output")
+		maxData     = fs.Int("max", 4096, "Maximum number of stream bytes to print, 0 for all")
+		verbose     = fs.Bool("verbose", false, "Enable verbose logging")
+	)
+	fs.Parse(args)
+	inputArg(fs, inputPDF)
+
+	if *inputPDF == "" {
+		log.Fatal("Error: -input flag is required")
+	}
+	objNums, err := cosObjectNumbers(*objects, fs.Args(), *inputPDF)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if *interactive && *inputPDF == stdio {
+		log.Fatal("Error: -i reads commands from standard input, so the PDF must be a file")
+	}
+
+	pdfBytes, err := readFile(*inputPDF)
+	if err != nil {
+		log.Fatalf("Error reading PDF: %v", err)
+	}
+	warnings := types.NewWarningCollector(true)
+	pdf, err := parse.OpenWithOptions(pdfBytes, parse.ParseOptions{
+		Password: []byte(*password),
+		Verbose:  *verbose,
+		Warnings: warnings,
+	})
+	if err != nil {
+		log.Fatalf("Error parsing PDF: %v", err)
+	}
+	for _, w := range warnings.Warnings() {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", w.Message)
+	}
+
+	c := &cosInspector{pdf: pdf, out: os.Stdout, raw: *raw, maxData: *maxData}
+
+	if *data {
+		if len(objNums) != 1 {
+			log.Fatal("Error: -data needs exactly one object number")
+		}
+		streamData, err := c.streamData(objNums[0])
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		os.Stdout.Write(streamData)
+		return
+	}
+
+	switch {
+	case *interactive:
+		c.browse(os.Stdin)
+	case *pages:
+		c.printPageTree()
+	case *xref:
+		c.printXRef()
+	case len(objNums) > 0:
+		for i, objNum := range objNums {
+			if i > 0 {
+				fmt.Fprintln(c.out)
+			}
+			if err := c.dump(objNum); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+		}
+	default:
+		c.printTrailer()
+	}
+}
+
+// cosObjectNumbers collects the object numbers of -obj and of the arguments,
+// skipping the input file when it was given as the first argument
+func cosObjectNumbers(list string, args []string, input string) ([]int, error) {
+	var fields []string
+	if list != "" {
+		fields = strings.Split(list, ",")
+	}
+	if len(args) > 0 && args[0] == input {
+		args = args[1:]
+	}
+	fields = append(fields, args...)
+
+	var objNums []int
+	for _, field := range fields {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid object number %q", field)
+		}
+		objNums = append(objNums, n)
+	}
+	return objNums, nil
+}
+
+// printTrailer prints the document overview: version, trailer entries and the catalog
+func (c *cosInspector) printTrailer() {
+	fmt.Fprintf(c.out, "PDF %s, %d revision(s), %d objects", c.pdf.Version(), c.pdf.RevisionCount(), c.pdf.ObjectCount())
+	if c.pdf.IsEncrypted() {
+		fmt.Fprint(c.out, ", encrypted")
+	}
+	fmt.Fprintln(c.out)
+
+	trailer := c.pdf.Trailer()
+	if trailer == nil {
+		fmt.Fprintln(c.out, "no trailer")
+		return
+	}
+	fmt.Fprintf(c.out, "trailer\n<< /Size %d", trailer.Size)
+	for _, entry := range []struct{ key, ref string }{{"Root", trailer.RootRef}, {"Info", trailer.InfoRef}, {"Encrypt", trailer.EncryptRef}} {
+		if entry.ref != "" {
+			fmt.Fprintf(c.out, " /%s %s", entry.key, entry.ref)
+		}
+	}
+	fmt.Fprintln(c.out, " >>")
+
+	if match := cosRefPattern.FindStringSubmatch(trailer.RootRef); match != nil {
+		root, _ := strconv.Atoi(match[1])
+		fmt.Fprintln(c.out)
+		if err := c.dump(root); err != nil {
+			fmt.Fprintf(c.out, "catalog: %v\n", err)
+		}
+	}
+}
+
+// printXRef prints the cross-reference sections of the /Prev chain, oldest first
+func (c *cosInspector) printXRef() {
+	chain := c.pdf.XRefChain()
+	if len(chain) == 0 {
+		fmt.Fprintln(c.out, "no cross-reference chain (objects were located by scanning the file)")
+		return
+	}
+	for i, section := range chain {
+		kind := "table"
+		if section.Stream {
+			kind = "stream"
+		}
+		fmt.Fprintf(c.out, "revision %d: xref %s at %d", i+1, kind, section.Offset)
+		if section.Prev > 0 {
+			fmt.Fprintf(c.out, ", /Prev %d", section.Prev)
+		}
+		if section.XRefStm > 0 {
+			fmt.Fprintf(c.out, ", /XRefStm %d", section.XRefStm)
+		}
+		fmt.Fprintln(c.out)
+		fmt.Fprintf(c.out, "  trailer: /Size %d", section.Size)
+		for _, entry := range []struct{ key, ref string }{{"Root", section.Root}, {"Info", section.Info}, {"Encrypt", section.Encrypt}} {
+			if entry.ref != "" {
+				fmt.Fprintf(c.out, " /%s %s", entry.key, entry.ref)
+			}
+		}
+		fmt.Fprintln(c.out)
+		fmt.Fprintf(c.out, "  objects: %s\n", cosNumberList(section.Objects))
+		if len(section.Compressed) > 0 {
+			fmt.Fprintf(c.out, "  in object streams: %s\n", cosNumberList(section.Compressed))
+		}
+		if len(section.Free) > 0 {
+			fmt.Fprintf(c.out, "  freed: %s\n", cosNumberList(section.Free))
+		}
+	}
+}
+
+// cosNumberList formats ascending object numbers, collapsing runs into ranges
+func cosNumberList(nums []int) string {
+	if len(nums) == 0 {
+		return "none"
+	}
+	var parts []string
+	for i := 0; i < len(nums); {
+		j := i
+		for j+1 < len(nums) && nums[j+1] == nums[j]+1 {
+			j++
+		}
+		if j > i {
+			parts = append(parts, fmt.Sprintf("%d-%d", nums[i], nums[j]))
+		} else {
+			parts = append(parts, strconv.Itoa(nums[i]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, " ")
+}
+
+// printPageTree prints the page tree from the catalog's /Pages, with the page
+// number, object and media box of each page
+func (c *cosInspector) printPageTree() {
+	trailer := c.pdf.Trailer()
+	if trailer == nil {
+		fmt.Fprintln(c.out, "no trailer")
+		return
+	}
+	match := cosRefPattern.FindStringSubmatch(trailer.RootRef)
+	if match == nil {
+		fmt.Fprintln(c.out, "no /Root in the trailer")
+		return
+	}
+	root, _ := strconv.Atoi(match[1])
+	catalog, err := c.pdf.GetObject(root)
+	if err != nil {
+		fmt.Fprintf(c.out, "catalog: %v\n", err)
+		return
+	}
+	pagesMatch := cosPagesRef.FindSubmatch(cosDict(catalog))
+	if pagesMatch == nil {
+		fmt.Fprintln(c.out, "no /Pages in the catalog")
+		return
+	}
+	pagesNum, _ := strconv.Atoi(string(pagesMatch[1]))
+	page := 0
+	c.printPageNode(pagesNum, 0, &page, make(map[int]bool))
+}
+
+// printPageNode prints a node of the page tree and its kids
+func (c *cosInspector) printPageNode(objNum, depth int, page *int, visited map[int]bool) {
+	indent := strings.Repeat("  ", depth)
+	if visited[objNum] {
+		fmt.Fprintf(c.out, "%s%d 0 R: cycle in the page tree\n", indent, objNum)
+		return
+	}
+	visited[objNum] = true
+
+	obj, err := c.pdf.GetObject(objNum)
+	if err != nil {
+		fmt.Fprintf(c.out, "%s%d 0 R: %v\n", indent, objNum, err)
+		return
+	}
+	dict := cosDict(obj)
+	nodeType := ""
+	if match := cosPageType.FindSubmatch(dict); match != nil {
+		nodeType = string(match[1])
+	}
+	kids := cosKids.FindSubmatch(dict)
+	if nodeType == "Pages" || (nodeType == "" && kids != nil) {
+		count := "?"
+		if match := cosCount.FindSubmatch(dict); match != nil {
+			count = string(match[1])
+		}
+		fmt.Fprintf(c.out, "%sPages %d 0 R (Count %s)\n", indent, objNum, count)
+		if kids != nil {
+			for _, kid := range cosRefPattern.FindAllSubmatch(kids[1], -1) {
+				kidNum, _ := strconv.Atoi(string(kid[1]))
+				c.printPageNode(kidNum, depth+1, page, visited)
+			}
+		}
+		return
+	}
+
+	*page++
+	fmt.Fprintf(c.out, "%sPage %d: %d 0 R", indent, *page, objNum)
+	if match := cosMediaBox.FindSubmatch(dict); match != nil {
+		fmt.Fprintf(c.out, " MediaBox [%s]", strings.Join(strings.Fields(string(match[1])), " "))
+	}
+	if nodeType == "" {
+		fmt.Fprint(c.out, " (no /Type /Page)")
+	}
+	fmt.Fprintln(c.out)
+}
+
+// dump prints an object with its location, decoded stream data and references
+func (c *cosInspector) dump(objNum int) error {
+	obj, err := c.pdf.GetObject(objNum)
+	if err != nil {
+		return err
+	}
+
+	gen := "0"
+	if match := cosObjHeader.FindSubmatch(obj); match != nil {
+		gen = string(match[1])
+	}
+	fmt.Fprintf(c.out, "%d %s obj", objNum, gen)
+	if ref, ok := c.pdf.Ref(objNum); ok {
+		if ref.InStream {
+			fmt.Fprintf(c.out, "  %% in object stream %d, index %d", ref.StreamObjNum, ref.StreamIndex)
+		} else {
+			fmt.Fprintf(c.out, "  %% at offset %d", ref.Offset)
+		}
+	} else if c.pdf.IsFree(objNum) {
+		fmt.Fprint(c.out, "  % free")
+	}
+	fmt.Fprintln(c.out)
+
+	content := cosContent(obj)
+	streamAt := cosStreamKeyword(content)
+	if streamAt == -1 {
+		fmt.Fprintln(c.out, string(bytes.TrimSpace(content)))
+	} else {
+		fmt.Fprintln(c.out, string(bytes.TrimSpace(content[:streamAt])))
+		c.printStream(content)
+	}
+	fmt.Fprintln(c.out, "endobj")
+
+	if refs := cosReferences(cosDict(content)); len(refs) > 0 {
+		fmt.Fprintf(c.out, "%% references: %s\n", strings.Join(refs, ", "))
+	}
+	return nil
+}
+
+// printStream prints the stream of an object, decoded unless -raw is given
+func (c *cosInspector) printStream(content []byte) {
+	stored := c.storedStream(content)
+	data := stored
+	note := fmt.Sprintf("%d bytes", len(stored))
+	if !c.raw {
+		decoded, applied, err := cosDecode(stored, cosDict(content))
+		switch {
+		case err != nil:
+			note += fmt.Sprintf(", not decoded: %v", err)
+		case len(applied) > 0:
+			data = decoded
+			note += fmt.Sprintf(", %d after %s", len(decoded), strings.Join(applied, " "))
+		}
+	}
+
+	fmt.Fprintf(c.out, "stream  %% %s\n", note)
+	shown := data
+	if c.maxData > 0 && len(shown) > c.maxData {
+		shown = shown[:c.maxData]
+	}
+	if cosIsText(shown) {
+		c.out.Write(shown)
+		if len(shown) > 0 && shown[len(shown)-1] != '\n' {
+			fmt.Fprintln(c.out)
+		}
+	} else {
+		fmt.Fprint(c.out, hex.Dump(shown))
+	}
+	if len(shown) < len(data) {
+		fmt.Fprintf(c.out, "%% ... %d more bytes (use -max 0 to print all)\n", len(data)-len(shown))
+	}
+	fmt.Fprintln(c.out, "endstream")
+}
+
+// streamData returns the stream data of an object, decoded unless -raw is given
+func (c *cosInspector) streamData(objNum int) ([]byte, error) {
+	obj, err := c.pdf.GetObject(objNum)
+	if err != nil {
+		return nil, err
+	}
+	content := cosContent(obj)
+	if cosStreamKeyword(content) == -1 {
+		return nil, fmt.Errorf("object %d is not a stream", objNum)
+	}
+	stored := c.storedStream(content)
+	if c.raw {
+		return stored, nil
+	}
+	decoded, _, err := cosDecode(stored, cosDict(content))
+	return decoded, err
+}
+
+// storedStream returns the stream data of an object's content as stored,
+// using /Length (which may be indirect) when it fits
+func (c *cosInspector) storedStream(content []byte) []byte {
+	start := cosStreamKeyword(content) + len("stream")
+	if start < len(content) && content[start] == '\r' {
+		start++
+	}
+	if start < len(content) && content[start] == '\n' {
+		start++
+	}
+
+	length := -1
+	if match := cosLength.FindSubmatch(content[:start]); match != nil {
+		length = cosAtoi(match[1])
+		if len(match[2]) > 0 {
+			// Indirect /Length
+			length = -1
+			if obj, err := c.pdf.GetObject(cosAtoi(match[1])); err == nil {
+				if n, err := strconv.Atoi(string(cosContent(obj))); err == nil {
+					length = n
+				}
+			}
+		}
+	}
+	if length >= 0 && start+length <= len(content) {
+		return content[start : start+length]
+	}
+	end := bytes.LastIndex(content, []byte("endstream"))
+	if end < start {
+		return content[start:]
+	}
+	return bytes.TrimRight(content[start:end], "\r\n")
+}
+
+// cosAtoi parses a decimal number, 0 if it isn't one
+func cosAtoi(b []byte) int {
+	n, _ := strconv.Atoi(string(b))
+	return n
+}
+
+// cosDecode applies the filters of a stream dictionary in order, stopping at
+// the first one it doesn't decode. It returns the filters it applied.
+func cosDecode(data, dict []byte) ([]byte, []string, error) {
+	match := cosFilter.FindSubmatch(dict)
+	if match == nil {
+		return data, nil, nil
+	}
+	var applied []string
+	for _, filter := range strings.Fields(strings.NewReplacer("[", " ", "]", " ", "/", " ").Replace(string(match[1]))) {
+		if !cosFilters[filter] {
+			break
+		}
+		decoded, err := parse.DecodeFilter(data, filter)
+		if err != nil {
+			return data, applied, fmt.Errorf("%s: %v", filter, err)
+		}
+		data = decoded
+		applied = append(applied, filter)
+	}
+	return data, applied, nil
+}
+
+// browse reads commands from r: an object number (or "N G R") dumps that
+// object, a key follows the reference stored under it in the current object,
+// and "back" returns to the previous object
+func (c *cosInspector) browse(r io.Reader) {
+	fmt.Fprintln(c.out, `Enter an object number, a key of the current object to follow its reference, or "help".`)
+	var history []int
+	current := -1
+	show := func(objNum int) {
+		if err := c.dump(objNum); err != nil {
+			fmt.Fprintf(c.out, "error: %v\n", err)
+			return
+		}
+		if current >= 0 && current != objNum {
+			history = append(history, current)
+		}
+		current = objNum
+	}
+
+	scanner := bufio.NewScanner(r)
+	for {
+		fmt.Fprint(c.out, "cos> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(c.out)
+			return
+		}
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case line == "q" || line == "quit" || line == "exit":
+			return
+		case line == "help" || line == "?":
+			fmt.Fprintln(c.out, "  N, N G R   dump object N")
+			fmt.Fprintln(c.out, "  /Key, Key  follow the reference under Key in the current object")
+			fmt.Fprintln(c.out, "  back       return to the previous object")
+			fmt.Fprintln(c.out, "  trailer    print the trailer and the catalog")
+			fmt.Fprintln(c.out, "  xref       print the cross-reference chain")
+			fmt.Fprintln(c.out, "  pages      print the page tree")
+			fmt.Fprintln(c.out, "  quit       leave")
+		case line == "back" || line == "..":
+			if len(history) == 0 {
+				fmt.Fprintln(c.out, "no previous object")
+				continue
+			}
+			prev := history[len(history)-1]
+			history = history[:len(history)-1]
+			current = -1
+			show(prev)
+		case line == "trailer":
+			c.printTrailer()
+		case line == "xref":
+			c.printXRef()
+		case line == "pages":
+			c.printPageTree()
+		default:
+			if match := cosRefPattern.FindStringSubmatch(line); match != nil && match[0] == line {
+				show(cosAtoi([]byte(match[1])))
+			} else if n, err := strconv.Atoi(line); err == nil {
+				show(n)
+			} else if objNum, ok := c.follow(current, strings.TrimPrefix(line, "/")); ok {
+				show(objNum)
+			} else {
+				fmt.Fprintf(c.out, "unknown command or key %q\n", line)
+			}
+		}
+	}
+}
+
+// follow returns the object referenced under key in an object's dictionary
+func (c *cosInspector) follow(objNum int, key string) (int, bool) {
+	if objNum < 0 {
+		return 0, false
+	}
+	obj, err := c.pdf.GetObject(objNum)
+	if err != nil {
+		return 0, false
+	}
+	for _, match := range cosKeyRef.FindAllSubmatch(cosDict(cosContent(obj)), -1) {
+		if string(match[1]) == key {
+			return cosAtoi(match[2]), true
+		}
+	}
+	return 0, false
+}
+
+// cosContent strips the "N G obj" header and "endobj" keyword from an object
+func cosContent(obj []byte) []byte {
+	content := bytes.TrimSpace(obj)
+	if loc := cosObjHeader.FindIndex(content); loc != nil {
+		content = content[loc[1]:]
+	}
+	return bytes.TrimSpace(bytes.TrimSuffix(content, []byte("endobj")))
+}
+
+// cosDict returns an object's content up to its stream keyword
+func cosDict(content []byte) []byte {
+	if i := cosStreamKeyword(content); i != -1 {
+		return content[:i]
+	}
+	return content
+}
+
+// cosStreamKeyword returns the position of the stream keyword after a
+// dictionary, or -1 for objects that aren't streams
+func cosStreamKeyword(content []byte) int {
+	if !bytes.HasPrefix(content, []byte("<<")) {
+		return -1
+	}
+	return bytes.Index(content, []byte("stream"))
+}
+
+// cosReferences lists the references of an object, each with the key it is
+// stored under when it is a dictionary entry
+func cosReferences(content []byte) []string {
+	keyed := make(map[int]string)
+	for _, loc := range cosKeyRef.FindAllSubmatchIndex(content, -1) {
+		keyed[loc[4]] = string(content[loc[2]:loc[3]])
+	}
+	var refs []string
+	seen := make(map[string]bool)
+	for _, loc := range cosRefPattern.FindAllSubmatchIndex(content, -1) {
+		ref := string(content[loc[0]:loc[1]])
+		if key, ok := keyed[loc[0]]; ok {
+			ref = "/" + key + " " + ref
+		}
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// cosIsText reports whether data is printable text rather than binary
+func cosIsText(data []byte) bool {
+	for _, b := range data {
+		if b < 0x20 && b != '\n' && b != '\r' && b != '\t' && b != '\f' {
+			return false
+		}
+		if b >= 0x7f {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/core/parse"
+)
+
+// runExtract handles "pdfer extract": writes the document's content as JSON,
+// or a page's vector graphics and text as SVG with -svg
+func runExtract(args []string) {
+	fs := flag.NewFlagSet("extract", flag.ExitOnError)
+	var (
+		inputPDF   = fs.String("input", "", "Path to input PDF file, or - for standard input")
+		outputJSON = fs.String("output", stdio, "Path to output JSON or SVG file, or - for standard output")
+		password   = fs.String("password", "", "Password if the PDF is encrypted")
+		profile    = fs.String("profile", "", "Extraction profile: fast (for indexing) or accurate (for display)")
+		svgPage    = fs.Int("svg", 0, "Write this page (1-based) as SVG instead of the content as JSON")
+		textOnly   = fs.Bool("text-only", false, "Extract page text only")
+		imagesOnly = fs.Bool("images-only", false, "Extract images only")
+		skipAnnots = fs.Bool("skip-annotations", false, "Do not extract annotations")
+		skipGraph  = fs.Bool("skip-graphics", false, "Do not extract paths, rectangles and colors")
+		verbose    = fs.Bool("verbose", false, "Enable verbose logging")
+	)
+	fs.Parse(args)
+	inputArg(fs, inputPDF)
+
+	if *inputPDF == "" {
+		log.Fatal("Error: -input flag is required")
+	}
+	extractProfile, err := extract.ParseExtractProfile(*profile)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	pdfBytes, err := readFile(*inputPDF)
+	if err != nil {
+		log.Fatalf("Error reading PDF: %v", err)
+	}
+
+	if *svgPage > 0 {
+		pdf, err := parse.OpenWithOptions(pdfBytes, parse.ParseOptions{Password: []byte(*password), Verbose: *verbose})
+		if err != nil {
+			log.Fatalf("Error parsing PDF: %v", err)
+		}
+		svg, err := extract.PageToSVG(pdf, *svgPage, *verbose)
+		if err != nil {
+			log.Fatalf("Error converting page: %v", err)
+		}
+		if err := writeOutput(*outputJSON, []byte(svg)); err != nil {
+			log.Fatalf("Error writing output: %v", err)
+		}
+		return
+	}
+
+	doc, err := extract.ExtractContentWithOptions(pdfBytes, extract.ExtractOptions{
+		Password: []byte(*password),
+		Verbose:  *verbose,
+		Profile:  extractProfile,
+
+		TextOnly:        *textOnly,
+		ImagesOnly:      *imagesOnly,
+		SkipAnnotations: *skipAnnots,
+		SkipGraphics:    *skipGraph,
+	})
+	if err != nil {
+		log.Fatalf("Error extracting content: %v", err)
+	}
+
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		log.Fatalf("Error encoding content: %v", err)
+	}
+	if err := writeOutput(*outputJSON, append(out, '\n')); err != nil {
+		log.Fatalf("Error writing output: %v", err)
+	}
+}
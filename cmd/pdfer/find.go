@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/core/manipulate"
+	"github.com/benedoc-inc/pdfer/core/parse"
+)
+
+// findPresets are named patterns for -pattern
+var findPresets = map[string]string{
+	"ssn":   `\b\d{3}-\d{2}-\d{4}\b`,
+	"email": `[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`,
+}
+
+// runFind handles "pdfer find": searches page text for a regular expression and
+// lists the matches with their page and area, or redacts them with -redact
+func runFind(args []string) {
+	fs := flag.NewFlagSet("find", flag.ExitOnError)
+	var (
+		inputPDF   = fs.String("input", "", "Path to input PDF file, or - for standard input")
+		password   = fs.String("password", "", "Password if the PDF is encrypted")
+		pattern    = fs.String("pattern", "", "Regular expression to search for, or a preset: ssn, email")
+		ignoreCase = fs.Bool("i", false, "Match case-insensitively")
+		jsonOutput = fs.Bool("json", false, "Print matches as JSON")
+		redact     = fs.Bool("redact", false, "Redact the matches and write the result to -output")
+		outputPDF  = fs.String("output", "", "Path to the redacted PDF file, or - for standard output")
+		verbose    = fs.Bool("verbose", false, "Enable verbose logging")
+	)
+	fs.Parse(args)
+	inputArg(fs, inputPDF)
+
+	if *inputPDF == "" || *pattern == "" {
+		log.Fatal("Error: -input and -pattern flags are required")
+	}
+	if *redact && *outputPDF == "" {
+		log.Fatal("Error: -redact requires -output")
+	}
+	expr := *pattern
+	if preset, ok := findPresets[strings.ToLower(expr)]; ok {
+		expr = preset
+	}
+	if *ignoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		log.Fatalf("Error: invalid pattern: %v", err)
+	}
+
+	pdfBytes, err := readFile(*inputPDF)
+	if err != nil {
+		log.Fatalf("Error reading PDF: %v", err)
+	}
+
+	pdf, err := parse.OpenWithOptions(pdfBytes, parse.ParseOptions{
+		Password: []byte(*password),
+		Verbose:  *verbose,
+	})
+	if err != nil {
+		log.Fatalf("Error parsing PDF: %v", err)
+	}
+
+	matches, err := extract.FindText(pdfBytes, pdf, re, *verbose)
+	if err != nil {
+		log.Fatalf("Error searching text: %v", err)
+	}
+
+	if *redact {
+		status := statusWriter(*outputPDF)
+		if len(matches) == 0 {
+			fmt.Fprintln(status, "No matches; nothing to redact")
+			return
+		}
+		out, err := manipulate.Redact(pdfBytes, []byte(*password), manipulate.RedactionAreas(matches), manipulate.RedactOptions{}, *verbose)
+		if err != nil {
+			log.Fatalf("Error redacting PDF: %v", err)
+		}
+		if err := writeOutput(*outputPDF, out); err != nil {
+			log.Fatalf("Error writing PDF: %v", err)
+		}
+		fmt.Fprintf(status, "Redacted %d matches\n", len(matches))
+		fmt.Fprintf(status, "Output: %s\n", *outputPDF)
+		return
+	}
+
+	if *jsonOutput {
+		out, err := json.MarshalIndent(matches, "", "  ")
+		if err != nil {
+			log.Fatalf("Error encoding matches: %v", err)
+		}
+		fmt.Println(string(out))
+		return
+	}
+
+	for _, match := range matches {
+		var rects []string
+		for _, r := range match.Rects {
+			rects = append(rects, fmt.Sprintf("[%.1f %.1f %.1f %.1f]", r.LowerX, r.LowerY, r.UpperX, r.UpperY))
+		}
+		fmt.Printf("page %d\t%q\t%s\n", match.PageNumber, match.Text, strings.Join(rects, " "))
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/benedoc-inc/pdfer/forms"
+)
+
+// runFlatten handles "pdfer flatten": bakes a filled form's field appearances
+// into its pages and removes the interactive form
+func runFlatten(args []string) {
+	fs := flag.NewFlagSet("flatten", flag.ExitOnError)
+	var (
+		inputPDF    = fs.String("input", "", "Path to input PDF file, or - for standard input")
+		outputPDF   = fs.String("output", stdio, "Path to output PDF file, or - for standard output")
+		password    = fs.String("password", "", "Password if the PDF is encrypted")
+		appearances = fs.Bool("appearances", false, "Rebuild the appearance streams of all fields from their values first")
+		fromXFA     = fs.Bool("xfa", false, "Show the values of a static XFA form's data rather than its widgets' own")
+		verbose     = fs.Bool("verbose", false, "Enable verbose logging")
+	)
+	fs.Parse(args)
+	inputArg(fs, inputPDF)
+
+	if *inputPDF == "" {
+		log.Fatal("Error: -input flag is required")
+	}
+
+	pdfBytes, err := readFile(*inputPDF)
+	if err != nil {
+		log.Fatalf("Error reading PDF: %v", err)
+	}
+	flattened, err := forms.Flatten(pdfBytes, forms.FlattenOptions{
+		Password:    []byte(*password),
+		Verbose:     *verbose,
+		Appearances: *appearances,
+		XFA:         *fromXFA,
+	})
+	if err != nil {
+		log.Fatalf("Error flattening form: %v", err)
+	}
+	if err := writeOutput(*outputPDF, flattened); err != nil {
+		log.Fatalf("Error writing PDF: %v", err)
+	}
+	fmt.Fprintf(statusWriter(*outputPDF), "Flattened form: %s\n", *outputPDF)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/benedoc-inc/pdfer/core/manipulate"
+)
+
+// runInvoice handles "pdfer invoice": embeds a CII invoice XML to make a
+// Factur-X/ZUGFeRD hybrid invoice, or extracts the XML with -extract
+func runInvoice(args []string) {
+	fs := flag.NewFlagSet("invoice", flag.ExitOnError)
+	var (
+		inputPDF     = fs.String("input", "", "Path to input PDF file, or - for standard input")
+		invoiceXML   = fs.String("xml", "", "Path to the invoice XML to embed, or - for standard input")
+		output       = fs.String("output", stdio, "Path to output PDF (or XML with -extract), or - for standard output")
+		password     = fs.String("password", "", "Password if the PDF is encrypted")
+		profile      = fs.String("profile", "", "Factur-X profile (MINIMUM, BASIC WL, BASIC, EN 16931, EXTENDED, XRECHNUNG); read from the XML if empty")
+		relationship = fs.String("relationship", "", "AFRelationship of the XML (Data, Alternative, Source); chosen by profile if empty")
+		extractXML   = fs.Bool("extract", false, "Extract the invoice XML of a hybrid invoice instead")
+		verbose      = fs.Bool("verbose", false, "Enable verbose logging")
+	)
+	fs.Parse(args)
+	inputArg(fs, inputPDF)
+
+	if *inputPDF == "" {
+		log.Fatal("Error: -input flag is required")
+	}
+	if !*extractXML && *invoiceXML == "" {
+		log.Fatal("Error: -xml flag is required unless -extract is set")
+	}
+	if err := checkStdin(*inputPDF, *invoiceXML); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	pdfBytes, err := readFile(*inputPDF)
+	if err != nil {
+		log.Fatalf("Error reading PDF: %v", err)
+	}
+
+	if *extractXML {
+		invoice, err := manipulate.ExtractInvoice(pdfBytes, []byte(*password), *verbose)
+		if err != nil {
+			log.Fatalf("Error extracting invoice: %v", err)
+		}
+		if invoice == nil {
+			fmt.Fprintln(os.Stderr, "No invoice found")
+			os.Exit(1)
+		}
+		if err := writeOutput(*output, invoice.XML); err != nil {
+			log.Fatalf("Error writing XML: %v", err)
+		}
+		fmt.Fprintf(statusWriter(*output), "Extracted %s (%s) invoice: %s\n", invoice.FileName, invoice.Profile, *output)
+		return
+	}
+
+	data, err := readFile(*invoiceXML)
+	if err != nil {
+		log.Fatalf("Error reading invoice XML: %v", err)
+	}
+	m, err := manipulate.NewPDFManipulator(pdfBytes, []byte(*password), *verbose)
+	if err != nil {
+		log.Fatalf("Error opening PDF: %v", err)
+	}
+	if err := m.AttachInvoice(data, manipulate.InvoiceOptions{Profile: *profile, Relationship: *relationship}); err != nil {
+		log.Fatalf("Error attaching invoice: %v", err)
+	}
+	out, err := m.Rebuild()
+	if err != nil {
+		log.Fatalf("Error writing PDF: %v", err)
+	}
+	if err := writeOutput(*output, out); err != nil {
+		log.Fatalf("Error writing PDF: %v", err)
+	}
+	fmt.Fprintf(statusWriter(*output), "Attached invoice: %s\n", *output)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+)
+
+// stdio is the file name that selects standard input or standard output, so
+// commands can be piped: cat in.pdf | pdfer fill -data d.json - > out.pdf
+const stdio = "-"
+
+// inputArg takes the input file from the first argument after the flags when
+// -input isn't given
+func inputArg(fs *flag.FlagSet, input *string) {
+	if *input == "" && fs.NArg() > 0 {
+		*input = fs.Arg(0)
+	}
+}
+
+// readInput loads the input PDF, memory-mapping it when requested.
+// The returned release function must be called once the bytes are no longer needed.
+func readInput(path string, useMmap bool) ([]byte, func(), error) {
+	if useMmap && path != stdio {
+		mf, err := parse.MapFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return mf.Bytes(), func() { mf.Close() }, nil
+	}
+	data, err := readFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() {}, nil
+}
+
+// readFile reads a file, or standard input for "-"
+func readFile(path string) ([]byte, error) {
+	if path == stdio {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// writeOutput writes a file, or standard output for "-"
+func writeOutput(path string, data []byte) error {
+	if path == stdio {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// statusWriter returns where to print progress messages: standard error when
+// the output goes to standard output, so they don't end up in the piped data
+func statusWriter(output string) io.Writer {
+	if output == stdio {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// checkStdin fails when more than one input is read from standard input
+func checkStdin(paths ...string) error {
+	n := 0
+	for _, path := range paths {
+		if path == stdio {
+			n++
+		}
+	}
+	if n > 1 {
+		return fmt.Errorf("only one input can be read from standard input")
+	}
+	return nil
+}
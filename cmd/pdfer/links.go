@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// runLinks handles "pdfer links": lists link annotations and their targets
+func runLinks(args []string) {
+	fs := flag.NewFlagSet("links", flag.ExitOnError)
+	var (
+		inputPDF   = fs.String("input", "", "Path to input PDF file, or - for standard input")
+		password   = fs.String("password", "", "Password if the PDF is encrypted")
+		jsonOutput = fs.Bool("json", false, "Print links as JSON")
+		external   = fs.Bool("external", false, "List only links to external URIs and files")
+		verbose    = fs.Bool("verbose", false, "Enable verbose logging")
+	)
+	fs.Parse(args)
+	inputArg(fs, inputPDF)
+
+	if *inputPDF == "" {
+		log.Fatal("Error: -input flag is required")
+	}
+
+	pdfBytes, err := readFile(*inputPDF)
+	if err != nil {
+		log.Fatalf("Error reading PDF: %v", err)
+	}
+
+	pdf, err := parse.OpenWithOptions(pdfBytes, parse.ParseOptions{
+		Password: []byte(*password),
+		Verbose:  *verbose,
+	})
+	if err != nil {
+		log.Fatalf("Error parsing PDF: %v", err)
+	}
+
+	links, err := extract.ExtractLinks(pdfBytes, pdf, *verbose)
+	if err != nil {
+		log.Fatalf("Error extracting links: %v", err)
+	}
+
+	if *external {
+		filtered := []types.Link{}
+		for _, link := range links {
+			if link.URI != "" || link.File != "" {
+				filtered = append(filtered, link)
+			}
+		}
+		links = filtered
+	}
+
+	if *jsonOutput {
+		out, err := json.MarshalIndent(links, "", "  ")
+		if err != nil {
+			log.Fatalf("Error encoding links: %v", err)
+		}
+		fmt.Println(string(out))
+		return
+	}
+
+	for _, link := range links {
+		fmt.Printf("page %d\t%s\t%s\n", link.PageNumber, link.Action, linkTarget(link))
+	}
+}
+
+// linkTarget formats the target of a link for text output
+func linkTarget(link types.Link) string {
+	switch {
+	case link.URI != "":
+		return link.URI
+	case link.File != "" && link.Destination != "":
+		return link.File + "#" + link.Destination
+	case link.File != "":
+		return link.File
+	case link.TargetPage > 0:
+		return fmt.Sprintf("page %d", link.TargetPage)
+	}
+	return link.Destination
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 
 	encrypt "github.com/benedoc-inc/pdfer/core/encrypt"
+	"github.com/benedoc-inc/pdfer/core/manipulate"
 	"github.com/benedoc-inc/pdfer/forms/xfa"
 	"github.com/benedoc-inc/pdfer/types"
 )
@@ -22,16 +23,112 @@ func main() {
 		}
 	}()
 
+	// Subcommands have their own flags
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "encrypt":
+			runEncrypt(os.Args[2:])
+			return
+		case "decrypt":
+			runDecrypt(os.Args[2:])
+			return
+		case "links":
+			runLinks(os.Args[2:])
+			return
+		case "cos":
+			runCos(os.Args[2:])
+			return
+		case "find":
+			runFind(os.Args[2:])
+			return
+		case "extract":
+			runExtract(os.Args[2:])
+			return
+		case "rewrite":
+			runRewrite(os.Args[2:])
+			return
+		case "convert-colors":
+			runConvertColors(os.Args[2:])
+			return
+		case "check":
+			runCheck(os.Args[2:])
+			return
+		case "preflight":
+			runPreflight(os.Args[2:])
+			return
+		case "probe":
+			runProbe(os.Args[2:])
+			return
+		case "accessibility":
+			runAccessibility(os.Args[2:])
+			return
+		case "spellcheck":
+			runSpellcheck(os.Args[2:])
+			return
+		case "fill":
+			runFill(os.Args[2:])
+			return
+		case "batch":
+			runBatch(os.Args[2:])
+			return
+		case "flatten":
+			runFlatten(os.Args[2:])
+			return
+		case "render-xfa":
+			runRenderXFA(os.Args[2:])
+			return
+		case "merge":
+			runMerge(os.Args[2:])
+			return
+		case "sign":
+			runSign(os.Args[2:])
+			return
+		case "verify":
+			runVerify(os.Args[2:])
+			return
+		case "invoice":
+			runInvoice(os.Args[2:])
+			return
+		}
+	}
+
+	// Without a subcommand, fill the form
+	runFill(os.Args[1:])
+}
+
+// runFill handles "pdfer fill" and "pdfer" without a subcommand: fills an XFA
+// form, or extracts its schema with -extract-schema. The input can also be given
+// as the last argument; "-" reads standard input or writes standard output.
+func runFill(args []string) {
+	fs := flag.NewFlagSet("fill", flag.ExitOnError)
 	var (
-		inputPDF      = flag.String("input", "", "Path to input eSTAR PDF file")
-		dataJSON      = flag.String("data", "", "Path to JSON file with form data")
-		outputPDF     = flag.String("output", "", "Path to output filled PDF file")
-		verbose       = flag.Bool("verbose", false, "Enable verbose logging")
-		logFile       = flag.String("log", "", "Path to log file (if empty, logs to stderr)")
-		verify        = flag.Bool("verify", false, "Run verification test with UniPDF instead of filling form")
-		extractSchema = flag.Bool("extract-schema", false, "Extract questionnaire schema from PDF and output as JSON (requires -output)")
+		inputPDF      = fs.String("input", "", "Path to input eSTAR PDF file, or - for standard input")
+		dataJSON      = fs.String("data", "", "Path to JSON file with form data, or - for standard input")
+		outputPDF     = fs.String("output", stdio, "Path to output filled PDF file, or - for standard output")
+		verbose       = fs.Bool("verbose", false, "Enable verbose logging")
+		logFile       = fs.String("log", "", "Path to log file (if empty, logs to stderr)")
+		verify        = fs.Bool("verify", false, "Run verification test with UniPDF instead of filling form")
+		extractSchema = fs.Bool("extract-schema", false, "Extract questionnaire schema from PDF and output as JSON")
+		useMmap       = fs.Bool("mmap", false, "Memory-map the input PDF instead of reading it into memory")
+		debugDump     = fs.String("debug-dump", "", "Write hex dumps of the objects decrypted while locating the XFA form to this file")
+		debugLimit    = fs.Int("debug-dump-limit", 512, "Maximum number of bytes dumped per object, 0 for all")
 	)
-	flag.Parse()
+	security := addOutputSecurityFlags(fs)
+	fs.Parse(args)
+	inputArg(fs, inputPDF)
+	if err := security.check(); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	debug := xfa.DebugOptions{Limit: *debugLimit}
+	if *debugDump != "" {
+		dumpF, err := os.Create(*debugDump)
+		if err != nil {
+			log.Fatalf("Error creating debug dump file: %v", err)
+		}
+		defer dumpF.Close()
+		debug.Output = dumpF
+	}
 
 	// Force stderr to be unbuffered
 	os.Stderr.WriteString("=== pdfer starting ===\n")
@@ -50,18 +147,15 @@ func main() {
 
 	// If extracting schema, handle that separately
 	if *extractSchema {
-		if *outputPDF == "" {
-			log.Fatal("Error: -output flag is required when using -extract-schema")
-		}
-		handleExtractSchema(*inputPDF, *outputPDF, *verbose)
+		handleExtractSchema(*inputPDF, *outputPDF, *useMmap, debug, *verbose)
 		return
 	}
 
 	if *dataJSON == "" {
 		log.Fatal("Error: -data flag is required")
 	}
-	if *outputPDF == "" {
-		log.Fatal("Error: -output flag is required")
+	if err := checkStdin(*inputPDF, *dataJSON); err != nil {
+		log.Fatalf("Error: %v", err)
 	}
 
 	// Set up logging - write to both file and stderr if log file specified
@@ -101,7 +195,7 @@ func main() {
 	}
 
 	// Read form data JSON
-	dataBytes, err := os.ReadFile(*dataJSON)
+	dataBytes, err := readFile(*dataJSON)
 	if err != nil {
 		log.Fatalf("Error reading data file: %v", err)
 	}
@@ -117,20 +211,22 @@ func main() {
 	}
 
 	// Read PDF file
-	pdfBytes, err := os.ReadFile(*inputPDF)
+	pdfBytes, release, err := readInput(*inputPDF, *useMmap)
 	if err != nil {
 		log.Fatalf("Error reading PDF: %v", err)
 	}
+	defer release()
 
 	// Check if PDF is encrypted and decrypt if needed
 	var encryptInfo *types.PDFEncryption
+	var password []byte
 	if bytes.Contains(pdfBytes, []byte("/Encrypt")) {
 		if *verbose {
 			log.Printf("PDF is encrypted, attempting to decrypt...")
 		}
 
 		// Try to decrypt with empty password (most eSTAR PDFs allow this)
-		decryptedBytes, encInfo, err := encrypt.DecryptPDF(pdfBytes, []byte(""), *verbose)
+		_, encInfo, err := encrypt.DecryptPDF(pdfBytes, []byte(""), *verbose)
 		if err != nil {
 			if *verbose {
 				log.Printf("Empty password failed, trying common passwords...")
@@ -139,10 +235,10 @@ func main() {
 			commonPasswords := [][]byte{[]byte(""), []byte("admin"), []byte("password"), []byte("1234")}
 			decrypted := false
 			for _, pwd := range commonPasswords {
-				decryptedBytes, encInfo, err := encrypt.DecryptPDF(pdfBytes, pwd, *verbose)
+				_, encInfo, err := encrypt.DecryptPDF(pdfBytes, pwd, *verbose)
 				if err == nil {
-					pdfBytes = decryptedBytes
 					encryptInfo = encInfo
+					password = pwd
 					decrypted = true
 					if *verbose {
 						log.Printf("Successfully decrypted PDF")
@@ -154,24 +250,45 @@ func main() {
 				log.Fatalf("Could not decrypt PDF: %v", err)
 			}
 		} else {
-			pdfBytes = decryptedBytes
 			encryptInfo = encInfo
 			if *verbose {
 				log.Printf("Successfully decrypted PDF with empty password")
 			}
 		}
+
+		// The copy filled below is decrypted, so look up the XFA form in the input
+		// too when the decrypted objects are to be dumped
+		if debug.Output != nil {
+			if _, err := xfa.ExtractAllXFAStreams(pdfBytes, encryptInfo, debug, *verbose); err != nil && *verbose {
+				log.Printf("Could not locate XFA in the encrypted input: %v", err)
+			}
+		}
+
+		// Fill a decrypted copy, so that the output is encrypted as a whole below
+		pdfBytes, err = manipulate.RemoveSecurity(pdfBytes, password, *verbose)
+		if err != nil {
+			log.Fatalf("Error decrypting PDF: %v", err)
+		}
 	}
 
 	// Update XFA in PDF
-	// Note: After decryption, objects are still encrypted in the PDF bytes
-	// We need to decrypt them on-demand when accessing them
-	updatedPDF, err := xfa.UpdateXFAInPDF(pdfBytes, formData, encryptInfo, *verbose)
+	template, err := xfa.PrepareTemplate(pdfBytes, nil, debug, *verbose)
+	if err != nil {
+		log.Fatalf("Error updating XFA: %v", err)
+	}
+	updatedPDF, err := template.Fill(formData, *verbose)
 	if err != nil {
 		log.Fatalf("Error updating XFA: %v", err)
 	}
 
+	// Apply the input's security again, or the passwords given
+	updatedPDF, err = security.apply(updatedPDF, encryptInfo, *verbose)
+	if err != nil {
+		log.Fatalf("Error encrypting PDF: %v", err)
+	}
+
 	// Write updated PDF
-	err = os.WriteFile(*outputPDF, updatedPDF, 0644)
+	err = writeOutput(*outputPDF, updatedPDF)
 	if err != nil {
 		log.Fatalf("Error writing PDF: %v", err)
 	}
@@ -193,21 +310,24 @@ func main() {
 	fmt.Fprintf(os.Stderr, "Output: %s\n", *outputPDF)
 	fmt.Fprintf(os.Stderr, "Fields filled: %d\n", len(formData))
 
-	// Also print to stdout
-	fmt.Printf("Successfully filled form\n")
-	fmt.Printf("Input:  %s\n", *inputPDF)
-	fmt.Printf("Data:   %s\n", *dataJSON)
-	fmt.Printf("Output: %s\n", *outputPDF)
-	fmt.Printf("Fields filled: %d\n", len(formData))
+	// Also print to stdout, unless it carries the PDF
+	if *outputPDF != stdio {
+		fmt.Printf("Successfully filled form\n")
+		fmt.Printf("Input:  %s\n", *inputPDF)
+		fmt.Printf("Data:   %s\n", *dataJSON)
+		fmt.Printf("Output: %s\n", *outputPDF)
+		fmt.Printf("Fields filled: %d\n", len(formData))
+	}
 }
 
 // handleExtractSchema extracts questionnaire schema from PDF and writes it as JSON
-func handleExtractSchema(inputPDF, outputJSON string, verbose bool) {
+func handleExtractSchema(inputPDF, outputJSON string, useMmap bool, debug xfa.DebugOptions, verbose bool) {
 	// Read PDF file
-	pdfBytes, err := os.ReadFile(inputPDF)
+	pdfBytes, release, err := readInput(inputPDF, useMmap)
 	if err != nil {
 		log.Fatalf("Error reading PDF: %v", err)
 	}
+	defer release()
 
 	// Check if PDF is encrypted and decrypt if needed
 	var encryptInfo *types.PDFEncryption
@@ -250,10 +370,14 @@ func handleExtractSchema(inputPDF, outputJSON string, verbose bool) {
 	}
 
 	// Extract XFA data from PDF
-	xfaData, _, err := xfa.FindXFADatasetsStream(pdfBytes, encryptInfo, verbose)
+	streams, err := xfa.ExtractAllXFAStreams(pdfBytes, encryptInfo, debug, verbose)
 	if err != nil {
 		log.Fatalf("Error finding XFA datasets stream: %v", err)
 	}
+	if streams.Datasets == nil {
+		log.Fatalf("Error finding XFA datasets stream: datasets stream not found in XFA")
+	}
+	xfaData := streams.Datasets.Data
 
 	// Decompress XFA XML
 	xfaXML, _, err := xfa.DecompressStream(xfaData)
@@ -273,7 +397,7 @@ func handleExtractSchema(inputPDF, outputJSON string, verbose bool) {
 		log.Fatalf("Error marshaling schema to JSON: %v", err)
 	}
 
-	err = os.WriteFile(outputJSON, schemaJSON, 0644)
+	err = writeOutput(outputJSON, schemaJSON)
 	if err != nil {
 		log.Fatalf("Error writing schema JSON: %v", err)
 	}
@@ -284,8 +408,10 @@ func handleExtractSchema(inputPDF, outputJSON string, verbose bool) {
 	fmt.Fprintf(os.Stderr, "Output: %s\n", outputJSON)
 	fmt.Fprintf(os.Stderr, "Questions extracted: %d\n", len(schema.Questions))
 
-	fmt.Printf("Successfully extracted questionnaire schema\n")
-	fmt.Printf("Input:  %s\n", inputPDF)
-	fmt.Printf("Output: %s\n", outputJSON)
-	fmt.Printf("Questions extracted: %d\n", len(schema.Questions))
+	if outputJSON != stdio {
+		fmt.Printf("Successfully extracted questionnaire schema\n")
+		fmt.Printf("Input:  %s\n", inputPDF)
+		fmt.Printf("Output: %s\n", outputJSON)
+		fmt.Printf("Questions extracted: %d\n", len(schema.Questions))
+	}
 }
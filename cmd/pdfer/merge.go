@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/core/manipulate"
+)
+
+// runMerge handles "pdfer merge": concatenates the PDFs given as arguments,
+// e.g. a cover page and a filled form, into one document
+func runMerge(args []string) {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	var (
+		outputPDF = fs.String("output", stdio, "Path to output PDF file, or - for standard output")
+		titles    = fs.String("titles", "", "Comma-separated bookmark titles, one per input; empty titles add no bookmark")
+		password  = fs.String("password", "", "Password for encrypted inputs")
+		verbose   = fs.Bool("verbose", false, "Enable verbose logging")
+	)
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		log.Fatal("Error: give the PDFs to merge as arguments")
+	}
+	if err := checkStdin(fs.Args()...); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	opts := manipulate.MergeOptions{Verbose: *verbose}
+	if *titles != "" {
+		opts.Titles = strings.Split(*titles, ",")
+	}
+	inputs := make([][]byte, fs.NArg())
+	for i, path := range fs.Args() {
+		data, err := readFile(path)
+		if err != nil {
+			log.Fatalf("Error reading PDF: %v", err)
+		}
+		inputs[i] = data
+		opts.Passwords = append(opts.Passwords, []byte(*password))
+	}
+
+	merged, err := manipulate.Merge(inputs, opts)
+	if err != nil {
+		log.Fatalf("Error merging PDFs: %v", err)
+	}
+	if err := writeOutput(*outputPDF, merged); err != nil {
+		log.Fatalf("Error writing PDF: %v", err)
+	}
+	fmt.Fprintf(statusWriter(*outputPDF), "Merged %d PDFs: %s\n", len(inputs), *outputPDF)
+}
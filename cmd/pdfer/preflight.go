@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/core/parse"
+)
+
+// runPreflight handles "pdfer preflight": reports the effective resolution of
+// placed images and pages over ink or complexity budgets, and exits with status
+// 1 when any check fails
+func runPreflight(args []string) {
+	defaults := extract.DefaultPrintQualityOptions()
+	fs := flag.NewFlagSet("preflight", flag.ExitOnError)
+	var (
+		inputPDF     = fs.String("input", "", "Path to input PDF file, or - for standard input")
+		password     = fs.String("password", "", "Password if the PDF is encrypted")
+		minDPI       = fs.Float64("min-dpi", defaults.MinDPI, "Flag images placed below this resolution (0 disables)")
+		maxTotalInk  = fs.Float64("max-total-ink", defaults.MaxTotalInk, "Total ink limit in percent (0 disables)")
+		maxCoverage  = fs.Float64("max-ink-coverage", defaults.MaxInkCoverage, "Highest fraction of a page covered by content, 0-1 (0 disables)")
+		maxVectorOps = fs.Int("max-vector-ops", defaults.MaxVectorOps, "Highest number of path operators per page (0 disables)")
+		maxContent   = fs.Int("max-content-bytes", defaults.MaxContentBytes, "Highest content stream size per page (0 disables)")
+		jsonOutput   = fs.Bool("json", false, "Print the report as JSON")
+		verbose      = fs.Bool("verbose", false, "Enable verbose logging")
+	)
+	fs.Parse(args)
+	inputArg(fs, inputPDF)
+
+	if *inputPDF == "" {
+		log.Fatal("Error: -input flag is required")
+	}
+
+	pdfBytes, err := readFile(*inputPDF)
+	if err != nil {
+		log.Fatalf("Error reading PDF: %v", err)
+	}
+	pdf, err := parse.OpenWithOptions(pdfBytes, parse.ParseOptions{
+		Password: []byte(*password),
+		Verbose:  *verbose,
+	})
+	if err != nil {
+		log.Fatalf("Error parsing PDF: %v", err)
+	}
+
+	report, err := extract.AnalyzePrintQuality(pdfBytes, pdf, extract.PrintQualityOptions{
+		MinDPI:          *minDPI,
+		MaxTotalInk:     *maxTotalInk,
+		MaxInkCoverage:  *maxCoverage,
+		MaxVectorOps:    *maxVectorOps,
+		MaxContentBytes: *maxContent,
+	}, *verbose)
+	if err != nil {
+		log.Fatalf("Error analyzing PDF: %v", err)
+	}
+
+	if *jsonOutput {
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatalf("Error encoding report: %v", err)
+		}
+		fmt.Println(string(out))
+	} else {
+		for _, img := range report.Images {
+			status := "PASS"
+			if img.LowResolution {
+				status = "FAIL"
+			}
+			fmt.Printf("%s\tpage %d\timage %s\t%dx%d px at %.0fx%.0f pt\t%.0f DPI\n",
+				status, img.PageNumber, img.ImageID, img.PixelWidth, img.PixelHeight, img.PlacedWidth, img.PlacedHeight, img.DPI)
+		}
+		for _, page := range report.Pages {
+			for _, exceeded := range page.Exceeded {
+				fmt.Printf("FAIL\tpage %d\t%s\n", page.PageNumber, exceeded)
+			}
+		}
+		if report.Passed {
+			fmt.Println("Preflight passed")
+		} else {
+			fmt.Printf("Preflight failed: %d low-resolution images, %d pages over budget\n", report.LowResolutionImages, report.PagesOverBudget)
+		}
+	}
+
+	if !report.Passed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/benedoc-inc/pdfer"
+)
+
+// runProbe handles "pdfer probe": reports what a PDF contains (encryption,
+// forms, signatures, tagging, PDF/A claim, JavaScript, attachments) without
+// extracting it
+func runProbe(args []string) {
+	fs := flag.NewFlagSet("probe", flag.ExitOnError)
+	var (
+		inputPDF   = fs.String("input", "", "Path to input PDF file, or - for standard input")
+		password   = fs.String("password", "", "Password if the PDF is encrypted")
+		jsonOutput = fs.Bool("json", false, "Print the capabilities as JSON")
+		verbose    = fs.Bool("verbose", false, "Enable verbose logging")
+	)
+	fs.Parse(args)
+	inputArg(fs, inputPDF)
+
+	if *inputPDF == "" {
+		log.Fatal("Error: -input flag is required")
+	}
+
+	pdfBytes, err := readFile(*inputPDF)
+	if err != nil {
+		log.Fatalf("Error reading PDF: %v", err)
+	}
+
+	caps, err := pdfer.Probe(pdfBytes, []byte(*password), *verbose)
+	if err != nil {
+		log.Fatalf("Error probing PDF: %v", err)
+	}
+
+	if *jsonOutput {
+		out, err := json.MarshalIndent(caps, "", "  ")
+		if err != nil {
+			log.Fatalf("Error encoding capabilities: %v", err)
+		}
+		fmt.Println(string(out))
+		return
+	}
+
+	encryption := "no"
+	if caps.Encrypted {
+		encryption = caps.Encryption
+		if caps.Locked {
+			encryption += " (locked)"
+		}
+	}
+	pdfa := caps.PDFA
+	if pdfa == "" {
+		pdfa = "no"
+	}
+	fmt.Printf("Encrypted:   %s\n", encryption)
+	if caps.Locked {
+		return
+	}
+	fmt.Printf("XFA:         %s\n", caps.XFA)
+	fmt.Printf("AcroForm:    %s\n", yesNo(caps.AcroForm))
+	fmt.Printf("Signed:      %s\n", yesNo(caps.Signed))
+	fmt.Printf("Linearized:  %s\n", yesNo(caps.Linearized))
+	fmt.Printf("Tagged:      %s\n", yesNo(caps.Tagged))
+	fmt.Printf("PDF/A:       %s\n", pdfa)
+	fmt.Printf("JavaScript:  %s\n", yesNo(caps.JavaScript))
+	fmt.Printf("Attachments: %s\n", yesNo(caps.Attachments))
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
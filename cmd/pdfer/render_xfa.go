@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+
+	encrypt "github.com/benedoc-inc/pdfer/core/encrypt"
+	"github.com/benedoc-inc/pdfer/forms/xfa"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// runRenderXFA handles "pdfer render-xfa": lays out an XFA form and its data
+// as a static PDF for viewers without XFA support
+func runRenderXFA(args []string) {
+	fs := flag.NewFlagSet("render-xfa", flag.ExitOnError)
+	var (
+		inputPDF  = fs.String("input", "", "Path to input PDF file, or - for standard input")
+		outputPDF = fs.String("output", stdio, "Path to output PDF file, or - for standard output")
+		password  = fs.String("password", "", "Password if the PDF is encrypted")
+		flatten   = fs.Bool("flatten", false, "Draw field values as text instead of form fields")
+		verbose   = fs.Bool("verbose", false, "Enable verbose logging")
+	)
+	fs.Parse(args)
+	inputArg(fs, inputPDF)
+
+	if *inputPDF == "" {
+		log.Fatal("Error: -input flag is required")
+	}
+
+	pdfBytes, err := readFile(*inputPDF)
+	if err != nil {
+		log.Fatalf("Error reading PDF: %v", err)
+	}
+	var encryptInfo *types.PDFEncryption
+	if bytes.Contains(pdfBytes, []byte("/Encrypt")) {
+		if _, encryptInfo, err = encrypt.DecryptPDF(pdfBytes, []byte(*password), *verbose); err != nil {
+			log.Fatalf("Error decrypting PDF: %v", err)
+		}
+	}
+
+	rendered, err := xfa.Render(pdfBytes, encryptInfo, xfa.RenderOptions{Flatten: *flatten}, *verbose)
+	if err != nil {
+		log.Fatalf("Error rendering XFA form: %v", err)
+	}
+	if err := writeOutput(*outputPDF, rendered); err != nil {
+		log.Fatalf("Error writing PDF: %v", err)
+	}
+	fmt.Fprintf(statusWriter(*outputPDF), "Rendered XFA form: %s\n", *outputPDF)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/benedoc-inc/pdfer/core/manipulate"
+)
+
+// runRewrite handles "pdfer rewrite": parses a PDF and writes it again through the writer
+func runRewrite(args []string) {
+	fs := flag.NewFlagSet("rewrite", flag.ExitOnError)
+	var (
+		inputPDF         = fs.String("input", "", "Path to input PDF file, or - for standard input")
+		outputPDF        = fs.String("output", "", "Path to output PDF file, or - for standard output")
+		password         = fs.String("password", "", "Password if the PDF is encrypted")
+		objectStreams    = fs.Bool("object-streams", false, "Pack objects into object streams")
+		keepUnreferenced = fs.Bool("keep-unreferenced", false, "Keep objects not reachable from the trailer")
+		stripImageMeta   = fs.Bool("strip-image-metadata", false, "Remove EXIF, XMP and ICC metadata from images and image attachments")
+		level            = fs.Int("level", 9, "Flate compression level for streams, 1 (fastest) to 9 (smallest)")
+		verify           = fs.Bool("verify", false, "Check the output for structural errors before writing it")
+		verbose          = fs.Bool("verbose", false, "Enable verbose logging")
+	)
+	fs.Parse(args)
+	inputArg(fs, inputPDF)
+
+	if *inputPDF == "" || *outputPDF == "" {
+		log.Fatal("Error: -input and -output flags are required")
+	}
+
+	pdfBytes, err := readFile(*inputPDF)
+	if err != nil {
+		log.Fatalf("Error reading PDF: %v", err)
+	}
+
+	if *level < 1 || *level > 9 {
+		log.Fatal("Error: -level must be between 1 and 9")
+	}
+	compression := manipulate.RewriteCompression()
+	compression.Level = *level
+
+	out, err := manipulate.Rewrite(pdfBytes, manipulate.RewriteOptions{
+		Password:           []byte(*password),
+		ObjectStreams:      *objectStreams,
+		KeepUnreferenced:   *keepUnreferenced,
+		StripImageMetadata: *stripImageMeta,
+		Compression:        &compression,
+		Verify:             *verify,
+		Verbose:            *verbose,
+	})
+	if err != nil {
+		log.Fatalf("Error rewriting PDF: %v", err)
+	}
+
+	if err := writeOutput(*outputPDF, out); err != nil {
+		log.Fatalf("Error writing PDF: %v", err)
+	}
+
+	status := statusWriter(*outputPDF)
+	fmt.Fprintf(status, "Successfully rewrote PDF (%d -> %d bytes)\n", len(pdfBytes), len(out))
+	fmt.Fprintf(status, "Input:  %s\n", *inputPDF)
+	fmt.Fprintf(status, "Output: %s\n", *outputPDF)
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/core/manipulate"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// permissionNames maps -allow values to permission flags
+var permissionNames = map[string]int32{
+	"print":      manipulate.PermPrint,
+	"modify":     manipulate.PermModify,
+	"copy":       manipulate.PermCopy,
+	"annotate":   manipulate.PermAnnotate,
+	"fill-forms": manipulate.PermFillForms,
+	"extract":    manipulate.PermExtract,
+	"assemble":   manipulate.PermAssemble,
+	"print-hq":   manipulate.PermPrintHighQuality,
+	"all":        manipulate.PermAll,
+	"none":       0,
+}
+
+// runEncrypt handles "pdfer encrypt": sets passwords, permissions and cipher
+func runEncrypt(args []string) {
+	fs := flag.NewFlagSet("encrypt", flag.ExitOnError)
+	var (
+		inputPDF      = fs.String("input", "", "Path to input PDF file, or - for standard input")
+		outputPDF     = fs.String("output", "", "Path to output encrypted PDF file, or - for standard output")
+		password      = fs.String("password", "", "Password to open the input if it is already encrypted")
+		userPassword  = fs.String("user-password", "", "Password required to open the output (empty opens without prompting)")
+		ownerPassword = fs.String("owner-password", "", "Password granting full access (defaults to -user-password)")
+		allow         = fs.String("allow", "all", "Comma-separated permissions: print, modify, copy, annotate, fill-forms, extract, assemble, print-hq, all or none")
+		cipher        = fs.String("cipher", manipulate.CipherAES256, "Cipher: aes-256, aes-128 or rc4-128")
+		plainMetadata = fs.Bool("plaintext-metadata", false, "Leave XMP metadata unencrypted (AES only)")
+		attachments   = fs.Bool("attachments-only", false, "Encrypt only embedded files; the document opens without a password (AES only)")
+		verbose       = fs.Bool("verbose", false, "Enable verbose logging")
+	)
+	fs.Parse(args)
+	inputArg(fs, inputPDF)
+
+	if *inputPDF == "" || *outputPDF == "" {
+		log.Fatal("Error: -input and -output flags are required")
+	}
+	if *userPassword == "" && *ownerPassword == "" {
+		log.Fatal("Error: -user-password or -owner-password is required")
+	}
+
+	permissions, err := parsePermissions(*allow)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	pdfBytes, err := readFile(*inputPDF)
+	if err != nil {
+		log.Fatalf("Error reading PDF: %v", err)
+	}
+
+	out, err := manipulate.SetSecurity(pdfBytes, []byte(*password), manipulate.SecurityOptions{
+		UserPassword:      []byte(*userPassword),
+		OwnerPassword:     []byte(*ownerPassword),
+		Permissions:       permissions,
+		Cipher:            *cipher,
+		PlaintextMetadata: *plainMetadata,
+		AttachmentsOnly:   *attachments,
+	}, *verbose)
+	if err != nil {
+		log.Fatalf("Error encrypting PDF: %v", err)
+	}
+
+	if err := writeOutput(*outputPDF, out); err != nil {
+		log.Fatalf("Error writing PDF: %v", err)
+	}
+
+	status := statusWriter(*outputPDF)
+	fmt.Fprintf(status, "Successfully encrypted PDF (%s)\n", *cipher)
+	fmt.Fprintf(status, "Input:  %s\n", *inputPDF)
+	fmt.Fprintf(status, "Output: %s\n", *outputPDF)
+}
+
+// runDecrypt handles "pdfer decrypt": removes passwords and encryption
+func runDecrypt(args []string) {
+	fs := flag.NewFlagSet("decrypt", flag.ExitOnError)
+	var (
+		inputPDF  = fs.String("input", "", "Path to input encrypted PDF file, or - for standard input")
+		outputPDF = fs.String("output", "", "Path to output decrypted PDF file, or - for standard output")
+		password  = fs.String("password", "", "User or owner password")
+		verbose   = fs.Bool("verbose", false, "Enable verbose logging")
+	)
+	fs.Parse(args)
+	inputArg(fs, inputPDF)
+
+	if *inputPDF == "" || *outputPDF == "" {
+		log.Fatal("Error: -input and -output flags are required")
+	}
+
+	pdfBytes, err := readFile(*inputPDF)
+	if err != nil {
+		log.Fatalf("Error reading PDF: %v", err)
+	}
+
+	out, err := manipulate.RemoveSecurity(pdfBytes, []byte(*password), *verbose)
+	if err != nil {
+		log.Fatalf("Error decrypting PDF: %v", err)
+	}
+
+	if err := writeOutput(*outputPDF, out); err != nil {
+		log.Fatalf("Error writing PDF: %v", err)
+	}
+
+	status := statusWriter(*outputPDF)
+	fmt.Fprintf(status, "Successfully decrypted PDF\n")
+	fmt.Fprintf(status, "Input:  %s\n", *inputPDF)
+	fmt.Fprintf(status, "Output: %s\n", *outputPDF)
+}
+
+// outputSecurity holds the fill flags that encrypt the filled output
+type outputSecurity struct {
+	userPassword  *string
+	ownerPassword *string
+	allow         *string
+	cipher        *string
+	decrypt       *bool
+}
+
+// addOutputSecurityFlags registers the flags that encrypt the output of a fill
+func addOutputSecurityFlags(fs *flag.FlagSet) *outputSecurity {
+	return &outputSecurity{
+		userPassword:  fs.String("user-password", "", "Encrypt the output with this open password instead of the input's security"),
+		ownerPassword: fs.String("owner-password", "", "Encrypt the output with this owner password (defaults to -user-password)"),
+		allow:         fs.String("allow", "", "With new passwords, comma-separated permissions (default: the input's, or all)"),
+		cipher:        fs.String("cipher", "", "With new passwords, cipher: aes-256, aes-128 or rc4-128 (default: the input's, or aes-256)"),
+		decrypt:       fs.Bool("decrypt-output", false, "Write the output of an encrypted input without encryption"),
+	}
+}
+
+// check reports flag combinations that cannot be applied
+func (s *outputSecurity) check() error {
+	newPasswords := *s.userPassword != "" || *s.ownerPassword != ""
+	if *s.decrypt && newPasswords {
+		return fmt.Errorf("-decrypt-output cannot be combined with -user-password or -owner-password")
+	}
+	if !newPasswords && (*s.allow != "" || *s.cipher != "") {
+		return fmt.Errorf("-allow and -cipher require -user-password or -owner-password")
+	}
+	_, err := parsePermissions(*s.allow)
+	return err
+}
+
+// apply encrypts a filled document: with new passwords when they are given, and
+// otherwise with the security of the input, original, when it was encrypted
+func (s *outputSecurity) apply(pdfBytes []byte, original *types.PDFEncryption, verbose bool) ([]byte, error) {
+	if *s.decrypt {
+		return pdfBytes, nil
+	}
+	if *s.userPassword == "" && *s.ownerPassword == "" {
+		if original == nil {
+			return pdfBytes, nil
+		}
+		return manipulate.EncryptPDF(pdfBytes, original, nil, verbose)
+	}
+
+	permissions := manipulate.PermAll
+	if *s.allow != "" {
+		permissions, _ = parsePermissions(*s.allow)
+	} else if original != nil {
+		permissions = original.P
+	}
+	return manipulate.EncryptPDF(pdfBytes, original, &manipulate.SecurityOptions{
+		UserPassword:  []byte(*s.userPassword),
+		OwnerPassword: []byte(*s.ownerPassword),
+		Permissions:   permissions,
+		Cipher:        *s.cipher,
+	}, verbose)
+}
+
+// parsePermissions converts a comma-separated -allow value to permission flags
+func parsePermissions(value string) (int32, error) {
+	var permissions int32
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		if name == "" {
+			continue
+		}
+		perm, ok := permissionNames[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown permission %q", name)
+		}
+		permissions |= perm
+	}
+	return permissions, nil
+}
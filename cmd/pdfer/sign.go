@@ -0,0 +1,161 @@
+package main
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/core/sign"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// runSign handles "pdfer sign": adds a digital signature with a PEM key and
+// certificate
+func runSign(args []string) {
+	fs := flag.NewFlagSet("sign", flag.ExitOnError)
+	var (
+		inputPDF    = fs.String("input", "", "Path to input PDF file, or - for standard input")
+		outputPDF   = fs.String("output", stdio, "Path to output PDF file, or - for standard output")
+		keyFile     = fs.String("key", "", "PEM file with the private key (PKCS#8, PKCS#1 or EC)")
+		certFile    = fs.String("cert", "", "PEM file with the signer's certificate, followed by any intermediate certificates")
+		field       = fs.String("field", sign.DefaultFieldName, "Signature field name; an unsigned signature field of this name is signed")
+		page        = fs.Int("page", 1, "Page of an added signature field")
+		rect        = fs.String("rect", "", "Rectangle llx,lly,urx,ury of a visible signature field in points (default invisible)")
+		name        = fs.String("name", "", "Signer name")
+		reason      = fs.String("reason", "", "Reason for signing")
+		location    = fs.String("location", "", "Place of signing")
+		contact     = fs.String("contact", "", "Signer contact information")
+		handwriting = fs.String("image", "", "JPEG or PNG of a handwritten signature drawn in a visible signature")
+		logo        = fs.String("logo", "", "JPEG or PNG drawn faded behind a visible signature")
+		pkcs7       = fs.Bool("pkcs7", false, "Make an adbe.pkcs7.detached signature rather than PAdES (ETSI.CAdES.detached)")
+		certify     = fs.Int("certify", 0, "Make a certification signature permitting 1 no changes, 2 form filling and signing, or 3 also annotations")
+		verbose     = fs.Bool("verbose", false, "Enable verbose logging")
+	)
+	fs.Parse(args)
+	inputArg(fs, inputPDF)
+
+	if *inputPDF == "" || *keyFile == "" || *certFile == "" {
+		log.Fatal("Error: -input, -key and -cert flags are required")
+	}
+
+	opts := sign.Options{
+		FieldName:   *field,
+		Page:        *page,
+		Name:        *name,
+		Reason:      *reason,
+		Location:    *location,
+		ContactInfo: *contact,
+		Certify:     *certify,
+		Verbose:     *verbose,
+	}
+	if *pkcs7 {
+		opts.SubFilter = sign.SubFilterPKCS7
+	}
+	if *rect != "" {
+		r, err := parseRect(*rect)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		opts.Rect = r
+	}
+
+	keyPEM, err := readFile(*keyFile)
+	if err != nil {
+		log.Fatalf("Error reading key: %v", err)
+	}
+	if opts.Signer, err = parsePrivateKey(keyPEM); err != nil {
+		log.Fatalf("Error reading key: %v", err)
+	}
+	certPEM, err := readFile(*certFile)
+	if err != nil {
+		log.Fatalf("Error reading certificate: %v", err)
+	}
+	var certs []*x509.Certificate
+	for block, rest := pem.Decode(certPEM); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			log.Fatalf("Error reading certificate: %v", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		log.Fatalf("Error: no certificate in %s", *certFile)
+	}
+	opts.Certificate, opts.Chain = certs[0], certs[1:]
+
+	if *handwriting != "" {
+		if opts.Appearance.Handwriting, err = readFile(*handwriting); err != nil {
+			log.Fatalf("Error reading signature image: %v", err)
+		}
+	}
+	if *logo != "" {
+		if opts.Appearance.Logo, err = readFile(*logo); err != nil {
+			log.Fatalf("Error reading logo: %v", err)
+		}
+	}
+
+	pdfBytes, err := readFile(*inputPDF)
+	if err != nil {
+		log.Fatalf("Error reading PDF: %v", err)
+	}
+	signed, err := sign.Sign(pdfBytes, opts)
+	if err != nil {
+		log.Fatalf("Error signing PDF: %v", err)
+	}
+	if err := writeOutput(*outputPDF, signed); err != nil {
+		log.Fatalf("Error writing PDF: %v", err)
+	}
+	fmt.Fprintf(statusWriter(*outputPDF), "Signed field '%s' as %s: %s\n", *field, opts.Certificate.Subject.CommonName, *outputPDF)
+}
+
+// parsePrivateKey reads the first private key of a PEM file
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		var key interface{}
+		var err error
+		switch block.Type {
+		case "PRIVATE KEY":
+			key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		case "RSA PRIVATE KEY":
+			key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			key, err = x509.ParseECPrivateKey(block.Bytes)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported key type %T", key)
+		}
+		return signer, nil
+	}
+	return nil, fmt.Errorf("no private key found")
+}
+
+// parseRect parses "llx,lly,urx,ury"
+func parseRect(value string) (types.Rectangle, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return types.Rectangle{}, fmt.Errorf("invalid rectangle %q: expected llx,lly,urx,ury", value)
+	}
+	var n [4]float64
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return types.Rectangle{}, fmt.Errorf("invalid rectangle %q: %v", value, err)
+		}
+		n[i] = f
+	}
+	return types.Rectangle{LowerX: n[0], LowerY: n[1], UpperX: n[2], UpperY: n[3]}, nil
+}
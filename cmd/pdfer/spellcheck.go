@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/core/parse"
+)
+
+// runSpellcheck handles "pdfer spellcheck": reports the words of a document that
+// are missing from the given word lists and exits with status 1 when there are any
+func runSpellcheck(args []string) {
+	fs := flag.NewFlagSet("spellcheck", flag.ExitOnError)
+	var (
+		inputPDF      = fs.String("input", "", "Path to input PDF file, or - for standard input")
+		wordLists     = fs.String("words", "", "Comma-separated paths of word lists, one word or phrase per line")
+		password      = fs.String("password", "", "Password if the PDF is encrypted")
+		caseSensitive = fs.Bool("case-sensitive", false, "Match words with the case of the word lists")
+		jsonOutput    = fs.Bool("json", false, "Print the report as JSON")
+		verbose       = fs.Bool("verbose", false, "Enable verbose logging")
+	)
+	fs.Parse(args)
+	inputArg(fs, inputPDF)
+
+	if *inputPDF == "" || *wordLists == "" {
+		log.Fatal("Error: -input and -words flags are required")
+	}
+
+	dict := extract.NewDictionary(*caseSensitive)
+	for _, path := range strings.Split(*wordLists, ",") {
+		data, err := readFile(strings.TrimSpace(path))
+		if err != nil {
+			log.Fatalf("Error reading word list: %v", err)
+		}
+		if err := dict.Load(bytes.NewReader(data)); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	}
+
+	pdfBytes, err := readFile(*inputPDF)
+	if err != nil {
+		log.Fatalf("Error reading PDF: %v", err)
+	}
+	pdf, err := parse.OpenWithOptions(pdfBytes, parse.ParseOptions{
+		Password: []byte(*password),
+		Verbose:  *verbose,
+	})
+	if err != nil {
+		log.Fatalf("Error parsing PDF: %v", err)
+	}
+
+	report, err := extract.AuditDictionary(pdfBytes, pdf, dict, *verbose)
+	if err != nil {
+		log.Fatalf("Error checking words: %v", err)
+	}
+
+	if *jsonOutput {
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatalf("Error encoding report: %v", err)
+		}
+		fmt.Println(string(out))
+	} else {
+		for _, term := range report.Unknown {
+			var pages []string
+			for _, o := range term.Occurrences {
+				pages = append(pages, fmt.Sprintf("p%d@%.0f,%.0f", o.PageNumber, o.Rect.LowerX, o.Rect.LowerY))
+			}
+			fmt.Printf("%s\t%d\t%s\n", term.Word, term.Count, strings.Join(pages, " "))
+		}
+		fmt.Printf("%d of %d words not in the word lists\n", report.UnknownWords, report.WordsChecked)
+	}
+
+	if !report.Passed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/core/sign"
+)
+
+// runVerify handles "pdfer verify": checks every signature of a PDF and exits
+// with status 1 when any is invalid
+func runVerify(args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	var (
+		inputPDF   = fs.String("input", "", "Path to input PDF file, or - for standard input")
+		rootsFile  = fs.String("roots", "", "PEM file with the trusted root certificates (default: the system roots)")
+		jsonOutput = fs.Bool("json", false, "Print the reports as JSON")
+		verbose    = fs.Bool("verbose", false, "Enable verbose logging")
+	)
+	fs.Parse(args)
+	inputArg(fs, inputPDF)
+
+	if *inputPDF == "" {
+		log.Fatal("Error: -input flag is required")
+	}
+	if err := checkStdin(*inputPDF, *rootsFile); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	opts := sign.VerifyOptions{Verbose: *verbose}
+	if *rootsFile != "" {
+		rootsPEM, err := readFile(*rootsFile)
+		if err != nil {
+			log.Fatalf("Error reading roots: %v", err)
+		}
+		opts.Roots = x509.NewCertPool()
+		if !opts.Roots.AppendCertsFromPEM(rootsPEM) {
+			log.Fatalf("Error: no certificate in %s", *rootsFile)
+		}
+	}
+
+	pdfBytes, err := readFile(*inputPDF)
+	if err != nil {
+		log.Fatalf("Error reading PDF: %v", err)
+	}
+	reports, err := sign.Verify(pdfBytes, opts)
+	if err != nil {
+		log.Fatalf("Error verifying PDF: %v", err)
+	}
+
+	valid := true
+	for _, r := range reports {
+		valid = valid && r.Valid
+	}
+	if *jsonOutput {
+		out, err := json.MarshalIndent(reports, "", "  ")
+		if err != nil {
+			log.Fatalf("Error encoding reports: %v", err)
+		}
+		fmt.Println(string(out))
+	} else {
+		if len(reports) == 0 {
+			fmt.Println("No signatures")
+		}
+		for _, r := range reports {
+			status := "VALID"
+			if !r.Valid {
+				status = "INVALID"
+			}
+			fmt.Printf("%s\t%s\t%s\n", status, r.FieldName, r.Subject)
+			if !r.SignedAt.IsZero() {
+				fmt.Printf("\tSigned %s, revision %d of %d\n", r.SignedAt.Format("2006-01-02 15:04:05 MST"), r.Revision, r.Revision+r.LaterRevisions)
+			}
+			if len(r.Modifications) > 0 {
+				kinds := make([]string, len(r.Modifications))
+				for i, mod := range r.Modifications {
+					kinds[i] = fmt.Sprintf("%s (object %d)", mod.Kind, mod.Object)
+				}
+				fmt.Printf("\tChanged since: %s\n", strings.Join(kinds, ", "))
+			}
+			for _, problem := range r.Problems {
+				fmt.Printf("\t%s\n", problem)
+			}
+		}
+	}
+
+	if !valid {
+		os.Exit(1)
+	}
+}
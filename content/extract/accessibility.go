@@ -0,0 +1,340 @@
+package extract
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+const (
+	// maxStructureDepth bounds the walks of the structure tree and field hierarchy
+	maxStructureDepth = 256
+	// maxRoleMapDepth bounds the chain of role map entries followed for a structure type
+	maxRoleMapDepth = 8
+)
+
+// paintOperators are the content stream operators that put marks on a page
+var paintOperators = map[string]bool{
+	"Tj": true, "TJ": true, "'": true, `"`: true,
+	"f": true, "F": true, "f*": true, "B": true, "B*": true, "b": true, "b*": true, "S": true, "s": true,
+	"Do": true, "sh": true,
+}
+
+// AnalyzeAccessibility reports what stands between a document and PDF/UA: a
+// missing structure tree or document language, page content that is neither
+// tagged nor an artifact, Figure elements without alternate text, form fields
+// without a tooltip and pages with annotations whose tab order is not the
+// structure order. Content drawn inside form XObjects is not inspected, and
+// untagged content is only reported for tagged documents.
+func AnalyzeAccessibility(pdfBytes []byte, pdf *parse.PDF, verbose bool) (*types.AccessibilityReport, error) {
+	report := &types.AccessibilityReport{Issues: []types.AccessibilityIssue{}}
+	add := func(issue types.AccessibilityIssue) {
+		report.Issues = append(report.Issues, issue)
+	}
+
+	trailer := pdf.Trailer()
+	if trailer == nil || trailer.RootRef == "" {
+		return nil, fmt.Errorf("no document catalog")
+	}
+	r := &linkResolver{pdf: pdf, verbose: verbose}
+	catalog := r.deref(trailer.RootRef)
+	if catalog == "" {
+		return nil, fmt.Errorf("failed to read document catalog %s", trailer.RootRef)
+	}
+
+	structTree := r.deref(dictEntry(catalog, "/StructTreeRoot"))
+	marked := dictEntry(r.deref(dictEntry(catalog, "/MarkInfo")), "/Marked") == "true"
+	report.Tagged = structTree != "" && marked
+	switch {
+	case structTree == "":
+		add(types.AccessibilityIssue{Check: types.AccessibilityUntaggedDocument, Message: "document has no structure tree"})
+	case !marked:
+		add(types.AccessibilityIssue{Check: types.AccessibilityUntaggedDocument, Message: "document is not marked as tagged (/MarkInfo /Marked true)"})
+	}
+	report.Language = strings.TrimSpace(pdfString(dictEntry(catalog, "/Lang")))
+	if report.Language == "" {
+		add(types.AccessibilityIssue{Check: types.AccessibilityMissingLanguage, Message: "document language (/Lang) is not set"})
+	}
+
+	pageNumbers := make(map[int]int) // page object number -> page number
+	it := pdf.Pages()
+	for it.Next() {
+		ref := it.Page()
+		pageNumbers[ref.ObjectNumber] = ref.Number
+		page := objectContent(ref.Dict)
+
+		if len(arrayItems(r.deref(dictEntry(page, "/Annots")))) > 0 {
+			if tabs := decodeName(dictEntry(page, "/Tabs")); tabs != "S" {
+				message := "page has annotations but no tab order"
+				if tabs != "" {
+					message = fmt.Sprintf("page has annotations in /%s tab order rather than structure order", tabs)
+				}
+				add(types.AccessibilityIssue{Check: types.AccessibilityTabOrder, PageNumber: ref.Number, Object: ref.ObjectNumber, Message: message})
+			}
+		}
+
+		if report.Tagged {
+			properties := r.deref(dictEntry(ref.Resources, "/Properties"))
+			count := 0
+			for _, content := range pageContentStreams(pdf, ref.Dict, verbose) {
+				count += untaggedContent(r, content, properties)
+			}
+			if count > 0 {
+				add(types.AccessibilityIssue{
+					Check:      types.AccessibilityUntaggedContent,
+					PageNumber: ref.Number,
+					Object:     ref.ObjectNumber,
+					Count:      count,
+					Message:    fmt.Sprintf("%d content operators are neither tagged nor marked as artifacts", count),
+				})
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, fmt.Errorf("failed to walk pages tree: %w", err)
+	}
+
+	w := &accessibilityWalker{
+		r:       r,
+		report:  report,
+		pages:   pageNumbers,
+		roleMap: r.deref(dictEntry(structTree, "/RoleMap")),
+		visited: make(map[int]bool),
+	}
+	if structTree != "" {
+		w.walkStructure(dictEntry(structTree, "/K"), 0, 0)
+	}
+	w.walkFields(dictEntry(r.deref(dictEntry(catalog, "/AcroForm")), "/Fields"), "", false, 0)
+
+	report.IssueCounts = make(map[string]int)
+	for _, issue := range report.Issues {
+		report.IssueCounts[issue.Check]++
+	}
+	report.Passed = len(report.Issues) == 0
+	return report, nil
+}
+
+// accessibilityWalker checks the structure tree and form fields of a document
+type accessibilityWalker struct {
+	r       *linkResolver
+	report  *types.AccessibilityReport
+	pages   map[int]int // page object number -> page number
+	roleMap string
+	visited map[int]bool
+}
+
+// walkStructure checks the structure elements of a /K value: a structure
+// element, a reference to one, or an array of kids. pageNumber is the page of
+// the nearest ancestor with a /Pg entry.
+func (w *accessibilityWalker) walkStructure(value string, pageNumber, depth int) {
+	if depth > maxStructureDepth {
+		return
+	}
+	objNum := refNumber(value)
+	if objNum != 0 {
+		if w.visited[objNum] {
+			return
+		}
+		w.visited[objNum] = true
+	}
+	elem := w.r.deref(value)
+	if strings.HasPrefix(elem, "[") {
+		for _, kid := range arrayItems(elem) {
+			w.walkStructure(kid, pageNumber, depth+1)
+		}
+		return
+	}
+	// Marked-content IDs and references to marked content or objects end the walk
+	if !strings.HasPrefix(elem, "<<") {
+		return
+	}
+	if kind := decodeName(dictEntry(elem, "/Type")); kind == "MCR" || kind == "OBJR" {
+		return
+	}
+
+	if n, ok := w.pages[refNumber(dictEntry(elem, "/Pg"))]; ok {
+		pageNumber = n
+	}
+	if w.role(decodeName(dictEntry(elem, "/S"))) == "Figure" {
+		w.report.Figures++
+		alt := strings.TrimSpace(pdfString(dictEntry(elem, "/Alt")))
+		actual := strings.TrimSpace(pdfString(dictEntry(elem, "/ActualText")))
+		if alt == "" && actual == "" {
+			w.report.Issues = append(w.report.Issues, types.AccessibilityIssue{
+				Check:      types.AccessibilityMissingAltText,
+				PageNumber: pageNumber,
+				Object:     objNum,
+				Message:    "figure has no alternate text (/Alt)",
+			})
+		}
+	}
+	w.walkStructure(dictEntry(elem, "/K"), pageNumber, depth+1)
+}
+
+// role returns the standard structure type a type maps to through the role map
+func (w *accessibilityWalker) role(structType string) string {
+	for i := 0; i < maxRoleMapDepth; i++ {
+		mapped := decodeName(dictEntry(w.roleMap, "/"+structType))
+		if mapped == "" || mapped == structType {
+			break
+		}
+		structType = mapped
+	}
+	return structType
+}
+
+// walkFields checks the terminal fields of a /Fields or /Kids array for a
+// tooltip, which may also be set on an ancestor such as a radio button group
+func (w *accessibilityWalker) walkFields(value, parentName string, tooltip bool, depth int) {
+	if depth > maxStructureDepth {
+		return
+	}
+	for _, item := range arrayItems(w.r.deref(value)) {
+		objNum := refNumber(item)
+		if objNum != 0 {
+			if w.visited[objNum] {
+				continue
+			}
+			w.visited[objNum] = true
+		}
+		field := w.r.deref(item)
+		if !strings.HasPrefix(field, "<<") {
+			continue
+		}
+
+		name := pdfString(dictEntry(field, "/T"))
+		if parentName != "" {
+			name = parentName + "." + name
+		}
+		hasTooltip := tooltip || strings.TrimSpace(pdfString(dictEntry(field, "/TU"))) != ""
+
+		// Kids with a partial name are fields; the others are its widgets
+		kids := w.r.deref(dictEntry(field, "/Kids"))
+		var widgets []string
+		fieldKids := false
+		for _, kid := range arrayItems(kids) {
+			kidDict := w.r.deref(kid)
+			if dictEntry(kidDict, "/T") != "" {
+				fieldKids = true
+				break
+			}
+			widgets = append(widgets, kidDict)
+		}
+		if fieldKids {
+			w.walkFields(kids, name, hasTooltip, depth+1)
+			continue
+		}
+
+		w.report.FormFields++
+		if hasTooltip {
+			continue
+		}
+		page := dictEntry(field, "/P")
+		if page == "" && len(widgets) > 0 {
+			page = dictEntry(widgets[0], "/P")
+		}
+		w.report.Issues = append(w.report.Issues, types.AccessibilityIssue{
+			Check:      types.AccessibilityMissingTooltip,
+			PageNumber: w.pages[refNumber(page)],
+			Object:     objNum,
+			Field:      name,
+			Message:    fmt.Sprintf("field %q has no tooltip (/TU)", name),
+		})
+	}
+}
+
+// refNumber returns the object number of an indirect reference, or 0 if value
+// is not one
+func refNumber(value string) int {
+	match := indirectRefPattern.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil || len(match[0]) != len(strings.TrimSpace(value)) {
+		return 0
+	}
+	n, _ := strconv.Atoi(match[1])
+	return n
+}
+
+// untaggedContent counts the operators of a content stream that paint text,
+// paths, images or shadings outside any marked-content sequence with an MCID
+// and outside artifacts. Sequences nested in a tagged sequence or an artifact
+// are covered by it. properties is the page's /Properties resource dictionary,
+// which named BDC property lists refer to.
+func untaggedContent(r *linkResolver, content, properties string) int {
+	var (
+		stack    []bool // Whether each open marked-content sequence covers its content
+		covered  int    // Open sequences that cover their content
+		operands []string
+		count    int
+	)
+	for i := skipSpace(content, 0); i < len(content); i = skipSpace(content, i) {
+		c := content[i]
+		switch {
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '(' || c == '[' || c == '<' || c == '/':
+			end := valueEnd(content, i)
+			if end <= i {
+				end = i + 1
+			}
+			operands = append(operands, content[i:end])
+			i = end
+		case strings.IndexByte(")]>{}", c) != -1:
+			i++
+		default:
+			end := tokenEnd(content, i)
+			if end == i {
+				end = i + 1
+			}
+			token := content[i:end]
+			i = end
+			if _, err := strconv.ParseFloat(token, 64); err == nil {
+				operands = append(operands, token)
+				continue
+			}
+
+			switch token {
+			case "BMC", "BDC":
+				tagged := covered > 0 || (len(operands) > 0 && operands[0] == "/Artifact")
+				if !tagged && token == "BDC" && len(operands) > 1 {
+					props := operands[1]
+					if strings.HasPrefix(props, "/") {
+						props = r.deref(dictEntry(properties, props))
+					}
+					tagged = dictEntry(props, "/MCID") != ""
+				}
+				stack = append(stack, tagged)
+				if tagged {
+					covered++
+				}
+			case "EMC":
+				if len(stack) > 0 {
+					if stack[len(stack)-1] {
+						covered--
+					}
+					stack = stack[:len(stack)-1]
+				}
+			case "BI":
+				// Inline image: skip its data through the EI operator
+				if end := strings.Index(content[i:], "EI"); end != -1 {
+					i += end + 2
+				} else {
+					i = len(content)
+				}
+				if covered == 0 {
+					count++
+				}
+			default:
+				if paintOperators[token] && covered == 0 {
+					count++
+				}
+			}
+			operands = operands[:0]
+		}
+	}
+	return count
+}
@@ -0,0 +1,100 @@
+package extract
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+func TestAnalyzeAccessibility(t *testing.T) {
+	writer := write.NewPDFWriter()
+	catalogNum := writer.AddObject(nil)
+	pagesNum := writer.AddObject(nil)
+	pageNum := writer.AddObject(nil)
+	content := writer.AddStreamObject(write.Dictionary{}, []byte(
+		"/P <</MCID 0>> BDC BT /F1 12 Tf 72 720 Td (Tagged) Tj ET EMC\n"+
+			"/Artifact BMC 0 0 612 10 re f EMC\n"+
+			"/Span /MC1 BDC /Figure BMC 72 600 100 100 re f EMC EMC\n"+
+			"BT /F1 12 Tf 72 500 Td (Loose) Tj ET\n"), false)
+
+	named := writer.AddObject([]byte("<</T(name)/TU(Your name)/FT/Tx/Subtype/Widget/Rect[72 400 272 420]>>"))
+	groupNum := writer.AddObject(nil)
+	option := writer.AddObject([]byte(fmt.Sprintf("<</T(a)/Parent %d 0 R/FT/Btn/Subtype/Widget/P %d 0 R/Rect[72 360 92 380]>>", groupNum, pageNum)))
+	writer.SetObject(groupNum, []byte(fmt.Sprintf("<</T(group)/Kids[%d 0 R]>>", option)))
+
+	writer.SetObject(pageNum, []byte(fmt.Sprintf(
+		"<</Type/Page/Parent %d 0 R/MediaBox[0 0 612 792]/Contents %d 0 R/Resources<</Properties<</MC1 <</MCID 1>>>>>>/Annots[%d 0 R %d 0 R]>>",
+		pagesNum, content, named, option)))
+	writer.SetObject(pagesNum, []byte(fmt.Sprintf("<</Type/Pages/Kids[%d 0 R]/Count 1>>", pageNum)))
+
+	structRoot := writer.AddObject(nil)
+	document := writer.AddObject(nil)
+	paragraph := writer.AddObject([]byte(fmt.Sprintf("<</Type/StructElem/S/P/P %d 0 R/Pg %d 0 R/K 0>>", document, pageNum)))
+	figure := writer.AddObject([]byte(fmt.Sprintf("<</Type/StructElem/S/Img/P %d 0 R/Pg %d 0 R/K 1>>", document, pageNum)))
+	described := writer.AddObject([]byte(fmt.Sprintf("<</Type/StructElem/S/Figure/P %d 0 R/Alt(Company logo)/K[]>>", document)))
+	writer.SetObject(document, []byte(fmt.Sprintf("<</Type/StructElem/S/Document/P %d 0 R/K[%d 0 R %d 0 R %d 0 R]>>", structRoot, paragraph, figure, described)))
+	writer.SetObject(structRoot, []byte(fmt.Sprintf("<</Type/StructTreeRoot/K %d 0 R/RoleMap<</Img/Figure>>>>", document)))
+
+	writer.SetObject(catalogNum, []byte(fmt.Sprintf(
+		"<</Type/Catalog/Pages %d 0 R/StructTreeRoot %d 0 R/MarkInfo<</Marked true>>/AcroForm<</Fields[%d 0 R %d 0 R]>>>>",
+		pagesNum, structRoot, named, groupNum)))
+	writer.SetRoot(catalogNum)
+
+	pdfBytes, err := writer.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+	pdf, err := ParseTestPDF(pdfBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse PDF: %v", err)
+	}
+
+	report, err := AnalyzeAccessibility(pdfBytes, pdf, false)
+	if err != nil {
+		t.Fatalf("AnalyzeAccessibility failed: %v", err)
+	}
+	if report.Passed || !report.Tagged || report.Figures != 2 || report.FormFields != 2 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+	issues := make(map[string]types.AccessibilityIssue)
+	for _, issue := range report.Issues {
+		issues[issue.Check] = issue
+	}
+	if len(report.Issues) != 5 || len(issues) != 5 {
+		t.Fatalf("Expected one issue of each of 5 checks, got %+v", report.Issues)
+	}
+	if got := issues[types.AccessibilityUntaggedContent]; got.PageNumber != 1 || got.Count != 1 {
+		t.Errorf("Expected the loose text on page 1 as untagged, got %+v", got)
+	}
+	if got := issues[types.AccessibilityMissingAltText]; got.Object != figure || got.PageNumber != 1 {
+		t.Errorf("Expected the role-mapped figure to lack alt text, got %+v", got)
+	}
+	if got := issues[types.AccessibilityMissingTooltip]; got.Field != "group.a" || got.Object != option || got.PageNumber != 1 {
+		t.Errorf("Expected group.a to lack a tooltip, got %+v", got)
+	}
+	if got := issues[types.AccessibilityTabOrder]; got.PageNumber != 1 {
+		t.Errorf("Expected page 1 to lack a tab order, got %+v", got)
+	}
+	if _, ok := issues[types.AccessibilityMissingLanguage]; !ok || report.IssueCounts[types.AccessibilityMissingLanguage] != 1 {
+		t.Error("Expected a missing language issue")
+	}
+
+	// An untagged document is reported once rather than page by page
+	pdfBytes, _, err = CreateTestPDFWithText([]TestText{{Text: "Hello", X: 72, Y: 720, FontSize: 12}})
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+	pdf, err = ParseTestPDF(pdfBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse PDF: %v", err)
+	}
+	report, err = AnalyzeAccessibility(pdfBytes, pdf, false)
+	if err != nil {
+		t.Fatalf("AnalyzeAccessibility failed: %v", err)
+	}
+	if report.Tagged || report.IssueCounts[types.AccessibilityUntaggedDocument] != 1 || report.IssueCounts[types.AccessibilityUntaggedContent] != 0 {
+		t.Errorf("Unexpected report for an untagged document %+v", report)
+	}
+}
@@ -115,24 +115,14 @@ func extractAnnotation(annotObjNum int, pdf *parse.PDF, pageNum int, verbose boo
 		}
 	}
 
-	// Extract Contents (annotation text/contents)
-	contents := extractDictValue(annotStr, "/Contents")
-	if contents != "" {
-		// Unescape PDF string
-		annotation.Contents = unescapePDFString(contents)
-	}
-
-	// Extract Title
-	title := extractDictValue(annotStr, "/T")
-	if title != "" {
-		annotation.Title = unescapePDFString(title)
-	}
-
-	// Extract Subject
-	subject := extractDictValue(annotStr, "/Subj")
-	if subject != "" {
-		annotation.Subject = unescapePDFString(subject)
-	}
+	// Extract text strings. These are read with dictEntry so that literal
+	// strings keep their spaces and hex and UTF-16 strings are decoded.
+	dict := objectContent(annotStr)
+	annotation.Contents = pdfString(dictEntry(dict, "/Contents"))
+	annotation.Title = pdfString(dictEntry(dict, "/T"))
+	annotation.Subject = pdfString(dictEntry(dict, "/Subj"))
+	annotation.Name = pdfString(dictEntry(dict, "/NM"))
+	annotation.Modified = pdfString(dictEntry(dict, "/M"))
 
 	// Extract Color
 	color := extractArrayValue(annotStr, "/C")
@@ -1,6 +1,7 @@
 package extract
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/benedoc-inc/pdfer/core/write"
@@ -80,3 +81,38 @@ func TestExtractAnnotation_Structure(t *testing.T) {
 
 	_ = annotStr // Suppress unused variable warning
 }
+
+func TestExtractAnnotation_TextStrings(t *testing.T) {
+	writer := write.NewPDFWriter()
+	catalogNum := writer.AddObject(nil)
+	pagesNum := writer.AddObject(nil)
+	pageNum := writer.AddObject(nil)
+	annotNum := writer.AddObject([]byte("<</Type/Annot/Subtype/Text/Rect [10 10 30 30]/Contents(Please review \\(page 2\\))/T <FEFF004A006F>/Subj(Review note)/NM(note-1)/M(D:20240102030405Z)/C [1 0 0]>>"))
+	writer.SetObject(catalogNum, []byte(fmt.Sprintf("<</Type/Catalog/Pages %d 0 R>>", pagesNum)))
+	writer.SetObject(pagesNum, []byte(fmt.Sprintf("<</Type/Pages/Kids [%d 0 R]/Count 1>>", pageNum)))
+	writer.SetObject(pageNum, []byte(fmt.Sprintf("<</Type/Page/Parent %d 0 R/MediaBox [0 0 612 792]/Annots [%d 0 R]>>", pagesNum, annotNum)))
+	writer.SetRoot(catalogNum)
+
+	pdfBytes, err := writer.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+	doc, err := ExtractContent(pdfBytes, nil, false)
+	if err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	if len(doc.Annotations) != 1 {
+		t.Fatalf("Expected 1 annotation, got %d", len(doc.Annotations))
+	}
+
+	a := doc.Annotations[0]
+	if a.Contents != "Please review (page 2)" {
+		t.Errorf("Contents = %q", a.Contents)
+	}
+	if a.Title != "Jo" || a.Subject != "Review note" || a.Name != "note-1" || a.Modified != "D:20240102030405Z" {
+		t.Errorf("Unexpected strings: title=%q subject=%q name=%q modified=%q", a.Title, a.Subject, a.Name, a.Modified)
+	}
+	if a.Color == nil || a.Color.R != 1 {
+		t.Errorf("Color = %+v", a.Color)
+	}
+}
@@ -0,0 +1,188 @@
+package extract
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// ExtractAttachments returns the files in the catalog's /Names /EmbeddedFiles
+// name tree, in name tree order, with the SHA-256 hash of each file's decoded data
+func ExtractAttachments(pdfBytes []byte, pdf *parse.PDF, verbose bool) ([]types.Attachment, error) {
+	attachments := []types.Attachment{}
+	walkAttachments(pdf, verbose, func(attachment *types.Attachment, data []byte) bool {
+		attachments = append(attachments, *attachment)
+		return true
+	})
+	return attachments, nil
+}
+
+// ExtractAttachmentData returns the decoded data of the attachment with the
+// given name in the EmbeddedFiles name tree, or of the first attachment whose
+// file specification has that file name
+func ExtractAttachmentData(pdfBytes []byte, pdf *parse.PDF, name string, verbose bool) (*types.Attachment, []byte, error) {
+	var found *types.Attachment
+	var foundData []byte
+	walkAttachments(pdf, verbose, func(attachment *types.Attachment, data []byte) bool {
+		if attachment.Name == name || attachment.FileName == name {
+			found, foundData = attachment, data
+			return false
+		}
+		return true
+	})
+	if found == nil {
+		return nil, nil, fmt.Errorf("attachment %q not found", name)
+	}
+	return found, foundData, nil
+}
+
+// walkAttachments calls fn with each file in the EmbeddedFiles name tree and
+// its decoded data, in name tree order, until fn returns false
+func walkAttachments(pdf *parse.PDF, verbose bool, fn func(attachment *types.Attachment, data []byte) bool) {
+	trailer := pdf.Trailer()
+	if trailer == nil || trailer.RootRef == "" {
+		return
+	}
+
+	// The link resolver's deref follows references for any object
+	r := &linkResolver{pdf: pdf, verbose: verbose}
+	catalog := r.deref(trailer.RootRef)
+	names := r.deref(dictEntry(catalog, "/Names"))
+	if names == "" {
+		return
+	}
+
+	var walk func(node string, depth int) bool
+	walk = func(node string, depth int) bool {
+		if node == "" || depth > maxNameTreeDepth {
+			return true
+		}
+		if items := arrayItems(dictEntry(node, "/Names")); len(items) > 0 {
+			for i := 0; i+1 < len(items); i += 2 {
+				attachment, data, err := extractAttachment(r, pdfString(items[i]), items[i+1])
+				if err != nil {
+					if verbose {
+						fmt.Printf("Warning: failed to extract attachment %q: %v\n", pdfString(items[i]), err)
+					}
+					continue
+				}
+				if !fn(attachment, data) {
+					return false
+				}
+			}
+		}
+		for _, kid := range parseObjectRefArray(dictEntry(node, "/Kids")) {
+			if !walk(r.deref(kid), depth+1) {
+				return false
+			}
+		}
+		return true
+	}
+	walk(r.deref(dictEntry(names, "/EmbeddedFiles")), 0)
+}
+
+// extractAttachment reads the embedded file of a file specification
+func extractAttachment(r *linkResolver, name, specValue string) (*types.Attachment, []byte, error) {
+	spec := r.deref(specValue)
+	if !strings.HasPrefix(spec, "<<") {
+		return nil, nil, fmt.Errorf("file specification is not a dictionary")
+	}
+
+	// Prefer the Unicode file stream, as writers point /UF and /F at the same one
+	ef := r.deref(dictEntry(spec, "/EF"))
+	streamRef := dictEntry(ef, "/UF")
+	if streamRef == "" {
+		streamRef = dictEntry(ef, "/F")
+	}
+	objNum, err := parseObjectRef(streamRef)
+	if err != nil {
+		return nil, nil, fmt.Errorf("no embedded file stream: %w", err)
+	}
+	obj, err := r.pdf.GetObject(objNum)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get embedded file object %d: %w", objNum, err)
+	}
+	content := objectContent(string(obj))
+	data, err := decodeStream(r, content)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode embedded file object %d: %w", objNum, err)
+	}
+
+	sum := sha256.Sum256(data)
+	attachment := &types.Attachment{
+		Name:         name,
+		FileName:     r.fileSpec(spec),
+		Description:  pdfString(dictEntry(spec, "/Desc")),
+		Size:         len(data),
+		SHA256:       hex.EncodeToString(sum[:]),
+		ModDate:      pdfString(dictEntry(r.deref(dictEntry(content, "/Params")), "/ModDate")),
+		Relationship: decodeName(dictEntry(spec, "/AFRelationship")),
+	}
+	if subtype := dictEntry(content, "/Subtype"); subtype != "" {
+		attachment.MIMEType = decodeName(subtype)
+	}
+	return attachment, data, nil
+}
+
+// decodeStream returns the decoded data of a stream object's content, reading
+// /Length (which may be indirect) and applying each of its /Filter entries
+func decodeStream(r *linkResolver, content string) ([]byte, error) {
+	dictEnd := valueEnd(content, 0)
+	start := skipSpace(content, dictEnd)
+	if !strings.HasPrefix(content[start:], "stream") {
+		return nil, fmt.Errorf("not a stream")
+	}
+	start += len("stream")
+	if strings.HasPrefix(content[start:], "\r") {
+		start++
+	}
+	if strings.HasPrefix(content[start:], "\n") {
+		start++
+	}
+
+	var data string
+	length, err := strconv.Atoi(strings.TrimSpace(r.deref(dictEntry(content, "/Length"))))
+	if err == nil && length >= 0 && start+length <= len(content) {
+		data = content[start : start+length]
+	} else {
+		end := strings.LastIndex(content, "endstream")
+		if end < start {
+			return nil, fmt.Errorf("missing endstream")
+		}
+		data = strings.TrimRight(content[start:end], "\r\n")
+	}
+
+	decoded := []byte(data)
+	filters := dictEntry(content, "/Filter")
+	if strings.HasPrefix(filters, "[") {
+		filters = strings.Join(arrayItems(filters), " ")
+	}
+	for _, filter := range strings.Fields(filters) {
+		if decoded, err = parse.DecodeFilter(decoded, filter); err != nil {
+			return nil, err
+		}
+	}
+	return decoded, nil
+}
+
+// decodeName returns a name token without its slash, decoding #xx escapes
+func decodeName(token string) string {
+	name := strings.TrimPrefix(strings.TrimSpace(token), "/")
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '#' && i+2 < len(name) {
+			if v, err := strconv.ParseUint(name[i+1:i+3], 16, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(name[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,79 @@
+package extract
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+)
+
+func TestExtractAttachments(t *testing.T) {
+	writer := write.NewPDFWriter()
+	catalogNum := writer.AddObject(nil)
+	pagesNum := writer.AddObject([]byte("<</Type/Pages/Kids []/Count 0>>"))
+
+	csv := []byte("id,value\n1,42\n")
+	csvNum := writer.AddStreamObject(write.Dictionary{
+		"Type":    "/EmbeddedFile",
+		"Subtype": "/text#2Fcsv",
+		"Params":  write.Dictionary{"Size": 14, "ModDate": "D:20260101120000Z"},
+	}, csv, true)
+	notesNum := writer.AddStreamObject(write.Dictionary{"Type": "/EmbeddedFile"}, []byte("notes"), false)
+
+	csvSpec := writer.AddObject([]byte(fmt.Sprintf("<</Type/Filespec/F(data.csv)/UF <FEFF0064006100740061002E006300730076>/Desc(Raw data)/EF <</F %d 0 R>>>>", csvNum)))
+	leaf := writer.AddObject([]byte(fmt.Sprintf("<</Limits [(data.csv) (notes.txt)]/Names [(data.csv) %d 0 R (notes.txt) <</Type/Filespec/F(notes.txt)/AFRelationship/Supplement/EF <</F %d 0 R>>>>]>>", csvSpec, notesNum)))
+	writer.SetObject(catalogNum, []byte(fmt.Sprintf("<</Type/Catalog/Pages %d 0 R/Names <</EmbeddedFiles <</Kids [%d 0 R]>>>>>>", pagesNum, leaf)))
+	writer.SetRoot(catalogNum)
+
+	pdfBytes, err := writer.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+	pdf, err := ParseTestPDF(pdfBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse PDF: %v", err)
+	}
+
+	attachments, err := ExtractAttachments(pdfBytes, pdf, false)
+	if err != nil {
+		t.Fatalf("ExtractAttachments failed: %v", err)
+	}
+	if len(attachments) != 2 {
+		t.Fatalf("Expected 2 attachments, got %d: %+v", len(attachments), attachments)
+	}
+
+	sum := sha256.Sum256(csv)
+	a := attachments[0]
+	if a.Name != "data.csv" || a.FileName != "data.csv" || a.Description != "Raw data" || a.MIMEType != "text/csv" ||
+		a.Size != len(csv) || a.SHA256 != hex.EncodeToString(sum[:]) || a.ModDate != "D:20260101120000Z" {
+		t.Errorf("Unexpected first attachment: %+v", a)
+	}
+	sum = sha256.Sum256([]byte("notes"))
+	if b := attachments[1]; b.Name != "notes.txt" || b.Size != 5 || b.SHA256 != hex.EncodeToString(sum[:]) || b.Relationship != "Supplement" {
+		t.Errorf("Unexpected second attachment: %+v", b)
+	}
+
+	a2, data, err := ExtractAttachmentData(pdfBytes, pdf, "data.csv", false)
+	if err != nil || a2.Name != "data.csv" || string(data) != string(csv) {
+		t.Errorf("ExtractAttachmentData = %+v, %q, %v", a2, data, err)
+	}
+	if _, _, err := ExtractAttachmentData(pdfBytes, pdf, "missing.txt", false); err == nil {
+		t.Error("Expected an error for a missing attachment")
+	}
+}
+
+func TestExtractAttachments_None(t *testing.T) {
+	pdfBytes, _, err := CreateTestPDFWithText([]TestText{{Text: "Hello", X: 72, Y: 720, FontSize: 12}})
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+	doc, err := ExtractContent(pdfBytes, nil, false)
+	if err != nil {
+		t.Fatalf("ExtractContent failed: %v", err)
+	}
+	if len(doc.Attachments) != 0 {
+		t.Errorf("Expected no attachments, got %+v", doc.Attachments)
+	}
+}
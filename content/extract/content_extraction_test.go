@@ -280,6 +280,12 @@ func TestExtractGraphics_Line(t *testing.T) {
 				lineGraphic.StrokeColor.R, lineGraphic.StrokeColor.G, lineGraphic.StrokeColor.B)
 		}
 	}
+	if lineGraphic.Path == nil || len(lineGraphic.Path.Operations) != 2 || lineGraphic.Path.Operations[0].Points[0] != (types.Point{X: 50, Y: 50}) {
+		t.Errorf("Expected path from (50, 50), got %+v", lineGraphic.Path)
+	}
+	if bb := lineGraphic.BoundingBox; bb == nil || *bb != (types.Rectangle{LowerX: 50, LowerY: 50, UpperX: 200, UpperY: 200}) {
+		t.Errorf("Unexpected bounding box %+v", bb)
+	}
 }
 
 func TestExtractGraphics_LineWidth(t *testing.T) {
@@ -1,9 +1,11 @@
 package extract
 
 import (
+	"math"
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/benedoc-inc/pdfer/core/parse"
 	"github.com/benedoc-inc/pdfer/types"
@@ -17,7 +19,69 @@ func parseContentStream(contentStr string, pdf *parse.PDF, pageNum int, verbose
 
 // parseContentStreamWithDecoders parses a PDF content stream with font decoding support
 func parseContentStreamWithDecoders(contentStr string, pdf *parse.PDF, pageNum int, fontDecoders map[string]*FontDecoder, verbose bool) ([]types.TextElement, []types.Graphic, []types.ImageRef) {
+	text, graphics, images, _ := parseContentStreamParts(contentStr, pdf, pageNum, fontDecoders, contentParts{text: true, graphics: true, images: true}, verbose)
+	return text, graphics, images
+}
+
+// ContentStreamText returns the text elements a decoded content stream shows
+// and, for each, the index of the line (counting lines split on "\n") whose
+// operator shows it. Widths are estimated without font metrics.
+func ContentStreamText(contentStr string) ([]types.TextElement, []int) {
+	text, _, _, lines := parseContentStreamParts(contentStr, nil, 0, nil, contentParts{text: true}, false)
+	return text, lines
+}
+
+// contentParts selects what parseContentStreamParts extracts
+type contentParts struct {
+	text     bool
+	graphics bool
+	images   bool
+}
+
+// any reports whether any part is selected
+func (c contentParts) any() bool {
+	return c.text || c.graphics || c.images
+}
+
+// skips reports whether a line can be skipped because the operator ending it
+// only matters to parts that are not selected
+func (c contentParts) skips(line string) bool {
+	op := line
+	if i := strings.LastIndexAny(line, " \t\r"); i >= 0 {
+		op = line[i+1:]
+	}
+	switch {
+	case textOperators[op]:
+		return !c.text
+	case graphicsOperators[op]:
+		return !c.graphics
+	case imageOperators[op]:
+		return !c.images
+	}
+	return false
+}
+
+// Operators that only affect text, graphics or image placement, respectively
+var (
+	textOperators = map[string]bool{
+		"BT": true, "ET": true, "Td": true, "TD": true, "Tm": true, "T*": true, "Tf": true, "Tc": true,
+		"Tw": true, "Ts": true, "TL": true, "Tz": true, "Tr": true, "Tj": true, "TJ": true, "'": true, `"`: true,
+	}
+	graphicsOperators = map[string]bool{
+		"re": true, "m": true, "l": true, "c": true, "v": true, "y": true, "h": true,
+		"S": true, "s": true, "f": true, "F": true, "f*": true, "B": true, "B*": true, "b": true, "b*": true, "n": true,
+		"w": true, "rg": true, "RG": true, "k": true, "K": true, "g": true, "G": true,
+	}
+	imageOperators = map[string]bool{"q": true, "Q": true, "cm": true, "Do": true}
+)
+
+// parseContentStreamParts parses the selected parts of a PDF content stream,
+// returning with the text elements the index of the line that shows each.
+// Lines whose operator only serves unselected parts are skipped before any
+// pattern matching.
+func parseContentStreamParts(contentStr string, pdf *parse.PDF, pageNum int, fontDecoders map[string]*FontDecoder, parts contentParts, verbose bool) ([]types.TextElement, []types.Graphic, []types.ImageRef, []int) {
 	var textElements []types.TextElement
+	var textLines []int
 	var graphics []types.Graphic
 	var imageRefs []types.ImageRef
 
@@ -45,12 +109,13 @@ func parseContentStreamWithDecoders(contentStr string, pdf *parse.PDF, pageNum i
 	// Default is identity matrix [1 0 0 1 0 0]
 	currentMatrix := [6]float64{1, 0, 0, 1, 0, 0}
 	matrixStack := [][6]float64{} // Stack for q/Q operators
+	var currentPoint *types.Point // Current point of the path being built
 
 	// Split content stream into tokens
 	lines := strings.Split(contentStr, "\n")
-	for _, line := range lines {
+	for lineIndex, line := range lines {
 		line = strings.TrimSpace(line)
-		if line == "" {
+		if line == "" || parts.skips(line) {
 			continue
 		}
 
@@ -133,6 +198,7 @@ func parseContentStreamWithDecoders(contentStr string, pdf *parse.PDF, pageNum i
 			text := decodeTextWithFont(rawText, textState.decoder, false)
 			textElement := createTextElement(text, textState)
 			textElements = append(textElements, textElement)
+			textLines = append(textLines, lineIndex)
 			continue
 		}
 		if match := regexp.MustCompile(`^<([0-9A-Fa-f\s]*)>\s+Tj`).FindStringSubmatch(line); match != nil {
@@ -140,6 +206,7 @@ func parseContentStreamWithDecoders(contentStr string, pdf *parse.PDF, pageNum i
 			text := decodeTextWithFont(match[1], textState.decoder, true)
 			textElement := createTextElement(text, textState)
 			textElements = append(textElements, textElement)
+			textLines = append(textLines, lineIndex)
 			continue
 		}
 
@@ -149,6 +216,7 @@ func parseContentStreamWithDecoders(contentStr string, pdf *parse.PDF, pageNum i
 			text := decodeTextWithFont(rawText, textState.decoder, false)
 			textElement := createTextElement(text, textState)
 			textElements = append(textElements, textElement)
+			textLines = append(textLines, lineIndex)
 			// Move to next line (simplified - would need leading)
 			textState.y -= textState.fontSize * 1.2
 			continue
@@ -157,6 +225,7 @@ func parseContentStreamWithDecoders(contentStr string, pdf *parse.PDF, pageNum i
 			text := decodeTextWithFont(match[1], textState.decoder, true)
 			textElement := createTextElement(text, textState)
 			textElements = append(textElements, textElement)
+			textLines = append(textLines, lineIndex)
 			textState.y -= textState.fontSize * 1.2
 			continue
 		}
@@ -166,6 +235,7 @@ func parseContentStreamWithDecoders(contentStr string, pdf *parse.PDF, pageNum i
 			text := parseTextArrayWithDecoder(match[1], textState.decoder)
 			textElement := createTextElement(text, textState)
 			textElements = append(textElements, textElement)
+			textLines = append(textLines, lineIndex)
 			continue
 		}
 
@@ -198,21 +268,38 @@ func parseContentStreamWithDecoders(contentStr string, pdf *parse.PDF, pageNum i
 
 		// Move to (m operator)
 		if match := regexp.MustCompile(`^([\d\.\-]+)\s+([\d\.\-]+)\s+m`).FindStringSubmatch(line); match != nil {
-			// Start of a path - we'll track this for line/path extraction
-			// For now, just note it
+			// Start of a path - lines drawn from here record it as their start
+			x, _ := strconv.ParseFloat(match[1], 64)
+			y, _ := strconv.ParseFloat(match[2], 64)
+			currentPoint = &types.Point{X: x, Y: y}
 			continue
 		}
 
 		// Line to (l operator)
 		if match := regexp.MustCompile(`^([\d\.\-]+)\s+([\d\.\-]+)\s+l`).FindStringSubmatch(line); match != nil {
-			// Simplified - would need to track path start
-			// For now, just note that a line was drawn
+			// Each segment is recorded as a line from the current point
 			graphic := types.Graphic{
 				Type:        types.GraphicTypeLine,
 				StrokeColor: graphicsState.strokeColor,
 				LineWidth:   graphicsState.lineWidth,
 			}
+			x, _ := strconv.ParseFloat(match[1], 64)
+			y, _ := strconv.ParseFloat(match[2], 64)
+			end := types.Point{X: x, Y: y}
+			if currentPoint != nil {
+				graphic.Path = &types.Path{Operations: []types.PathOperation{
+					{Type: types.PathOpMove, Points: []types.Point{*currentPoint}},
+					{Type: types.PathOpLine, Points: []types.Point{end}},
+				}}
+				graphic.BoundingBox = &types.Rectangle{
+					LowerX: math.Min(currentPoint.X, x),
+					LowerY: math.Min(currentPoint.Y, y),
+					UpperX: math.Max(currentPoint.X, x),
+					UpperY: math.Max(currentPoint.Y, y),
+				}
+			}
 			graphics = append(graphics, graphic)
+			currentPoint = &end
 			continue
 		}
 
@@ -292,6 +379,29 @@ func parseContentStreamWithDecoders(contentStr string, pdf *parse.PDF, pageNum i
 			continue
 		}
 
+		// Set fill or stroke color CMYK (k and K operators)
+		if match := regexp.MustCompile(`^([\d\.\-]+)\s+([\d\.\-]+)\s+([\d\.\-]+)\s+([\d\.\-]+)\s+(k|K)\b`).FindStringSubmatch(line); match != nil {
+			var cmyk [4]float64
+			valid := true
+			for i := range cmyk {
+				v, err := strconv.ParseFloat(match[i+1], 64)
+				if err != nil {
+					valid = false
+					break
+				}
+				cmyk[i] = v
+			}
+			if valid {
+				color := &types.Color{Space: types.ColorSpaceCMYK, C: cmyk[0], M: cmyk[1], Y: cmyk[2], K: cmyk[3]}
+				if match[5] == "k" {
+					graphicsState.fillColor = color
+				} else {
+					graphicsState.strokeColor = color
+				}
+			}
+			continue
+		}
+
 		// Draw image (Do operator)
 		// Extract position and size from current transformation matrix
 		if match := regexp.MustCompile(`^([/\w]+)\s+Do`).FindStringSubmatch(line); match != nil {
@@ -325,7 +435,7 @@ func parseContentStreamWithDecoders(contentStr string, pdf *parse.PDF, pageNum i
 		}
 	}
 
-	return textElements, graphics, imageRefs
+	return textElements, graphics, imageRefs, textLines
 }
 
 // textState tracks the current text rendering state
@@ -351,8 +461,13 @@ type graphicsState struct {
 
 // createTextElement creates a TextElement from text and current text state
 func createTextElement(text string, state *textState) types.TextElement {
-	// Calculate approximate width (simplified - would need font metrics)
+	// Calculate approximate width unless the font's widths were loaded
 	width := float64(len(text)) * state.fontSize * 0.6
+	if state.decoder != nil {
+		if w, ok := state.decoder.TextWidth(text, state.fontSize); ok {
+			width = w + state.charSpacing*float64(utf8.RuneCountInString(text)) + state.wordSpacing*float64(strings.Count(text, " "))
+		}
+	}
 
 	return types.TextElement{
 		Text:        text,
@@ -0,0 +1,178 @@
+package extract
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// dictionaryWordPattern matches words: letters, joined by apostrophes and hyphens
+var dictionaryWordPattern = regexp.MustCompile(`\p{L}[\p{L}\p{M}]*(?:['’-]\p{L}[\p{L}\p{M}]*)*`)
+
+// dictionarySkipPattern matches email addresses and URLs, whose words are not checked
+var dictionarySkipPattern = regexp.MustCompile(`[^\s@]+@[^\s@]+\.\S+|(?:https?://|www\.)\S+`)
+
+// Dictionary is a list of accepted words for AuditDictionary
+type Dictionary struct {
+	words         map[string]bool
+	caseSensitive bool
+}
+
+// NewDictionary returns an empty dictionary. A case-sensitive dictionary still
+// accepts a capitalized word whose lowercase form it contains, as at the start
+// of a sentence.
+func NewDictionary(caseSensitive bool) *Dictionary {
+	return &Dictionary{words: make(map[string]bool), caseSensitive: caseSensitive}
+}
+
+// Add adds words to the dictionary. Entries of several words, such as approved
+// phrases, add each of their words.
+func (d *Dictionary) Add(words ...string) {
+	for _, entry := range words {
+		for _, word := range dictionaryWordPattern.FindAllString(entry, -1) {
+			d.words[d.key(word)] = true
+		}
+	}
+}
+
+// Load adds a word list with one entry per line. Blank lines and lines
+// starting with # are skipped.
+func (d *Dictionary) Load(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		d.Add(line)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read word list: %w", err)
+	}
+	return nil
+}
+
+// Len returns the number of words in the dictionary
+func (d *Dictionary) Len() int {
+	return len(d.words)
+}
+
+// Contains reports whether a word is in the dictionary. A hyphenated word is
+// accepted when the dictionary contains it whole or each of its parts.
+func (d *Dictionary) Contains(word string) bool {
+	if d.contains(word) {
+		return true
+	}
+	parts := strings.Split(word, "-")
+	if len(parts) == 1 {
+		return false
+	}
+	for _, part := range parts {
+		if !d.contains(part) {
+			return false
+		}
+	}
+	return true
+}
+
+// contains looks up a word without splitting it
+func (d *Dictionary) contains(word string) bool {
+	word = strings.ReplaceAll(word, "’", "'")
+	if d.words[d.key(word)] {
+		return true
+	}
+	if !d.caseSensitive {
+		return false
+	}
+	first, size := utf8.DecodeRuneInString(word)
+	return unicode.IsUpper(first) && d.words[string(unicode.ToLower(first))+word[size:]]
+}
+
+// key returns the form a word is stored and looked up in
+func (d *Dictionary) key(word string) string {
+	word = strings.ReplaceAll(word, "’", "'")
+	if d.caseSensitive {
+		return word
+	}
+	return strings.ToLower(word)
+}
+
+// AuditDictionary checks the words of every page's text against a dictionary
+// and reports the words it does not contain with where they occur, most
+// frequent first. Words are runs of letters joined by apostrophes or hyphens;
+// numbers, words with digits, email addresses and URLs are not checked.
+func AuditDictionary(pdfBytes []byte, pdf *parse.PDF, dict *Dictionary, verbose bool) (*types.DictionaryReport, error) {
+	pages, err := ExtractPagesWithOptions(pdfBytes, pdf, ExtractOptions{Verbose: verbose, TextOnly: true})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &types.DictionaryReport{Unknown: []types.DictionaryTerm{}}
+	unknown := make(map[string]*types.DictionaryTerm)
+	for i := range pages {
+		text, spans := pageText(&pages[i])
+		skipped := dictionarySkipPattern.FindAllStringIndex(text, -1)
+		for _, loc := range dictionaryWordPattern.FindAllStringIndex(text, -1) {
+			// Skip words glued to digits, such as part numbers
+			if loc[0] > 0 && isDigitByte(text[loc[0]-1]) || loc[1] < len(text) && isDigitByte(text[loc[1]]) {
+				continue
+			}
+			for len(skipped) > 0 && skipped[0][1] <= loc[0] {
+				skipped = skipped[1:]
+			}
+			if len(skipped) > 0 && skipped[0][0] < loc[1] {
+				continue
+			}
+			word := text[loc[0]:loc[1]]
+			report.WordsChecked++
+			if dict.Contains(word) {
+				continue
+			}
+
+			term, ok := unknown[word]
+			if !ok {
+				term = &types.DictionaryTerm{Word: word}
+				unknown[word] = term
+			}
+			term.Count++
+			for _, span := range spans {
+				if span.end > loc[0] && span.start < loc[1] {
+					term.Occurrences = append(term.Occurrences, types.TermOccurrence{
+						PageNumber: pages[i].PageNumber,
+						Rect:       spanRect(span, max(loc[0], span.start), min(loc[1], span.end)),
+					})
+					break
+				}
+			}
+		}
+	}
+
+	for _, term := range unknown {
+		report.Unknown = append(report.Unknown, *term)
+		report.UnknownWords += term.Count
+	}
+	sort.Slice(report.Unknown, func(i, j int) bool {
+		a, b := report.Unknown[i], report.Unknown[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Word < b.Word
+	})
+	report.Passed = len(report.Unknown) == 0
+	if verbose {
+		fmt.Printf("Checked %d words, %d not in the dictionary\n", report.WordsChecked, report.UnknownWords)
+	}
+	return report, nil
+}
+
+func isDigitByte(c byte) bool {
+	return c >= '0' && c <= '9'
+}
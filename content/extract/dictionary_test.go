@@ -0,0 +1,69 @@
+package extract
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDictionary_Contains(t *testing.T) {
+	dict := NewDictionary(false)
+	if err := dict.Load(strings.NewReader("# approved terms\nthe\nDevice\nwell\nknown\n\nbiocompatible material\npatient's\n")); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	for _, word := range []string{"The", "device", "DEVICE", "well-known", "material", "patient’s"} {
+		if !dict.Contains(word) {
+			t.Errorf("Contains(%q) = false", word)
+		}
+	}
+	for _, word := range []string{"devise", "well-made", "#"} {
+		if dict.Contains(word) {
+			t.Errorf("Contains(%q) = true", word)
+		}
+	}
+
+	strict := NewDictionary(true)
+	strict.Add("FDA", "labeling")
+	if !strict.Contains("FDA") || !strict.Contains("Labeling") || strict.Contains("fda") || strict.Contains("LABELING") {
+		t.Error("Case-sensitive lookups failed")
+	}
+}
+
+func TestAuditDictionary(t *testing.T) {
+	pdfBytes, _, err := CreateTestPDFWithText([]TestText{
+		{Text: "The devise is sterile", X: 72, Y: 720, FontSize: 12},
+		{Text: "Model X200 devise", X: 72, Y: 700, FontSize: 12},
+		{Text: "support@example.com www.example.com/ifu", X: 72, Y: 680, FontSize: 12},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+	pdf, err := ParseTestPDF(pdfBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse PDF: %v", err)
+	}
+
+	dict := NewDictionary(false)
+	dict.Add("the is sterile")
+	report, err := AuditDictionary(pdfBytes, pdf, dict, false)
+	if err != nil {
+		t.Fatalf("AuditDictionary failed: %v", err)
+	}
+	if report.Passed || report.WordsChecked != 6 || report.UnknownWords != 3 || len(report.Unknown) != 2 {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+	devise := report.Unknown[0]
+	if devise.Word != "devise" || devise.Count != 2 || len(devise.Occurrences) != 2 {
+		t.Fatalf("Unexpected term %+v", devise)
+	}
+	if o := devise.Occurrences[0]; o.PageNumber != 1 || o.Rect.LowerX <= 72 || o.Rect.LowerY >= 720 || o.Rect.UpperY <= 720 {
+		t.Errorf("Unexpected occurrence %+v", o)
+	}
+	if report.Unknown[1].Word != "Model" {
+		t.Errorf("Expected Model to be reported, got %+v", report.Unknown[1])
+	}
+
+	dict.Add("devise model")
+	if report, _ := AuditDictionary(pdfBytes, pdf, dict, false); !report.Passed {
+		t.Errorf("Expected the audit to pass, got %+v", report.Unknown)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+
+	"github.com/benedoc-inc/pdfer/core/write"
 )
 
 // FontDecoder decodes character codes to Unicode for a specific font
@@ -17,6 +19,13 @@ type FontDecoder struct {
 	// Differences array overlays the base encoding
 	differences map[int]rune
 
+	// Glyph widths in thousandths of text space units, keyed by decoded
+	// character. Loaded by the accurate extraction profile only.
+	widths map[rune]float64
+
+	// Standard 14 font whose metrics give widths when the font has no /Widths
+	standardFont string
+
 	// Font name for debugging
 	fontName string
 }
@@ -133,6 +142,32 @@ func (fd *FontDecoder) DecodeHex(hexStr string) string {
 	return result.String()
 }
 
+// SetWidths sets glyph widths from a /Widths array starting at code firstChar.
+// Widths are keyed by decoded character, so the encoding and ToUnicode map must
+// be set first.
+func (fd *FontDecoder) SetWidths(firstChar int, widths []float64) {
+	fd.widths = make(map[rune]float64, len(widths))
+	for i, w := range widths {
+		fd.widths[fd.lookupCode(firstChar+i)] = w
+	}
+}
+
+// TextWidth returns the width of decoded text at the given font size, and false
+// when the font's widths were not loaded
+func (fd *FontDecoder) TextWidth(text string, size float64) (float64, bool) {
+	if fd.widths == nil {
+		if fd.standardFont == "" {
+			return 0, false
+		}
+		return write.StandardTextWidth(text, fd.standardFont, size), true
+	}
+	width := 0.0
+	for _, r := range text {
+		width += fd.widths[r]
+	}
+	return width * size / 1000, true
+}
+
 // has2ByteMapping checks if the font has 2-byte ToUnicode mappings
 func (fd *FontDecoder) has2ByteMapping() bool {
 	for code := range fd.toUnicode {
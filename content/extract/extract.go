@@ -6,6 +6,8 @@ package extract
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"sort"
 
 	"github.com/benedoc-inc/pdfer/core/parse"
 	"github.com/benedoc-inc/pdfer/types"
@@ -14,21 +16,20 @@ import (
 // ExtractContent extracts all content from a PDF into a ContentDocument
 // This is the main entry point for content extraction
 func ExtractContent(pdfBytes []byte, password []byte, verbose bool) (*types.ContentDocument, error) {
-	// Parse PDF
-	pdf, err := parse.OpenWithOptions(pdfBytes, parse.ParseOptions{
-		Password: password,
-		Verbose:  verbose,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse PDF: %w", err)
-	}
+	return ExtractContentWithOptions(pdfBytes, ExtractOptions{Password: password, Verbose: verbose})
+}
 
+// extractContent extracts all content from an open PDF
+func extractContent(pdfBytes []byte, pdf *parse.PDF, opts ExtractOptions) (*types.ContentDocument, error) {
+	verbose := opts.Verbose
 	doc := &types.ContentDocument{
 		Pages:       []types.Page{},
 		Bookmarks:   []types.Bookmark{},
 		Annotations: []types.Annotation{},
 		Images:      []types.Image{},
 		Fonts:       []types.FontInfo{},
+		Attachments: []types.Attachment{},
+		Signatures:  []types.Signature{},
 	}
 
 	// Extract metadata
@@ -45,23 +46,62 @@ func ExtractContent(pdfBytes []byte, password []byte, verbose bool) (*types.Cont
 	}
 
 	// Extract pages
-	pages, err := ExtractPages(pdfBytes, pdf, verbose)
+	pages, err := ExtractPagesWithOptions(pdfBytes, pdf, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to extract pages: %w", err)
 	}
 	doc.Pages = pages
 
-	// Extract bookmarks/outlines
-	bookmarks, err := ExtractBookmarks(pdfBytes, pdf, verbose)
-	if err != nil {
-		// Use warning collector if available, otherwise fall back to verbose logging
-		if pdf.Warnings() != nil {
-			pdf.Warnings().AddWarningf(types.WarningLevelWarning, "failed to extract bookmarks: %v", err)
-		} else if verbose {
-			fmt.Printf("Warning: failed to extract bookmarks: %v\n", err)
+	// Extract document-level parts unless only one content type was asked for
+	if opts.wantDocumentParts() {
+		// Extract bookmarks/outlines
+		bookmarks, err := ExtractBookmarks(pdfBytes, pdf, verbose)
+		if err != nil {
+			// Use warning collector if available, otherwise fall back to verbose logging
+			if pdf.Warnings() != nil {
+				pdf.Warnings().AddWarningf(types.WarningLevelWarning, "failed to extract bookmarks: %v", err)
+			} else if verbose {
+				fmt.Printf("Warning: failed to extract bookmarks: %v\n", err)
+			}
+		} else {
+			doc.Bookmarks = bookmarks
+		}
+
+		// Extract article threads
+		threads, err := ExtractThreads(pdf, verbose)
+		if err != nil {
+			if pdf.Warnings() != nil {
+				pdf.Warnings().AddWarningf(types.WarningLevelWarning, "failed to extract article threads: %v", err)
+			} else if verbose {
+				fmt.Printf("Warning: failed to extract article threads: %v\n", err)
+			}
+		} else if len(threads) > 0 {
+			doc.Threads = threads
+		}
+
+		// Extract embedded files
+		attachments, err := ExtractAttachments(pdfBytes, pdf, verbose)
+		if err != nil {
+			if pdf.Warnings() != nil {
+				pdf.Warnings().AddWarningf(types.WarningLevelWarning, "failed to extract attachments: %v", err)
+			} else if verbose {
+				fmt.Printf("Warning: failed to extract attachments: %v\n", err)
+			}
+		} else {
+			doc.Attachments = attachments
+		}
+
+		// Extract signatures
+		signatures, err := ExtractSignatures(pdfBytes, pdf, verbose)
+		if err != nil {
+			if pdf.Warnings() != nil {
+				pdf.Warnings().AddWarningf(types.WarningLevelWarning, "failed to extract signatures: %v", err)
+			} else if verbose {
+				fmt.Printf("Warning: failed to extract signatures: %v\n", err)
+			}
+		} else {
+			doc.Signatures = signatures
 		}
-	} else {
-		doc.Bookmarks = bookmarks
 	}
 
 	// Extract annotations (from all pages)
@@ -82,8 +122,8 @@ func ExtractContent(pdfBytes []byte, password []byte, verbose bool) (*types.Cont
 			}
 		}
 	}
-	for _, img := range imageMap {
-		doc.Images = append(doc.Images, img)
+	for _, id := range sortedKeys(imageMap) {
+		doc.Images = append(doc.Images, imageMap[id])
 	}
 
 	// Extract fonts (collect unique fonts from all pages)
@@ -97,13 +137,24 @@ func ExtractContent(pdfBytes []byte, password []byte, verbose bool) (*types.Cont
 			}
 		}
 	}
-	for _, font := range fontMap {
-		doc.Fonts = append(doc.Fonts, font)
+	for _, id := range sortedKeys(fontMap) {
+		doc.Fonts = append(doc.Fonts, fontMap[id])
 	}
 
 	return doc, nil
 }
 
+// sortedKeys returns the keys of m in order, so documents list images and fonts
+// in the same order on every extraction
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // ExtractContentToJSON extracts content and returns as JSON string
 func ExtractContentToJSON(pdfBytes []byte, password []byte, verbose bool) (string, error) {
 	doc, err := ExtractContent(pdfBytes, password, verbose)
@@ -119,3 +170,19 @@ func ExtractContentToJSON(pdfBytes []byte, password []byte, verbose bool) (strin
 
 	return string(jsonBytes), nil
 }
+
+// WriteContentJSON extracts content and writes it as JSON to out, for example
+// os.Stdout or an HTTP response
+func WriteContentJSON(out io.Writer, pdfBytes []byte, password []byte, verbose bool) error {
+	doc, err := ExtractContent(pdfBytes, password, verbose)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to write JSON: %w", err)
+	}
+	return nil
+}
@@ -1,8 +1,11 @@
 package extract
 
 import (
+	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/benedoc-inc/pdfer/core/write"
@@ -100,6 +103,14 @@ func TestExtractFromGeneratedPDF(t *testing.T) {
 	}
 
 	t.Logf("JSON serialization: %d bytes", len(jsonBytes))
+
+	var buf bytes.Buffer
+	if err := WriteContentJSON(&buf, pdfBytes, nil, false); err != nil {
+		t.Fatalf("WriteContentJSON failed: %v", err)
+	}
+	if strings.TrimSpace(buf.String()) != jsonBytes {
+		t.Error("WriteContentJSON output differs from ExtractContentToJSON")
+	}
 }
 
 func TestExtractComplexText(t *testing.T) {
@@ -249,3 +260,112 @@ func TestExtractGraphics(t *testing.T) {
 	// - Line is extracted with correct endpoints, width, and stroke color
 	// - Circle/path is extracted correctly
 }
+
+func TestExtractSinglePage(t *testing.T) {
+	builder := write.NewSimplePDFBuilder()
+	for _, text := range []string{"First", "Second", "Third"} {
+		page := builder.AddPage(write.PageSizeLetter)
+		fontName := page.AddStandardFont("Helvetica")
+		page.Content().BeginText().SetFont(fontName, 12).SetTextPosition(72, 720).ShowText(text).EndText()
+		builder.FinalizePage(page)
+	}
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+
+	pdf, err := ParseTestPDF(pdfBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse PDF: %v", err)
+	}
+
+	count, err := PageCount(pdf)
+	if err != nil {
+		t.Fatalf("PageCount failed: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 pages, got %d", count)
+	}
+
+	page, err := ExtractPage(pdfBytes, pdf, 2, false)
+	if err != nil {
+		t.Fatalf("ExtractPage failed: %v", err)
+	}
+	if page.PageNumber != 2 || len(page.Text) != 1 || page.Text[0].Text != "Second" {
+		t.Errorf("Expected page 2 with text 'Second', got %+v", page.Text)
+	}
+
+	if _, err := ExtractPage(pdfBytes, pdf, 4, false); err == nil {
+		t.Error("Expected error for out-of-range page")
+	}
+}
+
+// createInheritedAttributesPDF builds a PDF whose page gets Resources, MediaBox and
+// Rotate only from its parent /Pages node
+func createInheritedAttributesPDF() []byte {
+	content := "BT\n/F1 12 Tf\n72 720 Td\n(Inherited) Tj\nET\nq\n50 0 0 50 72 600 cm\n/Im1 Do\nQ\n"
+	objects := []string{
+		"<</Type/Catalog/Pages 2 0 R>>",
+		"<</Type/Pages/Kids[3 0 R]/Count 1/MediaBox[0 0 595 842]/Rotate 90/Resources 4 0 R>>",
+		"<</Type/Page/Parent 2 0 R/Contents 5 0 R>>",
+		"<</Font<</F1 6 0 R>>/XObject<</Im1 7 0 R>>>>",
+		fmt.Sprintf("<</Length %d>>\nstream\n%sendstream", len(content), content),
+		"<</Type/Font/Subtype/Type1/BaseFont/Helvetica>>",
+		"<</Type/XObject/Subtype/Image/Width 2/Height 2/ColorSpace/DeviceGray/BitsPerComponent 8/Length 4>>\nstream\n\x00\xff\xff\x00\nendstream",
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, body := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, body)
+	}
+	xrefOffset := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<</Size %d/Root 1 0 R>>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xrefOffset)
+	return buf.Bytes()
+}
+
+func TestExtractInheritedPageAttributes(t *testing.T) {
+	pdfBytes := createInheritedAttributesPDF()
+
+	doc, err := ExtractContent(pdfBytes, nil, false)
+	if err != nil {
+		t.Fatalf("ExtractContent failed: %v", err)
+	}
+	if len(doc.Pages) != 1 {
+		t.Fatalf("Expected 1 page, got %d", len(doc.Pages))
+	}
+	page := doc.Pages[0]
+
+	if page.MediaBox == nil || page.Width != 595 || page.Height != 842 {
+		t.Errorf("Expected inherited 595x842 MediaBox, got %+v", page.MediaBox)
+	}
+	if page.Rotation != 90 {
+		t.Errorf("Expected inherited rotation 90, got %d", page.Rotation)
+	}
+	if page.Resources == nil {
+		t.Fatal("Expected inherited Resources")
+	}
+	if font, ok := page.Resources.Fonts["F1"]; !ok || !strings.Contains(font.Name, "Helvetica") {
+		t.Errorf("Expected inherited font F1 (Helvetica), got %+v", page.Resources.Fonts)
+	}
+	if _, ok := page.Resources.Images["Im1"]; !ok {
+		t.Errorf("Expected inherited image Im1, got %+v", page.Resources.Images)
+	}
+	if len(page.Text) != 1 || page.Text[0].Text != "Inherited" {
+		t.Errorf("Expected text 'Inherited', got %+v", page.Text)
+	}
+
+	images, err := ExtractAllImages(pdfBytes, nil, false)
+	if err != nil {
+		t.Fatalf("ExtractAllImages failed: %v", err)
+	}
+	if len(images) != 1 || len(images[0].Data) != 4 {
+		t.Errorf("Expected 1 image with 4 bytes of data, got %+v", images)
+	}
+}
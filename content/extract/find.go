@@ -0,0 +1,96 @@
+package extract
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// textSpan locates a text element in the text of its page
+type textSpan struct {
+	start, end int // Byte offsets in the page text
+	element    *types.TextElement
+}
+
+// FindText searches the text of every page for a regular expression and
+// returns the matches in page order with the area each covers. Text elements
+// on the same baseline are joined, with a space where they are set apart, so
+// that a match may span several elements; elements on different lines are
+// joined with a newline.
+func FindText(pdfBytes []byte, pdf *parse.PDF, pattern *regexp.Regexp, verbose bool) ([]types.TextMatch, error) {
+	pages, err := ExtractPagesWithOptions(pdfBytes, pdf, ExtractOptions{Verbose: verbose, TextOnly: true})
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []types.TextMatch{}
+	for i := range pages {
+		text, spans := pageText(&pages[i])
+		for _, loc := range pattern.FindAllStringIndex(text, -1) {
+			if loc[0] == loc[1] {
+				continue
+			}
+			match := types.TextMatch{PageNumber: pages[i].PageNumber, Text: text[loc[0]:loc[1]]}
+			for _, span := range spans {
+				if span.end <= loc[0] || span.start >= loc[1] {
+					continue
+				}
+				match.Rects = append(match.Rects, spanRect(span, max(loc[0], span.start), min(loc[1], span.end)))
+			}
+			if verbose {
+				fmt.Printf("Page %d: %q\n", match.PageNumber, match.Text)
+			}
+			matches = append(matches, match)
+		}
+	}
+	return matches, nil
+}
+
+// pageText joins the text elements of a page in content order and returns
+// where each element lies in the result
+func pageText(page *types.Page) (string, []textSpan) {
+	var b strings.Builder
+	var spans []textSpan
+	var prev *types.TextElement
+	for i := range page.Text {
+		e := &page.Text[i]
+		if e.Text == "" {
+			continue
+		}
+		if prev != nil {
+			switch {
+			case math.Abs(e.Y-prev.Y) > math.Max(prev.Height, 1)/2:
+				b.WriteByte('\n')
+			case e.X-(prev.X+prev.Width) > 0.2*math.Max(e.FontSize, 1):
+				b.WriteByte(' ')
+			}
+		}
+		start := b.Len()
+		b.WriteString(e.Text)
+		spans = append(spans, textSpan{start: start, end: b.Len(), element: e})
+		prev = e
+	}
+	return b.String(), spans
+}
+
+// spanRect returns the area of the part of a text element between two offsets
+// of the page text, spreading the element's width evenly over its characters
+// and reaching below the baseline for descenders
+func spanRect(span textSpan, start, end int) types.Rectangle {
+	e := span.element
+	total := utf8.RuneCountInString(e.Text)
+	before := utf8.RuneCountInString(e.Text[:start-span.start])
+	inside := utf8.RuneCountInString(e.Text[start-span.start : end-span.start])
+	charWidth := e.Width / float64(total)
+	return types.Rectangle{
+		LowerX: e.X + charWidth*float64(before),
+		LowerY: e.Y - 0.2*e.Height,
+		UpperX: e.X + charWidth*float64(before+inside),
+		UpperY: e.Y + e.Height,
+	}
+}
@@ -0,0 +1,76 @@
+package extract
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+)
+
+func TestFindText(t *testing.T) {
+	pdfBytes, _, err := CreateTestPDFWithText([]TestText{
+		{Text: "Patient: Jane Doe", X: 72, Y: 720, FontSize: 12},
+		{Text: "SSN: 123-45-6789", X: 72, Y: 700, FontSize: 12},
+		{Text: "Contact jane@example.com", X: 72, Y: 680, FontSize: 12},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+	pdf, err := ParseTestPDF(pdfBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse PDF: %v", err)
+	}
+
+	matches, err := FindText(pdfBytes, pdf, regexp.MustCompile(`\d{3}-\d{2}-\d{4}`), false)
+	if err != nil {
+		t.Fatalf("FindText failed: %v", err)
+	}
+	if len(matches) != 1 || matches[0].PageNumber != 1 || matches[0].Text != "123-45-6789" || len(matches[0].Rects) != 1 {
+		t.Fatalf("Unexpected matches %+v", matches)
+	}
+
+	// The area starts after "SSN: " and is as wide as the match
+	page, err := ExtractPage(pdfBytes, pdf, 1, false)
+	if err != nil {
+		t.Fatalf("Failed to extract page: %v", err)
+	}
+	var ssn = page.Text[1]
+	charWidth := ssn.Width / float64(len(ssn.Text))
+	r := matches[0].Rects[0]
+	if r.LowerX != ssn.X+5*charWidth || r.UpperX != ssn.X+16*charWidth || r.LowerY >= ssn.Y || r.UpperY <= ssn.Y {
+		t.Errorf("Match area %+v for element %+v", r, ssn)
+	}
+
+	// Matches can span lines, which are joined with newlines
+	matches, _ = FindText(pdfBytes, pdf, regexp.MustCompile(`Doe\nSSN`), false)
+	if len(matches) != 1 || len(matches[0].Rects) != 2 {
+		t.Errorf("Expected one match over two elements, got %+v", matches)
+	}
+	matches, _ = FindText(pdfBytes, pdf, regexp.MustCompile(`nothing`), false)
+	if len(matches) != 0 {
+		t.Errorf("Expected no matches, got %+v", matches)
+	}
+}
+
+func TestFindText_OpenReader(t *testing.T) {
+	pdfBytes, _, err := CreateTestPDFWithText([]TestText{
+		{Text: "Invoice 2024-117", X: 72, Y: 720, FontSize: 12},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+	pdf, err := parse.OpenReader(bytes.NewReader(pdfBytes), int64(len(pdfBytes)))
+	if err != nil {
+		t.Fatalf("OpenReader failed: %v", err)
+	}
+
+	// Without the document bytes, everything is read through the PDF
+	matches, err := FindText(nil, pdf, regexp.MustCompile(`\d{4}-\d{3}`), false)
+	if err != nil {
+		t.Fatalf("FindText failed: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Text != "2024-117" {
+		t.Errorf("Unexpected matches %+v", matches)
+	}
+}
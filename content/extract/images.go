@@ -142,6 +142,39 @@ func extractImageData(imageObjNum int, pdf *parse.PDF, verbose bool) (*types.Ima
 	return image, nil
 }
 
+// ExtractPageImages returns the image XObjects in a page's resources, including
+// inherited resources, keyed by resource name (e.g. "/Im1") with binary data.
+// pageNumber is 1-based (first page is 1)
+func ExtractPageImages(pdf *parse.PDF, pageNumber int, verbose bool) (map[string]types.Image, error) {
+	ref, err := pdf.Page(pageNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page %d: %w", pageNumber, err)
+	}
+
+	images := make(map[string]types.Image)
+	if ref.Resources == "" {
+		return images, nil
+	}
+
+	xobjects, objNums := extractXObjectsDictWithObjNums(ref.Resources, pdf, verbose)
+	for name, objNum := range objNums {
+		if xobject, ok := xobjects[name]; !ok || xobject.Subtype != "/Image" {
+			continue
+		}
+		image, err := extractImageData(objNum, pdf, verbose)
+		if err != nil {
+			if verbose {
+				fmt.Printf("Warning: failed to extract image data for %s (obj %d): %v\n", name, objNum, err)
+			}
+			continue
+		}
+		image.ID = "/" + name
+		images[image.ID] = *image
+	}
+
+	return images, nil
+}
+
 // ExtractAllImages extracts all images from a PDF document with binary data
 func ExtractAllImages(pdfBytes []byte, password []byte, verbose bool) ([]types.Image, error) {
 	// Parse PDF
@@ -163,77 +196,27 @@ func ExtractAllImages(pdfBytes []byte, password []byte, verbose bool) ([]types.I
 	imageMap := make(map[string]bool)      // Track unique images by ID
 	imageObjNumMap := make(map[string]int) // Map image name to object number
 
-	// Collect image object numbers by re-parsing Resources from pages
-	// We need to get the actual page object numbers to extract Resources
-	trailer := pdf.Trailer()
-	if trailer == nil || trailer.RootRef == "" {
-		return []types.Image{}, fmt.Errorf("no root reference found")
-	}
-
-	rootObjNum, err := parseObjectRef(trailer.RootRef)
-	if err != nil {
-		return []types.Image{}, fmt.Errorf("failed to parse root reference: %w", err)
-	}
-
-	catalogObj, err := pdf.GetObject(rootObjNum)
-	if err != nil {
-		return []types.Image{}, fmt.Errorf("failed to get catalog: %w", err)
-	}
-
-	catalogStr := string(catalogObj)
-	pagesRef := extractDictValue(catalogStr, "/Pages")
-	if pagesRef == "" {
-		return []types.Image{}, fmt.Errorf("no /Pages reference in catalog")
-	}
-
-	pagesObjNum, err := parseObjectRef(pagesRef)
-	if err != nil {
-		return []types.Image{}, fmt.Errorf("failed to parse Pages reference: %w", err)
-	}
-
-	// Recursively find all page objects
-	pageObjNums := extractPageObjectNumbers(pdf, pagesObjNum, verbose)
-
-	// For each page, extract Resources and get image object numbers
-	for i, pageObjNum := range pageObjNums {
-		if i >= len(doc.Pages) {
-			break
-		}
-
-		pageObj, err := pdf.GetObject(pageObjNum)
-		if err != nil {
+	// Collect image object numbers from each page's Resources, including inherited ones
+	it := pdf.Pages()
+	for it.Next() {
+		resourcesStr := it.Page().Resources
+		if resourcesStr == "" {
 			continue
 		}
 
-		pageStr := string(pageObj)
-		// Extract Resources
-		resourcesRef := extractDictValue(pageStr, "/Resources")
-		var resourcesStr string
-		if resourcesRef != "" {
-			resourcesObjNum, err := parseObjectRef(resourcesRef)
-			if err == nil {
-				resourcesObj, err := pdf.GetObject(resourcesObjNum)
-				if err == nil {
-					resourcesStr = string(resourcesObj)
-				}
-			}
-		} else {
-			// Inline Resources
-			resourcesStr = extractInlineDict(pageStr, "/Resources")
-		}
-
-		if resourcesStr != "" {
-			// Extract XObject object numbers
-			_, objNums := extractXObjectsDictWithObjNums(resourcesStr, pdf, verbose)
-			for name, objNum := range objNums {
-				imageID := "/" + name
-				if !imageMap[imageID] {
-					imageObjNumMap[name] = objNum
-					imageMap[imageID] = true
-				}
+		// Extract XObject object numbers
+		_, objNums := extractXObjectsDictWithObjNums(resourcesStr, pdf, verbose)
+		for name, objNum := range objNums {
+			imageID := "/" + name
+			if !imageMap[imageID] {
+				imageObjNumMap[name] = objNum
+				imageMap[imageID] = true
 			}
 		}
 	}
+	if err := it.Err(); err != nil {
+		return []types.Image{}, fmt.Errorf("failed to walk pages tree: %w", err)
+	}
 
 	// Extract full image data with binary for each unique image
 	for name, objNum := range imageObjNumMap {
@@ -271,43 +254,3 @@ func ExtractAllImages(pdfBytes []byte, password []byte, verbose bool) ([]types.I
 
 	return allImages, nil
 }
-
-// extractPageObjectNumbers recursively extracts page object numbers from the pages tree
-func extractPageObjectNumbers(pdf *parse.PDF, pagesObjNum int, verbose bool) []int {
-	var pageObjNums []int
-
-	pagesObj, err := pdf.GetObject(pagesObjNum)
-	if err != nil {
-		return pageObjNums
-	}
-
-	pagesStr := string(pagesObj)
-
-	// Check if this is a /Page (leaf) or /Pages (intermediate)
-	pageType := extractDictValue(pagesStr, "/Type")
-	if pageType == "/Page" {
-		// This is a page - add it
-		pageObjNums = append(pageObjNums, pagesObjNum)
-		return pageObjNums
-	}
-
-	// This is a /Pages node - get Kids and recurse
-	kidsStr := extractDictValue(pagesStr, "/Kids")
-	if kidsStr == "" {
-		return pageObjNums
-	}
-
-	// Parse kids array
-	kidsRefs := parseObjectRefArray(kidsStr)
-	for _, kidRef := range kidsRefs {
-		kidObjNum, err := parseObjectRef(kidRef)
-		if err != nil {
-			continue
-		}
-		// Recurse
-		childPages := extractPageObjectNumbers(pdf, kidObjNum, verbose)
-		pageObjNums = append(pageObjNums, childPages...)
-	}
-
-	return pageObjNums
-}
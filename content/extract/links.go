@@ -0,0 +1,444 @@
+package extract
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/benedoc-inc/pdfer/core/encrypt"
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// maxNameTreeDepth bounds the walk of the /Dests name tree
+const maxNameTreeDepth = 32
+
+var (
+	linkSubtypePattern = regexp.MustCompile(`/Subtype\s*/Link\b`)
+	indirectRefPattern = regexp.MustCompile(`^(\d+)\s+(\d+)\s+R`)
+	objectHeaderPrefix = regexp.MustCompile(`^\s*\d+\s+\d+\s+obj\s*`)
+)
+
+// ExtractLinks returns the Link annotations of every page with their targets:
+// external URIs, internal destinations resolved to page numbers where possible,
+// and other actions such as launching or opening remote files
+func ExtractLinks(pdfBytes []byte, pdf *parse.PDF, verbose bool) ([]types.Link, error) {
+	var pages []*parse.PageRef
+	pageNumbers := make(map[int]int) // page object number -> page number
+
+	it := pdf.Pages()
+	for it.Next() {
+		ref := it.Page()
+		pages = append(pages, ref)
+		pageNumbers[ref.ObjectNumber] = ref.Number
+	}
+	if err := it.Err(); err != nil {
+		return nil, fmt.Errorf("failed to walk pages tree: %w", err)
+	}
+
+	resolver := &linkResolver{pdf: pdf, pageNumbers: pageNumbers, verbose: verbose}
+	links := []types.Link{}
+	for _, ref := range pages {
+		annots := resolver.deref(dictEntry(objectContent(ref.Dict), "/Annots"))
+		for _, annotRef := range parseObjectRefArray(annots) {
+			annotObjNum, err := parseObjectRef(annotRef)
+			if err != nil {
+				continue
+			}
+			annotObj, err := pdf.GetObject(annotObjNum)
+			if err != nil {
+				if verbose {
+					fmt.Printf("Warning: failed to get annotation object %d: %v\n", annotObjNum, err)
+				}
+				continue
+			}
+			annotStr := objectContent(string(annotObj))
+			if !linkSubtypePattern.MatchString(annotStr) {
+				continue
+			}
+
+			link := types.Link{PageNumber: ref.Number}
+			if rect := extractArrayValue(annotStr, "/Rect"); len(rect) >= 4 {
+				link.Rect = &types.Rectangle{LowerX: rect[0], LowerY: rect[1], UpperX: rect[2], UpperY: rect[3]}
+			}
+			resolver.resolve(&link, annotStr)
+			links = append(links, link)
+		}
+	}
+
+	return links, nil
+}
+
+// linkResolver resolves link actions and destinations against a document
+type linkResolver struct {
+	pdf         *parse.PDF
+	pageNumbers map[int]int
+	verbose     bool
+
+	namedDests map[string]string // Named destinations, loaded on first use
+}
+
+// resolve fills a link's action and target from its annotation dictionary
+func (r *linkResolver) resolve(link *types.Link, annotStr string) {
+	if dest := dictEntry(annotStr, "/Dest"); dest != "" {
+		link.Action = "GoTo"
+		r.setDestination(link, dest)
+		return
+	}
+
+	action := r.deref(dictEntry(annotStr, "/A"))
+	if action == "" {
+		return
+	}
+	link.Action = strings.TrimPrefix(dictEntry(action, "/S"), "/")
+
+	switch link.Action {
+	case "URI":
+		link.URI = pdfString(r.deref(dictEntry(action, "/URI")))
+	case "GoTo":
+		r.setDestination(link, dictEntry(action, "/D"))
+	case "GoToR", "GoToE", "Launch":
+		link.File = r.fileSpec(dictEntry(action, "/F"))
+		if dest := dictEntry(action, "/D"); dest != "" {
+			link.Destination = destinationName(dest)
+		}
+	case "Named":
+		link.Destination = strings.TrimPrefix(dictEntry(action, "/N"), "/")
+	}
+}
+
+// setDestination records an internal destination and the page it points to
+func (r *linkResolver) setDestination(link *types.Link, dest string) {
+	dest = r.deref(dest)
+	if strings.HasPrefix(dest, "<<") {
+		// Destination dictionary: << /D [...] >>
+		dest = r.deref(dictEntry(dest, "/D"))
+	}
+	link.Destination = destinationName(dest)
+
+	if !strings.HasPrefix(dest, "[") {
+		// Named destination
+		named, ok := r.namedDestinations()[link.Destination]
+		if !ok {
+			return
+		}
+		dest = named
+	}
+
+	// Explicit destination: [page /XYZ left top zoom] or similar
+	if match := indirectRefPattern.FindStringSubmatch(strings.TrimSpace(strings.TrimPrefix(dest, "["))); match != nil {
+		pageObjNum, _ := strconv.Atoi(match[1])
+		link.TargetPage = r.pageNumbers[pageObjNum]
+	}
+}
+
+// namedDestinations loads the catalog's /Dests dictionary and /Names /Dests name tree,
+// mapping each name to its explicit destination array
+func (r *linkResolver) namedDestinations() map[string]string {
+	if r.namedDests != nil {
+		return r.namedDests
+	}
+	r.namedDests = make(map[string]string)
+
+	trailer := r.pdf.Trailer()
+	if trailer == nil {
+		return r.namedDests
+	}
+	catalog := r.deref(trailer.RootRef)
+	if catalog == "" {
+		return r.namedDests
+	}
+
+	// PDF 1.1 /Dests dictionary: /Name [...] or /Name << /D [...] >>
+	if dests := r.deref(dictEntry(catalog, "/Dests")); strings.HasPrefix(dests, "<<") {
+		for _, key := range dictKeys(dests) {
+			r.namedDests[key] = r.explicitDestination(dictEntry(dests, "/"+key))
+		}
+	}
+
+	// PDF 1.2 name tree: /Names << /Dests root >>
+	if names := r.deref(dictEntry(catalog, "/Names")); names != "" {
+		r.walkNameTree(r.deref(dictEntry(names, "/Dests")), 0)
+	}
+
+	return r.namedDests
+}
+
+// walkNameTree adds the leaves of a destination name tree node
+func (r *linkResolver) walkNameTree(node string, depth int) {
+	if node == "" || depth > maxNameTreeDepth {
+		return
+	}
+
+	if names := dictEntry(node, "/Names"); strings.HasPrefix(names, "[") {
+		items := arrayItems(names)
+		for i := 0; i+1 < len(items); i += 2 {
+			r.namedDests[pdfString(items[i])] = r.explicitDestination(items[i+1])
+		}
+	}
+	for _, kid := range parseObjectRefArray(dictEntry(node, "/Kids")) {
+		r.walkNameTree(r.deref(kid), depth+1)
+	}
+}
+
+// explicitDestination resolves a destination value to its array form
+func (r *linkResolver) explicitDestination(value string) string {
+	value = r.deref(value)
+	if strings.HasPrefix(value, "<<") {
+		value = r.deref(dictEntry(value, "/D"))
+	}
+	return value
+}
+
+// fileSpec returns the file name of a file specification string or dictionary
+func (r *linkResolver) fileSpec(value string) string {
+	value = r.deref(value)
+	if strings.HasPrefix(value, "<<") {
+		for _, key := range []string{"/UF", "/F", "/Unix", "/DOS", "/Mac"} {
+			if name := dictEntry(value, key); name != "" {
+				return pdfString(name)
+			}
+		}
+		return ""
+	}
+	return pdfString(value)
+}
+
+// deref returns the content of the object an indirect reference points to;
+// other values are returned unchanged. A free object dereferences to nothing.
+func (r *linkResolver) deref(value string) string {
+	match := indirectRefPattern.FindStringSubmatch(value)
+	if match == nil || len(match[0]) != len(value) {
+		return value
+	}
+	objNum, _ := strconv.Atoi(match[1])
+	obj, err := r.pdf.GetObject(objNum)
+	if err != nil {
+		if r.verbose {
+			fmt.Printf("Warning: failed to get object %d: %v\n", objNum, err)
+		}
+		return ""
+	}
+	if parse.IsNullObject(obj) {
+		return ""
+	}
+	return objectContent(string(obj))
+}
+
+// destinationName returns a readable form of a destination: the decoded name of a
+// named destination, or the raw explicit destination array
+func destinationName(dest string) string {
+	switch {
+	case strings.HasPrefix(dest, "/"):
+		return dest[1:]
+	case strings.HasPrefix(dest, "("), strings.HasPrefix(dest, "<") && !strings.HasPrefix(dest, "<<"):
+		return pdfString(dest)
+	}
+	return dest
+}
+
+// objectContent strips the "N G obj" header and "endobj" keyword from an object
+func objectContent(obj string) string {
+	if loc := objectHeaderPrefix.FindStringIndex(obj); loc != nil {
+		obj = obj[loc[1]:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(obj), "endobj"))
+}
+
+// pdfString decodes a literal or hex string token; names and other tokens are
+// returned without their leading slash
+func pdfString(token string) string {
+	token = strings.TrimSpace(token)
+	if strings.HasPrefix(token, "(") || (strings.HasPrefix(token, "<") && !strings.HasPrefix(token, "<<")) {
+		if tokens, err := encrypt.FindStrings([]byte(token)); err == nil && len(tokens) > 0 {
+			return decodeTextString(tokens[0].Value)
+		}
+	}
+	return strings.TrimPrefix(token, "/")
+}
+
+// decodeTextString decodes a PDF text string: UTF-16BE or, in PDF 2.0, UTF-8
+// with a byte order mark, otherwise single-byte text
+func decodeTextString(b []byte) string {
+	if len(b) >= 2 && b[0] == 0xfe && b[1] == 0xff {
+		units := make([]uint16, 0, (len(b)-2)/2)
+		for i := 2; i+1 < len(b); i += 2 {
+			units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	if len(b) >= 3 && b[0] == 0xef && b[1] == 0xbb && b[2] == 0xbf {
+		return string(b[3:])
+	}
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// dictEntry returns the raw value of a dictionary key: a string, array or nested
+// dictionary with its delimiters, an indirect reference, or a single token.
+// Keys inside nested dictionaries and strings are not matched.
+func dictEntry(dict, key string) string {
+	dict = strings.TrimSpace(dict)
+	if !strings.HasPrefix(dict, "<<") {
+		return ""
+	}
+
+	for i := 2; i < len(dict); {
+		c := dict[i]
+		if c == '/' {
+			end := tokenEnd(dict, i+1)
+			name := dict[i:end]
+			valueStart := skipSpace(dict, end)
+			valueEnd := valueEnd(dict, valueStart)
+			if name == key {
+				return dict[valueStart:valueEnd]
+			}
+			i = valueEnd
+			continue
+		}
+		if c == '>' {
+			return ""
+		}
+		i++
+	}
+	return ""
+}
+
+// dictKeys returns the keys of a dictionary (without slashes) in order
+func dictKeys(dict string) []string {
+	dict = strings.TrimSpace(dict)
+	if !strings.HasPrefix(dict, "<<") {
+		return nil
+	}
+
+	var keys []string
+	for i := 2; i < len(dict); {
+		if dict[i] == '/' {
+			end := tokenEnd(dict, i+1)
+			keys = append(keys, dict[i+1:end])
+			i = valueEnd(dict, skipSpace(dict, end))
+			continue
+		}
+		if dict[i] == '>' {
+			break
+		}
+		i++
+	}
+	return keys
+}
+
+// arrayItems splits an array value into its raw items, keeping "N G R" references together
+func arrayItems(array string) []string {
+	array = strings.TrimSpace(array)
+	if !strings.HasPrefix(array, "[") {
+		return nil
+	}
+
+	var items []string
+	for i := skipSpace(array, 1); i < len(array) && array[i] != ']'; i = skipSpace(array, i) {
+		if match := indirectRefPattern.FindString(array[i:]); match != "" {
+			items = append(items, match)
+			i += len(match)
+			continue
+		}
+		end := valueEnd(array, i)
+		if end == i {
+			end++
+		}
+		items = append(items, array[i:end])
+		i = end
+	}
+	return items
+}
+
+// valueEnd returns the offset just past the value starting at i
+func valueEnd(s string, i int) int {
+	if i >= len(s) {
+		return i
+	}
+	switch {
+	case strings.HasPrefix(s[i:], "<<"):
+		depth := 0
+		for j := i; j < len(s); j++ {
+			switch {
+			case s[j] == '(':
+				j = valueEnd(s, j) - 1
+			case strings.HasPrefix(s[j:], "<<"):
+				depth++
+				j++
+			case strings.HasPrefix(s[j:], ">>"):
+				depth--
+				j++
+				if depth == 0 {
+					return j + 1
+				}
+			}
+		}
+		return len(s)
+	case s[i] == '[':
+		depth := 0
+		for j := i; j < len(s); j++ {
+			switch s[j] {
+			case '(':
+				j = valueEnd(s, j) - 1
+			case '[':
+				depth++
+			case ']':
+				depth--
+				if depth == 0 {
+					return j + 1
+				}
+			}
+		}
+		return len(s)
+	case s[i] == '(':
+		depth := 0
+		for j := i; j < len(s); j++ {
+			switch s[j] {
+			case '\\':
+				j++
+			case '(':
+				depth++
+			case ')':
+				depth--
+				if depth == 0 {
+					return j + 1
+				}
+			}
+		}
+		return len(s)
+	case s[i] == '<':
+		if end := strings.IndexByte(s[i:], '>'); end != -1 {
+			return i + end + 1
+		}
+		return len(s)
+	case s[i] == '/':
+		return tokenEnd(s, i+1)
+	}
+
+	if match := indirectRefPattern.FindString(s[i:]); match != "" {
+		return i + len(match)
+	}
+	return tokenEnd(s, i)
+}
+
+// tokenEnd returns the offset of the first delimiter or whitespace at or after i
+func tokenEnd(s string, i int) int {
+	for i < len(s) && !strings.ContainsRune(" \t\r\n\f/<>[]()%{}", rune(s[i])) {
+		i++
+	}
+	return i
+}
+
+// skipSpace returns the offset of the first non-whitespace byte at or after i
+func skipSpace(s string, i int) int {
+	for i < len(s) && strings.ContainsRune(" \t\r\n\f", rune(s[i])) {
+		i++
+	}
+	return i
+}
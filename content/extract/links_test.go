@@ -0,0 +1,117 @@
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+func TestExtractLinks(t *testing.T) {
+	writer := write.NewPDFWriter()
+	catalogNum := writer.AddObject(nil)
+	pagesNum := writer.AddObject(nil)
+	page1Num := writer.AddObject(nil)
+	page2Num := writer.AddObject(nil)
+
+	uriAction := writer.AddObject([]byte("<</S/URI/URI(https://example.com/a path?q=\\(1\\))>>"))
+	annots := []int{
+		// URI action in an indirect dictionary
+		writer.AddObject([]byte(fmt.Sprintf("<</Type/Annot/Subtype/Link/Rect [10 20 110 40]/A %d 0 R>>", uriAction))),
+		// Explicit destination to page 2
+		writer.AddObject([]byte(fmt.Sprintf("<</Type/Annot/Subtype/Link/Rect [10 50 110 70]/Dest [%d 0 R /XYZ 0 792 0]>>", page2Num))),
+		// Inline GoTo action with a named destination
+		writer.AddObject([]byte("<</Type/Annot/Subtype/Link/Rect [10 80 110 100]/A <</S/GoTo/D(chapter1)>>>>")),
+		// Remote file
+		writer.AddObject([]byte("<</Type/Annot/Subtype/Link/Rect [10 110 110 130]/A <</S/GoToR/F <</Type/Filespec/F(other.pdf)>>/D/intro>>>>")),
+		// Not a link
+		writer.AddObject([]byte("<</Type/Annot/Subtype/Text/Rect [0 0 10 10]/Contents(note)>>")),
+	}
+
+	namesNum := writer.AddObject([]byte(fmt.Sprintf("<</Names [(chapter1) [%d 0 R /Fit]]>>", page2Num)))
+	writer.SetObject(catalogNum, []byte(fmt.Sprintf("<</Type/Catalog/Pages %d 0 R/Names <</Dests %d 0 R>>>>", pagesNum, namesNum)))
+	writer.SetObject(pagesNum, []byte(fmt.Sprintf("<</Type/Pages/Kids [%d 0 R %d 0 R]/Count 2>>", page1Num, page2Num)))
+	writer.SetObject(page1Num, []byte(fmt.Sprintf("<</Type/Page/Parent %d 0 R/MediaBox [0 0 612 792]/Annots [%d 0 R %d 0 R %d 0 R %d 0 R %d 0 R]>>",
+		pagesNum, annots[0], annots[1], annots[2], annots[3], annots[4])))
+	writer.SetObject(page2Num, []byte(fmt.Sprintf("<</Type/Page/Parent %d 0 R/MediaBox [0 0 612 792]>>", pagesNum)))
+	writer.SetRoot(catalogNum)
+
+	pdfBytes, err := writer.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+	pdf, err := ParseTestPDF(pdfBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse PDF: %v", err)
+	}
+
+	links, err := ExtractLinks(pdfBytes, pdf, false)
+	if err != nil {
+		t.Fatalf("ExtractLinks failed: %v", err)
+	}
+	if len(links) != 4 {
+		t.Fatalf("Expected 4 links, got %d: %+v", len(links), links)
+	}
+
+	want := []types.Link{
+		{PageNumber: 1, Action: "URI", URI: "https://example.com/a path?q=(1)"},
+		{PageNumber: 1, Action: "GoTo", TargetPage: 2},
+		{PageNumber: 1, Action: "GoTo", Destination: "chapter1", TargetPage: 2},
+		{PageNumber: 1, Action: "GoToR", File: "other.pdf", Destination: "intro"},
+	}
+	for i, w := range want {
+		got := links[i]
+		got.Rect = nil
+		if i == 1 {
+			got.Destination = ""
+		}
+		if got != w {
+			t.Errorf("Link %d = %+v, want %+v", i, got, w)
+		}
+	}
+
+	if r := links[0].Rect; r == nil || r.LowerX != 10 || r.UpperY != 40 {
+		t.Errorf("Unexpected rect %+v", r)
+	}
+}
+
+func TestExtractLinks_FreedAnnotation(t *testing.T) {
+	writer := write.NewPDFWriter()
+	catalogNum := writer.AddObject(nil)
+	pagesNum := writer.AddObject(nil)
+	pageNum := writer.AddObject(nil)
+	kept := writer.AddObject([]byte("<</Type/Annot/Subtype/Link/Rect [10 20 110 40]/A <</S/URI/URI(https://example.com/kept)>>>>"))
+	deleted := writer.AddObject([]byte("<</Type/Annot/Subtype/Link/Rect [10 50 110 70]/A <</S/URI/URI(https://example.com/deleted)>>>>"))
+	writer.SetObject(catalogNum, []byte(fmt.Sprintf("<</Type/Catalog/Pages %d 0 R>>", pagesNum)))
+	writer.SetObject(pagesNum, []byte(fmt.Sprintf("<</Type/Pages/Kids [%d 0 R]/Count 1>>", pageNum)))
+	writer.SetObject(pageNum, []byte(fmt.Sprintf("<</Type/Page/Parent %d 0 R/MediaBox [0 0 612 792]/Annots [%d 0 R %d 0 R]>>", pagesNum, kept, deleted)))
+	writer.SetRoot(catalogNum)
+	base, err := writer.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+
+	// An incremental update frees the second annotation but, as some editors
+	// do, leaves the page's reference to it in place
+	startxref := bytes.LastIndex(base, []byte("startxref"))
+	prev, _ := strconv.Atoi(strings.Fields(string(base[startxref+len("startxref"):]))[0])
+	update := fmt.Sprintf("xref\n%d 1\n0000000000 00001 f \ntrailer\n<</Size %d/Root %d 0 R/Prev %d>>\nstartxref\n%d\n%%%%EOF\n",
+		deleted, deleted+1, catalogNum, prev, len(base))
+	pdfBytes := append(append([]byte{}, base...), update...)
+
+	pdf, err := ParseTestPDF(pdfBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse PDF: %v", err)
+	}
+	links, err := ExtractLinks(pdfBytes, pdf, false)
+	if err != nil {
+		t.Fatalf("ExtractLinks failed: %v", err)
+	}
+	if len(links) != 1 || links[0].URI != "https://example.com/kept" {
+		t.Errorf("Expected only the kept link, got %+v", links)
+	}
+}
@@ -2,10 +2,8 @@ package extract
 
 import (
 	"fmt"
-	"regexp"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/benedoc-inc/pdfer/core/parse"
 	"github.com/benedoc-inc/pdfer/types"
@@ -28,7 +26,7 @@ func ExtractMetadata(pdfBytes []byte, pdf *parse.PDF, verbose bool) (*types.Docu
 		if err == nil {
 			infoObj, err := pdf.GetObject(infoObjNum)
 			if err == nil {
-				parseInfoDict(string(infoObj), metadata, verbose)
+				parseInfoDict(string(infoObj), metadata, &linkResolver{pdf: pdf, verbose: verbose})
 			}
 		}
 	}
@@ -38,6 +36,8 @@ func ExtractMetadata(pdfBytes []byte, pdf *parse.PDF, verbose bool) (*types.Docu
 		extractMetadataFromBytes(pdfBytes, metadata, verbose)
 	}
 
+	extractCatalogRequirements(pdf, metadata, verbose)
+
 	return metadata, nil
 }
 
@@ -54,69 +54,43 @@ func parseObjectRef(ref string) (int, error) {
 	return objNum, nil
 }
 
-// parseInfoDict parses a PDF Info dictionary
-func parseInfoDict(infoStr string, metadata *types.DocumentMetadata, verbose bool) {
-	// Extract common fields
-	patterns := map[string]*regexp.Regexp{
-		"title":         regexp.MustCompile(`/Title\s*\(([^)]*)\)`),
-		"author":        regexp.MustCompile(`/Author\s*\(([^)]*)\)`),
-		"subject":       regexp.MustCompile(`/Subject\s*\(([^)]*)\)`),
-		"keywords":      regexp.MustCompile(`/Keywords\s*\(([^)]*)\)`),
-		"creator":       regexp.MustCompile(`/Creator\s*\(([^)]*)\)`),
-		"producer":      regexp.MustCompile(`/Producer\s*\(([^)]*)\)`),
-		"creation_date": regexp.MustCompile(`/CreationDate\s*\(([^)]*)\)`),
-		"mod_date":      regexp.MustCompile(`/ModDate\s*\(([^)]*)\)`),
-	}
-
-	fieldMap := map[string]*string{
-		"title":         &metadata.Title,
-		"author":        &metadata.Author,
-		"subject":       &metadata.Subject,
-		"keywords":      &metadata.Keywords,
-		"creator":       &metadata.Creator,
-		"producer":      &metadata.Producer,
-		"creation_date": &metadata.CreationDate,
-		"mod_date":      &metadata.ModDate,
-	}
+// ParseInfoDict parses the content of an Info dictionary object, resolving
+// indirect values through pdf
+func ParseInfoDict(info string, pdf *parse.PDF, verbose bool) *types.DocumentMetadata {
+	metadata := &types.DocumentMetadata{Custom: make(map[string]string)}
+	parseInfoDict(info, metadata, &linkResolver{pdf: pdf, verbose: verbose})
+	return metadata
+}
 
-	for key, pattern := range patterns {
-		match := pattern.FindStringSubmatch(infoStr)
-		if len(match) > 1 {
-			value := unescapePDFString(match[1])
-			if fieldPtr, ok := fieldMap[key]; ok {
-				*fieldPtr = value
-			}
+// parseInfoDict parses a PDF Info dictionary. Standard entries fill the typed
+// fields and any other key is kept in Custom, with names decoded from their #xx
+// escapes and values decoded as text strings.
+func parseInfoDict(infoStr string, metadata *types.DocumentMetadata, resolver *linkResolver) {
+	info := objectContent(infoStr)
+	for _, rawKey := range dictKeys(info) {
+		value := resolver.deref(dictEntry(info, "/"+rawKey))
+		var text string
+		if strings.HasPrefix(value, "/") {
+			text = decodeName(value)
+		} else {
+			text = pdfString(value)
 		}
-	}
-
-	// Extract custom fields (any field that's not a standard field)
-	// Pattern: /FieldName (value)
-	customPattern := regexp.MustCompile(`/([A-Za-z0-9_]+)\s*\(([^)]*)\)`)
-	standardFields := map[string]bool{
-		"Title":        true,
-		"Author":       true,
-		"Subject":      true,
-		"Keywords":     true,
-		"Creator":      true,
-		"Producer":     true,
-		"CreationDate": true,
-		"ModDate":      true,
-	}
-
-	allMatches := customPattern.FindAllStringSubmatch(infoStr, -1)
-	for _, match := range allMatches {
-		if len(match) >= 3 {
-			fieldName := match[1]
-			fieldValue := unescapePDFString(match[2])
-
-			// Skip if it's a standard field (already extracted)
-			if !standardFields[fieldName] {
-				if metadata.Custom == nil {
-					metadata.Custom = make(map[string]string)
-				}
-				metadata.Custom[fieldName] = fieldValue
+		if text == "" {
+			continue
+		}
+		key := decodeName(rawKey)
+		if key == "Trapped" {
+			// A name, though older writers used a string or a boolean
+			switch strings.ToLower(text) {
+			case "true":
+				text = types.TrappedTrue
+			case "false":
+				text = types.TrappedFalse
+			default:
+				text = types.TrappedUnknown
 			}
 		}
+		metadata.SetInfoEntry(key, text)
 	}
 }
 
@@ -137,28 +111,3 @@ func unescapePDFString(s string) string {
 	s = strings.ReplaceAll(s, "\\\\", "\\")
 	return s
 }
-
-// parsePDFDate parses a PDF date string (D:YYYYMMDDHHmmSSOHH'mm)
-// parsePDFDate parses a PDF date string (D:YYYYMMDDHHmmSSOHH'mm).
-// This function is available for future use when date parsing is needed.
-var _ = parsePDFDate // Mark as available for future use
-
-func parsePDFDate(dateStr string) time.Time {
-	// Remove "D:" prefix if present
-	dateStr = strings.TrimPrefix(dateStr, "D:")
-
-	// Try to parse common formats
-	formats := []string{
-		"20060102150405",
-		"20060102150405-07'00",
-		"20060102150405Z07'00",
-	}
-
-	for _, format := range formats {
-		if t, err := time.Parse(format, dateStr); err == nil {
-			return t
-		}
-	}
-
-	return time.Time{}
-}
@@ -0,0 +1,91 @@
+package extract
+
+import (
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+func TestExtractMetadata_TextStrings(t *testing.T) {
+	for _, pdf20 := range []bool{false, true} {
+		b := write.NewSimplePDFBuilder()
+		b.Writer().UsePDF20(pdf20)
+		b.SetMetadata(&types.DocumentMetadata{Title: "Grüße \U0001F600", Author: "Plain"})
+		b.FinalizePage(b.AddPage(write.PageSizeLetter))
+		pdfBytes, err := b.Bytes()
+		if err != nil {
+			t.Fatalf("Failed to generate PDF: %v", err)
+		}
+
+		pdf, err := parse.Open(pdfBytes)
+		if err != nil {
+			t.Fatalf("Failed to parse PDF: %v", err)
+		}
+		metadata, err := ExtractMetadata(pdfBytes, pdf, false)
+		if err != nil {
+			t.Fatalf("ExtractMetadata failed: %v", err)
+		}
+		if metadata.Title != "Grüße \U0001F600" || metadata.Author != "Plain" {
+			t.Errorf("PDF 2.0=%v: got title %q, author %q", pdf20, metadata.Title, metadata.Author)
+		}
+	}
+}
+
+func TestExtractMetadata_InfoRoundTrip(t *testing.T) {
+	want := &types.DocumentMetadata{
+		Title:        "Report (draft) \\ v2",
+		Author:       "/Team",
+		CreationDate: "D:20240115103000Z",
+		ModDate:      "D:20240116144500Z",
+		Trapped:      types.TrappedFalse,
+		Custom: map[string]string{
+			"Review Status": "Approved",
+			"Département":   "Ventes Île-de-France",
+			"Ticket":        "12 0 R",
+		},
+	}
+	b := write.NewSimplePDFBuilder()
+	b.SetMetadata(want)
+	b.FinalizePage(b.AddPage(write.PageSizeLetter))
+	pdfBytes, err := b.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to generate PDF: %v", err)
+	}
+
+	pdf, err := parse.Open(pdfBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse PDF: %v", err)
+	}
+	got, err := ExtractMetadata(pdfBytes, pdf, false)
+	if err != nil {
+		t.Fatalf("ExtractMetadata failed: %v", err)
+	}
+	for _, key := range []string{"Title", "Author", "CreationDate", "ModDate", "Trapped", "Review Status", "Département", "Ticket"} {
+		if got.InfoEntry(key) != want.InfoEntry(key) {
+			t.Errorf("%s = %q, want %q", key, got.InfoEntry(key), want.InfoEntry(key))
+		}
+	}
+	if len(got.Custom) != len(want.Custom) {
+		t.Errorf("Got custom entries %v, want %v", got.Custom, want.Custom)
+	}
+}
+
+func TestParseInfoDict_Trapped(t *testing.T) {
+	for value, want := range map[string]string{
+		"/True":      types.TrappedTrue,
+		"(False)":    types.TrappedFalse,
+		"true":       types.TrappedTrue,
+		"/Unknown":   types.TrappedUnknown,
+		"/Something": types.TrappedUnknown,
+	} {
+		metadata := ParseInfoDict("<< /Trapped "+value+" /My#20Key /A#2FB >>", nil, false)
+		if metadata.Trapped != want {
+			t.Errorf("/Trapped %s = %q, want %q", value, metadata.Trapped, want)
+		}
+		if metadata.Custom["My Key"] != "A/B" {
+			t.Errorf("Custom entries = %v", metadata.Custom)
+		}
+	}
+}
@@ -12,49 +12,35 @@ import (
 
 // ExtractPages extracts all pages from a PDF
 func ExtractPages(pdfBytes []byte, pdf *parse.PDF, verbose bool) ([]types.Page, error) {
-	var pages []types.Page
-
-	// Get catalog to find pages tree
-	trailer := pdf.Trailer()
-	if trailer == nil || trailer.RootRef == "" {
-		return nil, fmt.Errorf("no root reference found in trailer")
-	}
-
-	rootObjNum, err := parseObjectRef(trailer.RootRef)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse root reference: %w", err)
-	}
-
-	catalogObj, err := pdf.GetObject(rootObjNum)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get catalog object: %w", err)
-	}
+	return ExtractPagesWithOptions(pdfBytes, pdf, ExtractOptions{Verbose: verbose})
+}
 
-	// Find Pages reference in catalog
-	catalogStr := string(catalogObj)
-	if verbose {
-		fmt.Printf("Catalog object: %s\n", catalogStr)
-	}
-	pagesRef := extractDictValue(catalogStr, "/Pages")
-	if pagesRef == "" {
-		return nil, fmt.Errorf("no /Pages reference found in catalog: %s", catalogStr)
+// ExtractPagesWithOptions extracts all pages from a PDF using the given options.
+// opts.Password is unused, as pdf is already open.
+func ExtractPagesWithOptions(pdfBytes []byte, pdf *parse.PDF, opts ExtractOptions) ([]types.Page, error) {
+	verbose := opts.Verbose
+	if err := opts.validate(); err != nil {
+		return nil, err
 	}
+	var pages []types.Page
 
-	if verbose {
-		fmt.Printf("Found Pages reference: %s\n", pagesRef)
-	}
-	pagesObjNum, err := parseObjectRef(pagesRef)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse Pages reference %s: %w", pagesRef, err)
-	}
+	it := pdf.Pages()
+	for it.Next() {
+		ref := it.Page()
+		if verbose {
+			fmt.Printf("Page %d: object %d\n", ref.Number, ref.ObjectNumber)
+		}
 
-	if verbose {
-		fmt.Printf("Pages object number: %d\n", pagesObjNum)
+		page, err := extractPage(pdfBytes, pdf, ref, opts)
+		if err != nil {
+			if verbose {
+				fmt.Printf("Warning: failed to extract page %d: %v\n", ref.ObjectNumber, err)
+			}
+			continue
+		}
+		pages = append(pages, page)
 	}
-
-	// Extract pages from pages tree
-	pages, err = extractPagesFromTree(pdfBytes, pdf, pagesObjNum, verbose)
-	if err != nil {
+	if err := it.Err(); err != nil {
 		return nil, fmt.Errorf("failed to extract pages from tree: %w", err)
 	}
 
@@ -66,93 +52,37 @@ func ExtractPages(pdfBytes []byte, pdf *parse.PDF, verbose bool) ([]types.Page,
 	return pages, nil
 }
 
-// extractPagesFromTree recursively extracts pages from a pages tree
-func extractPagesFromTree(pdfBytes []byte, pdf *parse.PDF, pagesObjNum int, verbose bool) ([]types.Page, error) {
-	var result []types.Page
+// PageCount returns the number of pages from the /Count of the root Pages node
+func PageCount(pdf *parse.PDF) (int, error) {
+	return pdf.PageCount()
+}
 
-	pagesObj, err := pdf.GetObject(pagesObjNum)
+// ExtractPage extracts a single page (1-based) without visiting other pages' content.
+// Subtrees are skipped using their /Count, so only the objects on the path to the page are read.
+func ExtractPage(pdfBytes []byte, pdf *parse.PDF, pageNumber int, verbose bool) (*types.Page, error) {
+	ref, err := pdf.Page(pageNumber)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get pages object %d: %w", pagesObjNum, err)
-	}
-
-	pagesStr := string(pagesObj)
-	if verbose {
-		fmt.Printf("Pages object %d: %s\n", pagesObjNum, pagesStr)
+		return nil, err
 	}
 
-	// Check if this is a page or a pages node
-	// Look for /Type/Page or /Type/Pages in the dictionary
-	// Need to check for exact match, not substring (e.g., /Type/Page should not match /Type/Pages)
-	pageType := ""
-	if strings.Contains(pagesStr, "/Type/Pages") || strings.Contains(pagesStr, "/Type /Pages") {
-		pageType = "/Pages"
-	} else if strings.Contains(pagesStr, "/Type/Page") || strings.Contains(pagesStr, "/Type /Page") {
-		// Make sure it's not /Type/Pages
-		if !strings.Contains(pagesStr, "/Type/Pages") && !strings.Contains(pagesStr, "/Type /Pages") {
-			pageType = "/Page"
-		}
-	}
-	if verbose {
-		fmt.Printf("Type: %s (from string check)\n", pageType)
-	}
-	if pageType == "/Page" {
-		// This is a page object
-		page, err := extractPage(pdfBytes, pdf, pagesObjNum, pagesStr, verbose)
-		if err != nil {
-			if verbose {
-				fmt.Printf("Warning: failed to extract page %d: %v\n", pagesObjNum, err)
-			}
-			return result, nil
-		}
-		return []types.Page{page}, nil
-	}
-
-	// This is a pages node - get Kids array
-	kidsStr := extractDictValue(pagesStr, "/Kids")
-	if verbose {
-		fmt.Printf("Kids string: %s\n", kidsStr)
-	}
-	if kidsStr == "" {
-		if verbose {
-			fmt.Printf("No Kids array found in pages object\n")
-		}
-		return result, nil
-	}
-
-	// Parse Kids array (e.g., "[5 0 R 6 0 R 7 0 R]" or "5 0 R 6 0 R")
-	// If kidsStr already starts with [, use it as-is, otherwise wrap it
-	if !strings.HasPrefix(kidsStr, "[") {
-		kidsStr = "[" + kidsStr + "]"
-	}
-	kids := parseObjectRefArray(kidsStr)
-	for _, kidRef := range kids {
-		kidObjNum, err := parseObjectRef(kidRef)
-		if err != nil {
-			if verbose {
-				fmt.Printf("Warning: failed to parse kid reference %s: %v\n", kidRef, err)
-			}
-			continue
-		}
-
-		// Recursively extract from child
-		childPages, err := extractPagesFromTree(pdfBytes, pdf, kidObjNum, verbose)
-		if err != nil {
-			if verbose {
-				fmt.Printf("Warning: failed to extract from child %d: %v\n", kidObjNum, err)
-			}
-			continue
-		}
-
-		result = append(result, childPages...)
+	page, err := extractPage(pdfBytes, pdf, ref, ExtractOptions{Verbose: verbose})
+	if err != nil {
+		return nil, err
 	}
-
-	return result, nil
+	page.PageNumber = pageNumber
+	return &page, nil
 }
 
-// extractPage extracts a single page
-func extractPage(pdfBytes []byte, pdf *parse.PDF, pageObjNum int, pageStr string, verbose bool) (types.Page, error) {
+// extractPage extracts a single page. Inheritable attributes (Resources, MediaBox,
+// CropBox, Rotate) come from the page tree walk, so pages that rely on an ancestor's
+// values are handled the same as pages that set them directly.
+func extractPage(pdfBytes []byte, pdf *parse.PDF, ref *parse.PageRef, opts ExtractOptions) (types.Page, error) {
+	verbose := opts.Verbose
+	pageObjNum := ref.ObjectNumber
+	pageStr := ref.Dict
 	page := types.Page{
 		PageNumber:  0, // Will be set by caller
+		Rotation:    ref.Rotate,
 		Text:        []types.TextElement{},
 		Graphics:    []types.Graphic{},
 		Images:      []types.ImageRef{},
@@ -160,8 +90,7 @@ func extractPage(pdfBytes []byte, pdf *parse.PDF, pageObjNum int, pageStr string
 	}
 
 	// Extract media box
-	mediaBox := extractArrayValue(pageStr, "/MediaBox")
-	if len(mediaBox) >= 4 {
+	if mediaBox := ref.MediaBox; len(mediaBox) >= 4 {
 		page.MediaBox = &types.Rectangle{
 			LowerX: mediaBox[0],
 			LowerY: mediaBox[1],
@@ -173,8 +102,7 @@ func extractPage(pdfBytes []byte, pdf *parse.PDF, pageObjNum int, pageStr string
 	}
 
 	// Extract crop box
-	cropBox := extractArrayValue(pageStr, "/CropBox")
-	if len(cropBox) >= 4 {
+	if cropBox := ref.CropBox; len(cropBox) >= 4 {
 		page.CropBox = &types.Rectangle{
 			LowerX: cropBox[0],
 			LowerY: cropBox[1],
@@ -183,115 +111,28 @@ func extractPage(pdfBytes []byte, pdf *parse.PDF, pageObjNum int, pageStr string
 		}
 	}
 
-	// Extract rotation
-	rotationStr := extractDictValue(pageStr, "/Rotate")
-	if rotationStr != "" {
-		if rot, err := strconv.Atoi(rotationStr); err == nil {
-			page.Rotation = rot
-		}
-	}
-
 	// Extract resources FIRST (needed for font decoders for text extraction)
-	var resourcesStr string
-	resourcesRef := extractDictValue(pageStr, "/Resources")
-	if resourcesRef != "" {
-		// Resources is a reference to another object
-		resourcesObjNum, err := parseObjectRef(resourcesRef)
-		if err == nil {
-			resourcesObj, err := pdf.GetObject(resourcesObjNum)
-			if err == nil {
-				resourcesStr = string(resourcesObj)
-				page.Resources = extractResources(resourcesStr, pdf, verbose)
-			}
-		}
-	} else {
-		// Resources might be inline - extract the Resources dictionary from page string
-		resourcesDictStr := extractInlineDict(pageStr, "/Resources")
-		if resourcesDictStr != "" {
-			resourcesStr = resourcesDictStr
-			page.Resources = extractResources(resourcesStr, pdf, verbose)
-		}
+	resourcesStr := ref.Resources
+	if resourcesStr != "" {
+		page.Resources = extractResources(resourcesStr, pdf, opts.wantText(), opts.wantImages(), verbose)
 	}
 
 	// Extract font decoders for text extraction
 	var fontDecoders map[string]*FontDecoder
-	if resourcesStr != "" {
-		fontDecoders = extractFontDecoders(resourcesStr, pdf, verbose)
-	}
-
-	// Extract content streams
-	contents := extractDictValue(pageStr, "/Contents")
-	if contents != "" {
-		// Contents can be a single reference or an array
-		// Decompress and parse content stream
-		contentRefs := parseObjectRefArray(contents)
-		if len(contentRefs) == 0 {
-			// Try as single reference
-			contentRefs = []string{contents}
-		}
-
-		// Extract text and graphics from content streams
-		for _, contentRef := range contentRefs {
-			contentObjNum, err := parseObjectRef(contentRef)
-			if err != nil {
-				if verbose {
-					fmt.Printf("Warning: failed to parse content reference %s: %v\n", contentRef, err)
-				}
-				continue
-			}
-
-			contentObj, err := pdf.GetObject(contentObjNum)
-			if err != nil {
-				if verbose {
-					fmt.Printf("Warning: failed to get content object %d: %v\n", contentObjNum, err)
-				}
-				continue
-			}
-
-			// Parse content stream
-			// Content streams are usually stored as stream objects with FlateDecode
-			// We need to get the actual stream data, not the object wrapper
-			contentStr := string(contentObj)
-
-			// Check if this is a stream object and extract/decompress the stream data
-			if strings.Contains(contentStr, "stream") {
-				streamIdx := strings.Index(contentStr, "stream")
-				if streamIdx != -1 {
-					// Check for FlateDecode filter
-					isCompressed := strings.Contains(contentStr, "/FlateDecode")
-
-					// Extract stream data
-					dataStart := streamIdx + 6 // Skip "stream"
-					// Skip EOL after "stream"
-					if dataStart < len(contentStr) && (contentStr[dataStart] == '\r' || contentStr[dataStart] == '\n') {
-						dataStart++
-					}
-					if dataStart < len(contentStr) && contentStr[dataStart] == '\n' {
-						dataStart++
-					}
-
-					endstreamIdx := strings.Index(contentStr[dataStart:], "endstream")
-					if endstreamIdx != -1 {
-						streamData := []byte(contentStr[dataStart : dataStart+endstreamIdx])
-
-						// Decompress if needed using parse package
-						if isCompressed {
-							// Use parse.DecodeFlateDecode which handles both zlib and raw deflate
-							decompressed, err := parse.DecodeFlateDecode(streamData)
-							if err == nil {
-								contentStr = string(decompressed)
-							} else {
-								// Fallback to raw if decompression fails
-								contentStr = string(streamData)
-							}
-						} else {
-							contentStr = string(streamData)
-						}
-					}
+	if resourcesStr != "" && opts.wantText() {
+		fontDecoders = extractFontDecoders(resourcesStr, pdf, opts.Profile, verbose)
+	}
+
+	// Extract text and graphics from content streams
+	parts := opts.contentParts()
+	if parts.any() {
+		for _, contentStr := range pageContentStreams(pdf, pageStr, verbose) {
+			textElements, graphics, images, _ := parseContentStreamParts(contentStr, pdf, pageObjNum, fontDecoders, parts, verbose)
+			if opts.Profile == ProfileAccurate {
+				for i := range textElements {
+					textElements[i].Text = logicalOrder(textElements[i].Text)
 				}
 			}
-
-			textElements, graphics, images := parseContentStreamWithDecoders(contentStr, pdf, pageObjNum, fontDecoders, verbose)
 			page.Text = append(page.Text, textElements...)
 			page.Graphics = append(page.Graphics, graphics...)
 			page.Images = append(page.Images, images...)
@@ -301,7 +142,7 @@ func extractPage(pdfBytes []byte, pdf *parse.PDF, pageObjNum int, pageStr string
 	// Extract annotations
 	// extractDictValue already handles arrays and returns them as "[...]" strings
 	annotsRef := extractDictValue(pageStr, "/Annots")
-	if annotsRef != "" {
+	if annotsRef != "" && opts.wantAnnotations() {
 		annotations := extractAnnotations(annotsRef, pdf, pageObjNum, verbose)
 		page.Annotations = annotations
 	}
@@ -309,11 +150,88 @@ func extractPage(pdfBytes []byte, pdf *parse.PDF, pageObjNum int, pageStr string
 	return page, nil
 }
 
+// pageContentStreams returns the decoded content streams of a page in order
+func pageContentStreams(pdf *parse.PDF, pageStr string, verbose bool) []string {
+	contents := extractDictValue(pageStr, "/Contents")
+	if contents == "" {
+		return nil
+	}
+
+	// Contents can be a single reference or an array
+	contentRefs := parseObjectRefArray(contents)
+	if len(contentRefs) == 0 {
+		// Try as single reference
+		contentRefs = []string{contents}
+	}
+
+	var streams []string
+	for _, contentRef := range contentRefs {
+		contentObjNum, err := parseObjectRef(contentRef)
+		if err != nil {
+			if verbose {
+				fmt.Printf("Warning: failed to parse content reference %s: %v\n", contentRef, err)
+			}
+			continue
+		}
+
+		contentObj, err := pdf.GetObject(contentObjNum)
+		if err != nil {
+			if verbose {
+				fmt.Printf("Warning: failed to get content object %d: %v\n", contentObjNum, err)
+			}
+			continue
+		}
+		streams = append(streams, decodeContentStream(contentObj))
+	}
+	return streams
+}
+
+// decodeContentStream returns the data of a content stream object, inflating
+// FlateDecode streams. Objects that are not streams are returned unchanged.
+func decodeContentStream(contentObj []byte) string {
+	contentStr := string(contentObj)
+	streamIdx := strings.Index(contentStr, "stream")
+	if streamIdx == -1 {
+		return contentStr
+	}
+
+	// Check for FlateDecode filter
+	isCompressed := strings.Contains(contentStr, "/FlateDecode")
+
+	// Extract stream data
+	dataStart := streamIdx + 6 // Skip "stream"
+	// Skip EOL after "stream"
+	if dataStart < len(contentStr) && (contentStr[dataStart] == '\r' || contentStr[dataStart] == '\n') {
+		dataStart++
+	}
+	if dataStart < len(contentStr) && contentStr[dataStart] == '\n' {
+		dataStart++
+	}
+
+	endstreamIdx := strings.Index(contentStr[dataStart:], "endstream")
+	if endstreamIdx == -1 {
+		return contentStr
+	}
+	streamData := []byte(contentStr[dataStart : dataStart+endstreamIdx])
+	if !isCompressed {
+		return string(streamData)
+	}
+
+	// Decode into a pooled buffer; handles both zlib and raw deflate
+	buf := parse.AcquireBuffer()
+	defer parse.ReleaseBuffer(buf)
+	if err := parse.DecodeFlateDecodeTo(buf, streamData); err != nil {
+		// Fallback to raw if decompression fails
+		return string(streamData)
+	}
+	return buf.String()
+}
+
 // Helper functions for parsing PDF dictionaries and arrays
 
 func extractDictValue(dictStr, key string) string {
-	// Try to match simple value with space (e.g., "/Pages 2 0 R")
-	pattern := regexp.MustCompile(regexp.QuoteMeta(key) + `\s+([^\s<>]+)`)
+	// Try to match simple value with space (e.g., "/Pages 2 0 R"); arrays are matched below
+	pattern := regexp.MustCompile(regexp.QuoteMeta(key) + `\s+([^\s<>\[\]]+)`)
 	match := pattern.FindStringSubmatch(dictStr)
 	if len(match) > 1 {
 		return match[1]
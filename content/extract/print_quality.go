@@ -0,0 +1,167 @@
+package extract
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// PrintQualityOptions are the thresholds of AnalyzePrintQuality. Budgets left at
+// zero are not checked.
+type PrintQualityOptions struct {
+	MinDPI          float64 // Images placed below this effective resolution are flagged
+	MaxTotalInk     float64 // Highest total ink (C+M+Y+K, in percent) of a page's colors
+	MaxInkCoverage  float64 // Highest fraction of a page covered by content (0-1)
+	MaxVectorOps    int     // Highest number of path operators on a page
+	MaxContentBytes int     // Highest decoded content stream size of a page
+}
+
+// DefaultPrintQualityOptions returns thresholds suited to commercial offset
+// printing: images of at least 150 DPI, 300% total ink and 100,000 path
+// operators per page
+func DefaultPrintQualityOptions() PrintQualityOptions {
+	return PrintQualityOptions{
+		MinDPI:       150,
+		MaxTotalInk:  300,
+		MaxVectorOps: 100000,
+	}
+}
+
+// AnalyzePrintQuality computes the effective resolution of every image placed
+// on a page, flagging those below opts.MinDPI, and reports the pages that
+// exceed the ink and complexity budgets of opts. Total ink is measured on the
+// colors of vector graphics; RGB and gray colors are converted to CMYK without
+// a color profile. Images drawn inside form XObjects are not measured.
+func AnalyzePrintQuality(pdfBytes []byte, pdf *parse.PDF, opts PrintQualityOptions, verbose bool) (*types.PrintQualityReport, error) {
+	report := &types.PrintQualityReport{
+		Images: []types.ImageResolution{},
+		Pages:  []types.PagePrintQuality{},
+	}
+
+	it := pdf.Pages()
+	for it.Next() {
+		ref := it.Page()
+		page, err := extractPage(pdfBytes, pdf, ref, ExtractOptions{Verbose: verbose})
+		if err != nil {
+			if verbose {
+				fmt.Printf("Warning: failed to extract page %d: %v\n", ref.Number, err)
+			}
+			continue
+		}
+		page.PageNumber = ref.Number
+
+		for _, res := range imageResolutions(&page) {
+			if opts.MinDPI > 0 && res.DPI < opts.MinDPI {
+				res.LowResolution = true
+				report.LowResolutionImages++
+			}
+			report.Images = append(report.Images, res)
+		}
+
+		stats := pageStats(&page, pageContentStreams(pdf, ref.Dict, verbose))
+		quality := types.PagePrintQuality{
+			PageNumber:   page.PageNumber,
+			InkCoverage:  stats.InkCoverage,
+			TotalInk:     maxTotalInk(page.Graphics),
+			VectorOps:    stats.VectorOps,
+			ContentBytes: stats.ContentBytes,
+		}
+		if opts.MaxTotalInk > 0 && quality.TotalInk > opts.MaxTotalInk {
+			quality.Exceeded = append(quality.Exceeded, fmt.Sprintf("total_ink %.0f%% > %.0f%%", quality.TotalInk, opts.MaxTotalInk))
+		}
+		if opts.MaxInkCoverage > 0 && quality.InkCoverage > opts.MaxInkCoverage {
+			quality.Exceeded = append(quality.Exceeded, fmt.Sprintf("ink_coverage %.0f%% > %.0f%%", quality.InkCoverage*100, opts.MaxInkCoverage*100))
+		}
+		if opts.MaxVectorOps > 0 && quality.VectorOps > opts.MaxVectorOps {
+			quality.Exceeded = append(quality.Exceeded, fmt.Sprintf("vector_ops %d > %d", quality.VectorOps, opts.MaxVectorOps))
+		}
+		if opts.MaxContentBytes > 0 && quality.ContentBytes > opts.MaxContentBytes {
+			quality.Exceeded = append(quality.Exceeded, fmt.Sprintf("content_bytes %d > %d", quality.ContentBytes, opts.MaxContentBytes))
+		}
+		if len(quality.Exceeded) > 0 {
+			report.PagesOverBudget++
+		}
+		report.Pages = append(report.Pages, quality)
+	}
+	if err := it.Err(); err != nil {
+		return nil, fmt.Errorf("failed to walk pages tree: %w", err)
+	}
+
+	report.Passed = report.LowResolutionImages == 0 && report.PagesOverBudget == 0
+	return report, nil
+}
+
+// imageResolutions returns the effective resolution of each image drawn on a
+// page. The placed size is taken from the transformation matrix, so rotated
+// and skewed placements are measured along the image's own axes.
+func imageResolutions(page *types.Page) []types.ImageResolution {
+	if page.Resources == nil {
+		return nil
+	}
+	var resolutions []types.ImageResolution
+	for _, ref := range page.Images {
+		img, ok := page.Resources.Images[strings.TrimPrefix(ref.ImageID, "/")]
+		if !ok || img.Width <= 0 || img.Height <= 0 {
+			continue
+		}
+		m := ref.Transform
+		width, height := math.Hypot(m[0], m[1]), math.Hypot(m[2], m[3])
+		if width == 0 || height == 0 {
+			width, height = ref.Width, ref.Height
+		}
+		if width <= 0 || height <= 0 {
+			continue
+		}
+		dpiX := float64(img.Width) / (width / 72)
+		dpiY := float64(img.Height) / (height / 72)
+		resolutions = append(resolutions, types.ImageResolution{
+			PageNumber:   page.PageNumber,
+			ImageID:      ref.ImageID,
+			PixelWidth:   img.Width,
+			PixelHeight:  img.Height,
+			PlacedWidth:  width,
+			PlacedHeight: height,
+			DPI:          math.Min(dpiX, dpiY),
+		})
+	}
+	return resolutions
+}
+
+// maxTotalInk returns the highest total ink, in percent, among the fill and
+// stroke colors of graphics
+func maxTotalInk(graphics []types.Graphic) float64 {
+	highest := 0.0
+	for _, g := range graphics {
+		for _, col := range []*types.Color{g.FillColor, g.StrokeColor} {
+			if col != nil {
+				highest = math.Max(highest, totalInk(col))
+			}
+		}
+	}
+	return highest
+}
+
+// totalInk returns the sum of a color's C, M, Y and K components in percent.
+// RGB and gray colors are converted with the naive formula, black going
+// entirely to K.
+func totalInk(col *types.Color) float64 {
+	switch col.Space {
+	case types.ColorSpaceCMYK:
+		return (col.C + col.M + col.Y + col.K) * 100
+	case types.ColorSpaceGray:
+		return (1 - col.R) * 100
+	case types.ColorSpaceLab, types.ColorSpaceIndex:
+		return 0
+	}
+	k := 1 - math.Max(col.R, math.Max(col.G, col.B))
+	if k >= 1 {
+		return 100
+	}
+	c := (1 - col.R - k) / (1 - k)
+	m := (1 - col.G - k) / (1 - k)
+	y := (1 - col.B - k) / (1 - k)
+	return (c + m + y + k) * 100
+}
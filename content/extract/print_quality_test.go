@@ -0,0 +1,110 @@
+package extract
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"math"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+func TestAnalyzePrintQuality(t *testing.T) {
+	builder := write.NewSimplePDFBuilder()
+	addImage := func(page *write.PageBuilder, pixels int) string {
+		var img bytes.Buffer
+		if err := png.Encode(&img, image.NewGray(image.Rect(0, 0, pixels, pixels))); err != nil {
+			t.Fatalf("Failed to encode image: %v", err)
+		}
+		info, err := builder.Writer().AddImage(img.Bytes(), "")
+		if err != nil {
+			t.Fatalf("Failed to add image: %v", err)
+		}
+		return page.AddImage(info)
+	}
+
+	// 600 pixels over 2 inches is 300 DPI; 100 pixels over 1 inch is 100 DPI
+	page := builder.AddPage(write.PageSizeLetter)
+	sharp := addImage(page, 600)
+	blurry := addImage(page, 100)
+	page.Content().
+		DrawImageAt(sharp, 72, 500, 144, 144).
+		DrawImageAt(blurry, 72, 300, 72, 72)
+	builder.FinalizePage(page)
+
+	// Rich black of 400% total ink
+	inked := builder.AddPage(write.PageSizeLetter)
+	inked.Content().Raw("1 1 1 1 k\n").Rectangle(72, 72, 200, 200).Fill()
+	builder.FinalizePage(inked)
+
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+	pdf, err := ParseTestPDF(pdfBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse PDF: %v", err)
+	}
+
+	report, err := AnalyzePrintQuality(pdfBytes, pdf, DefaultPrintQualityOptions(), false)
+	if err != nil {
+		t.Fatalf("AnalyzePrintQuality failed: %v", err)
+	}
+	if report.Passed {
+		t.Error("Expected the report to fail")
+	}
+	if len(report.Images) != 2 {
+		t.Fatalf("Expected 2 images, got %+v", report.Images)
+	}
+	dpi := map[int]types.ImageResolution{}
+	for _, img := range report.Images {
+		dpi[img.PixelWidth] = img
+	}
+	if got := dpi[600]; math.Abs(got.DPI-300) > 0.01 || got.LowResolution {
+		t.Errorf("Expected a 300 DPI image, got %+v", got)
+	}
+	if got := dpi[100]; math.Abs(got.DPI-100) > 0.01 || !got.LowResolution || got.PlacedWidth != 72 {
+		t.Errorf("Expected a low-resolution 100 DPI image, got %+v", got)
+	}
+	if report.LowResolutionImages != 1 {
+		t.Errorf("Expected 1 low-resolution image, got %d", report.LowResolutionImages)
+	}
+
+	if len(report.Pages) != 2 {
+		t.Fatalf("Expected 2 pages, got %d", len(report.Pages))
+	}
+	if got := report.Pages[1]; math.Abs(got.TotalInk-400) > 0.01 || len(got.Exceeded) != 1 {
+		t.Errorf("Expected page 2 over the total ink budget, got %+v", got)
+	}
+	if len(report.Pages[0].Exceeded) != 0 || report.PagesOverBudget != 1 {
+		t.Errorf("Expected only page 2 over budget, got %+v", report.Pages)
+	}
+
+	// Tighter complexity budgets
+	opts := PrintQualityOptions{MaxVectorOps: 1}
+	if report, err = AnalyzePrintQuality(pdfBytes, pdf, opts, false); err != nil {
+		t.Fatalf("AnalyzePrintQuality failed: %v", err)
+	}
+	if report.LowResolutionImages != 0 || report.PagesOverBudget != 1 || report.Pages[1].Exceeded[0] != "vector_ops 2 > 1" {
+		t.Errorf("Unexpected report with only a vector budget: %+v", report)
+	}
+}
+
+func TestTotalInk(t *testing.T) {
+	tests := []struct {
+		color types.Color
+		want  float64
+	}{
+		{types.Color{}, 100},
+		{types.Color{R: 1, G: 1, B: 1}, 0},
+		{types.Color{R: 1, G: 0, B: 0}, 200},
+		{types.Color{Space: types.ColorSpaceCMYK, C: 0.6, M: 0.4, Y: 0.4, K: 1}, 240},
+	}
+	for _, tt := range tests {
+		if got := totalInk(&tt.color); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("totalInk(%+v) = %v, want %v", tt.color, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package extract
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// ExtractProfile trades extraction accuracy for speed
+type ExtractProfile string
+
+const (
+	// ProfileDefault decodes text through ToUnicode maps and font encodings,
+	// extracts images and approximates text widths
+	ProfileDefault ExtractProfile = ""
+
+	// ProfileFast skips ToUnicode parsing and XObjects, including images, for
+	// indexing large collections. Text is decoded through font encodings only.
+	ProfileFast ExtractProfile = "fast"
+
+	// ProfileAccurate adds glyph widths from the font's /Widths array, or the
+	// standard font metrics, and puts right-to-left text in logical order, for
+	// display
+	ProfileAccurate ExtractProfile = "accurate"
+)
+
+// ParseExtractProfile returns the profile with the given name; "" and "default"
+// name ProfileDefault
+func ParseExtractProfile(name string) (ExtractProfile, error) {
+	switch profile := ExtractProfile(strings.ToLower(name)); profile {
+	case ProfileDefault, "default":
+		return ProfileDefault, nil
+	case ProfileFast, ProfileAccurate:
+		return profile, nil
+	default:
+		return "", fmt.Errorf("unknown extraction profile %q (want fast or accurate)", name)
+	}
+}
+
+// ExtractOptions controls content extraction
+type ExtractOptions struct {
+	Password []byte         // Password for encrypted PDFs
+	Verbose  bool           // Print progress and warnings
+	Profile  ExtractProfile // Speed/accuracy trade-off (default: ProfileDefault)
+
+	// The content type flags prune the pipeline when only part of the content
+	// is needed. TextOnly and ImagesOnly also skip annotations, graphics and
+	// the document-level bookmarks, threads, attachments and signatures.
+	TextOnly        bool // Extract page text only; skip XObjects and images
+	ImagesOnly      bool // Extract images only; skip fonts and text
+	SkipAnnotations bool // Do not read page annotations
+	SkipGraphics    bool // Do not collect paths, rectangles and colors
+}
+
+// validate rejects content type flags that cannot be combined
+func (o ExtractOptions) validate() error {
+	if o.TextOnly && o.ImagesOnly {
+		return fmt.Errorf("TextOnly and ImagesOnly cannot be combined")
+	}
+	if o.ImagesOnly && o.Profile == ProfileFast {
+		return fmt.Errorf("ImagesOnly cannot be combined with the fast profile, which skips images")
+	}
+	return nil
+}
+
+// wantText reports whether page text, and so fonts, are extracted
+func (o ExtractOptions) wantText() bool { return !o.ImagesOnly }
+
+// wantImages reports whether XObjects and image placements are extracted
+func (o ExtractOptions) wantImages() bool { return !o.TextOnly && o.Profile != ProfileFast }
+
+// wantGraphics reports whether paths, rectangles and colors are collected
+func (o ExtractOptions) wantGraphics() bool { return !o.TextOnly && !o.ImagesOnly && !o.SkipGraphics }
+
+// wantAnnotations reports whether page annotations are read
+func (o ExtractOptions) wantAnnotations() bool {
+	return !o.TextOnly && !o.ImagesOnly && !o.SkipAnnotations
+}
+
+// wantDocumentParts reports whether bookmarks, threads, attachments and
+// signatures are extracted
+func (o ExtractOptions) wantDocumentParts() bool { return !o.TextOnly && !o.ImagesOnly }
+
+// contentParts returns the parts of content streams to parse
+func (o ExtractOptions) contentParts() contentParts {
+	return contentParts{text: o.wantText(), graphics: o.wantGraphics(), images: o.wantImages()}
+}
+
+// ExtractContentWithOptions extracts all content from a PDF using the given options
+func ExtractContentWithOptions(pdfBytes []byte, opts ExtractOptions) (*types.ContentDocument, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	pdf, err := parse.OpenWithOptions(pdfBytes, parse.ParseOptions{
+		Password: opts.Password,
+		Verbose:  opts.Verbose,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PDF: %w", err)
+	}
+	return extractContent(pdfBytes, pdf, opts)
+}
+
+// ExtractContentFromReader extracts content like ExtractContentWithOptions from
+// a PDF read from r, which holds size bytes, without reading the whole file into
+// memory. Encrypted documents are opened with opts.Password. See
+// parse.OpenReaderWithOptions.
+func ExtractContentFromReader(r io.ReaderAt, size int64, opts ExtractOptions) (*types.ContentDocument, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	pdf, err := parse.OpenReaderWithOptions(r, size, parse.ParseOptions{
+		Password: opts.Password,
+		Verbose:  opts.Verbose,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PDF: %w", err)
+	}
+	return extractContent(nil, pdf, opts)
+}
+
+// logicalOrder converts a run of text from the visual order in which glyphs are
+// drawn to reading order. Right-to-left runs (Hebrew, Arabic and similar) are
+// reversed, keeping digit sequences and left-to-right words inside them in
+// order and mirroring brackets. Text without right-to-left characters is
+// returned unchanged.
+func logicalOrder(text string) string {
+	runes := []rune(text)
+	rtl := false
+	for _, r := range runes {
+		if isRTL(r) {
+			rtl = true
+			break
+		}
+	}
+	if !rtl {
+		return text
+	}
+
+	// Reverse the whole line, then restore the order of each left-to-right
+	// run so that numbers and embedded Latin words read correctly
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	for i := 0; i < len(runes); {
+		if !isLTR(runes[i]) {
+			if mirrored, ok := bidiMirror[runes[i]]; ok {
+				runes[i] = mirrored
+			}
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(runes) && (isLTR(runes[j]) || (runes[j] == '.' || runes[j] == ',' || runes[j] == ' ') && j+1 < len(runes) && isLTR(runes[j+1])) {
+			j++
+		}
+		for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+			runes[a], runes[b] = runes[b], runes[a]
+		}
+		i = j
+	}
+	return string(runes)
+}
+
+// isRTL reports whether r is a strong right-to-left character
+func isRTL(r rune) bool {
+	return !unicode.IsDigit(r) && unicode.In(r, unicode.Hebrew, unicode.Arabic, unicode.Syriac, unicode.Thaana, unicode.Nko)
+}
+
+// isLTR reports whether r belongs to a left-to-right run: a digit or a letter
+// of a left-to-right script
+func isLTR(r rune) bool {
+	return unicode.IsDigit(r) || unicode.IsLetter(r) && !isRTL(r)
+}
+
+// bidiMirror maps brackets to their mirrored form in right-to-left text
+var bidiMirror = map[rune]rune{
+	'(': ')', ')': '(',
+	'[': ']', ']': '[',
+	'{': '}', '}': '{',
+	'<': '>', '>': '<',
+}
@@ -0,0 +1,193 @@
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+)
+
+// createProfilePDF creates a page with a line of Helvetica text and an image
+func createProfilePDF(t *testing.T) []byte {
+	t.Helper()
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewGray(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatalf("Failed to encode image: %v", err)
+	}
+
+	builder := write.NewSimplePDFBuilder()
+	page := builder.AddPage(write.PageSizeLetter)
+	info, err := builder.Writer().AddImage(img.Bytes(), "")
+	if err != nil {
+		t.Fatalf("Failed to add image: %v", err)
+	}
+	imgName := page.AddImage(info)
+	fontName := page.AddStandardFont("Helvetica")
+
+	content := page.Content()
+	content.BeginText()
+	content.SetFont(fontName, 12)
+	content.SetTextPosition(72, 720)
+	content.ShowText("Hello")
+	content.EndText()
+	content.DrawImageAt(imgName, 72, 600, 50, 50)
+	content.Rectangle(72, 500, 100, 20).Fill()
+
+	builder.FinalizePage(page)
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+	return pdfBytes
+}
+
+func TestExtractContentWithOptions_Profiles(t *testing.T) {
+	pdfBytes := createProfilePDF(t)
+
+	tests := []struct {
+		profile    ExtractProfile
+		wantImages bool
+		wantWidth  float64
+	}{
+		{ProfileDefault, true, 5 * 12 * 0.6},
+		{ProfileFast, false, 5 * 12 * 0.6},
+		{ProfileAccurate, true, write.StandardTextWidth("Hello", "Helvetica", 12)},
+	}
+	for _, tt := range tests {
+		doc, err := ExtractContentWithOptions(pdfBytes, ExtractOptions{Profile: tt.profile})
+		if err != nil {
+			t.Fatalf("%q: extraction failed: %v", tt.profile, err)
+		}
+		page := doc.Pages[0]
+		if len(page.Text) != 1 || page.Text[0].Text != "Hello" {
+			t.Fatalf("%q: unexpected text %+v", tt.profile, page.Text)
+		}
+		if got := page.Text[0].Width; math.Abs(got-tt.wantWidth) > 0.001 {
+			t.Errorf("%q: width = %.3f, want %.3f", tt.profile, got, tt.wantWidth)
+		}
+		if hasImages := len(page.Images) > 0 && len(page.Resources.Images) > 0; hasImages != tt.wantImages {
+			t.Errorf("%q: images extracted = %v, want %v", tt.profile, hasImages, tt.wantImages)
+		}
+	}
+}
+
+func TestExtractContentWithOptions_ContentTypes(t *testing.T) {
+	pdfBytes := createProfilePDF(t)
+
+	tests := []struct {
+		name                               string
+		opts                               ExtractOptions
+		wantText, wantImages, wantGraphics bool
+	}{
+		{"all", ExtractOptions{}, true, true, true},
+		{"text only", ExtractOptions{TextOnly: true}, true, false, false},
+		{"images only", ExtractOptions{ImagesOnly: true}, false, true, false},
+		{"skip graphics", ExtractOptions{SkipGraphics: true}, true, true, false},
+	}
+	for _, tt := range tests {
+		doc, err := ExtractContentWithOptions(pdfBytes, tt.opts)
+		if err != nil {
+			t.Fatalf("%s: extraction failed: %v", tt.name, err)
+		}
+		page := doc.Pages[0]
+		if hasText := len(page.Text) > 0 && len(page.Resources.Fonts) > 0; hasText != tt.wantText {
+			t.Errorf("%s: text extracted = %v, want %v", tt.name, hasText, tt.wantText)
+		}
+		if hasImages := len(page.Images) > 0 && len(doc.Images) > 0; hasImages != tt.wantImages {
+			t.Errorf("%s: images extracted = %v, want %v", tt.name, hasImages, tt.wantImages)
+		}
+		if hasGraphics := len(page.Graphics) > 0; hasGraphics != tt.wantGraphics {
+			t.Errorf("%s: graphics extracted = %v, want %v", tt.name, hasGraphics, tt.wantGraphics)
+		}
+	}
+
+	// Image placements survive without the text and graphics around them
+	doc, _ := ExtractContentWithOptions(pdfBytes, ExtractOptions{ImagesOnly: true})
+	if img := doc.Pages[0].Images[0]; img.X != 72 || img.Y != 600 || img.Width != 50 {
+		t.Errorf("ImagesOnly placement = %+v", img)
+	}
+
+	for _, opts := range []ExtractOptions{{TextOnly: true, ImagesOnly: true}, {ImagesOnly: true, Profile: ProfileFast}} {
+		if _, err := ExtractContentWithOptions(pdfBytes, opts); err == nil {
+			t.Errorf("%+v should be rejected", opts)
+		}
+	}
+}
+
+func TestExtractContentWithOptions_SkipAnnotations(t *testing.T) {
+	writer := write.NewPDFWriter()
+	catalogNum := writer.AddObject(nil)
+	pagesNum := writer.AddObject(nil)
+	pageNum := writer.AddObject(nil)
+	annotNum := writer.AddObject([]byte("<</Type/Annot/Subtype/Text/Rect [10 10 30 30]/Contents(Note)>>"))
+	writer.SetObject(catalogNum, []byte(fmt.Sprintf("<</Type/Catalog/Pages %d 0 R>>", pagesNum)))
+	writer.SetObject(pagesNum, []byte(fmt.Sprintf("<</Type/Pages/Kids [%d 0 R]/Count 1>>", pageNum)))
+	writer.SetObject(pageNum, []byte(fmt.Sprintf("<</Type/Page/Parent %d 0 R/MediaBox [0 0 612 792]/Annots [%d 0 R]>>", pagesNum, annotNum)))
+	writer.SetRoot(catalogNum)
+	pdfBytes, err := writer.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+
+	for _, tt := range []struct {
+		opts ExtractOptions
+		want int
+	}{
+		{ExtractOptions{}, 1},
+		{ExtractOptions{SkipAnnotations: true}, 0},
+		{ExtractOptions{TextOnly: true}, 0},
+	} {
+		doc, err := ExtractContentWithOptions(pdfBytes, tt.opts)
+		if err != nil {
+			t.Fatalf("%+v: extraction failed: %v", tt.opts, err)
+		}
+		if len(doc.Annotations) != tt.want || len(doc.Pages[0].Annotations) != tt.want {
+			t.Errorf("%+v: %d annotations, want %d", tt.opts, len(doc.Annotations), tt.want)
+		}
+	}
+}
+
+func TestFontDecoder_TextWidth(t *testing.T) {
+	decoder := NewFontDecoder("F1")
+	decoder.SetBaseEncoding("WinAnsiEncoding")
+	if _, ok := decoder.TextWidth("AB", 10); ok {
+		t.Error("Expected no width before widths are loaded")
+	}
+	decoder.SetWidths(65, []float64{700, 600})
+	if w, ok := decoder.TextWidth("ABA", 10); !ok || math.Abs(w-20) > 0.001 {
+		t.Errorf("TextWidth = %.3f, %v; want 20, true", w, ok)
+	}
+}
+
+func TestLogicalOrder(t *testing.T) {
+	tests := []struct {
+		visual string
+		want   string
+	}{
+		{"Hello, world", "Hello, world"},
+		{"םולש", "שלום"},
+		{"2024 תנשב", "בשנת 2024"},
+		{"PDF ץבוק", "קובץ PDF"},
+		{"(םולש)", "(שלום)"},
+	}
+	for _, tt := range tests {
+		if got := logicalOrder(tt.visual); got != tt.want {
+			t.Errorf("logicalOrder(%q) = %q, want %q", tt.visual, got, tt.want)
+		}
+	}
+}
+
+func TestParseExtractProfile(t *testing.T) {
+	for name, want := range map[string]ExtractProfile{"": ProfileDefault, "default": ProfileDefault, "Fast": ProfileFast, "accurate": ProfileAccurate} {
+		if got, err := ParseExtractProfile(name); err != nil || got != want {
+			t.Errorf("ParseExtractProfile(%q) = %q, %v", name, got, err)
+		}
+	}
+	if _, err := ParseExtractProfile("slow"); err == nil {
+		t.Error("Expected error for unknown profile")
+	}
+}
@@ -0,0 +1,87 @@
+package extract
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// defaultRequirementPenalty is the /Penalty of a requirement that doesn't state one
+const defaultRequirementPenalty = 100
+
+// extractCatalogRequirements fills the extensions and requirements a document
+// declares in its catalog, so that callers can tell which reader features and
+// versions it depends on
+func extractCatalogRequirements(pdf *parse.PDF, metadata *types.DocumentMetadata, verbose bool) {
+	trailer := pdf.Trailer()
+	if trailer == nil || trailer.RootRef == "" {
+		return
+	}
+	rootObjNum, err := parseObjectRef(trailer.RootRef)
+	if err != nil {
+		return
+	}
+	catalogObj, err := pdf.GetObject(rootObjNum)
+	if err != nil {
+		return
+	}
+	catalog := objectContent(string(catalogObj))
+	resolver := &linkResolver{pdf: pdf, verbose: verbose}
+
+	metadata.Extensions = extractExtensions(resolver, resolver.deref(dictEntry(catalog, "/Extensions")))
+	metadata.Requirements = extractRequirements(resolver, resolver.deref(dictEntry(catalog, "/Requirements")))
+}
+
+// extractExtensions reads an /Extensions dictionary. Each prefix maps to a
+// developer extensions dictionary or, since PDF 2.0, to an array of them.
+func extractExtensions(resolver *linkResolver, extensions string) []types.Extension {
+	var result []types.Extension
+	for _, prefix := range dictKeys(extensions) {
+		if prefix == "Type" {
+			continue
+		}
+		value := resolver.deref(dictEntry(extensions, "/"+prefix))
+		entries := []string{value}
+		if strings.HasPrefix(value, "[") {
+			entries = arrayItems(value)
+		}
+		for _, entry := range entries {
+			entry = resolver.deref(entry)
+			if !strings.HasPrefix(entry, "<<") {
+				continue
+			}
+			ext := types.Extension{
+				Prefix:      prefix,
+				BaseVersion: strings.TrimPrefix(resolver.deref(dictEntry(entry, "/BaseVersion")), "/"),
+				URL:         pdfString(resolver.deref(dictEntry(entry, "/URL"))),
+				Revision:    pdfString(resolver.deref(dictEntry(entry, "/ExtensionRevision"))),
+			}
+			ext.ExtensionLevel, _ = strconv.Atoi(resolver.deref(dictEntry(entry, "/ExtensionLevel")))
+			result = append(result, ext)
+		}
+	}
+	return result
+}
+
+// extractRequirements reads a /Requirements array of requirement dictionaries
+func extractRequirements(resolver *linkResolver, requirements string) []types.Requirement {
+	var result []types.Requirement
+	for _, item := range arrayItems(requirements) {
+		entry := resolver.deref(item)
+		if !strings.HasPrefix(entry, "<<") {
+			continue
+		}
+		req := types.Requirement{
+			Type:    strings.TrimPrefix(resolver.deref(dictEntry(entry, "/S")), "/"),
+			Version: strings.TrimPrefix(resolver.deref(dictEntry(entry, "/V")), "/"),
+			Penalty: defaultRequirementPenalty,
+		}
+		if penalty, err := strconv.Atoi(resolver.deref(dictEntry(entry, "/Penalty"))); err == nil {
+			req.Penalty = penalty
+		}
+		result = append(result, req)
+	}
+	return result
+}
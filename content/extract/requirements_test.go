@@ -0,0 +1,69 @@
+package extract
+
+import (
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+func TestExtractMetadata_ExtensionsAndRequirements(t *testing.T) {
+	builder := write.NewSimplePDFBuilder()
+	builder.FinalizePage(builder.AddPage(write.PageSizeLetter))
+	extensions := []types.Extension{
+		{Prefix: "ADBE", BaseVersion: "1.7", ExtensionLevel: 8},
+		{Prefix: "XYZ", BaseVersion: "2.0", ExtensionLevel: 1, URL: "https://example.com/xyz"},
+		{Prefix: "XYZ", BaseVersion: "2.0", ExtensionLevel: 2},
+	}
+	if err := builder.SetExtensions(extensions); err != nil {
+		t.Fatalf("SetExtensions failed: %v", err)
+	}
+	requirements := []types.Requirement{{Type: "EnableJavaScripts", Penalty: 100}, {Type: "DigSig", Version: "2.0", Penalty: 50}}
+	if err := builder.SetRequirements(requirements); err != nil {
+		t.Fatalf("SetRequirements failed: %v", err)
+	}
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+
+	pdf, err := ParseTestPDF(pdfBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse PDF: %v", err)
+	}
+	metadata, err := ExtractMetadata(pdfBytes, pdf, false)
+	if err != nil {
+		t.Fatalf("ExtractMetadata failed: %v", err)
+	}
+
+	if len(metadata.Extensions) != len(extensions) {
+		t.Fatalf("Got extensions %+v, want %+v", metadata.Extensions, extensions)
+	}
+	for i, want := range extensions {
+		if metadata.Extensions[i] != want {
+			t.Errorf("Extension %d = %+v, want %+v", i, metadata.Extensions[i], want)
+		}
+	}
+	if v := metadata.Extensions[0].AcrobatVersion(); v != "10.0" {
+		t.Errorf("AcrobatVersion = %q, want 10.0", v)
+	}
+	if v := metadata.Extensions[1].AcrobatVersion(); v != "" {
+		t.Errorf("AcrobatVersion of a non-Adobe extension = %q", v)
+	}
+
+	if len(metadata.Requirements) != len(requirements) {
+		t.Fatalf("Got requirements %+v, want %+v", metadata.Requirements, requirements)
+	}
+	for i, want := range requirements {
+		if metadata.Requirements[i] != want {
+			t.Errorf("Requirement %d = %+v, want %+v", i, metadata.Requirements[i], want)
+		}
+	}
+}
+
+func TestExtractRequirements_DefaultPenalty(t *testing.T) {
+	reqs := extractRequirements(&linkResolver{}, "[<< /Type /Requirement /S /OCInteract >>]")
+	if len(reqs) != 1 || reqs[0].Type != "OCInteract" || reqs[0].Penalty != 100 {
+		t.Errorf("Got %+v", reqs)
+	}
+}
@@ -10,8 +10,9 @@ import (
 	"github.com/benedoc-inc/pdfer/types"
 )
 
-// extractResources extracts resources from a Resources dictionary
-func extractResources(resourcesStr string, pdf *parse.PDF, verbose bool) *types.PageResources {
+// extractResources extracts resources from a Resources dictionary. XObjects,
+// including images, are skipped unless xobjects is set.
+func extractResources(resourcesStr string, pdf *parse.PDF, fonts, xobjects bool, verbose bool) *types.PageResources {
 	resources := &types.PageResources{
 		Fonts:       make(map[string]types.FontInfo),
 		Images:      make(map[string]types.Image),
@@ -22,9 +23,14 @@ func extractResources(resourcesStr string, pdf *parse.PDF, verbose bool) *types.
 	}
 
 	// Extract fonts
-	fontsDict := extractFontsDict(resourcesStr, pdf, verbose)
-	if fontsDict != nil {
-		resources.Fonts = fontsDict
+	if fonts {
+		if fontsDict := extractFontsDict(resourcesStr, pdf, verbose); fontsDict != nil {
+			resources.Fonts = fontsDict
+		}
+	}
+
+	if !xobjects {
+		return resources
 	}
 
 	// Extract XObjects (images and forms)
@@ -343,7 +349,7 @@ func extractImageDataWithBinary(imageObjNum int, pdf *parse.PDF, verbose bool) (
 }
 
 // extractFontDecoders extracts font decoders for text extraction from a Resources string
-func extractFontDecoders(resourcesStr string, pdf *parse.PDF, verbose bool) map[string]*FontDecoder {
+func extractFontDecoders(resourcesStr string, pdf *parse.PDF, profile ExtractProfile, verbose bool) map[string]*FontDecoder {
 	decoders := make(map[string]*FontDecoder)
 
 	if verbose {
@@ -402,7 +408,7 @@ func extractFontDecoders(resourcesStr string, pdf *parse.PDF, verbose bool) map[
 		fontName := entry[1]
 		fontObjNum, _ := parseObjectRef(entry[2] + " 0 R")
 
-		decoder := extractFontDecoder(fontObjNum, fontName, pdf, verbose)
+		decoder := extractFontDecoder(fontObjNum, fontName, pdf, profile, verbose)
 		if decoder != nil {
 			decoders["/"+fontName] = decoder
 		}
@@ -411,8 +417,9 @@ func extractFontDecoders(resourcesStr string, pdf *parse.PDF, verbose bool) map[
 	return decoders
 }
 
-// extractFontDecoder creates a FontDecoder for a font object
-func extractFontDecoder(fontObjNum int, fontName string, pdf *parse.PDF, verbose bool) *FontDecoder {
+// extractFontDecoder creates a FontDecoder for a font object. The fast profile
+// skips the ToUnicode CMap; the accurate profile also loads glyph widths.
+func extractFontDecoder(fontObjNum int, fontName string, pdf *parse.PDF, profile ExtractProfile, verbose bool) *FontDecoder {
 	fontObj, err := pdf.GetObject(fontObjNum)
 	if err != nil {
 		if verbose {
@@ -474,7 +481,7 @@ func extractFontDecoder(fontObjNum int, fontName string, pdf *parse.PDF, verbose
 
 	// Extract ToUnicode CMap (highest priority for text decoding)
 	toUnicodeRef := extractDictValue(fontStr, "/ToUnicode")
-	if toUnicodeRef != "" {
+	if toUnicodeRef != "" && profile != ProfileFast {
 		toUnicodeObjNum, err := parseObjectRef(toUnicodeRef)
 		if err == nil {
 			cmapData := extractStreamData(toUnicodeObjNum, pdf, verbose)
@@ -487,6 +494,10 @@ func extractFontDecoder(fontObjNum int, fontName string, pdf *parse.PDF, verbose
 		}
 	}
 
+	if profile == ProfileAccurate {
+		extractFontWidths(decoder, fontStr, pdf)
+	}
+
 	// For Type0 (CID) fonts, extract the descendant font's encoding
 	subtype := extractDictValue(fontStr, "/Subtype")
 	if subtype == "/Type0" {
@@ -513,6 +524,38 @@ func extractFontDecoder(fontObjNum int, fontName string, pdf *parse.PDF, verbose
 	return decoder
 }
 
+// extractFontWidths loads a simple font's glyph widths from /FirstChar and
+// /Widths. Standard 14 fonts without /Widths use the standard metrics.
+func extractFontWidths(decoder *FontDecoder, fontStr string, pdf *parse.PDF) {
+	widthsStr := extractDictValue(fontStr, "/Widths")
+	if widthsStr != "" && !strings.HasPrefix(widthsStr, "[") {
+		// Indirect array: "/Widths 12 0 R" yields the object number
+		widthsStr = ""
+		if objNum, err := parseObjectRef(extractDictValue(fontStr, "/Widths")); err == nil {
+			if obj, err := pdf.GetObject(objNum); err == nil {
+				widthsStr = string(obj)
+			}
+		}
+	}
+	if start, end := strings.Index(widthsStr, "["), strings.LastIndex(widthsStr, "]"); start != -1 && end > start {
+		firstChar, _ := strconv.Atoi(extractDictValue(fontStr, "/FirstChar"))
+		var widths []float64
+		for _, field := range strings.Fields(widthsStr[start+1 : end]) {
+			w, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return
+			}
+			widths = append(widths, w)
+		}
+		decoder.SetWidths(firstChar, widths)
+		return
+	}
+
+	if baseFont := extractDictValue(fontStr, "/BaseFont"); isStandard14Font(baseFont) {
+		decoder.standardFont = strings.TrimPrefix(baseFont, "/")
+	}
+}
+
 // extractDifferencesArray extracts the Differences array from an encoding dictionary or font
 func extractDifferencesArray(str string) string {
 	// Find /Differences array
@@ -586,9 +629,11 @@ func extractStreamData(objNum int, pdf *parse.PDF, verbose bool) string {
 
 	// Decompress if needed
 	if isCompressed {
-		decompressed, err := parse.DecodeFlateDecode(streamData)
+		buf := parse.AcquireBuffer()
+		defer parse.ReleaseBuffer(buf)
+		err := parse.DecodeFlateDecodeTo(buf, streamData)
 		if err == nil {
-			return string(decompressed)
+			return buf.String()
 		}
 		if verbose {
 			fmt.Printf("Warning: failed to decompress stream %d: %v\n", objNum, err)
@@ -0,0 +1,133 @@
+package extract
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// maxFieldDepth bounds the walk of the AcroForm field tree
+const maxFieldDepth = 32
+
+// ExtractSignatures returns the signed signature fields of the AcroForm, in
+// field tree order. Each signature's byte range is checked against the file:
+// it must cover everything except the /Contents string, and its bytes are
+// hashed so that versions of a document can be compared. The CMS signature
+// value itself is not verified.
+func ExtractSignatures(pdfBytes []byte, pdf *parse.PDF, verbose bool) ([]types.Signature, error) {
+	signatures := []types.Signature{}
+
+	trailer := pdf.Trailer()
+	if trailer == nil || trailer.RootRef == "" {
+		return signatures, nil
+	}
+
+	// The link resolver's deref follows references for any object
+	r := &linkResolver{pdf: pdf, verbose: verbose}
+	acroForm := r.deref(dictEntry(r.deref(trailer.RootRef), "/AcroForm"))
+	if acroForm == "" {
+		return signatures, nil
+	}
+
+	raw := pdf.Raw()
+	var walk func(field, parentName, fieldType string, depth int)
+	walk = func(field, parentName, fieldType string, depth int) {
+		if !strings.HasPrefix(field, "<<") || depth > maxFieldDepth {
+			return
+		}
+		name := parentName
+		if partial := pdfString(dictEntry(field, "/T")); partial != "" {
+			if name != "" {
+				name += "."
+			}
+			name += partial
+		}
+		if ft := dictEntry(field, "/FT"); ft != "" {
+			fieldType = ft
+		}
+
+		if fieldType == "/Sig" {
+			if value := r.deref(dictEntry(field, "/V")); strings.HasPrefix(value, "<<") {
+				signatures = append(signatures, signatureInfo(r, name, value, raw))
+				return
+			}
+		}
+		for _, kid := range parseObjectRefArray(dictEntry(field, "/Kids")) {
+			walk(r.deref(kid), name, fieldType, depth+1)
+		}
+	}
+	for _, field := range parseObjectRefArray(r.deref(dictEntry(acroForm, "/Fields"))) {
+		walk(r.deref(field), "", "", 0)
+	}
+
+	return signatures, nil
+}
+
+// signatureInfo describes a signature dictionary and checks its byte range
+// against the raw file
+func signatureInfo(r *linkResolver, fieldName, sig string, raw []byte) types.Signature {
+	signature := types.Signature{
+		FieldName:   fieldName,
+		Signer:      pdfString(dictEntry(sig, "/Name")),
+		Reason:      pdfString(dictEntry(sig, "/Reason")),
+		Location:    pdfString(dictEntry(sig, "/Location")),
+		SigningTime: pdfString(dictEntry(sig, "/M")),
+		SubFilter:   pdfString(dictEntry(sig, "/SubFilter")),
+	}
+	for _, item := range arrayItems(dictEntry(sig, "/ByteRange")) {
+		n, err := strconv.Atoi(item)
+		if err != nil {
+			signature.ByteRange = nil
+			break
+		}
+		signature.ByteRange = append(signature.ByteRange, n)
+	}
+
+	// A certification signature has a DocMDP signature reference, whose
+	// permissions default to 2
+	for _, ref := range arrayItems(dictEntry(sig, "/Reference")) {
+		ref = r.deref(ref)
+		if dictEntry(ref, "/TransformMethod") != "/DocMDP" {
+			continue
+		}
+		signature.DocMDP = 2
+		if p, err := strconv.Atoi(dictEntry(r.deref(dictEntry(ref, "/TransformParams")), "/P")); err == nil && p >= 1 && p <= 3 {
+			signature.DocMDP = p
+		}
+	}
+
+	contents := []byte(dictEntry(sig, "/Contents"))
+	br := signature.ByteRange
+	if len(br) == 4 && br[0] == 0 && br[1] > 0 && br[1] < br[2] && br[3] >= 0 && br[2]+br[3] <= len(raw) {
+		gap := raw[br[1]:br[2]]
+		if len(gap) >= 2 && gap[0] == '<' && gap[len(gap)-1] == '>' && isHex(gap[1:len(gap)-1]) {
+			signature.Intact = true
+			contents = gap
+		}
+		h := sha256.New()
+		h.Write(raw[:br[1]])
+		h.Write(raw[br[2] : br[2]+br[3]])
+		signature.SignedHash = hex.EncodeToString(h.Sum(nil))
+		signature.CoversDocument = len(bytes.TrimRight(raw[br[2]+br[3]:], " \t\r\n\f\x00")) == 0
+	}
+	if len(contents) > 0 {
+		sum := sha256.Sum256(contents)
+		signature.ContentsHash = hex.EncodeToString(sum[:])
+	}
+	return signature
+}
+
+// isHex reports whether b holds only hex digits and whitespace
+func isHex(b []byte) bool {
+	for _, c := range b {
+		if !strings.ContainsRune("0123456789abcdefABCDEF \t\r\n\f", rune(c)) {
+			return false
+		}
+	}
+	return true
+}
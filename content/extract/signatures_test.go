@@ -0,0 +1,51 @@
+package extract
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+func TestExtractSignatures(t *testing.T) {
+	pdfBytes, err := CreateTestSignedPDF("Original", "3082abcd")
+	if err != nil {
+		t.Fatalf("Failed to create signed PDF: %v", err)
+	}
+
+	extract := func(pdfBytes []byte) []types.Signature {
+		t.Helper()
+		pdf, err := ParseTestPDF(pdfBytes)
+		if err != nil {
+			t.Fatalf("Failed to parse PDF: %v", err)
+		}
+		signatures, err := ExtractSignatures(pdfBytes, pdf, false)
+		if err != nil {
+			t.Fatalf("ExtractSignatures failed: %v", err)
+		}
+		if len(signatures) != 1 {
+			t.Fatalf("Expected 1 signature, got %d: %+v", len(signatures), signatures)
+		}
+		return signatures
+	}
+
+	sig := extract(pdfBytes)[0]
+	if sig.FieldName != "Approval" || sig.Signer != "Jane Doe" || sig.Reason != "Approved" || sig.SigningTime != "D:20260101120000Z" || sig.SubFilter != "adbe.pkcs7.detached" {
+		t.Errorf("Unexpected signature properties: %+v", sig)
+	}
+	if !sig.Intact || !sig.CoversDocument || len(sig.ByteRange) != 4 || sig.SignedHash == "" || sig.ContentsHash == "" {
+		t.Errorf("Expected an intact signature covering the document: %+v", sig)
+	}
+
+	// Bytes appended after signing are not covered
+	appended := extract(append(append([]byte{}, pdfBytes...), "\n% update\n"...))[0]
+	if !appended.Intact || appended.CoversDocument || appended.SignedHash != sig.SignedHash {
+		t.Errorf("Expected an intact signature that no longer covers the document: %+v", appended)
+	}
+
+	// Tampering with the signed bytes changes their hash
+	tampered := extract(bytes.Replace(pdfBytes, []byte("(Original)"), []byte("(Tampered)"), 1))[0]
+	if !tampered.Intact || tampered.SignedHash == sig.SignedHash || tampered.ContentsHash != sig.ContentsHash {
+		t.Errorf("Expected the signed hash to change: %+v", tampered)
+	}
+}
@@ -0,0 +1,202 @@
+package extract
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// inkGridSize is the number of cells along each page axis used to estimate ink coverage
+const inkGridSize = 100
+
+// vectorOperators are the path construction and painting operators counted by PageStats
+var vectorOperators = map[string]bool{
+	"m": true, "l": true, "c": true, "v": true, "y": true, "h": true, "re": true,
+	"S": true, "s": true, "f": true, "F": true, "f*": true,
+	"B": true, "B*": true, "b": true, "b*": true, "n": true,
+}
+
+// PageStats returns content statistics for every page: text elements, words, images,
+// vector operators, fonts used, an ink coverage estimate and content stream size
+func PageStats(pdfBytes []byte, pdf *parse.PDF, verbose bool) ([]types.PageStats, error) {
+	var stats []types.PageStats
+
+	it := pdf.Pages()
+	for it.Next() {
+		ref := it.Page()
+		page, err := extractPage(pdfBytes, pdf, ref, ExtractOptions{Verbose: verbose})
+		if err != nil {
+			if verbose {
+				fmt.Printf("Warning: failed to extract page %d: %v\n", ref.Number, err)
+			}
+			continue
+		}
+		page.PageNumber = ref.Number
+		stats = append(stats, pageStats(&page, pageContentStreams(pdf, ref.Dict, verbose)))
+	}
+	if err := it.Err(); err != nil {
+		return nil, fmt.Errorf("failed to walk pages tree: %w", err)
+	}
+
+	return stats, nil
+}
+
+// pageStats computes the statistics of an extracted page and its decoded content streams
+func pageStats(page *types.Page, contents []string) types.PageStats {
+	stats := types.PageStats{
+		PageNumber:   page.PageNumber,
+		TextElements: len(page.Text),
+	}
+
+	fonts := make(map[string]bool)
+	for _, t := range page.Text {
+		stats.Words += len(strings.Fields(t.Text))
+		if t.FontName == "" {
+			continue
+		}
+		name := t.FontName
+		if page.Resources != nil {
+			if font, ok := page.Resources.Fonts[strings.TrimPrefix(name, "/")]; ok && font.Name != "" {
+				name = font.Name
+			}
+		}
+		fonts[strings.TrimPrefix(name, "/")] = true
+	}
+	for name := range fonts {
+		stats.Fonts = append(stats.Fonts, name)
+	}
+	sort.Strings(stats.Fonts)
+
+	for _, ref := range page.Images {
+		// Do also draws form XObjects; count only images when the resources say which is which
+		if page.Resources != nil && len(page.Resources.XObjects) > 0 {
+			if _, ok := page.Resources.Images[strings.TrimPrefix(ref.ImageID, "/")]; !ok {
+				continue
+			}
+		}
+		stats.Images++
+	}
+
+	for _, content := range contents {
+		stats.ContentBytes += len(content)
+		stats.VectorOps += countVectorOperators(content)
+	}
+
+	stats.InkCoverage = inkCoverage(page)
+	return stats
+}
+
+// countVectorOperators counts path construction and painting operators in a content
+// stream, skipping strings, comments and inline image data
+func countVectorOperators(content string) int {
+	count := 0
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			depth := 0
+			for ; i < len(content); i++ {
+				if content[i] == '\\' {
+					i++
+				} else if content[i] == '(' {
+					depth++
+				} else if content[i] == ')' {
+					depth--
+					if depth == 0 {
+						i++
+						break
+					}
+				}
+			}
+		case c == '<' && i+1 < len(content) && content[i+1] != '<':
+			if end := strings.IndexByte(content[i:], '>'); end != -1 {
+				i += end + 1
+			} else {
+				i = len(content)
+			}
+		case strings.IndexByte(" \t\r\n\f\x00)[]{}<>/", c) != -1:
+			// Delimiters and whitespace; a name is skipped whole
+			i++
+			if c == '/' {
+				for i < len(content) && strings.IndexByte(" \t\r\n\f\x00()[]{}<>/%", content[i]) == -1 {
+					i++
+				}
+			}
+		default:
+			start := i
+			for i < len(content) && strings.IndexByte(" \t\r\n\f\x00()[]{}<>/%", content[i]) == -1 {
+				i++
+			}
+			token := content[start:i]
+			if vectorOperators[token] {
+				count++
+			} else if token == "BI" {
+				// Inline image: skip binary data through the EI operator
+				if end := strings.Index(content[i:], "EI"); end != -1 {
+					i += end + 2
+				} else {
+					i = len(content)
+				}
+			}
+		}
+	}
+	return count
+}
+
+// inkCoverage estimates the fraction of the page covered by text, images and graphics.
+// Bounding boxes are rasterized onto a coarse grid so overlapping content counts once.
+func inkCoverage(page *types.Page) float64 {
+	box := page.MediaBox
+	if box == nil || page.Width <= 0 || page.Height <= 0 {
+		return 0
+	}
+
+	var grid [inkGridSize][inkGridSize]bool
+	covered := 0
+	mark := func(x0, y0, x1, y1 float64) {
+		if x0 > x1 {
+			x0, x1 = x1, x0
+		}
+		if y0 > y1 {
+			y0, y1 = y1, y0
+		}
+		cx0 := int(math.Floor((x0 - box.LowerX) / page.Width * inkGridSize))
+		cx1 := int(math.Ceil((x1 - box.LowerX) / page.Width * inkGridSize))
+		cy0 := int(math.Floor((y0 - box.LowerY) / page.Height * inkGridSize))
+		cy1 := int(math.Ceil((y1 - box.LowerY) / page.Height * inkGridSize))
+		for x := max(cx0, 0); x < min(cx1, inkGridSize); x++ {
+			for y := max(cy0, 0); y < min(cy1, inkGridSize); y++ {
+				if !grid[x][y] {
+					grid[x][y] = true
+					covered++
+				}
+			}
+		}
+	}
+
+	for _, t := range page.Text {
+		if r := t.BoundingBox; r != nil {
+			mark(r.LowerX, r.LowerY, r.UpperX, r.UpperY)
+		} else if t.Width > 0 {
+			mark(t.X, t.Y, t.X+t.Width, t.Y+t.Height)
+		}
+	}
+	for _, img := range page.Images {
+		mark(img.X, img.Y, img.X+img.Width, img.Y+img.Height)
+	}
+	for _, g := range page.Graphics {
+		if r := g.BoundingBox; r != nil {
+			mark(r.LowerX, r.LowerY, r.UpperX, r.UpperY)
+		}
+	}
+
+	return float64(covered) / (inkGridSize * inkGridSize)
+}
@@ -0,0 +1,76 @@
+package extract
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+)
+
+func TestPageStats(t *testing.T) {
+	builder := write.NewSimplePDFBuilder()
+
+	page := builder.AddPage(write.PageSizeLetter)
+	font := page.AddStandardFont("Helvetica")
+	page.Content().
+		BeginText().
+		SetFont(font, 12).
+		SetTextPosition(72, 720).
+		ShowText("Quarterly draft report").
+		EndText().
+		Rectangle(0, 0, 306, 792).
+		Fill().
+		MoveTo(0, 0).
+		LineTo(612, 792).
+		Stroke()
+	builder.FinalizePage(page)
+
+	empty := builder.AddPage(write.PageSizeLetter)
+	builder.FinalizePage(empty)
+
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+	pdf, err := ParseTestPDF(pdfBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse PDF: %v", err)
+	}
+
+	stats, err := PageStats(pdfBytes, pdf, false)
+	if err != nil {
+		t.Fatalf("PageStats failed: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("Expected 2 pages, got %d", len(stats))
+	}
+
+	first := stats[0]
+	if first.PageNumber != 1 || first.TextElements != 1 || first.Words != 3 {
+		t.Errorf("Unexpected text stats: %+v", first)
+	}
+	// re, f, m, l, S
+	if first.VectorOps != 5 {
+		t.Errorf("Expected 5 vector operators, got %d", first.VectorOps)
+	}
+	if !reflect.DeepEqual(first.Fonts, []string{"Helvetica"}) {
+		t.Errorf("Expected fonts [Helvetica], got %v", first.Fonts)
+	}
+	if first.InkCoverage < 0.5 || first.InkCoverage > 1 {
+		t.Errorf("Expected at least half the page covered, got %.2f", first.InkCoverage)
+	}
+	if first.ContentBytes == 0 {
+		t.Error("Expected content stream size")
+	}
+
+	if second := stats[1]; second.PageNumber != 2 || second.TextElements != 0 || second.VectorOps != 0 || second.InkCoverage != 0 {
+		t.Errorf("Expected empty second page, got %+v", second)
+	}
+}
+
+func TestCountVectorOperators(t *testing.T) {
+	content := "% comment with m l\n(string re f) Tj <6d6c> Tj /f gs\n0 0 m 10 10 l h S\nBI /W 1 /H 1 ID \x00re\x00 EI\n1 2 3 4 re f*"
+	if got := countVectorOperators(content); got != 6 {
+		t.Errorf("countVectorOperators = %d, want 6", got)
+	}
+}
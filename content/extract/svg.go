@@ -0,0 +1,732 @@
+package extract
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/benedoc-inc/pdfer/core/encrypt"
+	"github.com/benedoc-inc/pdfer/core/parse"
+)
+
+// maxSVGFormDepth limits how deeply form XObjects drawn by other forms are followed
+const maxSVGFormDepth = 8
+
+// PageToSVG converts the vector graphics and text of a page (1-based) into an
+// SVG document the size of its crop box, for previews of diagrams and charts
+// without rasterizing the page. Paths keep their fill rule, stroke style, dash
+// pattern and clipping, form XObjects are drawn inline, and text is written as
+// SVG text in a generic font family matching the PDF font. JPEG images are
+// embedded; other images, shadings, patterns and transparency are left out.
+func PageToSVG(pdf *parse.PDF, pageNumber int, verbose bool) (string, error) {
+	ref, err := pdf.Page(pageNumber)
+	if err != nil {
+		return "", fmt.Errorf("failed to get page %d: %w", pageNumber, err)
+	}
+
+	box := []float64{0, 0, 612, 792}
+	if len(ref.MediaBox) >= 4 {
+		box = ref.MediaBox
+	}
+	if len(ref.CropBox) >= 4 {
+		box = ref.CropBox
+	}
+	llx, lly := math.Min(box[0], box[2]), math.Min(box[1], box[3])
+	urx, ury := math.Max(box[0], box[2]), math.Max(box[1], box[3])
+	width, height := urx-llx, ury-lly
+
+	r := &svgRenderer{pdf: pdf, verbose: verbose}
+	r.out.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	// The page is rotated clockwise on display
+	pageWidth, pageHeight := width, height
+	view := fmt.Sprintf("matrix(1 0 0 -1 %s %s)", svgNumber(-llx), svgNumber(ury))
+	switch ((ref.Rotate % 360) + 360) % 360 {
+	case 90:
+		pageWidth, pageHeight = height, width
+		view = fmt.Sprintf("matrix(0 1 1 0 %s %s)", svgNumber(-lly), svgNumber(-llx))
+	case 180:
+		view = fmt.Sprintf("matrix(-1 0 0 1 %s %s)", svgNumber(urx), svgNumber(-lly))
+	case 270:
+		pageWidth, pageHeight = height, width
+		view = fmt.Sprintf("matrix(0 -1 -1 0 %s %s)", svgNumber(ury), svgNumber(urx))
+	}
+	fmt.Fprintf(&r.out, `<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" viewBox="0 0 %s %s">`+"\n",
+		svgNumber(pageWidth), svgNumber(pageHeight), svgNumber(pageWidth), svgNumber(pageHeight))
+	fmt.Fprintf(&r.out, `<g transform="%s">`+"\n", view)
+
+	for _, content := range pageContentStreams(pdf, ref.Dict, verbose) {
+		r.render(content, ref.Resources, newSVGState(), 0)
+	}
+
+	r.out.WriteString("</g>\n</svg>\n")
+	return r.out.String(), nil
+}
+
+// svgRenderer writes the SVG elements of content streams
+type svgRenderer struct {
+	pdf     *parse.PDF
+	verbose bool
+	out     strings.Builder
+	clips   int // Number of clip paths defined, for their IDs
+}
+
+// svgState is the part of the graphics state PageToSVG draws with
+type svgState struct {
+	ctm         [6]float64
+	fill        string
+	stroke      string
+	lineWidth   float64
+	lineCap     int
+	lineJoin    int
+	miterLimit  float64
+	dash        []float64
+	dashPhase   float64
+	font        *FontDecoder
+	fontFamily  string
+	fontSize    float64
+	charSpacing float64
+	wordSpacing float64
+	scale       float64 // Horizontal scaling, 1 for 100%
+	leading     float64
+	rise        float64
+	renderMode  int
+	groups      int // Clipping groups opened in this state
+}
+
+// newSVGState returns the initial graphics state of a page
+func newSVGState() svgState {
+	return svgState{
+		ctm:        [6]float64{1, 0, 0, 1, 0, 0},
+		fill:       "#000000",
+		stroke:     "#000000",
+		lineWidth:  1,
+		miterLimit: 10,
+		scale:      1,
+	}
+}
+
+// render draws a content stream with its resources, starting from state gs.
+// The clipping groups of gs and those opened by the content are closed when
+// it ends.
+func (r *svgRenderer) render(content, resources string, gs svgState, depth int) {
+	fonts := extractFontDecoders(resources, r.pdf, ProfileAccurate, r.verbose)
+	families := make(map[string]string)
+	for name, info := range extractFontsDict(resources, r.pdf, r.verbose) {
+		families["/"+strings.TrimPrefix(name, "/")] = svgFontFamily(info.Name)
+	}
+	var xobjects map[string]int
+	loadXObjects := func() map[string]int {
+		if xobjects == nil {
+			_, xobjects = extractXObjectsDictWithObjNums(resources, r.pdf, r.verbose)
+		}
+		return xobjects
+	}
+
+	var stack []svgState
+	var path strings.Builder
+	var current, subpath [2]float64 // Current point and start of the subpath
+	var clip string                 // Fill rule of a W or W* waiting for the path to be painted
+	var tm, tlm [6]float64
+
+	lexer := &contentLexer{content: content}
+	var operands []contentOperand
+	for {
+		tok, ok := lexer.next()
+		if !ok {
+			break
+		}
+		if tok.kind != operandOperator {
+			operands = append(operands, tok)
+			continue
+		}
+		nums := operandNumbers(operands)
+		switch op := tok.text; op {
+		case "q":
+			stack = append(stack, gs)
+			gs.groups = 0
+		case "Q":
+			r.closeGroups(gs.groups)
+			if len(stack) > 0 {
+				gs = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case "cm":
+			if len(nums) == 6 {
+				gs.ctm = multiplyMatrix([6]float64(nums), gs.ctm)
+			}
+		case "w":
+			if len(nums) == 1 {
+				gs.lineWidth = nums[0]
+			}
+		case "J":
+			if len(nums) == 1 {
+				gs.lineCap = int(nums[0])
+			}
+		case "j":
+			if len(nums) == 1 {
+				gs.lineJoin = int(nums[0])
+			}
+		case "M":
+			if len(nums) == 1 {
+				gs.miterLimit = nums[0]
+			}
+		case "d":
+			if len(operands) == 2 && operands[0].kind == operandArray {
+				gs.dash = operandNumbers(operands[0].items)
+				gs.dashPhase = operands[1].num
+			}
+		case "g", "rg", "k", "sc", "scn":
+			if c, ok := svgColor(nums); ok {
+				gs.fill = c
+			}
+		case "G", "RG", "K", "SC", "SCN":
+			if c, ok := svgColor(nums); ok {
+				gs.stroke = c
+			}
+		case "cs":
+			gs.fill = "#000000"
+		case "CS":
+			gs.stroke = "#000000"
+
+		// Path construction
+		case "m", "l":
+			if len(nums) == 2 {
+				fmt.Fprintf(&path, "%s%s ", strings.ToUpper(op), svgNumbers(nums))
+				current = [2]float64{nums[0], nums[1]}
+				if op == "m" {
+					subpath = current
+				}
+			}
+		case "c":
+			if len(nums) == 6 {
+				path.WriteString("C" + svgNumbers(nums) + " ")
+				current = [2]float64{nums[4], nums[5]}
+			}
+		case "v":
+			// The first control point is the current point
+			if len(nums) == 4 {
+				path.WriteString("C" + svgNumbers(current[:]) + " " + svgNumbers(nums) + " ")
+				current = [2]float64{nums[2], nums[3]}
+			}
+		case "y":
+			// The second control point is the end point
+			if len(nums) == 4 {
+				path.WriteString("C" + svgNumbers(nums) + " " + svgNumbers(nums[2:]) + " ")
+				current = [2]float64{nums[2], nums[3]}
+			}
+		case "h":
+			path.WriteString("Z ")
+			current = subpath
+		case "re":
+			if len(nums) == 4 {
+				fmt.Fprintf(&path, "M%s %s h%s v%s h%s Z ", svgNumber(nums[0]), svgNumber(nums[1]),
+					svgNumber(nums[2]), svgNumber(nums[3]), svgNumber(-nums[2]))
+				current = [2]float64{nums[0], nums[1]}
+				subpath = current
+			}
+		case "W":
+			clip = "nonzero"
+		case "W*":
+			clip = "evenodd"
+
+		// Path painting
+		case "S", "s", "f", "F", "f*", "B", "B*", "b", "b*", "n":
+			d := strings.TrimSpace(path.String())
+			if op == "s" || op == "b" || op == "b*" {
+				d += " Z"
+			}
+			if d != "" && op != "n" {
+				fill := strings.ContainsAny(op, "fFBb")
+				stroke := strings.ContainsAny(op, "SsBb")
+				r.writePath(d, gs, fill, stroke, strings.HasSuffix(op, "*"))
+			}
+			if clip != "" && d != "" {
+				r.clips++
+				fmt.Fprintf(&r.out, `<clipPath id="clip%d"><path d="%s" clip-rule="%s"%s/></clipPath>`+"\n",
+					r.clips, d, clip, svgTransform(gs.ctm))
+				fmt.Fprintf(&r.out, `<g clip-path="url(#clip%d)">`+"\n", r.clips)
+				gs.groups++
+			}
+			path.Reset()
+			clip = ""
+
+		// Text
+		case "BT":
+			tm, tlm = [6]float64{1, 0, 0, 1, 0, 0}, [6]float64{1, 0, 0, 1, 0, 0}
+		case "Tf":
+			if len(operands) == 2 && operands[0].kind == operandName {
+				gs.font = fonts[operands[0].text]
+				gs.fontFamily = families[operands[0].text]
+				gs.fontSize = operands[1].num
+			}
+		case "Tc":
+			if len(nums) == 1 {
+				gs.charSpacing = nums[0]
+			}
+		case "Tw":
+			if len(nums) == 1 {
+				gs.wordSpacing = nums[0]
+			}
+		case "Tz":
+			if len(nums) == 1 {
+				gs.scale = nums[0] / 100
+			}
+		case "TL":
+			if len(nums) == 1 {
+				gs.leading = nums[0]
+			}
+		case "Ts":
+			if len(nums) == 1 {
+				gs.rise = nums[0]
+			}
+		case "Tr":
+			if len(nums) == 1 {
+				gs.renderMode = int(nums[0])
+			}
+		case "Td", "TD":
+			if len(nums) == 2 {
+				if op == "TD" {
+					gs.leading = -nums[1]
+				}
+				tlm = multiplyMatrix([6]float64{1, 0, 0, 1, nums[0], nums[1]}, tlm)
+				tm = tlm
+			}
+		case "Tm":
+			if len(nums) == 6 {
+				tlm = [6]float64(nums)
+				tm = tlm
+			}
+		case "T*":
+			tlm = multiplyMatrix([6]float64{1, 0, 0, 1, 0, -gs.leading}, tlm)
+			tm = tlm
+		case "Tj", "'", "\"":
+			if op != "Tj" {
+				if op == "\"" && len(operands) == 3 {
+					gs.wordSpacing, gs.charSpacing = operands[0].num, operands[1].num
+				}
+				tlm = multiplyMatrix([6]float64{1, 0, 0, 1, 0, -gs.leading}, tlm)
+				tm = tlm
+			}
+			if len(operands) > 0 && operands[len(operands)-1].kind == operandString {
+				r.writeText(operands[len(operands)-1], gs, &tm)
+			}
+		case "TJ":
+			if len(operands) == 1 && operands[0].kind == operandArray {
+				for _, item := range operands[0].items {
+					switch item.kind {
+					case operandString:
+						r.writeText(item, gs, &tm)
+					case operandNumber:
+						tm = multiplyMatrix([6]float64{1, 0, 0, 1, -item.num / 1000 * gs.fontSize * gs.scale, 0}, tm)
+					}
+				}
+			}
+
+		// XObjects
+		case "Do":
+			if len(operands) == 1 && operands[0].kind == operandName {
+				if objNum, ok := loadXObjects()[strings.TrimPrefix(operands[0].text, "/")]; ok {
+					r.drawXObject(objNum, resources, gs, depth)
+				}
+			}
+		case "BI":
+			lexer.skipInlineImage()
+		}
+		operands = operands[:0]
+	}
+
+	// Content may leave states saved or clipping groups open
+	for len(stack) > 0 {
+		r.closeGroups(gs.groups)
+		gs = stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+	}
+	r.closeGroups(gs.groups)
+}
+
+// closeGroups closes n clipping groups
+func (r *svgRenderer) closeGroups(n int) {
+	for i := 0; i < n; i++ {
+		r.out.WriteString("</g>\n")
+	}
+}
+
+// writePath writes a painted path
+func (r *svgRenderer) writePath(d string, gs svgState, fill, stroke, evenOdd bool) {
+	fmt.Fprintf(&r.out, `<path d="%s"`, d)
+	if fill {
+		fmt.Fprintf(&r.out, ` fill="%s"`, gs.fill)
+		if evenOdd {
+			r.out.WriteString(` fill-rule="evenodd"`)
+		}
+	} else {
+		r.out.WriteString(` fill="none"`)
+	}
+	if stroke {
+		r.writeStroke(gs)
+	}
+	r.out.WriteString(svgTransform(gs.ctm) + "/>\n")
+}
+
+// writeStroke writes the stroke attributes of the graphics state
+func (r *svgRenderer) writeStroke(gs svgState) {
+	fmt.Fprintf(&r.out, ` stroke="%s"`, gs.stroke)
+	if gs.lineWidth <= 0 {
+		// A zero width is the thinnest line the device can draw
+		r.out.WriteString(` stroke-width="1" vector-effect="non-scaling-stroke"`)
+	} else if gs.lineWidth != 1 {
+		fmt.Fprintf(&r.out, ` stroke-width="%s"`, svgNumber(gs.lineWidth))
+	}
+	if gs.lineCap == 1 {
+		r.out.WriteString(` stroke-linecap="round"`)
+	} else if gs.lineCap == 2 {
+		r.out.WriteString(` stroke-linecap="square"`)
+	}
+	if gs.lineJoin == 1 {
+		r.out.WriteString(` stroke-linejoin="round"`)
+	} else if gs.lineJoin == 2 {
+		r.out.WriteString(` stroke-linejoin="bevel"`)
+	}
+	if gs.miterLimit != 4 && gs.lineJoin == 0 {
+		fmt.Fprintf(&r.out, ` stroke-miterlimit="%s"`, svgNumber(math.Max(gs.miterLimit, 1)))
+	}
+	if len(gs.dash) > 0 {
+		fmt.Fprintf(&r.out, ` stroke-dasharray="%s"`, strings.ReplaceAll(svgNumbers(gs.dash), " ", ","))
+		if gs.dashPhase != 0 {
+			fmt.Fprintf(&r.out, ` stroke-dashoffset="%s"`, svgNumber(gs.dashPhase))
+		}
+	}
+}
+
+// writeText writes a shown string as a text element and advances the text matrix
+func (r *svgRenderer) writeText(str contentOperand, gs svgState, tm *[6]float64) {
+	text := decodeTextWithFont(str.text, gs.font, str.hex)
+	if !utf8.ValidString(text) {
+		text = decodeTextString([]byte(text))
+	}
+
+	// Glyph space is flipped so that text reads upright in the flipped page
+	trm := multiplyMatrix([6]float64{gs.fontSize * gs.scale, 0, 0, gs.fontSize, 0, gs.rise}, multiplyMatrix(*tm, gs.ctm))
+	if mode := gs.renderMode % 4; mode != 3 && strings.TrimSpace(text) != "" {
+		fmt.Fprintf(&r.out, `<text transform="%s" font-size="1"`, svgMatrix(multiplyMatrix([6]float64{1, 0, 0, -1, 0, 0}, trm)))
+		if gs.fontFamily != "" {
+			fmt.Fprintf(&r.out, ` font-family="%s"`, gs.fontFamily)
+		}
+		if mode == 1 {
+			r.out.WriteString(` fill="none"`)
+		} else {
+			fmt.Fprintf(&r.out, ` fill="%s"`, gs.fill)
+		}
+		if mode == 1 || mode == 2 {
+			fmt.Fprintf(&r.out, ` stroke="%s" stroke-width="%s"`, gs.stroke, svgNumber(gs.lineWidth/math.Max(gs.fontSize, 1)))
+		}
+		fmt.Fprintf(&r.out, ` xml:space="preserve">%s</text>`+"\n", svgEscape(text))
+	}
+
+	width, ok := 0.0, false
+	if gs.font != nil {
+		width, ok = gs.font.TextWidth(text, gs.fontSize)
+	}
+	if !ok {
+		width = float64(utf8.RuneCountInString(text)) * gs.fontSize * 0.5
+	}
+	advance := (width + gs.charSpacing*float64(utf8.RuneCountInString(text)) + gs.wordSpacing*float64(strings.Count(text, " "))) * gs.scale
+	*tm = multiplyMatrix([6]float64{1, 0, 0, 1, advance, 0}, *tm)
+}
+
+// drawXObject draws a form XObject inline or embeds a JPEG image
+func (r *svgRenderer) drawXObject(objNum int, resources string, gs svgState, depth int) {
+	obj, err := r.pdf.GetObject(objNum)
+	if err != nil {
+		if r.verbose {
+			fmt.Printf("Warning: failed to get XObject %d: %v\n", objNum, err)
+		}
+		return
+	}
+	objStr := string(obj)
+	dict := objStr
+	if i := strings.Index(objStr, "stream"); i != -1 {
+		dict = objStr[:i]
+	}
+	dict = objectContent(dict)
+
+	switch extractDictValue(dict, "/Subtype") {
+	case "/Form":
+		if depth >= maxSVGFormDepth {
+			return
+		}
+		if matrix := extractArrayValue(dict, "/Matrix"); len(matrix) == 6 {
+			gs.ctm = multiplyMatrix([6]float64(matrix), gs.ctm)
+		}
+		// Forms without resources use those of the content drawing them
+		resolver := &linkResolver{pdf: r.pdf, verbose: r.verbose}
+		if formResources := resolver.deref(dictEntry(dict, "/Resources")); formResources != "" {
+			resources = formResources
+		}
+		gs.groups = 0
+		if bbox := extractArrayValue(dict, "/BBox"); len(bbox) == 4 {
+			r.clips++
+			fmt.Fprintf(&r.out, `<clipPath id="clip%d"><rect x="%s" y="%s" width="%s" height="%s"%s/></clipPath>`+"\n",
+				r.clips, svgNumber(math.Min(bbox[0], bbox[2])), svgNumber(math.Min(bbox[1], bbox[3])),
+				svgNumber(math.Abs(bbox[2]-bbox[0])), svgNumber(math.Abs(bbox[3]-bbox[1])), svgTransform(gs.ctm))
+			fmt.Fprintf(&r.out, `<g clip-path="url(#clip%d)">`+"\n", r.clips)
+			gs.groups = 1
+		}
+		r.render(decodeContentStream(obj), resources, gs, depth+1)
+	case "/Image":
+		image, err := extractImageData(objNum, r.pdf, r.verbose)
+		if err != nil || image.Format != "jpeg" || !isSingleDCT(image.Filter) {
+			return
+		}
+		// Images fill the unit square; SVG draws them top down
+		m := multiplyMatrix([6]float64{1, 0, 0, -1, 0, 1}, gs.ctm)
+		fmt.Fprintf(&r.out, `<image width="1" height="1" preserveAspectRatio="none" transform="%s" href="data:image/jpeg;base64,%s"/>`+"\n",
+			svgMatrix(m), base64.StdEncoding.EncodeToString(image.Data))
+	}
+}
+
+// isSingleDCT reports whether a /Filter value is DCTDecode alone
+func isSingleDCT(filter string) bool {
+	return strings.Join(strings.Fields(strings.Trim(filter, "[]")), " ") == "/DCTDecode"
+}
+
+// multiplyMatrix returns the matrix applying a, then b
+func multiplyMatrix(a, b [6]float64) [6]float64 {
+	return [6]float64{
+		a[0]*b[0] + a[1]*b[2],
+		a[0]*b[1] + a[1]*b[3],
+		a[2]*b[0] + a[3]*b[2],
+		a[2]*b[1] + a[3]*b[3],
+		a[4]*b[0] + a[5]*b[2] + b[4],
+		a[4]*b[1] + a[5]*b[3] + b[5],
+	}
+}
+
+// svgColor converts the operands of a color operator to an SVG color: one
+// operand is gray, three RGB and four CMYK. Pattern names have no numbers.
+func svgColor(nums []float64) (string, bool) {
+	var r, g, b float64
+	switch len(nums) {
+	case 1:
+		r, g, b = nums[0], nums[0], nums[0]
+	case 3:
+		r, g, b = nums[0], nums[1], nums[2]
+	case 4:
+		k := nums[3]
+		r, g, b = (1-nums[0])*(1-k), (1-nums[1])*(1-k), (1-nums[2])*(1-k)
+	default:
+		return "", false
+	}
+	channel := func(v float64) int {
+		return int(math.Round(math.Max(0, math.Min(1, v)) * 255))
+	}
+	return fmt.Sprintf("#%02x%02x%02x", channel(r), channel(g), channel(b)), true
+}
+
+// svgFontFamily returns the generic SVG font family closest to a PDF font name
+func svgFontFamily(baseFont string) string {
+	name := strings.ToLower(baseFont)
+	switch {
+	case strings.Contains(name, "courier"), strings.Contains(name, "mono"), strings.Contains(name, "consol"):
+		return "monospace"
+	case strings.Contains(name, "times"), strings.Contains(name, "serif") && !strings.Contains(name, "sans"),
+		strings.Contains(name, "georgia"), strings.Contains(name, "garamond"):
+		return "serif"
+	case strings.Contains(name, "symbol"), strings.Contains(name, "dingbats"):
+		return ""
+	}
+	return "sans-serif"
+}
+
+// svgTransform returns the transform attribute of a matrix, or nothing for the identity
+func svgTransform(m [6]float64) string {
+	if m == [6]float64{1, 0, 0, 1, 0, 0} {
+		return ""
+	}
+	return fmt.Sprintf(` transform="%s"`, svgMatrix(m))
+}
+
+// svgMatrix formats a matrix as an SVG transform function
+func svgMatrix(m [6]float64) string {
+	return "matrix(" + svgNumbers(m[:]) + ")"
+}
+
+// svgNumbers formats numbers separated by spaces
+func svgNumbers(values []float64) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = svgNumber(v)
+	}
+	return strings.Join(parts, " ")
+}
+
+// svgNumber formats a number with at most four decimals
+func svgNumber(v float64) string {
+	s := strconv.FormatFloat(math.Round(v*10000)/10000, 'f', -1, 64)
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// svgEscape escapes text for SVG character data
+func svgEscape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(strings.Map(func(r rune) rune {
+		// Control characters are not allowed in XML
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' {
+			return -1
+		}
+		return r
+	}, text))
+}
+
+// Kinds of content stream operands
+const (
+	operandNumber = iota
+	operandName
+	operandString
+	operandArray
+	operandDict
+	operandOperator
+)
+
+// contentOperand is an operand or operator of a content stream
+type contentOperand struct {
+	kind  int
+	num   float64
+	text  string // Name, operator or string bytes; hex digits for hex strings
+	hex   bool
+	items []contentOperand // Array elements
+}
+
+// operandNumbers returns the values of operands that are all numbers, or nil
+func operandNumbers(operands []contentOperand) []float64 {
+	nums := make([]float64, 0, len(operands))
+	for _, op := range operands {
+		if op.kind != operandNumber {
+			return nil
+		}
+		nums = append(nums, op.num)
+	}
+	return nums
+}
+
+// contentLexer reads the operands and operators of a content stream
+type contentLexer struct {
+	content string
+	pos     int
+}
+
+// next returns the next token, skipping whitespace and comments
+func (l *contentLexer) next() (contentOperand, bool) {
+	const delimiters = " \t\r\n\f\x00()<>[]{}/%"
+	s := l.content
+	for l.pos < len(s) {
+		c := s[l.pos]
+		switch {
+		case strings.IndexByte(" \t\r\n\f\x00", c) != -1:
+			l.pos++
+		case c == '%':
+			for l.pos < len(s) && s[l.pos] != '\n' && s[l.pos] != '\r' {
+				l.pos++
+			}
+		case c == '(':
+			start, depth := l.pos, 0
+			for ; l.pos < len(s); l.pos++ {
+				if s[l.pos] == '\\' {
+					l.pos++
+				} else if s[l.pos] == '(' {
+					depth++
+				} else if s[l.pos] == ')' {
+					if depth--; depth == 0 {
+						break
+					}
+				}
+			}
+			l.pos = min(l.pos+1, len(s))
+			text := ""
+			if tokens, err := encrypt.FindStrings([]byte(s[start:l.pos])); err == nil && len(tokens) > 0 {
+				text = string(tokens[0].Value)
+			}
+			return contentOperand{kind: operandString, text: text}, true
+		case c == '<' && l.pos+1 < len(s) && s[l.pos+1] == '<':
+			// Dictionaries only appear as marked content properties
+			start := l.pos
+			l.pos += 2
+			for depth := 1; l.pos < len(s) && depth > 0; {
+				if strings.HasPrefix(s[l.pos:], "<<") {
+					depth++
+					l.pos += 2
+				} else if strings.HasPrefix(s[l.pos:], ">>") {
+					depth--
+					l.pos += 2
+				} else {
+					l.pos++
+				}
+			}
+			return contentOperand{kind: operandDict, text: s[start:l.pos]}, true
+		case c == '<':
+			end := strings.IndexByte(s[l.pos:], '>')
+			if end == -1 {
+				end = len(s) - l.pos - 1
+			}
+			hex := s[l.pos+1 : l.pos+end]
+			l.pos += end + 1
+			return contentOperand{kind: operandString, text: hex, hex: true}, true
+		case c == '[':
+			l.pos++
+			var items []contentOperand
+			for {
+				item, ok := l.next()
+				if !ok || (item.kind == operandOperator && item.text == "]") {
+					break
+				}
+				items = append(items, item)
+			}
+			return contentOperand{kind: operandArray, items: items}, true
+		case c == ']' || c == '>' || c == ')' || c == '{' || c == '}':
+			l.pos++
+			return contentOperand{kind: operandOperator, text: string(c)}, true
+		default:
+			start := l.pos
+			l.pos++
+			for l.pos < len(s) && strings.IndexByte(delimiters, s[l.pos]) == -1 {
+				l.pos++
+			}
+			token := s[start:l.pos]
+			if c == '/' {
+				return contentOperand{kind: operandName, text: token}, true
+			}
+			if v, err := strconv.ParseFloat(token, 64); err == nil {
+				return contentOperand{kind: operandNumber, num: v}, true
+			}
+			return contentOperand{kind: operandOperator, text: token}, true
+		}
+	}
+	return contentOperand{}, false
+}
+
+// skipInlineImage moves past the data of an inline image, after its BI operator
+func (l *contentLexer) skipInlineImage() {
+	for {
+		tok, ok := l.next()
+		if !ok {
+			return
+		}
+		if tok.kind == operandOperator && tok.text == "ID" {
+			break
+		}
+	}
+	// The data ends at EI between whitespace
+	for i := l.pos; i+2 <= len(l.content); i++ {
+		if l.content[i:i+2] == "EI" && i > 0 && strings.IndexByte(" \t\r\n\f\x00", l.content[i-1]) != -1 &&
+			(i+2 == len(l.content) || strings.IndexByte(" \t\r\n\f\x00", l.content[i+2]) != -1) {
+			l.pos = i + 2
+			return
+		}
+	}
+	l.pos = len(l.content)
+}
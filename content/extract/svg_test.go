@@ -0,0 +1,107 @@
+package extract
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+)
+
+// svgTestPDF builds a one-page PDF with the given content, a Helvetica font
+// /F1 and a form XObject /Fm1 drawing a blue circle segment
+func svgTestPDF(t *testing.T, content, pageExtra string) []byte {
+	t.Helper()
+	writer := write.NewPDFWriter()
+	fontNum := writer.AddObject([]byte("<</Type/Font/Subtype/Type1/BaseFont/Helvetica/Encoding/WinAnsiEncoding>>"))
+	form := "0 0 1 rg 0 0 m 50 0 50 50 0 50 c f"
+	formNum := writer.AddObject([]byte(fmt.Sprintf("<</Type/XObject/Subtype/Form/BBox [0 0 100 100]/Matrix [1 0 0 1 200 200]/Length %d>>\nstream\n%s\nendstream", len(form), form)))
+	contentNum := writer.AddObject([]byte(fmt.Sprintf("<</Length %d>>\nstream\n%s\nendstream", len(content), content)))
+	pagesNum := writer.AddObject(nil)
+	pageNum := writer.AddObject([]byte(fmt.Sprintf("<</Type/Page/Parent %d 0 R/MediaBox [0 0 400 300]%s/Contents %d 0 R/Resources <</Font <</F1 %d 0 R>>/XObject <</Fm1 %d 0 R>>>>>>", pagesNum, pageExtra, contentNum, fontNum, formNum)))
+	writer.SetObject(pagesNum, []byte(fmt.Sprintf("<</Type/Pages/Kids [%d 0 R]/Count 1>>", pageNum)))
+	writer.SetRoot(writer.AddObject([]byte(fmt.Sprintf("<</Type/Catalog/Pages %d 0 R>>", pagesNum))))
+	pdfBytes, err := writer.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+	return pdfBytes
+}
+
+// checkWellFormed fails the test when svg is not well-formed XML
+func checkWellFormed(t *testing.T, svg string) {
+	t.Helper()
+	decoder := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		if _, err := decoder.Token(); err == io.EOF {
+			return
+		} else if err != nil {
+			t.Fatalf("SVG is not well-formed: %v\n%s", err, svg)
+		}
+	}
+}
+
+func TestPageToSVG(t *testing.T) {
+	content := "q 1 0 0 rg 2 w 1 J [3 1] 0 d 10 10 100 50 re B Q\n" +
+		"q 10 10 m 200 10 l 200 100 l h W n 0 0.5 0 RG 0 0 m 300 300 l S Q\n" +
+		"BT /F1 12 Tf 20 250 Td (Hello <world> & more) Tj [(A) -500 (B)] TJ 3 Tr (hidden) Tj ET\n" +
+		"BI /W 1 /H 1 /CS /G /BPC 8 ID \x00 EI\n" +
+		"/Fm1 Do"
+	pdf, err := ParseTestPDF(svgTestPDF(t, content, ""))
+	if err != nil {
+		t.Fatalf("Failed to parse PDF: %v", err)
+	}
+
+	svg, err := PageToSVG(pdf, 1, false)
+	if err != nil {
+		t.Fatalf("PageToSVG failed: %v", err)
+	}
+	checkWellFormed(t, svg)
+
+	for _, want := range []string{
+		`width="400" height="300"`,
+		`transform="matrix(1 0 0 -1 0 300)"`,
+		`<path d="M10 10 h100 v50 h-100 Z" fill="#ff0000" stroke="#000000" stroke-width="2" stroke-linecap="round" stroke-miterlimit="10" stroke-dasharray="3,1"/>`,
+		`<clipPath id="clip1"><path d="M10 10 L200 10 L200 100 Z" clip-rule="nonzero"/></clipPath>`,
+		`stroke="#008000"`,
+		`font-family="sans-serif"`,
+		`>Hello &lt;world&gt; &amp; more</text>`,
+		`<path d="M0 0 C50 0 50 50 0 50" fill="#0000ff" transform="matrix(1 0 0 1 200 200)"/>`,
+	} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("SVG doesn't contain %s:\n%s", want, svg)
+		}
+	}
+	if strings.Contains(svg, "hidden") {
+		t.Error("Invisible text drawn")
+	}
+
+	// Text is flipped back upright at the text position
+	if !strings.Contains(svg, `<text transform="matrix(12 0 0 -12 20 250)"`) {
+		t.Errorf("Unexpected text transform:\n%s", svg)
+	}
+	// The TJ adjustment moves B right of A by half an em
+	if strings.Count(svg, "<text") != 3 {
+		t.Errorf("Expected 3 text elements, got %d", strings.Count(svg, "<text"))
+	}
+}
+
+func TestPageToSVG_Rotated(t *testing.T) {
+	pdf, err := ParseTestPDF(svgTestPDF(t, "0 0 10 10 re f", "/Rotate 90"))
+	if err != nil {
+		t.Fatalf("Failed to parse PDF: %v", err)
+	}
+	svg, err := PageToSVG(pdf, 1, false)
+	if err != nil {
+		t.Fatalf("PageToSVG failed: %v", err)
+	}
+	if !strings.Contains(svg, `width="300" height="400"`) || !strings.Contains(svg, `matrix(0 1 1 0 0 0)`) {
+		t.Errorf("Rotation not applied:\n%s", svg)
+	}
+
+	if _, err := PageToSVG(pdf, 2, false); err == nil {
+		t.Error("Expected an error for a missing page")
+	}
+}
@@ -1,6 +1,9 @@
 package extract
 
 import (
+	"bytes"
+	"fmt"
+
 	"github.com/benedoc-inc/pdfer/core/parse"
 	"github.com/benedoc-inc/pdfer/core/write"
 	"github.com/benedoc-inc/pdfer/types"
@@ -41,6 +44,7 @@ func CreateTestPDFWithText(texts []TestText) ([]byte, []types.TextElement, error
 	}
 
 	content.EndText()
+	builder.FinalizePage(page)
 
 	pdfBytes, err := builder.Bytes()
 	if err != nil {
@@ -139,6 +143,7 @@ func CreateTestPDFWithComplexText() ([]byte, []types.TextElement, error) {
 	})
 
 	content.EndText()
+	builder.FinalizePage(page)
 
 	pdfBytes, err := builder.Bytes()
 	if err != nil {
@@ -193,6 +198,7 @@ func CreateTestPDFWithGraphics() ([]byte, []types.Graphic, error) {
 		StrokeColor: &types.Color{R: 1, G: 0, B: 0},
 		LineWidth:   1,
 	})
+	builder.FinalizePage(page)
 
 	pdfBytes, err := builder.Bytes()
 	if err != nil {
@@ -202,6 +208,43 @@ func CreateTestPDFWithGraphics() ([]byte, []types.Graphic, error) {
 	return pdfBytes, expectedGraphics, nil
 }
 
+// CreateTestSignedPDF creates a PDF with a signature field named "Approval"
+// whose byte range covers the file except its /Contents, as a signer would
+// write it. The signature value is filled with contents, a hex string standing
+// in for a CMS signature, and note is written to another object inside the
+// signed bytes so that tests can tamper with it.
+func CreateTestSignedPDF(note, contents string) ([]byte, error) {
+	const placeholder = "[0 0000000000 0000000000 0000000000]"
+
+	writer := write.NewPDFWriter()
+	catalogNum := writer.AddObject(nil)
+	pagesNum := writer.AddObject(nil)
+	pageNum := writer.AddObject(nil)
+	sigNum := writer.AddObject([]byte(fmt.Sprintf("<</Type/Sig/Filter/Adobe.PPKLite/SubFilter/adbe.pkcs7.detached/Name(Jane Doe)/Reason(Approved)/M(D:20260101120000Z)/ByteRange %s/Contents <%0128x>>>", placeholder, 0)))
+	fieldNum := writer.AddObject([]byte(fmt.Sprintf("<</FT/Sig/T(Approval)/V %d 0 R/Type/Annot/Subtype/Widget/Rect [0 0 0 0]/P %d 0 R>>", sigNum, pageNum)))
+	writer.AddObject([]byte(fmt.Sprintf("<</Note(%s)>>", note)))
+	writer.SetObject(catalogNum, []byte(fmt.Sprintf("<</Type/Catalog/Pages %d 0 R/AcroForm <</Fields [%d 0 R]/SigFlags 3>>>>", pagesNum, fieldNum)))
+	writer.SetObject(pagesNum, []byte(fmt.Sprintf("<</Type/Pages/Kids [%d 0 R]/Count 1>>", pageNum)))
+	writer.SetObject(pageNum, []byte(fmt.Sprintf("<</Type/Page/Parent %d 0 R/MediaBox [0 0 612 792]/Annots [%d 0 R]>>", pagesNum, fieldNum)))
+	writer.SetRoot(catalogNum)
+
+	pdfBytes, err := writer.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	// Fill the byte range around the /Contents string, then the signature value
+	brIdx := bytes.Index(pdfBytes, []byte(placeholder))
+	start := bytes.Index(pdfBytes, []byte("/Contents <")) + len("/Contents ")
+	end := bytes.IndexByte(pdfBytes[start:], '>') + start + 1
+	if brIdx == -1 || end-start-2 < len(contents) {
+		return nil, fmt.Errorf("signature placeholder not found or too small")
+	}
+	copy(pdfBytes[brIdx:], fmt.Sprintf("[0 %010d %010d %010d]", start, end, len(pdfBytes)-end))
+	copy(pdfBytes[start+1:], contents)
+	return pdfBytes, nil
+}
+
 // ParseTestPDF parses a PDF created by test helpers
 func ParseTestPDF(pdfBytes []byte) (*parse.PDF, error) {
 	return parse.OpenWithOptions(pdfBytes, parse.ParseOptions{
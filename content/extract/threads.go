@@ -0,0 +1,120 @@
+package extract
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// maxThreadBeads bounds the walk of a thread's bead list, in case its /N links
+// never return to the first bead
+const maxThreadBeads = 100000
+
+// ExtractThreads returns the article threads of a document (/Threads in the
+// catalog) with their beads in reading order. Beads on pages that are not in
+// the page tree have a page number of 0.
+func ExtractThreads(pdf *parse.PDF, verbose bool) ([]types.ArticleThread, error) {
+	threads := []types.ArticleThread{}
+	trailer := pdf.Trailer()
+	if trailer == nil || trailer.RootRef == "" {
+		return threads, nil
+	}
+	rootObjNum, err := parseObjectRef(trailer.RootRef)
+	if err != nil {
+		return threads, nil
+	}
+	catalogObj, err := pdf.GetObject(rootObjNum)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get catalog: %w", err)
+	}
+
+	resolver := &linkResolver{pdf: pdf, verbose: verbose}
+	threadRefs := parseObjectRefArray(resolver.deref(dictEntry(objectContent(string(catalogObj)), "/Threads")))
+	if len(threadRefs) == 0 {
+		return threads, nil
+	}
+
+	pageNumbers := make(map[int]int) // page object number -> page number
+	it := pdf.Pages()
+	for it.Next() {
+		pageNumbers[it.Page().ObjectNumber] = it.Page().Number
+	}
+	if err := it.Err(); err != nil {
+		return nil, fmt.Errorf("failed to walk pages tree: %w", err)
+	}
+
+	for _, threadRef := range threadRefs {
+		thread := resolver.deref(threadRef)
+		if thread == "" {
+			continue
+		}
+		article := types.ArticleThread{Beads: []types.ArticleBead{}}
+		if info := resolver.deref(dictEntry(thread, "/I")); info != "" {
+			article.Title = pdfString(resolver.deref(dictEntry(info, "/Title")))
+			article.Author = pdfString(resolver.deref(dictEntry(info, "/Author")))
+		}
+
+		// Beads form a circular list starting at /F
+		seen := make(map[string]bool)
+		for beadRef := dictEntry(thread, "/F"); beadRef != "" && !seen[beadRef] && len(seen) < maxThreadBeads; {
+			seen[beadRef] = true
+			bead := resolver.deref(beadRef)
+			if bead == "" {
+				break
+			}
+			entry := types.ArticleBead{}
+			if pageObjNum, err := parseObjectRef(dictEntry(bead, "/P")); err == nil {
+				entry.PageNumber = pageNumbers[pageObjNum]
+			}
+			if rect := extractArrayValue(bead, "/R"); len(rect) >= 4 {
+				entry.Rect = &types.Rectangle{
+					LowerX: min(rect[0], rect[2]),
+					LowerY: min(rect[1], rect[3]),
+					UpperX: max(rect[0], rect[2]),
+					UpperY: max(rect[1], rect[3]),
+				}
+			}
+			article.Beads = append(article.Beads, entry)
+			beadRef = dictEntry(bead, "/N")
+		}
+		threads = append(threads, article)
+	}
+	return threads, nil
+}
+
+// ThreadText returns the text of an article thread in reading order: for each
+// bead, the text elements of its page whose position lies in the bead's area,
+// top to bottom and left to right. Layout code can use it in place of the
+// geometric order of a page when a document declares its reading order with
+// threads.
+func ThreadText(doc *types.ContentDocument, thread types.ArticleThread) []types.TextElement {
+	pages := make(map[int]*types.Page, len(doc.Pages))
+	for i := range doc.Pages {
+		pages[doc.Pages[i].PageNumber] = &doc.Pages[i]
+	}
+
+	text := []types.TextElement{}
+	for _, bead := range thread.Beads {
+		page, ok := pages[bead.PageNumber]
+		if !ok || bead.Rect == nil {
+			continue
+		}
+		var inBead []types.TextElement
+		for _, elem := range page.Text {
+			if elem.X >= bead.Rect.LowerX && elem.X <= bead.Rect.UpperX &&
+				elem.Y >= bead.Rect.LowerY && elem.Y <= bead.Rect.UpperY {
+				inBead = append(inBead, elem)
+			}
+		}
+		sort.SliceStable(inBead, func(i, j int) bool {
+			if inBead[i].Y != inBead[j].Y {
+				return inBead[i].Y > inBead[j].Y
+			}
+			return inBead[i].X < inBead[j].X
+		})
+		text = append(text, inBead...)
+	}
+	return text
+}
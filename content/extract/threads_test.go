@@ -0,0 +1,83 @@
+package extract
+
+import (
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+func TestExtractThreads(t *testing.T) {
+	// Two columns on page 1, read left then right, continued on page 2
+	builder := write.NewSimplePDFBuilder()
+	page := builder.AddPage(write.PageSizeLetter)
+	font := page.AddStandardFont("Helvetica")
+	page.Content().
+		BeginText().SetFont(font, 12).
+		SetTextMatrix(1, 0, 0, 1, 320, 700).ShowText("Second").
+		SetTextMatrix(1, 0, 0, 1, 40, 700).ShowText("First").
+		SetTextMatrix(1, 0, 0, 1, 40, 680).ShowText("Also first").
+		EndText()
+	builder.FinalizePage(page)
+	page2 := builder.AddPage(write.PageSizeLetter)
+	font2 := page2.AddStandardFont("Helvetica")
+	page2.Content().BeginText().SetFont(font2, 12).SetTextPosition(40, 700).ShowText("Third").EndText()
+	builder.FinalizePage(page2)
+
+	want := types.ArticleThread{
+		Title:  "Front page",
+		Author: "Desk",
+		Beads: []types.ArticleBead{
+			{PageNumber: 1, Rect: &types.Rectangle{LowerX: 36, LowerY: 36, UpperX: 300, UpperY: 756}},
+			{PageNumber: 1, Rect: &types.Rectangle{LowerX: 312, LowerY: 36, UpperX: 576, UpperY: 756}},
+			{PageNumber: 2, Rect: &types.Rectangle{LowerX: 36, LowerY: 36, UpperX: 576, UpperY: 756}},
+		},
+	}
+	if err := builder.SetThreads([]types.ArticleThread{want}); err != nil {
+		t.Fatalf("SetThreads failed: %v", err)
+	}
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+
+	doc, err := ExtractContent(pdfBytes, nil, false)
+	if err != nil {
+		t.Fatalf("ExtractContent failed: %v", err)
+	}
+	if len(doc.Threads) != 1 {
+		t.Fatalf("Expected 1 thread, got %+v", doc.Threads)
+	}
+	got := doc.Threads[0]
+	if got.Title != want.Title || got.Author != want.Author || len(got.Beads) != len(want.Beads) {
+		t.Fatalf("Got thread %+v, want %+v", got, want)
+	}
+	for i, bead := range got.Beads {
+		if bead.PageNumber != want.Beads[i].PageNumber || *bead.Rect != *want.Beads[i].Rect {
+			t.Errorf("Bead %d is %+v on page %d, want %+v on page %d", i, *bead.Rect, bead.PageNumber, *want.Beads[i].Rect, want.Beads[i].PageNumber)
+		}
+	}
+
+	var order []string
+	for _, elem := range ThreadText(doc, got) {
+		order = append(order, elem.Text)
+	}
+	if len(order) != 4 || order[0] != "First" || order[1] != "Also first" || order[2] != "Second" || order[3] != "Third" {
+		t.Errorf("Unexpected reading order %q", order)
+	}
+}
+
+func TestExtractThreads_None(t *testing.T) {
+	pdfBytes, _, err := CreateTestPDFWithText([]TestText{{Text: "Hello", X: 72, Y: 720, FontSize: 12}})
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+	pdf, err := ParseTestPDF(pdfBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse PDF: %v", err)
+	}
+	threads, err := ExtractThreads(pdf, false)
+	if err != nil || len(threads) != 0 {
+		t.Errorf("Expected no threads, got %+v, %v", threads, err)
+	}
+}
@@ -0,0 +1,172 @@
+// Package layout measures and breaks text into lines for positioning on a page or
+// in an appearance stream, using the metrics of embedded fonts
+package layout
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Measurer measures text; *font.Font and *font.FallbackChain implement it
+type Measurer interface {
+	TextWidth(text string, size float64) (float64, error)
+}
+
+// Line is one line of broken text
+type Line struct {
+	Text  string  // Line text without the trailing spaces at the break
+	Width float64 // Advance width of Text in points
+}
+
+// LineBreak breaks text into lines no wider than width points when set in the font
+// at the given size. Lines break at spaces, after hyphens and between CJK
+// characters; explicit newlines always start a new line. A word wider than the line
+// is split between characters.
+func LineBreak(text string, width float64, font Measurer, size float64) ([]Line, error) {
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+
+	var lines []Line
+	for _, paragraph := range strings.Split(text, "\n") {
+		broken, err := breakParagraph(paragraph, width, font, size)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, broken...)
+	}
+	return lines, nil
+}
+
+// breakParagraph fills lines greedily with the segments between break opportunities
+func breakParagraph(text string, width float64, font Measurer, size float64) ([]Line, error) {
+	var lines []Line
+	var current strings.Builder
+
+	// flush ends the current line, dropping the spaces it ended with
+	flush := func() error {
+		line := strings.TrimRight(current.String(), " ")
+		w, err := font.TextWidth(line, size)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, Line{Text: line, Width: w})
+		current.Reset()
+		return nil
+	}
+
+	for _, segment := range segments(text) {
+		candidate := current.String() + segment
+		w, err := font.TextWidth(strings.TrimRight(candidate, " "), size)
+		if err != nil {
+			return nil, err
+		}
+		if w <= width || current.Len() == 0 && strings.TrimSpace(segment) == "" {
+			current.WriteString(segment)
+			continue
+		}
+
+		if current.Len() > 0 {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+		// A segment that does not fit on an empty line is split between characters
+		for segment != "" {
+			n, err := fitPrefix(segment, width, font, size)
+			if err != nil {
+				return nil, err
+			}
+			if n == len(segment) {
+				current.WriteString(segment)
+				break
+			}
+			current.WriteString(segment[:n])
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			segment = strings.TrimLeft(segment[n:], " ")
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// fitPrefix returns the byte length of the longest prefix of s that fits in width,
+// at least one character
+func fitPrefix(s string, width float64, font Measurer, size float64) (int, error) {
+	_, first := utf8.DecodeRuneInString(s)
+	n := first
+	for i := first; i < len(s); {
+		_, l := utf8.DecodeRuneInString(s[i:])
+		w, err := font.TextWidth(strings.TrimRight(s[:i+l], " "), size)
+		if err != nil {
+			return 0, err
+		}
+		if w > width {
+			break
+		}
+		i += l
+		n = i
+	}
+	return n, nil
+}
+
+// segments splits text after each break opportunity; every segment keeps the
+// spaces that follow it
+func segments(text string) []string {
+	var out []string
+	start := 0
+	runes := []rune(text)
+	offset := 0
+	for i, r := range runes {
+		offset += utf8.RuneLen(r)
+		var next rune
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+		if next == 0 || !canBreakBetween(r, next) {
+			continue
+		}
+		out = append(out, text[start:offset])
+		start = offset
+	}
+	if start < len(text) {
+		out = append(out, text[start:])
+	}
+	return out
+}
+
+// canBreakBetween reports whether a line may break between a and b
+func canBreakBetween(a, b rune) bool {
+	switch {
+	case b == ' ' || b == '\u00A0' || isClosingPunctuation(b) || unicode.Is(unicode.Mn, b):
+		return false
+	case a == ' ', a == '\u200B':
+		return true
+	case a == '-' || a == '\u2010' || a == '\u2013':
+		return unicode.IsLetter(b) || unicode.IsDigit(b)
+	case isOpeningPunctuation(a):
+		return false
+	}
+	return isCJK(a) || isCJK(b)
+}
+
+// isCJK reports whether r is a Chinese or Japanese character, between which lines
+// may break without spaces
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) ||
+		r >= 0x3000 && r <= 0x303F // CJK symbols and punctuation
+}
+
+// isClosingPunctuation covers characters a line must not start with
+func isClosingPunctuation(r rune) bool {
+	return unicode.Is(unicode.Pe, r) || unicode.Is(unicode.Pf, r) ||
+		strings.ContainsRune(".,;:!?%、。，．：；！？", r)
+}
+
+// isOpeningPunctuation covers characters a line must not end with
+func isOpeningPunctuation(r rune) bool {
+	return unicode.Is(unicode.Ps, r) || unicode.Is(unicode.Pi, r)
+}
@@ -0,0 +1,85 @@
+package layout
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/benedoc-inc/pdfer/resources/font"
+)
+
+// monospace measures every character as 1 point per point of font size
+type monospace struct{}
+
+func (monospace) TextWidth(text string, size float64) (float64, error) {
+	return float64(utf8.RuneCountInString(text)) * size, nil
+}
+
+func lineTexts(lines []Line) []string {
+	var texts []string
+	for _, line := range lines {
+		texts = append(texts, line.Text)
+	}
+	return texts
+}
+
+func TestLineBreak(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		width float64
+		want  []string
+	}{
+		{"fits", "hello world", 20, []string{"hello world"}},
+		{"spaces", "the quick brown fox", 10, []string{"the quick", "brown fox"}},
+		{"trailing spaces ignored", "abcd    efgh", 4, []string{"abcd", "efgh"}},
+		{"hyphen", "well-known fact", 6, []string{"well-", "known", "fact"}},
+		{"long word", "abcdefghij xy", 4, []string{"abcd", "efgh", "ij", "xy"}},
+		{"newlines", "a\n\nb\r\nc", 10, []string{"a", "", "b", "c"}},
+		{"cjk", "日本語の文章です。", 4, []string{"日本語の", "文章で", "す。"}},
+		{"punctuation", "word (paren) end.", 7, []string{"word", "(paren)", "end."}},
+	}
+	for _, tt := range tests {
+		lines, err := LineBreak(tt.text, tt.width, monospace{}, 1)
+		if err != nil {
+			t.Fatalf("%s: LineBreak failed: %v", tt.name, err)
+		}
+		if got := lineTexts(lines); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: LineBreak(%q, %v) = %q, want %q", tt.name, tt.text, tt.width, got, tt.want)
+		}
+		for _, line := range lines {
+			if line.Width != float64(utf8.RuneCountInString(line.Text)) {
+				t.Errorf("%s: line %q has width %v", tt.name, line.Text, line.Width)
+			}
+		}
+	}
+}
+
+func TestLineBreak_Font(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "tests", "resources", "test_font.ttf"))
+	if err != nil {
+		t.Skipf("test font not available: %v", err)
+	}
+	f, err := font.NewFont("Gothic", data)
+	if err != nil {
+		t.Fatalf("NewFont failed: %v", err)
+	}
+
+	word := "\U00010330\U00010331\U00010332"
+	wordWidth, err := f.TextWidth(word, 12)
+	if err != nil {
+		t.Fatalf("TextWidth failed: %v", err)
+	}
+	lines, err := LineBreak(word+" "+word+" "+word, 2.5*wordWidth, f, 12)
+	if err != nil {
+		t.Fatalf("LineBreak failed: %v", err)
+	}
+	if len(lines) != 2 || lines[1].Text != word || lines[1].Width != wordWidth {
+		t.Errorf("Unexpected lines %+v", lines)
+	}
+	if lines[0].Width > 2.5*wordWidth {
+		t.Errorf("First line is %v wide, wider than %v", lines[0].Width, 2.5*wordWidth)
+	}
+}
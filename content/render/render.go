@@ -0,0 +1,267 @@
+// Package render provides low-fidelity raster previews of PDF pages.
+// Pages are drawn from extracted content (text runs, rectangles, image
+// placements), which is enough for thumbnails in document management UIs
+// but is not a full PDF rasterizer.
+package render
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/resources/font"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// RenderOptions configures page preview rendering
+type RenderOptions struct {
+	MaxWidth   int         // Maximum output width in pixels (default: 106)
+	MaxHeight  int         // Maximum output height in pixels (default: 106)
+	Background color.Color // Page background (default: white)
+
+	// Fonts supplies replacement metrics for fonts the page references but does not
+	// embed; substitutions made are recorded in its Report. Nil uses estimated widths.
+	Fonts *font.SubstitutionRegistry
+}
+
+// DefaultRenderOptions returns options suitable for /Thumb images
+func DefaultRenderOptions() RenderOptions {
+	return RenderOptions{
+		MaxWidth:   106,
+		MaxHeight:  106,
+		Background: color.White,
+	}
+}
+
+// RenderPage draws a preview of an extracted page scaled to fit the configured size
+func RenderPage(page *types.Page, opts RenderOptions) *image.RGBA {
+	if opts.MaxWidth <= 0 {
+		opts.MaxWidth = DefaultRenderOptions().MaxWidth
+	}
+	if opts.MaxHeight <= 0 {
+		opts.MaxHeight = DefaultRenderOptions().MaxHeight
+	}
+	if opts.Background == nil {
+		opts.Background = color.White
+	}
+
+	box := pageBox(page)
+	pageW := box.UpperX - box.LowerX
+	pageH := box.UpperY - box.LowerY
+	if pageW <= 0 || pageH <= 0 {
+		pageW, pageH = 612, 792
+	}
+
+	// Rotated pages are rendered upright and then turned
+	rotation := ((page.Rotation % 360) + 360) % 360
+	fitW, fitH := opts.MaxWidth, opts.MaxHeight
+	if rotation == 90 || rotation == 270 {
+		fitW, fitH = fitH, fitW
+	}
+
+	scale := math.Min(float64(fitW)/pageW, float64(fitH)/pageH)
+	w := int(math.Max(1, math.Round(pageW*scale)))
+	h := int(math.Max(1, math.Round(pageH*scale)))
+
+	c := &canvas{
+		img:   image.NewRGBA(image.Rect(0, 0, w, h)),
+		scale: scale,
+		ox:    box.LowerX,
+		oy:    box.LowerY,
+		page:  page,
+		fonts: opts.Fonts,
+	}
+	c.fill(c.img.Bounds(), opts.Background)
+
+	for _, g := range page.Graphics {
+		c.drawGraphic(g)
+	}
+	for _, ref := range page.Images {
+		c.fillBox(ref.X, ref.Y, ref.X+ref.Width, ref.Y+ref.Height, color.RGBA{R: 0xB0, G: 0xB0, B: 0xB0, A: 0xFF})
+	}
+	for _, t := range page.Text {
+		c.drawText(t)
+	}
+
+	return rotate(c.img, rotation)
+}
+
+// substituteWidth measures text drawn in a non-embedded font with its registered
+// replacement, returning 0 when there is none
+func (c *canvas) substituteWidth(t types.TextElement, size float64) float64 {
+	if c.fonts == nil || c.page.Resources == nil || t.FontName == "" {
+		return 0
+	}
+	info, ok := c.page.Resources.Fonts[strings.TrimPrefix(t.FontName, "/")]
+	if !ok || info.Embedded || info.Name == "" {
+		return 0
+	}
+	f, err := c.fonts.Substitute(info.Name, "render")
+	if err != nil {
+		return 0
+	}
+	width, err := f.TextWidth(t.Text, size)
+	if err != nil {
+		return 0
+	}
+	return width
+}
+
+// pageBox returns the visible page box (CropBox, then MediaBox, then Width/Height)
+func pageBox(page *types.Page) types.Rectangle {
+	if page.CropBox != nil {
+		return *page.CropBox
+	}
+	if page.MediaBox != nil {
+		return *page.MediaBox
+	}
+	return types.Rectangle{UpperX: page.Width, UpperY: page.Height}
+}
+
+// canvas maps PDF user space onto a pixel buffer
+type canvas struct {
+	img   *image.RGBA
+	scale float64
+	ox    float64
+	oy    float64
+	page  *types.Page
+	fonts *font.SubstitutionRegistry
+}
+
+// toPixel converts a PDF point to pixel coordinates (origin top-left)
+func (c *canvas) toPixel(x, y float64) (int, int) {
+	px := (x - c.ox) * c.scale
+	py := float64(c.img.Bounds().Dy()) - (y-c.oy)*c.scale
+	return int(math.Floor(px)), int(math.Floor(py))
+}
+
+// fill paints a pixel rectangle, clipped to the canvas
+func (c *canvas) fill(r image.Rectangle, col color.Color) {
+	r = r.Intersect(c.img.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c.img.Set(x, y, col)
+		}
+	}
+}
+
+// fillBox paints a rectangle given in PDF user space, at least one pixel in size
+func (c *canvas) fillBox(llx, lly, urx, ury float64, col color.Color) {
+	x0, y0 := c.toPixel(math.Min(llx, urx), math.Max(lly, ury))
+	x1, y1 := c.toPixel(math.Max(llx, urx), math.Min(lly, ury))
+	if x1 <= x0 {
+		x1 = x0 + 1
+	}
+	if y1 <= y0 {
+		y1 = y0 + 1
+	}
+	c.fill(image.Rect(x0, y0, x1, y1), col)
+}
+
+// strokeBox outlines a rectangle given in PDF user space
+func (c *canvas) strokeBox(llx, lly, urx, ury float64, col color.Color) {
+	c.fillBox(llx, lly, urx, lly, col)
+	c.fillBox(llx, ury, urx, ury, col)
+	c.fillBox(llx, lly, llx, ury, col)
+	c.fillBox(urx, lly, urx, ury, col)
+}
+
+// drawGraphic draws rectangles and other bounded graphics
+func (c *canvas) drawGraphic(g types.Graphic) {
+	if g.BoundingBox == nil {
+		return
+	}
+	bb := g.BoundingBox
+	if g.Type == types.GraphicTypeRectangle && g.FillColor != nil && !isBlack(g.FillColor) {
+		c.fillBox(bb.LowerX, bb.LowerY, bb.UpperX, bb.UpperY, toRGBA(g.FillColor))
+	}
+	stroke := color.Color(color.RGBA{A: 0xFF})
+	if g.StrokeColor != nil {
+		stroke = toRGBA(g.StrokeColor)
+	}
+	c.strokeBox(bb.LowerX, bb.LowerY, bb.UpperX, bb.UpperY, stroke)
+}
+
+// drawText draws a text run as a solid bar roughly the size of its glyphs
+func (c *canvas) drawText(t types.TextElement) {
+	size := t.FontSize
+	if size <= 0 {
+		size = t.Height
+	}
+	if size <= 0 {
+		size = 12
+	}
+	width := t.Width
+	if width <= 0 {
+		width = c.substituteWidth(t, size)
+	}
+	if width <= 0 {
+		width = float64(len([]rune(t.Text))) * size * 0.5
+	}
+	col := color.Color(color.RGBA{R: 0x40, G: 0x40, B: 0x40, A: 0xFF})
+	if t.Color != nil {
+		col = toRGBA(t.Color)
+	}
+	// Only the x-height band is painted so adjacent lines stay distinct
+	c.fillBox(t.X, t.Y, t.X+width, t.Y+size*0.6, col)
+}
+
+// isBlack reports whether a color is the default black fill
+func isBlack(col *types.Color) bool {
+	return col.R == 0 && col.G == 0 && col.B == 0 && col.K == 0 && col.Space != types.ColorSpaceCMYK
+}
+
+// toRGBA converts an extracted color to an opaque RGBA value
+func toRGBA(col *types.Color) color.RGBA {
+	r, g, b := col.R, col.G, col.B
+	switch col.Space {
+	case types.ColorSpaceGray:
+		g, b = r, r
+	case types.ColorSpaceCMYK:
+		r = (1 - col.C) * (1 - col.K)
+		g = (1 - col.M) * (1 - col.K)
+		b = (1 - col.Y) * (1 - col.K)
+	}
+	return color.RGBA{R: clampByte(r), G: clampByte(g), B: clampByte(b), A: 0xFF}
+}
+
+// clampByte converts a 0-1 component to a byte
+func clampByte(v float64) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 1 {
+		return 0xFF
+	}
+	return uint8(math.Round(v * 255))
+}
+
+// rotate turns an image clockwise by 90, 180 or 270 degrees
+func rotate(src *image.RGBA, degrees int) *image.RGBA {
+	if degrees != 90 && degrees != 180 && degrees != 270 {
+		return src
+	}
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	var dst *image.RGBA
+	if degrees == 180 {
+		dst = image.NewRGBA(image.Rect(0, 0, w, h))
+	} else {
+		dst = image.NewRGBA(image.Rect(0, 0, h, w))
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			col := src.RGBAAt(b.Min.X+x, b.Min.Y+y)
+			switch degrees {
+			case 90:
+				dst.SetRGBA(h-1-y, x, col)
+			case 180:
+				dst.SetRGBA(w-1-x, h-1-y, col)
+			case 270:
+				dst.SetRGBA(y, w-1-x, col)
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,154 @@
+package render
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/resources/font"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+func createTestPDF(t *testing.T, pages int) []byte {
+	t.Helper()
+	builder := write.NewSimplePDFBuilder()
+	for i := 0; i < pages; i++ {
+		page := builder.AddPage(write.PageSizeLetter)
+		font := page.AddStandardFont("Helvetica")
+		page.Content().
+			SetFillColorRGB(1, 0, 0).
+			Rectangle(100, 100, 200, 100).
+			Fill().
+			BeginText().
+			SetFont(font, 24).
+			SetTextPosition(72, 700).
+			ShowText("Preview").
+			EndText()
+		builder.FinalizePage(page)
+	}
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create test PDF: %v", err)
+	}
+	return pdfBytes
+}
+
+func TestRenderPageSize(t *testing.T) {
+	page := &types.Page{Width: 612, Height: 792}
+	img := RenderPage(page, DefaultRenderOptions())
+	b := img.Bounds()
+	if b.Dy() != 106 {
+		t.Errorf("Expected height 106, got %d", b.Dy())
+	}
+	if b.Dx() != 82 {
+		t.Errorf("Expected width 82, got %d", b.Dx())
+	}
+
+	page.Rotation = 90
+	img = RenderPage(page, DefaultRenderOptions())
+	if img.Bounds().Dx() != 106 || img.Bounds().Dy() != 82 {
+		t.Errorf("Expected rotated size 106x82, got %dx%d", img.Bounds().Dx(), img.Bounds().Dy())
+	}
+}
+
+func TestRenderPageDrawsContent(t *testing.T) {
+	page := &types.Page{
+		Width:  100,
+		Height: 100,
+		Graphics: []types.Graphic{{
+			Type:        types.GraphicTypeRectangle,
+			FillColor:   &types.Color{Space: types.ColorSpaceRGB, R: 1},
+			BoundingBox: &types.Rectangle{LowerX: 0, LowerY: 0, UpperX: 50, UpperY: 50},
+		}},
+	}
+	img := RenderPage(page, RenderOptions{MaxWidth: 100, MaxHeight: 100})
+
+	if got := img.RGBAAt(25, 75); got != (color.RGBA{R: 0xFF, A: 0xFF}) {
+		t.Errorf("Expected red fill inside rectangle, got %v", got)
+	}
+	if got := img.RGBAAt(75, 25); got != (color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}) {
+		t.Errorf("Expected white background outside rectangle, got %v", got)
+	}
+}
+
+func TestExportThumbnailsPNG(t *testing.T) {
+	pdfBytes := createTestPDF(t, 2)
+
+	pngs, err := ExportThumbnailsPNG(pdfBytes, nil, DefaultRenderOptions(), false)
+	if err != nil {
+		t.Fatalf("ExportThumbnailsPNG failed: %v", err)
+	}
+	if len(pngs) != 2 {
+		t.Fatalf("Expected 2 thumbnails, got %d", len(pngs))
+	}
+	img, err := png.Decode(bytes.NewReader(pngs[0]))
+	if err != nil {
+		t.Fatalf("Failed to decode PNG: %v", err)
+	}
+	if img.Bounds().Dy() != 106 {
+		t.Errorf("Expected thumbnail height 106, got %d", img.Bounds().Dy())
+	}
+}
+
+func TestEmbedThumbnails(t *testing.T) {
+	pdfBytes := createTestPDF(t, 2)
+
+	out, err := EmbedThumbnails(pdfBytes, nil, DefaultRenderOptions(), false)
+	if err != nil {
+		t.Fatalf("EmbedThumbnails failed: %v", err)
+	}
+
+	pdf, err := parse.Open(out)
+	if err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+
+	thumbs := 0
+	for _, objNum := range pdf.Objects() {
+		obj, err := pdf.GetObject(objNum)
+		if err != nil {
+			continue
+		}
+		s := string(obj)
+		if strings.Contains(s, "/Type/Page") && !strings.Contains(s, "/Type/Pages") && strings.Contains(s, "/Thumb ") {
+			thumbs++
+		}
+	}
+	if thumbs != 2 {
+		t.Errorf("Expected 2 pages with /Thumb, got %d", thumbs)
+	}
+}
+
+func TestRenderPageFontSubstitution(t *testing.T) {
+	fontPath := "../../tests/resources/test_font.ttf"
+	if _, err := os.Stat(fontPath); err != nil {
+		t.Skip("test font not found")
+	}
+
+	page := &types.Page{
+		Width:  612,
+		Height: 792,
+		Text:   []types.TextElement{{Text: "Substituted", FontName: "/F1", FontSize: 24, X: 72, Y: 700}},
+		Resources: &types.PageResources{
+			Fonts: map[string]types.FontInfo{
+				"F1": {ID: "/F1", Name: "Helvetica", Subtype: "Type1"},
+			},
+		},
+	}
+
+	fonts := font.NewSubstitutionRegistry()
+	fonts.Register("Helvetica", fontPath)
+	opts := DefaultRenderOptions()
+	opts.Fonts = fonts
+	RenderPage(page, opts)
+
+	report := fonts.Report()
+	if len(report) != 1 || report[0].Requested != "Helvetica" || report[0].Context != "render" {
+		t.Errorf("Expected one render substitution for Helvetica, got %+v", report)
+	}
+}
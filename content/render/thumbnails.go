@@ -0,0 +1,64 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/core/manipulate"
+)
+
+// RenderThumbnails renders a preview image for every page of a PDF
+func RenderThumbnails(pdfBytes []byte, password []byte, opts RenderOptions, verbose bool) ([]*image.RGBA, error) {
+	doc, err := extract.ExtractContent(pdfBytes, password, verbose)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract content: %w", err)
+	}
+
+	thumbs := make([]*image.RGBA, 0, len(doc.Pages))
+	for i := range doc.Pages {
+		thumbs = append(thumbs, RenderPage(&doc.Pages[i], opts))
+	}
+	return thumbs, nil
+}
+
+// ExportThumbnailsPNG renders every page and returns PNG-encoded previews in page order
+func ExportThumbnailsPNG(pdfBytes []byte, password []byte, opts RenderOptions, verbose bool) ([][]byte, error) {
+	thumbs, err := RenderThumbnails(pdfBytes, password, opts, verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	pngs := make([][]byte, 0, len(thumbs))
+	for i, img := range thumbs {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, fmt.Errorf("failed to encode thumbnail for page %d: %w", i+1, err)
+		}
+		pngs = append(pngs, buf.Bytes())
+	}
+	return pngs, nil
+}
+
+// EmbedThumbnails renders every page and stores the result as the page's /Thumb image
+func EmbedThumbnails(pdfBytes []byte, password []byte, opts RenderOptions, verbose bool) ([]byte, error) {
+	thumbs, err := RenderThumbnails(pdfBytes, password, opts, verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := manipulate.NewPDFManipulator(pdfBytes, password, verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, img := range thumbs {
+		if err := m.SetPageThumbnail(i+1, img); err != nil {
+			return nil, fmt.Errorf("failed to set thumbnail for page %d: %w", i+1, err)
+		}
+	}
+
+	return m.Rebuild()
+}
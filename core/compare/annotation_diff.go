@@ -0,0 +1,140 @@
+package compare
+
+import (
+	"fmt"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// annotationTolerance is the distance in points within which annotation
+// rectangles are considered unchanged
+const annotationTolerance = 0.5
+
+// compareAnnotations compares the annotations of two pages. Annotations are
+// matched in passes: by unique name (/NM), by type and rectangle, by type and
+// contents (an annotation that moved), and finally by type and author in page
+// order (an annotation that was moved and edited). Matched annotations whose
+// properties differ are reported as modified; the rest as added or removed.
+// Colors are compared within opts.ColorTolerance.
+func compareAnnotations(a1, a2 []types.Annotation, opts CompareOptions) *AnnotationDiff {
+	diff := &AnnotationDiff{
+		Added:    []types.Annotation{},
+		Removed:  []types.Annotation{},
+		Modified: []AnnotationChange{},
+	}
+
+	matched1 := make([]bool, len(a1))
+	matched2 := make([]bool, len(a2))
+	match := func(same func(x, y types.Annotation) bool) {
+		for i, x := range a1 {
+			if matched1[i] {
+				continue
+			}
+			for j, y := range a2 {
+				if matched2[j] || x.Type != y.Type || !same(x, y) {
+					continue
+				}
+				matched1[i], matched2[j] = true, true
+				if changes := annotationChanges(x, y, opts); len(changes) > 0 {
+					diff.Modified = append(diff.Modified, AnnotationChange{Old: x, New: y, Changes: changes})
+				}
+				break
+			}
+		}
+	}
+
+	match(func(x, y types.Annotation) bool { return x.Name != "" && x.Name == y.Name })
+	match(func(x, y types.Annotation) bool { return rectsMatch(x.Rect, y.Rect) })
+	match(func(x, y types.Annotation) bool { return x.Contents != "" && x.Contents == y.Contents })
+	match(func(x, y types.Annotation) bool { return x.Title != "" && x.Title == y.Title })
+
+	for i, a := range a1 {
+		if !matched1[i] {
+			diff.Removed = append(diff.Removed, a)
+		}
+	}
+	for j, a := range a2 {
+		if !matched2[j] {
+			diff.Added = append(diff.Added, a)
+		}
+	}
+
+	if len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Modified) == 0 {
+		return nil
+	}
+
+	return diff
+}
+
+// annotationChanges lists the properties that differ between two matched
+// annotations
+func annotationChanges(before, after types.Annotation, opts CompareOptions) []PropertyDiff {
+	var changes []PropertyDiff
+	if !rectsMatch(before.Rect, after.Rect) {
+		changes = append(changes, PropertyDiff{Property: AnnotationPropertyRect, OldValue: before.Rect, NewValue: after.Rect})
+	}
+	fields := []struct {
+		property AnnotationProperty
+		old, new string
+	}{
+		{AnnotationPropertyContents, before.Contents, after.Contents},
+		{AnnotationPropertyAuthor, before.Title, after.Title},
+		{AnnotationPropertySubject, before.Subject, after.Subject},
+	}
+	for _, s := range fields {
+		if s.old != s.new {
+			changes = append(changes, PropertyDiff{Property: s.property, OldValue: s.old, NewValue: s.new})
+		}
+	}
+	if !colorsMatch(before.Color, after.Color, opts.ColorTolerance) {
+		changes = append(changes, PropertyDiff{Property: AnnotationPropertyColor, OldValue: before.Color, NewValue: after.Color})
+	}
+	if before.Modified != after.Modified {
+		changes = append(changes, PropertyDiff{Property: AnnotationPropertyModified, OldValue: before.Modified, NewValue: after.Modified})
+	}
+	return changes
+}
+
+// rectsMatch reports whether two rectangles are equal within annotationTolerance;
+// two missing rectangles match
+func rectsMatch(r1, r2 *types.Rectangle) bool {
+	if r1 == nil || r2 == nil {
+		return r1 == nil && r2 == nil
+	}
+	return positionsMatch(r1.LowerX, r1.LowerY, r2.LowerX, r2.LowerY, annotationTolerance) &&
+		positionsMatch(r1.UpperX, r1.UpperY, r2.UpperX, r2.UpperY, annotationTolerance)
+}
+
+// describePropertyDiff formats a property change for reports
+func describePropertyDiff(p PropertyDiff) string {
+	return fmt.Sprintf("%s: %v -> %v", p.Property, propertyValue(p.OldValue), propertyValue(p.NewValue))
+}
+
+// propertyValue formats a property value for reports
+func propertyValue(v interface{}) string {
+	switch v := v.(type) {
+	case *types.Rectangle:
+		if v == nil {
+			return "none"
+		}
+		return fmt.Sprintf("[%.1f %.1f %.1f %.1f]", v.LowerX, v.LowerY, v.UpperX, v.UpperY)
+	case *types.Color:
+		if v == nil {
+			return "none"
+		}
+		switch v.Space {
+		case types.ColorSpaceCMYK:
+			return fmt.Sprintf("cmyk(%g %g %g %g)", v.C, v.M, v.Y, v.K)
+		case types.ColorSpaceRGB:
+			return fmt.Sprintf("rgb(%g %g %g)", v.R, v.G, v.B)
+		case types.ColorSpaceLab:
+			return fmt.Sprintf("lab(%g %g %g)", v.L, v.A, v.BB)
+		default:
+			return fmt.Sprintf("%s(%g)", v.Space, v.R)
+		}
+	case string:
+		return fmt.Sprintf("%q", v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
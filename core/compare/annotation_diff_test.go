@@ -0,0 +1,113 @@
+package compare
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+func note(contents, author string, x float64) types.Annotation {
+	return types.Annotation{
+		Type:     types.AnnotationTypeText,
+		Rect:     &types.Rectangle{LowerX: x, LowerY: 100, UpperX: x + 20, UpperY: 120},
+		Contents: contents,
+		Title:    author,
+	}
+}
+
+func TestCompareAnnotations_Identical(t *testing.T) {
+	a := []types.Annotation{note("Check", "Ana", 10), note("Fix", "Ben", 50)}
+	if diff := compareAnnotations(a, a, DefaultCompareOptions()); diff != nil {
+		t.Errorf("Expected no diff, got %+v", diff)
+	}
+}
+
+func TestCompareAnnotations_PropertyChanges(t *testing.T) {
+	old := note("Check the total", "Ana", 10)
+	old.Modified = "D:20240101000000Z"
+	edited := note("Check the grand total", "Ana", 10)
+	edited.Modified = "D:20240201000000Z"
+	edited.Color = &types.Color{Space: types.ColorSpaceRGB, R: 1}
+
+	diff := compareAnnotations([]types.Annotation{old}, []types.Annotation{edited}, DefaultCompareOptions())
+	if diff == nil || len(diff.Modified) != 1 || len(diff.Added) != 0 || len(diff.Removed) != 0 {
+		t.Fatalf("Expected one modification, got %+v", diff)
+	}
+	got := map[AnnotationProperty]PropertyDiff{}
+	for _, p := range diff.Modified[0].Changes {
+		got[p.Property] = p
+	}
+	if len(got) != 3 {
+		t.Errorf("Expected contents, color and modified changes, got %+v", diff.Modified[0].Changes)
+	}
+	if p := got[AnnotationPropertyContents]; p.OldValue != "Check the total" || p.NewValue != "Check the grand total" {
+		t.Errorf("Unexpected contents change %+v", p)
+	}
+	if p := got[AnnotationPropertyModified]; p.NewValue != "D:20240201000000Z" {
+		t.Errorf("Unexpected modified change %+v", p)
+	}
+	if _, ok := got[AnnotationPropertyColor]; !ok {
+		t.Error("Expected color change")
+	}
+}
+
+func TestCompareAnnotations_MatchesAcrossPositions(t *testing.T) {
+	old := []types.Annotation{note("Moved note", "Ana", 10), note("Edited note", "Ben", 50)}
+	moved := note("Moved note", "Ana", 300)
+	movedAndEdited := note("Edited and moved", "Ben", 400)
+
+	diff := compareAnnotations(old, []types.Annotation{movedAndEdited, moved}, DefaultCompareOptions())
+	if diff == nil || len(diff.Added) != 0 || len(diff.Removed) != 0 || len(diff.Modified) != 2 {
+		t.Fatalf("Expected two modifications, got %+v", diff)
+	}
+	for _, change := range diff.Modified {
+		if change.Changes[0].Property != AnnotationPropertyRect {
+			t.Errorf("Expected a rect change first, got %+v", change.Changes)
+		}
+	}
+	if n := len(diff.Modified[1].Changes); n != 2 {
+		t.Errorf("Expected rect and contents changes for the edited note, got %+v", diff.Modified[1].Changes)
+	}
+}
+
+func TestCompareAnnotations_NameMatch(t *testing.T) {
+	old := note("Draft", "Ana", 10)
+	old.Name = "n1"
+	renamed := note("Final", "Dana", 200)
+	renamed.Name = "n1"
+	other := note("New", "Ben", 10)
+
+	diff := compareAnnotations([]types.Annotation{old}, []types.Annotation{other, renamed}, DefaultCompareOptions())
+	if diff == nil || len(diff.Modified) != 1 || len(diff.Added) != 1 || diff.Added[0].Contents != "New" {
+		t.Fatalf("Expected the named annotation to match, got %+v", diff)
+	}
+	if n := len(diff.Modified[0].Changes); n != 3 {
+		t.Errorf("Expected rect, contents and author changes, got %+v", diff.Modified[0].Changes)
+	}
+}
+
+func TestCompareAnnotations_AddedRemoved(t *testing.T) {
+	highlight := types.Annotation{Type: types.AnnotationTypeHighlight, Rect: &types.Rectangle{UpperX: 10, UpperY: 10}}
+	diff := compareAnnotations([]types.Annotation{note("Gone", "Ana", 10)}, []types.Annotation{highlight}, DefaultCompareOptions())
+	if diff == nil || len(diff.Added) != 1 || len(diff.Removed) != 1 || len(diff.Modified) != 0 {
+		t.Fatalf("Expected one added and one removed, got %+v", diff)
+	}
+}
+
+func TestGenerateReport_AnnotationChanges(t *testing.T) {
+	result := &ComparisonResult{
+		PageDiffs: []PageDifference{{
+			PageNumber: 1,
+			AnnotationDiff: &AnnotationDiff{Modified: []AnnotationChange{{
+				New:     note("New text", "Ana", 10),
+				Changes: []PropertyDiff{{Property: AnnotationPropertyContents, OldValue: "Old text", NewValue: "New text"}},
+			}}},
+		}},
+		Summary: ComparisonSummary{PagesChanged: true, TotalDifferences: 1},
+	}
+	report := GenerateReport(result)
+	if !strings.Contains(report, `contents: "Old text" -> "New text"`) {
+		t.Errorf("Report missing property change:\n%s", report)
+	}
+}
@@ -0,0 +1,72 @@
+package compare
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// compareAttachments compares the embedded files of two documents. Files are
+// matched by name, then files left over are matched by content hash (a file
+// that was renamed). Matched files whose content or name differ are reported
+// as modified; the rest as added or removed.
+func compareAttachments(a1, a2 []types.Attachment) *AttachmentDiff {
+	diff := &AttachmentDiff{
+		Added:    []types.Attachment{},
+		Removed:  []types.Attachment{},
+		Modified: []AttachmentChange{},
+	}
+
+	matched1 := make([]bool, len(a1))
+	matched2 := make([]bool, len(a2))
+	match := func(same func(x, y types.Attachment) bool) {
+		for i, x := range a1 {
+			if matched1[i] {
+				continue
+			}
+			for j, y := range a2 {
+				if matched2[j] || !same(x, y) {
+					continue
+				}
+				matched1[i], matched2[j] = true, true
+				if x.Name != y.Name || x.SHA256 != y.SHA256 {
+					diff.Modified = append(diff.Modified, AttachmentChange{Old: x, New: y})
+				}
+				break
+			}
+		}
+	}
+
+	match(func(x, y types.Attachment) bool { return x.Name == y.Name })
+	match(func(x, y types.Attachment) bool { return x.SHA256 != "" && x.SHA256 == y.SHA256 })
+
+	for i, a := range a1 {
+		if !matched1[i] {
+			diff.Removed = append(diff.Removed, a)
+		}
+	}
+	for j, a := range a2 {
+		if !matched2[j] {
+			diff.Added = append(diff.Added, a)
+		}
+	}
+
+	if len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Modified) == 0 {
+		return nil
+	}
+
+	return diff
+}
+
+// describeAttachmentChange formats an attachment change for reports
+func describeAttachmentChange(c AttachmentChange) string {
+	var parts []string
+	if c.Old.Name != c.New.Name {
+		parts = append(parts, fmt.Sprintf("renamed to %s", c.New.Name))
+	}
+	if c.Old.SHA256 != c.New.SHA256 {
+		parts = append(parts, fmt.Sprintf("content changed (%d -> %d bytes)", c.Old.Size, c.New.Size))
+	}
+	return fmt.Sprintf("%s: %s", c.Old.Name, strings.Join(parts, ", "))
+}
@@ -0,0 +1,86 @@
+package compare
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+func TestCompareAttachments(t *testing.T) {
+	old := []types.Attachment{
+		{Name: "data.csv", Size: 10, SHA256: "aaa"},
+		{Name: "notes.txt", Size: 5, SHA256: "bbb"},
+		{Name: "draft.txt", Size: 3, SHA256: "ccc"},
+		{Name: "same.bin", Size: 1, SHA256: "ddd"},
+	}
+	updated := []types.Attachment{
+		{Name: "data.csv", Size: 12, SHA256: "eee"},
+		{Name: "notes-v2.txt", Size: 5, SHA256: "bbb"},
+		{Name: "same.bin", Size: 1, SHA256: "ddd"},
+		{Name: "extra.json", Size: 2, SHA256: "fff"},
+	}
+
+	diff := compareAttachments(old, updated)
+	if diff == nil {
+		t.Fatal("Expected attachment differences")
+	}
+	if len(diff.Added) != 1 || diff.Added[0].Name != "extra.json" {
+		t.Errorf("Expected extra.json added, got %+v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Name != "draft.txt" {
+		t.Errorf("Expected draft.txt removed, got %+v", diff.Removed)
+	}
+	if len(diff.Modified) != 2 {
+		t.Fatalf("Expected 2 modified attachments, got %+v", diff.Modified)
+	}
+	if got := describeAttachmentChange(diff.Modified[0]); got != "data.csv: content changed (10 -> 12 bytes)" {
+		t.Errorf("describeAttachmentChange() = %q", got)
+	}
+	if got := describeAttachmentChange(diff.Modified[1]); got != "notes.txt: renamed to notes-v2.txt" {
+		t.Errorf("describeAttachmentChange() = %q", got)
+	}
+
+	if diff := compareAttachments(old, old); diff != nil {
+		t.Errorf("Expected no differences for identical attachments, got %+v", diff)
+	}
+}
+
+func TestComparePDFs_Attachments(t *testing.T) {
+	build := func(data string) []byte {
+		writer := write.NewPDFWriter()
+		catalogNum := writer.AddObject(nil)
+		pagesNum := writer.AddObject([]byte("<</Type/Pages/Kids []/Count 0>>"))
+		fileNum := writer.AddStreamObject(write.Dictionary{"Type": "/EmbeddedFile"}, []byte(data), true)
+		specNum := writer.AddObject([]byte(fmt.Sprintf("<</Type/Filespec/F(dataset.csv)/EF <</F %d 0 R>>>>", fileNum)))
+		writer.SetObject(catalogNum, []byte(fmt.Sprintf("<</Type/Catalog/Pages %d 0 R/Names <</EmbeddedFiles <</Names [(dataset.csv) %d 0 R]>>>>>>", pagesNum, specNum)))
+		writer.SetRoot(catalogNum)
+		pdf, err := writer.Bytes()
+		if err != nil {
+			t.Fatalf("Failed to build PDF: %v", err)
+		}
+		return pdf
+	}
+
+	pdf1, pdf2 := build("id,value\n1,42\n"), build("id,value\n1,43\n")
+	result, err := ComparePDFs(pdf1, pdf1, nil, nil, false)
+	if err != nil {
+		t.Fatalf("Failed to compare: %v", err)
+	}
+	if !result.Identical {
+		t.Errorf("Expected identical PDFs, got %+v", result.Differences)
+	}
+
+	result, err = ComparePDFs(pdf1, pdf2, nil, nil, false)
+	if err != nil {
+		t.Fatalf("Failed to compare: %v", err)
+	}
+	if result.Identical || result.AttachmentDiff == nil || len(result.AttachmentDiff.Modified) != 1 {
+		t.Fatalf("Expected a modified attachment, got %+v", result.AttachmentDiff)
+	}
+	if report := GenerateReport(result); !strings.Contains(report, "Modified: dataset.csv: content changed (14 -> 14 bytes)") {
+		t.Errorf("Report missing attachment change:\n%s", report)
+	}
+}
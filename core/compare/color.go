@@ -0,0 +1,91 @@
+package compare
+
+import (
+	"math"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// colorsMatch reports whether two colors are equal within tolerance; two
+// missing colors match. Colors in the same device space match when no component
+// differs by more than tolerance. Lab colors, and colors in different spaces,
+// are converted to CIE L*a*b* and match when their CIE76 delta-E is at most
+// tolerance*100, the same fraction of the L* range.
+func colorsMatch(c1, c2 *types.Color, tolerance float64) bool {
+	if c1 == nil || c2 == nil {
+		return c1 == nil && c2 == nil
+	}
+	s1, s2 := colorSpace(c1), colorSpace(c2)
+	if s1 == s2 && s1 != types.ColorSpaceLab {
+		return maxComponentDelta(c1, c2) <= tolerance
+	}
+	if tolerance == 0 && s1 == s2 {
+		return *c1 == *c2
+	}
+	return deltaE(toLab(c1), toLab(c2)) <= tolerance*100
+}
+
+// colorSpace returns the space of a color; colors from rg/RG operators have no
+// space set and are RGB
+func colorSpace(c *types.Color) types.ColorSpace {
+	if c.Space == "" {
+		return types.ColorSpaceRGB
+	}
+	return c.Space
+}
+
+// maxComponentDelta returns the largest difference between the components of
+// two colors in the same space
+func maxComponentDelta(c1, c2 *types.Color) float64 {
+	delta := 0.0
+	for _, d := range []float64{c1.R - c2.R, c1.G - c2.G, c1.B - c2.B, c1.C - c2.C, c1.M - c2.M, c1.Y - c2.Y, c1.K - c2.K} {
+		delta = math.Max(delta, math.Abs(d))
+	}
+	return delta
+}
+
+// lab is a color in CIE L*a*b* (D65 white point)
+type lab struct {
+	L, A, B float64
+}
+
+// deltaE returns the CIE76 color difference
+func deltaE(x, y lab) float64 {
+	return math.Sqrt((x.L-y.L)*(x.L-y.L) + (x.A-y.A)*(x.A-y.A) + (x.B-y.B)*(x.B-y.B))
+}
+
+// toLab converts a color to L*a*b*. Device colors are treated as sRGB; CMYK is
+// converted without a profile.
+func toLab(c *types.Color) lab {
+	var r, g, b float64
+	switch colorSpace(c) {
+	case types.ColorSpaceLab:
+		return lab{c.L, c.A, c.BB}
+	case types.ColorSpaceGray:
+		r, g, b = c.R, c.R, c.R
+	case types.ColorSpaceCMYK:
+		r, g, b = (1-c.C)*(1-c.K), (1-c.M)*(1-c.K), (1-c.Y)*(1-c.K)
+	default:
+		r, g, b = c.R, c.G, c.B
+	}
+
+	linear := func(v float64) float64 {
+		if v <= 0.04045 {
+			return v / 12.92
+		}
+		return math.Pow((v+0.055)/1.055, 2.4)
+	}
+	r, g, b = linear(r), linear(g), linear(b)
+	x := (0.4124*r + 0.3576*g + 0.1805*b) / 0.95047
+	y := 0.2126*r + 0.7152*g + 0.0722*b
+	z := (0.0193*r + 0.1192*g + 0.9505*b) / 1.08883
+
+	f := func(t float64) float64 {
+		if t > 216.0/24389 {
+			return math.Cbrt(t)
+		}
+		return (24389.0/27*t + 16) / 116
+	}
+	fx, fy, fz := f(x), f(y), f(z)
+	return lab{L: 116*fy - 16, A: 500 * (fx - fy), B: 200 * (fy - fz)}
+}
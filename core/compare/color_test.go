@@ -0,0 +1,61 @@
+package compare
+
+import (
+	"math"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+func TestColorsMatch(t *testing.T) {
+	white := &types.Color{R: 1, G: 1, B: 1}
+	nearWhite := &types.Color{R: 0.999, G: 1, B: 0.998}
+	tests := []struct {
+		name      string
+		c1, c2    *types.Color
+		tolerance float64
+		want      bool
+	}{
+		{"exact", white, white, 0, true},
+		{"slight change without tolerance", white, nearWhite, 0, false},
+		{"slight change within tolerance", white, nearWhite, 0.01, true},
+		{"large change", white, &types.Color{R: 0.9, G: 1, B: 1}, 0.01, false},
+		{"unset space is RGB", white, &types.Color{Space: types.ColorSpaceRGB, R: 1, G: 1, B: 1}, 0, true},
+		{"gray and RGB", &types.Color{Space: types.ColorSpaceGray, R: 0.5}, &types.Color{R: 0.5, G: 0.5, B: 0.5}, 0, true},
+		{"lab within delta-E", &types.Color{Space: types.ColorSpaceLab, L: 50, A: 10, BB: -5}, &types.Color{Space: types.ColorSpaceLab, L: 50.5, A: 10.3, BB: -5}, 0.01, true},
+		{"lab beyond delta-E", &types.Color{Space: types.ColorSpaceLab, L: 50}, &types.Color{Space: types.ColorSpaceLab, L: 55}, 0.01, false},
+		{"lab and RGB", &types.Color{Space: types.ColorSpaceLab, L: 100}, white, 0.01, true},
+		{"missing", nil, white, 1, false},
+		{"both missing", nil, nil, 0, true},
+	}
+	for _, tt := range tests {
+		if got := colorsMatch(tt.c1, tt.c2, tt.tolerance); got != tt.want {
+			t.Errorf("%s: colorsMatch = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestToLab(t *testing.T) {
+	white := toLab(&types.Color{R: 1, G: 1, B: 1})
+	if math.Abs(white.L-100) > 0.1 || math.Abs(white.A) > 0.1 || math.Abs(white.B) > 0.1 {
+		t.Errorf("White = %+v, want L=100 a=0 b=0", white)
+	}
+	red := toLab(&types.Color{R: 1})
+	if math.Abs(red.L-53.2) > 0.5 || math.Abs(red.A-80.1) > 0.5 || math.Abs(red.B-67.2) > 0.5 {
+		t.Errorf("Red = %+v, want about L=53.2 a=80.1 b=67.2", red)
+	}
+}
+
+func TestCompareGraphics_ColorTolerance(t *testing.T) {
+	old := []types.Graphic{rect(100, 100, 50, 50, &types.Color{R: 1})}
+	converted := []types.Graphic{rect(100, 100, 50, 50, &types.Color{R: 0.999, G: 0.002})}
+
+	opts := DefaultCompareOptions()
+	if diff := compareGraphics(old, converted, opts); diff == nil || diff.Modified[0].Kind != GraphicRestyled {
+		t.Errorf("Expected a restyle without tolerance, got %+v", diff)
+	}
+	opts.ColorTolerance = 0.01
+	if diff := compareGraphics(old, converted, opts); diff != nil {
+		t.Errorf("Expected no change within tolerance, got %+v", diff)
+	}
+}
@@ -4,21 +4,28 @@ import (
 	"bytes"
 	"fmt"
 	"reflect"
+	"time"
 
 	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/core/dates"
 	"github.com/benedoc-inc/pdfer/forms"
 	"github.com/benedoc-inc/pdfer/types"
 )
 
 // ComparisonResult represents the result of comparing two PDFs
 type ComparisonResult struct {
-	Identical     bool                 `json:"identical"`
-	Differences   []Difference         `json:"differences"`
-	Summary       ComparisonSummary    `json:"summary"`
-	MetadataDiff  *MetadataDifference  `json:"metadata_diff,omitempty"`
-	PageDiffs     []PageDifference     `json:"page_diffs,omitempty"`
-	StructureDiff *StructureDifference `json:"structure_diff,omitempty"`
-	FormDiff      *FormDiff            `json:"form_diff,omitempty"`
+	Identical      bool                 `json:"identical"`
+	Differences    []Difference         `json:"differences"`
+	Summary        ComparisonSummary    `json:"summary"`
+	MetadataDiff   *MetadataDifference  `json:"metadata_diff,omitempty"`
+	PageDiffs      []PageDifference     `json:"page_diffs,omitempty"`
+	StructureDiff  *StructureDifference `json:"structure_diff,omitempty"`
+	FormDiff       *FormDiff            `json:"form_diff,omitempty"`
+	AttachmentDiff *AttachmentDiff      `json:"attachment_diff,omitempty"`
+	SignatureDiff  *SignatureDiff       `json:"signature_diff,omitempty"`
+
+	SimilarityScore float64              `json:"similarity_score"` // From 0 (entirely different) to 1 (identical)
+	Similarity      SimilarityComponents `json:"similarity"`       // Components SimilarityScore is computed from
 }
 
 // ComparisonSummary provides a high-level summary of differences
@@ -49,11 +56,14 @@ const (
 	DifferenceTypePageContent DifferenceType = "page_content"
 	DifferenceTypeStructure   DifferenceType = "structure"
 	DifferenceTypeText        DifferenceType = "text"
+	DifferenceTypeFont        DifferenceType = "font"
 	DifferenceTypeGraphic     DifferenceType = "graphic"
 	DifferenceTypeImage       DifferenceType = "image"
 	DifferenceTypeAnnotation  DifferenceType = "annotation"
 	DifferenceTypeBookmark    DifferenceType = "bookmark"
 	DifferenceTypeForm        DifferenceType = "form"
+	DifferenceTypeAttachment  DifferenceType = "attachment"
+	DifferenceTypeSignature   DifferenceType = "signature"
 )
 
 // MetadataDifference represents differences in document metadata
@@ -80,8 +90,10 @@ type FieldDiff struct {
 // PageDifference represents differences in a specific page
 type PageDifference struct {
 	PageNumber     int             `json:"page_number"`
+	NewPageNumber  int             `json:"new_page_number,omitempty"` // Page of the second document, when CompareOptions.PageMap paired a different one
 	Differences    []Difference    `json:"differences"`
 	TextDiff       *TextDiff       `json:"text_diff,omitempty"`
+	FontChanges    []FontChange    `json:"font_changes,omitempty"`
 	GraphicDiff    *GraphicDiff    `json:"graphic_diff,omitempty"`
 	ImageDiff      *ImageDiff      `json:"image_diff,omitempty"`
 	AnnotationDiff *AnnotationDiff `json:"annotation_diff,omitempty"`
@@ -100,10 +112,38 @@ type TextModification struct {
 	New types.TextElement `json:"new"`
 }
 
+// FontChange is text that appears unchanged at the same position in both
+// documents but is rendered with a different font, size or embedding. Fields
+// that did not change are nil.
+type FontChange struct {
+	Old      types.TextElement `json:"old"`
+	New      types.TextElement `json:"new"`
+	Font     *FieldDiff        `json:"font,omitempty"`     // Base font name, without a subset tag
+	Size     *FieldDiff        `json:"size,omitempty"`     // Font size in points
+	Embedded *FieldDiff        `json:"embedded,omitempty"` // Whether the font is embedded
+}
+
 // GraphicDiff represents differences in graphics
 type GraphicDiff struct {
-	Added   []types.Graphic `json:"added,omitempty"`
-	Removed []types.Graphic `json:"removed,omitempty"`
+	Added    []types.Graphic `json:"added,omitempty"`
+	Removed  []types.Graphic `json:"removed,omitempty"`
+	Modified []GraphicChange `json:"modified,omitempty"`
+}
+
+// GraphicChangeKind classifies a change to a graphic present in both documents
+type GraphicChangeKind string
+
+const (
+	GraphicRestyled GraphicChangeKind = "restyled" // Same shape and position; colors or line width changed
+	GraphicMoved    GraphicChangeKind = "moved"    // Same shape and style at a different position
+	GraphicReshaped GraphicChangeKind = "reshaped" // Same path operators and style; points or size changed
+)
+
+// GraphicChange is a graphic matched across documents and how it changed
+type GraphicChange struct {
+	Kind GraphicChangeKind `json:"kind"`
+	Old  types.Graphic     `json:"old"`
+	New  types.Graphic     `json:"new"`
 }
 
 // ImageDiff represents differences in images
@@ -124,8 +164,36 @@ type ImageModification struct {
 
 // AnnotationDiff represents differences in annotations
 type AnnotationDiff struct {
-	Added   []types.Annotation `json:"added,omitempty"`
-	Removed []types.Annotation `json:"removed,omitempty"`
+	Added    []types.Annotation `json:"added,omitempty"`
+	Removed  []types.Annotation `json:"removed,omitempty"`
+	Modified []AnnotationChange `json:"modified,omitempty"`
+}
+
+// AnnotationChange is an annotation present in both documents whose properties
+// changed
+type AnnotationChange struct {
+	Old     types.Annotation `json:"old"`
+	New     types.Annotation `json:"new"`
+	Changes []PropertyDiff   `json:"changes"`
+}
+
+// AnnotationProperty names an annotation property compared by compareAnnotations
+type AnnotationProperty string
+
+const (
+	AnnotationPropertyRect     AnnotationProperty = "rect"     // Position and size; values are *types.Rectangle
+	AnnotationPropertyContents AnnotationProperty = "contents" // Text of the annotation
+	AnnotationPropertyAuthor   AnnotationProperty = "author"   // Author (/T)
+	AnnotationPropertySubject  AnnotationProperty = "subject"  // Subject (/Subj)
+	AnnotationPropertyColor    AnnotationProperty = "color"    // Color (/C); values are *types.Color
+	AnnotationPropertyModified AnnotationProperty = "modified" // Modification date (/M)
+)
+
+// PropertyDiff is a change to a single property of a matched element
+type PropertyDiff struct {
+	Property AnnotationProperty `json:"property"`
+	OldValue interface{}        `json:"old_value,omitempty"`
+	NewValue interface{}        `json:"new_value,omitempty"`
 }
 
 // StructureDifference represents differences in PDF structure
@@ -150,6 +218,38 @@ type FormFieldChange struct {
 	FieldType string      `json:"field_type,omitempty"`
 }
 
+// AttachmentDiff represents differences in embedded files
+type AttachmentDiff struct {
+	Added    []types.Attachment `json:"added,omitempty"`
+	Removed  []types.Attachment `json:"removed,omitempty"`
+	Modified []AttachmentChange `json:"modified,omitempty"` // Same name with different content, or same content renamed
+}
+
+// AttachmentChange is an embedded file present in both documents that changed
+type AttachmentChange struct {
+	Old types.Attachment `json:"old"`
+	New types.Attachment `json:"new"`
+}
+
+// SignatureDiff represents differences in signatures. A signature is the same
+// in both documents when its field and signature value are. Signatures of
+// compared PDFs are verified with sign.Verify, apart from their certificate
+// chains; signatures compared with CompareContent only have their byte ranges
+// and signed bytes checked.
+type SignatureDiff struct {
+	Added                []types.Signature `json:"added,omitempty"`
+	Removed              []types.Signature `json:"removed,omitempty"`                // Removed, or replaced by a new signature in the same field
+	Invalidated          []SignatureChange `json:"invalidated,omitempty"`            // No longer verifies: signed bytes changed or the byte range no longer fits the file
+	ModifiedAfterSigning []SignatureChange `json:"modified_after_signing,omitempty"` // Still intact, but no longer covering the whole document
+}
+
+// SignatureChange is a signature present in both documents whose status changed
+type SignatureChange struct {
+	Old    types.Signature `json:"old"`
+	New    types.Signature `json:"new"`
+	Reason string          `json:"reason"`
+}
+
 // TextGranularity defines the level of detail for text comparison
 type TextGranularity string
 
@@ -171,22 +271,33 @@ const (
 // CompareOptions configures PDF comparison behavior
 type CompareOptions struct {
 	// Metadata options
-	IgnoreMetadata bool // Ignore metadata differences (Producer, CreationDate, etc.)
-	IgnoreProducer bool // Ignore Producer field differences
-	IgnoreDates    bool // Ignore CreationDate and ModDate differences
+	IgnoreMetadata bool          // Ignore metadata differences (Producer, CreationDate, etc.)
+	IgnoreProducer bool          // Ignore Producer field differences
+	IgnoreDates    bool          // Ignore CreationDate and ModDate differences
+	DateTolerance  time.Duration // Without IgnoreDates, dates at most this far apart are equal; dates are compared as instants, so the same time written in another zone or notation is equal (default: 0)
 
 	// Position tolerance
 	TextTolerance    float64 // Position tolerance for text matching (default: 5.0 points)
 	GraphicTolerance float64 // Position tolerance for graphic matching (default: 5.0 points)
+	ColorTolerance   float64 // Color difference treated as equal: per component (0-1), or delta-E/100 for Lab and mixed spaces (default: 0, exact)
 
 	// Text comparison granularity and specificity
-	TextGranularity    TextGranularity // Level of text comparison: element, word, or char (default: element)
-	DiffSensitivity    DiffSensitivity // How sensitive to changes: strict, normal, or relaxed (default: normal)
-	DetectMoves        bool            // Detect when text/images move (default: true)
-	MoveTolerance      float64         // Position tolerance for detecting moves (default: 10x TextTolerance)
-	MinChangeThreshold float64         // Minimum change percentage to report (0.0-1.0, default: 0.0 = report all)
-	IgnoreWhitespace   bool            // Ignore whitespace differences in text (default: false)
-	IgnoreCase         bool            // Case-insensitive text comparison (default: false)
+	TextGranularity    TextGranularity  // Level of text comparison: element, word, or char (default: element)
+	DiffSensitivity    DiffSensitivity  // How sensitive to changes: strict, normal, or relaxed (default: normal)
+	DetectMoves        bool             // Detect when text/images move (default: true)
+	MoveTolerance      float64          // Position tolerance for detecting moves (default: 10x TextTolerance)
+	MinChangeThreshold float64          // Minimum change percentage to report (0.0-1.0, default: 0.0 = report all)
+	IgnoreWhitespace   bool             // Ignore whitespace differences in text (default: false)
+	IgnoreCase         bool             // Case-insensitive text comparison (default: false)
+	TextNormalizers    []TextNormalizer // Normalizers applied in order to text before it is compared; results show the normalized text (default: none)
+
+	// Page selection
+	PageRanges []PageRange // Pages of the first document to compare, e.g. from ParsePageRanges("1-3,7") (default: all)
+	PageMap    map[int]int // Pages of the first document paired with a different page of the second, e.g. {4: 5} after a page was inserted before page 4; later pages follow the same offset, and {4: 3} reports page 3 as removed after it was deleted (default: none)
+
+	// Similarity scoring
+	SimilarityWeights SimilarityWeights // Weights of the components of ComparisonResult.SimilarityScore (default: DefaultSimilarityWeights)
+	SimilarityScorer  SimilarityScorer  // Computes ComparisonResult.SimilarityScore in place of the weighted mean (default: none)
 
 	// Performance options
 	Verbose bool // Enable verbose logging
@@ -220,6 +331,42 @@ func ComparePDFsWithOptions(pdf1Bytes, pdf2Bytes []byte, password1, password2 []
 		return nil, fmt.Errorf("failed to extract content from second PDF: %w", err)
 	}
 
+	result := compareDocuments(doc1, doc2, opts, pdf1Bytes, pdf2Bytes)
+
+	// Compare forms
+	formDiff := compareForms(pdf1Bytes, pdf2Bytes, password1, password2, opts.Verbose)
+	if formDiff != nil && (len(formDiff.Added) > 0 || len(formDiff.Removed) > 0 || len(formDiff.Modified) > 0 || formDiff.FormType != nil) {
+		result.FormDiff = formDiff
+		result.Differences = append(result.Differences, Difference{
+			Type:        DifferenceTypeForm,
+			Category:    "modified",
+			Description: fmt.Sprintf("Form fields changed: %d added, %d removed, %d modified", len(formDiff.Added), len(formDiff.Removed), len(formDiff.Modified)),
+		})
+		result.Summary.TotalDifferences++
+		result.Summary.ContentChanged = true
+	}
+
+	// Determine if identical
+	result.Identical = result.Summary.TotalDifferences == 0
+	scoreSimilarity(result, opts)
+
+	return result, nil
+}
+
+// CompareContent compares two previously extracted documents, such as ones
+// stored with types.MarshalContent. Images are compared by their extracted
+// metadata and data, signatures are not verified, and form fields are not
+// compared, as these need the PDFs.
+func CompareContent(doc1, doc2 *types.ContentDocument, opts CompareOptions) *ComparisonResult {
+	result := compareDocuments(doc1, doc2, opts, nil, nil)
+	result.Identical = result.Summary.TotalDifferences == 0
+	scoreSimilarity(result, opts)
+	return result
+}
+
+// compareDocuments compares the extracted content of two documents. The PDF
+// bytes, when given, are used to load image data for comparison.
+func compareDocuments(doc1, doc2 *types.ContentDocument, opts CompareOptions, pdf1Bytes, pdf2Bytes []byte) *ComparisonResult {
 	result := &ComparisonResult{
 		Differences: []Difference{},
 		Summary:     ComparisonSummary{},
@@ -259,23 +406,34 @@ func ComparePDFsWithOptions(pdf1Bytes, pdf2Bytes []byte, password1, password2 []
 		result.Summary.TotalDifferences++
 	}
 
-	// Compare forms
-	formDiff := compareForms(pdf1Bytes, pdf2Bytes, password1, password2, opts.Verbose)
-	if formDiff != nil && (len(formDiff.Added) > 0 || len(formDiff.Removed) > 0 || len(formDiff.Modified) > 0 || formDiff.FormType != nil) {
-		result.FormDiff = formDiff
+	// Compare signatures, verified when the PDF bytes are given
+	sigs1 := signatureStatuses(doc1.Signatures, pdf1Bytes, opts.Verbose)
+	sigs2 := signatureStatuses(doc2.Signatures, pdf2Bytes, opts.Verbose)
+	if signatureDiff := compareSignatures(sigs1, sigs2); signatureDiff != nil {
+		result.SignatureDiff = signatureDiff
 		result.Differences = append(result.Differences, Difference{
-			Type:        DifferenceTypeForm,
+			Type:     DifferenceTypeSignature,
+			Category: "modified",
+			Description: fmt.Sprintf("Signatures changed: %d added, %d removed, %d invalidated, %d modified after signing",
+				len(signatureDiff.Added), len(signatureDiff.Removed), len(signatureDiff.Invalidated), len(signatureDiff.ModifiedAfterSigning)),
+		})
+		result.Summary.TotalDifferences++
+	}
+
+	// Compare embedded files
+	if attachmentDiff := compareAttachments(doc1.Attachments, doc2.Attachments); attachmentDiff != nil {
+		result.AttachmentDiff = attachmentDiff
+		result.Differences = append(result.Differences, Difference{
+			Type:        DifferenceTypeAttachment,
 			Category:    "modified",
-			Description: fmt.Sprintf("Form fields changed: %d added, %d removed, %d modified", len(formDiff.Added), len(formDiff.Removed), len(formDiff.Modified)),
+			Description: fmt.Sprintf("Attachments changed: %d added, %d removed, %d modified", len(attachmentDiff.Added), len(attachmentDiff.Removed), len(attachmentDiff.Modified)),
 		})
 		result.Summary.TotalDifferences++
 		result.Summary.ContentChanged = true
 	}
 
-	// Determine if identical
-	result.Identical = result.Summary.TotalDifferences == 0
-
-	return result, nil
+	result.Similarity = similarityComponents(result, documentPageStats(doc1.Pages), documentPageStats(doc2.Pages), opts)
+	return result
 }
 
 // compareMetadata compares document metadata
@@ -320,11 +478,11 @@ func compareMetadata(m1, m2 *types.DocumentMetadata, opts CompareOptions) *Metad
 		hasDiff = true
 	}
 	if !opts.IgnoreDates {
-		if m1.CreationDate != m2.CreationDate {
+		if !dates.Equal(m1.CreationDate, m2.CreationDate, opts.DateTolerance) {
 			diff.CreationDate = &FieldDiff{OldValue: m1.CreationDate, NewValue: m2.CreationDate}
 			hasDiff = true
 		}
-		if m1.ModDate != m2.ModDate {
+		if !dates.Equal(m1.ModDate, m2.ModDate, opts.DateTolerance) {
 			diff.ModDate = &FieldDiff{OldValue: m1.ModDate, NewValue: m2.ModDate}
 			hasDiff = true
 		}
@@ -373,49 +531,9 @@ func compareStructure(doc1, doc2 *types.ContentDocument) *StructureDifference {
 
 // comparePages compares pages between two documents
 func comparePages(pages1, pages2 []types.Page, opts CompareOptions, pdf1Bytes, pdf2Bytes []byte) []PageDifference {
-	var diffs []PageDifference
-
-	// Compare up to the minimum page count
-	minPages := len(pages1)
-	if len(pages2) < minPages {
-		minPages = len(pages2)
-	}
-
-	for i := 0; i < minPages; i++ {
-		pageDiff := compareSinglePage(pages1[i], pages2[i], i+1, opts, pdf1Bytes, pdf2Bytes)
-		if pageDiff != nil && (len(pageDiff.Differences) > 0 || pageDiff.TextDiff != nil || pageDiff.GraphicDiff != nil || pageDiff.ImageDiff != nil || pageDiff.AnnotationDiff != nil) {
-			diffs = append(diffs, *pageDiff)
-		}
-	}
-
-	// Handle extra pages in either document
-	if len(pages1) > len(pages2) {
-		for i := len(pages2); i < len(pages1); i++ {
-			diffs = append(diffs, PageDifference{
-				PageNumber: i + 1,
-				Differences: []Difference{{
-					Type:        DifferenceTypePageContent,
-					Category:    "removed",
-					Description: fmt.Sprintf("Page %d removed in second PDF", i+1),
-					Location:    fmt.Sprintf("Page %d", i+1),
-				}},
-			})
-		}
-	} else if len(pages2) > len(pages1) {
-		for i := len(pages1); i < len(pages2); i++ {
-			diffs = append(diffs, PageDifference{
-				PageNumber: i + 1,
-				Differences: []Difference{{
-					Type:        DifferenceTypePageContent,
-					Category:    "added",
-					Description: fmt.Sprintf("Page %d added in second PDF", i+1),
-					Location:    fmt.Sprintf("Page %d", i+1),
-				}},
-			})
-		}
-	}
-
-	return diffs
+	return comparePagePairs(len(pages1), len(pages2), opts, func(i, j int) *PageDifference {
+		return compareSinglePage(pages1[i], pages2[j], i+1, opts, pdf1Bytes, pdf2Bytes)
+	})
 }
 
 // compareSinglePage compares a single page between two documents
@@ -425,13 +543,53 @@ func compareSinglePage(page1, page2 types.Page, pageNum int, opts CompareOptions
 		Differences: []Difference{},
 	}
 
+	comparePageLayout(diff, page1, page2, opts)
+
+	// Compare graphics
+	graphicDiff := compareGraphics(page1.Graphics, page2.Graphics, opts)
+	if graphicDiff != nil {
+		diff.GraphicDiff = graphicDiff
+		diff.Differences = append(diff.Differences, Difference{
+			Type:        DifferenceTypeGraphic,
+			Category:    "modified",
+			Description: fmt.Sprintf("Graphics changed: %d added, %d removed, %d modified", len(graphicDiff.Added), len(graphicDiff.Removed), len(graphicDiff.Modified)),
+			Location:    fmt.Sprintf("Page %d", pageNum),
+		})
+	}
+
+	// Compare images (with binary data comparison)
+	imageDiff := compareImagesWithBinary(page1.Images, page2.Images, page1.Resources, page2.Resources, pdf1Bytes, pdf2Bytes)
+	addImageDifference(diff, imageDiff, len(page1.Images), len(page2.Images))
+
+	// Compare annotations
+	annotationDiff := compareAnnotations(page1.Annotations, page2.Annotations, opts)
+	if annotationDiff != nil {
+		diff.AnnotationDiff = annotationDiff
+		diff.Differences = append(diff.Differences, Difference{
+			Type:        DifferenceTypeAnnotation,
+			Category:    "modified",
+			Description: fmt.Sprintf("Annotations changed: %d added, %d removed, %d modified", len(annotationDiff.Added), len(annotationDiff.Removed), len(annotationDiff.Modified)),
+			Location:    fmt.Sprintf("Page %d", pageNum),
+		})
+	}
+
+	if len(diff.Differences) == 0 && diff.TextDiff == nil && diff.GraphicDiff == nil && diff.ImageDiff == nil && diff.AnnotationDiff == nil {
+		return nil
+	}
+
+	return diff
+}
+
+// comparePageLayout adds the differences in the dimensions, rotation and text of
+// two pages to diff
+func comparePageLayout(diff *PageDifference, page1, page2 types.Page, opts CompareOptions) {
 	// Compare page dimensions
 	if page1.Width != page2.Width || page1.Height != page2.Height {
 		diff.Differences = append(diff.Differences, Difference{
 			Type:        DifferenceTypePageContent,
 			Category:    "modified",
 			Description: fmt.Sprintf("Page dimensions changed: %.2fx%.2f -> %.2fx%.2f", page1.Width, page1.Height, page2.Width, page2.Height),
-			Location:    fmt.Sprintf("Page %d", pageNum),
+			Location:    fmt.Sprintf("Page %d", diff.PageNumber),
 			OldValue:    fmt.Sprintf("%.2fx%.2f", page1.Width, page1.Height),
 			NewValue:    fmt.Sprintf("%.2fx%.2f", page2.Width, page2.Height),
 		})
@@ -443,38 +601,42 @@ func compareSinglePage(page1, page2 types.Page, pageNum int, opts CompareOptions
 			Type:        DifferenceTypePageContent,
 			Category:    "modified",
 			Description: fmt.Sprintf("Page rotation changed: %d° -> %d°", page1.Rotation, page2.Rotation),
-			Location:    fmt.Sprintf("Page %d", pageNum),
+			Location:    fmt.Sprintf("Page %d", diff.PageNumber),
 			OldValue:    page1.Rotation,
 			NewValue:    page2.Rotation,
 		})
 	}
 
-	// Compare text
-	textDiff := compareText(page1.Text, page2.Text, opts)
-	if textDiff != nil && (len(textDiff.Added) > 0 || len(textDiff.Removed) > 0 || len(textDiff.Modified) > 0) {
-		diff.TextDiff = textDiff
+	// Compare fonts of text that is otherwise unchanged, then the remaining text
+	page1.Text = normalizeTextElements(page1.Text, opts)
+	page2.Text = normalizeTextElements(page2.Text, opts)
+	fontChanges, text1, text2 := compareFonts(page1, page2, opts)
+	if len(fontChanges) > 0 {
+		diff.FontChanges = fontChanges
 		diff.Differences = append(diff.Differences, Difference{
-			Type:        DifferenceTypeText,
+			Type:        DifferenceTypeFont,
 			Category:    "modified",
-			Description: fmt.Sprintf("Text content changed: %d added, %d removed, %d modified", len(textDiff.Added), len(textDiff.Removed), len(textDiff.Modified)),
-			Location:    fmt.Sprintf("Page %d", pageNum),
+			Description: fmt.Sprintf("Font changed for %d text elements", len(fontChanges)),
+			Location:    fmt.Sprintf("Page %d", diff.PageNumber),
 		})
 	}
 
-	// Compare graphics
-	graphicDiff := compareGraphics(page1.Graphics, page2.Graphics)
-	if graphicDiff != nil && (len(graphicDiff.Added) > 0 || len(graphicDiff.Removed) > 0) {
-		diff.GraphicDiff = graphicDiff
+	// Compare text
+	textDiff := compareText(text1, text2, opts)
+	if textDiff != nil && (len(textDiff.Added) > 0 || len(textDiff.Removed) > 0 || len(textDiff.Modified) > 0) {
+		diff.TextDiff = textDiff
 		diff.Differences = append(diff.Differences, Difference{
-			Type:        DifferenceTypeGraphic,
+			Type:        DifferenceTypeText,
 			Category:    "modified",
-			Description: fmt.Sprintf("Graphics changed: %d added, %d removed", len(graphicDiff.Added), len(graphicDiff.Removed)),
-			Location:    fmt.Sprintf("Page %d", pageNum),
+			Description: fmt.Sprintf("Text content changed: %d added, %d removed, %d modified", len(textDiff.Added), len(textDiff.Removed), len(textDiff.Modified)),
+			Location:    fmt.Sprintf("Page %d", diff.PageNumber),
 		})
 	}
+}
 
-	// Compare images (with binary data comparison)
-	imageDiff := compareImagesWithBinary(page1.Images, page2.Images, page1.Resources, page2.Resources, pdf1Bytes, pdf2Bytes)
+// addImageDifference adds an image diff to a page difference when it has changes,
+// or notes a changed image count when it doesn't
+func addImageDifference(diff *PageDifference, imageDiff *ImageDiff, count1, count2 int) {
 	if imageDiff != nil && (len(imageDiff.Added) > 0 || len(imageDiff.Removed) > 0 || len(imageDiff.Modified) > 0 || len(imageDiff.Moved) > 0) {
 		diff.ImageDiff = imageDiff
 		desc := fmt.Sprintf("Images changed: %d added, %d removed, %d modified", len(imageDiff.Added), len(imageDiff.Removed), len(imageDiff.Modified))
@@ -485,37 +647,19 @@ func compareSinglePage(page1, page2 types.Page, pageNum int, opts CompareOptions
 			Type:        DifferenceTypeImage,
 			Category:    "modified",
 			Description: desc,
-			Location:    fmt.Sprintf("Page %d", pageNum),
+			Location:    fmt.Sprintf("Page %d", diff.PageNumber),
 		})
-	} else if len(page1.Images) != len(page2.Images) {
+	} else if count1 != count2 {
 		// Different number of images even if diff is nil (might be due to extraction issues)
 		diff.Differences = append(diff.Differences, Difference{
 			Type:        DifferenceTypeImage,
 			Category:    "modified",
-			Description: fmt.Sprintf("Image count changed: %d -> %d", len(page1.Images), len(page2.Images)),
-			Location:    fmt.Sprintf("Page %d", pageNum),
-			OldValue:    len(page1.Images),
-			NewValue:    len(page2.Images),
+			Description: fmt.Sprintf("Image count changed: %d -> %d", count1, count2),
+			Location:    fmt.Sprintf("Page %d", diff.PageNumber),
+			OldValue:    count1,
+			NewValue:    count2,
 		})
 	}
-
-	// Compare annotations
-	annotationDiff := compareAnnotations(page1.Annotations, page2.Annotations)
-	if annotationDiff != nil && (len(annotationDiff.Added) > 0 || len(annotationDiff.Removed) > 0) {
-		diff.AnnotationDiff = annotationDiff
-		diff.Differences = append(diff.Differences, Difference{
-			Type:        DifferenceTypeAnnotation,
-			Category:    "modified",
-			Description: fmt.Sprintf("Annotations changed: %d added, %d removed", len(annotationDiff.Added), len(annotationDiff.Removed)),
-			Location:    fmt.Sprintf("Page %d", pageNum),
-		})
-	}
-
-	if len(diff.Differences) == 0 && diff.TextDiff == nil && diff.GraphicDiff == nil && diff.ImageDiff == nil && diff.AnnotationDiff == nil {
-		return nil
-	}
-
-	return diff
 }
 
 // compareText compares text elements between two pages
@@ -543,51 +687,6 @@ func abs(x float64) float64 {
 	return x
 }
 
-// compareGraphics compares graphics between two pages
-func compareGraphics(g1, g2 []types.Graphic) *GraphicDiff {
-	diff := &GraphicDiff{
-		Added:   []types.Graphic{},
-		Removed: []types.Graphic{},
-	}
-
-	// Simple comparison: compare by type and approximate position
-	// More sophisticated matching could use shape matching
-	g1Map := make(map[string]types.Graphic)
-	for _, g := range g1 {
-		key := graphicKey(g)
-		g1Map[key] = g
-	}
-
-	for _, g := range g2 {
-		key := graphicKey(g)
-		if _, exists := g1Map[key]; !exists {
-			diff.Added = append(diff.Added, g)
-		} else {
-			delete(g1Map, key)
-		}
-	}
-
-	// Remaining in g1Map are removed
-	for _, g := range g1Map {
-		diff.Removed = append(diff.Removed, g)
-	}
-
-	if len(diff.Added) == 0 && len(diff.Removed) == 0 {
-		return nil
-	}
-
-	return diff
-}
-
-// graphicKey generates a key for a graphic element for comparison
-func graphicKey(g types.Graphic) string {
-	// Use type and bounding box for key
-	if g.BoundingBox != nil {
-		return fmt.Sprintf("%s:%.1f:%.1f:%.1f:%.1f", g.Type, g.BoundingBox.LowerX, g.BoundingBox.LowerY, g.BoundingBox.UpperX, g.BoundingBox.UpperY)
-	}
-	return fmt.Sprintf("%s", g.Type)
-}
-
 // compareImagesWithBinary compares images between two pages, including binary data
 func compareImagesWithBinary(img1, img2 []types.ImageRef, resources1, resources2 *types.PageResources, pdf1Bytes, pdf2Bytes []byte) *ImageDiff {
 	diff := &ImageDiff{
@@ -790,49 +889,6 @@ func imagesEqual(img1, img2 *types.Image) bool {
 	return bytes.Equal(img1.Data, img2.Data)
 }
 
-// compareAnnotations compares annotations between two pages
-func compareAnnotations(a1, a2 []types.Annotation) *AnnotationDiff {
-	diff := &AnnotationDiff{
-		Added:   []types.Annotation{},
-		Removed: []types.Annotation{},
-	}
-
-	// Compare by type and position
-	a1Map := make(map[string]types.Annotation)
-	for _, a := range a1 {
-		key := annotationKey(a)
-		a1Map[key] = a
-	}
-
-	for _, a := range a2 {
-		key := annotationKey(a)
-		if _, exists := a1Map[key]; !exists {
-			diff.Added = append(diff.Added, a)
-		} else {
-			delete(a1Map, key)
-		}
-	}
-
-	// Remaining in a1Map are removed
-	for _, a := range a1Map {
-		diff.Removed = append(diff.Removed, a)
-	}
-
-	if len(diff.Added) == 0 && len(diff.Removed) == 0 {
-		return nil
-	}
-
-	return diff
-}
-
-// annotationKey generates a key for an annotation for comparison
-func annotationKey(a types.Annotation) string {
-	if a.Rect != nil {
-		return fmt.Sprintf("%s:%.1f:%.1f:%.1f:%.1f", a.Type, a.Rect.LowerX, a.Rect.LowerY, a.Rect.UpperX, a.Rect.UpperY)
-	}
-	return fmt.Sprintf("%s", a.Type)
-}
-
 // compareBookmarks compares bookmarks between two documents
 func compareBookmarks(b1, b2 []types.Bookmark) *Difference {
 	if len(b1) != len(b2) {
@@ -845,8 +901,9 @@ func compareBookmarks(b1, b2 []types.Bookmark) *Difference {
 		}
 	}
 
-	// Simple comparison - could be more sophisticated
-	if !reflect.DeepEqual(b1, b2) {
+	// Simple comparison - could be more sophisticated. Empty lists are equal
+	// whether nil or not, as they are after a JSON round trip.
+	if len(b1) > 0 && !reflect.DeepEqual(b1, b2) {
 		return &Difference{
 			Type:        DifferenceTypeBookmark,
 			Category:    "modified",
@@ -859,25 +916,35 @@ func compareBookmarks(b1, b2 []types.Bookmark) *Difference {
 
 // compareForms compares form fields between two PDFs
 func compareForms(pdf1Bytes, pdf2Bytes []byte, password1, password2 []byte, verbose bool) *FormDiff {
+	// Extract forms from both PDFs
+	var type1, type2 string
+	var values1, values2 map[string]interface{}
+	if form1, err := forms.Extract(pdf1Bytes, password1, verbose); err == nil {
+		type1, values1 = string(form1.Type()), form1.GetValues()
+	}
+	if form2, err := forms.Extract(pdf2Bytes, password2, verbose); err == nil {
+		type2, values2 = string(form2.Type()), form2.GetValues()
+	}
+	return compareFormValues(type1, values1, type2, values2)
+}
+
+// compareFormValues compares the field values of two forms; an empty form type
+// means the document has no form
+func compareFormValues(type1 string, values1 map[string]interface{}, type2 string, values2 map[string]interface{}) *FormDiff {
 	diff := &FormDiff{
 		Added:    []FormFieldChange{},
 		Removed:  []FormFieldChange{},
 		Modified: []FormFieldChange{},
 	}
 
-	// Extract forms from both PDFs
-	form1, err1 := forms.Extract(pdf1Bytes, password1, verbose)
-	form2, err2 := forms.Extract(pdf2Bytes, password2, verbose)
-
 	// If neither PDF has forms, no differences
-	if err1 != nil && err2 != nil {
+	if type1 == "" && type2 == "" {
 		return nil
 	}
 
 	// If one has a form and the other doesn't, that's a difference
-	if err1 != nil && err2 == nil {
+	if type1 == "" {
 		// PDF1 has no form, PDF2 has form - all fields in PDF2 are "added"
-		values2 := form2.GetValues()
 		for name, value := range values2 {
 			diff.Added = append(diff.Added, FormFieldChange{
 				FieldName: name,
@@ -887,9 +954,8 @@ func compareForms(pdf1Bytes, pdf2Bytes []byte, password1, password2 []byte, verb
 		return diff
 	}
 
-	if err1 == nil && err2 != nil {
+	if type2 == "" {
 		// PDF1 has form, PDF2 has no form - all fields in PDF1 are "removed"
-		values1 := form1.GetValues()
 		for name, value := range values1 {
 			diff.Removed = append(diff.Removed, FormFieldChange{
 				FieldName: name,
@@ -900,14 +966,12 @@ func compareForms(pdf1Bytes, pdf2Bytes []byte, password1, password2 []byte, verb
 	}
 
 	// Both have forms - compare form types
-	if form1.Type() != form2.Type() {
+	if type1 != type2 {
 		diff.FormType = &FieldDiff{
-			OldValue: string(form1.Type()),
-			NewValue: string(form2.Type()),
+			OldValue: type1,
+			NewValue: type2,
 		}
 		// If form types differ, treat all fields as changed
-		values1 := form1.GetValues()
-		values2 := form2.GetValues()
 		for name, value := range values1 {
 			diff.Removed = append(diff.Removed, FormFieldChange{
 				FieldName: name,
@@ -923,10 +987,6 @@ func compareForms(pdf1Bytes, pdf2Bytes []byte, password1, password2 []byte, verb
 		return diff
 	}
 
-	// Same form type - compare field values
-	values1 := form1.GetValues()
-	values2 := form2.GetValues()
-
 	// Find added, removed, and modified fields
 	// Fields in PDF2 but not in PDF1
 	for name, value := range values2 {
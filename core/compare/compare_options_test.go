@@ -2,8 +2,10 @@ package compare
 
 import (
 	"testing"
+	"time"
 
 	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
 )
 
 func TestCompareOptions_TextTolerance(t *testing.T) {
@@ -568,3 +570,23 @@ func TestCompareOptions_GraphicsChanges(t *testing.T) {
 		}
 	}
 }
+
+func TestCompareMetadata_DateTolerance(t *testing.T) {
+	m1 := &types.DocumentMetadata{CreationDate: "D:20240115103000Z", ModDate: "D:20240115103000Z"}
+	m2 := &types.DocumentMetadata{CreationDate: "D:20240115113000+01'00'", ModDate: "D:20240115103030Z"}
+
+	opts := DefaultCompareOptions()
+	opts.IgnoreDates = false
+	diff := compareMetadata(m1, m2, opts)
+	if diff == nil || diff.ModDate == nil {
+		t.Fatalf("Expected a ModDate difference, got %+v", diff)
+	}
+	if diff.CreationDate != nil {
+		t.Errorf("The same instant in another zone reported as a difference: %+v", diff.CreationDate)
+	}
+
+	opts.DateTolerance = time.Minute
+	if diff := compareMetadata(m1, m2, opts); diff != nil {
+		t.Errorf("Dates within the tolerance reported as different: %+v", diff)
+	}
+}
@@ -0,0 +1,96 @@
+package compare
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// subsetTag matches the "ABCDEF+" prefix of a subset font's name
+var subsetTag = regexp.MustCompile(`^[A-Z]{6}\+`)
+
+// pageFont describes the font of a text element
+type pageFont struct {
+	name     string
+	embedded bool
+	known    bool // Whether the font was found in the page resources
+}
+
+// textFont resolves the font resource of a text element to its base font name
+// and embedding. Without resources, as for snapshots, the resource name is used.
+func textFont(t types.TextElement, resources *types.PageResources) pageFont {
+	if resources != nil {
+		if info, ok := resources.Fonts[strings.TrimPrefix(t.FontName, "/")]; ok {
+			return pageFont{name: subsetTag.ReplaceAllString(strings.TrimPrefix(info.Name, "/"), ""), embedded: info.Embedded, known: true}
+		}
+	}
+	return pageFont{name: t.FontName}
+}
+
+// compareFonts pairs text elements with the same text at the same position and
+// reports those whose font, size or embedding changed. Pairs that differ only in
+// font resource name, such as /F1 and /F2 both naming Helvetica, are unchanged.
+// The elements left unpaired are returned for text comparison.
+func compareFonts(page1, page2 types.Page, opts CompareOptions) ([]FontChange, []types.TextElement, []types.TextElement) {
+	var changes []FontChange
+	matched1 := make([]bool, len(page1.Text))
+	matched2 := make([]bool, len(page2.Text))
+
+	for i, t1 := range page1.Text {
+		for j, t2 := range page2.Text {
+			if matched2[j] || t1.Text != t2.Text || !positionsMatch(t1.X, t1.Y, t2.X, t2.Y, opts.TextTolerance) {
+				continue
+			}
+			f1, f2 := textFont(t1, page1.Resources), textFont(t2, page2.Resources)
+			change := FontChange{Old: t1, New: t2}
+			if f1.name != f2.name {
+				change.Font = &FieldDiff{OldValue: f1.name, NewValue: f2.name}
+			}
+			if abs(t1.FontSize-t2.FontSize) >= 0.1 {
+				change.Size = &FieldDiff{OldValue: t1.FontSize, NewValue: t2.FontSize}
+			}
+			if f1.known && f2.known && f1.embedded != f2.embedded {
+				change.Embedded = &FieldDiff{OldValue: f1.embedded, NewValue: f2.embedded}
+			}
+			if change.Font != nil || change.Size != nil || change.Embedded != nil {
+				changes = append(changes, change)
+			}
+			matched1[i], matched2[j] = true, true
+			break
+		}
+	}
+
+	var rest1, rest2 []types.TextElement
+	for i, t := range page1.Text {
+		if !matched1[i] {
+			rest1 = append(rest1, t)
+		}
+	}
+	for j, t := range page2.Text {
+		if !matched2[j] {
+			rest2 = append(rest2, t)
+		}
+	}
+	return changes, rest1, rest2
+}
+
+// describeFontChange formats a font change for reports
+func describeFontChange(c FontChange) string {
+	var parts []string
+	if c.Font != nil {
+		parts = append(parts, fmt.Sprintf("font %v -> %v", c.Font.OldValue, c.Font.NewValue))
+	}
+	if c.Size != nil {
+		parts = append(parts, fmt.Sprintf("size %v -> %v", c.Size.OldValue, c.Size.NewValue))
+	}
+	if c.Embedded != nil {
+		if c.Embedded.NewValue == true {
+			parts = append(parts, "now embedded")
+		} else {
+			parts = append(parts, "no longer embedded")
+		}
+	}
+	return fmt.Sprintf("%q: %s", c.New.Text, strings.Join(parts, ", "))
+}
@@ -0,0 +1,92 @@
+package compare
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+func fontPage(fonts map[string]types.FontInfo, text ...types.TextElement) types.Page {
+	return types.Page{Text: text, Resources: &types.PageResources{Fonts: fonts}}
+}
+
+func TestCompareFonts(t *testing.T) {
+	helvetica := map[string]types.FontInfo{"F1": {ID: "/F1", Name: "Helvetica"}}
+	page1 := fontPage(helvetica,
+		types.TextElement{Text: "Title", FontName: "/F1", FontSize: 18, X: 72, Y: 720},
+		types.TextElement{Text: "Body", FontName: "/F1", FontSize: 12, X: 72, Y: 700},
+		types.TextElement{Text: "Old", FontName: "/F1", FontSize: 12, X: 72, Y: 680},
+	)
+	page2 := fontPage(map[string]types.FontInfo{
+		"F1": {ID: "/F1", Name: "ABCDEF+Times-Roman", Embedded: true},
+		"F2": {ID: "/F2", Name: "Helvetica"},
+	},
+		types.TextElement{Text: "Title", FontName: "/F1", FontSize: 18, X: 72, Y: 720},
+		types.TextElement{Text: "Body", FontName: "/F2", FontSize: 12, X: 72, Y: 700},
+		types.TextElement{Text: "New", FontName: "/F2", FontSize: 12, X: 72, Y: 680},
+	)
+
+	changes, rest1, rest2 := compareFonts(page1, page2, DefaultCompareOptions())
+	if len(changes) != 1 {
+		t.Fatalf("Expected 1 font change, got %d: %+v", len(changes), changes)
+	}
+	c := changes[0]
+	if c.New.Text != "Title" || c.Font == nil || c.Font.NewValue != "Times-Roman" || c.Embedded == nil || c.Size != nil {
+		t.Errorf("Unexpected font change: %+v", c)
+	}
+	if len(rest1) != 1 || rest1[0].Text != "Old" || len(rest2) != 1 || rest2[0].Text != "New" {
+		t.Errorf("Expected only the edited text to remain, got %v and %v", rest1, rest2)
+	}
+	if got := describeFontChange(c); got != `"Title": font Helvetica -> Times-Roman, now embedded` {
+		t.Errorf("describeFontChange() = %q", got)
+	}
+}
+
+func TestCompareFonts_SizeWithoutResources(t *testing.T) {
+	page1 := types.Page{Text: []types.TextElement{{Text: "Note", FontName: "/F1", FontSize: 10, X: 72, Y: 100}}}
+	page2 := types.Page{Text: []types.TextElement{{Text: "Note", FontName: "/F1", FontSize: 11, X: 72, Y: 100}}}
+
+	changes, _, _ := compareFonts(page1, page2, DefaultCompareOptions())
+	if len(changes) != 1 || changes[0].Size == nil || changes[0].Font != nil || changes[0].Embedded != nil {
+		t.Errorf("Expected a size-only change, got %+v", changes)
+	}
+}
+
+func TestComparePDFs_FontChange(t *testing.T) {
+	build := func(font string) []byte {
+		builder := write.NewSimplePDFBuilder()
+		page := builder.AddPage(write.PageSizeLetter)
+		content := page.Content()
+		content.BeginText()
+		content.SetFont(page.AddStandardFont(font), 12)
+		content.SetTextPosition(72, 720)
+		content.ShowText("Same words")
+		content.EndText()
+		builder.FinalizePage(page)
+		pdf, err := builder.Bytes()
+		if err != nil {
+			t.Fatalf("Failed to build PDF: %v", err)
+		}
+		return pdf
+	}
+
+	result, err := ComparePDFs(build("Helvetica"), build("Times-Roman"), nil, nil, false)
+	if err != nil {
+		t.Fatalf("Failed to compare: %v", err)
+	}
+	if result.Identical || len(result.PageDiffs) != 1 {
+		t.Fatalf("Expected one page difference, got %+v", result.PageDiffs)
+	}
+	pd := result.PageDiffs[0]
+	if len(pd.FontChanges) != 1 || pd.TextDiff != nil {
+		t.Fatalf("Expected a font change and no text diff, got fonts=%+v text=%+v", pd.FontChanges, pd.TextDiff)
+	}
+	if pd.Differences[0].Type != DifferenceTypeFont {
+		t.Errorf("Expected a %s difference, got %s", DifferenceTypeFont, pd.Differences[0].Type)
+	}
+	if report := GenerateReport(result); !strings.Contains(report, "Font Changed: \"Same words\": font Helvetica -> Times-Roman") {
+		t.Errorf("Report missing font change:\n%s", report)
+	}
+}
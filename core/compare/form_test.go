@@ -53,12 +53,7 @@ func TestCompareForms_FormFieldValueChange(t *testing.T) {
 	field1 := fieldBuilder1.AddTextField("FirstName", []float64{72, 700, 300, 720}, 0)
 	field1.SetValue("John")
 	builder1.FinalizePage(page1)
-	acroFormNum1, _ := fieldBuilder1.Build()
-	w1 := builder1.Writer()
-	pagesObjNum1 := builder1.PagesObjNum()
-	catalogDict1 := fmt.Sprintf("<</Type/Catalog/Pages %d 0 R/AcroForm %d 0 R>>", pagesObjNum1, acroFormNum1)
-	catalogNum1 := w1.AddObject([]byte(catalogDict1))
-	w1.SetRoot(catalogNum1)
+	builder1.SetAcroForm(fieldBuilder1)
 	pdf1, _ := builder1.Bytes()
 
 	// Create second PDF with same form but different value
@@ -68,12 +63,7 @@ func TestCompareForms_FormFieldValueChange(t *testing.T) {
 	field2 := fieldBuilder2.AddTextField("FirstName", []float64{72, 700, 300, 720}, 0)
 	field2.SetValue("Jane")
 	builder2.FinalizePage(page2)
-	acroFormNum2, _ := fieldBuilder2.Build()
-	w2 := builder2.Writer()
-	pagesObjNum2 := builder2.PagesObjNum()
-	catalogDict2 := fmt.Sprintf("<</Type/Catalog/Pages %d 0 R/AcroForm %d 0 R>>", pagesObjNum2, acroFormNum2)
-	catalogNum2 := w2.AddObject([]byte(catalogDict2))
-	w2.SetRoot(catalogNum2)
+	builder2.SetAcroForm(fieldBuilder2)
 	pdf2, _ := builder2.Bytes()
 
 	result, err := ComparePDFs(pdf1, pdf2, nil, nil, false)
@@ -110,12 +100,7 @@ func TestCompareForms_FormFieldAdded(t *testing.T) {
 	field1 := fieldBuilder1.AddTextField("FirstName", []float64{72, 700, 300, 720}, 0)
 	field1.SetValue("John")
 	builder1.FinalizePage(page1)
-	acroFormNum1, _ := fieldBuilder1.Build()
-	w1 := builder1.Writer()
-	pagesObjNum1 := builder1.PagesObjNum()
-	catalogDict1 := fmt.Sprintf("<</Type/Catalog/Pages %d 0 R/AcroForm %d 0 R>>", pagesObjNum1, acroFormNum1)
-	catalogNum1 := w1.AddObject([]byte(catalogDict1))
-	w1.SetRoot(catalogNum1)
+	builder1.SetAcroForm(fieldBuilder1)
 	pdf1, _ := builder1.Bytes()
 
 	// Create second PDF with two form fields
@@ -127,12 +112,7 @@ func TestCompareForms_FormFieldAdded(t *testing.T) {
 	field2b := fieldBuilder2.AddTextField("LastName", []float64{72, 680, 300, 700}, 0)
 	field2b.SetValue("Doe")
 	builder2.FinalizePage(page2)
-	acroFormNum2, _ := fieldBuilder2.Build()
-	w2 := builder2.Writer()
-	pagesObjNum2 := builder2.PagesObjNum()
-	catalogDict2 := fmt.Sprintf("<</Type/Catalog/Pages %d 0 R/AcroForm %d 0 R>>", pagesObjNum2, acroFormNum2)
-	catalogNum2 := w2.AddObject([]byte(catalogDict2))
-	w2.SetRoot(catalogNum2)
+	builder2.SetAcroForm(fieldBuilder2)
 	pdf2, _ := builder2.Bytes()
 
 	result, err := ComparePDFs(pdf1, pdf2, nil, nil, false)
@@ -163,12 +143,7 @@ func TestCompareForms_FormFieldRemoved(t *testing.T) {
 	field1b := fieldBuilder1.AddTextField("LastName", []float64{72, 680, 300, 700}, 0)
 	field1b.SetValue("Doe")
 	builder1.FinalizePage(page1)
-	acroFormNum1, _ := fieldBuilder1.Build()
-	w1 := builder1.Writer()
-	pagesObjNum1 := builder1.PagesObjNum()
-	catalogDict1 := fmt.Sprintf("<</Type/Catalog/Pages %d 0 R/AcroForm %d 0 R>>", pagesObjNum1, acroFormNum1)
-	catalogNum1 := w1.AddObject([]byte(catalogDict1))
-	w1.SetRoot(catalogNum1)
+	builder1.SetAcroForm(fieldBuilder1)
 	pdf1, _ := builder1.Bytes()
 
 	// Create second PDF with one form field
@@ -178,12 +153,7 @@ func TestCompareForms_FormFieldRemoved(t *testing.T) {
 	field2 := fieldBuilder2.AddTextField("FirstName", []float64{72, 700, 300, 720}, 0)
 	field2.SetValue("John")
 	builder2.FinalizePage(page2)
-	acroFormNum2, _ := fieldBuilder2.Build()
-	w2 := builder2.Writer()
-	pagesObjNum2 := builder2.PagesObjNum()
-	catalogDict2 := fmt.Sprintf("<</Type/Catalog/Pages %d 0 R/AcroForm %d 0 R>>", pagesObjNum2, acroFormNum2)
-	catalogNum2 := w2.AddObject([]byte(catalogDict2))
-	w2.SetRoot(catalogNum2)
+	builder2.SetAcroForm(fieldBuilder2)
 	pdf2, _ := builder2.Bytes()
 
 	result, err := ComparePDFs(pdf1, pdf2, nil, nil, false)
@@ -212,12 +182,7 @@ func TestCompareForms_IdenticalForms(t *testing.T) {
 	field1 := fieldBuilder1.AddTextField("FirstName", []float64{72, 700, 300, 720}, 0)
 	field1.SetValue("John")
 	builder1.FinalizePage(page1)
-	acroFormNum1, _ := fieldBuilder1.Build()
-	w1 := builder1.Writer()
-	pagesObjNum1 := builder1.PagesObjNum()
-	catalogDict1 := fmt.Sprintf("<</Type/Catalog/Pages %d 0 R/AcroForm %d 0 R>>", pagesObjNum1, acroFormNum1)
-	catalogNum1 := w1.AddObject([]byte(catalogDict1))
-	w1.SetRoot(catalogNum1)
+	builder1.SetAcroForm(fieldBuilder1)
 	pdf1, _ := builder1.Bytes()
 
 	builder2 := write.NewSimplePDFBuilder()
@@ -226,12 +191,7 @@ func TestCompareForms_IdenticalForms(t *testing.T) {
 	field2 := fieldBuilder2.AddTextField("FirstName", []float64{72, 700, 300, 720}, 0)
 	field2.SetValue("John")
 	builder2.FinalizePage(page2)
-	acroFormNum2, _ := fieldBuilder2.Build()
-	w2 := builder2.Writer()
-	pagesObjNum2 := builder2.PagesObjNum()
-	catalogDict2 := fmt.Sprintf("<</Type/Catalog/Pages %d 0 R/AcroForm %d 0 R>>", pagesObjNum2, acroFormNum2)
-	catalogNum2 := w2.AddObject([]byte(catalogDict2))
-	w2.SetRoot(catalogNum2)
+	builder2.SetAcroForm(fieldBuilder2)
 	pdf2, _ := builder2.Bytes()
 
 	result, err := ComparePDFs(pdf1, pdf2, nil, nil, false)
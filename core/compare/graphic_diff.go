@@ -0,0 +1,171 @@
+package compare
+
+import (
+	"math"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// compareGraphics compares the graphics of two pages. Graphics are matched in
+// passes, each pairing graphics of the same type: unchanged (same geometry and
+// style), restyled (same geometry), moved (same shape and style elsewhere on the
+// page) and reshaped (same style, overlapping the original). Shapes are compared
+// by path operator sequence and points relative to the bounding box. The rest
+// are reported as added or removed. Positions are compared within
+// opts.GraphicTolerance and colors within opts.ColorTolerance.
+func compareGraphics(g1, g2 []types.Graphic, opts CompareOptions) *GraphicDiff {
+	diff := &GraphicDiff{
+		Added:    []types.Graphic{},
+		Removed:  []types.Graphic{},
+		Modified: []GraphicChange{},
+	}
+
+	tolerance := opts.GraphicTolerance
+	if tolerance <= 0 {
+		tolerance = 0.1
+	}
+
+	matched1 := make([]bool, len(g1))
+	matched2 := make([]bool, len(g2))
+	match := func(kind GraphicChangeKind, same func(x, y types.Graphic) bool) {
+		for i, x := range g1 {
+			if matched1[i] {
+				continue
+			}
+			for j, y := range g2 {
+				if matched2[j] || x.Type != y.Type || !same(x, y) {
+					continue
+				}
+				matched1[i], matched2[j] = true, true
+				if kind != "" {
+					diff.Modified = append(diff.Modified, GraphicChange{Kind: kind, Old: x, New: y})
+				}
+				break
+			}
+		}
+	}
+
+	match("", func(x, y types.Graphic) bool {
+		return sameGeometry(x, y, tolerance) && sameStyle(x, y, opts.ColorTolerance)
+	})
+	match(GraphicRestyled, func(x, y types.Graphic) bool {
+		return sameGeometry(x, y, tolerance)
+	})
+	match(GraphicMoved, func(x, y types.Graphic) bool {
+		return sameShape(x, y, tolerance) && sameStyle(x, y, opts.ColorTolerance)
+	})
+	match(GraphicReshaped, func(x, y types.Graphic) bool {
+		return sameStyle(x, y, opts.ColorTolerance) && boundsOverlap(graphicBounds(x), graphicBounds(y))
+	})
+
+	for i, g := range g1 {
+		if !matched1[i] {
+			diff.Removed = append(diff.Removed, g)
+		}
+	}
+	for j, g := range g2 {
+		if !matched2[j] {
+			diff.Added = append(diff.Added, g)
+		}
+	}
+
+	if len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Modified) == 0 {
+		return nil
+	}
+
+	return diff
+}
+
+// sameStyle reports whether two graphics have the same fill and stroke colors
+// and line width, comparing colors within colorTolerance
+func sameStyle(x, y types.Graphic, colorTolerance float64) bool {
+	return colorsMatch(x.FillColor, y.FillColor, colorTolerance) &&
+		colorsMatch(x.StrokeColor, y.StrokeColor, colorTolerance) &&
+		abs(x.LineWidth-y.LineWidth) < 0.01
+}
+
+// sameGeometry reports whether two graphics have the same shape at the same
+// position
+func sameGeometry(x, y types.Graphic, tolerance float64) bool {
+	b1, b2 := graphicBounds(x), graphicBounds(y)
+	if b1 == nil || b2 == nil {
+		return b1 == nil && b2 == nil && sameShape(x, y, tolerance)
+	}
+	return positionsMatch(b1.LowerX, b1.LowerY, b2.LowerX, b2.LowerY, tolerance) && sameShape(x, y, tolerance)
+}
+
+// sameShape reports whether two graphics have the same shape, wherever they are
+// on the page: the same path operators with the same points relative to the
+// path's bounding box, or for graphics without paths, the same size
+func sameShape(x, y types.Graphic, tolerance float64) bool {
+	b1, b2 := graphicBounds(x), graphicBounds(y)
+	if b1 == nil || b2 == nil {
+		return b1 == nil && b2 == nil && x.Path == nil && y.Path == nil
+	}
+	if !positionsMatch(b1.UpperX-b1.LowerX, b1.UpperY-b1.LowerY, b2.UpperX-b2.LowerX, b2.UpperY-b2.LowerY, tolerance) {
+		return false
+	}
+	if x.Path == nil || y.Path == nil {
+		return x.Path == nil && y.Path == nil
+	}
+	if !sameOperators(x, y) {
+		return false
+	}
+	for i, op := range x.Path.Operations {
+		for k, p := range op.Points {
+			q := y.Path.Operations[i].Points[k]
+			if !positionsMatch(p.X-b1.LowerX, p.Y-b1.LowerY, q.X-b2.LowerX, q.Y-b2.LowerY, tolerance) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// sameOperators reports whether two graphics have paths with the same sequence
+// of operators and point counts
+func sameOperators(x, y types.Graphic) bool {
+	if x.Path == nil || y.Path == nil || len(x.Path.Operations) != len(y.Path.Operations) {
+		return false
+	}
+	for i, op := range x.Path.Operations {
+		other := y.Path.Operations[i]
+		if op.Type != other.Type || len(op.Points) != len(other.Points) {
+			return false
+		}
+	}
+	return true
+}
+
+// graphicBounds returns a graphic's bounding box, computing it from the path
+// when the graphic has none
+func graphicBounds(g types.Graphic) *types.Rectangle {
+	if g.BoundingBox != nil {
+		return g.BoundingBox
+	}
+	if g.Path == nil {
+		return nil
+	}
+	var bounds *types.Rectangle
+	for _, op := range g.Path.Operations {
+		for _, p := range op.Points {
+			if bounds == nil {
+				bounds = &types.Rectangle{LowerX: p.X, LowerY: p.Y, UpperX: p.X, UpperY: p.Y}
+				continue
+			}
+			bounds.LowerX = math.Min(bounds.LowerX, p.X)
+			bounds.LowerY = math.Min(bounds.LowerY, p.Y)
+			bounds.UpperX = math.Max(bounds.UpperX, p.X)
+			bounds.UpperY = math.Max(bounds.UpperY, p.Y)
+		}
+	}
+	return bounds
+}
+
+// boundsOverlap reports whether two bounding boxes intersect
+func boundsOverlap(r1, r2 *types.Rectangle) bool {
+	if r1 == nil || r2 == nil {
+		return false
+	}
+	return r1.LowerX <= r2.UpperX && r2.LowerX <= r1.UpperX && r1.LowerY <= r2.UpperY && r2.LowerY <= r1.UpperY
+}
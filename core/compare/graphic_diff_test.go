@@ -0,0 +1,105 @@
+package compare
+
+import (
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+var red = &types.Color{R: 1}
+
+func rect(x, y, w, h float64, fill *types.Color) types.Graphic {
+	return types.Graphic{
+		Type:        types.GraphicTypeRectangle,
+		FillColor:   fill,
+		LineWidth:   1,
+		BoundingBox: &types.Rectangle{LowerX: x, LowerY: y, UpperX: x + w, UpperY: y + h},
+	}
+}
+
+func line(x1, y1, x2, y2 float64) types.Graphic {
+	return types.Graphic{
+		Type:      types.GraphicTypeLine,
+		LineWidth: 1,
+		Path: &types.Path{Operations: []types.PathOperation{
+			{Type: types.PathOpMove, Points: []types.Point{{X: x1, Y: y1}}},
+			{Type: types.PathOpLine, Points: []types.Point{{X: x2, Y: y2}}},
+		}},
+	}
+}
+
+func TestCompareGraphics_Kinds(t *testing.T) {
+	opts := DefaultCompareOptions()
+	tests := []struct {
+		name     string
+		old, new types.Graphic
+		want     GraphicChangeKind
+	}{
+		{"restyled", rect(100, 100, 50, 50, nil), rect(100, 100, 50, 50, red), GraphicRestyled},
+		{"moved rect", rect(100, 100, 50, 50, red), rect(300, 400, 50, 50, red), GraphicMoved},
+		{"moved line", line(0, 0, 100, 50), line(200, 300, 300, 350), GraphicMoved},
+		{"reshaped rect", rect(100, 100, 50, 50, red), rect(100, 100, 120, 50, red), GraphicReshaped},
+		{"reshaped line", line(0, 0, 100, 50), line(0, 0, 100, 0), GraphicReshaped},
+	}
+	for _, tt := range tests {
+		diff := compareGraphics([]types.Graphic{tt.old}, []types.Graphic{tt.new}, opts)
+		if diff == nil || len(diff.Modified) != 1 || len(diff.Added)+len(diff.Removed) != 0 {
+			t.Errorf("%s: expected one change, got %+v", tt.name, diff)
+			continue
+		}
+		if kind := diff.Modified[0].Kind; kind != tt.want {
+			t.Errorf("%s: kind = %s, want %s", tt.name, kind, tt.want)
+		}
+	}
+}
+
+func TestCompareGraphics_UnchangedAndUnrelated(t *testing.T) {
+	opts := DefaultCompareOptions()
+	page := []types.Graphic{rect(10, 10, 20, 20, red), line(0, 0, 100, 100)}
+	if diff := compareGraphics(page, page, opts); diff != nil {
+		t.Errorf("Expected no diff, got %+v", diff)
+	}
+
+	// A differently styled rectangle elsewhere is not the same graphic
+	diff := compareGraphics([]types.Graphic{rect(10, 10, 20, 20, red)}, []types.Graphic{rect(300, 300, 40, 40, nil)}, opts)
+	if diff == nil || len(diff.Added) != 1 || len(diff.Removed) != 1 || len(diff.Modified) != 0 {
+		t.Errorf("Expected one added and one removed, got %+v", diff)
+	}
+}
+
+func TestComparePDFs_GraphicRestyledAndMoved(t *testing.T) {
+	build := func(r, x float64) []byte {
+		builder := write.NewSimplePDFBuilder()
+		page := builder.AddPage(write.PageSizeLetter)
+		content := page.Content()
+		content.SetFillColorRGB(r, 0, 0)
+		content.Rectangle(100, 100, 50, 50)
+		content.Fill()
+		content.SetStrokeColorRGB(0, 0, 1)
+		content.MoveTo(x, 500)
+		content.LineTo(x+100, 550)
+		content.Stroke()
+		builder.FinalizePage(page)
+		pdfBytes, err := builder.Bytes()
+		if err != nil {
+			t.Fatalf("Failed to create PDF: %v", err)
+		}
+		return pdfBytes
+	}
+
+	result, err := ComparePDFs(build(0, 50), build(1, 250), nil, nil, false)
+	if err != nil {
+		t.Fatalf("ComparePDFs failed: %v", err)
+	}
+	if len(result.PageDiffs) == 0 || result.PageDiffs[0].GraphicDiff == nil {
+		t.Fatal("Expected graphic differences")
+	}
+	kinds := map[GraphicChangeKind]int{}
+	for _, change := range result.PageDiffs[0].GraphicDiff.Modified {
+		kinds[change.Kind]++
+	}
+	if kinds[GraphicRestyled] != 1 || kinds[GraphicMoved] != 1 {
+		t.Errorf("Expected one restyled and one moved graphic, got %v", kinds)
+	}
+}
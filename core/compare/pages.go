@@ -0,0 +1,169 @@
+package compare
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PageRange is a range of page numbers (1-based, inclusive)
+type PageRange struct {
+	Start int
+	End   int
+}
+
+// ParsePageRanges parses a comma-separated list of pages and ranges, such as
+// "1-3,7", for CompareOptions.PageRanges
+func ParsePageRanges(s string) ([]PageRange, error) {
+	var ranges []PageRange
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(strings.TrimSpace(first))
+		if err != nil || start < 1 {
+			return nil, fmt.Errorf("invalid page %q in %q", first, s)
+		}
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(strings.TrimSpace(last))
+			if err != nil || end < start {
+				return nil, fmt.Errorf("invalid page range %q in %q", part, s)
+			}
+		}
+		ranges = append(ranges, PageRange{Start: start, End: end})
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("no pages in %q", s)
+	}
+	return ranges, nil
+}
+
+// pagePair is a page of the first document and the page of the second it is
+// compared with; new is 0 when the second document has no such page
+type pagePair struct {
+	old, new int
+}
+
+// pagePairs pairs the pages of two documents for comparison. Only pages of the
+// first document within opts.PageRanges are paired, when set. Pages are paired
+// by number, except that each opts.PageMap entry maps a page of the first
+// document to a page of the second and shifts the pages after it by the same
+// offset, up to the next entry, so that an inserted or deleted page doesn't
+// offset every page that follows. Each page of the second document is paired
+// with one page at most: the page an entry maps to it, or else the first page
+// shifted onto it, so that the page before a deleted page is returned as
+// removed. Pages of the second document that no page is paired with are
+// returned as added, unless opts.PageRanges is set.
+func pagePairs(count1, count2 int, opts CompareOptions) ([]pagePair, []int) {
+	anchors := make([]int, 0, len(opts.PageMap))
+	for page := range opts.PageMap {
+		anchors = append(anchors, page)
+	}
+	sort.Ints(anchors)
+
+	// Pages of the second document claimed by an entry, whether or not the
+	// page of the first document is selected
+	claimed := make(map[int]int)
+	for _, anchor := range anchors {
+		target := opts.PageMap[anchor]
+		if anchor >= 1 && anchor <= count1 && target >= 1 && target <= count2 && claimed[target] == 0 {
+			claimed[target] = anchor
+		}
+	}
+
+	var pairs []pagePair
+	paired := make(map[int]bool)
+	for page := 1; page <= count1; page++ {
+		if !pageSelected(page, opts.PageRanges) {
+			continue
+		}
+		target := page
+		for _, anchor := range anchors {
+			if anchor > page {
+				break
+			}
+			target = opts.PageMap[anchor] + page - anchor
+		}
+		if target < 1 || target > count2 || paired[target] {
+			target = 0
+		} else if owner := claimed[target]; owner != 0 && owner != page {
+			target = 0
+		}
+		pairs = append(pairs, pagePair{old: page, new: target})
+		if target != 0 {
+			paired[target] = true
+		}
+	}
+
+	var added []int
+	if len(opts.PageRanges) == 0 {
+		for page := 1; page <= count2; page++ {
+			if !paired[page] {
+				added = append(added, page)
+			}
+		}
+	}
+	return pairs, added
+}
+
+// pageSelected reports whether a page is within ranges; every page is when
+// there are none
+func pageSelected(page int, ranges []PageRange) bool {
+	if len(ranges) == 0 {
+		return true
+	}
+	for _, r := range ranges {
+		if page >= r.Start && page <= r.End {
+			return true
+		}
+	}
+	return false
+}
+
+// comparePagePairs compares the paired pages of two documents with compare,
+// reporting pages missing from the second document as removed and added pages
+// of the second document as added
+func comparePagePairs(count1, count2 int, opts CompareOptions, compare func(i, j int) *PageDifference) []PageDifference {
+	pairs, added := pagePairs(count1, count2, opts)
+
+	var diffs []PageDifference
+	for _, pair := range pairs {
+		if pair.new == 0 {
+			diffs = append(diffs, PageDifference{
+				PageNumber: pair.old,
+				Differences: []Difference{{
+					Type:        DifferenceTypePageContent,
+					Category:    "removed",
+					Description: fmt.Sprintf("Page %d removed in second PDF", pair.old),
+					Location:    fmt.Sprintf("Page %d", pair.old),
+				}},
+			})
+			continue
+		}
+		diff := compare(pair.old-1, pair.new-1)
+		if diff == nil || len(diff.Differences) == 0 {
+			continue
+		}
+		diff.PageNumber = pair.old
+		if pair.new != pair.old {
+			diff.NewPageNumber = pair.new
+		}
+		diffs = append(diffs, *diff)
+	}
+	for _, page := range added {
+		diffs = append(diffs, PageDifference{
+			PageNumber: page,
+			Differences: []Difference{{
+				Type:        DifferenceTypePageContent,
+				Category:    "added",
+				Description: fmt.Sprintf("Page %d added in second PDF", page),
+				Location:    fmt.Sprintf("Page %d", page),
+			}},
+		})
+	}
+	return diffs
+}
@@ -0,0 +1,126 @@
+package compare
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+)
+
+func TestParsePageRanges(t *testing.T) {
+	ranges, err := ParsePageRanges("1-3, 7,9-9")
+	if err != nil {
+		t.Fatalf("ParsePageRanges failed: %v", err)
+	}
+	want := []PageRange{{1, 3}, {7, 7}, {9, 9}}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("ParsePageRanges() = %v, want %v", ranges, want)
+	}
+
+	for _, s := range []string{"", "0", "a-3", "3-1", "2-", ","} {
+		if _, err := ParsePageRanges(s); err == nil {
+			t.Errorf("Expected an error for %q", s)
+		}
+	}
+}
+
+func TestPagePairs(t *testing.T) {
+	tests := []struct {
+		name           string
+		count1, count2 int
+		opts           CompareOptions
+		pairs          []pagePair
+		added          []int
+	}{
+		{"same count", 2, 2, CompareOptions{}, []pagePair{{1, 1}, {2, 2}}, nil},
+		{"page removed", 3, 2, CompareOptions{}, []pagePair{{1, 1}, {2, 2}, {3, 0}}, nil},
+		{"page added", 2, 3, CompareOptions{}, []pagePair{{1, 1}, {2, 2}}, []int{3}},
+		{"inserted page mapped", 3, 4, CompareOptions{PageMap: map[int]int{2: 3}}, []pagePair{{1, 1}, {2, 3}, {3, 4}}, []int{2}},
+		{"deleted page mapped", 4, 3, CompareOptions{PageMap: map[int]int{3: 2}}, []pagePair{{1, 1}, {2, 0}, {3, 2}, {4, 3}}, nil},
+		{"deleted page mapped, selected", 4, 3, CompareOptions{PageMap: map[int]int{3: 2}, PageRanges: []PageRange{{2, 2}}}, []pagePair{{2, 0}}, nil},
+		{"deleted pages mapped", 5, 3, CompareOptions{PageMap: map[int]int{2: 2, 4: 3}}, []pagePair{{1, 1}, {2, 2}, {3, 0}, {4, 3}, {5, 0}}, nil},
+		{"ranges", 5, 6, CompareOptions{PageRanges: []PageRange{{2, 3}, {5, 5}}}, []pagePair{{2, 2}, {3, 3}, {5, 5}}, nil},
+	}
+	for _, tt := range tests {
+		pairs, added := pagePairs(tt.count1, tt.count2, tt.opts)
+		if !reflect.DeepEqual(pairs, tt.pairs) || !reflect.DeepEqual(added, tt.added) {
+			t.Errorf("%s: pagePairs() = %v, %v; want %v, %v", tt.name, pairs, added, tt.pairs, tt.added)
+		}
+	}
+}
+
+func TestComparePDFs_PageMap(t *testing.T) {
+	build := func(texts ...string) []byte {
+		builder := write.NewSimplePDFBuilder()
+		for _, text := range texts {
+			page := builder.AddPage(write.PageSizeLetter)
+			content := page.Content()
+			content.BeginText()
+			content.SetFont(page.AddStandardFont("Helvetica"), 12)
+			content.SetTextPosition(72, 720)
+			content.ShowText(text)
+			content.EndText()
+			builder.FinalizePage(page)
+		}
+		pdf, err := builder.Bytes()
+		if err != nil {
+			t.Fatalf("Failed to build PDF: %v", err)
+		}
+		return pdf
+	}
+	pdf1 := build("One", "Two", "Three")
+	pdf2 := build("One", "Inserted", "Two", "Three")
+
+	// Without a hint, every page after the insertion differs
+	result, err := ComparePDFs(pdf1, pdf2, nil, nil, false)
+	if err != nil {
+		t.Fatalf("Failed to compare: %v", err)
+	}
+	if len(result.PageDiffs) != 3 {
+		t.Errorf("Expected 3 page differences without a page map, got %d", len(result.PageDiffs))
+	}
+
+	opts := DefaultCompareOptions()
+	opts.PageMap = map[int]int{2: 3}
+	result, err = ComparePDFsWithOptions(pdf1, pdf2, nil, nil, opts)
+	if err != nil {
+		t.Fatalf("Failed to compare: %v", err)
+	}
+	if len(result.PageDiffs) != 1 || result.PageDiffs[0].PageNumber != 2 || result.PageDiffs[0].Differences[0].Category != "added" {
+		t.Errorf("Expected only the inserted page, got %+v", result.PageDiffs)
+	}
+
+	// A page deleted in the middle is removed, not compared with the page after it
+	opts = DefaultCompareOptions()
+	opts.PageMap = map[int]int{3: 2}
+	result, err = ComparePDFsWithOptions(pdf1, build("One", "Three"), nil, nil, opts)
+	if err != nil {
+		t.Fatalf("Failed to compare: %v", err)
+	}
+	if len(result.PageDiffs) != 1 || result.PageDiffs[0].PageNumber != 2 || result.PageDiffs[0].Differences[0].Category != "removed" {
+		t.Errorf("Expected only the deleted page, got %+v", result.PageDiffs)
+	}
+
+	// Selected pages only
+	opts = DefaultCompareOptions()
+	opts.PageRanges = []PageRange{{1, 1}, {3, 3}}
+	result, err = ComparePDFsWithOptions(pdf1, build("One", "Changed", "Three"), nil, nil, opts)
+	if err != nil {
+		t.Fatalf("Failed to compare: %v", err)
+	}
+	if !result.Identical {
+		t.Errorf("Expected pages 1 and 3 to be identical, got %+v", result.PageDiffs)
+	}
+
+	// Mapped pages are labeled with both page numbers
+	opts = DefaultCompareOptions()
+	opts.PageMap = map[int]int{2: 3}
+	result, err = ComparePDFsWithOptions(pdf1, build("One", "Inserted", "Two", "Three, revised"), nil, nil, opts)
+	if err != nil {
+		t.Fatalf("Failed to compare: %v", err)
+	}
+	if report := GenerateReport(result); !strings.Contains(report, "Page 3 (page 4 in second PDF):") {
+		t.Errorf("Report missing mapped page label:\n%s", report)
+	}
+}
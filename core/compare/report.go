@@ -19,7 +19,8 @@ func GenerateReport(result *ComparisonResult) string {
 	}
 
 	report.WriteString("❌ PDFs are DIFFERENT\n\n")
-	report.WriteString(fmt.Sprintf("Total Differences: %d\n\n", result.Summary.TotalDifferences))
+	report.WriteString(fmt.Sprintf("Total Differences: %d\n", result.Summary.TotalDifferences))
+	report.WriteString(fmt.Sprintf("Similarity: %.1f%%\n\n", result.SimilarityScore*100))
 
 	// Metadata differences
 	if result.MetadataDiff != nil {
@@ -52,7 +53,11 @@ func GenerateReport(result *ComparisonResult) string {
 		report.WriteString("Page Differences:\n")
 		report.WriteString(strings.Repeat("-", 30) + "\n")
 		for _, pd := range result.PageDiffs {
-			report.WriteString(fmt.Sprintf("\nPage %d:\n", pd.PageNumber))
+			if pd.NewPageNumber != 0 {
+				report.WriteString(fmt.Sprintf("\nPage %d (page %d in second PDF):\n", pd.PageNumber, pd.NewPageNumber))
+			} else {
+				report.WriteString(fmt.Sprintf("\nPage %d:\n", pd.PageNumber))
+			}
 
 			// Text differences
 			if pd.TextDiff != nil {
@@ -67,6 +72,11 @@ func GenerateReport(result *ComparisonResult) string {
 				}
 			}
 
+			// Font changes
+			for _, change := range pd.FontChanges {
+				report.WriteString(fmt.Sprintf("  Font Changed: %s\n", describeFontChange(change)))
+			}
+
 			// Graphic differences
 			if pd.GraphicDiff != nil {
 				if len(pd.GraphicDiff.Added) > 0 {
@@ -75,6 +85,18 @@ func GenerateReport(result *ComparisonResult) string {
 				if len(pd.GraphicDiff.Removed) > 0 {
 					report.WriteString(fmt.Sprintf("  Graphics Removed: %d elements\n", len(pd.GraphicDiff.Removed)))
 				}
+				kinds := make(map[GraphicChangeKind]int)
+				for _, change := range pd.GraphicDiff.Modified {
+					kinds[change.Kind]++
+				}
+				for _, k := range []struct {
+					kind  GraphicChangeKind
+					label string
+				}{{GraphicMoved, "Moved"}, {GraphicRestyled, "Restyled"}, {GraphicReshaped, "Reshaped"}} {
+					if kinds[k.kind] > 0 {
+						report.WriteString(fmt.Sprintf("  Graphics %s: %d elements\n", k.label, kinds[k.kind]))
+					}
+				}
 			}
 
 			// Image differences
@@ -95,6 +117,12 @@ func GenerateReport(result *ComparisonResult) string {
 				if len(pd.AnnotationDiff.Removed) > 0 {
 					report.WriteString(fmt.Sprintf("  Annotations Removed: %d elements\n", len(pd.AnnotationDiff.Removed)))
 				}
+				for _, change := range pd.AnnotationDiff.Modified {
+					report.WriteString(fmt.Sprintf("  Annotation Modified (%s):\n", change.New.Type))
+					for _, p := range change.Changes {
+						report.WriteString(fmt.Sprintf("    %s\n", describePropertyDiff(p)))
+					}
+				}
 			}
 
 			// Other differences
@@ -138,6 +166,45 @@ func GenerateReport(result *ComparisonResult) string {
 		report.WriteString("\n")
 	}
 
+	// Signature differences
+	if result.SignatureDiff != nil {
+		report.WriteString("Signature Differences:\n")
+		report.WriteString(strings.Repeat("-", 30) + "\n")
+
+		for _, s := range result.SignatureDiff.Added {
+			report.WriteString(fmt.Sprintf("  Added: %s\n", s.FieldName))
+		}
+		for _, s := range result.SignatureDiff.Removed {
+			report.WriteString(fmt.Sprintf("  Removed: %s\n", s.FieldName))
+		}
+		for _, change := range result.SignatureDiff.Invalidated {
+			report.WriteString(fmt.Sprintf("  Invalidated: %s (%s)\n", change.New.FieldName, change.Reason))
+		}
+		for _, change := range result.SignatureDiff.ModifiedAfterSigning {
+			report.WriteString(fmt.Sprintf("  Modified After Signing: %s\n", change.New.FieldName))
+		}
+
+		report.WriteString("\n")
+	}
+
+	// Attachment differences
+	if result.AttachmentDiff != nil {
+		report.WriteString("Attachment Differences:\n")
+		report.WriteString(strings.Repeat("-", 30) + "\n")
+
+		for _, a := range result.AttachmentDiff.Added {
+			report.WriteString(fmt.Sprintf("  Added: %s (%d bytes)\n", a.Name, a.Size))
+		}
+		for _, a := range result.AttachmentDiff.Removed {
+			report.WriteString(fmt.Sprintf("  Removed: %s (%d bytes)\n", a.Name, a.Size))
+		}
+		for _, change := range result.AttachmentDiff.Modified {
+			report.WriteString(fmt.Sprintf("  Modified: %s\n", describeAttachmentChange(change)))
+		}
+
+		report.WriteString("\n")
+	}
+
 	return report.String()
 }
 
@@ -0,0 +1,111 @@
+package compare
+
+import (
+	"github.com/benedoc-inc/pdfer/core/sign"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// signatureStatus is a signature of a compared document with the result of
+// verifying it with sign.Verify, when the document's bytes were given
+type signatureStatus struct {
+	types.Signature
+	verified       bool // sign.Verify checked the signature
+	digestValid    bool // The signed digest is the digest of the bytes the byte range covers
+	signatureValid bool // The CMS signature verifies with the signer's certificate
+}
+
+// signatureStatuses verifies the signatures of a document. The signer's
+// certificate chain is not checked, as no trusted roots are given. Without the
+// document bytes, or when they can't be verified, e.g. because the document is
+// encrypted, the extracted signatures are returned unverified.
+func signatureStatuses(sigs []types.Signature, pdfBytes []byte, verbose bool) []signatureStatus {
+	if pdfBytes != nil {
+		if reports, err := sign.Verify(pdfBytes, sign.VerifyOptions{Verbose: verbose}); err == nil {
+			statuses := make([]signatureStatus, len(reports))
+			for i, r := range reports {
+				statuses[i] = signatureStatus{Signature: r.Signature, verified: true, digestValid: r.DigestValid, signatureValid: r.SignatureValid}
+			}
+			return statuses
+		}
+	}
+	statuses := make([]signatureStatus, len(sigs))
+	for i, sig := range sigs {
+		statuses[i] = signatureStatus{Signature: sig}
+	}
+	return statuses
+}
+
+// broken returns why a signature does not hold, or "" when it does. Of an
+// unverified signature only the fit of its byte range is known.
+func (s signatureStatus) broken() string {
+	switch {
+	case !s.Intact:
+		return "byte range no longer matches the signature contents"
+	case !s.verified:
+		return ""
+	case !s.digestValid:
+		return "signed bytes changed"
+	case !s.signatureValid:
+		return "signature does not verify"
+	}
+	return ""
+}
+
+// compareSignatures compares the signatures of two documents. Signatures are
+// matched by field name and signature value; a field signed anew is reported
+// as a removed and an added signature. A matched signature that held in the
+// old document is invalidated when it no longer verifies in the new one or,
+// unverified, when its byte range no longer fits the new file or the bytes it
+// covers changed. It is modified after signing when it covered the whole old
+// document but not the new one, as after an incremental update.
+func compareSignatures(s1, s2 []signatureStatus) *SignatureDiff {
+	diff := &SignatureDiff{
+		Added:                []types.Signature{},
+		Removed:              []types.Signature{},
+		Invalidated:          []SignatureChange{},
+		ModifiedAfterSigning: []SignatureChange{},
+	}
+
+	matched2 := make([]bool, len(s2))
+	for _, x := range s1 {
+		j := -1
+		for k, y := range s2 {
+			if !matched2[k] && x.FieldName == y.FieldName && x.ContentsHash == y.ContentsHash {
+				j = k
+				break
+			}
+		}
+		if j == -1 {
+			diff.Removed = append(diff.Removed, x.Signature)
+			continue
+		}
+		matched2[j] = true
+		y := s2[j]
+
+		change := SignatureChange{Old: x.Signature, New: y.Signature}
+		switch {
+		case x.broken() != "":
+			// A signature that was already broken can't be invalidated
+		case y.broken() != "":
+			change.Reason = y.broken()
+			diff.Invalidated = append(diff.Invalidated, change)
+		case x.SignedHash != y.SignedHash:
+			change.Reason = "signed bytes changed"
+			diff.Invalidated = append(diff.Invalidated, change)
+		case x.CoversDocument && !y.CoversDocument:
+			change.Reason = "document changed after signing"
+			diff.ModifiedAfterSigning = append(diff.ModifiedAfterSigning, change)
+		}
+	}
+	for j, s := range s2 {
+		if !matched2[j] {
+			diff.Added = append(diff.Added, s.Signature)
+		}
+	}
+
+	if len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Invalidated) == 0 && len(diff.ModifiedAfterSigning) == 0 {
+		return nil
+	}
+
+	return diff
+}
@@ -0,0 +1,212 @@
+package compare
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/core/sign"
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// createSignedPDF returns a PDF titled Original with an approval signature
+// made with a self-signed certificate
+func createSignedPDF(t *testing.T) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Jane Doe"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+
+	builder := write.NewSimplePDFBuilder()
+	builder.SetMetadata(&types.DocumentMetadata{Title: "Original"})
+	builder.FinalizePage(builder.AddPage(write.PageSizeLetter))
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to build PDF: %v", err)
+	}
+	signed, err := sign.Sign(pdfBytes, sign.Options{Signer: key, Certificate: cert, FieldName: "Approval"})
+	if err != nil {
+		t.Fatalf("Failed to sign PDF: %v", err)
+	}
+	return signed
+}
+
+// unverified returns signatures as compared without the document bytes
+func unverified(sigs []types.Signature) []signatureStatus {
+	return signatureStatuses(sigs, nil, false)
+}
+
+func TestCompareSignatures(t *testing.T) {
+	sig := func(field, contents, signed string, intact, covers bool) types.Signature {
+		return types.Signature{FieldName: field, ContentsHash: contents, SignedHash: signed, Intact: intact, CoversDocument: covers}
+	}
+	old := []types.Signature{
+		sig("Author", "a", "1", true, false),
+		sig("Reviewer", "b", "2", true, false),
+		sig("Approver", "c", "3", true, true),
+		sig("Witness", "d", "4", true, false),
+		sig("Broken", "e", "5", false, false),
+	}
+	updated := []types.Signature{
+		sig("Author", "a", "1", true, false),
+		sig("Reviewer", "b", "9", true, false),
+		sig("Approver", "c", "3", true, false),
+		sig("Witness", "f", "6", true, true),
+		sig("Broken", "e", "7", false, false),
+		sig("Notary", "g", "8", true, true),
+	}
+
+	diff := compareSignatures(unverified(old), unverified(updated))
+	if diff == nil {
+		t.Fatal("Expected signature differences")
+	}
+	if len(diff.Invalidated) != 1 || diff.Invalidated[0].New.FieldName != "Reviewer" || diff.Invalidated[0].Reason != "signed bytes changed" {
+		t.Errorf("Expected Reviewer invalidated, got %+v", diff.Invalidated)
+	}
+	if len(diff.ModifiedAfterSigning) != 1 || diff.ModifiedAfterSigning[0].New.FieldName != "Approver" {
+		t.Errorf("Expected Approver modified after signing, got %+v", diff.ModifiedAfterSigning)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].FieldName != "Witness" {
+		t.Errorf("Expected the old Witness signature removed, got %+v", diff.Removed)
+	}
+	if len(diff.Added) != 2 || diff.Added[0].FieldName != "Witness" || diff.Added[1].FieldName != "Notary" {
+		t.Errorf("Expected Witness re-signed and Notary added, got %+v", diff.Added)
+	}
+
+	if diff := compareSignatures(unverified(old), unverified(old)); diff != nil {
+		t.Errorf("Expected no differences for identical signatures, got %+v", diff)
+	}
+
+	// Verified signatures are invalidated by what the verifier finds, and one
+	// that never verified can't be
+	valid := signatureStatus{Signature: sig("Author", "a", "1", true, true), verified: true, digestValid: true, signatureValid: true}
+	forged := valid
+	forged.signatureValid = false
+	diff = compareSignatures([]signatureStatus{valid}, []signatureStatus{forged})
+	if diff == nil || len(diff.Invalidated) != 1 || diff.Invalidated[0].Reason != "signature does not verify" {
+		t.Errorf("Expected the signature invalidated, got %+v", diff)
+	}
+	if diff := compareSignatures([]signatureStatus{forged}, []signatureStatus{forged}); diff != nil {
+		t.Errorf("Expected no differences for a signature that never verified, got %+v", diff)
+	}
+}
+
+func TestComparePDFs_Signatures(t *testing.T) {
+	signed := createSignedPDF(t)
+
+	result, err := ComparePDFs(signed, signed, nil, nil, false)
+	if err != nil {
+		t.Fatalf("Failed to compare: %v", err)
+	}
+	if !result.Identical {
+		t.Errorf("Expected identical PDFs, got %+v", result.Differences)
+	}
+
+	tampered := bytes.Replace(signed, []byte("(Original)"), []byte("(Tampered)"), 1)
+	if bytes.Equal(tampered, signed) {
+		t.Fatal("Title not found in the signed PDF")
+	}
+	result, err = ComparePDFs(signed, tampered, nil, nil, false)
+	if err != nil {
+		t.Fatalf("Failed to compare: %v", err)
+	}
+	if result.SignatureDiff == nil || len(result.SignatureDiff.Invalidated) != 1 {
+		t.Fatalf("Expected an invalidated signature, got %+v", result.SignatureDiff)
+	}
+	if report := GenerateReport(result); !strings.Contains(report, "Invalidated: Approval (signed bytes changed)") {
+		t.Errorf("Report missing invalidated signature:\n%s", report)
+	}
+
+	updated := append(append([]byte{}, signed...), "\n% update\n"...)
+	result, err = ComparePDFs(signed, updated, nil, nil, false)
+	if err != nil {
+		t.Fatalf("Failed to compare: %v", err)
+	}
+	if result.SignatureDiff == nil || len(result.SignatureDiff.ModifiedAfterSigning) != 1 || len(result.SignatureDiff.Invalidated) != 0 {
+		t.Errorf("Expected a signature modified after signing, got %+v", result.SignatureDiff)
+	}
+}
+
+func TestComparePDFs_SignatureNeverValid(t *testing.T) {
+	// The signature's byte range fits, but its digest is not the digest of the
+	// signed bytes: it did not hold before the change either
+	signed, err := extract.CreateTestSignedPDF("Original", "3082abcd")
+	if err != nil {
+		t.Fatalf("Failed to create signed PDF: %v", err)
+	}
+	tampered := bytes.Replace(signed, []byte("(Original)"), []byte("(Tampered)"), 1)
+	result, err := ComparePDFs(signed, tampered, nil, nil, false)
+	if err != nil {
+		t.Fatalf("Failed to compare: %v", err)
+	}
+	if result.SignatureDiff != nil && len(result.SignatureDiff.Invalidated) != 0 {
+		t.Errorf("Expected a signature that never verified not to be invalidated, got %+v", result.SignatureDiff.Invalidated)
+	}
+
+	// Compared without the PDFs, only the signed bytes are checked
+	doc1, _ := extract.ExtractContent(signed, nil, false)
+	doc2, _ := extract.ExtractContent(tampered, nil, false)
+	if result := CompareContent(doc1, doc2, DefaultCompareOptions()); result.SignatureDiff == nil || len(result.SignatureDiff.Invalidated) != 1 {
+		t.Errorf("Expected the unverified signature invalidated, got %+v", result.SignatureDiff)
+	}
+}
+
+func TestComparePDFs_SignatureIncrementalUpdate(t *testing.T) {
+	signed := createSignedPDF(t)
+
+	// Rewrite the info dictionary in a new revision, as an incremental update does
+	pdf, err := parse.Open(signed)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	infoNum := 0
+	for objNum := 1; objNum < 20 && infoNum == 0; objNum++ {
+		if obj, err := pdf.GetObject(objNum); err == nil && bytes.Contains(obj, []byte("/Title")) {
+			infoNum = objNum
+		}
+	}
+	w, err := write.NewIncrementalWriter(signed, nil)
+	if err != nil {
+		t.Fatalf("NewIncrementalWriter failed: %v", err)
+	}
+	w.SetObject(infoNum, 0, []byte("<</Title(Amended)>>"))
+	updated, err := w.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to write update: %v", err)
+	}
+
+	result, err := ComparePDFs(signed, updated, nil, nil, false)
+	if err != nil {
+		t.Fatalf("Failed to compare: %v", err)
+	}
+	diff := result.SignatureDiff
+	if diff == nil || len(diff.ModifiedAfterSigning) != 1 || len(diff.Invalidated) != 0 {
+		t.Errorf("Expected the signature intact and modified after signing, got %+v", diff)
+	}
+}
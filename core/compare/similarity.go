@@ -0,0 +1,189 @@
+package compare
+
+import (
+	"math"
+	"unicode/utf8"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// SimilarityComponents are the per-area similarities a similarity score is
+// computed from, each from 0 (entirely different) to 1 (unchanged)
+type SimilarityComponents struct {
+	Text       float64 `json:"text"`        // Share of characters not added, removed or modified
+	Images     float64 `json:"images"`      // Share of image placements not added, removed or modified; a moved image counts half
+	Structure  float64 `json:"structure"`   // Agreement of page count and of page sizes and rotations
+	TextLength int     `json:"text_length"` // Characters compared in both documents; Text is 1 when there are none
+	ImageCount int     `json:"image_count"` // Images compared in both documents; Images is 1 when there are none
+}
+
+// SimilarityWeights weighs the similarity components in a similarity score
+type SimilarityWeights struct {
+	Text      float64
+	Images    float64
+	Structure float64
+}
+
+// DefaultSimilarityWeights returns the weights used when CompareOptions sets none
+func DefaultSimilarityWeights() SimilarityWeights {
+	return SimilarityWeights{Text: 0.6, Images: 0.2, Structure: 0.2}
+}
+
+// Score returns the weighted mean of the components. Text and images are left
+// out when neither document has any, so that a text-only document isn't scored
+// as partly similar because of its absent images.
+func (w SimilarityWeights) Score(c SimilarityComponents) float64 {
+	total, weight := w.Structure*c.Structure, w.Structure
+	if c.TextLength > 0 {
+		total += w.Text * c.Text
+		weight += w.Text
+	}
+	if c.ImageCount > 0 {
+		total += w.Images * c.Images
+		weight += w.Images
+	}
+	if weight <= 0 {
+		return 1
+	}
+	return clampUnit(total / weight)
+}
+
+// SimilarityScorer computes a similarity score from 0 to 1 for a comparison
+// result and its similarity components
+type SimilarityScorer func(c SimilarityComponents, result *ComparisonResult) float64
+
+// pageStats counts the characters and images of a page
+type pageStats struct {
+	chars  int
+	images int
+}
+
+// documentPageStats counts the characters and images of each page of a document
+func documentPageStats(pages []types.Page) []pageStats {
+	stats := make([]pageStats, len(pages))
+	for i, page := range pages {
+		stats[i] = pageStats{chars: textLength(page.Text), images: len(page.Images)}
+	}
+	return stats
+}
+
+// snapshotPageStats counts the characters and images of each page of a snapshot
+func snapshotPageStats(pages []PageSnapshot) []pageStats {
+	stats := make([]pageStats, len(pages))
+	for i, page := range pages {
+		for _, t := range page.Text {
+			stats[i].chars += utf8.RuneCountInString(t.Text)
+		}
+		stats[i].images = len(page.Images)
+	}
+	return stats
+}
+
+// textLength counts the characters of text elements
+func textLength(text []types.TextElement) int {
+	n := 0
+	for _, t := range text {
+		n += utf8.RuneCountInString(t.Text)
+	}
+	return n
+}
+
+// similarityComponents measures how much of the compared pages the page
+// differences of result cover. Only the pages selected by opts are counted.
+func similarityComponents(result *ComparisonResult, stats1, stats2 []pageStats, opts CompareOptions) SimilarityComponents {
+	pairs, added := pagePairs(len(stats1), len(stats2), opts)
+
+	var c SimilarityComponents
+	compared := 0
+	for _, pair := range pairs {
+		c.TextLength += stats1[pair.old-1].chars
+		c.ImageCount += stats1[pair.old-1].images
+		if pair.new != 0 {
+			c.TextLength += stats2[pair.new-1].chars
+			c.ImageCount += stats2[pair.new-1].images
+			compared++
+		}
+	}
+	for _, page := range added {
+		c.TextLength += stats2[page-1].chars
+		c.ImageCount += stats2[page-1].images
+	}
+
+	changedChars, changedImages, relaidPages := 0, 0, 0
+	for _, pd := range result.PageDiffs {
+		if removedOrAddedPage(pd) {
+			stats := stats2
+			if pd.Differences[0].Category == "removed" {
+				stats = stats1
+			}
+			if pd.PageNumber >= 1 && pd.PageNumber <= len(stats) {
+				changedChars += stats[pd.PageNumber-1].chars
+				changedImages += stats[pd.PageNumber-1].images
+			}
+			continue
+		}
+		if td := pd.TextDiff; td != nil {
+			changedChars += textLength(td.Added) + textLength(td.Removed)
+			for _, m := range td.Modified {
+				changedChars += utf8.RuneCountInString(m.Old.Text) + utf8.RuneCountInString(m.New.Text)
+			}
+		}
+		if id := pd.ImageDiff; id != nil {
+			changedImages += len(id.Added) + len(id.Removed) + 2*len(id.Modified) + len(id.Moved)
+		}
+		for _, d := range pd.Differences {
+			if d.Type == DifferenceTypePageContent && d.Category == "modified" {
+				relaidPages++
+				break
+			}
+		}
+	}
+
+	c.Text = unchangedShare(changedChars, c.TextLength)
+	c.Images = unchangedShare(changedImages, c.ImageCount)
+
+	pageRatio := 1.0
+	if n1, n2 := len(stats1), len(stats2); n1 != n2 {
+		pageRatio = float64(min(n1, n2)) / float64(max(n1, n2))
+	}
+	c.Structure = (pageRatio + unchangedShare(relaidPages, compared)) / 2
+	return c
+}
+
+// removedOrAddedPage reports whether a page difference is a page missing from
+// one of the documents
+func removedOrAddedPage(pd PageDifference) bool {
+	if len(pd.Differences) != 1 || pd.Differences[0].Type != DifferenceTypePageContent {
+		return false
+	}
+	category := pd.Differences[0].Category
+	return category == "removed" || category == "added"
+}
+
+// unchangedShare returns the share of total that changed doesn't cover, or 1
+// when total is 0
+func unchangedShare(changed, total int) float64 {
+	if total <= 0 {
+		return 1
+	}
+	return clampUnit(1 - float64(changed)/float64(total))
+}
+
+// clampUnit limits v to [0, 1]
+func clampUnit(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+// scoreSimilarity sets result.SimilarityScore from result.Similarity, with
+// opts.SimilarityScorer when set and otherwise with opts.SimilarityWeights
+func scoreSimilarity(result *ComparisonResult, opts CompareOptions) {
+	if opts.SimilarityScorer != nil {
+		result.SimilarityScore = clampUnit(opts.SimilarityScorer(result.Similarity, result))
+		return
+	}
+	weights := opts.SimilarityWeights
+	if weights == (SimilarityWeights{}) {
+		weights = DefaultSimilarityWeights()
+	}
+	result.SimilarityScore = weights.Score(result.Similarity)
+}
@@ -0,0 +1,90 @@
+package compare
+
+import (
+	"math"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+func textPage(texts ...string) types.Page {
+	page := types.Page{Width: 612, Height: 792}
+	for i, text := range texts {
+		page.Text = append(page.Text, types.TextElement{Text: text, X: 72, Y: 700 - float64(i)*20, FontName: "/F1", FontSize: 12})
+	}
+	return page
+}
+
+func TestCompareContent_SimilarityScore(t *testing.T) {
+	doc1 := &types.ContentDocument{Pages: []types.Page{textPage("aaaaaaaaaa", "bbbbbbbbbb")}}
+
+	result := CompareContent(doc1, doc1, DefaultCompareOptions())
+	if result.SimilarityScore != 1 {
+		t.Errorf("Identical documents should score 1, got %v", result.SimilarityScore)
+	}
+
+	// One of two elements removed: 10 of 30 characters changed
+	doc2 := &types.ContentDocument{Pages: []types.Page{textPage("aaaaaaaaaa")}}
+	result = CompareContent(doc1, doc2, DefaultCompareOptions())
+	if result.Similarity.TextLength != 30 {
+		t.Errorf("Expected 30 characters compared, got %d", result.Similarity.TextLength)
+	}
+	if math.Abs(result.Similarity.Text-2.0/3) > 1e-9 {
+		t.Errorf("Expected text similarity 2/3, got %v", result.Similarity.Text)
+	}
+	if result.Similarity.Structure != 1 {
+		t.Errorf("Expected structure similarity 1, got %v", result.Similarity.Structure)
+	}
+	// No images: text weighs 0.6 and structure 0.2
+	want := (0.6*2.0/3 + 0.2) / 0.8
+	if math.Abs(result.SimilarityScore-want) > 1e-9 {
+		t.Errorf("Expected score %v, got %v", want, result.SimilarityScore)
+	}
+
+	opts := DefaultCompareOptions()
+	opts.SimilarityWeights = SimilarityWeights{Text: 1}
+	if result = CompareContent(doc1, doc2, opts); math.Abs(result.SimilarityScore-2.0/3) > 1e-9 {
+		t.Errorf("Expected text-only score 2/3, got %v", result.SimilarityScore)
+	}
+
+	opts.SimilarityScorer = func(c SimilarityComponents, result *ComparisonResult) float64 {
+		return 1 - float64(result.Summary.TotalDifferences)/10
+	}
+	if result = CompareContent(doc1, doc2, opts); math.Abs(result.SimilarityScore-0.9) > 1e-9 {
+		t.Errorf("Expected custom scorer's 0.9, got %v", result.SimilarityScore)
+	}
+}
+
+func TestSimilarityComponents_Pages(t *testing.T) {
+	doc1 := &types.ContentDocument{Pages: []types.Page{textPage("first"), textPage("second")}}
+	doc2 := &types.ContentDocument{Pages: []types.Page{textPage("first")}}
+	doc2.Pages[0].Rotation = 90
+
+	result := CompareContent(doc1, doc2, DefaultCompareOptions())
+	// The removed page's 6 characters of 16 changed
+	if want := 10.0 / 16; math.Abs(result.Similarity.Text-want) > 1e-9 {
+		t.Errorf("Expected text similarity %v, got %v", want, result.Similarity.Text)
+	}
+	// Half the pages remain, and the one compared page was rotated
+	if want := (0.5 + 0) / 2; result.Similarity.Structure != want {
+		t.Errorf("Expected structure similarity %v, got %v", want, result.Similarity.Structure)
+	}
+
+	// Only page 1 selected: the removed page is not counted
+	opts := DefaultCompareOptions()
+	opts.PageRanges = []PageRange{{Start: 1, End: 1}}
+	result = CompareContent(doc1, doc2, opts)
+	if result.Similarity.TextLength != 10 || result.Similarity.Text != 1 {
+		t.Errorf("Expected 10 unchanged characters, got %+v", result.Similarity)
+	}
+}
+
+func TestSimilarityWeights_Score(t *testing.T) {
+	c := SimilarityComponents{Text: 0.5, Images: 0, Structure: 1, TextLength: 10, ImageCount: 2}
+	if got, want := DefaultSimilarityWeights().Score(c), 0.6*0.5+0.2; math.Abs(got-want) > 1e-9 {
+		t.Errorf("Score = %v, want %v", got, want)
+	}
+	if got := (SimilarityWeights{}).Score(c); got != 1 {
+		t.Errorf("Zero weights should score 1, got %v", got)
+	}
+}
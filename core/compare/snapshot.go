@@ -0,0 +1,340 @@
+package compare
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/forms"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// SnapshotVersion is the version of the snapshot format written by Marshal
+const SnapshotVersion = 1
+
+// DocumentSnapshot is a compact record of a document's text, form field values
+// and image hashes. It can be stored in place of the document and compared
+// against later versions to monitor it for changes.
+type DocumentSnapshot struct {
+	Version  int                     `json:"v"`
+	Created  time.Time               `json:"created"`
+	Metadata *types.DocumentMetadata `json:"metadata,omitempty"`
+	Pages    []PageSnapshot          `json:"pages"`
+	FormType string                  `json:"form_type,omitempty"` // Empty when the document has no form
+	Fields   map[string]string       `json:"fields,omitempty"`    // Field values by name
+}
+
+// PageSnapshot records the content of a single page
+type PageSnapshot struct {
+	Width    float64         `json:"w"`
+	Height   float64         `json:"h"`
+	Rotation int             `json:"r,omitempty"`
+	Text     []SnapshotText  `json:"t,omitempty"`
+	Images   []SnapshotImage `json:"i,omitempty"`
+}
+
+// SnapshotText records a text element and its position
+type SnapshotText struct {
+	Text  string  `json:"s"`
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+	Width float64 `json:"w,omitempty"`
+	Font  string  `json:"f,omitempty"`
+	Size  float64 `json:"z,omitempty"`
+}
+
+// SnapshotImage records an image placement and a hash of the image data
+type SnapshotImage struct {
+	ID     string  `json:"id"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"w"`
+	Height float64 `json:"h"`
+	Hash   string  `json:"sha256,omitempty"` // SHA-256 of the image data; empty when it couldn't be extracted
+}
+
+// Snapshot extracts a DocumentSnapshot from a PDF
+func Snapshot(pdfBytes []byte, password []byte, verbose bool) (*DocumentSnapshot, error) {
+	doc, err := extract.ExtractContent(pdfBytes, password, verbose)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract content: %w", err)
+	}
+
+	snapshot := &DocumentSnapshot{
+		Version:  SnapshotVersion,
+		Created:  time.Now().UTC(),
+		Metadata: doc.Metadata,
+		Pages:    make([]PageSnapshot, 0, len(doc.Pages)),
+	}
+	if snapshot.Metadata != nil {
+		// XMP properties don't survive a JSON round trip unchanged
+		metadata := *snapshot.Metadata
+		metadata.XMP = nil
+		snapshot.Metadata = &metadata
+	}
+
+	// Image data missing from page resources is looked up among all of the
+	// document's images, extracted once on first use
+	var allImages map[string][]byte
+	imageData := func(id string, page types.Page) []byte {
+		if page.Resources != nil {
+			if img, ok := page.Resources.Images[id[1:]]; ok && len(img.Data) > 0 {
+				return img.Data
+			}
+		}
+		if allImages == nil {
+			allImages = make(map[string][]byte)
+			images, err := extract.ExtractAllImages(pdfBytes, password, verbose)
+			if err != nil && verbose {
+				fmt.Printf("Warning: failed to extract images for snapshot: %v\n", err)
+			}
+			for _, img := range images {
+				allImages[img.ID] = img.Data
+			}
+		}
+		return allImages[id]
+	}
+
+	for _, page := range doc.Pages {
+		ps := PageSnapshot{
+			Width:    page.Width,
+			Height:   page.Height,
+			Rotation: page.Rotation,
+		}
+		for _, t := range page.Text {
+			ps.Text = append(ps.Text, SnapshotText{
+				Text:  t.Text,
+				X:     roundSnapshot(t.X),
+				Y:     roundSnapshot(t.Y),
+				Width: roundSnapshot(t.Width),
+				Font:  t.FontName,
+				Size:  roundSnapshot(t.FontSize),
+			})
+		}
+		for _, img := range page.Images {
+			si := SnapshotImage{
+				ID:     img.ImageID,
+				X:      roundSnapshot(img.X),
+				Y:      roundSnapshot(img.Y),
+				Width:  roundSnapshot(img.Width),
+				Height: roundSnapshot(img.Height),
+			}
+			if len(img.ImageID) > 1 {
+				if data := imageData(img.ImageID, page); len(data) > 0 {
+					sum := sha256.Sum256(data)
+					si.Hash = hex.EncodeToString(sum[:])
+				}
+			}
+			ps.Images = append(ps.Images, si)
+		}
+		snapshot.Pages = append(snapshot.Pages, ps)
+	}
+
+	if form, err := forms.Extract(pdfBytes, password, verbose); err == nil {
+		snapshot.FormType = string(form.Type())
+		snapshot.Fields = make(map[string]string)
+		for name, value := range form.GetValues() {
+			snapshot.Fields[name] = snapshotValue(value)
+		}
+	}
+
+	return snapshot, nil
+}
+
+// Marshal serializes the snapshot as JSON
+func (s *DocumentSnapshot) Marshal() ([]byte, error) {
+	return json.Marshal(s)
+}
+
+// UnmarshalSnapshot parses a snapshot serialized with Marshal
+func UnmarshalSnapshot(data []byte) (*DocumentSnapshot, error) {
+	var snapshot DocumentSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %w", err)
+	}
+	if snapshot.Version < 1 || snapshot.Version > SnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", snapshot.Version)
+	}
+	return &snapshot, nil
+}
+
+// CompareWithSnapshot compares a stored snapshot against the current version of
+// a document
+func CompareWithSnapshot(old *DocumentSnapshot, pdfBytes []byte, password []byte, opts CompareOptions) (*ComparisonResult, error) {
+	current, err := Snapshot(pdfBytes, password, opts.Verbose)
+	if err != nil {
+		return nil, err
+	}
+	return CompareSnapshots(old, current, opts), nil
+}
+
+// CompareSnapshots compares two snapshots. Text is compared as by
+// ComparePDFsWithOptions; images are compared by hash, so a modified image is
+// one whose data changed while its resource name and position stayed the same.
+// Graphics, annotations and bookmarks are not part of snapshots.
+func CompareSnapshots(old, current *DocumentSnapshot, opts CompareOptions) *ComparisonResult {
+	result := &ComparisonResult{
+		Differences: []Difference{},
+		Summary:     ComparisonSummary{},
+	}
+
+	// Compare metadata (with options)
+	metadataDiff := compareMetadata(old.Metadata, current.Metadata, opts)
+	if metadataDiff != nil {
+		result.MetadataDiff = metadataDiff
+		result.Summary.MetadataChanged = true
+		result.Summary.TotalDifferences++
+	}
+
+	// Compare structure (page count)
+	if len(old.Pages) != len(current.Pages) {
+		result.StructureDiff = &StructureDifference{
+			PageCountDiff: &FieldDiff{OldValue: len(old.Pages), NewValue: len(current.Pages)},
+		}
+		result.Summary.StructureChanged = true
+		result.Summary.TotalDifferences++
+	}
+
+	// Compare pages
+	pageDiffs := comparePagePairs(len(old.Pages), len(current.Pages), opts, func(i, j int) *PageDifference {
+		diff := &PageDifference{PageNumber: i + 1, Differences: []Difference{}}
+		comparePageLayout(diff, old.Pages[i].page(), current.Pages[j].page(), opts)
+		addImageDifference(diff, compareSnapshotImages(old.Pages[i].Images, current.Pages[j].Images), len(old.Pages[i].Images), len(current.Pages[j].Images))
+		return diff
+	})
+	if len(pageDiffs) > 0 {
+		result.PageDiffs = pageDiffs
+		result.Summary.PagesChanged = true
+		result.Summary.ContentChanged = true
+		for _, pd := range pageDiffs {
+			result.Summary.TotalDifferences += len(pd.Differences)
+		}
+	}
+
+	// Compare forms
+	formDiff := compareFormValues(old.FormType, fieldValues(old.Fields), current.FormType, fieldValues(current.Fields))
+	if formDiff != nil && (len(formDiff.Added) > 0 || len(formDiff.Removed) > 0 || len(formDiff.Modified) > 0 || formDiff.FormType != nil) {
+		result.FormDiff = formDiff
+		result.Differences = append(result.Differences, Difference{
+			Type:        DifferenceTypeForm,
+			Category:    "modified",
+			Description: fmt.Sprintf("Form fields changed: %d added, %d removed, %d modified", len(formDiff.Added), len(formDiff.Removed), len(formDiff.Modified)),
+		})
+		result.Summary.TotalDifferences++
+		result.Summary.ContentChanged = true
+	}
+
+	result.Similarity = similarityComponents(result, snapshotPageStats(old.Pages), snapshotPageStats(current.Pages), opts)
+	result.Identical = result.Summary.TotalDifferences == 0
+	scoreSimilarity(result, opts)
+	return result
+}
+
+// page converts the snapshot back to a page for comparison
+func (p PageSnapshot) page() types.Page {
+	page := types.Page{Width: p.Width, Height: p.Height, Rotation: p.Rotation}
+	for _, t := range p.Text {
+		page.Text = append(page.Text, types.TextElement{
+			Text:     t.Text,
+			X:        t.X,
+			Y:        t.Y,
+			Width:    t.Width,
+			Height:   t.Size,
+			FontName: t.Font,
+			FontSize: t.Size,
+		})
+	}
+	return page
+}
+
+// compareSnapshotImages compares image placements by hash: an image at the same
+// position with the same ID and hash is unchanged, one whose hash differs is
+// modified, and one with the same hash at a different position has moved
+func compareSnapshotImages(img1, img2 []SnapshotImage) *ImageDiff {
+	diff := &ImageDiff{
+		Added:    []types.ImageRef{},
+		Removed:  []types.ImageRef{},
+		Modified: []ImageModification{},
+		Moved:    []ImageModification{},
+	}
+
+	matched1 := make([]bool, len(img1))
+	matched2 := make([]bool, len(img2))
+
+	// Same ID and position
+	for i1, a := range img1 {
+		for i2, b := range img2 {
+			if matched2[i2] || a.ID != b.ID || !positionsMatch(a.X, a.Y, b.X, b.Y, 1.0) {
+				continue
+			}
+			if a.Hash != b.Hash {
+				diff.Modified = append(diff.Modified, ImageModification{Old: a.ref(), New: b.ref()})
+			}
+			matched1[i1], matched2[i2] = true, true
+			break
+		}
+	}
+
+	// Same data at a different position
+	for i1, a := range img1 {
+		if matched1[i1] || a.Hash == "" {
+			continue
+		}
+		for i2, b := range img2 {
+			if matched2[i2] || a.Hash != b.Hash {
+				continue
+			}
+			diff.Moved = append(diff.Moved, ImageModification{Old: a.ref(), New: b.ref()})
+			matched1[i1], matched2[i2] = true, true
+			break
+		}
+	}
+
+	for i1, a := range img1 {
+		if !matched1[i1] {
+			diff.Removed = append(diff.Removed, a.ref())
+		}
+	}
+	for i2, b := range img2 {
+		if !matched2[i2] {
+			diff.Added = append(diff.Added, b.ref())
+		}
+	}
+
+	if len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Modified) == 0 && len(diff.Moved) == 0 {
+		return nil
+	}
+	return diff
+}
+
+// ref converts the snapshot back to an image reference
+func (i SnapshotImage) ref() types.ImageRef {
+	return types.ImageRef{ImageID: i.ID, X: i.X, Y: i.Y, Width: i.Width, Height: i.Height}
+}
+
+// fieldValues converts snapshot field values for compareFormValues
+func fieldValues(fields map[string]string) map[string]interface{} {
+	values := make(map[string]interface{}, len(fields))
+	for name, value := range fields {
+		values[name] = value
+	}
+	return values
+}
+
+// snapshotValue formats a field value the way valuesEqual compares it
+func snapshotValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// roundSnapshot rounds a coordinate to hundredths of a point, keeping snapshots
+// compact without affecting position tolerances
+func roundSnapshot(v float64) float64 {
+	return math.Round(v*100) / 100
+}
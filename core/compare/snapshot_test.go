@@ -0,0 +1,158 @@
+package compare
+
+import (
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// snapshotTestPDF builds a single page PDF showing text
+func snapshotTestPDF(t *testing.T, text string) []byte {
+	t.Helper()
+	builder := write.NewSimplePDFBuilder()
+	page := builder.AddPage(write.PageSizeLetter)
+	content := page.Content()
+	content.BeginText()
+	font := page.AddStandardFont("Helvetica")
+	content.SetFont(font, 12)
+	content.SetTextPosition(72, 720)
+	content.ShowText(text)
+	content.EndText()
+	builder.FinalizePage(page)
+
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create test PDF: %v", err)
+	}
+	return pdfBytes
+}
+
+func TestSnapshot_RoundTrip(t *testing.T) {
+	pdfBytes := snapshotTestPDF(t, "Monitored Document")
+
+	snapshot, err := Snapshot(pdfBytes, nil, false)
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	if len(snapshot.Pages) != 1 {
+		t.Fatalf("Expected 1 page, got %d", len(snapshot.Pages))
+	}
+	if len(snapshot.Pages[0].Text) == 0 {
+		t.Fatal("Expected snapshot to record page text")
+	}
+
+	data, err := snapshot.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	restored, err := UnmarshalSnapshot(data)
+	if err != nil {
+		t.Fatalf("UnmarshalSnapshot failed: %v", err)
+	}
+
+	result, err := CompareWithSnapshot(restored, pdfBytes, nil, DefaultCompareOptions())
+	if err != nil {
+		t.Fatalf("CompareWithSnapshot failed: %v", err)
+	}
+	if !result.Identical {
+		t.Errorf("Expected document to match its snapshot, got %d differences: %+v", result.Summary.TotalDifferences, result.PageDiffs)
+	}
+}
+
+func TestCompareWithSnapshot_TextChanged(t *testing.T) {
+	snapshot, err := Snapshot(snapshotTestPDF(t, "Original Text"), nil, false)
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+
+	result, err := CompareWithSnapshot(snapshot, snapshotTestPDF(t, "Changed Text"), nil, DefaultCompareOptions())
+	if err != nil {
+		t.Fatalf("CompareWithSnapshot failed: %v", err)
+	}
+	if result.Identical {
+		t.Fatal("Expected changed text to be reported")
+	}
+	if len(result.PageDiffs) != 1 || result.PageDiffs[0].TextDiff == nil {
+		t.Errorf("Expected a text diff on page 1, got %+v", result.PageDiffs)
+	}
+}
+
+func TestCompareSnapshots_ImagesAndFields(t *testing.T) {
+	old := &DocumentSnapshot{
+		Version: SnapshotVersion,
+		Pages: []PageSnapshot{{
+			Width: 612, Height: 792,
+			Images: []SnapshotImage{
+				{ID: "/Im1", X: 72, Y: 600, Width: 100, Height: 50, Hash: "aa"},
+				{ID: "/Im2", X: 300, Y: 600, Width: 100, Height: 50, Hash: "bb"},
+			},
+		}},
+		FormType: "acroform",
+		Fields:   map[string]string{"name": "Alice", "city": "Berlin"},
+	}
+	current := &DocumentSnapshot{
+		Version: SnapshotVersion,
+		Pages: []PageSnapshot{
+			{
+				Width: 612, Height: 792,
+				Images: []SnapshotImage{
+					{ID: "/Im1", X: 72, Y: 600, Width: 100, Height: 50, Hash: "cc"},
+					{ID: "/Im2", X: 300, Y: 400, Width: 100, Height: 50, Hash: "bb"},
+				},
+			},
+			{Width: 612, Height: 792},
+		},
+		FormType: "acroform",
+		Fields:   map[string]string{"name": "Bob", "city": "Berlin"},
+	}
+
+	result := CompareSnapshots(old, current, DefaultCompareOptions())
+	if result.StructureDiff == nil || result.StructureDiff.PageCountDiff == nil {
+		t.Error("Expected page count change")
+	}
+	if len(result.PageDiffs) != 2 {
+		t.Fatalf("Expected differences on 2 pages, got %d", len(result.PageDiffs))
+	}
+	imageDiff := result.PageDiffs[0].ImageDiff
+	if imageDiff == nil || len(imageDiff.Modified) != 1 || len(imageDiff.Moved) != 1 {
+		t.Errorf("Expected 1 modified and 1 moved image, got %+v", imageDiff)
+	}
+	if result.FormDiff == nil || len(result.FormDiff.Modified) != 1 || result.FormDiff.Modified[0].FieldName != "name" {
+		t.Errorf("Expected the name field to be modified, got %+v", result.FormDiff)
+	}
+}
+
+func TestUnmarshalSnapshot_UnsupportedVersion(t *testing.T) {
+	if _, err := UnmarshalSnapshot([]byte(`{"v":99,"pages":[]}`)); err == nil {
+		t.Error("Expected error for unsupported snapshot version")
+	}
+}
+
+func TestCompareContent_StoredExtraction(t *testing.T) {
+	doc1, err := extract.ExtractContent(snapshotTestPDF(t, "Version one"), nil, false)
+	if err != nil {
+		t.Fatalf("ExtractContent failed: %v", err)
+	}
+	stored, err := types.MarshalContent(doc1)
+	if err != nil {
+		t.Fatalf("MarshalContent failed: %v", err)
+	}
+	restored, err := types.UnmarshalContent(stored)
+	if err != nil {
+		t.Fatalf("UnmarshalContent failed: %v", err)
+	}
+
+	if result := CompareContent(restored, doc1, DefaultCompareOptions()); !result.Identical {
+		t.Errorf("Expected stored content to match, got %d differences", result.Summary.TotalDifferences)
+	}
+
+	doc2, err := extract.ExtractContent(snapshotTestPDF(t, "Version two"), nil, false)
+	if err != nil {
+		t.Fatalf("ExtractContent failed: %v", err)
+	}
+	if result := CompareContent(restored, doc2, DefaultCompareOptions()); result.Identical || !result.Summary.ContentChanged {
+		t.Error("Expected a content change")
+	}
+}
@@ -0,0 +1,156 @@
+package compare
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// TextNormalizer is a transformation applied to text before it is compared
+type TextNormalizer string
+
+const (
+	NormalizeNFKC        TextNormalizer = "nfkc"         // Unicode NFKC: compatibility characters such as fullwidth forms and superscripts become plain ones
+	NormalizeLigatures   TextNormalizer = "ligatures"    // Expand ligatures such as "ﬁ" to their letters
+	NormalizeHyphenation TextNormalizer = "hyphenation"  // Join words hyphenated across a line break
+	NormalizeSoftHyphens TextNormalizer = "soft_hyphens" // Remove soft hyphens (U+00AD)
+	NormalizeQuotes      TextNormalizer = "quotes"       // Fold curly quotes to straight ones
+)
+
+// softHyphen is U+00AD, an invisible hyphenation point
+const softHyphen = '\u00AD'
+
+// ligatures maps ligature characters to the letters they join
+var ligatures = map[rune]string{
+	'Ĳ': "IJ", 'ĳ': "ij",
+	'ﬀ': "ff", 'ﬁ': "fi", 'ﬂ': "fl", 'ﬃ': "ffi", 'ﬄ': "ffl", 'ﬅ': "st", 'ﬆ': "st",
+}
+
+// smartQuotes maps typographic quotes to straight ones
+var smartQuotes = strings.NewReplacer(
+	"‘", "'", "’", "'", "‚", "'", "‛", "'",
+	"“", `"`, "”", `"`, "„", `"`, "‟", `"`,
+)
+
+// normalizeTextElements applies opts.TextNormalizers, in order, to the text of a
+// page. The elements are copied; hyphenation joining merges an element ending
+// in a hyphen with the element that continues the word on the next line.
+func normalizeTextElements(text []types.TextElement, opts CompareOptions) []types.TextElement {
+	if len(opts.TextNormalizers) == 0 || len(text) == 0 {
+		return text
+	}
+
+	out := make([]types.TextElement, len(text))
+	copy(out, text)
+	for _, normalizer := range opts.TextNormalizers {
+		if normalizer == NormalizeHyphenation {
+			out = joinHyphenation(out)
+			continue
+		}
+		for i := range out {
+			out[i].Text = normalizeString(out[i].Text, normalizer)
+		}
+	}
+	return out
+}
+
+// normalizeString applies a normalizer that works on a single string
+func normalizeString(s string, normalizer TextNormalizer) string {
+	switch normalizer {
+	case NormalizeNFKC:
+		return write.NormalizeNFKC(s)
+	case NormalizeLigatures:
+		return expandLigatures(s)
+	case NormalizeSoftHyphens:
+		return strings.ReplaceAll(s, string(softHyphen), "")
+	case NormalizeQuotes:
+		return smartQuotes.Replace(s)
+	}
+	return s
+}
+
+// expandLigatures replaces ligature characters with their letters
+func expandLigatures(s string) string {
+	if !strings.ContainsFunc(s, func(r rune) bool { _, ok := ligatures[r]; return ok }) {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		if letters, ok := ligatures[r]; ok {
+			b.WriteString(letters)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// joinHyphenation joins words hyphenated across a line break: within an element,
+// a hyphen or soft hyphen followed by a newline, and across elements, one ending
+// in a hyphen or soft hyphen followed by an element on a lower line that starts
+// with a lowercase letter
+func joinHyphenation(text []types.TextElement) []types.TextElement {
+	out := make([]types.TextElement, 0, len(text))
+	for i := 0; i < len(text); i++ {
+		t := text[i]
+		t.Text = joinLineHyphens(t.Text)
+		for i+1 < len(text) {
+			stem, ok := hyphenatedStem(t.Text)
+			next := text[i+1]
+			if !ok || !startsLowercase(next.Text) || next.Y >= t.Y-t.FontSize/2 {
+				break
+			}
+			t.Text = stem + joinLineHyphens(next.Text)
+			i++
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// joinLineHyphens removes hyphens at line breaks inside a string, along with the
+// line break, when a lowercase letter follows
+func joinLineHyphens(s string) string {
+	if !strings.Contains(s, "\n") {
+		return s
+	}
+	lines := strings.Split(s, "\n")
+	var b strings.Builder
+	b.WriteString(lines[0])
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimLeft(line, " \t\r")
+		if stem, ok := hyphenatedStem(b.String()); ok && startsLowercase(trimmed) {
+			joined := stem + trimmed
+			b.Reset()
+			b.WriteString(joined)
+			continue
+		}
+		b.WriteString("\n")
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+// hyphenatedStem returns s without a trailing hyphen or soft hyphen that
+// follows a letter
+func hyphenatedStem(s string) (string, bool) {
+	s = strings.TrimRight(s, " \t\r")
+	last, size := utf8.DecodeLastRuneInString(s)
+	if last != '-' && last != softHyphen {
+		return "", false
+	}
+	stem := s[:len(s)-size]
+	if r, _ := utf8.DecodeLastRuneInString(stem); !unicode.IsLetter(r) {
+		return "", false
+	}
+	return stem, true
+}
+
+// startsLowercase reports whether s starts with a lowercase letter
+func startsLowercase(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsLower(r)
+}
@@ -0,0 +1,76 @@
+package compare
+
+import (
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+func TestNormalizeString(t *testing.T) {
+	tests := []struct {
+		normalizer TextNormalizer
+		in, want   string
+	}{
+		{NormalizeNFKC, "Ａ² ﬁ", "A2 fi"},
+		{NormalizeLigatures, "ﬁnal eﬃcient", "final efficient"},
+		{NormalizeSoftHyphens, "hyphen\u00ADation", "hyphenation"},
+		{NormalizeQuotes, "“It’s”", `"It's"`},
+		{NormalizeQuotes, "plain", "plain"},
+	}
+	for _, tt := range tests {
+		if got := normalizeString(tt.in, tt.normalizer); got != tt.want {
+			t.Errorf("normalizeString(%q, %s) = %q, want %q", tt.in, tt.normalizer, got, tt.want)
+		}
+	}
+}
+
+func TestJoinHyphenation(t *testing.T) {
+	text := []types.TextElement{
+		{Text: "The exam-", X: 72, Y: 700, FontSize: 12},
+		{Text: "ple is long-", X: 72, Y: 686, FontSize: 12},
+		{Text: "Term plans", X: 72, Y: 672, FontSize: 12}, // capitalized: a real hyphen
+		{Text: "co-\nordinate and well-\n known", X: 72, Y: 600, FontSize: 12},
+		{Text: "side-", X: 300, Y: 500, FontSize: 12},
+		{Text: "bar", X: 72, Y: 700, FontSize: 12}, // next column, not the next line
+	}
+	got := joinHyphenation(text)
+	want := []string{"The example is long-", "Term plans", "coordinate and wellknown", "side-", "bar"}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d elements, got %d: %+v", len(want), len(got), got)
+	}
+	for i, w := range want {
+		if got[i].Text != w {
+			t.Errorf("Element %d = %q, want %q", i, got[i].Text, w)
+		}
+	}
+	if got[0].Y != 700 {
+		t.Errorf("Joined element should keep the first line's position, got Y=%v", got[0].Y)
+	}
+}
+
+func TestCompareText_Normalizers(t *testing.T) {
+	page1 := types.Page{Text: []types.TextElement{
+		{Text: "“Eﬃcient” pro-", X: 72, Y: 700, FontName: "/F1", FontSize: 12},
+		{Text: "cessing", X: 72, Y: 686, FontName: "/F1", FontSize: 12},
+	}}
+	page2 := types.Page{Text: []types.TextElement{
+		{Text: `"Efficient" processing`, X: 72, Y: 700, FontName: "/F1", FontSize: 12},
+	}}
+
+	diff := &PageDifference{PageNumber: 1}
+	comparePageLayout(diff, page1, page2, DefaultCompareOptions())
+	if diff.TextDiff == nil {
+		t.Fatal("Expected text differences without normalizers")
+	}
+
+	opts := DefaultCompareOptions()
+	opts.TextNormalizers = []TextNormalizer{NormalizeHyphenation, NormalizeLigatures, NormalizeQuotes}
+	diff = &PageDifference{PageNumber: 1}
+	comparePageLayout(diff, page1, page2, opts)
+	if diff.TextDiff != nil || len(diff.Differences) != 0 {
+		t.Errorf("Expected no differences after normalization, got %+v", diff.Differences)
+	}
+	if page1.Text[0].Text != "“Eﬃcient” pro-" {
+		t.Error("Normalization should not modify the caller's text elements")
+	}
+}
@@ -0,0 +1,251 @@
+// Package dates converts between PDF date strings (D:YYYYMMDDHHmmSSOHH'mm'),
+// ISO 8601 dates as used by XMP, and time.Time.
+package dates
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Parser parses PDF and ISO 8601 dates. The zero value is lenient and reads
+// dates without a UT offset as UTC.
+type Parser struct {
+	Location *time.Location // Zone of dates without a UT offset (default: UTC)
+	Strict   bool           // Require the "D:" prefix of PDF dates and the "T" separator of ISO dates, and reject trailing text
+}
+
+// Parse parses a PDF date with the default parser
+func Parse(s string) (time.Time, error) {
+	return Parser{}.Parse(s)
+}
+
+// ParseISO parses an ISO 8601 date with the default parser
+func ParseISO(s string) (time.Time, error) {
+	return Parser{}.ParseISO(s)
+}
+
+// ParseAny parses a PDF or an ISO 8601 date with the default parser
+func ParseAny(s string) (time.Time, error) {
+	return Parser{}.ParseAny(s)
+}
+
+// location returns the zone of dates without a UT offset
+func (p Parser) location() *time.Location {
+	if p.Location != nil {
+		return p.Location
+	}
+	return time.UTC
+}
+
+// Parse parses a PDF date. Every field after the year is optional, as are the
+// apostrophes of the UT offset; fields left out take their earliest value.
+// Leniently, the "D:" prefix may be missing and text after the offset (such as
+// the "00'00'" some writers put after "Z") is ignored.
+func (p Parser) Parse(s string) (time.Time, error) {
+	rest := strings.TrimSpace(s)
+	if strings.HasPrefix(rest, "D:") {
+		rest = rest[2:]
+	} else if p.Strict {
+		return time.Time{}, fmt.Errorf("invalid PDF date %q: missing D: prefix", s)
+	}
+
+	// Year, then month, day, hour, minute and second of two digits each
+	fields := []int{0, 1, 1, 0, 0, 0}
+	widths := []int{4, 2, 2, 2, 2, 2}
+	for i, width := range widths {
+		if len(rest) == 0 || !isDigit(rest[0]) {
+			if i == 0 {
+				return time.Time{}, fmt.Errorf("invalid PDF date %q: missing year", s)
+			}
+			break
+		}
+		n, ok := digits(rest, width)
+		if !ok {
+			return time.Time{}, fmt.Errorf("invalid PDF date %q", s)
+		}
+		fields[i] = n
+		rest = rest[width:]
+	}
+
+	loc := p.location()
+	if len(rest) > 0 {
+		switch rest[0] {
+		case 'Z', 'z':
+			loc = time.UTC
+			rest = rest[1:]
+			if !p.Strict {
+				rest = ""
+			}
+		case '+', '-':
+			sign := 1
+			if rest[0] == '-' {
+				sign = -1
+			}
+			hours, ok := digits(rest[1:], 2)
+			if !ok {
+				return time.Time{}, fmt.Errorf("invalid PDF date %q: bad UT offset", s)
+			}
+			rest = strings.TrimPrefix(rest[3:], "'")
+			minutes := 0
+			if m, ok := digits(rest, 2); ok {
+				minutes = m
+				rest = strings.TrimPrefix(rest[2:], "'")
+			}
+			if hours > 23 || minutes > 59 {
+				return time.Time{}, fmt.Errorf("invalid PDF date %q: bad UT offset", s)
+			}
+			offset := sign * (hours*3600 + minutes*60)
+			loc = time.FixedZone(offsetName(offset), offset)
+		}
+	}
+	if p.Strict && rest != "" {
+		return time.Time{}, fmt.Errorf("invalid PDF date %q: unexpected %q", s, rest)
+	}
+	return date(s, fields, loc)
+}
+
+// isoLayouts are the ISO 8601 forms of W3C-DTF, which XMP uses, without and
+// with a time zone designator
+var isoLayouts = []struct {
+	layout string
+	zone   bool
+}{
+	{"2006-01-02T15:04:05.999999999Z07:00", true},
+	{"2006-01-02T15:04:05Z07:00", true},
+	{"2006-01-02T15:04Z07:00", true},
+	{"2006-01-02T15:04:05.999999999", false},
+	{"2006-01-02T15:04:05", false},
+	{"2006-01-02T15:04", false},
+	{"2006-01-02", false},
+	{"2006-01", false},
+	{"2006", false},
+}
+
+// ParseISO parses an ISO 8601 date in one of the W3C-DTF forms XMP uses, from
+// a year alone to a time with fractional seconds and a zone. Leniently, a space
+// may separate the date and the time.
+func (p Parser) ParseISO(s string) (time.Time, error) {
+	value := strings.TrimSpace(s)
+	if !p.Strict && len(value) > 10 && value[10] == ' ' {
+		value = value[:10] + "T" + value[11:]
+	}
+	for _, l := range isoLayouts {
+		if l.zone {
+			if t, err := time.Parse(l.layout, value); err == nil {
+				return t, nil
+			}
+		} else if t, err := time.ParseInLocation(l.layout, value, p.location()); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid ISO 8601 date %q", s)
+}
+
+// ParseAny parses a PDF date, recognized by its "D:" prefix or by starting
+// with more than four digits, or else an ISO 8601 date
+func (p Parser) ParseAny(s string) (time.Time, error) {
+	value := strings.TrimSpace(s)
+	if strings.HasPrefix(value, "D:") || (len(value) > 4 && isDigit(value[4])) {
+		return p.Parse(value)
+	}
+	return p.ParseISO(value)
+}
+
+// Format formats t as a PDF date with its UT offset, "Z" for UTC
+func Format(t time.Time) string {
+	_, offset := t.Zone()
+	if offset == 0 {
+		return t.Format("D:20060102150405Z")
+	}
+	sign := byte('+')
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	return fmt.Sprintf("%s%c%02d'%02d'", t.Format("D:20060102150405"), sign, offset/3600, offset/60%60)
+}
+
+// FormatISO formats t as an ISO 8601 date and time for XMP, e.g.
+// "2024-01-15T10:30:00+01:00"
+func FormatISO(t time.Time) string {
+	return t.Format(time.RFC3339)
+}
+
+// PDFToISO converts a PDF date to ISO 8601
+func PDFToISO(s string) (string, error) {
+	t, err := Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return FormatISO(t), nil
+}
+
+// ISOToPDF converts an ISO 8601 date to a PDF date
+func ISOToPDF(s string) (string, error) {
+	t, err := ParseISO(s)
+	if err != nil {
+		return "", err
+	}
+	return Format(t), nil
+}
+
+// Equal reports whether two PDF or ISO 8601 dates are at most tolerance apart.
+// Identical strings are equal; other dates are equal only if both parse.
+func Equal(a, b string, tolerance time.Duration) bool {
+	if a == b {
+		return true
+	}
+	ta, err := ParseAny(a)
+	if err != nil {
+		return false
+	}
+	tb, err := ParseAny(b)
+	if err != nil {
+		return false
+	}
+	diff := ta.Sub(tb)
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= tolerance
+}
+
+// date builds a time from year, month, day, hour, minute and second fields,
+// rejecting values out of range rather than normalizing them
+func date(s string, f []int, loc *time.Location) (time.Time, error) {
+	t := time.Date(f[0], time.Month(f[1]), f[2], f[3], f[4], f[5], 0, loc)
+	if t.Year() != f[0] || int(t.Month()) != f[1] || t.Day() != f[2] || t.Hour() != f[3] || t.Minute() != f[4] || t.Second() != f[5] {
+		return time.Time{}, fmt.Errorf("invalid PDF date %q: field out of range", s)
+	}
+	return t, nil
+}
+
+// digits parses the first n bytes of s as a decimal number
+func digits(s string, n int) (int, bool) {
+	if len(s) < n {
+		return 0, false
+	}
+	v := 0
+	for i := 0; i < n; i++ {
+		if !isDigit(s[i]) {
+			return 0, false
+		}
+		v = v*10 + int(s[i]-'0')
+	}
+	return v, true
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// offsetName names a fixed zone after its offset, e.g. "+01:00"
+func offsetName(offset int) string {
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	return fmt.Sprintf("%c%02d:%02d", sign, offset/3600, offset/60%60)
+}
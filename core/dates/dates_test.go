@@ -0,0 +1,131 @@
+package dates
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	plusOne := time.FixedZone("", 3600)
+	minusFive := time.FixedZone("", -5*3600-30*60)
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"D:20240115103045+01'00'", time.Date(2024, 1, 15, 10, 30, 45, 0, plusOne)},
+		{"D:20240115103045+01'00", time.Date(2024, 1, 15, 10, 30, 45, 0, plusOne)},
+		{"D:20240115103045-05'30'", time.Date(2024, 1, 15, 10, 30, 45, 0, minusFive)},
+		{"D:20240115103045Z", time.Date(2024, 1, 15, 10, 30, 45, 0, time.UTC)},
+		{"D:20240115103045Z00'00'", time.Date(2024, 1, 15, 10, 30, 45, 0, time.UTC)},
+		{"D:202401151030", time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)},
+		{"D:2024", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"20240115", time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{"D:20240115103045+0100", time.Date(2024, 1, 15, 10, 30, 45, 0, plusOne)},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("Parse(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "D:", "D:24", "D:20241315", "D:20240230", "D:20240115+1", "D:20240115+25'00'", "hello"} {
+		if _, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) should fail", in)
+		}
+	}
+}
+
+func TestParser_Options(t *testing.T) {
+	loc := time.FixedZone("CET", 3600)
+	got, err := Parser{Location: loc}.Parse("D:20240115103045")
+	if err != nil || !got.Equal(time.Date(2024, 1, 15, 9, 30, 45, 0, time.UTC)) {
+		t.Errorf("Parse with a location = %v, %v", got, err)
+	}
+
+	strict := Parser{Strict: true}
+	for _, in := range []string{"20240115", "D:20240115103045Z00'00'", "D:20240115 garbage", "2024-01-15 10:30:00"} {
+		if _, err := strict.ParseAny(in); err == nil {
+			t.Errorf("Strict ParseAny(%q) should fail", in)
+		}
+	}
+	if _, err := strict.Parse("D:20240115103045+01'00'"); err != nil {
+		t.Errorf("Strict Parse failed: %v", err)
+	}
+}
+
+func TestParseISO(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"2024-01-15T10:30:45.5+01:00", time.Date(2024, 1, 15, 9, 30, 45, 5e8, time.UTC)},
+		{"2024-01-15T10:30:45Z", time.Date(2024, 1, 15, 10, 30, 45, 0, time.UTC)},
+		{"2024-01-15T10:30-05:00", time.Date(2024, 1, 15, 15, 30, 0, 0, time.UTC)},
+		{"2024-01-15 10:30:45", time.Date(2024, 1, 15, 10, 30, 45, 0, time.UTC)},
+		{"2024-01-15", time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{"2024-01", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"2024", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseISO(tt.in)
+		if err != nil {
+			t.Errorf("ParseISO(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseISO(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+	if _, err := ParseISO("15/01/2024"); err == nil {
+		t.Error("ParseISO should fail for a non-ISO date")
+	}
+}
+
+func TestFormatAndConvert(t *testing.T) {
+	ts := time.Date(2024, 1, 15, 10, 30, 45, 0, time.FixedZone("", -(3*3600+30*60)))
+	if got := Format(ts); got != "D:20240115103045-03'30'" {
+		t.Errorf("Format = %q", got)
+	}
+	if got := Format(ts.UTC()); got != "D:20240115140045Z" {
+		t.Errorf("Format UTC = %q", got)
+	}
+	if got := FormatISO(ts); got != "2024-01-15T10:30:45-03:30" {
+		t.Errorf("FormatISO = %q", got)
+	}
+
+	// Both conversions round trip
+	if back, err := Parse(Format(ts)); err != nil || !back.Equal(ts) {
+		t.Errorf("Parse(Format) = %v, %v", back, err)
+	}
+	if iso, err := PDFToISO("D:20240115103045+01'00'"); err != nil || iso != "2024-01-15T10:30:45+01:00" {
+		t.Errorf("PDFToISO = %q, %v", iso, err)
+	}
+	if pdf, err := ISOToPDF("2024-01-15T10:30:45+01:00"); err != nil || pdf != "D:20240115103045+01'00'" {
+		t.Errorf("ISOToPDF = %q, %v", pdf, err)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		a, b      string
+		tolerance time.Duration
+		want      bool
+	}{
+		{"D:20240115103045Z", "D:20240115113045+01'00'", 0, true},
+		{"D:20240115103045Z", "2024-01-15T10:30:45Z", 0, true},
+		{"D:20240115103045Z", "D:20240115103050Z", 0, false},
+		{"D:20240115103045Z", "D:20240115103050Z", 10 * time.Second, true},
+		{"not a date", "not a date", 0, true},
+		{"not a date", "D:20240115103045Z", time.Hour, false},
+	}
+	for _, tt := range tests {
+		if got := Equal(tt.a, tt.b, tt.tolerance); got != tt.want {
+			t.Errorf("Equal(%q, %q, %v) = %v, want %v", tt.a, tt.b, tt.tolerance, got, tt.want)
+		}
+	}
+}
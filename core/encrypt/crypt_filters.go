@@ -0,0 +1,48 @@
+package encrypt
+
+import (
+	"regexp"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// IdentityFilter is the crypt filter that leaves data unencrypted (ISO 32000-1, 7.6.5)
+const IdentityFilter = "Identity"
+
+var (
+	xrefStreamPattern   = regexp.MustCompile(`/Type\s*/XRef\b`)
+	metadataPattern     = regexp.MustCompile(`/Type\s*/Metadata\b`)
+	embeddedFilePattern = regexp.MustCompile(`/Type\s*/EmbeddedFile\b`)
+)
+
+// EncryptsStrings reports whether strings are encrypted. Before V4 everything is
+// encrypted; from V4 the /StrF crypt filter decides.
+func EncryptsStrings(encrypt *types.PDFEncryption) bool {
+	return encrypt != nil && (encrypt.V < 4 || encrypt.StrF != IdentityFilter)
+}
+
+// EncryptsStream reports whether the stream with the raw dictionary dict is
+// encrypted. Cross-reference streams never are, metadata streams are not with
+// /EncryptMetadata false, embedded files use the /EFF crypt filter and other
+// streams /StmF.
+func EncryptsStream(dict []byte, encrypt *types.PDFEncryption) bool {
+	if encrypt == nil || xrefStreamPattern.Match(dict) {
+		return false
+	}
+	filter := encrypt.StmF
+	switch {
+	case embeddedFilePattern.Match(dict) && encrypt.EFF != "":
+		filter = encrypt.EFF
+	case metadataPattern.Match(dict) && !encrypt.EncryptMetadata:
+		return false
+	}
+	return encrypt.V < 4 || filter != IdentityFilter
+}
+
+// AttachmentsOnly reports whether only embedded files are encrypted: strings and
+// streams use the Identity crypt filter and embedded files the /EFF filter.
+// Such documents open without a password; it is needed for the attachments.
+func AttachmentsOnly(encrypt *types.PDFEncryption) bool {
+	return encrypt != nil && encrypt.V >= 4 && encrypt.StmF == IdentityFilter && encrypt.StrF == IdentityFilter &&
+		encrypt.EFF != "" && encrypt.EFF != IdentityFilter
+}
@@ -0,0 +1,40 @@
+package encrypt
+
+import (
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+func TestCryptFilters(t *testing.T) {
+	rc4 := &types.PDFEncryption{V: 2, StmF: IdentityFilter, StrF: IdentityFilter}
+	attachments := &types.PDFEncryption{V: 5, StmF: IdentityFilter, StrF: IdentityFilter, EFF: "StdCF", EncryptMetadata: true}
+	aes := &types.PDFEncryption{V: 4, StmF: "StdCF", StrF: "StdCF"}
+
+	tests := []struct {
+		name string
+		enc  *types.PDFEncryption
+		dict string
+		want bool
+	}{
+		{"RC4 ignores crypt filters", rc4, "<</Length 10>>", true},
+		{"attachments-only content", attachments, "<</Length 10>>", false},
+		{"attachments-only embedded file", attachments, "<</Type /EmbeddedFile /Length 10>>", true},
+		{"xref stream", aes, "<</Type/XRef/W [1 2 1]>>", false},
+		{"plaintext metadata", aes, "<</Type/Metadata/Subtype/XML>>", false},
+		{"embedded file without EFF", aes, "<</Type/EmbeddedFile>>", true},
+		{"no encryption", nil, "<</Length 10>>", false},
+	}
+	for _, tt := range tests {
+		if got := EncryptsStream([]byte(tt.dict), tt.enc); got != tt.want {
+			t.Errorf("%s: EncryptsStream = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if !EncryptsStrings(rc4) || EncryptsStrings(attachments) || !EncryptsStrings(aes) {
+		t.Error("EncryptsStrings ignored /StrF")
+	}
+	if !AttachmentsOnly(attachments) || AttachmentsOnly(aes) || AttachmentsOnly(rc4) {
+		t.Error("AttachmentsOnly misreported")
+	}
+}
@@ -55,8 +55,14 @@ func DecryptPDF(pdfBytes []byte, password []byte, verbose bool) ([]byte, *types.
 			}
 			ownerPasswordKey, err2 := DeriveOwnerKeyV5(password, encrypt, fileID, verbose)
 			if err2 == nil {
-				// For owner password, we need to verify differently
-				// Owner password verification involves unwrapping /OE and checking if it works
+				// Verify the owner password against /O before trusting the key unwrapped from /OE;
+				// a wrong key can still produce valid-looking padding
+				var oMatch bool
+				if oMatch, err2 = VerifyOValueV5(password, ownerPasswordKey, encrypt, verbose); err2 == nil && !oMatch {
+					err2 = types.NewPDFError(types.ErrCodeWrongPassword, "owner password does not match /O")
+				}
+			}
+			if err2 == nil {
 				ownerKey, err2 := UnwrapOwnerKeyV5(ownerPasswordKey, encrypt, verbose)
 				if err2 == nil {
 					// If unwrapping succeeded, owner password is correct
@@ -0,0 +1,198 @@
+package encrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rc4"
+	"fmt"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// EncryptObject encrypts a string or stream with the per-object key.
+// It is the inverse of DecryptObject: RC4 for V1/V2, AES-CBC with a random
+// IV prepended for V4/V5.
+func EncryptObject(data []byte, objNum, genNum int, encrypt *types.PDFEncryption) ([]byte, error) {
+	if encrypt == nil || len(encrypt.EncryptKey) == 0 {
+		return data, nil
+	}
+
+	key := objectKey(objNum, genNum, encrypt)
+
+	switch encrypt.V {
+	case 1, 2:
+		c, err := rc4.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		encrypted := make([]byte, len(data))
+		c.XORKeyStream(encrypted, data)
+		return encrypted, nil
+	case 4, 5:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+
+		// PKCS#7 padding
+		paddingLen := aes.BlockSize - len(data)%aes.BlockSize
+		padded := make([]byte, len(data)+paddingLen)
+		copy(padded, data)
+		for i := len(data); i < len(padded); i++ {
+			padded[i] = byte(paddingLen)
+		}
+
+		encrypted := make([]byte, aes.BlockSize+len(padded))
+		if _, err := rand.Read(encrypted[:aes.BlockSize]); err != nil {
+			return nil, fmt.Errorf("failed to generate IV: %v", err)
+		}
+		cipher.NewCBCEncrypter(block, encrypted[:aes.BlockSize]).CryptBlocks(encrypted[aes.BlockSize:], padded)
+		return encrypted, nil
+	}
+
+	return nil, types.NewPDFErrorf(types.ErrCodeUnsupportedCrypto, "unsupported encryption version V=%d", encrypt.V).WithContext("version", encrypt.V)
+}
+
+// objectKey derives the per-object key exactly as DecryptObject does
+func objectKey(objNum, genNum int, encrypt *types.PDFEncryption) []byte {
+	n := 5
+	if encrypt.V > 1 {
+		n = encrypt.KeyLength
+	}
+
+	keyData := make([]byte, n+5)
+	copy(keyData, encrypt.EncryptKey[:n])
+	keyData[n] = byte(objNum & 0xff)
+	keyData[n+1] = byte((objNum >> 8) & 0xff)
+	keyData[n+2] = byte((objNum >> 16) & 0xff)
+	keyData[n+3] = byte(genNum & 0xff)
+	keyData[n+4] = byte((genNum >> 8) & 0xff)
+
+	hash := md5.New()
+	hash.Write(keyData)
+	if encrypt.V == 4 || encrypt.V == 5 {
+		hash.Write([]byte("sAlT"))
+	}
+	return hash.Sum(nil)[:min(n+5, 16)]
+}
+
+// EncryptStrings encrypts every string in an object's syntax (not stream data)
+func EncryptStrings(obj []byte, objNum, genNum int, encrypt *types.PDFEncryption) ([]byte, error) {
+	if encrypt == nil || len(encrypt.EncryptKey) == 0 {
+		return obj, nil
+	}
+	return TransformStrings(obj, func(s []byte) ([]byte, error) {
+		return EncryptObject(s, objNum, genNum, encrypt)
+	})
+}
+
+// DecryptStrings decrypts every string in an object's syntax (not stream data).
+// Strings that fail to decrypt are kept unchanged.
+func DecryptStrings(obj []byte, objNum, genNum int, encrypt *types.PDFEncryption) ([]byte, error) {
+	if encrypt == nil || len(encrypt.EncryptKey) == 0 {
+		return obj, nil
+	}
+	return TransformStrings(obj, func(s []byte) ([]byte, error) {
+		if len(s) == 0 {
+			return s, nil
+		}
+		decrypted, err := DecryptObject(s, objNum, genNum, encrypt)
+		if err != nil {
+			// Leave strings that were not encrypted (e.g. malformed AES data) as they are
+			return s, nil
+		}
+		return decrypted, nil
+	})
+}
+
+// NewStandardEncryption creates Standard security handler parameters for
+// RC4-128 (V2/R3) or AES-128 (V4/R4) and derives the document key.
+// AES-256 is set up by write.SetupAES256Encryption.
+// Algorithms 2, 3 and 5 from ISO 32000-1:2008
+func NewStandardEncryption(v int, userPassword, ownerPassword, fileID []byte, permissions int32, encryptMetadata bool) (*types.PDFEncryption, error) {
+	if v != 2 && v != 4 {
+		return nil, types.NewPDFErrorf(types.ErrCodeUnsupportedCrypto, "unsupported encryption version V=%d", v).WithContext("version", v)
+	}
+	if len(ownerPassword) == 0 {
+		ownerPassword = userPassword
+	}
+
+	r := 3
+	if v == 4 {
+		r = 4
+	}
+
+	encrypt := &types.PDFEncryption{
+		V:               v,
+		R:               r,
+		KeyLength:       16,
+		Filter:          "Standard",
+		P:               permissions,
+		EncryptMetadata: encryptMetadata,
+	}
+	encrypt.O = ComputeOValue(ownerPassword, userPassword, encrypt)
+
+	key, err := DeriveEncryptionKey(userPassword, encrypt, fileID, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive encryption key: %v", err)
+	}
+	encrypt.EncryptKey = key
+
+	u, err := ComputeUValue(key, encrypt, fileID, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute U value: %v", err)
+	}
+	encrypt.U = u
+
+	return encrypt, nil
+}
+
+// ComputeOValue computes the O value from the owner and user passwords (R2-R4)
+// Algorithm 3 from ISO 32000-1:2008
+func ComputeOValue(ownerPassword, userPassword []byte, encrypt *types.PDFEncryption) []byte {
+	ownerKey := md5.Sum(padPassword(ownerPassword))
+	key := ownerKey[:]
+	if encrypt.R >= 3 {
+		for i := 0; i < 50; i++ {
+			sum := md5.Sum(key)
+			key = sum[:]
+		}
+		key = key[:encrypt.KeyLength]
+	} else {
+		key = key[:5]
+	}
+
+	o := padPassword(userPassword)
+	c, _ := rc4.NewCipher(key)
+	c.XORKeyStream(o, o)
+
+	if encrypt.R >= 3 {
+		iterKey := make([]byte, len(key))
+		for i := 1; i <= 19; i++ {
+			for j := range key {
+				iterKey[j] = key[j] ^ byte(i)
+			}
+			c, _ := rc4.NewCipher(iterKey)
+			c.XORKeyStream(o, o)
+		}
+	}
+	return o
+}
+
+// passwordPadding is the padding string from Algorithm 2 of ISO 32000-1:2008
+var passwordPadding = []byte{
+	0x28, 0xBF, 0x4E, 0x5E, 0x4E, 0x75, 0x8A, 0x41,
+	0x64, 0x00, 0x4E, 0x56, 0xFF, 0xFA, 0x01, 0x08,
+	0x2E, 0x2E, 0x00, 0xB6, 0xD0, 0x68, 0x3E, 0x80,
+	0x2F, 0x0C, 0xA9, 0xFE, 0x64, 0x53, 0x69, 0x7A,
+}
+
+// padPassword truncates a password to 32 bytes or pads it with passwordPadding
+func padPassword(password []byte) []byte {
+	padded := make([]byte, 32)
+	n := copy(padded, password)
+	copy(padded[n:], passwordPadding)
+	return padded
+}
@@ -0,0 +1,79 @@
+package encrypt
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// newTestEncryption returns RC4 (V2) or AES-128 (V4) parameters with a fixed key
+func newTestEncryption(v int) *types.PDFEncryption {
+	return &types.PDFEncryption{
+		V:         v,
+		R:         v - 1,
+		KeyLength: 16,
+		EncryptKey: []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+			0x09, 0x0A, 0x0B, 0x0C, 0x0D, 0x0E, 0x0F, 0x10},
+	}
+}
+
+func TestEncryptObject_RoundTrip(t *testing.T) {
+	plaintext := []byte("BT /F1 12 Tf 72 700 Td (Hello) Tj ET")
+
+	for _, v := range []int{2, 4} {
+		enc := newTestEncryption(v)
+
+		encrypted, err := EncryptObject(plaintext, 7, 0, enc)
+		if err != nil {
+			t.Fatalf("V%d: EncryptObject() error = %v", v, err)
+		}
+		if bytes.Equal(encrypted, plaintext) {
+			t.Fatalf("V%d: EncryptObject() returned plaintext", v)
+		}
+
+		decrypted, err := DecryptObject(encrypted, 7, 0, enc)
+		if err != nil {
+			t.Fatalf("V%d: DecryptObject() error = %v", v, err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Errorf("V%d: round trip = %q, want %q", v, decrypted, plaintext)
+		}
+	}
+}
+
+func TestNewStandardEncryption_Passwords(t *testing.T) {
+	fileID := []byte{0x7F, 0xB1, 0x57, 0xEB, 0x01, 0x02, 0x03, 0x04}
+
+	for _, v := range []int{2, 4} {
+		enc, err := NewStandardEncryption(v, []byte("user"), []byte("owner"), fileID, -3900, true)
+		if err != nil {
+			t.Fatalf("V%d: NewStandardEncryption() error = %v", v, err)
+		}
+		if len(enc.O) != 32 || len(enc.U) != 32 {
+			t.Errorf("V%d: O/U lengths = %d/%d, want 32/32", v, len(enc.O), len(enc.U))
+		}
+
+		// The user password derives the document key directly
+		userKey, err := DeriveEncryptionKey([]byte("user"), enc, fileID, false)
+		if err != nil {
+			t.Fatalf("V%d: DeriveEncryptionKey() error = %v", v, err)
+		}
+		if !bytes.Equal(userKey, enc.EncryptKey) {
+			t.Errorf("V%d: user password key mismatch", v)
+		}
+
+		// The owner password recovers the user password from O
+		ownerKey, err := DeriveOwnerKey([]byte("owner"), enc, fileID, false)
+		if err != nil {
+			t.Fatalf("V%d: DeriveOwnerKey() error = %v", v, err)
+		}
+		if !bytes.Equal(ownerKey, enc.EncryptKey) {
+			t.Errorf("V%d: owner password key mismatch", v)
+		}
+	}
+
+	if _, err := NewStandardEncryption(3, nil, nil, fileID, -4, true); err == nil {
+		t.Error("expected error for unsupported version")
+	}
+}
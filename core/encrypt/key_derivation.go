@@ -24,29 +24,9 @@ func DeriveEncryptionKey(password []byte, encrypt *types.PDFEncryption, fileID [
 		return DeriveEncryptionKeyV5(password, encrypt, fileID, verbose)
 	}
 	// Pad or truncate password to 32 bytes
-	// According to PDF spec Algorithm 2:
-	// - If password is shorter than 32 bytes, pad by appending bytes from the password cyclically
-	// - For empty password, the padding string itself is used
-	paddingString := []byte{
-		0x28, 0xBF, 0x4E, 0x5E, 0x4E, 0x75, 0x8A, 0x41,
-		0x64, 0x00, 0x4E, 0x56, 0xFF, 0xFA, 0x01, 0x08,
-		0x2E, 0x2E, 0x00, 0xB6, 0xD0, 0x68, 0x3E, 0x80,
-		0x2F, 0x0C, 0xA9, 0xFE, 0x64, 0x53, 0x69, 0x7A,
-	}
-
-	paddedPassword := make([]byte, 32)
-	if len(password) == 0 {
-		// Empty password: use padding string directly
-		copy(paddedPassword, paddingString)
-	} else {
-		copy(paddedPassword, password)
-		if len(password) < 32 {
-			// Pad with password bytes cyclically
-			for i := len(password); i < 32; i++ {
-				paddedPassword[i] = password[i%len(password)]
-			}
-		}
-	}
+	// According to PDF spec Algorithm 2, a short password is completed with
+	// the leading bytes of the padding string (an empty password is the padding string itself)
+	paddedPassword := padPassword(password)
 
 	// Step 1: Compute hash of padded password + O + P (as 32-bit int, little-endian) + ID[0]
 	hash := md5.New()
@@ -202,25 +182,7 @@ func DeriveOwnerKey(ownerPassword []byte, encrypt *types.PDFEncryption, fileID [
 		return DeriveOwnerKeyV5(ownerPassword, encrypt, fileID, verbose)
 	}
 	// Pad owner password to 32 bytes
-	paddingString := []byte{
-		0x28, 0xBF, 0x4E, 0x5E, 0x4E, 0x75, 0x8A, 0x41,
-		0x64, 0x00, 0x4E, 0x56, 0xFF, 0xFA, 0x01, 0x08,
-		0x2E, 0x2E, 0x00, 0xB6, 0xD0, 0x68, 0x3E, 0x80,
-		0x2F, 0x0C, 0xA9, 0xFE, 0x64, 0x53, 0x69, 0x7A,
-	}
-
-	paddedOwnerPassword := make([]byte, 32)
-	if len(ownerPassword) > 0 {
-		copy(paddedOwnerPassword, ownerPassword)
-		if len(ownerPassword) < 32 {
-			for i := len(ownerPassword); i < 32; i++ {
-				paddedOwnerPassword[i] = ownerPassword[i%len(ownerPassword)]
-			}
-		}
-	} else {
-		// Empty password - use padding string
-		copy(paddedOwnerPassword, paddingString)
-	}
+	paddedOwnerPassword := padPassword(ownerPassword)
 
 	// MD5 hash of padded owner password
 	hash := md5.New()
@@ -241,22 +203,29 @@ func DeriveOwnerKey(ownerPassword []byte, encrypt *types.PDFEncryption, fileID [
 		ownerHash = ownerHash[:encrypt.KeyLength]
 	}
 
-	// Decrypt O value using owner key
-	// O value is encrypted with RC4 using owner key
-	decryptedO := make([]byte, len(encrypt.O))
+	// Decrypt O value using owner key (Algorithm 7)
+	// Revision 2 uses a single RC4 pass; revision 3+ undoes the 20 passes of
+	// Algorithm 3 with the key XORed by 19 down to 0
+	decryptedO := make([]byte, min(32, len(encrypt.O)))
+	copy(decryptedO, encrypt.O)
 	if encrypt.R == 2 {
 		cipher, err := rc4.NewCipher(ownerHash)
 		if err != nil {
 			return nil, err
 		}
-		cipher.XORKeyStream(decryptedO, encrypt.O)
+		cipher.XORKeyStream(decryptedO, decryptedO)
 	} else {
-		// Revision 3+: decrypt O with RC4, then use it to derive user key
-		cipher, err := rc4.NewCipher(ownerHash)
-		if err != nil {
-			return nil, err
+		iterKey := make([]byte, len(ownerHash))
+		for i := 19; i >= 0; i-- {
+			for j := range ownerHash {
+				iterKey[j] = ownerHash[j] ^ byte(i)
+			}
+			cipher, err := rc4.NewCipher(iterKey)
+			if err != nil {
+				return nil, err
+			}
+			cipher.XORKeyStream(decryptedO, decryptedO)
 		}
-		cipher.XORKeyStream(decryptedO, encrypt.O[:min(32, len(encrypt.O))])
 	}
 
 	// Now derive user key from decrypted O (which is the user password hash)
@@ -504,6 +473,37 @@ func DeriveOwnerKeyV5(ownerPassword []byte, encrypt *types.PDFEncryption, fileID
 	return DeriveEncryptionKeyV5(ownerPassword, encrypt, fileID, verbose)
 }
 
+// VerifyOValueV5 verifies an owner password against the stored O value:
+// SHA-256(password + owner validation salt + U) encrypted with the owner password key
+func VerifyOValueV5(password []byte, ownerPasswordKey []byte, encrypt *types.PDFEncryption, verbose bool) (bool, error) {
+	if len(encrypt.O) < 48 || len(encrypt.U) < 48 {
+		return false, fmt.Errorf("O or U value too short for V5: %d/%d bytes (expected at least 48)", len(encrypt.O), len(encrypt.U))
+	}
+	if len(ownerPasswordKey) < 16 {
+		return false, fmt.Errorf("owner password key too short: %d bytes (need at least 16 for AES-128)", len(ownerPasswordKey))
+	}
+
+	hash := sha256.New()
+	hash.Write(password)
+	hash.Write(encrypt.O[:8])
+	hash.Write(encrypt.U[:48])
+	hashed := hash.Sum(nil)
+
+	block, err := aes.NewCipher(ownerPasswordKey[:16])
+	if err != nil {
+		return false, fmt.Errorf("failed to create AES cipher: %v", err)
+	}
+	encrypted := make([]byte, 32)
+	block.Encrypt(encrypted[:16], hashed[:16])
+	block.Encrypt(encrypted[16:], hashed[16:])
+
+	match := bytes.Equal(encrypted, encrypt.O[8:40])
+	if verbose {
+		log.Printf("V5 O value verification: %v", match)
+	}
+	return match, nil
+}
+
 // ComputeUValueV5 computes the U value for V5/R5/R6 password verification
 // Based on ISO 32000-2 section 7.6.4.4.9 - uses SHA-256 and AES-128
 // This function computes the U value from a password for verification purposes.
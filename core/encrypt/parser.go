@@ -224,6 +224,15 @@ func ParseEncryptionDictionary(pdfBytes []byte, verbose bool) (*types.PDFEncrypt
 		encrypt.EncryptMetadata = true
 	}
 
+	// Parse the crypt filters applied to streams, strings and embedded files - V4+ only
+	if encrypt.V >= 4 {
+		for key, field := range map[string]*string{"StmF": &encrypt.StmF, "StrF": &encrypt.StrF, "EFF": &encrypt.EFF} {
+			if match := regexp.MustCompile(`/` + key + `\s*/(\w+)`).FindStringSubmatch(dictContent); match != nil {
+				*field = match[1]
+			}
+		}
+	}
+
 	// Parse /UE (encrypted user encryption key) - V5+ only
 	// Format: /UE <hex> or /UE (binary)
 	if encrypt.R >= 5 {
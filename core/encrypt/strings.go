@@ -0,0 +1,183 @@
+package encrypt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+)
+
+// StringToken is a string found in PDF object syntax
+type StringToken struct {
+	Start int    // Offset of the opening "(" or "<"
+	End   int    // Offset just past the closing ")" or ">"
+	Value []byte // Decoded string bytes
+	Hex   bool   // True for "<...>" hex strings
+}
+
+// FindStrings locates every literal "(...)" and hex "<...>" string in PDF
+// object syntax, skipping comments and dictionary delimiters. obj must not
+// include stream data.
+func FindStrings(obj []byte) ([]StringToken, error) {
+	var tokens []StringToken
+
+	for i := 0; i < len(obj); {
+		c := obj[i]
+		switch {
+		case c == '%':
+			// Comment: skip through end of line
+			end := bytes.IndexAny(obj[i:], "\r\n")
+			if end == -1 {
+				return tokens, nil
+			}
+			i += end
+		case c == '(':
+			value, n, err := readLiteralString(obj[i:])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, StringToken{Start: i, End: i + n, Value: value})
+			i += n
+		case c == '<' && i+1 < len(obj) && obj[i+1] == '<':
+			i += 2
+		case c == '<':
+			end := bytes.IndexByte(obj[i:], '>')
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated hex string at offset %d", i)
+			}
+			tokens = append(tokens, StringToken{Start: i, End: i + end + 1, Value: decodeHexString(obj[i+1 : i+end]), Hex: true})
+			i += end + 1
+		default:
+			i++
+		}
+	}
+
+	return tokens, nil
+}
+
+// TransformStrings rewrites every literal "(...)" and hex "<...>" string in
+// PDF object syntax through fn. Binary results are written back as hex
+// strings so they need no escaping. obj must not include stream data.
+func TransformStrings(obj []byte, fn func([]byte) ([]byte, error)) ([]byte, error) {
+	tokens, err := FindStrings(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	out.Grow(len(obj))
+
+	last := 0
+	for _, token := range tokens {
+		out.Write(obj[last:token.Start])
+		if err := writeTransformed(&out, token.Value, fn); err != nil {
+			return nil, err
+		}
+		last = token.End
+	}
+	out.Write(obj[last:])
+
+	return out.Bytes(), nil
+}
+
+// writeTransformed applies fn to a string value and writes the result, as a
+// literal string when it is printable text and as a hex string otherwise
+func writeTransformed(out *bytes.Buffer, value []byte, fn func([]byte) ([]byte, error)) error {
+	transformed, err := fn(value)
+	if err != nil {
+		return err
+	}
+	for _, b := range transformed {
+		if b < 0x20 || b > 0x7e {
+			out.WriteByte('<')
+			out.WriteString(hex.EncodeToString(transformed))
+			out.WriteByte('>')
+			return nil
+		}
+	}
+	out.WriteByte('(')
+	for _, b := range transformed {
+		if b == '(' || b == ')' || b == '\\' {
+			out.WriteByte('\\')
+		}
+		out.WriteByte(b)
+	}
+	out.WriteByte(')')
+	return nil
+}
+
+// readLiteralString decodes a literal string starting at data[0] == '('.
+// It returns the string bytes and the number of input bytes consumed.
+func readLiteralString(data []byte) ([]byte, int, error) {
+	var value []byte
+	depth := 0
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch c {
+		case '(':
+			depth++
+			if depth == 1 {
+				continue
+			}
+		case ')':
+			depth--
+			if depth == 0 {
+				return value, i + 1, nil
+			}
+		case '\\':
+			i++
+			if i >= len(data) {
+				return nil, 0, fmt.Errorf("unterminated literal string")
+			}
+			switch e := data[i]; e {
+			case 'n':
+				value = append(value, '\n')
+			case 'r':
+				value = append(value, '\r')
+			case 't':
+				value = append(value, '\t')
+			case 'b':
+				value = append(value, '\b')
+			case 'f':
+				value = append(value, '\f')
+			case '\r':
+				// Line continuation
+				if i+1 < len(data) && data[i+1] == '\n' {
+					i++
+				}
+			case '\n':
+				// Line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					octal := int(e - '0')
+					for k := 0; k < 2 && i+1 < len(data) && data[i+1] >= '0' && data[i+1] <= '7'; k++ {
+						i++
+						octal = octal*8 + int(data[i]-'0')
+					}
+					value = append(value, byte(octal))
+				} else {
+					value = append(value, e)
+				}
+			}
+			continue
+		}
+		value = append(value, c)
+	}
+	return nil, 0, fmt.Errorf("unterminated literal string")
+}
+
+// decodeHexString decodes hex string contents, ignoring whitespace and
+// padding an odd final digit with 0
+func decodeHexString(data []byte) []byte {
+	digits := make([]byte, 0, len(data)+1)
+	for _, c := range data {
+		if (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 != 0 {
+		digits = append(digits, '0')
+	}
+	decoded := make([]byte, len(digits)/2)
+	hex.Decode(decoded, digits)
+	return decoded
+}
@@ -0,0 +1,59 @@
+package encrypt
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTransformStrings(t *testing.T) {
+	identity := func(s []byte) ([]byte, error) { return s, nil }
+
+	tests := []struct {
+		name string
+		obj  string
+		want string
+	}{
+		{"literal", "<</T(field1)/V (a b)>>", "<</T(field1)/V (a b)>>"},
+		{"escapes", `<</V (a\(b\)\\c\nd\101)>>`, "<</V <612862295c630a6441>>>"},
+		{"nested parens", "<</V (a(b)c)>>", `<</V (a\(b\)c)>>`},
+		{"hex", "<</ID [<7FB1 57E> <00ff>]>>", "<</ID [<7fb157e0> <00ff>]>>"},
+		{"printable hex", "<</T <6669656c6431>>>", "<</T (field1)>>"},
+		{"no strings", "<</Type/Page/Kids [1 0 R]>>", "<</Type/Page/Kids [1 0 R]>>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TransformStrings([]byte(tt.obj), identity)
+			if err != nil {
+				t.Fatalf("TransformStrings() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("TransformStrings() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := TransformStrings([]byte("<</V (open"), identity); err == nil {
+		t.Error("expected error for unterminated string")
+	}
+}
+
+func TestEncryptStrings_RoundTrip(t *testing.T) {
+	enc := newTestEncryption(4)
+	obj := []byte("<</Type/Annot/T(field1)/Contents (Caf\\351)>>")
+
+	encrypted, err := EncryptStrings(obj, 12, 0, enc)
+	if err != nil {
+		t.Fatalf("EncryptStrings() error = %v", err)
+	}
+	if bytes.Contains(encrypted, []byte("field1")) {
+		t.Errorf("EncryptStrings() left plaintext: %s", encrypted)
+	}
+
+	decrypted, err := DecryptStrings(encrypted, 12, 0, enc)
+	if err != nil {
+		t.Fatalf("DecryptStrings() error = %v", err)
+	}
+	if want := "<</Type/Annot/T(field1)/Contents <436166e9>>>"; string(decrypted) != want {
+		t.Errorf("DecryptStrings() = %q, want %q", decrypted, want)
+	}
+}
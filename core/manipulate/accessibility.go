@@ -0,0 +1,110 @@
+package manipulate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// maxFieldDepth bounds the walk of the form's field hierarchy
+const maxFieldDepth = 64
+
+// SetAltText sets the alternate text (/Alt) of structure elements, such as
+// figures, given by object number as extract.AnalyzeAccessibility reports
+// them. An empty text removes /Alt. Nothing is changed when one of the objects
+// is not a structure element.
+func (m *PDFManipulator) SetAltText(alt map[int]string) error {
+	objNums := make([]int, 0, len(alt))
+	for objNum := range alt {
+		elem := string(objectBody(m.objects[objNum]))
+		if !strings.HasPrefix(elem, "<<") || topLevelValue(elem, "/S") == "" || topLevelValue(elem, "/P") == "" {
+			return types.NewPDFError(types.ErrCodeInvalidInput, fmt.Sprintf("object %d is not a structure element", objNum))
+		}
+		objNums = append(objNums, objNum)
+	}
+	sort.Ints(objNums)
+	for _, objNum := range objNums {
+		m.objects[objNum] = []byte(setTextEntry(string(objectBody(m.objects[objNum])), "/Alt", alt[objNum]))
+	}
+	if m.verbose {
+		fmt.Printf("Set alternate text of %d structure elements\n", len(objNums))
+	}
+	return nil
+}
+
+// SetTooltips sets the tooltips (/TU) of form fields by full name, such as
+// "applicant.name". Assistive technology announces a field by its tooltip; a
+// tooltip on a radio button group covers its buttons. An empty tooltip removes
+// /TU. Nothing is changed when one of the names is not a field.
+func (m *PDFManipulator) SetTooltips(tooltips map[string]string) error {
+	fields := m.fieldObjects()
+	var missing []string
+	for name := range tooltips {
+		if _, ok := fields[name]; !ok {
+			missing = append(missing, strconv.Quote(name))
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return types.NewPDFError(types.ErrCodeInvalidInput, fmt.Sprintf("no form field named %s", strings.Join(missing, ", ")))
+	}
+	for name, tooltip := range tooltips {
+		objNum := fields[name]
+		m.objects[objNum] = []byte(setTextEntry(string(objectBody(m.objects[objNum])), "/TU", tooltip))
+	}
+	if m.verbose {
+		fmt.Printf("Set tooltips of %d form fields\n", len(tooltips))
+	}
+	return nil
+}
+
+// fieldObjects returns the object numbers of the form's fields, terminal or
+// not, by full name. Widgets without a partial name of their own are left out.
+func (m *PDFManipulator) fieldObjects() map[string]int {
+	fields := make(map[string]int)
+	visited := make(map[int]bool)
+	var visit func(array, parent string, depth int)
+	visit = func(array, parent string, depth int) {
+		if depth > maxFieldDepth {
+			return
+		}
+		if array != "" && !strings.HasPrefix(array, "[") {
+			objNum, err := parseObjectRef(array)
+			if err != nil {
+				return
+			}
+			array = string(objectBody(m.objects[objNum]))
+		}
+		for _, objNum := range objectRefs(array) {
+			if visited[objNum] {
+				continue
+			}
+			visited[objNum] = true
+			field := string(objectBody(m.objects[objNum]))
+			partial := topLevelValue(field, "/T")
+			if partial == "" {
+				continue
+			}
+			name := pdfTextString(decodedString(partial))
+			if parent != "" {
+				name = parent + "." + name
+			}
+			fields[name] = objNum
+			visit(topLevelValue(field, "/Kids"), name, depth+1)
+		}
+	}
+	visit(rawDictValue(m.catalogAcroForm(), "/Fields"), "", 0)
+	return fields
+}
+
+// setTextEntry sets a text string entry of a dictionary, or removes it when
+// text is empty
+func setTextEntry(dict, key, text string) string {
+	if text == "" {
+		return removeTopLevelKey(dict, key)
+	}
+	return setTopLevelValue(dict, key, utf16PDFString(text))
+}
@@ -0,0 +1,97 @@
+package manipulate
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+func TestSetAltTextAndTooltips(t *testing.T) {
+	writer := write.NewPDFWriter()
+	catalogNum := writer.AddObject(nil)
+	pagesNum := writer.AddObject(nil)
+	pageNum := writer.AddObject([]byte(fmt.Sprintf("<</Type/Page/Parent %d 0 R/MediaBox[0 0 612 792]>>", pagesNum)))
+	writer.SetObject(pagesNum, []byte(fmt.Sprintf("<</Type/Pages/Kids[%d 0 R]/Count 1>>", pageNum)))
+
+	structRoot := writer.AddObject(nil)
+	figure := writer.AddObject([]byte(fmt.Sprintf("<</Type/StructElem/S/Figure/P %d 0 R/Pg %d 0 R/K 0>>", structRoot, pageNum)))
+	writer.SetObject(structRoot, []byte(fmt.Sprintf("<</Type/StructTreeRoot/K[%d 0 R]>>", figure)))
+
+	name := writer.AddObject([]byte("<</T(name)/FT/Tx/Subtype/Widget/Rect[72 400 272 420]>>"))
+	group := writer.AddObject(nil)
+	option := writer.AddObject([]byte(fmt.Sprintf("<</T(a)/Parent %d 0 R/FT/Btn/Subtype/Widget/Rect[72 360 92 380]>>", group)))
+	writer.SetObject(group, []byte(fmt.Sprintf("<</T(group)/Kids[%d 0 R]>>", option)))
+	writer.SetObject(catalogNum, []byte(fmt.Sprintf(
+		"<</Type/Catalog/Pages %d 0 R/Lang(en)/StructTreeRoot %d 0 R/MarkInfo<</Marked true>>/AcroForm<</Fields[%d 0 R %d 0 R]>>>>",
+		pagesNum, structRoot, name, group)))
+	writer.SetRoot(catalogNum)
+	pdfBytes, err := writer.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+
+	m, err := NewPDFManipulator(pdfBytes, nil, false)
+	if err != nil {
+		t.Fatalf("NewPDFManipulator failed: %v", err)
+	}
+	if err := m.SetAltText(map[int]string{figure: "Chart", name: "Not a figure"}); err == nil {
+		t.Error("Expected an error for an object that is not a structure element")
+	}
+	if err := m.SetTooltips(map[string]string{"name": "Full name", "group.b": "Missing"}); err == nil {
+		t.Error("Expected an error for an unknown field")
+	}
+	if err := m.SetAltText(map[int]string{figure: "Sales by quarter, 2024"}); err != nil {
+		t.Fatalf("SetAltText failed: %v", err)
+	}
+	if err := m.SetTooltips(map[string]string{"name": "Full name", "group": "Preferred contact"}); err != nil {
+		t.Fatalf("SetTooltips failed: %v", err)
+	}
+	out, err := m.Rebuild()
+	if err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+
+	pdf, err := parse.Open(out)
+	if err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+	report, err := extract.AnalyzeAccessibility(out, pdf, false)
+	if err != nil {
+		t.Fatalf("AnalyzeAccessibility failed: %v", err)
+	}
+	if !report.Passed || report.Figures != 1 || report.FormFields != 2 {
+		t.Errorf("Expected the fixes to clear all issues, got %+v", report)
+	}
+	if elem, _ := pdf.GetObject(figure); !strings.Contains(string(elem), "/Alt "+utf16PDFString("Sales by quarter, 2024")) {
+		t.Errorf("Unexpected figure %s", elem)
+	}
+
+	// Empty texts remove the entries again
+	m, err = NewPDFManipulator(out, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetAltText(map[int]string{figure: ""}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetTooltips(map[string]string{"group": ""}); err != nil {
+		t.Fatal(err)
+	}
+	if out, err = m.Rebuild(); err != nil {
+		t.Fatal(err)
+	}
+	if pdf, err = parse.Open(out); err != nil {
+		t.Fatal(err)
+	}
+	if report, err = extract.AnalyzeAccessibility(out, pdf, false); err != nil {
+		t.Fatal(err)
+	}
+	if report.IssueCounts[types.AccessibilityMissingAltText] != 1 || report.IssueCounts[types.AccessibilityMissingTooltip] != 1 {
+		t.Errorf("Expected the alt text and group tooltip removed, got %+v", report.Issues)
+	}
+}
@@ -0,0 +1,217 @@
+package manipulate
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/md5"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/benedoc-inc/pdfer/core/dates"
+)
+
+// Relationships of an attachment to the document, for /AFRelationship (ISO 32000-2, 14.13)
+const (
+	RelationshipSource      = "Source"      // Original content the document was created from
+	RelationshipData        = "Data"        // Data the document's visual content represents
+	RelationshipAlternative = "Alternative" // Alternative representation of the content
+	RelationshipSupplement  = "Supplement"  // Supplemental representation of the content
+	RelationshipUnspecified = "Unspecified"
+)
+
+// Attachment is a file to embed in a PDF
+type Attachment struct {
+	Name         string // File name shown by viewers
+	Data         []byte
+	MIMEType     string    // Media type, e.g. "application/json"
+	Description  string    // Optional description
+	Relationship string    // Relationship to the document; Unspecified when empty
+	ModDate      time.Time // Modification date; the current time when zero
+}
+
+// nameTreeEntryPattern matches a "(key) n g R" pair of a name tree's /Names array
+var nameTreeEntryPattern = regexp.MustCompile(`\(((?:\\.|[^\\)])*)\)\s*(\d+\s+\d+\s+R)`)
+
+// AddAttachment embeds a file in the document. The file is listed in the catalog's
+// EmbeddedFiles name tree, so viewers show it as an attachment, and in the
+// catalog's /AF array with its relationship to the document.
+func (m *PDFManipulator) AddAttachment(a Attachment) error {
+	if a.Name == "" {
+		return fmt.Errorf("attachment name is empty")
+	}
+	trailer := m.pdf.Trailer()
+	if trailer == nil || trailer.RootRef == "" {
+		return fmt.Errorf("no catalog found")
+	}
+	rootObjNum, err := parseObjectRef(trailer.RootRef)
+	if err != nil {
+		return err
+	}
+	catalogStr, ok := m.objects[rootObjNum]
+	if !ok {
+		return fmt.Errorf("catalog object %d not found", rootObjNum)
+	}
+
+	relationship := a.Relationship
+	if relationship == "" {
+		relationship = RelationshipUnspecified
+	}
+	modDate := a.ModDate
+	if modDate.IsZero() {
+		modDate = time.Now()
+	}
+
+	// Embedded file stream
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(a.Data)
+	zw.Close()
+
+	params := fmt.Sprintf("/Size %d /ModDate (%s) /CheckSum <%x>", len(a.Data), dates.Format(modDate.UTC()), md5.Sum(a.Data))
+	subtype := ""
+	if a.MIMEType != "" {
+		subtype = " /Subtype " + pdfName(a.MIMEType)
+	}
+
+	var stream bytes.Buffer
+	stream.WriteString(fmt.Sprintf("<< /Type /EmbeddedFile%s /Params << %s >> /Filter /FlateDecode /Length %d >>\nstream\n",
+		subtype, params, buf.Len()))
+	stream.Write(buf.Bytes())
+	stream.WriteString("\nendstream")
+	fileObjNum := m.nextObjectNumber()
+	m.objects[fileObjNum] = stream.Bytes()
+
+	// File specification
+	desc := ""
+	if a.Description != "" {
+		desc = fmt.Sprintf(" /Desc %s", utf16PDFString(a.Description))
+	}
+	specObjNum := m.nextObjectNumber()
+	m.objects[specObjNum] = []byte(fmt.Sprintf("<< /Type /Filespec /F (%s) /UF %s%s /AFRelationship /%s /EF << /F %d 0 R /UF %d 0 R >> >>",
+		encodeStampText(a.Name), utf16PDFString(a.Name), desc, relationship, fileObjNum, fileObjNum))
+	specRef := fmt.Sprintf("%d 0 R", specObjNum)
+
+	// Catalog /Names /EmbeddedFiles name tree
+	catalog := string(catalogStr)
+	names := rawDictValue(catalog, "/Names")
+	switch {
+	case names == "":
+		catalog = setRawDictValue(catalog, "/Names", fmt.Sprintf("<< /EmbeddedFiles << /Names [(%s) %s] >> >>", encodeStampText(a.Name), specRef))
+	case strings.HasPrefix(names, "<<"):
+		updated, err := m.addEmbeddedFileName(names, a.Name, specRef)
+		if err != nil {
+			return err
+		}
+		catalog = setRawDictValue(catalog, "/Names", updated)
+	default:
+		namesObjNum, err := parseObjectRef(names)
+		if err != nil {
+			return err
+		}
+		updated, err := m.addEmbeddedFileName(string(m.objects[namesObjNum]), a.Name, specRef)
+		if err != nil {
+			return err
+		}
+		m.objects[namesObjNum] = []byte(updated)
+	}
+
+	// Catalog /AF associated files
+	af := rawDictValue(catalog, "/AF")
+	switch {
+	case af == "":
+		catalog = setRawDictValue(catalog, "/AF", "["+specRef+"]")
+	case strings.HasPrefix(af, "["):
+		catalog = setRawDictValue(catalog, "/AF", strings.TrimSuffix(af, "]")+" "+specRef+"]")
+	default:
+		afObjNum, err := parseObjectRef(af)
+		if err != nil {
+			return err
+		}
+		afArray := strings.TrimSpace(string(m.objects[afObjNum]))
+		m.objects[afObjNum] = []byte(strings.TrimSuffix(afArray, "]") + " " + specRef + "]")
+	}
+	m.objects[rootObjNum] = []byte(catalog)
+
+	if m.verbose {
+		fmt.Printf("Attached %s (%d bytes, %s) as object %d\n", a.Name, len(a.Data), relationship, specObjNum)
+	}
+	return nil
+}
+
+// addEmbeddedFileName adds a file specification to the EmbeddedFiles name tree of
+// a document name dictionary
+func (m *PDFManipulator) addEmbeddedFileName(namesDict, name, specRef string) (string, error) {
+	tree := rawDictValue(namesDict, "/EmbeddedFiles")
+	if tree == "" {
+		return setRawDictValue(namesDict, "/EmbeddedFiles", fmt.Sprintf("<< /Names [(%s) %s] >>", encodeStampText(name), specRef)), nil
+	}
+	if strings.HasPrefix(tree, "<<") {
+		updated, err := insertNameTreeEntry(tree, name, specRef)
+		if err != nil {
+			return "", err
+		}
+		return setRawDictValue(namesDict, "/EmbeddedFiles", updated), nil
+	}
+
+	treeObjNum, err := parseObjectRef(tree)
+	if err != nil {
+		return "", err
+	}
+	updated, err := insertNameTreeEntry(string(m.objects[treeObjNum]), name, specRef)
+	if err != nil {
+		return "", err
+	}
+	m.objects[treeObjNum] = []byte(updated)
+	return namesDict, nil
+}
+
+// insertNameTreeEntry adds a key to the /Names array of a single-node name tree,
+// keeping the keys sorted
+func insertNameTreeEntry(treeDict, name, ref string) (string, error) {
+	if rawDictKeyIndex(treeDict, "/Kids") != -1 {
+		return "", fmt.Errorf("name trees with /Kids are not supported")
+	}
+	type entry struct{ key, ref string }
+	var entries []entry
+	for _, match := range nameTreeEntryPattern.FindAllStringSubmatch(rawDictValue(treeDict, "/Names"), -1) {
+		entries = append(entries, entry{match[1], match[2]})
+	}
+	entries = append(entries, entry{encodeStampText(name), ref})
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	parts := make([]string, len(entries))
+	for i, e := range entries {
+		parts[i] = fmt.Sprintf("(%s) %s", e.key, e.ref)
+	}
+	return setRawDictValue(treeDict, "/Names", "["+strings.Join(parts, " ")+"]"), nil
+}
+
+// pdfName formats s as a PDF name, escaping delimiters such as the "/" of a MIME type
+func pdfName(s string) string {
+	var b strings.Builder
+	b.WriteByte('/')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < '!' || c > '~' || strings.IndexByte("#()<>[]{}/%", c) != -1 {
+			b.WriteString(fmt.Sprintf("#%02X", c))
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// utf16PDFString formats s as a UTF-16BE hex string with a byte order mark
+func utf16PDFString(s string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, u := range utf16.Encode([]rune(s)) {
+		b.WriteString(fmt.Sprintf("%04X", u))
+	}
+	b.WriteByte('>')
+	return b.String()
+}
@@ -0,0 +1,101 @@
+package manipulate
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/core/write"
+)
+
+// embeddedFiles returns the decompressed content of every embedded file stream
+// of a PDF, and the content of its catalog
+func embeddedFiles(t *testing.T, pdfBytes []byte) ([][]byte, string) {
+	t.Helper()
+	pdf, err := parse.Open(pdfBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse PDF: %v", err)
+	}
+	rootObjNum, _ := parseObjectRef(pdf.Trailer().RootRef)
+	catalog, _ := pdf.GetObject(rootObjNum)
+
+	var files [][]byte
+	for _, objNum := range pdf.Objects() {
+		obj, err := pdf.GetObject(objNum)
+		if err != nil || !bytes.Contains(obj, []byte("/Type /EmbeddedFile")) {
+			continue
+		}
+		start := bytes.Index(obj, []byte("stream\n")) + len("stream\n")
+		end := bytes.LastIndex(obj, []byte("\nendstream"))
+		r, err := zlib.NewReader(bytes.NewReader(obj[start:end]))
+		if err != nil {
+			t.Fatalf("Embedded file %d is not compressed: %v", objNum, err)
+		}
+		data, _ := io.ReadAll(r)
+		files = append(files, data)
+	}
+	return files, string(catalog)
+}
+
+func TestAddAttachment(t *testing.T) {
+	builder := write.NewSimplePDFBuilder()
+	builder.FinalizePage(builder.AddPage(write.PageSizeLetter))
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create test PDF: %v", err)
+	}
+
+	m, err := NewPDFManipulator(pdfBytes, nil, false)
+	if err != nil {
+		t.Fatalf("Failed to create manipulator: %v", err)
+	}
+	if err := m.AddAttachment(Attachment{Name: "b.json", Data: []byte(`{"b":1}`), MIMEType: "application/json", Relationship: RelationshipData}); err != nil {
+		t.Fatalf("AddAttachment failed: %v", err)
+	}
+	if err := m.AddAttachment(Attachment{Name: "a.txt", Data: []byte("notes"), Description: "Notes"}); err != nil {
+		t.Fatalf("AddAttachment failed: %v", err)
+	}
+	if err := m.AddAttachment(Attachment{}); err == nil {
+		t.Error("Expected error for an attachment without a name")
+	}
+	out, err := m.Rebuild()
+	if err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+
+	files, catalog := embeddedFiles(t, out)
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 embedded files, got %d", len(files))
+	}
+	contents := string(files[0]) + "|" + string(files[1])
+	if !strings.Contains(contents, `{"b":1}`) || !strings.Contains(contents, "notes") {
+		t.Errorf("Unexpected embedded file contents %q", contents)
+	}
+
+	names := rawDictValue(rawDictValue(rawDictValue(catalog, "/Names"), "/EmbeddedFiles"), "/Names")
+	if a, b := strings.Index(names, "(a.txt)"), strings.Index(names, "(b.json)"); a == -1 || b == -1 || a > b {
+		t.Errorf("EmbeddedFiles names not sorted: %s", names)
+	}
+	if af := rawDictValue(catalog, "/AF"); len(refPattern.FindAllString(af, -1)) != 2 {
+		t.Errorf("Expected 2 associated files, got %s", af)
+	}
+	if !bytes.Contains(out, []byte("/AFRelationship /Data")) || !bytes.Contains(out, []byte("/Subtype /application#2Fjson")) {
+		t.Error("File specification or embedded file entries missing")
+	}
+}
+
+func TestInsertNameTreeEntry(t *testing.T) {
+	got, err := insertNameTreeEntry("<< /Names [(a) 1 0 R (c) 3 0 R] >>", "b", "2 0 R")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<< /Names [(a) 1 0 R (b) 2 0 R (c) 3 0 R] >>"; got != want {
+		t.Errorf("insertNameTreeEntry = %q, want %q", got, want)
+	}
+	if _, err := insertNameTreeEntry("<< /Kids [4 0 R] >>", "b", "2 0 R"); err == nil {
+		t.Error("Expected error for a name tree with kids")
+	}
+}
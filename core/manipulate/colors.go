@@ -0,0 +1,517 @@
+package manipulate
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// ColorTarget is the color space ConvertColors converts a document to
+type ColorTarget string
+
+const (
+	ColorTargetCMYK ColorTarget = "cmyk" // DeviceCMYK, for print; gray stays gray, as it prints on the black plate alone
+	ColorTargetGray ColorTarget = "gray" // DeviceGray, for grayscale archival copies
+)
+
+// ColorConversionOptions configures ConvertColors
+type ColorConversionOptions struct {
+	Target          ColorTarget // Color space to convert to
+	ICCProfile      []byte      // ICC profile of the output device, embedded as the document's output intent; its color space must match Target (optional)
+	OutputCondition string      // Output condition identifier of the output intent, e.g. "FOGRA39" (default: "Custom")
+	JPEGQuality     int         // Quality of JPEG images re-encoded in gray, 1-100 (default: 90)
+}
+
+// defaultColorJPEGQuality is the JPEG quality used when ColorConversionOptions sets none
+const defaultColorJPEGQuality = 90
+
+// inlineImageEndPattern finds the EI operator ending inline image data
+var inlineImageEndPattern = regexp.MustCompile(`\sEI(\s|$)`)
+
+// ConvertColors converts the page content and images of a document to a target
+// color space and writes it through Rewrite. Colors set with the device color
+// operators (rg, RG, k, K, and sc, scn, SC and SCN after cs or CS with a device
+// space) are converted in every content stream, and 8-bit DeviceRGB and
+// DeviceCMYK images, raw, Flate or JPEG, are converted pixel by pixel. Colors
+// are converted with the device formulas of ISO 32000-1 section 10.3; the ICC
+// profile, when given, is embedded as the output intent that tells printers
+// and PDF/X or PDF/A validators which device the colors are meant for, but its
+// tables are not evaluated.
+//
+// Colors in named color spaces (ICCBased, Indexed, Separation and others),
+// shadings, inline images and annotation colors are left unchanged.
+func ConvertColors(pdfBytes []byte, password []byte, opts ColorConversionOptions, verbose bool) ([]byte, error) {
+	components := 0
+	switch opts.Target {
+	case ColorTargetCMYK:
+		components = 4
+	case ColorTargetGray:
+		components = 1
+	default:
+		return nil, types.NewPDFError(types.ErrCodeInvalidInput, fmt.Sprintf("unsupported color target %q", opts.Target))
+	}
+	if len(opts.ICCProfile) > 0 {
+		if err := checkICCProfile(opts.ICCProfile, opts.Target); err != nil {
+			return nil, err
+		}
+	}
+	if opts.JPEGQuality <= 0 || opts.JPEGQuality > 100 {
+		opts.JPEGQuality = defaultColorJPEGQuality
+	}
+
+	// Content streams are the pages' contents and form XObjects, such as
+	// appearance streams; tiling patterns are drawn the same way
+	contents := make(map[int]bool)
+	converted := 0
+	hooks := rewriteHooks{
+		prepare: func(pdf *parse.PDF) error {
+			it := pdf.Pages()
+			for it.Next() {
+				for _, match := range refPattern.FindAllStringSubmatch(rawDictValue(it.Page().Dict, "/Contents"), -1) {
+					if objNum, err := strconv.Atoi(match[1]); err == nil {
+						contents[objNum] = true
+					}
+				}
+			}
+			return it.Err()
+		},
+		stream: func(objNum int, dict, data []byte) ([]byte, []byte) {
+			d := string(dict)
+			if contents[objNum] || formXObjectPattern.MatchString(d) || tilingPatternPattern.MatchString(d) {
+				plainDict, content, ok := decodedStream(dict, data)
+				if !ok {
+					if verbose {
+						fmt.Printf("Warning: content stream %d has filters that can't be decoded, colors left unchanged\n", objNum)
+					}
+					return dict, data
+				}
+				if out, changed := convertContentColors(content, opts.Target); changed {
+					converted++
+					return plainDict, out
+				}
+				return dict, data
+			}
+			if imageSubtypePattern.MatchString(d) {
+				newDict, newData, err := convertImageColors(dict, data, opts)
+				if err != nil {
+					if verbose {
+						fmt.Printf("Warning: image %d left unchanged: %v\n", objNum, err)
+					}
+					return dict, data
+				}
+				if newData != nil {
+					converted++
+					return newDict, newData
+				}
+			}
+			return dict, data
+		},
+	}
+	if len(opts.ICCProfile) > 0 {
+		hooks.catalog = func(catalog string, add func(obj []byte) int) string {
+			var profile bytes.Buffer
+			fmt.Fprintf(&profile, "<< /N %d /Length %d >>\nstream\n", components, len(opts.ICCProfile))
+			profile.Write(opts.ICCProfile)
+			profile.WriteString("\nendstream")
+			profileNum := add(profile.Bytes())
+
+			condition := opts.OutputCondition
+			if condition == "" {
+				condition = "Custom"
+			}
+			subtype := "/GTS_PDFX"
+			if opts.Target == ColorTargetGray {
+				subtype = "/GTS_PDFA1"
+			}
+			intent := fmt.Sprintf("[<< /Type /OutputIntent /S %s /OutputConditionIdentifier (%s) /Info (%s) /DestOutputProfile %d 0 R >>]",
+				subtype, encodeStampText(condition), encodeStampText(condition), profileNum)
+			return setTopLevelValue(catalog, "/OutputIntents", intent)
+		}
+	}
+
+	out, err := rewrite(pdfBytes, RewriteOptions{Password: password, Verbose: verbose}, hooks)
+	if err != nil {
+		return nil, err
+	}
+	if verbose {
+		fmt.Printf("Converted colors of %d streams to %s\n", converted, opts.Target)
+	}
+	return out, nil
+}
+
+var (
+	formXObjectPattern   = regexp.MustCompile(`/Subtype\s*/Form\b`)
+	tilingPatternPattern = regexp.MustCompile(`/PatternType\s+1\b`)
+)
+
+// checkICCProfile checks that data is an ICC profile whose color space matches
+// the target
+func checkICCProfile(data []byte, target ColorTarget) error {
+	if len(data) < 128 || string(data[36:40]) != "acsp" {
+		return types.NewPDFError(types.ErrCodeInvalidInput, "ICC profile has no valid header")
+	}
+	space := strings.TrimSpace(string(data[16:20]))
+	if (target == ColorTargetCMYK && space != "CMYK") || (target == ColorTargetGray && space != "GRAY") {
+		return types.NewPDFError(types.ErrCodeInvalidInput, fmt.Sprintf("ICC profile color space %s does not match target %s", space, target))
+	}
+	return nil
+}
+
+// decodedStream returns the unfiltered dictionary and data of a stream that is
+// unfiltered or Flate-encoded without predictors
+func decodedStream(dict, data []byte) ([]byte, []byte, bool) {
+	d := string(dict)
+	filter := topLevelValue(d, "/Filter")
+	if filter == "" {
+		return dict, data, true
+	}
+	if !isSingleFilter(filter, "/FlateDecode") || topLevelKeyIndex(d, "/DecodeParms") != -1 {
+		return nil, nil, false
+	}
+	decoded, err := parse.DecodeFlateDecode(data)
+	if err != nil {
+		return nil, nil, false
+	}
+	return []byte(removeTopLevelKey(d, "/Filter")), decoded, true
+}
+
+// Device color space names
+const (
+	deviceGray = "/DeviceGray"
+	deviceRGB  = "/DeviceRGB"
+	deviceCMYK = "/DeviceCMYK"
+)
+
+// targetSpace returns the device color space name of a target
+func targetSpace(target ColorTarget) string {
+	if target == ColorTargetGray {
+		return deviceGray
+	}
+	return deviceCMYK
+}
+
+// convertsSpace reports whether colors in a device space are converted for a target
+func convertsSpace(space string, target ColorTarget) bool {
+	return space == deviceRGB || (space == deviceCMYK && target == ColorTargetGray)
+}
+
+// convertColor converts the components of a color in a device space to the
+// target's space
+func convertColor(space string, values []float64, target ColorTarget) []float64 {
+	var r, g, b float64
+	switch space {
+	case deviceRGB:
+		r, g, b = values[0], values[1], values[2]
+		if target == ColorTargetCMYK {
+			c, m, y, k := rgbToCMYK(r, g, b)
+			return []float64{c, m, y, k}
+		}
+		return []float64{0.3*r + 0.59*g + 0.11*b}
+	case deviceCMYK:
+		return []float64{1 - math.Min(1, 0.3*values[0]+0.59*values[1]+0.11*values[2]+values[3])}
+	}
+	return values
+}
+
+// rgbToCMYK converts an RGB color with black generation and full undercolor removal
+func rgbToCMYK(r, g, b float64) (c, m, y, k float64) {
+	k = 1 - math.Max(r, math.Max(g, b))
+	if k >= 1 {
+		return 0, 0, 0, 1
+	}
+	return (1 - r - k) / (1 - k), (1 - g - k) / (1 - k), (1 - b - k) / (1 - k), k
+}
+
+// contentToken is a token of a content stream
+type contentToken struct {
+	start, end int
+	operator   bool // An operator rather than an operand
+}
+
+// colorState is the color space of the fill and stroke colors, as set by the
+// original content
+type colorState struct {
+	fill, stroke string
+}
+
+// convertContentColors rewrites the device color operators of a content stream
+// for a target, leaving everything else as it is
+func convertContentColors(content []byte, target ColorTarget) ([]byte, bool) {
+	var out bytes.Buffer
+	copied := 0
+	state := colorState{fill: deviceGray, stroke: deviceGray}
+	var stack []colorState
+	var operands []contentToken
+
+	// replace swaps the operands and operator for a new operation
+	replace := func(op contentToken, values []float64, operator string) {
+		start := op.start
+		if len(operands) > 0 {
+			start = operands[0].start
+		}
+		out.Write(content[copied:start])
+		if len(values) > 0 {
+			out.WriteString(formatNumbers(values))
+			out.WriteString(" ")
+		}
+		out.WriteString(operator)
+		copied = op.end
+	}
+	numbers := func(n int) []float64 {
+		if len(operands) != n {
+			return nil
+		}
+		values := make([]float64, n)
+		for i, tok := range operands {
+			v, err := strconv.ParseFloat(string(content[tok.start:tok.end]), 64)
+			if err != nil {
+				return nil
+			}
+			values[i] = v
+		}
+		return values
+	}
+
+	for pos := 0; ; {
+		tok, ok := nextContentToken(content, pos)
+		if !ok {
+			break
+		}
+		pos = tok.end
+		if !tok.operator {
+			operands = append(operands, tok)
+			continue
+		}
+
+		op := string(content[tok.start:tok.end])
+		stroke := op == strings.ToUpper(op) && op != "q" && op != "Q"
+		space := &state.fill
+		if stroke {
+			space = &state.stroke
+		}
+		switch op {
+		case "q":
+			stack = append(stack, state)
+		case "Q":
+			if len(stack) > 0 {
+				state = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case "g", "G":
+			*space = deviceGray
+		case "rg", "RG", "k", "K":
+			*space = deviceRGB
+			n := 3
+			if op == "k" || op == "K" {
+				*space = deviceCMYK
+				n = 4
+			}
+			if values := numbers(n); values != nil && convertsSpace(*space, target) {
+				operator := map[ColorTarget]string{ColorTargetCMYK: "k", ColorTargetGray: "g"}[target]
+				if stroke {
+					operator = strings.ToUpper(operator)
+				}
+				replace(tok, convertColor(*space, values, target), operator)
+			}
+		case "cs", "CS":
+			if len(operands) == 1 {
+				*space = string(content[operands[0].start:operands[0].end])
+				if convertsSpace(*space, target) {
+					out.Write(content[copied:operands[0].start])
+					out.WriteString(targetSpace(target))
+					copied = operands[0].end
+				}
+			}
+		case "sc", "scn", "SC", "SCN":
+			n := 3
+			if *space == deviceCMYK {
+				n = 4
+			}
+			if values := numbers(n); values != nil && convertsSpace(*space, target) {
+				replace(tok, convertColor(*space, values, target), op)
+			}
+		case "BI":
+			// Inline image data runs from ID to EI and isn't tokenized
+			if id := bytes.Index(content[pos:], []byte("ID")); id != -1 {
+				if loc := inlineImageEndPattern.FindIndex(content[pos+id+2:]); loc != nil {
+					pos += id + 2 + loc[0] + 3
+				} else {
+					pos = len(content)
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+
+	if copied == 0 {
+		return content, false
+	}
+	out.Write(content[copied:])
+	return out.Bytes(), true
+}
+
+// nextContentToken returns the next token of a content stream at or after pos,
+// skipping whitespace and comments. Strings, names, numbers, arrays and
+// dictionaries are operands.
+func nextContentToken(content []byte, pos int) (contentToken, bool) {
+	const delimiters = " \t\r\n\f\x00()<>[]{}/%"
+	for pos < len(content) {
+		c := content[pos]
+		switch {
+		case strings.IndexByte(" \t\r\n\f\x00", c) != -1:
+			pos++
+		case c == '%':
+			for pos < len(content) && content[pos] != '\n' && content[pos] != '\r' {
+				pos++
+			}
+		case c == '(':
+			end, depth := pos, 0
+			for ; end < len(content); end++ {
+				if content[end] == '\\' {
+					end++
+				} else if content[end] == '(' {
+					depth++
+				} else if content[end] == ')' {
+					if depth--; depth == 0 {
+						break
+					}
+				}
+			}
+			return contentToken{start: pos, end: min(end+1, len(content))}, true
+		case c == '<' && pos+1 < len(content) && content[pos+1] == '<',
+			c == '>' && pos+1 < len(content) && content[pos+1] == '>':
+			return contentToken{start: pos, end: pos + 2}, true
+		case c == '<':
+			end := bytes.IndexByte(content[pos:], '>')
+			if end == -1 {
+				return contentToken{start: pos, end: len(content)}, true
+			}
+			return contentToken{start: pos, end: pos + end + 1}, true
+		case strings.IndexByte("[]{}", c) != -1:
+			return contentToken{start: pos, end: pos + 1}, true
+		default:
+			end := pos + 1
+			for end < len(content) && strings.IndexByte(delimiters, content[end]) == -1 {
+				end++
+			}
+			token := content[pos:end]
+			operator := c != '/' && !isNumberToken(token) && string(token) != "true" && string(token) != "false" && string(token) != "null"
+			return contentToken{start: pos, end: end, operator: operator}, true
+		}
+	}
+	return contentToken{}, false
+}
+
+// isNumberToken reports whether a token is a PDF number
+func isNumberToken(token []byte) bool {
+	_, err := strconv.ParseFloat(string(token), 64)
+	return err == nil
+}
+
+// convertImageColors converts an 8-bit DeviceRGB or DeviceCMYK image. It
+// returns nil data when the image is already in a color space the target
+// keeps, and an error when it can't be converted.
+func convertImageColors(dict, data []byte, opts ColorConversionOptions) ([]byte, []byte, error) {
+	d := string(dict)
+	space := topLevelValue(d, "/ColorSpace")
+	if !convertsSpace(space, opts.Target) || topLevelValue(d, "/ImageMask") == "true" {
+		return dict, nil, nil
+	}
+	if topLevelKeyIndex(d, "/Decode") != -1 {
+		return nil, nil, fmt.Errorf("images with a /Decode array are not supported")
+	}
+	if bpc := topLevelValue(d, "/BitsPerComponent"); bpc != "8" {
+		return nil, nil, fmt.Errorf("%s bits per component are not supported", bpc)
+	}
+	width, _ := strconv.Atoi(topLevelValue(d, "/Width"))
+	height, _ := strconv.Atoi(topLevelValue(d, "/Height"))
+	if width <= 0 || height <= 0 {
+		return nil, nil, fmt.Errorf("invalid image size %dx%d", width, height)
+	}
+
+	// Samples of the source image, in its own color space
+	var pixel func(x, y int) []float64
+	if isSingleFilter(topLevelValue(d, "/Filter"), "/DCTDecode") {
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decode JPEG: %w", err)
+		}
+		bounds := img.Bounds()
+		if bounds.Dx() != width || bounds.Dy() != height {
+			return nil, nil, fmt.Errorf("JPEG size %dx%d doesn't match the image dictionary", bounds.Dx(), bounds.Dy())
+		}
+		pixel = func(x, y int) []float64 {
+			col := img.At(bounds.Min.X+x, bounds.Min.Y+y)
+			if cmyk, ok := col.(color.CMYK); ok && space == deviceCMYK {
+				return []float64{float64(cmyk.C) / 255, float64(cmyk.M) / 255, float64(cmyk.Y) / 255, float64(cmyk.K) / 255}
+			}
+			rgb := color.RGBAModel.Convert(col).(color.RGBA)
+			r, g, b := float64(rgb.R)/255, float64(rgb.G)/255, float64(rgb.B)/255
+			if space == deviceCMYK {
+				c, m, y, k := rgbToCMYK(r, g, b)
+				return []float64{c, m, y, k}
+			}
+			return []float64{r, g, b}
+		}
+		d = removeTopLevelKey(d, "/Filter")
+	} else {
+		plainDict, samples, ok := decodedStream(dict, data)
+		if !ok {
+			return nil, nil, fmt.Errorf("unsupported filter %s", topLevelValue(d, "/Filter"))
+		}
+		n := 3
+		if space == deviceCMYK {
+			n = 4
+		}
+		if len(samples) < width*height*n {
+			return nil, nil, fmt.Errorf("image data is %d bytes, expected %d", len(samples), width*height*n)
+		}
+		pixel = func(x, y int) []float64 {
+			i := (y*width + x) * n
+			values := make([]float64, n)
+			for c := range values {
+				values[c] = float64(samples[i+c]) / 255
+			}
+			return values
+		}
+		d = string(plainDict)
+	}
+
+	// Gray JPEG images are re-encoded as JPEG; others are stored for recompression
+	if opts.Target == ColorTargetGray {
+		gray := image.NewGray(image.Rect(0, 0, width, height))
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				gray.Pix[y*gray.Stride+x] = uint8(math.Round(convertColor(space, pixel(x, y), opts.Target)[0] * 255))
+			}
+		}
+		d = setTopLevelValue(d, "/ColorSpace", deviceGray)
+		if isSingleFilter(topLevelValue(string(dict), "/Filter"), "/DCTDecode") {
+			var buf bytes.Buffer
+			if err := jpeg.Encode(&buf, gray, &jpeg.Options{Quality: opts.JPEGQuality}); err != nil {
+				return nil, nil, fmt.Errorf("failed to encode JPEG: %w", err)
+			}
+			return []byte(setTopLevelValue(d, "/Filter", "/DCTDecode")), buf.Bytes(), nil
+		}
+		return []byte(d), gray.Pix, nil
+	}
+
+	samples := make([]byte, 0, width*height*4)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			for _, v := range convertColor(space, pixel(x, y), opts.Target) {
+				samples = append(samples, uint8(math.Round(v*255)))
+			}
+		}
+	}
+	return []byte(setTopLevelValue(d, "/ColorSpace", deviceCMYK)), samples, nil
+}
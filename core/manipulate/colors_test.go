@@ -0,0 +1,182 @@
+package manipulate
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/core/write"
+)
+
+func TestConvertContentColors(t *testing.T) {
+	content := "q 1 0 0 rg 0 0 1 RG (red 1 0 0 rg) Tj % 1 0 0 rg\n" +
+		"/DeviceRGB cs 0 1 0 sc /P1 scn Q 0 0 0 1 k 0.5 g " +
+		"BI /W 1 /H 1 /CS /RGB /BPC 8 ID \x01\x02\x03 EI 1 1 1 rg"
+
+	tests := []struct {
+		target ColorTarget
+		want   string
+	}{
+		{ColorTargetCMYK, "q 0 1 1 0 k 1 1 0 0 K (red 1 0 0 rg) Tj % 1 0 0 rg\n" +
+			"/DeviceCMYK cs 1 0 1 0 sc /P1 scn Q 0 0 0 1 k 0.5 g " +
+			"BI /W 1 /H 1 /CS /RGB /BPC 8 ID \x01\x02\x03 EI 0 0 0 0 k"},
+		{ColorTargetGray, "q 0.3 g 0.11 G (red 1 0 0 rg) Tj % 1 0 0 rg\n" +
+			"/DeviceGray cs 0.59 sc /P1 scn Q 0 g 0.5 g " +
+			"BI /W 1 /H 1 /CS /RGB /BPC 8 ID \x01\x02\x03 EI 1 g"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.target), func(t *testing.T) {
+			out, changed := convertContentColors([]byte(content), tt.target)
+			if !changed {
+				t.Fatal("Expected content to change")
+			}
+			if string(out) != tt.want {
+				t.Errorf("Got\n%q\nwant\n%q", out, tt.want)
+			}
+		})
+	}
+
+	if _, changed := convertContentColors([]byte("0.5 g 0 0 m 10 10 l S"), ColorTargetGray); changed {
+		t.Error("Gray content changed")
+	}
+}
+
+func TestConvertImageColors_Raw(t *testing.T) {
+	dict := []byte("<< /Type /XObject /Subtype /Image /Width 2 /Height 1 /ColorSpace /DeviceRGB /BitsPerComponent 8 >>")
+	data := []byte{255, 0, 0, 255, 255, 255}
+
+	newDict, out, err := convertImageColors(dict, data, ColorConversionOptions{Target: ColorTargetCMYK})
+	if err != nil {
+		t.Fatalf("convertImageColors failed: %v", err)
+	}
+	if !strings.Contains(string(newDict), "/ColorSpace /DeviceCMYK") {
+		t.Errorf("Color space not changed: %s", newDict)
+	}
+	if want := []byte{0, 255, 255, 0, 0, 0, 0, 0}; !bytes.Equal(out, want) {
+		t.Errorf("Got samples %v, want %v", out, want)
+	}
+
+	// Gray images are kept
+	gray := []byte("<< /Subtype /Image /Width 2 /Height 1 /ColorSpace /DeviceGray /BitsPerComponent 8 >>")
+	if _, out, err := convertImageColors(gray, []byte{0, 255}, ColorConversionOptions{Target: ColorTargetCMYK}); err != nil || out != nil {
+		t.Errorf("Gray image converted: %v, %v", out, err)
+	}
+
+	// Truncated data is reported
+	if _, _, err := convertImageColors(dict, data[:3], ColorConversionOptions{Target: ColorTargetGray}); err == nil {
+		t.Error("Expected an error for truncated image data")
+	}
+}
+
+// testICCProfile returns a minimal ICC profile header for a color space
+func testICCProfile(space string) []byte {
+	profile := make([]byte, 128)
+	copy(profile[12:], "prtr")
+	copy(profile[16:], space)
+	copy(profile[36:], "acsp")
+	return profile
+}
+
+func TestConvertColors(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	for y := 0; y < 8; y++ {
+		img.Set(0, y, color.RGBA{R: 255, A: 255})
+	}
+	var jpegData bytes.Buffer
+	if err := jpeg.Encode(&jpegData, img, nil); err != nil {
+		t.Fatalf("Failed to encode JPEG: %v", err)
+	}
+
+	writer := write.NewPDFWriter()
+	imageNum := writer.AddObject([]byte(fmt.Sprintf("<</Type/XObject/Subtype/Image/Width 8/Height 8/ColorSpace/DeviceRGB/BitsPerComponent 8/Filter/DCTDecode/Length %d>>\nstream\n%s\nendstream", jpegData.Len(), jpegData.Bytes())))
+	content := "q 1 0 0 rg 0 0 100 100 re f 72 0 0 72 100 100 cm /Im1 Do Q"
+	contentNum := writer.AddObject([]byte(fmt.Sprintf("<</Length %d>>\nstream\n%s\nendstream", len(content), content)))
+	pagesNum := writer.AddObject(nil)
+	pageNum := writer.AddObject([]byte(fmt.Sprintf("<</Type/Page/Parent %d 0 R/MediaBox [0 0 612 792]/Contents %d 0 R/Resources <</XObject <</Im1 %d 0 R>>>>>>", pagesNum, contentNum, imageNum)))
+	writer.SetObject(pagesNum, []byte(fmt.Sprintf("<</Type/Pages/Kids [%d 0 R]/Count 1>>", pageNum)))
+	writer.SetRoot(writer.AddObject([]byte(fmt.Sprintf("<</Type/Catalog/Pages %d 0 R>>", pagesNum))))
+	input, err := writer.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create test PDF: %v", err)
+	}
+
+	t.Run("gray", func(t *testing.T) {
+		out, err := ConvertColors(input, nil, ColorConversionOptions{Target: ColorTargetGray, ICCProfile: testICCProfile("GRAY")}, false)
+		if err != nil {
+			t.Fatalf("ConvertColors failed: %v", err)
+		}
+		pdf, err := parse.Open(out)
+		if err != nil {
+			t.Fatalf("Failed to open output: %v", err)
+		}
+		var sawContent, sawImage, sawIntent bool
+		for _, objNum := range pdf.Objects() {
+			obj, err := pdf.GetObject(objNum)
+			if err != nil {
+				continue
+			}
+			dict, data, isStream := splitStreamObject(objectBody(obj))
+			d := string(dict)
+			switch {
+			case strings.Contains(d, "/OutputIntents"):
+				sawIntent = strings.Contains(d, "/GTS_PDFA1") && strings.Contains(d, "/DestOutputProfile")
+			case isStream && imageSubtypePattern.MatchString(d):
+				sawImage = true
+				if !strings.Contains(d, "/DeviceGray") || !strings.Contains(d, "/DCTDecode") {
+					t.Errorf("Image not converted to a gray JPEG: %s", d)
+				}
+				decoded, err := jpeg.Decode(bytes.NewReader(data))
+				if err != nil {
+					t.Fatalf("Converted JPEG doesn't decode: %v", err)
+				}
+				if _, ok := decoded.(*image.Gray); !ok {
+					t.Errorf("Converted JPEG is %T, want *image.Gray", decoded)
+				}
+			case isStream && strings.Contains(d, "/FlateDecode"):
+				decoded, err := parse.DecodeFlateDecode(data)
+				if err == nil && strings.Contains(string(decoded), " re f") {
+					sawContent = true
+					if !strings.Contains(string(decoded), "0.3 g") {
+						t.Errorf("Content not converted: %s", decoded)
+					}
+				}
+			}
+		}
+		if !sawContent || !sawImage || !sawIntent {
+			t.Errorf("Missing content %v, image %v or output intent %v", sawContent, sawImage, sawIntent)
+		}
+	})
+
+	t.Run("cmyk", func(t *testing.T) {
+		out, err := ConvertColors(input, nil, ColorConversionOptions{Target: ColorTargetCMYK}, false)
+		if err != nil {
+			t.Fatalf("ConvertColors failed: %v", err)
+		}
+		if strings.Contains(string(out), "/DeviceRGB") {
+			t.Error("DeviceRGB image kept")
+		}
+		if strings.Contains(string(out), "/OutputIntents") {
+			t.Error("Output intent added without a profile")
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		if _, err := ConvertColors(input, nil, ColorConversionOptions{Target: "lab"}, false); err == nil {
+			t.Error("Expected an error for an unsupported target")
+		}
+		if _, err := ConvertColors(input, nil, ColorConversionOptions{Target: ColorTargetCMYK, ICCProfile: testICCProfile("RGB ")}, false); err == nil {
+			t.Error("Expected an error for an RGB profile with a CMYK target")
+		}
+		if _, err := ConvertColors(input, nil, ColorConversionOptions{Target: ColorTargetGray, ICCProfile: []byte("short")}, false); err == nil {
+			t.Error("Expected an error for an invalid profile")
+		}
+	})
+}
@@ -0,0 +1,673 @@
+package manipulate
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/benedoc-inc/pdfer/core/encrypt"
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// maxOutlineDepth bounds the nesting of outline items and name tree nodes read
+// from a merged document
+const maxOutlineDepth = 64
+
+// MergeOptions controls Merge
+type MergeOptions struct {
+	Passwords [][]byte // Password of each input, for encrypted inputs
+	Titles    []string // Bookmark title of each input; inputs without one get no bookmark
+	Verbose   bool
+}
+
+// Merge concatenates the pages of inputs into one document with a
+// DocumentMerger: objects are renumbered, identical fonts, images and other
+// resources are written once, and the inputs' outlines are rebuilt, under a
+// bookmark for each input given a title.
+//
+// Merge is here rather than in core/write because it reads and decrypts its
+// inputs like the other functions of this package that rework existing
+// documents (ExtractPages, SplitPDF, SetSecurity); core/write builds the
+// output and is used by this package, so it can't use DocumentMerger.
+func Merge(inputs [][]byte, opts MergeOptions) ([]byte, error) {
+	dm := NewDocumentMerger(opts.Verbose)
+	for i, input := range inputs {
+		var password []byte
+		if i < len(opts.Passwords) {
+			password = opts.Passwords[i]
+		}
+		title := ""
+		if i < len(opts.Titles) {
+			title = opts.Titles[i]
+		}
+		if err := dm.Add(input, password, title); err != nil {
+			return nil, fmt.Errorf("input %d: %w", i+1, err)
+		}
+	}
+	return dm.Bytes()
+}
+
+// DocumentMerger combines documents into one, writing the objects that are the
+// same in several of them once. It suits mail merge, where every document is a
+// copy of one template filled or laid out for a record: fonts, images, form
+// XObjects and page content that do not change between copies are shared, and
+// only what differs is written for each copy.
+//
+// Each document's pages are kept with their resources and annotations, and a
+// bookmark to its first page is added when it has a title. The document's own
+// outline is rebuilt under that bookmark, or at the top level without one, with
+// named destinations resolved to the copied pages. Its form fields are kept
+// under a parent field named "docN", for the Nth document, so the same field in
+// different copies keeps its own value; the form's default resources are those
+// of the first document with a form. Other document-level objects, such as
+// named destinations, XFA and document JavaScript, are left out.
+type DocumentMerger struct {
+	writer          *write.PDFWriter
+	verbose         bool
+	shared          map[[sha256.Size]byte]int // Objects written once, by content
+	pages           []int
+	bookmarks       []types.Bookmark
+	fieldGroups     []int
+	formDefaults    string // /DR and /DA entries of the merged AcroForm
+	needAppearances bool
+	documents       int
+	reused          int
+}
+
+// NewDocumentMerger starts an empty merged document
+func NewDocumentMerger(verbose bool) *DocumentMerger {
+	return &DocumentMerger{
+		writer:  write.NewPDFWriter(),
+		verbose: verbose,
+		shared:  make(map[[sha256.Size]byte]int),
+	}
+}
+
+// Documents returns the number of documents added
+func (dm *DocumentMerger) Documents() int {
+	return dm.documents
+}
+
+// Pages returns the number of pages added
+func (dm *DocumentMerger) Pages() int {
+	return len(dm.pages)
+}
+
+// SharedObjects returns the number of times an object was shared rather than
+// written again
+func (dm *DocumentMerger) SharedObjects() int {
+	return dm.reused
+}
+
+// mergeSource is an object of a document being added
+type mergeSource struct {
+	dict   []byte // The whole object unless it is a stream
+	data   []byte
+	stream bool
+	refs   []int
+}
+
+// documentCopy holds the state of one Add
+type documentCopy struct {
+	pdf     *parse.PDF
+	objects map[int]*mergeSource
+	unique  map[int]bool // Objects written for this document alone
+	order   []int        // Objects in depth-first post-order
+	state   map[int]int  // 1 while on the depth-first stack, 2 when done
+	stack   []int
+	hashes  map[int][sha256.Size]byte
+	numbers map[int]int // Object numbers in the merged document
+}
+
+// Add appends the pages of a document, decrypted with password if it is
+// encrypted, with a bookmark titled title unless it is empty
+func (dm *DocumentMerger) Add(pdfBytes, password []byte, title string) error {
+	pdf, err := parse.OpenWithOptions(pdfBytes, parse.ParseOptions{Password: password, Verbose: dm.verbose})
+	if err != nil {
+		return fmt.Errorf("failed to parse PDF: %w", err)
+	}
+	c := &documentCopy{
+		pdf:     pdf,
+		objects: make(map[int]*mergeSource),
+		unique:  make(map[int]bool),
+		state:   make(map[int]int),
+		hashes:  make(map[int][sha256.Size]byte),
+		numbers: make(map[int]int),
+	}
+
+	// Pages are written for each document, with their inherited attributes and
+	// without the link to their old parent
+	var pageObjNums []int
+	pages := pdf.Pages()
+	for pages.Next() {
+		page := pages.Page()
+		dict := string(objectBody([]byte(page.Dict)))
+		dict = removeRawDictKey(dict, "/Parent")
+		if page.Resources != "" && rawDictValue(dict, "/Resources") == "" {
+			dict = setRawDictValue(dict, "/Resources", page.Resources)
+		}
+		if len(page.MediaBox) == 4 && rawDictValue(dict, "/MediaBox") == "" {
+			dict = setRawDictValue(dict, "/MediaBox", formatNumberArray(page.MediaBox))
+		}
+		if len(page.CropBox) == 4 && rawDictValue(dict, "/CropBox") == "" {
+			dict = setRawDictValue(dict, "/CropBox", formatNumberArray(page.CropBox))
+		}
+		if page.Rotate != 0 && rawDictValue(dict, "/Rotate") == "" {
+			dict = setRawDictValue(dict, "/Rotate", strconv.Itoa(page.Rotate))
+		}
+		c.objects[page.ObjectNumber] = &mergeSource{dict: []byte(dict), refs: objectRefs(dict)}
+		c.unique[page.ObjectNumber] = true
+		pageObjNums = append(pageObjNums, page.ObjectNumber)
+	}
+	if err := pages.Err(); err != nil {
+		return fmt.Errorf("failed to read pages: %w", err)
+	}
+	if len(pageObjNums) == 0 {
+		return fmt.Errorf("document has no pages")
+	}
+
+	// Annotations and form fields belong to one page or form, so they are never
+	// shared either
+	for _, pageObjNum := range pageObjNums {
+		for _, annotObjNum := range c.arrayRefs(rawDictValue(string(c.objects[pageObjNum].dict), "/Annots")) {
+			c.unique[annotObjNum] = true
+		}
+	}
+	acroForm := c.acroForm()
+	fields := c.arrayRefs(rawDictValue(acroForm, "/Fields"))
+	for objNum := range c.fieldTree(fields) {
+		c.unique[objNum] = true
+	}
+
+	for _, pageObjNum := range pageObjNums {
+		c.visit(pageObjNum)
+	}
+	for _, objNum := range fields {
+		c.visit(objNum)
+	}
+	defaults := ""
+	if dm.formDefaults == "" {
+		for _, key := range []string{"/DR", "/DA"} {
+			if value := rawDictValue(acroForm, key); value != "" {
+				defaults += " " + key + " " + value
+				for _, objNum := range objectRefs(value) {
+					c.visit(objNum)
+				}
+			}
+		}
+	}
+	c.propagateUnique()
+	dm.write(c)
+
+	// The document's top-level fields go under a parent of their own
+	dm.documents++
+	if len(fields) > 0 {
+		groupObjNum := dm.writer.AddObject(nil)
+		var kids []string
+		for _, objNum := range fields {
+			newObjNum, ok := c.numbers[objNum]
+			if !ok {
+				continue
+			}
+			kids = append(kids, fmt.Sprintf("%d 0 R", newObjNum))
+			field := string(objectBody(dm.objectContent(newObjNum)))
+			dm.writer.SetObject(newObjNum, []byte(setRawDictValue(field, "/Parent", fmt.Sprintf("%d 0 R", groupObjNum))))
+		}
+		dm.writer.SetObject(groupObjNum, []byte(fmt.Sprintf("<< /T (doc%d) /Kids [%s] >>", dm.documents, strings.Join(kids, " "))))
+		dm.fieldGroups = append(dm.fieldGroups, groupObjNum)
+		if defaults != "" {
+			dm.formDefaults = c.remap(defaults)
+		}
+		if rawDictValue(acroForm, "/NeedAppearances") == "true" {
+			dm.needAppearances = true
+		}
+	}
+
+	outline := c.outline()
+	if title != "" {
+		dm.bookmarks = append(dm.bookmarks, types.Bookmark{Title: title, PageNumber: len(dm.pages) + 1, Children: outline})
+	} else {
+		dm.bookmarks = append(dm.bookmarks, outline...)
+	}
+	for _, pageObjNum := range pageObjNums {
+		dm.pages = append(dm.pages, c.numbers[pageObjNum])
+	}
+	if dm.verbose {
+		fmt.Printf("Added document %d: %d pages, %d objects written, %d shared so far\n",
+			dm.documents, len(pageObjNums), len(c.numbers), dm.reused)
+	}
+	return nil
+}
+
+// objectContent returns the content of an object already written
+func (dm *DocumentMerger) objectContent(objNum int) []byte {
+	content, err := dm.writer.FormatObject(objNum)
+	if err != nil {
+		return nil
+	}
+	return content
+}
+
+// write numbers the objects of a document in the merged document, sharing
+// those already written with the same content, and writes the others
+func (dm *DocumentMerger) write(c *documentCopy) {
+	var fresh []int
+	for _, objNum := range c.order {
+		if !c.unique[objNum] {
+			if shared, ok := dm.shared[c.hashes[objNum]]; ok {
+				c.numbers[objNum] = shared
+				dm.reused++
+				continue
+			}
+		}
+		newObjNum := dm.writer.AddObject(nil)
+		c.numbers[objNum] = newObjNum
+		if !c.unique[objNum] {
+			dm.shared[c.hashes[objNum]] = newObjNum
+		}
+		fresh = append(fresh, objNum)
+	}
+	for _, objNum := range fresh {
+		src := c.objects[objNum]
+		dict := []byte(c.remap(string(src.dict)))
+		dm.writer.SetObject(c.numbers[objNum], joinStreamObject(dict, src.data, src.stream))
+	}
+}
+
+// Bytes writes the merged document
+func (dm *DocumentMerger) Bytes() ([]byte, error) {
+	if len(dm.pages) == 0 {
+		return nil, fmt.Errorf("no documents to merge")
+	}
+	catalogObjNum := dm.writer.AddObject(nil)
+	pagesObjNum := dm.writer.AddObject(nil)
+
+	kids := make([]string, len(dm.pages))
+	pageNumbers := make(map[int]int, len(dm.pages))
+	for i, pageObjNum := range dm.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", pageObjNum)
+		pageNumbers[i+1] = pageObjNum
+		page := string(objectBody(dm.objectContent(pageObjNum)))
+		dm.writer.SetObject(pageObjNum, []byte(setRawDictValue(page, "/Parent", fmt.Sprintf("%d 0 R", pagesObjNum))))
+	}
+	dm.writer.SetObject(pagesObjNum, []byte(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids))))
+
+	catalog := fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R", pagesObjNum)
+	if len(dm.fieldGroups) > 0 {
+		groups := make([]string, len(dm.fieldGroups))
+		for i, objNum := range dm.fieldGroups {
+			groups[i] = fmt.Sprintf("%d 0 R", objNum)
+		}
+		acroForm := fmt.Sprintf("<< /Fields [%s]%s", strings.Join(groups, " "), dm.formDefaults)
+		if dm.needAppearances {
+			acroForm += " /NeedAppearances true"
+		}
+		catalog += fmt.Sprintf(" /AcroForm %d 0 R", dm.writer.AddObject([]byte(acroForm+" >>")))
+	}
+	outlinesObjNum, err := dm.writer.SetBookmarks(dm.bookmarks, pageNumbers)
+	if err != nil {
+		return nil, err
+	}
+	if outlinesObjNum != 0 {
+		catalog += fmt.Sprintf(" /Outlines %d 0 R /PageMode /UseOutlines", outlinesObjNum)
+	}
+	dm.writer.SetObject(catalogObjNum, []byte(catalog+" >>"))
+	dm.writer.SetRoot(catalogObjNum)
+	return dm.writer.Bytes()
+}
+
+// deref returns the object a value references, or the value itself when it is
+// not an indirect reference
+func (c *documentCopy) deref(value string) string {
+	value = strings.TrimSpace(value)
+	if !refPattern.MatchString(value) || refPattern.FindString(value) != value {
+		return value
+	}
+	objNum, err := parseObjectRef(value)
+	if err != nil {
+		return ""
+	}
+	obj, err := c.pdf.GetObject(objNum)
+	if err != nil {
+		return ""
+	}
+	return string(objectBody(obj))
+}
+
+// catalog returns the document's catalog dictionary
+func (c *documentCopy) catalog() string {
+	return c.deref(c.pdf.Trailer().RootRef)
+}
+
+// acroForm returns the document's AcroForm dictionary, or "" if it has none
+func (c *documentCopy) acroForm() string {
+	acroForm := c.deref(rawDictValue(c.catalog(), "/AcroForm"))
+	if !strings.HasPrefix(acroForm, "<<") {
+		return ""
+	}
+	return acroForm
+}
+
+// outline returns the document's outline as bookmarks of the merged document.
+// Destinations point at the copied pages; items whose target was not copied
+// are dropped unless they have children.
+func (c *documentCopy) outline() []types.Bookmark {
+	outlines := c.deref(rawDictValue(c.catalog(), "/Outlines"))
+	return c.outlineItems(rawDictValue(outlines, "/First"), make(map[int]bool), 0)
+}
+
+// outlineItems returns the outline items from first along their /Next chain
+func (c *documentCopy) outlineItems(first string, visited map[int]bool, depth int) []types.Bookmark {
+	var bookmarks []types.Bookmark
+	if depth > maxOutlineDepth {
+		return nil
+	}
+	for ref := first; ref != ""; {
+		objNum, err := parseObjectRef(ref)
+		if err != nil || visited[objNum] {
+			break
+		}
+		visited[objNum] = true
+		item := c.deref(ref)
+
+		bookmark := types.Bookmark{Title: pdfTextString(decodedString(c.deref(topLevelValue(item, "/Title"))))}
+		dest := topLevelValue(item, "/Dest")
+		if action := c.deref(topLevelValue(item, "/A")); dest == "" && action != "" {
+			switch rawDictValue(action, "/S") {
+			case "/GoTo":
+				dest = topLevelValue(action, "/D")
+			case "/URI":
+				bookmark.URI = decodedString(c.deref(topLevelValue(action, "/URI")))
+			}
+		}
+		if dest != "" {
+			bookmark.Destination = c.destination(dest)
+		}
+		bookmark.Children = c.outlineItems(topLevelValue(item, "/First"), visited, depth+1)
+		if bookmark.Destination != "" || bookmark.URI != "" || len(bookmark.Children) > 0 {
+			bookmarks = append(bookmarks, bookmark)
+		}
+		ref = topLevelValue(item, "/Next")
+	}
+	return bookmarks
+}
+
+// destination returns an explicit destination in the merged document for a
+// destination of the document, resolving named destinations, or "" if its
+// page was not copied
+func (c *documentCopy) destination(dest string) string {
+	dest = c.deref(dest)
+	switch {
+	case strings.HasPrefix(dest, "/"):
+		// A name in the catalog's /Dests dictionary (PDF 1.1)
+		dest = c.deref(rawDictValue(c.deref(rawDictValue(c.catalog(), "/Dests")), dest))
+	case strings.HasPrefix(dest, "(") || strings.HasPrefix(dest, "<") && !strings.HasPrefix(dest, "<<"):
+		names := c.deref(rawDictValue(c.catalog(), "/Names"))
+		dest = c.deref(c.lookupName(c.deref(rawDictValue(names, "/Dests")), decodedString(dest), 0))
+	}
+	// Named destinations may be dictionaries with the destination in /D
+	if strings.HasPrefix(dest, "<<") {
+		dest = c.deref(topLevelValue(dest, "/D"))
+	}
+	if !strings.HasPrefix(dest, "[") {
+		return ""
+	}
+	refs := objectRefs(dest)
+	if len(refs) == 0 {
+		return ""
+	}
+	if _, ok := c.numbers[refs[0]]; !ok {
+		return ""
+	}
+	return c.remap(dest)
+}
+
+// lookupName returns the value of a key in a name tree, or ""
+func (c *documentCopy) lookupName(node, key string, depth int) string {
+	if node == "" || depth > maxOutlineDepth {
+		return ""
+	}
+	// rawDictValue would end the /Names array at the end of its first
+	// destination array, so the array is read here
+	names := ""
+	if idx := topLevelKeyIndex(node, "/Names"); idx != -1 {
+		names = strings.TrimSpace(node[idx+len("/Names"):])
+		names = c.deref(names[:rawValueLength(names)])
+	}
+	for rest := strings.TrimSpace(strings.TrimPrefix(names, "[")); rest != "" && !strings.HasPrefix(rest, "]"); {
+		tokens, err := encrypt.FindStrings([]byte(rest))
+		if err != nil || len(tokens) == 0 || tokens[0].Start != 0 {
+			break
+		}
+		rest = strings.TrimSpace(rest[tokens[0].End:])
+		value := rest[:rawValueLength(rest)]
+		if string(tokens[0].Value) == key {
+			return value
+		}
+		rest = strings.TrimSpace(rest[len(value):])
+	}
+	for _, kid := range c.arrayRefs(rawDictValue(node, "/Kids")) {
+		if value := c.lookupName(c.deref(fmt.Sprintf("%d 0 R", kid)), key, depth+1); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// arrayRefs returns the object numbers referenced by an array, given inline or
+// by reference
+func (c *documentCopy) arrayRefs(array string) []int {
+	if array != "" && !strings.HasPrefix(array, "[") {
+		objNum, err := parseObjectRef(array)
+		if err != nil {
+			return nil
+		}
+		obj, err := c.pdf.GetObject(objNum)
+		if err != nil {
+			return nil
+		}
+		array = string(objectBody(obj))
+	}
+	return objectRefs(array)
+}
+
+// fieldTree returns the object numbers of fields and all their descendants
+func (c *documentCopy) fieldTree(fields []int) map[int]bool {
+	tree := make(map[int]bool)
+	var visit func(objNums []int)
+	visit = func(objNums []int) {
+		for _, objNum := range objNums {
+			if tree[objNum] {
+				continue
+			}
+			obj, err := c.pdf.GetObject(objNum)
+			if err != nil {
+				continue
+			}
+			tree[objNum] = true
+			visit(c.arrayRefs(rawDictValue(string(objectBody(obj)), "/Kids")))
+		}
+	}
+	visit(fields)
+	return tree
+}
+
+// visit loads an object and everything it references, depth first, marking the
+// objects of reference cycles unique and hashing the others by content
+func (c *documentCopy) visit(objNum int) {
+	switch c.state[objNum] {
+	case 1:
+		// A cycle: the objects from objNum up the stack reference each other
+		for i := len(c.stack) - 1; i >= 0; i-- {
+			c.unique[c.stack[i]] = true
+			if c.stack[i] == objNum {
+				break
+			}
+		}
+		return
+	case 2:
+		return
+	}
+
+	src, ok := c.objects[objNum]
+	if !ok {
+		obj, err := c.pdf.GetObject(objNum)
+		if err != nil || parse.IsNullObject(obj) {
+			c.state[objNum] = 2
+			return
+		}
+		dict, data, isStream := splitStreamObject(objectBody(obj))
+		if isStream {
+			dict = setStreamLength(dict, len(data))
+		}
+		src = &mergeSource{dict: dict, data: data, stream: isStream, refs: objectRefs(string(dict))}
+		c.objects[objNum] = src
+	}
+
+	c.state[objNum] = 1
+	c.stack = append(c.stack, objNum)
+	for _, ref := range src.refs {
+		c.visit(ref)
+	}
+	c.stack = c.stack[:len(c.stack)-1]
+	c.state[objNum] = 2
+	c.order = append(c.order, objNum)
+}
+
+// propagateUnique marks the objects that reference unique objects unique as
+// well, then hashes the objects left to share. Shared objects only reference
+// other shared objects, which come first in depth-first post-order.
+func (c *documentCopy) propagateUnique() {
+	for changed := true; changed; {
+		changed = false
+		for _, objNum := range c.order {
+			if c.unique[objNum] {
+				continue
+			}
+			for _, ref := range c.objects[objNum].refs {
+				if c.unique[ref] {
+					c.unique[objNum] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	for _, objNum := range c.order {
+		if c.unique[objNum] {
+			continue
+		}
+		src := c.objects[objNum]
+		h := sha256.New()
+		h.Write([]byte(refPattern.ReplaceAllStringFunc(string(src.dict), func(ref string) string {
+			objNum, err := parseObjectRef(ref)
+			if err != nil {
+				return ref
+			}
+			if hash, ok := c.hashes[objNum]; ok {
+				return fmt.Sprintf("#%x", hash)
+			}
+			return "null"
+		})))
+		if src.stream {
+			h.Write([]byte("stream"))
+			h.Write(src.data)
+		}
+		var hash [sha256.Size]byte
+		h.Sum(hash[:0])
+		c.hashes[objNum] = hash
+	}
+}
+
+// remap rewrites the references of a document's object for the merged
+// document; references to objects that were not copied become null
+func (c *documentCopy) remap(s string) string {
+	return refPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		objNum, err := parseObjectRef(ref)
+		if err != nil {
+			return ref
+		}
+		if newObjNum, ok := c.numbers[objNum]; ok {
+			return fmt.Sprintf("%d 0 R", newObjNum)
+		}
+		return "null"
+	})
+}
+
+// rawValueLength returns the length of the value at the start of s: a string,
+// an array or dictionary with everything nested in it, a reference or a token
+func rawValueLength(s string) int {
+	if strings.HasPrefix(s, "(") || strings.HasPrefix(s, "<") && !strings.HasPrefix(s, "<<") {
+		if tokens, err := encrypt.FindStrings([]byte(s)); err == nil && len(tokens) > 0 && tokens[0].Start == 0 {
+			return tokens[0].End
+		}
+		return len(s)
+	}
+	if strings.HasPrefix(s, "[") || strings.HasPrefix(s, "<<") {
+		depth := 0
+		for i := 0; i < len(s); i++ {
+			switch {
+			case s[i] == '(' || s[i] == '<' && !strings.HasPrefix(s[i:], "<<"):
+				i += rawValueLength(s[i:]) - 1
+			case s[i] == '[':
+				depth++
+			case strings.HasPrefix(s[i:], "<<"):
+				depth++
+				i++
+			case s[i] == ']':
+				depth--
+			case strings.HasPrefix(s[i:], ">>"):
+				depth--
+				i++
+			}
+			if depth == 0 {
+				return i + 1
+			}
+		}
+		return len(s)
+	}
+	if match := refPattern.FindStringIndex(s); match != nil && match[0] == 0 {
+		return match[1]
+	}
+	end := 1
+	for end < len(s) && !strings.ContainsRune(" \t\r\n/<>[]()", rune(s[end])) {
+		end++
+	}
+	return end
+}
+
+// pdfTextString decodes the bytes of a PDF text string: UTF-16BE or UTF-8 with
+// a byte order mark, otherwise single-byte text
+func pdfTextString(b string) string {
+	if strings.HasPrefix(b, "\xfe\xff") {
+		units := make([]uint16, 0, len(b)/2)
+		for i := 2; i+1 < len(b); i += 2 {
+			units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	if strings.HasPrefix(b, "\xef\xbb\xbf") {
+		return b[3:]
+	}
+	runes := make([]rune, len(b))
+	for i := 0; i < len(b); i++ {
+		runes[i] = rune(b[i])
+	}
+	return string(runes)
+}
+
+// objectRefs returns the object numbers referenced in s
+func objectRefs(s string) []int {
+	var objNums []int
+	for _, match := range refPattern.FindAllStringSubmatch(s, -1) {
+		if objNum, err := strconv.Atoi(match[1]); err == nil {
+			objNums = append(objNums, objNum)
+		}
+	}
+	return objNums
+}
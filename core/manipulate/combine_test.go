@@ -0,0 +1,217 @@
+package manipulate
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/forms/acroform"
+)
+
+// createLetter builds a two-page letter: a personal first page and terms on
+// the second page that are the same in every letter
+func createLetter(t *testing.T, name string) []byte {
+	t.Helper()
+	builder := write.NewSimplePDFBuilder()
+	for _, text := range []string{"Dear " + name, "Terms and conditions"} {
+		page := builder.AddPage(write.PageSizeLetter)
+		font := page.AddStandardFont("Helvetica")
+		page.Content().BeginText().SetFont(font, 12).SetTextPosition(72, 720).ShowText(text).EndText()
+		builder.FinalizePage(page)
+	}
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create test PDF: %v", err)
+	}
+	return pdfBytes
+}
+
+func TestDocumentMerger(t *testing.T) {
+	names := []string{"Ada", "Grace", "Edsger"}
+	dm := NewDocumentMerger(false)
+	for _, name := range names {
+		if err := dm.Add(createLetter(t, name), nil, "Letter to "+name); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	if dm.Documents() != 3 || dm.Pages() != 6 {
+		t.Errorf("Expected 3 documents with 6 pages, got %d and %d", dm.Documents(), dm.Pages())
+	}
+	// The font and the terms page's content are written once
+	if dm.SharedObjects() < 4 {
+		t.Errorf("Expected the font and terms content to be shared, got %d shared objects", dm.SharedObjects())
+	}
+	merged, err := dm.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes failed: %v", err)
+	}
+	if n := bytes.Count(merged, []byte("(Terms and conditions)")); n != 1 {
+		t.Errorf("Expected the terms content once, found %d times", n)
+	}
+
+	doc, err := extract.ExtractContent(merged, nil, false)
+	if err != nil {
+		t.Fatalf("Failed to extract merged PDF: %v", err)
+	}
+	if len(doc.Pages) != 6 {
+		t.Fatalf("Expected 6 pages, got %d", len(doc.Pages))
+	}
+	for i, name := range names {
+		for j, want := range []string{"Dear " + name, "Terms and conditions"} {
+			page := doc.Pages[2*i+j]
+			if len(page.Text) == 0 || page.Text[0].Text != want {
+				t.Errorf("Page %d should show %q, got %+v", 2*i+j+1, want, page.Text)
+			}
+		}
+	}
+	if len(doc.Bookmarks) != 3 {
+		t.Fatalf("Expected 3 bookmarks, got %+v", doc.Bookmarks)
+	}
+	pdf, err := parse.Open(merged)
+	if err != nil {
+		t.Fatalf("Failed to parse merged PDF: %v", err)
+	}
+	for i, bookmark := range doc.Bookmarks {
+		page, err := pdf.Page(2*i + 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(bookmark.Destination, fmt.Sprintf("[%d 0 R ", page.ObjectNumber)) {
+			t.Errorf("Bookmark %d should go to page %d, got %+v", i, 2*i+1, bookmark)
+		}
+		if !bytes.Contains(merged, []byte("/Title (Letter to "+names[i]+")")) {
+			t.Errorf("Bookmark title for %s missing", names[i])
+		}
+	}
+
+	if _, err := NewDocumentMerger(false).Bytes(); err == nil {
+		t.Error("Expected an error merging no documents")
+	}
+}
+
+func TestDocumentMerger_Forms(t *testing.T) {
+	form := createFlattenPDF(t)
+	dm := NewDocumentMerger(false)
+	for i := 0; i < 2; i++ {
+		if err := dm.Add(form, nil, ""); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	merged, err := dm.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes failed: %v", err)
+	}
+
+	// Each copy keeps its own widgets and fields, grouped by document
+	pdf, err := parse.Open(merged)
+	if err != nil {
+		t.Fatalf("Failed to parse merged PDF: %v", err)
+	}
+	widgets := 0
+	for _, objNum := range pdf.Objects() {
+		if obj, err := pdf.GetObject(objNum); err == nil && strings.Contains(string(obj), "/Subtype/Widget") {
+			widgets++
+		}
+	}
+	if widgets != 6 {
+		t.Errorf("Expected 6 widgets, got %d", widgets)
+	}
+	af, err := acroform.ExtractAcroForm(merged, nil, false)
+	if err != nil {
+		t.Fatalf("ExtractAcroForm failed: %v", err)
+	}
+	var names []string
+	for _, group := range af.Fields {
+		for _, field := range group.Kids {
+			names = append(names, field.GetFullName())
+		}
+	}
+	if got := strings.Join(names, ","); got != "doc1.name,doc1.agree,doc1.internal,doc2.name,doc2.agree,doc2.internal" {
+		t.Errorf("Unexpected field names %s", got)
+	}
+	if !strings.Contains(string(merged), "/NeedAppearances true") {
+		t.Error("Merged form should keep /NeedAppearances")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	builder := write.NewSimplePDFBuilder()
+	cover := builder.AddPage(write.PageSizeLetter)
+	font := cover.AddStandardFont("Helvetica")
+	cover.Content().BeginText().SetFont(font, 24).SetTextPosition(72, 720).ShowText("Cover").EndText()
+	builder.FinalizePage(cover)
+	coverPDF, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create cover: %v", err)
+	}
+
+	// A guide whose outline uses a UTF-16 title, a named destination from the
+	// name tree and a GoTo action to a name in the catalog's /Dests
+	writer := write.NewPDFWriter()
+	catalogNum := writer.AddObject(nil)
+	pagesNum := writer.AddObject(nil)
+	var pageNums []int
+	for i := 0; i < 2; i++ {
+		pageNums = append(pageNums, writer.AddObject([]byte(fmt.Sprintf("<</Type/Page/Parent %d 0 R/MediaBox[0 0 612 792]>>", pagesNum))))
+	}
+	writer.SetObject(pagesNum, []byte(fmt.Sprintf("<</Type/Pages/Kids[%d 0 R %d 0 R]/Count 2>>", pageNums[0], pageNums[1])))
+	outlinesNum := writer.AddObject(nil)
+	overview := writer.AddObject(nil)
+	details := writer.AddObject(nil)
+	writer.SetObject(overview, []byte(fmt.Sprintf("<</Title <FEFF004F0076006500720076006900650077>/Parent %d 0 R/Next %d 0 R/Dest (intro)>>", outlinesNum, details)))
+	writer.SetObject(details, []byte(fmt.Sprintf("<</Title (Details)/Parent %d 0 R/Prev %d 0 R/A <</S/GoTo/D/details>>>>", outlinesNum, overview)))
+	writer.SetObject(outlinesNum, []byte(fmt.Sprintf("<</Type/Outlines/First %d 0 R/Last %d 0 R/Count 2>>", overview, details)))
+	writer.SetObject(catalogNum, []byte(fmt.Sprintf(
+		"<</Type/Catalog/Pages %d 0 R/Outlines %d 0 R/Dests <</details [%d 0 R /Fit]>>/Names <</Dests <</Names [(intro) [%d 0 R /XYZ 0 792 0]]>>>>>>",
+		pagesNum, outlinesNum, pageNums[1], pageNums[0])))
+	writer.SetRoot(catalogNum)
+	guidePDF, err := writer.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create guide: %v", err)
+	}
+
+	merged, err := Merge([][]byte{coverPDF, guidePDF, createLetter(t, "Ada")}, MergeOptions{Titles: []string{"", "Guide"}})
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	pdf, err := parse.Open(merged)
+	if err != nil {
+		t.Fatalf("Failed to parse merged PDF: %v", err)
+	}
+	if n, err := pdf.PageCount(); err != nil || n != 5 {
+		t.Fatalf("Expected 5 pages, got %d (%v)", n, err)
+	}
+	pageRef := func(n int) string {
+		page, err := pdf.Page(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fmt.Sprintf("[%d 0 R ", page.ObjectNumber)
+	}
+
+	bookmarks, err := extract.ExtractBookmarks(merged, pdf, false)
+	if err != nil {
+		t.Fatalf("ExtractBookmarks failed: %v", err)
+	}
+	if len(bookmarks) != 1 || len(bookmarks[0].Children) != 2 {
+		t.Fatalf("Expected the guide's bookmark with its two items, got %+v", bookmarks)
+	}
+	for i, want := range []string{pageRef(2), pageRef(3)} {
+		if got := bookmarks[0].Children[i].Destination; !strings.HasPrefix(got, want) {
+			t.Errorf("Guide item %d should go to %s, got %s", i+1, want, got)
+		}
+	}
+	for _, title := range []string{"/Title (Guide)", "/Title (Overview)", "/Title (Details)"} {
+		if !bytes.Contains(merged, []byte(title)) {
+			t.Errorf("Merged outline lacks %s", title)
+		}
+	}
+
+	if _, err := Merge([][]byte{coverPDF, []byte("not a pdf")}, MergeOptions{}); err == nil || !strings.Contains(err.Error(), "input 2") {
+		t.Errorf("Expected an error naming input 2, got %v", err)
+	}
+}
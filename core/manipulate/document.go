@@ -0,0 +1,92 @@
+package manipulate
+
+import (
+	"fmt"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/core/write"
+)
+
+// Document is a parsed PDF that is never modified after OpenDocument returns.
+// One Document can be shared by any number of goroutines, for example a form
+// template parsed once at startup and filled for each request. Edits are made in
+// sessions returned by Edit, each of which produces a new revision with Rebuild
+// and leaves the Document and other sessions untouched.
+type Document struct {
+	pdf      *parse.PDF
+	pdfBytes []byte
+	objects  map[int][]byte // object number -> content; shared read-only with sessions
+	verbose  bool
+}
+
+// OpenDocument parses a PDF and loads its objects for sharing between editing
+// sessions. pdfBytes must not be modified while the Document is in use.
+func OpenDocument(pdfBytes []byte, password []byte, verbose bool) (*Document, error) {
+	pdf, err := parse.OpenWithOptions(pdfBytes, parse.ParseOptions{
+		Password: password,
+		Verbose:  verbose,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PDF: %w", err)
+	}
+
+	objects := make(map[int][]byte)
+	for _, objNum := range pdf.Objects() {
+		obj, err := pdf.GetObject(objNum)
+		if err != nil {
+			if verbose {
+				fmt.Printf("Warning: failed to get object %d: %v\n", objNum, err)
+			}
+			continue
+		}
+		// Clip the capacity so that appending to an object copies it instead of
+		// writing into memory shared with the input or other sessions
+		objects[objNum] = obj[:len(obj):len(obj)]
+	}
+
+	return &Document{
+		pdf:      pdf,
+		pdfBytes: pdfBytes,
+		objects:  objects,
+		verbose:  verbose,
+	}, nil
+}
+
+// PDF returns the parsed document for read-only access
+func (d *Document) PDF() *parse.PDF {
+	return d.pdf
+}
+
+// Bytes returns the bytes the document was opened from
+func (d *Document) Bytes() []byte {
+	return d.pdfBytes
+}
+
+// ObjectCount returns the number of objects loaded from the document
+func (d *Document) ObjectCount() int {
+	return len(d.objects)
+}
+
+// Edit starts an editing session. The session starts from the document's
+// objects and copies only the object table: object contents are shared until
+// the session replaces them, which manipulator methods do rather than changing
+// them in place. A session is not safe for concurrent use, but any number of
+// sessions may edit the same Document concurrently.
+func (d *Document) Edit() *PDFManipulator {
+	objects := make(map[int][]byte, len(d.objects))
+	for objNum, obj := range d.objects {
+		objects[objNum] = obj
+	}
+	return d.session(objects)
+}
+
+// session returns a manipulator editing objects on top of the document
+func (d *Document) session(objects map[int][]byte) *PDFManipulator {
+	return &PDFManipulator{
+		pdf:      d.pdf,
+		pdfBytes: d.pdfBytes,
+		writer:   write.NewPDFWriter(),
+		objects:  objects,
+		verbose:  d.verbose,
+	}
+}
@@ -0,0 +1,102 @@
+package manipulate
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/core/write"
+)
+
+func TestDocument_ConcurrentSessions(t *testing.T) {
+	builder := write.NewSimplePDFBuilder()
+	for i := 1; i <= 2; i++ {
+		page := builder.AddPage(write.PageSizeLetter)
+		font := page.AddStandardFont("Helvetica")
+		page.Content().
+			BeginText().
+			SetFont(font, 12).
+			SetTextPosition(72, 720).
+			ShowText(fmt.Sprintf("Page %d", i)).
+			EndText()
+		builder.FinalizePage(page)
+	}
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create test PDF: %v", err)
+	}
+
+	doc, err := OpenDocument(pdfBytes, nil, false)
+	if err != nil {
+		t.Fatalf("OpenDocument failed: %v", err)
+	}
+	before := make(map[int]string)
+	for objNum, obj := range doc.objects {
+		before[objNum] = string(obj)
+	}
+
+	angles := []int{0, 90, 180, 270, 90, 180, 270, 0}
+	results := make([][]byte, len(angles))
+	errs := make([]error, len(angles))
+	var wg sync.WaitGroup
+	for i, angle := range angles {
+		wg.Add(1)
+		go func(i, angle int) {
+			defer wg.Done()
+			session := doc.Edit()
+			if angle != 0 {
+				if err := session.RotatePage(1, angle); err != nil {
+					errs[i] = err
+					return
+				}
+			}
+			results[i], errs[i] = session.Rebuild()
+		}(i, angle)
+	}
+	wg.Wait()
+
+	for i, angle := range angles {
+		if errs[i] != nil {
+			t.Fatalf("Session %d failed: %v", i, errs[i])
+		}
+		content, err := extract.ExtractContent(results[i], nil, false)
+		if err != nil {
+			t.Fatalf("Failed to extract session %d output: %v", i, err)
+		}
+		if got := content.Pages[0].Rotation; got != angle {
+			t.Errorf("Session %d: rotation %d, want %d", i, got, angle)
+		}
+		if got := content.Pages[1].Rotation; got != 0 {
+			t.Errorf("Session %d: page 2 rotation %d, want 0", i, got)
+		}
+	}
+
+	// The shared document is unchanged
+	if len(doc.objects) != len(before) {
+		t.Fatalf("Document has %d objects, had %d", len(doc.objects), len(before))
+	}
+	for objNum, obj := range doc.objects {
+		if string(obj) != before[objNum] {
+			t.Errorf("Object %d changed by a session", objNum)
+		}
+	}
+}
+
+func TestDocument_SessionIsolation(t *testing.T) {
+	doc, err := OpenDocument(createUsageRightsPDF(t, ""), nil, false)
+	if err != nil {
+		t.Fatalf("OpenDocument failed: %v", err)
+	}
+
+	session := doc.Edit()
+	if session.RemoveUsageRights() == nil {
+		t.Fatal("Usage rights not removed")
+	}
+	if session.UsageRights() != nil {
+		t.Error("Usage rights still present in the session")
+	}
+	if doc.Edit().UsageRights() == nil {
+		t.Error("Removing usage rights in one session affected the document")
+	}
+}
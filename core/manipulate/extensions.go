@@ -0,0 +1,67 @@
+package manipulate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// SetExtension declares a developer extension in the catalog's /Extensions
+// dictionary, replacing any extension already declared with the same prefix.
+// An indirect /Extensions dictionary is updated in place.
+func (m *PDFManipulator) SetExtension(ext types.Extension) error {
+	rootObjNum, catalog, err := m.catalog()
+	if err != nil {
+		return err
+	}
+	formatted, err := write.FormatExtensions([]types.Extension{ext})
+	if err != nil {
+		return err
+	}
+	key := "/" + strings.TrimPrefix(ext.Prefix, "/")
+	entry := topLevelValue(formatted, key)
+
+	existing := topLevelValue(catalog, "/Extensions")
+	switch {
+	case existing == "":
+		m.objects[rootObjNum] = []byte(setTopLevelValue(catalog, "/Extensions", formatted))
+	case !strings.HasPrefix(existing, "<<"):
+		objNum, err := parseObjectRef(existing)
+		if err != nil {
+			return fmt.Errorf("invalid /Extensions reference %q: %w", existing, err)
+		}
+		dict, ok := m.objects[objNum]
+		if !ok {
+			return fmt.Errorf("extensions object %d not found", objNum)
+		}
+		m.objects[objNum] = []byte(setTopLevelValue(string(dict), key, entry))
+	default:
+		m.objects[rootObjNum] = []byte(setTopLevelValue(catalog, "/Extensions", setTopLevelValue(existing, key, entry)))
+	}
+
+	if m.verbose {
+		fmt.Printf("Declared extension %s %s level %d\n", key, ext.BaseVersion, ext.ExtensionLevel)
+	}
+	return nil
+}
+
+// SetRequirements replaces the catalog's /Requirements array. An empty list
+// removes it.
+func (m *PDFManipulator) SetRequirements(requirements []types.Requirement) error {
+	rootObjNum, catalog, err := m.catalog()
+	if err != nil {
+		return err
+	}
+	if len(requirements) == 0 {
+		m.objects[rootObjNum] = []byte(removeTopLevelKey(catalog, "/Requirements"))
+		return nil
+	}
+	formatted, err := write.FormatRequirements(requirements)
+	if err != nil {
+		return err
+	}
+	m.objects[rootObjNum] = []byte(setTopLevelValue(catalog, "/Requirements", formatted))
+	return nil
+}
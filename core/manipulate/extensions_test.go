@@ -0,0 +1,59 @@
+package manipulate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+func TestSetExtension(t *testing.T) {
+	builder := write.NewSimplePDFBuilder()
+	builder.FinalizePage(builder.AddPage(write.PageSizeLetter))
+	if err := builder.SetExtensions([]types.Extension{{Prefix: "XYZ", BaseVersion: "2.0", ExtensionLevel: 1}}); err != nil {
+		t.Fatalf("SetExtensions failed: %v", err)
+	}
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create test PDF: %v", err)
+	}
+
+	m, err := NewPDFManipulator(pdfBytes, nil, false)
+	if err != nil {
+		t.Fatalf("NewPDFManipulator failed: %v", err)
+	}
+	for _, level := range []int{3, 5} {
+		if err := m.SetExtension(types.Extension{Prefix: "ADBE", BaseVersion: "1.7", ExtensionLevel: level}); err != nil {
+			t.Fatalf("SetExtension failed: %v", err)
+		}
+	}
+	if err := m.SetRequirements([]types.Requirement{{Type: "EnableJavaScripts", Penalty: 100}}); err != nil {
+		t.Fatalf("SetRequirements failed: %v", err)
+	}
+
+	_, catalog, err := m.catalog()
+	if err != nil {
+		t.Fatalf("catalog failed: %v", err)
+	}
+	extensions := topLevelValue(catalog, "/Extensions")
+	if !strings.Contains(extensions, "/XYZ << /Type /DeveloperExtensions /BaseVersion /2.0 /ExtensionLevel 1 >>") {
+		t.Errorf("Existing extension lost: %s", extensions)
+	}
+	if strings.Count(extensions, "/ADBE") != 1 || !strings.Contains(extensions, "/ExtensionLevel 5") {
+		t.Errorf("ADBE extension not replaced: %s", extensions)
+	}
+	if !strings.Contains(catalog, "/S /EnableJavaScripts") {
+		t.Errorf("Requirements not set: %s", catalog)
+	}
+
+	if err := m.SetRequirements(nil); err != nil {
+		t.Fatalf("SetRequirements failed: %v", err)
+	}
+	if _, catalog, _ := m.catalog(); strings.Contains(catalog, "/Requirements") {
+		t.Errorf("Requirements not removed: %s", catalog)
+	}
+	if err := m.SetExtension(types.Extension{Prefix: "ADBE", BaseVersion: "x"}); err == nil {
+		t.Error("Expected an error for an invalid base version")
+	}
+}
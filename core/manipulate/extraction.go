@@ -2,6 +2,9 @@ package manipulate
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/benedoc-inc/pdfer/core/write"
 )
@@ -34,30 +37,34 @@ func ExtractPages(pdfBytes []byte, pageNumbers []int, password []byte, verbose b
 
 	// Create a new PDF with only the extracted pages
 	writer := write.NewPDFWriter()
-	pagesObjNum := writer.AddObject([]byte("")) // Placeholder, will update later
 	pageObjNums := make([]int, 0, len(pageNumbers))
-
-	// Copy page objects and their dependencies
 	for _, pageNum := range pageNumbers {
 		pageObjNum := allPageObjNums[pageNum-1]
-
-		// Get page object
 		pageObj, ok := manipulator.objects[pageObjNum]
 		if !ok {
 			return nil, fmt.Errorf("page object %d not found", pageObjNum)
 		}
-
-		// Copy page object to new PDF
-		writer.SetObject(pageObjNum, pageObj)
+		// The original page tree is not copied, so inherited attributes move onto the page
+		manipulator.objects[pageObjNum] = []byte(manipulator.materializeInheritedAttributes(string(pageObj)))
 		pageObjNums = append(pageObjNums, pageObjNum)
+	}
+
+	// Carry over the interactive form so widgets on the extracted pages stay fields
+	acroForm := manipulator.catalogAcroForm()
 
-		// Update page's Parent reference to point to new Pages object
-		pageStr := string(pageObj)
+	// Copy pages and everything they reference (content streams, resources,
+	// annotations and form widgets) under their original object numbers
+	deps := manipulator.collectDependencies(pageObjNums, acroForm)
+	for objNum := range deps {
+		writer.SetObject(objNum, manipulator.objects[objNum])
+	}
+
+	// New objects are numbered after the copied ones so nothing is overwritten
+	pagesObjNum := writer.AddObject([]byte(""))
+	for _, pageObjNum := range pageObjNums {
+		pageStr := string(manipulator.objects[pageObjNum])
 		updatedPageStr := setDictValue(pageStr, "/Parent", fmt.Sprintf("%d 0 R", pagesObjNum))
 		writer.SetObject(pageObjNum, []byte(updatedPageStr))
-
-		// TODO: Copy page dependencies (content streams, resources, fonts, images, etc.)
-		// For now, we'll copy the page object as-is and hope dependencies are included
 	}
 
 	// Build Kids array
@@ -75,8 +82,132 @@ func ExtractPages(pdfBytes []byte, pageNumbers []int, password []byte, verbose b
 	writer.SetObject(pagesObjNum, []byte(pagesDict))
 
 	// Create Catalog
-	catalogObjNum := writer.AddObject([]byte(fmt.Sprintf("<</Type/Catalog/Pages %d 0 R>>", pagesObjNum)))
+	catalog := fmt.Sprintf("<</Type/Catalog/Pages %d 0 R>>", pagesObjNum)
+	if acroForm != "" {
+		var fields []string
+		for _, ref := range parseObjectRefArray(extractDictValue(acroForm, "/Fields")) {
+			if objNum, err := parseObjectRef(ref); err == nil && deps[objNum] {
+				fields = append(fields, ref)
+			}
+		}
+		acroForm = setDictValue(acroForm, "/Fields", "["+strings.Join(fields, " ")+"]")
+		acroFormObjNum := writer.AddObject([]byte(acroForm))
+		catalog = fmt.Sprintf("<</Type/Catalog/Pages %d 0 R/AcroForm %d 0 R>>", pagesObjNum, acroFormObjNum)
+	}
+	catalogObjNum := writer.AddObject([]byte(catalog))
 	writer.SetRoot(catalogObjNum)
 
 	return writer.Bytes()
 }
+
+// catalogAcroForm returns the catalog's /AcroForm dictionary ("<<...>>"), or "" if none
+func (m *PDFManipulator) catalogAcroForm() string {
+	trailer := m.pdf.Trailer()
+	if trailer == nil || trailer.RootRef == "" {
+		return ""
+	}
+	rootObjNum, err := parseObjectRef(trailer.RootRef)
+	if err != nil {
+		return ""
+	}
+	catalogStr := string(m.objects[rootObjNum])
+
+	idx := strings.Index(catalogStr, "/AcroForm")
+	if idx == -1 {
+		return ""
+	}
+	value := strings.TrimSpace(catalogStr[idx+len("/AcroForm"):])
+	if !strings.HasPrefix(value, "<<") {
+		match := refPattern.FindStringSubmatch(value)
+		if match == nil || !strings.HasPrefix(value, match[0]) {
+			return ""
+		}
+		objNum, _ := strconv.Atoi(match[1])
+		value = string(m.objects[objNum])
+		start := strings.Index(value, "<<")
+		if start == -1 {
+			return ""
+		}
+		value = value[start:]
+	}
+	return balancedDict(value)
+}
+
+// collectDependencies returns the pages and every object reachable from them or from
+// extra, without following links back into the page tree or to pages not being copied
+func (m *PDFManipulator) collectDependencies(pageObjNums []int, extra string) map[int]bool {
+	selected := make(map[int]bool, len(pageObjNums))
+	for _, n := range pageObjNums {
+		selected[n] = true
+	}
+
+	deps := make(map[int]bool)
+	queue := append([]int{}, pageObjNums...)
+
+	enqueueRefs := func(s string) {
+		for _, match := range refPattern.FindAllStringSubmatch(s, -1) {
+			objNum, err := strconv.Atoi(match[1])
+			if err != nil || deps[objNum] {
+				continue
+			}
+			obj, ok := m.objects[objNum]
+			if !ok {
+				continue
+			}
+			if pageTypePattern.MatchString(dictPart(string(obj))) && !selected[objNum] {
+				continue
+			}
+			queue = append(queue, objNum)
+		}
+	}
+
+	// /Fields is rebuilt from the copied widgets, so it is not followed directly
+	enqueueRefs(strings.Replace(extra, extractDictValue(extra, "/Fields"), "", 1))
+
+	for len(queue) > 0 {
+		objNum := queue[0]
+		queue = queue[1:]
+		if deps[objNum] {
+			continue
+		}
+		deps[objNum] = true
+
+		dict := dictPart(string(m.objects[objNum]))
+		if selected[objNum] {
+			// A page's /Parent is replaced by the new pages node
+			dict = parentRefPattern.ReplaceAllString(dict, "")
+		}
+		enqueueRefs(dict)
+	}
+
+	return deps
+}
+
+// pageTypePattern matches page tree nodes (/Type /Page or /Type /Pages)
+var pageTypePattern = regexp.MustCompile(`/Type\s*/Pages?\b`)
+
+// dictPart returns an object's content before any stream data, so binary data is not scanned for references
+func dictPart(obj string) string {
+	if idx := strings.Index(obj, "stream"); idx != -1 {
+		return obj[:idx]
+	}
+	return obj
+}
+
+// balancedDict returns the leading "<<...>>" dictionary of s
+func balancedDict(s string) string {
+	depth := 0
+	for i := 0; i+1 < len(s); i++ {
+		if s[i] == '<' && s[i+1] == '<' {
+			depth++
+			i++
+		} else if s[i] == '>' && s[i+1] == '>' {
+			depth--
+			i++
+			if depth == 0 {
+				return s[:i+1]
+			}
+		}
+	}
+	return ""
+}
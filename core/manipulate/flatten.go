@@ -0,0 +1,240 @@
+package manipulate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Annotation flags that keep a widget off the screen (PDF 32000 12.5.3)
+const (
+	annotFlagHidden = 1 << 1
+	annotFlagNoView = 1 << 5
+)
+
+// FlattenForm draws the normal appearance of every form widget into its page's
+// content and removes the interactive form: the widget annotations, the field
+// dictionaries and the catalog's /AcroForm, with any XFA it holds. Check boxes
+// and radio buttons show the appearance of their /AS state. Hidden widgets and
+// widgets without an appearance are removed without being drawn, so values that
+// only /NeedAppearances would show should get appearance streams first. Other
+// annotations, such as links and comments, are kept.
+func FlattenForm(pdfBytes []byte, password []byte, verbose bool) ([]byte, error) {
+	m, err := NewPDFManipulator(pdfBytes, password, verbose)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := m.FlattenForm(); err != nil {
+		return nil, err
+	}
+	return m.rebuildPDF()
+}
+
+// FlattenForm flattens the form like FlattenForm and returns the number of
+// widgets drawn into page content
+func (m *PDFManipulator) FlattenForm() (int, error) {
+	pageObjNums, err := m.getAllPageObjectNumbers()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pages: %w", err)
+	}
+
+	widgets := make(map[int]bool)
+	drawn := 0
+	for _, pageObjNum := range pageObjNums {
+		n, err := m.flattenPage(pageObjNum, widgets)
+		if err != nil {
+			return drawn, err
+		}
+		drawn += n
+	}
+
+	rootObjNum, catalog, err := m.catalog()
+	if err != nil {
+		return drawn, err
+	}
+	for objNum := range m.fieldTree(m.catalogAcroForm()) {
+		widgets[objNum] = true
+	}
+	for objNum := range widgets {
+		delete(m.objects, objNum)
+	}
+	if ref := rawDictValue(catalog, "/AcroForm"); !strings.HasPrefix(ref, "<<") {
+		if objNum, err := parseObjectRef(ref); err == nil {
+			delete(m.objects, objNum)
+		}
+	}
+	catalog = removeRawDictKey(catalog, "/AcroForm")
+	catalog = removeRawDictKey(catalog, "/NeedsRendering")
+	m.objects[rootObjNum] = []byte(catalog)
+
+	if m.verbose {
+		fmt.Printf("Flattened %d of %d widgets\n", drawn, len(widgets))
+	}
+	return drawn, nil
+}
+
+// flattenPage draws the appearances of a page's widgets into its content and
+// removes them from its /Annots, adding their object numbers to widgets
+func (m *PDFManipulator) flattenPage(pageObjNum int, widgets map[int]bool) (int, error) {
+	pageObj, ok := m.objects[pageObjNum]
+	if !ok {
+		return 0, fmt.Errorf("page object %d not found", pageObjNum)
+	}
+	pageStr := string(pageObj)
+	annots := m.pageAnnotations(pageStr)
+
+	var kept []string
+	var stream strings.Builder
+	drawn := 0
+	for _, annotObjNum := range annots {
+		annot := string(objectBody(m.objects[annotObjNum]))
+		if rawDictValue(annot, "/Subtype") != "/Widget" {
+			kept = append(kept, fmt.Sprintf("%d 0 R", annotObjNum))
+			continue
+		}
+		widgets[annotObjNum] = true
+
+		if flags, _ := strconv.Atoi(rawDictValue(annot, "/F")); flags&(annotFlagHidden|annotFlagNoView) != 0 {
+			continue
+		}
+		rect := parseNumberArray(rawDictValue(annot, "/Rect"))
+		apObjNum := m.widgetAppearance(annot)
+		if apObjNum == 0 || len(rect) < 4 {
+			continue
+		}
+		matrix, ok := m.appearanceMatrix(apObjNum, rect)
+		if !ok {
+			continue
+		}
+		if drawn == 0 {
+			pageStr = m.materializeInheritedAttributes(pageStr)
+		}
+		var name string
+		pageStr, name = m.addPageResource(pageStr, "/XObject", "FmFlat", apObjNum)
+		stream.WriteString(fmt.Sprintf("q\n%s cm\n/%s Do\nQ\n", formatNumbers(matrix[:]), name))
+		drawn++
+	}
+	if len(kept) == len(annots) {
+		return 0, nil
+	}
+
+	if len(kept) == 0 {
+		pageStr = removeRawDictKey(pageStr, "/Annots")
+	} else {
+		pageStr = setRawDictValue(pageStr, "/Annots", "["+strings.Join(kept, " ")+"]")
+	}
+	if drawn > 0 {
+		// Isolate the existing content's graphics state from the appearances
+		preObjNum := m.nextObjectNumber()
+		m.objects[preObjNum] = rawStreamObject("q\n")
+		formObjNum := m.nextObjectNumber()
+		m.objects[formObjNum] = rawStreamObject("\nQ\n" + stream.String())
+
+		existing := m.pageContentRefs(pageStr)
+		contents := fmt.Sprintf("[%d 0 R %s %d 0 R]", preObjNum, strings.Join(existing, " "), formObjNum)
+		if len(existing) == 0 {
+			contents = fmt.Sprintf("[%d 0 R %d 0 R]", preObjNum, formObjNum)
+		}
+		pageStr = setRawDictValue(pageStr, "/Contents", contents)
+	}
+	m.objects[pageObjNum] = []byte(pageStr)
+	return drawn, nil
+}
+
+// widgetAppearance returns the object number of a widget's normal appearance
+// stream, chosen by /AS when there is one per state, or 0 if it has none
+func (m *PDFManipulator) widgetAppearance(annot string) int {
+	ap := rawDictValue(annot, "/AP")
+	if !strings.HasPrefix(ap, "<<") {
+		objNum, err := parseObjectRef(ap)
+		if err != nil {
+			return 0
+		}
+		ap = string(objectBody(m.objects[objNum]))
+	}
+	normal := rawDictValue(ap, "/N")
+	if !strings.HasPrefix(normal, "<<") {
+		objNum, err := parseObjectRef(normal)
+		if err != nil {
+			return 0
+		}
+		if _, _, isStream := splitStreamObject(objectBody(m.objects[objNum])); isStream {
+			return objNum
+		}
+		normal = string(objectBody(m.objects[objNum]))
+	}
+	state := rawDictValue(annot, "/AS")
+	if !strings.HasPrefix(state, "/") {
+		return 0
+	}
+	objNum, err := parseObjectRef(rawDictValue(normal, state))
+	if err != nil {
+		return 0
+	}
+	if _, _, isStream := splitStreamObject(objectBody(m.objects[objNum])); !isStream {
+		return 0
+	}
+	return objNum
+}
+
+// appearanceMatrix returns the matrix that maps an appearance stream onto a
+// widget's rectangle (PDF 32000 12.5.5): its bounding box, transformed by its
+// /Matrix, is scaled and moved to fill the rectangle. An appearance without
+// /Subtype /Form is given one, so it can be drawn as a form XObject.
+func (m *PDFManipulator) appearanceMatrix(apObjNum int, rect []float64) ([6]float64, bool) {
+	dict, data, _ := splitStreamObject(objectBody(m.objects[apObjNum]))
+	dictStr := string(dict)
+	bbox := parseNumberArray(rawDictValue(dictStr, "/BBox"))
+	if len(bbox) < 4 {
+		return [6]float64{}, false
+	}
+	formMatrix := [6]float64{1, 0, 0, 1, 0, 0}
+	if values := parseNumberArray(rawDictValue(dictStr, "/Matrix")); len(values) == 6 {
+		copy(formMatrix[:], values)
+	}
+	box := transformRect(formMatrix, bbox)
+	rect = transformRect([6]float64{1, 0, 0, 1, 0, 0}, rect) // normalize
+	boxWidth, boxHeight := box[2]-box[0], box[3]-box[1]
+	if boxWidth <= 0 || boxHeight <= 0 {
+		return [6]float64{}, false
+	}
+
+	if rawDictValue(dictStr, "/Subtype") != "/Form" {
+		dictStr = setRawDictValue(setRawDictValue(dictStr, "/Type", "/XObject"), "/Subtype", "/Form")
+		m.objects[apObjNum] = joinStreamObject([]byte(dictStr), data, true)
+	}
+	sx, sy := (rect[2]-rect[0])/boxWidth, (rect[3]-rect[1])/boxHeight
+	return [6]float64{sx, 0, 0, sy, rect[0] - sx*box[0], rect[1] - sy*box[1]}, true
+}
+
+// fieldTree returns the object numbers of the fields of an AcroForm dictionary
+// and all their descendants
+func (m *PDFManipulator) fieldTree(acroForm string) map[int]bool {
+	fields := make(map[int]bool)
+	var visit func(array string)
+	visit = func(array string) {
+		if !strings.HasPrefix(array, "[") {
+			objNum, err := parseObjectRef(array)
+			if err != nil {
+				return
+			}
+			array = string(objectBody(m.objects[objNum]))
+		}
+		for _, match := range refPattern.FindAllStringSubmatch(array, -1) {
+			objNum, err := strconv.Atoi(match[1])
+			if err != nil || fields[objNum] {
+				continue
+			}
+			obj, ok := m.objects[objNum]
+			if !ok {
+				continue
+			}
+			fields[objNum] = true
+			if kids := rawDictValue(string(objectBody(obj)), "/Kids"); kids != "" {
+				visit(kids)
+			}
+		}
+	}
+	visit(rawDictValue(acroForm, "/Fields"))
+	return fields
+}
@@ -0,0 +1,116 @@
+package manipulate
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/core/write"
+)
+
+// createFlattenPDF builds a page with a filled text field, a check box switched
+// on, a hidden text field and a link. The text appearance has a bounding box
+// half the size of its widget, so it is drawn scaled.
+func createFlattenPDF(t *testing.T) []byte {
+	t.Helper()
+	writer := write.NewPDFWriter()
+	catalogNum := writer.AddObject(nil)
+	pagesNum := writer.AddObject(nil)
+	pageNum := writer.AddObject(nil)
+	fontNum := writer.AddObject([]byte("<</Type/Font/Subtype/Type1/BaseFont/Helvetica>>"))
+
+	stream := func(dict, content string) int {
+		return writer.AddObject([]byte(fmt.Sprintf("<<%s/Length %d>>\nstream\n%s\nendstream", dict, len(content), content)))
+	}
+	textAP := stream(fmt.Sprintf("/Type/XObject/Subtype/Form/BBox [0 0 100 10]/Resources <</Font <</Helv %d 0 R>>>>", fontNum),
+		"/Tx BMC\nBT\n/Helv 8 Tf\n2 2 Td\n(Jane Doe) Tj\nET\nEMC")
+	onAP := stream("/BBox [0 0 14 14]", "0 0 m 14 14 l S")
+	offAP := stream("/Subtype/Form/BBox [0 0 14 14]", "")
+	hiddenAP := stream(fmt.Sprintf("/Subtype/Form/BBox [0 0 100 10]/Resources <</Font <</Helv %d 0 R>>>>", fontNum),
+		"BT\n/Helv 8 Tf\n(Secret) Tj\nET")
+
+	nameNum := writer.AddObject([]byte(fmt.Sprintf("<</Type/Annot/Subtype/Widget/FT/Tx/T(name)/V(Jane Doe)/Rect [100 700 300 720]/P %d 0 R/AP <</N %d 0 R>>>>", pageNum, textAP)))
+	agreeNum := writer.AddObject([]byte(fmt.Sprintf("<</Type/Annot/Subtype/Widget/FT/Btn/T(agree)/V/Yes/AS/Yes/Rect [100 650 114 664]/P %d 0 R/AP <</N <</Yes %d 0 R/Off %d 0 R>>>>>>", pageNum, onAP, offAP)))
+	hiddenNum := writer.AddObject([]byte(fmt.Sprintf("<</Type/Annot/Subtype/Widget/FT/Tx/T(internal)/F 2/Rect [100 600 300 620]/P %d 0 R/AP <</N %d 0 R>>>>", pageNum, hiddenAP)))
+	linkNum := writer.AddObject([]byte("<</Type/Annot/Subtype/Link/Rect [100 550 200 560]/A <</S/URI/URI(https://example.com)>>>>"))
+
+	content := "BT\n/F1 12 Tf\n72 740 Td\n(Application) Tj\nET\n"
+	contentNum := writer.AddObject([]byte(fmt.Sprintf("<</Length %d>>\nstream\n%s\nendstream", len(content), content)))
+	writer.SetObject(pageNum, []byte(fmt.Sprintf("<</Type/Page/Parent %d 0 R/Contents %d 0 R/Annots [%d 0 R %d 0 R %d 0 R %d 0 R]>>",
+		pagesNum, contentNum, nameNum, agreeNum, hiddenNum, linkNum)))
+	writer.SetObject(pagesNum, []byte(fmt.Sprintf("<</Type/Pages/Kids [%d 0 R]/Count 1/MediaBox [0 0 612 792]/Resources <</Font <</F1 %d 0 R>>>>>>", pageNum, fontNum)))
+	acroFormNum := writer.AddObject([]byte(fmt.Sprintf("<</Fields [%d 0 R %d 0 R %d 0 R]/NeedAppearances true>>", nameNum, agreeNum, hiddenNum)))
+	writer.SetObject(catalogNum, []byte(fmt.Sprintf("<</Type/Catalog/Pages %d 0 R/AcroForm %d 0 R>>", pagesNum, acroFormNum)))
+	writer.SetRoot(catalogNum)
+
+	pdfBytes, err := writer.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create test PDF: %v", err)
+	}
+	return pdfBytes
+}
+
+func TestFlattenForm(t *testing.T) {
+	pdfBytes := createFlattenPDF(t)
+
+	m, err := NewPDFManipulator(pdfBytes, nil, false)
+	if err != nil {
+		t.Fatalf("NewPDFManipulator failed: %v", err)
+	}
+	drawn, err := m.FlattenForm()
+	if err != nil {
+		t.Fatalf("FlattenForm failed: %v", err)
+	}
+	if drawn != 2 {
+		t.Errorf("Expected 2 widgets drawn, got %d", drawn)
+	}
+	out, err := m.Rebuild()
+	if err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+
+	pdf, err := parse.Open(out)
+	if err != nil {
+		t.Fatalf("Failed to parse flattened PDF: %v", err)
+	}
+	flattened := ""
+	for _, objNum := range pdf.Objects() {
+		obj, _ := pdf.GetObject(objNum)
+		s := string(obj)
+		if strings.Contains(s, "/Widget") || strings.Contains(s, "/AcroForm") {
+			t.Errorf("Object %d should have been removed with the form: %s", objNum, s)
+		}
+		if strings.Contains(s, "/Type/Page/") || strings.Contains(s, "/Type /Page ") {
+			if annots := extractDictValue(s, "/Annots"); strings.Count(annots, " R") != 1 {
+				t.Errorf("Only the link should be left in /Annots, got %s", annots)
+			}
+		}
+		if strings.Contains(s, "/BBox [0 0 14 14]") && !strings.Contains(s, "/Subtype /Form") && !strings.Contains(s, "/Subtype/Form") {
+			t.Errorf("Drawn appearance should be a form XObject: %s", s)
+		}
+		if strings.Contains(s, " Do\n") {
+			flattened = s
+		}
+	}
+
+	// The text appearance is scaled from its 100x10 box to the 200x20 widget, the
+	// check box shows its on state, and the hidden field is not drawn
+	for _, want := range []string{"2 0 0 2 100 700 cm\n/FmFlat Do", "1 0 0 1 100 650 cm\n/FmFlat1 Do"} {
+		if !strings.Contains(flattened, want) {
+			t.Errorf("Flattened content missing %q: %s", want, flattened)
+		}
+	}
+	if n := strings.Count(flattened, " Do\n"); n != 2 {
+		t.Errorf("Expected 2 appearances drawn, got %d: %s", n, flattened)
+	}
+
+	doc, err := extract.ExtractContent(out, nil, false)
+	if err != nil {
+		t.Fatalf("Failed to extract flattened PDF: %v", err)
+	}
+	if len(doc.Pages) != 1 || len(doc.Pages[0].Text) == 0 || doc.Pages[0].Text[0].Text != "Application" {
+		t.Errorf("Page should keep its own content, got %+v", doc.Pages)
+	}
+}
@@ -0,0 +1,350 @@
+package manipulate
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/core/encrypt"
+)
+
+// Flags for FormAction.Flags (ISO 32000-1 Tables 237 and 239)
+const (
+	SubmitExclude              = 1 << 0  // Submit every field except those in Fields
+	SubmitIncludeNoValueFields = 1 << 1  // Submit fields without a value
+	SubmitExportFormat         = 1 << 2  // Submit as an HTML form instead of FDF
+	SubmitGetMethod            = 1 << 3  // Use HTTP GET instead of POST (HTML format)
+	SubmitCoordinates          = 1 << 4  // Submit the mouse coordinates of the click
+	SubmitXFDF                 = 1 << 5  // Submit as XFDF
+	SubmitIncludeAppendSaves   = 1 << 6  // Include incremental updates (FDF)
+	SubmitIncludeAnnotations   = 1 << 7  // Include annotations (FDF)
+	SubmitPDF                  = 1 << 8  // Submit the whole document as PDF
+	SubmitCanonicalFormat      = 1 << 9  // Submit dates in the canonical format
+	SubmitExclNonUserAnnots    = 1 << 10 // Include only annotations by the current user
+	SubmitExclFKey             = 1 << 11 // Omit the F entry of the FDF
+	SubmitEmbedForm            = 1 << 13 // Embed the form in the FDF
+
+	ResetExclude = 1 << 0 // Reset every field except those in Fields
+)
+
+// Form action types
+const (
+	FormActionSubmit = "SubmitForm"
+	FormActionReset  = "ResetForm"
+)
+
+var formActionPattern = regexp.MustCompile(`/S\s*/(SubmitForm|ResetForm)\b`)
+
+// FormAction is a SubmitForm or ResetForm action found in a document, on a
+// button, a link, a page or the document's open action
+type FormAction struct {
+	ObjectNum int      // Object containing the action dictionary
+	Type      string   // FormActionSubmit or FormActionReset
+	URL       string   // Submit target; empty for ResetForm
+	Flags     int      // Submit* or ResetExclude flags
+	Fields    []string // Fields submitted or reset: names, or references such as "12 0 R"; empty for all fields
+}
+
+// FormActions returns the SubmitForm and ResetForm actions of the document,
+// ordered by object number
+func (m *PDFManipulator) FormActions() []FormAction {
+	var actions []FormAction
+	for _, objNum := range m.sortedObjectNumbers() {
+		content := dictPart(string(m.objects[objNum]))
+		for _, loc := range formActionDicts(content) {
+			actions = append(actions, m.parseFormAction(objNum, content[loc[0]:loc[1]]))
+		}
+	}
+	return actions
+}
+
+// RewriteFormActions calls rewrite for every SubmitForm and ResetForm action of
+// the document. rewrite may change the action's URL, Flags and Fields and
+// returns true to write the changes back. It returns the number of actions
+// changed.
+func (m *PDFManipulator) RewriteFormActions(rewrite func(action *FormAction) bool) (int, error) {
+	changed := 0
+	for _, objNum := range m.sortedObjectNumbers() {
+		obj := string(m.objects[objNum])
+		// Actions are rewritten one at a time, since a change shifts the ones after it
+		for i := 0; ; i++ {
+			content := dictPart(obj)
+			locs := formActionDicts(content)
+			if i >= len(locs) {
+				break
+			}
+			loc := locs[i]
+			action := m.parseFormAction(objNum, content[loc[0]:loc[1]])
+			original := action
+			original.Fields = append([]string(nil), action.Fields...)
+			if !rewrite(&action) || formActionsEqual(original, action) {
+				continue
+			}
+			dict, err := m.updateFormAction(content[loc[0]:loc[1]], original, action)
+			if err != nil {
+				return changed, fmt.Errorf("failed to rewrite action in object %d: %w", objNum, err)
+			}
+			obj = obj[:loc[0]] + dict + obj[loc[1]:]
+			changed++
+		}
+		m.objects[objNum] = []byte(obj)
+	}
+	if m.verbose {
+		fmt.Printf("Rewrote %d form actions\n", changed)
+	}
+	return changed, nil
+}
+
+// ReplaceSubmitURLs points every SubmitForm action whose URL starts with
+// oldPrefix at newPrefix instead, keeping the rest of the URL. It returns the
+// rewritten document and the number of actions changed.
+func ReplaceSubmitURLs(pdfBytes, password []byte, oldPrefix, newPrefix string, verbose bool) ([]byte, int, error) {
+	if oldPrefix == "" {
+		return nil, 0, fmt.Errorf("URL prefix to replace is empty")
+	}
+	m, err := NewPDFManipulator(pdfBytes, password, verbose)
+	if err != nil {
+		return nil, 0, err
+	}
+	changed, err := m.RewriteFormActions(func(action *FormAction) bool {
+		if action.Type != FormActionSubmit || !strings.HasPrefix(action.URL, oldPrefix) {
+			return false
+		}
+		action.URL = newPrefix + strings.TrimPrefix(action.URL, oldPrefix)
+		return true
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	out, err := m.Rebuild()
+	if err != nil {
+		return nil, 0, err
+	}
+	return out, changed, nil
+}
+
+// sortedObjectNumbers returns the numbers of the document's objects in order
+func (m *PDFManipulator) sortedObjectNumbers() []int {
+	objNums := make([]int, 0, len(m.objects))
+	for objNum := range m.objects {
+		objNums = append(objNums, objNum)
+	}
+	sort.Ints(objNums)
+	return objNums
+}
+
+// formActionDicts returns the start and end of every SubmitForm and ResetForm
+// action dictionary in object syntax, in order
+func formActionDicts(content string) [][2]int {
+	var locs [][2]int
+	for _, match := range formActionPattern.FindAllStringIndex(content, -1) {
+		// The innermost dictionary enclosing the /S entry
+		for start := strings.LastIndex(content[:match[0]], "<<"); start != -1; start = strings.LastIndex(content[:start], "<<") {
+			if dict := balancedDict(content[start:]); start+len(dict) > match[0] {
+				locs = append(locs, [2]int{start, start + len(dict)})
+				break
+			}
+		}
+	}
+	return locs
+}
+
+// parseFormAction reads a SubmitForm or ResetForm action dictionary
+func (m *PDFManipulator) parseFormAction(objNum int, dict string) FormAction {
+	action := FormAction{ObjectNum: objNum, Type: formActionPattern.FindStringSubmatch(dict)[1]}
+	if flags, err := strconv.Atoi(topLevelValue(dict, "/Flags")); err == nil {
+		action.Flags = flags
+	}
+
+	if action.Type == FormActionSubmit {
+		f := topLevelValue(dict, "/F")
+		if refPattern.MatchString(f) && !strings.HasPrefix(f, "<<") {
+			if specObjNum, err := parseObjectRef(f); err == nil {
+				f = dictPart(string(objectBody(m.objects[specObjNum])))
+			}
+		}
+		if strings.HasPrefix(f, "<<") {
+			f = topLevelValue(f, "/F")
+		}
+		action.URL = decodedString(f)
+	}
+
+	fields := topLevelValue(dict, "/Fields")
+	if !strings.HasPrefix(fields, "[") {
+		if arrayObjNum, err := parseObjectRef(fields); err == nil {
+			fields = string(objectBody(m.objects[arrayObjNum]))
+		}
+	}
+	if strings.HasPrefix(fields, "[") {
+		action.Fields = arrayFieldNames(fields)
+	}
+	return action
+}
+
+// updateFormAction writes the changes between two versions of an action into its
+// dictionary. A URL in an indirect file specification is updated in place.
+func (m *PDFManipulator) updateFormAction(dict string, original, action FormAction) (string, error) {
+	if action.URL != original.URL {
+		if action.Type != FormActionSubmit {
+			return "", fmt.Errorf("%s actions have no URL", action.Type)
+		}
+		url := "(" + encodeStampText(action.URL) + ")"
+		switch f := topLevelValue(dict, "/F"); {
+		case strings.HasPrefix(f, "<<"):
+			dict = setTopLevelValue(dict, "/F", setFileSpecURL(f, url))
+		case refPattern.MatchString(f):
+			specObjNum, err := parseObjectRef(f)
+			if err != nil {
+				return "", err
+			}
+			spec, ok := m.objects[specObjNum]
+			if !ok {
+				return "", fmt.Errorf("file specification %d not found", specObjNum)
+			}
+			m.objects[specObjNum] = []byte(setFileSpecURL(string(objectBody(spec)), url))
+		case f == "":
+			dict = setTopLevelValue(dict, "/F", "<< /FS /URL /F "+url+" >>")
+		default:
+			dict = setTopLevelValue(dict, "/F", url)
+		}
+	}
+
+	if action.Flags != original.Flags {
+		if action.Flags == 0 {
+			dict = removeTopLevelKey(dict, "/Flags")
+		} else {
+			dict = setTopLevelValue(dict, "/Flags", strconv.Itoa(action.Flags))
+		}
+	}
+
+	if strings.Join(action.Fields, "\x00") != strings.Join(original.Fields, "\x00") {
+		if len(action.Fields) == 0 {
+			dict = removeTopLevelKey(dict, "/Fields")
+		} else {
+			entries := make([]string, len(action.Fields))
+			for i, field := range action.Fields {
+				if refPattern.MatchString(field) && strings.HasSuffix(field, "R") {
+					entries[i] = field
+				} else {
+					entries[i] = "(" + encodeStampText(field) + ")"
+				}
+			}
+			dict = setTopLevelValue(dict, "/Fields", "["+strings.Join(entries, " ")+"]")
+		}
+	}
+	return dict, nil
+}
+
+// formActionsEqual reports whether two versions of an action are the same
+func formActionsEqual(a, b FormAction) bool {
+	return a.URL == b.URL && a.Flags == b.Flags && strings.Join(a.Fields, "\x00") == strings.Join(b.Fields, "\x00")
+}
+
+// setFileSpecURL sets the URL of a URL file specification dictionary
+func setFileSpecURL(spec, url string) string {
+	spec = setTopLevelValue(spec, "/F", url)
+	if topLevelValue(spec, "/UF") != "" {
+		spec = setTopLevelValue(spec, "/UF", url)
+	}
+	return spec
+}
+
+// arrayFieldNames returns the entries of a /Fields array: decoded names and
+// references, in order
+func arrayFieldNames(array string) []string {
+	type entry struct {
+		pos   int
+		value string
+	}
+	var entries []entry
+	if tokens, err := encrypt.FindStrings([]byte(array)); err == nil {
+		for _, token := range tokens {
+			entries = append(entries, entry{token.Start, string(token.Value)})
+		}
+	}
+	for _, loc := range refPattern.FindAllStringIndex(array, -1) {
+		entries = append(entries, entry{loc[0], array[loc[0]:loc[1]]})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].pos < entries[j].pos })
+
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.value
+	}
+	return names
+}
+
+// decodedString returns the value of a PDF string at the start of s, or ""
+func decodedString(s string) string {
+	tokens, err := encrypt.FindStrings([]byte(s))
+	if err != nil || len(tokens) == 0 || tokens[0].Start != 0 {
+		return ""
+	}
+	return string(tokens[0].Value)
+}
+
+// topLevelKeyIndex returns the index of a key in a dictionary's own entries,
+// ignoring keys of nested dictionaries, or -1
+func topLevelKeyIndex(dict, key string) int {
+	masked := []byte(dict)
+	depth := 0
+	for i := 0; i+1 < len(masked); i++ {
+		switch {
+		case masked[i] == '<' && masked[i+1] == '<':
+			depth++
+			i++
+		case masked[i] == '>' && masked[i+1] == '>':
+			depth--
+			i++
+		case depth > 1:
+			masked[i] = ' '
+		}
+	}
+	return rawDictKeyIndex(string(masked), key)
+}
+
+// topLevelValue returns the raw value of a key in a dictionary's own entries
+func topLevelValue(dict, key string) string {
+	idx := topLevelKeyIndex(dict, key)
+	if idx == -1 {
+		return ""
+	}
+	// A string value is returned whole, which rawDictValue doesn't do
+	rest := strings.TrimLeft(dict[idx+len(key):], " \t\r\n")
+	if strings.HasPrefix(rest, "(") || (strings.HasPrefix(rest, "<") && !strings.HasPrefix(rest, "<<")) {
+		if tokens, err := encrypt.FindStrings([]byte(rest)); err == nil && len(tokens) > 0 && tokens[0].Start == 0 {
+			return rest[:tokens[0].End]
+		}
+	}
+	return rawDictValue(dict[idx:], key)
+}
+
+// setTopLevelValue sets a key in a dictionary's own entries
+func setTopLevelValue(dict, key, value string) string {
+	idx := topLevelKeyIndex(dict, key)
+	if idx == -1 {
+		end := strings.LastIndex(dict, ">>")
+		if end == -1 {
+			return dict
+		}
+		return dict[:end] + key + " " + value + " " + dict[end:]
+	}
+	old := topLevelValue(dict, key)
+	rest := strings.TrimLeft(dict[idx+len(key):], " \t\r\n")
+	start := len(dict) - len(rest)
+	return dict[:idx] + key + " " + value + dict[start+len(old):]
+}
+
+// removeTopLevelKey removes a key and its value from a dictionary's own entries
+func removeTopLevelKey(dict, key string) string {
+	idx := topLevelKeyIndex(dict, key)
+	if idx == -1 {
+		return dict
+	}
+	old := topLevelValue(dict, key)
+	rest := strings.TrimLeft(dict[idx+len(key):], " \t\r\n")
+	start := len(dict) - len(rest)
+	return dict[:idx] + dict[start+len(old):]
+}
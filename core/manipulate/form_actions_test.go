@@ -0,0 +1,111 @@
+package manipulate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+)
+
+// createFormActionsPDF creates a document with submit and reset buttons
+func createFormActionsPDF(t *testing.T) []byte {
+	t.Helper()
+	writer := write.NewPDFWriter()
+	writer.AddObject([]byte("<</Type/Page/Parent 2 0 R/MediaBox [0 0 612 792]/Annots [4 0 R 5 0 R 6 0 R]>>"))
+	writer.AddObject([]byte("<</Type/Pages/Kids [1 0 R]/Count 1>>"))
+	writer.AddObject([]byte("<</FS/URL/F (https://old.example.com/forms/submit)>>"))
+	writer.AddObject([]byte("<</Type/Annot/Subtype/Widget/FT/Btn/T (submit)/Rect [0 0 10 10]/A <</S/SubmitForm/F (https://old.example.com/forms/a)/Flags 4/Fields [(name) 7 0 R]>>>>"))
+	writer.AddObject([]byte("<</Type/Annot/Subtype/Widget/FT/Btn/T (send)/Rect [0 0 10 10]/A <</S /SubmitForm /F 3 0 R /Next <</S/ResetForm/Flags 1/Fields [(keep)]>>>>>>"))
+	writer.AddObject([]byte("<</Type/Annot/Subtype/Widget/FT/Btn/T (other)/Rect [0 0 10 10]/A <</S/SubmitForm/F <</FS/URL/F (https://other.example.com/x)>>>>>>"))
+	writer.AddObject([]byte("<</FT/Tx/T (name)>>"))
+	writer.SetRoot(writer.AddObject([]byte("<</Type/Catalog/Pages 2 0 R>>")))
+	pdfBytes, err := writer.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create test PDF: %v", err)
+	}
+	return pdfBytes
+}
+
+func TestFormActions(t *testing.T) {
+	m, err := NewPDFManipulator(createFormActionsPDF(t), nil, false)
+	if err != nil {
+		t.Fatalf("Failed to create manipulator: %v", err)
+	}
+	actions := m.FormActions()
+	if len(actions) != 4 {
+		t.Fatalf("Expected 4 actions, got %+v", actions)
+	}
+	want := []FormAction{
+		{ObjectNum: 4, Type: FormActionSubmit, URL: "https://old.example.com/forms/a", Flags: SubmitExportFormat, Fields: []string{"name", "7 0 R"}},
+		{ObjectNum: 5, Type: FormActionSubmit, URL: "https://old.example.com/forms/submit"},
+		{ObjectNum: 5, Type: FormActionReset, Flags: ResetExclude, Fields: []string{"keep"}},
+		{ObjectNum: 6, Type: FormActionSubmit, URL: "https://other.example.com/x"},
+	}
+	for i, action := range actions {
+		if action.ObjectNum != want[i].ObjectNum || action.Type != want[i].Type || !formActionsEqual(action, want[i]) {
+			t.Errorf("Action %d = %+v, want %+v", i, action, want[i])
+		}
+	}
+
+	changed, err := m.RewriteFormActions(func(action *FormAction) bool {
+		switch action.Type {
+		case FormActionReset:
+			action.Fields = nil
+			action.Flags = 0
+		case FormActionSubmit:
+			if action.ObjectNum != 4 {
+				return false
+			}
+			action.Flags |= SubmitXFDF
+			action.Fields = []string{"7 0 R", "email"}
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatalf("RewriteFormActions failed: %v", err)
+	}
+	if changed != 2 {
+		t.Errorf("Changed %d actions, want 2", changed)
+	}
+	actions = m.FormActions()
+	if a := actions[0]; a.Flags != SubmitExportFormat|SubmitXFDF || strings.Join(a.Fields, ",") != "7 0 R,email" || a.URL != want[0].URL {
+		t.Errorf("Rewritten submit action = %+v", a)
+	}
+	if a := actions[2]; a.Flags != 0 || len(a.Fields) != 0 {
+		t.Errorf("Rewritten reset action = %+v", a)
+	}
+	if a := actions[1]; a.URL != want[1].URL {
+		t.Errorf("Outer action changed with its /Next action: %+v", a)
+	}
+}
+
+func TestReplaceSubmitURLs(t *testing.T) {
+	out, changed, err := ReplaceSubmitURLs(createFormActionsPDF(t), nil, "https://old.example.com/", "https://new.example.com/v2/", false)
+	if err != nil {
+		t.Fatalf("ReplaceSubmitURLs failed: %v", err)
+	}
+	if changed != 2 {
+		t.Errorf("Changed %d actions, want 2", changed)
+	}
+	if strings.Contains(string(out), "old.example.com") {
+		t.Error("Old URL left in the document")
+	}
+
+	m, err := NewPDFManipulator(out, nil, false)
+	if err != nil {
+		t.Fatalf("Failed to open output: %v", err)
+	}
+	var urls []string
+	for _, action := range m.FormActions() {
+		if action.Type == FormActionSubmit {
+			urls = append(urls, action.URL)
+		}
+	}
+	if got := strings.Join(urls, " "); got != "https://new.example.com/v2/forms/a https://new.example.com/v2/forms/submit https://other.example.com/x" {
+		t.Errorf("URLs = %s", got)
+	}
+
+	if _, _, err := ReplaceSubmitURLs(createFormActionsPDF(t), nil, "", "x", false); err == nil {
+		t.Error("Expected error for an empty prefix")
+	}
+}
@@ -0,0 +1,322 @@
+package manipulate
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// pageBoxKeys are the page boundary boxes transformed with the page content
+var pageBoxKeys = []string{"/MediaBox", "/CropBox", "/BleedBox", "/TrimBox", "/ArtBox"}
+
+// annotationPointKeys are annotation arrays of x/y coordinate pairs
+var annotationPointKeys = []string{"/QuadPoints", "/Vertices"}
+
+var (
+	contentsEntryPattern = regexp.MustCompile(`/Contents\s*(\d+\s+\d+\s+R|\[[^\]]*\])`)
+	refPattern           = regexp.MustCompile(`(\d+)\s+(\d+)\s+R`)
+	parentRefPattern     = regexp.MustCompile(`/Parent\s+(\d+)\s+\d+\s+R`)
+)
+
+// ScalePage scales a page's content, page boxes and annotations by sx and sy
+// pageNumber is 1-based (first page is 1)
+func (m *PDFManipulator) ScalePage(pageNumber int, sx, sy float64) error {
+	if sx <= 0 || sy <= 0 {
+		return fmt.Errorf("scale factors must be positive, got %g x %g", sx, sy)
+	}
+	return m.TransformPage(pageNumber, [6]float64{sx, 0, 0, sy, 0, 0})
+}
+
+// TransformPage applies the affine matrix [a b c d e f] to a page's content, page boxes
+// and annotations. Annotation rectangles and form widget positions are moved with the
+// content so fields stay aligned on the output page.
+// pageNumber is 1-based (first page is 1)
+func (m *PDFManipulator) TransformPage(pageNumber int, matrix [6]float64) error {
+	if matrix[0]*matrix[3]-matrix[1]*matrix[2] == 0 {
+		return fmt.Errorf("transformation matrix is not invertible")
+	}
+
+	pageObjNum, err := m.getPageObjectNumber(pageNumber)
+	if err != nil {
+		return fmt.Errorf("failed to get page object: %w", err)
+	}
+
+	pageObj, ok := m.objects[pageObjNum]
+	if !ok {
+		return fmt.Errorf("page object %d not found", pageObjNum)
+	}
+	pageStr := string(pageObj)
+
+	// Page boxes; inherited boxes are written onto the page so siblings are unaffected
+	for _, key := range pageBoxKeys {
+		box := parseNumberArray(m.inheritedPageValue(pageStr, key))
+		if len(box) < 4 {
+			continue
+		}
+		pageStr = setDictValue(pageStr, key, formatNumberArray(transformRect(matrix, box)))
+	}
+
+	// Wrap the existing content between a q/cm prologue and a Q epilogue
+	if match := contentsEntryPattern.FindStringSubmatch(pageStr); match != nil {
+		existing := strings.TrimSuffix(strings.TrimPrefix(match[1], "["), "]")
+
+		prologue := fmt.Sprintf("q\n%s cm\n", formatNumbers(matrix[:]))
+		preObjNum := m.nextObjectNumber()
+		m.objects[preObjNum] = rawStreamObject(prologue)
+		postObjNum := m.nextObjectNumber()
+		m.objects[postObjNum] = rawStreamObject("\nQ\n")
+
+		contents := fmt.Sprintf("/Contents [%d 0 R %s %d 0 R]", preObjNum, strings.TrimSpace(existing), postObjNum)
+		pageStr = strings.Replace(pageStr, match[0], contents, 1)
+	}
+
+	m.objects[pageObjNum] = []byte(pageStr)
+
+	// Annotations (including form widgets)
+	for _, annotObjNum := range m.pageAnnotations(pageStr) {
+		annotObj, ok := m.objects[annotObjNum]
+		if !ok {
+			continue
+		}
+		m.objects[annotObjNum] = []byte(transformAnnotation(string(annotObj), matrix))
+	}
+
+	if m.verbose {
+		fmt.Printf("Transformed page %d by [%s]\n", pageNumber, formatNumbers(matrix[:]))
+	}
+
+	return nil
+}
+
+// pageAnnotations returns the object numbers of a page's annotations
+func (m *PDFManipulator) pageAnnotations(pageStr string) []int {
+	annots := extractDictValue(pageStr, "/Annots")
+	if annots == "" {
+		return nil
+	}
+	if !strings.HasPrefix(annots, "[") {
+		// Indirect array: "/Annots 12 0 R"
+		match := regexp.MustCompile(`/Annots\s+(\d+)\s+\d+\s+R`).FindStringSubmatch(pageStr)
+		if match == nil {
+			return nil
+		}
+		arrObjNum, _ := strconv.Atoi(match[1])
+		arrObj, ok := m.objects[arrObjNum]
+		if !ok {
+			return nil
+		}
+		annots = string(arrObj)
+		if idx := strings.Index(annots, "obj"); idx != -1 {
+			annots = annots[idx+3:]
+		}
+	}
+
+	var objNums []int
+	for _, match := range refPattern.FindAllStringSubmatch(annots, -1) {
+		if n, err := strconv.Atoi(match[1]); err == nil {
+			objNums = append(objNums, n)
+		}
+	}
+	return objNums
+}
+
+// inheritablePageKeys are the page attributes a page may inherit from its ancestors
+var inheritablePageKeys = []string{"/Resources", "/MediaBox", "/CropBox", "/Rotate"}
+
+// inheritedPageValue returns a page attribute's raw value, following /Parent links for inherited values
+func (m *PDFManipulator) inheritedPageValue(pageStr, key string) string {
+	nodeStr := pageStr
+	for depth := 0; depth < 64; depth++ {
+		if value := rawDictValue(nodeStr, key); value != "" {
+			return value
+		}
+		match := parentRefPattern.FindStringSubmatch(nodeStr)
+		if match == nil {
+			return ""
+		}
+		parentObjNum, _ := strconv.Atoi(match[1])
+		parent, ok := m.objects[parentObjNum]
+		if !ok {
+			return ""
+		}
+		nodeStr = string(parent)
+	}
+	return ""
+}
+
+// materializeInheritedAttributes copies inherited attributes onto a page so it can be
+// moved out of its page tree without losing its resources or geometry
+func (m *PDFManipulator) materializeInheritedAttributes(pageStr string) string {
+	for _, key := range inheritablePageKeys {
+		if rawDictValue(pageStr, key) != "" {
+			continue
+		}
+		if value := m.inheritedPageValue(pageStr, key); value != "" {
+			pageStr = setDictValue(pageStr, key, value)
+		}
+	}
+	return pageStr
+}
+
+// rawDictValue returns the raw value following a key: a reference, an array,
+// a nested dictionary, or a single token
+func rawDictValue(dictStr, key string) string {
+	idx := rawDictKeyIndex(dictStr, key)
+	if idx == -1 {
+		return ""
+	}
+
+	rest := strings.TrimLeft(dictStr[idx+len(key):], " \t\r\n")
+	switch {
+	case strings.HasPrefix(rest, "<<"):
+		return balancedDict(rest)
+	case strings.HasPrefix(rest, "["):
+		if end := strings.Index(rest, "]"); end != -1 {
+			return rest[:end+1]
+		}
+		return ""
+	}
+	if match := refPattern.FindStringIndex(rest); match != nil && match[0] == 0 {
+		return rest[:match[1]]
+	}
+	end := 0
+	for end < len(rest) && !strings.ContainsRune(" \t\r\n/<>[]()", rune(rest[end])) {
+		end++
+	}
+	if end == 0 && strings.HasPrefix(rest, "/") {
+		end = 1
+		for end < len(rest) && !strings.ContainsRune(" \t\r\n/<>[]()", rune(rest[end])) {
+			end++
+		}
+	}
+	return rest[:end]
+}
+
+// rawDictKeyIndex returns the index of key in dictStr where it is a whole name
+// (so "/Font" does not match "/FontFile"), or -1
+func rawDictKeyIndex(dictStr, key string) int {
+	idx := strings.Index(dictStr, key)
+	for idx != -1 {
+		next := idx + len(key)
+		if next >= len(dictStr) || strings.ContainsRune(" \t\r\n/<[(", rune(dictStr[next])) {
+			return idx
+		}
+		rel := strings.Index(dictStr[next:], key)
+		if rel == -1 {
+			return -1
+		}
+		idx = next + rel
+	}
+	return -1
+}
+
+// setRawDictValue replaces the raw value of key, whatever its type, or adds the key
+// before the closing ">>" if it is missing
+func setRawDictValue(dictStr, key, value string) string {
+	idx := rawDictKeyIndex(dictStr, key)
+	if idx == -1 {
+		lastIdx := strings.LastIndex(dictStr, ">>")
+		if lastIdx == -1 {
+			return dictStr
+		}
+		return dictStr[:lastIdx] + key + " " + value + dictStr[lastIdx:]
+	}
+
+	old := rawDictValue(dictStr, key)
+	rest := strings.TrimLeft(dictStr[idx+len(key):], " \t\r\n")
+	start := len(dictStr) - len(rest)
+	return dictStr[:idx+len(key)] + " " + value + dictStr[start+len(old):]
+}
+
+// removeRawDictKey removes key and its value from dictStr
+func removeRawDictKey(dictStr, key string) string {
+	idx := rawDictKeyIndex(dictStr, key)
+	if idx == -1 {
+		return dictStr
+	}
+	old := rawDictValue(dictStr, key)
+	rest := strings.TrimLeft(dictStr[idx+len(key):], " \t\r\n")
+	start := len(dictStr) - len(rest)
+	return dictStr[:idx] + dictStr[start+len(old):]
+}
+
+// transformAnnotation transforms an annotation's /Rect and coordinate arrays
+func transformAnnotation(annotStr string, matrix [6]float64) string {
+	if rect := parseNumberArray(extractDictValue(annotStr, "/Rect")); len(rect) >= 4 {
+		annotStr = setDictValue(annotStr, "/Rect", formatNumberArray(transformRect(matrix, rect)))
+	}
+	for _, key := range annotationPointKeys {
+		points := parseNumberArray(extractDictValue(annotStr, key))
+		if len(points) < 2 || len(points)%2 != 0 {
+			continue
+		}
+		for i := 0; i+1 < len(points); i += 2 {
+			points[i], points[i+1] = transformPoint(matrix, points[i], points[i+1])
+		}
+		annotStr = setDictValue(annotStr, key, formatNumberArray(points))
+	}
+	return annotStr
+}
+
+// transformPoint applies matrix to a point
+func transformPoint(matrix [6]float64, x, y float64) (float64, float64) {
+	return matrix[0]*x + matrix[2]*y + matrix[4], matrix[1]*x + matrix[3]*y + matrix[5]
+}
+
+// transformRect returns the normalized bounding box of a transformed rectangle
+func transformRect(matrix [6]float64, rect []float64) []float64 {
+	corners := [][2]float64{
+		{rect[0], rect[1]}, {rect[2], rect[1]},
+		{rect[0], rect[3]}, {rect[2], rect[3]},
+	}
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, c := range corners {
+		x, y := transformPoint(matrix, c[0], c[1])
+		minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+		minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+	}
+	return []float64{minX, minY, maxX, maxY}
+}
+
+// parseNumberArray parses a flat array value like "[0 0 612 792]"
+func parseNumberArray(arrStr string) []float64 {
+	arrStr = strings.TrimSpace(arrStr)
+	if !strings.HasPrefix(arrStr, "[") {
+		return nil
+	}
+	var values []float64
+	for _, part := range strings.Fields(strings.Trim(arrStr, "[]")) {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return nil
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+// formatNumberArray formats numbers as a PDF array
+func formatNumberArray(values []float64) string {
+	return "[" + formatNumbers(values) + "]"
+}
+
+// formatNumbers formats numbers separated by spaces, without trailing zeros
+func formatNumbers(values []float64) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		v = math.Round(v*10000) / 10000
+		if v == 0 {
+			v = 0 // normalize negative zero
+		}
+		parts[i] = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return strings.Join(parts, " ")
+}
+
+// rawStreamObject builds an unfiltered stream object
+func rawStreamObject(data string) []byte {
+	return []byte(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(data), data))
+}
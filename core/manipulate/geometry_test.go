@@ -0,0 +1,187 @@
+package manipulate
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/forms/acroform"
+)
+
+// createFormPDF builds a two-page PDF where each page has one text field widget.
+// MediaBox and Resources are set only on the Pages node.
+func createFormPDF(t *testing.T) []byte {
+	t.Helper()
+
+	writer := write.NewPDFWriter()
+	catalogNum := writer.AddObject(nil)
+	pagesNum := writer.AddObject(nil)
+	fontNum := writer.AddObject([]byte("<</Type/Font/Subtype/Type1/BaseFont/Helvetica>>"))
+
+	var pageRefs, fieldRefs []string
+	for i := 1; i <= 2; i++ {
+		content := fmt.Sprintf("BT\n/F1 12 Tf\n72 700 Td\n(Page %d) Tj\nET\n", i)
+		contentNum := writer.AddObject([]byte(fmt.Sprintf("<</Length %d>>\nstream\n%s\nendstream", len(content), content)))
+		pageNum := writer.NextObjectNumber()
+		widgetNum := pageNum + 1
+		writer.AddObject([]byte(fmt.Sprintf("<</Type/Page/Parent %d 0 R/Contents %d 0 R/Annots [%d 0 R]>>", pagesNum, contentNum, widgetNum)))
+		writer.AddObject([]byte(fmt.Sprintf("<</Type/Annot/Subtype/Widget/FT/Tx/T(field%d)/Rect [100 500 300 520]/QuadPoints [100 520 300 520 100 500 300 500]/P %d 0 R>>", i, pageNum)))
+		pageRefs = append(pageRefs, fmt.Sprintf("%d 0 R", pageNum))
+		fieldRefs = append(fieldRefs, fmt.Sprintf("%d 0 R", widgetNum))
+	}
+
+	writer.SetObject(pagesNum, []byte(fmt.Sprintf("<</Type/Pages/Kids [%s]/Count 2/MediaBox [0 0 600 800]/Resources <</Font <</F1 %d 0 R>>>>>>",
+		strings.Join(pageRefs, " "), fontNum)))
+	acroFormNum := writer.AddObject([]byte(fmt.Sprintf("<</Fields [%s]/DA (/Helv 0 Tf 0 g)>>", strings.Join(fieldRefs, " "))))
+	writer.SetObject(catalogNum, []byte(fmt.Sprintf("<</Type/Catalog/Pages %d 0 R/AcroForm %d 0 R>>", pagesNum, acroFormNum)))
+	writer.SetRoot(catalogNum)
+
+	pdfBytes, err := writer.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create test PDF: %v", err)
+	}
+	return pdfBytes
+}
+
+// widgetRects returns the /Rect of each widget in a PDF, keyed by field name
+func widgetRects(t *testing.T, pdfBytes []byte) map[string][]float64 {
+	t.Helper()
+
+	pdf, err := parse.Open(pdfBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse PDF: %v", err)
+	}
+	rects := make(map[string][]float64)
+	for _, objNum := range pdf.Objects() {
+		obj, err := pdf.GetObject(objNum)
+		if err != nil || !strings.Contains(string(obj), "/Widget") {
+			continue
+		}
+		s := string(obj)
+		name := s[strings.Index(s, "/T(")+3:]
+		name = name[:strings.Index(name, ")")]
+		rects[name] = parseNumberArray(extractDictValue(s, "/Rect"))
+	}
+	return rects
+}
+
+func TestScalePage_TransformsWidgets(t *testing.T) {
+	m, err := NewPDFManipulator(createFormPDF(t), nil, false)
+	if err != nil {
+		t.Fatalf("Failed to create manipulator: %v", err)
+	}
+
+	if err := m.ScalePage(1, 0.5, 0.5); err != nil {
+		t.Fatalf("ScalePage failed: %v", err)
+	}
+	out, err := m.Rebuild()
+	if err != nil {
+		t.Fatalf("Failed to rebuild PDF: %v", err)
+	}
+
+	pdf, err := parse.Open(out)
+	if err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+
+	page1, err := pdf.Page(1)
+	if err != nil {
+		t.Fatalf("Page(1) failed: %v", err)
+	}
+	if fmt.Sprint(page1.MediaBox) != "[0 0 300 400]" {
+		t.Errorf("page 1 MediaBox = %v, want [0 0 300 400]", page1.MediaBox)
+	}
+	if !strings.Contains(page1.Dict, "/Contents [") {
+		t.Errorf("page 1 content not wrapped: %s", page1.Dict)
+	}
+
+	// The inherited MediaBox of the untouched page is unchanged
+	page2, err := pdf.Page(2)
+	if err != nil {
+		t.Fatalf("Page(2) failed: %v", err)
+	}
+	if fmt.Sprint(page2.MediaBox) != "[0 0 600 800]" {
+		t.Errorf("page 2 MediaBox = %v, want [0 0 600 800]", page2.MediaBox)
+	}
+
+	rects := widgetRects(t, out)
+	if got := fmt.Sprint(rects["field1"]); got != "[50 250 150 260]" {
+		t.Errorf("field1 Rect = %s, want [50 250 150 260]", got)
+	}
+	if got := fmt.Sprint(rects["field2"]); got != "[100 500 300 520]" {
+		t.Errorf("field2 Rect = %s, want unchanged", got)
+	}
+}
+
+func TestTransformPage_Rotation(t *testing.T) {
+	m, err := NewPDFManipulator(createFormPDF(t), nil, false)
+	if err != nil {
+		t.Fatalf("Failed to create manipulator: %v", err)
+	}
+
+	// Rotate 90 degrees clockwise and translate back into positive space
+	if err := m.TransformPage(1, [6]float64{0, -1, 1, 0, 0, 600}); err != nil {
+		t.Fatalf("TransformPage failed: %v", err)
+	}
+
+	pageObjNum, _ := m.getPageObjectNumber(1)
+	pageStr := string(m.objects[pageObjNum])
+	if got := extractDictValue(pageStr, "/MediaBox"); got != "[0 0 800 600]" {
+		t.Errorf("MediaBox = %s, want [0 0 800 600]", got)
+	}
+
+	for _, annotObjNum := range m.pageAnnotations(pageStr) {
+		annot := string(m.objects[annotObjNum])
+		if got := extractDictValue(annot, "/Rect"); got != "[500 300 520 500]" {
+			t.Errorf("widget Rect = %s, want [500 300 520 500]", got)
+		}
+		if got := extractDictValue(annot, "/QuadPoints"); got != "[520 500 520 300 500 500 500 300]" {
+			t.Errorf("widget QuadPoints = %s", got)
+		}
+	}
+
+	if err := m.TransformPage(1, [6]float64{1, 0, 2, 0, 0, 0}); err == nil {
+		t.Error("expected error for singular matrix")
+	}
+}
+
+func TestExtractPages_KeepsWidgetsAndInheritedAttributes(t *testing.T) {
+	out, err := ExtractPages(createFormPDF(t), []int{2}, nil, false)
+	if err != nil {
+		t.Fatalf("ExtractPages failed: %v", err)
+	}
+
+	pdf, err := parse.Open(out)
+	if err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+	page, err := pdf.Page(1)
+	if err != nil {
+		t.Fatalf("Page(1) failed: %v", err)
+	}
+	if fmt.Sprint(page.MediaBox) != "[0 0 600 800]" {
+		t.Errorf("MediaBox = %v, want inherited [0 0 600 800]", page.MediaBox)
+	}
+	if !strings.Contains(page.Resources, "/F1") {
+		t.Errorf("Resources = %q, want inherited font", page.Resources)
+	}
+
+	rects := widgetRects(t, out)
+	if got := fmt.Sprint(rects["field2"]); got != "[100 500 300 520]" {
+		t.Errorf("field2 Rect = %s, want [100 500 300 520]", got)
+	}
+
+	form, err := acroform.ParseAcroForm(out, nil, false)
+	if err != nil {
+		t.Fatalf("ParseAcroForm failed: %v", err)
+	}
+	if len(form.Fields) != 1 || form.Fields[0].T != "field2" {
+		names := []string{}
+		for _, f := range form.Fields {
+			names = append(names, f.T)
+		}
+		t.Errorf("fields = %v, want [field2]", names)
+	}
+}
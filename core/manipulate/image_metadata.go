@@ -0,0 +1,170 @@
+package manipulate
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+)
+
+var (
+	imageSubtypePattern = regexp.MustCompile(`/Subtype\s*/Image\b`)
+	embeddedFilePattern = regexp.MustCompile(`/Type\s*/EmbeddedFile\b`)
+	pngSignature        = []byte("\x89PNG\r\n\x1a\n")
+)
+
+// pngMetadataChunks are the PNG chunks removed by stripPNGMetadata: EXIF, text
+// (which holds XMP as "XML:com.adobe.xmp"), the ICC profile and the timestamp
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true, "iTXt": true, "tEXt": true, "zTXt": true, "iCCP": true, "tIME": true,
+}
+
+// stripImageMetadata removes metadata from a stream object that holds an image:
+// the /Metadata entry of an image XObject, the metadata segments of its JPEG
+// data, and the metadata of a JPEG or PNG embedded file. It reports whether the
+// stream changed.
+func stripImageMetadata(dict, data []byte) ([]byte, []byte, bool) {
+	d := string(dict)
+	filter := topLevelValue(d, "/Filter")
+
+	if imageSubtypePattern.MatchString(d) {
+		changed := false
+		if topLevelKeyIndex(d, "/Metadata") != -1 {
+			d = removeTopLevelKey(d, "/Metadata")
+			changed = true
+		}
+		if isSingleFilter(filter, "/DCTDecode") {
+			if stripped, ok := stripJPEGMetadata(data); ok {
+				data = stripped
+				changed = true
+			}
+		}
+		return []byte(d), data, changed
+	}
+
+	if !embeddedFilePattern.MatchString(d) {
+		return dict, data, false
+	}
+	// Embedded files are read decoded; the caller recompresses them
+	decoded := data
+	if filter != "" {
+		if !isSingleFilter(filter, "/FlateDecode") || topLevelKeyIndex(d, "/DecodeParms") != -1 {
+			return dict, data, false
+		}
+		var err error
+		if decoded, err = parse.DecodeFlateDecode(data); err != nil {
+			return dict, data, false
+		}
+	}
+	stripped, ok := stripJPEGMetadata(decoded)
+	if !ok {
+		stripped, ok = stripPNGMetadata(decoded)
+	}
+	if !ok {
+		return dict, data, false
+	}
+	d = removeTopLevelKey(d, "/Filter")
+	if params := topLevelValue(d, "/Params"); strings.HasPrefix(params, "<<") {
+		// The checksum and size describe the original file
+		params = removeTopLevelKey(params, "/CheckSum")
+		if topLevelKeyIndex(params, "/Size") != -1 {
+			params = setTopLevelValue(params, "/Size", fmt.Sprint(len(stripped)))
+		}
+		d = setTopLevelValue(d, "/Params", params)
+	}
+	return []byte(d), stripped, true
+}
+
+// isSingleFilter reports whether a /Filter value is exactly one filter
+func isSingleFilter(filter, name string) bool {
+	return strings.Join(strings.Fields(strings.Trim(filter, "[]")), " ") == name
+}
+
+// stripJPEGMetadata removes the APP1-APP13 and APP15 segments (EXIF, XMP, ICC
+// profiles, Photoshop IPTC) and comments from JPEG data. APP0 (JFIF) and APP14
+// (Adobe, which records the color transform) are kept, as decoders depend on
+// them. It returns false when data is not a JPEG or has no such segments.
+func stripJPEGMetadata(data []byte) ([]byte, bool) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, false
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+	changed := false
+	pos := 2
+	for pos+1 < len(data) {
+		if data[pos] != 0xFF {
+			return nil, false
+		}
+		marker := data[pos+1]
+		if marker == 0xFF {
+			// Fill byte
+			pos++
+			continue
+		}
+		if marker == 0xD9 || marker == 0xDA {
+			// EOI, or SOS followed by the entropy-coded data
+			out = append(out, data[pos:]...)
+			return out, changed
+		}
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			// Markers without a length
+			out = append(out, data[pos:pos+2]...)
+			pos += 2
+			continue
+		}
+		if pos+4 > len(data) {
+			return nil, false
+		}
+		end := pos + 2 + int(binary.BigEndian.Uint16(data[pos+2:pos+4]))
+		if end > len(data) || end < pos+4 {
+			return nil, false
+		}
+		if (marker >= 0xE1 && marker <= 0xED) || marker == 0xEF || marker == 0xFE {
+			changed = true
+		} else {
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+	return nil, false
+}
+
+// stripPNGMetadata removes the chunks in pngMetadataChunks from PNG data. It
+// returns false when data is not a PNG or has no such chunks.
+func stripPNGMetadata(data []byte) ([]byte, bool) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, false
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+	changed := false
+	pos := len(pngSignature)
+	for pos < len(data) {
+		if pos+8 > len(data) {
+			return nil, false
+		}
+		length := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		chunkType := string(data[pos+4 : pos+8])
+		end := pos + 12 + length
+		if length < 0 || end > len(data) || end < pos {
+			return nil, false
+		}
+		if pngMetadataChunks[chunkType] {
+			changed = true
+		} else {
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+		if chunkType == "IEND" {
+			out = append(out, data[pos:]...)
+			break
+		}
+	}
+	return out, changed
+}
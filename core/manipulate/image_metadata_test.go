@@ -0,0 +1,162 @@
+package manipulate
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/core/write"
+)
+
+// jpegWithEXIF encodes a small JPEG with an EXIF segment holding a GPS tag
+// and a comment inserted after the SOI marker
+func jpegWithEXIF(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatalf("Failed to encode JPEG: %v", err)
+	}
+	segment := func(marker byte, payload string) []byte {
+		seg := []byte{0xFF, marker, 0, 0}
+		binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+		return append(seg, payload...)
+	}
+	data := buf.Bytes()
+	var out []byte
+	out = append(out, data[:2]...)
+	out = append(out, segment(0xE1, "Exif\x00\x00GPSLatitude 51.5")...)
+	out = append(out, segment(0xFE, "scanned at home")...)
+	return append(out, data[2:]...)
+}
+
+// pngWithText encodes a small PNG with a tEXt chunk after IHDR
+func pngWithText(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	data := buf.Bytes()
+	payload := "Comment\x00GPSLatitude 51.5"
+	chunk := make([]byte, 8, 12+len(payload))
+	binary.BigEndian.PutUint32(chunk, uint32(len(payload)))
+	copy(chunk[4:], "tEXt")
+	chunk = append(chunk, payload...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+	ihdrEnd := len(pngSignature) + 25
+	return append(append(append([]byte{}, data[:ihdrEnd]...), chunk...), data[ihdrEnd:]...)
+}
+
+func TestStripJPEGMetadata(t *testing.T) {
+	data := jpegWithEXIF(t)
+	stripped, ok := stripJPEGMetadata(data)
+	if !ok {
+		t.Fatal("Expected metadata to be stripped")
+	}
+	if bytes.Contains(stripped, []byte("GPSLatitude")) || bytes.Contains(stripped, []byte("scanned")) {
+		t.Error("EXIF or comment segment kept")
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Errorf("Stripped JPEG doesn't decode: %v", err)
+	}
+	if _, ok := stripJPEGMetadata(stripped); ok {
+		t.Error("Stripping a stripped JPEG reported a change")
+	}
+	if _, ok := stripJPEGMetadata([]byte("not a jpeg")); ok {
+		t.Error("Non-JPEG data reported as stripped")
+	}
+}
+
+func TestStripPNGMetadata(t *testing.T) {
+	stripped, ok := stripPNGMetadata(pngWithText(t))
+	if !ok {
+		t.Fatal("Expected metadata to be stripped")
+	}
+	if bytes.Contains(stripped, []byte("GPSLatitude")) {
+		t.Error("tEXt chunk kept")
+	}
+	if _, err := png.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Errorf("Stripped PNG doesn't decode: %v", err)
+	}
+}
+
+func TestRewrite_StripImageMetadata(t *testing.T) {
+	jpegData := jpegWithEXIF(t)
+	pngData := pngWithText(t)
+
+	writer := write.NewPDFWriter()
+	xmp := "<x:xmpmeta><exif:GPSLatitude>51.5</exif:GPSLatitude></x:xmpmeta>"
+	xmpNum := writer.AddObject([]byte(fmt.Sprintf("<</Type/Metadata/Subtype/XML/Length %d>>\nstream\n%s\nendstream", len(xmp), xmp)))
+	imageNum := writer.AddObject([]byte(fmt.Sprintf("<</Type/XObject/Subtype/Image/Width 8/Height 8/ColorSpace/DeviceGray/BitsPerComponent 8/Metadata %d 0 R/Filter/DCTDecode/Length %d>>\nstream\n%s\nendstream", xmpNum, len(jpegData), jpegData)))
+	content := "q 8 0 0 8 72 700 cm /Im1 Do Q"
+	contentNum := writer.AddObject([]byte(fmt.Sprintf("<</Length %d>>\nstream\n%s\nendstream", len(content), content)))
+	fileNum := writer.AddObject([]byte(fmt.Sprintf("<</Type/EmbeddedFile/Subtype/image#2Fpng/Params <</Size %d/CheckSum <00>>>/Length %d>>\nstream\n%s\nendstream", len(pngData), len(pngData), pngData)))
+	specNum := writer.AddObject([]byte(fmt.Sprintf("<</Type/Filespec/F (scan.png)/EF <</F %d 0 R>>>>", fileNum)))
+	pagesNum := writer.AddObject(nil)
+	pageNum := writer.AddObject([]byte(fmt.Sprintf("<</Type/Page/Parent %d 0 R/MediaBox [0 0 612 792]/Contents %d 0 R/Resources <</XObject <</Im1 %d 0 R>>>>>>", pagesNum, contentNum, imageNum)))
+	writer.SetObject(pagesNum, []byte(fmt.Sprintf("<</Type/Pages/Kids [%d 0 R]/Count 1>>", pageNum)))
+	writer.SetRoot(writer.AddObject([]byte(fmt.Sprintf("<</Type/Catalog/Pages %d 0 R/Names <</EmbeddedFiles <</Names [(scan.png) %d 0 R]>>>>>>", pagesNum, specNum))))
+	input, err := writer.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create test PDF: %v", err)
+	}
+
+	// Without the option images are copied as they are
+	kept, err := Rewrite(input, RewriteOptions{})
+	if err != nil {
+		t.Fatalf("Rewrite failed: %v", err)
+	}
+	if !strings.Contains(string(kept), "GPSLatitude") {
+		t.Fatal("Metadata stripped without StripImageMetadata")
+	}
+
+	out, err := Rewrite(input, RewriteOptions{StripImageMetadata: true, Verify: true})
+	if err != nil {
+		t.Fatalf("Rewrite failed: %v", err)
+	}
+	if strings.Contains(string(out), "GPSLatitude") || strings.Contains(string(out), "xmpmeta") {
+		t.Error("Image metadata kept in the JPEG stream or the image's XMP")
+	}
+	if strings.Contains(string(out), "/CheckSum") {
+		t.Error("Attachment checksum of the original file kept")
+	}
+
+	// The compressed attachment no longer holds the text chunk and still decodes
+	pdf, err := parse.Open(out)
+	if err != nil {
+		t.Fatalf("Failed to open output: %v", err)
+	}
+	found := false
+	for _, objNum := range pdf.Objects() {
+		obj, err := pdf.GetObject(objNum)
+		if err != nil || !embeddedFilePattern.Match(obj) {
+			continue
+		}
+		dict, data, _ := splitStreamObject(objectBody(obj))
+		if strings.Contains(string(dict), "/FlateDecode") {
+			if data, err = parse.DecodeFlateDecode(data); err != nil {
+				t.Fatalf("Failed to decode attachment: %v", err)
+			}
+		}
+		found = true
+		if bytes.Contains(data, []byte("GPSLatitude")) {
+			t.Error("PNG attachment text chunk kept")
+		}
+		if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+			t.Errorf("Stripped attachment doesn't decode: %v", err)
+		}
+		if want := fmt.Sprintf("/Size %d", len(data)); !strings.Contains(string(dict), want) {
+			t.Errorf("Attachment parameters %s don't have %s", dict, want)
+		}
+	}
+	if !found {
+		t.Error("Attachment dropped")
+	}
+}
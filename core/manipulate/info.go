@@ -0,0 +1,69 @@
+package manipulate
+
+import (
+	"fmt"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// infoObject returns the object number of the Info dictionary, or 0 when the
+// document has none
+func (m *PDFManipulator) infoObject() int {
+	if m.infoObjNum != 0 {
+		return m.infoObjNum
+	}
+	trailer := m.pdf.Trailer()
+	if trailer == nil || trailer.InfoRef == "" {
+		return 0
+	}
+	objNum, err := parseObjectRef(trailer.InfoRef)
+	if err != nil {
+		return 0
+	}
+	if _, ok := m.objects[objNum]; !ok {
+		return 0
+	}
+	return objNum
+}
+
+// Info returns the entries of the document's Info dictionary, including
+// /Trapped and custom keys, with the changes made in this session
+func (m *PDFManipulator) Info() (*types.DocumentMetadata, error) {
+	objNum := m.infoObject()
+	if objNum == 0 {
+		return &types.DocumentMetadata{Custom: make(map[string]string)}, nil
+	}
+	return extract.ParseInfoDict(string(m.objects[objNum]), m.pdf, m.verbose), nil
+}
+
+// SetInfo replaces the document's Info dictionary, reusing the existing object
+// if there is one. A missing ModDate is set to the current time.
+func (m *PDFManipulator) SetInfo(metadata *types.DocumentMetadata) error {
+	if metadata == nil {
+		return fmt.Errorf("no metadata")
+	}
+	objNum := m.infoObject()
+	if objNum == 0 {
+		objNum = m.nextObjectNumber()
+		m.infoObjNum = objNum
+		m.writer.SetInfo(objNum)
+	}
+	m.objects[objNum] = m.writer.FormatMetadata(metadata)
+
+	if m.verbose {
+		fmt.Printf("Set Info dictionary (object %d)\n", objNum)
+	}
+	return nil
+}
+
+// SetInfoEntry sets a single Info entry by key, e.g. "Title", "Trapped" or a
+// custom key, keeping the other entries. An empty value removes a custom entry.
+func (m *PDFManipulator) SetInfoEntry(key, value string) error {
+	info, err := m.Info()
+	if err != nil {
+		return err
+	}
+	info.SetInfoEntry(key, value)
+	return m.SetInfo(info)
+}
@@ -0,0 +1,66 @@
+package manipulate
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+func TestSetInfo(t *testing.T) {
+	// The writer is used without the builder so the document has no Info dictionary
+	writer := write.NewPDFWriter()
+	pagesNum := writer.AddObject(nil)
+	pageNum := writer.AddObject([]byte("<</Type/Page/MediaBox [0 0 612 792]>>"))
+	writer.SetObject(pagesNum, []byte(fmt.Sprintf("<</Type/Pages/Kids [%d 0 R]/Count 1>>", pageNum)))
+	writer.SetRoot(writer.AddObject([]byte(fmt.Sprintf("<</Type/Catalog/Pages %d 0 R>>", pagesNum))))
+	pdfBytes, err := writer.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create test PDF: %v", err)
+	}
+
+	m, err := NewPDFManipulator(pdfBytes, nil, false)
+	if err != nil {
+		t.Fatalf("NewPDFManipulator failed: %v", err)
+	}
+	if info, err := m.Info(); err != nil || info.Title != "" || len(info.Custom) != 0 {
+		t.Fatalf("Info = %+v, %v; want empty", info, err)
+	}
+	if err := m.SetInfo(&types.DocumentMetadata{Title: "Brochure", Trapped: types.TrappedTrue}); err != nil {
+		t.Fatalf("SetInfo failed: %v", err)
+	}
+
+	for _, step := range []struct{ key, value string }{
+		{"Job Number", "J-1024 (rev. 2)"},
+		{"Trapped", types.TrappedFalse},
+		{"Author", "Prépresse"},
+	} {
+		if err := m.SetInfoEntry(step.key, step.value); err != nil {
+			t.Fatalf("SetInfoEntry failed: %v", err)
+		}
+		out, err := m.Rebuild()
+		if err != nil {
+			t.Fatalf("Rebuild failed: %v", err)
+		}
+		if m, err = NewPDFManipulator(out, nil, false); err != nil {
+			t.Fatalf("Failed to reopen output: %v", err)
+		}
+	}
+
+	info, err := m.Info()
+	if err != nil {
+		t.Fatalf("Info failed: %v", err)
+	}
+	if info.Title != "Brochure" || info.Author != "Prépresse" || info.Trapped != types.TrappedFalse ||
+		info.Custom["Job Number"] != "J-1024 (rev. 2)" || info.ModDate == "" {
+		t.Errorf("Unexpected Info after round trip: %+v", info)
+	}
+
+	if err := m.SetInfoEntry("Job Number", ""); err != nil {
+		t.Fatalf("SetInfoEntry failed: %v", err)
+	}
+	if info, _ := m.Info(); len(info.Custom) != 0 {
+		t.Errorf("Custom entry not removed: %v", info.Custom)
+	}
+}
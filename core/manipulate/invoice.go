@@ -0,0 +1,350 @@
+package manipulate
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/core/dates"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// Factur-X profiles, which ZUGFeRD 2.1 and later share, as written to the
+// fx:ConformanceLevel XMP property
+const (
+	InvoiceProfileMinimum   = "MINIMUM"
+	InvoiceProfileBasicWL   = "BASIC WL"
+	InvoiceProfileBasic     = "BASIC"
+	InvoiceProfileEN16931   = "EN 16931"
+	InvoiceProfileExtended  = "EXTENDED"
+	InvoiceProfileXRechnung = "XRECHNUNG"
+)
+
+const (
+	// FacturXNamespace is the namespace of the Factur-X XMP properties
+	FacturXNamespace = "urn:factur-x:pdfa:CrossIndustryDocument:invoice:1p0#"
+	// FacturXFileName is the name of the embedded invoice XML
+	FacturXFileName = "factur-x.xml"
+	// XRechnungFileName is the name of embedded XRechnung invoice XML
+	XRechnungFileName = "xrechnung.xml"
+)
+
+// invoiceFileNames are the names of the invoice XML in Factur-X and ZUGFeRD
+// 2.0 and 1.0 documents, in the order ExtractInvoice looks for them
+var invoiceFileNames = []string{FacturXFileName, XRechnungFileName, "zugferd-invoice.xml", "ZUGFeRD-invoice.xml"}
+
+// invoiceGuidelines map substrings of the guideline ID of a CII invoice
+// (GuidelineSpecifiedDocumentContextParameter) to its profile; the first
+// match wins, as the IDs of the Factur-X profiles extend EN 16931's
+var invoiceGuidelines = []struct{ id, profile string }{
+	{"xrechnung", InvoiceProfileXRechnung},
+	{"extended", InvoiceProfileExtended},
+	{"basicwl", InvoiceProfileBasicWL},
+	{"minimum", InvoiceProfileMinimum},
+	{"basic", InvoiceProfileBasic},
+	{"en16931", InvoiceProfileEN16931},
+}
+
+var (
+	// xmpDescriptionPattern matches an rdf:Description element of an XMP packet
+	xmpDescriptionPattern = regexp.MustCompile(`(?s)<rdf:Description\b[^>]*?(?:/>|>.*?</rdf:Description>)`)
+	// invoiceXMPPropertyPattern matches a PDF/A identification or Factur-X
+	// property, as an element or an attribute
+	invoiceXMPPropertyPattern = regexp.MustCompile(`\s*<(?:pdfaid|fx):\w+>[^<]*</(?:pdfaid|fx):\w+>|\s(?:pdfaid|fx):\w+="[^"]*"`)
+	// invoiceXMPValuePattern reads a property of the Factur-X or ZUGFeRD XMP
+	// schemas, whatever their prefix
+	invoiceXMPValuePattern = regexp.MustCompile(`(?:<\w+:(DocumentFileName|ConformanceLevel|Version)>|\s\w+:(DocumentFileName|ConformanceLevel|Version)=")([^<"]*)`)
+)
+
+// InvoiceOptions controls AttachInvoice
+type InvoiceOptions struct {
+	// Profile is the invoice's Factur-X profile; empty reads it from the
+	// guideline ID of the invoice XML
+	Profile string
+	// Relationship of the XML to the document. Empty uses Data for MINIMUM and
+	// BASIC WL invoices, whose XML does not hold the whole invoice, and
+	// Alternative for the others, as Factur-X requires.
+	Relationship string
+	// ModDate is the modification date of the XML; the current time when zero
+	ModDate time.Time
+}
+
+// Invoice is the structured invoice of a hybrid PDF invoice
+type Invoice struct {
+	FileName     string // Name of the embedded XML file
+	Profile      string // Profile from the XMP metadata, or else from the XML's guideline ID
+	Version      string // Version of the Factur-X or ZUGFeRD schema from the XMP metadata
+	Relationship string // Relationship of the XML to the document (/AFRelationship)
+	XML          []byte
+}
+
+// AttachInvoice makes the document a Factur-X hybrid invoice, which ZUGFeRD
+// 2.1 and later readers also accept: the CII invoice XML is embedded as
+// factur-x.xml (xrechnung.xml for XRechnung) with the relationship its profile
+// calls for, and the XMP metadata declares PDF/A-3B conformance and the
+// Factur-X properties with their extension schema, and mirrors the Info
+// dictionary as PDF/A requires. The rest of PDF/A-3, such as embedded fonts and
+// an output intent for device colors, is up to the document.
+func (m *PDFManipulator) AttachInvoice(invoiceXML []byte, opts InvoiceOptions) error {
+	if m.pdf.IsEncrypted() {
+		return types.NewPDFError(types.ErrCodeEncrypted, "PDF/A-3 invoices cannot be encrypted")
+	}
+	guideline, err := invoiceGuideline(invoiceXML)
+	if err != nil {
+		return err
+	}
+	profile := opts.Profile
+	if profile == "" {
+		if profile = invoiceProfile(guideline); profile == "" {
+			return types.NewPDFError(types.ErrCodeInvalidInput, fmt.Sprintf("unknown invoice guideline %q: set the profile", guideline))
+		}
+	}
+	valid := false
+	for _, g := range invoiceGuidelines {
+		valid = valid || g.profile == profile
+	}
+	if !valid {
+		return types.NewPDFError(types.ErrCodeInvalidInput, fmt.Sprintf("unknown invoice profile %q", profile))
+	}
+
+	existing, err := extract.ExtractAttachments(nil, m.pdf, m.verbose)
+	if err != nil {
+		return err
+	}
+	for _, a := range existing {
+		for _, name := range invoiceFileNames {
+			if a.Name == name || a.FileName == name {
+				return fmt.Errorf("document already has an invoice (%s)", name)
+			}
+		}
+	}
+
+	fileName, description := FacturXFileName, "Factur-X invoice"
+	if profile == InvoiceProfileXRechnung {
+		fileName, description = XRechnungFileName, "XRechnung invoice"
+	}
+	relationship := opts.Relationship
+	if relationship == "" {
+		relationship = RelationshipAlternative
+		if profile == InvoiceProfileMinimum || profile == InvoiceProfileBasicWL {
+			relationship = RelationshipData
+		}
+	}
+	if err := m.AddAttachment(Attachment{
+		Name:         fileName,
+		Data:         invoiceXML,
+		MIMEType:     "text/xml",
+		Description:  description,
+		Relationship: relationship,
+		ModDate:      opts.ModDate,
+	}); err != nil {
+		return err
+	}
+
+	xmp, err := m.XMPMetadata()
+	if err != nil {
+		return err
+	}
+	info, err := m.Info()
+	if err != nil {
+		return err
+	}
+	if m.verbose {
+		fmt.Printf("Attached %s invoice as %s\n", profile, fileName)
+	}
+	return m.SetXMPMetadata(setInvoiceXMP(xmp, info, profile, fileName))
+}
+
+// ExtractInvoice returns the structured invoice of a Factur-X, ZUGFeRD or
+// XRechnung hybrid PDF, or nil when the document has none. The embedded file
+// named in the XMP metadata is preferred over the standard file names.
+func ExtractInvoice(pdfBytes []byte, password []byte, verbose bool) (*Invoice, error) {
+	m, err := NewPDFManipulator(pdfBytes, password, verbose)
+	if err != nil {
+		return nil, err
+	}
+	xmp, err := m.XMPMetadata()
+	if err != nil && verbose {
+		fmt.Printf("Warning: failed to read XMP metadata: %v\n", err)
+	}
+	invoice := &Invoice{}
+	names := invoiceFileNames
+	for _, match := range invoiceXMPValuePattern.FindAllSubmatch(xmp, -1) {
+		property, value := string(match[1])+string(match[2]), strings.TrimSpace(string(match[3]))
+		switch property {
+		case "DocumentFileName":
+			names = append([]string{value}, names...)
+		case "ConformanceLevel":
+			invoice.Profile = value
+		case "Version":
+			invoice.Version = value
+		}
+	}
+
+	for _, name := range names {
+		attachment, data, err := extract.ExtractAttachmentData(pdfBytes, m.pdf, name, verbose)
+		if err != nil {
+			continue
+		}
+		invoice.FileName, invoice.Relationship, invoice.XML = attachment.Name, attachment.Relationship, data
+		if invoice.Profile == "" {
+			if guideline, err := invoiceGuideline(data); err == nil {
+				invoice.Profile = invoiceProfile(guideline)
+			}
+		}
+		return invoice, nil
+	}
+	return nil, nil
+}
+
+// invoiceGuideline checks that data is a UN/CEFACT Cross Industry Invoice and
+// returns its guideline ID
+func invoiceGuideline(data []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var path []string
+	guideline, root := "", false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", types.NewPDFError(types.ErrCodeInvalidInput, fmt.Sprintf("invalid invoice XML: %v", err))
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if len(path) == 0 && t.Name.Local != "CrossIndustryInvoice" {
+				return "", types.NewPDFError(types.ErrCodeInvalidInput, fmt.Sprintf("invoice XML is a %s, not a CrossIndustryInvoice", t.Name.Local))
+			}
+			path, root = append(path, t.Name.Local), true
+		case xml.EndElement:
+			path = path[:len(path)-1]
+		case xml.CharData:
+			if guideline == "" && len(path) >= 2 && path[len(path)-1] == "ID" && path[len(path)-2] == "GuidelineSpecifiedDocumentContextParameter" {
+				guideline = strings.TrimSpace(string(t))
+			}
+		}
+	}
+	if !root {
+		return "", types.NewPDFError(types.ErrCodeInvalidInput, "invoice XML is empty")
+	}
+	return guideline, nil
+}
+
+// invoiceProfile returns the profile of a guideline ID, or "" if it is not known
+func invoiceProfile(guideline string) string {
+	id := strings.ToLower(guideline)
+	for _, g := range invoiceGuidelines {
+		if strings.Contains(id, g.id) {
+			return g.profile
+		}
+	}
+	return ""
+}
+
+// setInvoiceXMP adds the PDF/A identification, the Factur-X properties and
+// their extension schema to an XMP packet, replacing any that are there, and
+// the Info dictionary's entries that the packet lacks. The packet is created
+// when xmp has no rdf:RDF element.
+func setInvoiceXMP(xmp []byte, info *types.DocumentMetadata, profile, fileName string) []byte {
+	packet := xmpDescriptionPattern.ReplaceAllStringFunc(string(xmp), func(desc string) string {
+		if strings.Contains(desc, "pdfaExtension") && strings.Contains(desc, FacturXNamespace) {
+			return ""
+		}
+		return invoiceXMPPropertyPattern.ReplaceAllString(desc, "")
+	})
+
+	var b strings.Builder
+	text := func(s string) string {
+		var escaped strings.Builder
+		xml.EscapeText(&escaped, []byte(s))
+		return escaped.String()
+	}
+	b.WriteString(`<rdf:Description rdf:about="" xmlns:pdfaid="http://www.aiim.org/pdfa/ns/id/">
+   <pdfaid:part>3</pdfaid:part>
+   <pdfaid:conformance>B</pdfaid:conformance>
+  </rdf:Description>
+  <rdf:Description rdf:about="" xmlns:fx="` + FacturXNamespace + `">
+   <fx:DocumentType>INVOICE</fx:DocumentType>
+   <fx:DocumentFileName>` + text(fileName) + `</fx:DocumentFileName>
+   <fx:Version>1.0</fx:Version>
+   <fx:ConformanceLevel>` + text(profile) + `</fx:ConformanceLevel>
+  </rdf:Description>
+  <rdf:Description rdf:about="" xmlns:pdfaExtension="http://www.aiim.org/pdfa/ns/extension/" xmlns:pdfaSchema="http://www.aiim.org/pdfa/ns/schema#" xmlns:pdfaProperty="http://www.aiim.org/pdfa/ns/property#">
+   <pdfaExtension:schemas>
+    <rdf:Bag>
+     <rdf:li rdf:parseType="Resource">
+      <pdfaSchema:schema>Factur-X PDFA Extension Schema</pdfaSchema:schema>
+      <pdfaSchema:namespaceURI>` + FacturXNamespace + `</pdfaSchema:namespaceURI>
+      <pdfaSchema:prefix>fx</pdfaSchema:prefix>
+      <pdfaSchema:property>
+       <rdf:Seq>
+`)
+	for _, property := range []struct{ name, description string }{
+		{"DocumentFileName", "The name of the embedded XML document"},
+		{"DocumentType", "The type of the hybrid document in capital letters, e.g. INVOICE or ORDER"},
+		{"Version", "The actual version of the standard applying to the embedded XML document"},
+		{"ConformanceLevel", "The conformance level of the embedded XML document"},
+	} {
+		b.WriteString(`        <rdf:li rdf:parseType="Resource">
+         <pdfaProperty:name>` + property.name + `</pdfaProperty:name>
+         <pdfaProperty:valueType>Text</pdfaProperty:valueType>
+         <pdfaProperty:category>external</pdfaProperty:category>
+         <pdfaProperty:description>` + property.description + `</pdfaProperty:description>
+        </rdf:li>
+`)
+	}
+	b.WriteString(`       </rdf:Seq>
+      </pdfaSchema:property>
+     </rdf:li>
+    </rdf:Bag>
+   </pdfaExtension:schemas>
+  </rdf:Description>
+`)
+
+	// PDF/A requires the Info entries in the XMP metadata as well
+	var mirrored strings.Builder
+	for _, entry := range []struct{ element, value, format string }{
+		{"dc:title", info.Title, `<rdf:Alt><rdf:li xml:lang="x-default">%s</rdf:li></rdf:Alt>`},
+		{"dc:creator", info.Author, `<rdf:Seq><rdf:li>%s</rdf:li></rdf:Seq>`},
+		{"dc:description", info.Subject, `<rdf:Alt><rdf:li xml:lang="x-default">%s</rdf:li></rdf:Alt>`},
+		{"pdf:Keywords", info.Keywords, "%s"},
+		{"xmp:CreatorTool", info.Creator, "%s"},
+		{"pdf:Producer", info.Producer, "%s"},
+		{"xmp:CreateDate", isoDate(info.CreationDate), "%s"},
+		{"xmp:ModifyDate", isoDate(info.ModDate), "%s"},
+	} {
+		if entry.value == "" || strings.Contains(packet, "<"+entry.element) || strings.Contains(packet, " "+entry.element+"=") {
+			continue
+		}
+		fmt.Fprintf(&mirrored, "   <%s>"+entry.format+"</%s>\n", entry.element, text(entry.value), entry.element)
+	}
+	if mirrored.Len() > 0 {
+		b.WriteString(`  <rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:pdf="http://ns.adobe.com/pdf/1.3/" xmlns:xmp="http://ns.adobe.com/xap/1.0/">
+` + mirrored.String() + "  </rdf:Description>\n")
+	}
+
+	if end := strings.LastIndex(packet, "</rdf:RDF>"); end != -1 {
+		return []byte(packet[:end] + " " + b.String() + " " + packet[end:])
+	}
+	return []byte(`<?xpacket begin="` + "\ufeff" + `" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  ` + b.String() + ` </rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`)
+}
+
+// isoDate converts a PDF date to ISO 8601, or returns "" if it is not valid
+func isoDate(pdfDate string) string {
+	iso, err := dates.PDFToISO(pdfDate)
+	if err != nil {
+		return ""
+	}
+	return iso
+}
@@ -0,0 +1,125 @@
+package manipulate
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// testInvoiceXML is a Cross Industry Invoice with the given guideline ID
+func testInvoiceXML(guideline string) []byte {
+	return []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<rsm:CrossIndustryInvoice xmlns:rsm="urn:un:unece:uncefact:data:standard:CrossIndustryInvoice:100" xmlns:ram="urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100">
+ <rsm:ExchangedDocumentContext>
+  <ram:GuidelineSpecifiedDocumentContextParameter>
+   <ram:ID>` + guideline + `</ram:ID>
+  </ram:GuidelineSpecifiedDocumentContextParameter>
+ </rsm:ExchangedDocumentContext>
+ <rsm:ExchangedDocument>
+  <ram:ID>INV-42</ram:ID>
+ </rsm:ExchangedDocument>
+</rsm:CrossIndustryInvoice>`)
+}
+
+func TestAttachInvoice(t *testing.T) {
+	builder := write.NewSimplePDFBuilder()
+	builder.FinalizePage(builder.AddPage(write.PageSizeLetter))
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create test PDF: %v", err)
+	}
+
+	invoiceXML := testInvoiceXML("urn:cen.eu:en16931:2017")
+	m, err := NewPDFManipulator(pdfBytes, nil, false)
+	if err != nil {
+		t.Fatalf("NewPDFManipulator failed: %v", err)
+	}
+	if err := m.SetInfo(&types.DocumentMetadata{Title: "Invoice INV-42 & co", Producer: "pdfer"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AttachInvoice([]byte("<Order/>"), InvoiceOptions{}); err == nil {
+		t.Error("Expected an error for XML that is not an invoice")
+	}
+	if err := m.AttachInvoice(invoiceXML, InvoiceOptions{}); err != nil {
+		t.Fatalf("AttachInvoice failed: %v", err)
+	}
+	out, err := m.Rebuild()
+	if err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+	if !bytes.Contains(out, []byte("/AFRelationship /Alternative")) || !bytes.Contains(out, []byte("/Subtype /text#2Fxml")) {
+		t.Error("Invoice file specification entries missing")
+	}
+
+	m, err = NewPDFManipulator(out, nil, false)
+	if err != nil {
+		t.Fatalf("Failed to reopen output: %v", err)
+	}
+	xmp, err := m.XMPMetadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<pdfaid:part>3</pdfaid:part>",
+		"<pdfaid:conformance>B</pdfaid:conformance>",
+		"<fx:DocumentFileName>factur-x.xml</fx:DocumentFileName>",
+		"<fx:ConformanceLevel>EN 16931</fx:ConformanceLevel>",
+		"<pdfaSchema:prefix>fx</pdfaSchema:prefix>",
+		"Invoice INV-42 &amp; co</rdf:li>",
+		"<pdf:Producer>pdfer</pdf:Producer>",
+	} {
+		if !strings.Contains(string(xmp), want) {
+			t.Errorf("XMP metadata lacks %s:\n%s", want, xmp)
+		}
+	}
+	if err := m.AttachInvoice(invoiceXML, InvoiceOptions{}); err == nil {
+		t.Error("Expected an error attaching a second invoice")
+	}
+
+	invoice, err := ExtractInvoice(out, nil, false)
+	if err != nil || invoice == nil {
+		t.Fatalf("ExtractInvoice = %+v, %v", invoice, err)
+	}
+	if invoice.FileName != FacturXFileName || invoice.Profile != InvoiceProfileEN16931 || invoice.Version != "1.0" ||
+		invoice.Relationship != RelationshipAlternative || !bytes.Equal(invoice.XML, invoiceXML) {
+		t.Errorf("Unexpected invoice %+v", invoice)
+	}
+
+	// Without XMP metadata the profile comes from the XML
+	m, err = NewPDFManipulator(pdfBytes, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddAttachment(Attachment{Name: "zugferd-invoice.xml", Data: testInvoiceXML("urn:factur-x.eu:1p0:basicwl")}); err != nil {
+		t.Fatal(err)
+	}
+	out, err = m.Rebuild()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if invoice, err = ExtractInvoice(out, nil, false); err != nil || invoice == nil || invoice.Profile != InvoiceProfileBasicWL {
+		t.Errorf("ExtractInvoice = %+v, %v; want a BASIC WL invoice", invoice, err)
+	}
+	if invoice, err = ExtractInvoice(pdfBytes, nil, false); err != nil || invoice != nil {
+		t.Errorf("ExtractInvoice = %+v, %v; want none", invoice, err)
+	}
+}
+
+func TestInvoiceProfile(t *testing.T) {
+	for guideline, want := range map[string]string{
+		"urn:factur-x.eu:1p0:minimum":                                           InvoiceProfileMinimum,
+		"urn:factur-x.eu:1p0:basicwl":                                           InvoiceProfileBasicWL,
+		"urn:cen.eu:en16931:2017#compliant#urn:factur-x.eu:1p0:basic":           InvoiceProfileBasic,
+		"urn:cen.eu:en16931:2017":                                               InvoiceProfileEN16931,
+		"urn:cen.eu:en16931:2017#conformant#urn:factur-x.eu:1p0:extended":       InvoiceProfileExtended,
+		"urn:cen.eu:en16931:2017#compliant#urn:xeinkauf.de:kosit:xrechnung_3.0": InvoiceProfileXRechnung,
+		"urn:example:other":                                                     "",
+	} {
+		if got := invoiceProfile(guideline); got != want {
+			t.Errorf("invoiceProfile(%q) = %q, want %q", guideline, got, want)
+		}
+	}
+}
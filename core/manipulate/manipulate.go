@@ -8,6 +8,7 @@ import (
 
 	"github.com/benedoc-inc/pdfer/core/parse"
 	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
 )
 
 // PDFManipulator provides functions to modify existing PDFs
@@ -17,53 +18,38 @@ type PDFManipulator struct {
 	writer   *write.PDFWriter
 	objects  map[int][]byte // object number -> content
 	verbose  bool
+	warnings *types.WarningCollector
+
+	infoObjNum int // Info dictionary added by SetInfo to a document without one
 }
 
-// NewPDFManipulator creates a new PDF manipulator from existing PDF bytes
+// NewPDFManipulator creates a new PDF manipulator from existing PDF bytes. To
+// edit the same document repeatedly or from several goroutines, parse it once
+// with OpenDocument and start a session per edit with Document.Edit.
 func NewPDFManipulator(pdfBytes []byte, password []byte, verbose bool) (*PDFManipulator, error) {
-	pdf, err := parse.OpenWithOptions(pdfBytes, parse.ParseOptions{
-		Password: password,
-		Verbose:  verbose,
-	})
+	doc, err := OpenDocument(pdfBytes, password, verbose)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse PDF: %w", err)
-	}
-
-	// Create a new writer for rebuilding
-	writer := write.NewPDFWriter()
-
-	// Copy all objects from original PDF
-	objects := make(map[int][]byte)
-	for _, objNum := range pdf.Objects() {
-		obj, err := pdf.GetObject(objNum)
-		if err != nil {
-			if verbose {
-				fmt.Printf("Warning: failed to get object %d: %v\n", objNum, err)
-			}
-			continue
-		}
-		objects[objNum] = obj
+		return nil, err
 	}
-
-	return &PDFManipulator{
-		pdf:      pdf,
-		pdfBytes: pdfBytes,
-		writer:   writer,
-		objects:  objects,
-		verbose:  verbose,
-	}, nil
+	// The document isn't shared, so the session can take its objects as they are
+	return doc.session(doc.objects), nil
 }
 
-// Rebuild rebuilds the PDF with modified objects and returns the new PDF bytes
+// Rebuild rebuilds the PDF with modified objects and returns the new PDF bytes.
+// A usage rights signature, which the rebuilt file would no longer match, is
+// removed with a WarningUsageRightsRemoved warning.
 func (m *PDFManipulator) Rebuild() ([]byte, error) {
 	return m.rebuildPDF()
 }
 
 // rebuildPDF rebuilds the PDF with modified objects
 func (m *PDFManipulator) rebuildPDF() ([]byte, error) {
-	// Add all objects to writer
+	m.removeUsageRightsForRebuild()
+
+	// Add all objects to writer. Objects loaded from the document still have
+	// their "N G obj" wrapper, which the writer adds again.
 	for objNum, content := range m.objects {
-		m.writer.SetObject(objNum, content)
+		m.writer.SetObject(objNum, objectBody(content))
 	}
 
 	// Get trailer info
@@ -172,9 +158,10 @@ func setDictValue(dictStr, key, value string) string {
 	if existingValue != "" {
 		// Replace existing value
 		// Try to match and replace with space (e.g., "/Count 3" -> "/Count 2")
-		// Use word boundary or whitespace to ensure we match the full value
+		// Use word boundary or whitespace to ensure we match the full value.
+		// Arrays are handled below so "/Kids [1 0 R]" is replaced as a whole.
 		pattern := regexp.MustCompile(regexp.QuoteMeta(key) + `\s+([^\s<>]+)`)
-		if pattern.MatchString(dictStr) {
+		if !strings.HasPrefix(existingValue, "[") && pattern.MatchString(dictStr) {
 			replaced := pattern.ReplaceAllString(dictStr, key+" "+value)
 			// Verify the replacement worked
 			newValue := extractDictValue(replaced, key)
@@ -0,0 +1,521 @@
+package manipulate
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/core/encrypt"
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+var (
+	startXRefPattern         = regexp.MustCompile(`startxref\s+(\d+)`)
+	trailerOffsetPattern     = regexp.MustCompile(`/(Prev|XRefStm)\s+(\d+)`)
+	xrefEntryPattern         = regexp.MustCompile(`(?m)^[ \t]*(\d{10})[ \t]+\d{5}[ \t]+n`)
+	xrefTypePattern          = regexp.MustCompile(`/Type\s*/XRef\b`)
+	xrefWidthsPattern        = regexp.MustCompile(`/W\s*\[[^\]]*\]`)
+	xrefIndexPattern         = regexp.MustCompile(`/Index\s*\[([^\]]*)\]`)
+	xrefSizePattern          = regexp.MustCompile(`/Size\s+(\d+)`)
+	xrefPrevPattern          = regexp.MustCompile(`/Prev\s+(\d+)`)
+	filterEntryPattern       = regexp.MustCompile(`/Filter\s*(/\w+|\[[^\]]*\])`)
+	decodeParmsPattern       = regexp.MustCompile(`/DecodeParms\s*(<<[^>]*>>|\[[^\]]*\])`)
+	integerObjectPattern     = regexp.MustCompile(`^\s*\d+\s+\d+\s+obj\s*(\d+)`)
+	signatureContentsPattern = regexp.MustCompile(`/Contents\s*$`)
+)
+
+// byteEdit replaces src[start:end] of the input with data
+type byteEdit struct {
+	start, end int64
+	data       []byte
+}
+
+// reencryption holds the state of a ChangePasswords run
+type reencryption struct {
+	pdf           *parse.PDF
+	doc           *parse.PDFDocument
+	src           []byte
+	from, to      *types.PDFEncryption
+	encryptObjNum int
+	lengths       map[int]int // Indirect stream lengths that changed, by object number
+}
+
+// ChangePasswords re-encrypts an encrypted document under new user and owner
+// passwords without rewriting it. Only encrypted strings and stream data, the
+// Encrypt dictionary, stream lengths and cross-reference offsets change; all
+// other bytes, including every revision and signature /Contents, are kept as
+// written. password opens the input with either of its passwords.
+//
+// The cipher, permissions and file ID of the input are kept. Cross-reference
+// streams are re-encoded with new offsets; linearization hints are not updated.
+func ChangePasswords(pdfBytes, password, userPassword, ownerPassword []byte, verbose bool) ([]byte, error) {
+	pdf, err := parse.OpenWithOptions(pdfBytes, parse.ParseOptions{
+		Password: password,
+		Verbose:  verbose,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PDF: %w", err)
+	}
+	if !pdf.IsEncrypted() {
+		return nil, types.NewPDFError(types.ErrCodeInvalidInput, "PDF is not encrypted")
+	}
+	from := pdf.Encryption()
+	if len(from.EncryptKey) == 0 {
+		// Opened without the password of a document encrypting only its attachments
+		return nil, types.NewPDFError(types.ErrCodeWrongPassword, "password required to re-encrypt attachments")
+	}
+
+	to, err := newEncryption(cipherOf(from), userPassword, ownerPassword, encrypt.ExtractFileID(pdfBytes, false), from.P, from.EncryptMetadata)
+	if err != nil {
+		return nil, err
+	}
+	to.StmF, to.StrF, to.EFF = from.StmF, from.StrF, from.EFF
+
+	doc, err := parse.ParsePDFDocument(pdfBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse document structure: %w", err)
+	}
+
+	rc := &reencryption{
+		pdf:     pdf,
+		doc:     doc,
+		src:     pdfBytes,
+		from:    from,
+		to:      to,
+		lengths: make(map[int]int),
+	}
+	if rc.encryptObjNum, err = parseObjectRef(pdf.Trailer().EncryptRef); err != nil {
+		return nil, fmt.Errorf("invalid encrypt reference: %w", err)
+	}
+
+	content, err := rc.contentEdits()
+	if err != nil {
+		return nil, err
+	}
+
+	// Cross-reference offsets depend on the size of every edit before them,
+	// including other offsets, so they are recomputed until their sizes settle
+	var structure []byteEdit
+	for i := 0; ; i++ {
+		next, err := rc.structureEdits(mergeEdits(content, structure))
+		if err != nil {
+			return nil, err
+		}
+		if sameEditSizes(next, structure) {
+			structure = next
+			break
+		}
+		if i == 10 {
+			return nil, fmt.Errorf("cross-reference offsets did not settle")
+		}
+		structure = next
+	}
+
+	edits := mergeEdits(content, structure)
+	if verbose {
+		fmt.Printf("Re-encrypted document with %d edits across %d revisions\n", len(edits), len(doc.Revisions))
+	}
+	return applyEdits(pdfBytes, edits), nil
+}
+
+// contentEdits re-encrypts the strings and streams of every object in every revision
+// and replaces the Encrypt dictionary
+func (rc *reencryption) contentEdits() ([]byteEdit, error) {
+	var edits []byteEdit
+	seen := make(map[int64]bool)
+	for _, rev := range rc.doc.Revisions {
+		for _, obj := range rev.Objects {
+			if seen[obj.Offset] {
+				continue
+			}
+			seen[obj.Offset] = true
+
+			objEdits, err := rc.objectEdits(obj)
+			if err != nil {
+				return nil, err
+			}
+			edits = append(edits, objEdits...)
+		}
+	}
+
+	// Indirect lengths of streams whose size changed
+	for objNum, length := range rc.lengths {
+		for _, rev := range rc.doc.Revisions {
+			obj, ok := rev.Objects[objNum]
+			if !ok {
+				continue
+			}
+			loc := integerObjectPattern.FindSubmatchIndex(obj.RawBytes)
+			if loc == nil {
+				return nil, fmt.Errorf("stream length object %d is not an integer", objNum)
+			}
+			edits = append(edits, byteEdit{obj.Offset + int64(loc[2]), obj.Offset + int64(loc[3]), []byte(strconv.Itoa(length))})
+		}
+	}
+
+	return mergeEdits(edits), nil
+}
+
+// objectEdits re-encrypts one object's strings and stream data in place
+func (rc *reencryption) objectEdits(obj *parse.PDFRawObject) ([]byteEdit, error) {
+	header := objectHeaderPattern.FindIndex(obj.RawBytes)
+	if header == nil {
+		return nil, nil
+	}
+	bodyStart := header[1]
+	bodyEnd := len(obj.RawBytes) - len("endobj")
+
+	if obj.Number == rc.encryptObjNum {
+		return []byteEdit{{obj.Offset + int64(bodyStart), obj.Offset + int64(bodyEnd), append(write.CreateEncryptionDictionary(rc.to), '\n')}}, nil
+	}
+
+	syntaxEnd := bodyEnd
+	if obj.IsStream {
+		if xrefTypePattern.Match(obj.DictRaw) {
+			// Rebuilt with the cross-reference offsets
+			return nil, nil
+		}
+		syntaxEnd = obj.DictEnd
+	}
+	if syntaxEnd < bodyStart {
+		return nil, nil
+	}
+	syntax := obj.RawBytes[bodyStart:syntaxEnd]
+
+	var tokens []encrypt.StringToken
+	if encrypt.EncryptsStrings(rc.from) {
+		var err error
+		if tokens, err = encrypt.FindStrings(syntax); err != nil {
+			return nil, fmt.Errorf("failed to scan strings in object %d: %w", obj.Number, err)
+		}
+	}
+
+	var edits []byteEdit
+	signature := bytes.Contains(syntax, []byte("/ByteRange"))
+	for _, token := range tokens {
+		if len(token.Value) == 0 {
+			continue
+		}
+		// Signature values are not encrypted
+		if signature && signatureContentsPattern.Match(syntax[:token.Start]) {
+			continue
+		}
+		plain, err := encrypt.DecryptObject(token.Value, obj.Number, obj.Generation, rc.from)
+		if err != nil {
+			// Not encrypted (e.g. malformed AES data); leave it as written
+			continue
+		}
+		encrypted, err := encrypt.EncryptObject(plain, obj.Number, obj.Generation, rc.to)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt string in object %d: %w", obj.Number, err)
+		}
+		start := obj.Offset + int64(bodyStart+token.Start)
+		edits = append(edits, byteEdit{start, start + int64(token.End-token.Start), encodeString(encrypted, syntax[token.Start:token.End])})
+	}
+
+	if !obj.IsStream || !encrypt.EncryptsStream(obj.DictRaw, rc.from) {
+		return edits, nil
+	}
+
+	length, lengthObjNum, err := rc.streamLength(obj)
+	if err != nil {
+		return nil, err
+	}
+	if length == 0 {
+		return edits, nil
+	}
+	if obj.StreamStart+length > bodyEnd {
+		return nil, fmt.Errorf("stream %d is shorter than its /Length %d", obj.Number, length)
+	}
+
+	plain, err := encrypt.DecryptObject(obj.RawBytes[obj.StreamStart:obj.StreamStart+length], obj.Number, obj.Generation, rc.from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt stream %d: %w", obj.Number, err)
+	}
+	encrypted, err := encrypt.EncryptObject(plain, obj.Number, obj.Generation, rc.to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt stream %d: %w", obj.Number, err)
+	}
+	start := obj.Offset + int64(obj.StreamStart)
+	edits = append(edits, byteEdit{start, start + int64(length), encrypted})
+
+	if len(encrypted) != length {
+		if lengthObjNum != 0 {
+			rc.lengths[lengthObjNum] = len(encrypted)
+		} else if loc := streamLengthPattern.FindSubmatchIndex(obj.DictRaw); loc != nil {
+			dictStart := obj.Offset + int64(obj.DictStart)
+			edits = append(edits, byteEdit{dictStart + int64(loc[2]), dictStart + int64(loc[3]), []byte(strconv.Itoa(len(encrypted)))})
+		}
+	}
+
+	return edits, nil
+}
+
+// streamLength returns a stream's /Length and, when it is indirect, the number of
+// the object holding it
+func (rc *reencryption) streamLength(obj *parse.PDFRawObject) (int, int, error) {
+	match := streamLengthPattern.FindSubmatch(obj.DictRaw)
+	if match == nil {
+		return obj.StreamEnd - obj.StreamStart, 0, nil
+	}
+	length, err := strconv.Atoi(string(match[1]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid /Length in stream %d: %w", obj.Number, err)
+	}
+	if len(match[2]) == 0 {
+		return length, 0, nil
+	}
+
+	lengthObjNum := length
+	lengthObj, err := rc.pdf.GetObject(lengthObjNum)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get length object %d of stream %d: %w", lengthObjNum, obj.Number, err)
+	}
+	if length, err = strconv.Atoi(string(objectBody(lengthObj))); err != nil {
+		return 0, 0, fmt.Errorf("invalid length object %d of stream %d: %w", lengthObjNum, obj.Number, err)
+	}
+	return length, lengthObjNum, nil
+}
+
+// structureEdits updates startxref values, cross-reference tables and streams, and
+// trailer offsets for the positions objects move to under edits
+func (rc *reencryption) structureEdits(edits []byteEdit) ([]byteEdit, error) {
+	position := func(offset int64) int64 {
+		return newOffset(edits, offset)
+	}
+
+	var out []byteEdit
+	rebuilt := make(map[int64]bool)
+	rebuildStream := func(offset int64) error {
+		if rebuilt[offset] {
+			return nil
+		}
+		rebuilt[offset] = true
+		obj, err := parse.ParseRawObjectAt(rc.src, offset)
+		if err != nil {
+			return fmt.Errorf("failed to parse cross-reference stream at %d: %w", offset, err)
+		}
+		data, err := rc.rebuildXRefStream(obj, position)
+		if err != nil {
+			return err
+		}
+		out = append(out, byteEdit{obj.Offset, obj.EndOffset, data})
+		return nil
+	}
+
+	for _, rev := range rc.doc.Revisions {
+		searchStart := max(rev.EOFOffset-100, 0)
+		if matches := startXRefPattern.FindAllSubmatchIndex(rc.src[searchStart:rev.EOFOffset], -1); len(matches) > 0 {
+			loc := matches[len(matches)-1]
+			out = append(out, byteEdit{searchStart + int64(loc[2]), searchStart + int64(loc[3]), []byte(strconv.FormatInt(position(rev.StartXRef), 10))})
+		}
+
+		if rev.XRef.Type == parse.XRefTypeStream {
+			if err := rebuildStream(rev.XRef.Offset); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		for _, loc := range xrefEntryPattern.FindAllSubmatchIndex(rev.XRef.RawBytes, -1) {
+			offset, _ := strconv.ParseInt(string(rev.XRef.RawBytes[loc[2]:loc[3]]), 10, 64)
+			start := rev.XRef.Offset + int64(loc[2])
+			out = append(out, byteEdit{start, start + 10, []byte(fmt.Sprintf("%010d", position(offset)))})
+		}
+
+		if rev.Trailer == nil {
+			continue
+		}
+		for _, loc := range trailerOffsetPattern.FindAllSubmatchIndex(rev.Trailer.RawBytes, -1) {
+			offset, _ := strconv.ParseInt(string(rev.Trailer.RawBytes[loc[4]:loc[5]]), 10, 64)
+			start := rev.Trailer.Offset + int64(loc[4])
+			out = append(out, byteEdit{start, rev.Trailer.Offset + int64(loc[5]), []byte(strconv.FormatInt(position(offset), 10))})
+			// Hybrid-reference files also index objects in a cross-reference stream
+			if string(rev.Trailer.RawBytes[loc[2]:loc[3]]) == "XRefStm" {
+				if err := rebuildStream(offset); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return mergeEdits(out), nil
+}
+
+// rebuildXRefStream re-encodes a cross-reference stream object with its offsets moved
+// by position. The stream is written unpredicted with /FlateDecode.
+func (rc *reencryption) rebuildXRefStream(obj *parse.PDFRawObject, position func(int64) int64) ([]byte, error) {
+	result, err := parse.ParseXRefStreamFull(rc.src, obj.Offset, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse cross-reference stream %d: %w", obj.Number, err)
+	}
+	dict := string(obj.DictRaw)
+
+	var subsections [][2]int
+	if match := xrefIndexPattern.FindStringSubmatch(dict); match != nil {
+		fields := strings.Fields(match[1])
+		for i := 0; i+1 < len(fields); i += 2 {
+			first, _ := strconv.Atoi(fields[i])
+			count, _ := strconv.Atoi(fields[i+1])
+			subsections = append(subsections, [2]int{first, count})
+		}
+	} else if match := xrefSizePattern.FindStringSubmatch(dict); match != nil {
+		size, _ := strconv.Atoi(match[1])
+		subsections = [][2]int{{0, size}}
+	}
+
+	type entry struct{ kind, field2, field3 int64 }
+	var entries []entry
+	var maxField2, maxField3 int64 = 0, 0xffff
+	for _, sub := range subsections {
+		for objNum := sub[0]; objNum < sub[0]+sub[1]; objNum++ {
+			e := entry{}
+			if offset, ok := result.Objects[objNum]; ok {
+				e = entry{1, position(offset), 0}
+			} else if inStream, ok := result.ObjectStreams[objNum]; ok {
+				e = entry{2, int64(inStream.StreamObjNum), int64(inStream.IndexInStream)}
+			} else if objNum == 0 {
+				e = entry{0, 0, 0xffff}
+			}
+			maxField2 = max(maxField2, e.field2)
+			maxField3 = max(maxField3, e.field3)
+			entries = append(entries, e)
+		}
+	}
+
+	w2, w3 := byteWidth(maxField2), byteWidth(maxField3)
+	var data bytes.Buffer
+	for _, e := range entries {
+		data.WriteByte(byte(e.kind))
+		writeBigEndian(&data, e.field2, w2)
+		writeBigEndian(&data, e.field3, w3)
+	}
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(data.Bytes())
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress cross-reference stream %d: %w", obj.Number, err)
+	}
+
+	dict = decodeParmsPattern.ReplaceAllString(dict, "")
+	dict = xrefWidthsPattern.ReplaceAllString(dict, fmt.Sprintf("/W [1 %d %d]", w2, w3))
+	dict = string(setStreamLength([]byte(dict), compressed.Len()))
+	if filterEntryPattern.MatchString(dict) {
+		dict = filterEntryPattern.ReplaceAllString(dict, "/Filter /FlateDecode")
+	} else {
+		dict = dict[:len(dict)-2] + "/Filter /FlateDecode>>"
+	}
+	dict = xrefPrevPattern.ReplaceAllStringFunc(dict, func(prev string) string {
+		offset, _ := strconv.ParseInt(xrefPrevPattern.FindStringSubmatch(prev)[1], 10, 64)
+		return "/Prev " + strconv.FormatInt(position(offset), 10)
+	})
+
+	var buf bytes.Buffer
+	buf.Write(obj.RawBytes[:obj.DictStart])
+	buf.WriteString(dict)
+	buf.WriteString("\nstream\n")
+	buf.Write(compressed.Bytes())
+	buf.WriteString("\nendstream\nendobj")
+	return buf.Bytes(), nil
+}
+
+// encodeString writes an encrypted string in the form of the token it replaces:
+// hex strings keep their digit case, literal strings escape only what they must
+func encodeString(value, original []byte) []byte {
+	if original[0] == '<' {
+		digits := hex.EncodeToString(value)
+		if bytes.ContainsAny(original, "ABCDEF") {
+			digits = strings.ToUpper(digits)
+		}
+		return []byte("<" + digits + ">")
+	}
+
+	out := make([]byte, 0, len(value)+len(value)/8+2)
+	out = append(out, '(')
+	for _, b := range value {
+		switch b {
+		case '(', ')', '\\':
+			out = append(out, '\\', b)
+		case '\r':
+			// A raw CR would be read back as a line end
+			out = append(out, '\\', 'r')
+		default:
+			out = append(out, b)
+		}
+	}
+	return append(out, ')')
+}
+
+// byteWidth returns the number of bytes needed to hold v big-endian (at least 1)
+func byteWidth(v int64) int {
+	n := 1
+	for v > 0xff {
+		v >>= 8
+		n++
+	}
+	return n
+}
+
+// writeBigEndian writes the low width bytes of v, most significant first
+func writeBigEndian(buf *bytes.Buffer, v int64, width int) {
+	for i := width - 1; i >= 0; i-- {
+		buf.WriteByte(byte(v >> (8 * i)))
+	}
+}
+
+// mergeEdits combines edit lists sorted by position
+func mergeEdits(lists ...[]byteEdit) []byteEdit {
+	var merged []byteEdit
+	for _, list := range lists {
+		merged = append(merged, list...)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].start < merged[j].start })
+	return merged
+}
+
+// sameEditSizes reports whether two edit lists replace the same ranges with data of the same sizes
+func sameEditSizes(a, b []byteEdit) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].start != b[i].start || a[i].end != b[i].end || len(a[i].data) != len(b[i].data) {
+			return false
+		}
+	}
+	return true
+}
+
+// newOffset maps an input offset to its position after sorted edits are applied.
+// Offsets are expected to fall outside edited ranges.
+func newOffset(edits []byteEdit, offset int64) int64 {
+	moved := offset
+	for _, e := range edits {
+		if e.end > offset {
+			break
+		}
+		moved += int64(len(e.data)) - (e.end - e.start)
+	}
+	return moved
+}
+
+// applyEdits applies sorted, non-overlapping edits to src
+func applyEdits(src []byte, edits []byteEdit) []byte {
+	var out bytes.Buffer
+	out.Grow(len(src))
+	last := int64(0)
+	for _, e := range edits {
+		out.Write(src[last:e.start])
+		out.Write(e.data)
+		last = e.end
+	}
+	out.Write(src[last:])
+	return out.Bytes()
+}
@@ -0,0 +1,189 @@
+package manipulate
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/encrypt"
+	"github.com/benedoc-inc/pdfer/core/parse"
+)
+
+// encryptedRegions matches stream data and hex strings, the only bytes ChangePasswords
+// may change in a document written by SetSecurity
+var encryptedRegions = regexp.MustCompile(`(?s)stream\r?\n.*?endstream|<[0-9A-Fa-f\s]*>`)
+
+// testObjectPattern matches an indirect object written by PDFWriter
+var testObjectPattern = regexp.MustCompile(`(?s)(\d+) 0 obj\n.*?endobj`)
+
+func TestChangePasswords_PreservesLayout(t *testing.T) {
+	for _, cipher := range []string{CipherRC4128, CipherAES128, CipherAES256} {
+		t.Run(cipher, func(t *testing.T) {
+			in, err := SetSecurity(createFormPDF(t), nil, SecurityOptions{
+				UserPassword:  []byte("user"),
+				OwnerPassword: []byte("owner"),
+				Permissions:   PermPrint,
+				Cipher:        cipher,
+			}, false)
+			if err != nil {
+				t.Fatalf("SetSecurity failed: %v", err)
+			}
+			// Short encrypted strings are sometimes all printable and written as
+			// literal strings, whose escapes vary in length with the ciphertext
+			in = withHexStrings(t, in)
+
+			out, err := ChangePasswords(in, []byte("owner"), []byte("user2"), []byte("owner2"), false)
+			if err != nil {
+				t.Fatalf("ChangePasswords failed: %v", err)
+			}
+
+			if len(out) != len(in) {
+				t.Errorf("output length = %d, want %d", len(out), len(in))
+			}
+			mask := func(b []byte) []byte { return encryptedRegions.ReplaceAll(b, []byte("#")) }
+			if !bytes.Equal(mask(in), mask(out)) {
+				t.Error("bytes outside strings and streams changed")
+			}
+
+			for _, password := range []string{"user", "owner"} {
+				if _, err := parse.OpenWithOptions(out, parse.ParseOptions{Password: []byte(password)}); err == nil {
+					t.Errorf("old %s password still opens the document", password)
+				}
+			}
+			for _, password := range []string{"user2", "owner2"} {
+				pdf, err := parse.OpenWithOptions(out, parse.ParseOptions{Password: []byte(password)})
+				if err != nil {
+					t.Fatalf("open with %s failed: %v", password, err)
+				}
+				if want := PermPrint | permissionsReserved; pdf.Encryption().P != want {
+					t.Errorf("P = %d, want %d", pdf.Encryption().P, want)
+				}
+				if content := pageContent(t, pdf); !strings.Contains(content, "(Page 1) Tj") {
+					t.Errorf("page content not decrypted: %q", content)
+				}
+			}
+
+			if rects := widgetRects(t, mustDecrypt(t, out, "user2")); len(rects) != 2 {
+				t.Errorf("widgets = %v, want 2", rects)
+			}
+		})
+	}
+}
+
+func TestChangePasswords_XRefStream(t *testing.T) {
+	pdfPath := filepath.Join("..", "..", "tests", "resources", "estar.pdf")
+	in, err := os.ReadFile(pdfPath)
+	if err != nil {
+		t.Skipf("Test PDF not found: %s", pdfPath)
+	}
+
+	out, err := ChangePasswords(in, nil, []byte("user"), []byte("owner"), false)
+	if err != nil {
+		t.Fatalf("ChangePasswords failed: %v", err)
+	}
+
+	before, err := parse.Open(in)
+	if err != nil {
+		t.Fatalf("Failed to parse input: %v", err)
+	}
+	after, err := parse.OpenWithOptions(out, parse.ParseOptions{Password: []byte("user")})
+	if err != nil {
+		t.Fatalf("Failed to open output with new password: %v", err)
+	}
+
+	skip := regexp.MustCompile(`/Type\s*/XRef\b|/Filter\s*/Standard\b`)
+	for _, objNum := range before.Objects() {
+		want, err := before.GetObject(objNum)
+		if err != nil || skip.Match(want) {
+			continue
+		}
+		got, err := after.GetObject(objNum)
+		if err != nil {
+			t.Errorf("object %d: %v", objNum, err)
+			continue
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("object %d differs after re-encryption", objNum)
+		}
+	}
+}
+
+func TestChangePasswords_NotEncrypted(t *testing.T) {
+	if _, err := ChangePasswords(createFormPDF(t), nil, []byte("user"), nil, false); err == nil {
+		t.Error("expected error for unencrypted PDF")
+	}
+}
+
+// mustDecrypt removes the security of an encrypted test document
+// withHexStrings rewrites the literal strings of a document written by PDFWriter
+// as hex strings, which ChangePasswords re-encrypts at the same length, and
+// rebuilds its cross-reference table
+func withHexStrings(t *testing.T, pdfBytes []byte) []byte {
+	t.Helper()
+	xrefStart := bytes.LastIndex(pdfBytes, []byte("\nxref\n")) + 1
+	trailerStart := bytes.LastIndex(pdfBytes, []byte("trailer"))
+	startXRef := bytes.LastIndex(pdfBytes, []byte("startxref"))
+	if xrefStart == 0 || trailerStart < xrefStart || startXRef < trailerStart {
+		t.Fatal("document has no cross-reference table")
+	}
+
+	var out bytes.Buffer
+	offsets := make(map[int]int)
+	last := 0
+	for _, m := range testObjectPattern.FindAllSubmatchIndex(pdfBytes[:xrefStart], -1) {
+		out.Write(pdfBytes[last:m[0]])
+		objNum, _ := strconv.Atoi(string(pdfBytes[m[2]:m[3]]))
+		offsets[objNum] = out.Len()
+
+		obj := pdfBytes[m[0]:m[1]]
+		syntax := obj
+		if i := bytes.Index(obj, []byte("stream")); i != -1 {
+			syntax = obj[:i]
+		}
+		tokens, err := encrypt.FindStrings(syntax)
+		if err != nil {
+			t.Fatalf("object %d: %v", objNum, err)
+		}
+		prev := 0
+		for _, token := range tokens {
+			out.Write(syntax[prev:token.Start])
+			if token.Hex {
+				out.Write(syntax[token.Start:token.End])
+			} else {
+				fmt.Fprintf(&out, "<%x>", token.Value)
+			}
+			prev = token.End
+		}
+		out.Write(obj[prev:])
+		last = m[1]
+	}
+	out.Write(pdfBytes[last:xrefStart])
+
+	xrefOffset := out.Len()
+	size := 0
+	for objNum := range offsets {
+		size = max(size, objNum+1)
+	}
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", size)
+	for objNum := 1; objNum < size; objNum++ {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offsets[objNum])
+	}
+	out.Write(pdfBytes[trailerStart:startXRef])
+	fmt.Fprintf(&out, "startxref\n%d\n%%%%EOF\n", xrefOffset)
+	return out.Bytes()
+}
+
+func mustDecrypt(t *testing.T, pdfBytes []byte, password string) []byte {
+	t.Helper()
+
+	out, err := RemoveSecurity(pdfBytes, []byte(password), false)
+	if err != nil {
+		t.Fatalf("RemoveSecurity failed: %v", err)
+	}
+	return out
+}
@@ -0,0 +1,144 @@
+package manipulate
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// Image placement defaults, in points
+const (
+	placementMaxCaptionSize = 8
+	placementMinCaptionSize = 4
+)
+
+// ImagePlacement is an image to draw in an area of a page, such as a scanned
+// signature in the area of a signature field
+type ImagePlacement struct {
+	Page     int             // 1-based page number
+	Rect     types.Rectangle // Area in default user space
+	Image    []byte          // JPEG or PNG data
+	Caption  string          // Text drawn along the bottom of the area, such as a timestamp (optional)
+	FontName string          // Standard font for the caption (default: Helvetica)
+}
+
+// PlaceImages draws images on pages, each scaled to fit its area while keeping
+// its aspect ratio and centered in it. A caption takes a line at the bottom of
+// the area, set as large as fits up to 8 points, and the image fills the rest.
+func PlaceImages(pdfBytes []byte, password []byte, placements []ImagePlacement, verbose bool) ([]byte, error) {
+	if len(placements) == 0 {
+		return nil, fmt.Errorf("no images to place")
+	}
+
+	m, err := NewPDFManipulator(pdfBytes, password, verbose)
+	if err != nil {
+		return nil, err
+	}
+	for i := range placements {
+		if err := m.PlaceImage(placements[i]); err != nil {
+			return nil, err
+		}
+	}
+	return m.rebuildPDF()
+}
+
+// PlaceImage draws an image on a page like PlaceImages
+func (m *PDFManipulator) PlaceImage(p ImagePlacement) error {
+	if len(p.Image) == 0 {
+		return fmt.Errorf("no image data")
+	}
+	width, height := p.Rect.UpperX-p.Rect.LowerX, p.Rect.UpperY-p.Rect.LowerY
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("invalid image area %gx%g", width, height)
+	}
+	pageObjNum, err := m.getPageObjectNumber(p.Page)
+	if err != nil {
+		return fmt.Errorf("failed to get page object: %w", err)
+	}
+	pageObj, ok := m.objects[pageObjNum]
+	if !ok {
+		return fmt.Errorf("page object %d not found", pageObjNum)
+	}
+	pageStr := m.materializeInheritedAttributes(string(pageObj))
+
+	info, err := m.addImageObjects(p.Image)
+	if err != nil {
+		return err
+	}
+	pageStr, imageName := m.addPageResource(pageStr, "/XObject", "ImPlace", info.ObjectNum)
+
+	var stream strings.Builder
+	box := p.Rect
+	if p.Caption != "" {
+		fontName := p.FontName
+		if fontName == "" {
+			fontName = "Helvetica"
+		}
+		size := math.Min(placementMaxCaptionSize, height/4)
+		if textWidth := write.StandardTextWidth(p.Caption, fontName, 1); textWidth > 0 {
+			size = math.Min(size, (width-2*stampPadding)/textWidth)
+		}
+		size = math.Max(size, placementMinCaptionSize)
+
+		fontObjNum := m.nextObjectNumber()
+		m.objects[fontObjNum] = []byte(fmt.Sprintf("<</Type/Font/Subtype/Type1/BaseFont/%s/Encoding/WinAnsiEncoding>>", fontName))
+		var fontRes string
+		pageStr, fontRes = m.addPageFont(pageStr, fontObjNum)
+
+		x := box.LowerX + (width-write.StandardTextWidth(p.Caption, fontName, size))/2
+		baseline := box.LowerY + stampPadding + 0.2*size
+		stream.WriteString(fmt.Sprintf("BT\n/%s %s Tf\n1 0 0 1 %s Tm\n(%s) Tj\nET\n",
+			fontRes, formatNumbers([]float64{size}), formatNumbers([]float64{math.Max(x, box.LowerX), baseline}), encodeStampText(p.Caption)))
+		box.LowerY += size + 2*stampPadding
+	}
+
+	if boxWidth, boxHeight := box.UpperX-box.LowerX, box.UpperY-box.LowerY; boxHeight > 0 && info.Width > 0 && info.Height > 0 {
+		scale := math.Min(boxWidth/float64(info.Width), boxHeight/float64(info.Height))
+		w, h := float64(info.Width)*scale, float64(info.Height)*scale
+		stream.WriteString(fmt.Sprintf("q\n%s cm\n/%s Do\nQ\n",
+			formatNumbers([]float64{w, 0, 0, h, box.LowerX + (boxWidth-w)/2, box.LowerY + (boxHeight-h)/2}), imageName))
+	}
+
+	// Isolate the existing content's graphics state from the image
+	preObjNum := m.nextObjectNumber()
+	m.objects[preObjNum] = rawStreamObject("q\n")
+	imageObjNum := m.nextObjectNumber()
+	m.objects[imageObjNum] = rawStreamObject("\nQ\nq\n" + stream.String() + "Q\n")
+
+	existing := m.pageContentRefs(pageStr)
+	contents := fmt.Sprintf("[%d 0 R %s %d 0 R]", preObjNum, strings.Join(existing, " "), imageObjNum)
+	if len(existing) == 0 {
+		contents = fmt.Sprintf("[%d 0 R %d 0 R]", preObjNum, imageObjNum)
+	}
+	m.objects[pageObjNum] = []byte(setRawDictValue(pageStr, "/Contents", contents))
+
+	if m.verbose {
+		fmt.Printf("Placed %dx%d image on page %d at %s\n", info.Width, info.Height, p.Page,
+			formatNumberArray([]float64{p.Rect.LowerX, p.Rect.LowerY, p.Rect.UpperX, p.Rect.UpperY}))
+	}
+	return nil
+}
+
+// addImageObjects adds the image XObject for JPEG or PNG data, and its soft mask
+// if it has one, and returns the image's info
+func (m *PDFManipulator) addImageObjects(data []byte) (*write.ImageInfo, error) {
+	// Number the writer's objects after the document's so they can be copied as they are
+	first := m.nextObjectNumber()
+	w := write.NewPDFWriter()
+	w.SetObject(first-1, nil)
+	info, err := w.AddImage(data, "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid image: %w", err)
+	}
+	for objNum := first; objNum < w.NextObjectNumber(); objNum++ {
+		body, err := w.FormatObject(objNum)
+		if err != nil {
+			return nil, err
+		}
+		m.objects[objNum] = body
+	}
+	return info, nil
+}
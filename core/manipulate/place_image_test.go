@@ -0,0 +1,69 @@
+package manipulate
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"math"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+func TestPlaceImages(t *testing.T) {
+	pdfBytes, _, err := extract.CreateTestPDFWithText([]extract.TestText{
+		{Text: "Signature:", X: 72, Y: 700, FontSize: 12},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewNRGBA(image.Rect(0, 0, 40, 10))); err != nil {
+		t.Fatalf("Failed to encode image: %v", err)
+	}
+
+	rect := types.Rectangle{LowerX: 150, LowerY: 690, UpperX: 350, UpperY: 740}
+	out, err := PlaceImages(pdfBytes, nil, []ImagePlacement{
+		{Page: 1, Rect: rect, Image: img.Bytes(), Caption: "2024-03-15 10:30"},
+	}, false)
+	if err != nil {
+		t.Fatalf("PlaceImages failed: %v", err)
+	}
+
+	doc, err := extract.ExtractContent(out, nil, false)
+	if err != nil {
+		t.Fatalf("Failed to extract PDF: %v", err)
+	}
+	page := doc.Pages[0]
+	if len(page.Images) != 1 {
+		t.Fatalf("Expected 1 image on the page, got %d", len(page.Images))
+	}
+	ref := page.Images[0]
+	if ref.X < rect.LowerX || ref.X+ref.Width > rect.UpperX+0.01 || ref.Y < rect.LowerY || ref.Y+ref.Height > rect.UpperY+0.01 {
+		t.Errorf("Image at %g,%g %gx%g outside %+v", ref.X, ref.Y, ref.Width, ref.Height, rect)
+	}
+	if math.Abs(ref.Width/ref.Height-4) > 0.01 {
+		t.Errorf("Image aspect ratio not kept: %gx%g", ref.Width, ref.Height)
+	}
+
+	var caption *types.TextElement
+	for i := range page.Text {
+		if page.Text[i].Text == "2024-03-15 10:30" {
+			caption = &page.Text[i]
+		}
+	}
+	if caption == nil {
+		t.Fatalf("Caption not found in %+v", page.Text)
+	}
+	if caption.Y >= ref.Y || caption.Y < rect.LowerY {
+		t.Errorf("Caption baseline %g should be below the image at %g, inside the area", caption.Y, ref.Y)
+	}
+
+	if _, err := PlaceImages(pdfBytes, nil, []ImagePlacement{{Page: 2, Rect: rect, Image: img.Bytes()}}, false); err == nil {
+		t.Error("Expected an error for a page out of range")
+	}
+	if _, err := PlaceImages(pdfBytes, nil, []ImagePlacement{{Page: 1, Rect: rect, Image: []byte("not an image")}}, false); err == nil {
+		t.Error("Expected an error for invalid image data")
+	}
+}
@@ -0,0 +1,184 @@
+package manipulate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// RedactionArea is an area of a page to redact
+type RedactionArea struct {
+	Page int             // 1-based page number
+	Rect types.Rectangle // Area in default user space
+}
+
+// RedactOptions configures Redact
+type RedactOptions struct {
+	Color *Color // Fill color of the redaction boxes (default: black)
+}
+
+// RedactionAreas returns the areas covered by text search matches, for
+// redacting what extract.FindText found
+func RedactionAreas(matches []types.TextMatch) []RedactionArea {
+	var areas []RedactionArea
+	for _, match := range matches {
+		for _, rect := range match.Rects {
+			areas = append(areas, RedactionArea{Page: match.PageNumber, Rect: rect})
+		}
+	}
+	return areas
+}
+
+// Redact removes the text shown inside the given areas from page content and
+// paints a filled box over each area. A text-showing operator that overlaps an
+// area is removed whole, so text set in the same operator as the redacted
+// text goes with it. Images, vector graphics, annotations and form XObjects
+// under an area are covered but not removed. A content stream with a filter
+// other than FlateDecode cannot be rewritten and fails the redaction rather
+// than leaving the text in place.
+func Redact(pdfBytes []byte, password []byte, areas []RedactionArea, opts RedactOptions, verbose bool) ([]byte, error) {
+	if len(areas) == 0 {
+		return nil, fmt.Errorf("no areas to redact")
+	}
+
+	m, err := NewPDFManipulator(pdfBytes, password, verbose)
+	if err != nil {
+		return nil, err
+	}
+	pageObjNums, err := m.getAllPageObjectNumbers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pages: %w", err)
+	}
+
+	byPage := make(map[int][]types.Rectangle)
+	var pageNumbers []int
+	for _, area := range areas {
+		if area.Page < 1 || area.Page > len(pageObjNums) {
+			return nil, fmt.Errorf("page %d out of range (document has %d pages)", area.Page, len(pageObjNums))
+		}
+		if _, seen := byPage[area.Page]; !seen {
+			pageNumbers = append(pageNumbers, area.Page)
+		}
+		byPage[area.Page] = append(byPage[area.Page], area.Rect)
+	}
+
+	// Content streams shared with pages that are not being redacted are
+	// copied rather than rewritten in place
+	users := make(map[string]int)
+	for _, pageObjNum := range pageObjNums {
+		for _, ref := range m.pageContentRefs(string(m.objects[pageObjNum])) {
+			users[ref]++
+		}
+	}
+
+	for _, pageNumber := range pageNumbers {
+		if err := m.redactPage(pageObjNums[pageNumber-1], byPage[pageNumber], users, &opts); err != nil {
+			return nil, fmt.Errorf("failed to redact page %d: %w", pageNumber, err)
+		}
+	}
+
+	return m.rebuildPDF()
+}
+
+// redactPage removes the text inside rects from a page's content streams and
+// paints boxes over them
+func (m *PDFManipulator) redactPage(pageObjNum int, rects []types.Rectangle, users map[string]int, opts *RedactOptions) error {
+	pageObj, ok := m.objects[pageObjNum]
+	if !ok {
+		return fmt.Errorf("page object %d not found", pageObjNum)
+	}
+	pageStr := string(pageObj)
+
+	existing := m.pageContentRefs(pageStr)
+	for i, ref := range existing {
+		objNum, err := parseObjectRef(ref)
+		if err != nil {
+			return err
+		}
+		obj, ok := m.objects[objNum]
+		if !ok {
+			return fmt.Errorf("content stream %d not found", objNum)
+		}
+		dict, data, isStream := splitStreamObject(objectBody(obj))
+		if !isStream {
+			return fmt.Errorf("content object %d is not a stream", objNum)
+		}
+		dict, data, ok = decodedStream(dict, data)
+		if !ok {
+			return fmt.Errorf("content stream %d uses a filter that cannot be rewritten", objNum)
+		}
+
+		redacted, removed := redactContent(string(data), rects)
+		if removed == 0 {
+			continue
+		}
+		if m.verbose {
+			fmt.Printf("Removed %d text operators from content stream %d\n", removed, objNum)
+		}
+		updated := joinStreamObject(dict, []byte(redacted), true)
+		if users[ref] > 1 {
+			objNum = m.nextObjectNumber()
+			existing[i] = fmt.Sprintf("%d 0 R", objNum)
+		}
+		m.objects[objNum] = updated
+	}
+
+	color := opts.Color
+	if color == nil {
+		color = &Color{}
+	}
+	var boxes strings.Builder
+	boxes.WriteString(fmt.Sprintf("%s rg\n", formatNumbers([]float64{color.R, color.G, color.B})))
+	for _, r := range rects {
+		boxes.WriteString(fmt.Sprintf("%s re\n", formatNumbers([]float64{r.LowerX, r.LowerY, r.UpperX - r.LowerX, r.UpperY - r.LowerY})))
+	}
+	boxes.WriteString("f\n")
+
+	// Isolate the existing content's graphics state from the boxes
+	preObjNum := m.nextObjectNumber()
+	m.objects[preObjNum] = rawStreamObject("q\n")
+	boxObjNum := m.nextObjectNumber()
+	m.objects[boxObjNum] = rawStreamObject("\nQ\nq\n" + boxes.String() + "Q\n")
+
+	contents := fmt.Sprintf("[%d 0 R %s %d 0 R]", preObjNum, strings.Join(existing, " "), boxObjNum)
+	if len(existing) == 0 {
+		contents = fmt.Sprintf("[%d 0 R %d 0 R]", preObjNum, boxObjNum)
+	}
+	m.objects[pageObjNum] = []byte(setRawDictValue(pageStr, "/Contents", contents))
+	return nil
+}
+
+// redactContent removes the text-showing operators whose text overlaps any of
+// rects from a decoded content stream and returns the result with the number
+// of operators removed. The ' operator keeps its move to the next line.
+func redactContent(content string, rects []types.Rectangle) (string, int) {
+	elements, lineIndexes := extract.ContentStreamText(content)
+	lines := strings.Split(content, "\n")
+	removed := 0
+	for i, e := range elements {
+		box := types.Rectangle{LowerX: e.X, LowerY: e.Y - 0.2*e.Height, UpperX: e.X + e.Width, UpperY: e.Y + e.Height}
+		if !overlapsAny(box, rects) {
+			continue
+		}
+		line := strings.TrimSpace(lines[lineIndexes[i]])
+		if strings.HasSuffix(line, "'") {
+			lines[lineIndexes[i]] = "T*"
+		} else {
+			lines[lineIndexes[i]] = ""
+		}
+		removed++
+	}
+	return strings.Join(lines, "\n"), removed
+}
+
+// overlapsAny reports whether r overlaps any of rects
+func overlapsAny(r types.Rectangle, rects []types.Rectangle) bool {
+	for _, o := range rects {
+		if r.LowerX < o.UpperX && r.UpperX > o.LowerX && r.LowerY < o.UpperY && r.UpperY > o.LowerY {
+			return true
+		}
+	}
+	return false
+}
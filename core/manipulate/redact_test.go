@@ -0,0 +1,74 @@
+package manipulate
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+func TestRedact_Matches(t *testing.T) {
+	pdfBytes, _, err := extract.CreateTestPDFWithText([]extract.TestText{
+		{Text: "Patient: Jane Doe", X: 72, Y: 720, FontSize: 12},
+		{Text: "SSN: 123-45-6789", X: 72, Y: 700, FontSize: 12},
+		{Text: "Serial SN-00042", X: 72, Y: 680, FontSize: 12},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+	pdf, err := parse.Open(pdfBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse PDF: %v", err)
+	}
+	matches, err := extract.FindText(pdfBytes, pdf, regexp.MustCompile(`\d{3}-\d{2}-\d{4}|SN-\d+`), false)
+	if err != nil || len(matches) != 2 {
+		t.Fatalf("FindText = %+v, %v", matches, err)
+	}
+
+	out, err := Redact(pdfBytes, nil, RedactionAreas(matches), RedactOptions{}, false)
+	if err != nil {
+		t.Fatalf("Redact failed: %v", err)
+	}
+	if bytes.Contains(out, []byte("123-45-6789")) || bytes.Contains(out, []byte("SN-00042")) {
+		t.Error("Redacted text is still in the file")
+	}
+
+	doc, err := extract.ExtractContent(out, nil, false)
+	if err != nil {
+		t.Fatalf("Failed to extract redacted PDF: %v", err)
+	}
+	var text []string
+	for _, e := range doc.Pages[0].Text {
+		text = append(text, e.Text)
+	}
+	if got := strings.Join(text, "|"); got != "Patient: Jane Doe" {
+		t.Errorf("Text after redaction = %q", got)
+	}
+	boxes := 0
+	for _, g := range doc.Pages[0].Graphics {
+		if g.Type == types.GraphicTypeRectangle {
+			boxes++
+		}
+	}
+	if boxes != 2 {
+		t.Errorf("Expected 2 redaction boxes, got %d", boxes)
+	}
+}
+
+func TestRedact_Errors(t *testing.T) {
+	pdfBytes, _, err := extract.CreateTestPDFWithText([]extract.TestText{{Text: "Hello", X: 72, Y: 720, FontSize: 12}})
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+	if _, err := Redact(pdfBytes, nil, nil, RedactOptions{}, false); err == nil {
+		t.Error("Expected an error without areas")
+	}
+	area := RedactionArea{Page: 2, Rect: types.Rectangle{UpperX: 10, UpperY: 10}}
+	if _, err := Redact(pdfBytes, nil, []RedactionArea{area}, RedactOptions{}, false); err == nil {
+		t.Error("Expected an error for a page out of range")
+	}
+}
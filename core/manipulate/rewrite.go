@@ -0,0 +1,301 @@
+package manipulate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/core/encrypt"
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// RewriteOptions control how Rewrite writes a document
+type RewriteOptions struct {
+	Password           []byte                   // Password to open the input if it is encrypted
+	ObjectStreams      bool                     // Pack objects into object streams with a cross-reference stream
+	KeepUnreferenced   bool                     // Keep objects that are not reachable from the trailer
+	StripImageMetadata bool                     // Remove EXIF, XMP and ICC metadata from images and from JPEG and PNG attachments
+	Compression        *write.CompressionPolicy // How streams are recompressed; nil uses RewriteCompression
+	Verify             bool                     // Check the output with write.Verify
+	Verbose            bool
+}
+
+// RewriteCompression is the default compression policy of Rewrite: the best
+// compression, except for XMP metadata, which is left readable by tools that
+// don't parse PDF, and data that doesn't compress
+func RewriteCompression() write.CompressionPolicy {
+	policy := write.BestCompression()
+	policy.Store = map[write.StreamKind]bool{write.StreamMetadata: true}
+	return policy
+}
+
+// Rewrite parses a document and writes it again through the writer. Objects
+// reachable from the trailer's /Root and /Info are renumbered in the order they
+// are reached, and unreachable ones are dropped. Flate and unfiltered streams are
+// recompressed according to opts.Compression, streams with other filters such as
+// JPEG images are copied as they are, and the cross-reference table is rebuilt. References to missing objects become null.
+// Encrypted documents are written with the same security settings.
+//
+// Rewrite repairs documents the parser can read but other tools can't, such as
+// files with wrong offsets or stream lengths. A usage rights signature, which
+// the rewritten file would no longer match, is removed.
+//
+// With opts.StripImageMetadata, EXIF, XMP, ICC profile and comment segments are
+// removed from JPEG images, image XObjects lose their XMP /Metadata streams, and
+// JPEG and PNG attachments are stripped the same way, so that details such as
+// the GPS position of a scan don't leak with the document.
+func Rewrite(pdfBytes []byte, opts RewriteOptions) ([]byte, error) {
+	return rewrite(pdfBytes, opts, rewriteHooks{})
+}
+
+// rewriteHooks let operations built on Rewrite change the document as it is
+// written. Hooks left nil are skipped.
+type rewriteHooks struct {
+	// prepare is called with the parsed document before any object is read
+	prepare func(pdf *parse.PDF) error
+	// stream is called with the dictionary and data, still encoded, of each
+	// stream object before it is recompressed
+	stream func(objNum int, dict, data []byte) ([]byte, []byte)
+	// catalog is called with the catalog dictionary before objects are
+	// numbered; add stores a new object and returns its number
+	catalog func(catalog string, add func(obj []byte) int) string
+}
+
+// rewrite is Rewrite with hooks
+func rewrite(pdfBytes []byte, opts RewriteOptions, hooks rewriteHooks) ([]byte, error) {
+	pdf, err := parse.OpenWithOptions(pdfBytes, parse.ParseOptions{
+		Password: opts.Password,
+		Verbose:  opts.Verbose,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PDF: %w", err)
+	}
+	trailer := pdf.Trailer()
+	if trailer == nil {
+		return nil, fmt.Errorf("no trailer found")
+	}
+	rootObjNum, err := parseObjectRef(trailer.RootRef)
+	if err != nil {
+		return nil, fmt.Errorf("invalid root reference %q", trailer.RootRef)
+	}
+
+	policy := RewriteCompression()
+	if opts.Compression != nil {
+		policy = *opts.Compression
+	}
+
+	var enc *types.PDFEncryption
+	if pdf.IsEncrypted() {
+		if opts.ObjectStreams {
+			return nil, types.NewPDFError(types.ErrCodeInvalidInput, "object streams are not supported for encrypted documents")
+		}
+		enc = pdf.Encryption()
+		if len(enc.EncryptKey) == 0 {
+			// Opened without the password of a document encrypting only its attachments
+			return nil, types.NewPDFError(types.ErrCodeWrongPassword, "password required to rewrite encrypted attachments")
+		}
+	}
+	if hooks.prepare != nil {
+		if err := hooks.prepare(pdf); err != nil {
+			return nil, err
+		}
+	}
+	skip := make(map[int]bool)
+	if trailer.EncryptRef != "" {
+		if encryptObjNum, err := parseObjectRef(trailer.EncryptRef); err == nil {
+			skip[encryptObjNum] = true
+		}
+	}
+
+	// Split every object into its dictionary and decoded stream data
+	type object struct {
+		dict, data []byte
+		isStream   bool
+	}
+	objects := make(map[int]*object)
+	stripped := 0
+	for _, objNum := range pdf.Objects() {
+		if skip[objNum] {
+			continue
+		}
+		obj, err := pdf.GetObject(objNum)
+		if err != nil {
+			if opts.Verbose {
+				fmt.Printf("Warning: failed to get object %d: %v\n", objNum, err)
+			}
+			continue
+		}
+		dict, data, isStream := splitStreamObject(objectBody(obj))
+		if isStream && streamSkipPattern.Match(dict) {
+			continue
+		}
+		if isStream && opts.StripImageMetadata {
+			var changed bool
+			if dict, data, changed = stripImageMetadata(dict, data); changed {
+				stripped++
+			}
+		}
+		if isStream && hooks.stream != nil {
+			dict, data = hooks.stream(objNum, dict, data)
+		}
+		if isStream {
+			dict, data = recompressStream(dict, data, policy)
+			// The length is written directly, so an indirect length is no longer referenced
+			dict = setStreamLength(dict, len(data))
+		}
+		objects[objNum] = &object{dict: dict, data: data, isStream: isStream}
+	}
+	if _, ok := objects[rootObjNum]; !ok {
+		return nil, fmt.Errorf("catalog object %d not found", rootObjNum)
+	}
+
+	// A usage rights signature would not match the rewritten file
+	catalog, rights := stripUsageRights(string(objects[rootObjNum].dict), func(objNum int) string {
+		if obj, ok := objects[objNum]; ok {
+			return string(obj.dict)
+		}
+		return ""
+	}, func(objNum int, content string) {
+		objects[objNum].dict = []byte(content)
+	})
+	if rights != nil {
+		objects[rootObjNum].dict = []byte(catalog)
+		if opts.Verbose {
+			fmt.Printf("Warning: removed /%s usage rights signature\n", rights.Key)
+		}
+	}
+
+	if hooks.catalog != nil {
+		next := 0
+		for objNum := range objects {
+			next = max(next, objNum)
+		}
+		catalog := hooks.catalog(string(objects[rootObjNum].dict), func(obj []byte) int {
+			next++
+			dict, data, isStream := splitStreamObject(obj)
+			if isStream {
+				dict, data = recompressStream(dict, data, policy)
+				dict = setStreamLength(dict, len(data))
+			}
+			objects[next] = &object{dict: dict, data: data, isStream: isStream}
+			return next
+		})
+		objects[rootObjNum].dict = []byte(catalog)
+	}
+
+	// Number objects in the order they are reached from the trailer
+	roots := []int{rootObjNum}
+	infoObjNum, err := parseObjectRef(trailer.InfoRef)
+	if err == nil {
+		if _, ok := objects[infoObjNum]; ok {
+			roots = append(roots, infoObjNum)
+		}
+	}
+	numbers := make(map[int]int)
+	order := []int{}
+	visit := func(objNum int) {
+		if _, ok := objects[objNum]; !ok {
+			return
+		}
+		if _, seen := numbers[objNum]; !seen {
+			numbers[objNum] = len(order) + 1
+			order = append(order, objNum)
+		}
+	}
+	for _, objNum := range roots {
+		visit(objNum)
+	}
+	for i := 0; i < len(order); i++ {
+		for _, match := range refPattern.FindAllSubmatch(objects[order[i]].dict, -1) {
+			if objNum, err := strconv.Atoi(string(match[1])); err == nil {
+				visit(objNum)
+			}
+		}
+	}
+	if opts.KeepUnreferenced {
+		var rest []int
+		for objNum := range objects {
+			if _, seen := numbers[objNum]; !seen {
+				rest = append(rest, objNum)
+			}
+		}
+		sort.Ints(rest)
+		for _, objNum := range rest {
+			visit(objNum)
+		}
+	}
+
+	writer := write.NewPDFWriter()
+	writer.SetVerify(opts.Verify)
+	if opts.ObjectStreams {
+		writer.UseObjectStream(true)
+	}
+	for _, oldNum := range order {
+		obj := objects[oldNum]
+		newNum := numbers[oldNum]
+		dict := refPattern.ReplaceAllFunc(obj.dict, func(ref []byte) []byte {
+			match := refPattern.FindSubmatch(ref)
+			objNum, _ := strconv.Atoi(string(match[1]))
+			if n, ok := numbers[objNum]; ok {
+				return []byte(fmt.Sprintf("%d 0 R", n))
+			}
+			return []byte("null")
+		})
+		data := obj.data
+		if enc != nil {
+			if dict, data, err = encryptObjectParts(newNum, dict, data, obj.isStream, enc); err != nil {
+				return nil, err
+			}
+		}
+		writer.SetObject(newNum, joinStreamObject(dict, data, obj.isStream))
+	}
+
+	writer.SetRoot(numbers[rootObjNum])
+	if n, ok := numbers[infoObjNum]; ok {
+		writer.SetInfo(n)
+	}
+	if enc != nil {
+		writer.SetEncryptRef(writer.AddObject(write.CreateEncryptionDictionary(enc)))
+	}
+	if fileID := encrypt.ExtractFileID(pdfBytes, false); len(fileID) > 0 {
+		writer.SetFileID(fileID)
+	}
+
+	if opts.Verbose {
+		if opts.StripImageMetadata {
+			fmt.Printf("Stripped metadata from %d images\n", stripped)
+		}
+		fmt.Printf("Rewrote %d of %d objects\n", len(order), len(objects))
+	}
+	return writer.Bytes()
+}
+
+// recompressStream recompresses unfiltered and Flate stream data according to
+// a compression policy. Streams with other filters are returned unchanged.
+func recompressStream(dict, data []byte, policy write.CompressionPolicy) ([]byte, []byte) {
+	filter := rawDictValue(string(dict), "/Filter")
+	switch strings.Join(strings.Fields(strings.Trim(filter, "[]")), " ") {
+	case "":
+		if compressed, ok := policy.Compress(dict, data); ok {
+			return []byte(setRawDictValue(string(dict), "/Filter", "/FlateDecode")), compressed
+		}
+	case "/FlateDecode":
+		decoded, err := parse.DecodeFlateDecode(data)
+		if err != nil {
+			return dict, data
+		}
+		// Predictor parameters apply to the decoded data, so it stays Flate-encoded
+		if rawDictKeyIndex(string(dict), "/DecodeParms") != -1 {
+			return dict, policy.Flate(write.ClassifyStream(dict), decoded)
+		}
+		unfiltered := []byte(removeRawDictKey(string(dict), "/Filter"))
+		if compressed, ok := policy.Compress(unfiltered, decoded); ok {
+			return dict, compressed
+		}
+		return unfiltered, decoded
+	}
+	return dict, data
+}
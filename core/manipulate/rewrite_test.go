@@ -0,0 +1,184 @@
+package manipulate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/core/write"
+)
+
+// pageTexts returns the text of each page of a document
+func pageTexts(t *testing.T, pdfBytes []byte, password []byte) []string {
+	t.Helper()
+	doc, err := extract.ExtractContent(pdfBytes, password, false)
+	if err != nil {
+		t.Fatalf("ExtractContent failed: %v", err)
+	}
+	var texts []string
+	for _, page := range doc.Pages {
+		var parts []string
+		for _, text := range page.Text {
+			parts = append(parts, text.Text)
+		}
+		texts = append(texts, strings.Join(parts, " "))
+	}
+	return texts
+}
+
+func TestRewrite(t *testing.T) {
+	// A page with an unreferenced object and a reference to a missing object
+	writer := write.NewPDFWriter()
+	content := strings.Repeat("BT\n/F1 12 Tf\n72 700 Td\n(Hello) Tj\nET\n", 4)
+	writer.AddObject([]byte(fmt.Sprintf("<</Length %d>>\nstream\n%s\nendstream", len(content), content)))
+	writer.AddObject([]byte("(orphan)"))
+	writer.AddObject([]byte("<</Type/Font/Subtype/Type1/BaseFont/Helvetica>>"))
+	writer.AddObject([]byte("<</Type/Page/Parent 5 0 R/MediaBox [0 0 612 792]/Contents 1 0 R/Resources <</Font <</F1 3 0 R>>>>/Thumb 99 0 R>>"))
+	writer.AddObject([]byte("<</Type/Pages/Kids [4 0 R]/Count 1>>"))
+	writer.SetRoot(writer.AddObject([]byte("<</Type/Catalog/Pages 5 0 R>>")))
+	input, err := writer.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create test PDF: %v", err)
+	}
+
+	out, err := Rewrite(input, RewriteOptions{Verify: true})
+	if err != nil {
+		t.Fatalf("Rewrite failed: %v", err)
+	}
+	if strings.Contains(string(out), "orphan") {
+		t.Error("Unreferenced object kept")
+	}
+	if !strings.Contains(string(out), "/Thumb null") {
+		t.Error("Reference to missing object not replaced with null")
+	}
+	if !strings.Contains(string(out), "/FlateDecode") {
+		t.Error("Content streams not compressed")
+	}
+	if !strings.HasPrefix(string(out[strings.Index(string(out), "1 0 obj"):]), "1 0 obj\n<</Type/Catalog") {
+		t.Error("Catalog not numbered first")
+	}
+
+	if got := pageTexts(t, out, nil); len(got) != 1 || !strings.HasPrefix(got[0], "Hello") {
+		t.Errorf("Text = %q", got)
+	}
+
+	// Rewriting is idempotent
+	again, err := Rewrite(out, RewriteOptions{})
+	if err != nil {
+		t.Fatalf("Second rewrite failed: %v", err)
+	}
+	if string(again) != string(out) {
+		t.Error("Rewriting a rewritten document changed it")
+	}
+
+	kept, err := Rewrite(input, RewriteOptions{KeepUnreferenced: true})
+	if err != nil {
+		t.Fatalf("Rewrite failed: %v", err)
+	}
+	if !strings.Contains(string(kept), "(orphan)") {
+		t.Error("Unreferenced object dropped with KeepUnreferenced")
+	}
+}
+
+func TestRewrite_Compression(t *testing.T) {
+	// Content streams stored raw
+	policy := write.CompressionPolicy{Store: map[write.StreamKind]bool{write.StreamContent: true}}
+	out, err := Rewrite(createFormPDF(t), RewriteOptions{Compression: &policy, Verify: true})
+	if err != nil {
+		t.Fatalf("Rewrite failed: %v", err)
+	}
+	if strings.Contains(string(out), "/FlateDecode") || !strings.Contains(string(out), "(Page 2) Tj") {
+		t.Error("Content streams compressed despite the policy")
+	}
+
+	// Flate streams are decoded when stored
+	flate := write.CompressionPolicy{Level: 9}
+	compressed, err := Rewrite(createFormPDF(t), RewriteOptions{Compression: &flate})
+	if err != nil {
+		t.Fatalf("Rewrite failed: %v", err)
+	}
+	if !strings.Contains(string(compressed), "/FlateDecode") {
+		t.Fatal("Content streams not compressed")
+	}
+	stored, err := Rewrite(compressed, RewriteOptions{Compression: &policy})
+	if err != nil {
+		t.Fatalf("Rewrite failed: %v", err)
+	}
+	if string(stored) != string(out) {
+		t.Error("Recompressed streams not stored raw")
+	}
+}
+
+func TestRewrite_ObjectStreams(t *testing.T) {
+	out, err := Rewrite(createFormPDF(t), RewriteOptions{ObjectStreams: true, Verify: true})
+	if err != nil {
+		t.Fatalf("Rewrite failed: %v", err)
+	}
+	if !strings.Contains(string(out), "/ObjStm") {
+		t.Error("No object streams written")
+	}
+	if got := pageTexts(t, out, nil); len(got) != 2 || !strings.Contains(got[1], "Page 2") {
+		t.Errorf("Text = %q", got)
+	}
+}
+
+func TestRewrite_Encrypted(t *testing.T) {
+	encrypted, err := SetSecurity(createFormPDF(t), nil, SecurityOptions{
+		UserPassword: []byte("secret"),
+		Cipher:       CipherAES256,
+	}, false)
+	if err != nil {
+		t.Fatalf("SetSecurity failed: %v", err)
+	}
+
+	out, err := Rewrite(encrypted, RewriteOptions{Password: []byte("secret")})
+	if err != nil {
+		t.Fatalf("Rewrite failed: %v", err)
+	}
+	if strings.Contains(string(out), "field1") {
+		t.Error("Output contains plaintext strings")
+	}
+	pdf, err := parse.OpenWithOptions(out, parse.ParseOptions{Password: []byte("secret")})
+	if err != nil {
+		t.Fatalf("Failed to open output: %v", err)
+	}
+	if !pdf.IsEncrypted() {
+		t.Fatal("Output is not encrypted")
+	}
+	if got := pageTexts(t, out, []byte("secret")); len(got) != 2 || !strings.Contains(got[0], "Page 1") {
+		t.Errorf("Text = %q", got)
+	}
+
+	if _, err := Rewrite(encrypted, RewriteOptions{Password: []byte("secret"), ObjectStreams: true}); err == nil {
+		t.Error("Expected object streams to be rejected for encrypted input")
+	}
+}
+
+func TestRewrite_Resources(t *testing.T) {
+	paths, _ := filepath.Glob(filepath.Join("..", "..", "tests", "resources", "*.pdf"))
+	if len(paths) == 0 {
+		t.Skip("No test PDFs found")
+	}
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			pdfBytes, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read PDF: %v", err)
+			}
+			out, err := Rewrite(pdfBytes, RewriteOptions{})
+			if err != nil {
+				t.Fatalf("Rewrite failed: %v", err)
+			}
+			if issues := write.Verify(out); len(issues) > 0 {
+				t.Errorf("Output has issues: %v", issues)
+			}
+			if got, want := pageTexts(t, out, nil), pageTexts(t, pdfBytes, nil); strings.Join(got, "|") != strings.Join(want, "|") {
+				t.Errorf("Text changed by rewrite")
+			}
+		})
+	}
+}
@@ -0,0 +1,366 @@
+package manipulate
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/benedoc-inc/pdfer/core/encrypt"
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// Ciphers accepted by SecurityOptions
+const (
+	CipherRC4128 = "rc4-128" // RC4 with a 128-bit key (V2/R3)
+	CipherAES128 = "aes-128" // AES-128 (V4/R4)
+	CipherAES256 = "aes-256" // AES-256 (V5)
+)
+
+// Permission flags for SecurityOptions.Permissions (ISO 32000-1 Table 22)
+const (
+	PermPrint            int32 = 1 << 2  // Print the document
+	PermModify           int32 = 1 << 3  // Modify contents
+	PermCopy             int32 = 1 << 4  // Copy or extract text and graphics
+	PermAnnotate         int32 = 1 << 5  // Add or modify annotations and fill form fields
+	PermFillForms        int32 = 1 << 8  // Fill form fields
+	PermExtract          int32 = 1 << 9  // Extract text and graphics for accessibility
+	PermAssemble         int32 = 1 << 10 // Insert, rotate or delete pages
+	PermPrintHighQuality int32 = 1 << 11 // Print at full resolution
+
+	PermAll = PermPrint | PermModify | PermCopy | PermAnnotate | PermFillForms | PermExtract | PermAssemble | PermPrintHighQuality
+)
+
+// permissionsReserved has the /P bits the specification requires to be set (7-8 and 13-32)
+const permissionsReserved int32 = -3904
+
+// SecurityOptions describe the security applied by SetSecurity
+type SecurityOptions struct {
+	UserPassword      []byte // Password required to open the document (empty opens without prompting)
+	OwnerPassword     []byte // Password granting full access (defaults to UserPassword)
+	Permissions       int32  // Operations allowed with the user password (Perm* flags)
+	Cipher            string // CipherRC4128, CipherAES128 or CipherAES256 (default)
+	PlaintextMetadata bool   // Leave XMP metadata streams unencrypted (AES ciphers only)
+	AttachmentsOnly   bool   // Encrypt only embedded files; the document opens without a password (AES ciphers only)
+}
+
+var (
+	objectHeaderPattern = regexp.MustCompile(`^\s*\d+\s+\d+\s+obj\s*`)
+	streamLengthPattern = regexp.MustCompile(`/Length\s+(\d+)(\s+\d+\s+R)?`)
+	streamSkipPattern   = regexp.MustCompile(`/Type\s*/(XRef|ObjStm)\b`)
+	metadataTypePattern = regexp.MustCompile(`/Type\s*/Metadata\b`)
+)
+
+// SetSecurity encrypts a document with new passwords, permissions and cipher.
+// password opens the input when it is already encrypted, so this also changes
+// the security of an encrypted document. Page content and other objects are
+// carried over unchanged apart from their encryption.
+func SetSecurity(pdfBytes []byte, password []byte, opts SecurityOptions, verbose bool) ([]byte, error) {
+	pdf, err := parse.OpenWithOptions(pdfBytes, parse.ParseOptions{
+		Password: password,
+		Verbose:  verbose,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PDF: %w", err)
+	}
+	return applySecurity(pdf, pdfBytes, opts, verbose)
+}
+
+// EncryptPDF encrypts an unencrypted document, such as the output of a fill of a
+// decrypted form. With opts nil, original is applied again as it was: its cipher,
+// permissions, passwords and key, so the passwords that opened the original open
+// the output. original must then carry its key, as returned by encrypt.DecryptPDF
+// or parse.PDF.Encryption, and the document must keep the original's file ID,
+// which fills do. Otherwise the document is encrypted with the passwords and
+// permissions of opts and, unless opts.Cipher is set, the cipher of original
+// (AES-256 when original is nil).
+//
+// EncryptPDF is here rather than in core/encrypt because it parses and rewrites
+// the whole document with core/parse and core/write, which both use
+// core/encrypt. The new passwords and permissions are the SecurityOptions of
+// SetSecurity, and original is a parameter of its own, so that it can set the
+// default cipher for opts as well.
+func EncryptPDF(pdfBytes []byte, original *types.PDFEncryption, opts *SecurityOptions, verbose bool) ([]byte, error) {
+	pdf, err := parse.OpenWithOptions(pdfBytes, parse.ParseOptions{Verbose: verbose})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PDF: %w", err)
+	}
+	if pdf.IsEncrypted() {
+		return nil, types.NewPDFError(types.ErrCodeInvalidInput, "PDF is already encrypted")
+	}
+
+	if opts != nil {
+		newOpts := *opts
+		if newOpts.Cipher == "" && original != nil {
+			newOpts.Cipher = cipherOf(original)
+		}
+		return applySecurity(pdf, pdfBytes, newOpts, verbose)
+	}
+
+	if original == nil || len(original.EncryptKey) == 0 {
+		return nil, types.NewPDFError(types.ErrCodeInvalidInput, "original encryption with its key is required to apply it again")
+	}
+	// Keys below V5 are derived with the file ID, so the original's must be kept
+	fileID := encrypt.ExtractFileID(pdfBytes, false)
+	if len(fileID) == 0 && original.V < 5 {
+		return nil, types.NewPDFError(types.ErrCodeInvalidInput, "PDF has no file ID to apply the original encryption with")
+	}
+	if fileID, err = ensureFileID(fileID); err != nil {
+		return nil, err
+	}
+	return rewriteSecurity(pdf, original, fileID, verbose)
+}
+
+// applySecurity encrypts a parsed document with the security described by opts
+func applySecurity(pdf *parse.PDF, pdfBytes []byte, opts SecurityOptions, verbose bool) ([]byte, error) {
+	fileID, err := ensureFileID(encrypt.ExtractFileID(pdfBytes, false))
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := (opts.Permissions | permissionsReserved) &^ 3
+	enc, err := newEncryption(opts.Cipher, opts.UserPassword, opts.OwnerPassword, fileID, permissions, !opts.PlaintextMetadata)
+	if err != nil {
+		return nil, err
+	}
+	if opts.AttachmentsOnly {
+		if enc.V < 4 {
+			return nil, types.NewPDFError(types.ErrCodeUnsupportedCrypto, "encrypting only attachments requires an AES cipher")
+		}
+		enc.StmF = encrypt.IdentityFilter
+		enc.StrF = encrypt.IdentityFilter
+		enc.EFF = "StdCF"
+	}
+
+	return rewriteSecurity(pdf, enc, fileID, verbose)
+}
+
+// ensureFileID returns fileID, or a random ID when it is empty
+func ensureFileID(fileID []byte) ([]byte, error) {
+	if len(fileID) > 0 {
+		return fileID, nil
+	}
+	fileID = make([]byte, 16)
+	if _, err := rand.Read(fileID); err != nil {
+		return nil, fmt.Errorf("failed to generate file ID: %v", err)
+	}
+	return fileID, nil
+}
+
+// cipherOf returns the SecurityOptions cipher closest to an encryption's:
+// RC4 handlers map to CipherRC4128
+func cipherOf(enc *types.PDFEncryption) string {
+	switch enc.V {
+	case 1, 2:
+		return CipherRC4128
+	case 4:
+		return CipherAES128
+	}
+	return CipherAES256
+}
+
+// newEncryption sets up Standard security handler parameters for a cipher.
+// The owner password defaults to the user password; RC4 always encrypts metadata.
+func newEncryption(cipher string, userPassword, ownerPassword, fileID []byte, permissions int32, encryptMetadata bool) (*types.PDFEncryption, error) {
+	if len(ownerPassword) == 0 {
+		ownerPassword = userPassword
+	}
+
+	var enc *types.PDFEncryption
+	var err error
+	switch cipher {
+	case CipherAES256, "":
+		enc, err = write.SetupAES256Encryption(userPassword, ownerPassword, fileID, permissions, encryptMetadata)
+	case CipherAES128:
+		enc, err = encrypt.NewStandardEncryption(4, userPassword, ownerPassword, fileID, permissions, encryptMetadata)
+	case CipherRC4128:
+		enc, err = encrypt.NewStandardEncryption(2, userPassword, ownerPassword, fileID, permissions, true)
+	default:
+		return nil, types.NewPDFErrorf(types.ErrCodeUnsupportedCrypto, "unsupported cipher %q", cipher).WithContext("cipher", cipher)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up encryption: %w", err)
+	}
+	return enc, nil
+}
+
+// RemoveSecurity decrypts a document opened with password and writes it without encryption
+func RemoveSecurity(pdfBytes []byte, password []byte, verbose bool) ([]byte, error) {
+	pdf, err := parse.OpenWithOptions(pdfBytes, parse.ParseOptions{
+		Password: password,
+		Verbose:  verbose,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PDF: %w", err)
+	}
+	if !pdf.IsEncrypted() {
+		return nil, types.NewPDFError(types.ErrCodeInvalidInput, "PDF is not encrypted")
+	}
+
+	return rewriteSecurity(pdf, nil, encrypt.ExtractFileID(pdfBytes, false), verbose)
+}
+
+// rewriteSecurity writes every object of a parsed document, encrypting strings and
+// streams with enc (nil writes them in the clear). Cross-reference and object streams
+// are dropped; their objects are written as top-level objects.
+func rewriteSecurity(pdf *parse.PDF, enc *types.PDFEncryption, fileID []byte, verbose bool) ([]byte, error) {
+	trailer := pdf.Trailer()
+	if trailer == nil {
+		return nil, fmt.Errorf("no trailer found")
+	}
+	if pdf.IsEncrypted() && len(pdf.Encryption().EncryptKey) == 0 {
+		// Opened without the password of a document encrypting only its attachments
+		return nil, types.NewPDFError(types.ErrCodeWrongPassword, "password required to decrypt attachments")
+	}
+
+	skip := make(map[int]bool)
+	if trailer.EncryptRef != "" {
+		if encryptObjNum, err := parseObjectRef(trailer.EncryptRef); err == nil {
+			skip[encryptObjNum] = true
+		}
+	}
+
+	writer := write.NewPDFWriter()
+	for _, objNum := range pdf.Objects() {
+		if skip[objNum] {
+			continue
+		}
+		obj, err := pdf.GetObject(objNum)
+		if err != nil {
+			if verbose {
+				fmt.Printf("Warning: failed to get object %d: %v\n", objNum, err)
+			}
+			continue
+		}
+
+		dict, data, isStream := splitStreamObject(objectBody(obj))
+		if isStream && streamSkipPattern.Match(dict) {
+			continue
+		}
+
+		if enc != nil {
+			if dict, data, err = encryptObjectParts(objNum, dict, data, isStream, enc); err != nil {
+				return nil, err
+			}
+		}
+		writer.SetObject(objNum, joinStreamObject(dict, data, isStream))
+	}
+
+	if enc != nil {
+		writer.SetEncryptRef(writer.AddObject(write.CreateEncryptionDictionary(enc)))
+	}
+	writer.SetFileID(fileID)
+
+	if rootObjNum, err := parseObjectRef(trailer.RootRef); err == nil {
+		writer.SetRoot(rootObjNum)
+	} else {
+		return nil, fmt.Errorf("invalid root reference %q", trailer.RootRef)
+	}
+	if trailer.InfoRef != "" {
+		if infoObjNum, err := parseObjectRef(trailer.InfoRef); err == nil {
+			writer.SetInfo(infoObjNum)
+		}
+	}
+
+	if verbose {
+		fmt.Printf("Rewrote %d objects (encrypted: %v)\n", len(pdf.Objects())-len(skip), enc != nil)
+	}
+
+	return writer.Bytes()
+}
+
+// encryptObjectParts encrypts the strings and stream data of an object written
+// as objNum. Objects are written with generation 0, so they are encrypted with it too.
+func encryptObjectParts(objNum int, dict, data []byte, isStream bool, enc *types.PDFEncryption) ([]byte, []byte, error) {
+	if encrypt.EncryptsStrings(enc) {
+		var err error
+		if dict, err = encrypt.EncryptStrings(dict, objNum, 0, enc); err != nil {
+			return nil, nil, fmt.Errorf("failed to encrypt strings in object %d: %w", objNum, err)
+		}
+	}
+	if isStream && encrypt.EncryptsStream(dict, enc) {
+		var err error
+		if data, err = encrypt.EncryptObject(data, objNum, 0, enc); err != nil {
+			return nil, nil, fmt.Errorf("failed to encrypt stream %d: %w", objNum, err)
+		}
+	}
+	return dict, data, nil
+}
+
+// joinStreamObject joins a dictionary and its stream data into an object body
+// with a direct /Length. Non-stream objects are returned as is.
+func joinStreamObject(dict, data []byte, isStream bool) []byte {
+	if !isStream {
+		return dict
+	}
+	var buf bytes.Buffer
+	buf.Write(setStreamLength(dict, len(data)))
+	buf.WriteString("\nstream\n")
+	buf.Write(data)
+	buf.WriteString("\nendstream")
+	return buf.Bytes()
+}
+
+// objectBody strips the "N G obj" header and "endobj" keyword from an object
+func objectBody(obj []byte) []byte {
+	if loc := objectHeaderPattern.FindIndex(obj); loc != nil {
+		obj = obj[loc[1]:]
+	}
+	obj = bytes.TrimSpace(obj)
+	return bytes.TrimSpace(bytes.TrimSuffix(obj, []byte("endobj")))
+}
+
+// splitStreamObject splits an object body into its dictionary and stream data.
+// Non-stream objects are returned whole with isStream false.
+func splitStreamObject(body []byte) (dict, data []byte, isStream bool) {
+	if !bytes.HasPrefix(body, []byte("<<")) {
+		return body, nil, false
+	}
+	dictStr := balancedDict(string(body))
+	if dictStr == "" {
+		return body, nil, false
+	}
+	rest := bytes.TrimLeft(body[len(dictStr):], " \t\r\n")
+	if !bytes.HasPrefix(rest, []byte("stream")) {
+		return body, nil, false
+	}
+
+	rest = rest[len("stream"):]
+	if bytes.HasPrefix(rest, []byte("\r\n")) {
+		rest = rest[2:]
+	} else if len(rest) > 0 && (rest[0] == '\n' || rest[0] == '\r') {
+		rest = rest[1:]
+	}
+
+	end := bytes.LastIndex(rest, []byte("endstream"))
+	if end == -1 {
+		end = len(rest)
+	}
+
+	// Prefer a direct /Length that lands just before endstream; otherwise drop the EOL
+	if match := streamLengthPattern.FindSubmatch([]byte(dictStr)); match != nil && len(match[2]) == 0 {
+		if length, err := strconv.Atoi(string(match[1])); err == nil && length <= end &&
+			len(bytes.TrimSpace(rest[length:end])) == 0 {
+			return []byte(dictStr), rest[:length], true
+		}
+	}
+	data = rest[:end]
+	if bytes.HasSuffix(data, []byte("\r\n")) {
+		data = data[:len(data)-2]
+	} else if bytes.HasSuffix(data, []byte("\n")) || bytes.HasSuffix(data, []byte("\r")) {
+		data = data[:len(data)-1]
+	}
+	return []byte(dictStr), data, true
+}
+
+// setStreamLength sets a stream dictionary's /Length to a direct value
+func setStreamLength(dict []byte, length int) []byte {
+	value := []byte(fmt.Sprintf("/Length %d", length))
+	if loc := streamLengthPattern.FindIndex(dict); loc != nil {
+		return append(append(append([]byte{}, dict[:loc[0]]...), value...), dict[loc[1]:]...)
+	}
+	return append(append(append([]byte{}, dict[:len(dict)-2]...), value...), ">>"...)
+}
@@ -0,0 +1,284 @@
+package manipulate
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// pageContent returns the decoded content stream of the first page
+func pageContent(t *testing.T, pdf *parse.PDF) string {
+	t.Helper()
+
+	page, err := pdf.Page(1)
+	if err != nil {
+		t.Fatalf("Page(1) failed: %v", err)
+	}
+	contentObjNum, err := parseObjectRef(rawDictValue(page.Dict, "/Contents"))
+	if err != nil {
+		t.Fatalf("page has no content reference: %s", page.Dict)
+	}
+	obj, err := pdf.GetObject(contentObjNum)
+	if err != nil {
+		t.Fatalf("GetObject(%d) failed: %v", contentObjNum, err)
+	}
+	return string(obj)
+}
+
+func TestSetSecurity_Ciphers(t *testing.T) {
+	for _, cipher := range []string{CipherRC4128, CipherAES128, CipherAES256} {
+		t.Run(cipher, func(t *testing.T) {
+			out, err := SetSecurity(createFormPDF(t), nil, SecurityOptions{
+				UserPassword:  []byte("user"),
+				OwnerPassword: []byte("owner"),
+				Permissions:   PermPrint | PermFillForms,
+				Cipher:        cipher,
+			}, false)
+			if err != nil {
+				t.Fatalf("SetSecurity failed: %v", err)
+			}
+			if strings.Contains(string(out), "(Page 1) Tj") || strings.Contains(string(out), "field1") {
+				t.Error("output contains plaintext content")
+			}
+
+			if _, err := parse.OpenWithOptions(out, parse.ParseOptions{Password: []byte("wrong")}); err == nil {
+				t.Error("expected wrong password to fail")
+			}
+
+			for _, password := range []string{"user", "owner"} {
+				pdf, err := parse.OpenWithOptions(out, parse.ParseOptions{Password: []byte(password)})
+				if err != nil {
+					t.Fatalf("open with %s password failed: %v", password, err)
+				}
+				if !pdf.IsEncrypted() {
+					t.Fatal("output is not encrypted")
+				}
+				if want := PermPrint | PermFillForms | permissionsReserved; pdf.Encryption().P != want {
+					t.Errorf("P = %d, want %d", pdf.Encryption().P, want)
+				}
+				if content := pageContent(t, pdf); !strings.Contains(content, "(Page 1) Tj") {
+					t.Errorf("%s password: content = %q", password, content)
+				}
+			}
+		})
+	}
+
+	if _, err := SetSecurity(createFormPDF(t), nil, SecurityOptions{Cipher: "des"}, false); err == nil {
+		t.Error("expected error for unsupported cipher")
+	}
+}
+
+func TestRemoveSecurity(t *testing.T) {
+	if _, err := RemoveSecurity(createFormPDF(t), nil, false); err == nil {
+		t.Error("expected error for unencrypted input")
+	}
+
+	encrypted, err := SetSecurity(createFormPDF(t), nil, SecurityOptions{
+		UserPassword: []byte("secret"),
+		Cipher:       CipherAES128,
+	}, false)
+	if err != nil {
+		t.Fatalf("SetSecurity failed: %v", err)
+	}
+
+	if _, err := RemoveSecurity(encrypted, []byte("wrong"), false); err == nil {
+		t.Error("expected wrong password to fail")
+	}
+
+	out, err := RemoveSecurity(encrypted, []byte("secret"), false)
+	if err != nil {
+		t.Fatalf("RemoveSecurity failed: %v", err)
+	}
+	if strings.Contains(string(out), "/Encrypt") {
+		t.Error("output still references /Encrypt")
+	}
+
+	pdf, err := parse.Open(out)
+	if err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+	if pdf.IsEncrypted() {
+		t.Error("output is encrypted")
+	}
+	if content := pageContent(t, pdf); !strings.Contains(content, "(Page 1) Tj") {
+		t.Errorf("content = %q", content)
+	}
+
+	rects := widgetRects(t, out)
+	if len(rects) != 2 || rects["field1"] == nil {
+		t.Errorf("widgets = %v, want field1 and field2 with plaintext names", rects)
+	}
+}
+
+func TestSetSecurity_AttachmentsOnly(t *testing.T) {
+	m, err := NewPDFManipulator(createFormPDF(t), nil, false)
+	if err != nil {
+		t.Fatalf("Failed to create manipulator: %v", err)
+	}
+	if err := m.AddAttachment(Attachment{Name: "notes.txt", Data: []byte("attached notes")}); err != nil {
+		t.Fatalf("AddAttachment failed: %v", err)
+	}
+	withAttachment, err := m.Rebuild()
+	if err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+
+	if _, err := SetSecurity(withAttachment, nil, SecurityOptions{UserPassword: []byte("secret"), Cipher: CipherRC4128, AttachmentsOnly: true}, false); err == nil {
+		t.Error("expected RC4 to be rejected")
+	}
+	out, err := SetSecurity(withAttachment, nil, SecurityOptions{UserPassword: []byte("secret"), AttachmentsOnly: true}, false)
+	if err != nil {
+		t.Fatalf("SetSecurity failed: %v", err)
+	}
+	if !strings.Contains(string(out), "(Page 1) Tj") || !strings.Contains(string(out), "(notes.txt)") {
+		t.Error("content or strings encrypted")
+	}
+	if !strings.Contains(string(out), "/EFF /StdCF") || !strings.Contains(string(out), "/AuthEvent /EFOpen") {
+		t.Error("Encrypt dictionary has no embedded file filter")
+	}
+
+	attachment := func(password string) ([]byte, error) {
+		pdf, err := parse.OpenWithOptions(out, parse.ParseOptions{Password: []byte(password)})
+		if err != nil {
+			t.Fatalf("open with password %q failed: %v", password, err)
+		}
+		if content := pageContent(t, pdf); !strings.Contains(content, "(Page 1) Tj") {
+			t.Errorf("password %q: content = %q", password, content)
+		}
+		for _, objNum := range pdf.Objects() {
+			obj, err := pdf.GetObject(objNum)
+			if err != nil || !bytes.Contains(obj, []byte("/Type /EmbeddedFile")) {
+				continue
+			}
+			_, data, _ := splitStreamObject(objectBody(obj))
+			r, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			return io.ReadAll(r)
+		}
+		t.Fatal("no embedded file found")
+		return nil, nil
+	}
+	if data, err := attachment("secret"); err != nil || string(data) != "attached notes" {
+		t.Errorf("attachment = %q, %v", data, err)
+	}
+	if data, err := attachment(""); err == nil && string(data) == "attached notes" {
+		t.Error("attachment readable without the password")
+	}
+
+	if _, err := RemoveSecurity(out, nil, false); err == nil {
+		t.Error("expected RemoveSecurity without the password to fail")
+	}
+	plain, err := RemoveSecurity(out, []byte("secret"), false)
+	if err != nil {
+		t.Fatalf("RemoveSecurity failed: %v", err)
+	}
+	if files, _ := embeddedFiles(t, plain); len(files) != 1 || string(files[0]) != "attached notes" {
+		t.Errorf("decrypted attachments = %q", files)
+	}
+}
+
+// decryptForFill encrypts the test form like a protected original and returns its
+// decrypted bytes with the original's encryption, as a fill would start from
+func decryptForFill(t *testing.T, cipher string) ([]byte, *types.PDFEncryption) {
+	t.Helper()
+	encrypted, err := SetSecurity(createFormPDF(t), nil, SecurityOptions{
+		UserPassword:  []byte("user"),
+		OwnerPassword: []byte("owner"),
+		Permissions:   PermPrint | PermFillForms,
+		Cipher:        cipher,
+	}, false)
+	if err != nil {
+		t.Fatalf("SetSecurity failed: %v", err)
+	}
+	pdf, err := parse.OpenWithOptions(encrypted, parse.ParseOptions{Password: []byte("user")})
+	if err != nil {
+		t.Fatalf("Failed to open original: %v", err)
+	}
+	plain, err := RemoveSecurity(encrypted, []byte("user"), false)
+	if err != nil {
+		t.Fatalf("RemoveSecurity failed: %v", err)
+	}
+	return plain, pdf.Encryption()
+}
+
+func TestEncryptPDF_Original(t *testing.T) {
+	for _, cipher := range []string{CipherRC4128, CipherAES128, CipherAES256} {
+		t.Run(cipher, func(t *testing.T) {
+			plain, original := decryptForFill(t, cipher)
+			out, err := EncryptPDF(plain, original, nil, false)
+			if err != nil {
+				t.Fatalf("EncryptPDF failed: %v", err)
+			}
+			if strings.Contains(string(out), "(Page 1) Tj") {
+				t.Error("output contains plaintext content")
+			}
+			if _, err := parse.OpenWithOptions(out, parse.ParseOptions{Password: []byte("wrong")}); err == nil {
+				t.Error("expected wrong password to fail")
+			}
+
+			for _, password := range []string{"user", "owner"} {
+				pdf, err := parse.OpenWithOptions(out, parse.ParseOptions{Password: []byte(password)})
+				if err != nil {
+					t.Fatalf("open with original %s password failed: %v", password, err)
+				}
+				enc := pdf.Encryption()
+				if enc.V != original.V || enc.R != original.R || enc.P != original.P {
+					t.Errorf("handler V%d R%d P%d, want V%d R%d P%d like the original", enc.V, enc.R, enc.P, original.V, original.R, original.P)
+				}
+				if content := pageContent(t, pdf); !strings.Contains(content, "(Page 1) Tj") {
+					t.Errorf("%s password: content = %q", password, content)
+				}
+			}
+		})
+	}
+}
+
+func TestEncryptPDF_NewPasswords(t *testing.T) {
+	plain, original := decryptForFill(t, CipherAES128)
+	out, err := EncryptPDF(plain, original, &SecurityOptions{
+		UserPassword: []byte("new"),
+		Permissions:  PermPrint,
+	}, false)
+	if err != nil {
+		t.Fatalf("EncryptPDF failed: %v", err)
+	}
+
+	if _, err := parse.OpenWithOptions(out, parse.ParseOptions{Password: []byte("user")}); err == nil {
+		t.Error("expected the original password to fail")
+	}
+	pdf, err := parse.OpenWithOptions(out, parse.ParseOptions{Password: []byte("new")})
+	if err != nil {
+		t.Fatalf("open with new password failed: %v", err)
+	}
+	if enc := pdf.Encryption(); enc.V != 4 || enc.P != PermPrint|permissionsReserved {
+		t.Errorf("V = %d, P = %d; want the original's AES-128 cipher with the new permissions", enc.V, enc.P)
+	}
+	if content := pageContent(t, pdf); !strings.Contains(content, "(Page 1) Tj") {
+		t.Errorf("content = %q", content)
+	}
+}
+
+func TestEncryptPDF_Errors(t *testing.T) {
+	plain, original := decryptForFill(t, CipherAES256)
+	if _, err := EncryptPDF(plain, nil, nil, false); err == nil {
+		t.Error("expected an error without original encryption or options")
+	}
+	if _, err := EncryptPDF(plain, &types.PDFEncryption{V: 5, R: 6}, nil, false); err == nil {
+		t.Error("expected an error for original encryption without its key")
+	}
+
+	encrypted, err := EncryptPDF(plain, original, nil, false)
+	if err != nil {
+		t.Fatalf("EncryptPDF failed: %v", err)
+	}
+	if _, err := EncryptPDF(encrypted, original, nil, false); err == nil {
+		t.Error("expected an error for encrypted input")
+	}
+}
@@ -0,0 +1,423 @@
+package manipulate
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// Header/footer defaults, in points
+const (
+	defaultStampFontSize    = 9
+	defaultStampMargin      = 36 // Half an inch from the page edge
+	defaultStampMinMargin   = 9
+	defaultStampMinFontSize = 6
+	stampPadding            = 2 // Clearance kept between the stamp and page content
+)
+
+var stampVariablePattern = regexp.MustCompile(`\{(page|pages|date|filename)\}`)
+
+// HeaderFooterOptions configures StampHeaderFooter. Each segment is a text template
+// that may contain {page}, {pages}, {date} and {filename}; empty segments are skipped.
+type HeaderFooterOptions struct {
+	HeaderLeft, HeaderCenter, HeaderRight string
+	FooterLeft, FooterCenter, FooterRight string
+
+	FontName    string    // Standard font (default: "Helvetica")
+	FontSize    float64   // Font size in points (default: 9)
+	Margin      float64   // Distance from the page edges to the stamp in points (default: 36)
+	MinMargin   float64   // Closest the stamp may move to the page edge to avoid content (default: 9)
+	MinFontSize float64   // Smallest font size used to fit between content and the edge (default: 6)
+	Color       *Color    // Text color (default: black)
+	Filename    string    // Value of {filename}
+	Date        time.Time // Value of {date} (default: now)
+	DateFormat  string    // Go layout for {date} (default: "2006-01-02")
+	Pages       []int     // 1-based pages to stamp (default: all)
+}
+
+// Color represents an RGB color with components from 0.0 to 1.0
+type Color struct {
+	R, G, B float64
+}
+
+// stampBand is a header or footer line: its segments and where it may be placed
+type stampBand struct {
+	segments [3]string // Left, center, right
+	header   bool
+}
+
+// StampHeaderFooter adds headers and footers to the pages of an existing document.
+// When page content reaches into the margin where a header or footer would be drawn,
+// the stamp moves toward the page edge (no closer than MinMargin) and, if the gap is
+// still too narrow, shrinks down to MinFontSize. Positions follow the unrotated page.
+func StampHeaderFooter(pdfBytes []byte, password []byte, opts HeaderFooterOptions, verbose bool) ([]byte, error) {
+	if opts.HeaderLeft+opts.HeaderCenter+opts.HeaderRight+opts.FooterLeft+opts.FooterCenter+opts.FooterRight == "" {
+		return nil, fmt.Errorf("no header or footer text")
+	}
+
+	m, err := NewPDFManipulator(pdfBytes, password, verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.FontName == "" {
+		opts.FontName = "Helvetica"
+	}
+	if opts.FontSize <= 0 {
+		opts.FontSize = defaultStampFontSize
+	}
+	if opts.Margin <= 0 {
+		opts.Margin = defaultStampMargin
+	}
+	if opts.MinMargin <= 0 || opts.MinMargin > opts.Margin {
+		opts.MinMargin = math.Min(defaultStampMinMargin, opts.Margin)
+	}
+	if opts.MinFontSize <= 0 || opts.MinFontSize > opts.FontSize {
+		opts.MinFontSize = math.Min(defaultStampMinFontSize, opts.FontSize)
+	}
+	if opts.Date.IsZero() {
+		opts.Date = time.Now()
+	}
+	if opts.DateFormat == "" {
+		opts.DateFormat = "2006-01-02"
+	}
+
+	bands := []stampBand{
+		{segments: [3]string{opts.HeaderLeft, opts.HeaderCenter, opts.HeaderRight}, header: true},
+		{segments: [3]string{opts.FooterLeft, opts.FooterCenter, opts.FooterRight}},
+	}
+
+	pageObjNums, err := m.getAllPageObjectNumbers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pages: %w", err)
+	}
+	pageNumbers := opts.Pages
+	if len(pageNumbers) == 0 {
+		for i := range pageObjNums {
+			pageNumbers = append(pageNumbers, i+1)
+		}
+	}
+
+	fontObjNum := m.nextObjectNumber()
+	m.objects[fontObjNum] = []byte(fmt.Sprintf("<</Type/Font/Subtype/Type1/BaseFont/%s/Encoding/WinAnsiEncoding>>", opts.FontName))
+
+	for _, pageNumber := range pageNumbers {
+		if pageNumber < 1 || pageNumber > len(pageObjNums) {
+			return nil, fmt.Errorf("page %d out of range (document has %d pages)", pageNumber, len(pageObjNums))
+		}
+
+		variables := map[string]string{
+			"page":     strconv.Itoa(pageNumber),
+			"pages":    strconv.Itoa(len(pageObjNums)),
+			"date":     opts.Date.Format(opts.DateFormat),
+			"filename": opts.Filename,
+		}
+
+		// Content boxes come from the original document, which pages have not diverged from
+		var content []*types.Rectangle
+		if page, err := extract.ExtractPage(m.pdfBytes, m.pdf, pageNumber, verbose); err == nil {
+			content = contentBoxes(page)
+		} else if verbose {
+			fmt.Printf("Warning: failed to extract page %d, stamping without collision checks: %v\n", pageNumber, err)
+		}
+
+		if err := m.stampPage(pageObjNums[pageNumber-1], fontObjNum, bands, variables, content, &opts); err != nil {
+			return nil, fmt.Errorf("failed to stamp page %d: %w", pageNumber, err)
+		}
+	}
+
+	return m.rebuildPDF()
+}
+
+// stampPage draws the header and footer bands on a page
+func (m *PDFManipulator) stampPage(pageObjNum, fontObjNum int, bands []stampBand, variables map[string]string, content []*types.Rectangle, opts *HeaderFooterOptions) error {
+	pageObj, ok := m.objects[pageObjNum]
+	if !ok {
+		return fmt.Errorf("page object %d not found", pageObjNum)
+	}
+	pageStr := m.materializeInheritedAttributes(string(pageObj))
+
+	box := parseNumberArray(rawDictValue(pageStr, "/CropBox"))
+	if len(box) < 4 {
+		box = parseNumberArray(rawDictValue(pageStr, "/MediaBox"))
+	}
+	if len(box) < 4 {
+		box = []float64{0, 0, 612, 792}
+	}
+	page := types.Rectangle{
+		LowerX: math.Min(box[0], box[2]), LowerY: math.Min(box[1], box[3]),
+		UpperX: math.Max(box[0], box[2]), UpperY: math.Max(box[1], box[3]),
+	}
+
+	pageStr, fontName := m.addPageFont(pageStr, fontObjNum)
+
+	var stream strings.Builder
+	for _, band := range bands {
+		var texts [3]string
+		empty := true
+		for i, segment := range band.segments {
+			texts[i] = stampVariablePattern.ReplaceAllStringFunc(segment, func(v string) string {
+				return variables[strings.Trim(v, "{}")]
+			})
+			if texts[i] != "" {
+				empty = false
+			}
+		}
+		if empty {
+			continue
+		}
+
+		size, baseline := placeBand(band.header, texts, page, content, opts)
+		if opts.Color != nil {
+			stream.WriteString(fmt.Sprintf("%s rg\n", formatNumbers([]float64{opts.Color.R, opts.Color.G, opts.Color.B})))
+		}
+		for i, text := range texts {
+			if text == "" {
+				continue
+			}
+			x := segmentX(i, write.StandardTextWidth(text, opts.FontName, size), page, opts.Margin)
+			stream.WriteString(fmt.Sprintf("BT\n/%s %s Tf\n1 0 0 1 %s Tm\n(%s) Tj\nET\n",
+				fontName, formatNumbers([]float64{size}), formatNumbers([]float64{x, baseline}), encodeStampText(text)))
+		}
+	}
+	if stream.Len() == 0 {
+		m.objects[pageObjNum] = []byte(pageStr)
+		return nil
+	}
+
+	// Isolate the existing content's graphics state from the stamp
+	preObjNum := m.nextObjectNumber()
+	m.objects[preObjNum] = rawStreamObject("q\n")
+	stampObjNum := m.nextObjectNumber()
+	m.objects[stampObjNum] = rawStreamObject("\nQ\nq\n" + stream.String() + "Q\n")
+
+	existing := m.pageContentRefs(pageStr)
+	contents := fmt.Sprintf("[%d 0 R %s %d 0 R]", preObjNum, strings.Join(existing, " "), stampObjNum)
+	if len(existing) == 0 {
+		contents = fmt.Sprintf("[%d 0 R %d 0 R]", preObjNum, stampObjNum)
+	}
+	pageStr = setRawDictValue(pageStr, "/Contents", contents)
+
+	m.objects[pageObjNum] = []byte(pageStr)
+	return nil
+}
+
+// pageContentRefs returns the references of a page's content streams, flattening an
+// indirect /Contents array
+func (m *PDFManipulator) pageContentRefs(pageStr string) []string {
+	value := rawDictValue(pageStr, "/Contents")
+	if match := refPattern.FindStringSubmatch(value); match != nil && !strings.HasPrefix(value, "[") {
+		objNum, _ := strconv.Atoi(match[1])
+		if obj, ok := m.objects[objNum]; ok {
+			if body := strings.TrimSpace(string(objectBody(obj))); strings.HasPrefix(body, "[") {
+				value = body
+			}
+		}
+	}
+	return refPattern.FindAllString(value, -1)
+}
+
+// addPageFont adds the stamp font to a page's font resources and returns the updated
+// page and the font's resource name. Shared resource dictionaries are updated in place.
+func (m *PDFManipulator) addPageFont(pageStr string, fontObjNum int) (string, string) {
+	return m.addPageResource(pageStr, "/Font", "FStamp", fontObjNum)
+}
+
+// addPageResource adds an object to a category of a page's resources, such as /Font
+// or /XObject, under a name starting with prefix, and returns the updated page and the
+// resource name. Shared resource dictionaries are updated in place.
+func (m *PDFManipulator) addPageResource(pageStr, category, prefix string, objNum int) (string, string) {
+	ref := fmt.Sprintf("%d 0 R", objNum)
+
+	// addEntry adds the entry to a category dictionary, reusing an existing entry
+	addEntry := func(entries string) (string, string) {
+		name := prefix
+		for i := 1; ; i++ {
+			value := rawDictValue(entries, "/"+name)
+			if value == ref {
+				return entries, name
+			}
+			if value == "" {
+				break
+			}
+			name = fmt.Sprintf("%s%d", prefix, i)
+		}
+		return setRawDictValue(entries, "/"+name, ref), name
+	}
+
+	// addToResources adds the entry to a resources dictionary, following an indirect category
+	addToResources := func(resources string) (string, string) {
+		entries := rawDictValue(resources, category)
+		if match := refPattern.FindStringSubmatch(entries); match != nil && match[0] == entries {
+			entriesObjNum, _ := strconv.Atoi(match[1])
+			if obj, ok := m.objects[entriesObjNum]; ok {
+				updated, name := addEntry(string(obj))
+				m.objects[entriesObjNum] = []byte(updated)
+				return resources, name
+			}
+			entries = ""
+		}
+		if entries == "" {
+			entries = "<<>>"
+		}
+		updated, name := addEntry(entries)
+		return setRawDictValue(resources, category, updated), name
+	}
+
+	resources := rawDictValue(pageStr, "/Resources")
+	if match := refPattern.FindStringSubmatch(resources); match != nil && match[0] == resources {
+		resourcesObjNum, _ := strconv.Atoi(match[1])
+		if obj, ok := m.objects[resourcesObjNum]; ok {
+			updated, name := addToResources(string(obj))
+			m.objects[resourcesObjNum] = []byte(updated)
+			return pageStr, name
+		}
+		resources = ""
+	}
+	if resources == "" {
+		resources = "<<>>"
+	}
+	updated, name := addToResources(resources)
+	return setRawDictValue(pageStr, "/Resources", updated), name
+}
+
+// placeBand returns the font size and baseline of a header or footer band. The band sits
+// Margin from the page edge unless that overlaps content, in which case it moves into the
+// gap between the content and the edge, shrinking the text if the gap is narrow.
+func placeBand(header bool, texts [3]string, page types.Rectangle, content []*types.Rectangle, opts *HeaderFooterOptions) (float64, float64) {
+	// baseline places a band of the given size with its outer edge at distance from the page edge
+	baseline := func(size, distance float64) float64 {
+		if header {
+			return page.UpperY - distance - 0.8*size
+		}
+		return page.LowerY + distance + 0.2*size
+	}
+
+	size := opts.FontSize
+	bandLow := baseline(size, opts.Margin) - 0.2*size - stampPadding
+	bandHigh := bandLow + size + 2*stampPadding
+
+	// Content under one of the band's segments; its extent toward the page edge
+	// bounds where the band can go
+	var limit float64
+	found, collides := false, false
+	for _, r := range content {
+		overlaps := false
+		for i, text := range texts {
+			if text == "" {
+				continue
+			}
+			width := write.StandardTextWidth(text, opts.FontName, opts.FontSize)
+			x := segmentX(i, width, page, opts.Margin)
+			if r.LowerX < x+width && r.UpperX > x {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			continue
+		}
+		if r.LowerY < bandHigh && r.UpperY > bandLow {
+			collides = true
+		}
+		// Extent toward the band's page edge
+		edge := r.LowerY
+		if header {
+			edge = r.UpperY
+		}
+		if !found || (header && edge > limit) || (!header && edge < limit) {
+			limit = edge
+			found = true
+		}
+	}
+
+	if !collides {
+		return size, baseline(size, opts.Margin)
+	}
+
+	// Free space between the content and the page edge
+	gap := page.UpperY - limit
+	if !header {
+		gap = limit - page.LowerY
+	}
+	gap -= stampPadding
+	if gap >= opts.MinMargin+size {
+		return size, baseline(size, gap-size)
+	}
+	if fitted := gap - opts.MinMargin; fitted >= opts.MinFontSize {
+		return fitted, baseline(fitted, opts.MinMargin)
+	}
+
+	// No room between the content and the edge; keep the default placement
+	return size, baseline(size, opts.Margin)
+}
+
+// contentBoxes returns the bounding boxes of a page's text, images and graphics,
+// ignoring full-page backgrounds
+func contentBoxes(page *types.Page) []*types.Rectangle {
+	var boxes []*types.Rectangle
+	add := func(r *types.Rectangle) {
+		if page.Width > 0 && page.Height > 0 &&
+			r.UpperX-r.LowerX >= 0.9*page.Width && r.UpperY-r.LowerY >= 0.9*page.Height {
+			return
+		}
+		boxes = append(boxes, r)
+	}
+
+	for _, t := range page.Text {
+		if t.BoundingBox != nil {
+			add(t.BoundingBox)
+		} else if t.Width > 0 {
+			add(&types.Rectangle{LowerX: t.X, LowerY: t.Y, UpperX: t.X + t.Width, UpperY: t.Y + t.Height})
+		}
+	}
+	for _, img := range page.Images {
+		add(&types.Rectangle{LowerX: img.X, LowerY: img.Y, UpperX: img.X + img.Width, UpperY: img.Y + img.Height})
+	}
+	for _, g := range page.Graphics {
+		if g.BoundingBox != nil {
+			add(g.BoundingBox)
+		}
+	}
+	return boxes
+}
+
+// segmentX returns the x position of a left (0), center (1) or right (2) segment
+func segmentX(segment int, width float64, page types.Rectangle, margin float64) float64 {
+	switch segment {
+	case 1:
+		return (page.LowerX+page.UpperX)/2 - width/2
+	case 2:
+		return page.UpperX - margin - width
+	}
+	return page.LowerX + margin
+}
+
+// encodeStampText encodes text as a WinAnsi literal string body; characters outside
+// Latin-1 are replaced with '?'
+func encodeStampText(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32:
+			b.WriteByte(' ')
+		case r < 128:
+			b.WriteRune(r)
+		case r < 256:
+			b.WriteString(fmt.Sprintf("\\%03o", r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
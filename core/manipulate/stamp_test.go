@@ -0,0 +1,95 @@
+package manipulate
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// stampTestPDF builds two pages: body text on the first, and a title reaching into
+// the top margin on the second
+func stampTestPDF(t *testing.T) []byte {
+	t.Helper()
+
+	builder := write.NewSimplePDFBuilder()
+	for _, y := range []float64{400, 750} {
+		page := builder.AddPage(write.PageSizeLetter)
+		font := page.AddStandardFont("Times-Roman")
+		page.Content().BeginText().SetFont(font, 12).SetTextPosition(250, y).ShowText("Body text here").EndText()
+		builder.FinalizePage(page)
+	}
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+	return pdfBytes
+}
+
+// findText returns the extracted text element of a page containing s
+func findText(t *testing.T, pdfBytes []byte, pageNumber int, s string) types.TextElement {
+	t.Helper()
+
+	pdf, err := parse.Open(pdfBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse PDF: %v", err)
+	}
+	page, err := extract.ExtractPage(pdfBytes, pdf, pageNumber, false)
+	if err != nil {
+		t.Fatalf("ExtractPage failed: %v", err)
+	}
+	for _, text := range page.Text {
+		if strings.Contains(text.Text, s) {
+			return text
+		}
+	}
+	t.Fatalf("Text %q not found on page %d: %+v", s, pageNumber, page.Text)
+	return types.TextElement{}
+}
+
+func TestStampHeaderFooter(t *testing.T) {
+	out, err := StampHeaderFooter(stampTestPDF(t), nil, HeaderFooterOptions{
+		HeaderCenter: "Page {page} of {pages}",
+		FooterLeft:   "{filename}",
+		FooterRight:  "{date}",
+		Filename:     "report.pdf",
+		Date:         time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+	}, false)
+	if err != nil {
+		t.Fatalf("StampHeaderFooter failed: %v", err)
+	}
+
+	header := findText(t, out, 1, "Page 1 of 2")
+	if header.Y < 740 || header.Y > 760 {
+		t.Errorf("Header on an uncluttered page at y=%.1f, want the default margin", header.Y)
+	}
+	if footer := findText(t, out, 1, "report.pdf"); footer.X != 36 || footer.Y > 45 {
+		t.Errorf("Footer at (%.1f, %.1f), want bottom left", footer.X, footer.Y)
+	}
+	if date := findText(t, out, 2, "2024-03-01"); date.X < 500 {
+		t.Errorf("Date at x=%.1f, want right-aligned", date.X)
+	}
+
+	// The title on page 2 occupies the header band; the header moves above it
+	body := findText(t, out, 2, "Body text here")
+	header = findText(t, out, 2, "Page 2 of 2")
+	if header.Y < body.Y+body.Height || header.Y > 792-defaultStampMinMargin {
+		t.Errorf("Header at y=%.1f collides with content at y=%.1f", header.Y, body.Y)
+	}
+	if body.FontName == header.FontName {
+		t.Errorf("Stamp reuses the page font %s", body.FontName)
+	}
+}
+
+func TestStampHeaderFooter_Errors(t *testing.T) {
+	if _, err := StampHeaderFooter(stampTestPDF(t), nil, HeaderFooterOptions{}, false); err == nil {
+		t.Error("expected error without header or footer text")
+	}
+	if _, err := StampHeaderFooter(stampTestPDF(t), nil, HeaderFooterOptions{FooterCenter: "x", Pages: []int{3}}, false); err == nil {
+		t.Error("expected error for page out of range")
+	}
+}
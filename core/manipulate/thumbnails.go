@@ -0,0 +1,70 @@
+package manipulate
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+)
+
+// SetPageThumbnail embeds img as the /Thumb image of a page
+// pageNumber is 1-based (first page is 1)
+func (m *PDFManipulator) SetPageThumbnail(pageNumber int, img image.Image) error {
+	if img == nil {
+		return fmt.Errorf("thumbnail image is nil")
+	}
+
+	pageObjNum, err := m.getPageObjectNumber(pageNumber)
+	if err != nil {
+		return fmt.Errorf("failed to get page object: %w", err)
+	}
+
+	pageObj, ok := m.objects[pageObjNum]
+	if !ok {
+		return fmt.Errorf("page object %d not found", pageObjNum)
+	}
+
+	// Thumbnails are DeviceRGB, 8 bits per component (ISO 32000-1, 12.3.4)
+	b := img.Bounds()
+	raw := make([]byte, 0, b.Dx()*b.Dy()*3)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			raw = append(raw, byte(r>>8), byte(g>>8), byte(bl>>8))
+		}
+	}
+
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(raw)
+	zw.Close()
+
+	var obj bytes.Buffer
+	obj.WriteString(fmt.Sprintf("<< /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /FlateDecode /Length %d >>\nstream\n",
+		b.Dx(), b.Dy(), buf.Len()))
+	obj.Write(buf.Bytes())
+	obj.WriteString("\nendstream")
+
+	thumbObjNum := m.nextObjectNumber()
+	m.objects[thumbObjNum] = obj.Bytes()
+
+	updatedPageStr := setDictValue(string(pageObj), "/Thumb", fmt.Sprintf("%d 0 R", thumbObjNum))
+	m.objects[pageObjNum] = []byte(updatedPageStr)
+
+	if m.verbose {
+		fmt.Printf("Set %dx%d thumbnail on page %d (object %d)\n", b.Dx(), b.Dy(), pageNumber, thumbObjNum)
+	}
+
+	return nil
+}
+
+// nextObjectNumber returns an object number not used by any object
+func (m *PDFManipulator) nextObjectNumber() int {
+	maxObjNum := 0
+	for objNum := range m.objects {
+		if objNum > maxObjNum {
+			maxObjNum = objNum
+		}
+	}
+	return maxObjNum + 1
+}
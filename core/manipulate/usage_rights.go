@@ -0,0 +1,180 @@
+package manipulate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// WarningUsageRightsRemoved is the code of the warning added when Rebuild strips
+// a usage rights signature
+const WarningUsageRightsRemoved = "USAGE_RIGHTS_REMOVED"
+
+// usageRightsKeys are the /Perms entries holding usage rights signatures: /UR3,
+// and /UR from before PDF 1.6
+var usageRightsKeys = []string{"/UR3", "/UR"}
+
+// usageRightsCategories are the TransformParams entries listing granted rights
+var usageRightsCategories = []string{"Document", "Msg", "Form", "FormEx", "Signature", "Annots", "EF"}
+
+// UsageRights describes a usage rights signature (Reader extensions), which
+// enables features such as saving filled forms in Adobe Reader. Any change to the
+// document invalidates the signature, and Reader then shows an error and
+// disables the extended features.
+type UsageRights struct {
+	Key             string              // "UR3", or "UR" for documents from before PDF 1.6
+	SignatureObjNum int                 // Object number of the signature dictionary; 0 when it is direct
+	Rights          map[string][]string // Granted rights by category, e.g. "Form": {"FillIn", "Import"}
+}
+
+// UsageRights returns the usage rights signature of the document, or nil when it has none
+func (m *PDFManipulator) UsageRights() *UsageRights {
+	_, catalog, err := m.catalog()
+	if err != nil {
+		return nil
+	}
+	_, rights := stripUsageRights(catalog, m.objectString, nil)
+	return rights
+}
+
+// RemoveUsageRights removes the usage rights signature from the catalog's /Perms
+// dictionary and returns it, or nil when there was none. Other permissions such
+// as a DocMDP certification signature are kept.
+func (m *PDFManipulator) RemoveUsageRights() *UsageRights {
+	rootObjNum, catalog, err := m.catalog()
+	if err != nil {
+		return nil
+	}
+	catalog, rights := stripUsageRights(catalog, m.objectString, func(objNum int, content string) {
+		m.objects[objNum] = []byte(content)
+	})
+	if rights == nil {
+		return nil
+	}
+	m.objects[rootObjNum] = []byte(catalog)
+	if rights.SignatureObjNum != 0 {
+		delete(m.objects, rights.SignatureObjNum)
+	}
+	if m.verbose {
+		fmt.Printf("Removed /%s usage rights signature\n", rights.Key)
+	}
+	return rights
+}
+
+// SetWarnings sets a collector for non-fatal issues, such as usage rights
+// removed by Rebuild
+func (m *PDFManipulator) SetWarnings(warnings *types.WarningCollector) {
+	m.warnings = warnings
+}
+
+// removeUsageRightsForRebuild strips the usage rights signature before the
+// document is rewritten, since the rewritten bytes would no longer match it
+func (m *PDFManipulator) removeUsageRightsForRebuild() {
+	rights := m.RemoveUsageRights()
+	if rights == nil {
+		return
+	}
+	if m.warnings != nil {
+		m.warnings.Add(types.NewWarningWithCode(types.WarningLevelWarning, WarningUsageRightsRemoved,
+			fmt.Sprintf("removed /%s usage rights signature invalidated by the changes; Reader extensions are no longer enabled", rights.Key)).
+			WithContext("rights", rights.Rights))
+	}
+}
+
+// catalog returns the object number and content of the document catalog
+func (m *PDFManipulator) catalog() (int, string, error) {
+	trailer := m.pdf.Trailer()
+	if trailer == nil || trailer.RootRef == "" {
+		return 0, "", fmt.Errorf("no catalog found")
+	}
+	rootObjNum, err := parseObjectRef(trailer.RootRef)
+	if err != nil {
+		return 0, "", err
+	}
+	catalog, ok := m.objects[rootObjNum]
+	if !ok {
+		return 0, "", fmt.Errorf("catalog object %d not found", rootObjNum)
+	}
+	return rootObjNum, string(catalog), nil
+}
+
+// objectString returns the content of an object, or "" when it doesn't exist
+func (m *PDFManipulator) objectString(objNum int) string {
+	return string(m.objects[objNum])
+}
+
+// stripUsageRights finds the usage rights signature in a catalog's /Perms
+// dictionary. get returns the content of an indirect object. When set is not
+// nil, the usage rights entries are removed: the updated catalog is returned and
+// an indirect /Perms dictionary is updated with set. A /Perms dictionary left
+// empty is removed from the catalog.
+func stripUsageRights(catalog string, get func(int) string, set func(int, string)) (string, *UsageRights) {
+	perms := rawDictValue(catalog, "/Perms")
+	permsObjNum := 0
+	if perms != "" && !strings.HasPrefix(perms, "<<") {
+		objNum, err := parseObjectRef(perms)
+		if err != nil {
+			return catalog, nil
+		}
+		permsObjNum = objNum
+		perms = string(objectBody([]byte(get(objNum))))
+	}
+	if perms == "" {
+		return catalog, nil
+	}
+
+	var rights *UsageRights
+	for _, key := range usageRightsKeys {
+		value := rawDictValue(perms, key)
+		if value == "" {
+			continue
+		}
+		if rights == nil {
+			rights = &UsageRights{Key: strings.TrimPrefix(key, "/")}
+			sig := value
+			if !strings.HasPrefix(sig, "<<") {
+				if objNum, err := parseObjectRef(sig); err == nil {
+					rights.SignatureObjNum = objNum
+					sig = string(objectBody([]byte(get(objNum))))
+				}
+			}
+			rights.Rights = usageRightsGranted(sig, get)
+		}
+		perms = removeRawDictKey(perms, key)
+	}
+	if rights == nil || set == nil {
+		return catalog, rights
+	}
+
+	if strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(perms), "<<"), ">>")) == "" {
+		return removeRawDictKey(catalog, "/Perms"), rights
+	}
+	if permsObjNum != 0 {
+		set(permsObjNum, perms)
+		return catalog, rights
+	}
+	return setRawDictValue(catalog, "/Perms", perms), rights
+}
+
+// usageRightsGranted returns the rights listed in the TransformParams of a usage
+// rights signature dictionary
+func usageRightsGranted(sig string, get func(int) string) map[string][]string {
+	params := rawDictValue(sig, "/TransformParams")
+	if params != "" && !strings.HasPrefix(params, "<<") {
+		if objNum, err := parseObjectRef(params); err == nil {
+			params = string(objectBody([]byte(get(objNum))))
+		}
+	}
+	rights := make(map[string][]string)
+	for _, category := range usageRightsCategories {
+		value := rawDictValue(params, "/"+category)
+		if !strings.HasPrefix(value, "[") {
+			continue
+		}
+		for _, name := range strings.Fields(strings.NewReplacer("[", " ", "]", " ", "/", " /").Replace(value)) {
+			rights[category] = append(rights[category], strings.TrimPrefix(name, "/"))
+		}
+	}
+	return rights
+}
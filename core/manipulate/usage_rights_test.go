@@ -0,0 +1,95 @@
+package manipulate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// createUsageRightsPDF creates a one-page document with a /UR3 usage rights
+// signature and the given extra /Perms entries
+func createUsageRightsPDF(t *testing.T, perms string) []byte {
+	t.Helper()
+	writer := write.NewPDFWriter()
+	writer.AddObject([]byte("<</Type/Page/Parent 2 0 R/MediaBox [0 0 612 792]>>"))
+	writer.AddObject([]byte("<</Type/Pages/Kids [1 0 R]/Count 1>>"))
+	writer.AddObject([]byte("<</Type/Sig/Filter/Adobe.PPKLite/SubFilter/adbe.pkcs7.detached/Contents <3082>/ByteRange [0 10 20 30]" +
+		"/Reference [<</Type/SigRef/TransformMethod/UR3/TransformParams <</Type/TransformParams/V/2.2/Document [/FullSave]/Form [/FillIn /Import]/Annots [/Create]>>>>]>>"))
+	writer.SetRoot(writer.AddObject([]byte("<</Type/Catalog/Pages 2 0 R/Perms <</UR3 3 0 R" + perms + ">>>>")))
+	pdfBytes, err := writer.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create test PDF: %v", err)
+	}
+	return pdfBytes
+}
+
+func TestUsageRights(t *testing.T) {
+	m, err := NewPDFManipulator(createUsageRightsPDF(t, ""), nil, false)
+	if err != nil {
+		t.Fatalf("Failed to create manipulator: %v", err)
+	}
+	rights := m.UsageRights()
+	if rights == nil {
+		t.Fatal("Usage rights not detected")
+	}
+	if rights.Key != "UR3" || rights.SignatureObjNum != 3 {
+		t.Errorf("UsageRights = %+v", rights)
+	}
+	if got := strings.Join(rights.Rights["Form"], ","); got != "FillIn,Import" {
+		t.Errorf("Form rights = %q", got)
+	}
+	if got := strings.Join(rights.Rights["Document"], ","); got != "FullSave" {
+		t.Errorf("Document rights = %q", got)
+	}
+
+	// Rebuild strips the signature with a warning
+	warnings := types.NewWarningCollector(true)
+	m.SetWarnings(warnings)
+	out, err := m.Rebuild()
+	if err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+	if strings.Contains(string(out), "/UR3") || strings.Contains(string(out), "/Perms") {
+		t.Error("Usage rights left in the rebuilt document")
+	}
+	if len(warnings.GetByCode(WarningUsageRightsRemoved)) != 1 {
+		t.Errorf("Warnings = %v", warnings.Warnings())
+	}
+
+	clean, err := NewPDFManipulator(out, nil, false)
+	if err != nil {
+		t.Fatalf("Failed to open rebuilt document: %v", err)
+	}
+	if clean.UsageRights() != nil || clean.RemoveUsageRights() != nil {
+		t.Error("Usage rights found after removal")
+	}
+}
+
+func TestRemoveUsageRights_KeepsDocMDP(t *testing.T) {
+	m, err := NewPDFManipulator(createUsageRightsPDF(t, "/DocMDP 9 0 R"), nil, false)
+	if err != nil {
+		t.Fatalf("Failed to create manipulator: %v", err)
+	}
+	if m.RemoveUsageRights() == nil {
+		t.Fatal("RemoveUsageRights found nothing")
+	}
+	_, catalog, _ := m.catalog()
+	if perms := rawDictValue(catalog, "/Perms"); !strings.Contains(perms, "/DocMDP 9 0 R") || strings.Contains(perms, "/UR3") {
+		t.Errorf("Perms = %q", perms)
+	}
+	if _, ok := m.objects[3]; ok {
+		t.Error("Usage rights signature object kept")
+	}
+}
+
+func TestRewrite_RemovesUsageRights(t *testing.T) {
+	out, err := Rewrite(createUsageRightsPDF(t, ""), RewriteOptions{Verify: true})
+	if err != nil {
+		t.Fatalf("Rewrite failed: %v", err)
+	}
+	if strings.Contains(string(out), "/UR3") || strings.Contains(string(out), "/TransformParams") {
+		t.Error("Usage rights left in the rewritten document")
+	}
+}
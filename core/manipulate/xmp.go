@@ -0,0 +1,69 @@
+package manipulate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+)
+
+// XMPMetadata returns the XMP packet of the catalog's /Metadata stream, or nil
+// when the document has none
+func (m *PDFManipulator) XMPMetadata() ([]byte, error) {
+	_, catalog, err := m.catalog()
+	if err != nil {
+		return nil, err
+	}
+	ref := rawDictValue(catalog, "/Metadata")
+	if ref == "" {
+		return nil, nil
+	}
+	objNum, err := parseObjectRef(ref)
+	if err != nil {
+		return nil, fmt.Errorf("invalid /Metadata reference %q: %w", ref, err)
+	}
+	obj, ok := m.objects[objNum]
+	if !ok {
+		return nil, nil
+	}
+
+	dict, data, isStream := splitStreamObject(objectBody(obj))
+	if !isStream {
+		return nil, fmt.Errorf("metadata object %d is not a stream", objNum)
+	}
+	switch filter := strings.Trim(rawDictValue(string(dict), "/Filter"), "[] "); filter {
+	case "":
+		return data, nil
+	case "/FlateDecode":
+		return parse.DecodeFlateDecode(data)
+	default:
+		return nil, fmt.Errorf("unsupported metadata filter %s", filter)
+	}
+}
+
+// SetXMPMetadata replaces the document's XMP metadata, reusing the existing
+// metadata object if there is one. The packet is stored unfiltered so that
+// tools scanning files for XMP can read it.
+func (m *PDFManipulator) SetXMPMetadata(xmp []byte) error {
+	rootObjNum, catalog, err := m.catalog()
+	if err != nil {
+		return err
+	}
+
+	objNum := 0
+	if ref := rawDictValue(catalog, "/Metadata"); ref != "" {
+		if n, err := parseObjectRef(ref); err == nil {
+			objNum = n
+		}
+	}
+	if objNum == 0 {
+		objNum = m.nextObjectNumber()
+		m.objects[rootObjNum] = []byte(setRawDictValue(catalog, "/Metadata", fmt.Sprintf("%d 0 R", objNum)))
+	}
+	m.objects[objNum] = joinStreamObject([]byte("<< /Type /Metadata /Subtype /XML >>"), xmp, true)
+
+	if m.verbose {
+		fmt.Printf("Set %d bytes of XMP metadata (object %d)\n", len(xmp), objNum)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package manipulate
+
+import (
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+)
+
+func TestSetXMPMetadata(t *testing.T) {
+	builder := write.NewSimplePDFBuilder()
+	builder.FinalizePage(builder.AddPage(write.PageSizeLetter))
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create test PDF: %v", err)
+	}
+
+	m, err := NewPDFManipulator(pdfBytes, nil, false)
+	if err != nil {
+		t.Fatalf("NewPDFManipulator failed: %v", err)
+	}
+	if xmp, err := m.XMPMetadata(); err != nil || xmp != nil {
+		t.Fatalf("XMPMetadata = %q, %v; want none", xmp, err)
+	}
+
+	for _, packet := range []string{"<x:xmpmeta>first</x:xmpmeta>", "<x:xmpmeta>second</x:xmpmeta>"} {
+		if err := m.SetXMPMetadata([]byte(packet)); err != nil {
+			t.Fatalf("SetXMPMetadata failed: %v", err)
+		}
+		out, err := m.Rebuild()
+		if err != nil {
+			t.Fatalf("Rebuild failed: %v", err)
+		}
+		m, err = NewPDFManipulator(out, nil, false)
+		if err != nil {
+			t.Fatalf("Failed to reopen output: %v", err)
+		}
+		xmp, err := m.XMPMetadata()
+		if err != nil || string(xmp) != packet {
+			t.Errorf("XMPMetadata = %q, %v; want %q", xmp, err, packet)
+		}
+	}
+}
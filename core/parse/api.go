@@ -13,12 +13,23 @@
 //
 //	obj, err := pdf.GetObject(5)
 //
+// Walk pages with inherited attributes resolved:
+//
+//	it := pdf.Pages()
+//	for it.Next() {
+//	    page := it.Page() // page.Resources, page.MediaBox, page.Rotate ...
+//	}
+//
 // For encrypted PDFs:
 //
 //	pdf, err := parser.OpenWithOptions(pdfBytes, parser.ParseOptions{
 //	    Password: []byte("secret"),
 //	})
 //
+// For files too large to read into memory, objects are read on demand:
+//
+//	pdf, err := parser.OpenReader(file, size)
+//
 // For byte-perfect reconstruction:
 //
 //	pdf, err := parser.OpenWithOptions(pdfBytes, parser.ParseOptions{
@@ -28,6 +39,12 @@
 package parse
 
 import (
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"sync"
+
 	"github.com/benedoc-inc/pdfer/core/encrypt"
 	"github.com/benedoc-inc/pdfer/types"
 )
@@ -38,10 +55,13 @@ type ParseOptions struct {
 	Verbose     bool                    // Enable verbose logging
 	BytePerfect bool                    // Preserve exact bytes for reconstruction
 	Warnings    *types.WarningCollector // Optional warning collector for non-fatal issues
+	MemoryMap   bool                    // Memory-map the input in OpenFile (falls back to reading when unsupported)
 }
 
 // PDF represents a parsed PDF document.
 // This is the main entry point for working with PDF files.
+// A PDF is not modified after Open returns, so its methods may be called from
+// multiple goroutines; Close must not be called while it is still in use.
 type PDF struct {
 	raw        []byte
 	doc        *PDFDocument         // Populated when BytePerfect is true
@@ -49,12 +69,25 @@ type PDF struct {
 	encryption *types.PDFEncryption // Encryption info (nil if unencrypted)
 	trailer    *TrailerInfo         // Parsed trailer information
 	opts       ParseOptions
+	mapped     *MappedFile // Backing mapping when opened with OpenFile and MemoryMap
+	src        io.ReaderAt // Source when opened with OpenReader; raw is nil then
+	size       int64       // Size of src
+
+	scanOnce sync.Once     // Guards scanned
+	scanned  map[int]int64 // Object offsets from a full scan, built when xref offsets are wrong
+
+	chainOnce sync.Once         // Guards chain
+	chain     []XRefSectionInfo // Cross-reference sections of the /Prev chain, oldest first
+
+	objStmMu sync.Mutex            // Guards objStms
+	objStms  map[int]*objectStream // Decoded object streams by object number
 }
 
 // XRef represents consolidated cross-reference data for all objects in the PDF.
 // It merges data from all revisions (for incremental updates) into a single view.
 type XRef struct {
 	Objects map[int]*ObjectRef // Object number -> reference info
+	Free    map[int]bool       // Objects freed by the newest revision that lists them
 	Size    int                // Total number of objects
 }
 
@@ -92,7 +125,7 @@ func OpenWithOptions(data []byte, opts ParseOptions) (*PDF, error) {
 	pdf := &PDF{
 		raw:  data,
 		opts: opts,
-		xref: &XRef{Objects: make(map[int]*ObjectRef)},
+		xref: &XRef{Objects: make(map[int]*ObjectRef), Free: make(map[int]bool)},
 	}
 
 	// Handle encryption first
@@ -116,8 +149,14 @@ func OpenWithOptions(data []byte, opts ParseOptions) (*PDF, error) {
 
 // handleEncryption checks for encryption and validates password
 func (p *PDF) handleEncryption() error {
+	return p.validatePassword(p.raw)
+}
+
+// validatePassword validates the password against the /Encrypt dictionary and
+// /ID found in data, the document bytes or the parts of them holding both
+func (p *PDF) validatePassword(data []byte) error {
 	// Try to parse encryption dictionary
-	enc, err := encrypt.ParseEncryptionDictionary(p.raw, p.opts.Verbose)
+	enc, err := encrypt.ParseEncryptionDictionary(data, p.opts.Verbose)
 	if err != nil {
 		// No encryption or parse error - assume unencrypted
 		return nil
@@ -125,7 +164,17 @@ func (p *PDF) handleEncryption() error {
 
 	if enc != nil {
 		// PDF is encrypted - validate password
-		_, validatedEnc, err := encrypt.DecryptPDF(p.raw, p.opts.Password, p.opts.Verbose)
+		_, validatedEnc, err := encrypt.DecryptPDF(data, p.opts.Password, p.opts.Verbose)
+		if err != nil && encrypt.AttachmentsOnly(enc) {
+			// Only embedded files are encrypted; the document itself opens without
+			// the password and the attachments are returned as they are stored
+			if p.opts.Verbose {
+				log.Printf("Embedded files are encrypted and the password is wrong; opening without attachment access")
+			}
+			enc.EncryptKey = nil
+			p.encryption = enc
+			return nil
+		}
 		if err != nil {
 			return types.WrapError(types.ErrCodeWrongPassword, "decryption failed (wrong password?)", err)
 		}
@@ -143,9 +192,19 @@ func (p *PDF) parseBytePerfect() error {
 	}
 	p.doc = doc
 
-	// Build unified xref from document revisions
+	// Build unified xref from document revisions. A free entry drops the object
+	// of earlier revisions; objects of the same revision take precedence.
 	for _, rev := range doc.Revisions {
+		if rev.XRef != nil {
+			for _, entry := range rev.XRef.Entries {
+				if !entry.InUse && !entry.InObjectStream && entry.ObjectNum != 0 {
+					delete(p.xref.Objects, entry.ObjectNum)
+					p.xref.Free[entry.ObjectNum] = true
+				}
+			}
+		}
 		for objNum, obj := range rev.Objects {
+			delete(p.xref.Free, objNum)
 			p.xref.Objects[objNum] = &ObjectRef{
 				Number:     obj.Number,
 				Generation: obj.Generation,
@@ -158,16 +217,26 @@ func (p *PDF) parseBytePerfect() error {
 		}
 	}
 
-	// Build trailer info from last revision
-	if len(doc.Revisions) > 0 {
-		lastRev := doc.Revisions[len(doc.Revisions)-1]
-		if lastRev.Trailer != nil {
-			p.trailer = &TrailerInfo{
-				Size:       lastRev.Trailer.Size,
-				RootRef:    lastRev.Trailer.Root,
-				InfoRef:    lastRev.Trailer.Info,
-				EncryptRef: lastRev.Trailer.Encrypt,
-			}
+	// Build trailer info from the revisions, each entry from the newest
+	// trailer that has it
+	for _, rev := range doc.Revisions {
+		if rev.Trailer == nil {
+			continue
+		}
+		if p.trailer == nil {
+			p.trailer = &TrailerInfo{}
+		}
+		if rev.Trailer.Size > 0 {
+			p.trailer.Size = rev.Trailer.Size
+		}
+		if rev.Trailer.Root != "" {
+			p.trailer.RootRef = rev.Trailer.Root
+		}
+		if rev.Trailer.Info != "" {
+			p.trailer.InfoRef = rev.Trailer.Info
+		}
+		if rev.Trailer.Encrypt != "" {
+			p.trailer.EncryptRef = rev.Trailer.Encrypt
 		}
 	}
 
@@ -200,14 +269,20 @@ func (p *PDF) parseStandard() error {
 		return nil
 	}
 
-	// Build xref from incremental parser results
+	p.loadXRef(incParser)
+	return nil
+}
+
+// loadXRef builds the object view and trailer from the parsed /Prev chain
+func (p *PDF) loadXRef(incParser *incrementalParser) {
 	mergedObjs := incParser.getObjectMap()
 	mergedStreams := incParser.getObjectStreamMap()
 
 	for objNum, offset := range mergedObjs {
 		p.xref.Objects[objNum] = &ObjectRef{
-			Number: objNum,
-			Offset: offset,
+			Number:     objNum,
+			Generation: incParser.mergedGens[objNum],
+			Offset:     offset,
 		}
 	}
 
@@ -219,21 +294,22 @@ func (p *PDF) parseStandard() error {
 			StreamIndex:  entry.IndexInStream,
 		}
 	}
-
-	// Get trailer info from last section
-	sections := incParser.getSections()
-	if len(sections) > 0 {
-		lastSection := sections[len(sections)-1]
-		p.trailer = &TrailerInfo{
-			Size:       lastSection.Size,
-			RootRef:    lastSection.Root,
-			InfoRef:    lastSection.Info,
-			EncryptRef: lastSection.Encrypt,
-		}
-		p.xref.Size = lastSection.Size
+	p.xref.Free = incParser.mergedFree
+
+	// Trailer entries come from the newest section that has them
+	trailer := incParser.trailer()
+	p.trailer = &TrailerInfo{
+		Size:       trailer.Size,
+		RootRef:    trailer.Root,
+		InfoRef:    trailer.Info,
+		EncryptRef: trailer.Encrypt,
+		IDArray:    []byte(trailer.ID),
+	}
+	p.xref.Size = trailer.Size
+	p.chain = incParser.chain()
+	for _, problem := range incParser.problems {
+		p.addWarningf(types.WarningLevelWarning, "broken cross-reference chain: %s", problem)
 	}
-
-	return nil
 }
 
 // Version returns the PDF version string (e.g., "1.7")
@@ -242,7 +318,11 @@ func (p *PDF) Version() string {
 		return p.doc.Header.Version
 	}
 	// Parse version from raw bytes
-	header, err := ParsePDFHeader(p.raw)
+	data := p.raw
+	if p.src != nil {
+		data, _ = readWindow(p.src, p.size, 0, nil)
+	}
+	header, err := ParsePDFHeader(data)
 	if err != nil {
 		return "unknown"
 	}
@@ -254,6 +334,9 @@ func (p *PDF) RevisionCount() int {
 	if p.doc != nil {
 		return len(p.doc.Revisions)
 	}
+	if p.src != nil {
+		return len(p.chain)
+	}
 	// For standard parsing, count %%EOF markers
 	return len(FindAllEOFMarkers(p.raw))
 }
@@ -263,12 +346,13 @@ func (p *PDF) ObjectCount() int {
 	return len(p.xref.Objects)
 }
 
-// Objects returns a list of all object numbers in the PDF
+// Objects returns a list of all object numbers in the PDF, in ascending order
 func (p *PDF) Objects() []int {
 	result := make([]int, 0, len(p.xref.Objects))
 	for objNum := range p.xref.Objects {
 		result = append(result, objNum)
 	}
+	sort.Ints(result)
 	return result
 }
 
@@ -278,23 +362,93 @@ func (p *PDF) HasObject(objNum int) bool {
 	return ok
 }
 
+// Ref returns where an object is stored, as listed by the cross-reference data
+func (p *PDF) Ref(objNum int) (ObjectRef, bool) {
+	ref, ok := p.xref.Objects[objNum]
+	if !ok {
+		return ObjectRef{}, false
+	}
+	return *ref, true
+}
+
+// IsFree reports whether the newest cross-reference entry of an object is free,
+// as for annotations and form fields deleted by an incremental update
+func (p *PDF) IsFree(objNum int) bool {
+	return p.xref.Free[objNum]
+}
+
 // GetObject returns the content of a PDF object by number.
-// Returns the raw bytes between "N G obj" and "endobj".
+// Returns the raw bytes between "N G obj" and "endobj". A free object is the
+// null object (see IsNullObject) rather than the data of an older revision.
+// Objects stored in object streams are returned as the stream holds them, with
+// no header; each object stream is decoded once and kept for later calls.
 func (p *PDF) GetObject(objNum int) ([]byte, error) {
 	ref, ok := p.xref.Objects[objNum]
+	if !ok && p.xref.Free[objNum] {
+		return nullObject(objNum), nil
+	}
 	if !ok {
 		return nil, types.NewPDFErrorf(types.ErrCodeObjectNotFound, "object %d not found", objNum).WithContext("object_number", objNum)
 	}
 
 	if ref.InStream {
-		// Object is in an object stream - extract it
-		return GetObjectFromStream(p.raw, objNum, ref.StreamObjNum, ref.StreamIndex, p.encryption, p.opts.Verbose)
+		return p.objectFromStream(objNum, ref)
+	}
+	if p.src != nil {
+		return p.readObject(objNum, ref)
 	}
 
-	// Direct object - get from byte offset
+	// Direct object - get from byte offset, recovering it from a full scan of the
+	// document when the xref offset does not point at its header
+	if !hasObjectHeader(p.raw, ref.Offset, objNum) {
+		if offset, ok := p.recoveredOffset(objNum); ok {
+			if p.opts.Verbose {
+				log.Printf("Object %d recovered at offset %d (xref offset %d)", objNum, offset, ref.Offset)
+			}
+			ref.Offset = offset
+		}
+	}
 	return GetDirectObject(p.raw, objNum, ref.Offset, p.encryption, p.opts.Verbose)
 }
 
+// objectFromStream returns an object stored in an object stream, decoding the
+// stream, located through the cross-reference data, on first use
+func (p *PDF) objectFromStream(objNum int, ref *ObjectRef) ([]byte, error) {
+	p.objStmMu.Lock()
+	stm, ok := p.objStms[ref.StreamObjNum]
+	p.objStmMu.Unlock()
+
+	if !ok {
+		streamRef, found := p.xref.Objects[ref.StreamObjNum]
+		if !found || streamRef.InStream {
+			return nil, types.NewPDFErrorf(types.ErrCodeObjectNotFound, "object stream %d of object %d not found", ref.StreamObjNum, objNum).WithContext("object_number", objNum)
+		}
+		container, err := p.GetObject(ref.StreamObjNum)
+		if err != nil {
+			return nil, err
+		}
+		stm, err = parseObjectStream(container)
+		if err != nil {
+			return nil, types.WrapError(types.ErrCodeStreamError, fmt.Sprintf("object stream %d", ref.StreamObjNum), err).WithContext("object_number", objNum)
+		}
+		if p.opts.Verbose {
+			log.Printf("Decoded object stream %d: %d objects", ref.StreamObjNum, len(stm.numbers))
+		}
+		p.objStmMu.Lock()
+		if p.objStms == nil {
+			p.objStms = make(map[int]*objectStream)
+		}
+		p.objStms[ref.StreamObjNum] = stm
+		p.objStmMu.Unlock()
+	}
+
+	data, err := stm.object(objNum, ref.StreamIndex)
+	if err != nil {
+		return nil, types.WrapError(types.ErrCodeObjectNotFound, fmt.Sprintf("object stream %d", ref.StreamObjNum), err).WithContext("object_number", objNum)
+	}
+	return data, nil
+}
+
 // GetRawObject returns a PDFRawObject with full byte preservation.
 // Only available when parsed with BytePerfect option.
 func (p *PDF) GetRawObject(objNum int) (*PDFRawObject, error) {
@@ -312,6 +466,48 @@ func (p *PDF) GetRawObject(objNum int) (*PDFRawObject, error) {
 	return nil, types.NewPDFErrorf(types.ErrCodeObjectNotFound, "object %d not found", objNum).WithContext("object_number", objNum)
 }
 
+// XRefChain returns the cross-reference sections of the document's /Prev
+// chain, oldest first. The object view merges them in that order, so each
+// object resolves to the entry of the newest section that lists it, and each
+// trailer entry comes from the newest trailer that has it. It is nil when the
+// chain can't be parsed and objects were located by other means.
+func (p *PDF) XRefChain() []XRefSectionInfo {
+	p.chainOnce.Do(func() {
+		if p.chain != nil {
+			return
+		}
+		incParser := newIncrementalParser(p.raw, false)
+		if incParser.parse() == nil {
+			p.chain = incParser.chain()
+		}
+	})
+	return p.chain
+}
+
+// ReadXRefChain parses the cross-reference sections of a file's /Prev chain,
+// oldest first, like PDF.XRefChain. It neither opens the document nor decrypts
+// anything, so it needs no password.
+func ReadXRefChain(data []byte) ([]XRefSectionInfo, error) {
+	incParser := newIncrementalParser(data, false)
+	if err := incParser.parse(); err != nil {
+		return nil, types.WrapError(types.ErrCodeXRefError, "failed to parse cross-reference chain", err)
+	}
+	return incParser.chain(), nil
+}
+
+// ObjectGeneration returns the generation number of an object stored directly
+// in data, from the newest cross-reference entry of the object
+func ObjectGeneration(data []byte, objNum int) (int, error) {
+	incParser := newIncrementalParser(data, false)
+	if err := incParser.parse(); err != nil {
+		return 0, types.WrapError(types.ErrCodeXRefError, "failed to parse cross-reference chain", err)
+	}
+	if _, ok := incParser.mergedObjs[objNum]; !ok {
+		return 0, types.NewPDFErrorf(types.ErrCodeObjectNotFound, "object %d is not stored directly in the file", objNum).WithContext("object_number", objNum)
+	}
+	return incParser.mergedGens[objNum], nil
+}
+
 // Trailer returns the trailer information
 func (p *PDF) Trailer() *TrailerInfo {
 	return p.trailer
@@ -329,7 +525,7 @@ func (p *PDF) Encryption() *types.PDFEncryption {
 
 // Bytes returns the PDF as bytes.
 // If parsed with BytePerfect option, returns byte-identical reconstruction.
-// Otherwise, returns the original input bytes.
+// Otherwise, returns the original input bytes, or nil when opened with OpenReader.
 func (p *PDF) Bytes() []byte {
 	if p.doc != nil {
 		return p.doc.Bytes()
@@ -337,11 +533,24 @@ func (p *PDF) Bytes() []byte {
 	return p.raw
 }
 
-// Raw returns the original input bytes (always available)
+// Raw returns the original input bytes (nil when opened with OpenReader)
 func (p *PDF) Raw() []byte {
 	return p.raw
 }
 
+// Close releases the memory mapping created by OpenFile with MemoryMap.
+// Byte slices obtained from the PDF must not be used after Close.
+// It is a no-op for PDFs opened from a byte slice.
+func (p *PDF) Close() error {
+	if p.mapped == nil {
+		return nil
+	}
+	err := p.mapped.Close()
+	p.mapped = nil
+	p.raw = nil
+	return err
+}
+
 // Document returns the underlying PDFDocument (only for BytePerfect mode)
 func (p *PDF) Document() *PDFDocument {
 	return p.doc
@@ -171,6 +171,30 @@ func TestPDF_Trailer(t *testing.T) {
 	}
 }
 
+func TestPDF_Ref_Generation(t *testing.T) {
+	pdfBytes := createTestPDFForAPI()
+	obj3Offset := bytes.Index(pdfBytes, []byte("3 0 obj"))
+	pdfBytes = bytes.Replace(pdfBytes, []byte("3 0 obj"), []byte("3 2 obj"), 1)
+	pdfBytes = bytes.Replace(pdfBytes, []byte(fmt.Sprintf("%010d 00000 n", obj3Offset)), []byte(fmt.Sprintf("%010d 00002 n", obj3Offset)), 1)
+
+	pdf, err := Open(pdfBytes)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if ref, ok := pdf.Ref(3); !ok || ref.Generation != 2 {
+		t.Errorf("Ref(3) = %+v, %v; want generation 2", ref, ok)
+	}
+	if ref, ok := pdf.Ref(2); !ok || ref.Generation != 0 {
+		t.Errorf("Ref(2) = %+v, %v; want generation 0", ref, ok)
+	}
+	if gen, err := ObjectGeneration(pdfBytes, 3); err != nil || gen != 2 {
+		t.Errorf("ObjectGeneration(3) = %d, %v; want 2", gen, err)
+	}
+	if _, err := ObjectGeneration(pdfBytes, 9); err == nil {
+		t.Error("Expected an error for a missing object")
+	}
+}
+
 func TestPDF_RevisionCount(t *testing.T) {
 	pdfBytes := createTestPDFForAPI()
 
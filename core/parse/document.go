@@ -285,8 +285,8 @@ func isWhitespace(b byte) bool {
 // This is a lightweight type for quick trailer parsing without byte preservation.
 // For byte-perfect reconstruction, use TrailerData instead.
 type PDFTrailer struct {
-	RootRef    string // Root reference (e.g., "/Root 204 0 R")
-	EncryptRef string // Encrypt reference if present
-	InfoRef    string // Info reference if present
+	RootRef    string // Root reference (e.g., "204 0 R")
+	EncryptRef string // Encrypt reference if present, in the same form
+	InfoRef    string // Info reference if present, in the same form
 	StartXRef  int64  // Byte offset from startxref
 }
@@ -3,9 +3,7 @@ package parse
 
 import (
 	"bytes"
-	"compress/zlib"
 	"fmt"
-	"io"
 )
 
 // DecodeFilter applies the appropriate filter to decode stream data
@@ -29,13 +27,16 @@ func DecodeFilter(data []byte, filterName string) ([]byte, error) {
 
 // DecodeFlateDecode decompresses zlib/deflate compressed data
 func DecodeFlateDecode(data []byte) ([]byte, error) {
-	reader, err := zlib.NewReader(bytes.NewReader(data))
-	if err != nil {
+	buf := AcquireBuffer()
+	defer ReleaseBuffer(buf)
+
+	if err := inflateZlibTo(buf, data); err != nil {
 		return nil, fmt.Errorf("zlib error: %v", err)
 	}
-	defer reader.Close()
 
-	return io.ReadAll(reader)
+	out := make([]byte, buf.Len())
+	copy(out, buf.Bytes())
+	return out, nil
 }
 
 // DecodeASCIIHex decodes ASCIIHexDecode filter data
@@ -17,89 +17,51 @@ type ObjectLocation struct {
 	ByteOffset    int64 // For direct objects: byte offset in PDF
 	StreamObjNum  int   // For object stream objects: containing stream's object number
 	IndexInStream int   // For object stream objects: index within the stream
+	StreamOffset  int64 // For object stream objects: byte offset of the containing stream, 0 if not in the xref
+	IsFree        bool  // True if the newest cross-reference entry frees the object
 }
 
-// FindObjectLocation finds where an object is located (direct or in object stream)
+// FindObjectLocation finds where an object is located (direct or in object
+// stream), following the /Prev chain so that objects updated or freed by
+// incremental saves resolve to their newest version
 func FindObjectLocation(pdfBytes []byte, objNum int, verbose bool) (*ObjectLocation, error) {
 	// ALWAYS use xref table first - it's the authoritative source
 	// Direct search using bytes.Index is dangerous because "5 0 obj" matches inside "265 0 obj"
-
-	// Parse xref to find object
-	startxrefPattern := regexp.MustCompile(`startxref\s+(\d+)`)
-	startxrefMatch := startxrefPattern.FindStringSubmatch(string(pdfBytes))
-	if startxrefMatch == nil {
-		return nil, fmt.Errorf("startxref not found")
-	}
-
-	startXRef, err := strconv.ParseInt(startxrefMatch[1], 10, 64)
-	if err != nil || startXRef <= 0 {
-		return nil, fmt.Errorf("invalid startxref: %s", startxrefMatch[1])
-	}
-
-	// Bounds check
-	if startXRef >= int64(len(pdfBytes)) {
-		return nil, fmt.Errorf("startxref offset %d is beyond PDF length %d", startXRef, len(pdfBytes))
-	}
-
-	// Determine if this is a traditional xref or xref stream
-	xrefSection := pdfBytes[startXRef:]
-
-	// Check if it's an xref stream (PDF 1.5+)
-	// Xref streams start with "N 0 obj" instead of "xref"
-	if bytes.Contains(xrefSection[:min(100, len(xrefSection))], []byte("obj")) {
-		// It's an xref stream - use full parsing
-		result, err := ParseXRefStreamFull(pdfBytes, startXRef, verbose)
-		if err != nil {
+	incParser := newIncrementalParser(pdfBytes, verbose)
+	if err := incParser.parse(); err != nil {
+		if verbose {
+			log.Printf("Failed to parse cross-reference chain: %v", err)
+		}
+	} else {
+		if incParser.mergedFree[objNum] {
 			if verbose {
-				log.Printf("Failed to parse xref stream: %v", err)
+				log.Printf("Object %d is free", objNum)
 			}
-		} else {
-			// Check if object is in object stream
-			if entry, ok := result.ObjectStreams[objNum]; ok {
-				if verbose {
-					log.Printf("Object %d is in object stream %d at index %d", objNum, entry.StreamObjNum, entry.IndexInStream)
-				}
-				return &ObjectLocation{
-					IsDirect:      false,
-					StreamObjNum:  entry.StreamObjNum,
-					IndexInStream: entry.IndexInStream,
-				}, nil
-			}
-
-			// Check regular objects
-			if offset, ok := result.Objects[objNum]; ok {
-				if verbose {
-					log.Printf("Object %d at byte offset %d (from xref stream)", objNum, offset)
-				}
-				return &ObjectLocation{IsDirect: true, ByteOffset: offset}, nil
+			return &ObjectLocation{IsFree: true}, nil
+		}
+		if entry, ok := incParser.mergedStreams[objNum]; ok {
+			if verbose {
+				log.Printf("Object %d is in object stream %d at index %d", objNum, entry.StreamObjNum, entry.IndexInStream)
 			}
+			return &ObjectLocation{
+				IsDirect:      false,
+				StreamObjNum:  entry.StreamObjNum,
+				IndexInStream: entry.IndexInStream,
+				StreamOffset:  incParser.mergedObjs[entry.StreamObjNum],
+			}, nil
 		}
-	} else if bytes.HasPrefix(xrefSection, []byte("xref")) {
-		// Traditional xref table
-		objMap, err := ParseTraditionalXRefTable(pdfBytes, startXRef)
-		if err == nil {
-			if offset, ok := objMap[objNum]; ok {
-				if verbose {
-					log.Printf("Object %d at byte offset %d (from traditional xref)", objNum, offset)
-				}
-				return &ObjectLocation{IsDirect: true, ByteOffset: offset}, nil
+		if offset, ok := incParser.mergedObjs[objNum]; ok {
+			if verbose {
+				log.Printf("Object %d at byte offset %d (from cross-reference chain)", objNum, offset)
 			}
+			return &ObjectLocation{IsDirect: true, ByteOffset: offset}, nil
 		}
 	}
 
-	// Not found in xref - try regex search for object header with word boundary
-	// Use negative lookbehind equivalent: require whitespace or start of file before objNum
-	pattern := regexp.MustCompile(fmt.Sprintf(`(^|\s|[\r\n])%d\s+0\s+obj`, objNum))
-	matches := pattern.FindIndex(pdfBytes)
-	if matches != nil {
-		// Find the actual start of the object number
-		offset := int64(matches[0])
-		// Skip the preceding whitespace/newline
-		for offset < int64(len(pdfBytes)) && (pdfBytes[offset] == ' ' || pdfBytes[offset] == '\r' || pdfBytes[offset] == '\n' || pdfBytes[offset] == '\t') {
-			offset++
-		}
+	// Not found in xref - look it up in a scan of the document's object headers
+	if offset, ok := recoverObjectOffset(pdfBytes, objNum, verbose); ok {
 		if verbose {
-			log.Printf("Object %d found via regex at offset %d", objNum, offset)
+			log.Printf("Object %d found by scanning at offset %d", objNum, offset)
 		}
 		return &ObjectLocation{IsDirect: true, ByteOffset: offset}, nil
 	}
@@ -116,12 +78,16 @@ func GetObject(pdfBytes []byte, objNum int, encryptInfo *types.PDFEncryption, ve
 		return nil, fmt.Errorf("object %d not found: %v", objNum, err)
 	}
 
+	if loc.IsFree {
+		return nullObject(objNum), nil
+	}
+
 	if !loc.IsDirect {
 		// Object is in an object stream - extract it
 		if verbose {
 			log.Printf("Extracting object %d from object stream %d (index %d)", objNum, loc.StreamObjNum, loc.IndexInStream)
 		}
-		return GetObjectFromStream(pdfBytes, objNum, loc.StreamObjNum, loc.IndexInStream, encryptInfo, verbose)
+		return objectFromStream(pdfBytes, objNum, loc.StreamObjNum, loc.IndexInStream, loc.StreamOffset, encryptInfo, verbose)
 	}
 
 	// Direct object - read it from the byte offset
@@ -131,6 +97,49 @@ func GetObject(pdfBytes []byte, objNum int, encryptInfo *types.PDFEncryption, ve
 	return GetDirectObject(pdfBytes, objNum, loc.ByteOffset, encryptInfo, verbose)
 }
 
+// rootRefPattern matches the /Root entry of a trailer or cross-reference stream
+var rootRefPattern = regexp.MustCompile(`/Root\s+(\d+)\s+\d+\s+R`)
+
+// GetCatalog returns the document catalog named by the /Root of the newest
+// trailer, whether it is stored directly or in an object stream, where its
+// entries can't be found by searching the document bytes
+func GetCatalog(pdfBytes []byte, encryptInfo *types.PDFEncryption, verbose bool) ([]byte, error) {
+	rootObjNum := 0
+	incParser := newIncrementalParser(pdfBytes, verbose)
+	if err := incParser.parse(); err == nil {
+		rootObjNum, _ = parseRef(incParser.trailer().Root)
+	}
+	if rootObjNum == 0 {
+		matches := rootRefPattern.FindAllSubmatch(pdfBytes, -1)
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no root reference found in trailer")
+		}
+		rootObjNum, _ = strconv.Atoi(string(matches[len(matches)-1][1]))
+	}
+	return GetObject(pdfBytes, rootObjNum, encryptInfo, verbose)
+}
+
+// objectHeaderPattern matches the "N G obj" header of an object
+var objectHeaderPattern = regexp.MustCompile(`^\d+\s+\d+\s+obj\b`)
+
+// nullObject returns the null object that a reference to a free object
+// resolves to
+func nullObject(objNum int) []byte {
+	return []byte(fmt.Sprintf("%d 0 obj\nnull\nendobj", objNum))
+}
+
+// IsNullObject reports whether an object, with or without its "N G obj" header,
+// is the null object, such as GetObject returns for a free object. Like a
+// missing dictionary entry, it should be treated as absent.
+func IsNullObject(obj []byte) bool {
+	content := bytes.TrimSpace(obj)
+	if loc := objectHeaderPattern.FindIndex(content); loc != nil {
+		content = bytes.TrimSpace(content[loc[1]:])
+	}
+	content = bytes.TrimSpace(bytes.TrimSuffix(content, []byte("endobj")))
+	return string(content) == "null"
+}
+
 // GetDirectObject reads a PDF object at a specific byte offset
 func GetDirectObject(pdfBytes []byte, objNum int, offset int64, encryptInfo *types.PDFEncryption, verbose bool) ([]byte, error) {
 	if offset < 0 || offset >= int64(len(pdfBytes)) {
@@ -145,20 +154,19 @@ func GetDirectObject(pdfBytes []byte, objNum int, offset int64, encryptInfo *typ
 
 	var genNum int
 	if headerMatch == nil {
-		// Header not exactly at offset - try to find it nearby
-		searchArea := pdfBytes[max(0, int(offset)-100):min(len(pdfBytes), int(offset)+100)]
-		pattern := regexp.MustCompile(fmt.Sprintf(`%d\s+(\d+)\s+obj`, objNum))
-		match := pattern.FindIndex(searchArea)
-		if match == nil {
+		// Header not at the xref offset - recover it from a scan of the document
+		recovered, ok := recoverObjectOffset(pdfBytes, objNum, verbose)
+		if !ok {
 			if verbose {
-				log.Printf("Object %d header not found near offset %d", objNum, offset)
+				log.Printf("Object %d header not found at offset %d", objNum, offset)
 			}
 			// Continue anyway with content at offset
 			genNum = 0
 		} else {
-			// Adjust offset
-			newOffset := max(0, int(offset)-100) + match[0]
-			offset = int64(newOffset)
+			if verbose {
+				log.Printf("Object %d recovered at offset %d (xref offset %d)", objNum, recovered, offset)
+			}
+			offset = recovered
 			objData = pdfBytes[offset:]
 			headerMatch = headerPattern.FindSubmatch(objData[:min(50, len(objData))])
 			if headerMatch != nil {
@@ -202,7 +210,7 @@ func GetDirectObject(pdfBytes []byte, objNum int, offset int64, encryptInfo *typ
 		content = content[:endstreamPos+len("endstream")]
 
 		// Decrypt stream data if needed
-		if encryptInfo != nil {
+		if encrypt.EncryptsStream(content[:streamStart], encryptInfo) {
 			// Get /Length from dictionary for exact stream data size
 			dictPart := content[:streamStart]
 			lengthPattern := regexp.MustCompile(`/Length\s+(\d+)`)
@@ -247,6 +255,14 @@ func GetDirectObject(pdfBytes []byte, objNum int, offset int64, encryptInfo *typ
 				newLength := fmt.Sprintf("/Length %d", len(decryptedStream))
 				dictPart = lengthPattern.ReplaceAll(dictPart, []byte(newLength))
 
+				// Strings in the stream dictionary are encrypted too (cross-reference streams are not)
+				xrefTypePattern := regexp.MustCompile(`/Type\s*/XRef\b`)
+				if encrypt.EncryptsStrings(encryptInfo) && !xrefTypePattern.Match(dictPart) {
+					if decryptedDict, err := encrypt.DecryptStrings(dictPart, objNum, genNum, encryptInfo); err == nil {
+						dictPart = decryptedDict
+					}
+				}
+
 				// Reconstruct content with updated dictionary and decrypted stream
 				newContent := make([]byte, 0, len(dictPart)+len(decryptedStream)+20)
 				newContent = append(newContent, dictPart...)
@@ -262,13 +278,14 @@ func GetDirectObject(pdfBytes []byte, objNum int, offset int64, encryptInfo *typ
 				log.Printf("Decryption failed: %v", err)
 			}
 		}
-	} else if encryptInfo != nil {
-		// Not a stream - decrypt string values in dictionary
-		// For now, just decrypt the entire content (simplified approach)
-		// TODO: Parse dictionary properly and decrypt only string values
-		decrypted, err := encrypt.DecryptObject(content, objNum, genNum, encryptInfo)
+	} else if encrypt.EncryptsStrings(encryptInfo) && !regexp.MustCompile(`/Filter\s*/Standard\b`).Match(content) {
+		// Not a stream - decrypt the string values in the object.
+		// The Encrypt dictionary itself is never encrypted.
+		decrypted, err := encrypt.DecryptStrings(content, objNum, genNum, encryptInfo)
 		if err == nil {
 			content = decrypted
+		} else if verbose {
+			log.Printf("String decryption failed for object %d: %v", objNum, err)
 		}
 	}
 
@@ -4,29 +4,44 @@ package parse
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"regexp"
 	"sort"
 	"strconv"
+	"strings"
 )
 
+// trailerIDPattern matches the /ID entry of a trailer
+var trailerIDPattern = regexp.MustCompile(`/ID\s*\[[^\]]*\]`)
+
 // xrefSection represents a single cross-reference section from a PDF
 type xrefSection struct {
 	StartXRef int64                     // Byte offset where this xref section starts
 	Objects   map[int]int64             // Object number -> byte offset (Type 1 entries)
 	Streams   map[int]ObjectStreamEntry // Object number -> object stream info (Type 2 entries)
+	Free      map[int]bool              // Objects freed by this section ('f' and Type 0 entries, except object 0)
+	Gens      map[int]int               // Generation numbers of Objects, where not 0
 	Prev      int64                     // Offset of previous xref section (from /Prev in trailer)
+	XRefStm   int64                     // Offset of the cross-reference stream of a hybrid-reference file (from /XRefStm)
+	IsStream  bool                      // Cross-reference stream rather than a table
 	Root      string                    // /Root reference from trailer
 	Info      string                    // /Info reference from trailer
 	Encrypt   string                    // /Encrypt reference from trailer
 	Size      int                       // /Size from trailer
+	ID        string                    // /ID array from trailer
 }
 
 // incrementalParser handles PDFs with multiple revisions (incremental updates)
 type incrementalParser struct {
 	pdfBytes      []byte
+	src           io.ReaderAt               // Source of a file opened with OpenReader, read a section at a time
+	size          int64                     // Size of src
 	sections      []*xrefSection            // Ordered from oldest to newest
 	mergedObjs    map[int]int64             // Final merged object map
 	mergedStreams map[int]ObjectStreamEntry // Final merged object stream entries
+	mergedFree    map[int]bool              // Objects whose newest entry is free
+	mergedGens    map[int]int               // Generation numbers of mergedObjs, where not 0
+	problems      []string                  // Broken links of the /Prev chain, reported as warnings
 	verbose       bool
 }
 
@@ -37,6 +52,8 @@ func newIncrementalParser(pdfBytes []byte, verbose bool) *incrementalParser {
 		sections:      make([]*xrefSection, 0),
 		mergedObjs:    make(map[int]int64),
 		mergedStreams: make(map[int]ObjectStreamEntry),
+		mergedFree:    make(map[int]bool),
+		mergedGens:    make(map[int]int),
 		verbose:       verbose,
 	}
 }
@@ -78,6 +95,8 @@ func (p *incrementalParser) getFullXRefResult() *XRefResult {
 	return &XRefResult{
 		Objects:       p.mergedObjs,
 		ObjectStreams: p.mergedStreams,
+		Free:          p.mergedFree,
+		Generations:   p.mergedGens,
 	}
 }
 
@@ -86,10 +105,37 @@ func (p *incrementalParser) getSections() []*xrefSection {
 	return p.sections
 }
 
+// fileSize returns the size of the file being parsed
+func (p *incrementalParser) fileSize() int64 {
+	if p.src != nil {
+		return p.size
+	}
+	return int64(len(p.pdfBytes))
+}
+
+// window returns file data that holds offset, with the position of offset in
+// it: the whole file when it is in memory, otherwise what readWindow reads from
+// offset on to marker
+func (p *incrementalParser) window(offset int64, marker []byte) ([]byte, int64, error) {
+	if p.src == nil {
+		return p.pdfBytes, offset, nil
+	}
+	data, err := readWindow(p.src, p.size, offset, marker)
+	return data, 0, err
+}
+
 // findLastStartXRef finds the startxref value before the last %%EOF
 func (p *incrementalParser) findLastStartXRef() (int64, error) {
 	// Search backwards from end for %%EOF
 	pdfStr := string(p.pdfBytes)
+	if p.src != nil {
+		tailStart := max(0, p.size-tailWindow)
+		tail, err := readWindow(p.src, p.size, tailStart, nil)
+		if err != nil {
+			return 0, err
+		}
+		pdfStr = string(tail)
+	}
 
 	// Find all %%EOF markers
 	eofPattern := regexp.MustCompile(`%%EOF`)
@@ -128,134 +174,105 @@ func (p *incrementalParser) findLastStartXRef() (int64, error) {
 	return offset, nil
 }
 
-// parseXRefChain parses xref sections following the /Prev chain
+// parseXRefChain parses xref sections following the /Prev chain from the
+// newest section back to the first one. A /Prev that points outside the file,
+// back into the chain, or at a section that doesn't parse ends the chain there
+// and is recorded as a problem. The cross-reference stream of a hybrid-reference
+// file (/XRefStm) is part of its table's section.
 func (p *incrementalParser) parseXRefChain(startXRef int64) error {
 	visited := make(map[int64]bool) // Prevent infinite loops
-	offsets := []int64{startXRef}
-
-	// First, collect all offsets by following /Prev chain
-	currentOffset := startXRef
-	for currentOffset > 0 && !visited[currentOffset] {
-		visited[currentOffset] = true
+	var newestFirst []*xrefSection
 
-		prev, err := p.findPrevOffset(currentOffset)
-		if err != nil {
-			// No /Prev found, this is the first revision
+	for offset := startXRef; offset > 0; {
+		if visited[offset] {
+			p.problems = append(p.problems, fmt.Sprintf("/Prev %d loops back into the cross-reference chain", offset))
 			break
 		}
-
-		if prev > 0 && prev < int64(len(p.pdfBytes)) {
-			offsets = append(offsets, prev)
-			currentOffset = prev
-		} else {
+		visited[offset] = true
+		if offset >= p.fileSize() {
+			p.problems = append(p.problems, fmt.Sprintf("cross-reference offset %d is beyond the end of the file", offset))
 			break
 		}
-	}
-
-	// Reverse to get oldest first
-	for i, j := 0, len(offsets)-1; i < j; i, j = i+1, j-1 {
-		offsets[i], offsets[j] = offsets[j], offsets[i]
-	}
 
-	if p.verbose {
-		fmt.Printf("Found %d xref sections at offsets: %v\n", len(offsets), offsets)
-	}
-
-	// Parse each section
-	for _, offset := range offsets {
 		section, err := p.parsexrefSection(offset)
 		if err != nil {
+			p.problems = append(p.problems, fmt.Sprintf("cross-reference section at %d: %v", offset, err))
 			if p.verbose {
 				fmt.Printf("Warning: failed to parse xref at offset %d: %v\n", offset, err)
 			}
-			continue
+			break
 		}
-		p.sections = append(p.sections, section)
+		if section.XRefStm > 0 {
+			p.addHybridStream(section)
+		}
+		newestFirst = append(newestFirst, section)
+		offset = section.Prev
 	}
 
-	if len(p.sections) == 0 {
+	if len(newestFirst) == 0 {
 		return fmt.Errorf("no valid xref sections found")
 	}
 
-	return nil
-}
-
-// findPrevOffset finds the /Prev value in the trailer at the given xref offset
-func (p *incrementalParser) findPrevOffset(startXRef int64) (int64, error) {
-	if startXRef >= int64(len(p.pdfBytes)) {
-		return 0, fmt.Errorf("startXRef out of bounds")
+	// Reverse to get oldest first
+	for i := len(newestFirst) - 1; i >= 0; i-- {
+		p.sections = append(p.sections, newestFirst[i])
 	}
 
-	section := p.pdfBytes[startXRef:]
-
-	// Check if this is an xref stream or traditional xref
-	if bytes.HasPrefix(section, []byte("xref")) {
-		// Traditional xref - find trailer and /Prev
-		trailerIdx := bytes.Index(section, []byte("trailer"))
-		if trailerIdx == -1 {
-			return 0, fmt.Errorf("no trailer found")
-		}
-
-		// Find the end of the trailer dictionary (>>)
-		trailerStart := trailerIdx + 7 // len("trailer")
-		dictStart := bytes.Index(section[trailerStart:], []byte("<<"))
-		if dictStart == -1 {
-			return 0, fmt.Errorf("trailer dictionary not found")
-		}
-		dictStart += trailerStart
-
-		// Find matching >> for trailer dict
-		depth := 1
-		dictEnd := dictStart + 2
-		for dictEnd < len(section) && depth > 0 {
-			if dictEnd+1 < len(section) && section[dictEnd] == '<' && section[dictEnd+1] == '<' {
-				depth++
-				dictEnd++
-			} else if dictEnd+1 < len(section) && section[dictEnd] == '>' && section[dictEnd+1] == '>' {
-				depth--
-				if depth == 0 {
-					dictEnd += 2
-					break
-				}
-				dictEnd++
-			}
-			dictEnd++
+	if p.verbose {
+		offsets := make([]int64, len(p.sections))
+		for i, section := range p.sections {
+			offsets[i] = section.StartXRef
 		}
+		fmt.Printf("Found %d xref sections at offsets: %v\n", len(offsets), offsets)
+	}
 
-		trailerDict := string(section[dictStart:dictEnd])
-		prevPattern := regexp.MustCompile(`/Prev\s+(\d+)`)
-		match := prevPattern.FindStringSubmatch(trailerDict)
-		if match == nil {
-			return 0, fmt.Errorf("no /Prev in trailer")
-		}
+	return nil
+}
 
-		return strconv.ParseInt(match[1], 10, 64)
+// addHybridStream adds the entries of a hybrid-reference file's cross-reference
+// stream to its table's section. In-use entries of the table take precedence:
+// readers that don't know xref streams use the table alone. The table lists
+// compressed objects as free for those readers, so the stream's entries replace
+// its free ones.
+func (p *incrementalParser) addHybridStream(section *xrefSection) {
+	data, pos, err := p.window(section.XRefStm, []byte("endobj"))
+	if err != nil {
+		p.problems = append(p.problems, fmt.Sprintf("/XRefStm %d: %v", section.XRefStm, err))
+		return
 	}
-
-	// Cross-reference stream - /Prev is in the stream dictionary
-	// Find the dictionary end (before "stream" keyword)
-	streamIdx := bytes.Index(section, []byte("stream"))
-	if streamIdx == -1 {
-		streamIdx = min(2000, len(section))
+	result, err := ParseXRefStreamFull(data, pos, p.verbose)
+	if err != nil {
+		p.problems = append(p.problems, fmt.Sprintf("/XRefStm %d: %v", section.XRefStm, err))
+		return
+	}
+	for objNum, offset := range result.Objects {
+		if _, ok := section.Objects[objNum]; !ok {
+			section.Objects[objNum] = offset
+			if gen, ok := result.Generations[objNum]; ok {
+				section.Gens[objNum] = gen
+			}
+			delete(section.Free, objNum)
+		}
 	}
-
-	dictSection := string(section[:streamIdx])
-	prevPattern := regexp.MustCompile(`/Prev\s+(\d+)`)
-	match := prevPattern.FindStringSubmatch(dictSection)
-	if match == nil {
-		return 0, fmt.Errorf("no /Prev in xref stream")
+	for objNum, entry := range result.ObjectStreams {
+		if _, ok := section.Objects[objNum]; !ok {
+			section.Streams[objNum] = entry
+			delete(section.Free, objNum)
+		}
 	}
-
-	return strconv.ParseInt(match[1], 10, 64)
 }
 
 // parsexrefSection parses a single xref section at the given offset
 func (p *incrementalParser) parsexrefSection(startXRef int64) (*xrefSection, error) {
-	if startXRef >= int64(len(p.pdfBytes)) {
-		return nil, fmt.Errorf("startXRef out of bounds: %d >= %d", startXRef, len(p.pdfBytes))
+	if startXRef >= p.fileSize() {
+		return nil, fmt.Errorf("startXRef out of bounds: %d >= %d", startXRef, p.fileSize())
 	}
 
-	xrefData := p.pdfBytes[startXRef:]
+	data, pos, err := p.window(startXRef, nil)
+	if err != nil {
+		return nil, err
+	}
+	xrefData := data[pos:]
 	xrefStr := string(xrefData[:min(5000, len(xrefData))])
 
 	// Determine type and parse
@@ -280,19 +297,33 @@ func (p *incrementalParser) parseTraditionalxrefSection(startXRef int64) (*xrefS
 		StartXRef: startXRef,
 		Objects:   make(map[int]int64),
 		Streams:   make(map[int]ObjectStreamEntry),
+		Free:      make(map[int]bool),
+		Gens:      make(map[int]int),
 	}
 
-	xrefData := p.pdfBytes[startXRef:]
-	xrefStr := string(xrefData[:min(10000, len(xrefData))])
+	data, pos, err := p.window(startXRef, []byte("startxref"))
+	if err != nil {
+		return nil, err
+	}
+	xrefData := data[pos:]
+
+	// Entries run up to the trailer keyword; large tables exceed any fixed window
+	xrefEntries := xrefData
+	if trailerIdx := bytes.Index(xrefData, []byte("trailer")); trailerIdx != -1 {
+		xrefEntries = xrefData[:trailerIdx]
+	}
+	xrefStr := string(xrefEntries)
 
 	// Parse xref entries
 	lines := regexp.MustCompile(`\r?\n`).Split(xrefStr, -1)
+	trimPattern := regexp.MustCompile(`^\s+|\s+$`)
+	fieldPattern := regexp.MustCompile(`\s+`)
 
 	currentObjNum := 0
 	inSubsection := false
 
 	for i, line := range lines {
-		line = regexp.MustCompile(`^\s+|\s+$`).ReplaceAllString(line, "")
+		line = trimPattern.ReplaceAllString(line, "")
 		if line == "" {
 			continue
 		}
@@ -307,7 +338,7 @@ func (p *incrementalParser) parseTraditionalxrefSection(startXRef int64) (*xrefS
 			break
 		}
 
-		fields := regexp.MustCompile(`\s+`).Split(line, -1)
+		fields := fieldPattern.Split(line, -1)
 
 		if len(fields) == 2 {
 			// Subsection header: "first_obj_num count"
@@ -323,11 +354,18 @@ func (p *incrementalParser) parseTraditionalxrefSection(startXRef int64) (*xrefS
 		if inSubsection && len(fields) >= 3 {
 			// Entry: "offset generation flag"
 			offset, err1 := strconv.ParseInt(fields[0], 10, 64)
-			_, err2 := strconv.Atoi(fields[1])
+			gen, err2 := strconv.Atoi(fields[1])
 			flag := fields[2]
 
-			if err1 == nil && err2 == nil && flag == "n" {
-				section.Objects[currentObjNum] = offset
+			if err1 == nil && err2 == nil {
+				if flag == "n" {
+					section.Objects[currentObjNum] = offset
+					if gen != 0 {
+						section.Gens[currentObjNum] = gen
+					}
+				} else if flag == "f" && currentObjNum != 0 {
+					section.Free[currentObjNum] = true
+				}
 			}
 			currentObjNum++
 		}
@@ -377,6 +415,12 @@ func (p *incrementalParser) parseTraditionalxrefSection(startXRef int64) (*xrefS
 			if match := regexp.MustCompile(`/Size\s+(\d+)`).FindStringSubmatch(trailerDict); match != nil {
 				section.Size, _ = strconv.Atoi(match[1])
 			}
+			if match := regexp.MustCompile(`/XRefStm\s+(\d+)`).FindStringSubmatch(trailerDict); match != nil {
+				section.XRefStm, _ = strconv.ParseInt(match[1], 10, 64)
+			}
+			if match := trailerIDPattern.FindString(trailerDict); match != "" {
+				section.ID = match[strings.Index(match, "["):]
+			}
 		}
 	}
 
@@ -392,21 +436,30 @@ func (p *incrementalParser) parseTraditionalxrefSection(startXRef int64) (*xrefS
 func (p *incrementalParser) parseXRefStreamSection(startXRef int64) (*xrefSection, error) {
 	section := &xrefSection{
 		StartXRef: startXRef,
+		IsStream:  true,
 		Objects:   make(map[int]int64),
 		Streams:   make(map[int]ObjectStreamEntry),
+		Free:      make(map[int]bool),
+		Gens:      make(map[int]int),
 	}
 
 	// Use existing full xref stream parser
-	result, err := ParseXRefStreamFull(p.pdfBytes, startXRef, p.verbose)
+	data, pos, err := p.window(startXRef, []byte("endobj"))
+	if err != nil {
+		return nil, err
+	}
+	result, err := ParseXRefStreamFull(data, pos, p.verbose)
 	if err != nil {
 		return nil, err
 	}
 
 	section.Objects = result.Objects
 	section.Streams = result.ObjectStreams
+	section.Free = result.Free
+	section.Gens = result.Generations
 
 	// Extract trailer info from stream dictionary
-	xrefData := p.pdfBytes[startXRef:]
+	xrefData := data[pos:]
 	xrefStr := string(xrefData[:min(2000, len(xrefData))])
 
 	if match := regexp.MustCompile(`/Root\s+(\d+\s+\d+\s+R)`).FindStringSubmatch(xrefStr); match != nil {
@@ -424,6 +477,9 @@ func (p *incrementalParser) parseXRefStreamSection(startXRef int64) (*xrefSectio
 	if match := regexp.MustCompile(`/Size\s+(\d+)`).FindStringSubmatch(xrefStr); match != nil {
 		section.Size, _ = strconv.Atoi(match[1])
 	}
+	if match := trailerIDPattern.FindString(xrefStr); match != "" {
+		section.ID = match[strings.Index(match, "["):]
+	}
 
 	if p.verbose {
 		fmt.Printf("Parsed xref stream at %d: %d objects, %d in streams, Prev=%d\n",
@@ -433,24 +489,122 @@ func (p *incrementalParser) parseXRefStreamSection(startXRef int64) (*xrefSectio
 	return section, nil
 }
 
-// mergeSections merges all xref sections (older entries first, newer override)
+// mergeSections merges all xref sections, oldest first, so that the newest
+// entry of each object wins whether it is stored directly, in an object stream
+// or freed. A freed object no longer resolves to the data of older revisions.
 func (p *incrementalParser) mergeSections() {
-	// sections are already ordered oldest to newest
 	for _, section := range p.sections {
+		// Free entries
+		for objNum := range section.Free {
+			p.mergedFree[objNum] = true
+			delete(p.mergedObjs, objNum)
+			delete(p.mergedGens, objNum)
+			delete(p.mergedStreams, objNum)
+		}
 		// Regular objects
 		for objNum, offset := range section.Objects {
 			p.mergedObjs[objNum] = offset
+			if gen, ok := section.Gens[objNum]; ok {
+				p.mergedGens[objNum] = gen
+			} else {
+				delete(p.mergedGens, objNum)
+			}
+			delete(p.mergedStreams, objNum)
+			delete(p.mergedFree, objNum)
 		}
 		// Object stream entries
 		for objNum, entry := range section.Streams {
 			p.mergedStreams[objNum] = entry
+			delete(p.mergedObjs, objNum)
+			delete(p.mergedGens, objNum)
+			delete(p.mergedFree, objNum)
 		}
 	}
 
 	if p.verbose {
-		fmt.Printf("Merged %d sections: %d objects, %d in streams\n",
-			len(p.sections), len(p.mergedObjs), len(p.mergedStreams))
+		fmt.Printf("Merged %d sections: %d objects, %d in streams, %d free\n",
+			len(p.sections), len(p.mergedObjs), len(p.mergedStreams), len(p.mergedFree))
+	}
+}
+
+// trailer returns the trailer entries of the chain: each entry from the newest
+// section that has it, since some writers leave /Info or /Encrypt out of the
+// trailers of incremental updates
+func (p *incrementalParser) trailer() *xrefSection {
+	merged := &xrefSection{}
+	for _, section := range p.sections {
+		if section.Root != "" {
+			merged.Root = section.Root
+		}
+		if section.Info != "" {
+			merged.Info = section.Info
+		}
+		if section.Encrypt != "" {
+			merged.Encrypt = section.Encrypt
+		}
+		if section.ID != "" {
+			merged.ID = section.ID
+		}
+		if section.Size > merged.Size {
+			merged.Size = section.Size
+		}
+	}
+	if n := len(p.sections); n > 0 {
+		merged.StartXRef = p.sections[n-1].StartXRef
+	}
+	return merged
+}
+
+// XRefSectionInfo describes a cross-reference section of a document's /Prev
+// chain, for diagnostics
+type XRefSectionInfo struct {
+	Offset     int64  // Byte offset of the section
+	Stream     bool   // Cross-reference stream rather than a table
+	Prev       int64  // /Prev offset of the previous section, 0 for the first
+	XRefStm    int64  // /XRefStm offset of a hybrid-reference file's stream, 0 if none
+	Objects    []int  // Objects stored directly in the file, ascending
+	Compressed []int  // Objects stored in object streams, ascending
+	Free       []int  // Objects freed by the section, ascending
+	Root       string // /Root reference of the trailer
+	Info       string // /Info reference of the trailer
+	Encrypt    string // /Encrypt reference of the trailer
+	Size       int    // /Size of the trailer
+	ID         string // /ID array of the trailer, as written
+}
+
+// chain returns the diagnostics of the parsed sections, oldest first
+func (p *incrementalParser) chain() []XRefSectionInfo {
+	chain := make([]XRefSectionInfo, len(p.sections))
+	for i, section := range p.sections {
+		info := XRefSectionInfo{
+			Offset:     section.StartXRef,
+			Stream:     section.IsStream,
+			Prev:       section.Prev,
+			XRefStm:    section.XRefStm,
+			Objects:    make([]int, 0, len(section.Objects)),
+			Compressed: make([]int, 0, len(section.Streams)),
+			Free:       make([]int, 0, len(section.Free)),
+			Root:       section.Root,
+			Info:       section.Info,
+			Encrypt:    section.Encrypt,
+			Size:       section.Size,
+			ID:         section.ID,
+		}
+		for objNum := range section.Objects {
+			info.Objects = append(info.Objects, objNum)
+		}
+		for objNum := range section.Streams {
+			info.Compressed = append(info.Compressed, objNum)
+		}
+		for objNum := range section.Free {
+			info.Free = append(info.Free, objNum)
+		}
+		sort.Ints(info.Objects)
+		sort.Ints(info.Compressed)
+		sort.Ints(info.Free)
+		chain[i] = info
 	}
+	return chain
 }
 
 // FindAllEOFMarkers returns the byte offsets of all %%EOF markers in the PDF
@@ -3,7 +3,10 @@ package parse
 import (
 	"bytes"
 	"fmt"
+	"strings"
 	"testing"
+
+	"github.com/benedoc-inc/pdfer/types"
 )
 
 // createSimplePDF creates a minimal valid PDF for testing
@@ -304,3 +307,205 @@ func TestGetRevisionBoundaries(t *testing.T) {
 
 	t.Logf("Revision boundaries: %v", boundaries)
 }
+
+func TestMergeSections_NewestWins(t *testing.T) {
+	parser := newIncrementalParser(nil, false)
+	parser.sections = []*xrefSection{
+		{Objects: map[int]int64{1: 10}, Streams: map[int]ObjectStreamEntry{2: {StreamObjNum: 9, IndexInStream: 0}}},
+		{Objects: map[int]int64{2: 200}, Streams: map[int]ObjectStreamEntry{1: {StreamObjNum: 9, IndexInStream: 1}}},
+	}
+	parser.mergeSections()
+
+	if _, ok := parser.mergedObjs[1]; ok {
+		t.Error("Object 1 was moved into an object stream and should not keep its old offset")
+	}
+	if entry, ok := parser.mergedStreams[1]; !ok || entry.IndexInStream != 1 {
+		t.Errorf("Object 1 stream entry = %+v, %v", entry, ok)
+	}
+	if _, ok := parser.mergedStreams[2]; ok {
+		t.Error("Object 2 was rewritten directly and should not keep its stream entry")
+	}
+	if parser.mergedObjs[2] != 200 {
+		t.Errorf("Object 2 offset = %d, want 200", parser.mergedObjs[2])
+	}
+}
+
+// appendRevision appends an incremental update with a new version of object
+// 3 and the given trailer entries
+func appendRevision(base []byte, trailer string) []byte {
+	var buf bytes.Buffer
+	buf.Write(base)
+	objOffset := buf.Len()
+	buf.WriteString("3 0 obj\n<</Type/Page/Parent 2 0 R/MediaBox[0 0 595 842]>>\nendobj\n")
+	xrefOffset := buf.Len()
+	buf.WriteString("xref\n3 1\n")
+	buf.WriteString(formatXRefEntry(objOffset))
+	buf.WriteString("trailer\n" + trailer + "\n")
+	buf.WriteString(fmt.Sprintf("startxref\n%d\n%%%%EOF\n", xrefOffset))
+	return buf.Bytes()
+}
+
+func TestPrevChain_TrailerAndObjects(t *testing.T) {
+	base := bytes.Replace(createSimplePDF(), []byte("<</Size 4/Root 1 0 R>>"), []byte("<</Size 4/Root 1 0 R/Info 1 0 R>>"), 1)
+	prev := bytes.LastIndex(base, []byte("startxref\n"))
+	var firstXRef int
+	fmt.Sscanf(string(base[prev+len("startxref\n"):]), "%d", &firstXRef)
+
+	// The update's trailer leaves /Info out
+	data := appendRevision(base, fmt.Sprintf("<</Size 4/Root 1 0 R/Prev %d>>", firstXRef))
+
+	pdf, err := Open(data)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if pdf.Trailer().InfoRef != "1 0 R" {
+		t.Errorf("InfoRef = %q, want the first revision's 1 0 R", pdf.Trailer().InfoRef)
+	}
+
+	obj, err := GetObject(data, 3, nil, false)
+	if err != nil {
+		t.Fatalf("GetObject failed: %v", err)
+	}
+	if !bytes.Contains(obj, []byte("595 842")) {
+		t.Errorf("GetObject returned the old revision of object 3: %s", obj)
+	}
+
+	chain := pdf.XRefChain()
+	if len(chain) != 2 {
+		t.Fatalf("XRefChain has %d sections, want 2", len(chain))
+	}
+	if chain[1].Prev != chain[0].Offset || chain[0].Offset != int64(firstXRef) {
+		t.Errorf("Chain offsets = %+v", chain)
+	}
+	if len(chain[1].Objects) != 1 || chain[1].Objects[0] != 3 {
+		t.Errorf("Second section objects = %v, want [3]", chain[1].Objects)
+	}
+	if chain[1].Info != "" || chain[0].Info != "1 0 R" {
+		t.Errorf("Section trailers = %q, %q", chain[0].Info, chain[1].Info)
+	}
+}
+
+func TestPrevChain_Broken(t *testing.T) {
+	base := createSimplePDF()
+	tests := []struct {
+		name    string
+		trailer func(data []byte) string
+	}{
+		{"beyond EOF", func(data []byte) string {
+			return fmt.Sprintf("<</Size 4/Root 1 0 R/Prev %d>>", len(data)+1000)
+		}},
+		{"loop", func(data []byte) string {
+			// The update's own xref starts after the new object 3
+			objLen := len("3 0 obj\n<</Type/Page/Parent 2 0 R/MediaBox[0 0 595 842]>>\nendobj\n")
+			return fmt.Sprintf("<</Size 4/Root 1 0 R/Prev %d>>", len(data)+objLen)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := appendRevision(base, tt.trailer(base))
+			warnings := types.NewWarningCollector(true)
+			pdf, err := OpenWithOptions(data, ParseOptions{Warnings: warnings})
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			if pdf.Trailer().RootRef != "1 0 R" {
+				t.Errorf("RootRef = %q", pdf.Trailer().RootRef)
+			}
+			found := false
+			for _, w := range warnings.Warnings() {
+				if strings.Contains(w.Message, "cross-reference chain") {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("No warning about the broken chain: %v", warnings.Warnings())
+			}
+		})
+	}
+}
+
+// appendFreeRevision appends an incremental update that frees object 5 of
+// createIncrementalPDF
+func appendFreeRevision(base []byte) []byte {
+	prev := bytes.LastIndex(base, []byte("startxref\n"))
+	var prevXRef int
+	fmt.Sscanf(string(base[prev+len("startxref\n"):]), "%d", &prevXRef)
+
+	var buf bytes.Buffer
+	buf.Write(base)
+	xrefOffset := buf.Len()
+	buf.WriteString("xref\n5 1\n0000000000 00001 f \n")
+	buf.WriteString(fmt.Sprintf("trailer\n<</Size 6/Root 1 0 R/Prev %d>>\n", prevXRef))
+	buf.WriteString(fmt.Sprintf("startxref\n%d\n%%%%EOF\n", xrefOffset))
+	return buf.Bytes()
+}
+
+func TestFreedObject(t *testing.T) {
+	data := appendFreeRevision(createIncrementalPDF())
+
+	for _, opts := range []ParseOptions{{}, {BytePerfect: true}} {
+		pdf, err := OpenWithOptions(data, opts)
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		if pdf.HasObject(5) || !pdf.IsFree(5) {
+			t.Errorf("BytePerfect=%v: object 5 should be free, HasObject=%v IsFree=%v", opts.BytePerfect, pdf.HasObject(5), pdf.IsFree(5))
+		}
+		obj, err := pdf.GetObject(5)
+		if err != nil {
+			t.Fatalf("BytePerfect=%v: GetObject of a free object failed: %v", opts.BytePerfect, err)
+		}
+		if !IsNullObject(obj) {
+			t.Errorf("BytePerfect=%v: GetObject(5) = %q, want the null object", opts.BytePerfect, obj)
+		}
+		if pdf.IsFree(4) {
+			t.Errorf("BytePerfect=%v: object 4 should not be free", opts.BytePerfect)
+		}
+	}
+
+	obj, err := GetObject(data, 5, nil, false)
+	if err != nil || !IsNullObject(obj) {
+		t.Errorf("GetObject(5) = %q, %v, want the null object", obj, err)
+	}
+	if _, err := FindObjectByNumber(data, 5, nil, false); err == nil {
+		t.Error("FindObjectByNumber should not find a free object")
+	}
+
+	pdf, _ := Open(data)
+	chain := pdf.XRefChain()
+	if len(chain) != 3 || len(chain[2].Free) != 1 || chain[2].Free[0] != 5 {
+		t.Errorf("Newest section should free object 5: %+v", chain)
+	}
+}
+
+func TestFreedObject_Reused(t *testing.T) {
+	parser := newIncrementalParser(nil, false)
+	parser.sections = []*xrefSection{
+		{Objects: map[int]int64{5: 10}},
+		{Free: map[int]bool{5: true}},
+		{Objects: map[int]int64{5: 300}},
+	}
+	parser.mergeSections()
+	if parser.mergedFree[5] || parser.mergedObjs[5] != 300 {
+		t.Errorf("A reused object should resolve to its newest entry: free=%v offset=%d", parser.mergedFree[5], parser.mergedObjs[5])
+	}
+}
+
+func TestIsNullObject(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"5 0 obj\nnull\nendobj", true},
+		{"12 3 obj null endobj", true},
+		{"null", true},
+		{"5 0 obj\n<</Type/Annot>>\nendobj", false},
+		{"5 0 obj\n(null)\nendobj", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsNullObject([]byte(tt.in)); got != tt.want {
+			t.Errorf("IsNullObject(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
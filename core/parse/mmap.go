@@ -0,0 +1,99 @@
+package parse
+
+import (
+	"io/fs"
+	"os"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// MappedFile holds the contents of a file on disk.
+// When the platform supports it the contents are memory-mapped copy-on-write, so
+// large inputs are not duplicated into the heap; otherwise the file is read normally.
+type MappedFile struct {
+	data   []byte
+	mapped bool
+}
+
+// MapFile memory-maps the file at path, falling back to reading it into memory
+// on platforms without mmap support or when mapping fails.
+func MapFile(path string) (*MappedFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, types.WrapError(types.ErrCodeIOError, "failed to open file", err).WithContext("path", path)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, types.WrapError(types.ErrCodeIOError, "failed to stat file", err).WithContext("path", path)
+	}
+
+	if info.Size() > 0 {
+		if data, err := mmapFile(f, info.Size()); err == nil {
+			return &MappedFile{data: data, mapped: true}, nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, types.WrapError(types.ErrCodeIOError, "failed to read file", err).WithContext("path", path)
+	}
+	return &MappedFile{data: data}, nil
+}
+
+// Bytes returns the file contents. The slice is invalid after Close.
+func (m *MappedFile) Bytes() []byte {
+	return m.data
+}
+
+// Mapped reports whether the contents are memory-mapped
+func (m *MappedFile) Mapped() bool {
+	return m.mapped
+}
+
+// Close releases the mapping. It is safe to call more than once.
+func (m *MappedFile) Close() error {
+	data := m.data
+	m.data = nil
+	if !m.mapped || data == nil {
+		return nil
+	}
+	m.mapped = false
+	return munmapFile(data)
+}
+
+// OpenFile parses a PDF from disk. With opts.MemoryMap set, the file is memory-mapped
+// where supported; call Close on the returned PDF to release the mapping.
+func OpenFile(path string, opts ParseOptions) (*PDF, error) {
+	if !opts.MemoryMap {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, types.WrapError(types.ErrCodeIOError, "failed to read file", err).WithContext("path", path)
+		}
+		return OpenWithOptions(data, opts)
+	}
+
+	mf, err := MapFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pdf, err := OpenWithOptions(mf.Bytes(), opts)
+	if err != nil {
+		mf.Close()
+		return nil, err
+	}
+	pdf.mapped = mf
+	return pdf, nil
+}
+
+// OpenFS parses a PDF read from fsys, such as an embed.FS, a directory from
+// os.DirFS or an in-memory fstest.MapFS. opts.MemoryMap is ignored.
+func OpenFS(fsys fs.FS, name string, opts ParseOptions) (*PDF, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, types.WrapError(types.ErrCodeIOError, "failed to read file", err).WithContext("path", name)
+	}
+	return OpenWithOptions(data, opts)
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+package parse
+
+import (
+	"errors"
+	"os"
+)
+
+// mmapFile is not supported on this platform; MapFile falls back to reading the file
+func mmapFile(f *os.File, size int64) ([]byte, error) {
+	return nil, errors.New("mmap not supported on this platform")
+}
+
+// munmapFile is a no-op on platforms without mmap
+func munmapFile(data []byte) error {
+	return nil
+}
@@ -0,0 +1,74 @@
+package parse
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMapFile(t *testing.T) {
+	data := createTestPDFForAPI()
+	path := filepath.Join(t.TempDir(), "test.pdf")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	mf, err := MapFile(path)
+	if err != nil {
+		t.Fatalf("MapFile failed: %v", err)
+	}
+	if !bytes.Equal(mf.Bytes(), data) {
+		t.Error("Mapped bytes do not match file contents")
+	}
+	t.Logf("mapped: %v", mf.Mapped())
+
+	if err := mf.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if err := mf.Close(); err != nil {
+		t.Errorf("Second Close failed: %v", err)
+	}
+}
+
+func TestOpenFile(t *testing.T) {
+	data := createTestPDFForAPI()
+	path := filepath.Join(t.TempDir(), "test.pdf")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	for _, useMmap := range []bool{false, true} {
+		pdf, err := OpenFile(path, ParseOptions{MemoryMap: useMmap})
+		if err != nil {
+			t.Fatalf("OpenFile(MemoryMap=%v) failed: %v", useMmap, err)
+		}
+		if !pdf.HasObject(3) {
+			t.Errorf("OpenFile(MemoryMap=%v): expected object 3", useMmap)
+		}
+		if err := pdf.Close(); err != nil {
+			t.Errorf("Close failed: %v", err)
+		}
+	}
+
+	if _, err := OpenFile(filepath.Join(t.TempDir(), "missing.pdf"), ParseOptions{MemoryMap: true}); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+func TestOpenFS(t *testing.T) {
+	fsys := fstest.MapFS{"forms/test.pdf": {Data: createTestPDFForAPI()}}
+
+	pdf, err := OpenFS(fsys, "forms/test.pdf", ParseOptions{})
+	if err != nil {
+		t.Fatalf("OpenFS failed: %v", err)
+	}
+	if !pdf.HasObject(3) {
+		t.Error("Expected object 3")
+	}
+
+	if _, err := OpenFS(fsys, "missing.pdf", ParseOptions{}); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package parse
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmapFile maps a file privately; writes to the slice never reach the file
+func mmapFile(f *os.File, size int64) ([]byte, error) {
+	if int64(int(size)) != size {
+		return nil, syscall.EFBIG
+	}
+	return syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_PRIVATE)
+}
+
+// munmapFile releases a mapping created by mmapFile
+func munmapFile(data []byte) error {
+	return syscall.Munmap(data)
+}
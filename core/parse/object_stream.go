@@ -2,15 +2,13 @@ package parse
 
 import (
 	"bytes"
-	"compress/flate"
-	"compress/zlib"
+	"crypto/sha256"
 	"fmt"
-	"io"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 
-	"github.com/benedoc-inc/pdfer/core/encrypt"
 	"github.com/benedoc-inc/pdfer/types"
 )
 
@@ -26,6 +24,10 @@ type XRefResult struct {
 	Objects map[int]int64
 	// Objects in object streams (Type 2): objNum -> ObjectStreamEntry
 	ObjectStreams map[int]ObjectStreamEntry
+	// Free objects (Type 0), except object 0
+	Free map[int]bool
+	// Generation numbers of regular objects, where not 0
+	Generations map[int]int
 }
 
 // ParseXRefStreamFull parses a PDF cross-reference stream and returns both regular and compressed object info
@@ -33,6 +35,8 @@ func ParseXRefStreamFull(pdfBytes []byte, startXRef int64, verbose bool) (*XRefR
 	result := &XRefResult{
 		Objects:       make(map[int]int64),
 		ObjectStreams: make(map[int]ObjectStreamEntry),
+		Free:          make(map[int]bool),
+		Generations:   make(map[int]int),
 	}
 
 	// The xref stream object should be at startXRef
@@ -47,7 +51,7 @@ func ParseXRefStreamFull(pdfBytes []byte, startXRef int64, verbose bool) (*XRefR
 		if startXRef > 100 {
 			searchStart = startXRef - 100
 		}
-		searchSection := pdfBytes[searchStart : startXRef+500]
+		searchSection := pdfBytes[searchStart:min(startXRef+500, int64(len(pdfBytes)))]
 		searchStr := string(searchSection)
 		objMatch = objPattern.FindStringSubmatch(searchStr)
 		if objMatch == nil {
@@ -57,7 +61,7 @@ func ParseXRefStreamFull(pdfBytes []byte, startXRef int64, verbose bool) (*XRefR
 
 	// Find the stream dictionary
 	dictStart := bytes.Index(xrefSection, []byte("<<"))
-	if dictStart == -1 {
+	if dictStart == -1 || dictStart >= len(xrefStr) {
 		return nil, fmt.Errorf("xref stream dictionary not found")
 	}
 
@@ -119,20 +123,7 @@ func ParseXRefStreamFull(pdfBytes []byte, startXRef int64, verbose bool) (*XRefR
 	var decompressed []byte
 	var err error
 
-	zlibReader, zlibErr := zlib.NewReader(bytes.NewReader(streamContent))
-	if zlibErr == nil {
-		decompressed, err = io.ReadAll(zlibReader)
-		zlibReader.Close()
-		if err != nil {
-			flateReader := flate.NewReader(bytes.NewReader(streamContent))
-			decompressed, err = io.ReadAll(flateReader)
-			flateReader.Close()
-		}
-	} else {
-		flateReader := flate.NewReader(bytes.NewReader(streamContent))
-		decompressed, err = io.ReadAll(flateReader)
-		flateReader.Close()
-	}
+	decompressed, err = inflate(streamContent)
 
 	if err != nil {
 		return nil, fmt.Errorf("error decompressing xref stream: %v", err)
@@ -232,12 +223,18 @@ func ParseXRefStreamFull(pdfBytes []byte, startXRef int64, verbose bool) (*XRefR
 
 			switch typeVal {
 			case 0:
-				// Type 0: free object - skip
+				// Type 0: free object
+				if objNum != 0 {
+					result.Free[objNum] = true
+				}
 			case 1:
 				// Type 1: uncompressed, in-use object
 				// field2 = byte offset, field3 = generation
 				if field2 > 0 {
 					result.Objects[objNum] = field2
+					if field3 != 0 {
+						result.Generations[objNum] = int(field3)
+					}
 				}
 			case 2:
 				// Type 2: compressed object in object stream
@@ -260,184 +257,204 @@ func ParseXRefStreamFull(pdfBytes []byte, startXRef int64, verbose bool) (*XRefR
 	return result, nil
 }
 
-// GetObjectFromStream extracts an object from an object stream (ObjStm)
-// This implements PyPDF's _get_object_from_stream method
+// GetObjectFromStream extracts an object from an object stream (ObjStm). The
+// stream object is located through the cross-reference data, the newest
+// version when an incremental update rewrote it, decrypted and decoded. Like
+// PDF.GetObject, the result has no "N G obj" header, as objects in object
+// streams are stored without one.
 func GetObjectFromStream(pdfBytes []byte, objNum int, streamObjNum int, indexInStream int, encryptInfo *types.PDFEncryption, verbose bool) ([]byte, error) {
-	// Step 1: Get the object stream itself
-	// First, we need to find the object stream's byte offset
-	// This requires having the xref table for direct objects
-
-	// For now, search for the object stream in the PDF
-	streamPattern := fmt.Sprintf(`%d\s+0\s+obj`, streamObjNum)
-	re := regexp.MustCompile(streamPattern)
-	matches := re.FindIndex(pdfBytes)
-	if matches == nil {
-		return nil, fmt.Errorf("object stream %d not found", streamObjNum)
+	var streamOffset int64
+	incParser := newIncrementalParser(pdfBytes, verbose)
+	if err := incParser.parse(); err == nil {
+		streamOffset = incParser.mergedObjs[streamObjNum]
 	}
+	return objectFromStream(pdfBytes, objNum, streamObjNum, indexInStream, streamOffset, encryptInfo, verbose)
+}
 
-	streamObjStart := matches[0]
-
-	// Find the object stream's dictionary and stream data
-	objSection := pdfBytes[streamObjStart:]
-
-	// Find dictionary
-	dictStart := bytes.Index(objSection, []byte("<<"))
-	if dictStart == -1 {
-		return nil, fmt.Errorf("object stream dictionary not found")
+// objectFromStream extracts an object from the object stream at streamOffset.
+// Without an offset, or when the offset does not point at the stream's header,
+// the stream is recovered from a scan of the document. Decoded streams are
+// shared through objectStreams.
+func objectFromStream(pdfBytes []byte, objNum, streamObjNum, indexInStream int, streamOffset int64, encryptInfo *types.PDFEncryption, verbose bool) ([]byte, error) {
+	if streamOffset <= 0 || !hasObjectHeader(pdfBytes, streamOffset, streamObjNum) {
+		offset, ok := recoverObjectOffset(pdfBytes, streamObjNum, verbose)
+		if !ok {
+			return nil, fmt.Errorf("object stream %d not found", streamObjNum)
+		}
+		streamOffset = offset
 	}
 
-	// Find dictionary end to get dict content
-	dictEnd := bytes.Index(objSection[dictStart:], []byte(">>"))
-	if dictEnd == -1 {
-		return nil, fmt.Errorf("dictionary end not found")
+	key := objectStreams.key(pdfBytes, streamObjNum, streamOffset, encryptInfo)
+	stm, ok := objectStreams.get(key)
+	if !ok {
+		container, err := GetDirectObject(pdfBytes, streamObjNum, streamOffset, encryptInfo, verbose)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read object stream %d: %v", streamObjNum, err)
+		}
+		stm, err = parseObjectStream(container)
+		if err != nil {
+			return nil, fmt.Errorf("object stream %d: %v", streamObjNum, err)
+		}
+		objectStreams.put(key, stm)
 	}
-	dictContent := string(objSection[dictStart : dictStart+dictEnd+2])
-
-	// Get /Length from dictionary
-	lengthPattern := regexp.MustCompile(`/Length\s+(\d+)`)
-	lengthMatch := lengthPattern.FindStringSubmatch(dictContent)
-	if lengthMatch == nil {
-		return nil, fmt.Errorf("/Length not found in object stream dictionary")
+	data, err := stm.object(objNum, indexInStream)
+	if err != nil {
+		return nil, err
 	}
-	streamLength, _ := strconv.Atoi(lengthMatch[1])
-
-	// Find stream keyword
-	streamKeyword := bytes.Index(objSection[dictStart:], []byte("stream"))
-	if streamKeyword == -1 {
-		return nil, fmt.Errorf("stream keyword not found in object stream")
+	if verbose {
+		fmt.Printf("Extracted object %d from stream %d: %d bytes\n", objNum, streamObjNum, len(data))
 	}
-	streamKeyword += dictStart
+	return data, nil
+}
 
-	// Skip "stream" and exactly one EOL marker (per PDF spec)
-	streamDataStart := streamKeyword + 6
-	if streamDataStart < len(objSection) && objSection[streamDataStart] == '\r' {
-		streamDataStart++
-	}
-	if streamDataStart < len(objSection) && objSection[streamDataStart] == '\n' {
-		streamDataStart++
-	}
+// maxCachedObjectStreams bounds the decoded object streams kept by
+// objectStreams
+const maxCachedObjectStreams = 64
+
+// objectStreams caches decoded object streams for the functions that take the
+// document bytes, which, unlike PDF, have nowhere to keep them between calls.
+// Streams are keyed by a digest of their encoded object, so a changed document
+// never gets a stale stream.
+var objectStreams = &objectStreamCache{streams: make(map[[sha256.Size]byte]*objectStream)}
+
+// objectStreamCache is a bounded cache of decoded object streams, safe for
+// concurrent use
+type objectStreamCache struct {
+	mu      sync.Mutex
+	streams map[[sha256.Size]byte]*objectStream
+	order   [][sha256.Size]byte // Keys oldest first, for eviction
+}
 
-	// Use /Length to get exact stream data
-	if streamDataStart+streamLength > len(objSection) {
-		return nil, fmt.Errorf("stream length %d exceeds available data", streamLength)
+// key identifies the object stream at offset by its encoded object and the key
+// it is decrypted with
+func (c *objectStreamCache) key(pdfBytes []byte, streamObjNum int, offset int64, encryptInfo *types.PDFEncryption) [sha256.Size]byte {
+	obj := pdfBytes[offset:]
+	if end := bytes.Index(obj, []byte("endobj")); end != -1 {
+		obj = obj[:end]
 	}
-
-	streamData := objSection[streamDataStart : streamDataStart+streamLength]
-
-	// Decrypt stream data if needed (object streams ARE encrypted)
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\x00", streamObjNum)
 	if encryptInfo != nil {
-		decrypted, err := encrypt.DecryptObject(streamData, streamObjNum, 0, encryptInfo)
-		if err != nil {
-			return nil, fmt.Errorf("failed to decrypt object stream: %v", err)
-		}
-		streamData = decrypted
+		h.Write(encryptInfo.EncryptKey)
 	}
+	h.Write([]byte{0})
+	h.Write(obj)
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+	return key
+}
 
-	// Decompress stream data (FlateDecode)
-	var decompressed []byte
-	var err error
+func (c *objectStreamCache) get(key [sha256.Size]byte) (*objectStream, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stm, ok := c.streams[key]
+	return stm, ok
+}
 
-	zlibReader, zlibErr := zlib.NewReader(bytes.NewReader(streamData))
-	if zlibErr == nil {
-		decompressed, err = io.ReadAll(zlibReader)
-		zlibReader.Close()
+func (c *objectStreamCache) put(key [sha256.Size]byte, stm *objectStream) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.streams[key]; ok {
+		return
 	}
-
-	if err != nil || zlibErr != nil {
-		// Try raw deflate
-		flateReader := flate.NewReader(bytes.NewReader(streamData))
-		decompressed, err = io.ReadAll(flateReader)
-		flateReader.Close()
-		if err != nil {
-			return nil, fmt.Errorf("failed to decompress object stream: %v", err)
-		}
+	if len(c.order) == maxCachedObjectStreams {
+		delete(c.streams, c.order[0])
+		c.order = c.order[1:]
 	}
+	c.streams[key] = stm
+	c.order = append(c.order, key)
+}
 
-	// Parse object stream header
-	// Object stream format:
-	// - /N = number of objects
-	// - /First = byte offset to first object's data
-	// - Header format: "objnum1 offset1 objnum2 offset2 ..."
+// objectStream is a decoded object stream: the objects it holds and where
+// their data starts
+type objectStream struct {
+	data    []byte // Decoded stream data
+	first   int    // Offset of the first object's data (/First)
+	numbers []int  // Object numbers in stream order
+	offsets []int  // Offsets of the objects' data relative to first
+}
 
-	// Find /N and /First from dictionary
-	dictSection := string(objSection[dictStart:streamKeyword])
+// objectStreamFilterPattern matches the /Filter entry of a stream dictionary
+var objectStreamFilterPattern = regexp.MustCompile(`/Filter\s*(/[^\s/\[\]<>]+|\[[^\]]*\])`)
 
-	nPattern := regexp.MustCompile(`/N\s+(\d+)`)
-	nMatch := nPattern.FindStringSubmatch(dictSection)
-	if nMatch == nil {
-		return nil, fmt.Errorf("/N not found in object stream dictionary")
+// parseObjectStream decodes an object stream from its object as GetDirectObject
+// returns it, with the stream data decrypted
+func parseObjectStream(obj []byte) (*objectStream, error) {
+	keyword := regexp.MustCompile(`stream\r?\n`).FindIndex(obj)
+	if keyword == nil {
+		return nil, fmt.Errorf("not a stream")
 	}
-	_ = nMatch[1] // Number of objects (for validation if needed)
-
-	firstPattern := regexp.MustCompile(`/First\s+(\d+)`)
-	firstMatch := firstPattern.FindStringSubmatch(dictSection)
-	if firstMatch == nil {
-		return nil, fmt.Errorf("/First not found in object stream dictionary")
+	dict := string(obj[:keyword[0]])
+	if !regexp.MustCompile(`/Type\s*/ObjStm\b`).MatchString(dict) {
+		return nil, fmt.Errorf("not an object stream")
 	}
-	firstOffset, _ := strconv.Atoi(firstMatch[1])
 
-	// Parse header: pairs of "objnum offset"
-	type objEntry struct {
-		objNum int
-		offset int
+	data := obj[keyword[1]:]
+	if end := bytes.LastIndex(data, []byte("endstream")); end != -1 {
+		data = data[:end]
+	}
+	if m := regexp.MustCompile(`/Length\s+(\d+)(\s+\d+\s+R)?`).FindStringSubmatch(dict); m != nil && m[2] == "" {
+		if length, _ := strconv.Atoi(m[1]); length <= len(data) {
+			data = data[:length]
+		}
 	}
-	var entries []objEntry
 
-	headerData := decompressed[:firstOffset]
-	headerStr := string(headerData)
-	fields := strings.Fields(headerStr)
+	if m := objectStreamFilterPattern.FindStringSubmatch(dict); m != nil {
+		for _, filter := range strings.Fields(strings.NewReplacer("[", " ", "]", " ", "/", " /").Replace(m[1])) {
+			decoded, err := DecodeFilter(data, filter)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode object stream: %v", err)
+			}
+			data = decoded
+		}
+	}
 
-	for i := 0; i < len(fields)-1; i += 2 {
-		on, _ := strconv.Atoi(fields[i])
-		off, _ := strconv.Atoi(fields[i+1])
-		entries = append(entries, objEntry{objNum: on, offset: off})
+	firstMatch := regexp.MustCompile(`/First\s+(\d+)`).FindStringSubmatch(dict)
+	if firstMatch == nil {
+		return nil, fmt.Errorf("/First not found in object stream dictionary")
+	}
+	first, _ := strconv.Atoi(firstMatch[1])
+	if first > len(data) {
+		return nil, fmt.Errorf("/First %d is beyond the %d bytes of the stream", first, len(data))
 	}
 
-	if indexInStream >= len(entries) {
-		return nil, fmt.Errorf("index %d out of range (stream has %d objects)", indexInStream, len(entries))
+	stm := &objectStream{data: data, first: first}
+	fields := strings.Fields(string(data[:first]))
+	for i := 0; i+1 < len(fields); i += 2 {
+		objNum, err1 := strconv.Atoi(fields[i])
+		offset, err2 := strconv.Atoi(fields[i+1])
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("invalid object stream header")
+		}
+		stm.numbers = append(stm.numbers, objNum)
+		stm.offsets = append(stm.offsets, offset)
 	}
+	return stm, nil
+}
 
-	// Find the object at the specified index
-	targetEntry := entries[indexInStream]
-	if targetEntry.objNum != objNum {
-		// Try to find by object number instead
-		found := false
-		for _, e := range entries {
-			if e.objNum == objNum {
-				targetEntry = e
-				found = true
+// object returns the data of an object, which the cross-reference data places
+// at index; a stream that lists it elsewhere is searched by number
+func (s *objectStream) object(objNum, index int) ([]byte, error) {
+	if index < 0 || index >= len(s.numbers) || s.numbers[index] != objNum {
+		index = -1
+		for i, n := range s.numbers {
+			if n == objNum {
+				index = i
 				break
 			}
 		}
-		if !found {
-			return nil, fmt.Errorf("object %d not found in object stream (expected at index %d)", objNum, indexInStream)
+		if index == -1 {
+			return nil, fmt.Errorf("object %d not found in object stream", objNum)
 		}
 	}
 
-	// Calculate object data range
-	objDataStart := firstOffset + targetEntry.offset
-	var objDataEnd int
-
-	// Find next object's offset or end of stream
-	if indexInStream < len(entries)-1 {
-		nextEntry := entries[indexInStream+1]
-		objDataEnd = firstOffset + nextEntry.offset
-	} else {
-		objDataEnd = len(decompressed)
+	start := s.first + s.offsets[index]
+	end := len(s.data)
+	if index+1 < len(s.offsets) {
+		end = s.first + s.offsets[index+1]
 	}
-
-	if objDataStart >= len(decompressed) || objDataEnd > len(decompressed) {
-		return nil, fmt.Errorf("object data offset out of range")
+	if start > end || end > len(s.data) {
+		return nil, fmt.Errorf("object %d data offset out of range", objNum)
 	}
-
-	objectData := decompressed[objDataStart:objDataEnd]
-
-	// Trim trailing whitespace
-	objectData = bytes.TrimRight(objectData, " \t\r\n")
-
-	if verbose {
-		fmt.Printf("Extracted object %d from stream %d: %d bytes\n", objNum, streamObjNum, len(objectData))
-	}
-
-	return objectData, nil
+	return bytes.TrimRight(s.data[start:end], " \t\r\n"), nil
 }
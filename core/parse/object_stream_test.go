@@ -0,0 +1,108 @@
+package parse
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPDF_GetObject_ObjectStream(t *testing.T) {
+	pdf, err := Open(createObjStmPDF(16))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	ref, ok := pdf.Ref(1)
+	if !ok || !ref.InStream || ref.StreamObjNum != 5 {
+		t.Fatalf("Ref(1) = %+v, want object stream 5", ref)
+	}
+	catalog, err := pdf.GetObject(1)
+	if err != nil || string(catalog) != "<</Type/Catalog/Pages 2 0 R>>" {
+		t.Errorf("GetObject(1) = %q, %v", catalog, err)
+	}
+	pages, err := pdf.GetObject(2)
+	if err != nil || !bytes.Contains(pages, []byte("/Kids[3 0 R]")) {
+		t.Errorf("GetObject(2) = %q, %v", pages, err)
+	}
+	if len(pdf.objStms) != 1 {
+		t.Errorf("Expected the object stream decoded once, got %d cached", len(pdf.objStms))
+	}
+	if count, err := pdf.PageCount(); err != nil || count != 1 {
+		t.Errorf("PageCount() = %d, %v", count, err)
+	}
+}
+
+func TestGetObjectFromStream(t *testing.T) {
+	pdfBytes := createObjStmPDF(16)
+	obj, err := GetObjectFromStream(pdfBytes, 2, 5, 1, nil, false)
+	if err != nil || !bytes.Contains(obj, []byte("/Type/Pages")) {
+		t.Errorf("GetObjectFromStream() = %q, %v", obj, err)
+	}
+
+	// A stream that lists the object at another index is searched by number
+	obj, err = GetObjectFromStream(pdfBytes, 2, 5, 0, nil, false)
+	if err != nil || !bytes.Contains(obj, []byte("/Type/Pages")) {
+		t.Errorf("GetObjectFromStream() with a stale index = %q, %v", obj, err)
+	}
+
+	catalog, err := GetCatalog(pdfBytes, nil, false)
+	if err != nil || !bytes.Contains(catalog, []byte("/Type/Catalog")) {
+		t.Errorf("GetCatalog() = %q, %v", catalog, err)
+	}
+}
+
+func TestObjectFromStream_Location(t *testing.T) {
+	pdfBytes := createObjStmPDF(16)
+	header := int64(bytes.Index(pdfBytes, []byte("\n5 0 obj")) + 1)
+
+	// The stream is located through the cross-reference data
+	loc, err := FindObjectLocation(pdfBytes, 2, false)
+	if err != nil || loc.IsDirect || loc.StreamOffset != header {
+		t.Fatalf("FindObjectLocation(2) = %+v, %v; want the stream at offset %d", loc, err, header)
+	}
+
+	// and recovered from a scan when its offset is unknown or wrong
+	for _, offset := range []int64{0, header + 3} {
+		obj, err := objectFromStream(pdfBytes, 2, 5, 1, offset, nil, false)
+		if err != nil || !bytes.Contains(obj, []byte("/Type/Pages")) {
+			t.Errorf("objectFromStream() at offset %d = %q, %v", offset, obj, err)
+		}
+	}
+
+	// Decoded streams are cached by their encoded object, for any copy of the bytes
+	key := objectStreams.key(pdfBytes, 5, header, nil)
+	if _, ok := objectStreams.get(key); !ok {
+		t.Error("Expected the decoded object stream cached")
+	}
+	if copied := objectStreams.key(append([]byte{}, pdfBytes...), 5, header, nil); copied != key {
+		t.Error("Expected the same key for a copy of the document")
+	}
+	changed := append([]byte{}, pdfBytes...)
+	changed[header+int64(bytes.Index(pdfBytes[header:], []byte("endstream")))-2]++
+	if objectStreams.key(changed, 5, header, nil) == key {
+		t.Error("Expected another key once the stream data changes")
+	}
+}
+
+func TestParseObjectStream_Filters(t *testing.T) {
+	data := "7 0 8 5 true null"
+	encoded := EncodeASCIIHex(deflate(data))
+	obj := []byte("<</Type/ObjStm/N 2/First 8/Filter[/ASCIIHexDecode/FlateDecode]/Length 3 0 R>>stream\n" + string(encoded) + "\nendstream")
+
+	stm, err := parseObjectStream(obj)
+	if err != nil {
+		t.Fatalf("parseObjectStream() error = %v", err)
+	}
+	if got, err := stm.object(7, 0); err != nil || string(got) != "true" {
+		t.Errorf("object(7) = %q, %v", got, err)
+	}
+	if got, err := stm.object(8, 1); err != nil || string(got) != "null" {
+		t.Errorf("object(8) = %q, %v", got, err)
+	}
+	if _, err := stm.object(9, 2); err == nil {
+		t.Error("Expected an error for an object the stream does not hold")
+	}
+
+	if _, err := parseObjectStream([]byte("<</Type/XObject>>stream\nabc\nendstream")); err == nil {
+		t.Error("Expected an error for a stream that is not an object stream")
+	}
+}
@@ -0,0 +1,408 @@
+package parse
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// maxPageTreeDepth bounds page tree recursion so malformed or cyclic trees terminate
+const maxPageTreeDepth = 64
+
+var (
+	pageRefPattern      = regexp.MustCompile(`^(\d+)\s+(\d+)\s+R`)
+	pageRefArrayPattern = regexp.MustCompile(`(\d+)\s+\d+\s+R`)
+	pageCountPattern    = regexp.MustCompile(`/Count\s+(\d+)`)
+	pageTypePattern     = regexp.MustCompile(`/Type\s*/(Pages|Page)\b`)
+)
+
+// PageRef is a page located in the page tree with its inheritable attributes resolved
+type PageRef struct {
+	Number       int       // 1-based page number
+	ObjectNumber int       // Page object number
+	Dict         string    // Page object as returned by GetObject
+	Resources    string    // Resources dictionary from the page or nearest ancestor (empty if none)
+	MediaBox     []float64 // MediaBox from the page or nearest ancestor (nil if none)
+	CropBox      []float64 // CropBox from the page or nearest ancestor (nil if none)
+	Rotate       int       // Rotate from the page or nearest ancestor
+}
+
+// pageAttrs holds the inheritable page attributes in effect at a tree node
+type pageAttrs struct {
+	resources string // Raw value: "N G R" or an inline "<<...>>" dictionary
+	mediaBox  []float64
+	cropBox   []float64
+	rotate    int
+}
+
+// inherit returns the attributes in effect for a node, overriding with those set in its dictionary
+func (a pageAttrs) inherit(dict string) pageAttrs {
+	if v := dictRawValue(dict, "/Resources"); v != "" {
+		a.resources = v
+	}
+	if v := dictNumberArray(dict, "/MediaBox"); len(v) >= 4 {
+		a.mediaBox = v
+	}
+	if v := dictNumberArray(dict, "/CropBox"); len(v) >= 4 {
+		a.cropBox = v
+	}
+	if v := dictRawValue(dict, "/Rotate"); v != "" {
+		if rot, err := strconv.Atoi(v); err == nil {
+			a.rotate = rot
+		}
+	}
+	return a
+}
+
+// pageFrame is a pages node whose kids are being visited
+type pageFrame struct {
+	kids  []int
+	next  int
+	attrs pageAttrs
+}
+
+// PageIterator walks the page tree in document order, loading nodes only as they are reached.
+//
+//	it := pdf.Pages()
+//	for it.Next() {
+//	    page := it.Page()
+//	}
+//	if err := it.Err(); err != nil { ... }
+type PageIterator struct {
+	pdf       *PDF
+	stack     []*pageFrame
+	visited   map[int]bool
+	resources map[int]string // Indirect Resources already loaded, shared across pages
+	current   *PageRef
+	count     int
+	started   bool
+	err       error
+}
+
+// Pages returns an iterator over the document's pages
+func (p *PDF) Pages() *PageIterator {
+	return &PageIterator{
+		pdf:       p,
+		visited:   make(map[int]bool),
+		resources: make(map[int]string),
+	}
+}
+
+// Next advances to the next page, returning false when the tree is exhausted or an error occurs
+func (it *PageIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if !it.started {
+		it.started = true
+		rootObjNum, err := it.pdf.rootPagesObjectNumber()
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.stack = []*pageFrame{{kids: []int{rootObjNum}}}
+	}
+
+	for len(it.stack) > 0 {
+		frame := it.stack[len(it.stack)-1]
+		if frame.next >= len(frame.kids) {
+			it.stack = it.stack[:len(it.stack)-1]
+			continue
+		}
+		objNum := frame.kids[frame.next]
+		frame.next++
+
+		if it.visited[objNum] {
+			it.pdf.addWarningf(types.WarningLevelWarning, "page tree revisits object %d; skipping", objNum)
+			continue
+		}
+		it.visited[objNum] = true
+
+		obj, err := it.pdf.GetObject(objNum)
+		if err != nil {
+			it.pdf.addWarningf(types.WarningLevelWarning, "failed to load page tree node %d: %v", objNum, err)
+			continue
+		}
+		dict := string(obj)
+		attrs := frame.attrs.inherit(dict)
+
+		if isPagesNodeDict(dict) {
+			if len(it.stack) >= maxPageTreeDepth {
+				it.err = fmt.Errorf("pages tree too deep at object %d", objNum)
+				return false
+			}
+			it.stack = append(it.stack, &pageFrame{
+				kids:  dictRefArray(dict, "/Kids"),
+				attrs: attrs,
+			})
+			continue
+		}
+
+		it.count++
+		it.current = it.pdf.newPageRef(it.count, objNum, dict, attrs, it.resources)
+		return true
+	}
+
+	it.current = nil
+	return false
+}
+
+// Page returns the page at the current position
+func (it *PageIterator) Page() *PageRef {
+	return it.current
+}
+
+// Err returns the first error that stopped iteration
+func (it *PageIterator) Err() error {
+	return it.err
+}
+
+// PageCount returns the /Count of the root pages node
+func (p *PDF) PageCount() (int, error) {
+	rootObjNum, err := p.rootPagesObjectNumber()
+	if err != nil {
+		return 0, err
+	}
+
+	obj, err := p.GetObject(rootObjNum)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pages object %d: %w", rootObjNum, err)
+	}
+
+	match := pageCountPattern.FindStringSubmatch(string(obj))
+	if match == nil {
+		return 0, fmt.Errorf("invalid /Count in pages object %d", rootObjNum)
+	}
+	return strconv.Atoi(match[1])
+}
+
+// Page returns a single page (1-based). Subtrees are skipped using their /Count,
+// so only the nodes on the path to the page are loaded.
+func (p *PDF) Page(number int) (*PageRef, error) {
+	if number < 1 {
+		return nil, fmt.Errorf("page number %d out of range", number)
+	}
+
+	nodeObjNum, err := p.rootPagesObjectNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	index := number - 1
+	var attrs pageAttrs
+	for depth := 0; depth <= maxPageTreeDepth; depth++ {
+		obj, err := p.GetObject(nodeObjNum)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pages object %d: %w", nodeObjNum, err)
+		}
+		dict := string(obj)
+		attrs = attrs.inherit(dict)
+
+		if !isPagesNodeDict(dict) {
+			if index != 0 {
+				return nil, fmt.Errorf("page number %d out of range", number)
+			}
+			return p.newPageRef(number, nodeObjNum, dict, attrs, nil), nil
+		}
+
+		found := false
+		for _, kidObjNum := range dictRefArray(dict, "/Kids") {
+			kidObj, err := p.GetObject(kidObjNum)
+			if err != nil {
+				continue
+			}
+			kidDict := string(kidObj)
+
+			size := 1
+			if isPagesNodeDict(kidDict) {
+				size = 0
+				if match := pageCountPattern.FindStringSubmatch(kidDict); match != nil {
+					size, _ = strconv.Atoi(match[1])
+				}
+			}
+
+			if index < size {
+				nodeObjNum = kidObjNum
+				found = true
+				break
+			}
+			index -= size
+		}
+		if !found {
+			return nil, fmt.Errorf("page number %d out of range", number)
+		}
+	}
+
+	return nil, fmt.Errorf("pages tree too deep at object %d", nodeObjNum)
+}
+
+// newPageRef builds a PageRef, loading indirect Resources through the optional cache
+func (p *PDF) newPageRef(number, objNum int, dict string, attrs pageAttrs, cache map[int]string) *PageRef {
+	page := &PageRef{
+		Number:       number,
+		ObjectNumber: objNum,
+		Dict:         dict,
+		MediaBox:     attrs.mediaBox,
+		CropBox:      attrs.cropBox,
+		Rotate:       attrs.rotate,
+		Resources:    attrs.resources,
+	}
+
+	if resObjNum, ok := parseRef(attrs.resources); ok {
+		if cached, ok := cache[resObjNum]; ok {
+			page.Resources = cached
+		} else if obj, err := p.GetObject(resObjNum); err == nil {
+			page.Resources = string(obj)
+			if cache != nil {
+				cache[resObjNum] = page.Resources
+			}
+		} else {
+			p.addWarningf(types.WarningLevelWarning, "failed to load resources %d for page %d: %v", resObjNum, number, err)
+			page.Resources = ""
+		}
+	}
+
+	return page
+}
+
+// rootPagesObjectNumber returns the object number of the catalog's /Pages node
+func (p *PDF) rootPagesObjectNumber() (int, error) {
+	if p.trailer == nil || p.trailer.RootRef == "" {
+		return 0, fmt.Errorf("no root reference found in trailer")
+	}
+
+	rootObjNum, ok := parseRef(p.trailer.RootRef)
+	if !ok {
+		return 0, fmt.Errorf("failed to parse root reference %q", p.trailer.RootRef)
+	}
+
+	catalog, err := p.GetObject(rootObjNum)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get catalog object: %w", err)
+	}
+
+	pagesObjNum, ok := parseRef(dictRawValue(string(catalog), "/Pages"))
+	if !ok {
+		return 0, fmt.Errorf("no /Pages reference found in catalog")
+	}
+	return pagesObjNum, nil
+}
+
+// isPagesNodeDict reports whether a dictionary is an intermediate /Pages node.
+// Nodes without a /Type but with /Kids are treated as /Pages nodes.
+func isPagesNodeDict(dict string) bool {
+	if match := pageTypePattern.FindStringSubmatch(dict); match != nil {
+		return match[1] == "Pages"
+	}
+	return strings.Contains(dict, "/Kids")
+}
+
+// parseRef parses an indirect reference ("N G R") and returns its object number
+func parseRef(s string) (int, bool) {
+	match := pageRefPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return 0, false
+	}
+	objNum, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, false
+	}
+	return objNum, true
+}
+
+// dictRefArray returns the object numbers of an array of references, e.g. /Kids
+func dictRefArray(dict, key string) []int {
+	value := dictRawValue(dict, key)
+	var refs []int
+	for _, match := range pageRefArrayPattern.FindAllStringSubmatch(value, -1) {
+		if n, err := strconv.Atoi(match[1]); err == nil {
+			refs = append(refs, n)
+		}
+	}
+	return refs
+}
+
+// dictNumberArray returns the numbers of an inline array value, e.g. /MediaBox
+func dictNumberArray(dict, key string) []float64 {
+	value := dictRawValue(dict, key)
+	if !strings.HasPrefix(value, "[") {
+		return nil
+	}
+	var values []float64
+	for _, part := range strings.Fields(strings.Trim(value, "[]")) {
+		if v, err := strconv.ParseFloat(part, 64); err == nil {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// dictRawValue returns the raw value following a key: a reference, an array,
+// a nested dictionary (with delimiters), or a single token
+func dictRawValue(dict, key string) string {
+	idx := indexKey(dict, key)
+	if idx == -1 {
+		return ""
+	}
+	rest := strings.TrimLeft(dict[idx+len(key):], " \t\r\n")
+	if rest == "" {
+		return ""
+	}
+
+	switch {
+	case strings.HasPrefix(rest, "<<"):
+		depth := 0
+		for i := 0; i+1 < len(rest); i++ {
+			if rest[i] == '<' && rest[i+1] == '<' {
+				depth++
+				i++
+			} else if rest[i] == '>' && rest[i+1] == '>' {
+				depth--
+				i++
+				if depth == 0 {
+					return rest[:i+1]
+				}
+			}
+		}
+		return ""
+	case rest[0] == '[':
+		if end := strings.IndexByte(rest, ']'); end != -1 {
+			return rest[:end+1]
+		}
+		return ""
+	}
+
+	if match := pageRefPattern.FindString(rest); match != "" {
+		return match
+	}
+	end := 1
+	for end < len(rest) && !strings.ContainsRune(" \t\r\n/<>[]()", rune(rest[end])) {
+		end++
+	}
+	return rest[:end]
+}
+
+// indexKey finds a dictionary key, ignoring longer names that share the prefix
+func indexKey(dict, key string) int {
+	offset := 0
+	for {
+		idx := strings.Index(dict[offset:], key)
+		if idx == -1 {
+			return -1
+		}
+		idx += offset
+		next := idx + len(key)
+		if next >= len(dict) || !isNameChar(dict[next]) {
+			return idx
+		}
+		offset = next
+	}
+}
+
+// isNameChar reports whether b can continue a PDF name
+func isNameChar(b byte) bool {
+	return !isWhitespace(b) && !strings.ContainsRune("/<>[]()%{}", rune(b))
+}
@@ -0,0 +1,209 @@
+package parse
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildTestPDF assembles a PDF from object bodies; objects[i] becomes object i+1
+func buildTestPDF(objects []string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+
+	offsets := make([]int, len(objects))
+	for i, body := range objects {
+		offsets[i] = buf.Len()
+		buf.WriteString(fmt.Sprintf("%d 0 obj\n%s\nendobj\n", i+1, body))
+	}
+
+	xrefOffset := buf.Len()
+	buf.WriteString(fmt.Sprintf("xref\n0 %d\n", len(objects)+1))
+	buf.WriteString("0000000000 65535 f \n")
+	for _, off := range offsets {
+		buf.WriteString(fmt.Sprintf("%010d 00000 n \n", off))
+	}
+	buf.WriteString(fmt.Sprintf("trailer\n<</Size %d/Root 1 0 R>>\n", len(objects)+1))
+	buf.WriteString(fmt.Sprintf("startxref\n%d\n%%%%EOF\n", xrefOffset))
+	return buf.Bytes()
+}
+
+// createNestedPagesPDF builds a two-level page tree:
+//
+//	2 Pages (MediaBox, Resources 9, Rotate 90)
+//	├── 3 Pages (CropBox) ── 5 Page, 6 Page (own MediaBox, Rotate 0)
+//	└── 4 Pages (inline Resources) ── 7 Page, 8 Page (own Resources)
+func createNestedPagesPDF() []byte {
+	return buildTestPDF([]string{
+		"<</Type/Catalog/Pages 2 0 R>>",
+		"<</Type/Pages/Kids[3 0 R 4 0 R]/Count 4/MediaBox[0 0 612 792]/Resources 9 0 R/Rotate 90>>",
+		"<</Type/Pages/Parent 2 0 R/Kids[5 0 R 6 0 R]/Count 2/CropBox[10 10 600 780]>>",
+		"<</Type/Pages/Parent 2 0 R/Kids[7 0 R 8 0 R]/Count 2/Resources<</Font<</F2 11 0 R>>>>>>",
+		"<</Type/Page/Parent 3 0 R>>",
+		"<</Type/Page/Parent 3 0 R/MediaBox[0 0 300 400]/Rotate 0>>",
+		"<</Type/Page/Parent 4 0 R>>",
+		"<</Type/Page/Parent 4 0 R/Resources 10 0 R>>",
+		"<</Font<</F1 12 0 R>>>>",
+		"<</XObject<</Im1 13 0 R>>>>",
+	})
+}
+
+func TestPages_Inheritance(t *testing.T) {
+	pdf, err := Open(createNestedPagesPDF())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	var pages []*PageRef
+	it := pdf.Pages()
+	for it.Next() {
+		pages = append(pages, it.Page())
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Pages() error = %v", err)
+	}
+
+	if len(pages) != 4 {
+		t.Fatalf("got %d pages, want 4", len(pages))
+	}
+
+	wantObjs := []int{5, 6, 7, 8}
+	for i, p := range pages {
+		if p.Number != i+1 {
+			t.Errorf("page %d: Number = %d", i+1, p.Number)
+		}
+		if p.ObjectNumber != wantObjs[i] {
+			t.Errorf("page %d: ObjectNumber = %d, want %d", i+1, p.ObjectNumber, wantObjs[i])
+		}
+	}
+
+	// Page 1 inherits everything
+	if fmt.Sprint(pages[0].MediaBox) != "[0 0 612 792]" {
+		t.Errorf("page 1 MediaBox = %v", pages[0].MediaBox)
+	}
+	if fmt.Sprint(pages[0].CropBox) != "[10 10 600 780]" {
+		t.Errorf("page 1 CropBox = %v", pages[0].CropBox)
+	}
+	if pages[0].Rotate != 90 {
+		t.Errorf("page 1 Rotate = %d, want 90", pages[0].Rotate)
+	}
+	if !strings.Contains(pages[0].Resources, "/F1 12 0 R") {
+		t.Errorf("page 1 Resources = %q, want indirect resources from root", pages[0].Resources)
+	}
+
+	// Page 2 overrides MediaBox and Rotate
+	if fmt.Sprint(pages[1].MediaBox) != "[0 0 300 400]" {
+		t.Errorf("page 2 MediaBox = %v", pages[1].MediaBox)
+	}
+	if pages[1].Rotate != 0 {
+		t.Errorf("page 2 Rotate = %d, want 0", pages[1].Rotate)
+	}
+
+	// Page 3 inherits inline Resources from its direct parent, not the root
+	if !strings.Contains(pages[2].Resources, "/F2 11 0 R") {
+		t.Errorf("page 3 Resources = %q, want inline resources from parent", pages[2].Resources)
+	}
+	if pages[2].CropBox != nil {
+		t.Errorf("page 3 CropBox = %v, want nil", pages[2].CropBox)
+	}
+
+	// Page 4 has its own Resources
+	if !strings.Contains(pages[3].Resources, "/Im1 13 0 R") {
+		t.Errorf("page 4 Resources = %q, want own resources", pages[3].Resources)
+	}
+}
+
+func TestPage_MatchesIterator(t *testing.T) {
+	pdf, err := Open(createNestedPagesPDF())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	count, err := pdf.PageCount()
+	if err != nil {
+		t.Fatalf("PageCount() error = %v", err)
+	}
+	if count != 4 {
+		t.Fatalf("PageCount() = %d, want 4", count)
+	}
+
+	it := pdf.Pages()
+	for it.Next() {
+		want := it.Page()
+		got, err := pdf.Page(want.Number)
+		if err != nil {
+			t.Fatalf("Page(%d) error = %v", want.Number, err)
+		}
+		if got.ObjectNumber != want.ObjectNumber || got.Rotate != want.Rotate ||
+			got.Resources != want.Resources || fmt.Sprint(got.MediaBox) != fmt.Sprint(want.MediaBox) ||
+			fmt.Sprint(got.CropBox) != fmt.Sprint(want.CropBox) {
+			t.Errorf("Page(%d) = %+v, want %+v", want.Number, got, want)
+		}
+	}
+
+	for _, n := range []int{0, 5} {
+		if _, err := pdf.Page(n); err == nil {
+			t.Errorf("Page(%d) expected error", n)
+		}
+	}
+}
+
+func TestPages_Cycle(t *testing.T) {
+	pdf, err := Open(buildTestPDF([]string{
+		"<</Type/Catalog/Pages 2 0 R>>",
+		"<</Type/Pages/Kids[3 0 R 2 0 R 4 0 R]/Count 2>>",
+		"<</Type/Page/Parent 2 0 R>>",
+		"<</Type/Page/Parent 2 0 R>>",
+	}))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	n := 0
+	it := pdf.Pages()
+	for it.Next() {
+		n++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Pages() error = %v", err)
+	}
+	if n != 2 {
+		t.Errorf("got %d pages, want 2", n)
+	}
+}
+
+func TestPages_NoRoot(t *testing.T) {
+	pdf, err := Open(buildTestPDF([]string{"<</Type/Catalog>>"}))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	it := pdf.Pages()
+	if it.Next() {
+		t.Error("Next() = true for catalog without /Pages")
+	}
+	if it.Err() == nil {
+		t.Error("Err() = nil, want error")
+	}
+}
+
+func TestDictRawValue(t *testing.T) {
+	dict := "<</Type/Page/Resources<</Font<</F1 5 0 R>>>>/MediaBox [0 0 612 792]/Parent 2 0 R/Rotate 90/RotateX 1>>"
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"/Resources", "<</Font<</F1 5 0 R>>>>"},
+		{"/MediaBox", "[0 0 612 792]"},
+		{"/Parent", "2 0 R"},
+		{"/Rotate", "90"},
+		{"/Type", "/Page"},
+		{"/CropBox", ""},
+	}
+	for _, tt := range tests {
+		if got := dictRawValue(dict, tt.key); got != tt.want {
+			t.Errorf("dictRawValue(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
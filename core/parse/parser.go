@@ -11,8 +11,23 @@ import (
 	"github.com/benedoc-inc/pdfer/types"
 )
 
-// ParsePDFTrailer parses the PDF trailer to find object references
+// ParsePDFTrailer parses the PDF trailer to find object references. The
+// references come from the newest trailer of the /Prev chain that has them and
+// StartXRef is the offset of the newest cross-reference section.
 func ParsePDFTrailer(pdfBytes []byte) (*PDFTrailer, error) {
+	incParser := newIncrementalParser(pdfBytes, false)
+	if err := incParser.parse(); err == nil {
+		if merged := incParser.trailer(); merged.Root != "" {
+			return &PDFTrailer{
+				RootRef:    merged.Root,
+				InfoRef:    merged.Info,
+				EncryptRef: merged.Encrypt,
+				StartXRef:  merged.StartXRef,
+			}, nil
+		}
+	}
+
+	// Without a usable chain, read the last trailer dictionary in the file
 	pdfStr := string(pdfBytes)
 
 	// Find trailer - search from the end (trailer is usually near EOF)
@@ -69,27 +84,27 @@ func ParsePDFTrailer(pdfBytes []byte) (*PDFTrailer, error) {
 	rootPattern := regexp.MustCompile(`/Root\s+(\d+)\s+(\d+)\s+R`)
 	rootMatch := rootPattern.FindStringSubmatch(trailerSection)
 	if rootMatch != nil {
-		trailer.RootRef = rootMatch[0]
+		trailer.RootRef = rootMatch[1] + " " + rootMatch[2] + " R"
 	}
 
 	// Extract Info reference
 	infoPattern := regexp.MustCompile(`/Info\s+(\d+)\s+(\d+)\s+R`)
 	infoMatch := infoPattern.FindStringSubmatch(trailerSection)
 	if infoMatch != nil {
-		trailer.InfoRef = infoMatch[0]
+		trailer.InfoRef = infoMatch[1] + " " + infoMatch[2] + " R"
 	}
 
 	// Extract Encrypt reference
 	encryptPattern := regexp.MustCompile(`/Encrypt\s+(\d+)\s+(\d+)\s+R`)
 	encryptMatch := encryptPattern.FindStringSubmatch(trailerSection)
 	if encryptMatch != nil {
-		trailer.EncryptRef = encryptMatch[0]
+		trailer.EncryptRef = encryptMatch[1] + " " + encryptMatch[2] + " R"
 	}
 
-	// Find startxref
+	// Find the last startxref
 	startXRefPattern := regexp.MustCompile(`startxref\s+(\d+)`)
-	startXRefMatch := startXRefPattern.FindStringSubmatch(pdfStr)
-	if startXRefMatch != nil {
+	if matches := startXRefPattern.FindAllStringSubmatch(pdfStr, -1); len(matches) > 0 {
+		startXRefMatch := matches[len(matches)-1]
 		offset, err := strconv.ParseInt(startXRefMatch[1], 10, 64)
 		if err == nil {
 			trailer.StartXRef = offset
@@ -99,9 +114,21 @@ func ParsePDFTrailer(pdfBytes []byte) (*PDFTrailer, error) {
 	return trailer, nil
 }
 
-// FindObjectByNumber finds a PDF object by its number
+// FindObjectByNumber finds a PDF object by its number and returns the offset of
+// its header. The cross-reference chain is used first, so that the newest
+// version of an object updated by incremental saves is found, and an object
+// they freed is not found at all.
 func FindObjectByNumber(pdfBytes []byte, objNum int, encryptInfo *types.PDFEncryption, verbose bool) (int, error) {
-	// First, try direct search (works for unencrypted or if object header is visible)
+	if loc, err := FindObjectLocation(pdfBytes, objNum, verbose); err == nil {
+		if loc.IsFree {
+			return 0, fmt.Errorf("object %d is free", objNum)
+		}
+		if loc.IsDirect && hasObjectHeader(pdfBytes, loc.ByteOffset, objNum) {
+			return int(loc.ByteOffset), nil
+		}
+	}
+
+	// Then try direct search (works for unencrypted or if object header is visible)
 	objPattern := []byte(fmt.Sprintf("%d 0 obj", objNum))
 	objIndex := bytes.Index(pdfBytes, objPattern)
 	if objIndex != -1 {
@@ -180,9 +207,13 @@ func ParseCrossReferenceTableWithEncryption(pdfBytes []byte, startXRef int64, en
 func ParseTraditionalXRefTable(pdfBytes []byte, startXRef int64) (map[int]int64, error) {
 	objMap := make(map[int]int64)
 
-	// Read from startxref position
+	// Read from startxref position up to the trailer (tables have 20 bytes per entry,
+	// so large documents run well past any fixed window)
 	xrefSection := pdfBytes[startXRef:]
-	xrefStr := string(xrefSection[:min(10000, len(xrefSection))])
+	if end := bytes.Index(xrefSection, []byte("trailer")); end != -1 {
+		xrefSection = xrefSection[:end]
+	}
+	xrefStr := string(xrefSection)
 
 	// Find xref keyword
 	xrefPos := strings.Index(xrefStr, "xref")
@@ -1,6 +1,7 @@
 package parse
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -28,11 +29,14 @@ startxref
 		t.Fatalf("ParsePDFTrailer() error = %v", err)
 	}
 
-	if trailer.RootRef == "" {
-		t.Error("ParsePDFTrailer() RootRef is empty")
+	if trailer.RootRef != "2 0 R" {
+		t.Errorf("ParsePDFTrailer() RootRef = %q, want %q", trailer.RootRef, "2 0 R")
 	}
-	if trailer.EncryptRef == "" {
-		t.Error("ParsePDFTrailer() EncryptRef is empty")
+	if trailer.EncryptRef != "3 0 R" {
+		t.Errorf("ParsePDFTrailer() EncryptRef = %q, want %q", trailer.EncryptRef, "3 0 R")
+	}
+	if trailer.InfoRef != "4 0 R" {
+		t.Errorf("ParsePDFTrailer() InfoRef = %q, want %q", trailer.InfoRef, "4 0 R")
 	}
 	if trailer.StartXRef != 100 {
 		t.Errorf("ParsePDFTrailer() StartXRef = %d, want 100", trailer.StartXRef)
@@ -199,3 +203,12 @@ func TestParseCrossReferenceTableWithEncryption_InvalidOffset(t *testing.T) {
 		t.Error("ParseCrossReferenceTableWithEncryption() should return error for offset beyond file size")
 	}
 }
+
+func TestParseXRefStreamFullDictionaryOutOfWindow(t *testing.T) {
+	// startxref points at an object whose dictionary starts beyond the scan window;
+	// this used to panic with a slice bounds error
+	pdf := []byte("1 0 obj\n" + strings.Repeat("%", 600) + "\n<</Type/XRef/Size 1/W[1 1 1]>>\nstream\n\x01\x00\x00\nendstream\nendobj\n")
+	if _, err := ParseXRefStreamFull(pdf, 0, false); err == nil {
+		t.Error("Expected error for xref stream dictionary outside scan window")
+	}
+}
@@ -0,0 +1,111 @@
+package parse
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"io"
+	"sync"
+)
+
+// maxPooledBufferSize caps the capacity of buffers returned to the pool so a
+// single huge stream does not pin memory for the life of the process
+const maxPooledBufferSize = 16 << 20
+
+var (
+	bufferPool = sync.Pool{
+		New: func() interface{} { return new(bytes.Buffer) },
+	}
+	zlibReaderPool  sync.Pool // io.ReadCloser implementing zlib.Resetter
+	flateReaderPool sync.Pool // io.ReadCloser implementing flate.Resetter
+)
+
+// AcquireBuffer returns an empty buffer from the shared decode pool.
+// Release it with ReleaseBuffer once its contents are no longer referenced.
+func AcquireBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// ReleaseBuffer returns a buffer obtained from AcquireBuffer to the pool
+func ReleaseBuffer(buf *bytes.Buffer) {
+	if buf == nil || buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(buf)
+}
+
+// DecodeFlateDecodeTo decompresses zlib or raw deflate data, appending to dst.
+// Decompressor state is pooled, so repeated calls avoid per-stream allocations.
+func DecodeFlateDecodeTo(dst *bytes.Buffer, data []byte) error {
+	start := dst.Len()
+	err := inflateZlibTo(dst, data)
+	if err == nil {
+		return nil
+	}
+
+	// Not zlib-wrapped (or corrupt header) - retry as raw deflate
+	dst.Truncate(start)
+	return inflateRawTo(dst, data)
+}
+
+// inflate decompresses zlib or raw deflate data using pooled scratch space
+// and returns an exactly-sized copy of the result
+func inflate(data []byte) ([]byte, error) {
+	buf := AcquireBuffer()
+	defer ReleaseBuffer(buf)
+
+	if err := DecodeFlateDecodeTo(buf, data); err != nil {
+		return nil, err
+	}
+	out := make([]byte, buf.Len())
+	copy(out, buf.Bytes())
+	return out, nil
+}
+
+// inflateZlibTo decompresses zlib-wrapped data into dst
+func inflateZlibTo(dst *bytes.Buffer, data []byte) error {
+	src := bytes.NewReader(data)
+
+	var r io.ReadCloser
+	if pooled, ok := zlibReaderPool.Get().(io.ReadCloser); ok {
+		if err := pooled.(zlib.Resetter).Reset(src, nil); err != nil {
+			zlibReaderPool.Put(pooled)
+			return err
+		}
+		r = pooled
+	} else {
+		var err error
+		r, err = zlib.NewReader(src)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := dst.ReadFrom(r)
+	r.Close()
+	zlibReaderPool.Put(r)
+	return err
+}
+
+// inflateRawTo decompresses raw deflate data into dst
+func inflateRawTo(dst *bytes.Buffer, data []byte) error {
+	src := bytes.NewReader(data)
+
+	var r io.ReadCloser
+	if pooled, ok := flateReaderPool.Get().(io.ReadCloser); ok {
+		if err := pooled.(flate.Resetter).Reset(src, nil); err != nil {
+			flateReaderPool.Put(pooled)
+			return err
+		}
+		r = pooled
+	} else {
+		r = flate.NewReader(src)
+	}
+
+	_, err := dst.ReadFrom(r)
+	r.Close()
+	flateReaderPool.Put(r)
+	return err
+}
@@ -0,0 +1,145 @@
+package parse
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"testing"
+)
+
+func zlibCompress(t testing.TB, data []byte) []byte {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+func deflateCompress(t testing.TB, data []byte) []byte {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestDecodeFlateDecodeTo(t *testing.T) {
+	plain := bytes.Repeat([]byte("BT /F1 12 Tf 72 720 Td (Hello) Tj ET\n"), 50)
+
+	tests := []struct {
+		name    string
+		input   []byte
+		wantErr bool
+	}{
+		{name: "zlib", input: zlibCompress(t, plain)},
+		{name: "raw deflate", input: deflateCompress(t, plain)},
+		{name: "garbage", input: []byte("not compressed at all"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := AcquireBuffer()
+			defer ReleaseBuffer(buf)
+
+			err := DecodeFlateDecodeTo(buf, tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeFlateDecodeTo() error = %v", err)
+			}
+			if !bytes.Equal(buf.Bytes(), plain) {
+				t.Errorf("decoded %d bytes, want %d", buf.Len(), len(plain))
+			}
+		})
+	}
+}
+
+func TestDecodeFlateDecodeToAppends(t *testing.T) {
+	buf := AcquireBuffer()
+	defer ReleaseBuffer(buf)
+
+	buf.WriteString("prefix:")
+	if err := DecodeFlateDecodeTo(buf, deflateCompress(t, []byte("body"))); err != nil {
+		t.Fatalf("DecodeFlateDecodeTo() error = %v", err)
+	}
+	if got := buf.String(); got != "prefix:body" {
+		t.Errorf("buffer = %q, want %q", got, "prefix:body")
+	}
+}
+
+func TestDecodeFlateDecodeReusesReaders(t *testing.T) {
+	// Alternate inputs so pooled readers are reset across formats and sizes
+	inputs := [][]byte{
+		[]byte("first stream"),
+		bytes.Repeat([]byte{0xAB}, 4096),
+		[]byte(""),
+		[]byte("last stream"),
+	}
+
+	for round := 0; round < 3; round++ {
+		for i, plain := range inputs {
+			got, err := DecodeFlateDecode(zlibCompress(t, plain))
+			if err != nil {
+				t.Fatalf("round %d input %d: DecodeFlateDecode() error = %v", round, i, err)
+			}
+			if !bytes.Equal(got, plain) {
+				t.Errorf("round %d input %d: got %d bytes, want %d", round, i, len(got), len(plain))
+			}
+
+			got, err = inflate(deflateCompress(t, plain))
+			if err != nil {
+				t.Fatalf("round %d input %d: inflate() error = %v", round, i, err)
+			}
+			if !bytes.Equal(got, plain) {
+				t.Errorf("round %d input %d: inflate got %d bytes, want %d", round, i, len(got), len(plain))
+			}
+		}
+	}
+}
+
+func TestDecodeFlateDecodeResultNotShared(t *testing.T) {
+	first, err := DecodeFlateDecode(zlibCompress(t, []byte("aaaa")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecodeFlateDecode(zlibCompress(t, []byte("bbbb"))); err != nil {
+		t.Fatal(err)
+	}
+	if string(first) != "aaaa" {
+		t.Errorf("earlier result was overwritten: %q", first)
+	}
+}
+
+func TestReleaseBufferDropsOversized(t *testing.T) {
+	big := bytes.NewBuffer(make([]byte, 0, maxPooledBufferSize+1))
+	ReleaseBuffer(big)
+	ReleaseBuffer(nil)
+
+	buf := AcquireBuffer()
+	defer ReleaseBuffer(buf)
+	if buf.Len() != 0 {
+		t.Errorf("acquired buffer not empty: %d bytes", buf.Len())
+	}
+}
+
+func BenchmarkDecodeFlateDecode(b *testing.B) {
+	data := zlibCompress(b, bytes.Repeat([]byte("0 0 m 100 100 l S\n"), 4096))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := DecodeFlateDecode(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
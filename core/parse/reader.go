@@ -0,0 +1,119 @@
+package parse
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// Read sizes for files opened with OpenReader
+const (
+	readChunk  = 64 << 10 // First read of an object or cross-reference section
+	tailWindow = 64 << 10 // End of the file searched for the last startxref
+)
+
+// OpenReader parses a PDF from r, which holds size bytes, with default options.
+// See OpenReaderWithOptions.
+func OpenReader(r io.ReaderAt, size int64) (*PDF, error) {
+	return OpenReaderWithOptions(r, size, ParseOptions{})
+}
+
+// OpenReaderWithOptions parses a PDF from r without reading it into memory:
+// only the cross-reference sections are read when it opens, and GetObject reads
+// each object from its cross-reference offset when asked for it, so that large
+// files can be processed in little memory. r must stay readable while the PDF
+// is in use; Close does not close it.
+//
+// Raw and Bytes return nil for such a PDF, and functions that take the document
+// bytes as well as the PDF should be given nil. Encrypted documents are opened
+// with opts.Password, like OpenWithOptions, and their objects decrypted as they
+// are read. BytePerfect parsing and files whose cross-reference data is broken
+// are not supported and need OpenWithOptions. opts.MemoryMap is ignored.
+func OpenReaderWithOptions(r io.ReaderAt, size int64, opts ParseOptions) (*PDF, error) {
+	if size < 8 {
+		return nil, types.NewPDFErrorf(types.ErrCodeInvalidPDF, "PDF too short: %d bytes", size).WithContext("bytes", size)
+	}
+	if opts.BytePerfect {
+		return nil, types.NewPDFError(types.ErrCodeInvalidInput, "byte-perfect parsing needs the PDF in memory")
+	}
+
+	pdf := &PDF{
+		src:  r,
+		size: size,
+		opts: opts,
+		xref: &XRef{Objects: make(map[int]*ObjectRef), Free: make(map[int]bool)},
+	}
+
+	incParser := newIncrementalParser(nil, opts.Verbose)
+	incParser.src = r
+	incParser.size = size
+	if err := incParser.parse(); err != nil {
+		return nil, types.WrapError(types.ErrCodeXRefError, "failed to read cross-reference data", err)
+	}
+	pdf.loadXRef(incParser)
+
+	if pdf.trailer.EncryptRef != "" {
+		if err := pdf.readEncryption(); err != nil {
+			return nil, types.WrapError(types.ErrCodeDecryptionFailed, "encryption error", err)
+		}
+	}
+	return pdf, nil
+}
+
+// readEncryption validates the password of an encrypted PDF opened with
+// OpenReader. Only the /Encrypt dictionary and the trailer's /ID are read; they
+// are given to the password check in the form they have in the document.
+func (p *PDF) readEncryption() error {
+	var objNum, genNum int
+	if _, err := fmt.Sscanf(p.trailer.EncryptRef, "%d %d R", &objNum, &genNum); err != nil {
+		return types.WrapError(types.ErrCodeInvalidObject, "invalid /Encrypt reference", err).WithContext("reference", p.trailer.EncryptRef)
+	}
+	// The encryption dictionary's own strings are never encrypted, so it is read
+	// before the key is known
+	dict, err := p.GetObject(objNum)
+	if err != nil {
+		return types.WrapErrorf(types.ErrCodeObjectNotFound, err, "failed to read encryption dictionary %d", objNum)
+	}
+
+	var data bytes.Buffer
+	fmt.Fprintf(&data, "%d 0 obj\n%s\nendobj\ntrailer\n<< /Encrypt %s", objNum, dict, p.trailer.EncryptRef)
+	if len(p.trailer.IDArray) > 0 {
+		fmt.Fprintf(&data, " /ID %s", p.trailer.IDArray)
+	}
+	data.WriteString(" >>\n")
+	return p.validatePassword(data.Bytes())
+}
+
+// readWindow reads from offset on until the data read contains marker, in
+// growing reads, or to the end of the file. A nil marker reads one chunk.
+func readWindow(r io.ReaderAt, size, offset int64, marker []byte) ([]byte, error) {
+	for n := int64(readChunk); ; n *= 4 {
+		n = min(n, size-offset)
+		buf := make([]byte, n)
+		if read, err := r.ReadAt(buf, offset); err != nil && !(err == io.EOF && int64(read) == n) {
+			return nil, types.WrapError(types.ErrCodeIOError, "failed to read PDF", err).WithContext("offset", offset)
+		}
+		if marker == nil || bytes.Contains(buf, marker) || offset+n >= size {
+			return buf, nil
+		}
+	}
+}
+
+// readObject reads a direct object of a PDF opened with OpenReader
+func (p *PDF) readObject(objNum int, ref *ObjectRef) ([]byte, error) {
+	data, err := p.readAt(ref.Offset)
+	if err != nil {
+		return nil, err
+	}
+	return GetDirectObject(data, objNum, 0, p.encryption, p.opts.Verbose)
+}
+
+// readAt reads the object that starts at offset of a PDF opened with OpenReader
+func (p *PDF) readAt(offset int64) ([]byte, error) {
+	if offset < 0 || offset >= p.size {
+		return nil, types.NewPDFErrorf(types.ErrCodeXRefError, "object offset %d is outside the file", offset).WithContext("offset", offset)
+	}
+	return readWindow(p.src, p.size, offset, []byte("endobj"))
+}
@@ -0,0 +1,187 @@
+package parse
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/encrypt"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// countingReader counts the bytes read from a reader
+type countingReader struct {
+	r    *bytes.Reader
+	read int64
+}
+
+func (c *countingReader) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.r.ReadAt(p, off)
+	atomic.AddInt64(&c.read, int64(n))
+	return n, err
+}
+
+func deflate(data string) []byte {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write([]byte(data))
+	zw.Close()
+	return buf.Bytes()
+}
+
+// createObjStmPDF creates a PDF with a cross-reference stream whose catalog
+// and page tree root are stored in an object stream, followed by padding
+// large enough to tell whether a reader reads the whole file
+func createObjStmPDF(padding int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.5\n")
+
+	objects := []string{"<</Type/Catalog/Pages 2 0 R>>", "<</Type/Pages/Kids[3 0 R]/Count 1>>"}
+	var header, body strings.Builder
+	for i, obj := range objects {
+		header.WriteString(fmt.Sprintf("%d %d ", i+1, body.Len()))
+		body.WriteString(obj + "\n")
+	}
+	streamData := deflate(header.String() + body.String())
+
+	obj3Offset := buf.Len()
+	buf.WriteString("3 0 obj\n<</Type/Page/Parent 2 0 R/MediaBox[0 0 612 792]>>\nendobj\n")
+
+	obj4Offset := buf.Len()
+	buf.WriteString(fmt.Sprintf("4 0 obj\n<</Length %d>>\nstream\n%s\nendstream\nendobj\n", padding, strings.Repeat("%", padding)))
+
+	obj5Offset := buf.Len()
+	buf.WriteString(fmt.Sprintf("5 0 obj\n<</Type/ObjStm/N 2/First %d/Filter/FlateDecode/Length %d>>\nstream\n", header.Len(), len(streamData)))
+	buf.Write(streamData)
+	buf.WriteString("\nendstream\nendobj\n")
+
+	entry := func(typ, field2, field3 int) string {
+		return string([]byte{byte(typ), byte(field2 >> 24), byte(field2 >> 16), byte(field2 >> 8), byte(field2), byte(field3)})
+	}
+	xrefOffset := buf.Len()
+	entries := entry(0, 0, 255) + entry(2, 5, 0) + entry(2, 5, 1) + entry(1, obj3Offset, 0) +
+		entry(1, obj4Offset, 0) + entry(1, obj5Offset, 0) + entry(1, xrefOffset, 0)
+	xrefData := deflate(entries)
+	buf.WriteString(fmt.Sprintf("6 0 obj\n<</Type/XRef/Size 7/W[1 4 1]/Root 1 0 R/Filter/FlateDecode/Length %d>>\nstream\n", len(xrefData)))
+	buf.Write(xrefData)
+	buf.WriteString("\nendstream\nendobj\n")
+	buf.WriteString(fmt.Sprintf("startxref\n%d\n%%%%EOF\n", xrefOffset))
+	return buf.Bytes()
+}
+
+func TestOpenReader(t *testing.T) {
+	pdfBytes := createTestPDFForAPI()
+	pdf, err := OpenReader(bytes.NewReader(pdfBytes), int64(len(pdfBytes)))
+	if err != nil {
+		t.Fatalf("OpenReader() error = %v", err)
+	}
+	if pdf.Version() != "1.4" {
+		t.Errorf("Version() = %q, want 1.4", pdf.Version())
+	}
+	if pdf.Raw() != nil {
+		t.Error("Raw() should be nil for a PDF opened from a reader")
+	}
+	obj, err := pdf.GetObject(1)
+	if err != nil || !bytes.Contains(obj, []byte("/Catalog")) {
+		t.Errorf("GetObject(1) = %q, %v", obj, err)
+	}
+	if count, err := pdf.PageCount(); err != nil || count != 1 {
+		t.Errorf("PageCount() = %d, %v", count, err)
+	}
+}
+
+func TestOpenReader_ReadsLazily(t *testing.T) {
+	pdfBytes := createObjStmPDF(4 << 20)
+	r := &countingReader{r: bytes.NewReader(pdfBytes)}
+	pdf, err := OpenReader(r, int64(len(pdfBytes)))
+	if err != nil {
+		t.Fatalf("OpenReader() error = %v", err)
+	}
+
+	catalog, err := pdf.GetObject(1)
+	if err != nil || !bytes.Contains(catalog, []byte("/Catalog")) {
+		t.Fatalf("GetObject(1) from the object stream = %q, %v", catalog, err)
+	}
+	page, err := pdf.Page(1)
+	if err != nil || page.MediaBox[2] != 612 {
+		t.Fatalf("Page(1) = %+v, %v", page, err)
+	}
+	if read := atomic.LoadInt64(&r.read); read > int64(len(pdfBytes))/4 {
+		t.Errorf("Read %d of %d bytes without touching the padding object", read, len(pdfBytes))
+	}
+}
+
+// createEncryptedPDF returns an RC4-encrypted PDF whose info dictionary has an
+// encrypted title
+func createEncryptedPDF(t *testing.T, password string) []byte {
+	t.Helper()
+	fileID := []byte("0123456789abcdef")
+	enc, err := encrypt.NewStandardEncryption(2, []byte(password), []byte("owner"), fileID, -4, true)
+	if err != nil {
+		t.Fatalf("NewStandardEncryption() error = %v", err)
+	}
+	title, err := encrypt.EncryptStrings([]byte("<</Title (Quarterly report)>>"), 4, 0, enc)
+	if err != nil {
+		t.Fatalf("EncryptStrings() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	objects := []string{
+		"<</Type/Catalog/Pages 2 0 R>>",
+		"<</Type/Pages/Kids[3 0 R]/Count 1>>",
+		"<</Type/Page/Parent 2 0 R/MediaBox[0 0 612 792]>>",
+		string(title),
+		fmt.Sprintf("<</Filter/Standard/V 2/R 3/Length 128/P -4/O <%x>/U <%x>>>", enc.O, enc.U),
+	}
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xrefOffset := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<</Size %d/Root 1 0 R/Info 4 0 R/Encrypt 5 0 R/ID[<%x><%x>]>>\n", len(objects)+1, fileID, fileID)
+	fmt.Fprintf(&buf, "startxref\n%d\n%%%%EOF\n", xrefOffset)
+	return buf.Bytes()
+}
+
+func TestOpenReader_Encrypted(t *testing.T) {
+	pdfBytes := createEncryptedPDF(t, "secret")
+	if bytes.Contains(pdfBytes, []byte("Quarterly")) {
+		t.Fatal("Test PDF title is not encrypted")
+	}
+
+	pdf, err := OpenReaderWithOptions(bytes.NewReader(pdfBytes), int64(len(pdfBytes)), ParseOptions{Password: []byte("secret")})
+	if err != nil {
+		t.Fatalf("OpenReaderWithOptions() error = %v", err)
+	}
+	if !pdf.IsEncrypted() {
+		t.Error("IsEncrypted() = false, want true")
+	}
+	info, err := pdf.GetObject(4)
+	if err != nil || !bytes.Contains(info, []byte("(Quarterly report)")) {
+		t.Errorf("GetObject(4) = %q, %v; want the decrypted title", info, err)
+	}
+	if count, err := pdf.PageCount(); err != nil || count != 1 {
+		t.Errorf("PageCount() = %d, %v", count, err)
+	}
+
+	_, err = OpenReaderWithOptions(bytes.NewReader(pdfBytes), int64(len(pdfBytes)), ParseOptions{Password: []byte("wrong")})
+	if !errors.Is(err, types.ErrWrongPassword) {
+		t.Errorf("Expected a wrong password error, got %v", err)
+	}
+
+	// A missing encryption dictionary is reported rather than ignored
+	broken := bytes.Replace(createTestPDFForAPI(), []byte("/Root 1 0 R"), []byte("/Root 1 0 R/Encrypt 9 0 R"), 1)
+	if _, err := OpenReader(bytes.NewReader(broken), int64(len(broken))); err == nil {
+		t.Error("Expected an error for a missing encryption dictionary")
+	}
+}
@@ -0,0 +1,164 @@
+package parse
+
+import (
+	"bytes"
+	"log"
+	"sync"
+)
+
+// ScanObjects scans the whole document for "N G obj" headers and returns the
+// byte offset of each object number. Stream data is skipped, so headers that
+// appear inside streams are not picked up. When an object is defined more than
+// once, as in incrementally updated files, the last definition wins.
+//
+// It is the fallback used to recover objects whose cross-reference offsets do
+// not point at their headers.
+func ScanObjects(pdfBytes []byte) map[int]int64 {
+	index := make(map[int]int64)
+	pos := 0
+	for pos < len(pdfBytes) {
+		i := bytes.Index(pdfBytes[pos:], []byte("obj"))
+		if i == -1 {
+			break
+		}
+		objPos := pos + i
+		pos = objPos + 3
+
+		// "obj" must end a token and follow "N G "
+		if pos < len(pdfBytes) && !isPDFDelimiter(pdfBytes[pos]) {
+			continue
+		}
+		start, objNum, ok := objectHeaderStart(pdfBytes, objPos)
+		if !ok {
+			continue
+		}
+		index[objNum] = int64(start)
+
+		// Skip the stream data of the object, if any
+		endobj := bytes.Index(pdfBytes[pos:], []byte("endobj"))
+		stream := bytes.Index(pdfBytes[pos:], []byte("stream"))
+		if stream != -1 && (endobj == -1 || stream < endobj) {
+			if endstream := bytes.Index(pdfBytes[pos+stream+6:], []byte("endstream")); endstream != -1 {
+				pos += stream + 6 + endstream + len("endstream")
+			}
+		}
+	}
+	return index
+}
+
+// objectHeaderStart parses the "N G " preceding the "obj" keyword at objPos and
+// returns the offset of N and the object number
+func objectHeaderStart(data []byte, objPos int) (start, objNum int, ok bool) {
+	i := objPos
+	skipSpace := func() bool {
+		n := i
+		for i > 0 && isPDFWhitespace(data[i-1]) {
+			i--
+		}
+		return i < n
+	}
+	digits := func() (int, bool) {
+		end := i
+		for i > 0 && data[i-1] >= '0' && data[i-1] <= '9' {
+			i--
+		}
+		if i == end || end-i > 10 {
+			return 0, false
+		}
+		value := 0
+		for _, c := range data[i:end] {
+			value = value*10 + int(c-'0')
+		}
+		return value, true
+	}
+
+	skipSpace()
+	if _, ok := digits(); !ok {
+		return 0, 0, false
+	}
+	if !skipSpace() {
+		return 0, 0, false
+	}
+	objNum, ok = digits()
+	if !ok || (i > 0 && !isPDFDelimiter(data[i-1])) {
+		return 0, 0, false
+	}
+	return i, objNum, true
+}
+
+// isPDFWhitespace reports whether c is a PDF whitespace character
+func isPDFWhitespace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+// isPDFDelimiter reports whether c ends a token: whitespace or a delimiter
+func isPDFDelimiter(c byte) bool {
+	return isPDFWhitespace(c) || bytes.IndexByte([]byte("()<>[]{}/%"), c) != -1
+}
+
+// hasObjectHeader reports whether the header of object objNum starts at offset
+func hasObjectHeader(pdfBytes []byte, offset int64, objNum int) bool {
+	if offset < 0 || offset >= int64(len(pdfBytes)) {
+		return false
+	}
+	i := bytes.Index(pdfBytes[offset:min(int64(len(pdfBytes)), offset+40)], []byte("obj"))
+	if i == -1 {
+		return false
+	}
+	start, num, ok := objectHeaderStart(pdfBytes, int(offset)+i)
+	return ok && start == int(offset) && num == objNum
+}
+
+// scanCache holds the object index of the last document scanned by
+// recoverObjectOffset, so repeated lookups in the same bytes scan only once
+var scanCache struct {
+	sync.Mutex
+	data  *byte
+	size  int
+	index map[int]int64
+}
+
+// recoverObjectOffset finds the offset of an object whose cross-reference offset
+// is wrong, scanning the document once and reusing the index for later lookups
+// in the same bytes
+func recoverObjectOffset(pdfBytes []byte, objNum int, verbose bool) (int64, bool) {
+	if len(pdfBytes) == 0 {
+		return 0, false
+	}
+	scanCache.Lock()
+	defer scanCache.Unlock()
+
+	offset, ok := int64(0), false
+	if scanCache.data == &pdfBytes[0] && scanCache.size == len(pdfBytes) {
+		offset, ok = scanCache.index[objNum]
+		// The bytes may have been modified in place since the scan
+		if ok && !hasObjectHeader(pdfBytes, offset, objNum) {
+			ok = false
+			scanCache.index = nil
+		}
+		if ok || scanCache.index != nil {
+			return offset, ok
+		}
+	}
+
+	if verbose {
+		log.Printf("Scanning %d bytes for object headers to recover object %d", len(pdfBytes), objNum)
+	}
+	scanCache.data, scanCache.size = &pdfBytes[0], len(pdfBytes)
+	scanCache.index = ScanObjects(pdfBytes)
+	offset, ok = scanCache.index[objNum]
+	return offset, ok
+}
+
+// recoveredOffset returns the offset of an object from the document's scanned
+// object index, built on first use
+func (p *PDF) recoveredOffset(objNum int) (int64, bool) {
+	p.scanOnce.Do(func() {
+		if p.opts.Verbose {
+			log.Printf("Cross-reference offsets are wrong; scanning %d bytes for object headers", len(p.raw))
+		}
+		p.scanned = ScanObjects(p.raw)
+	})
+	offset, ok := p.scanned[objNum]
+	return offset, ok
+}
@@ -0,0 +1,107 @@
+package parse
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestScanObjects(t *testing.T) {
+	data := []byte("%PDF-1.7\n" +
+		"1 0 obj\n<< /Type /Catalog >>\nendobj\n" +
+		"265 0 obj\n<< /Length 22 >>\nstream\n9 0 obj (fake) endobj\nendstream\nendobj\n" +
+		"2 0 obj\n(first)\nendobj\n" +
+		"2 0 obj\n(second)\nendobj\n" +
+		"3 1 obj[1]endobj\n")
+
+	index := ScanObjects(data)
+	for objNum, want := range map[int]string{
+		1:   "1 0 obj\n<< /Type /Catalog",
+		265: "265 0 obj",
+		2:   "2 0 obj\n(second)",
+		3:   "3 1 obj[1]",
+	} {
+		offset, ok := index[objNum]
+		if !ok || !bytes.HasPrefix(data[offset:], []byte(want)) {
+			t.Errorf("Object %d: offset %d, ok %v", objNum, offset, ok)
+		}
+	}
+	if _, ok := index[9]; ok {
+		t.Error("Header inside stream data was indexed")
+	}
+	if _, ok := index[65]; ok {
+		t.Error("Object 65 matched inside 265")
+	}
+}
+
+// shiftedPDF returns a PDF whose xref offsets all point 200 bytes before the
+// objects, as a comment was inserted after the header without updating them
+func shiftedPDF(t *testing.T) []byte {
+	t.Helper()
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.7\n")
+	var offsets []int
+	for i, obj := range []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [] /Count 0 >>",
+		"<< /Title (Recovered) >>",
+	} {
+		offsets = append(offsets, pdf.Len())
+		fmt.Fprintf(&pdf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := pdf.Len()
+	fmt.Fprintf(&pdf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&pdf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&pdf, "trailer\n<< /Size %d /Root 1 0 R /Info 3 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	// Insert the comment and move only startxref
+	padding := "%" + strings.Repeat("x", 198) + "\n"
+	shifted := "%PDF-1.7\n" + padding + pdf.String()[len("%PDF-1.7\n"):]
+	startxref := regexp.MustCompile(`startxref\n(\d+)`)
+	return []byte(startxref.ReplaceAllStringFunc(shifted, func(m string) string {
+		n, _ := strconv.Atoi(strings.TrimPrefix(m, "startxref\n"))
+		return fmt.Sprintf("startxref\n%d", n+len(padding))
+	}))
+}
+
+func TestPDF_GetObject_RecoversWrongOffsets(t *testing.T) {
+	data := shiftedPDF(t)
+	pdf, err := Open(data)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	obj, err := pdf.GetObject(3)
+	if err != nil || !strings.Contains(string(obj), "(Recovered)") {
+		t.Fatalf("GetObject(3) = %q, %v", obj, err)
+	}
+	if pdf.scanned == nil {
+		t.Error("Expected the object index to be built")
+	}
+	if offset := pdf.xref.Objects[3].Offset; !hasObjectHeader(data, offset, 3) {
+		t.Errorf("Xref entry not corrected: offset %d", offset)
+	}
+	if obj, err := pdf.GetObject(1); err != nil || !strings.Contains(string(obj), "/Catalog") {
+		t.Errorf("GetObject(1) = %q, %v", obj, err)
+	}
+}
+
+func TestGetObject_RecoversWrongOffsets(t *testing.T) {
+	data := shiftedPDF(t)
+	for objNum, want := range map[int]string{1: "/Catalog", 2: "/Pages", 3: "(Recovered)"} {
+		obj, err := GetObject(data, objNum, nil, false)
+		if err != nil || !strings.Contains(string(obj), want) {
+			t.Errorf("GetObject(%d) = %q, %v", objNum, obj, err)
+		}
+	}
+
+	// The index is reused, and rebuilt when the bytes change in place
+	copy(data[bytes.Index(data, []byte("Recovered")):], "Refreshed")
+	if obj, _ := GetObject(data, 3, nil, false); !strings.Contains(string(obj), "(Refreshed)") {
+		t.Errorf("Modified object not read: %q", obj)
+	}
+}
@@ -0,0 +1,186 @@
+package sign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Object identifiers used in the CMS signature (RFC 5652, RFC 5754, RFC 5035)
+var (
+	oidData                 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidContentType          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidMessageDigest        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSigningTime          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	oidSigningCertificateV2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 47}
+	oidRSAEncryption        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+
+	digestOIDs = map[crypto.Hash]asn1.ObjectIdentifier{
+		crypto.SHA256: {2, 16, 840, 1, 101, 3, 4, 2, 1},
+		crypto.SHA384: {2, 16, 840, 1, 101, 3, 4, 2, 2},
+		crypto.SHA512: {2, 16, 840, 1, 101, 3, 4, 2, 3},
+	}
+	ecdsaOIDs = map[crypto.Hash]asn1.ObjectIdentifier{
+		crypto.SHA256: {1, 2, 840, 10045, 4, 3, 2},
+		crypto.SHA384: {1, 2, 840, 10045, 4, 3, 3},
+		crypto.SHA512: {1, 2, 840, 10045, 4, 3, 4},
+	}
+)
+
+// DER tags of the structures built by hand
+const (
+	tagSequence    = 0x30
+	tagSet         = 0x31
+	tagOctetString = 0x04
+	tagContext0    = 0xa0 // [0], constructed
+	tagContext4    = 0xa4 // [4], constructed
+)
+
+// der encodes a value with tag from the concatenation of its encoded parts
+func der(tag byte, parts ...[]byte) []byte {
+	var content []byte
+	for _, part := range parts {
+		content = append(content, part...)
+	}
+	n := len(content)
+	out := []byte{tag}
+	switch {
+	case n < 0x80:
+		out = append(out, byte(n))
+	case n <= 0xff:
+		out = append(out, 0x81, byte(n))
+	case n <= 0xffff:
+		out = append(out, 0x82, byte(n>>8), byte(n))
+	default:
+		out = append(out, 0x83, byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(out, content...)
+}
+
+// derSetOf encodes a SET OF with its elements in the sorted order DER requires
+func derSetOf(elements ...[]byte) []byte {
+	sorted := append([][]byte(nil), elements...)
+	sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i], sorted[j]) < 0 })
+	return der(tagSet, sorted...)
+}
+
+// mustMarshal encodes a value asn1 can always encode, such as an object
+// identifier or an integer
+func mustMarshal(v interface{}) []byte {
+	b, err := asn1.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// algorithmIdentifier encodes an AlgorithmIdentifier, with NULL parameters
+// when null is set and none otherwise
+func algorithmIdentifier(oid asn1.ObjectIdentifier, null bool) []byte {
+	if null {
+		return der(tagSequence, mustMarshal(oid), asn1.NullBytes)
+	}
+	return der(tagSequence, mustMarshal(oid))
+}
+
+// signatureAlgorithm returns the AlgorithmIdentifier of the signatures a key
+// makes with hash
+func signatureAlgorithm(pub crypto.PublicKey, hash crypto.Hash) ([]byte, error) {
+	switch pub.(type) {
+	case *rsa.PublicKey:
+		return algorithmIdentifier(oidRSAEncryption, true), nil
+	case *ecdsa.PublicKey:
+		return algorithmIdentifier(ecdsaOIDs[hash], false), nil
+	}
+	return nil, fmt.Errorf("unsupported signing key type %T: RSA and ECDSA keys are supported", pub)
+}
+
+// cmsOptions controls buildCMS
+type cmsOptions struct {
+	Signer      crypto.Signer
+	Certificate *x509.Certificate
+	Chain       []*x509.Certificate
+	Hash        crypto.Hash
+	CAdES       bool      // Add the signing-certificate-v2 attribute rather than the signing time
+	Time        time.Time // Signing time, for signatures that are not CAdES
+}
+
+// buildCMS returns a detached CMS SignedData (RFC 5652) over content with the
+// given digest: the signer's certificate and chain, and one SignerInfo whose
+// signed attributes hold the content type and message digest. CAdES
+// signatures (ETSI EN 319 122-1) identify the signing certificate by its hash
+// in a signing-certificate-v2 attribute and leave the signing time to the
+// signature dictionary's /M, as PAdES requires; PKCS#7 signatures carry the
+// signing time.
+func buildCMS(digest []byte, opts cmsOptions) ([]byte, error) {
+	cert := opts.Certificate
+	sigAlg, err := signatureAlgorithm(cert.PublicKey, opts.Hash)
+	if err != nil {
+		return nil, err
+	}
+	digestAlg := algorithmIdentifier(digestOIDs[opts.Hash], false)
+	issuerAndSerial := der(tagSequence, cert.RawIssuer, mustMarshal(cert.SerialNumber))
+
+	attributes := [][]byte{
+		der(tagSequence, mustMarshal(oidContentType), der(tagSet, mustMarshal(oidData))),
+		der(tagSequence, mustMarshal(oidMessageDigest), der(tagSet, der(tagOctetString, digest))),
+	}
+	if opts.CAdES {
+		certHash := sha256.Sum256(cert.Raw)
+		// ESSCertIDv2 with the default SHA-256 hash algorithm left out
+		essCertID := der(tagSequence,
+			der(tagOctetString, certHash[:]),
+			der(tagSequence, der(tagSequence, der(tagContext4, cert.RawIssuer)), mustMarshal(cert.SerialNumber)))
+		attributes = append(attributes, der(tagSequence, mustMarshal(oidSigningCertificateV2),
+			der(tagSet, der(tagSequence, der(tagSequence, essCertID)))))
+	} else {
+		signingTime, err := asn1.Marshal(opts.Time.UTC())
+		if err != nil {
+			return nil, fmt.Errorf("invalid signing time: %w", err)
+		}
+		attributes = append(attributes, der(tagSequence, mustMarshal(oidSigningTime), der(tagSet, signingTime)))
+	}
+
+	// The signature covers the attributes encoded as a SET OF; the SignerInfo
+	// holds the same encoding tagged [0]
+	signedAttrs := derSetOf(attributes...)
+	h := opts.Hash.New()
+	h.Write(signedAttrs)
+	signature, err := opts.Signer.Sign(rand.Reader, h.Sum(nil), opts.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("signer failed: %w", err)
+	}
+	signedAttrs[0] = tagContext0
+
+	signerInfo := der(tagSequence,
+		mustMarshal(1),
+		issuerAndSerial,
+		digestAlg,
+		signedAttrs,
+		sigAlg,
+		der(tagOctetString, signature))
+
+	certificates := [][]byte{cert.Raw}
+	for _, c := range opts.Chain {
+		if !c.Equal(cert) {
+			certificates = append(certificates, c.Raw)
+		}
+	}
+
+	signedData := der(tagSequence,
+		mustMarshal(1),
+		der(tagSet, digestAlg),
+		der(tagSequence, mustMarshal(oidData)),
+		der(tagContext0, certificates...),
+		der(tagSet, signerInfo))
+	return der(tagSequence, mustMarshal(oidSignedData), der(tagContext0, signedData)), nil
+}
@@ -0,0 +1,728 @@
+package sign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/benedoc-inc/pdfer/core/dates"
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// Signature encodings (PDF 32000-2 12.8.3)
+const (
+	SubFilterCAdES = "ETSI.CAdES.detached" // PAdES baseline signatures
+	SubFilterPKCS7 = "adbe.pkcs7.detached" // Signatures for readers without PAdES support
+)
+
+// Defaults for Options
+const (
+	DefaultFieldName = "Signature1"
+	DefaultReserve   = 8192
+)
+
+// byteRangePlaceholder holds the place of the signature's /ByteRange until the
+// offsets are known; it is wide enough for any offset up to 10 digits
+const byteRangePlaceholder = "[0 0000000000 0000000000 0000000000]"
+
+var (
+	// refPattern matches an indirect reference
+	refPattern = regexp.MustCompile(`(\d+)\s+(\d+)\s+R`)
+	// objectHeaderPattern matches the "N G obj" header at the start of an object
+	objectHeaderPattern = regexp.MustCompile(`^\s*\d+\s+\d+\s+obj\b`)
+)
+
+// Options controls Sign
+type Options struct {
+	// Signer makes the signature. Keys held in an HSM or a cloud KMS can sign
+	// through any crypto.Signer; RSA and ECDSA keys are supported.
+	Signer crypto.Signer
+	// Certificate is the signer's certificate, whose public key is Signer's
+	Certificate *x509.Certificate
+	// Chain holds the intermediate certificates embedded with Certificate
+	Chain []*x509.Certificate
+	// Hash is the digest algorithm: SHA-256 (default), SHA-384 or SHA-512
+	Hash crypto.Hash
+	// SubFilter is SubFilterCAdES (default) or SubFilterPKCS7
+	SubFilter string
+
+	// FieldName is the name of the signature field. An unsigned signature
+	// field of that name is signed where it is; otherwise a field is added.
+	FieldName string
+	// Page is the 1-based page of an added field (default 1)
+	Page int
+	// Rect places a visible added field on its page in default user space; a
+	// zero rectangle makes the signature invisible
+	Rect types.Rectangle
+	// Appearance is drawn in visible fields. Its size is the field's, and an
+	// empty name, reason, location or date is taken from the signature.
+	Appearance write.SignatureAppearance
+
+	Name        string    // Signer name (/Name)
+	Reason      string    // Reason for signing (/Reason)
+	Location    string    // Place of signing (/Location)
+	ContactInfo string    // How to reach the signer (/ContactInfo)
+	Time        time.Time // Signing time (/M); the zero time uses the current time
+	// Certify makes a certification signature, which permits only some changes
+	// after signing (DocMDP, PDF 32000-2 12.8.2.2): 1 none, 2 filling in forms
+	// and signing, 3 also annotating. It must be the document's first
+	// signature. Zero makes an approval signature.
+	Certify int
+	// Reserve is the number of bytes kept for the encoded signature (default
+	// DefaultReserve); long certificate chains need more
+	Reserve int
+	Verbose bool
+}
+
+// Sign adds a digital signature to a PDF (PDF 32000-2 12.8) and returns the
+// signed document. The signature field, its widget and the signature
+// dictionary are appended as an incremental update, so earlier revisions and
+// their signatures stay valid. The signature covers the whole signed file
+// except the /Contents string that holds it: a detached CMS SignedData made
+// with opts.Signer, which only receives the digest of the signed attributes,
+// so the private key never has to leave an HSM.
+//
+// CAdES signatures meet PAdES baseline B-B (ETSI EN 319 142-1). Encrypted
+// documents cannot be signed.
+func Sign(pdfBytes []byte, opts Options) ([]byte, error) {
+	if opts.Signer == nil || opts.Certificate == nil {
+		return nil, fmt.Errorf("a signer and its certificate are required")
+	}
+	if opts.Hash == 0 {
+		opts.Hash = crypto.SHA256
+	}
+	if _, ok := digestOIDs[opts.Hash]; !ok {
+		return nil, fmt.Errorf("unsupported digest algorithm %v: SHA-256, SHA-384 and SHA-512 are supported", opts.Hash)
+	}
+	if opts.SubFilter == "" {
+		opts.SubFilter = SubFilterCAdES
+	}
+	if opts.SubFilter != SubFilterCAdES && opts.SubFilter != SubFilterPKCS7 {
+		return nil, fmt.Errorf("unsupported signature SubFilter %q", opts.SubFilter)
+	}
+	if opts.Certify < 0 || opts.Certify > 3 {
+		return nil, fmt.Errorf("invalid DocMDP permissions %d: want 1, 2 or 3", opts.Certify)
+	}
+	if opts.FieldName == "" {
+		opts.FieldName = DefaultFieldName
+	}
+	if opts.Page == 0 {
+		opts.Page = 1
+	}
+	if opts.Reserve <= 0 {
+		opts.Reserve = DefaultReserve
+	}
+	if opts.Time.IsZero() {
+		opts.Time = time.Now()
+	}
+	if _, err := signatureAlgorithm(opts.Certificate.PublicKey, opts.Hash); err != nil {
+		return nil, err
+	}
+
+	pdf, err := parse.OpenWithOptions(pdfBytes, parse.ParseOptions{Verbose: opts.Verbose})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PDF: %w", err)
+	}
+	if pdf.IsEncrypted() {
+		return nil, types.NewPDFError(types.ErrCodeEncrypted, "signing encrypted documents is not supported")
+	}
+	w, err := write.NewIncrementalWriter(pdfBytes, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start incremental update: %w", err)
+	}
+
+	u := &update{pdf: pdf, w: w, opts: opts}
+	sigObjNum := w.AddObject(u.signatureDictionary())
+	if err := u.addField(sigObjNum); err != nil {
+		return nil, err
+	}
+	out, err := w.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	if err := embedSignature(out, len(pdfBytes), opts); err != nil {
+		return nil, err
+	}
+	if opts.Verbose {
+		fmt.Printf("Signed field '%s' (signature object %d)\n", opts.FieldName, sigObjNum)
+	}
+	return out, nil
+}
+
+// embedSignature fills in the byte range of the signature dictionary appended
+// to signed after offset start, then signs the bytes it covers and writes the
+// signature into the /Contents placeholder
+func embedSignature(signed []byte, start int, opts Options) error {
+	idx := bytes.Index(signed[start:], []byte("/ByteRange "+byteRangePlaceholder))
+	if idx < 0 {
+		return fmt.Errorf("signature placeholder not found")
+	}
+	rangeStart := start + idx + len("/ByteRange ")
+	contentsStart := rangeStart + len(byteRangePlaceholder) + len(" /Contents ")
+	contentsEnd := contentsStart + 2*opts.Reserve + 2
+	if contentsEnd > len(signed) || signed[contentsStart] != '<' || signed[contentsEnd-1] != '>' {
+		return fmt.Errorf("signature placeholder not found")
+	}
+
+	byteRange := fmt.Sprintf("[0 %d %d %d", contentsStart, contentsEnd, len(signed)-contentsEnd)
+	byteRange += strings.Repeat(" ", len(byteRangePlaceholder)-len(byteRange)-1) + "]"
+	copy(signed[rangeStart:], byteRange)
+
+	h := opts.Hash.New()
+	h.Write(signed[:contentsStart])
+	h.Write(signed[contentsEnd:])
+	cms, err := buildCMS(h.Sum(nil), cmsOptions{
+		Signer:      opts.Signer,
+		Certificate: opts.Certificate,
+		Chain:       opts.Chain,
+		Hash:        opts.Hash,
+		CAdES:       opts.SubFilter == SubFilterCAdES,
+		Time:        opts.Time,
+	})
+	if err != nil {
+		return err
+	}
+	if len(cms) > opts.Reserve {
+		return fmt.Errorf("signature is %d bytes, more than the %d reserved for it", len(cms), opts.Reserve)
+	}
+	hex.Encode(signed[contentsStart+1:], cms)
+	return nil
+}
+
+// update builds the incremental update of a document being signed
+type update struct {
+	pdf  *parse.PDF
+	w    *write.IncrementalWriter
+	opts Options
+}
+
+// signatureDictionary returns the signature dictionary with placeholders for
+// its byte range and contents
+func (u *update) signatureDictionary() []byte {
+	var b strings.Builder
+	b.WriteString("<< /Type /Sig /Filter /Adobe.PPKLite /SubFilter /" + u.opts.SubFilter)
+	b.WriteString(" /ByteRange " + byteRangePlaceholder)
+	b.WriteString(" /Contents <" + strings.Repeat("0", 2*u.opts.Reserve) + ">")
+	b.WriteString(" /M " + textString(dates.Format(u.opts.Time)))
+	if u.opts.Certify != 0 {
+		fmt.Fprintf(&b, " /Reference [<< /Type /SigRef /TransformMethod /DocMDP /TransformParams << /Type /TransformParams /P %d /V /1.2 >> >>]", u.opts.Certify)
+	}
+	for _, entry := range []struct{ key, value string }{
+		{"/Name", u.opts.Name},
+		{"/Reason", u.opts.Reason},
+		{"/Location", u.opts.Location},
+		{"/ContactInfo", u.opts.ContactInfo},
+	} {
+		if entry.value != "" {
+			b.WriteString(" " + entry.key + " " + textString(entry.value))
+		}
+	}
+	b.WriteString(" >>")
+	return []byte(b.String())
+}
+
+// addField points the signature field named in the options at the signature
+// dictionary, adding the field, its widget and the AcroForm as needed
+func (u *update) addField(sigObjNum int) error {
+	rootObjNum, err := parseRef(u.pdf.Trailer().RootRef)
+	if err != nil {
+		return fmt.Errorf("invalid catalog reference: %w", err)
+	}
+	catalog, err := u.object(rootObjNum)
+	if err != nil {
+		return fmt.Errorf("failed to read catalog: %w", err)
+	}
+
+	// The AcroForm is edited where it is: in the catalog or in its own object
+	acroFormObjNum := 0
+	acroForm := dictValue(catalog, "/AcroForm")
+	if acroForm != "" && !strings.HasPrefix(acroForm, "<<") {
+		if acroFormObjNum, err = parseRef(acroForm); err != nil {
+			return fmt.Errorf("invalid /AcroForm: %w", err)
+		}
+		obj, err := u.object(acroFormObjNum)
+		if err != nil {
+			return fmt.Errorf("failed to read AcroForm: %w", err)
+		}
+		acroForm = obj
+	}
+	if acroForm == "" {
+		acroForm = "<< /Fields [] >>"
+	}
+
+	fieldObjNum, field, found := u.findField(dictValue(acroForm, "/Fields"), "")
+	if found {
+		if err := u.signExistingField(fieldObjNum, field, sigObjNum); err != nil {
+			return err
+		}
+	} else {
+		fieldObjNum, err = u.addWidget(sigObjNum)
+		if err != nil {
+			return err
+		}
+		fields, err := u.appendRef(dictValue(acroForm, "/Fields"), fieldObjNum)
+		if err != nil {
+			return err
+		}
+		acroForm = setDictValue(acroForm, "/Fields", fields)
+	}
+
+	// SignaturesExist and AppendOnly (PDF 32000-2 12.7.3)
+	flags, _ := strconv.Atoi(dictValue(acroForm, "/SigFlags"))
+	if u.opts.Certify != 0 && (flags&1 != 0 || dictValue(catalog, "/Perms") != "") {
+		return fmt.Errorf("a certification signature must be the document's first signature")
+	}
+	acroForm = setDictValue(acroForm, "/SigFlags", strconv.Itoa(flags|3))
+	catalogChanged := false
+	switch {
+	case acroFormObjNum != 0:
+		u.setObject(acroFormObjNum, []byte(acroForm))
+	case dictValue(catalog, "/AcroForm") != "":
+		catalog, catalogChanged = setDictValue(catalog, "/AcroForm", acroForm), true
+	default:
+		acroFormObjNum = u.w.AddObject([]byte(acroForm))
+		catalog, catalogChanged = setDictValue(catalog, "/AcroForm", fmt.Sprintf("%d 0 R", acroFormObjNum)), true
+	}
+	if u.opts.Certify != 0 {
+		catalog, catalogChanged = setDictValue(catalog, "/Perms", fmt.Sprintf("<< /DocMDP %d 0 R >>", sigObjNum)), true
+	}
+	if catalogChanged {
+		u.setObject(rootObjNum, []byte(catalog))
+	}
+	return nil
+}
+
+// findField looks for the field named in the options among a fields array and
+// their descendants, whose names are qualified by prefix
+func (u *update) findField(fields, prefix string) (int, string, bool) {
+	for _, objNum := range u.refs(fields) {
+		obj, err := u.object(objNum)
+		if err != nil {
+			continue
+		}
+		field := obj
+		name := pdfStringValue(dictValue(field, "/T"))
+		if name == "" {
+			continue
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		if name == u.opts.FieldName {
+			return objNum, field, true
+		}
+		if strings.HasPrefix(u.opts.FieldName, name+".") {
+			if objNum, field, ok := u.findField(dictValue(field, "/Kids"), name); ok {
+				return objNum, field, true
+			}
+		}
+	}
+	return 0, "", false
+}
+
+// signExistingField sets an unsigned signature field's value to the signature
+// dictionary and, if its widget is visible, draws the signature appearance in it
+func (u *update) signExistingField(fieldObjNum int, field string, sigObjNum int) error {
+	if dictValue(field, "/FT") != "/Sig" {
+		return fmt.Errorf("field '%s' is not a signature field", u.opts.FieldName)
+	}
+	if dictValue(field, "/V") != "" {
+		return fmt.Errorf("signature field '%s' is already signed", u.opts.FieldName)
+	}
+	field = setDictValue(field, "/V", fmt.Sprintf("%d 0 R", sigObjNum))
+
+	// The widget is the field itself or its first kid
+	widgetObjNum, widget := fieldObjNum, field
+	if kids := u.refs(dictValue(field, "/Kids")); len(kids) > 0 {
+		obj, err := u.object(kids[0])
+		if err != nil {
+			return fmt.Errorf("failed to read signature widget: %w", err)
+		}
+		widgetObjNum, widget = kids[0], obj
+	}
+	rect := parseNumbers(dictValue(widget, "/Rect"))
+	if len(rect) == 4 && rect[2] != rect[0] && rect[3] != rect[1] {
+		ap, err := u.addAppearance(abs(rect[2]-rect[0]), abs(rect[3]-rect[1]))
+		if err != nil {
+			return err
+		}
+		widget = setDictValue(widget, "/AP", fmt.Sprintf("<< /N %d 0 R >>", ap))
+	}
+
+	if widgetObjNum != fieldObjNum {
+		u.setObject(widgetObjNum, []byte(widget))
+	} else {
+		field = widget
+	}
+	u.setObject(fieldObjNum, []byte(field))
+	return nil
+}
+
+// addWidget adds a signature field merged with its widget to the page in the
+// options and returns its object number
+func (u *update) addWidget(sigObjNum int) (int, error) {
+	page, err := u.pdf.Page(u.opts.Page)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find page %d: %w", u.opts.Page, err)
+	}
+
+	r := u.opts.Rect
+	rect := fmt.Sprintf("[%s %s %s %s]", formatNumber(r.LowerX), formatNumber(r.LowerY), formatNumber(r.UpperX), formatNumber(r.UpperY))
+	widget := fmt.Sprintf("<< /Type /Annot /Subtype /Widget /FT /Sig /T %s /V %d 0 R /F 132 /Rect %s /P %d 0 R",
+		textString(u.opts.FieldName), sigObjNum, rect, page.ObjectNumber)
+	if width, height := abs(r.UpperX-r.LowerX), abs(r.UpperY-r.LowerY); width > 0 && height > 0 {
+		ap, err := u.addAppearance(width, height)
+		if err != nil {
+			return 0, err
+		}
+		widget += fmt.Sprintf(" /AP << /N %d 0 R >>", ap)
+	}
+	widgetObjNum := u.w.AddObject([]byte(widget + " >>"))
+
+	pageDict := objectBody(page.Dict)
+	annots := dictValue(pageDict, "/Annots")
+	if annots != "" && !strings.HasPrefix(annots, "[") {
+		// An indirect /Annots array is updated in its own object
+		annotsObjNum, err := parseRef(annots)
+		if err != nil {
+			return 0, fmt.Errorf("invalid /Annots on page %d: %w", u.opts.Page, err)
+		}
+		array, err := u.appendRef(annots, widgetObjNum)
+		if err != nil {
+			return 0, err
+		}
+		u.setObject(annotsObjNum, []byte(array))
+		return widgetObjNum, nil
+	}
+	array, err := u.appendRef(annots, widgetObjNum)
+	if err != nil {
+		return 0, err
+	}
+	u.setObject(page.ObjectNumber, []byte(setDictValue(pageDict, "/Annots", array)))
+	return widgetObjNum, nil
+}
+
+// addAppearance adds the appearance of a visible signature of the given size
+// to the update and returns the object number of its form XObject
+func (u *update) addAppearance(width, height float64) (int, error) {
+	a := u.opts.Appearance
+	a.Width, a.Height = width, height
+	if a.Name == "" {
+		a.Name = u.opts.Name
+	}
+	if a.Name == "" {
+		a.Name = u.opts.Certificate.Subject.CommonName
+	}
+	if a.Reason == "" {
+		a.Reason = u.opts.Reason
+	}
+	if a.Location == "" {
+		a.Location = u.opts.Location
+	}
+	if a.Date.IsZero() {
+		a.Date = u.opts.Time
+	}
+
+	// Number the writer's objects after the update's so they can be copied as they are
+	first := u.w.NextObjectNumber()
+	pw := write.NewPDFWriter()
+	pw.SetObject(first-1, nil)
+	ap, err := pw.AddSignatureAppearance(a)
+	if err != nil {
+		return 0, err
+	}
+	for objNum := first; objNum < pw.NextObjectNumber(); objNum++ {
+		body, err := pw.FormatObject(objNum)
+		if err != nil {
+			return 0, err
+		}
+		u.w.SetObject(objNum, 0, body)
+	}
+	return ap, nil
+}
+
+// appendRef returns an array, given inline, by reference or empty, with a
+// reference to objNum added
+func (u *update) appendRef(array string, objNum int) (string, error) {
+	if array != "" && !strings.HasPrefix(array, "[") {
+		arrayObjNum, err := parseRef(array)
+		if err != nil {
+			return "", fmt.Errorf("invalid array reference %q", array)
+		}
+		obj, err := u.object(arrayObjNum)
+		if err != nil {
+			return "", err
+		}
+		array = obj
+	}
+	items := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(array), "["), "]"))
+	if items == "" {
+		return fmt.Sprintf("[%d 0 R]", objNum), nil
+	}
+	return fmt.Sprintf("[%s %d 0 R]", items, objNum), nil
+}
+
+// refs returns the object numbers referenced by an array, given inline or by
+// reference
+func (u *update) refs(array string) []int {
+	if array != "" && !strings.HasPrefix(array, "[") {
+		objNum, err := parseRef(array)
+		if err != nil {
+			return nil
+		}
+		obj, err := u.object(objNum)
+		if err != nil {
+			return nil
+		}
+		array = obj
+	}
+	var objNums []int
+	for _, match := range refPattern.FindAllStringSubmatch(array, -1) {
+		if objNum, err := strconv.Atoi(match[1]); err == nil {
+			objNums = append(objNums, objNum)
+		}
+	}
+	return objNums
+}
+
+// object returns the body of an object of the document, without its header
+func (u *update) object(objNum int) (string, error) {
+	obj, err := u.pdf.GetObject(objNum)
+	if err != nil {
+		return "", err
+	}
+	return objectBody(string(obj)), nil
+}
+
+// setObject replaces an object of the document in the update, keeping its
+// generation
+func (u *update) setObject(objNum int, content []byte) {
+	generation := 0
+	if ref, ok := u.pdf.Ref(objNum); ok {
+		generation = ref.Generation
+	}
+	u.w.SetObject(objNum, generation, content)
+}
+
+// objectBody strips the "N G obj" header and "endobj" keyword from an object
+func objectBody(obj string) string {
+	if loc := objectHeaderPattern.FindStringIndex(obj); loc != nil {
+		obj = obj[loc[1]:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(obj), "endobj"))
+}
+
+// parseRef returns the object number of an indirect reference
+func parseRef(ref string) (int, error) {
+	match := refPattern.FindStringSubmatch(ref)
+	if match == nil {
+		return 0, fmt.Errorf("not a reference: %q", ref)
+	}
+	return strconv.Atoi(match[1])
+}
+
+// dictValue returns the raw value following the first occurrence of a key in
+// dict: a reference, an array, a nested dictionary, a string or a single token
+func dictValue(dict, key string) string {
+	idx := dictKeyIndex(dict, key)
+	if idx == -1 {
+		return ""
+	}
+	rest := strings.TrimLeft(dict[idx+len(key):], " \t\r\n")
+	switch {
+	case strings.HasPrefix(rest, "<<"):
+		depth := 0
+		for i := 0; i+1 < len(rest); i++ {
+			switch rest[i : i+2] {
+			case "<<":
+				depth++
+				i++
+			case ">>":
+				depth--
+				i++
+				if depth == 0 {
+					return rest[:i+1]
+				}
+			}
+		}
+		return ""
+	case strings.HasPrefix(rest, "["):
+		depth := 0
+		for i := 0; i < len(rest); i++ {
+			switch rest[i] {
+			case '[':
+				depth++
+			case ']':
+				depth--
+				if depth == 0 {
+					return rest[:i+1]
+				}
+			}
+		}
+		return ""
+	case strings.HasPrefix(rest, "("):
+		depth := 0
+		for i := 0; i < len(rest); i++ {
+			switch rest[i] {
+			case '\\':
+				i++
+			case '(':
+				depth++
+			case ')':
+				depth--
+				if depth == 0 {
+					return rest[:i+1]
+				}
+			}
+		}
+		return ""
+	case strings.HasPrefix(rest, "<"):
+		if end := strings.Index(rest, ">"); end != -1 {
+			return rest[:end+1]
+		}
+		return ""
+	}
+	if loc := refPattern.FindStringIndex(rest); loc != nil && loc[0] == 0 {
+		return rest[:loc[1]]
+	}
+	end := 0
+	if strings.HasPrefix(rest, "/") {
+		end = 1
+	}
+	for end < len(rest) && !strings.ContainsRune(" \t\r\n/<>[]()", rune(rest[end])) {
+		end++
+	}
+	return rest[:end]
+}
+
+// dictKeyIndex returns the index of key in dict where it is a whole name (so
+// "/Font" does not match "/FontFile"), or -1
+func dictKeyIndex(dict, key string) int {
+	for from := 0; ; {
+		rel := strings.Index(dict[from:], key)
+		if rel == -1 {
+			return -1
+		}
+		idx := from + rel
+		next := idx + len(key)
+		if next >= len(dict) || strings.ContainsRune(" \t\r\n/<[(", rune(dict[next])) {
+			return idx
+		}
+		from = next
+	}
+}
+
+// setDictValue replaces the raw value of key, or adds the key before the
+// closing ">>" if it is missing
+func setDictValue(dict, key, value string) string {
+	idx := dictKeyIndex(dict, key)
+	if idx == -1 {
+		end := strings.LastIndex(dict, ">>")
+		if end == -1 {
+			return dict
+		}
+		return strings.TrimRight(dict[:end], " \t\r\n") + " " + key + " " + value + " " + dict[end:]
+	}
+	old := dictValue(dict, key)
+	rest := strings.TrimLeft(dict[idx+len(key):], " \t\r\n")
+	start := len(dict) - len(rest)
+	return dict[:idx+len(key)] + " " + value + dict[start+len(old):]
+}
+
+// textString encodes s as a PDF text string: a literal string when it is
+// printable ASCII, and UTF-16BE with a byte order mark otherwise
+func textString(s string) string {
+	ascii := true
+	for _, r := range s {
+		if r < 0x20 || r > 0x7e {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return "(" + strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(s) + ")"
+	}
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, unit := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", unit)
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+// pdfStringValue decodes a literal or hex string that holds a field name
+func pdfStringValue(s string) string {
+	var raw []byte
+	switch {
+	case strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")"):
+		s = s[1 : len(s)-1]
+		for i := 0; i < len(s); i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+				switch s[i] {
+				case 'n':
+					raw = append(raw, '\n')
+				case 'r':
+					raw = append(raw, '\r')
+				case 't':
+					raw = append(raw, '\t')
+				default:
+					raw = append(raw, s[i])
+				}
+				continue
+			}
+			raw = append(raw, s[i])
+		}
+	case strings.HasPrefix(s, "<") && strings.HasSuffix(s, ">"):
+		decoded, err := hex.DecodeString(strings.Join(strings.Fields(s[1:len(s)-1]), ""))
+		if err != nil {
+			return ""
+		}
+		raw = decoded
+	default:
+		return ""
+	}
+	if len(raw) >= 2 && raw[0] == 0xfe && raw[1] == 0xff {
+		units := make([]uint16, 0, len(raw)/2)
+		for i := 2; i+1 < len(raw); i += 2 {
+			units = append(units, uint16(raw[i])<<8|uint16(raw[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	return string(raw)
+}
+
+// parseNumbers parses an array of numbers
+func parseNumbers(array string) []float64 {
+	var numbers []float64
+	for _, field := range strings.Fields(strings.Trim(array, "[]")) {
+		n, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers
+}
+
+// formatNumber formats a coordinate without trailing zeros
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+func abs(n float64) float64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
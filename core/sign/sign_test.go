@@ -0,0 +1,270 @@
+package sign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// testSigner returns a key and a self-signed certificate for it
+func testSigner(t *testing.T, key crypto.Signer) (crypto.Signer, *x509.Certificate) {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "Jane Doe", Organization: []string{"Example"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	return key, cert
+}
+
+func testPDF(t *testing.T) []byte {
+	t.Helper()
+	builder := write.NewSimplePDFBuilder()
+	page := builder.AddPage(write.PageSizeLetter)
+	font := page.AddStandardFont("Helvetica")
+	page.Content().BeginText().SetFont(font, 12).SetTextPosition(72, 720).ShowText("Agreement").EndText()
+	builder.FinalizePage(page)
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to build PDF: %v", err)
+	}
+	return pdfBytes
+}
+
+// signatures returns the signatures of a signed PDF, checking that it parses
+func signatures(t *testing.T, pdfBytes []byte) []types.Signature {
+	t.Helper()
+	pdf, err := parse.Open(pdfBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse signed PDF: %v", err)
+	}
+	sigs, err := extract.ExtractSignatures(pdfBytes, pdf, false)
+	if err != nil {
+		t.Fatalf("ExtractSignatures failed: %v", err)
+	}
+	return sigs
+}
+
+// verifyCMS checks the CMS signature in the /Contents of sig: its message
+// digest must be the digest of the bytes the byte range covers, and its
+// signature over the signed attributes must verify with cert
+func verifyCMS(t *testing.T, pdfBytes []byte, sig types.Signature, cert *x509.Certificate, algorithm x509.SignatureAlgorithm, hash crypto.Hash) {
+	t.Helper()
+	br := sig.ByteRange
+	// The zero padding after the signature is left over by Unmarshal
+	contents, err := hex.DecodeString(string(pdfBytes[br[1]+1 : br[2]-1]))
+	if err != nil {
+		t.Fatalf("Invalid /Contents: %v", err)
+	}
+
+	var ci struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue `asn1:"explicit,tag:0"`
+	}
+	if _, err := asn1.Unmarshal(contents, &ci); err != nil {
+		t.Fatalf("Invalid ContentInfo: %v", err)
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		t.Fatalf("Content type = %v, want signedData", ci.ContentType)
+	}
+	var sd struct {
+		Version          int
+		DigestAlgorithms asn1.RawValue
+		EncapContentInfo asn1.RawValue
+		Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+		SignerInfos      []struct {
+			Version            int
+			SID                asn1.RawValue
+			DigestAlgorithm    asn1.RawValue
+			SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+			SignatureAlgorithm asn1.RawValue
+			Signature          []byte
+		} `asn1:"set"`
+	}
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		t.Fatalf("Invalid SignedData: %v", err)
+	}
+	if len(sd.SignerInfos) != 1 {
+		t.Fatalf("Expected 1 SignerInfo, got %d", len(sd.SignerInfos))
+	}
+	if embedded, err := x509.ParseCertificates(sd.Certificates.Bytes); err != nil || len(embedded) == 0 || !embedded[0].Equal(cert) {
+		t.Errorf("SignedData should embed the signer's certificate (err %v)", err)
+	}
+	si := sd.SignerInfos[0]
+
+	signedAttrs := append([]byte{0x31}, si.SignedAttrs.FullBytes[1:]...)
+	var attrs []struct {
+		Type   asn1.ObjectIdentifier
+		Values asn1.RawValue `asn1:"set"`
+	}
+	if _, err := asn1.UnmarshalWithParams(signedAttrs, &attrs, "set"); err != nil {
+		t.Fatalf("Invalid signed attributes: %v", err)
+	}
+	h := hash.New()
+	h.Write(pdfBytes[:br[1]])
+	h.Write(pdfBytes[br[2] : br[2]+br[3]])
+	found := false
+	for _, attr := range attrs {
+		if attr.Type.Equal(oidMessageDigest) {
+			var digest []byte
+			if _, err := asn1.Unmarshal(attr.Values.Bytes, &digest); err != nil || !bytes.Equal(digest, h.Sum(nil)) {
+				t.Errorf("Message digest does not match the signed bytes (err %v)", err)
+			}
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Signed attributes should include the message digest")
+	}
+
+	if err := cert.CheckSignature(algorithm, signedAttrs, si.Signature); err != nil {
+		t.Errorf("Signature does not verify: %v", err)
+	}
+}
+
+func TestSign(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, cert := testSigner(t, ecKey)
+	original := testPDF(t)
+
+	signed, err := Sign(original, Options{
+		Signer:      signer,
+		Certificate: cert,
+		Rect:        types.Rectangle{LowerX: 72, LowerY: 600, UpperX: 272, UpperY: 660},
+		Name:        "Jane Doe",
+		Reason:      "Approved",
+		Location:    "Zürich",
+	})
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	if !bytes.HasPrefix(signed, original) {
+		t.Error("Signing should append to the original file")
+	}
+	for _, want := range []string{"/SubFilter /ETSI.CAdES.detached", "/Name (Jane Doe)", "/SigFlags 3", "/FT /Sig", "/AP << /N "} {
+		if !bytes.Contains(signed, []byte(want)) {
+			t.Errorf("Signed PDF missing %q", want)
+		}
+	}
+
+	sigs := signatures(t, signed)
+	if len(sigs) != 1 {
+		t.Fatalf("Expected 1 signature, got %+v", sigs)
+	}
+	sig := sigs[0]
+	if sig.FieldName != DefaultFieldName || sig.Signer != "Jane Doe" || sig.Location != "Zürich" {
+		t.Errorf("Unexpected signature details: %+v", sig)
+	}
+	if !sig.Intact || !sig.CoversDocument {
+		t.Errorf("Signature should cover the whole file: %+v", sig)
+	}
+	verifyCMS(t, signed, sig, cert, x509.ECDSAWithSHA256, crypto.SHA256)
+
+	// A second, invisible PKCS#7 signature leaves the first one intact
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaSigner, rsaCert := testSigner(t, rsaKey)
+	twice, err := Sign(signed, Options{
+		Signer:      rsaSigner,
+		Certificate: rsaCert,
+		FieldName:   "Countersignature",
+		SubFilter:   SubFilterPKCS7,
+		Hash:        crypto.SHA384,
+	})
+	if err != nil {
+		t.Fatalf("Second Sign failed: %v", err)
+	}
+	sigs = signatures(t, twice)
+	if len(sigs) != 2 {
+		t.Fatalf("Expected 2 signatures, got %+v", sigs)
+	}
+	if !sigs[0].Intact || sigs[0].CoversDocument || sigs[0].SignedHash != sig.SignedHash {
+		t.Errorf("First signature should still cover its revision: %+v", sigs[0])
+	}
+	if sigs[1].FieldName != "Countersignature" || !sigs[1].Intact || !sigs[1].CoversDocument {
+		t.Errorf("Second signature should cover the whole file: %+v", sigs[1])
+	}
+	verifyCMS(t, twice, sigs[1], rsaCert, x509.SHA384WithRSA, crypto.SHA384)
+}
+
+func TestSign_ExistingField(t *testing.T) {
+	w := write.NewPDFWriter()
+	for _, obj := range []string{
+		"<< /Type /Catalog /Pages 2 0 R /AcroForm << /Fields [4 0 R] >> >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Annots [4 0 R] >>",
+		"<< /Type /Annot /Subtype /Widget /FT /Sig /T (Approval) /Rect [72 100 272 150] /P 3 0 R >>",
+	} {
+		w.AddObject([]byte(obj))
+	}
+	w.SetRoot(1)
+	original, err := w.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to generate PDF: %v", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, cert := testSigner(t, key)
+	opts := Options{Signer: signer, Certificate: cert, FieldName: "Approval", Hash: crypto.SHA384}
+	signed, err := Sign(original, opts)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	sigs := signatures(t, signed)
+	if len(sigs) != 1 || sigs[0].FieldName != "Approval" || !sigs[0].CoversDocument {
+		t.Fatalf("Expected the existing field to be signed, got %+v", sigs)
+	}
+	verifyCMS(t, signed, sigs[0], cert, x509.ECDSAWithSHA384, crypto.SHA384)
+
+	pdf, _ := parse.Open(signed)
+	widget, err := pdf.GetObject(4)
+	if err != nil || !bytes.Contains(widget, []byte("/AP << /N ")) {
+		t.Errorf("The field's widget should get an appearance: %s", widget)
+	}
+
+	if _, err := Sign(signed, opts); err == nil || !strings.Contains(err.Error(), "already signed") {
+		t.Errorf("Expected an error signing a signed field, got %v", err)
+	}
+	opts.Reserve = 64
+	opts.FieldName = ""
+	if _, err := Sign(original, opts); err == nil {
+		t.Error("Expected an error when the signature does not fit the reserved space")
+	}
+	if _, err := Sign(original, Options{}); err == nil {
+		t.Error("Expected an error without a signer")
+	}
+}
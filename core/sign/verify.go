@@ -0,0 +1,643 @@
+package sign
+
+import (
+	"bytes"
+	"crypto"
+	_ "crypto/sha1" // SHA-1, for adbe.pkcs7.sha1 signatures
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/core/dates"
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// Kinds of Modification, from the least to the most restrictive
+const (
+	ChangeValidationData = "validation-data" // The document security store (/DSS) that keeps revocation data
+	ChangeSignature      = "signature"       // Signature fields added or signed
+	ChangeFormFill       = "form-fill"       // Field values, widget appearances and the AcroForm
+	ChangeAnnotation     = "annotation"      // Annotations other than widgets added, changed or removed
+	ChangeOther          = "other"           // Page content, resources or document structure
+)
+
+// changeLevels is the lowest DocMDP permission level that permits each kind of
+// change; other changes are never permitted
+var changeLevels = map[string]int{
+	ChangeValidationData: 1,
+	ChangeSignature:      2,
+	ChangeFormFill:       2,
+	ChangeAnnotation:     3,
+	ChangeOther:          4,
+}
+
+// Object identifiers only used to verify signatures
+var (
+	oidSHA1         = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidRSAPSS       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 10}
+	oidECPublicKey  = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidECDSAWithSHA = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 1}
+	oidEd25519      = asn1.ObjectIdentifier{1, 3, 101, 112}
+
+	// rsaSignatureOIDs name both the key type and the digest algorithm
+	rsaSignatureOIDs = map[crypto.Hash]asn1.ObjectIdentifier{
+		crypto.SHA1:   {1, 2, 840, 113549, 1, 1, 5},
+		crypto.SHA256: {1, 2, 840, 113549, 1, 1, 11},
+		crypto.SHA384: {1, 2, 840, 113549, 1, 1, 12},
+		crypto.SHA512: {1, 2, 840, 113549, 1, 1, 13},
+	}
+	rsaAlgorithms = map[crypto.Hash]x509.SignatureAlgorithm{
+		crypto.SHA1:   x509.SHA1WithRSA,
+		crypto.SHA256: x509.SHA256WithRSA,
+		crypto.SHA384: x509.SHA384WithRSA,
+		crypto.SHA512: x509.SHA512WithRSA,
+	}
+	pssAlgorithms = map[crypto.Hash]x509.SignatureAlgorithm{
+		crypto.SHA256: x509.SHA256WithRSAPSS,
+		crypto.SHA384: x509.SHA384WithRSAPSS,
+		crypto.SHA512: x509.SHA512WithRSAPSS,
+	}
+	ecdsaAlgorithms = map[crypto.Hash]x509.SignatureAlgorithm{
+		crypto.SHA1:   x509.ECDSAWithSHA1,
+		crypto.SHA256: x509.ECDSAWithSHA256,
+		crypto.SHA384: x509.ECDSAWithSHA384,
+		crypto.SHA512: x509.ECDSAWithSHA512,
+	}
+)
+
+// VerifyOptions controls Verify
+type VerifyOptions struct {
+	// Roots are the trusted root certificates; nil uses the system roots
+	Roots *x509.CertPool
+	// Intermediates are intermediate certificates besides those embedded in
+	// the signatures
+	Intermediates []*x509.Certificate
+	// Time is when certificates must have been valid. The zero time uses each
+	// signature's signing time, as claimed by the signer, or else the current
+	// time.
+	Time    time.Time
+	Verbose bool
+}
+
+// SignatureReport is the result of verifying one signature
+type SignatureReport struct {
+	types.Signature
+	Valid          bool      `json:"valid"`               // All of the checks below pass
+	DigestValid    bool      `json:"digest_valid"`        // The signed digest is the digest of the bytes the byte range covers
+	SignatureValid bool      `json:"signature_valid"`     // The CMS signature verifies with the signer's certificate
+	ChainValid     bool      `json:"chain_valid"`         // The signer's certificate chains to a trusted root
+	Subject        string    `json:"subject,omitempty"`   // Subject of the signer's certificate
+	Issuer         string    `json:"issuer,omitempty"`    // Issuer of the signer's certificate
+	SignedAt       time.Time `json:"signed_at,omitempty"` // Signing time from the CMS signature or timestamp, else from /M
+	Revision       int       `json:"revision"`            // The 1-based revision the signature covers
+	LaterRevisions int       `json:"later_revisions"`     // Incremental updates saved after signing
+	// Modifications lists the objects of the signed revision that later
+	// updates changed
+	Modifications []Modification `json:"modifications,omitempty"`
+	// ModificationsAllowed is set when the later updates only make changes the
+	// signature permits: what a certification signature's DocMDP permissions
+	// allow, or form filling, signing and annotating after approval signatures
+	ModificationsAllowed bool     `json:"modifications_allowed"`
+	Problems             []string `json:"problems,omitempty"` // Why checks failed
+
+	Certificate *x509.Certificate   `json:"-"` // The signer's certificate
+	Chain       []*x509.Certificate `json:"-"` // The verified chain, from the signer's certificate to a root
+}
+
+// Modification is an object of a signed revision that a later update changed
+type Modification struct {
+	Object int    `json:"object"`
+	Kind   string `json:"kind"` // One of the Change kinds
+}
+
+// contentInfo is a CMS ContentInfo (RFC 5652 3)
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+// signedData is a CMS SignedData (RFC 5652 5.1)
+type signedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	EncapContentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     []byte `asn1:"explicit,optional,tag:0"`
+	}
+	Certificates asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs         asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos  []signerInfo  `asn1:"set"`
+}
+
+// signerInfo is a CMS SignerInfo (RFC 5652 5.3)
+type signerInfo struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+}
+
+// attribute is a CMS Attribute (RFC 5652 5.3)
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue `asn1:"set"`
+}
+
+// tstInfo is the start of a timestamp token's TSTInfo (RFC 3161 2.4.2)
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint struct {
+		HashAlgorithm pkix.AlgorithmIdentifier
+		HashedMessage []byte
+	}
+	SerialNumber *big.Int
+	GenTime      time.Time `asn1:"generalized"`
+}
+
+// Verify checks every signature of a PDF (PDF 32000-2 12.8.1) and reports on
+// each, in field tree order. A signature is valid when its byte range covers
+// the file except its /Contents, the CMS signature in /Contents verifies over
+// those bytes with the signer's certificate, the certificate chains to one of
+// opts.Roots, and the incremental updates saved after signing only make
+// changes the signature permits. Detached CMS signatures (adbe.pkcs7.detached
+// and ETSI.CAdES.detached), adbe.pkcs7.sha1 signatures and document timestamps
+// (ETSI.RFC3161) with RSA, ECDSA or Ed25519 keys are verified; revocation is
+// not checked.
+func Verify(pdfBytes []byte, opts VerifyOptions) ([]SignatureReport, error) {
+	pdf, err := parse.OpenWithOptions(pdfBytes, parse.ParseOptions{Verbose: opts.Verbose})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PDF: %w", err)
+	}
+	sigs, err := extract.ExtractSignatures(pdfBytes, pdf, opts.Verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	boundaries := parse.GetRevisionBoundaries(pdfBytes)
+	modifications := make(map[int][]Modification)
+	reports := make([]SignatureReport, len(sigs))
+	for i, sig := range sigs {
+		r := &reports[i]
+		r.Signature = sig
+		r.verifySignature(pdfBytes, opts)
+		if !r.Intact {
+			continue
+		}
+		end := signedEnd(sig)
+		for _, boundary := range boundaries {
+			if boundary <= end {
+				r.Revision++
+			} else {
+				r.LaterRevisions++
+			}
+		}
+
+		// Changes are judged by the strictest certification signed before or
+		// with this signature
+		level := changeLevels[ChangeAnnotation]
+		for _, other := range sigs {
+			if other.DocMDP != 0 && other.DocMDP < level && len(other.ByteRange) == 4 && signedEnd(other) <= end {
+				level = other.DocMDP
+			}
+		}
+		r.ModificationsAllowed = true
+		if sig.CoversDocument {
+			continue
+		}
+		mods, ok := modifications[end]
+		if !ok {
+			if mods, err = modifiedObjects(pdf, pdfBytes[:end], opts.Verbose); err != nil {
+				r.problem("failed to read the signed revision: %v", err)
+				r.ModificationsAllowed = false
+			}
+			modifications[end] = mods
+		}
+		r.Modifications = mods
+		for _, mod := range mods {
+			if changeLevels[mod.Kind] > level {
+				r.ModificationsAllowed = false
+			}
+		}
+		if !r.ModificationsAllowed && len(mods) > 0 {
+			r.problem("the document was changed after signing in ways the signature does not permit")
+		}
+	}
+	for i := range reports {
+		r := &reports[i]
+		r.Valid = r.Intact && r.DigestValid && r.SignatureValid && r.ChainValid && r.ModificationsAllowed
+		if opts.Verbose {
+			fmt.Printf("Signature '%s': valid %v\n", r.FieldName, r.Valid)
+		}
+	}
+	return reports, nil
+}
+
+// signedEnd returns the offset just past the last byte a signature covers
+func signedEnd(sig types.Signature) int {
+	return sig.ByteRange[2] + sig.ByteRange[3]
+}
+
+// problem records why a check failed
+func (r *SignatureReport) problem(format string, args ...interface{}) {
+	r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+}
+
+// verifySignature checks the CMS signature in /Contents against the bytes the
+// byte range covers, and the signer's certificate chain
+func (r *SignatureReport) verifySignature(pdfBytes []byte, opts VerifyOptions) {
+	if t, err := dates.Parse(r.SigningTime); err == nil {
+		r.SignedAt = t
+	}
+	if !r.Intact {
+		r.problem("the byte range does not cover the whole file except the signature")
+		return
+	}
+	br := r.ByteRange
+	contents, err := hex.DecodeString(strings.Join(strings.Fields(string(pdfBytes[br[1]+1:br[2]-1])), ""))
+	if err != nil {
+		r.problem("invalid /Contents: %v", err)
+		return
+	}
+
+	// /Contents is padded with zeros after the DER-encoded signature
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(contents, &ci); err != nil || !ci.ContentType.Equal(oidSignedData) {
+		r.problem("/Contents does not hold a CMS SignedData")
+		return
+	}
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		r.problem("invalid CMS SignedData: %v", err)
+		return
+	}
+	if len(sd.SignerInfos) != 1 {
+		r.problem("expected one signer, found %d", len(sd.SignerInfos))
+		return
+	}
+	si := sd.SignerInfos[0]
+	certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+	if err != nil {
+		r.problem("invalid certificate: %v", err)
+		return
+	}
+	cert := signerCertificate(si.SID, certs)
+	if cert == nil {
+		r.problem("the signer's certificate is not embedded in the signature")
+		return
+	}
+	r.Certificate, r.Subject, r.Issuer = cert, cert.Subject.String(), cert.Issuer.String()
+
+	hash := digestHash(si.DigestAlgorithm.Algorithm)
+	if hash == 0 || !hash.Available() {
+		r.problem("unsupported digest algorithm %v", si.DigestAlgorithm.Algorithm)
+		return
+	}
+	rangeDigest := digest(hash, pdfBytes, br)
+
+	// Detached signatures sign the bytes in the byte range. The others
+	// encapsulate what they sign: the SHA-1 digest of those bytes, or a
+	// timestamp of them.
+	contentValid := true
+	encapsulated := sd.EncapContentInfo.Content
+	switch {
+	case r.SubFilter == "adbe.pkcs7.sha1":
+		contentValid = bytes.Equal(encapsulated, digest(crypto.SHA1, pdfBytes, br))
+	case r.SubFilter == "ETSI.RFC3161":
+		var tst tstInfo
+		contentValid = false
+		if _, err := asn1.Unmarshal(encapsulated, &tst); err == nil {
+			if imprintHash := digestHash(tst.MessageImprint.HashAlgorithm.Algorithm); imprintHash != 0 && imprintHash.Available() {
+				contentValid = bytes.Equal(tst.MessageImprint.HashedMessage, digest(imprintHash, pdfBytes, br))
+			}
+			r.SignedAt = tst.GenTime
+		}
+	case encapsulated != nil:
+		contentValid = false
+	}
+	if !contentValid {
+		r.problem("the signed content does not match the bytes the byte range covers")
+	}
+
+	var signed []byte
+	if len(si.SignedAttrs.FullBytes) > 0 {
+		// The signature is over the DER encoding of the signed attributes as
+		// a SET, whose message digest is the digest of the content
+		signed = append([]byte{tagSet}, si.SignedAttrs.FullBytes[1:]...)
+		var attrs []attribute
+		if _, err := asn1.UnmarshalWithParams(signed, &attrs, "set"); err != nil {
+			r.problem("invalid signed attributes: %v", err)
+			return
+		}
+		want := rangeDigest
+		if encapsulated != nil {
+			h := hash.New()
+			h.Write(encapsulated)
+			want = h.Sum(nil)
+		}
+		for _, attr := range attrs {
+			switch {
+			case attr.Type.Equal(oidMessageDigest):
+				var md []byte
+				if _, err := asn1.Unmarshal(attr.Values.Bytes, &md); err == nil && bytes.Equal(md, want) {
+					r.DigestValid = contentValid
+				}
+			case attr.Type.Equal(oidSigningTime) && r.SubFilter != "ETSI.RFC3161":
+				var t time.Time
+				if _, err := asn1.Unmarshal(attr.Values.Bytes, &t); err == nil {
+					r.SignedAt = t
+				}
+			}
+		}
+		if !r.DigestValid && contentValid {
+			r.problem("the message digest does not match the signed content")
+		}
+	} else if encapsulated != nil {
+		signed = encapsulated
+		r.DigestValid = contentValid
+	} else {
+		signed = make([]byte, 0, br[1]+br[3])
+		signed = append(append(signed, pdfBytes[:br[1]]...), pdfBytes[br[2]:br[2]+br[3]]...)
+	}
+
+	algorithm := verifyAlgorithm(si.SignatureAlgorithm.Algorithm, hash)
+	if err := cert.CheckSignature(algorithm, signed, si.Signature); err != nil {
+		r.problem("the signature does not verify: %v", err)
+	} else {
+		r.SignatureValid = true
+		if len(si.SignedAttrs.FullBytes) == 0 && encapsulated == nil {
+			// The signature is over the covered bytes themselves
+			r.DigestValid = true
+		}
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range append(certs, opts.Intermediates...) {
+		if c != cert {
+			intermediates.AddCert(c)
+		}
+	}
+	at := opts.Time
+	if at.IsZero() {
+		at = r.SignedAt
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+	chains, err := cert.Verify(x509.VerifyOptions{
+		Roots:         opts.Roots,
+		Intermediates: intermediates,
+		CurrentTime:   at,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		r.problem("the signer's certificate is not trusted: %v", err)
+		return
+	}
+	r.ChainValid, r.Chain = true, chains[0]
+}
+
+// digest hashes the bytes a byte range covers
+func digest(hash crypto.Hash, pdfBytes []byte, br []int) []byte {
+	h := hash.New()
+	h.Write(pdfBytes[:br[1]])
+	h.Write(pdfBytes[br[2] : br[2]+br[3]])
+	return h.Sum(nil)
+}
+
+// digestHash returns the hash of a digest algorithm, or 0 if it is not known
+func digestHash(oid asn1.ObjectIdentifier) crypto.Hash {
+	if oid.Equal(oidSHA1) {
+		return crypto.SHA1
+	}
+	for hash, digestOID := range digestOIDs {
+		if oid.Equal(digestOID) {
+			return hash
+		}
+	}
+	return 0
+}
+
+// verifyAlgorithm returns the x509 algorithm of a SignerInfo's signature
+// algorithm, which names either the key type alone or also the digest
+func verifyAlgorithm(oid asn1.ObjectIdentifier, hash crypto.Hash) x509.SignatureAlgorithm {
+	switch {
+	case oid.Equal(oidRSAEncryption):
+		return rsaAlgorithms[hash]
+	case oid.Equal(oidRSAPSS):
+		return pssAlgorithms[hash]
+	case oid.Equal(oidECPublicKey):
+		return ecdsaAlgorithms[hash]
+	case oid.Equal(oidECDSAWithSHA):
+		return x509.ECDSAWithSHA1
+	case oid.Equal(oidEd25519):
+		return x509.PureEd25519
+	}
+	for h, rsaOID := range rsaSignatureOIDs {
+		if oid.Equal(rsaOID) {
+			return rsaAlgorithms[h]
+		}
+	}
+	for h, ecdsaOID := range ecdsaOIDs {
+		if oid.Equal(ecdsaOID) {
+			return ecdsaAlgorithms[h]
+		}
+	}
+	return x509.UnknownSignatureAlgorithm
+}
+
+// signerCertificate finds the certificate a SignerInfo's SID identifies: by
+// issuer and serial number, or by subject key identifier
+func signerCertificate(sid asn1.RawValue, certs []*x509.Certificate) *x509.Certificate {
+	if sid.Class == asn1.ClassContextSpecific && sid.Tag == 0 {
+		for _, cert := range certs {
+			if bytes.Equal(cert.SubjectKeyId, sid.Bytes) {
+				return cert
+			}
+		}
+		return nil
+	}
+	var ias struct {
+		Issuer       asn1.RawValue
+		SerialNumber *big.Int
+	}
+	if _, err := asn1.Unmarshal(sid.FullBytes, &ias); err != nil {
+		return nil
+	}
+	for _, cert := range certs {
+		if bytes.Equal(cert.RawIssuer, ias.Issuer.FullBytes) && cert.SerialNumber.Cmp(ias.SerialNumber) == 0 {
+			return cert
+		}
+	}
+	return nil
+}
+
+// modifiedObjects compares the objects of a signed revision with their
+// current versions and classifies the changes
+func modifiedObjects(pdf *parse.PDF, signedRevision []byte, verbose bool) ([]Modification, error) {
+	old, err := parse.OpenWithOptions(signedRevision, parse.ParseOptions{Verbose: verbose})
+	if err != nil {
+		return nil, err
+	}
+	var mods []Modification
+	for _, objNum := range old.Objects() {
+		before, err := old.GetObject(objNum)
+		if err != nil {
+			continue
+		}
+		after, err := pdf.GetObject(objNum)
+		if err != nil || pdf.IsFree(objNum) {
+			mods = append(mods, Modification{Object: objNum, Kind: ChangeOther})
+			continue
+		}
+		b, a := objectBody(string(before)), objectBody(string(after))
+		if b != a {
+			mods = append(mods, Modification{Object: objNum, Kind: changeKind(pdf, b, a)})
+		}
+	}
+	return mods, nil
+}
+
+// changeKind classifies the change of an object from before to after
+func changeKind(pdf *parse.PDF, before, after string) string {
+	if strings.HasPrefix(after, "[") {
+		// An array of annotations or fields
+		return addedKind(pdf, before, after)
+	}
+	if kind := objectKind(after); kind != "" {
+		return kind
+	}
+	changed := changedKeys(before, after)
+	switch dictValue(after, "/Type") {
+	case "/Catalog":
+		kind := ""
+		for _, key := range changed {
+			switch key {
+			case "/DSS":
+				kind = strictest(kind, ChangeValidationData)
+			case "/AcroForm":
+				kind = strictest(kind, ChangeFormFill)
+			default:
+				return ChangeOther
+			}
+		}
+		if kind != "" {
+			return kind
+		}
+	case "/Page":
+		if len(changed) == 1 && changed[0] == "/Annots" {
+			return addedKind(pdf, dictValue(before, "/Annots"), dictValue(after, "/Annots"))
+		}
+	}
+	return ChangeOther
+}
+
+// objectKind classifies a field, widget, annotation or AcroForm dictionary,
+// and returns "" for other objects
+func objectKind(obj string) string {
+	if !strings.HasPrefix(obj, "<<") {
+		return ""
+	}
+	switch {
+	case dictValue(obj, "/FT") == "/Sig":
+		return ChangeSignature
+	case dictValue(obj, "/FT") != "", dictValue(obj, "/Subtype") == "/Widget", dictValue(obj, "/Fields") != "":
+		return ChangeFormFill
+	case dictValue(obj, "/Subtype") != "" && dictValue(obj, "/Rect") != "":
+		return ChangeAnnotation
+	case dictValue(obj, "/T") != "" && dictValue(obj, "/Kids") != "":
+		return ChangeFormFill
+	}
+	return ""
+}
+
+// addedKind classifies a change to an array of references by the objects
+// added to or removed from it
+func addedKind(pdf *parse.PDF, before, after string) string {
+	counts := make(map[string]int)
+	for _, match := range refPattern.FindAllString(before, -1) {
+		counts[match]--
+	}
+	for _, match := range refPattern.FindAllString(after, -1) {
+		counts[match]++
+	}
+	kind := ""
+	for ref, n := range counts {
+		if n == 0 {
+			continue
+		}
+		objNum, _ := parseRef(ref)
+		obj, err := pdf.GetObject(objNum)
+		if err != nil {
+			return ChangeOther
+		}
+		objKind := objectKind(objectBody(string(obj)))
+		if objKind == "" {
+			return ChangeOther
+		}
+		kind = strictest(kind, objKind)
+	}
+	if kind == "" {
+		return ChangeOther
+	}
+	return kind
+}
+
+// strictest returns the kind of change that fewer signatures permit
+func strictest(a, b string) string {
+	if a == "" || changeLevels[b] > changeLevels[a] {
+		return b
+	}
+	return a
+}
+
+// changedKeys returns the keys whose values differ between two dictionaries
+func changedKeys(before, after string) []string {
+	normalize := func(value string) string { return strings.Join(strings.Fields(value), " ") }
+	var changed []string
+	seen := make(map[string]bool)
+	for _, key := range append(dictKeys(before), dictKeys(after)...) {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if normalize(dictValue(before, key)) != normalize(dictValue(after, key)) {
+			changed = append(changed, key)
+		}
+	}
+	return changed
+}
+
+// dictKeys returns the keys at the top level of a dictionary, in order
+func dictKeys(dict string) []string {
+	dict = strings.TrimSpace(dict)
+	if !strings.HasPrefix(dict, "<<") {
+		return nil
+	}
+	var keys []string
+	rest := strings.TrimLeft(dict[2:], " \t\r\n")
+	for strings.HasPrefix(rest, "/") {
+		end := 1
+		for end < len(rest) && !strings.ContainsRune(" \t\r\n/<>[]()", rune(rest[end])) {
+			end++
+		}
+		key := rest[:end]
+		value := dictValue(rest, key)
+		if value == "" {
+			break
+		}
+		keys = append(keys, key)
+		rest = strings.TrimLeft(rest[end:], " \t\r\n")
+		rest = strings.TrimLeft(rest[len(value):], " \t\r\n")
+	}
+	return keys
+}
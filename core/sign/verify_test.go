@@ -0,0 +1,168 @@
+package sign
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/core/write"
+)
+
+// testKey returns a new ECDSA key
+func testKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// verify verifies a PDF's signatures against roots
+func verify(t *testing.T, pdfBytes []byte, roots ...*x509.Certificate) []SignatureReport {
+	t.Helper()
+	pool := x509.NewCertPool()
+	for _, root := range roots {
+		pool.AddCert(root)
+	}
+	reports, err := Verify(pdfBytes, VerifyOptions{Roots: pool})
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	return reports
+}
+
+func TestVerify(t *testing.T) {
+	signer, cert := testSigner(t, testKey(t))
+	signed, err := Sign(testPDF(t), Options{Signer: signer, Certificate: cert, Name: "Jane Doe"})
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+
+	reports := verify(t, signed, cert)
+	if len(reports) != 1 {
+		t.Fatalf("Expected 1 report, got %+v", reports)
+	}
+	r := reports[0]
+	if !r.Valid || !r.DigestValid || !r.SignatureValid || !r.ChainValid || !r.ModificationsAllowed {
+		t.Errorf("Expected a valid signature: %+v", r)
+	}
+	if r.Revision != 2 || r.LaterRevisions != 0 || len(r.Modifications) != 0 {
+		t.Errorf("Signature should cover the second and last revision: %+v", r)
+	}
+	if !strings.Contains(r.Subject, "Jane Doe") || r.SignedAt.IsZero() || !r.Certificate.Equal(cert) || len(r.Chain) != 1 {
+		t.Errorf("Unexpected signer details: %+v", r)
+	}
+
+	// An untrusted certificate fails only the chain
+	r = verify(t, signed)[0]
+	if r.Valid || r.ChainValid || !r.SignatureValid || !r.DigestValid || len(r.Problems) != 1 {
+		t.Errorf("Expected an untrusted but intact signature: %+v", r)
+	}
+
+	// Changing a signed byte breaks the digest
+	r = verify(t, bytes.Replace(signed, []byte("(Jane Doe)"), []byte("(Jane Dot)"), 1), cert)[0]
+	if r.Valid || r.DigestValid {
+		t.Errorf("Expected the digest of tampered bytes to fail: %+v", r)
+	}
+
+	// A second signature is a permitted change
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaSigner, rsaCert := testSigner(t, rsaKey)
+	twice, err := Sign(signed, Options{Signer: rsaSigner, Certificate: rsaCert, FieldName: "Countersignature", SubFilter: SubFilterPKCS7})
+	if err != nil {
+		t.Fatalf("Second Sign failed: %v", err)
+	}
+	reports = verify(t, twice, cert, rsaCert)
+	if len(reports) != 2 || !reports[0].Valid || !reports[1].Valid {
+		t.Fatalf("Expected two valid signatures: %+v", reports)
+	}
+	if reports[0].LaterRevisions != 1 || len(reports[0].Modifications) == 0 {
+		t.Errorf("First signature should see the second as a later update: %+v", reports[0])
+	}
+	for _, mod := range reports[0].Modifications {
+		if mod.Kind != ChangeSignature && mod.Kind != ChangeFormFill {
+			t.Errorf("Unexpected modification %+v", mod)
+		}
+	}
+
+	// Rewriting the page's content is not
+	pdf, err := parse.Open(twice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := pdf.Page(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents, err := parseRef(dictValue(objectBody(string(page.Dict)), "/Contents"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := write.NewIncrementalWriter(twice, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.SetStreamObject(contents, 0, write.Dictionary{}, []byte("BT /F1 12 Tf 72 720 Td (Void) Tj ET"), false)
+	edited, err := w.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range verify(t, edited, cert, rsaCert) {
+		if r.Valid || r.ModificationsAllowed || !r.SignatureValid || r.LaterRevisions != 2-i {
+			t.Errorf("Signature %d should report the forbidden change: %+v", i+1, r)
+		}
+		found := false
+		for _, mod := range r.Modifications {
+			found = found || (mod.Object == contents && mod.Kind == ChangeOther)
+		}
+		if !found {
+			t.Errorf("Signature %d should list the content stream, got %+v", i+1, r.Modifications)
+		}
+	}
+}
+
+func TestVerify_Certification(t *testing.T) {
+	signer, cert := testSigner(t, testKey(t))
+	approver, approverCert := testSigner(t, testKey(t))
+
+	for _, tc := range []struct {
+		permissions int
+		allowed     bool
+	}{
+		{1, false},
+		{2, true},
+	} {
+		certified, err := Sign(testPDF(t), Options{Signer: signer, Certificate: cert, Certify: tc.permissions})
+		if err != nil {
+			t.Fatalf("Certify failed: %v", err)
+		}
+		if !bytes.Contains(certified, []byte("/Perms << /DocMDP ")) {
+			t.Error("Certified document should have DocMDP permissions")
+		}
+		approved, err := Sign(certified, Options{Signer: approver, Certificate: approverCert, FieldName: "Approval"})
+		if err != nil {
+			t.Fatalf("Approval failed: %v", err)
+		}
+		reports := verify(t, approved, cert, approverCert)
+		if len(reports) != 2 || reports[0].DocMDP != tc.permissions || reports[1].DocMDP != 0 {
+			t.Fatalf("Unexpected reports: %+v", reports)
+		}
+		if reports[0].ModificationsAllowed != tc.allowed || reports[0].Valid != tc.allowed || !reports[1].Valid {
+			t.Errorf("P=%d: signing after certification should be allowed: %v, got %+v", tc.permissions, tc.allowed, reports)
+		}
+
+		if _, err := Sign(approved, Options{Signer: signer, Certificate: cert, FieldName: "Again", Certify: 2}); err == nil {
+			t.Error("Expected an error certifying a signed document")
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package write
+
+import (
+	"fmt"
+)
+
+// asset is a named XObject shared by the pages of a SimplePDFBuilder document
+type asset struct {
+	objNum        int
+	width, height float64 // image pixels, or the Form XObject bounding box
+	form          bool
+	draw          func(*PageBuilder) // draws a Form XObject asset on first use
+}
+
+// AddImageAsset embeds an image (JPEG, PNG, ...) once as a named asset that any
+// page can draw with DrawAsset or reference with UseAsset
+func (b *SimplePDFBuilder) AddImageAsset(name string, data []byte) error {
+	if err := b.checkAssetName(name); err != nil {
+		return err
+	}
+	info, err := b.writer.AddImage(data, "")
+	if err != nil {
+		return fmt.Errorf("asset %q: %w", name, err)
+	}
+	b.assets[name] = &asset{objNum: info.ObjectNum, width: float64(info.Width), height: float64(info.Height)}
+	return nil
+}
+
+// DefineAsset defines a named vector asset, such as a logo or a signature line,
+// of the given size in points. draw renders it on a PageBuilder of that size the
+// first time a page uses it; it is written once as a Form XObject.
+func (b *SimplePDFBuilder) DefineAsset(name string, width, height float64, draw func(asset *PageBuilder)) error {
+	if err := b.checkAssetName(name); err != nil {
+		return err
+	}
+	if draw == nil {
+		return fmt.Errorf("asset %q has no draw function", name)
+	}
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("asset %q has invalid size %gx%g", name, width, height)
+	}
+	b.assets[name] = &asset{width: width, height: height, form: true, draw: draw}
+	return nil
+}
+
+// checkAssetName validates the name of a new asset
+func (b *SimplePDFBuilder) checkAssetName(name string) error {
+	if name == "" {
+		return fmt.Errorf("asset name is empty")
+	}
+	if _, exists := b.assets[name]; exists {
+		return fmt.Errorf("asset %q already defined", name)
+	}
+	return nil
+}
+
+// UseAsset adds a named asset to the page resources and returns its resource
+// name (e.g., "/A1") for the Do operator
+func (pb *PageBuilder) UseAsset(name string) (string, error) {
+	if pb.builder == nil {
+		return "", fmt.Errorf("assets require a page created by SimplePDFBuilder.AddPage")
+	}
+	a, ok := pb.builder.assets[name]
+	if !ok {
+		return "", fmt.Errorf("asset %q not defined", name)
+	}
+	if resourceName, ok := pb.assets[name]; ok {
+		return "/" + resourceName, nil
+	}
+
+	if a.form && a.objNum == 0 {
+		a.objNum = pb.builder.formXObject(PageSize{a.width, a.height}, a.draw)
+	}
+	if pb.assets == nil {
+		pb.assets = make(map[string]string)
+	}
+	resourceName := fmt.Sprintf("A%d", len(pb.assets)+1)
+	pb.images[resourceName] = a.objNum
+	pb.assets[name] = resourceName
+	return "/" + resourceName, nil
+}
+
+// DrawAsset draws a named asset with its lower-left corner at (x, y), scaled to
+// width x height points
+func (pb *PageBuilder) DrawAsset(name string, x, y, width, height float64) error {
+	resourceName, err := pb.UseAsset(name)
+	if err != nil {
+		return err
+	}
+	a := pb.builder.assets[name]
+	if !a.form {
+		pb.content.DrawImageAt(resourceName, x, y, width, height)
+		return nil
+	}
+
+	// Form XObjects are drawn in their own units, scaled from their bounding box
+	pb.content.SaveState()
+	pb.content.SetMatrix(width/a.width, 0, 0, height/a.height, x, y)
+	pb.content.DrawImage(resourceName)
+	pb.content.RestoreState()
+	return nil
+}
@@ -0,0 +1,107 @@
+package write
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// testImage encodes a small gradient image as JPEG or PNG
+func testImage(t *testing.T, format string) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for x := 0; x < 4; x++ {
+		img.Set(x, 0, color.RGBA{uint8(x * 60), 0, 0, 255})
+		img.Set(x, 1, color.RGBA{0, uint8(x * 60), 0, 255})
+	}
+	var buf bytes.Buffer
+	var err error
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, img, nil)
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		t.Fatalf("Failed to encode %s: %v", format, err)
+	}
+	return buf.Bytes()
+}
+
+func TestPDFWriter_ImageDeduplication(t *testing.T) {
+	w := NewPDFWriter()
+	for _, format := range []string{"jpeg", "png"} {
+		data := testImage(t, format)
+		first, err := w.AddImage(data, "Im1")
+		if err != nil {
+			t.Fatalf("AddImage(%s) failed: %v", format, err)
+		}
+		next := w.NextObjectNumber()
+		second, err := w.AddImage(data, "Logo")
+		if err != nil {
+			t.Fatalf("AddImage(%s) failed: %v", format, err)
+		}
+		if second.ObjectNum != first.ObjectNum || w.NextObjectNumber() != next {
+			t.Errorf("%s image embedded twice: objects %d and %d", format, first.ObjectNum, second.ObjectNum)
+		}
+		if second.Name != "Logo" || first.Name != "Im1" {
+			t.Errorf("Resource names not kept: %q, %q", first.Name, second.Name)
+		}
+	}
+}
+
+func TestSimplePDFBuilder_Assets(t *testing.T) {
+	b := NewSimplePDFBuilder()
+	if err := b.AddImageAsset("photo", testImage(t, "jpeg")); err != nil {
+		t.Fatalf("AddImageAsset failed: %v", err)
+	}
+	if err := b.AddImageAsset("photo", testImage(t, "png")); err == nil {
+		t.Error("Expected error redefining an asset")
+	}
+	draws := 0
+	err := b.DefineAsset("box", 50, 20, func(a *PageBuilder) {
+		draws++
+		a.Content().Rectangle(0, 0, 50, 20).Stroke()
+	})
+	if err != nil {
+		t.Fatalf("DefineAsset failed: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		page := b.AddPage(PageSizeLetter)
+		if err := page.DrawAsset("photo", 72, 600, 40, 20); err != nil {
+			t.Fatalf("DrawAsset failed: %v", err)
+		}
+		page.DrawAsset("box", 72, 500, 100, 20)
+		page.DrawAsset("box", 72, 400, 50, 20)
+		b.FinalizePage(page)
+	}
+	if _, err := b.AddPage(PageSizeLetter).UseAsset("missing"); err == nil {
+		t.Error("Expected error for an undefined asset")
+	}
+
+	pdfBytes, err := b.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to generate PDF: %v", err)
+	}
+	if draws != 1 {
+		t.Errorf("Expected the vector asset drawn once, got %d", draws)
+	}
+	if n := bytes.Count(pdfBytes, []byte("/Subtype /Image")); n != 1 {
+		t.Errorf("Expected 1 image stream, got %d", n)
+	}
+	if n := bytes.Count(pdfBytes, []byte("/Subtype /Form")); n != 1 {
+		t.Errorf("Expected 1 Form XObject, got %d", n)
+	}
+
+	content, _ := b.Writer().GetObject(b.pageBuilders[0].contentObjNum)
+	got := decompressStream(t, content)
+	for _, want := range []string{"/A1 Do", "2.0000 0.0000 0.0000 1.0000 72.0000 500.0000 cm\n/A2 Do", "1.0000 0.0000 0.0000 1.0000 72.0000 400.0000 cm\n/A2 Do"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Page content missing %q:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package write
+
+import (
+	"bytes"
+	"compress/zlib"
+	"regexp"
+)
+
+// StreamKind is the kind of content a stream holds, for CompressionPolicy
+type StreamKind int
+
+const (
+	StreamData     StreamKind = iota // Anything not covered below, e.g. embedded files
+	StreamContent                    // Page, form and appearance content, object streams and CMaps
+	StreamImage                      // Image XObjects and soft masks
+	StreamFont                       // Embedded font programs
+	StreamMetadata                   // XMP metadata
+)
+
+var (
+	imageSubtypePattern = regexp.MustCompile(`/Subtype\s*/Image\b`)
+	fontFilePattern     = regexp.MustCompile(`/(Length1|Length2|Length3)\b|/Subtype\s*/(Type1C|CIDFontType0C|OpenType)\b`)
+	metadataPattern     = regexp.MustCompile(`/Type\s*/Metadata\b`)
+	contentPattern      = regexp.MustCompile(`/Subtype\s*/Form\b|/Type\s*/(ObjStm|XRef|CMap)\b`)
+	otherTypePattern    = regexp.MustCompile(`/Type\s*/\w|/Subtype\s*/\w`)
+	filterPattern       = regexp.MustCompile(`/Filter\b`)
+)
+
+// ClassifyStream returns the kind of a stream from the raw text of its dictionary.
+// Streams without a /Type or /Subtype, such as page content, are StreamContent.
+func ClassifyStream(dict []byte) StreamKind {
+	switch {
+	case imageSubtypePattern.Match(dict):
+		return StreamImage
+	case fontFilePattern.Match(dict):
+		return StreamFont
+	case metadataPattern.Match(dict):
+		return StreamMetadata
+	case contentPattern.Match(dict), !otherTypePattern.Match(dict):
+		return StreamContent
+	}
+	return StreamData
+}
+
+// CompressionPolicy decides how the writer compresses stream data. The zero
+// value compresses every stream with zlib's default level.
+//
+// Streams that already have a /Filter, such as DCT (JPEG) and JPX images, are
+// never recompressed.
+type CompressionPolicy struct {
+	Level      int                 // Flate level, 1 (fastest) to 9 (smallest); 0 uses zlib's default
+	Levels     map[StreamKind]int  // Flate levels by stream kind, overriding Level
+	Store      map[StreamKind]bool // Stream kinds written without compression
+	MinSavings float64             // Store data raw unless Flate shrinks it by at least this fraction, e.g. 0.05
+}
+
+// BestCompression is a policy for the smallest output: the highest level for
+// every stream, storing data that doesn't compress, such as zip files, raw
+func BestCompression() CompressionPolicy {
+	return CompressionPolicy{Level: zlib.BestCompression, MinSavings: 0.01}
+}
+
+// SetCompression sets the policy for streams the writer compresses: those added
+// with AddStreamObject or SetStreamObject with compress set, object streams and
+// cross-reference streams
+func (w *PDFWriter) SetCompression(policy CompressionPolicy) {
+	w.compression = policy
+}
+
+// level returns the Flate level for a kind of stream
+func (p CompressionPolicy) level(kind StreamKind) int {
+	level, ok := p.Levels[kind]
+	if !ok {
+		level = p.Level
+	}
+	if level <= 0 || level > zlib.BestCompression {
+		return zlib.DefaultCompression
+	}
+	return level
+}
+
+// Compress applies the policy to the data of a stream with the given raw
+// dictionary. It returns the Flate-compressed data and true, or the data
+// unchanged and false when the stream should be stored as it is.
+func (p CompressionPolicy) Compress(dict, data []byte) ([]byte, bool) {
+	if len(data) == 0 || filterPattern.Match(dict) {
+		return data, false
+	}
+	kind := ClassifyStream(dict)
+	if p.Store[kind] {
+		return data, false
+	}
+	compressed := p.Flate(kind, data)
+	if p.MinSavings > 0 && float64(len(compressed)) > float64(len(data))*(1-p.MinSavings) {
+		return data, false
+	}
+	return compressed, true
+}
+
+// Flate compresses data at the policy's level for a kind of stream
+func (p CompressionPolicy) Flate(kind StreamKind, data []byte) []byte {
+	var buf bytes.Buffer
+	zw, _ := zlib.NewWriterLevel(&buf, p.level(kind))
+	zw.Write(data)
+	zw.Close()
+	return buf.Bytes()
+}
+
+// compressStream compresses the data of a stream object being added, setting
+// its /Filter when the policy compresses it
+func (w *PDFWriter) compressStream(dict Dictionary, data []byte) []byte {
+	compressed, ok := w.compression.Compress(w.formatDictionary(dict), data)
+	if !ok {
+		return data
+	}
+	dict["Filter"] = "/FlateDecode"
+	return compressed
+}
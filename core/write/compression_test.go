@@ -0,0 +1,110 @@
+package write
+
+import (
+	"bytes"
+	"compress/zlib"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestClassifyStream(t *testing.T) {
+	tests := []struct {
+		dict string
+		want StreamKind
+	}{
+		{"<</Length 10>>", StreamContent},
+		{"<</Type/XObject/Subtype/Form/BBox [0 0 10 10]>>", StreamContent},
+		{"<</Type /ObjStm /N 3 /First 12>>", StreamContent},
+		{"<</Type/XObject/Subtype/Image/Width 2/Height 2>>", StreamImage},
+		{"<</Length1 1200>>", StreamFont},
+		{"<</Subtype/Type1C>>", StreamFont},
+		{"<</Type/Metadata/Subtype/XML>>", StreamMetadata},
+		{"<</Type/EmbeddedFile/Subtype/application#2Fzip>>", StreamData},
+	}
+	for _, tt := range tests {
+		if got := ClassifyStream([]byte(tt.dict)); got != tt.want {
+			t.Errorf("ClassifyStream(%s) = %d, want %d", tt.dict, got, tt.want)
+		}
+	}
+}
+
+func TestCompressionPolicy_Compress(t *testing.T) {
+	text := []byte(strings.Repeat("BT /F1 12 Tf 72 700 Td (Hello, world) Tj ET\n", 200))
+	noise := make([]byte, 4096)
+	rand.New(rand.NewSource(1)).Read(noise)
+
+	var policy CompressionPolicy
+	if _, ok := policy.Compress([]byte("<</Filter/DCTDecode/Subtype/Image>>"), noise); ok {
+		t.Error("Stream with a filter recompressed")
+	}
+	if _, ok := policy.Compress([]byte("<<>>"), noise); !ok {
+		t.Error("Zero policy should compress everything")
+	}
+
+	policy = CompressionPolicy{MinSavings: 0.05, Store: map[StreamKind]bool{StreamMetadata: true}}
+	if _, ok := policy.Compress([]byte("<<>>"), noise); ok {
+		t.Error("Incompressible data compressed despite MinSavings")
+	}
+	if _, ok := policy.Compress([]byte("<</Type/Metadata>>"), text); ok {
+		t.Error("Stored kind compressed")
+	}
+	compressed, ok := policy.Compress([]byte("<<>>"), text)
+	if !ok {
+		t.Fatal("Compressible text stored")
+	}
+	r, err := zlib.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("Output is not zlib data: %v", err)
+	}
+	var decoded bytes.Buffer
+	decoded.ReadFrom(r)
+	if !bytes.Equal(decoded.Bytes(), text) {
+		t.Error("Compressed data does not round-trip")
+	}
+
+	// Data from a small alphabet compresses better at higher levels
+	letters := make([]byte, 65536)
+	rng := rand.New(rand.NewSource(2))
+	for i := range letters {
+		letters[i] = 'a' + byte(rng.Intn(4))
+	}
+	fast := CompressionPolicy{Level: 1, Levels: map[StreamKind]int{StreamImage: 9}}
+	if len(fast.Flate(StreamContent, letters)) <= len(fast.Flate(StreamImage, letters)) {
+		t.Error("Expected per-kind level 9 to compress better than level 1")
+	}
+}
+
+func TestPDFWriter_SetCompression(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	img.Set(0, 0, color.Black)
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, img); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+
+	w := NewPDFWriter()
+	w.SetCompression(CompressionPolicy{Store: map[StreamKind]bool{StreamImage: true}})
+	info, err := w.AddImage(pngData.Bytes(), "Im1")
+	if err != nil {
+		t.Fatalf("AddImage failed: %v", err)
+	}
+	content := w.AddStreamObject(Dictionary{"/Length": 0}, []byte("q Q"), true)
+
+	if _, ok := w.objects[info.ObjectNum].Dict["Filter"]; ok {
+		t.Error("Stored image has a filter")
+	}
+	obj := w.objects[content]
+	if obj.Dict["Filter"] != "/FlateDecode" {
+		t.Error("Content stream not compressed")
+	}
+	if _, ok := obj.Dict["/Length"]; ok {
+		t.Error("Caller's /Length left next to the computed Length")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"crypto/sha256"
 	"fmt"
 
+	pdfencrypt "github.com/benedoc-inc/pdfer/core/encrypt"
 	"github.com/benedoc-inc/pdfer/types"
 )
 
@@ -220,7 +221,8 @@ func wrapKeyV5(key, wrappingKey []byte) ([]byte, error) {
 	return wrapped, nil
 }
 
-// CreateEncryptionDictionary creates the encryption dictionary object content for V5
+// CreateEncryptionDictionary creates the encryption dictionary object content
+// for the Standard security handler (RC4 V2, AES-128 V4 or AES-256 V5)
 func CreateEncryptionDictionary(encrypt *types.PDFEncryption) []byte {
 	var buf bytes.Buffer
 	buf.WriteString("<<\n")
@@ -262,6 +264,25 @@ func CreateEncryptionDictionary(encrypt *types.PDFEncryption) []byte {
 		buf.WriteString(">\n")
 	}
 
+	// V4 and V5 name their AES crypt filter
+	if encrypt.V >= 4 {
+		cfm := "AESV2"
+		if encrypt.V >= 5 {
+			cfm = "AESV3"
+		}
+		// Documents encrypting only embedded files ask for the password when an attachment is opened
+		authEvent := "DocOpen"
+		if pdfencrypt.AttachmentsOnly(encrypt) {
+			authEvent = "EFOpen"
+		}
+		buf.WriteString(fmt.Sprintf("/CF <</StdCF <</CFM /%s /AuthEvent /%s /Length %d>>>>\n", cfm, authEvent, encrypt.KeyLength))
+		buf.WriteString(fmt.Sprintf("/StmF /%s\n", cryptFilterName(encrypt.StmF)))
+		buf.WriteString(fmt.Sprintf("/StrF /%s\n", cryptFilterName(encrypt.StrF)))
+		if encrypt.EFF != "" {
+			buf.WriteString(fmt.Sprintf("/EFF /%s\n", cryptFilterName(encrypt.EFF)))
+		}
+	}
+
 	if !encrypt.EncryptMetadata {
 		buf.WriteString("/EncryptMetadata false\n")
 	}
@@ -269,3 +290,12 @@ func CreateEncryptionDictionary(encrypt *types.PDFEncryption) []byte {
 	buf.WriteString(">>")
 	return buf.Bytes()
 }
+
+// cryptFilterName returns the crypt filter written for a /StmF, /StrF or /EFF
+// setting: Identity, or the StdCF filter defined in /CF
+func cryptFilterName(name string) string {
+	if name == pdfencrypt.IdentityFilter {
+		return name
+	}
+	return "StdCF"
+}
@@ -0,0 +1,105 @@
+package write
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+var (
+	extensionPrefixPattern = regexp.MustCompile(`^[A-Za-z0-9_.\-]+$`)
+	versionPattern         = regexp.MustCompile(`^\d+\.\d+$`)
+)
+
+// FormatExtensions formats developer extensions as an /Extensions dictionary.
+// Several extensions with the same prefix are written as an array (PDF 2.0).
+func FormatExtensions(extensions []types.Extension) (string, error) {
+	byPrefix := make(map[string][]string)
+	for _, ext := range extensions {
+		prefix := strings.TrimPrefix(ext.Prefix, "/")
+		if !extensionPrefixPattern.MatchString(prefix) {
+			return "", fmt.Errorf("invalid extension prefix %q", ext.Prefix)
+		}
+		if !versionPattern.MatchString(ext.BaseVersion) {
+			return "", fmt.Errorf("extension %s: invalid base version %q", prefix, ext.BaseVersion)
+		}
+		if ext.ExtensionLevel < 0 {
+			return "", fmt.Errorf("extension %s: negative extension level %d", prefix, ext.ExtensionLevel)
+		}
+
+		entry := fmt.Sprintf("<< /Type /DeveloperExtensions /BaseVersion /%s /ExtensionLevel %d", ext.BaseVersion, ext.ExtensionLevel)
+		if ext.URL != "" {
+			entry += fmt.Sprintf(" /URL (%s)", escapePDFString(ext.URL))
+		}
+		if ext.Revision != "" {
+			entry += fmt.Sprintf(" /ExtensionRevision (%s)", escapePDFString(ext.Revision))
+		}
+		byPrefix[prefix] = append(byPrefix[prefix], entry+" >>")
+	}
+
+	prefixes := make([]string, 0, len(byPrefix))
+	for prefix := range byPrefix {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	var b strings.Builder
+	b.WriteString("<<")
+	for _, prefix := range prefixes {
+		entries := byPrefix[prefix]
+		if len(entries) == 1 {
+			fmt.Fprintf(&b, " /%s %s", prefix, entries[0])
+		} else {
+			fmt.Fprintf(&b, " /%s [%s]", prefix, strings.Join(entries, " "))
+		}
+	}
+	b.WriteString(" >>")
+	return b.String(), nil
+}
+
+// FormatRequirements formats reader requirements as a /Requirements array
+func FormatRequirements(requirements []types.Requirement) (string, error) {
+	entries := make([]string, 0, len(requirements))
+	for _, req := range requirements {
+		if !extensionPrefixPattern.MatchString(req.Type) {
+			return "", fmt.Errorf("invalid requirement type %q", req.Type)
+		}
+		if req.Penalty < 0 || req.Penalty > 100 {
+			return "", fmt.Errorf("requirement %s: penalty %d outside 0-100", req.Type, req.Penalty)
+		}
+		entry := fmt.Sprintf("<< /Type /Requirement /S /%s", req.Type)
+		if req.Version != "" {
+			if !versionPattern.MatchString(req.Version) {
+				return "", fmt.Errorf("requirement %s: invalid version %q", req.Type, req.Version)
+			}
+			entry += " /V /" + req.Version
+		}
+		entries = append(entries, fmt.Sprintf("%s /Penalty %d >>", entry, req.Penalty))
+	}
+	return "[" + strings.Join(entries, " ") + "]", nil
+}
+
+// SetExtensions declares the developer extensions the document uses in the
+// catalog's /Extensions dictionary
+func (b *SimplePDFBuilder) SetExtensions(extensions []types.Extension) error {
+	value, err := FormatExtensions(extensions)
+	if err != nil {
+		return err
+	}
+	b.SetCatalogEntry("/Extensions", value)
+	return nil
+}
+
+// SetRequirements declares the features a reader must support to process the
+// document in the catalog's /Requirements array
+func (b *SimplePDFBuilder) SetRequirements(requirements []types.Requirement) error {
+	value, err := FormatRequirements(requirements)
+	if err != nil {
+		return err
+	}
+	b.SetCatalogEntry("/Requirements", value)
+	return nil
+}
@@ -0,0 +1,55 @@
+package write
+
+import (
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+func TestFormatExtensions(t *testing.T) {
+	got, err := FormatExtensions([]types.Extension{
+		{Prefix: "XYZ", BaseVersion: "2.0", ExtensionLevel: 1, URL: "https://example.com/ext(1)"},
+		{Prefix: "ADBE", BaseVersion: "1.7", ExtensionLevel: 3},
+		{Prefix: "XYZ", BaseVersion: "2.0", ExtensionLevel: 2, Revision: "r2"},
+	})
+	if err != nil {
+		t.Fatalf("FormatExtensions failed: %v", err)
+	}
+	want := "<< /ADBE << /Type /DeveloperExtensions /BaseVersion /1.7 /ExtensionLevel 3 >>" +
+		" /XYZ [<< /Type /DeveloperExtensions /BaseVersion /2.0 /ExtensionLevel 1 /URL (https://example.com/ext\\(1\\)) >>" +
+		" << /Type /DeveloperExtensions /BaseVersion /2.0 /ExtensionLevel 2 /ExtensionRevision (r2) >>] >>"
+	if got != want {
+		t.Errorf("Got\n%s\nwant\n%s", got, want)
+	}
+
+	for _, ext := range []types.Extension{
+		{Prefix: "A B", BaseVersion: "1.7"},
+		{Prefix: "ADBE", BaseVersion: "seven"},
+		{Prefix: "ADBE", BaseVersion: "1.7", ExtensionLevel: -1},
+	} {
+		if _, err := FormatExtensions([]types.Extension{ext}); err == nil {
+			t.Errorf("Expected an error for %+v", ext)
+		}
+	}
+}
+
+func TestFormatRequirements(t *testing.T) {
+	got, err := FormatRequirements([]types.Requirement{
+		{Type: "DigSig", Version: "2.0", Penalty: 100},
+		{Type: "EnableJavaScripts", Penalty: 0},
+	})
+	if err != nil {
+		t.Fatalf("FormatRequirements failed: %v", err)
+	}
+	want := "[<< /Type /Requirement /S /DigSig /V /2.0 /Penalty 100 >> << /Type /Requirement /S /EnableJavaScripts /Penalty 0 >>]"
+	if got != want {
+		t.Errorf("Got\n%s\nwant\n%s", got, want)
+	}
+
+	if _, err := FormatRequirements([]types.Requirement{{Type: "DigSig", Penalty: 101}}); err == nil {
+		t.Error("Expected an error for a penalty above 100")
+	}
+	if _, err := FormatRequirements([]types.Requirement{{Type: ""}}); err == nil {
+		t.Error("Expected an error for a missing type")
+	}
+}
@@ -0,0 +1,111 @@
+package write
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/benedoc-inc/pdfer/resources/font"
+)
+
+// colorGlyphPixelsPerPoint sets the bitmap strike requested for color glyphs
+// relative to the font size, about 288 dpi
+const colorGlyphPixelsPerPoint = 4
+
+// drawFallbackText shows text run by run with the font the fallback chain picks for
+// each character
+func (pb *PageBuilder) drawFallbackText(size, x, y float64, text string, options *TextOptions) {
+	cx := x
+	for _, run := range options.Fallback.Segment(text) {
+		if run.Font == nil {
+			continue
+		}
+		if options.ColorGlyphs && run.Font.HasColorGlyphs() {
+			cx = pb.drawColorGlyphs(run.Font, size, cx, y, run.Text)
+			continue
+		}
+		// Fonts the writer may not embed leave blank space for their characters
+		if err := pb.writer.checkFontLicense(run.Font); err != nil {
+			if w, err := run.Font.TextWidth(run.Text, size); err == nil {
+				cx += w
+			}
+			continue
+		}
+
+		for _, r := range run.Text {
+			run.Font.AddRune(r)
+		}
+		pb.content.
+			BeginText().
+			SetFont(pb.fallbackFontName(run.Font), size).
+			SetTextPosition(cx, y).
+			ShowTextHex(run.Font.GlyphHex(run.Text)).
+			EndText()
+
+		if w, err := run.Font.TextWidth(run.Text, size); err == nil {
+			cx += w
+		}
+	}
+}
+
+// fallbackFontName returns the resource name of a fallback font, registering it
+// for embedding when the page is built
+func (pb *PageBuilder) fallbackFontName(f *font.Font) string {
+	for i, existing := range pb.fallbackFonts {
+		if existing == f {
+			return fmt.Sprintf("/FB%d", i+1)
+		}
+	}
+	pb.fallbackFonts = append(pb.fallbackFonts, f)
+	return fmt.Sprintf("/FB%d", len(pb.fallbackFonts))
+}
+
+// drawColorGlyphs draws each character as its color bitmap, scaled to the glyph's
+// advance and the font's ascent and descent, and returns the new x position.
+// Characters without a bitmap (joiners, variation selectors) only advance.
+func (pb *PageBuilder) drawColorGlyphs(f *font.Font, size, x, y float64, text string) float64 {
+	ttf, err := font.ParseTTF(f.Data)
+	if err != nil || ttf.UnitsPerEm == 0 {
+		return x
+	}
+	scale := size / float64(ttf.UnitsPerEm)
+	bottom := y + float64(ttf.Descent)*scale
+	height := float64(ttf.Ascent-ttf.Descent) * scale
+	ppem := int(math.Ceil(size * colorGlyphPixelsPerPoint))
+
+	for _, r := range text {
+		width, err := f.TextWidth(string(r), size)
+		if err != nil {
+			continue
+		}
+		if name := pb.colorGlyphImage(f, r, ppem); name != "" && width > 0 {
+			pb.content.DrawImageAt(name, x, bottom, width, height)
+		}
+		x += width
+	}
+	return x
+}
+
+// colorGlyphImage embeds the bitmap of a color glyph once per page and returns its
+// resource name, or "" when the font has no bitmap for r
+func (pb *PageBuilder) colorGlyphImage(f *font.Font, r rune, ppem int) string {
+	gid, _ := f.GlyphIndex(r)
+	key := fmt.Sprintf("%p/%d", f, gid)
+	if name, ok := pb.glyphImages[key]; ok {
+		return name
+	}
+
+	png, ok := f.ColorGlyph(r, ppem)
+	if !ok {
+		return ""
+	}
+	if pb.glyphImages == nil {
+		pb.glyphImages = make(map[string]string)
+	}
+	info, err := pb.writer.AddImage(png, fmt.Sprintf("CG%d", len(pb.glyphImages)+1))
+	if err != nil {
+		return ""
+	}
+	name := pb.AddImage(info)
+	pb.glyphImages[key] = name
+	return name
+}
@@ -0,0 +1,80 @@
+package write
+
+import (
+	"fmt"
+
+	"github.com/benedoc-inc/pdfer/resources/font"
+)
+
+// FontLicensePolicy decides what the writer does with fonts whose licence
+// (the OS/2 fsType flags) forbids embedding them
+type FontLicensePolicy int
+
+const (
+	FontLicenseWarn   FontLicensePolicy = iota // Embed the font anyway and record a warning (default)
+	FontLicenseRefuse                          // Don't embed the font: AddFont fails and fallback text skips it
+)
+
+// FontEmbedding records the writer's decision about embedding a font
+type FontEmbedding struct {
+	Font       string         // Font name
+	Permission font.Embedding // Embedding permission from the font's OS/2 table
+	Embedded   bool           // Whether the font was embedded
+	Warning    string         // Why the licence forbids embedding, empty if it permits it
+}
+
+// FontLicenseError is returned by AddFont when the policy is FontLicenseRefuse
+// and the font's licence forbids embedding it
+type FontLicenseError struct {
+	Font       string
+	Permission font.Embedding
+}
+
+func (e *FontLicenseError) Error() string {
+	return fmt.Sprintf("font %q may not be embedded (%s licence)", e.Font, e.Permission)
+}
+
+// SetFontLicensePolicy sets what the writer does with fonts whose licence
+// forbids embedding. Either way, the decision is recorded in FontEmbeddings.
+func (w *PDFWriter) SetFontLicensePolicy(policy FontLicensePolicy) {
+	w.fontLicensePolicy = policy
+}
+
+// FontEmbeddings returns the decisions about the fonts the writer was asked to
+// embed so far, one per font, in the order they were first added
+func (w *PDFWriter) FontEmbeddings() []FontEmbedding {
+	embeddings := make([]FontEmbedding, len(w.fontEmbeddings))
+	copy(embeddings, w.fontEmbeddings)
+	return embeddings
+}
+
+// checkFontLicense decides whether a font may be embedded under the writer's
+// policy and records the decision, once per font
+func (w *PDFWriter) checkFontLicense(f *font.Font) error {
+	if i, ok := w.fontDecisions[f]; ok {
+		if e := w.fontEmbeddings[i]; !e.Embedded {
+			return &FontLicenseError{Font: e.Font, Permission: e.Permission}
+		}
+		return nil
+	}
+
+	permission, err := f.Embedding()
+	if err != nil {
+		return err
+	}
+	decision := FontEmbedding{Font: f.Name, Permission: permission, Embedded: true}
+	if !permission.Embeddable() {
+		decision.Warning = fmt.Sprintf("licence of font %q forbids embedding (%s)", f.Name, permission)
+		decision.Embedded = w.fontLicensePolicy != FontLicenseRefuse
+	}
+	if w.fontDecisions == nil {
+		w.fontDecisions = make(map[*font.Font]int)
+	}
+	w.fontDecisions[f] = len(w.fontEmbeddings)
+	w.fontEmbeddings = append(w.fontEmbeddings, decision)
+
+	if !decision.Embedded {
+		return &FontLicenseError{Font: f.Name, Permission: permission}
+	}
+	return nil
+}
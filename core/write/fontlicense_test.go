@@ -0,0 +1,73 @@
+package write
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/resources/font"
+)
+
+// restrictedFont returns the test font with its licence set to restricted
+// embedding
+func restrictedFont(t *testing.T) *font.Font {
+	data, err := os.ReadFile(filepath.Join("..", "..", "tests", "resources", "test_font.ttf"))
+	if err != nil {
+		t.Skipf("test font not available: %v", err)
+	}
+	ttf, err := font.ParseTTF(data)
+	if err != nil {
+		t.Fatalf("ParseTTF failed: %v", err)
+	}
+	os2, ok := ttf.Tables["OS/2"]
+	if !ok {
+		t.Skip("test font has no OS/2 table")
+	}
+	binary.BigEndian.PutUint16(data[os2.Offset+8:], uint16(font.EmbeddingRestricted))
+	f, err := font.NewFont("Restricted", data)
+	if err != nil {
+		t.Fatalf("NewFont failed: %v", err)
+	}
+	return f
+}
+
+func TestFontLicensePolicy(t *testing.T) {
+	// By default the font is embedded with a warning
+	f := restrictedFont(t)
+	f.AddString("Hi")
+	w := NewPDFWriter()
+	if _, err := w.AddFont(f); err != nil {
+		t.Fatalf("AddFont failed: %v", err)
+	}
+	embeddings := w.FontEmbeddings()
+	if len(embeddings) != 1 || !embeddings[0].Embedded || embeddings[0].Warning == "" ||
+		embeddings[0].Permission != font.EmbeddingRestricted {
+		t.Errorf("Unexpected font embeddings %+v", embeddings)
+	}
+
+	// Refused fonts fail AddFont and are left out of fallback text
+	builder := NewSimplePDFBuilder()
+	builder.Writer().SetFontLicensePolicy(FontLicenseRefuse)
+	var licenseErr *FontLicenseError
+	if _, err := builder.Writer().AddFont(f); !errors.As(err, &licenseErr) || licenseErr.Font != "Restricted" {
+		t.Errorf("AddFont = %v, want a *FontLicenseError", err)
+	}
+	page := builder.AddPage(PageSizeLetter)
+	page.DrawText(page.AddStandardFont("Helvetica"), 12, 72, 700, "\U00010330", &TextOptions{
+		Fallback: font.NewFallbackChain(f),
+	})
+	if content := string(page.Content().Bytes()); strings.Contains(content, "/FB1") {
+		t.Errorf("Refused font used for fallback text:\n%s", content)
+	}
+	builder.FinalizePage(page)
+	if _, err := builder.Bytes(); err != nil {
+		t.Fatalf("Bytes() failed: %v", err)
+	}
+	embeddings = builder.Writer().FontEmbeddings()
+	if len(embeddings) != 1 || embeddings[0].Embedded {
+		t.Errorf("Expected one refused font, got %+v", embeddings)
+	}
+}
@@ -3,6 +3,7 @@ package write
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/binary"
 	"fmt"
 	"image"
@@ -22,9 +23,36 @@ type ImageInfo struct {
 	Name       string // Resource name (e.g., "/Im1")
 }
 
+// cachedImage returns the info of an image already embedded from the same data,
+// under the given resource name
+func (w *PDFWriter) cachedImage(key [sha256.Size]byte, name string) (*ImageInfo, bool) {
+	cached, ok := w.imageCache[key]
+	if !ok {
+		return nil, false
+	}
+	info := *cached
+	info.Name = name
+	return &info, true
+}
+
+// cacheImage records an embedded image so later calls with the same data reuse it
+func (w *PDFWriter) cacheImage(key [sha256.Size]byte, info *ImageInfo) {
+	if w.imageCache == nil {
+		w.imageCache = make(map[[sha256.Size]byte]*ImageInfo)
+	}
+	cached := *info
+	w.imageCache[key] = &cached
+}
+
 // AddJPEGImage adds a JPEG image to the PDF and returns its info
-// JPEG images are embedded directly without re-encoding (DCTDecode)
+// JPEG images are embedded directly without re-encoding (DCTDecode). Adding the
+// same data again reuses the embedded image instead of duplicating the stream.
 func (w *PDFWriter) AddJPEGImage(jpegData []byte, name string) (*ImageInfo, error) {
+	key := sha256.Sum256(jpegData)
+	if info, ok := w.cachedImage(key, name); ok {
+		return info, nil
+	}
+
 	// Parse JPEG header to get dimensions and color info
 	width, height, colorSpace, err := parseJPEGHeader(jpegData)
 	if err != nil {
@@ -54,18 +82,26 @@ func (w *PDFWriter) AddJPEGImage(jpegData []byte, name string) (*ImageInfo, erro
 		Stream:     jpegData,
 	}
 
-	return &ImageInfo{
+	info := &ImageInfo{
 		ObjectNum:  objNum,
 		Width:      width,
 		Height:     height,
 		ColorSpace: colorSpace,
 		Name:       name,
-	}, nil
+	}
+	w.cacheImage(key, info)
+	return info, nil
 }
 
 // AddImage adds a generic image (PNG, etc.) to the PDF
-// The image is converted to raw RGB/Gray data and compressed with FlateDecode
+// The image is converted to raw RGB/Gray data and compressed with FlateDecode.
+// Adding the same data again reuses the embedded image.
 func (w *PDFWriter) AddImage(imgData []byte, name string) (*ImageInfo, error) {
+	key := sha256.Sum256(imgData)
+	if info, ok := w.cachedImage(key, name); ok {
+		return info, nil
+	}
+
 	// Decode image
 	img, format, err := image.Decode(bytes.NewReader(imgData))
 	if err != nil {
@@ -161,6 +197,7 @@ func (w *PDFWriter) AddImage(imgData []byte, name string) (*ImageInfo, error) {
 		w.objects[objNum].Dict["SMask"] = fmt.Sprintf("%d 0 R", maskObjNum)
 	}
 
+	w.cacheImage(key, info)
 	return info, nil
 }
 
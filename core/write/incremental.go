@@ -0,0 +1,300 @@
+package write
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/core/encrypt"
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// IncrementalWriter appends an incremental update to an existing PDF (ISO
+// 32000-1, 7.5.6): the objects it is given, a cross-reference section that
+// lists only them and a trailer whose /Prev points to the file's last section.
+// The original bytes are kept as they are, so earlier revisions stay readable
+// and existing digital signatures, whose byte ranges cover the original file,
+// remain valid.
+//
+// An object set again with the number of an existing object replaces it in
+// the new revision. The update uses a cross-reference stream when the file's
+// last section is one, and a table otherwise.
+type IncrementalWriter struct {
+	original    []byte
+	last        parse.XRefSectionInfo // Newest section, with trailer entries merged from older ones
+	objects     map[int]*PDFObject
+	nextObjNum  int
+	encryptInfo *types.PDFEncryption
+	format      *PDFWriter // Formats dictionaries and compresses streams
+}
+
+// NewIncrementalWriter starts an update of original. encryptInfo is the
+// document's encryption, with its key, when original is encrypted; objects are
+// then encrypted with it as they are written. It is nil for other documents.
+func NewIncrementalWriter(original []byte, encryptInfo *types.PDFEncryption) (*IncrementalWriter, error) {
+	chain, err := parse.ReadXRefChain(original)
+	if err != nil {
+		return nil, err
+	}
+	if len(chain) == 0 {
+		return nil, types.NewPDFError(types.ErrCodeXRefError, "no cross-reference section to append an update to")
+	}
+
+	// Trailer entries an update leaves out are inherited from earlier revisions
+	last := chain[len(chain)-1]
+	size := 0
+	for i := len(chain) - 1; i >= 0; i-- {
+		section := chain[i]
+		if last.Root == "" {
+			last.Root = section.Root
+		}
+		if last.Info == "" {
+			last.Info = section.Info
+		}
+		if last.Encrypt == "" {
+			last.Encrypt = section.Encrypt
+		}
+		if last.ID == "" {
+			last.ID = section.ID
+		}
+		size = max(size, section.Size)
+		for _, objNums := range [][]int{section.Objects, section.Compressed, section.Free} {
+			if n := len(objNums); n > 0 {
+				size = max(size, objNums[n-1]+1)
+			}
+		}
+	}
+	if last.Root == "" {
+		return nil, types.NewPDFError(types.ErrCodeXRefError, "trailer has no /Root")
+	}
+
+	return &IncrementalWriter{
+		original:    original,
+		last:        last,
+		objects:     make(map[int]*PDFObject),
+		nextObjNum:  max(size, 1),
+		encryptInfo: encryptInfo,
+		format:      NewPDFWriter(),
+	}, nil
+}
+
+// SetCompression sets the policy for streams added with compress set and for a
+// cross-reference stream
+func (w *IncrementalWriter) SetCompression(policy CompressionPolicy) {
+	w.format.SetCompression(policy)
+}
+
+// SetObject sets the content of an object in the update, replacing the object
+// of that number and generation in the original
+func (w *IncrementalWriter) SetObject(objNum, genNum int, content []byte) {
+	w.objects[objNum] = &PDFObject{Number: objNum, Generation: genNum, Content: content}
+	if objNum >= w.nextObjNum {
+		w.nextObjNum = objNum + 1
+	}
+}
+
+// SetStreamObject sets a stream object in the update like SetObject
+func (w *IncrementalWriter) SetStreamObject(objNum, genNum int, dict Dictionary, data []byte, compress bool) {
+	if compress {
+		data = w.format.compressStream(dict, data)
+	}
+	w.objects[objNum] = &PDFObject{Number: objNum, Generation: genNum, Dict: dict, Stream: data}
+	if objNum >= w.nextObjNum {
+		w.nextObjNum = objNum + 1
+	}
+}
+
+// AddObject adds a new object to the update and returns its object number
+func (w *IncrementalWriter) AddObject(content []byte) int {
+	objNum := w.nextObjNum
+	w.SetObject(objNum, 0, content)
+	return objNum
+}
+
+// AddStreamObject adds a new stream object to the update and returns its
+// object number
+func (w *IncrementalWriter) AddStreamObject(dict Dictionary, data []byte, compress bool) int {
+	objNum := w.nextObjNum
+	w.SetStreamObject(objNum, 0, dict, data, compress)
+	return objNum
+}
+
+// NextObjectNumber returns the number the next added object gets
+func (w *IncrementalWriter) NextObjectNumber() int {
+	return w.nextObjNum
+}
+
+// Bytes returns the original file with the update appended
+func (w *IncrementalWriter) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := w.Write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Write writes the original file with the update appended. Without objects
+// the original is written unchanged.
+func (w *IncrementalWriter) Write(out io.Writer) error {
+	if len(w.objects) == 0 {
+		_, err := out.Write(w.original)
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(len(w.original) + 4096)
+	buf.Write(w.original)
+	if end := w.original[len(w.original)-1]; end != '\n' && end != '\r' {
+		buf.WriteByte('\n')
+	}
+
+	objNums := make([]int, 0, len(w.objects))
+	for objNum := range w.objects {
+		objNums = append(objNums, objNum)
+	}
+	sort.Ints(objNums)
+
+	positions := make(map[int]int64, len(objNums))
+	for _, objNum := range objNums {
+		obj := w.objects[objNum]
+		positions[objNum] = int64(buf.Len())
+		buf.WriteString(fmt.Sprintf("%d %d obj\n", objNum, obj.Generation))
+		if obj.Stream != nil {
+			dict := make(Dictionary, len(obj.Dict)+1)
+			for k, v := range obj.Dict {
+				dict[k] = v
+			}
+			data := obj.Stream
+			if encrypt.EncryptsStream(w.format.formatDictionary(dict), w.encryptInfo) {
+				encrypted, err := encrypt.EncryptObject(data, objNum, obj.Generation, w.encryptInfo)
+				if err != nil {
+					return fmt.Errorf("failed to encrypt object %d: %w", objNum, err)
+				}
+				data = encrypted
+			}
+			delete(dict, "/Length")
+			dict["Length"] = len(data)
+			dictBytes, err := w.encryptStrings(w.format.formatDictionary(dict), obj)
+			if err != nil {
+				return err
+			}
+			buf.Write(dictBytes)
+			buf.WriteString("\nstream\n")
+			buf.Write(data)
+			buf.WriteString("\nendstream")
+		} else {
+			content, err := w.encryptStrings(obj.Content, obj)
+			if err != nil {
+				return err
+			}
+			buf.Write(content)
+		}
+		buf.WriteString("\nendobj\n")
+	}
+
+	if w.last.Stream {
+		w.writeXRefStream(&buf, objNums, positions)
+	} else {
+		w.writeXRefTable(&buf, objNums, positions)
+	}
+
+	_, err := out.Write(buf.Bytes())
+	return err
+}
+
+// encryptStrings encrypts the strings of an object's syntax when the document's
+// string filter encrypts them
+func (w *IncrementalWriter) encryptStrings(syntax []byte, obj *PDFObject) ([]byte, error) {
+	if !encrypt.EncryptsStrings(w.encryptInfo) {
+		return syntax, nil
+	}
+	encrypted, err := encrypt.EncryptStrings(syntax, obj.Number, obj.Generation, w.encryptInfo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt object %d: %w", obj.Number, err)
+	}
+	return encrypted, nil
+}
+
+// trailerEntries returns the trailer entries of the update for a given /Size
+func (w *IncrementalWriter) trailerEntries(size int) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("/Size %d\n/Root %s\n", size, w.last.Root))
+	if w.last.Info != "" {
+		b.WriteString(fmt.Sprintf("/Info %s\n", w.last.Info))
+	}
+	if w.last.Encrypt != "" {
+		b.WriteString(fmt.Sprintf("/Encrypt %s\n", w.last.Encrypt))
+	}
+	if w.last.ID != "" {
+		b.WriteString(fmt.Sprintf("/ID %s\n", w.last.ID))
+	}
+	b.WriteString(fmt.Sprintf("/Prev %d\n", w.last.Offset))
+	return b.String()
+}
+
+// writeXRefTable writes a cross-reference table listing the update's objects,
+// in subsections of consecutive numbers, with its trailer
+func (w *IncrementalWriter) writeXRefTable(buf *bytes.Buffer, objNums []int, positions map[int]int64) {
+	xrefPos := buf.Len()
+	buf.WriteString("xref\n")
+	for start := 0; start < len(objNums); {
+		end := start + 1
+		for end < len(objNums) && objNums[end] == objNums[end-1]+1 {
+			end++
+		}
+		buf.WriteString(fmt.Sprintf("%d %d\n", objNums[start], end-start))
+		for _, objNum := range objNums[start:end] {
+			buf.WriteString(fmt.Sprintf("%010d %05d n \n", positions[objNum], w.objects[objNum].Generation))
+		}
+		start = end
+	}
+	buf.WriteString("trailer\n<<\n" + w.trailerEntries(w.nextObjNum) + ">>\n")
+	buf.WriteString(fmt.Sprintf("startxref\n%d\n%%%%EOF\n", xrefPos))
+}
+
+// writeXRefStream writes a cross-reference stream listing the update's objects
+// and itself, carrying the trailer entries in its dictionary
+func (w *IncrementalWriter) writeXRefStream(buf *bytes.Buffer, objNums []int, positions map[int]int64) {
+	xrefObjNum := w.nextObjNum
+	xrefPos := int64(buf.Len())
+	objNums = append(objNums, xrefObjNum)
+	positions[xrefObjNum] = xrefPos
+
+	maxGen := 0
+	for _, obj := range w.objects {
+		maxGen = max(maxGen, obj.Generation)
+	}
+	w2, w3 := calculateBytesNeeded(xrefPos), calculateBytesNeeded(int64(maxGen))
+
+	var index []string
+	var data []byte
+	for start := 0; start < len(objNums); {
+		end := start + 1
+		for end < len(objNums) && objNums[end] == objNums[end-1]+1 {
+			end++
+		}
+		index = append(index, fmt.Sprintf("%d %d", objNums[start], end-start))
+		for _, objNum := range objNums[start:end] {
+			entry := make([]byte, 1+w2+w3)
+			entry[0] = 1
+			writeBigEndian(entry[1:], positions[objNum], w2)
+			if obj, ok := w.objects[objNum]; ok {
+				writeBigEndian(entry[1+w2:], int64(obj.Generation), w3)
+			}
+			data = append(data, entry...)
+		}
+		start = end
+	}
+	compressed := w.format.compression.Flate(StreamContent, data)
+
+	buf.WriteString(fmt.Sprintf("%d 0 obj\n<<\n/Type /XRef\n", xrefObjNum))
+	buf.WriteString(w.trailerEntries(xrefObjNum + 1))
+	buf.WriteString(fmt.Sprintf("/Index [%s]\n/W [1 %d %d]\n/Filter /FlateDecode\n/Length %d\n>>\nstream\n",
+		strings.Join(index, " "), w2, w3, len(compressed)))
+	buf.Write(compressed)
+	buf.WriteString("\nendstream\nendobj\n")
+	buf.WriteString(fmt.Sprintf("startxref\n%d\n%%%%EOF\n", xrefPos))
+}
@@ -0,0 +1,144 @@
+package write
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+)
+
+// buildIncrementalTestPDF creates a one-page PDF whose object 4 is a note
+// dictionary, with a cross-reference table or stream
+func buildIncrementalTestPDF(t *testing.T, xrefStream bool) []byte {
+	t.Helper()
+	w := NewPDFWriter()
+	w.UseXRefStream(xrefStream)
+	w.SetFileID([]byte("0123456789abcdef"))
+	catalogNum := w.AddObject([]byte("<</Type/Catalog/Pages 2 0 R>>"))
+	w.AddObject([]byte("<</Type/Pages/Kids [3 0 R]/Count 1>>"))
+	w.AddObject([]byte("<</Type/Page/Parent 2 0 R/MediaBox [0 0 612 792]>>"))
+	w.AddObject([]byte("<</Note(Original)>>"))
+	w.SetRoot(catalogNum)
+	pdfBytes, err := w.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to generate PDF: %v", err)
+	}
+	return pdfBytes
+}
+
+func TestIncrementalWriter_AppendsUpdate(t *testing.T) {
+	for _, xrefStream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("xrefStream=%v", xrefStream), func(t *testing.T) {
+			original := buildIncrementalTestPDF(t, xrefStream)
+			before, err := parse.ReadXRefChain(original)
+			if err != nil {
+				t.Fatalf("ReadXRefChain failed: %v", err)
+			}
+
+			w, err := NewIncrementalWriter(original, nil)
+			if err != nil {
+				t.Fatalf("NewIncrementalWriter failed: %v", err)
+			}
+			w.SetObject(4, 0, []byte("<</Note(Updated)>>"))
+			streamNum := w.AddStreamObject(Dictionary{"Type": "/Test"}, []byte(strings.Repeat("added stream ", 20)), true)
+			updated, err := w.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes failed: %v", err)
+			}
+
+			if !bytes.HasPrefix(updated, original) {
+				t.Fatal("Update should keep the original bytes as a prefix")
+			}
+			chain, err := parse.ReadXRefChain(updated)
+			if err != nil {
+				t.Fatalf("ReadXRefChain failed on the update: %v", err)
+			}
+			if len(chain) != len(before)+1 {
+				t.Fatalf("Expected %d cross-reference sections, got %d", len(before)+1, len(chain))
+			}
+			last := chain[len(chain)-1]
+			if last.Stream != xrefStream {
+				t.Errorf("Update section stream = %v, want %v like the original", last.Stream, xrefStream)
+			}
+			if last.Prev != before[len(before)-1].Offset {
+				t.Errorf("/Prev = %d, want %d", last.Prev, before[len(before)-1].Offset)
+			}
+			if last.Root != before[len(before)-1].Root || last.ID == "" || last.ID != before[len(before)-1].ID {
+				t.Errorf("Trailer entries not kept: /Root %q /ID %q, original /Root %q /ID %q",
+					last.Root, last.ID, before[len(before)-1].Root, before[len(before)-1].ID)
+			}
+			if last.Size <= streamNum {
+				t.Errorf("/Size = %d, should cover object %d", last.Size, streamNum)
+			}
+
+			pdf, err := parse.Open(updated)
+			if err != nil {
+				t.Fatalf("Failed to parse the update: %v", err)
+			}
+			note, err := pdf.GetObject(4)
+			if err != nil {
+				t.Fatalf("GetObject(4) failed: %v", err)
+			}
+			if !bytes.Contains(note, []byte("(Updated)")) {
+				t.Errorf("Object 4 should be the updated revision, got %s", note)
+			}
+			stream, err := pdf.GetObject(streamNum)
+			if err != nil || !bytes.Contains(stream, []byte("/FlateDecode")) {
+				t.Errorf("Added stream %d not readable or not compressed: %s (%v)", streamNum, stream, err)
+			}
+		})
+	}
+}
+
+func TestIncrementalWriter_NoObjects(t *testing.T) {
+	original := buildIncrementalTestPDF(t, false)
+	w, err := NewIncrementalWriter(original, nil)
+	if err != nil {
+		t.Fatalf("NewIncrementalWriter failed: %v", err)
+	}
+	updated, err := w.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes failed: %v", err)
+	}
+	if !bytes.Equal(updated, original) {
+		t.Error("An update without objects should leave the file unchanged")
+	}
+}
+
+func TestIncrementalWriter_ChainedUpdates(t *testing.T) {
+	pdfBytes := buildIncrementalTestPDF(t, false)
+	for i := 1; i <= 2; i++ {
+		w, err := NewIncrementalWriter(pdfBytes, nil)
+		if err != nil {
+			t.Fatalf("NewIncrementalWriter failed for update %d: %v", i, err)
+		}
+		w.SetObject(4, 0, []byte(fmt.Sprintf("<</Note(Update %d)>>", i)))
+		if pdfBytes, err = w.Bytes(); err != nil {
+			t.Fatalf("Bytes failed for update %d: %v", i, err)
+		}
+	}
+
+	chain, err := parse.ReadXRefChain(pdfBytes)
+	if err != nil {
+		t.Fatalf("ReadXRefChain failed: %v", err)
+	}
+	if len(chain) != 3 {
+		t.Fatalf("Expected 3 cross-reference sections, got %d", len(chain))
+	}
+	pdf, err := parse.Open(pdfBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	note, err := pdf.GetObject(4)
+	if err != nil || !bytes.Contains(note, []byte("(Update 2)")) {
+		t.Errorf("Object 4 should be the newest revision, got %s (%v)", note, err)
+	}
+}
+
+func TestNewIncrementalWriter_NotPDF(t *testing.T) {
+	if _, err := NewIncrementalWriter([]byte("not a pdf"), nil); err == nil {
+		t.Error("Expected an error for data without a cross-reference section")
+	}
+}
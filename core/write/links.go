@@ -0,0 +1,126 @@
+package write
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/resources/font"
+)
+
+// defaultCharWidth approximates the advance width of a character as a fraction of the
+// font size; it is exact for Courier and close to the average for Helvetica and Times
+const defaultCharWidth = 0.6
+
+var (
+	urlPattern   = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"'()\[\]{}]+`)
+	emailPattern = regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}\b`)
+)
+
+// TextLink is a URL or email address found in text
+type TextLink struct {
+	Start int    // Byte offset of the link text
+	End   int    // Byte offset just past the link text
+	URI   string // Target: the URL ("http://" added to "www." addresses) or a mailto: URI
+}
+
+// TextOptions configures DrawText
+type TextOptions struct {
+	Linkify   bool    // Create Link annotations over URLs and email addresses in the text
+	CharWidth float64 // Character advance width as a fraction of the font size (default 0.6)
+
+	// Fallback draws each character with the first font in the chain that has a
+	// glyph for it instead of the font passed to DrawText. The fonts are embedded
+	// when the page is built and link areas use their measured widths.
+	Fallback *font.FallbackChain
+	// ColorGlyphs draws characters from fonts with bitmap color glyphs (sbix, CBDT)
+	// as images, since those fonts have no outlines to embed
+	ColorGlyphs bool
+}
+
+// FindLinks returns the URLs and email addresses in text, in order.
+// Trailing sentence punctuation is not part of a link.
+func FindLinks(text string) []TextLink {
+	var links []TextLink
+	for _, loc := range urlPattern.FindAllStringIndex(text, -1) {
+		end := loc[1]
+		for end > loc[0] && strings.ContainsRune(".,;:!?", rune(text[end-1])) {
+			end--
+		}
+		uri := text[loc[0]:end]
+		if strings.HasPrefix(strings.ToLower(uri), "www.") {
+			uri = "http://" + uri
+		}
+		links = append(links, TextLink{Start: loc[0], End: end, URI: uri})
+	}
+
+	for _, loc := range emailPattern.FindAllStringIndex(text, -1) {
+		overlaps := false
+		for _, link := range links {
+			if loc[0] < link.End && loc[1] > link.Start {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			links = append(links, TextLink{Start: loc[0], End: loc[1], URI: "mailto:" + text[loc[0]:loc[1]]})
+		}
+	}
+
+	// Emails were appended after URLs; restore text order
+	for i := 1; i < len(links); i++ {
+		for j := i; j > 0 && links[j].Start < links[j-1].Start; j-- {
+			links[j], links[j-1] = links[j-1], links[j]
+		}
+	}
+	return links
+}
+
+// AddLink adds a Link annotation that opens uri when the rectangle is clicked
+func (pb *PageBuilder) AddLink(x, y, width, height float64, uri string) int {
+	annot := fmt.Sprintf("<</Type/Annot/Subtype/Link/Rect[%.2f %.2f %.2f %.2f]/Border[0 0 0]/A<</S/URI/URI(%s)>>>>",
+		x, y, x+width, y+height, escapePDFString(uri))
+	objNum := pb.writer.AddObject([]byte(annot))
+	pb.annots = append(pb.annots, objNum)
+	return objNum
+}
+
+// DrawText shows a line of text at (x, y) with the given font resource name (e.g., "/F1"),
+// or with the fonts of options.Fallback when set.
+// With options.Linkify, URLs and email addresses in the text become clickable Link
+// annotations sized from the approximate character width. Options can be nil.
+func (pb *PageBuilder) DrawText(fontName string, size, x, y float64, text string, options *TextOptions) {
+	if options != nil && options.Fallback != nil {
+		pb.drawFallbackText(size, x, y, text, options)
+	} else {
+		pb.content.
+			BeginText().
+			SetFont(fontName, size).
+			SetTextPosition(x, y).
+			ShowText(text).
+			EndText()
+	}
+
+	if options == nil || !options.Linkify {
+		return
+	}
+
+	charWidth := options.CharWidth
+	if charWidth <= 0 {
+		charWidth = defaultCharWidth
+	}
+	advance := func(s string) float64 {
+		if options.Fallback != nil {
+			if w, err := options.Fallback.TextWidth(s, size); err == nil {
+				return w
+			}
+		}
+		return float64(len([]rune(s))) * size * charWidth
+	}
+
+	// Cover the glyphs from descender to ascender
+	for _, link := range FindLinks(text) {
+		start := x + advance(text[:link.Start])
+		pb.AddLink(start, y-0.2*size, advance(text[link.Start:link.End]), size, link.URI)
+	}
+}
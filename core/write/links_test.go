@@ -0,0 +1,137 @@
+package write
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/resources/font"
+)
+
+func TestFindLinks(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"no links here", nil},
+		{"See https://example.com/docs?id=1.", []string{"https://example.com/docs?id=1"}},
+		{"Visit www.example.org, or mail info@example.org!", []string{"http://www.example.org", "mailto:info@example.org"}},
+		{"Write to a.b@c.co then (http://x.io/a)", []string{"mailto:a.b@c.co", "http://x.io/a"}},
+		{"https://user@example.com/path", []string{"https://user@example.com/path"}},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, link := range FindLinks(tt.text) {
+			got = append(got, link.URI)
+			if link.URI != "mailto:"+tt.text[link.Start:link.End] && !strings.HasSuffix(link.URI, tt.text[link.Start:link.End]) {
+				t.Errorf("%q: offsets [%d:%d] do not match %q", tt.text, link.Start, link.End, link.URI)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FindLinks(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestDrawText_Linkify(t *testing.T) {
+	builder := NewSimplePDFBuilder()
+	page := builder.AddPage(PageSizeLetter)
+	font := page.AddStandardFont("Courier")
+	page.DrawText(font, 10, 72, 700, "Docs: https://example.com or help@example.com", &TextOptions{Linkify: true})
+	page.DrawText(font, 10, 72, 680, "Plain https://example.net", nil)
+	builder.FinalizePage(page)
+
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() failed: %v", err)
+	}
+	pdf, err := parse.Open(pdfBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse PDF: %v", err)
+	}
+
+	ref, err := pdf.Page(1)
+	if err != nil {
+		t.Fatalf("Page(1) failed: %v", err)
+	}
+	if !strings.Contains(ref.Dict, "/Annots[") {
+		t.Fatalf("Expected annotations in page dictionary: %s", ref.Dict)
+	}
+
+	var annots []string
+	for _, objNum := range pdf.Objects() {
+		obj, err := pdf.GetObject(objNum)
+		if err == nil && strings.Contains(string(obj), "/Subtype/Link") {
+			annots = append(annots, string(obj))
+		}
+	}
+	if len(annots) != 2 {
+		t.Fatalf("Expected 2 link annotations, got %d", len(annots))
+	}
+
+	// "Docs: " is 6 Courier characters of 6pt each
+	want := fmt.Sprintf("/Rect[%.2f %.2f %.2f %.2f]", 72+6*6.0, 698.0, 72+25*6.0, 708.0)
+	if !strings.Contains(annots[0], want) || !strings.Contains(annots[0], "/URI(https://example.com)") {
+		t.Errorf("Unexpected URL annotation: %s", annots[0])
+	}
+	if !strings.Contains(annots[1], "/URI(mailto:help@example.com)") {
+		t.Errorf("Unexpected email annotation: %s", annots[1])
+	}
+}
+
+func TestDrawText_Fallback(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "tests", "resources", "test_font.ttf"))
+	if err != nil {
+		t.Skipf("test font not available: %v", err)
+	}
+	gothic, err := font.NewFont("Gothic", data)
+	if err != nil {
+		t.Fatalf("NewFont failed: %v", err)
+	}
+
+	builder := NewSimplePDFBuilder()
+	page := builder.AddPage(PageSizeLetter)
+	helvetica := page.AddStandardFont("Helvetica")
+	page.DrawText(helvetica, 12, 72, 700, "\U00010330\U00010331 https://x.io", &TextOptions{
+		Linkify:  true,
+		Fallback: font.NewFallbackChain(gothic),
+	})
+
+	content := string(page.Content().Bytes())
+	if !strings.Contains(content, "/FB1 12") || !strings.Contains(content, "> Tj") {
+		t.Errorf("Expected hex text shown with the fallback font:\n%s", content)
+	}
+	builder.FinalizePage(page)
+
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() failed: %v", err)
+	}
+	pdf, err := parse.Open(pdfBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse PDF: %v", err)
+	}
+	ref, err := pdf.Page(1)
+	if err != nil {
+		t.Fatalf("Page(1) failed: %v", err)
+	}
+	if !strings.Contains(ref.Dict, "/FB1") {
+		t.Errorf("Fallback font missing from page resources: %s", ref.Dict)
+	}
+
+	var type0 bool
+	for _, objNum := range pdf.Objects() {
+		obj, err := pdf.GetObject(objNum)
+		if err == nil && strings.Contains(string(obj), "/Type0") && strings.Contains(string(obj), "/Identity-H") {
+			type0 = true
+		}
+	}
+	if !type0 {
+		t.Error("Expected a Type0 font with Identity-H encoding")
+	}
+}
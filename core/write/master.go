@@ -0,0 +1,104 @@
+package write
+
+import (
+	"fmt"
+)
+
+// masterPage is a named background drawn once per page size as a Form XObject
+type masterPage struct {
+	draw  func(master *PageBuilder)
+	forms map[PageSize]int // Form XObject object number by page size
+}
+
+// DefineMaster defines a master page: shared background content such as a
+// letterhead or a grid, drawn by draw on a PageBuilder the size of the page
+// using it. Pages stamp it with UseMaster. The content is written once per page
+// size as a Form XObject that every page references, so it is stored only once
+// and renders identically on every page. Fonts and images added by draw become
+// resources of the XObject; annotations such as links are not carried over.
+func (b *SimplePDFBuilder) DefineMaster(name string, draw func(master *PageBuilder)) error {
+	if name == "" {
+		return fmt.Errorf("master page name is empty")
+	}
+	if draw == nil {
+		return fmt.Errorf("master page %q has no draw function", name)
+	}
+	if _, exists := b.masters[name]; exists {
+		return fmt.Errorf("master page %q already defined", name)
+	}
+	b.masters[name] = &masterPage{draw: draw, forms: make(map[PageSize]int)}
+	return nil
+}
+
+// UseMaster stamps a master page defined with SimplePDFBuilder.DefineMaster on
+// the page. Master pages are drawn behind the page content, in the order they
+// are used, whether UseMaster is called before or after drawing the page.
+func (pb *PageBuilder) UseMaster(name string) error {
+	if pb.builder == nil {
+		return fmt.Errorf("master pages require a page created by SimplePDFBuilder.AddPage")
+	}
+	objNum, err := pb.builder.masterForm(name, pb.size)
+	if err != nil {
+		return err
+	}
+
+	// A master is stamped once per page
+	for _, resourceName := range pb.masters {
+		if pb.images[resourceName] == objNum {
+			return nil
+		}
+	}
+	resourceName := fmt.Sprintf("MP%d", len(pb.masters)+1)
+	pb.images[resourceName] = objNum
+	pb.masters = append(pb.masters, resourceName)
+	return nil
+}
+
+// masterForm returns the Form XObject of a master page for a page size, drawing
+// it on first use
+func (b *SimplePDFBuilder) masterForm(name string, size PageSize) (int, error) {
+	m, ok := b.masters[name]
+	if !ok {
+		return 0, fmt.Errorf("master page %q not defined", name)
+	}
+	if objNum, ok := m.forms[size]; ok {
+		return objNum, nil
+	}
+
+	objNum := b.formXObject(size, m.draw)
+	m.forms[size] = objNum
+	return objNum, nil
+}
+
+// formXObject draws content on a PageBuilder of the given size and writes it as
+// a Form XObject with the fonts and images it uses as resources
+func (b *SimplePDFBuilder) formXObject(size PageSize, draw func(*PageBuilder)) int {
+	fb := b.writer.NewPageBuilder(size)
+	draw(fb)
+	fb.embedFallbackFonts()
+
+	resources := Dictionary{}
+	if len(fb.fonts) > 0 {
+		resources["Font"] = resourceRefs(fb.fonts)
+	}
+	if len(fb.images) > 0 {
+		resources["XObject"] = resourceRefs(fb.images)
+	}
+	dict := Dictionary{
+		"Type":      "/XObject",
+		"Subtype":   "/Form",
+		"BBox":      []interface{}{0, 0, size.Width, size.Height},
+		"Resources": resources,
+	}
+	return b.writer.AddStreamObject(dict, fb.content.Bytes(), true)
+}
+
+// resourceRefs converts a resource name -> object number map to a Dictionary
+// of references
+func resourceRefs(refs map[string]int) Dictionary {
+	dict := Dictionary{}
+	for name, objNum := range refs {
+		dict[name] = fmt.Sprintf("%d 0 R", objNum)
+	}
+	return dict
+}
@@ -0,0 +1,86 @@
+package write
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"strings"
+	"testing"
+)
+
+// decompressStream inflates FlateDecode stream data
+func decompressStream(t *testing.T, data []byte) string {
+	t.Helper()
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to decompress stream: %v", err)
+	}
+	out, _ := io.ReadAll(r)
+	return string(out)
+}
+
+func TestSimplePDFBuilder_MasterPages(t *testing.T) {
+	b := NewSimplePDFBuilder()
+	draws := 0
+	err := b.DefineMaster("letterhead", func(master *PageBuilder) {
+		draws++
+		master.Content().BeginText().
+			SetFont(master.AddStandardFont("Helvetica"), 10).
+			SetTextPosition(72, master.Size().Height-36).
+			ShowText("ACME Corp").
+			EndText()
+	})
+	if err != nil {
+		t.Fatalf("DefineMaster failed: %v", err)
+	}
+	if err := b.DefineMaster("letterhead", func(*PageBuilder) {}); err == nil {
+		t.Error("Expected error redefining a master page")
+	}
+
+	for i := 0; i < 3; i++ {
+		page := b.AddPage(PageSizeLetter)
+		page.Content().Rectangle(72, 72, 100, 100).Fill()
+		if err := page.UseMaster("letterhead"); err != nil {
+			t.Fatalf("UseMaster failed: %v", err)
+		}
+		page.UseMaster("letterhead")
+		b.FinalizePage(page)
+	}
+	a4 := b.AddPage(PageSizeA4)
+	a4.UseMaster("letterhead")
+	b.FinalizePage(a4)
+
+	if err := b.AddPage(PageSizeLetter).UseMaster("grid"); err == nil {
+		t.Error("Expected error for an undefined master page")
+	}
+	if err := NewPDFWriter().NewPageBuilder(PageSizeLetter).UseMaster("letterhead"); err == nil {
+		t.Error("Expected error for a page not created by the builder")
+	}
+
+	pdfBytes, err := b.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to generate PDF: %v", err)
+	}
+	if draws != 2 {
+		t.Errorf("Expected the master drawn once per page size, got %d", draws)
+	}
+	if n := bytes.Count(pdfBytes, []byte("/Subtype /Form")); n != 2 {
+		t.Errorf("Expected 2 Form XObjects, got %d", n)
+	}
+	if n := bytes.Count(pdfBytes, []byte("/XObject<</MP1 ")); n != 4 {
+		t.Errorf("Expected 4 pages referencing the master, got %d", n)
+	}
+	if !bytes.Contains(pdfBytes, []byte("/BBox [0 0 595 842]")) {
+		t.Error("A4 master does not match the page size")
+	}
+
+	// The stamp precedes the page content
+	page := b.pageBuilders[0]
+	content, err := b.Writer().GetObject(page.contentObjNum)
+	if err != nil {
+		t.Fatalf("Failed to get content stream: %v", err)
+	}
+	if got := decompressStream(t, content); !strings.HasPrefix(got, "q /MP1 Do Q\n") || strings.Count(got, "Do") != 1 {
+		t.Errorf("Unexpected page content %q", got)
+	}
+}
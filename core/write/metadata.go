@@ -5,6 +5,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/benedoc-inc/pdfer/core/dates"
 	"github.com/benedoc-inc/pdfer/types"
 )
 
@@ -15,26 +16,38 @@ func (w *PDFWriter) SetMetadata(metadata *types.DocumentMetadata) int {
 		return 0
 	}
 
+	// Create the Info object
+	objNum := w.AddObject(w.FormatMetadata(metadata))
+	w.SetInfo(objNum)
+
+	return objNum
+}
+
+// FormatMetadata returns the Info dictionary for metadata. Text is written in
+// PDFDocEncoding when it is ASCII and as a Unicode text string otherwise,
+// /Trapped as a name, and custom keys as names escaped where needed. Custom
+// entries with a standard key are ignored in favor of the typed fields.
+func (w *PDFWriter) FormatMetadata(metadata *types.DocumentMetadata) []byte {
 	// Build Info dictionary
 	dict := Dictionary{}
 
 	if metadata.Title != "" {
-		dict["/Title"] = escapePDFStringForMetadata(metadata.Title)
+		dict["/Title"] = w.infoText(metadata.Title)
 	}
 	if metadata.Author != "" {
-		dict["/Author"] = escapePDFStringForMetadata(metadata.Author)
+		dict["/Author"] = w.infoText(metadata.Author)
 	}
 	if metadata.Subject != "" {
-		dict["/Subject"] = escapePDFStringForMetadata(metadata.Subject)
+		dict["/Subject"] = w.infoText(metadata.Subject)
 	}
 	if metadata.Keywords != "" {
-		dict["/Keywords"] = escapePDFStringForMetadata(metadata.Keywords)
+		dict["/Keywords"] = w.infoText(metadata.Keywords)
 	}
 	if metadata.Creator != "" {
-		dict["/Creator"] = escapePDFStringForMetadata(metadata.Creator)
+		dict["/Creator"] = w.infoText(metadata.Creator)
 	}
 	if metadata.Producer != "" {
-		dict["/Producer"] = escapePDFStringForMetadata(metadata.Producer)
+		dict["/Producer"] = w.infoText(metadata.Producer)
 	}
 	if metadata.CreationDate != "" {
 		dict["/CreationDate"] = formatPDFDate(metadata.CreationDate)
@@ -45,26 +58,58 @@ func (w *PDFWriter) SetMetadata(metadata *types.DocumentMetadata) int {
 
 	// If no dates provided, set current time as ModDate
 	if metadata.ModDate == "" {
-		dict["/ModDate"] = formatPDFDate(time.Now().Format(time.RFC3339))
+		dict["/ModDate"] = dates.Format(time.Now())
+	}
+	if trapped := trappedName(metadata.Trapped); trapped != "" {
+		dict["/Trapped"] = trapped
 	}
 
 	// Add custom fields
 	for key, value := range metadata.Custom {
-		if key != "" && value != "" {
-			// Ensure key starts with /
-			if !strings.HasPrefix(key, "/") {
-				key = "/" + key
-			}
-			dict[key] = escapePDFStringForMetadata(value)
+		key = strings.TrimPrefix(key, "/")
+		if key != "" && value != "" && !types.IsStandardInfoKey(key) {
+			dict[pdfName(key)] = w.infoText(value)
 		}
 	}
 
-	// Create the Info object
-	content := w.formatDictionary(dict)
-	objNum := w.AddObject(content)
-	w.SetInfo(objNum)
+	return w.formatDictionary(dict)
+}
 
-	return objNum
+// infoText returns an Info dictionary text string value. Text that formatValue
+// would take for a name, a reference or an array is written as a hex string.
+func (w *PDFWriter) infoText(s string) interface{} {
+	text := w.textString(s)
+	if isRawValue(text) {
+		return []byte(text)
+	}
+	return escapePDFStringForMetadata(text)
+}
+
+// trappedName returns the /Trapped name for a value, accepting any case, or ""
+// for values that aren't valid
+func trappedName(value string) string {
+	for _, name := range []string{types.TrappedTrue, types.TrappedFalse, types.TrappedUnknown} {
+		if strings.EqualFold(value, name) {
+			return "/" + name
+		}
+	}
+	return ""
+}
+
+// pdfName formats s as a PDF name, escaping delimiters, whitespace and bytes
+// outside printable ASCII as #xx
+func pdfName(s string) string {
+	var b strings.Builder
+	b.WriteByte('/')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < '!' || c > '~' || strings.IndexByte("#()<>[]{}/%", c) != -1 {
+			fmt.Fprintf(&b, "#%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
 }
 
 // SetMetadataFields is a convenience method to set metadata fields individually
@@ -112,72 +157,23 @@ func escapePDFStringForMetadata(s string) string {
 	return s
 }
 
-// formatPDFDate formats a date string for PDF
-// Accepts ISO 8601 format (YYYY-MM-DD or YYYY-MM-DDTHH:MM:SS) or PDF format (D:YYYYMMDDHHmmSSOHH'mm)
-// Returns PDF date format: D:YYYYMMDDHHmmSSOHH'mm
+// formatPDFDate formats a PDF or ISO 8601 date string as a PDF date. A date
+// already in PDF format is kept as written and one that can't be parsed is
+// replaced by the current time.
 func formatPDFDate(dateStr string) string {
 	if dateStr == "" {
 		return ""
 	}
-
-	// If already in PDF format, return as-is
 	if strings.HasPrefix(dateStr, "D:") {
 		return escapePDFString(dateStr)
 	}
 
-	// Try to parse as ISO 8601 or other common formats
-	var t time.Time
-	var err error
-
-	// Try ISO 8601 with time
-	formats := []string{
-		time.RFC3339,
-		"2006-01-02T15:04:05Z07:00",
-		"2006-01-02T15:04:05",
-		"2006-01-02 15:04:05",
-		"2006-01-02",
-	}
-
-	for _, format := range formats {
-		t, err = time.Parse(format, dateStr)
-		if err == nil {
-			break
-		}
-	}
-
-	// If parsing failed, use current time
+	t, err := dates.ParseAny(dateStr)
 	if err != nil {
 		t = time.Now()
 	}
-
-	// Format as PDF date: D:YYYYMMDDHHmmSSOHH'mm
-	// O is timezone offset: +HH or -HH
-	// 'mm' is minutes offset (usually 00)
-	year := t.Year()
-	month := int(t.Month())
-	day := t.Day()
-	hour := t.Hour()
-	min := t.Minute()
-	sec := t.Second()
-
-	// Get timezone offset
-	_, offset := t.Zone()
-	offsetHours := offset / 3600
-	offsetMins := (offset % 3600) / 60
-
-	// Format timezone
-	var tzStr string
-	if offsetHours >= 0 {
-		tzStr = fmt.Sprintf("+%02d'%02d", offsetHours, offsetMins)
-	} else {
-		tzStr = fmt.Sprintf("-%02d'%02d", -offsetHours, -offsetMins)
-	}
-
-	pdfDate := fmt.Sprintf("D:%04d%02d%02d%02d%02d%02d%s",
-		year, month, day, hour, min, sec, tzStr)
-
-	// Return as escaped string (formatValue will add parentheses)
-	return escapePDFStringForMetadata(pdfDate)
+	// Returned escaped; formatValue adds the parentheses
+	return escapePDFStringForMetadata(dates.Format(t))
 }
 
 // NewMetadataFromFields creates a DocumentMetadata from a map of fields
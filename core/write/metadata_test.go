@@ -299,3 +299,35 @@ func TestSetMetadata_CurrentTimeModDate(t *testing.T) {
 		t.Error("ModDate should be in PDF format")
 	}
 }
+
+func TestFormatMetadata_TrappedAndCustomKeys(t *testing.T) {
+	writer := NewPDFWriter()
+	content := string(writer.FormatMetadata(&types.DocumentMetadata{
+		Title:   "/not a name",
+		ModDate: "D:20240101000000Z",
+		Trapped: "true",
+		Custom: map[string]string{
+			"Review Status": "Approved (final)",
+			"Title":         "ignored",
+			"Ref":           "5 0 R",
+		},
+	}))
+
+	for _, want := range []string{
+		"/Trapped /True ",
+		"/Review#20Status (Approved \\(final\\)) ",
+		"/Title <2F6E6F742061206E616D65> ",
+		"/Ref <3520302052> ",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("Info dictionary doesn't contain %q: %s", want, content)
+		}
+	}
+	if strings.Contains(content, "ignored") {
+		t.Errorf("Custom entry overrode a standard key: %s", content)
+	}
+
+	if content := string(writer.FormatMetadata(&types.DocumentMetadata{Trapped: "maybe"})); strings.Contains(content, "/Trapped") {
+		t.Errorf("Invalid /Trapped value written: %s", content)
+	}
+}
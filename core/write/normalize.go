@@ -0,0 +1,166 @@
+package write
+
+import (
+	"sort"
+	"sync"
+)
+
+// Hangul syllables are composed and decomposed algorithmically (Unicode 3.12)
+const (
+	hangulSBase  = 0xAC00
+	hangulLBase  = 0x1100
+	hangulVBase  = 0x1161
+	hangulTBase  = 0x11A7
+	hangulLCount = 19
+	hangulVCount = 21
+	hangulTCount = 28
+	hangulNCount = hangulVCount * hangulTCount
+	hangulSCount = hangulLCount * hangulNCount
+)
+
+var (
+	compositionsOnce sync.Once
+	compositions     map[[2]rune]rune
+)
+
+// NormalizeNFC returns text in Unicode Normalization Form C: canonically
+// decomposed, reordered by combining class and recomposed, so that for example
+// "e" followed by U+0301 becomes the single character "é".
+func NormalizeNFC(text string) string {
+	if isASCII(text) {
+		return text
+	}
+
+	var runes []rune
+	for _, r := range text {
+		runes = decompose(runes, r, false)
+	}
+	reorderCombiningMarks(runes)
+	return string(compose(runes))
+}
+
+// NormalizeNFKC returns text in Unicode Normalization Form KC: like NFC, but
+// compatibility characters are first replaced by their plain equivalents, so
+// that for example the "ﬁ" ligature becomes "fi" and "²" becomes "2".
+func NormalizeNFKC(text string) string {
+	if isASCII(text) {
+		return text
+	}
+
+	var runes []rune
+	for _, r := range text {
+		runes = decompose(runes, r, true)
+	}
+	reorderCombiningMarks(runes)
+	return string(compose(runes))
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// decompose appends the full canonical decomposition of r, or with compat, its
+// full compatibility decomposition
+func decompose(out []rune, r rune, compat bool) []rune {
+	if r >= hangulSBase && r < hangulSBase+hangulSCount {
+		s := r - hangulSBase
+		out = append(out, hangulLBase+s/hangulNCount, hangulVBase+(s%hangulNCount)/hangulTCount)
+		if t := s % hangulTCount; t != 0 {
+			out = append(out, hangulTBase+t)
+		}
+		return out
+	}
+	parts, ok := canonicalDecompositions[r]
+	if !ok && compat {
+		parts, ok = compatibilityDecompositions[r]
+	}
+	if !ok {
+		return append(out, r)
+	}
+	for _, p := range parts {
+		out = decompose(out, p, compat)
+	}
+	return out
+}
+
+// combiningClass returns the canonical combining class of r (0 for starters)
+func combiningClass(r rune) int {
+	i := sort.Search(len(combiningClassRanges), func(i int) bool {
+		return combiningClassRanges[i][1] >= r
+	})
+	if i < len(combiningClassRanges) && combiningClassRanges[i][0] <= r {
+		return int(combiningClassRanges[i][2])
+	}
+	return 0
+}
+
+// reorderCombiningMarks sorts each sequence of combining marks by combining class,
+// keeping marks of equal class in order
+func reorderCombiningMarks(runes []rune) {
+	for start := 0; start < len(runes); {
+		if combiningClass(runes[start]) == 0 {
+			start++
+			continue
+		}
+		end := start
+		for end < len(runes) && combiningClass(runes[end]) != 0 {
+			end++
+		}
+		marks := runes[start:end]
+		sort.SliceStable(marks, func(i, j int) bool {
+			return combiningClass(marks[i]) < combiningClass(marks[j])
+		})
+		start = end
+	}
+}
+
+// compose applies canonical composition to a decomposed, reordered sequence
+func compose(runes []rune) []rune {
+	compositionsOnce.Do(func() {
+		compositions = make(map[[2]rune]rune)
+		for r, parts := range canonicalDecompositions {
+			if len(parts) == 2 && !compositionExclusions[r] {
+				compositions[[2]rune{parts[0], parts[1]}] = r
+			}
+		}
+	})
+
+	var out []rune
+	starter := -1  // index in out of the last starter
+	lastClass := 0 // combining class of the last character kept after it
+	for _, r := range runes {
+		class := combiningClass(r)
+		// r combines with the starter unless a character in between blocks it
+		if starter >= 0 && (len(out)-1 == starter || lastClass != 0 && lastClass < class) {
+			if c, ok := composePair(out[starter], r); ok {
+				out[starter] = c
+				continue
+			}
+		}
+		if class == 0 {
+			starter = len(out)
+		}
+		lastClass = class
+		out = append(out, r)
+	}
+	return out
+}
+
+// composePair returns the primary composite of a and b
+func composePair(a, b rune) (rune, bool) {
+	// Hangul LV and LVT
+	if a >= hangulLBase && a < hangulLBase+hangulLCount && b >= hangulVBase && b < hangulVBase+hangulVCount {
+		return hangulSBase + ((a-hangulLBase)*hangulVCount+(b-hangulVBase))*hangulTCount, true
+	}
+	if a >= hangulSBase && a < hangulSBase+hangulSCount && (a-hangulSBase)%hangulTCount == 0 &&
+		b > hangulTBase && b < hangulTBase+hangulTCount {
+		return a + (b - hangulTBase), true
+	}
+	c, ok := compositions[[2]rune{a, b}]
+	return c, ok
+}
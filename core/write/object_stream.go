@@ -2,8 +2,7 @@ package write
 
 import (
 	"bytes"
-	"compress/zlib"
-	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -31,11 +30,13 @@ func (w *PDFWriter) createObjectStreams() (map[int]struct {
 		if obj.IsFree {
 			continue
 		}
-		// Only compress non-stream objects (stream objects are already compressed)
-		if obj.Stream == nil && obj.Content != nil {
+		// Only compress non-stream objects (stream objects are already compressed),
+		// including streams set as raw content
+		if obj.Stream == nil && obj.Content != nil && !bytes.HasSuffix(bytes.TrimSpace(obj.Content), []byte("endstream")) {
 			eligibleObjs = append(eligibleObjs, objNum)
 		}
 	}
+	sort.Ints(eligibleObjs)
 
 	if len(eligibleObjs) == 0 {
 		return nil, nil, nil
@@ -68,12 +69,10 @@ func (w *PDFWriter) createObjectStreams() (map[int]struct {
 		})
 	}
 
-	// Calculate offsets
+	// Calculate offsets, relative to the first object
 	currentOffset := 0
 	for i := range objInfos {
 		objInfos[i].offset = currentOffset
-		// Offset is relative to start of object data (after header)
-		// We'll add header length later
 		currentOffset += len(objInfos[i].data) + 1 // +1 for space separator
 	}
 
@@ -84,28 +83,8 @@ func (w *PDFWriter) createObjectStreams() (map[int]struct {
 		headerBuilder.WriteString(strconv.Itoa(info.offset))
 		headerBuilder.WriteString(" ")
 	}
-
-	headerStr := strings.TrimSpace(headerBuilder.String())
-	headerBytes := []byte(headerStr)
-	firstOffset := len(headerBytes)
-
-	// Adjust offsets to be relative to start of data (after header + space)
-	dataStartOffset := firstOffset + 1 // +1 for space after header
-	for i := range objInfos {
-		objInfos[i].offset += dataStartOffset
-	}
-
-	// Rebuild header with corrected offsets
-	headerBuilder.Reset()
-	for _, info := range objInfos {
-		headerBuilder.WriteString(strconv.Itoa(info.objNum))
-		headerBuilder.WriteString(" ")
-		headerBuilder.WriteString(strconv.Itoa(info.offset))
-		headerBuilder.WriteString(" ")
-	}
-	headerStr = strings.TrimSpace(headerBuilder.String())
-	headerBytes = []byte(headerStr)
-	firstOffset = len(headerBytes)
+	headerBytes := []byte(strings.TrimSpace(headerBuilder.String()))
+	firstOffset := len(headerBytes) + 1 // +1 for space after header
 
 	// Build data section
 	var dataBuilder bytes.Buffer
@@ -129,15 +108,7 @@ func (w *PDFWriter) createObjectStreams() (map[int]struct {
 	streamData.Write(dataBuilder.Bytes())
 
 	// Compress stream data
-	var compressed bytes.Buffer
-	zw := zlib.NewWriter(&compressed)
-	if _, err := zw.Write(streamData.Bytes()); err != nil {
-		return nil, nil, fmt.Errorf("failed to compress object stream: %v", err)
-	}
-	if err := zw.Close(); err != nil {
-		return nil, nil, fmt.Errorf("failed to close zlib writer: %v", err)
-	}
-	compressedData := compressed.Bytes()
+	compressedData := w.compression.Flate(StreamContent, streamData.Bytes())
 
 	// Create object stream dictionary
 	objStreamDict := Dictionary{
@@ -78,6 +78,9 @@ func TestObjectStream_Basic(t *testing.T) {
 	if !pdf.HasObject(pagesNum) {
 		t.Error("Should be able to access pages object")
 	}
+	if obj, err := pdf.GetObject(page2Num); err != nil || !bytes.HasPrefix(obj, []byte("<</MediaBox")) {
+		t.Errorf("Object read from object stream = %q, %v", obj, err)
+	}
 
 	t.Logf("Generated PDF with object stream: %d bytes", len(pdfBytes))
 }
@@ -125,3 +128,18 @@ func TestObjectStream_Disabled(t *testing.T) {
 		t.Error("Should be able to access catalog object")
 	}
 }
+
+func TestObjectStream_RawStreamsExcluded(t *testing.T) {
+	writer := NewPDFWriter()
+	writer.UseObjectStream(true)
+	streamNum := writer.AddObject([]byte("<</Length 5>>\nstream\nBT ET\nendstream"))
+	writer.SetRoot(writer.AddObject([]byte(fmt.Sprintf("<</Type/Catalog/Extra %d 0 R>>", streamNum))))
+
+	var buf bytes.Buffer
+	if err := writer.Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(fmt.Sprintf("%d 0 obj\n<</Length 5>>\nstream", streamNum))) {
+		t.Error("Stream object should be written as a top-level object")
+	}
+}
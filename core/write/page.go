@@ -2,8 +2,14 @@
 package write
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/benedoc-inc/pdfer/core/dates"
 	"github.com/benedoc-inc/pdfer/resources/font"
 	"github.com/benedoc-inc/pdfer/types"
 )
@@ -29,9 +35,21 @@ type PageBuilder struct {
 	size        PageSize
 	fonts       map[string]int // font name -> object number
 	images      map[string]int // image name -> object number
+	annots      []int          // annotation object numbers
+	beads       []int          // article bead object numbers
 	content     *ContentStream
 	pageObjNum  int
 	pagesObjNum int
+
+	contentObjNum int    // content stream object, set by Build
+	resources     string // resources dictionary, set by Build
+
+	fallbackFonts []*font.Font      // fonts used by fallback text, embedded at Build as /FB1, /FB2, ...
+	glyphImages   map[string]string // color glyph key -> image resource name
+
+	builder *SimplePDFBuilder // builder that created the page, for master pages
+	masters []string          // XObject resource names of the master pages to stamp
+	assets  map[string]string // asset name -> XObject resource name
 }
 
 // NewPageBuilder creates a new page builder
@@ -45,6 +63,11 @@ func (w *PDFWriter) NewPageBuilder(size PageSize) *PageBuilder {
 	}
 }
 
+// Size returns the page size
+func (pb *PageBuilder) Size() PageSize {
+	return pb.size
+}
+
 // Content returns the content stream for adding graphics/text
 func (pb *PageBuilder) Content() *ContentStream {
 	return pb.content
@@ -62,7 +85,12 @@ func (pb *PageBuilder) AddStandardFont(fontName string) string {
 
 	// Create font dictionary
 	resourceName := fmt.Sprintf("F%d", len(pb.fonts)+1)
-	fontDict := fmt.Sprintf("<</Type/Font/Subtype/Type1/BaseFont/%s>>", fontName)
+	// Text fonts use WinAnsiEncoding, which ShowTextWithOptions encodes to;
+	// Symbol and ZapfDingbats keep their built-in encodings
+	fontDict := fmt.Sprintf("<</Type/Font/Subtype/Type1/BaseFont/%s/Encoding/WinAnsiEncoding>>", fontName)
+	if fontName == "Symbol" || fontName == "ZapfDingbats" {
+		fontDict = fmt.Sprintf("<</Type/Font/Subtype/Type1/BaseFont/%s>>", fontName)
+	}
 	objNum := pb.writer.AddObject([]byte(fontDict))
 	pb.fonts[resourceName] = objNum
 
@@ -86,13 +114,9 @@ func (pb *PageBuilder) AddImage(info *ImageInfo) string {
 // AddEmbeddedFont adds an embedded TrueType/OpenType font and returns the resource name
 // The font will be subset to include only the characters added via font.AddString() or font.AddRune()
 func (pb *PageBuilder) AddEmbeddedFont(f *font.Font) (string, error) {
-	// Create a wrapper to make PDFWriter implement font.PDFWriter interface
-	wrapper := &fontWriterWrapper{w: pb.writer}
-
-	// Create PDF objects for the font
-	fontObjs, err := f.ToPDFObjects(wrapper)
+	fontObjs, err := pb.writer.AddFont(f)
 	if err != nil {
-		return "", fmt.Errorf("failed to create font objects: %w", err)
+		return "", err
 	}
 
 	// Store font by resource name
@@ -106,6 +130,22 @@ func (pb *PageBuilder) AddEmbeddedFont(f *font.Font) (string, error) {
 	return "/" + resourceName, nil
 }
 
+// AddFont writes the objects of an embedded TrueType/OpenType font, subset to the
+// characters added to it, for use outside a PageBuilder such as in appearance streams.
+// Fonts whose licence forbids embedding are refused with a *FontLicenseError under
+// FontLicenseRefuse; see SetFontLicensePolicy.
+func (w *PDFWriter) AddFont(f *font.Font) (*font.FontObjects, error) {
+	if err := w.checkFontLicense(f); err != nil {
+		return nil, err
+	}
+	// Create a wrapper to make PDFWriter implement font.PDFWriter interface
+	fontObjs, err := f.ToPDFObjects(&fontWriterWrapper{w: w})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create font objects: %w", err)
+	}
+	return fontObjs, nil
+}
+
 // fontWriterWrapper wraps PDFWriter to implement font.PDFWriter interface
 type fontWriterWrapper struct {
 	w *PDFWriter
@@ -127,54 +167,148 @@ func (w *fontWriterWrapper) NextObjectNumber() int {
 func (pb *PageBuilder) Build(pagesObjNum int) int {
 	pb.pagesObjNum = pagesObjNum
 
-	// Create content stream object
+	pb.embedFallbackFonts()
+
+	// Create content stream object, drawing master pages first as background
+	data := pb.content.Bytes()
+	if len(pb.masters) > 0 {
+		var stamp bytes.Buffer
+		for _, name := range pb.masters {
+			stamp.WriteString(fmt.Sprintf("q /%s Do Q\n", name))
+		}
+		data = append(stamp.Bytes(), data...)
+	}
 	contentDict := Dictionary{}
-	contentObjNum := pb.writer.AddStreamObject(contentDict, pb.content.Bytes(), true)
+	pb.contentObjNum = pb.writer.AddStreamObject(contentDict, data, true)
 
 	// Build resources dictionary
 	resources := "<<"
 
 	// Add fonts
 	if len(pb.fonts) > 0 {
-		resources += "/Font<<"
-		for name, objNum := range pb.fonts {
-			resources += fmt.Sprintf("/%s %d 0 R", name, objNum)
-		}
-		resources += ">>"
+		resources += "/Font" + resourceDict(pb.fonts)
 	}
 
 	// Add images as XObjects
 	if len(pb.images) > 0 {
-		resources += "/XObject<<"
-		for name, objNum := range pb.images {
-			resources += fmt.Sprintf("/%s %d 0 R", name, objNum)
-		}
-		resources += ">>"
+		resources += "/XObject" + resourceDict(pb.images)
 	}
 
 	resources += ">>"
+	pb.resources = resources
 
 	// Create page object
-	pageDict := fmt.Sprintf(`<</Type/Page/Parent %d 0 R/MediaBox[0 0 %.0f %.0f]/Contents %d 0 R/Resources%s>>`,
-		pagesObjNum, pb.size.Width, pb.size.Height, contentObjNum, resources)
-	pb.pageObjNum = pb.writer.AddObject([]byte(pageDict))
+	pb.pageObjNum = pb.writer.AddObject(pb.pageDict())
 
 	return pb.pageObjNum
 }
 
+// embedFallbackFonts embeds the fonts used by fallback text now that all their
+// characters are known
+func (pb *PageBuilder) embedFallbackFonts() {
+	for i, f := range pb.fallbackFonts {
+		fontObjs, err := f.ToType0PDFObjects(&fontWriterWrapper{w: pb.writer})
+		if err != nil {
+			continue
+		}
+		pb.fonts[fmt.Sprintf("FB%d", i+1)] = fontObjs.FontDictNum
+	}
+}
+
+// addAnnots adds annotations to a built page and rewrites its page object
+func (pb *PageBuilder) addAnnots(objNums []int) {
+	pb.annots = append(pb.annots, objNums...)
+	pb.writer.SetObject(pb.pageObjNum, pb.pageDict())
+}
+
+// pageDict formats the page object of a built page
+func (pb *PageBuilder) pageDict() []byte {
+	// Add annotations
+	annots := ""
+	if len(pb.annots) > 0 {
+		annots = "/Annots["
+		for i, objNum := range pb.annots {
+			if i > 0 {
+				annots += " "
+			}
+			annots += fmt.Sprintf("%d 0 R", objNum)
+		}
+		annots += "]"
+	}
+
+	// Add article beads
+	beads := ""
+	if len(pb.beads) > 0 {
+		beads = "/B" + refArray(pb.beads)
+	}
+
+	return []byte(fmt.Sprintf(`<</Type/Page/Parent %d 0 R/MediaBox[0 0 %.0f %.0f]/Contents %d 0 R/Resources%s%s%s>>`,
+		pb.pagesObjNum, pb.size.Width, pb.size.Height, pb.contentObjNum, pb.resources, annots, beads))
+}
+
+// resourceDict formats a resource name -> object number map as a dictionary,
+// sorted by name so the output does not depend on map iteration order
+func resourceDict(refs map[string]int) string {
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	dict := "<<"
+	for _, name := range names {
+		dict += fmt.Sprintf("/%s %d 0 R", name, refs[name])
+	}
+	return dict + ">>"
+}
+
 // SimplePDFBuilder provides a high-level API for creating simple PDFs
+//
+// Objects are numbered in the order they are created: the objects of each page
+// (fonts and images as they are added, then fallback fonts, the content stream
+// and the page object) when the page is finalized, interleaved with anything
+// added through Writer(). The Pages tree number is reserved the first time it
+// is needed, as is the AcroForm number by SetAcroForm. Bytes then writes the
+// AcroForm fields and widgets, the /Info dictionary, unless one was set through
+// Writer(), and the catalog as the last objects, and derives the trailer /ID
+// from the document content.
 type SimplePDFBuilder struct {
-	writer        *PDFWriter
-	pages         []int
-	pagesObjNum   int
-	catalogObjNum int
+	writer         *PDFWriter
+	pages          []int
+	pageBuilders   []*PageBuilder
+	pagesObjNum    int
+	catalogObjNum  int
+	catalogEntries map[string]string // extra catalog entries, e.g. /AcroForm
+	metadata       *types.DocumentMetadata
+	created        time.Time
+	masters        map[string]*masterPage
+	assets         map[string]*asset
+
+	acroForm       AcroFormSource
+	acroFormObjNum int
+	acroFormBuilt  bool
+}
+
+// AcroFormSource writes the fields of a SimplePDFBuilder document's AcroForm,
+// such as an acroform.FieldBuilder
+type AcroFormSource interface {
+	// BuildAcroForm writes the field objects and the AcroForm dictionary as
+	// object acroFormObjNum. pageObjNums lists the page objects in document
+	// order; the result lists the widget annotations of each page, by page index.
+	BuildAcroForm(acroFormObjNum int, pageObjNums []int) (map[int][]int, error)
 }
 
 // NewSimplePDFBuilder creates a new simple PDF builder
 func NewSimplePDFBuilder() *SimplePDFBuilder {
+	w := NewPDFWriter()
+	w.contentFileID = true
 	return &SimplePDFBuilder{
-		writer: NewPDFWriter(),
-		pages:  make([]int, 0),
+		writer:         w,
+		pages:          make([]int, 0),
+		catalogEntries: make(map[string]string),
+		created:        time.Now(),
+		masters:        make(map[string]*masterPage),
+		assets:         make(map[string]*asset),
 	}
 }
 
@@ -185,23 +319,57 @@ func (b *SimplePDFBuilder) Writer() *PDFWriter {
 
 // AddPage adds a new page and returns a page builder
 func (b *SimplePDFBuilder) AddPage(size PageSize) *PageBuilder {
-	return b.writer.NewPageBuilder(size)
+	pb := b.writer.NewPageBuilder(size)
+	pb.builder = b
+	return pb
 }
 
 // FinalizePage adds a built page to the document
 func (b *SimplePDFBuilder) FinalizePage(pb *PageBuilder) {
-	// Use a placeholder for pagesObjNum - we'll fix it later
-	if b.pagesObjNum == 0 {
-		// Reserve object number for pages
-		b.pagesObjNum = b.writer.nextObjNum
+	pageObjNum := pb.Build(b.PagesObjNum())
+	b.pages = append(b.pages, pageObjNum)
+	b.pageBuilders = append(b.pageBuilders, pb)
+}
+
+// SetCatalogEntry sets an entry of the document catalog, e.g. "/Outlines" or
+// "/PageMode", to a raw PDF value. /Type and /Pages are managed by the builder.
+func (b *SimplePDFBuilder) SetCatalogEntry(key, value string) {
+	if !strings.HasPrefix(key, "/") {
+		key = "/" + key
+	}
+	b.catalogEntries[key] = value
+}
+
+// SetAcroForm makes form the document's AcroForm and returns the object number
+// reserved for its dictionary. The form is built when the PDF is written, once
+// all pages are known: the catalog references it and its widget annotations
+// are added to the /Annots of their pages.
+func (b *SimplePDFBuilder) SetAcroForm(form AcroFormSource) int {
+	b.acroForm = form
+	if b.acroFormObjNum == 0 {
+		b.acroFormObjNum = b.writer.nextObjNum
 		b.writer.nextObjNum++
 	}
-	pageObjNum := pb.Build(b.pagesObjNum)
-	b.pages = append(b.pages, pageObjNum)
+	return b.acroFormObjNum
+}
+
+// SetMetadata sets the document information written to the /Info dictionary.
+// Producer defaults to "pdfer" and the dates to the time the builder was created.
+func (b *SimplePDFBuilder) SetMetadata(metadata *types.DocumentMetadata) {
+	b.metadata = metadata
 }
 
 // Bytes returns the complete PDF
 func (b *SimplePDFBuilder) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := b.Write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Write outputs the complete PDF to the given writer
+func (b *SimplePDFBuilder) Write(out io.Writer) error {
 	// Build Kids array
 	kids := "["
 	for _, pageNum := range b.pages {
@@ -210,14 +378,71 @@ func (b *SimplePDFBuilder) Bytes() ([]byte, error) {
 	kids += "]"
 
 	// Create/update Pages object
+	pagesObjNum := b.PagesObjNum()
 	pagesDict := fmt.Sprintf("<</Type/Pages/Kids%s/Count %d>>", kids, len(b.pages))
-	b.writer.SetObject(b.pagesObjNum, []byte(pagesDict))
+	b.writer.SetObject(pagesObjNum, []byte(pagesDict))
+
+	// AcroForm fields and widgets, once
+	if b.acroForm != nil && !b.acroFormBuilt {
+		annots, err := b.acroForm.BuildAcroForm(b.acroFormObjNum, b.pages)
+		if err != nil {
+			return fmt.Errorf("failed to build AcroForm: %w", err)
+		}
+		for i, pb := range b.pageBuilders {
+			if len(annots[i]) > 0 {
+				pb.addAnnots(annots[i])
+			}
+		}
+		b.acroFormBuilt = true
+		b.SetCatalogEntry("/AcroForm", fmt.Sprintf("%d 0 R", b.acroFormObjNum))
+	}
 
-	// Create Catalog
-	b.catalogObjNum = b.writer.AddObject([]byte(fmt.Sprintf("<</Type/Catalog/Pages %d 0 R>>", b.pagesObjNum)))
+	// Info dictionary, unless the caller set one on the writer
+	if b.writer.infoRef == "" {
+		b.writer.SetMetadata(b.info())
+	}
+
+	// Create Catalog, reusing its number when Bytes is called again
+	if b.writer.outlinesRef != "" {
+		b.catalogEntries["/Outlines"] = b.writer.outlinesRef
+	}
+	catalog := fmt.Sprintf("<</Type/Catalog/Pages %d 0 R", pagesObjNum)
+	keys := make([]string, 0, len(b.catalogEntries))
+	for key := range b.catalogEntries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		catalog += fmt.Sprintf("%s %s", key, b.catalogEntries[key])
+	}
+	catalog += ">>"
+	if b.catalogObjNum == 0 {
+		b.catalogObjNum = b.writer.AddObject([]byte(catalog))
+	} else {
+		b.writer.SetObject(b.catalogObjNum, []byte(catalog))
+	}
 	b.writer.SetRoot(b.catalogObjNum)
 
-	return b.writer.Bytes()
+	return b.writer.Write(out)
+}
+
+// info returns the document information for the /Info dictionary
+func (b *SimplePDFBuilder) info() *types.DocumentMetadata {
+	info := types.DocumentMetadata{}
+	if b.metadata != nil {
+		info = *b.metadata
+	}
+	if info.Producer == "" {
+		info.Producer = "pdfer"
+	}
+	created := dates.Format(b.created)
+	if info.CreationDate == "" {
+		info.CreationDate = created
+	}
+	if info.ModDate == "" {
+		info.ModDate = info.CreationDate
+	}
+	return &info
 }
 
 // SetBookmarks sets bookmarks for the document
@@ -233,8 +458,13 @@ func (b *SimplePDFBuilder) SetBookmarks(bookmarks []types.Bookmark) error {
 	return err
 }
 
-// PagesObjNum returns the pages object number
+// PagesObjNum returns the pages object number, reserving it if no page has
+// been finalized yet
 func (b *SimplePDFBuilder) PagesObjNum() int {
+	if b.pagesObjNum == 0 {
+		b.pagesObjNum = b.writer.nextObjNum
+		b.writer.nextObjNum++
+	}
 	return b.pagesObjNum
 }
 
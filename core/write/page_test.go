@@ -0,0 +1,141 @@
+package write
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// testAcroForm is an AcroFormSource with one widget on every page
+type testAcroForm struct {
+	w *PDFWriter
+}
+
+func (f testAcroForm) BuildAcroForm(acroFormObjNum int, pageObjNums []int) (map[int][]int, error) {
+	annots := make(map[int][]int)
+	var refs []string
+	for i, pageObjNum := range pageObjNums {
+		widget := f.w.AddObject([]byte(fmt.Sprintf("<< /Subtype /Widget /FT /Tx /T (f%d) /P %d 0 R >>", i, pageObjNum)))
+		annots[i] = append(annots[i], widget)
+		refs = append(refs, fmt.Sprintf("%d 0 R", widget))
+	}
+	f.w.SetObject(acroFormObjNum, []byte(fmt.Sprintf("<< /Fields [%s] >>", strings.Join(refs, " "))))
+	return annots, nil
+}
+
+// buildSimpleTestPDF creates a one-page document with two fonts and an AcroForm
+func buildSimpleTestPDF(t *testing.T) []byte {
+	t.Helper()
+	b := NewSimplePDFBuilder()
+	b.SetMetadata(&types.DocumentMetadata{Title: "Test", CreationDate: "2024-03-05T10:00:00Z"})
+	page := b.AddPage(PageSizeLetter)
+	page.Content().BeginText().
+		SetFont(page.AddStandardFont("Helvetica"), 12).
+		SetFont(page.AddStandardFont("Times-Roman"), 12).
+		EndText()
+	b.FinalizePage(page)
+	b.SetAcroForm(testAcroForm{b.Writer()})
+	b.SetCatalogEntry("PageMode", "/UseOutlines")
+
+	pdfBytes, err := b.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to generate PDF: %v", err)
+	}
+	return pdfBytes
+}
+
+func TestSimplePDFBuilder_Deterministic(t *testing.T) {
+	first, second := buildSimpleTestPDF(t), buildSimpleTestPDF(t)
+	if !bytes.Equal(first, second) {
+		t.Error("Identical documents produced different output")
+	}
+	if !bytes.Contains(first, []byte("/ID [<")) {
+		t.Error("Trailer /ID missing")
+	}
+	if !bytes.Contains(first, []byte("/Font<</F1 ")) || !bytes.Contains(first, []byte(" 0 R/F2 ")) {
+		t.Error("Font resources not sorted by name")
+	}
+}
+
+func TestSimplePDFBuilder_Write(t *testing.T) {
+	b := NewSimplePDFBuilder()
+	b.FinalizePage(b.AddPage(PageSizeLetter))
+	var buf bytes.Buffer
+	if err := b.Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	pdfBytes, err := b.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes failed: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), pdfBytes) {
+		t.Error("Write and Bytes produced different output")
+	}
+}
+
+func TestSimplePDFBuilder_Catalog(t *testing.T) {
+	pdf, err := parse.Open(buildSimpleTestPDF(t))
+	if err != nil {
+		t.Fatalf("Failed to parse PDF: %v", err)
+	}
+	trailer := pdf.Trailer()
+
+	rootObjNum, infoObjNum := 0, 0
+	fmt.Sscanf(trailer.RootRef, "%d 0 R", &rootObjNum)
+	fmt.Sscanf(trailer.InfoRef, "%d 0 R", &infoObjNum)
+	catalog, err := pdf.GetObject(rootObjNum)
+	if err != nil {
+		t.Fatalf("Failed to get catalog: %v", err)
+	}
+	// Fonts 1-2, Pages 3, content 4, page 5, AcroForm 6, widget 7, Info 8, catalog 9
+	if !strings.Contains(string(catalog), "<</Type/Catalog/Pages 3 0 R/AcroForm 6 0 R/PageMode /UseOutlines>>") {
+		t.Errorf("Unexpected catalog %s", catalog)
+	}
+
+	info, err := pdf.GetObject(infoObjNum)
+	if err != nil {
+		t.Fatalf("Failed to get info dictionary: %v", err)
+	}
+	for _, want := range []string{"/Title (Test)", "/Producer (pdfer)", "/CreationDate (D:20240305100000", "/ModDate (D:20240305100000"} {
+		if !strings.Contains(string(info), want) {
+			t.Errorf("Info dictionary %s missing %s", info, want)
+		}
+	}
+	if infoObjNum != 8 || rootObjNum != 9 {
+		t.Errorf("Expected info dictionary 8 and catalog 9, got %d and %d", infoObjNum, rootObjNum)
+	}
+
+	page, err := pdf.GetObject(5)
+	if err != nil || !strings.Contains(string(page), "/Annots[7 0 R]") {
+		t.Errorf("Widget not added to page: %s", page)
+	}
+}
+
+func TestSimplePDFBuilder_OutlinesKeepCatalogEntries(t *testing.T) {
+	b := NewSimplePDFBuilder()
+	page := b.AddPage(PageSizeLetter)
+	b.FinalizePage(page)
+	b.SetAcroForm(testAcroForm{b.Writer()})
+	if err := b.SetBookmarks([]types.Bookmark{{Title: "Start", PageNumber: 1}}); err != nil {
+		t.Fatalf("SetBookmarks failed: %v", err)
+	}
+
+	first, err := b.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to generate PDF: %v", err)
+	}
+	second, _ := b.Bytes()
+	if !bytes.Equal(first, second) {
+		t.Error("Calling Bytes twice produced different output")
+	}
+	if bytes.Count(first, []byte("/Type/Catalog")) != 1 {
+		t.Error("Expected a single catalog")
+	}
+	if !bytes.Contains(first, []byte("/AcroForm ")) || !bytes.Contains(first, []byte("/Outlines ")) {
+		t.Error("Catalog lost /AcroForm or /Outlines")
+	}
+}
@@ -0,0 +1,88 @@
+package write
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+)
+
+// xfaEntryPattern matches an /XFA entry of an AcroForm dictionary
+var xfaEntryPattern = regexp.MustCompile(`/XFA\s*(\[|\d+\s+\d+\s+R)`)
+
+// UsePDF20 enables the PDF 2.0 profile (ISO 32000-2): the header and the catalog
+// /Version are 2.0, text strings with non-ASCII characters are written as UTF-8,
+// and features PDF 2.0 removes are refused when writing: encryption other than
+// AES-256 (V5) and XFA forms. Disabling it restores version 1.7.
+func (w *PDFWriter) UsePDF20(enable bool) {
+	w.pdf20 = enable
+	if enable {
+		w.pdfVersion = "2.0"
+	} else if w.pdfVersion == "2.0" {
+		w.pdfVersion = "1.7"
+	}
+}
+
+// checkPDF20 reports content that PDF 2.0 does not allow
+func (w *PDFWriter) checkPDF20() error {
+	if w.encryptInfo != nil && w.encryptInfo.V != 5 {
+		return fmt.Errorf("PDF 2.0 requires AES-256 (V5) encryption, got V%d", w.encryptInfo.V)
+	}
+	for _, obj := range w.objects {
+		if obj.Stream == nil && xfaEntryPattern.Match(obj.Content) {
+			return fmt.Errorf("PDF 2.0 does not support XFA forms (object %d)", obj.Number)
+		}
+	}
+	return nil
+}
+
+// setCatalogVersion sets the catalog /Version to the PDF version, which takes
+// precedence over the header
+func (w *PDFWriter) setCatalogVersion() {
+	rootObjNum := 0
+	fmt.Sscanf(w.rootRef, "%d 0 R", &rootObjNum)
+	catalogObj, ok := w.objects[rootObjNum]
+	if !ok || catalogObj == nil {
+		return
+	}
+	version := "/" + w.pdfVersion
+
+	if catalogObj.Dict != nil {
+		catalogObj.Dict["/Version"] = version
+		catalogObj.Content = w.formatDictionary(catalogObj.Dict)
+		return
+	}
+	catalogStr := string(catalogObj.Content)
+	if strings.Contains(catalogStr, "/Version") {
+		return
+	}
+	if lastIdx := strings.LastIndex(catalogStr, ">>"); lastIdx > 0 {
+		catalogObj.Content = []byte(catalogStr[:lastIdx] + "/Version " + version + catalogStr[lastIdx:])
+	}
+}
+
+// textString encodes a text string for a PDF string literal: ASCII as is, other
+// text as UTF-8 under PDF 2.0 and as UTF-16BE before, both with a byte order mark
+func (w *PDFWriter) textString(s string) string {
+	ascii := true
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return s
+	}
+	if w.pdf20 {
+		return "\xEF\xBB\xBF" + s
+	}
+
+	var b strings.Builder
+	b.WriteString("\xFE\xFF")
+	for _, u := range utf16.Encode([]rune(s)) {
+		b.WriteByte(byte(u >> 8))
+		b.WriteByte(byte(u))
+	}
+	return b.String()
+}
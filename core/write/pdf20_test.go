@@ -0,0 +1,64 @@
+package write
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+func TestPDFWriter_UsePDF20(t *testing.T) {
+	b := NewSimplePDFBuilder()
+	b.Writer().UsePDF20(true)
+	b.SetMetadata(&types.DocumentMetadata{Title: "Résumé"})
+	b.FinalizePage(b.AddPage(PageSizeLetter))
+	pdfBytes, err := b.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to generate PDF: %v", err)
+	}
+	if !bytes.HasPrefix(pdfBytes, []byte("%PDF-2.0\n")) {
+		t.Errorf("Unexpected header %q", pdfBytes[:9])
+	}
+	if !bytes.Contains(pdfBytes, []byte("/Version /2.0>>")) {
+		t.Error("Catalog /Version missing")
+	}
+	if !bytes.Contains(pdfBytes, []byte("/Title (\xEF\xBB\xBFRésumé)")) {
+		t.Error("Title not written as UTF-8 with a byte order mark")
+	}
+
+	w := NewPDFWriter()
+	w.UsePDF20(true)
+	w.UsePDF20(false)
+	if w.pdfVersion != "1.7" {
+		t.Errorf("Expected version 1.7 after disabling PDF 2.0, got %s", w.pdfVersion)
+	}
+}
+
+func TestPDFWriter_UsePDF20_Refused(t *testing.T) {
+	w := NewPDFWriter()
+	w.UsePDF20(true)
+	w.SetRoot(w.AddObject([]byte("<</Type/Catalog/AcroForm 2 0 R>>")))
+	w.AddObject([]byte("<</Fields[]/XFA[(template)3 0 R]>>"))
+	if _, err := w.Bytes(); err == nil || !strings.Contains(err.Error(), "XFA") {
+		t.Errorf("Expected XFA error, got %v", err)
+	}
+
+	w = NewPDFWriter()
+	w.UsePDF20(true)
+	w.SetRoot(w.AddObject([]byte("<</Type/Catalog>>")))
+	w.SetEncryption(&types.PDFEncryption{V: 4, R: 4}, []byte("0123456789abcdef"))
+	if _, err := w.Bytes(); err == nil || !strings.Contains(err.Error(), "AES-256") {
+		t.Errorf("Expected encryption error, got %v", err)
+	}
+}
+
+func TestPDFWriter_TextString(t *testing.T) {
+	w := NewPDFWriter()
+	if got := w.textString("plain"); got != "plain" {
+		t.Errorf("textString(ASCII) = %q", got)
+	}
+	if got := w.textString("é\U0001F600"); got != "\xFE\xFF\x00\xE9\xD8\x3D\xDE\x00" {
+		t.Errorf("textString before PDF 2.0 = %q", got)
+	}
+}
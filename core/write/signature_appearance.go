@@ -0,0 +1,190 @@
+package write
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Signature appearance defaults, in points
+const (
+	signaturePadding     = 2
+	signatureMaxFontSize = 12
+	signatureLeading     = 1.2 // Line height as a multiple of the font size
+	signatureLogoOpacity = 0.3
+)
+
+// SignatureAppearance describes the visible appearance of a signature field:
+// the signer's name, the reason, location and date of signing, a logo drawn
+// faded behind the text and an image of a handwritten signature.
+type SignatureAppearance struct {
+	Width, Height float64   // Size of the signature widget in points
+	Name          string    // Signer name, shown as "Digitally signed by <Name>"
+	Reason        string    // Reason for signing
+	Location      string    // Place of signing
+	Date          time.Time // Signing time; the zero time omits the date
+	Logo          []byte    // JPEG or PNG drawn faded behind the text, scaled to fit
+	Handwriting   []byte    // JPEG or PNG of a handwritten signature, drawn in the left half
+	FontName      string    // Standard font for the text (default Helvetica)
+	FontSize      float64   // Font size; 0 uses the largest size up to 12 points that fits
+}
+
+// Lines returns the lines of text shown in the appearance
+func (a SignatureAppearance) Lines() []string {
+	var lines []string
+	if a.Name != "" {
+		lines = append(lines, "Digitally signed by "+a.Name)
+	}
+	if !a.Date.IsZero() {
+		lines = append(lines, "Date: "+formatSignatureDate(a.Date))
+	}
+	if a.Reason != "" {
+		lines = append(lines, "Reason: "+a.Reason)
+	}
+	if a.Location != "" {
+		lines = append(lines, "Location: "+a.Location)
+	}
+	return lines
+}
+
+// AddSignatureAppearance writes the appearance of a signature widget and returns
+// the object number of the Form XObject to use as its /AP /N entry.
+//
+// The appearance follows Adobe's layered conventions: it draws a Form XObject
+// named FRM, which draws the n0 background layer, left blank ("% DSBlank") as
+// Acrobat 6 and later expect, and the n2 signature layer holding the logo,
+// handwriting and text. Viewers that validate the signature may draw their own
+// status layers over n2.
+func (w *PDFWriter) AddSignatureAppearance(a SignatureAppearance) (int, error) {
+	if a.Width <= 0 || a.Height <= 0 {
+		return 0, fmt.Errorf("invalid signature appearance size %gx%g", a.Width, a.Height)
+	}
+	bbox := []interface{}{0, 0, a.Width, a.Height}
+
+	n0 := w.AddStreamObject(Dictionary{
+		"Type":      "/XObject",
+		"Subtype":   "/Form",
+		"BBox":      bbox,
+		"Resources": Dictionary{},
+	}, []byte("% DSBlank\n"), true)
+
+	n2, err := w.addSignatureLayer(a)
+	if err != nil {
+		return 0, err
+	}
+
+	frm := w.AddStreamObject(Dictionary{
+		"Type":      "/XObject",
+		"Subtype":   "/Form",
+		"BBox":      bbox,
+		"Resources": Dictionary{"XObject": resourceRefs(map[string]int{"n0": n0, "n2": n2})},
+	}, []byte("q 1 0 0 1 0 0 cm /n0 Do Q\nq 1 0 0 1 0 0 cm /n2 Do Q\n"), true)
+
+	return w.AddStreamObject(Dictionary{
+		"Type":      "/XObject",
+		"Subtype":   "/Form",
+		"BBox":      bbox,
+		"Resources": Dictionary{"XObject": resourceRefs(map[string]int{"FRM": frm})},
+	}, []byte("q 1 0 0 1 0 0 cm /FRM Do Q\n"), true), nil
+}
+
+// addSignatureLayer writes the n2 layer of a signature appearance
+func (w *PDFWriter) addSignatureLayer(a SignatureAppearance) (int, error) {
+	cs := NewContentStream()
+	images := make(map[string]int)
+	resources := Dictionary{}
+
+	if len(a.Logo) > 0 {
+		info, err := w.AddImage(a.Logo, "")
+		if err != nil {
+			return 0, fmt.Errorf("invalid signature logo: %w", err)
+		}
+		images["Logo"] = info.ObjectNum
+		resources["ExtGState"] = Dictionary{"GS1": Dictionary{"ca": signatureLogoOpacity, "CA": signatureLogoOpacity}}
+		cs.SaveState()
+		cs.Raw("/GS1 gs\n")
+		drawImageFit(cs, "/Logo", info, 0, 0, a.Width, a.Height)
+		cs.RestoreState()
+	}
+
+	textX := 0.0
+	if len(a.Handwriting) > 0 {
+		info, err := w.AddImage(a.Handwriting, "")
+		if err != nil {
+			return 0, fmt.Errorf("invalid signature image: %w", err)
+		}
+		images["Handwriting"] = info.ObjectNum
+		textX = a.Width / 2
+		drawImageFit(cs, "/Handwriting", info, signaturePadding, signaturePadding, textX-2*signaturePadding, a.Height-2*signaturePadding)
+	}
+
+	if lines := a.Lines(); len(lines) > 0 {
+		fontName := a.FontName
+		if fontName == "" {
+			fontName = "Helvetica"
+		}
+		size := a.FontSize
+		if size <= 0 {
+			size = fitSignatureText(lines, fontName, a.Width-textX-2*signaturePadding, a.Height-2*signaturePadding)
+		}
+		resources["Font"] = Dictionary{"F1": fmt.Sprintf("%d 0 R", w.AddObject([]byte(
+			fmt.Sprintf("<</Type/Font/Subtype/Type1/BaseFont/%s/Encoding/WinAnsiEncoding>>", fontName))))}
+
+		cs.BeginText()
+		cs.SetFont("/F1", size)
+		cs.SetTextLeading(size * signatureLeading)
+		cs.SetTextPosition(textX+signaturePadding, a.Height-signaturePadding-size)
+		for i, line := range lines {
+			if i > 0 {
+				cs.NextLine()
+			}
+			cs.ShowTextWithOptions(line, &ShowTextOptions{Replacements: ASCIIReplacements})
+		}
+		cs.EndText()
+	}
+
+	if len(images) > 0 {
+		resources["XObject"] = resourceRefs(images)
+	}
+	return w.AddStreamObject(Dictionary{
+		"Type":      "/XObject",
+		"Subtype":   "/Form",
+		"BBox":      []interface{}{0, 0, a.Width, a.Height},
+		"Resources": resources,
+	}, cs.Bytes(), true), nil
+}
+
+// drawImageFit draws an image centered in a box, scaled to fit while keeping its
+// aspect ratio
+func drawImageFit(cs *ContentStream, name string, info *ImageInfo, x, y, width, height float64) {
+	if info.Width <= 0 || info.Height <= 0 || width <= 0 || height <= 0 {
+		return
+	}
+	scale := math.Min(width/float64(info.Width), height/float64(info.Height))
+	w, h := float64(info.Width)*scale, float64(info.Height)*scale
+	cs.DrawImageAt(name, x+(width-w)/2, y+(height-h)/2, w, h)
+}
+
+// fitSignatureText returns the largest font size up to signatureMaxFontSize at
+// which lines fit in a box
+func fitSignatureText(lines []string, fontName string, width, height float64) float64 {
+	size := math.Min(signatureMaxFontSize, height/(float64(len(lines))*signatureLeading))
+	for _, line := range lines {
+		if lineWidth := StandardTextWidth(line, fontName, 1); lineWidth > 0 {
+			size = math.Min(size, width/lineWidth)
+		}
+	}
+	return math.Max(size, 1)
+}
+
+// formatSignatureDate formats a signing time as Acrobat shows it, e.g.
+// "2024.03.15 10:30:00 +01'00'"
+func formatSignatureDate(t time.Time) string {
+	_, offset := t.Zone()
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	return fmt.Sprintf("%s %c%02d'%02d'", t.Format("2006.01.02 15:04:05"), sign, offset/3600, offset/60%60)
+}
@@ -0,0 +1,91 @@
+package write
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddSignatureAppearance(t *testing.T) {
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewGray(image.Rect(0, 0, 40, 10))); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+
+	w := NewPDFWriter()
+	if _, err := w.AddSignatureAppearance(SignatureAppearance{Name: "Jane Doe"}); err == nil {
+		t.Error("Expected error for an appearance without a size")
+	}
+	if _, err := w.AddSignatureAppearance(SignatureAppearance{Width: 200, Height: 50, Logo: []byte("not an image")}); err == nil {
+		t.Error("Expected error for an invalid logo")
+	}
+
+	ap, err := w.AddSignatureAppearance(SignatureAppearance{
+		Width:       200,
+		Height:      50,
+		Name:        "Jane Doe",
+		Reason:      "I approve this document",
+		Location:    "Berlin",
+		Date:        time.Date(2024, 3, 15, 10, 30, 0, 0, time.FixedZone("CET", 3600)),
+		Logo:        img.Bytes(),
+		Handwriting: img.Bytes(),
+	})
+	if err != nil {
+		t.Fatalf("AddSignatureAppearance failed: %v", err)
+	}
+
+	// N -> FRM -> n0, n2
+	layer := func(objNum int, name string) int {
+		t.Helper()
+		ref, ok := w.objects[objNum].Dict["Resources"].(Dictionary)["XObject"].(Dictionary)[name].(string)
+		if !ok {
+			t.Fatalf("Object %d has no %s XObject", objNum, name)
+		}
+		var layerNum int
+		if _, err := fmt.Sscanf(ref, "%d 0 R", &layerNum); err != nil {
+			t.Fatalf("Invalid reference %q", ref)
+		}
+		return layerNum
+	}
+	if content := decompressStream(t, w.objects[ap].Stream); !strings.Contains(content, "/FRM Do") {
+		t.Errorf("Appearance content = %q", content)
+	}
+	frm := layer(ap, "FRM")
+	if content := decompressStream(t, w.objects[frm].Stream); !strings.Contains(content, "/n0 Do Q\nq 1 0 0 1 0 0 cm /n2 Do") {
+		t.Errorf("FRM content = %q", content)
+	}
+	if content := decompressStream(t, w.objects[layer(frm, "n0")].Stream); content != "% DSBlank\n" {
+		t.Errorf("n0 content = %q", content)
+	}
+
+	n2 := layer(frm, "n2")
+	content := decompressStream(t, w.objects[n2].Stream)
+	for _, want := range []string{
+		"/GS1 gs", "/Logo Do", "/Handwriting Do",
+		"(Digitally signed by Jane Doe)", "(Date: 2024.03.15 10:30:00 +01'00')", "(Reason: I approve this document)", "(Location: Berlin)",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("n2 content has no %q:\n%s", want, content)
+		}
+	}
+	if strings.Index(content, "/Logo Do") > strings.Index(content, "BT") {
+		t.Error("Logo drawn over the text")
+	}
+
+	// The text fits the right half of the widget
+	size := fitSignatureText(SignatureAppearance{Name: "Jane Doe", Reason: "I approve this document"}.Lines(), "Helvetica", 96, 46)
+	if size <= 0 || StandardTextWidth("Reason: I approve this document", "Helvetica", size) > 96 {
+		t.Errorf("Font size %g does not fit", size)
+	}
+}
+
+func TestFormatSignatureDate(t *testing.T) {
+	date := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("", -(5*3600+30*60)))
+	if got, want := formatSignatureDate(date), "2024.01.02 03:04:05 -05'30'"; got != want {
+		t.Errorf("formatSignatureDate = %q, want %q", got, want)
+	}
+}
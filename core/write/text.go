@@ -0,0 +1,486 @@
+package write
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/benedoc-inc/pdfer/resources/font"
+)
+
+// BidiMode selects how ShowTextWithOptions treats Unicode bidirectional formatting
+// characters (U+200E, U+200F, U+061C and U+202A-U+202E, U+2066-U+2069)
+type BidiMode int
+
+const (
+	// BidiKeep passes the control characters on to encoding, where they usually
+	// cannot be encoded and are reported
+	BidiKeep BidiMode = iota
+	// BidiStrip removes the control characters and keeps the logical order
+	BidiStrip
+	// BidiHonor reorders the text into visual order following the embeddings,
+	// overrides and isolates the controls set up, then removes them. Right-to-left
+	// letters (Hebrew, Arabic and related scripts) are reversed even without
+	// controls; the implicit rules are simplified to strong letters, digits and
+	// neutrals that take the direction of their surroundings.
+	BidiHonor
+)
+
+// ShowTextOptions controls how ShowTextWithOptions prepares text for a font
+type ShowTextOptions struct {
+	Normalize bool     // Convert the text to Unicode NFC first
+	Bidi      BidiMode // Handling of bidirectional control characters
+
+	// Replacements maps characters the font cannot encode to text that it can,
+	// e.g. '→' to "->". See ASCIIReplacements.
+	Replacements map[rune]string
+	// Replacement is written for characters that cannot be encoded and have no
+	// entry in Replacements (default "?")
+	Replacement string
+
+	// Font is the embedded font the text is shown with. Characters it has no glyph
+	// for cannot be encoded; text for a Type0 font (Subtype "CIDFontType2") is
+	// written as glyph IDs. When nil, text is encoded as WinAnsiEncoding for a
+	// standard font added with AddStandardFont.
+	Font *font.Font
+}
+
+// TextReport lists what ShowTextWithOptions had to change to encode text
+type TextReport struct {
+	Replaced    []rune // Characters written with their entry in Replacements
+	Unencodable []rune // Characters written as the Replacement string
+}
+
+// OK reports whether all characters were encoded as given
+func (r *TextReport) OK() bool {
+	return len(r.Replaced) == 0 && len(r.Unencodable) == 0
+}
+
+// ASCIIReplacements maps common typographic characters to ASCII, for fonts that
+// only cover basic Latin
+var ASCIIReplacements = map[rune]string{
+	'\u00A0': " ", '\u2002': " ", '\u2003': " ", '\u2009': " ", '\u202F': " ",
+	'‐': "-", '\u2011': "-", '‒': "-", '–': "-", '—': "--", '−': "-",
+	'‘': "'", '’': "'", '‚': ",", '“': "\"", '”': "\"", '„': "\"",
+	'…': "...", '•': "*", '·': ".", '←': "<-", '→': "->",
+	'©': "(c)", '®': "(R)", '™': "TM", '€': "EUR",
+	'¼': "1/4", '½': "1/2", '¾': "3/4", '×': "x",
+}
+
+// ShowTextWithOptions shows text (Tj operator) after normalizing it, resolving
+// bidirectional controls and encoding it for the font. Characters that cannot be
+// encoded are replaced and listed in the returned report. Options can be nil.
+func (cs *ContentStream) ShowTextWithOptions(text string, options *ShowTextOptions) *TextReport {
+	encoded, hex, report := EncodeText(text, options)
+	if hex {
+		cs.ShowTextHex(encoded)
+	} else {
+		cs.buf.WriteString(fmt.Sprintf("(%s) Tj\n", encoded))
+	}
+	return report
+}
+
+// EncodeText prepares text as ShowTextWithOptions does and returns the string to
+// show: a hex string of glyph IDs when hex is true, otherwise the body of an
+// escaped literal string.
+func EncodeText(text string, options *ShowTextOptions) (encoded string, hex bool, report *TextReport) {
+	if options == nil {
+		options = &ShowTextOptions{}
+	}
+	report = &TextReport{}
+
+	if options.Normalize {
+		text = NormalizeNFC(text)
+	}
+	switch options.Bidi {
+	case BidiStrip:
+		text = stripBidiControls(text)
+	case BidiHonor:
+		text = reorderBidi(text)
+	}
+
+	fallback := options.Replacement
+	if fallback == "" {
+		fallback = "?"
+	}
+	hex = options.Font != nil && options.Font.Subtype == "CIDFontType2"
+
+	var out strings.Builder
+	var write func(r rune, depth int)
+	write = func(r rune, depth int) {
+		if options.Font != nil {
+			if gid, ok := options.Font.GlyphIndex(r); ok && (hex || r < 256) {
+				options.Font.AddRune(r)
+				if hex {
+					out.WriteString(fmt.Sprintf("%04X", gid))
+				} else {
+					out.WriteString(escapeEncodedByte(byte(r)))
+				}
+				return
+			}
+		} else if b, ok := winAnsiByte(r); ok {
+			out.WriteString(escapeEncodedByte(b))
+			return
+		}
+
+		if replacement, ok := options.Replacements[r]; ok && depth == 0 {
+			report.Replaced = appendUnique(report.Replaced, r)
+			for _, rr := range replacement {
+				write(rr, depth+1)
+			}
+			return
+		}
+		if depth == 0 {
+			report.Unencodable = appendUnique(report.Unencodable, r)
+		}
+		if depth < 2 {
+			for _, rr := range fallback {
+				write(rr, 2)
+			}
+		}
+	}
+	for _, r := range text {
+		write(r, 0)
+	}
+	return out.String(), hex, report
+}
+
+func appendUnique(runes []rune, r rune) []rune {
+	for _, existing := range runes {
+		if existing == r {
+			return runes
+		}
+	}
+	return append(runes, r)
+}
+
+// escapeEncodedByte returns a byte of an encoded string as it appears in a literal string
+func escapeEncodedByte(b byte) string {
+	switch {
+	case b == '(' || b == ')' || b == '\\':
+		return "\\" + string(rune(b))
+	case b < 32 || b >= 127:
+		return fmt.Sprintf("\\%03o", b)
+	}
+	return string(rune(b))
+}
+
+// winAnsiSpecials maps the characters WinAnsiEncoding places in 0x80-0x9F
+var winAnsiSpecials = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86,
+	'‡': 0x87, 'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C,
+	'Ž': 0x8E, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95,
+	'–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B,
+	'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// winAnsiByte returns the WinAnsiEncoding code of r
+func winAnsiByte(r rune) (byte, bool) {
+	switch {
+	case r == '\t' || r == '\n' || r == '\r':
+		return byte(r), true
+	case r >= 0x20 && r < 0x7F, r >= 0xA0 && r <= 0xFF:
+		return byte(r), true
+	}
+	b, ok := winAnsiSpecials[r]
+	return b, ok
+}
+
+// Bidirectional formatting characters
+const (
+	bidiLRM = '\u200E'
+	bidiRLM = '\u200F'
+	bidiALM = '\u061C'
+	bidiLRE = '\u202A'
+	bidiRLE = '\u202B'
+	bidiPDF = '\u202C'
+	bidiLRO = '\u202D'
+	bidiRLO = '\u202E'
+	bidiLRI = '\u2066'
+	bidiRLI = '\u2067'
+	bidiFSI = '\u2068'
+	bidiPDI = '\u2069'
+)
+
+func isBidiControl(r rune) bool {
+	switch r {
+	case bidiLRM, bidiRLM, bidiALM, bidiLRE, bidiRLE, bidiPDF, bidiLRO, bidiRLO, bidiLRI, bidiRLI, bidiFSI, bidiPDI:
+		return true
+	}
+	return false
+}
+
+func stripBidiControls(text string) string {
+	return strings.Map(func(r rune) rune {
+		if isBidiControl(r) {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// Simplified bidirectional character types
+const (
+	bidiNeutral = iota
+	bidiL
+	bidiR
+	bidiNumber
+)
+
+// rtlScripts are the scripts whose letters are strong right-to-left
+var rtlScripts = []*unicode.RangeTable{
+	unicode.Hebrew, unicode.Arabic, unicode.Syriac, unicode.Thaana, unicode.Nko,
+	unicode.Samaritan, unicode.Mandaic,
+}
+
+func bidiType(r rune) int {
+	switch {
+	case r == bidiLRM:
+		return bidiL
+	case r == bidiRLM || r == bidiALM:
+		return bidiR
+	case unicode.IsDigit(r):
+		return bidiNumber
+	case unicode.IsLetter(r):
+		if unicode.In(r, rtlScripts...) {
+			return bidiR
+		}
+		return bidiL
+	}
+	return bidiNeutral
+}
+
+// bidiMirrors holds the characters drawn mirrored in right-to-left text
+var bidiMirrors = map[rune]rune{
+	'(': ')', ')': '(', '[': ']', ']': '[', '{': '}', '}': '{', '<': '>', '>': '<',
+	'«': '»', '»': '«', '‹': '›', '›': '‹',
+}
+
+// reorderBidi converts text from logical to visual order, see BidiHonor
+func reorderBidi(text string) string {
+	type entry struct {
+		level    int
+		override int // bidiL, bidiR or bidiNeutral
+		isolate  bool
+	}
+	runes := []rune(text)
+	levels := make([]int, len(runes))
+	types := make([]int, len(runes))
+	keep := make([]bool, len(runes))
+
+	// Explicit levels (X1-X9)
+	stack := []entry{{level: 0}}
+	nextLevel := func(odd bool) int {
+		level := stack[len(stack)-1].level + 1
+		if (level%2 == 1) != odd {
+			level++
+		}
+		return level
+	}
+	for i, r := range runes {
+		top := stack[len(stack)-1]
+		levels[i] = top.level
+		switch r {
+		case bidiLRE, bidiLRO:
+			override := bidiNeutral
+			if r == bidiLRO {
+				override = bidiL
+			}
+			stack = append(stack, entry{level: nextLevel(false), override: override})
+		case bidiRLE, bidiRLO:
+			override := bidiNeutral
+			if r == bidiRLO {
+				override = bidiR
+			}
+			stack = append(stack, entry{level: nextLevel(true), override: override})
+		case bidiLRI, bidiRLI, bidiFSI:
+			rtl := r == bidiRLI
+			if r == bidiFSI {
+				rtl = firstStrongIsRTL(runes[i+1:])
+			}
+			stack = append(stack, entry{level: nextLevel(rtl), isolate: true})
+		case bidiPDF:
+			if len(stack) > 1 && !top.isolate {
+				stack = stack[:len(stack)-1]
+			}
+		case bidiPDI:
+			for len(stack) > 1 && !stack[len(stack)-1].isolate {
+				stack = stack[:len(stack)-1]
+			}
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+			levels[i] = stack[len(stack)-1].level
+		default:
+			keep[i] = true
+			types[i] = bidiType(r)
+			if top.override != bidiNeutral {
+				types[i] = top.override
+			} else if unicode.Is(unicode.Mn, r) && i > 0 {
+				types[i] = types[i-1] // marks take the type of their base
+			}
+		}
+	}
+
+	// Drop the embedding, override and isolate controls (X9)
+	var chars []rune
+	var charLevels, charTypes []int
+	for i, r := range runes {
+		if keep[i] {
+			chars = append(chars, r)
+			charLevels = append(charLevels, levels[i])
+			charTypes = append(charTypes, types[i])
+		}
+	}
+
+	// Neutrals between characters of the same direction take that direction, the
+	// rest the direction of their embedding (N1, N2); numbers count as R (W7 aside)
+	direction := func(level int) int {
+		if level%2 == 1 {
+			return bidiR
+		}
+		return bidiL
+	}
+	for start := 0; start < len(chars); {
+		if charTypes[start] != bidiNeutral {
+			start++
+			continue
+		}
+		end := start
+		for end < len(chars) && charTypes[end] == bidiNeutral && charLevels[end] == charLevels[start] {
+			end++
+		}
+		before, after := direction(charLevels[start]), direction(charLevels[start])
+		if start > 0 && charLevels[start-1] == charLevels[start] {
+			before = strongDirection(charTypes[start-1])
+		}
+		if end < len(chars) && charLevels[end] == charLevels[start] {
+			after = strongDirection(charTypes[end])
+		}
+		resolved := direction(charLevels[start])
+		if before == after {
+			resolved = before
+		}
+		for i := start; i < end; i++ {
+			charTypes[i] = resolved
+		}
+		start = end
+	}
+
+	// Implicit levels (I1, I2)
+	maxLevel, minOddLevel := 0, -1
+	for i, t := range charTypes {
+		level := charLevels[i]
+		switch {
+		case level%2 == 0 && t == bidiR:
+			level++
+		case level%2 == 0 && t == bidiNumber:
+			level += 2
+		case level%2 == 1 && (t == bidiL || t == bidiNumber):
+			level++
+		}
+		charLevels[i] = level
+		if level > maxLevel {
+			maxLevel = level
+		}
+		if level%2 == 1 && (minOddLevel < 0 || level < minOddLevel) {
+			minOddLevel = level
+		}
+	}
+
+	// Reverse every run at or above each level, from the highest down to the lowest
+	// odd level (L2), and mirror paired characters in right-to-left runs (L4)
+	if minOddLevel < 0 {
+		return stripBidiControls(string(chars))
+	}
+	for i, r := range chars {
+		if charLevels[i]%2 == 1 {
+			if m, ok := bidiMirrors[r]; ok {
+				chars[i] = m
+			}
+		}
+	}
+	for level := maxLevel; level >= minOddLevel; level-- {
+		for start := 0; start < len(chars); {
+			if charLevels[start] < level {
+				start++
+				continue
+			}
+			end := start
+			for end < len(chars) && charLevels[end] >= level {
+				end++
+			}
+			for i, j := start, end-1; i < j; i, j = i+1, j-1 {
+				chars[i], chars[j] = chars[j], chars[i]
+				charLevels[i], charLevels[j] = charLevels[j], charLevels[i]
+			}
+			start = end
+		}
+	}
+
+	// LRM, RLM and ALM took part as strong characters and are dropped now
+	return stripBidiControls(string(chars))
+}
+
+// strongDirection treats numbers as right-to-left when resolving neutrals
+func strongDirection(t int) int {
+	if t == bidiNumber {
+		return bidiR
+	}
+	return t
+}
+
+// firstStrongIsRTL reports whether the first strong character of an isolate,
+// up to its matching PDI, is right-to-left (for FSI)
+func firstStrongIsRTL(runes []rune) bool {
+	depth := 0
+	for _, r := range runes {
+		switch r {
+		case bidiLRI, bidiRLI, bidiFSI:
+			depth++
+			continue
+		case bidiPDI:
+			if depth == 0 {
+				return false
+			}
+			depth--
+			continue
+		}
+		if depth == 0 {
+			switch bidiType(r) {
+			case bidiL:
+				return false
+			case bidiR:
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// helveticaWidths are the Helvetica glyph widths (1/1000 em) for ASCII 32-126
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// StandardTextWidth returns the width of text in a standard font. Helvetica and
+// Courier widths are exact for ASCII; other fonts are approximated.
+func StandardTextWidth(text, fontName string, size float64) float64 {
+	width := 0.0
+	for _, r := range text {
+		switch {
+		case strings.HasPrefix(fontName, "Courier"):
+			width += 600
+		case fontName == "Helvetica" && r >= 32 && r <= 126:
+			width += float64(helveticaWidths[r-32])
+		default:
+			width += 556
+		}
+	}
+	return width * size / 1000
+}
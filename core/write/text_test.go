@@ -0,0 +1,123 @@
+package write
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/resources/font"
+)
+
+func TestNormalizeNFC(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain ascii", "plain ascii"},
+		{"e\u0301te\u0301", "\u00E9t\u00E9"},
+		{"\u212B", "\u00C5"},                   // Angstrom sign is a singleton decomposition
+		{"a\u0323\u0302", "\u1EAD"},            // marks composed in combining class order
+		{"a\u0302\u0323", "\u1EAD"},            // and reordered first when out of order
+		{"\u0915\u093C", "\u0915\u093C"},       // composition exclusion stays decomposed
+		{"\u1100\u1161\u11A8", "\uAC01"},       // Hangul LVT
+		{"\u00C5\u0301", "\u01FA"},             // recomposes across a precomposed base
+		{"e\u0308\u0301x", "\u00EB\u0301x"},    // second mark has no composite with ë
+		{"\u0041\u030A\u0327", "\u00C5\u0327"}, // cedilla sorts first but does not compose with A
+	}
+	for _, tt := range tests {
+		if got := NormalizeNFC(tt.in); got != tt.want {
+			t.Errorf("NormalizeNFC(%+q) = %+q, want %+q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeNFKC(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain ascii", "plain ascii"},
+		{"\uFB01nance", "finance"}, // ligature
+		{"x\u00B2", "x2"},          // superscript
+		{"\uFF21\uFF22", "AB"},     // fullwidth
+		{"\u00BD", "1\u20442"},     // fraction
+		{"\u1E9B\u0323", "\u1E69"}, // compatibility and canonical marks recomposed
+		{"\u3326", "\u30C9\u30EB"}, // square katakana decomposed and recomposed
+		{"e\u0301", "\u00E9"},      // canonical composition as in NFC
+	}
+	for _, tt := range tests {
+		if got := NormalizeNFKC(tt.in); got != tt.want {
+			t.Errorf("NormalizeNFKC(%+q) = %+q, want %+q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestReorderBidi(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"ltr", "abc 123", "abc 123"},
+		{"rtl", "\u05D0\u05D1 \u05D2\u05D3", "\u05D3\u05D2 \u05D1\u05D0"},
+		{"rtl with number", "\u05D0\u05D1 123", "123 \u05D1\u05D0"},
+		{"mixed", "abc \u05D0\u05D1 def", "abc \u05D1\u05D0 def"},
+		{"mirrored", "\u05D0(\u05D1)\u05D2", "\u05D2(\u05D1)\u05D0"},
+		{"override", "ab\u202Ecd\u202Cef", "abdcef"},
+		{"ltr override of rtl", "\u202D\u05D0\u05D1\u202C", "\u05D0\u05D1"},
+		{"isolate", "\u2067abc\u2069 d", "abc d"},
+		{"marks", "marks\u200E", "marks"},
+	}
+	for _, tt := range tests {
+		if got := reorderBidi(tt.in); got != tt.want {
+			t.Errorf("%s: reorderBidi(%+q) = %+q, want %+q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestShowTextWithOptions(t *testing.T) {
+	cs := NewContentStream()
+	report := cs.ShowTextWithOptions("Café (1€) → ‮ok‬ 中", &ShowTextOptions{
+		Normalize:    true,
+		Bidi:         BidiStrip,
+		Replacements: ASCIIReplacements,
+	})
+	if want := "(Caf\\351 \\(1\\200\\) -> ok ?) Tj\n"; cs.String() != want {
+		t.Errorf("Content = %q, want %q", cs.String(), want)
+	}
+	if !reflect.DeepEqual(report.Replaced, []rune{0x2192}) || !reflect.DeepEqual(report.Unencodable, []rune{0x4E2D}) {
+		t.Errorf("Unexpected report %+v", report)
+	}
+	if report.OK() {
+		t.Error("Report should not be OK")
+	}
+
+	// Without options, controls are kept and reported
+	_, _, report = EncodeText("a\u200Fb", nil)
+	if !reflect.DeepEqual(report.Unencodable, []rune{0x200F}) {
+		t.Errorf("Expected RLM reported, got %+v", report)
+	}
+}
+
+func TestShowTextWithOptions_Type0Font(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "tests", "resources", "test_font.ttf"))
+	if err != nil {
+		t.Skipf("test font not available: %v", err)
+	}
+	f, err := font.NewFont("Gothic", data)
+	if err != nil {
+		t.Fatalf("NewFont failed: %v", err)
+	}
+	f.Subtype = "CIDFontType2"
+
+	cs := NewContentStream()
+	report := cs.ShowTextWithOptions("\U00010330 x", &ShowTextOptions{Font: f, Replacement: " "})
+	want := "<" + f.GlyphHex("\U00010330  ") + "> Tj\n"
+	if cs.String() != want {
+		t.Errorf("Content = %q, want %q", cs.String(), want)
+	}
+	if !reflect.DeepEqual(report.Unencodable, []rune{'x'}) {
+		t.Errorf("Unexpected report %+v", report)
+	}
+	if !strings.ContainsRune(string(f.Subset), 0x10330) {
+		t.Error("Shown character not added to the font subset")
+	}
+}
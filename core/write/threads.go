@@ -0,0 +1,120 @@
+package write
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// AddThreads writes article threads and their beads. pageObjNums maps page
+// numbers (1-based) to page object numbers. It returns the thread dictionaries,
+// in order, for the catalog's /Threads array, and the beads of each page object
+// in reading order, which belong in the page's /B array.
+func (w *PDFWriter) AddThreads(threads []types.ArticleThread, pageObjNums map[int]int) ([]int, map[int][]int, error) {
+	// Check every thread first, so that nothing is written on error
+	for i, thread := range threads {
+		if len(thread.Beads) == 0 {
+			return nil, nil, fmt.Errorf("thread %d has no beads", i+1)
+		}
+		for j, bead := range thread.Beads {
+			if _, ok := pageObjNums[bead.PageNumber]; !ok {
+				return nil, nil, fmt.Errorf("thread %d bead %d: page %d not found", i+1, j+1, bead.PageNumber)
+			}
+			if bead.Rect == nil {
+				return nil, nil, fmt.Errorf("thread %d bead %d has no rectangle", i+1, j+1)
+			}
+		}
+	}
+
+	threadNums := make([]int, 0, len(threads))
+	pageBeads := make(map[int][]int)
+	for _, thread := range threads {
+
+		// Reserve the thread and bead numbers, which reference each other
+		threadNum := w.nextObjNum
+		w.nextObjNum++
+		beadNums := make([]int, len(thread.Beads))
+		for j := range beadNums {
+			beadNums[j] = w.nextObjNum
+			w.nextObjNum++
+		}
+
+		// Beads form a circular list: the last one links back to the first
+		for j, bead := range thread.Beads {
+			pageObjNum := pageObjNums[bead.PageNumber]
+			beadDict := Dictionary{
+				"/Type": "/Bead",
+				"/N":    fmt.Sprintf("%d 0 R", beadNums[(j+1)%len(beadNums)]),
+				"/V":    fmt.Sprintf("%d 0 R", beadNums[(j+len(beadNums)-1)%len(beadNums)]),
+				"/P":    fmt.Sprintf("%d 0 R", pageObjNum),
+				"/R":    []interface{}{bead.Rect.LowerX, bead.Rect.LowerY, bead.Rect.UpperX, bead.Rect.UpperY},
+			}
+			if j == 0 {
+				beadDict["/T"] = fmt.Sprintf("%d 0 R", threadNum)
+			}
+			w.SetObject(beadNums[j], w.formatDictionary(beadDict))
+			pageBeads[pageObjNum] = append(pageBeads[pageObjNum], beadNums[j])
+		}
+
+		threadDict := Dictionary{
+			"/Type": "/Thread",
+			"/F":    fmt.Sprintf("%d 0 R", beadNums[0]),
+		}
+		info := Dictionary{}
+		if thread.Title != "" {
+			info["/Title"] = escapePDFString(thread.Title)
+		}
+		if thread.Author != "" {
+			info["/Author"] = escapePDFString(thread.Author)
+		}
+		if len(info) > 0 {
+			threadDict["/I"] = info
+		}
+		w.SetObject(threadNum, w.formatDictionary(threadDict))
+		threadNums = append(threadNums, threadNum)
+	}
+	return threadNums, pageBeads, nil
+}
+
+// refArray formats object numbers as an array of references
+func refArray(objNums []int) string {
+	refs := make([]string, len(objNums))
+	for i, objNum := range objNums {
+		refs[i] = fmt.Sprintf("%d 0 R", objNum)
+	}
+	return "[" + strings.Join(refs, " ") + "]"
+}
+
+// SetThreads adds article threads to the document, declaring the reading
+// order of stories that run across columns and pages. Beads refer to pages by
+// number, so SetThreads is called after the pages are finalized.
+func (b *SimplePDFBuilder) SetThreads(threads []types.ArticleThread) error {
+	pageObjNums := make(map[int]int)
+	for i, pageNum := range b.pages {
+		pageObjNums[i+1] = pageNum
+	}
+
+	threadNums, pageBeads, err := b.writer.AddThreads(threads, pageObjNums)
+	if err != nil {
+		return err
+	}
+	for _, pb := range b.pageBuilders {
+		if beads := pageBeads[pb.pageObjNum]; len(beads) > 0 {
+			pb.addBeads(beads)
+		}
+	}
+	if len(threadNums) > 0 {
+		b.SetCatalogEntry("/Threads", refArray(threadNums))
+	}
+	return nil
+}
+
+// addBeads adds article beads to a built page, sorted by object number so
+// that beads of the same thread stay in reading order
+func (pb *PageBuilder) addBeads(objNums []int) {
+	pb.beads = append(pb.beads, objNums...)
+	sort.Ints(pb.beads)
+	pb.writer.SetObject(pb.pageObjNum, pb.pageDict())
+}
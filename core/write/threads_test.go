@@ -0,0 +1,51 @@
+package write
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+func TestSetThreads(t *testing.T) {
+	builder := NewSimplePDFBuilder()
+	for i := 0; i < 2; i++ {
+		builder.FinalizePage(builder.AddPage(PageSizeLetter))
+	}
+
+	err := builder.SetThreads([]types.ArticleThread{{
+		Title: "Lead (story)",
+		Beads: []types.ArticleBead{
+			{PageNumber: 1, Rect: &types.Rectangle{LowerX: 36, LowerY: 36, UpperX: 300, UpperY: 756}},
+			{PageNumber: 1, Rect: &types.Rectangle{LowerX: 312, LowerY: 36, UpperX: 576, UpperY: 756}},
+			{PageNumber: 2, Rect: &types.Rectangle{LowerX: 36, LowerY: 36, UpperX: 576, UpperY: 756}},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("SetThreads failed: %v", err)
+	}
+
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to write PDF: %v", err)
+	}
+	out := string(pdfBytes)
+	for _, want := range []string{"/Threads [", "/Type /Thread", "/I <</Title (Lead \\(story\\)) >>", "/B[", "/R [36 36 300 756]"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Output doesn't contain %s", want)
+		}
+	}
+	if got := strings.Count(out, "/Type /Bead"); got != 3 {
+		t.Errorf("Expected 3 beads, got %d", got)
+	}
+	if issues := Verify(pdfBytes); len(issues) > 0 {
+		t.Errorf("Output doesn't verify: %v", issues)
+	}
+
+	if err := builder.SetThreads([]types.ArticleThread{{Beads: []types.ArticleBead{{PageNumber: 3, Rect: &types.Rectangle{}}}}}); err == nil {
+		t.Error("Expected an error for a bead on a missing page")
+	}
+	if err := builder.SetThreads([]types.ArticleThread{{Title: "Empty"}}); err == nil {
+		t.Error("Expected an error for a thread without beads")
+	}
+}
@@ -0,0 +1,459 @@
+package write
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+)
+
+// VerifyIssue is a spec violation found by Verify
+type VerifyIssue struct {
+	ObjectNum int // Object the issue was found in, 0 for file structure issues
+	Message   string
+}
+
+func (i VerifyIssue) String() string {
+	if i.ObjectNum == 0 {
+		return i.Message
+	}
+	return fmt.Sprintf("object %d: %s", i.ObjectNum, i.Message)
+}
+
+// VerifyError is returned by Write when verification is enabled and fails
+type VerifyError struct {
+	Issues []VerifyIssue
+}
+
+func (e *VerifyError) Error() string {
+	msgs := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		msgs[i] = issue.String()
+	}
+	return fmt.Sprintf("PDF verification failed: %s", strings.Join(msgs, "; "))
+}
+
+// SetVerify runs Verify on the output of Write, which then fails with a
+// *VerifyError instead of returning a broken file
+func (w *PDFWriter) SetVerify(enable bool) {
+	w.verify = enable
+}
+
+var (
+	startXRefPattern = regexp.MustCompile(`startxref\s+(\d+)\s+%%EOF\s*$`)
+	rootPattern      = regexp.MustCompile(`/Root\s+(\d+)\s+\d+\s+R`)
+	refPattern       = regexp.MustCompile(`^(\d+)\s+\d+\s+R$`)
+	refsPattern      = regexp.MustCompile(`(\d+)\s+\d+\s+R`)
+	refPrefixPattern = regexp.MustCompile(`^\d+\s+\d+\s+R\b`)
+)
+
+// Verify checks a PDF for structural errors a writer can introduce: the
+// cross-reference offsets, stream lengths, dictionary syntax (keys must be
+// names, without duplicates) and the page tree (/Parent links, /Count and
+// /Type). It returns the issues found, sorted by object number.
+func Verify(pdfBytes []byte) []VerifyIssue {
+	v := &verifier{data: pdfBytes, objects: parse.ScanObjects(pdfBytes)}
+	v.checkXRef()
+	for _, objNum := range v.objectNums() {
+		v.checkObject(objNum)
+	}
+	v.checkPageTree()
+
+	sort.SliceStable(v.issues, func(i, j int) bool { return v.issues[i].ObjectNum < v.issues[j].ObjectNum })
+	return v.issues
+}
+
+type verifier struct {
+	data       []byte
+	objects    map[int]int64 // object offsets from a scan of the headers
+	compressed map[int]bool  // objects stored in object streams
+	issues     []VerifyIssue
+}
+
+func (v *verifier) addIssue(objNum int, format string, args ...interface{}) {
+	v.issues = append(v.issues, VerifyIssue{ObjectNum: objNum, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *verifier) objectNums() []int {
+	nums := make([]int, 0, len(v.objects))
+	for objNum := range v.objects {
+		nums = append(nums, objNum)
+	}
+	sort.Ints(nums)
+	return nums
+}
+
+// checkXRef checks that every in-use cross-reference entry points at the header
+// of its object
+func (v *verifier) checkXRef() {
+	m := startXRefPattern.FindSubmatch(v.data)
+	if m == nil {
+		v.addIssue(0, "startxref not found at end of file")
+		return
+	}
+	startXRef, _ := strconv.ParseInt(string(m[1]), 10, 64)
+	if startXRef <= 0 || startXRef >= int64(len(v.data)) {
+		v.addIssue(0, "startxref offset %d out of range", startXRef)
+		return
+	}
+
+	var offsets map[int]int64
+	if bytes.HasPrefix(v.data[startXRef:], []byte("xref")) {
+		table, err := parse.ParseTraditionalXRefTable(v.data, startXRef)
+		if err != nil {
+			v.addIssue(0, "invalid xref table: %v", err)
+			return
+		}
+		offsets = table
+	} else {
+		result, err := parse.ParseXRefStreamFull(v.data, startXRef, false)
+		if err != nil {
+			v.addIssue(0, "invalid xref stream: %v", err)
+			return
+		}
+		offsets = result.Objects
+		v.compressed = make(map[int]bool)
+		for objNum := range result.ObjectStreams {
+			v.compressed[objNum] = true
+		}
+	}
+
+	for objNum, offset := range offsets {
+		if actual, ok := v.objects[objNum]; !ok {
+			v.addIssue(objNum, "listed in xref but not present")
+		} else if actual != offset {
+			v.addIssue(objNum, "xref offset %d, object header at %d", offset, actual)
+		}
+	}
+}
+
+// objectParts returns the dictionary or value of an object and, for streams,
+// the stream data as delimited by the stream and endstream keywords
+func (v *verifier) objectParts(objNum int) (body, stream []byte, isStream bool) {
+	offset := v.objects[objNum]
+	data := v.data[offset:]
+	start := bytes.Index(data, []byte("obj")) + 3
+	end := bytes.Index(data, []byte("endobj"))
+	streamIdx := bytes.Index(data[start:], []byte("stream"))
+	if streamIdx == -1 || (end != -1 && start+streamIdx > end) {
+		if end == -1 {
+			end = len(data)
+		}
+		return data[start:end], nil, false
+	}
+
+	streamIdx += start
+	dataStart := streamIdx + len("stream")
+	if dataStart < len(data) && data[dataStart] == '\r' {
+		dataStart++
+	}
+	if dataStart < len(data) && data[dataStart] == '\n' {
+		dataStart++
+	}
+	endstream := bytes.Index(data[dataStart:], []byte("endstream"))
+	if endstream == -1 {
+		return data[start:streamIdx], data[dataStart:], true
+	}
+	return data[start:streamIdx], data[dataStart : dataStart+endstream], true
+}
+
+// checkObject checks the dictionary syntax of an object and its stream length
+func (v *verifier) checkObject(objNum int) {
+	body, stream, isStream := v.objectParts(objNum)
+	trimmed := bytes.TrimSpace(body)
+	if bytes.HasPrefix(trimmed, []byte("<<")) {
+		if _, err := checkDict(trimmed, 0); err != nil {
+			v.addIssue(objNum, "%v", err)
+		}
+	} else if isStream {
+		v.addIssue(objNum, "stream without a dictionary")
+	}
+	if !isStream {
+		return
+	}
+
+	dict, _ := dictEntries(trimmed)
+	lengthValue, ok := dict["/Length"]
+	if !ok {
+		v.addIssue(objNum, "stream has no /Length")
+		return
+	}
+	if m := refPattern.FindStringSubmatch(lengthValue); m != nil {
+		lengthObj, _ := strconv.Atoi(m[1])
+		if _, ok := v.objects[lengthObj]; !ok {
+			v.addIssue(objNum, "/Length object %d not found", lengthObj)
+			return
+		}
+		lengthBody, _, _ := v.objectParts(lengthObj)
+		lengthValue = string(bytes.TrimSpace(lengthBody))
+	}
+	length, err := strconv.Atoi(lengthValue)
+	if err != nil {
+		v.addIssue(objNum, "/Length %q is not an integer", lengthValue)
+		return
+	}
+
+	// The data may be followed by an end-of-line marker before endstream
+	if length < 0 || length > len(stream) || !isEOL(stream[length:]) {
+		v.addIssue(objNum, "/Length %d, stream data is %d bytes", length, len(bytes.TrimRight(stream, "\r\n")))
+	}
+}
+
+// checkPageTree walks the page tree from the catalog, checking /Type, /Parent
+// and /Count. Page trees with nodes in object streams are not checked.
+func (v *verifier) checkPageTree() {
+	m := rootPattern.FindAllSubmatch(v.data, -1)
+	if m == nil {
+		v.addIssue(0, "trailer has no /Root")
+		return
+	}
+	rootNum, _ := strconv.Atoi(string(m[len(m)-1][1]))
+	if v.compressed[rootNum] {
+		return
+	}
+	if _, ok := v.objects[rootNum]; !ok {
+		v.addIssue(0, "catalog object %d not found", rootNum)
+		return
+	}
+	catalog, _, _ := v.objectParts(rootNum)
+	pagesNum, ok := v.dictRef(catalog, "/Pages")
+	if !ok {
+		v.addIssue(rootNum, "catalog has no /Pages reference")
+		return
+	}
+	v.countPages(pagesNum, 0, map[int]bool{})
+}
+
+// countPages checks a page tree node and returns the number of pages under it,
+// or -1 when part of the tree is in object streams
+func (v *verifier) countPages(objNum, parentNum int, seen map[int]bool) int {
+	if v.compressed[objNum] {
+		return -1
+	}
+	if seen[objNum] {
+		v.addIssue(objNum, "page tree contains a cycle")
+		return 0
+	}
+	seen[objNum] = true
+	if _, ok := v.objects[objNum]; !ok {
+		v.addIssue(parentNum, "page tree node %d not found", objNum)
+		return 0
+	}
+
+	node, _, _ := v.objectParts(objNum)
+	dict, _ := dictEntries(bytes.TrimSpace(node))
+	if parentNum != 0 {
+		if parent, ok := v.dictRef(node, "/Parent"); !ok || parent != parentNum {
+			v.addIssue(objNum, "/Parent should reference %d", parentNum)
+		}
+	}
+
+	switch dict["/Type"] {
+	case "/Page":
+		return 1
+	case "/Pages":
+	default:
+		v.addIssue(objNum, "page tree node has /Type %q", dict["/Type"])
+		return 0
+	}
+
+	count := 0
+	kids := strings.Trim(dict["/Kids"], "[]")
+	for _, ref := range refsPattern.FindAllStringSubmatch(kids, -1) {
+		kid, _ := strconv.Atoi(ref[1])
+		n := v.countPages(kid, objNum, seen)
+		if n == -1 {
+			return -1
+		}
+		count += n
+	}
+	if declared, err := strconv.Atoi(dict["/Count"]); err != nil || declared != count {
+		v.addIssue(objNum, "/Count %q, page tree has %d pages", dict["/Count"], count)
+	}
+	return count
+}
+
+// dictRef returns the object number of an indirect reference value
+func (v *verifier) dictRef(body []byte, key string) (int, bool) {
+	dict, _ := dictEntries(bytes.TrimSpace(body))
+	m := refPattern.FindStringSubmatch(dict[key])
+	if m == nil {
+		return 0, false
+	}
+	objNum, _ := strconv.Atoi(m[1])
+	return objNum, true
+}
+
+// dictEntries returns the raw values of a dictionary's entries
+func dictEntries(dict []byte) (map[string]string, error) {
+	entries := make(map[string]string)
+	if !bytes.HasPrefix(dict, []byte("<<")) {
+		return entries, fmt.Errorf("not a dictionary")
+	}
+	i := 2
+	for {
+		i = skipWhitespace(dict, i)
+		if i >= len(dict) {
+			return entries, fmt.Errorf("unterminated dictionary")
+		}
+		if bytes.HasPrefix(dict[i:], []byte(">>")) {
+			return entries, nil
+		}
+		if dict[i] != '/' {
+			return entries, fmt.Errorf("dictionary key at %q is not a name", truncate(dict[i:]))
+		}
+		keyEnd, err := skipValue(dict, i)
+		if err != nil {
+			return entries, err
+		}
+		key := string(dict[i:keyEnd])
+		valueStart := skipWhitespace(dict, keyEnd)
+		valueEnd, err := skipValue(dict, valueStart)
+		if err != nil {
+			return entries, fmt.Errorf("value of %s: %v", key, err)
+		}
+		if _, dup := entries[key]; dup {
+			return entries, fmt.Errorf("duplicate dictionary key %s", key)
+		}
+		entries[key] = strings.TrimSpace(string(dict[valueStart:valueEnd]))
+		i = valueEnd
+	}
+}
+
+// checkDict checks the syntax of the dictionary at i, returning its end
+func checkDict(data []byte, i int) (int, error) {
+	end, err := skipValue(data, i)
+	if err != nil {
+		return 0, err
+	}
+	_, err = dictEntries(data[i:end])
+	return end, err
+}
+
+// skipValue returns the end of the PDF value starting at i. Indirect references
+// ("n g R") are returned as a single value.
+func skipValue(data []byte, i int) (int, error) {
+	if i >= len(data) {
+		return 0, fmt.Errorf("missing value")
+	}
+	switch c := data[i]; {
+	case c == '/':
+		j := i + 1
+		for j < len(data) && !isDelimiter(data[j]) {
+			if data[j] == '#' && (j+2 >= len(data) || !isHex(data[j+1]) || !isHex(data[j+2])) {
+				return 0, fmt.Errorf("invalid escape in name %q", truncate(data[i:]))
+			}
+			j++
+		}
+		return j, nil
+	case c == '(':
+		depth := 0
+		for j := i; j < len(data); j++ {
+			switch data[j] {
+			case '\\':
+				j++
+			case '(':
+				depth++
+			case ')':
+				depth--
+				if depth == 0 {
+					return j + 1, nil
+				}
+			}
+		}
+		return 0, fmt.Errorf("unterminated string")
+	case bytes.HasPrefix(data[i:], []byte("<<")):
+		j := i + 2
+		for {
+			j = skipWhitespace(data, j)
+			if j >= len(data) {
+				return 0, fmt.Errorf("unterminated dictionary")
+			}
+			if bytes.HasPrefix(data[j:], []byte(">>")) {
+				if _, err := dictEntries(data[i : j+2]); err != nil {
+					return 0, err
+				}
+				return j + 2, nil
+			}
+			end, err := skipValue(data, j)
+			if err != nil {
+				return 0, err
+			}
+			j = end
+		}
+	case c == '<':
+		end := bytes.IndexByte(data[i:], '>')
+		if end == -1 {
+			return 0, fmt.Errorf("unterminated hex string")
+		}
+		return i + end + 1, nil
+	case c == '[':
+		j := i + 1
+		for {
+			j = skipWhitespace(data, j)
+			if j >= len(data) {
+				return 0, fmt.Errorf("unterminated array")
+			}
+			if data[j] == ']' {
+				return j + 1, nil
+			}
+			end, err := skipValue(data, j)
+			if err != nil {
+				return 0, err
+			}
+			j = end
+		}
+	case c == '>' || c == ']' || c == ')':
+		return 0, fmt.Errorf("unexpected %q", c)
+	default:
+		j := i
+		for j < len(data) && !isDelimiter(data[j]) {
+			j++
+		}
+		if j == i {
+			return 0, fmt.Errorf("unexpected %q", c)
+		}
+		// An indirect reference "n g R" is one value
+		if m := refPrefixPattern.Find(data[i:]); m != nil {
+			return i + len(m), nil
+		}
+		return j, nil
+	}
+}
+
+func skipWhitespace(data []byte, i int) int {
+	for i < len(data) {
+		switch data[i] {
+		case ' ', '\n', '\r', '\t', '\f', 0:
+			i++
+		case '%':
+			for i < len(data) && data[i] != '\n' && data[i] != '\r' {
+				i++
+			}
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+func isDelimiter(c byte) bool {
+	return bytes.IndexByte([]byte(" \n\r\t\f\x00()<>[]{}/%"), c) != -1
+}
+
+// isEOL reports whether b is empty or a single end-of-line marker
+func isEOL(b []byte) bool {
+	return len(b) == 0 || string(b) == "\n" || string(b) == "\r" || string(b) == "\r\n"
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func truncate(b []byte) string {
+	return string(b[:min(len(b), 20)])
+}
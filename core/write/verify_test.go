@@ -0,0 +1,123 @@
+package write
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// verifyMessages joins the issues Verify finds in a PDF
+func verifyMessages(pdfBytes []byte) string {
+	var msgs []string
+	for _, issue := range Verify(pdfBytes) {
+		msgs = append(msgs, issue.String())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func TestVerify_ValidOutput(t *testing.T) {
+	if msgs := verifyMessages(buildSimpleTestPDF(t)); msgs != "" {
+		t.Errorf("Issues in builder output: %s", msgs)
+	}
+
+	// Cross-reference stream with object streams
+	w := NewPDFWriter()
+	w.UseXRefStream(true)
+	w.UseObjectStream(true)
+	pages := w.AddObject([]byte("<< /Type /Pages /Kids [3 0 R] /Count 1 >>"))
+	w.SetRoot(w.AddObject([]byte("<< /Type /Catalog /Pages 1 0 R >>")))
+	w.AddObject([]byte("<< /Type /Page /Parent 1 0 R /MediaBox [0 0 612 792] >>"))
+	w.AddStreamObject(Dictionary{}, []byte("BT ET"), true)
+	if pages != 1 {
+		t.Fatalf("Unexpected object layout")
+	}
+	var buf bytes.Buffer
+	if err := w.Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if msgs := verifyMessages(buf.Bytes()); msgs != "" {
+		t.Errorf("Issues in xref stream output: %s", msgs)
+	}
+}
+
+func TestVerify_Corrupted(t *testing.T) {
+	valid := buildSimpleTestPDF(t)
+
+	tests := []struct {
+		name    string
+		corrupt func(string) string
+		want    string
+	}{
+		{
+			name: "xref offset",
+			corrupt: func(s string) string {
+				// Point the catalog entry one byte past its header
+				m := regexp.MustCompile(`(?m)^(\d{10}) 00000 n \r?\ntrailer`).FindStringSubmatchIndex(s)
+				return s[:m[3]-1] + string(s[m[3]-1]+1) + s[m[3]:]
+			},
+			want: "object 9: xref offset",
+		},
+		{
+			name: "stream length",
+			corrupt: func(s string) string {
+				return regexp.MustCompile(`/Length (\d+)`).ReplaceAllString(s, "/Length 1$1")
+			},
+			want: "/Length 1",
+		},
+		{
+			name:    "page count",
+			corrupt: func(s string) string { return strings.Replace(s, "/Count 1", "/Count 2", 1) },
+			want:    `/Count "2", page tree has 1 pages`,
+		},
+		{
+			name:    "page type",
+			corrupt: func(s string) string { return strings.Replace(s, "/Type/Page/", "/Type/Pagx/", 1) },
+			want:    `page tree node has /Type "/Pagx"`,
+		},
+		{
+			name:    "name escape",
+			corrupt: func(s string) string { return strings.Replace(s, "/PageMode", "/Page#zde", 1) },
+			want:    "invalid escape in name",
+		},
+		{
+			name: "duplicate key",
+			corrupt: func(s string) string {
+				return strings.Replace(s, "/PageMode /UseOutlines", "/PageMode 1/PageMode 2", 1)
+			},
+			want: "duplicate dictionary key /PageMode",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			corrupted := tt.corrupt(string(valid))
+			if corrupted == string(valid) {
+				t.Fatal("Corruption did not change the PDF")
+			}
+			if msgs := verifyMessages([]byte(corrupted)); !strings.Contains(msgs, tt.want) {
+				t.Errorf("Expected issue %q, got %q", tt.want, msgs)
+			}
+		})
+	}
+}
+
+func TestPDFWriter_SetVerify(t *testing.T) {
+	w := NewPDFWriter()
+	w.SetVerify(true)
+	w.SetRoot(w.AddObject([]byte("<< /Type /Catalog /Pages 2 0 R >>")))
+	w.AddObject([]byte("<< /Type /Pages /Kids [] /Count 3 >>"))
+
+	var buf bytes.Buffer
+	err := w.Write(&buf)
+	var verifyErr *VerifyError
+	if !errors.As(err, &verifyErr) {
+		t.Fatalf("Expected VerifyError, got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Error("Output written despite failed verification")
+	}
+	if len(verifyErr.Issues) != 1 || verifyErr.Issues[0].ObjectNum != 2 {
+		t.Errorf("Unexpected issues: %v", verifyErr.Issues)
+	}
+}
@@ -3,17 +3,18 @@ package write
 
 import (
 	"bytes"
-	"compress/zlib"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/md5"
 	"crypto/rand"
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/benedoc-inc/pdfer/resources/font"
 	"github.com/benedoc-inc/pdfer/types"
 )
 
@@ -30,6 +31,24 @@ type PDFObject struct {
 // Dictionary represents a PDF dictionary
 type Dictionary map[string]interface{}
 
+// Raw is a Dictionary value in PDF syntax, written as it is
+type Raw string
+
+// ParseDictionary parses PDF dictionary syntax into a Dictionary whose values
+// are the entries' Raw syntax, e.g. to carry the entries of an object over to
+// its replacement
+func ParseDictionary(dict []byte) (Dictionary, error) {
+	entries, err := dictEntries(bytes.TrimSpace(dict))
+	if err != nil {
+		return nil, err
+	}
+	parsed := make(Dictionary, len(entries))
+	for key, value := range entries {
+		parsed[strings.TrimPrefix(key, "/")] = Raw(value)
+	}
+	return parsed, nil
+}
+
 // PDFWriter builds PDF files from scratch
 type PDFWriter struct {
 	objects         map[int]*PDFObject
@@ -43,6 +62,16 @@ type PDFWriter struct {
 	pdfVersion      string
 	useXRefStream   bool // If true, use cross-reference stream instead of table
 	useObjectStream bool // If true, compress objects into object streams
+	contentFileID   bool // If true and no file ID is set, derive /ID from the written objects
+	pdf20           bool // If true, write PDF 2.0 (see UsePDF20)
+	verify          bool // If true, check the output with Verify before writing it
+	compression     CompressionPolicy
+
+	fontLicensePolicy FontLicensePolicy
+	fontEmbeddings    []FontEmbedding    // embedding decisions, see FontEmbeddings
+	fontDecisions     map[*font.Font]int // font -> index in fontEmbeddings
+
+	imageCache map[[sha256.Size]byte]*ImageInfo // embedded images by data hash
 }
 
 // NewPDFWriter creates a new PDF writer
@@ -65,6 +94,11 @@ func (w *PDFWriter) SetEncryption(encryptInfo *types.PDFEncryption, fileID []byt
 	w.fileID = fileID
 }
 
+// SetFileID sets the file identifier written to the trailer /ID
+func (w *PDFWriter) SetFileID(fileID []byte) {
+	w.fileID = fileID
+}
+
 // UseXRefStream enables cross-reference stream writing (PDF 1.5+)
 // If true, writes a compressed cross-reference stream instead of traditional xref table
 func (w *PDFWriter) UseXRefStream(enable bool) {
@@ -102,15 +136,11 @@ func (w *PDFWriter) AddStreamObject(dict Dictionary, data []byte, compress bool)
 	w.nextObjNum++
 
 	streamData := data
-	if compress && len(data) > 0 {
-		var buf bytes.Buffer
-		zw := zlib.NewWriter(&buf)
-		zw.Write(data)
-		zw.Close()
-		streamData = buf.Bytes()
-		dict["Filter"] = "/FlateDecode"
+	if compress {
+		streamData = w.compressStream(dict, data)
 	}
 
+	delete(dict, "/Length")
 	dict["Length"] = len(streamData)
 
 	w.objects[objNum] = &PDFObject{
@@ -138,15 +168,11 @@ func (w *PDFWriter) SetObject(objNum int, content []byte) {
 // SetStreamObject sets a stream object at a specific number
 func (w *PDFWriter) SetStreamObject(objNum int, dict Dictionary, data []byte, compress bool) {
 	streamData := data
-	if compress && len(data) > 0 {
-		var buf bytes.Buffer
-		zw := zlib.NewWriter(&buf)
-		zw.Write(data)
-		zw.Close()
-		streamData = buf.Bytes()
-		dict["Filter"] = "/FlateDecode"
+	if compress {
+		streamData = w.compressStream(dict, data)
 	}
 
+	delete(dict, "/Length")
 	dict["Length"] = len(streamData)
 
 	w.objects[objNum] = &PDFObject{
@@ -188,6 +214,9 @@ func (w *PDFWriter) updateCatalogWithOutlines() {
 	if !ok || catalogObj == nil {
 		return
 	}
+	if catalogObj.Content != nil && strings.Contains(string(catalogObj.Content), "/Outlines") {
+		return
+	}
 
 	// Parse catalog dictionary if it's a Dictionary, otherwise parse as string
 	var catalogDict Dictionary
@@ -259,6 +288,12 @@ func (w *PDFWriter) Write(out io.Writer) error {
 	if w.outlinesRef != "" {
 		w.updateCatalogWithOutlines()
 	}
+	if w.pdf20 {
+		if err := w.checkPDF20(); err != nil {
+			return err
+		}
+		w.setCatalogVersion()
+	}
 
 	var buf bytes.Buffer
 
@@ -334,6 +369,12 @@ func (w *PDFWriter) Write(out io.Writer) error {
 		buf.WriteString("\nendobj\n")
 	}
 
+	fileID := w.fileID
+	if len(fileID) == 0 && w.contentFileID {
+		sum := md5.Sum(buf.Bytes())
+		fileID = sum[:]
+	}
+
 	var xrefPos int64
 
 	// Write cross-reference (stream or table)
@@ -361,8 +402,8 @@ func (w *PDFWriter) Write(out io.Writer) error {
 		if w.encryptRef != "" {
 			buf.WriteString(fmt.Sprintf("/Encrypt %s\n", w.encryptRef))
 		}
-		if len(w.fileID) > 0 {
-			hexID := fmt.Sprintf("%X", w.fileID)
+		if len(fileID) > 0 {
+			hexID := fmt.Sprintf("%X", fileID)
 			buf.WriteString(fmt.Sprintf("/ID [<%s><%s>]\n", hexID, hexID))
 		}
 		buf.WriteString(">>\n")
@@ -402,8 +443,8 @@ func (w *PDFWriter) Write(out io.Writer) error {
 		if w.encryptRef != "" {
 			buf.WriteString(fmt.Sprintf("/Encrypt %s\n", w.encryptRef))
 		}
-		if len(w.fileID) > 0 {
-			hexID := fmt.Sprintf("%X", w.fileID)
+		if len(fileID) > 0 {
+			hexID := fmt.Sprintf("%X", fileID)
 			buf.WriteString(fmt.Sprintf("/ID [<%s><%s>]\n", hexID, hexID))
 		}
 		buf.WriteString(">>\n")
@@ -412,6 +453,12 @@ func (w *PDFWriter) Write(out io.Writer) error {
 	// Write startxref
 	buf.WriteString(fmt.Sprintf("startxref\n%d\n%%%%EOF\n", xrefPos))
 
+	if w.verify {
+		if issues := Verify(buf.Bytes()); len(issues) > 0 {
+			return &VerifyError{Issues: issues}
+		}
+	}
+
 	// Write to output
 	_, err = out.Write(buf.Bytes())
 	return err
@@ -455,14 +502,16 @@ func (w *PDFWriter) formatValue(value interface{}) string {
 	case float64:
 		return strconv.FormatFloat(v, 'f', -1, 64)
 	case string:
-		// If it looks like a name or reference, use as-is
-		if strings.HasPrefix(v, "/") || strings.HasSuffix(v, " R") {
+		// If it looks like a name, reference or array, use as-is
+		if isRawValue(v) {
 			return v
 		}
 		// If it's already escaped (contains \( or \)), use as-is
 		// Otherwise, it's a string - format as PDF string
 		// Note: escapePDFString in metadata.go returns escaped content without parentheses
 		return "(" + v + ")"
+	case Raw:
+		return string(v)
 	case []byte:
 		return "<" + fmt.Sprintf("%X", v) + ">"
 	case []interface{}:
@@ -478,6 +527,12 @@ func (w *PDFWriter) formatValue(value interface{}) string {
 	}
 }
 
+// isRawValue reports whether formatValue takes a string for PDF syntax rather
+// than text: a name, a reference or an array
+func isRawValue(s string) bool {
+	return strings.HasPrefix(s, "/") || strings.HasSuffix(s, " R") || strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]")
+}
+
 // encryptStream encrypts stream data using the PDF's encryption settings
 func (w *PDFWriter) encryptStream(data []byte, objNum, genNum int) ([]byte, error) {
 	if w.encryptInfo == nil || len(w.encryptInfo.EncryptKey) == 0 {
@@ -573,3 +628,23 @@ func (w *PDFWriter) GetObject(objNum int) ([]byte, error) {
 	}
 	return nil, fmt.Errorf("object %d has no content", objNum)
 }
+
+// FormatObject returns an object's body as Write writes it, without the object
+// header or encryption: the dictionary and data of a stream object, or the
+// content of any other. It lets objects built with a writer, such as images, be
+// copied into a document edited by other means.
+func (w *PDFWriter) FormatObject(objNum int) ([]byte, error) {
+	obj, ok := w.objects[objNum]
+	if !ok {
+		return nil, fmt.Errorf("object %d not found", objNum)
+	}
+	if obj.Stream == nil {
+		return obj.Content, nil
+	}
+	var buf bytes.Buffer
+	buf.Write(w.formatDictionary(obj.Dict))
+	buf.WriteString("\nstream\n")
+	buf.Write(obj.Stream)
+	buf.WriteString("\nendstream")
+	return buf.Bytes(), nil
+}
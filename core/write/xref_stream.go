@@ -2,7 +2,6 @@ package write
 
 import (
 	"bytes"
-	"compress/zlib"
 	"fmt"
 )
 
@@ -122,15 +121,7 @@ func (w *PDFWriter) writeXRefStream(buf *bytes.Buffer, positions map[int]int64,
 	writeBigEndian(streamData[xrefEntryStart+w1:], xrefPos, w2)
 
 	// Compress stream data with FlateDecode (zlib)
-	var compressed bytes.Buffer
-	zw := zlib.NewWriter(&compressed)
-	if _, err := zw.Write(streamData); err != nil {
-		return 0, fmt.Errorf("failed to compress xref stream: %v", err)
-	}
-	if err := zw.Close(); err != nil {
-		return 0, fmt.Errorf("failed to close zlib writer: %v", err)
-	}
-	compressedData := compressed.Bytes()
+	compressedData := w.compression.Flate(StreamContent, streamData)
 
 	// Create xref stream dictionary
 	// Note: /Root, /Info, /Encrypt, /ID go in the trailer, not the stream dict
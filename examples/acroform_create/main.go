@@ -42,26 +42,13 @@ func main() {
 		[]string{"USA", "Canada", "Mexico", "Other"})
 	dropdown.SetDefault("USA")
 
-	// Finalize page first
 	builder.FinalizePage(page)
 
-	// Build the AcroForm (after pages are created)
-	acroFormNum, err := fieldBuilder.Build()
-	if err != nil {
-		log.Fatalf("Failed to build AcroForm: %v", err)
-	}
-
-	// Get the writer and update catalog to include AcroForm
-	w := builder.Writer()
-	pagesObjNum := builder.PagesObjNum()
-
-	// Create catalog with AcroForm reference
-	catalogDict := fmt.Sprintf("<</Type/Catalog/Pages %d 0 R/AcroForm %d 0 R>>",
-		pagesObjNum, acroFormNum)
-	catalogNum := w.AddObject([]byte(catalogDict))
-	w.SetRoot(catalogNum)
+	// Make the fields the document's AcroForm; the catalog references it and the
+	// widgets are added to their pages when the PDF is written
+	builder.SetAcroForm(fieldBuilder)
 
-	// Generate PDF using builder (which will use the updated catalog)
+	// Generate PDF
 	pdfBytes, err := builder.Bytes()
 	if err != nil {
 		log.Fatalf("Failed to generate PDF: %v", err)
@@ -45,7 +45,7 @@ func main() {
 	}
 
 	// Extract XFA streams
-	streams, err := xfa.ExtractAllXFAStreams(pdfBytes, encryptInfo, *verbose)
+	streams, err := xfa.ExtractAllXFAStreams(pdfBytes, encryptInfo, xfa.DebugOptions{}, *verbose)
 	if err != nil {
 		log.Fatalf("Failed to extract XFA: %v", err)
 	}
@@ -8,21 +8,29 @@ import (
 
 	"github.com/benedoc-inc/pdfer/core/write"
 	"github.com/benedoc-inc/pdfer/resources/font"
+	"github.com/benedoc-inc/pdfer/resources/font/finder"
 )
 
 func main() {
-	// Try to find a system font, or use a font file path provided as argument
+	// Use a font file path provided as argument, or find an installed system font
 	var fontPath string
 	if len(os.Args) > 1 {
 		fontPath = os.Args[1]
 	} else {
-		// Default to Arial Unicode if available
-		fontPath = "/System/Library/Fonts/Supplemental/Arial Unicode.ttf"
+		for _, family := range []string{"Arial Unicode MS", "Arial", "Liberation Sans", "DejaVu Sans"} {
+			if face, err := finder.Find(family, finder.WeightRegular, false); err == nil {
+				fontPath = face.Path
+				break
+			}
+		}
+		if fontPath == "" {
+			log.Fatalf("No system font found\n\nUsage: %s [font_path.ttf]", os.Args[0])
+		}
 	}
 
 	// Check if font file exists
 	if _, err := os.Stat(fontPath); os.IsNotExist(err) {
-		log.Fatalf("Font file not found: %s\n\nUsage: %s [font_path.ttf]\n\nExample system fonts on macOS:\n  /System/Library/Fonts/Supplemental/Arial Bold.ttf\n  /System/Library/Fonts/Supplemental/Times New Roman.ttf", fontPath, os.Args[0])
+		log.Fatalf("Font file not found: %s\n\nUsage: %s [font_path.ttf]", fontPath, os.Args[0])
 	}
 
 	// Read font file
@@ -0,0 +1,91 @@
+//go:build js && wasm
+
+// Example: use pdfer from JavaScript in the browser
+//
+// Build:
+//
+//	GOOS=js GOARCH=wasm go build -o pdfer.wasm ./examples/wasm
+//	cp "$(go env GOROOT)/lib/wasm/wasm_exec.js" examples/wasm/  # misc/wasm before Go 1.24
+//
+// and serve examples/wasm with pdfer.wasm over HTTP. The module registers two
+// global functions; both return an object with an "error" property on failure:
+//
+//	pdferFill(pdf: Uint8Array, data: string, password?: string) -> {pdf: Uint8Array}
+//	pdferExtract(pdf: Uint8Array, password?: string) -> {json: string}
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"syscall/js"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/forms"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+func main() {
+	js.Global().Set("pdferFill", js.FuncOf(fill))
+	js.Global().Set("pdferExtract", js.FuncOf(extractContent))
+
+	// Keep the module running so the functions stay callable
+	select {}
+}
+
+// fill fills a form with JSON data and returns the filled PDF
+func fill(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return jsError(fmt.Errorf("usage: pdferFill(pdf, data, password?)"))
+	}
+	pdfBytes := bytesFromJS(args[0])
+
+	var data types.FormData
+	if err := json.Unmarshal([]byte(args[1].String()), &data); err != nil {
+		return jsError(fmt.Errorf("invalid form data: %w", err))
+	}
+
+	filled, err := forms.FillWithOptions(pdfBytes, data, forms.FillOptions{Password: password(args, 2)})
+	if err != nil {
+		return jsError(err)
+	}
+	return map[string]interface{}{"pdf": bytesToJS(filled)}
+}
+
+// extractContent extracts text, images, metadata and structure as JSON
+func extractContent(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return jsError(fmt.Errorf("usage: pdferExtract(pdf, password?)"))
+	}
+	out, err := extract.ExtractContentToJSON(bytesFromJS(args[0]), password(args, 1), false)
+	if err != nil {
+		return jsError(err)
+	}
+	return map[string]interface{}{"json": out}
+}
+
+// password returns the optional password argument at index i
+func password(args []js.Value, i int) []byte {
+	if len(args) > i && args[i].Type() == js.TypeString {
+		return []byte(args[i].String())
+	}
+	return nil
+}
+
+// bytesFromJS copies a Uint8Array into a Go byte slice
+func bytesFromJS(v js.Value) []byte {
+	b := make([]byte, v.Get("length").Int())
+	js.CopyBytesToGo(b, v)
+	return b
+}
+
+// bytesToJS copies a Go byte slice into a new Uint8Array
+func bytesToJS(b []byte) js.Value {
+	v := js.Global().Get("Uint8Array").New(len(b))
+	js.CopyBytesToJS(v, b)
+	return v
+}
+
+// jsError reports an error to JavaScript
+func jsError(err error) interface{} {
+	return map[string]interface{}{"error": err.Error()}
+}
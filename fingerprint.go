@@ -0,0 +1,113 @@
+package pdfer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"sort"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/forms"
+)
+
+// DocumentFingerprint holds SHA-256 hashes (hex) of a document's logical content.
+// Metadata, object numbering and file layout do not affect them, so two files with
+// the same pages and form values fingerprint the same.
+type DocumentFingerprint struct {
+	Document string            `json:"document"`            // Combined hash of all pages and form data
+	Pages    []PageFingerprint `json:"pages"`               // Per-page hashes, in page order
+	FormData string            `json:"form_data,omitempty"` // Hash of form field values (empty without a form)
+}
+
+// PageFingerprint holds the hashes of one page's content
+type PageFingerprint struct {
+	Text   string   `json:"text"`             // Hash of the page text with whitespace normalized
+	Images []string `json:"images,omitempty"` // Hashes of the images drawn on the page, in drawing order
+}
+
+// Fingerprint computes stable hashes of a document's text, images and form data for
+// deduplication and change detection. Compare Document for identity, or the page and
+// form hashes to locate what changed.
+func Fingerprint(pdfBytes []byte, password []byte, verbose bool) (*DocumentFingerprint, error) {
+	pdf, err := parse.OpenWithOptions(pdfBytes, parse.ParseOptions{
+		Password: password,
+		Verbose:  verbose,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PDF: %w", err)
+	}
+
+	pages, err := extract.ExtractPages(pdfBytes, pdf, verbose)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract pages: %w", err)
+	}
+
+	fingerprint := &DocumentFingerprint{Pages: make([]PageFingerprint, len(pages))}
+	for i, page := range pages {
+		var text []string
+		for _, t := range page.Text {
+			text = append(text, strings.Fields(t.Text)...)
+		}
+		fingerprint.Pages[i].Text = hashString(strings.Join(text, " "))
+
+		if len(page.Images) == 0 {
+			continue
+		}
+		images, err := extract.ExtractPageImages(pdf, i+1, verbose)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract images of page %d: %w", i+1, err)
+		}
+		for _, ref := range page.Images {
+			image, ok := images[ref.ImageID]
+			if !ok {
+				continue
+			}
+			h := sha256.New()
+			fmt.Fprintf(h, "%dx%d/%d\n", image.Width, image.Height, image.BitsPerComponent)
+			h.Write(image.Data)
+			fingerprint.Pages[i].Images = append(fingerprint.Pages[i].Images, hex.EncodeToString(h.Sum(nil)))
+		}
+	}
+
+	if form, err := forms.Extract(pdfBytes, password, verbose); err == nil {
+		values := form.GetValues()
+		names := make([]string, 0, len(values))
+		for name := range values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		h := sha256.New()
+		for _, name := range names {
+			writeHashField(h, name, fmt.Sprint(values[name]))
+		}
+		fingerprint.FormData = hex.EncodeToString(h.Sum(nil))
+	}
+
+	h := sha256.New()
+	for _, page := range fingerprint.Pages {
+		writeHashField(h, append([]string{page.Text}, page.Images...)...)
+	}
+	writeHashField(h, fingerprint.FormData)
+	fingerprint.Document = hex.EncodeToString(h.Sum(nil))
+
+	return fingerprint, nil
+}
+
+// hashString returns the hex SHA-256 of s
+func hashString(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// writeHashField writes values as one length-prefixed record so that
+// adjacent fields cannot run together
+func writeHashField(h hash.Hash, values ...string) {
+	fmt.Fprintf(h, "%d\n", len(values))
+	for _, v := range values {
+		fmt.Fprintf(h, "%d:%s\n", len(v), v)
+	}
+}
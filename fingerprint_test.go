@@ -0,0 +1,155 @@
+package pdfer
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+)
+
+// fingerprintTestPDF builds a PDF with one page per text and a text field holding value.
+// reorder writes the same objects in a different order under different numbers.
+func fingerprintTestPDF(t *testing.T, texts []string, title, value string, reorder bool) []byte {
+	t.Helper()
+
+	writer := write.NewPDFWriter()
+	if reorder {
+		writer.AddObject([]byte("<</Unused true>>"))
+	}
+	catalogNum := writer.AddObject(nil)
+	pagesNum := writer.AddObject(nil)
+
+	var fontNum int
+	if !reorder {
+		fontNum = writer.AddObject([]byte("<</Type/Font/Subtype/Type1/BaseFont/Helvetica>>"))
+	}
+
+	var pageRefs []string
+	var fieldNum int
+	for i, text := range texts {
+		content := fmt.Sprintf("BT\n/F1 12 Tf\n72 700 Td\n(%s) Tj\nET\n", text)
+		contentNum := writer.AddObject([]byte(fmt.Sprintf("<</Length %d>>\nstream\n%s\nendstream", len(content), content)))
+		pageNum := writer.AddObject(nil)
+		pageDict := fmt.Sprintf("<</Type/Page/Parent %d 0 R/MediaBox [0 0 612 792]/Contents %d 0 R", pagesNum, contentNum)
+		if i == 0 {
+			fieldNum = writer.AddObject([]byte(fmt.Sprintf("<</Type/Annot/Subtype/Widget/FT/Tx/T(name)/V(%s)/Rect [100 500 300 520]/P %d 0 R>>", value, pageNum)))
+			pageDict += fmt.Sprintf("/Annots [%d 0 R]", fieldNum)
+		}
+		writer.SetObject(pageNum, []byte(pageDict+"/Resources <</Font <</F1 FONT 0 R>>>>>>"))
+		pageRefs = append(pageRefs, fmt.Sprintf("%d 0 R", pageNum))
+	}
+	if reorder {
+		fontNum = writer.AddObject([]byte("<</Type/Font/Subtype/Type1/BaseFont/Helvetica>>"))
+	}
+
+	// Point page resources at the font now that its number is known
+	for _, ref := range pageRefs {
+		var pageNum int
+		fmt.Sscanf(ref, "%d", &pageNum)
+		page, _ := writer.GetObject(pageNum)
+		writer.SetObject(pageNum, []byte(strings.Replace(string(page), "FONT", fmt.Sprint(fontNum), 1)))
+	}
+
+	writer.SetObject(pagesNum, []byte(fmt.Sprintf("<</Type/Pages/Kids [%s]/Count %d>>", strings.Join(pageRefs, " "), len(pageRefs))))
+	acroFormNum := writer.AddObject([]byte(fmt.Sprintf("<</Fields [%d 0 R]>>", fieldNum)))
+	writer.SetObject(catalogNum, []byte(fmt.Sprintf("<</Type/Catalog/Pages %d 0 R/AcroForm %d 0 R>>", pagesNum, acroFormNum)))
+	writer.SetRoot(catalogNum)
+	writer.SetInfo(writer.AddObject([]byte(fmt.Sprintf("<</Title (%s)>>", title))))
+
+	pdfBytes, err := writer.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+	return pdfBytes
+}
+
+func fingerprintOf(t *testing.T, pdfBytes []byte) *DocumentFingerprint {
+	t.Helper()
+
+	fingerprint, err := Fingerprint(pdfBytes, nil, false)
+	if err != nil {
+		t.Fatalf("Fingerprint failed: %v", err)
+	}
+	return fingerprint
+}
+
+func TestFingerprint_IgnoresMetadataAndLayout(t *testing.T) {
+	texts := []string{"First page", "Second page"}
+	a := fingerprintOf(t, fingerprintTestPDF(t, texts, "Draft", "Alice", false))
+	b := fingerprintOf(t, fingerprintTestPDF(t, texts, "Final", "Alice", true))
+
+	if len(a.Pages) != 2 {
+		t.Fatalf("Expected 2 page fingerprints, got %d", len(a.Pages))
+	}
+	if a.FormData == "" {
+		t.Error("Expected a form data hash")
+	}
+	if a.Document != b.Document {
+		t.Errorf("Fingerprints differ for the same content:\n%+v\n%+v", a, b)
+	}
+}
+
+func TestFingerprint_DetectsChanges(t *testing.T) {
+	base := fingerprintOf(t, fingerprintTestPDF(t, []string{"First page", "Second page"}, "Report", "Alice", false))
+
+	edited := fingerprintOf(t, fingerprintTestPDF(t, []string{"First page", "Second  page edited"}, "Report", "Alice", false))
+	if edited.Document == base.Document {
+		t.Error("Document hash did not change with page text")
+	}
+	if edited.Pages[0].Text != base.Pages[0].Text {
+		t.Error("Unchanged page 1 text hash changed")
+	}
+	if edited.Pages[1].Text == base.Pages[1].Text {
+		t.Error("Page 2 text hash did not change")
+	}
+
+	filled := fingerprintOf(t, fingerprintTestPDF(t, []string{"First page", "Second page"}, "Report", "Bob", false))
+	if filled.FormData == base.FormData || filled.Document == base.Document {
+		t.Error("Form data change not detected")
+	}
+	if filled.Pages[0].Text != base.Pages[0].Text {
+		t.Error("Page text hash changed with form data")
+	}
+}
+
+func TestFingerprint_Images(t *testing.T) {
+	build := func(gray byte) []byte {
+		builder := write.NewSimplePDFBuilder()
+		img := image.NewGray(image.Rect(0, 0, 2, 2))
+		for i := range img.Pix {
+			img.Pix[i] = gray
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			t.Fatalf("Failed to encode image: %v", err)
+		}
+		info, err := builder.Writer().AddImage(buf.Bytes(), "Im1")
+		if err != nil {
+			t.Fatalf("AddImage failed: %v", err)
+		}
+		page := builder.AddPage(write.PageSizeLetter)
+		name := page.AddImage(info)
+		page.Content().DrawImageAt(name, 72, 72, 100, 100)
+		builder.FinalizePage(page)
+		pdfBytes, err := builder.Bytes()
+		if err != nil {
+			t.Fatalf("Failed to create PDF: %v", err)
+		}
+		return pdfBytes
+	}
+
+	a := fingerprintOf(t, build(10))
+	if len(a.Pages) != 1 || len(a.Pages[0].Images) != 1 {
+		t.Fatalf("Expected one image hash, got %+v", a.Pages)
+	}
+	if b := fingerprintOf(t, build(10)); b.Document != a.Document {
+		t.Error("Same image produced different fingerprints")
+	}
+	if c := fingerprintOf(t, build(200)); c.Pages[0].Images[0] == a.Pages[0].Images[0] {
+		t.Error("Different image produced the same hash")
+	}
+}
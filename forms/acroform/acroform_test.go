@@ -1,9 +1,15 @@
 package acroform
 
 import (
+	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/write"
 )
 
 func getTestResourcePath(filename string) string {
@@ -109,3 +115,33 @@ func TestGetFieldValues(t *testing.T) {
 		t.Logf("  %s = %v", name, value)
 	}
 }
+
+func TestParseAcroForm_FreedField(t *testing.T) {
+	writer := write.NewPDFWriter()
+	catalogNum := writer.AddObject(nil)
+	pagesNum := writer.AddObject([]byte("<</Type/Pages/Kids []/Count 0>>"))
+	name := writer.AddObject([]byte("<</FT/Tx/T(name)/V(Ada)>>"))
+	email := writer.AddObject([]byte("<</FT/Tx/T(email)/V(ada@example.com)>>"))
+	acroFormNum := writer.AddObject([]byte(fmt.Sprintf("<</Fields [%d 0 R %d 0 R]>>", name, email)))
+	writer.SetObject(catalogNum, []byte(fmt.Sprintf("<</Type/Catalog/Pages %d 0 R/AcroForm %d 0 R>>", pagesNum, acroFormNum)))
+	writer.SetRoot(catalogNum)
+	base, err := writer.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+
+	// An incremental update deletes the email field without rewriting /Fields
+	startxref := bytes.LastIndex(base, []byte("startxref"))
+	prev, _ := strconv.Atoi(strings.Fields(string(base[startxref+len("startxref"):]))[0])
+	update := fmt.Sprintf("xref\n%d 1\n0000000000 00001 f \ntrailer\n<</Size %d/Root %d 0 R/Prev %d>>\nstartxref\n%d\n%%%%EOF\n",
+		email, acroFormNum+1, catalogNum, prev, len(base))
+	pdfBytes := append(append([]byte{}, base...), update...)
+
+	form, err := ParseAcroForm(pdfBytes, nil, false)
+	if err != nil {
+		t.Fatalf("ParseAcroForm failed: %v", err)
+	}
+	if len(form.Fields) != 1 || form.Fields[0].T != "name" {
+		t.Errorf("Expected only the name field, got %d fields", len(form.Fields))
+	}
+}
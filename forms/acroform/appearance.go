@@ -6,14 +6,24 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/benedoc-inc/pdfer/content/layout"
 	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/resources/font"
 )
 
 // AppearanceBuilder helps create appearance streams for form fields
 type AppearanceBuilder struct {
-	writer *write.PDFWriter
+	writer       *write.PDFWriter
+	fonts        *font.SubstitutionRegistry
+	defaultDA    string
+	fallback     *font.Font
+	fallbackDict int // Font dictionary of the last embedded fallback font
 }
 
+// FallbackFontName is the resource name of the fallback font in appearance
+// streams, field /DA strings and the AcroForm /DR
+const FallbackFontName = "FUni"
+
 // NewAppearanceBuilder creates a new appearance builder
 func NewAppearanceBuilder(w *write.PDFWriter) *AppearanceBuilder {
 	return &AppearanceBuilder{
@@ -21,6 +31,38 @@ func NewAppearanceBuilder(w *write.PDFWriter) *AppearanceBuilder {
 	}
 }
 
+// SetFontSubstitutions makes text appearances embed a replacement font from the
+// registry in place of the named font, which is otherwise referenced but not written
+func (ab *AppearanceBuilder) SetFontSubstitutions(r *font.SubstitutionRegistry) {
+	ab.fonts = r
+}
+
+// SetFallbackFont sets a Unicode font for text the field's font cannot encode,
+// such as values in non-Latin scripts. Such a value is shown in f instead,
+// embedded with the appearance as a Type0 font subset with the value's glyphs,
+// and CreateFieldAppearance names it in the field's /DA. f's Subtype is set to
+// "CIDFontType2" for the Type0 embedding.
+func (ab *AppearanceBuilder) SetFallbackFont(f *font.Font) {
+	if f != nil {
+		f.Subtype = "CIDFontType2"
+	}
+	ab.fallback = f
+}
+
+// FallbackFont returns the font dictionary object number of the most recently
+// embedded fallback font, for the AcroForm /DR under FallbackFontName. Its
+// subset has the glyphs of every value shown with the fallback so far. It is 0
+// when no appearance needed the fallback.
+func (ab *AppearanceBuilder) FallbackFont() int {
+	return ab.fallbackDict
+}
+
+// SetDefaultAppearance sets the /DA used for fields that have none in their
+// hierarchy, normally the form-wide AcroForm.DA
+func (ab *AppearanceBuilder) SetDefaultAppearance(da string) {
+	ab.defaultDA = da
+}
+
 // CreateCheckboxAppearance creates an appearance stream for a checkbox
 func (ab *AppearanceBuilder) CreateCheckboxAppearance(checked bool, width, height float64) (int, error) {
 	var content strings.Builder
@@ -71,37 +113,141 @@ func (ab *AppearanceBuilder) CreateCheckboxAppearance(checked bool, width, heigh
 	return appearanceNum, nil
 }
 
-// CreateTextAppearance creates an appearance stream for a text field
+// CreateTextAppearance creates an appearance stream for a text field.
+// A fontSize of 0 sizes the text to fit the field on one line.
 func (ab *AppearanceBuilder) CreateTextAppearance(text string, width, height, fontSize float64, fontName string) (int, error) {
-	var content strings.Builder
+	return ab.createTextAppearance(text, &textAppearance{
+		da:     DefaultAppearance{FontName: fontName, FontSize: fontSize, Color: "0 0 0 rg"},
+		width:  width,
+		height: height,
+	})
+}
 
-	content.WriteString("q\n") // Save state
+// CreateFieldAppearance creates the appearance stream of a text field for text
+// from the field's default appearance (inherited from its parents), rectangle,
+// quadding and flags. A font size of 0 in /DA sizes the text to fit the widget.
+// Text is shown in the field's AFDate/AFNumber display format when it has one.
+// Text shown with the fallback font (see SetFallbackFont) sets field.DA to name
+// that font, keeping the size and color.
+func (ab *AppearanceBuilder) CreateFieldAppearance(field *Field, text string) (int, error) {
+	if len(field.Rect) < 4 {
+		return 0, fmt.Errorf("field %s has no rectangle", field.T)
+	}
+	ta, text, da := textLayout(field, field, ab.defaultDA, text)
+	appearanceNum, err := ab.createTextAppearance(text, ta)
+	if err != nil {
+		return 0, err
+	}
+	if ta.da.FontName == FallbackFontName {
+		fieldDA := ParseDefaultAppearance(da)
+		fieldDA.FontName = FallbackFontName
+		field.DA = fieldDA.String()
+	}
+	return appearanceNum, nil
+}
 
-	// Set up text
-	content.WriteString("BT\n") // Begin text
-	content.WriteString(fmt.Sprintf("/%s %.2f Tf\n", fontName, fontSize))
-	content.WriteString("0 0 0 rg\n") // Black text
+// defaultFieldAppearance is used for fields without /DA in their hierarchy
+const defaultFieldAppearance = "/Helv 0 Tf 0 g"
+
+// textLayout returns the layout of text in a widget of field, which is field
+// itself for a field merged with its widget, and the text in the field's display
+// format. The /DA and /Q are the nearest up the widget's hierarchy; the /DA,
+// defaultDA when there is none, is returned as well.
+func textLayout(field, widget *Field, defaultDA, text string) (*textAppearance, string, string) {
+	da := defaultDA
+	if da == "" {
+		da = defaultFieldAppearance
+	}
+	for f := widget; f != nil; f = f.Parent {
+		if f.DA != "" {
+			da = f.DA
+			break
+		}
+	}
+	quadding := 0
+	for f := widget; f != nil; f = f.Parent {
+		if f.Q != 0 {
+			quadding = f.Q
+			break
+		}
+	}
+	ta := &textAppearance{
+		da:        ParseDefaultAppearance(da),
+		width:     widget.Rect[2] - widget.Rect[0],
+		height:    widget.Rect[3] - widget.Rect[1],
+		quadding:  quadding,
+		multiline: field.Ff&FlagMultiline != 0,
+	}
+	if field.Ff&FlagComb != 0 && field.MaxLen > 0 {
+		ta.comb = field.MaxLen
+		ta.multiline = false
+	}
+	if format := fieldFormat(field); format != nil {
+		// Red negative numbers lose their sign when formatted
+		if format.negativeInRed(text) {
+			ta.da.Color = "1 0 0 rg"
+		}
+		if formatted, err := format.Format(text); err == nil {
+			text = formatted
+		}
+	}
+	return ta, text, da
+}
 
-	// Position text (bottom-left of field)
-	textY := height * 0.2 // Leave some margin
-	content.WriteString(fmt.Sprintf("0 %.2f Td\n", textY))
+// textAppearance describes how a text field appearance is laid out
+type textAppearance struct {
+	da            DefaultAppearance
+	width, height float64
+	quadding      int  // 0 left, 1 centered, 2 right
+	comb          int  // Number of comb cells, 0 when the field is not combed
+	multiline     bool // Wrap text to the field width
+}
 
-	// Escape text for PDF
-	escapedText := escapeAppearanceText(text)
-	content.WriteString(fmt.Sprintf("(%s) Tj\n", escapedText))
+// createTextAppearance writes the appearance stream of text laid out as ta
+// describes. When the font cannot encode the text and there is a fallback font,
+// the text is shown in the fallback and ta.da names it.
+func (ab *AppearanceBuilder) createTextAppearance(text string, ta *textAppearance) (int, error) {
+	fontName := ta.da.FontName
+	fontRef := fmt.Sprintf("%d 0 R", 0) // Font reference (would need actual font)
+	af := appearanceFont{}
+	if ab.fonts != nil {
+		if f, err := ab.fonts.Substitute(fontName, "appearance"); err == nil {
+			af.font = f
+		} else if err != font.ErrNoSubstitute {
+			return 0, fmt.Errorf("failed to load substitute for font %s: %w", fontName, err)
+		}
+	}
+	if ab.fallback != nil && !canEncode(text, af.font) {
+		af.font = ab.fallback
+		fontName = FallbackFontName
+		ta.da.FontName = FallbackFontName
+	}
+	if af.font != nil {
+		af.font.AddString(text)
+		fontObjs, err := ab.writer.AddFont(af.font)
+		if err != nil {
+			return 0, err
+		}
+		fontRef = fmt.Sprintf("%d 0 R", fontObjs.FontDictNum)
+		if af.font == ab.fallback {
+			ab.fallbackDict = fontObjs.FontDictNum
+		}
+	}
 
-	content.WriteString("ET\n") // End text
-	content.WriteString("Q\n")  // Restore state
+	var content strings.Builder
+	if err := writeTextContent(&content, text, ta, fontName, af); err != nil {
+		return 0, err
+	}
 
 	// Create appearance stream
 	appearanceDict := write.Dictionary{
 		"/Type":    "/XObject",
 		"/Subtype": "/Form",
-		"/BBox":    []interface{}{0, 0, width, height},
+		"/BBox":    []interface{}{0, 0, ta.width, ta.height},
 		"/Matrix":  []interface{}{1, 0, 0, 1, 0, 0},
 		"/Resources": write.Dictionary{
 			"/Font": write.Dictionary{
-				fontName: fmt.Sprintf("%d 0 R", 0), // Font reference (would need actual font)
+				fontName: fontRef,
 			},
 		},
 	}
@@ -110,6 +256,139 @@ func (ab *AppearanceBuilder) CreateTextAppearance(text string, width, height, fo
 	return appearanceNum, nil
 }
 
+// writeTextContent writes the marked content of a text appearance: text laid out
+// as ta describes in the font of resource name fontName, measured with af
+func writeTextContent(content *strings.Builder, text string, ta *textAppearance, fontName string, af appearanceFont) error {
+	fontSize := ta.da.FontSize
+	if fontSize <= 0 {
+		size, err := autoFontSize(text, ta.width, ta.height, ta.comb, ta.multiline, af)
+		if err != nil {
+			return fmt.Errorf("failed to size text: %w", err)
+		}
+		fontSize = size
+	}
+
+	lines := []string{text}
+	if ta.multiline {
+		broken, err := layout.LineBreak(text, ta.width-2*appearancePadding, af, fontSize)
+		if err != nil {
+			return fmt.Errorf("failed to break text: %w", err)
+		}
+		lines = lines[:0]
+		for _, line := range broken {
+			lines = append(lines, line.Text)
+		}
+	}
+
+	content.WriteString("/Tx BMC\n")
+	content.WriteString("q\n") // Save state
+
+	// Clip to the field inset
+	content.WriteString(fmt.Sprintf("%.2f %.2f %.2f %.2f re W n\n", appearancePadding/2, appearancePadding/2,
+		ta.width-appearancePadding, ta.height-appearancePadding))
+
+	// Set up text
+	content.WriteString("BT\n") // Begin text
+	content.WriteString(fmt.Sprintf("/%s %.2f Tf\n", fontName, fontSize))
+	content.WriteString(ta.da.Color + "\n")
+
+	// Single lines are centered vertically; wrapped text starts at the top
+	ascent, descent, lineHeight := af.verticalMetrics(fontSize)
+	y := (ta.height-(ascent-descent))/2 - descent
+	if ta.multiline {
+		y = ta.height - appearancePadding - ascent
+	}
+	if ta.comb > 0 {
+		if err := writeCombText(content, text, ta, fontSize, y, af); err != nil {
+			return err
+		}
+		lines = nil
+	}
+	for _, line := range lines {
+		if line == "" {
+			y -= lineHeight
+			continue
+		}
+		width, err := af.TextWidth(line, fontSize)
+		if err != nil {
+			return fmt.Errorf("failed to measure text: %w", err)
+		}
+		x := appearancePadding
+		switch ta.quadding {
+		case 1:
+			x = (ta.width - width) / 2
+		case 2:
+			x = ta.width - appearancePadding - width
+		}
+		content.WriteString(fmt.Sprintf("1 0 0 1 %.2f %.2f Tm\n", x, y))
+		content.WriteString(textOperator(line, af.font))
+		y -= lineHeight
+	}
+
+	content.WriteString("ET\n") // End text
+	content.WriteString("Q\n")  // Restore state
+	content.WriteString("EMC\n")
+	return nil
+}
+
+// writeCombText shows one character centered in each of the comb cells, which
+// split the field width evenly. Quadding shifts the text to the last or middle
+// cells; characters beyond the last cell are dropped.
+func writeCombText(content *strings.Builder, text string, ta *textAppearance, fontSize, y float64, af appearanceFont) error {
+	runes := []rune(text)
+	if len(runes) > ta.comb {
+		runes = runes[:ta.comb]
+	}
+	first := 0
+	switch ta.quadding {
+	case 1:
+		first = (ta.comb - len(runes)) / 2
+	case 2:
+		first = ta.comb - len(runes)
+	}
+
+	cellWidth := ta.width / float64(ta.comb)
+	for i, r := range runes {
+		width, err := af.TextWidth(string(r), fontSize)
+		if err != nil {
+			return fmt.Errorf("failed to measure text: %w", err)
+		}
+		x := float64(first+i)*cellWidth + (cellWidth-width)/2
+		content.WriteString(fmt.Sprintf("1 0 0 1 %.2f %.2f Tm\n", x, y))
+		content.WriteString(textOperator(string(r), af.font))
+	}
+	return nil
+}
+
+// canEncode reports whether f has every character of text, or when f is nil,
+// whether a standard font can show it in WinAnsiEncoding. Only a Type0 font
+// can show characters beyond U+00FF.
+func canEncode(text string, f *font.Font) bool {
+	if f == nil {
+		_, _, report := write.EncodeText(text, nil)
+		return report.OK()
+	}
+	for _, r := range text {
+		if r == '\n' || r == '\r' || r == '\t' {
+			continue
+		}
+		if !f.HasGlyph(r) || r > 0xFF && f.Subtype != "CIDFontType2" {
+			return false
+		}
+	}
+	return true
+}
+
+// textOperator returns the Tj operator showing text in f: glyph IDs for a
+// Type0 font, WinAnsiEncoding for a standard font when f is nil
+func textOperator(text string, f *font.Font) string {
+	encoded, hex, _ := write.EncodeText(text, &write.ShowTextOptions{Font: f})
+	if hex {
+		return fmt.Sprintf("<%s> Tj\n", encoded)
+	}
+	return fmt.Sprintf("(%s) Tj\n", encoded)
+}
+
 // CreateButtonAppearance creates an appearance stream for a button
 func (ab *AppearanceBuilder) CreateButtonAppearance(label string, width, height, fontSize float64) (int, error) {
 	var content strings.Builder
@@ -0,0 +1,100 @@
+package acroform
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+)
+
+func TestFitMaxLen(t *testing.T) {
+	field := &Field{T: "code", FT: "Tx", MaxLen: 3}
+
+	if got, err := fitMaxLen(field, "abcdef", false); err != nil || got != "abc" {
+		t.Errorf("fitMaxLen = %v, %v; want abc", got, err)
+	}
+	if got, err := fitMaxLen(field, "ab", false); err != nil || got != "ab" {
+		t.Errorf("Short value changed: %v, %v", got, err)
+	}
+	if got, _ := fitMaxLen(field, "ÄÖÜß", false); got != "ÄÖÜ" {
+		t.Errorf("Expected truncation by characters, got %q", got)
+	}
+
+	// Values of other field types are not touched
+	choice := &Field{T: "choice", FT: "Ch", MaxLen: 3}
+	if got, _ := fitMaxLen(choice, "abcdef", false); got != "abcdef" {
+		t.Errorf("Choice value changed: %v", got)
+	}
+
+	// Comb fields reject values with more characters than cells
+	field.Ff = FlagComb
+	_, err := fitMaxLen(field, "abcd", false)
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.FieldName != "code" {
+		t.Errorf("Expected a ValidationError for an overlong comb value, got %v", err)
+	}
+	if got, err := fitMaxLen(field, "abc", false); err != nil || got != "abc" {
+		t.Errorf("Fitting comb value rejected: %v, %v", got, err)
+	}
+}
+
+func TestCreateFieldAppearance_Comb(t *testing.T) {
+	w := write.NewPDFWriter()
+	ab := NewAppearanceBuilder(w)
+	tm := regexp.MustCompile(`1 0 0 1 ([\d.]+) [\d.]+ Tm\n\((.)\) Tj`)
+
+	// cellCenters returns the text of each cell, keyed by the cell the character is centered in
+	cellCenters := func(field *Field, text string) map[int]string {
+		t.Helper()
+		num, err := ab.CreateFieldAppearance(field, text)
+		if err != nil {
+			t.Fatalf("CreateFieldAppearance failed: %v", err)
+		}
+		content := appearanceContent(t, w, num)
+		size := appearanceFontSize(t, content)
+		cells := make(map[int]string)
+		for _, m := range tm.FindAllStringSubmatch(content, -1) {
+			x, _ := strconv.ParseFloat(m[1], 64)
+			center := x + size*approxCharWidth/2
+			cell := int(center / 20)
+			if d := center - (float64(cell)*20 + 10); d > 0.01 || d < -0.01 {
+				t.Errorf("Character %q is not centered in its cell (x=%v)", m[2], x)
+			}
+			cells[cell] = m[2]
+		}
+		return cells
+	}
+
+	field := &Field{T: "pin", FT: "Tx", Ff: FlagComb, MaxLen: 5, Rect: []float64{0, 0, 100, 20}, DA: "/Helv 0 Tf 0 g"}
+	cells := cellCenters(field, "123")
+	if len(cells) != 3 || cells[0] != "1" || cells[1] != "2" || cells[2] != "3" {
+		t.Errorf("Unexpected cells %v", cells)
+	}
+
+	// Right quadding fills the last cells
+	field.Q = 2
+	cells = cellCenters(field, "123")
+	if len(cells) != 3 || cells[2] != "1" || cells[4] != "3" {
+		t.Errorf("Unexpected right aligned cells %v", cells)
+	}
+
+	// Characters beyond the last cell are dropped
+	field.Q = 0
+	cells = cellCenters(field, "1234567")
+	if len(cells) != 5 || cells[4] != "5" {
+		t.Errorf("Unexpected cells for overlong text %v", cells)
+	}
+}
+
+func TestFieldDef_SetComb(t *testing.T) {
+	fd := &FieldDef{Name: "pin"}
+	fd.SetMaxLength(4).SetComb(true)
+	if fd.Flags&FlagComb == 0 || fd.MaxLen != 4 {
+		t.Errorf("Comb flag not set: %+v", fd)
+	}
+	if fd.SetComb(false).Flags&FlagComb != 0 {
+		t.Error("Comb flag not cleared")
+	}
+}
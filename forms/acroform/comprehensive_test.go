@@ -1,11 +1,15 @@
 package acroform
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
-	"github.com/benedoc-inc/pdfer/types"
 	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/resources/font"
+	"github.com/benedoc-inc/pdfer/types"
 )
 
 func TestFormBuilderIntegration(t *testing.T) {
@@ -84,6 +88,127 @@ func TestAppearanceBuilder(t *testing.T) {
 	t.Logf("Created appearances: checkbox=%d, text=%d, button=%d", appearanceNum, textAppearance, buttonAppearance)
 }
 
+func TestAppearanceBuilder_FontSubstitution(t *testing.T) {
+	fontPath := filepath.Join("..", "..", "tests", "resources", "test_font.ttf")
+	if _, err := os.Stat(fontPath); os.IsNotExist(err) {
+		t.Skipf("Test font not found, skipping substitution test")
+	}
+
+	w := write.NewPDFWriter()
+	ab := NewAppearanceBuilder(w)
+	fonts := font.NewSubstitutionRegistry()
+	fonts.Register("Helv", fontPath)
+	ab.SetFontSubstitutions(fonts)
+
+	appearanceNum, err := ab.CreateTextAppearance("Hello", 100, 20, 12, "Helv")
+	if err != nil {
+		t.Fatalf("Failed to create text appearance: %v", err)
+	}
+	if appearanceNum == 0 {
+		t.Fatal("Text appearance object number should not be zero")
+	}
+
+	appearance, err := w.GetObject(appearanceNum)
+	if err != nil {
+		t.Fatalf("Failed to get appearance: %v", err)
+	}
+	if strings.Contains(string(appearance), "/Helv 0 0 R") {
+		t.Errorf("Appearance still references a placeholder font: %s", appearance)
+	}
+	if report := fonts.Report(); len(report) != 1 || report[0].Context != "appearance" {
+		t.Errorf("Expected one appearance substitution, got %+v", report)
+	}
+}
+
+func TestAppearanceBuilder_FallbackFont(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "tests", "resources", "test_font.ttf"))
+	if err != nil {
+		t.Skipf("Test font not found: %v", err)
+	}
+	fallback, err := font.NewFont("Gothic", data)
+	if err != nil {
+		t.Fatalf("NewFont failed: %v", err)
+	}
+
+	w := write.NewPDFWriter()
+	ab := NewAppearanceBuilder(w)
+	ab.SetFallbackFont(fallback)
+
+	latin := &Field{T: "latin", Rect: []float64{0, 0, 200, 20}, DA: "/Helv 10 Tf 0 g"}
+	if _, err := ab.CreateFieldAppearance(latin, "Hello"); err != nil {
+		t.Fatalf("CreateFieldAppearance failed: %v", err)
+	}
+	if latin.DA != "/Helv 10 Tf 0 g" || ab.FallbackFont() != 0 {
+		t.Errorf("Encodable text should keep the field font: DA=%q fallback=%d", latin.DA, ab.FallbackFont())
+	}
+
+	gothic := &Field{T: "gothic", Rect: []float64{0, 0, 200, 20}, DA: "/Helv 10 Tf 1 0 0 rg"}
+	appearanceNum, err := ab.CreateFieldAppearance(gothic, "\U00010330\U00010331")
+	if err != nil {
+		t.Fatalf("CreateFieldAppearance failed: %v", err)
+	}
+	if gothic.DA != "/FUni 10 Tf 1 0 0 rg" {
+		t.Errorf("Expected /DA naming the fallback font, got %q", gothic.DA)
+	}
+	if ab.FallbackFont() == 0 {
+		t.Fatal("Fallback font not embedded")
+	}
+	appearance, err := w.GetObject(appearanceNum)
+	if err != nil {
+		t.Fatalf("Failed to get appearance: %v", err)
+	}
+	want := fmt.Sprintf("<%s> Tj", fallback.GlyphHex("\U00010330\U00010331"))
+	if !strings.Contains(string(appearance), "/FUni 10.00 Tf") || !strings.Contains(string(appearance), want) {
+		t.Errorf("Appearance does not show glyph IDs in the fallback font: %s", appearance)
+	}
+	fontDict, _ := w.GetObject(ab.FallbackFont())
+	if !strings.Contains(string(fontDict), "/Subtype /Type0") {
+		t.Errorf("Expected a Type0 font, got %s", fontDict)
+	}
+}
+
+func TestFormBuilder_FallbackFont(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "tests", "resources", "test_font.ttf"))
+	if err != nil {
+		t.Skipf("Test font not found: %v", err)
+	}
+	fallback, err := font.NewFont("Gothic", data)
+	if err != nil {
+		t.Fatalf("NewFont failed: %v", err)
+	}
+
+	builder := write.NewSimplePDFBuilder()
+	page := builder.AddPage(write.PageSizeLetter)
+	formBuilder := NewFormBuilder(builder)
+	formBuilder.SetFallbackFont(fallback)
+	formBuilder.AddTextField("latin", []float64{72, 700, 300, 720}, 0).SetValue("Hello")
+	formBuilder.AddTextField("gothic", []float64{72, 650, 300, 670}, 0).SetValue("\U00010330")
+	if _, err := formBuilder.BuildForm(); err != nil {
+		t.Fatalf("BuildForm failed: %v", err)
+	}
+	builder.FinalizePage(page)
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to generate PDF: %v", err)
+	}
+
+	pdfStr := string(pdfBytes)
+	if !strings.Contains(pdfStr, "/DR << /Font << /FUni ") {
+		t.Error("AcroForm /DR missing the fallback font")
+	}
+	if !strings.Contains(pdfStr, "/V <FEFFD800DF30> /DA (/FUni 0 Tf 0 g) /AP << /N ") {
+		t.Error("Gothic field missing its UTF-16 value, fallback /DA or appearance")
+	}
+	if strings.Count(pdfStr, "/AP <<") != 1 {
+		t.Error("Only the field the default font cannot show should get an appearance")
+	}
+
+	acroForm := parseFilledForm(t, pdfBytes)
+	if gothic := acroForm.FindFieldByName("gothic"); gothic == nil || gothic.V != "\U00010330" {
+		t.Errorf("Unicode value not read back: %+v", gothic)
+	}
+}
+
 func TestFormFlattening(t *testing.T) {
 	testPDFPath := getTestResourcePath("acroform_test.pdf")
 	if _, err := os.Stat(testPDFPath); os.IsNotExist(err) {
@@ -0,0 +1,161 @@
+package acroform
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/content/layout"
+	"github.com/benedoc-inc/pdfer/resources/font"
+)
+
+// Field flags (Ff)
+const (
+	FlagReadOnly  = 1 << 0  // Bit 1: the user may not change the value
+	FlagRequired  = 1 << 1  // Bit 2: the field must have a value when the form is submitted
+	FlagMultiline = 1 << 12 // Bit 13: text may span several lines
+	FlagComb      = 1 << 24 // Bit 25: text is spread over MaxLen equal cells
+
+	FlagRadio       = 1 << 15 // Bit 16: a button is a radio button
+	FlagPushbutton  = 1 << 16 // Bit 17: a button keeps no value
+	FlagCombo       = 1 << 17 // Bit 18: a choice field is a combo box rather than a list box
+	FlagMultiSelect = 1 << 21 // Bit 22: a list box may have several options selected
+)
+
+// Auto font sizing parameters, matching what viewers use for a size of 0 in /DA
+const (
+	appearancePadding = 2.0  // Inset of the text from the widget edges
+	minAutoFontSize   = 4.0  // Smallest size auto sizing shrinks text to
+	maxMultilineSize  = 12.0 // Multiline fields start from this size
+	autoFontSizeStep  = 0.5  // Step used to shrink multiline text
+)
+
+// Metrics used for fonts that are referenced by name only
+const (
+	approxCharWidth = 0.6  // Average advance width as a fraction of the font size
+	approxAscent    = 0.75 // Ascent as a fraction of the font size
+	approxDescent   = -0.25
+	approxLineGap   = 0.15
+)
+
+// DefaultAppearance is a parsed default appearance (/DA) string such as
+// "/Helv 0 Tf 0 g"
+type DefaultAppearance struct {
+	FontName string  // Font resource name without the slash
+	FontSize float64 // Font size; 0 means size the text to fit the widget
+	Color    string  // Color operator, e.g. "0 g" or "1 0 0 rg"
+}
+
+// ParseDefaultAppearance parses a /DA string. Operators other than Tf and the
+// color operators are ignored.
+func ParseDefaultAppearance(da string) DefaultAppearance {
+	result := DefaultAppearance{Color: "0 g"}
+	var operands []string
+	for _, token := range strings.Fields(da) {
+		switch token {
+		case "Tf":
+			if len(operands) >= 2 {
+				result.FontName = strings.TrimPrefix(operands[len(operands)-2], "/")
+				result.FontSize, _ = strconv.ParseFloat(operands[len(operands)-1], 64)
+			}
+		case "g", "rg", "k":
+			n := map[string]int{"g": 1, "rg": 3, "k": 4}[token]
+			if len(operands) >= n {
+				color := append([]string{}, operands[len(operands)-n:]...)
+				result.Color = strings.Join(append(color, token), " ")
+			}
+		default:
+			operands = append(operands, token)
+			continue
+		}
+		operands = operands[:0]
+	}
+	return result
+}
+
+// String formats the default appearance as a /DA string
+func (da DefaultAppearance) String() string {
+	return fmt.Sprintf("/%s %s Tf %s", da.FontName, strconv.FormatFloat(da.FontSize, 'f', -1, 64), da.Color)
+}
+
+// appearanceFont measures text for an appearance stream, with the metrics of an
+// embedded font or of a font in the form's /DR when there is one, and
+// approximate metrics otherwise
+type appearanceFont struct {
+	font     *font.Font
+	resource *resourceFont
+}
+
+func (af appearanceFont) TextWidth(text string, size float64) (float64, error) {
+	if af.font != nil {
+		return af.font.TextWidth(text, size)
+	}
+	if af.resource != nil {
+		return af.resource.textWidth(text, size), nil
+	}
+	return float64(len([]rune(text))) * size * approxCharWidth, nil
+}
+
+// verticalMetrics returns the ascent, descent (negative) and line height at size
+func (af appearanceFont) verticalMetrics(size float64) (ascent, descent, lineHeight float64) {
+	if af.font != nil {
+		if m, err := af.font.MeasureString("", size); err == nil && m.LineHeight > 0 {
+			return m.Ascent, m.Descent, m.LineHeight
+		}
+	}
+	return approxAscent * size, approxDescent * size, (approxAscent - approxDescent + approxLineGap) * size
+}
+
+// autoFontSize returns the largest font size at which text fits a width x height
+// widget: on one line, in comb cells when comb > 0, or wrapped when multiline
+func autoFontSize(text string, width, height float64, comb int, multiline bool, af appearanceFont) (float64, error) {
+	innerWidth := width - 2*appearancePadding
+	innerHeight := height - 2*appearancePadding
+	if innerWidth <= 0 || innerHeight <= 0 {
+		return minAutoFontSize, nil
+	}
+
+	if multiline {
+		for size := maxMultilineSize; size > minAutoFontSize; size -= autoFontSizeStep {
+			lines, err := layout.LineBreak(text, innerWidth, af, size)
+			if err != nil {
+				return 0, err
+			}
+			_, _, lineHeight := af.verticalMetrics(size)
+			if float64(len(lines))*lineHeight <= innerHeight {
+				return size, nil
+			}
+		}
+		return minAutoFontSize, nil
+	}
+
+	// Largest size for which a line fits the height
+	_, _, lineHeight := af.verticalMetrics(1)
+	size := innerHeight / lineHeight
+
+	// Shrink to the width: the whole text, or the widest character in a comb cell
+	if comb > 0 {
+		cellWidth := width / float64(comb)
+		for _, r := range text {
+			w, err := af.TextWidth(string(r), 1)
+			if err != nil {
+				return 0, err
+			}
+			if w > 0 {
+				size = math.Min(size, cellWidth/w)
+			}
+		}
+	} else if text != "" {
+		w, err := af.TextWidth(text, 1)
+		if err != nil {
+			return 0, err
+		}
+		if w > 0 {
+			size = math.Min(size, innerWidth/w)
+		}
+	}
+
+	size = math.Floor(size*100) / 100
+	return math.Max(size, minAutoFontSize), nil
+}
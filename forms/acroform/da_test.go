@@ -0,0 +1,131 @@
+package acroform
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+)
+
+// appearanceContent returns the decompressed content of an appearance stream
+func appearanceContent(t *testing.T, w *write.PDFWriter, objNum int) string {
+	t.Helper()
+	data, err := w.GetObject(objNum)
+	if err != nil {
+		t.Fatalf("Failed to get appearance: %v", err)
+	}
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Appearance is not compressed: %v", err)
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Failed to decompress appearance: %v", err)
+	}
+	return string(content)
+}
+
+// appearanceFontSize returns the size of the Tf operator in an appearance stream
+func appearanceFontSize(t *testing.T, content string) float64 {
+	t.Helper()
+	m := regexp.MustCompile(`/\w+ ([\d.]+) Tf`).FindStringSubmatch(content)
+	if m == nil {
+		t.Fatalf("No Tf operator in appearance:\n%s", content)
+	}
+	size, _ := strconv.ParseFloat(m[1], 64)
+	return size
+}
+
+func TestParseDefaultAppearance(t *testing.T) {
+	tests := []struct {
+		da   string
+		want DefaultAppearance
+	}{
+		{"/Helv 0 Tf 0 g", DefaultAppearance{FontName: "Helv", FontSize: 0, Color: "0 g"}},
+		{"0.5 0 1 rg /TiRo 10.5 Tf", DefaultAppearance{FontName: "TiRo", FontSize: 10.5, Color: "0.5 0 1 rg"}},
+		{"/Cour 9 Tf 0 0 0 1 k 2 Tz", DefaultAppearance{FontName: "Cour", FontSize: 9, Color: "0 0 0 1 k"}},
+		{"", DefaultAppearance{Color: "0 g"}},
+	}
+	for _, tt := range tests {
+		if got := ParseDefaultAppearance(tt.da); got != tt.want {
+			t.Errorf("ParseDefaultAppearance(%q) = %+v, want %+v", tt.da, got, tt.want)
+		}
+	}
+	if got := ParseDefaultAppearance("/Helv 0 Tf 0 g").String(); got != "/Helv 0 Tf 0 g" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestAutoFontSize(t *testing.T) {
+	af := appearanceFont{}
+
+	// Short text in a 20pt high field is limited by the height
+	size, err := autoFontSize("Hi", 200, 20, 0, false, af)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 16 / (approxAscent - approxDescent + approxLineGap); size != float64(int(want*100))/100 {
+		t.Errorf("Height-limited size = %v, want %v", size, want)
+	}
+
+	// Long text is limited by the width
+	size, _ = autoFontSize("A fairly long line of text", 100, 20, 0, false, af)
+	if width, _ := af.TextWidth("A fairly long line of text", size); width > 100-2*appearancePadding {
+		t.Errorf("Text at %v is %v wide, wider than the field", size, width)
+	}
+	if size <= minAutoFontSize {
+		t.Errorf("Expected a size above the minimum, got %v", size)
+	}
+
+	// Comb fields fit one character per cell
+	size, _ = autoFontSize("1234", 100, 40, 10, false, af)
+	if want := 10 / approxCharWidth; size != float64(int(want*100))/100 {
+		t.Errorf("Comb size = %v, want %v", size, want)
+	}
+
+	// Multiline text shrinks until every line fits
+	text := "one two three four five six seven eight nine ten eleven twelve"
+	size, _ = autoFontSize(text, 80, 40, 0, true, af)
+	if size >= maxMultilineSize || size < minAutoFontSize {
+		t.Errorf("Unexpected multiline size %v", size)
+	}
+
+	if size, _ := autoFontSize("x", 2, 2, 0, false, af); size != minAutoFontSize {
+		t.Errorf("Expected minimum size for a tiny field, got %v", size)
+	}
+}
+
+func TestCreateFieldAppearance_AutoSize(t *testing.T) {
+	w := write.NewPDFWriter()
+	ab := NewAppearanceBuilder(w)
+
+	parent := &Field{T: "parent", DA: "/Helv 0 Tf 1 0 0 rg"}
+	field := &Field{T: "name", Parent: parent, Rect: []float64{0, 0, 150, 22}, Q: 2}
+	num, err := ab.CreateFieldAppearance(field, "Jane Doe")
+	if err != nil {
+		t.Fatalf("CreateFieldAppearance failed: %v", err)
+	}
+	content := appearanceContent(t, w, num)
+	size := appearanceFontSize(t, content)
+	if size < minAutoFontSize || size > 22 {
+		t.Errorf("Unexpected auto size %v", size)
+	}
+	if !regexp.MustCompile(`1 0 0 rg`).MatchString(content) {
+		t.Errorf("Inherited color not used:\n%s", content)
+	}
+
+	// A fixed size is kept
+	field.DA = "/Helv 9 Tf 0 g"
+	num, _ = ab.CreateFieldAppearance(field, "Jane Doe")
+	if size := appearanceFontSize(t, appearanceContent(t, w, num)); size != 9 {
+		t.Errorf("Expected size 9, got %v", size)
+	}
+
+	if _, err := ab.CreateFieldAppearance(&Field{T: "norect"}, "x"); err == nil {
+		t.Error("Expected error for a field without a rectangle")
+	}
+}
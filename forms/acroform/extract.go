@@ -4,8 +4,10 @@ package acroform
 import (
 	"bytes"
 	"fmt"
+	"io"
 
 	"github.com/benedoc-inc/pdfer/core/encrypt"
+	"github.com/benedoc-inc/pdfer/core/parse"
 	"github.com/benedoc-inc/pdfer/types"
 )
 
@@ -31,6 +33,27 @@ func ExtractAcroForm(pdfBytes []byte, password []byte, verbose bool) (*AcroForm,
 	return acroForm, nil
 }
 
+// ExtractAcroFormFromReader extracts AcroForm structure like ExtractAcroForm from
+// a PDF read from r, which holds size bytes, without reading the whole file into
+// memory. Encrypted documents are opened with password. See
+// parse.OpenReaderWithOptions.
+func ExtractAcroFormFromReader(r io.ReaderAt, size int64, password []byte, verbose bool) (*AcroForm, error) {
+	pdf, err := parse.OpenReaderWithOptions(r, size, parse.ParseOptions{
+		Password: password,
+		Verbose:  verbose,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PDF: %w", err)
+	}
+
+	acroForm, err := parseAcroFormFromPDF(pdf, verbose)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse AcroForm: %w", err)
+	}
+
+	return acroForm, nil
+}
+
 // ExtractFormSchema extracts AcroForm and converts it to FormSchema
 func ExtractFormSchema(pdfBytes []byte, password []byte, verbose bool) (*types.FormSchema, error) {
 	acroForm, err := ExtractAcroForm(pdfBytes, password, verbose)
@@ -0,0 +1,188 @@
+package acroform
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// buildFillTestPDF creates a PDF with the text fields "name" and "city"
+func buildFillTestPDF(t *testing.T) []byte {
+	t.Helper()
+	builder := write.NewSimplePDFBuilder()
+	page := builder.AddPage(write.PageSizeLetter)
+	formBuilder := NewFormBuilder(builder)
+	formBuilder.AddTextField("name", []float64{72, 700, 300, 720}, 0)
+	formBuilder.AddTextField("city", []float64{72, 650, 300, 670}, 0).SetRequired(true)
+	if _, err := formBuilder.BuildForm(); err != nil {
+		t.Fatalf("Failed to build form: %v", err)
+	}
+	builder.FinalizePage(page)
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to generate PDF: %v", err)
+	}
+	return pdfBytes
+}
+
+// parseFilledForm parses the AcroForm of a filled PDF by searching its bytes, as
+// filling replaces objects in place without rewriting the xref table
+func parseFilledForm(t *testing.T, pdfBytes []byte) *AcroForm {
+	t.Helper()
+	acroForm, err := parseAcroFormFromBytes(pdfBytes, nil, false)
+	if err != nil {
+		t.Fatalf("Failed to parse filled form: %v", err)
+	}
+	return acroForm
+}
+
+func TestFillFormFieldsWithOptions_Lock(t *testing.T) {
+	pdfBytes := buildFillTestPDF(t)
+	data := types.FormData{"name": "Jane Doe"}
+
+	filled, err := FillFormFieldsWithOptions(pdfBytes, data, FillOptions{Lock: types.LockFilled})
+	if err != nil {
+		t.Fatalf("FillFormFieldsWithOptions failed: %v", err)
+	}
+	acroForm := parseFilledForm(t, filled)
+	name, city := acroForm.FindFieldByName("name"), acroForm.FindFieldByName("city")
+	if name == nil || city == nil {
+		t.Fatal("Fields missing after fill")
+	}
+	if name.V != "Jane Doe" || name.Ff&FlagReadOnly == 0 {
+		t.Errorf("Filled field not locked: V=%v Ff=%d", name.V, name.Ff)
+	}
+	if city.Ff&FlagReadOnly != 0 {
+		t.Errorf("Unfilled field locked: Ff=%d", city.Ff)
+	}
+
+	filled, err = FillFormFieldsWithOptions(pdfBytes, data, FillOptions{Lock: types.LockAll})
+	if err != nil {
+		t.Fatalf("FillFormFieldsWithOptions failed: %v", err)
+	}
+	city = parseFilledForm(t, filled).FindFieldByName("city")
+	if city.Ff != 0x2|FlagReadOnly {
+		t.Errorf("Expected Required and ReadOnly flags on unfilled field, got Ff=%d", city.Ff)
+	}
+
+	// Without a lock, flags are untouched
+	filled, _ = FillFormFieldsWithOptions(pdfBytes, data, FillOptions{})
+	if name := parseFilledForm(t, filled).FindFieldByName("name"); name.Ff != 0 {
+		t.Errorf("Expected no flags, got Ff=%d", name.Ff)
+	}
+}
+
+func TestFillFormFieldsWithOptions_Clear(t *testing.T) {
+	filled, err := FillFormFieldsWithOptions(buildFillTestPDF(t), types.FormData{"name": "Jane Doe", "city": "Oslo"}, FillOptions{})
+	if err != nil {
+		t.Fatalf("Fill failed: %v", err)
+	}
+	if name := parseFilledForm(t, filled).FindFieldByName("name"); name.V != "Jane Doe" {
+		t.Fatalf("Field not filled: %v", name.V)
+	}
+
+	// A nil value clears one field, ClearFields the other
+	cleared, err := FillFormFieldsWithOptions(filled, types.FormData{"name": nil}, FillOptions{ClearFields: []string{"city"}})
+	if err != nil {
+		t.Fatalf("Clearing fill failed: %v", err)
+	}
+	acroForm := parseFilledForm(t, cleared)
+	for _, name := range []string{"name", "city"} {
+		if field := acroForm.FindFieldByName(name); field == nil || field.V != nil {
+			t.Errorf("Field %s not cleared: %+v", name, field)
+		}
+	}
+}
+
+func TestApplyFieldEdit_Clear(t *testing.T) {
+	field := &Field{T: "agree", FT: "Btn"}
+	dict := `<< /FT /Btn /T (agree) /V /Yes /AS /Yes /AP << /N << /Yes 5 0 R /Off 6 0 R >> /D << /Yes 7 0 R >> >> /Rect [0 0 10 10] >>`
+	got, err := applyFieldEdit([]byte(dict), fieldEdit{field: field, clear: true})
+	if err != nil {
+		t.Fatalf("applyFieldEdit failed: %v", err)
+	}
+	want := `<< /FT /Btn /T (agree)  /AS /Off  /Rect [0 0 10 10] >>`
+	if string(got) != want {
+		t.Errorf("applyFieldEdit = %q, want %q", got, want)
+	}
+
+	field = &Field{T: "name", FT: "Tx"}
+	got, _ = applyFieldEdit([]byte(`<< /T (name) /V <FEFF0041> /AP 9 0 R >>`), fieldEdit{field: field, clear: true})
+	if strings.Contains(string(got), "/V") || strings.Contains(string(got), "/AP") {
+		t.Errorf("Value or appearance left: %q", got)
+	}
+}
+
+func TestFillFormFieldsWithReport(t *testing.T) {
+	data := types.FormData{"name": "Jane Doe", "country": "NO"}
+	_, report, err := FillFormFieldsWithReport(buildFillTestPDF(t), data, FillOptions{Lock: types.LockAll, ClearFields: []string{"city"}})
+	if err != nil {
+		t.Fatalf("FillFormFieldsWithReport failed: %v", err)
+	}
+	if report.Filled["name"] != "Jane Doe" || len(report.Filled) != 1 {
+		t.Errorf("Filled = %v", report.Filled)
+	}
+	if strings.Join(report.Cleared, ",") != "city" {
+		t.Errorf("Cleared = %v", report.Cleared)
+	}
+	if strings.Join(report.Locked, ",") != "city,name" {
+		t.Errorf("Locked = %v", report.Locked)
+	}
+	if strings.Join(report.NotFound, ",") != "country" {
+		t.Errorf("NotFound = %v", report.NotFound)
+	}
+}
+
+func TestFillFormFields_UnicodeValue(t *testing.T) {
+	filled, err := FillFormFieldsWithOptions(buildFillTestPDF(t), types.FormData{"name": "Иван Петров", "city": "Zürich"}, FillOptions{})
+	if err != nil {
+		t.Fatalf("Fill failed: %v", err)
+	}
+	if !strings.Contains(string(filled), "/V <FEFF0418") {
+		t.Error("Expected the Cyrillic value as a UTF-16BE hex string")
+	}
+	acroForm := parseFilledForm(t, filled)
+	if name := acroForm.FindFieldByName("name"); name == nil || name.V != "Иван Петров" {
+		t.Errorf("Unicode value not read back: %+v", name)
+	}
+	if city := acroForm.FindFieldByName("city"); city == nil || city.V != "Z\\374rich" {
+		t.Errorf("Expected a PDFDocEncoding literal for a Latin-1 value, got %+v", city)
+	}
+}
+
+func TestFillFormFieldsWithOptions_Incremental(t *testing.T) {
+	pdfBytes := buildFillTestPDF(t)
+	filled, report, err := FillFormFieldsWithReport(pdfBytes, types.FormData{"name": "Jane Doe", "city": "Oslo"}, FillOptions{Incremental: true, Lock: types.LockFilled})
+	if err != nil {
+		t.Fatalf("Incremental fill failed: %v", err)
+	}
+	if len(report.Filled) != 2 || len(report.Failed) != 0 {
+		t.Errorf("Filled = %v, Failed = %v", report.Filled, report.Failed)
+	}
+	if !bytes.HasPrefix(filled, pdfBytes) {
+		t.Fatal("Incremental fill should keep the original bytes as a prefix")
+	}
+	if revisions := parse.CountRevisions(filled); revisions != parse.CountRevisions(pdfBytes)+1 {
+		t.Errorf("Expected one more revision, got %d", revisions)
+	}
+
+	pdf, err := parse.Open(filled)
+	if err != nil {
+		t.Fatalf("Failed to parse filled PDF: %v", err)
+	}
+	acroForm, err := ParseAcroForm(filled, pdf.Encryption(), false)
+	if err != nil {
+		t.Fatalf("Failed to parse filled form: %v", err)
+	}
+	name, city := acroForm.FindFieldByName("name"), acroForm.FindFieldByName("city")
+	if name == nil || name.V != "Jane Doe" || name.Ff&FlagReadOnly == 0 {
+		t.Errorf("Field not filled and locked in the update: %+v", name)
+	}
+	if city == nil || city.V != "Oslo" {
+		t.Errorf("Field not filled in the update: %+v", city)
+	}
+}
@@ -5,143 +5,224 @@ import (
 	"bytes"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/core/write"
 	"github.com/benedoc-inc/pdfer/types"
 )
 
-// FillFormFieldsWithStreams fills form fields, handling both direct objects and object streams
-func FillFormFieldsWithStreams(pdfBytes []byte, formData types.FormData, password []byte, verbose bool) ([]byte, error) {
-	if len(pdfBytes) == 0 {
-		return nil, fmt.Errorf("PDF bytes are empty")
-	}
+// FillOptions controls how FillFormFieldsWithOptions fills a form
+type FillOptions struct {
+	Password    []byte
+	Verbose     bool
+	Lock        types.FieldLock // Fields to make read-only, for final submissions that are not flattened
+	ClearFields []string        // Fields to blank, like fields with a nil value in the form data
+	// Incremental appends the edited fields to the original file as an
+	// incremental update instead of rewriting them in place, which keeps
+	// earlier revisions and existing digital signatures intact
+	Incremental bool
+	// Appearances rebuilds the appearance streams of the widgets of the filled
+	// and cleared fields from their new values, for viewers that ignore
+	// /NeedAppearances. The streams are new objects, so the fill is appended as
+	// an incremental update as with Incremental.
+	Appearances bool
+}
 
-	// Parse PDF to get encryption info and object locations
-	pdf, err := parse.OpenWithOptions(pdfBytes, parse.ParseOptions{
-		Password: password,
-		Verbose:  verbose,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse PDF: %w", err)
-	}
+// FillReport records what a fill did to each field
+type FillReport struct {
+	Filled   map[string]interface{} `json:"filled,omitempty"` // Values written, after formatting
+	Cleared  []string               `json:"cleared,omitempty"`
+	Locked   []string               `json:"locked,omitempty"`
+	NotFound []string               `json:"not_found,omitempty"` // Names in the data that are not in the form
+	Failed   map[string]string      `json:"failed,omitempty"`    // Error by field name
+}
 
-	var encryptInfo *types.PDFEncryption
-	if pdf.Encryption() != nil {
-		encryptInfo = pdf.Encryption()
+// record adds a completed edit to the report
+func (r *FillReport) record(edit fieldEdit) {
+	if edit.field.T == "" {
+		return // Widget of a field that is reported already
 	}
-
-	// Extract AcroForm
-	acroForm, err := ParseAcroForm(pdfBytes, encryptInfo, verbose)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse AcroForm: %w", err)
+	if edit.setValue {
+		r.Filled[edit.name] = edit.value
 	}
-
-	if acroForm == nil {
-		return nil, fmt.Errorf("AcroForm is nil")
+	if edit.clear {
+		r.Cleared = append(r.Cleared, edit.name)
+	}
+	if edit.lock {
+		r.Locked = append(r.Locked, edit.name)
 	}
+}
 
-	// Get object locations from parser
-	result := make([]byte, len(pdfBytes))
-	copy(result, pdfBytes)
+// sort puts the report's name lists in order
+func (r *FillReport) sort() {
+	sort.Strings(r.Cleared)
+	sort.Strings(r.Locked)
+	sort.Strings(r.NotFound)
+}
 
-	// Track updates per stream
-	streamUpdates := make(map[int][]StreamObjectUpdate)
+// fieldEdit is a change a fill makes to a field dictionary
+type fieldEdit struct {
+	field    *Field
+	name     string
+	value    interface{}
+	setValue bool // Replace /V with value
+	clear    bool // Remove /V and the appearance streams
+	lock     bool // Set the ReadOnly field flag
+}
 
+// FillFormFieldsWithStreams fills form fields, handling both direct objects and object streams
+func FillFormFieldsWithStreams(pdfBytes []byte, formData types.FormData, password []byte, verbose bool) ([]byte, error) {
+	return FillFormFieldsWithOptions(pdfBytes, formData, FillOptions{Password: password, Verbose: verbose})
+}
+
+// FillFormFieldsWithOptions fills form fields like FillFormFieldsWithStreams and
+// can mark the filled fields, or all fields, read-only. A nil value in formData
+// (a JSON null) clears the field: its value and appearance streams are removed.
+func FillFormFieldsWithOptions(pdfBytes []byte, formData types.FormData, opts FillOptions) ([]byte, error) {
+	result, _, err := FillFormFieldsWithReport(pdfBytes, formData, opts)
+	return result, err
+}
+
+// FillFormFieldsWithReport fills form fields like FillFormFieldsWithOptions and
+// reports which fields were filled, cleared, locked, not found or failed
+func FillFormFieldsWithReport(pdfBytes []byte, formData types.FormData, opts FillOptions) ([]byte, *FillReport, error) {
+	t, err := PrepareTemplate(pdfBytes, opts.Password, opts.Verbose)
+	if err != nil {
+		return nil, nil, err
+	}
+	return t.Fill(formData, opts)
+}
+
+// Fill fills a copy of the template like FillFormFieldsWithReport. opts.Password
+// is not used: the template keeps the password it was prepared with.
+func (t *Template) Fill(formData types.FormData, opts FillOptions) ([]byte, *FillReport, error) {
+	verbose := opts.Verbose
+	pdfBytes, encryptInfo, acroForm := t.pdfBytes, t.encryptInfo, t.acroForm
+
+	report := &FillReport{Filled: make(map[string]interface{}), Failed: make(map[string]string)}
+	var edits []fieldEdit
+	edited := make(map[*Field]bool)
+	lockFilled := opts.Lock != types.LockNone
 	for fieldName, value := range formData {
-		field := acroForm.FindFieldByName(fieldName)
+		field := t.findField(fieldName)
 		if field == nil {
 			if verbose {
 				fmt.Printf("Warning: Field '%s' not found, skipping\n", fieldName)
 			}
+			report.NotFound = append(report.NotFound, fieldName)
 			continue
 		}
 
-		// Check if field is in an object stream by accessing xref
-		// We need to get the object reference from the parser's internal xref
-		// For now, try to get the object and check if it's accessible
-		objData, objErr := pdf.GetObject(field.ObjectNum)
-		if objErr != nil {
+		if value == nil {
+			edits = appendClearEdits(edits, field, fieldName, lockFilled)
+			edited[field] = true
+			continue
+		}
+		value, err := fitMaxLen(field, applyFieldFormat(field, value, verbose), verbose)
+		if err != nil {
+			return nil, nil, err
+		}
+		edits = append(edits, fieldEdit{field: field, name: fieldName, value: value, setValue: true, lock: lockFilled})
+		edited[field] = true
+	}
+	for _, fieldName := range opts.ClearFields {
+		field := t.findField(fieldName)
+		if field == nil {
 			if verbose {
-				fmt.Printf("Warning: Cannot access object %d: %v, trying direct replacement\n", field.ObjectNum, objErr)
-			}
-			// Try direct replacement
-			var fillErr error
-			result, fillErr = FillFieldValue(result, field, value, encryptInfo, verbose)
-			if fillErr != nil && verbose {
-				fmt.Printf("Warning: Failed to fill field '%s': %v\n", fieldName, fillErr)
+				fmt.Printf("Warning: Field '%s' not found, skipping\n", fieldName)
 			}
+			report.NotFound = append(report.NotFound, fieldName)
 			continue
 		}
+		if edited[field] {
+			continue
+		}
+		edits = appendClearEdits(edits, field, fieldName, lockFilled)
+		edited[field] = true
+	}
 
-		// Check if object appears to be from a stream (no "obj" header)
-		checkLen := 50
-		if len(objData) < checkLen {
-			checkLen = len(objData)
+	// Lock the named fields that were not filled
+	if opts.Lock == types.LockAll {
+		var walk func(fields []*Field)
+		walk = func(fields []*Field) {
+			for _, field := range fields {
+				if field.T != "" && !edited[field] {
+					edits = append(edits, fieldEdit{field: field, name: field.GetFullName(), lock: true})
+				}
+				walk(field.Kids)
+			}
 		}
-		isInStream := !bytes.Contains(objData[:checkLen], []byte(fmt.Sprintf("%d 0 obj", field.ObjectNum)))
+		walk(acroForm.Fields)
+	}
+
+	if opts.Incremental || opts.Appearances {
+		result, err := t.fillIncremental(edits, report, opts.Appearances, verbose)
+		if err != nil {
+			return nil, nil, err
+		}
+		report.sort()
+		return result, report, nil
+	}
+
+	// Get object locations from parser
+	result := make([]byte, len(pdfBytes))
+	copy(result, pdfBytes)
+
+	// Track updates per stream, and the edits they make for the report
+	streamUpdates := make(map[int][]StreamObjectUpdate)
+	streamEdits := make(map[int][]fieldEdit)
 
-		if isInStream {
+	for _, edit := range edits {
+		field, fieldName := edit.field, edit.name
+
+		if slot, ok := t.streamSlots[field.ObjectNum]; ok {
 			// Field is in an object stream - prepare update
-			updatedContent, err := updateFieldContent(objData, field, value)
+			updatedContent, err := applyFieldEdit(slot.objData, edit)
 			if err != nil {
 				if verbose {
 					fmt.Printf("Warning: Failed to update field content: %v\n", err)
 				}
+				report.Failed[fieldName] = err.Error()
 				continue
 			}
 
-			// Find which stream contains this object
-			streamObjNum := findStreamForObject(pdfBytes, field.ObjectNum, encryptInfo, verbose)
-			if streamObjNum > 0 {
-				// Get stream index from xref
-				startXRef := findStartXRef(pdfBytes)
-				var streamIndex int
-				if startXRef >= 0 {
-					xrefResult, err := parse.ParseXRefStreamFull(pdfBytes, int64(startXRef), false)
-					if err == nil {
-						if entry, ok := xrefResult.ObjectStreams[field.ObjectNum]; ok {
-							streamIndex = entry.IndexInStream
-						}
-					}
-				}
-
-				// Add to stream updates
-				streamUpdates[streamObjNum] = append(streamUpdates[streamObjNum], StreamObjectUpdate{
-					ObjNum:     field.ObjectNum,
-					Index:      streamIndex,
-					NewContent: updatedContent,
-				})
+			// Add to stream updates
+			streamUpdates[slot.streamObjNum] = append(streamUpdates[slot.streamObjNum], StreamObjectUpdate{
+				ObjNum:     field.ObjectNum,
+				Index:      slot.index,
+				NewContent: updatedContent,
+			})
+			streamEdits[slot.streamObjNum] = append(streamEdits[slot.streamObjNum], edit)
 
-				if verbose {
-					fmt.Printf("Prepared update for field '%s' (obj %d) in stream %d at index %d\n",
-						fieldName, field.ObjectNum, streamObjNum, streamIndex)
-				}
-			} else {
-				if verbose {
-					fmt.Printf("Warning: Could not find stream for object %d, using direct replacement\n", field.ObjectNum)
-				}
-				// Fall back to direct replacement
-				var fillErr error
-				result, fillErr = FillFieldValue(result, field, value, encryptInfo, verbose)
-				if fillErr != nil && verbose {
-					fmt.Printf("Warning: Failed to fill field '%s': %v\n", fieldName, fillErr)
-				}
+			if verbose {
+				fmt.Printf("Prepared update for field '%s' (obj %d) in stream %d at index %d\n",
+					fieldName, field.ObjectNum, slot.streamObjNum, slot.index)
 			}
 		} else {
 			// Direct object - use simple replacement
 			var fillErr error
-			result, fillErr = FillFieldValue(result, field, value, encryptInfo, verbose)
+			result, fillErr = replaceFieldEdit(result, edit, encryptInfo, verbose)
 			if fillErr != nil {
 				if verbose {
 					fmt.Printf("Warning: Failed to fill field '%s': %v\n", fieldName, fillErr)
 				}
+				report.Failed[fieldName] = fillErr.Error()
 				continue
 			}
+			report.record(edit)
 		}
 
 		if verbose {
-			fmt.Printf("Filled field '%s' with value '%v'\n", fieldName, value)
+			switch {
+			case edit.setValue:
+				fmt.Printf("Filled field '%s' with value '%v'\n", fieldName, edit.value)
+			case edit.clear:
+				fmt.Printf("Cleared field '%s'\n", fieldName)
+			default:
+				fmt.Printf("Locked field '%s'\n", fieldName)
+			}
 		}
 	}
 
@@ -157,9 +238,15 @@ func FillFormFieldsWithStreams(pdfBytes []byte, formData types.FormData, passwor
 			if verbose {
 				fmt.Printf("Warning: Failed to rebuild object stream %d: %v\n", streamObjNum, rebuildErr)
 			}
+			for _, edit := range streamEdits[streamObjNum] {
+				report.Failed[edit.name] = rebuildErr.Error()
+			}
 			// Continue with other streams
 			continue
 		}
+		for _, edit := range streamEdits[streamObjNum] {
+			report.record(edit)
+		}
 
 		if verbose {
 			fmt.Printf("Successfully rebuilt object stream %d\n", streamObjNum)
@@ -167,31 +254,230 @@ func FillFormFieldsWithStreams(pdfBytes []byte, formData types.FormData, passwor
 	}
 
 	if len(result) == 0 {
-		return nil, fmt.Errorf("result PDF is empty after filling")
+		return nil, nil, fmt.Errorf("result PDF is empty after filling")
 	}
 
-	return result, nil
+	report.sort()
+	return result, report, nil
 }
 
-// updateFieldContent updates field content with new value
-func updateFieldContent(fieldData []byte, field *Field, value interface{}) ([]byte, error) {
-	fieldStr := string(fieldData)
-	valueStr := formatFieldValue(value, field.FT)
+// fillIncremental applies edits as an incremental update: each edited field
+// dictionary, whether stored directly or in an object stream, is appended as a
+// new revision of its object and the original bytes are left as they are. With
+// appearances, the widgets of filled and cleared fields get appearance streams
+// showing their new values.
+func (t *Template) fillIncremental(edits []fieldEdit, report *FillReport, appearances, verbose bool) ([]byte, error) {
+	w, err := write.NewIncrementalWriter(t.pdfBytes, t.encryptInfo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start incremental update: %w", err)
+	}
 
-	// Replace or add /V entry
-	vPattern := regexp.MustCompile(`/V\s*(?:\([^)]*\)|/[^\s]+|\[[^\]]*\])`)
-	newV := fmt.Sprintf("/V (%s)", escapeFieldValue(valueStr))
+	// A field can be edited more than once, as a widget and a named field
+	updated := make(map[int][]byte)
+	for _, edit := range edits {
+		field := edit.field
+		fieldData, generation, err := t.currentObject(field, updated)
+		if err != nil {
+			report.Failed[edit.name] = err.Error()
+			continue
+		}
+
+		newData, err := applyFieldEdit(fieldData, edit)
+		if err != nil {
+			if verbose {
+				fmt.Printf("Warning: Failed to update field '%s': %v\n", edit.name, err)
+			}
+			report.Failed[edit.name] = err.Error()
+			continue
+		}
+		updated[field.ObjectNum] = newData
+		w.SetObject(field.ObjectNum, generation, newData)
+		report.record(edit)
+	}
+
+	if appearances {
+		g := newWidgetAppearances(t.pdfBytes, t.encryptInfo, t.acroForm, w, verbose)
+		for _, edit := range edits {
+			// Widgets of a field are rendered with the field's edit
+			if edit.field.T == "" || !edit.setValue && !edit.clear {
+				continue
+			}
+			if _, failed := report.Failed[edit.name]; failed {
+				continue
+			}
+			if err := t.renderWidgets(g, w, edit.field, edit.value, updated); err != nil {
+				if verbose {
+					fmt.Printf("Warning: Failed to render appearance of field '%s': %v\n", edit.name, err)
+				}
+				report.Failed[edit.name] = err.Error()
+			}
+		}
+	}
+
+	if verbose {
+		fmt.Printf("Appending %d field objects as an incremental update\n", len(updated))
+	}
+	return w.Bytes()
+}
+
+// currentObject returns the content of a field or widget dictionary as updated
+// so far, or as in the template, and the generation its new revision gets
+func (t *Template) currentObject(field *Field, updated map[int][]byte) ([]byte, int, error) {
+	fieldData, ok := updated[field.ObjectNum]
+	generation := field.Generation
+	if slot, inStream := t.streamSlots[field.ObjectNum]; inStream {
+		// Objects in object streams have generation 0
+		generation = 0
+		if !ok {
+			fieldData = slot.objData
+		}
+	} else if !ok {
+		obj, err := parse.GetObject(t.pdfBytes, field.ObjectNum, t.encryptInfo, false)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get field object: %v", err)
+		}
+		fieldData = objectContent(obj)
+	}
+	return fieldData, generation, nil
+}
+
+// renderWidgets sets the appearance streams of the widgets of a field to show
+// value, adding the widget dictionaries to the update
+func (t *Template) renderWidgets(g *widgetAppearances, w *write.IncrementalWriter, field *Field, value interface{}, updated map[int][]byte) error {
+	for _, widget := range fieldWidgets(field) {
+		widgetData, generation, err := t.currentObject(widget, updated)
+		if err != nil {
+			return err
+		}
+		original, _, err := t.currentObject(widget, nil)
+		if err != nil {
+			return err
+		}
+		widgetDict, err := g.render(field, widget, string(widgetData), string(original), value)
+		if err != nil {
+			return err
+		}
+		updated[widget.ObjectNum] = []byte(widgetDict)
+		w.SetObject(widget.ObjectNum, generation, []byte(widgetDict))
+	}
+	return nil
+}
+
+// RegenerateAppearances rebuilds the appearance streams of the widgets of the
+// named fields, or of all fields when names is empty, from their current values
+// and appends them as an incremental update. Viewers that ignore
+// /NeedAppearances then show the values, which is needed after a fill that only
+// set /V. Check boxes and radio buttons also get /AS set to match their value.
+func RegenerateAppearances(pdfBytes []byte, password []byte, names []string, verbose bool) ([]byte, error) {
+	t, err := PrepareTemplate(pdfBytes, password, verbose)
+	if err != nil {
+		return nil, err
+	}
+	w, err := write.NewIncrementalWriter(t.pdfBytes, t.encryptInfo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start incremental update: %w", err)
+	}
+	g := newWidgetAppearances(t.pdfBytes, t.encryptInfo, t.acroForm, w, verbose)
 
-	var newFieldStr string
-	if vPattern.MatchString(fieldStr) {
-		newFieldStr = vPattern.ReplaceAllString(fieldStr, newV)
+	var fields []*Field
+	if len(names) > 0 {
+		for _, name := range names {
+			field := t.findField(name)
+			if field == nil {
+				return nil, fmt.Errorf("field '%s' not found", name)
+			}
+			fields = append(fields, field)
+		}
 	} else {
-		dictEnd := strings.LastIndex(fieldStr, ">>")
-		if dictEnd == -1 {
-			return nil, fmt.Errorf("field dictionary not found")
+		var walk func(fields []*Field)
+		walk = func(kids []*Field) {
+			for _, field := range kids {
+				if field.T != "" {
+					fields = append(fields, field)
+				}
+				walk(field.Kids)
+			}
+		}
+		walk(t.acroForm.Fields)
+	}
+
+	updated := make(map[int][]byte)
+	for _, field := range fields {
+		value := field.V
+		for f := field; value == nil && f.Parent != nil; f = f.Parent {
+			value = f.Parent.V
+		}
+		if err := t.renderWidgets(g, w, field, value, updated); err != nil {
+			return nil, fmt.Errorf("failed to render appearance of field '%s': %w", field.GetFullName(), err)
+		}
+		if verbose {
+			fmt.Printf("Regenerated appearance of field '%s'\n", field.GetFullName())
+		}
+	}
+	return w.Bytes()
+}
+
+// objectHeaderPattern matches the "N G obj" header of an indirect object
+var objectHeaderPattern = regexp.MustCompile(`^\s*\d+\s+\d+\s+obj\s*`)
+
+// objectContent returns the content of an indirect object without its header
+// and endobj keyword
+func objectContent(obj []byte) []byte {
+	obj = objectHeaderPattern.ReplaceAll(obj, nil)
+	return bytes.TrimSpace(bytes.TrimSuffix(bytes.TrimSpace(obj), []byte("endobj")))
+}
+
+// appendClearEdits appends the edits that clear a field: its own dictionary and
+// the widgets among its kids, which hold their own appearance streams
+func appendClearEdits(edits []fieldEdit, field *Field, name string, lock bool) []fieldEdit {
+	edits = append(edits, fieldEdit{field: field, name: name, clear: true, lock: lock})
+	for _, kid := range field.Kids {
+		if kid.T == "" {
+			edits = append(edits, fieldEdit{field: kid, name: name, clear: true})
+		}
+	}
+	return edits
+}
+
+// fieldValuePattern matches the /V entry of a field dictionary
+var fieldValuePattern = regexp.MustCompile(`/V\s*(?:\([^)]*\)|<[^<>]*>|/[^\s/>]+|\[[^\]]*\])`)
+
+// applyFieldEdit applies an edit to the content of a field dictionary
+func applyFieldEdit(fieldData []byte, edit fieldEdit) ([]byte, error) {
+	fieldStr := string(fieldData)
+	dictEnd := strings.LastIndex(fieldStr, ">>")
+	if dictEnd == -1 {
+		return nil, fmt.Errorf("field dictionary not found")
+	}
+
+	if edit.setValue {
+		// Replace or add /V entry
+		valueStr := formatFieldValue(edit.value, edit.field.FT)
+		newV := "/V " + textStringOperand(valueStr)
+		if fieldValuePattern.MatchString(fieldStr) {
+			fieldStr = fieldValuePattern.ReplaceAllLiteralString(fieldStr, newV)
+		} else {
+			fieldStr = fieldStr[:dictEnd] + newV + " " + fieldStr[dictEnd:]
+		}
+	}
+
+	if edit.clear {
+		// Remove /V and /AP; buttons show their off state
+		fieldStr = removeAppearance(fieldValuePattern.ReplaceAllString(fieldStr, ""))
+		fieldStr = regexp.MustCompile(`/AS\s*/[^\s/>]+`).ReplaceAllString(fieldStr, "/AS /Off")
+	}
+
+	if edit.lock {
+		// Replace or add /Ff entry with the ReadOnly flag set
+		ffPattern := regexp.MustCompile(`/Ff\s+\d+`)
+		newFf := fmt.Sprintf("/Ff %d", edit.field.Ff|FlagReadOnly)
+		if ffPattern.MatchString(fieldStr) {
+			fieldStr = ffPattern.ReplaceAllString(fieldStr, newFf)
+		} else {
+			dictEnd = strings.LastIndex(fieldStr, ">>")
+			fieldStr = fieldStr[:dictEnd] + newFf + " " + fieldStr[dictEnd:]
 		}
-		newFieldStr = fieldStr[:dictEnd] + newV + " " + fieldStr[dictEnd:]
 	}
 
-	return []byte(newFieldStr), nil
+	return []byte(fieldStr), nil
 }
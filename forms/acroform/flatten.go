@@ -13,6 +13,10 @@ import (
 
 // FlattenForm converts form fields to static content (removes interactivity)
 // This creates a new PDF with form fields rendered as regular text/graphics
+//
+// Deprecated: FlattenForm only unlinks the AcroForm from the catalog, leaving
+// the widgets in place. Use forms.Flatten, which draws the widget appearances
+// into the pages and removes the widgets and fields.
 func FlattenForm(pdfBytes []byte, password []byte, verbose bool) ([]byte, error) {
 	// Parse PDF
 	pdf, err := parse.OpenWithOptions(pdfBytes, parse.ParseOptions{
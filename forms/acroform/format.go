@@ -0,0 +1,400 @@
+package acroform
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormatKind identifies the kind of a field's format script
+type FormatKind string
+
+const (
+	FormatDate   FormatKind = "date"   // AFDate_FormatEx, AFDate_Format, AFTime_FormatEx, AFTime_Format
+	FormatNumber FormatKind = "number" // AFNumber_Format
+)
+
+// FieldFormat is the display format of a text field, as set by the Acrobat
+// format script (AFDate_FormatEx, AFNumber_Format, ...) of its /AA /F action.
+// Viewers run the script to display the value; filling formats values the same
+// way so /V and the appearance agree with what the viewer shows.
+type FieldFormat struct {
+	Kind   FormatKind
+	Script string // Script the format was recognized from
+
+	// Date and time formats
+	DateFormat string // Acrobat date format, e.g. "mm/dd/yyyy" or "h:MM tt"
+
+	// Number formats
+	Decimals        int    // Digits after the decimal separator
+	SepStyle        int    // 0 "1,234.56", 1 "1234.56", 2 "1.234,56", 3 "1234,56", 4 "1'234.56"
+	NegStyle        int    // 0 minus sign, 1 red, 2 parentheses, 3 red parentheses
+	Currency        string // Currency symbol
+	CurrencyPrepend bool   // Currency symbol before the number
+}
+
+// Predefined formats of AFDate_Format and AFTime_Format, by index
+var (
+	afDateFormats = []string{"m/d", "m/d/yy", "mm/dd/yy", "mm/yy", "d-mmm", "d-mmm-yy", "dd-mmm-yy",
+		"yy-mm-dd", "mmm-yy", "mmmm-yy", "mmm d, yyyy", "mmmm d, yyyy", "m/d/yy h:MM tt", "m/d/yy HH:MM"}
+	afTimeFormats = []string{"HH:MM", "h:MM tt", "HH:MM:ss", "h:MM:ss tt"}
+)
+
+// Separators for AFNumber_Format sepStyle: group separator and decimal separator
+var afNumberSeparators = [][2]string{{",", "."}, {"", "."}, {".", ","}, {"", ","}, {"'", "."}}
+
+var afFormatCallPattern = regexp.MustCompile(`(AFDate_FormatEx|AFDate_Format|AFTime_FormatEx|AFTime_Format|AFNumber_Format)\s*\(([^)]*)\)`)
+
+// ParseFormatScript recognizes the Acrobat format functions in a format script.
+// It returns nil for scripts that do something else.
+func ParseFormatScript(script string) *FieldFormat {
+	m := afFormatCallPattern.FindStringSubmatch(script)
+	if m == nil {
+		return nil
+	}
+	args := splitScriptArgs(m[2])
+	format := &FieldFormat{Script: script}
+
+	switch m[1] {
+	case "AFDate_FormatEx", "AFTime_FormatEx":
+		if len(args) < 1 {
+			return nil
+		}
+		format.Kind = FormatDate
+		format.DateFormat = args[0]
+	case "AFDate_Format", "AFTime_Format":
+		table := afDateFormats
+		if m[1] == "AFTime_Format" {
+			table = afTimeFormats
+		}
+		if len(args) < 1 {
+			return nil
+		}
+		i, err := strconv.Atoi(args[0])
+		if err != nil || i < 0 || i >= len(table) {
+			return nil
+		}
+		format.Kind = FormatDate
+		format.DateFormat = table[i]
+	case "AFNumber_Format":
+		// AFNumber_Format(nDec, sepStyle, negStyle, currStyle, strCurrency, bCurrencyPrepend)
+		if len(args) < 3 {
+			return nil
+		}
+		format.Kind = FormatNumber
+		format.Decimals, _ = strconv.Atoi(args[0])
+		format.SepStyle, _ = strconv.Atoi(args[1])
+		format.NegStyle, _ = strconv.Atoi(args[2])
+		if format.SepStyle < 0 || format.SepStyle >= len(afNumberSeparators) {
+			format.SepStyle = 0
+		}
+		if len(args) >= 5 {
+			format.Currency = args[4]
+		}
+		if len(args) >= 6 {
+			format.CurrencyPrepend = args[5] == "true"
+		}
+	}
+	return format
+}
+
+// splitScriptArgs splits JavaScript call arguments, unquoting string literals
+func splitScriptArgs(s string) []string {
+	var args []string
+	for _, arg := range strings.Split(s, ",") {
+		arg = strings.TrimSpace(arg)
+		if len(arg) >= 2 && (arg[0] == '"' || arg[0] == '\'') && arg[len(arg)-1] == arg[0] {
+			arg = arg[1 : len(arg)-1]
+		}
+		args = append(args, arg)
+	}
+	return args
+}
+
+// Layouts accepted for date values, tried in order
+var isoDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"15:04:05",
+	"15:04",
+}
+
+// Format formats a value for display. Dates are accepted as time.Time or as ISO
+// 8601 strings, numbers as Go numbers or numeric strings; values already in the
+// display format are accepted too, so formatting is idempotent.
+func (f *FieldFormat) Format(value interface{}) (string, error) {
+	switch f.Kind {
+	case FormatDate:
+		t, err := f.parseDate(value)
+		if err != nil {
+			return "", err
+		}
+		return formatAFDate(t, f.DateFormat), nil
+	case FormatNumber:
+		n, err := f.parseNumber(value)
+		if err != nil {
+			return "", err
+		}
+		return f.formatNumber(n), nil
+	}
+	return "", fmt.Errorf("unsupported format kind: %s", f.Kind)
+}
+
+// negativeInRed reports whether the value is a negative number shown in red
+func (f *FieldFormat) negativeInRed(value interface{}) bool {
+	if f.Kind != FormatNumber || f.NegStyle != 1 && f.NegStyle != 3 {
+		return false
+	}
+	n, err := f.parseNumber(value)
+	return err == nil && n < 0
+}
+
+func (f *FieldFormat) parseDate(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		s := strings.TrimSpace(v)
+		for _, layout := range isoDateLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, nil
+			}
+		}
+		if layout, ok := afDateLayout(f.DateFormat); ok {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("cannot parse %q as a date for format %q", v, f.DateFormat)
+	}
+	return time.Time{}, fmt.Errorf("unsupported date value type %T", value)
+}
+
+func (f *FieldFormat) parseNumber(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case string:
+		s := strings.TrimSpace(v)
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return n, nil
+		}
+		// A value in the display format
+		negative := strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") || strings.Contains(s, "-")
+		s = strings.Trim(s, "()")
+		s = strings.ReplaceAll(s, "-", "")
+		if f.Currency != "" {
+			s = strings.ReplaceAll(s, f.Currency, "")
+		}
+		seps := afNumberSeparators[f.SepStyle]
+		if seps[0] != "" {
+			s = strings.ReplaceAll(s, seps[0], "")
+		}
+		s = strings.ReplaceAll(strings.TrimSpace(s), seps[1], ".")
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("cannot parse %q as a number", v)
+		}
+		if negative {
+			n = -n
+		}
+		return n, nil
+	}
+	return 0, fmt.Errorf("unsupported number value type %T", value)
+}
+
+// formatNumber formats n the way AFNumber_Format displays it
+func (f *FieldFormat) formatNumber(n float64) string {
+	decimals := f.Decimals
+	if decimals < 0 {
+		decimals = 0
+	}
+	digits := strconv.FormatFloat(math.Abs(n), 'f', decimals, 64)
+	intPart, fracPart, _ := strings.Cut(digits, ".")
+	seps := afNumberSeparators[f.SepStyle]
+
+	var b strings.Builder
+	for i, d := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteString(seps[0])
+		}
+		b.WriteRune(d)
+	}
+	if fracPart != "" {
+		b.WriteString(seps[1])
+		b.WriteString(fracPart)
+	}
+
+	text := b.String()
+	if f.Currency != "" {
+		if f.CurrencyPrepend {
+			text = f.Currency + text
+		} else {
+			text += f.Currency
+		}
+	}
+	// Rounding may turn a small negative number into zero
+	if n < 0 && strings.Trim(intPart+fracPart, "0") != "" {
+		switch f.NegStyle {
+		case 0:
+			text = "-" + text
+		case 2, 3:
+			text = "(" + text + ")"
+		}
+	}
+	return text
+}
+
+// afDateTokens are the Acrobat date format tokens, longest first
+var afDateTokens = []string{"yyyy", "yy", "mmmm", "mmm", "mm", "m", "dddd", "ddd", "dd", "d",
+	"HH", "H", "hh", "h", "MM", "M", "ss", "s", "tt", "t"}
+
+// tokenizeAFDate splits an Acrobat date format into tokens and literal text
+func tokenizeAFDate(format string) []string {
+	var tokens []string
+	for format != "" {
+		matched := false
+		for _, token := range afDateTokens {
+			if strings.HasPrefix(format, token) {
+				tokens = append(tokens, token)
+				format = format[len(token):]
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			tokens = append(tokens, format[:1])
+			format = format[1:]
+		}
+	}
+	return tokens
+}
+
+// formatAFDate formats t with an Acrobat date format: m is the month and M the
+// minute, H a 24-hour and h a 12-hour hour, tt "am"/"pm"
+func formatAFDate(t time.Time, format string) string {
+	var b strings.Builder
+	for _, token := range tokenizeAFDate(format) {
+		switch token {
+		case "yyyy":
+			b.WriteString(fmt.Sprintf("%04d", t.Year()))
+		case "yy":
+			b.WriteString(fmt.Sprintf("%02d", t.Year()%100))
+		case "mmmm":
+			b.WriteString(t.Month().String())
+		case "mmm":
+			b.WriteString(t.Month().String()[:3])
+		case "mm":
+			b.WriteString(fmt.Sprintf("%02d", int(t.Month())))
+		case "m":
+			b.WriteString(strconv.Itoa(int(t.Month())))
+		case "dddd":
+			b.WriteString(t.Weekday().String())
+		case "ddd":
+			b.WriteString(t.Weekday().String()[:3])
+		case "dd":
+			b.WriteString(fmt.Sprintf("%02d", t.Day()))
+		case "d":
+			b.WriteString(strconv.Itoa(t.Day()))
+		case "HH":
+			b.WriteString(fmt.Sprintf("%02d", t.Hour()))
+		case "H":
+			b.WriteString(strconv.Itoa(t.Hour()))
+		case "hh":
+			b.WriteString(fmt.Sprintf("%02d", hour12(t)))
+		case "h":
+			b.WriteString(strconv.Itoa(hour12(t)))
+		case "MM":
+			b.WriteString(fmt.Sprintf("%02d", t.Minute()))
+		case "M":
+			b.WriteString(strconv.Itoa(t.Minute()))
+		case "ss":
+			b.WriteString(fmt.Sprintf("%02d", t.Second()))
+		case "s":
+			b.WriteString(strconv.Itoa(t.Second()))
+		case "tt":
+			if t.Hour() < 12 {
+				b.WriteString("am")
+			} else {
+				b.WriteString("pm")
+			}
+		case "t":
+			if t.Hour() < 12 {
+				b.WriteString("a")
+			} else {
+				b.WriteString("p")
+			}
+		default:
+			b.WriteString(token)
+		}
+	}
+	return b.String()
+}
+
+func hour12(t time.Time) int {
+	if h := t.Hour() % 12; h != 0 {
+		return h
+	}
+	return 12
+}
+
+// afDateLayout converts an Acrobat date format to a Go time layout for parsing
+// displayed values. Formats with tokens Go cannot parse are reported as not ok.
+func afDateLayout(format string) (string, bool) {
+	layouts := map[string]string{
+		"yyyy": "2006", "yy": "06", "mmmm": "January", "mmm": "Jan", "mm": "01", "m": "1",
+		"dddd": "Monday", "ddd": "Mon", "dd": "02", "d": "2", "HH": "15", "H": "15", "hh": "03", "h": "3",
+		"MM": "04", "M": "4", "ss": "05", "s": "5", "tt": "pm",
+	}
+	var b strings.Builder
+	for _, token := range tokenizeAFDate(format) {
+		if layout, ok := layouts[token]; ok {
+			b.WriteString(layout)
+		} else if token == "t" || strings.ContainsAny(token, "0123456789") {
+			return "", false
+		} else {
+			b.WriteString(token)
+		}
+	}
+	return b.String(), true
+}
+
+// fieldFormat returns the format of a field, inherited from its parents
+func fieldFormat(field *Field) *FieldFormat {
+	for f := field; f != nil; f = f.Parent {
+		if f.Format != nil {
+			return f.Format
+		}
+	}
+	return nil
+}
+
+// applyFieldFormat formats a fill value with the field's format script. Values
+// that cannot be formatted are kept as they are.
+func applyFieldFormat(field *Field, value interface{}, verbose bool) interface{} {
+	format := fieldFormat(field)
+	if format == nil || field.FT != "Tx" && field.FT != "" {
+		return value
+	}
+	formatted, err := format.Format(value)
+	if err != nil {
+		if verbose {
+			fmt.Printf("Warning: Value for field '%s' not formatted: %v\n", field.GetFullName(), err)
+		}
+		return value
+	}
+	return formatted
+}
@@ -0,0 +1,135 @@
+package acroform
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+)
+
+func TestParseFormatScript(t *testing.T) {
+	tests := []struct {
+		script string
+		want   FieldFormat
+	}{
+		{`AFDate_FormatEx("mm/dd/yyyy");`, FieldFormat{Kind: FormatDate, DateFormat: "mm/dd/yyyy"}},
+		{`AFDate_Format(10);`, FieldFormat{Kind: FormatDate, DateFormat: "mmm d, yyyy"}},
+		{`AFTime_Format(1);`, FieldFormat{Kind: FormatDate, DateFormat: "h:MM tt"}},
+		{`AFNumber_Format(2, 0, 2, 0, "$", true);`, FieldFormat{Kind: FormatNumber, Decimals: 2, NegStyle: 2, Currency: "$", CurrencyPrepend: true}},
+		{`AFNumber_Format(1, 2, 0, 0, " €", false);`, FieldFormat{Kind: FormatNumber, Decimals: 1, SepStyle: 2, Currency: " €"}},
+	}
+	for _, tt := range tests {
+		got := ParseFormatScript(tt.script)
+		if got == nil {
+			t.Errorf("ParseFormatScript(%q) = nil", tt.script)
+			continue
+		}
+		tt.want.Script = tt.script
+		if *got != tt.want {
+			t.Errorf("ParseFormatScript(%q) = %+v, want %+v", tt.script, *got, tt.want)
+		}
+	}
+
+	for _, script := range []string{`event.value = "x";`, `AFDate_Format(99);`} {
+		if got := ParseFormatScript(script); got != nil {
+			t.Errorf("ParseFormatScript(%q) = %+v, want nil", script, got)
+		}
+	}
+}
+
+func TestFieldFormat_Date(t *testing.T) {
+	tests := []struct {
+		format string
+		value  interface{}
+		want   string
+	}{
+		{"mm/dd/yyyy", "2024-03-05", "03/05/2024"},
+		{"mm/dd/yyyy", "03/05/2024", "03/05/2024"}, // already formatted
+		{"d-mmm-yy", "2024-03-05T14:30:00Z", "5-Mar-24"},
+		{"dddd, mmmm d, yyyy", time.Date(2024, 12, 25, 0, 0, 0, 0, time.UTC), "Wednesday, December 25, 2024"},
+		{"h:MM tt", "2024-03-05 00:07", "12:07 am"},
+		{"HH:MM:ss", "17:04:09", "17:04:09"},
+		{"m/d/yy h:MM tt", "2024-03-05T13:30", "3/5/24 1:30 pm"},
+	}
+	for _, tt := range tests {
+		f := &FieldFormat{Kind: FormatDate, DateFormat: tt.format}
+		got, err := f.Format(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("Format(%q, %v) = %q, %v; want %q", tt.format, tt.value, got, err, tt.want)
+		}
+	}
+
+	f := &FieldFormat{Kind: FormatDate, DateFormat: "mm/dd/yyyy"}
+	if _, err := f.Format("next tuesday"); err == nil {
+		t.Error("Expected error for an unparseable date")
+	}
+}
+
+func TestFieldFormat_Number(t *testing.T) {
+	tests := []struct {
+		format FieldFormat
+		value  interface{}
+		want   string
+	}{
+		{FieldFormat{Decimals: 2}, 1234567.891, "1,234,567.89"},
+		{FieldFormat{Decimals: 2, SepStyle: 1}, "1234.5", "1234.50"},
+		{FieldFormat{Decimals: 2, SepStyle: 2}, 1234.5, "1.234,50"},
+		{FieldFormat{Decimals: 0, SepStyle: 4}, 1234567, "1'234'567"},
+		{FieldFormat{Decimals: 2, Currency: "$", CurrencyPrepend: true}, -1234.5, "-$1,234.50"},
+		{FieldFormat{Decimals: 2, NegStyle: 2, Currency: "$", CurrencyPrepend: true}, -5, "($5.00)"},
+		{FieldFormat{Decimals: 2, NegStyle: 1}, -5, "5.00"},
+		{FieldFormat{Decimals: 2, SepStyle: 2, Currency: " €"}, "1.234,50 €", "1.234,50 €"}, // already formatted
+		{FieldFormat{Decimals: 1}, -0.01, "0.0"},
+	}
+	for _, tt := range tests {
+		tt.format.Kind = FormatNumber
+		got, err := tt.format.Format(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("Format(%+v, %v) = %q, %v; want %q", tt.format, tt.value, got, err, tt.want)
+		}
+	}
+
+	red := &FieldFormat{Kind: FormatNumber, Decimals: 2, NegStyle: 3}
+	if !red.negativeInRed("-3") || red.negativeInRed("3") {
+		t.Error("negativeInRed mismatch")
+	}
+}
+
+func TestParseAdditionalActions(t *testing.T) {
+	dict := `<< /FT /Tx /T (date) /AA << /K << /S /JavaScript /JS (AFDate_KeystrokeEx\("mm/dd/yyyy"\);) >> ` +
+		`/F << /S /JavaScript /JS (AFDate_FormatEx\("mm/dd/yyyy"\);) >> >> /Rect [0 0 100 20] >>`
+	aa := parseAdditionalActions(objectSource{}, dict)
+	if aa["F"] != `AFDate_FormatEx("mm/dd/yyyy");` || aa["K"] != `AFDate_KeystrokeEx("mm/dd/yyyy");` {
+		t.Errorf("Unexpected actions %v", aa)
+	}
+	if parseAdditionalActions(objectSource{}, "<< /FT /Tx >>") != nil {
+		t.Error("Expected no actions without /AA")
+	}
+}
+
+func TestCreateFieldAppearance_Format(t *testing.T) {
+	w := write.NewPDFWriter()
+	ab := NewAppearanceBuilder(w)
+
+	parent := &Field{T: "amount", Format: ParseFormatScript(`AFNumber_Format(2, 0, 1, 0, "", false);`)}
+	field := &Field{T: "0", FT: "Tx", Parent: parent, Rect: []float64{0, 0, 100, 20}, DA: "/Helv 10 Tf 0 g"}
+	num, err := ab.CreateFieldAppearance(field, "-1234.5")
+	if err != nil {
+		t.Fatalf("CreateFieldAppearance failed: %v", err)
+	}
+	content := appearanceContent(t, w, num)
+	if !strings.Contains(content, "(1,234.50) Tj") {
+		t.Errorf("Formatted value not shown:\n%s", content)
+	}
+	if !strings.Contains(content, "1 0 0 rg") {
+		t.Errorf("Negative value not shown in red:\n%s", content)
+	}
+
+	if got := applyFieldFormat(field, "2.5", false); got != "2.50" {
+		t.Errorf("applyFieldFormat = %v, want 2.50", got)
+	}
+	if got := applyFieldFormat(field, "n/a", false); got != "n/a" {
+		t.Errorf("Unformattable value changed: %v", got)
+	}
+}
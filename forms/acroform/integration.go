@@ -6,6 +6,7 @@ import (
 
 	"github.com/benedoc-inc/pdfer/types"
 	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/resources/font"
 )
 
 // FormBuilder integrates AcroForm creation with SimplePDFBuilder
@@ -25,6 +26,12 @@ func NewFormBuilder(builder *write.SimplePDFBuilder) *FormBuilder {
 	}
 }
 
+// SetFallbackFont sets a Unicode font for text field values in scripts the
+// default Helvetica cannot show; see FieldBuilder.SetFallbackFont
+func (fb *FormBuilder) SetFallbackFont(f *font.Font) {
+	fb.fieldBuilder.SetFallbackFont(f)
+}
+
 // AddTextField adds a text field to the form
 func (fb *FormBuilder) AddTextField(name string, rect []float64, page int) *FieldDef {
 	return fb.fieldBuilder.AddTextField(name, rect, page)
@@ -50,24 +57,15 @@ func (fb *FormBuilder) AddButton(name string, rect []float64, page int) *FieldDe
 	return fb.fieldBuilder.AddButton(name, rect, page)
 }
 
-// BuildForm builds the AcroForm and integrates it with the PDF
+// BuildForm makes the fields the AcroForm of the builder's document and returns
+// the AcroForm object number. The fields are written, and their widgets added to
+// their pages, when the builder writes the PDF, so pages may be finalized before
+// or after BuildForm.
 func (fb *FormBuilder) BuildForm() (int, error) {
-	acroFormNum, err := fb.fieldBuilder.Build()
-	if err != nil {
-		return 0, fmt.Errorf("failed to build AcroForm: %w", err)
+	if len(fb.fieldBuilder.fields) == 0 {
+		return 0, fmt.Errorf("failed to build AcroForm: no fields to build")
 	}
-
-	// Get pages object number
-	pagesObjNum := fb.builder.PagesObjNum()
-
-	// Update catalog to include AcroForm
-	w := fb.builder.Writer()
-	catalogDict := fmt.Sprintf("<</Type/Catalog/Pages %d 0 R/AcroForm %d 0 R>>",
-		pagesObjNum, acroFormNum)
-	catalogNum := w.AddObject([]byte(catalogDict))
-	w.SetRoot(catalogNum)
-
-	return acroFormNum, nil
+	return fb.builder.SetAcroForm(fb.fieldBuilder), nil
 }
 
 // FillFormFromSchema fills a form from FormSchema and FormData
@@ -0,0 +1,41 @@
+package acroform_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/manipulate"
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/forms/acroform"
+)
+
+func TestParseAcroForm_CatalogInObjectStream(t *testing.T) {
+	builder := write.NewSimplePDFBuilder()
+	page := builder.AddPage(write.PageSizeLetter)
+	formBuilder := acroform.NewFormBuilder(builder)
+	formBuilder.AddTextField("name", []float64{72, 700, 300, 720}, 0)
+	if _, err := formBuilder.BuildForm(); err != nil {
+		t.Fatalf("Failed to build form: %v", err)
+	}
+	builder.FinalizePage(page)
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to generate PDF: %v", err)
+	}
+
+	packed, err := manipulate.Rewrite(pdfBytes, manipulate.RewriteOptions{ObjectStreams: true})
+	if err != nil {
+		t.Fatalf("Rewrite failed: %v", err)
+	}
+	if bytes.Contains(packed, []byte("/AcroForm")) {
+		t.Skip("Rewrite left the catalog outside the object stream")
+	}
+
+	form, err := acroform.ParseAcroForm(packed, nil, false)
+	if err != nil {
+		t.Fatalf("ParseAcroForm failed: %v", err)
+	}
+	if form.FindFieldByName("name") == nil {
+		t.Errorf("Field \"name\" not found in %d fields", len(form.Fields))
+	}
+}
@@ -11,12 +11,19 @@ import (
 	"github.com/benedoc-inc/pdfer/types"
 )
 
+var (
+	acroFormRefPattern    = regexp.MustCompile(`/AcroForm\s+(\d+)\s+(\d+)\s+R`)
+	acroFormInlinePattern = regexp.MustCompile(`/AcroForm\s*<<`)
+)
+
 // AcroForm represents an AcroForm dictionary structure
 type AcroForm struct {
 	Fields          []*Field
 	NeedAppearances bool
-	SignatureFields []int // Object numbers of signature fields
-	XFA             bool  // True if XFA is present (hybrid form)
+	SignatureFields []int  // Object numbers of signature fields
+	XFA             bool   // True if XFA is present (hybrid form)
+	DA              string // Form-wide default appearance string
+	DR              string // Default resources dictionary, read from its object when indirect
 }
 
 // Field represents a single AcroForm field
@@ -41,6 +48,7 @@ type Field struct {
 	I          []int                  // Selected indices (for choice fields)
 	Rect       []float64              // Field rectangle [llx lly urx ury]
 	Page       int                    // Page number (0-indexed)
+	Format     *FieldFormat           // Display format from the /AA /F script, if recognized
 }
 
 // ParseAcroForm extracts AcroForm structure from a PDF
@@ -60,17 +68,62 @@ func ParseAcroForm(pdfBytes []byte, encryptInfo *types.PDFEncryption, verbose bo
 	return parseAcroFormFromBytes(pdfBytes, encryptInfo, verbose)
 }
 
+// objectSource reads the objects of a form's document, from its bytes or, when
+// pdf is set, from a PDF opened with parse.OpenReader
+type objectSource struct {
+	pdfBytes    []byte
+	pdf         *parse.PDF
+	encryptInfo *types.PDFEncryption
+	verbose     bool
+}
+
+// getObject returns the content of an object, decrypted
+func (src objectSource) getObject(objNum int) ([]byte, error) {
+	if src.pdf != nil {
+		return src.pdf.GetObject(objNum)
+	}
+	return parse.GetObject(src.pdfBytes, objNum, src.encryptInfo, src.verbose)
+}
+
+// parseAcroFormFromPDF finds and parses AcroForm through the catalog of a PDF
+// opened with parse.OpenReader
+func parseAcroFormFromPDF(pdf *parse.PDF, verbose bool) (*AcroForm, error) {
+	src := objectSource{pdf: pdf, verbose: verbose}
+	var rootNum int
+	if _, err := fmt.Sscanf(pdf.Trailer().RootRef, "%d", &rootNum); err != nil {
+		return nil, types.NewPDFError(types.ErrCodeMalformedPDF, "document catalog not found")
+	}
+	catalog, err := src.getObject(rootNum)
+	if err != nil {
+		return nil, types.WrapErrorf(types.ErrCodeObjectNotFound, err, "failed to get catalog object %d", rootNum)
+	}
+
+	acroFormMatch := acroFormRefPattern.FindSubmatch(catalog)
+	if acroFormMatch == nil {
+		if acroFormInlinePattern.Match(catalog) {
+			return nil, types.NewPDFError(types.ErrCodeUnsupportedPDF, "inline AcroForm not yet supported")
+		}
+		return nil, types.NewPDFError(types.ErrCodeNoForms, "AcroForm not found in PDF")
+	}
+	acroFormObjNum, _ := strconv.Atoi(string(acroFormMatch[1]))
+	return parseAcroFormObject(src, acroFormObjNum)
+}
+
 // parseAcroFormFromBytes finds and parses AcroForm by searching PDF bytes
 func parseAcroFormFromBytes(pdfBytes []byte, encryptInfo *types.PDFEncryption, verbose bool) (*AcroForm, error) {
 	pdfStr := string(pdfBytes)
 
 	// Find AcroForm reference
-	acroFormPattern := regexp.MustCompile(`/AcroForm\s+(\d+)\s+(\d+)\s+R`)
-	acroFormMatch := acroFormPattern.FindStringSubmatch(pdfStr)
+	acroFormMatch := acroFormRefPattern.FindStringSubmatch(pdfStr)
+	if acroFormMatch == nil {
+		// A catalog stored in an object stream is compressed
+		if catalog, err := parse.GetCatalog(pdfBytes, encryptInfo, verbose); err == nil {
+			acroFormMatch = acroFormRefPattern.FindStringSubmatch(string(catalog))
+		}
+	}
 
 	if acroFormMatch == nil {
 		// Try inline AcroForm
-		acroFormInlinePattern := regexp.MustCompile(`/AcroForm\s*<<`)
 		if acroFormInlinePattern.FindStringIndex(pdfStr) == nil {
 			return nil, types.NewPDFError(types.ErrCodeNoForms, "AcroForm not found in PDF")
 		}
@@ -82,9 +135,13 @@ func parseAcroFormFromBytes(pdfBytes []byte, encryptInfo *types.PDFEncryption, v
 	if err != nil {
 		return nil, types.WrapError(types.ErrCodeInvalidObject, "invalid AcroForm object number", err)
 	}
+	return parseAcroFormObject(objectSource{pdfBytes: pdfBytes, encryptInfo: encryptInfo, verbose: verbose}, acroFormObjNum)
+}
 
+// parseAcroFormObject parses the AcroForm dictionary of object acroFormObjNum
+func parseAcroFormObject(src objectSource, acroFormObjNum int) (*AcroForm, error) {
 	// Get AcroForm object
-	acroFormData, err := parse.GetObject(pdfBytes, acroFormObjNum, encryptInfo, verbose)
+	acroFormData, err := src.getObject(acroFormObjNum)
 	if err != nil {
 		return nil, types.WrapErrorf(types.ErrCodeObjectNotFound, err, "failed to get AcroForm object %d", acroFormObjNum)
 	}
@@ -94,7 +151,7 @@ func parseAcroFormFromBytes(pdfBytes []byte, encryptInfo *types.PDFEncryption, v
 	}
 
 	// Parse AcroForm dictionary
-	if err := parseAcroFormDict(acroFormData, acroForm, pdfBytes, encryptInfo, verbose); err != nil {
+	if err := parseAcroFormDict(acroFormData, acroForm, src); err != nil {
 		return nil, types.WrapError(types.ErrCodeInvalidForm, "failed to parse AcroForm dictionary", err)
 	}
 
@@ -102,8 +159,9 @@ func parseAcroFormFromBytes(pdfBytes []byte, encryptInfo *types.PDFEncryption, v
 }
 
 // parseAcroFormDict parses the AcroForm dictionary
-func parseAcroFormDict(data []byte, acroForm *AcroForm, pdfBytes []byte, encryptInfo *types.PDFEncryption, verbose bool) error {
+func parseAcroFormDict(data []byte, acroForm *AcroForm, src objectSource) error {
 	dataStr := string(data)
+	verbose := src.verbose
 
 	// Check for XFA (hybrid form)
 	if strings.Contains(dataStr, "/XFA") {
@@ -113,6 +171,25 @@ func parseAcroFormDict(data []byte, acroForm *AcroForm, pdfBytes []byte, encrypt
 		}
 	}
 
+	// Default appearance for fields without their own /DA
+	if daMatch := regexp.MustCompile(`/DA\s*\(([^)]*)\)`).FindStringSubmatch(dataStr); daMatch != nil {
+		acroForm.DA = daMatch[1]
+	}
+
+	// Default resources, the fonts /DA strings name
+	if m := regexp.MustCompile(`/DR\s*(?:<<|(\d+)\s+\d+\s+R)`).FindStringSubmatchIndex(dataStr); m != nil {
+		if m[2] >= 0 {
+			objNum, _ := strconv.Atoi(dataStr[m[2]:m[3]])
+			if drData, err := src.getObject(objNum); err == nil {
+				acroForm.DR = string(objectContent(drData))
+			} else if verbose {
+				fmt.Printf("Warning: Failed to get default resources %d: %v\n", objNum, err)
+			}
+		} else {
+			acroForm.DR = balancedDict(dataStr[m[1]-2:])
+		}
+	}
+
 	// Find Fields array
 	fieldsPattern := regexp.MustCompile(`/Fields\s*\[([^\]]*)\]`)
 	fieldsMatch := fieldsPattern.FindStringSubmatch(dataStr)
@@ -133,7 +210,7 @@ func parseAcroFormDict(data []byte, acroForm *AcroForm, pdfBytes []byte, encrypt
 		objNum, _ := strconv.Atoi(ref[1])
 		genNum, _ := strconv.Atoi(ref[2])
 
-		field, err := parseField(pdfBytes, objNum, genNum, encryptInfo, verbose)
+		field, err := parseField(src, objNum, genNum)
 		if err != nil {
 			if verbose {
 				fmt.Printf("Warning: Failed to parse field %d: %v\n", objNum, err)
@@ -148,11 +225,15 @@ func parseAcroFormDict(data []byte, acroForm *AcroForm, pdfBytes []byte, encrypt
 }
 
 // parseField parses a single field dictionary
-func parseField(pdfBytes []byte, objNum, genNum int, encryptInfo *types.PDFEncryption, verbose bool) (*Field, error) {
-	fieldData, err := parse.GetObject(pdfBytes, objNum, encryptInfo, verbose)
+func parseField(src objectSource, objNum, genNum int) (*Field, error) {
+	verbose := src.verbose
+	fieldData, err := src.getObject(objNum)
 	if err != nil {
 		return nil, types.WrapError(types.ErrCodeFieldNotFound, "failed to get field object", err)
 	}
+	if parse.IsNullObject(fieldData) {
+		return nil, types.NewPDFErrorf(types.ErrCodeFieldNotFound, "field object %d is free", objNum)
+	}
 
 	field := &Field{
 		ObjectNum:  objNum,
@@ -189,6 +270,8 @@ func parseField(pdfBytes []byte, objNum, genNum int, encryptInfo *types.PDFEncry
 		field.V = vMatch[1]
 	} else if vMatch := regexp.MustCompile(`/V\s*/(\w+)`).FindStringSubmatch(dataStr); vMatch != nil {
 		field.V = vMatch[1]
+	} else if vMatch := regexp.MustCompile(`/V\s*<([0-9A-Fa-f\s]*)>`).FindStringSubmatch(dataStr); vMatch != nil {
+		field.V = decodeHexTextString(vMatch[1])
 	} else if vMatch := regexp.MustCompile(`/V\s*\[([^\]]*)\]`).FindStringSubmatch(dataStr); vMatch != nil {
 		// Array value (for choice fields)
 		field.V = parseArray(vMatch[1])
@@ -199,6 +282,22 @@ func parseField(pdfBytes []byte, objNum, genNum int, encryptInfo *types.PDFEncry
 		field.DV = dvMatch[1]
 	}
 
+	// Extract default appearance (DA) and quadding (Q)
+	if daMatch := regexp.MustCompile(`/DA\s*\(([^)]*)\)`).FindStringSubmatch(dataStr); daMatch != nil {
+		field.DA = daMatch[1]
+	}
+	if qMatch := regexp.MustCompile(`/Q\s+(\d+)`).FindStringSubmatch(dataStr); qMatch != nil {
+		field.Q, _ = strconv.Atoi(qMatch[1])
+	}
+
+	// Extract JavaScript of additional actions (AA) and the format it sets
+	if aa := parseAdditionalActions(src, dataStr); len(aa) > 0 {
+		field.AA = aa
+		if script, ok := aa["F"].(string); ok {
+			field.Format = ParseFormatScript(script)
+		}
+	}
+
 	// Extract maximum length (MaxLen)
 	if maxLenMatch := regexp.MustCompile(`/MaxLen\s+(\d+)`).FindStringSubmatch(dataStr); maxLenMatch != nil {
 		field.MaxLen, _ = strconv.Atoi(maxLenMatch[1])
@@ -224,7 +323,7 @@ func parseField(pdfBytes []byte, objNum, genNum int, encryptInfo *types.PDFEncry
 			kidObjNum, _ := strconv.Atoi(ref[1])
 			kidGenNum, _ := strconv.Atoi(ref[2])
 
-			kidField, err := parseField(pdfBytes, kidObjNum, kidGenNum, encryptInfo, verbose)
+			kidField, err := parseField(src, kidObjNum, kidGenNum)
 			if err != nil {
 				if verbose {
 					fmt.Printf("Warning: Failed to parse kid field %d: %v\n", kidObjNum, err)
@@ -239,6 +338,115 @@ func parseField(pdfBytes []byte, objNum, genNum int, encryptInfo *types.PDFEncry
 	return field, nil
 }
 
+// parseAdditionalActions returns the JavaScript of the keystroke (K), format (F),
+// validate (V) and calculate (C) actions in a field's /AA dictionary, keyed by
+// event. Scripts stored in streams are not read.
+func parseAdditionalActions(src objectSource, dataStr string) map[string]interface{} {
+	loc := regexp.MustCompile(`/AA\s*<<`).FindStringIndex(dataStr)
+	if loc == nil {
+		return nil
+	}
+	aaDict := balancedDict(dataStr[loc[1]-2:])
+
+	actions := make(map[string]interface{})
+	for _, event := range []string{"K", "F", "V", "C"} {
+		eventPattern := regexp.MustCompile(`/` + event + `\s*(<<|(\d+)\s+\d+\s+R)`)
+		m := eventPattern.FindStringSubmatchIndex(aaDict)
+		if m == nil {
+			continue
+		}
+		var actionStr string
+		if m[4] >= 0 {
+			objNum, _ := strconv.Atoi(aaDict[m[4]:m[5]])
+			actionData, err := src.getObject(objNum)
+			if err != nil {
+				if src.verbose {
+					fmt.Printf("Warning: Failed to get %s action %d: %v\n", event, objNum, err)
+				}
+				continue
+			}
+			actionStr = string(actionData)
+		} else {
+			actionStr = balancedDict(aaDict[m[2]:])
+		}
+		if jsLoc := regexp.MustCompile(`/JS\s*\(`).FindStringIndex(actionStr); jsLoc != nil {
+			actions[event], _ = parseLiteralString(actionStr[jsLoc[1]:])
+		}
+	}
+	return actions
+}
+
+// balancedDict returns the dictionary at the start of s up to its matching >>
+func balancedDict(s string) string {
+	depth := 0
+	for i := 0; i+1 < len(s); i++ {
+		switch {
+		case s[i] == '(':
+			// Skip string literals, which may contain << or >>
+			_, n := parseLiteralString(s[i+1:])
+			i += n
+		case s[i] == '<' && s[i+1] == '<':
+			depth++
+			i++
+		case s[i] == '>' && s[i+1] == '>':
+			depth--
+			i++
+			if depth == 0 {
+				return s[:i+1]
+			}
+		}
+	}
+	return s
+}
+
+// parseLiteralString unescapes a PDF literal string whose opening parenthesis
+// precedes s. It returns the string and the number of bytes of s up to and
+// including the balancing closing parenthesis.
+func parseLiteralString(s string) (string, int) {
+	var result strings.Builder
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch e := s[i]; {
+			case e == 'n':
+				result.WriteByte('\n')
+			case e == 'r':
+				result.WriteByte('\r')
+			case e == 't':
+				result.WriteByte('\t')
+			case e >= '0' && e <= '7':
+				// Octal escape of up to three digits
+				code := 0
+				for n := 0; n < 3 && i < len(s) && s[i] >= '0' && s[i] <= '7'; n++ {
+					code = code*8 + int(s[i]-'0')
+					i++
+				}
+				i--
+				result.WriteByte(byte(code))
+			case e == '\n' || e == '\r':
+				// Line continuation
+			default:
+				result.WriteByte(e)
+			}
+		case c == '(':
+			depth++
+			result.WriteByte(c)
+		case c == ')':
+			if depth == 0 {
+				return result.String(), i + 1
+			}
+			depth--
+			result.WriteByte(c)
+		default:
+			result.WriteByte(c)
+		}
+	}
+	return result.String(), len(s)
+}
+
 // parseArray parses a PDF array string
 func parseArray(arrStr string) []interface{} {
 	items := make([]interface{}, 0)
@@ -2,10 +2,12 @@
 package acroform
 
 import (
+	"encoding/hex"
 	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode/utf16"
 
 	"github.com/benedoc-inc/pdfer/core/parse"
 	"github.com/benedoc-inc/pdfer/types"
@@ -63,28 +65,32 @@ func FillFieldValue(pdfBytes []byte, field *Field, value interface{}, encryptInf
 		return nil, fmt.Errorf("failed to get field object: %w", err)
 	}
 
-	fieldStr := string(fieldData)
-	valueStr := formatFieldValue(value, field.FT)
+	value = applyFieldFormat(field, value, verbose)
+	value, err = fitMaxLen(field, value, verbose)
+	if err != nil {
+		return nil, err
+	}
 
-	// Replace or add /V entry
-	vPattern := regexp.MustCompile(`/V\s*(?:\([^)]*\)|/[^\s]+|\[[^\]]*\])`)
-	newV := fmt.Sprintf("/V (%s)", escapeFieldValue(valueStr))
+	return editFieldObject(pdfBytes, fieldData, fieldEdit{field: field, value: value, setValue: true}, encryptInfo, verbose)
+}
 
-	var newFieldStr string
-	if vPattern.MatchString(fieldStr) {
-		// Replace existing value
-		newFieldStr = vPattern.ReplaceAllString(fieldStr, newV)
-	} else {
-		// Add new /V entry before the closing >>
-		dictEnd := strings.LastIndex(fieldStr, ">>")
-		if dictEnd == -1 {
-			return nil, fmt.Errorf("field dictionary not found")
-		}
-		newFieldStr = fieldStr[:dictEnd] + newV + " " + fieldStr[dictEnd:]
+// replaceFieldEdit applies an edit to a field that is a direct object
+func replaceFieldEdit(pdfBytes []byte, edit fieldEdit, encryptInfo *types.PDFEncryption, verbose bool) ([]byte, error) {
+	fieldData, err := parse.GetObject(pdfBytes, edit.field.ObjectNum, encryptInfo, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get field object: %w", err)
 	}
+	return editFieldObject(pdfBytes, fieldData, edit, encryptInfo, verbose)
+}
 
-	// Replace the object
-	return ReplaceFieldObject(pdfBytes, field.ObjectNum, field.Generation, []byte(newFieldStr), encryptInfo, verbose)
+// editFieldObject applies an edit to the field dictionary fieldData and replaces
+// the field object with the result
+func editFieldObject(pdfBytes, fieldData []byte, edit fieldEdit, encryptInfo *types.PDFEncryption, verbose bool) ([]byte, error) {
+	newFieldData, err := applyFieldEdit(fieldData, edit)
+	if err != nil {
+		return nil, err
+	}
+	return ReplaceFieldObject(pdfBytes, edit.field.ObjectNum, edit.field.Generation, newFieldData, encryptInfo, verbose)
 }
 
 // formatFieldValue formats a value for PDF based on field type
@@ -110,6 +116,30 @@ func formatFieldValue(value interface{}, fieldType string) string {
 	}
 }
 
+// fitMaxLen applies a text field's /MaxLen to a value. Longer values are truncated,
+// as viewers do when typing, except in comb fields: each cell of a comb holds one
+// character of an identifier, so a value that does not fit is an error.
+func fitMaxLen(field *Field, value interface{}, verbose bool) (interface{}, error) {
+	if field.MaxLen <= 0 || field.FT != "Tx" && field.FT != "" {
+		return value, nil
+	}
+	runes := []rune(formatFieldValue(value, field.FT))
+	if len(runes) <= field.MaxLen {
+		return value, nil
+	}
+	if field.Ff&FlagComb != 0 {
+		return nil, &ValidationError{
+			FieldName: field.GetFullName(),
+			Message:   fmt.Sprintf("value does not fit the %d comb cells", field.MaxLen),
+			Value:     value,
+		}
+	}
+	if verbose {
+		fmt.Printf("Warning: Value for field '%s' truncated to %d characters\n", field.GetFullName(), field.MaxLen)
+	}
+	return string(runes[:field.MaxLen]), nil
+}
+
 // escapeFieldValue escapes special characters in field values
 func escapeFieldValue(s string) string {
 	var result strings.Builder
@@ -138,6 +168,49 @@ func escapeFieldValue(s string) string {
 	return result.String()
 }
 
+// textStringOperand formats s as a PDF text string operand: a literal string
+// when PDFDocEncoding covers it, otherwise a hex string of UTF-16BE with a byte
+// order mark, so that values in other scripts are not garbled
+func textStringOperand(s string) string {
+	for _, r := range s {
+		if r > 0xFF || r >= 0x7F && r < 0xA0 {
+			var b strings.Builder
+			b.WriteString("<FEFF")
+			for _, u := range utf16.Encode([]rune(s)) {
+				b.WriteString(fmt.Sprintf("%04X", u))
+			}
+			b.WriteString(">")
+			return b.String()
+		}
+	}
+	return "(" + escapeFieldValue(s) + ")"
+}
+
+// decodeHexTextString decodes the body of a hex string holding a text string,
+// UTF-16BE when it starts with a byte order mark and PDFDocEncoding otherwise
+func decodeHexTextString(s string) string {
+	s = strings.Join(strings.Fields(s), "")
+	if len(s)%2 == 1 {
+		s += "0"
+	}
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return ""
+	}
+	if len(data) >= 2 && data[0] == 0xFE && data[1] == 0xFF {
+		units := make([]uint16, 0, len(data)/2)
+		for i := 2; i+1 < len(data); i += 2 {
+			units = append(units, uint16(data[i])<<8|uint16(data[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	runes := make([]rune, len(data))
+	for i, c := range data {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
 // FillFormFields fills multiple fields in a PDF
 // This function attempts to handle both direct objects and object streams
 func FillFormFields(pdfBytes []byte, formData types.FormData, password []byte, verbose bool) ([]byte, error) {
@@ -0,0 +1,132 @@
+package acroform
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// Template is an AcroForm PDF parsed and indexed once for repeated fills: the
+// cross-reference data, the field tree, a field name index and the object stream
+// location of each field. Fill never modifies a Template, so one can be filled
+// from multiple goroutines.
+type Template struct {
+	pdfBytes    []byte
+	encryptInfo *types.PDFEncryption
+	acroForm    *AcroForm
+	fields      map[string]*Field       // Field by full or partial name, as FindFieldByName resolves them
+	streamSlots map[int]fieldStreamSlot // Field object number -> location, for fields in object streams
+}
+
+// fieldStreamSlot locates a field dictionary stored in an object stream
+type fieldStreamSlot struct {
+	objData      []byte // Decoded field dictionary
+	streamObjNum int
+	index        int
+}
+
+// PrepareTemplate parses an AcroForm PDF for repeated fills with Template.Fill.
+// pdfBytes must not be modified while the template is in use.
+func PrepareTemplate(pdfBytes []byte, password []byte, verbose bool) (*Template, error) {
+	if len(pdfBytes) == 0 {
+		return nil, fmt.Errorf("PDF bytes are empty")
+	}
+
+	// Parse PDF to get encryption info and object locations
+	pdf, err := parse.OpenWithOptions(pdfBytes, parse.ParseOptions{
+		Password: password,
+		Verbose:  verbose,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PDF: %w", err)
+	}
+
+	// Extract AcroForm
+	acroForm, err := ParseAcroForm(pdfBytes, pdf.Encryption(), verbose)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse AcroForm: %w", err)
+	}
+	if acroForm == nil {
+		return nil, fmt.Errorf("AcroForm is nil")
+	}
+
+	t := &Template{
+		pdfBytes:    pdfBytes,
+		encryptInfo: pdf.Encryption(),
+		acroForm:    acroForm,
+		fields:      make(map[string]*Field),
+		streamSlots: make(map[int]fieldStreamSlot),
+	}
+
+	// Index names in FindFieldByName's search order, keeping the first match
+	index := func(field *Field) {
+		for _, name := range []string{field.GetFullName(), field.T} {
+			if _, ok := t.fields[name]; !ok {
+				t.fields[name] = field
+			}
+		}
+	}
+	for _, field := range acroForm.Fields {
+		index(field)
+		for _, kid := range field.Kids {
+			index(kid)
+		}
+	}
+
+	// Locate the fields stored in object streams; their dictionaries have no
+	// "obj" header
+	var objectStreams map[int]parse.ObjectStreamEntry
+	if startXRef := findStartXRef(pdfBytes); startXRef >= 0 {
+		if xrefResult, err := parse.ParseXRefStreamFull(pdfBytes, startXRef, false); err == nil {
+			objectStreams = xrefResult.ObjectStreams
+		}
+	}
+	var locate func(fields []*Field)
+	locate = func(fields []*Field) {
+		for _, field := range fields {
+			locate(field.Kids)
+			if _, done := t.streamSlots[field.ObjectNum]; done {
+				continue
+			}
+			objData, err := pdf.GetObject(field.ObjectNum)
+			if err != nil {
+				if verbose {
+					fmt.Printf("Warning: Cannot access object %d: %v, using direct replacement\n", field.ObjectNum, err)
+				}
+				continue
+			}
+			header := objData[:min(len(objData), 50)]
+			if bytes.Contains(header, []byte(fmt.Sprintf("%d 0 obj", field.ObjectNum))) {
+				continue
+			}
+			entry, ok := objectStreams[field.ObjectNum]
+			if !ok || entry.StreamObjNum <= 0 {
+				if verbose {
+					fmt.Printf("Warning: Could not find stream for object %d, using direct replacement\n", field.ObjectNum)
+				}
+				continue
+			}
+			t.streamSlots[field.ObjectNum] = fieldStreamSlot{
+				objData:      objData,
+				streamObjNum: entry.StreamObjNum,
+				index:        entry.IndexInStream,
+			}
+		}
+	}
+	locate(acroForm.Fields)
+
+	return t, nil
+}
+
+// AcroForm returns the template's parsed form. It is shared by all fills and
+// must not be modified.
+func (t *Template) AcroForm() *AcroForm {
+	return t.acroForm
+}
+
+// findField returns a field by full or partial name, or nil
+func (t *Template) findField(name string) *Field {
+	return t.fields[name]
+}
@@ -0,0 +1,498 @@
+package acroform
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// Appearance defaults for widgets, in points
+const (
+	buttonGlyphScale  = 0.8  // Auto-sized check glyphs fill this fraction of the widget
+	zapfCapHeight     = 0.7  // Height of ZapfDingbats glyphs as a fraction of the font size
+	listBoxFontSize   = 12.0 // List box options are shown at this size when /DA sets 0
+	defaultBorderSize = 1.0  // Border width when /MK has a border color and /BS no width
+)
+
+// listHighlightColor fills the rows of selected list box options
+const listHighlightColor = "0.6 0.75 0.85 rg"
+
+// standardFontNames maps the resource names viewers use for the standard fonts
+// to their base fonts
+var standardFontNames = map[string]string{
+	"Helv": "Helvetica",
+	"HeBo": "Helvetica-Bold",
+	"HeOb": "Helvetica-Oblique",
+	"Cour": "Courier",
+	"CoBo": "Courier-Bold",
+	"TiRo": "Times-Roman",
+	"TiBo": "Times-Bold",
+	"TiIt": "Times-Italic",
+	"Symb": "Symbol",
+	"ZaDb": "ZapfDingbats",
+}
+
+// zapfGlyphWidths are the widths of the ZapfDingbats glyphs /MK /CA names most
+// often, in thousandths of the font size
+var zapfGlyphWidths = map[string]float64{
+	"4": 756, // Check
+	"8": 838, // Cross
+	"l": 791, // Circle
+	"n": 762, // Square
+	"u": 759, // Diamond
+	"H": 816, // Star
+}
+
+var (
+	resourceEntryPattern = regexp.MustCompile(`/([^\s/<>\[\]()]+)\s+(\d+)\s+(\d+)\s+R`)
+	mkPattern            = regexp.MustCompile(`/MK\s*<<`)
+	borderWidthPattern   = regexp.MustCompile(`/BS\s*<<[^>]*/W\s+([\d.]+)`)
+	appearancePattern    = regexp.MustCompile(`/AP\s*<<`)
+	normalStatesPattern  = regexp.MustCompile(`/N\s*<<`)
+	stateNamePattern     = regexp.MustCompile(`/([^\s/<>\[\]()]+)\s+\d+\s+\d+\s+R`)
+)
+
+// appearanceObjects is where widget appearance streams and their fonts are
+// written: a PDFWriter or the IncrementalWriter of an update
+type appearanceObjects interface {
+	AddObject(content []byte) int
+	AddStreamObject(dict write.Dictionary, data []byte, compress bool) int
+}
+
+// resourceFont is a font named in /DA strings: one of the AcroForm /DR, or a
+// standard font added for a name /DR does not list
+type resourceFont struct {
+	ref       string // Indirect reference to the font dictionary
+	baseFont  string
+	firstChar int
+	widths    []float64 // Widths from /FirstChar, in thousandths of the font size
+}
+
+// textWidth returns the width of WinAnsi text at size, from the font's /Widths
+// and the standard font metrics for characters they do not cover
+func (rf *resourceFont) textWidth(text string, size float64) float64 {
+	width := 0.0
+	for _, r := range text {
+		if i := int(r) - rf.firstChar; i >= 0 && i < len(rf.widths) && rf.widths[i] > 0 {
+			width += rf.widths[i] * size / 1000
+			continue
+		}
+		width += write.StandardTextWidth(string(r), rf.baseFont, size)
+	}
+	return width
+}
+
+// widgetAppearances renders the appearance streams of a form's widgets from
+// field values, using the fonts of the form's /DR
+type widgetAppearances struct {
+	defaultDA string
+	out       appearanceObjects
+	fonts     map[string]*resourceFont // By resource name
+}
+
+// newWidgetAppearances prepares to render the widgets of acroForm in pdfBytes,
+// writing the appearance streams to out
+func newWidgetAppearances(pdfBytes []byte, encryptInfo *types.PDFEncryption, acroForm *AcroForm, out appearanceObjects, verbose bool) *widgetAppearances {
+	g := &widgetAppearances{
+		defaultDA: acroForm.DA,
+		out:       out,
+		fonts:     make(map[string]*resourceFont),
+	}
+
+	fontDict := ""
+	if m := regexp.MustCompile(`/Font\s*(?:<<|(\d+)\s+\d+\s+R)`).FindStringSubmatchIndex(acroForm.DR); m != nil {
+		if m[2] >= 0 {
+			objNum, _ := strconv.Atoi(acroForm.DR[m[2]:m[3]])
+			if data, err := parse.GetObject(pdfBytes, objNum, encryptInfo, verbose); err == nil {
+				fontDict = string(objectContent(data))
+			}
+		} else {
+			fontDict = balancedDict(acroForm.DR[m[1]-2:])
+		}
+	}
+	for _, entry := range resourceEntryPattern.FindAllStringSubmatch(fontDict, -1) {
+		objNum, _ := strconv.Atoi(entry[2])
+		data, err := parse.GetObject(pdfBytes, objNum, encryptInfo, verbose)
+		if err != nil {
+			if verbose {
+				fmt.Printf("Warning: Failed to get font %s (object %d): %v\n", entry[1], objNum, err)
+			}
+			continue
+		}
+		dict := string(objectContent(data))
+		// Type0 fonts show CIDs, which WinAnsi text cannot address
+		if strings.Contains(dict, "/Type0") {
+			if verbose {
+				fmt.Printf("Warning: Font %s is a Type0 font; using Helvetica in appearances\n", entry[1])
+			}
+			continue
+		}
+		rf := &resourceFont{ref: fmt.Sprintf("%s %s R", entry[2], entry[3]), baseFont: "Helvetica"}
+		if m := regexp.MustCompile(`/BaseFont\s*/([^\s/<>\[\]()]+)`).FindStringSubmatch(dict); m != nil {
+			rf.baseFont = m[1]
+		}
+		if m := regexp.MustCompile(`/FirstChar\s+(\d+)`).FindStringSubmatch(dict); m != nil {
+			rf.firstChar, _ = strconv.Atoi(m[1])
+		}
+		if m := regexp.MustCompile(`/Widths\s*(?:\[([^\]]*)\]|(\d+)\s+\d+\s+R)`).FindStringSubmatch(dict); m != nil {
+			widths := m[1]
+			if m[2] != "" {
+				widthsObjNum, _ := strconv.Atoi(m[2])
+				if data, err := parse.GetObject(pdfBytes, widthsObjNum, encryptInfo, verbose); err == nil {
+					if arr := regexp.MustCompile(`\[([^\]]*)\]`).FindStringSubmatch(string(data)); arr != nil {
+						widths = arr[1]
+					}
+				}
+			}
+			rf.widths = parseRect(widths)
+		}
+		g.fonts[entry[1]] = rf
+	}
+	return g
+}
+
+// font returns the font of a resource name, adding a standard font dictionary
+// for a name /DR does not list
+func (g *widgetAppearances) font(name string) *resourceFont {
+	if rf, ok := g.fonts[name]; ok {
+		return rf
+	}
+	baseFont, ok := standardFontNames[name]
+	if !ok {
+		baseFont = "Helvetica"
+	}
+	encoding := "/Encoding /WinAnsiEncoding "
+	if baseFont == "Symbol" || baseFont == "ZapfDingbats" {
+		encoding = ""
+	}
+	objNum := g.out.AddObject([]byte(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s %s>>", baseFont, encoding)))
+	rf := &resourceFont{ref: fmt.Sprintf("%d 0 R", objNum), baseFont: baseFont}
+	g.fonts[name] = rf
+	return rf
+}
+
+// fieldType returns the field type of a field, inherited from its parents
+func fieldType(field *Field) string {
+	for f := field; f != nil; f = f.Parent {
+		if f.FT != "" {
+			return f.FT
+		}
+	}
+	return ""
+}
+
+// fieldWidgets returns the widget annotations of a terminal field: the field
+// itself when it is merged with its widget, and the kids without a name
+func fieldWidgets(field *Field) []*Field {
+	var widgets []*Field
+	if len(field.Rect) >= 4 {
+		widgets = append(widgets, field)
+	}
+	for _, kid := range field.Kids {
+		if kid.T == "" && len(kid.Rect) >= 4 {
+			widgets = append(widgets, kid)
+		}
+	}
+	return widgets
+}
+
+// render writes the appearance streams of a widget of field showing value, nil
+// for an empty field, and returns widgetDict, the widget's dictionary, with its
+// /AP, and for check boxes and radio buttons its /AS, set to them. original is
+// the widget's dictionary before any edit, whose appearances name the states of
+// a button. Push buttons and signature fields are returned unchanged.
+func (g *widgetAppearances) render(field, widget *Field, widgetDict, original string, value interface{}) (string, error) {
+	if len(widget.Rect) < 4 {
+		return "", fmt.Errorf("widget %d has no rectangle", widget.ObjectNum)
+	}
+	width := math.Abs(widget.Rect[2] - widget.Rect[0])
+	height := math.Abs(widget.Rect[3] - widget.Rect[1])
+	mk := parseWidgetMK(widgetDict)
+
+	switch ft := fieldType(field); {
+	case ft == "Btn" && field.Ff&FlagPushbutton == 0:
+		return g.renderButton(field, widget, widgetDict, widgetOnState(original), value, width, height, mk)
+	case ft == "Tx", ft == "Ch" && field.Ff&FlagCombo != 0:
+		text := ""
+		if value != nil {
+			text = formatFieldValue(value, ft)
+		}
+		return g.renderText(field, widget, widgetDict, text, width, height, mk)
+	case ft == "Ch":
+		return g.renderList(field, widget, widgetDict, value, width, height, mk)
+	}
+	return widgetDict, nil
+}
+
+// renderText renders a text field or combo box widget showing text
+func (g *widgetAppearances) renderText(field, widget *Field, widgetDict, text string, width, height float64, mk widgetMK) (string, error) {
+	// Lay out in the widget's rectangle as it would be unrotated
+	layoutWidget := *widget
+	layoutWidget.Rect = []float64{0, 0, width, height}
+	ta, text, _ := textLayout(field, &layoutWidget, g.defaultDA, text)
+	if ta.da.FontName == "" {
+		ta.da.FontName = "Helv"
+	}
+	rf := g.font(ta.da.FontName)
+
+	var content strings.Builder
+	mk.writeBox(&content, width, height)
+	if err := writeTextContent(&content, text, ta, ta.da.FontName, appearanceFont{resource: rf}); err != nil {
+		return "", err
+	}
+	objNum := g.out.AddStreamObject(g.formDict(width, height, ta.da.FontName, rf), []byte(content.String()), true)
+	return setWidgetAppearance(widgetDict, fmt.Sprintf("/N %d 0 R", objNum), ""), nil
+}
+
+// renderList renders a list box widget: the options from the top index, with
+// the selected ones highlighted
+func (g *widgetAppearances) renderList(field, widget *Field, widgetDict string, value interface{}, width, height float64, mk widgetMK) (string, error) {
+	layoutWidget := *widget
+	layoutWidget.Rect = []float64{0, 0, width, height}
+	ta, _, _ := textLayout(field, &layoutWidget, g.defaultDA, "")
+	if ta.da.FontName == "" {
+		ta.da.FontName = "Helv"
+	}
+	fontSize := ta.da.FontSize
+	if fontSize <= 0 {
+		fontSize = listBoxFontSize
+	}
+	rf := g.font(ta.da.FontName)
+	af := appearanceFont{resource: rf}
+	ascent, _, lineHeight := af.verticalMetrics(fontSize)
+
+	selected := make(map[string]bool)
+	switch v := value.(type) {
+	case nil:
+	case []interface{}:
+		for _, item := range v {
+			selected[formatFieldValue(item, "Ch")] = true
+		}
+	case []string:
+		for _, item := range v {
+			selected[item] = true
+		}
+	default:
+		selected[formatFieldValue(v, "Ch")] = true
+	}
+
+	var content strings.Builder
+	mk.writeBox(&content, width, height)
+	content.WriteString("/Tx BMC\nq\n")
+	content.WriteString(fmt.Sprintf("%.2f %.2f %.2f %.2f re W n\n", appearancePadding/2, appearancePadding/2,
+		width-appearancePadding, height-appearancePadding))
+
+	// Highlight rows first so the text is drawn over them
+	options := field.Opt
+	if field.TI > 0 && field.TI < len(options) {
+		options = options[field.TI:]
+	}
+	top := height - appearancePadding
+	for i, opt := range options {
+		rowTop := top - float64(i)*lineHeight
+		if rowTop <= 0 {
+			break
+		}
+		if selected[fmt.Sprintf("%v", opt)] {
+			content.WriteString(fmt.Sprintf("%s\n%.2f %.2f %.2f %.2f re f\n", listHighlightColor,
+				appearancePadding/2, rowTop-lineHeight, width-appearancePadding, lineHeight))
+		}
+	}
+	content.WriteString("BT\n")
+	content.WriteString(fmt.Sprintf("/%s %.2f Tf\n", ta.da.FontName, fontSize))
+	content.WriteString(ta.da.Color + "\n")
+	for i, opt := range options {
+		baseline := top - float64(i)*lineHeight - ascent
+		if baseline+ascent <= 0 {
+			break
+		}
+		content.WriteString(fmt.Sprintf("1 0 0 1 %.2f %.2f Tm\n", appearancePadding, baseline))
+		content.WriteString(textOperator(fmt.Sprintf("%v", opt), nil))
+	}
+	content.WriteString("ET\nQ\nEMC\n")
+
+	objNum := g.out.AddStreamObject(g.formDict(width, height, ta.da.FontName, rf), []byte(content.String()), true)
+	return setWidgetAppearance(widgetDict, fmt.Sprintf("/N %d 0 R", objNum), ""), nil
+}
+
+// renderButton renders the on and off states of a check box or radio button
+// widget and sets /AS to the state value selects. A check box is on for any
+// value other than "Off"; a radio button only for onState, the name of its on
+// state.
+func (g *widgetAppearances) renderButton(field, widget *Field, widgetDict, onState string, value interface{}, width, height float64, mk widgetMK) (string, error) {
+	state := ""
+	if value != nil {
+		state = formatFieldValue(value, "Btn")
+	}
+	on := state == onState
+	if field.Ff&FlagRadio == 0 {
+		on = state != "" && state != "Off" && state != "false"
+	}
+
+	da := g.defaultDA
+	for f := widget; f != nil; f = f.Parent {
+		if f.DA != "" {
+			da = f.DA
+			break
+		}
+	}
+	parsed := ParseDefaultAppearance(da)
+	glyph := mk.caption
+	if glyph == "" {
+		glyph = "4"
+		if field.Ff&FlagRadio != 0 {
+			glyph = "l"
+		}
+	}
+	glyphWidth, ok := zapfGlyphWidths[glyph]
+	if !ok {
+		glyphWidth = 800
+	}
+	size := parsed.FontSize
+	if size <= 0 {
+		size = buttonGlyphScale * math.Min(width*1000/glyphWidth, height/zapfCapHeight)
+	}
+	rf := g.font("ZaDb")
+
+	var onContent, offContent strings.Builder
+	mk.writeBox(&onContent, width, height)
+	mk.writeBox(&offContent, width, height)
+	onContent.WriteString("q\nBT\n")
+	onContent.WriteString(fmt.Sprintf("/ZaDb %.2f Tf\n%s\n", size, parsed.Color))
+	onContent.WriteString(fmt.Sprintf("1 0 0 1 %.2f %.2f Tm\n", (width-glyphWidth*size/1000)/2, (height-zapfCapHeight*size)/2))
+	onContent.WriteString(fmt.Sprintf("(%s) Tj\nET\nQ\n", escapeAppearanceText(glyph)))
+
+	onNum := g.out.AddStreamObject(g.formDict(width, height, "ZaDb", rf), []byte(onContent.String()), true)
+	offNum := g.out.AddStreamObject(g.formDict(width, height, "", nil), []byte(offContent.String()), true)
+	as := "Off"
+	if on {
+		as = onState
+	}
+	return setWidgetAppearance(widgetDict, fmt.Sprintf("/N << /%s %d 0 R /Off %d 0 R >>", onState, onNum, offNum), as), nil
+}
+
+// formDict returns the dictionary of a width x height form XObject using the
+// font rf under name, or no font when rf is nil
+func (g *widgetAppearances) formDict(width, height float64, name string, rf *resourceFont) write.Dictionary {
+	dict := write.Dictionary{
+		"/Type":    "/XObject",
+		"/Subtype": "/Form",
+		"/BBox":    []interface{}{0, 0, width, height},
+		"/Matrix":  []interface{}{1, 0, 0, 1, 0, 0},
+	}
+	if rf != nil {
+		dict["/Resources"] = write.Dictionary{
+			"/Font": write.Dictionary{"/" + name: rf.ref},
+		}
+	}
+	return dict
+}
+
+// widgetMK holds the appearance characteristics (/MK) of a widget
+type widgetMK struct {
+	background  string  // Fill color operator, empty for none
+	border      string  // Stroke color operator, empty for none
+	borderWidth float64 // From /BS /W
+	caption     string  // /CA: the ZapfDingbats character of a check box or radio button
+}
+
+// parseWidgetMK reads the /MK colors and caption and the /BS border width of a
+// widget dictionary
+func parseWidgetMK(widgetDict string) widgetMK {
+	mk := widgetMK{borderWidth: defaultBorderSize}
+	if m := borderWidthPattern.FindStringSubmatch(widgetDict); m != nil {
+		mk.borderWidth, _ = strconv.ParseFloat(m[1], 64)
+	}
+	loc := mkPattern.FindStringIndex(widgetDict)
+	if loc == nil {
+		return mk
+	}
+	dict := balancedDict(widgetDict[loc[1]-2:])
+	if m := regexp.MustCompile(`/BG\s*\[([^\]]*)\]`).FindStringSubmatch(dict); m != nil {
+		mk.background = colorOperator(m[1], false)
+	}
+	if m := regexp.MustCompile(`/BC\s*\[([^\]]*)\]`).FindStringSubmatch(dict); m != nil {
+		mk.border = colorOperator(m[1], true)
+	}
+	if loc := regexp.MustCompile(`/CA\s*\(`).FindStringIndex(dict); loc != nil {
+		mk.caption, _ = parseLiteralString(dict[loc[1]:])
+	}
+	return mk
+}
+
+// colorOperator returns the operator setting the fill, or with stroke the
+// stroking, color of an /MK color array: gray, RGB or CMYK by its length. An
+// empty array, meaning transparent, gives none.
+func colorOperator(components string, stroke bool) string {
+	fields := strings.Fields(components)
+	ops := map[int]string{1: "g", 3: "rg", 4: "k"}
+	op, ok := ops[len(fields)]
+	if !ok {
+		return ""
+	}
+	if stroke {
+		op = strings.ToUpper(op)
+	}
+	return strings.Join(fields, " ") + " " + op
+}
+
+// writeBox writes the background and border of a width x height widget
+func (mk widgetMK) writeBox(content *strings.Builder, width, height float64) {
+	if mk.background != "" {
+		content.WriteString(fmt.Sprintf("q\n%s\n0 0 %.2f %.2f re f\nQ\n", mk.background, width, height))
+	}
+	if mk.border != "" && mk.borderWidth > 0 {
+		bw := mk.borderWidth
+		content.WriteString(fmt.Sprintf("q\n%s\n%.2f w\n%.2f %.2f %.2f %.2f re S\nQ\n", mk.border, bw,
+			bw/2, bw/2, width-bw, height-bw))
+	}
+}
+
+// widgetOnState returns the name of a button widget's on state: the first
+// name other than Off in its normal appearance dictionary, or "Yes"
+func widgetOnState(widgetDict string) string {
+	loc := appearancePattern.FindStringIndex(widgetDict)
+	if loc == nil {
+		return "Yes"
+	}
+	ap := balancedDict(widgetDict[loc[1]-2:])
+	nLoc := normalStatesPattern.FindStringIndex(ap)
+	if nLoc == nil {
+		return "Yes"
+	}
+	for _, m := range stateNamePattern.FindAllStringSubmatch(balancedDict(ap[nLoc[1]-2:]), -1) {
+		if m[1] != "Off" {
+			return m[1]
+		}
+	}
+	return "Yes"
+}
+
+// removeAppearance removes the /AP entry of a widget dictionary, direct or
+// indirect
+func removeAppearance(widgetDict string) string {
+	if loc := appearancePattern.FindStringIndex(widgetDict); loc != nil {
+		ap := balancedDict(widgetDict[loc[1]-2:])
+		widgetDict = widgetDict[:loc[0]] + widgetDict[loc[1]-2+len(ap):]
+	}
+	return regexp.MustCompile(`/AP\s+\d+\s+\d+\s+R`).ReplaceAllString(widgetDict, "")
+}
+
+// setWidgetAppearance replaces the /AP of a widget dictionary with one whose
+// entries are ap, and with a non-empty state its /AS
+func setWidgetAppearance(widgetDict, ap, state string) string {
+	widgetDict = removeAppearance(widgetDict)
+	entries := "/AP << " + ap + " >>"
+	if state != "" {
+		widgetDict = regexp.MustCompile(`/AS\s*/[^\s/<>\[\]()]+`).ReplaceAllString(widgetDict, "")
+		entries += " /AS /" + state
+	}
+	dictEnd := strings.LastIndex(widgetDict, ">>")
+	return widgetDict[:dictEnd] + entries + " " + widgetDict[dictEnd:]
+}
@@ -0,0 +1,222 @@
+package acroform
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// Object numbers of the widgets of buildWidgetTestPDF
+const (
+	widgetName    = 6
+	widgetAgree   = 7
+	widgetRed     = 9
+	widgetBlue    = 10
+	widgetCountry = 11
+	widgetLangs   = 12
+)
+
+// buildWidgetTestPDF creates a form with a text field "name" with /MK colors, a
+// check box "agree", a radio group "color" with the widgets Red and Blue, a
+// combo box "country" and a list box "langs". /DR lists Helvetica as /Helv.
+func buildWidgetTestPDF(t *testing.T) []byte {
+	t.Helper()
+	w := write.NewPDFWriter()
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R /AcroForm 4 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Annots [6 0 R 7 0 R 9 0 R 10 0 R 11 0 R 12 0 R] >>",
+		"<< /Fields [6 0 R 7 0 R 8 0 R 11 0 R 12 0 R] /DA (/Helv 0 Tf 0 g) /DR << /Font << /Helv 5 0 R >> >> >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Annot /Subtype /Widget /P 3 0 R /FT /Tx /T (name) /Rect [72 700 272 720] /DA (/Helv 12 Tf 0 0 1 rg) /Q 1 /MK << /BG [1 1 0.8] /BC [0 0 1] >> >>",
+		"<< /Type /Annot /Subtype /Widget /P 3 0 R /FT /Btn /T (agree) /Rect [72 660 86 674] /MK << /CA (8) >> /AP << /N << /On 13 0 R /Off 14 0 R >> >> /AS /Off >>",
+		"<< /FT /Btn /T (color) /Ff 49152 /Kids [9 0 R 10 0 R] >>",
+		"<< /Type /Annot /Subtype /Widget /P 3 0 R /Parent 8 0 R /Rect [72 620 86 634] /AP << /N << /Red 13 0 R /Off 14 0 R >> >> /AS /Off >>",
+		"<< /Type /Annot /Subtype /Widget /P 3 0 R /Parent 8 0 R /Rect [100 620 114 634] /AP << /N << /Blue 13 0 R /Off 14 0 R >> >> /AS /Off >>",
+		"<< /Type /Annot /Subtype /Widget /P 3 0 R /FT /Ch /T (country) /Ff 131072 /Opt [(Norway) (Sweden)] /Rect [72 580 272 600] >>",
+		"<< /Type /Annot /Subtype /Widget /P 3 0 R /FT /Ch /T (langs) /Opt [(Go) (Rust) (C)] /Rect [72 500 272 560] /DA (/Helv 10 Tf 0 g) >>",
+	}
+	for _, obj := range objects {
+		w.AddObject([]byte(obj))
+	}
+	form := write.Dictionary{"/Type": "/XObject", "/Subtype": "/Form", "/BBox": []interface{}{0, 0, 14, 14}}
+	w.AddStreamObject(form, []byte("q Q"), false)
+	w.AddStreamObject(form, []byte("q Q"), false)
+	w.SetRoot(1)
+	pdfBytes, err := w.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to generate PDF: %v", err)
+	}
+	return pdfBytes
+}
+
+// widgetState returns a widget's dictionary in a parsed document and the
+// decompressed content of its normal appearance for state, or its only normal
+// appearance when state is empty
+func widgetState(t *testing.T, pdf *parse.PDF, widgetNum int, state string) (string, string) {
+	t.Helper()
+	dict, err := pdf.GetObject(widgetNum)
+	if err != nil {
+		t.Fatalf("GetObject(%d) failed: %v", widgetNum, err)
+	}
+	pattern := `/AP << /N (\d+) 0 R`
+	if state != "" {
+		pattern = `/AP << /N <<[^>]*/` + state + ` (\d+) 0 R`
+	}
+	m := regexp.MustCompile(pattern).FindSubmatch(dict)
+	if m == nil {
+		t.Fatalf("Widget %d has no appearance for %q: %s", widgetNum, state, dict)
+	}
+	objNum, _ := strconv.Atoi(string(m[1]))
+	stream, err := pdf.GetObject(objNum)
+	if err != nil {
+		t.Fatalf("GetObject(%d) failed: %v", objNum, err)
+	}
+	start := bytes.Index(stream, []byte("stream\n"))
+	end := bytes.LastIndex(stream, []byte("\nendstream"))
+	if start < 0 || end < start {
+		t.Fatalf("Object %d is not a stream: %s", objNum, stream)
+	}
+	r, err := zlib.NewReader(bytes.NewReader(stream[start+len("stream\n") : end]))
+	if err != nil {
+		t.Fatalf("Appearance %d is not compressed: %v", objNum, err)
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Failed to decompress appearance %d: %v", objNum, err)
+	}
+	return string(dict), string(stream[:start]) + string(content)
+}
+
+func TestFillFormFieldsWithOptions_Appearances(t *testing.T) {
+	pdfBytes := buildWidgetTestPDF(t)
+	data := types.FormData{"name": "Jane Doe", "agree": true, "color": "Blue", "country": "Sweden", "langs": "Rust"}
+	filled, report, err := FillFormFieldsWithReport(pdfBytes, data, FillOptions{Appearances: true})
+	if err != nil {
+		t.Fatalf("Fill failed: %v", err)
+	}
+	if len(report.Filled) != len(data) || len(report.Failed) != 0 {
+		t.Errorf("Filled = %v, Failed = %v", report.Filled, report.Failed)
+	}
+	if !bytes.HasPrefix(filled, pdfBytes) {
+		t.Fatal("A fill with appearances should be an incremental update")
+	}
+	pdf, err := parse.Open(filled)
+	if err != nil {
+		t.Fatalf("Failed to parse filled PDF: %v", err)
+	}
+
+	// Text in the /DA font of /DR, size and color, centered, on the /MK colors
+	_, content := widgetState(t, pdf, widgetName, "")
+	for _, want := range []string{"/Helv 5 0 R", "1 1 0.8 rg", "0 0 1 RG", "/Helv 12.00 Tf", "0 0 1 rg", "(Jane Doe) Tj"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("Text appearance missing %q:\n%s", want, content)
+		}
+	}
+	width := write.StandardTextWidth("Jane Doe", "Helvetica", 12)
+	if want := strconv.FormatFloat((200-width)/2, 'f', 2, 64); !strings.Contains(content, "1 0 0 1 "+want+" ") {
+		t.Errorf("Text not centered at x=%s:\n%s", want, content)
+	}
+
+	// The check box keeps its on state name and shows the /MK caption
+	dict, content := widgetState(t, pdf, widgetAgree, "On")
+	if !strings.Contains(dict, "/AS /On") || !strings.Contains(dict, "/Off ") {
+		t.Errorf("Check box not switched on with both states: %s", dict)
+	}
+	if !strings.Contains(content, "/ZaDb") || !strings.Contains(content, "(8) Tj") {
+		t.Errorf("Check box appearance should show the caption glyph:\n%s", content)
+	}
+
+	// Only the radio button whose state is the value is on
+	if dict, content := widgetState(t, pdf, widgetBlue, "Blue"); !strings.Contains(dict, "/AS /Blue") || !strings.Contains(content, "(l) Tj") {
+		t.Errorf("Selected radio button not on: %s\n%s", dict, content)
+	}
+	if dict, _ := widgetState(t, pdf, widgetRed, "Red"); !strings.Contains(dict, "/AS /Off") {
+		t.Errorf("Other radio button should be off: %s", dict)
+	}
+
+	if _, content := widgetState(t, pdf, widgetCountry, ""); !strings.Contains(content, "(Sweden) Tj") {
+		t.Errorf("Combo box should show its value:\n%s", content)
+	}
+
+	// A list box shows its options with the selected one highlighted
+	_, content = widgetState(t, pdf, widgetLangs, "")
+	for _, want := range []string{"(Go) Tj", "(Rust) Tj", "(C) Tj", "/Helv 10.00 Tf"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("List appearance missing %q:\n%s", want, content)
+		}
+	}
+	if n := strings.Count(content, listHighlightColor); n != 1 {
+		t.Errorf("Expected 1 highlighted option, got %d:\n%s", n, content)
+	}
+}
+
+func TestFillFormFieldsWithOptions_AppearancesClear(t *testing.T) {
+	filled, err := FillFormFieldsWithOptions(buildWidgetTestPDF(t), types.FormData{"name": "Jane Doe"},
+		FillOptions{Incremental: true})
+	if err != nil {
+		t.Fatalf("Fill failed: %v", err)
+	}
+	cleared, err := FillFormFieldsWithOptions(filled, nil, FillOptions{ClearFields: []string{"name", "agree"}, Appearances: true})
+	if err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	pdf, err := parse.Open(cleared)
+	if err != nil {
+		t.Fatalf("Failed to parse cleared PDF: %v", err)
+	}
+	dict, content := widgetState(t, pdf, widgetName, "")
+	if strings.Contains(dict, "/V ") || strings.Contains(content, "Tj") {
+		t.Errorf("Cleared field should show no text: %s\n%s", dict, content)
+	}
+	if !strings.Contains(content, "1 1 0.8 rg") {
+		t.Errorf("Cleared field should keep its background:\n%s", content)
+	}
+	if dict, _ := widgetState(t, pdf, widgetAgree, "On"); !strings.Contains(dict, "/AS /Off") {
+		t.Errorf("Cleared check box should be off: %s", dict)
+	}
+}
+
+func TestRegenerateAppearances(t *testing.T) {
+	// An incremental fill sets the values and leaves the appearances stale
+	filled, err := FillFormFieldsWithOptions(buildWidgetTestPDF(t), types.FormData{"name": "Jane Doe", "color": "Red"},
+		FillOptions{Incremental: true})
+	if err != nil {
+		t.Fatalf("Fill failed: %v", err)
+	}
+	if _, err := RegenerateAppearances(filled, nil, []string{"missing"}, false); err == nil {
+		t.Error("Expected an error for an unknown field name")
+	}
+
+	regenerated, err := RegenerateAppearances(filled, nil, nil, false)
+	if err != nil {
+		t.Fatalf("RegenerateAppearances failed: %v", err)
+	}
+	if revisions := parse.CountRevisions(regenerated); revisions != parse.CountRevisions(filled)+1 {
+		t.Errorf("Expected one more revision, got %d", revisions)
+	}
+	pdf, err := parse.Open(regenerated)
+	if err != nil {
+		t.Fatalf("Failed to parse regenerated PDF: %v", err)
+	}
+	if _, content := widgetState(t, pdf, widgetName, ""); !strings.Contains(content, "(Jane Doe) Tj") {
+		t.Errorf("Text appearance should show the value:\n%s", content)
+	}
+	if dict, _ := widgetState(t, pdf, widgetRed, "Red"); !strings.Contains(dict, "/AS /Red") {
+		t.Errorf("Radio button of the value should be on: %s", dict)
+	}
+	if dict, _ := widgetState(t, pdf, widgetAgree, "On"); !strings.Contains(dict, "/AS /Off") {
+		t.Errorf("Unset check box should be off: %s", dict)
+	}
+	if _, content := widgetState(t, pdf, widgetLangs, ""); strings.Contains(content, listHighlightColor) {
+		t.Errorf("List box without a value should have no selection:\n%s", content)
+	}
+}
@@ -7,13 +7,15 @@ import (
 	"strings"
 
 	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/resources/font"
 )
 
 // FieldBuilder helps build AcroForm fields
 type FieldBuilder struct {
-	writer     *write.PDFWriter
-	fields     []*FieldDef
-	nextObjNum int
+	writer      *write.PDFWriter
+	fields      []*FieldDef
+	nextObjNum  int
+	appearances *AppearanceBuilder // Set with a fallback font
 }
 
 // FieldDef represents a field definition for creation
@@ -39,6 +41,15 @@ func NewFieldBuilder(w *write.PDFWriter) *FieldBuilder {
 	}
 }
 
+// SetFallbackFont sets a Unicode font for text field values the default
+// Helvetica cannot encode, such as text in non-Latin scripts. Such a field gets
+// an appearance stream showing its value in f, and a /DA naming f, which the
+// AcroForm /DR lists for viewers that regenerate appearances.
+func (fb *FieldBuilder) SetFallbackFont(f *font.Font) {
+	fb.appearances = NewAppearanceBuilder(fb.writer)
+	fb.appearances.SetFallbackFont(f)
+}
+
 // AddTextField adds a text field to the form
 func (fb *FieldBuilder) AddTextField(name string, rect []float64, page int) *FieldDef {
 	field := &FieldDef{
@@ -142,31 +153,92 @@ func (fd *FieldDef) SetMaxLength(maxLen int) *FieldDef {
 	return fd
 }
 
+// SetComb spreads the text of the field evenly over MaxLen cells, one character
+// per cell; MaxLen must be set as well
+func (fd *FieldDef) SetComb(comb bool) *FieldDef {
+	if comb {
+		fd.Flags |= FlagComb
+	} else {
+		fd.Flags &^= FlagComb
+	}
+	return fd
+}
+
 // Build creates the AcroForm dictionary and field objects
 func (fb *FieldBuilder) Build() (int, error) {
 	if len(fb.fields) == 0 {
 		return 0, fmt.Errorf("no fields to build")
 	}
 
-	// Create field objects
+	fieldRefs, _, err := fb.buildFields(nil)
+	if err != nil {
+		return 0, err
+	}
+	acroFormNum := fb.writer.AddObject(fb.acroFormDict(fieldRefs))
+
+	return acroFormNum, nil
+}
+
+// BuildAcroForm creates the field objects, with each widget referencing its page,
+// and the AcroForm dictionary as object acroFormObjNum. It returns the widget
+// object numbers of each page index, for the page /Annots; it implements
+// write.AcroFormSource for SimplePDFBuilder.SetAcroForm.
+func (fb *FieldBuilder) BuildAcroForm(acroFormObjNum int, pageObjNums []int) (map[int][]int, error) {
+	if len(fb.fields) == 0 {
+		return nil, fmt.Errorf("no fields to build")
+	}
+
+	fieldRefs, annots, err := fb.buildFields(pageObjNums)
+	if err != nil {
+		return nil, err
+	}
+	fb.writer.SetObject(acroFormObjNum, fb.acroFormDict(fieldRefs))
+
+	return annots, nil
+}
+
+// buildFields creates the field objects and returns their references and the
+// widget object numbers by page index
+func (fb *FieldBuilder) buildFields(pageObjNums []int) ([]string, map[int][]int, error) {
 	fieldRefs := make([]string, 0, len(fb.fields))
+	annots := make(map[int][]int)
 	for _, field := range fb.fields {
-		fieldObjNum := fb.createFieldObject(field)
+		pageObjNum := 0
+		if field.Page >= 0 && field.Page < len(pageObjNums) {
+			pageObjNum = pageObjNums[field.Page]
+		}
+		fieldObjNum, err := fb.createFieldObject(field, pageObjNum)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create field %s: %w", field.Name, err)
+		}
 		fieldRefs = append(fieldRefs, fmt.Sprintf("%d 0 R", fieldObjNum))
+		if pageObjNum != 0 {
+			annots[field.Page] = append(annots[field.Page], fieldObjNum)
+		}
 	}
+	return fieldRefs, annots, nil
+}
 
-	// Create AcroForm dictionary
+// acroFormDict formats the AcroForm dictionary for the given field references,
+// with the fallback font in /DR when a field uses it
+func (fb *FieldBuilder) acroFormDict(fieldRefs []string) []byte {
 	fieldsArray := "[" + strings.Join(fieldRefs, " ") + "]"
-	acroFormDict := fmt.Sprintf("<< /Fields %s /NeedAppearances true >>", fieldsArray)
-	acroFormNum := fb.writer.AddObject([]byte(acroFormDict))
-
-	return acroFormNum, nil
+	dr := ""
+	if fb.appearances != nil && fb.appearances.FallbackFont() != 0 {
+		dr = fmt.Sprintf(" /DR << /Font << /%s %d 0 R >> >>", FallbackFontName, fb.appearances.FallbackFont())
+	}
+	return []byte(fmt.Sprintf("<< /Fields %s /NeedAppearances true%s >>", fieldsArray, dr))
 }
 
-// createFieldObject creates a PDF field object
-func (fb *FieldBuilder) createFieldObject(field *FieldDef) int {
+// createFieldObject creates a PDF field object, merged with its widget
+// annotation; pageObjNum is the page of the widget, or 0 when unknown
+func (fb *FieldBuilder) createFieldObject(field *FieldDef, pageObjNum int) (int, error) {
 	var dict strings.Builder
-	dict.WriteString("<<")
+	dict.WriteString("<< /Type /Annot /Subtype /Widget")
+
+	if pageObjNum != 0 {
+		dict.WriteString(fmt.Sprintf(" /P %d 0 R", pageObjNum))
+	}
 
 	// Field type
 	dict.WriteString(fmt.Sprintf(" /FT /%s", field.Type))
@@ -190,13 +262,26 @@ func (fb *FieldBuilder) createFieldObject(field *FieldDef) int {
 	// Value
 	if field.Value != nil {
 		valueStr := formatFieldValueForWriter(field.Value, field.Type)
-		dict.WriteString(fmt.Sprintf(" /V (%s)", escapeFieldValueForWriter(valueStr)))
+		dict.WriteString(" /V " + textStringOperand(valueStr))
+
+		// Values Helvetica cannot show get an appearance in the fallback font
+		if fb.appearances != nil && field.Type == "Tx" && len(field.Rect) >= 4 && !canEncode(valueStr, nil) {
+			widget := &Field{T: field.Name, Rect: field.Rect, Ff: field.Flags, MaxLen: field.MaxLen}
+			appearanceNum, err := fb.appearances.CreateFieldAppearance(widget, valueStr)
+			if err != nil {
+				return 0, err
+			}
+			if widget.DA != "" {
+				dict.WriteString(fmt.Sprintf(" /DA (%s)", widget.DA))
+			}
+			dict.WriteString(fmt.Sprintf(" /AP << /N %d 0 R >>", appearanceNum))
+		}
 	}
 
 	// Default value
 	if field.DefaultValue != nil {
 		valueStr := formatFieldValueForWriter(field.DefaultValue, field.Type)
-		dict.WriteString(fmt.Sprintf(" /DV (%s)", escapeFieldValueForWriter(valueStr)))
+		dict.WriteString(" /DV " + textStringOperand(valueStr))
 	}
 
 	// Maximum length
@@ -211,17 +296,14 @@ func (fb *FieldBuilder) createFieldObject(field *FieldDef) int {
 			if i > 0 {
 				dict.WriteString(" ")
 			}
-			dict.WriteString(fmt.Sprintf("(%s)", escapeFieldValue(opt)))
+			dict.WriteString(textStringOperand(opt))
 		}
 		dict.WriteString("]")
 	}
 
-	// Page reference (would need to be set properly in real implementation)
-	// For now, we'll add it as a property
-
 	dict.WriteString(" >>")
 
-	return fb.writer.AddObject([]byte(dict.String()))
+	return fb.writer.AddObject([]byte(dict.String())), nil
 }
 
 // formatFieldValueForWriter formats a value for PDF writing
@@ -1,8 +1,12 @@
 package acroform
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/benedoc-inc/pdfer/core/parse"
+
 	"github.com/benedoc-inc/pdfer/core/write"
 )
 
@@ -70,3 +74,46 @@ func TestFieldDef(t *testing.T) {
 		t.Error("MaxLen not set correctly")
 	}
 }
+
+func TestSimplePDFBuilder_SetAcroForm(t *testing.T) {
+	builder := write.NewSimplePDFBuilder()
+	fb := NewFieldBuilder(builder.Writer())
+	fb.AddTextField("first", []float64{72, 700, 300, 720}, 0)
+	fb.AddTextField("second", []float64{72, 700, 300, 720}, 1)
+	acroFormNum := builder.SetAcroForm(fb)
+
+	// Pages finalized after the form was attached still get their widgets
+	builder.FinalizePage(builder.AddPage(write.PageSizeLetter))
+	builder.FinalizePage(builder.AddPage(write.PageSizeLetter))
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to generate PDF: %v", err)
+	}
+
+	pdf, err := parse.Open(pdfBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse PDF: %v", err)
+	}
+	var rootObjNum int
+	fmt.Sscanf(pdf.Trailer().RootRef, "%d 0 R", &rootObjNum)
+	catalog, _ := pdf.GetObject(rootObjNum)
+	if !strings.Contains(string(catalog), fmt.Sprintf("/AcroForm %d 0 R", acroFormNum)) {
+		t.Errorf("Catalog does not reference AcroForm %d: %s", acroFormNum, catalog)
+	}
+
+	acroForm, err := ParseAcroForm(pdfBytes, nil, false)
+	if err != nil {
+		t.Fatalf("Failed to parse AcroForm: %v", err)
+	}
+	for i, pageObjNum := range builder.Pages() {
+		page, _ := pdf.GetObject(pageObjNum)
+		field := acroForm.Fields[i]
+		if !strings.Contains(string(page), fmt.Sprintf("/Annots[%d 0 R]", field.ObjectNum)) {
+			t.Errorf("Page %d does not list widget %s: %s", i, field.T, page)
+		}
+		widget, _ := pdf.GetObject(field.ObjectNum)
+		if !strings.Contains(string(widget), fmt.Sprintf("/Subtype /Widget /P %d 0 R", pageObjNum)) {
+			t.Errorf("Widget %s does not reference its page: %s", field.T, widget)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package forms
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/core/manipulate"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// ReadCSVRows reads bulk fill data from CSV: a header row naming the columns and
+// one fill per following row. Without a mapping, columns are named after the
+// fields they fill; with one, mapping gives the field of each column by header
+// and other columns are ignored. Empty cells leave their field as it is, "true"
+// and "false" (in any case) are booleans, for check boxes, and rows without
+// values are skipped.
+func ReadCSVRows(r io.Reader, mapping map[string]string) ([]types.FormData, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // Rows may leave out trailing empty cells
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV: %w", err)
+	}
+	if len(records) > 0 && len(records[0]) > 0 {
+		records[0][0] = strings.TrimPrefix(records[0][0], "\ufeff") // Byte order mark of spreadsheet exports
+	}
+	return rowsFromRecords(records, mapping)
+}
+
+// ReadXLSXRows reads bulk fill data from the named sheet of an Excel workbook,
+// or its first sheet when sheet is empty, laid out as ReadCSVRows expects. Cells
+// formatted as dates are read as YYYY-MM-DD.
+func ReadXLSXRows(data []byte, sheet string, mapping map[string]string) ([]types.FormData, error) {
+	records, err := readXLSXSheet(data, sheet)
+	if err != nil {
+		return nil, err
+	}
+	return rowsFromRecords(records, mapping)
+}
+
+// ReadRows reads bulk fill data from CSV or an Excel workbook, told apart by
+// content: XLSX files are ZIP archives
+func ReadRows(data []byte, sheet string, mapping map[string]string) ([]types.FormData, error) {
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return ReadXLSXRows(data, sheet, mapping)
+	}
+	return ReadCSVRows(bytes.NewReader(data), mapping)
+}
+
+// rowsFromRecords turns a header row and data rows into one FormData per data
+// row, as ReadCSVRows describes
+func rowsFromRecords(records [][]string, mapping map[string]string) ([]types.FormData, error) {
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no header row")
+	}
+	header := records[0]
+	fields := make([]string, len(header))
+	for i, column := range header {
+		column = strings.TrimSpace(column)
+		if mapping == nil {
+			fields[i] = column
+		} else {
+			fields[i] = mapping[column]
+		}
+	}
+	if mapping != nil {
+		present := make(map[string]bool, len(header))
+		for _, column := range header {
+			present[strings.TrimSpace(column)] = true
+		}
+		var missing []string
+		for column := range mapping {
+			if !present[column] {
+				missing = append(missing, column)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			return nil, fmt.Errorf("mapped columns not in the header: %s", strings.Join(missing, ", "))
+		}
+	}
+
+	var rows []types.FormData
+	for _, record := range records[1:] {
+		row := make(types.FormData)
+		for i, cell := range record {
+			if i >= len(fields) || fields[i] == "" || strings.TrimSpace(cell) == "" {
+				continue
+			}
+			switch strings.ToLower(strings.TrimSpace(cell)) {
+			case "true":
+				row[fields[i]] = true
+			case "false":
+				row[fields[i]] = false
+			default:
+				row[fields[i]] = cell
+			}
+		}
+		if len(row) > 0 {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+// FillRows fills a copy of the template for each row, as Fill does, and passes
+// each result, or the error filling it, to emit with the row's index. A row that
+// fails does not stop the others; an error returned by emit does.
+func (t *Template) FillRows(rows []types.FormData, opts FillOptions, emit func(row int, filled []byte, err error) error) error {
+	for i, row := range rows {
+		filled, err := t.Fill(row, opts)
+		if err := emit(i, filled, err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MergeOptions controls MergeRows
+type MergeOptions struct {
+	FillOptions
+	// Flatten flattens each filled copy (see Flatten), so the combined document
+	// has no form; otherwise each copy's fields are grouped under a field named
+	// docN
+	Flatten bool
+	// Title returns the bookmark title of a row's copy; rows with an empty title
+	// get no bookmark. Without it, no bookmarks are added.
+	Title func(row int, data types.FormData) string
+}
+
+// MergeRows fills a copy of the template for each row, as Fill does, and
+// combines the copies into one document in row order, mail-merge style. Objects
+// that are the same in every copy, such as fonts, images and unchanged pages,
+// are written once. Unlike FillRows, a row that fails stops the merge.
+func (t *Template) MergeRows(rows []types.FormData, opts MergeOptions) ([]byte, error) {
+	merger := manipulate.NewDocumentMerger(opts.Verbose)
+	for i, row := range rows {
+		filled, err := t.Fill(row, opts.FillOptions)
+		if err == nil && opts.Flatten {
+			filled, err = Flatten(filled, FlattenOptions{Verbose: opts.Verbose})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i+1, err)
+		}
+		title := ""
+		if opts.Title != nil {
+			title = opts.Title(i, row)
+		}
+		if err := merger.Add(filled, nil, title); err != nil {
+			return nil, fmt.Errorf("row %d: %w", i+1, err)
+		}
+	}
+	return merger.Bytes()
+}
@@ -0,0 +1,188 @@
+package forms
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// buildTestXLSX creates a workbook whose second sheet, "Applicants", has a
+// header row and two applicants, using shared and inline strings, a boolean and
+// a date-formatted number
+func buildTestXLSX(t *testing.T) []byte {
+	t.Helper()
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Notes" sheetId="1" r:id="rId1"/><sheet name="Applicants" sheetId="2" r:id="rId2"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Target="worksheets/sheet2.xml"/></Relationships>`,
+		"xl/sharedStrings.xml":     `<sst><si><t>Name</t></si><si><t>Agree</t></si><si><t>Born</t></si><si><r><t>Jane </t></r><r><t>Doe</t></r></si></sst>`,
+		"xl/styles.xml":            `<styleSheet><numFmts><numFmt numFmtId="164" formatCode="dd/mm/yyyy"/></numFmts><cellXfs><xf numFmtId="0"/><xf numFmtId="164"/></cellXfs></styleSheet>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData><row><c r="A1" t="inlineStr"><is><t>ignored</t></is></c></row></sheetData></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<worksheet><sheetData>` +
+			`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="D1" t="s"><v>2</v></c></row>` +
+			`<row r="2"><c r="A2" t="s"><v>3</v></c><c r="B2" t="b"><v>1</v></c><c r="C2"><v>7</v></c><c r="D2" s="1"><v>32874</v></c></row>` +
+			`<row r="3"><c r="A3" t="inlineStr"><is><t>John Roe</t></is></c><c r="B3" t="b"><v>0</v></c></row>` +
+			`</sheetData></worksheet>`,
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range parts {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to write workbook: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadCSVRows(t *testing.T) {
+	data := "\ufeffApplicant,Agreed,Notes\nJane Doe,TRUE,x\n,,\nJohn Roe,false\n"
+
+	rows, err := ReadCSVRows(strings.NewReader(data), map[string]string{"Applicant": "name", "Agreed": "agree"})
+	if err != nil {
+		t.Fatalf("ReadCSVRows failed: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %d: %v", len(rows), rows)
+	}
+	if rows[0]["name"] != "Jane Doe" || rows[0]["agree"] != true || len(rows[0]) != 2 {
+		t.Errorf("Row 0 = %v", rows[0])
+	}
+	if rows[1]["name"] != "John Roe" || rows[1]["agree"] != false {
+		t.Errorf("Row 1 = %v", rows[1])
+	}
+
+	// Without a mapping the headers are the field names
+	rows, err = ReadCSVRows(strings.NewReader(data), nil)
+	if err != nil {
+		t.Fatalf("ReadCSVRows failed: %v", err)
+	}
+	if rows[0]["Applicant"] != "Jane Doe" || rows[0]["Notes"] != "x" {
+		t.Errorf("Unmapped row 0 = %v", rows[0])
+	}
+
+	if _, err := ReadCSVRows(strings.NewReader(data), map[string]string{"Email": "email"}); err == nil || !strings.Contains(err.Error(), "Email") {
+		t.Errorf("Expected an error naming the missing column, got %v", err)
+	}
+	if _, err := ReadCSVRows(strings.NewReader(""), nil); err == nil {
+		t.Error("Expected an error for empty data")
+	}
+}
+
+func TestReadXLSXRows(t *testing.T) {
+	data := buildTestXLSX(t)
+
+	rows, err := ReadRows(data, "Applicants", map[string]string{"Name": "name", "Agree": "agree", "Born": "born"})
+	if err != nil {
+		t.Fatalf("ReadRows failed: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %d: %v", len(rows), rows)
+	}
+	if rows[0]["name"] != "Jane Doe" || rows[0]["agree"] != true || rows[0]["born"] != "1990-01-01" || len(rows[0]) != 3 {
+		t.Errorf("Row 0 = %v", rows[0])
+	}
+	if rows[1]["name"] != "John Roe" || rows[1]["agree"] != false {
+		t.Errorf("Row 1 = %v", rows[1])
+	}
+
+	// The first sheet is read when none is named
+	rows, err = ReadXLSXRows(data, "", nil)
+	if err != nil || len(rows) != 0 {
+		t.Errorf("Expected a header-only first sheet, got %v, %v", rows, err)
+	}
+	if _, err := ReadXLSXRows(data, "Missing", nil); err == nil {
+		t.Error("Expected an error for a missing sheet")
+	}
+}
+
+func TestTemplate_FillRows(t *testing.T) {
+	tmpl, err := NewTemplate(buildTemplateTestForm(t), nil, false)
+	if err != nil {
+		t.Fatalf("NewTemplate failed: %v", err)
+	}
+	rows, err := ReadCSVRows(strings.NewReader("name\nJane Doe\nJohn Roe\n"), nil)
+	if err != nil {
+		t.Fatalf("ReadCSVRows failed: %v", err)
+	}
+
+	var filled [][]byte
+	err = tmpl.FillRows(rows, FillOptions{}, func(row int, pdfBytes []byte, err error) error {
+		if err != nil {
+			t.Errorf("Row %d failed: %v", row, err)
+		}
+		filled = append(filled, pdfBytes)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("FillRows failed: %v", err)
+	}
+	if len(filled) != 2 {
+		t.Fatalf("Expected 2 filled PDFs, got %d", len(filled))
+	}
+	for i, want := range []string{"Jane Doe", "John Roe"} {
+		if _, err := parse.Open(filled[i]); err != nil {
+			t.Errorf("Row %d: invalid PDF: %v", i, err)
+		}
+		if !bytes.Contains(filled[i], []byte(want)) {
+			t.Errorf("Row %d should contain %q", i, want)
+		}
+	}
+}
+
+func TestTemplate_MergeRows(t *testing.T) {
+	tmpl, err := NewTemplate(buildTemplateTestForm(t), nil, false)
+	if err != nil {
+		t.Fatalf("NewTemplate failed: %v", err)
+	}
+	rows := []types.FormData{{"name": "Jane Doe"}, {"name": "John Roe"}, {"name": "Max Poe"}}
+	title := func(row int, data types.FormData) string { return fmt.Sprint(data["name"]) }
+
+	merged, err := tmpl.MergeRows(rows, MergeOptions{Title: title})
+	if err != nil {
+		t.Fatalf("MergeRows failed: %v", err)
+	}
+	pdf, err := parse.Open(merged)
+	if err != nil {
+		t.Fatalf("Invalid merged PDF: %v", err)
+	}
+	if n, err := pdf.PageCount(); err != nil || n != 3 {
+		t.Errorf("Expected 3 pages, got %d (%v)", n, err)
+	}
+	af, err := ExtractAcroForm(merged, nil, false)
+	if err != nil {
+		t.Fatalf("ExtractAcroForm failed: %v", err)
+	}
+	values := af.GetFieldValues()
+	for i, row := range rows {
+		name := fmt.Sprintf("doc%d.name", i+1)
+		if values[name] != row["name"] {
+			t.Errorf("%s = %v, want %v", name, values[name], row["name"])
+		}
+		if !bytes.Contains(merged, []byte("/Title ("+row["name"].(string)+")")) {
+			t.Errorf("Missing bookmark for %v", row["name"])
+		}
+	}
+
+	flat, err := tmpl.MergeRows(rows, MergeOptions{Flatten: true})
+	if err != nil {
+		t.Fatalf("MergeRows with Flatten failed: %v", err)
+	}
+	if bytes.Contains(flat, []byte("/AcroForm")) || bytes.Contains(flat, []byte("/Outlines")) {
+		t.Error("Flattened merge should have no form and no bookmarks")
+	}
+
+	if _, err := tmpl.MergeRows(nil, MergeOptions{}); err == nil {
+		t.Error("Expected an error merging no rows")
+	}
+}
@@ -0,0 +1,61 @@
+package forms
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+)
+
+var (
+	catalogAcroFormRefPattern  = regexp.MustCompile(`/AcroForm\s+(\d+)\s+\d+\s+R`)
+	catalogAcroFormDictPattern = regexp.MustCompile(`/AcroForm\s*<<`)
+	xfaEntryPattern            = regexp.MustCompile(`/XFA\s*[\[\d]`)
+	fieldsRefPattern           = regexp.MustCompile(`/Fields\s*\[\s*\d+\s+\d+\s+R`)
+)
+
+// DetectType reports the form type by reading only the catalog and AcroForm dictionary.
+// Unlike Detect it does not parse fields or XFA streams, so it is cheap on large documents.
+func DetectType(pdf *parse.PDF) FormType {
+	trailer := pdf.Trailer()
+	if trailer == nil || trailer.RootRef == "" {
+		return FormTypeUnknown
+	}
+
+	parts := strings.Fields(trailer.RootRef)
+	if len(parts) == 0 {
+		return FormTypeUnknown
+	}
+	rootObjNum, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return FormTypeUnknown
+	}
+
+	catalog, err := pdf.GetObject(rootObjNum)
+	if err != nil {
+		return FormTypeUnknown
+	}
+
+	acroFormDict := catalog
+	if match := catalogAcroFormRefPattern.FindSubmatch(catalog); match != nil {
+		objNum, err := strconv.Atoi(string(match[1]))
+		if err != nil {
+			return FormTypeUnknown
+		}
+		acroFormDict, err = pdf.GetObject(objNum)
+		if err != nil {
+			return FormTypeUnknown
+		}
+	} else if !catalogAcroFormDictPattern.Match(catalog) {
+		return FormTypeUnknown
+	}
+
+	if xfaEntryPattern.Match(acroFormDict) {
+		return FormTypeXFA
+	}
+	if fieldsRefPattern.Match(acroFormDict) {
+		return FormTypeAcroForm
+	}
+	return FormTypeUnknown
+}
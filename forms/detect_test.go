@@ -0,0 +1,61 @@
+package forms
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/forms/acroform"
+)
+
+func buildDetectTestPDF(t *testing.T, acroForm func(w *write.PDFWriter) string) *parse.PDF {
+	t.Helper()
+	w := write.NewPDFWriter()
+	pagesNum := w.AddObject([]byte("<</Type/Pages/Kids[]/Count 0>>"))
+
+	catalog := fmt.Sprintf("<</Type/Catalog/Pages %d 0 R", pagesNum)
+	if acroForm != nil {
+		catalog += " /AcroForm " + acroForm(w)
+	}
+	catalog += ">>"
+	w.SetRoot(w.AddObject([]byte(catalog)))
+
+	pdfBytes, err := w.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to write PDF: %v", err)
+	}
+	pdf, err := parse.Open(pdfBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse PDF: %v", err)
+	}
+	return pdf
+}
+
+func TestDetectType(t *testing.T) {
+	pdf := buildDetectTestPDF(t, nil)
+	if got := DetectType(pdf); got != FormTypeUnknown {
+		t.Errorf("Expected unknown for PDF without AcroForm, got %s", got)
+	}
+
+	pdf = buildDetectTestPDF(t, func(w *write.PDFWriter) string {
+		fb := acroform.NewFieldBuilder(w)
+		fb.AddTextField("name", []float64{72, 700, 300, 720}, 0)
+		num, err := fb.Build()
+		if err != nil {
+			t.Fatalf("Failed to build AcroForm: %v", err)
+		}
+		return fmt.Sprintf("%d 0 R", num)
+	})
+	if got := DetectType(pdf); got != FormTypeAcroForm {
+		t.Errorf("Expected acroform, got %s", got)
+	}
+
+	pdf = buildDetectTestPDF(t, func(w *write.PDFWriter) string {
+		xfaNum := w.AddObject([]byte("<</Length 0>>\nstream\n\nendstream"))
+		return fmt.Sprintf("<</Fields[] /XFA %d 0 R>>", xfaNum)
+	})
+	if got := DetectType(pdf); got != FormTypeXFA {
+		t.Errorf("Expected xfa, got %s", got)
+	}
+}
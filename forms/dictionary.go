@@ -0,0 +1,204 @@
+package forms
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// VersionedSchema pairs a form schema with the template version it was extracted from
+type VersionedSchema struct {
+	Version string
+	Schema  *types.FormSchema
+}
+
+// FieldDictionary is a canonical set of fields shared across several versions of a form
+type FieldDictionary struct {
+	Versions []string          `json:"versions"`
+	Fields   []*CanonicalField `json:"fields"`
+}
+
+// CanonicalField is a single field of the data contract with its per-version names
+type CanonicalField struct {
+	Key   string             `json:"key"`             // Canonical field key used by integrators
+	Label string             `json:"label,omitempty"` // First non-empty label seen
+	Type  types.ResponseType `json:"type"`            // Response type from the newest version
+	Names map[string]string  `json:"names"`           // Version -> field name in that version
+}
+
+// BuildFieldDictionary merges schemas from several form versions into one canonical
+// field dictionary. Schemas are processed in order; a field in a later version is
+// mapped onto an existing canonical field when its full name, normalized leaf name,
+// or normalized label matches.
+func BuildFieldDictionary(schemas []VersionedSchema) *FieldDictionary {
+	dict := &FieldDictionary{
+		Versions: make([]string, 0, len(schemas)),
+		Fields:   []*CanonicalField{},
+	}
+
+	byName := make(map[string]*CanonicalField)
+	byLeaf := make(map[string]*CanonicalField)
+	byLabel := make(map[string]*CanonicalField)
+	usedKeys := make(map[string]bool)
+
+	for _, vs := range schemas {
+		dict.Versions = append(dict.Versions, vs.Version)
+		if vs.Schema == nil {
+			continue
+		}
+
+		claimed := make(map[*CanonicalField]bool)
+		for _, q := range vs.Schema.Questions {
+			if q.Name == "" || q.Type == types.ResponseTypeButton {
+				continue
+			}
+
+			leaf := normalizeFieldKey(leafFieldName(q.Name))
+			label := normalizeFieldKey(q.Label)
+
+			field := byName[q.Name]
+			if field == nil || claimed[field] {
+				field = byLeaf[leaf]
+			}
+			if (field == nil || claimed[field]) && label != "" {
+				field = byLabel[label]
+			}
+			if field != nil && claimed[field] {
+				field = nil
+			}
+
+			if field == nil {
+				key := leaf
+				if key == "" {
+					key = normalizeFieldKey(q.Name)
+				}
+				base := key
+				for i := 2; usedKeys[key]; i++ {
+					key = base + "_" + strconv.Itoa(i)
+				}
+				usedKeys[key] = true
+
+				field = &CanonicalField{
+					Key:   key,
+					Label: q.Label,
+					Names: make(map[string]string),
+				}
+				dict.Fields = append(dict.Fields, field)
+			}
+
+			claimed[field] = true
+			field.Names[vs.Version] = q.Name
+			field.Type = q.Type
+			if field.Label == "" {
+				field.Label = q.Label
+			}
+
+			byName[q.Name] = field
+			if leaf != "" {
+				byLeaf[leaf] = field
+			}
+			if label != "" {
+				byLabel[label] = field
+			}
+		}
+	}
+
+	sort.SliceStable(dict.Fields, func(i, j int) bool {
+		return dict.Fields[i].Key < dict.Fields[j].Key
+	})
+
+	return dict
+}
+
+// Field returns the canonical field with the given key, or nil
+func (d *FieldDictionary) Field(key string) *CanonicalField {
+	for _, f := range d.Fields {
+		if f.Key == key {
+			return f
+		}
+	}
+	return nil
+}
+
+// ToVersion translates canonical form data into field names for a specific version.
+// Keys that have no field in that version are returned separately.
+func (d *FieldDictionary) ToVersion(version string, data types.FormData) (types.FormData, []string) {
+	result := make(types.FormData)
+	var unmapped []string
+
+	for key, value := range data {
+		field := d.Field(key)
+		if field == nil {
+			unmapped = append(unmapped, key)
+			continue
+		}
+		name, ok := field.Names[version]
+		if !ok {
+			unmapped = append(unmapped, key)
+			continue
+		}
+		result[name] = value
+	}
+
+	sort.Strings(unmapped)
+	return result, unmapped
+}
+
+// FromVersion translates form data keyed by a version's field names into canonical keys.
+// Field names not present in the dictionary are returned separately.
+func (d *FieldDictionary) FromVersion(version string, data types.FormData) (types.FormData, []string) {
+	names := make(map[string]string)
+	for _, f := range d.Fields {
+		if name, ok := f.Names[version]; ok {
+			names[name] = f.Key
+		}
+	}
+
+	result := make(types.FormData)
+	var unmapped []string
+
+	for name, value := range data {
+		key, ok := names[name]
+		if !ok {
+			unmapped = append(unmapped, name)
+			continue
+		}
+		result[key] = value
+	}
+
+	sort.Strings(unmapped)
+	return result, unmapped
+}
+
+// leafFieldName returns the last segment of a hierarchical field name
+func leafFieldName(name string) string {
+	if idx := strings.LastIndex(name, "."); idx != -1 {
+		name = name[idx+1:]
+	}
+	// Strip XFA occurrence index, e.g. "Name[0]"
+	if idx := strings.Index(name, "["); idx != -1 {
+		name = name[:idx]
+	}
+	return name
+}
+
+// normalizeFieldKey lowercases a name and collapses non-alphanumeric runs to underscores
+func normalizeFieldKey(s string) string {
+	var b strings.Builder
+	pendingSep := false
+	for _, r := range strings.TrimSpace(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if pendingSep && b.Len() > 0 {
+				b.WriteByte('_')
+			}
+			pendingSep = false
+			b.WriteRune(unicode.ToLower(r))
+		} else {
+			pendingSep = true
+		}
+	}
+	return b.String()
+}
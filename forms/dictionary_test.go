@@ -0,0 +1,78 @@
+package forms
+
+import (
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+func TestBuildFieldDictionary(t *testing.T) {
+	v1 := &types.FormSchema{Questions: []types.Question{
+		{Name: "form1[0].Applicant[0].Name[0]", Label: "Applicant Name", Type: types.ResponseTypeText},
+		{Name: "form1[0].Applicant[0].Phone[0]", Label: "Phone", Type: types.ResponseTypeText},
+		{Name: "form1[0].Submit[0]", Type: types.ResponseTypeButton},
+	}}
+	v2 := &types.FormSchema{Questions: []types.Question{
+		{Name: "root[0].Contact[0].Name[0]", Label: "Applicant Name", Type: types.ResponseTypeText},
+		{Name: "root[0].Contact[0].Telephone[0]", Label: "Phone", Type: types.ResponseTypeText},
+		{Name: "root[0].Contact[0].Email[0]", Label: "Email", Type: types.ResponseTypeEmail},
+	}}
+
+	dict := BuildFieldDictionary([]VersionedSchema{
+		{Version: "1.0", Schema: v1},
+		{Version: "2.0", Schema: v2},
+	})
+
+	if len(dict.Versions) != 2 {
+		t.Fatalf("Expected 2 versions, got %d", len(dict.Versions))
+	}
+	if len(dict.Fields) != 3 {
+		t.Fatalf("Expected 3 canonical fields, got %d", len(dict.Fields))
+	}
+
+	name := dict.Field("name")
+	if name == nil {
+		t.Fatal("Expected canonical field 'name'")
+	}
+	if name.Names["1.0"] != "form1[0].Applicant[0].Name[0]" || name.Names["2.0"] != "root[0].Contact[0].Name[0]" {
+		t.Errorf("Unexpected name mapping: %v", name.Names)
+	}
+
+	phone := dict.Field("phone")
+	if phone == nil || phone.Names["2.0"] != "root[0].Contact[0].Telephone[0]" {
+		t.Errorf("Expected phone to be matched by label across versions, got %+v", phone)
+	}
+
+	email := dict.Field("email")
+	if email == nil || len(email.Names) != 1 {
+		t.Errorf("Expected email to exist only in 2.0, got %+v", email)
+	}
+}
+
+func TestFieldDictionaryTranslate(t *testing.T) {
+	dict := BuildFieldDictionary([]VersionedSchema{
+		{Version: "a", Schema: &types.FormSchema{Questions: []types.Question{
+			{Name: "Doc.Name", Type: types.ResponseTypeText},
+		}}},
+		{Version: "b", Schema: &types.FormSchema{Questions: []types.Question{
+			{Name: "Document.Name", Type: types.ResponseTypeText},
+			{Name: "Document.Date", Type: types.ResponseTypeDate},
+		}}},
+	})
+
+	data, unmapped := dict.ToVersion("a", types.FormData{"name": "Jo", "date": "2024-01-01"})
+	if data["Doc.Name"] != "Jo" {
+		t.Errorf("Expected Doc.Name to be Jo, got %v", data)
+	}
+	if len(unmapped) != 1 || unmapped[0] != "date" {
+		t.Errorf("Expected 'date' to be unmapped for version a, got %v", unmapped)
+	}
+
+	canonical, unmapped := dict.FromVersion("b", types.FormData{"Document.Name": "Jo", "Other": 1})
+	if canonical["name"] != "Jo" {
+		t.Errorf("Expected canonical name Jo, got %v", canonical)
+	}
+	if len(unmapped) != 1 || unmapped[0] != "Other" {
+		t.Errorf("Expected 'Other' to be unmapped, got %v", unmapped)
+	}
+}
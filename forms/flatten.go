@@ -0,0 +1,227 @@
+package forms
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/core/manipulate"
+	"github.com/benedoc-inc/pdfer/forms/acroform"
+	"github.com/benedoc-inc/pdfer/forms/xfa"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// FlattenOptions controls Flatten
+type FlattenOptions struct {
+	Password []byte
+	Verbose  bool
+	// Appearances rebuilds the appearance streams of all AcroForm fields from
+	// their values before flattening (see acroform.RegenerateAppearances), for
+	// forms filled by tools that only set /NeedAppearances
+	Appearances bool
+	// XFA fills the AcroForm widgets of a static XFA form with the values of its
+	// XFA data before flattening, for forms whose XFA data was updated without
+	// the widgets. Dynamic XFA forms have no widgets and cannot be flattened.
+	XFA bool
+}
+
+// somSegmentIndex matches the occurrence index at the end of a SOM name segment
+var somSegmentIndex = regexp.MustCompile(`\[\d+\]$`)
+
+// Flatten bakes the widget appearances of a filled form into its pages and
+// removes the interactive form, producing a document that shows the filled
+// values but can no longer be edited, for archiving or for systems that reject
+// live forms. See manipulate.FlattenForm for what is drawn and removed. The
+// document is rewritten without the objects only the form used, so earlier
+// revisions are not kept and existing signatures are no longer valid.
+func Flatten(pdfBytes []byte, opts FlattenOptions) ([]byte, error) {
+	af, err := acroform.ExtractAcroForm(pdfBytes, opts.Password, opts.Verbose)
+	if err != nil || len(af.Fields) == 0 {
+		if streams, xfaErr := xfa.ExtractAllXFAStreams(pdfBytes, nil, xfa.DebugOptions{}, opts.Verbose); xfaErr == nil && streams.Template != nil {
+			return nil, types.NewPDFError(types.ErrCodeInvalidForm, "dynamic XFA form has no widgets to flatten")
+		}
+		return nil, types.NewPDFError(types.ErrCodeNoForms, "no form fields to flatten")
+	}
+
+	// Appearance updates are incremental, so repair a cross-reference table an
+	// in-place fill left stale first
+	if opts.XFA && af.XFA || opts.Appearances {
+		if pdfBytes, err = manipulate.Rewrite(pdfBytes, manipulate.RewriteOptions{Password: opts.Password, Verbose: opts.Verbose}); err != nil {
+			return nil, err
+		}
+	}
+	if opts.XFA && af.XFA {
+		data, err := xfaWidgetValues(pdfBytes, af, opts.Verbose)
+		if err != nil {
+			return nil, err
+		}
+		pdfBytes, err = acroform.FillFormFieldsWithOptions(pdfBytes, data, acroform.FillOptions{
+			Password:    opts.Password,
+			Verbose:     opts.Verbose,
+			Appearances: true,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fill widgets from XFA data: %w", err)
+		}
+	}
+	if opts.Appearances {
+		pdfBytes, err = acroform.RegenerateAppearances(pdfBytes, opts.Password, nil, opts.Verbose)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	flattened, err := manipulate.FlattenForm(pdfBytes, opts.Password, opts.Verbose)
+	if err != nil {
+		return nil, err
+	}
+	// Drop what only the live form used, such as the XFA packets, and the
+	// earlier revisions an appearance update appended to
+	return manipulate.Rewrite(flattened, manipulate.RewriteOptions{Password: opts.Password, Verbose: opts.Verbose})
+}
+
+// xfaWidgetValues returns the values of a static XFA form's data keyed by the
+// names of the AcroForm fields that show them. Field names are matched to data
+// paths with unnamed subforms ("#subform[0]") left out, then by their last
+// segment when only one data value has it. Check box values other than "0" or
+// empty are on.
+func xfaWidgetValues(pdfBytes []byte, af *acroform.AcroForm, verbose bool) (types.FormData, error) {
+	stream, _, err := xfa.FindXFADatasetsStream(pdfBytes, nil, verbose)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find XFA datasets: %w", err)
+	}
+	datasets, _, err := xfa.DecompressStream(stream)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress XFA datasets: %w", err)
+	}
+	values, err := xfaDataValues(datasets)
+	if err != nil {
+		return nil, err
+	}
+
+	byLast := make(map[string][]string)
+	for path := range values {
+		last := path[strings.LastIndex(path, ".")+1:]
+		byLast[last] = append(byLast[last], path)
+	}
+
+	var terminal []*acroform.Field
+	var walk func(fields []*acroform.Field)
+	walk = func(fields []*acroform.Field) {
+		for _, field := range fields {
+			if len(field.Kids) > 0 && field.Kids[0].T != "" {
+				walk(field.Kids)
+			} else {
+				terminal = append(terminal, field)
+			}
+		}
+	}
+	walk(af.Fields)
+
+	data := make(types.FormData)
+	for _, field := range terminal {
+		name := field.GetFullName()
+		path := xfaFieldPath(name)
+		value, ok := values[path]
+		if !ok {
+			last := path[strings.LastIndex(path, ".")+1:]
+			if paths := byLast[last]; len(paths) == 1 {
+				value, ok = values[paths[0]]
+			}
+		}
+		if !ok || value == "" {
+			continue
+		}
+		if field.FT == "Btn" && field.Ff&(acroform.FlagRadio|acroform.FlagPushbutton) == 0 {
+			data[name] = value != "0"
+		} else {
+			data[name] = value
+		}
+	}
+	if verbose {
+		fmt.Printf("Matched %d AcroForm fields to XFA data values\n", len(data))
+	}
+	return data, nil
+}
+
+// xfaFieldPath returns the data path an AcroForm field of a static XFA form
+// binds to: its name without unnamed subforms and with an occurrence index on
+// every segment
+func xfaFieldPath(name string) string {
+	var segments []string
+	for _, segment := range strings.Split(name, ".") {
+		if segment == "" || strings.HasPrefix(segment, "#") {
+			continue
+		}
+		if !somSegmentIndex.MatchString(segment) {
+			segment += "[0]"
+		}
+		segments = append(segments, segment)
+	}
+	return strings.Join(segments, ".")
+}
+
+// xfaDataValues returns the text of the leaf elements of the xfa:data element
+// of an XFA datasets packet, keyed by their paths with occurrence indexes, such
+// as "form1[0].Applicant[0].Name[0]"
+func xfaDataValues(datasets []byte) (map[string]string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(datasets))
+	decoder.Strict = false
+
+	values := make(map[string]string)
+	var path []string
+	var counts []map[string]int // Occurrences of each child name, per open element
+	var hasChildren []bool
+	var text strings.Builder
+	inData := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid XFA datasets: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if !inData {
+				inData = t.Name.Local == "data"
+				counts = []map[string]int{{}}
+				continue
+			}
+			siblings := counts[len(counts)-1]
+			path = append(path, t.Name.Local+"["+strconv.Itoa(siblings[t.Name.Local])+"]")
+			siblings[t.Name.Local]++
+			if len(hasChildren) > 0 {
+				hasChildren[len(hasChildren)-1] = true
+			}
+			counts = append(counts, map[string]int{})
+			hasChildren = append(hasChildren, false)
+			text.Reset()
+		case xml.CharData:
+			if inData {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if !inData {
+				continue
+			}
+			if len(path) == 0 {
+				inData = false
+				continue
+			}
+			if !hasChildren[len(hasChildren)-1] {
+				values[strings.Join(path, ".")] = strings.TrimSpace(text.String())
+			}
+			path = path[:len(path)-1]
+			counts = counts[:len(counts)-1]
+			hasChildren = hasChildren[:len(hasChildren)-1]
+			text.Reset()
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,137 @@
+package forms
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// flattenedContent checks that a flattened PDF has no form left and returns the
+// decoded data of all its streams
+func flattenedContent(t *testing.T, pdfBytes []byte) string {
+	t.Helper()
+	pdf, err := parse.Open(pdfBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse flattened PDF: %v", err)
+	}
+	var content strings.Builder
+	for _, objNum := range pdf.Objects() {
+		obj, err := pdf.GetObject(objNum)
+		if err != nil {
+			continue
+		}
+		if bytes.Contains(obj, []byte("/Widget")) || bytes.Contains(obj, []byte("/AcroForm")) || bytes.Contains(obj, []byte("xfa:datasets")) {
+			t.Errorf("Object %d should have been removed with the form: %s", objNum, obj)
+		}
+		start := bytes.Index(obj, []byte("stream\n"))
+		end := bytes.LastIndex(obj, []byte("\nendstream"))
+		if start < 0 || end < start {
+			continue
+		}
+		data := obj[start+len("stream\n") : end]
+		if r, err := zlib.NewReader(bytes.NewReader(data)); err == nil {
+			if decoded, err := io.ReadAll(r); err == nil {
+				data = decoded
+			}
+		}
+		content.Write(data)
+		content.WriteByte('\n')
+	}
+	return content.String()
+}
+
+func TestFlatten(t *testing.T) {
+	filled, err := FillWithOptions(buildTemplateTestForm(t), types.FormData{"name": "Jane Doe"}, FillOptions{})
+	if err != nil {
+		t.Fatalf("Fill failed: %v", err)
+	}
+	flattened, err := Flatten(filled, FlattenOptions{Appearances: true})
+	if err != nil {
+		t.Fatalf("Flatten failed: %v", err)
+	}
+	content := flattenedContent(t, flattened)
+	if !strings.Contains(content, "(Jane Doe) Tj") {
+		t.Errorf("Flattened PDF should show the field value:\n%s", content)
+	}
+	if !strings.Contains(content, " cm\n/FmFlat Do") {
+		t.Errorf("Page content should draw the appearance:\n%s", content)
+	}
+
+	if _, err := Flatten(flattened, FlattenOptions{}); err == nil {
+		t.Error("Expected an error flattening a PDF without a form")
+	}
+}
+
+// buildHybridTestPDF creates a static XFA form whose AcroForm has the empty text
+// field "form1[0].Name[0]" and check box "form1[0].Agree[0]", and whose XFA data
+// has values for them
+func buildHybridTestPDF(t *testing.T) []byte {
+	t.Helper()
+	w := write.NewPDFWriter()
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R /AcroForm 4 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Annots [7 0 R 8 0 R] >>",
+		"<< /Fields [6 0 R] /DA (/Helv 0 Tf 0 g) /DR << /Font << /Helv 5 0 R >> >> /XFA [(datasets) 9 0 R] >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /T (form1[0]) /Kids [7 0 R 8 0 R] >>",
+		"<< /Type /Annot /Subtype /Widget /P 3 0 R /Parent 6 0 R /FT /Tx /T (Name[0]) /Rect [72 700 272 720] /DA (/Helv 12 Tf 0 g) >>",
+		"<< /Type /Annot /Subtype /Widget /P 3 0 R /Parent 6 0 R /FT /Btn /T (Agree[0]) /Rect [72 660 86 674] /AP << /N << /1 10 0 R /Off 10 0 R >> >> /AS /Off >>",
+	}
+	for _, obj := range objects {
+		w.AddObject([]byte(obj))
+	}
+	datasets := `<xfa:datasets xmlns:xfa="http://www.xfa.org/schema/xfa-data/1.0/"><xfa:data><form1><Name>Jane Doe</Name><Agree>1</Agree></form1></xfa:data></xfa:datasets>`
+	w.AddObject([]byte(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(datasets), datasets)))
+	w.AddObject([]byte("<< /Type /XObject /Subtype /Form /BBox [0 0 14 14] /Length 3 >>\nstream\nq Q\nendstream"))
+	w.SetRoot(1)
+	pdfBytes, err := w.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to generate PDF: %v", err)
+	}
+	return pdfBytes
+}
+
+func TestFlatten_XFA(t *testing.T) {
+	flattened, err := Flatten(buildHybridTestPDF(t), FlattenOptions{XFA: true})
+	if err != nil {
+		t.Fatalf("Flatten failed: %v", err)
+	}
+	content := flattenedContent(t, flattened)
+	if !strings.Contains(content, "(Jane Doe) Tj") {
+		t.Errorf("Flattened PDF should show the XFA value of the text field:\n%s", content)
+	}
+	// The check box is drawn too, in the state its XFA value switched it to
+	if n := strings.Count(content, " Do\n"); n != 2 {
+		t.Errorf("Expected 2 appearances drawn, got %d:\n%s", n, content)
+	}
+}
+
+func TestXFADataValues(t *testing.T) {
+	values, err := xfaDataValues([]byte(`<xfa:datasets xmlns:xfa="http://www.xfa.org/schema/xfa-data/1.0/">` +
+		`<xfa:data><form1><Item><Qty>2</Qty></Item><Item><Qty>5</Qty></Item><Note/></form1></xfa:data>` +
+		`<dd:dataDescription xmlns:dd="http://ns.adobe.com/data-description/"><form1><Other/></form1></dd:dataDescription></xfa:datasets>`))
+	if err != nil {
+		t.Fatalf("xfaDataValues failed: %v", err)
+	}
+	want := map[string]string{"form1[0].Item[0].Qty[0]": "2", "form1[0].Item[1].Qty[0]": "5", "form1[0].Note[0]": ""}
+	if len(values) != len(want) {
+		t.Errorf("Expected %v, got %v", want, values)
+	}
+	for path, value := range want {
+		if got, ok := values[path]; !ok || got != value {
+			t.Errorf("%s = %q, want %q", path, got, value)
+		}
+	}
+
+	if got := xfaFieldPath("form1[0].#subform[0].Item[1].Qty"); got != "form1[0].Item[1].Qty[0]" {
+		t.Errorf("xfaFieldPath = %q", got)
+	}
+}
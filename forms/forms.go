@@ -115,7 +115,7 @@ func Detect(pdfBytes []byte, password []byte, verbose bool) (FormType, error) {
 	}
 
 	// Try XFA
-	streams, err := xfa.ExtractAllXFAStreams(pdfBytes, nil, verbose)
+	streams, err := xfa.ExtractAllXFAStreams(pdfBytes, nil, xfa.DebugOptions{}, verbose)
 	if err == nil && streams.Template != nil && len(streams.Template.Data) > 0 {
 		return FormTypeXFA, nil
 	}
@@ -137,7 +137,7 @@ func Extract(pdfBytes []byte, password []byte, verbose bool) (Form, error) {
 	}
 
 	// Try XFA
-	streams, err := xfa.ExtractAllXFAStreams(pdfBytes, nil, verbose)
+	streams, err := xfa.ExtractAllXFAStreams(pdfBytes, nil, xfa.DebugOptions{}, verbose)
 	if err == nil && streams.Template != nil && len(streams.Template.Data) > 0 {
 		// Parse XFA form
 		formSchema, err := xfa.ParseXFAForm(string(streams.Template.Data), verbose)
@@ -161,6 +161,43 @@ func Extract(pdfBytes []byte, password []byte, verbose bool) (Form, error) {
 	return nil, types.NewPDFError(types.ErrCodeNoForms, "no forms found in PDF")
 }
 
+// FillOptions controls how FillWithOptions fills a form
+type FillOptions struct {
+	Password    []byte
+	Verbose     bool
+	Lock        types.FieldLock // Fields to make read-only after filling
+	ClearFields []string        // Fields to blank, like fields with a nil value in the data
+	AttachData  bool            // Embed the submitted data, and for AcroForms a fill report, as attachments
+	Provenance  *Provenance     // Record which pipeline produced the document in its XMP metadata
+	Incremental bool            // Append the changes as an incremental update, keeping existing signatures valid
+	Appearances bool            // Rebuild the appearance streams of filled AcroForm fields (see acroform.FillOptions)
+}
+
+// Names of the attachments FillWithOptions embeds when AttachData is set
+const (
+	DataAttachmentName   = "form-data.json"
+	ReportAttachmentName = "fill-report.json"
+)
+
+// FillWithLock fills an AcroForm or XFA form and makes the filled fields, or all
+// fields, read-only: the ReadOnly field flag for AcroForms, access="readOnly" for
+// XFA. This produces a final, non-editable submission without flattening.
+func FillWithLock(pdfBytes []byte, data types.FormData, password []byte, lock types.FieldLock, verbose bool) ([]byte, error) {
+	return FillWithOptions(pdfBytes, data, FillOptions{Password: password, Verbose: verbose, Lock: lock})
+}
+
+// FillWithOptions fills an AcroForm or XFA form. With AttachData, the submitted
+// data is embedded as JSON with AFRelationship /Data, so reviewers can retrieve
+// exactly what was filled; AcroForm fills also embed the acroform.FillReport.
+// With Provenance, a provenance block is added to the XMP metadata.
+func FillWithOptions(pdfBytes []byte, data types.FormData, opts FillOptions) ([]byte, error) {
+	t, err := NewTemplate(pdfBytes, opts.Password, opts.Verbose)
+	if err != nil {
+		return nil, err
+	}
+	return t.Fill(data, opts)
+}
+
 // ExtractAcroForm extracts an AcroForm (type-specific)
 func ExtractAcroForm(pdfBytes []byte, password []byte, verbose bool) (*acroform.AcroForm, error) {
 	return acroform.ExtractAcroForm(pdfBytes, password, verbose)
@@ -169,7 +206,7 @@ func ExtractAcroForm(pdfBytes []byte, password []byte, verbose bool) (*acroform.
 // ExtractXFA extracts XFA form data (type-specific)
 // Returns the FormSchema and Datasets separately
 func ExtractXFA(pdfBytes []byte, password []byte, verbose bool) (*types.FormSchema, *types.XFADatasets, error) {
-	streams, err := xfa.ExtractAllXFAStreams(pdfBytes, nil, verbose)
+	streams, err := xfa.ExtractAllXFAStreams(pdfBytes, nil, xfa.DebugOptions{}, verbose)
 	if err != nil {
 		return nil, nil, err
 	}
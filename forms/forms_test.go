@@ -0,0 +1,75 @@
+package forms
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/forms/acroform"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+func TestFillWithOptions_AttachData(t *testing.T) {
+	builder := write.NewSimplePDFBuilder()
+	page := builder.AddPage(write.PageSizeLetter)
+	formBuilder := acroform.NewFormBuilder(builder)
+	formBuilder.AddTextField("name", []float64{72, 700, 300, 720}, 0)
+	if _, err := formBuilder.BuildForm(); err != nil {
+		t.Fatalf("Failed to build form: %v", err)
+	}
+	builder.FinalizePage(page)
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to generate PDF: %v", err)
+	}
+
+	data := types.FormData{"name": "Jane Doe"}
+	filled, err := FillWithOptions(pdfBytes, data, FillOptions{AttachData: true})
+	if err != nil {
+		t.Fatalf("FillWithOptions failed: %v", err)
+	}
+	for _, name := range []string{DataAttachmentName, ReportAttachmentName} {
+		if !bytes.Contains(filled, []byte("("+name+")")) {
+			t.Errorf("Attachment %s missing", name)
+		}
+	}
+	if !bytes.Contains(filled, []byte("/EmbeddedFiles")) || !bytes.Contains(filled, []byte("/AFRelationship /Data")) {
+		t.Error("Attachments not registered in the catalog")
+	}
+
+	plain, err := FillWithOptions(pdfBytes, data, FillOptions{})
+	if err != nil {
+		t.Fatalf("FillWithOptions failed: %v", err)
+	}
+	if bytes.Contains(plain, []byte("/EmbeddedFiles")) {
+		t.Error("Attachments embedded without AttachData")
+	}
+}
+
+func TestFillWithOptions_Incremental(t *testing.T) {
+	builder := write.NewSimplePDFBuilder()
+	page := builder.AddPage(write.PageSizeLetter)
+	formBuilder := acroform.NewFormBuilder(builder)
+	formBuilder.AddTextField("name", []float64{72, 700, 300, 720}, 0)
+	if _, err := formBuilder.BuildForm(); err != nil {
+		t.Fatalf("Failed to build form: %v", err)
+	}
+	builder.FinalizePage(page)
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to generate PDF: %v", err)
+	}
+
+	data := types.FormData{"name": "Jane Doe"}
+	filled, err := FillWithOptions(pdfBytes, data, FillOptions{Incremental: true})
+	if err != nil {
+		t.Fatalf("FillWithOptions failed: %v", err)
+	}
+	if !bytes.HasPrefix(filled, pdfBytes) || !bytes.Contains(filled[len(pdfBytes):], []byte("(Jane Doe)")) {
+		t.Error("Expected the filled field appended after the original bytes")
+	}
+
+	if _, err := FillWithOptions(pdfBytes, data, FillOptions{Incremental: true, AttachData: true}); err == nil {
+		t.Error("Expected an error for an incremental fill that attaches data")
+	}
+}
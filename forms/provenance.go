@@ -0,0 +1,166 @@
+package forms
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benedoc-inc/pdfer/core/dates"
+	"github.com/benedoc-inc/pdfer/core/manipulate"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// ProvenanceNamespace is the XMP namespace of the provenance block
+const ProvenanceNamespace = "https://github.com/benedoc-inc/pdfer/ns/fill/1.0/"
+
+// Provenance records which pipeline filled a document. Set FillOptions.Provenance
+// to stamp it into the XMP metadata after a fill, and read it back with
+// ReadProvenance.
+type Provenance struct {
+	Tool       string    `json:"tool"`               // Producing tool and version; defaults to pdfer's module version
+	Pipeline   string    `json:"pipeline,omitempty"` // Caller-defined pipeline or job identifier
+	Timestamp  time.Time `json:"timestamp"`          // Time of the fill; defaults to the current time
+	DataHash   string    `json:"data_hash"`          // HashFormData of the submitted data; always computed
+	FieldCount int       `json:"field_count"`        // Number of fields in the submitted data; always computed
+}
+
+// Matches reports whether data is the data the document was filled with
+func (p *Provenance) Matches(data types.FormData) bool {
+	hash, err := HashFormData(data)
+	return err == nil && hash == p.DataHash
+}
+
+// HashFormData returns the SHA-256 of the data's JSON encoding, whose object
+// keys are sorted, as "sha256:<hex>"
+func HashFormData(data types.FormData) (string, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode form data: %w", err)
+	}
+	sum := sha256.Sum256(encoded)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// ReadProvenance returns the provenance block of a filled document, or nil when
+// it has none
+func ReadProvenance(pdfBytes []byte, password []byte) (*Provenance, error) {
+	m, err := manipulate.NewPDFManipulator(pdfBytes, password, false)
+	if err != nil {
+		return nil, err
+	}
+	xmp, err := m.XMPMetadata()
+	if err != nil {
+		return nil, err
+	}
+	return parseProvenance(xmp)
+}
+
+// stampProvenance completes p for the fill of data and records it in the XMP
+// metadata, replacing an earlier provenance block and keeping other metadata
+func stampProvenance(m *manipulate.PDFManipulator, data types.FormData, p Provenance) error {
+	hash, err := HashFormData(data)
+	if err != nil {
+		return err
+	}
+	p.DataHash = hash
+	p.FieldCount = len(data)
+	if p.Tool == "" {
+		p.Tool = toolVersion()
+	}
+	if p.Timestamp.IsZero() {
+		p.Timestamp = time.Now()
+	}
+
+	xmp, err := m.XMPMetadata()
+	if err != nil {
+		return err
+	}
+	return m.SetXMPMetadata(setProvenanceXMP(xmp, p))
+}
+
+// provenanceDescription matches the rdf:Description holding a provenance block
+var provenanceDescription = regexp.MustCompile(`(?s)<rdf:Description[^>]*xmlns:pdferfill="` + regexp.QuoteMeta(ProvenanceNamespace) + `".*?</rdf:Description>\s*`)
+
+// setProvenanceXMP adds a provenance block to an XMP packet, creating the packet
+// when xmp has no rdf:RDF element
+func setProvenanceXMP(xmp []byte, p Provenance) []byte {
+	var desc strings.Builder
+	desc.WriteString(`<rdf:Description rdf:about="" xmlns:pdferfill="` + ProvenanceNamespace + `">` + "\n")
+	property := func(name, value string) {
+		desc.WriteString("   <pdferfill:" + name + ">")
+		xml.EscapeText(&desc, []byte(value))
+		desc.WriteString("</pdferfill:" + name + ">\n")
+	}
+	property("Tool", p.Tool)
+	if p.Pipeline != "" {
+		property("Pipeline", p.Pipeline)
+	}
+	property("Timestamp", dates.FormatISO(p.Timestamp.UTC()))
+	property("DataHash", p.DataHash)
+	property("FieldCount", strconv.Itoa(p.FieldCount))
+	desc.WriteString("  </rdf:Description>\n")
+
+	packet := provenanceDescription.ReplaceAllString(string(xmp), "")
+	if end := strings.LastIndex(packet, "</rdf:RDF>"); end != -1 {
+		return []byte(packet[:end] + " " + desc.String() + " " + packet[end:])
+	}
+	return []byte(`<?xpacket begin="` + "\ufeff" + `" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  ` + desc.String() + ` </rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`)
+}
+
+// parseProvenance reads the provenance block from an XMP packet
+func parseProvenance(xmp []byte) (*Provenance, error) {
+	block := provenanceDescription.Find(xmp)
+	if block == nil {
+		return nil, nil
+	}
+	var desc struct {
+		Tool       string `xml:"https://github.com/benedoc-inc/pdfer/ns/fill/1.0/ Tool"`
+		Pipeline   string `xml:"https://github.com/benedoc-inc/pdfer/ns/fill/1.0/ Pipeline"`
+		Timestamp  string `xml:"https://github.com/benedoc-inc/pdfer/ns/fill/1.0/ Timestamp"`
+		DataHash   string `xml:"https://github.com/benedoc-inc/pdfer/ns/fill/1.0/ DataHash"`
+		FieldCount int    `xml:"https://github.com/benedoc-inc/pdfer/ns/fill/1.0/ FieldCount"`
+	}
+	// The block is parsed on its own, so declare the rdf prefix it uses
+	block = []byte(strings.Replace(string(block), "<rdf:Description", `<rdf:Description xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"`, 1))
+	if err := xml.Unmarshal(block, &desc); err != nil {
+		return nil, fmt.Errorf("invalid provenance block: %w", err)
+	}
+	timestamp, err := dates.ParseISO(desc.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid provenance timestamp: %w", err)
+	}
+	return &Provenance{
+		Tool:       desc.Tool,
+		Pipeline:   desc.Pipeline,
+		Timestamp:  timestamp,
+		DataHash:   desc.DataHash,
+		FieldCount: desc.FieldCount,
+	}, nil
+}
+
+// toolVersion names pdfer and, when the binary was built with module
+// information, its version
+func toolVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "pdfer"
+	}
+	for _, mod := range append([]*debug.Module{&info.Main}, info.Deps...) {
+		if mod.Path == "github.com/benedoc-inc/pdfer" && mod.Version != "" && mod.Version != "(devel)" {
+			return "pdfer " + mod.Version
+		}
+	}
+	return "pdfer"
+}
@@ -0,0 +1,79 @@
+package forms
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/forms/acroform"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+func TestFillWithOptions_Provenance(t *testing.T) {
+	builder := write.NewSimplePDFBuilder()
+	page := builder.AddPage(write.PageSizeLetter)
+	formBuilder := acroform.NewFormBuilder(builder)
+	formBuilder.AddTextField("name", []float64{72, 700, 300, 720}, 0)
+	formBuilder.AddTextField("city", []float64{72, 660, 300, 680}, 0)
+	if _, err := formBuilder.BuildForm(); err != nil {
+		t.Fatalf("Failed to build form: %v", err)
+	}
+	builder.FinalizePage(page)
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to generate PDF: %v", err)
+	}
+
+	if p, err := ReadProvenance(pdfBytes, nil); err != nil || p != nil {
+		t.Fatalf("ReadProvenance on a blank form = %+v, %v", p, err)
+	}
+
+	data := types.FormData{"name": "Jane <Doe>", "city": "Springfield"}
+	stamp := time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC)
+	filled, err := FillWithOptions(pdfBytes, data, FillOptions{
+		AttachData: true,
+		Provenance: &Provenance{Pipeline: "intake/v2", Timestamp: stamp},
+	})
+	if err != nil {
+		t.Fatalf("FillWithOptions failed: %v", err)
+	}
+
+	p, err := ReadProvenance(filled, nil)
+	if err != nil || p == nil {
+		t.Fatalf("ReadProvenance = %+v, %v", p, err)
+	}
+	if !strings.HasPrefix(p.Tool, "pdfer") || p.Pipeline != "intake/v2" || !p.Timestamp.Equal(stamp) || p.FieldCount != 2 {
+		t.Errorf("Unexpected provenance %+v", p)
+	}
+	if !p.Matches(data) {
+		t.Error("Provenance does not match the fill data")
+	}
+	if p.Matches(types.FormData{"name": "Jane <Doe>"}) {
+		t.Error("Provenance matches different data")
+	}
+
+	// A second fill replaces the block rather than adding another
+	refilled, err := FillWithOptions(filled, types.FormData{"name": "John"}, FillOptions{Provenance: &Provenance{}})
+	if err != nil {
+		t.Fatalf("FillWithOptions failed: %v", err)
+	}
+	p, err = ReadProvenance(refilled, nil)
+	if err != nil || p == nil || p.FieldCount != 1 || p.Pipeline != "" {
+		t.Errorf("ReadProvenance after refill = %+v, %v", p, err)
+	}
+}
+
+func TestSetProvenanceXMP_KeepsMetadata(t *testing.T) {
+	existing := `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` +
+		`<rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:format>application/pdf</dc:format></rdf:Description>` +
+		`</rdf:RDF></x:xmpmeta>`
+	xmp := setProvenanceXMP([]byte(existing), Provenance{Tool: "test", DataHash: "sha256:00", Timestamp: time.Unix(0, 0)})
+	if !strings.Contains(string(xmp), "<dc:format>application/pdf</dc:format>") {
+		t.Error("Existing metadata lost")
+	}
+	p, err := parseProvenance(xmp)
+	if err != nil || p == nil || p.Tool != "test" {
+		t.Errorf("parseProvenance = %+v, %v", p, err)
+	}
+}
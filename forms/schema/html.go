@@ -0,0 +1,318 @@
+// Package schema renders extracted form schemas for use outside the PDF, such as
+// web pages that capture the data a form is later filled with
+package schema
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// HTMLOptions controls how ToHTML renders a form
+type HTMLOptions struct {
+	Action      string // URL the form submits to (default: none, the current page)
+	Method      string // HTTP method (default: "post")
+	SubmitLabel string // Text of the submit button (default: "Submit")
+	IDPrefix    string // Prefix of element IDs, to keep several forms on one page apart
+}
+
+// somIndexPattern matches the occurrence indexes of XFA names, e.g. "[0]"
+var somIndexPattern = regexp.MustCompile(`\[\d+\]`)
+
+// ToHTML renders a form schema as an HTML form for web capture. Each control is
+// named after its question's field name, so the submitted values are keyed like
+// the types.FormData that fills the PDF.
+//
+// Questions are grouped in fieldsets by section: the "section" property when a
+// question has one, otherwise the parent of its hierarchical name, such as
+// "Applicant" for "form1.Applicant.Name". Radio groups and check box groups are
+// fieldsets of their own. Every control has a label; required ones are marked
+// with an asterisk and the required attribute, and the length, value range and
+// pattern of the validation rules become minlength, maxlength, min, max and
+// pattern attributes, with the rules' error message as the title. Buttons and
+// signature fields, which have no value to capture, are left out.
+func ToHTML(s *types.FormSchema, opts HTMLOptions) (string, error) {
+	if s == nil {
+		return "", fmt.Errorf("schema is nil")
+	}
+	method := opts.Method
+	if method == "" {
+		method = "post"
+	}
+	submit := opts.SubmitLabel
+	if submit == "" {
+		submit = "Submit"
+	}
+
+	var b strings.Builder
+	b.WriteString(`<form class="pdfer-form"`)
+	if opts.Action != "" {
+		b.WriteString(` action="` + html.EscapeString(opts.Action) + `"`)
+	}
+	b.WriteString(` method="` + html.EscapeString(method) + `">` + "\n")
+	if s.Metadata.Title != "" {
+		b.WriteString("<h1>" + html.EscapeString(s.Metadata.Title) + "</h1>\n")
+	}
+	if s.Metadata.Description != "" {
+		b.WriteString("<p>" + html.EscapeString(s.Metadata.Description) + "</p>\n")
+	}
+
+	ids := make(map[string]int)
+	section := ""
+	for i := range s.Questions {
+		q := &s.Questions[i]
+		if q.Type == types.ResponseTypeButton || q.Type == types.ResponseTypeSignature {
+			continue
+		}
+		if next := questionSection(q); next != section {
+			if section != "" {
+				b.WriteString("</fieldset>\n")
+			}
+			if next != "" {
+				b.WriteString(`<fieldset class="pdfer-section">` + "\n<legend>" + html.EscapeString(sectionLegend(next)) + "</legend>\n")
+			}
+			section = next
+		}
+		writeQuestion(&b, q, elementID(opts.IDPrefix, q, ids))
+	}
+	if section != "" {
+		b.WriteString("</fieldset>\n")
+	}
+
+	b.WriteString(`<button type="submit">` + html.EscapeString(submit) + "</button>\n</form>\n")
+	return b.String(), nil
+}
+
+// questionSection returns the section of a question: its "section" property, or
+// the parent of its name without XFA occurrence indexes
+func questionSection(q *types.Question) string {
+	if section, ok := q.Properties["section"].(string); ok {
+		return section
+	}
+	name := somIndexPattern.ReplaceAllString(q.Name, "")
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[:i]
+	}
+	return ""
+}
+
+// sectionLegend returns the legend of a section: the last part of its path
+func sectionLegend(section string) string {
+	if i := strings.LastIndex(section, "."); i >= 0 {
+		return section[i+1:]
+	}
+	return section
+}
+
+// elementID returns a unique element ID for a question, from its ID or name
+func elementID(prefix string, q *types.Question, seen map[string]int) string {
+	base := q.ID
+	if base == "" {
+		base = q.Name
+	}
+	var b strings.Builder
+	b.WriteString(prefix)
+	for _, r := range base {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	id := b.String()
+	seen[id]++
+	if n := seen[id]; n > 1 {
+		id += "-" + strconv.Itoa(n)
+	}
+	return id
+}
+
+// questionLabel returns the text that labels a question: its label, or its
+// field name without the path
+func questionLabel(q *types.Question) string {
+	if q.Label != "" {
+		return q.Label
+	}
+	return sectionLegend(somIndexPattern.ReplaceAllString(q.Name, ""))
+}
+
+// writeQuestion writes the control of a question with its label and description
+func writeQuestion(b *strings.Builder, q *types.Question, id string) {
+	b.WriteString(`<div class="pdfer-field"`)
+	if q.Hidden {
+		b.WriteString(" hidden")
+	}
+	b.WriteString(">\n")
+
+	labelHTML := html.EscapeString(questionLabel(q))
+	if q.Required {
+		labelHTML += ` <span class="pdfer-required" aria-hidden="true">*</span>`
+	}
+	describedBy := ""
+	if q.Description != "" {
+		describedBy = id + "-desc"
+	}
+	attrs := controlAttributes(q, describedBy)
+	name := html.EscapeString(q.Name)
+	def := defaultValue(q)
+
+	switch {
+	case q.Type == types.ResponseTypeRadio || q.Type == types.ResponseTypeCheckbox && len(q.Options) > 0:
+		inputType := "radio"
+		if q.Type == types.ResponseTypeCheckbox {
+			inputType = "checkbox"
+		}
+		b.WriteString(`<fieldset id="` + id + `"`)
+		if describedBy != "" {
+			b.WriteString(` aria-describedby="` + describedBy + `"`)
+		}
+		b.WriteString(">\n<legend>" + labelHTML + "</legend>\n")
+		for i, opt := range q.Options {
+			optID := id + "-" + strconv.Itoa(i)
+			b.WriteString(`<input type="` + inputType + `" id="` + optID + `" name="` + name + `" value="` + html.EscapeString(opt.Value) + `"`)
+			if opt.Selected || def != "" && opt.Value == def {
+				b.WriteString(" checked")
+			}
+			// A required check box group is satisfied by any one box, which
+			// browsers cannot check, so only radio buttons carry required
+			if inputType == "radio" && q.Required {
+				b.WriteString(` required`)
+			}
+			if q.ReadOnly {
+				b.WriteString(" disabled")
+			}
+			b.WriteString(">\n")
+			b.WriteString(`<label for="` + optID + `">` + html.EscapeString(optionLabel(opt)) + "</label>\n")
+		}
+		b.WriteString("</fieldset>\n")
+
+	case q.Type == types.ResponseTypeCheckbox:
+		b.WriteString(`<input type="checkbox" id="` + id + `" name="` + name + `" value="Yes"`)
+		if def != "" && def != "Off" && def != "false" && def != "0" {
+			b.WriteString(" checked")
+		}
+		if q.ReadOnly {
+			b.WriteString(" disabled")
+		}
+		b.WriteString(attrs + ">\n")
+		b.WriteString(`<label for="` + id + `">` + labelHTML + "</label>\n")
+
+	case q.Type == types.ResponseTypeSelect:
+		b.WriteString(`<label for="` + id + `">` + labelHTML + "</label>\n")
+		b.WriteString(`<select id="` + id + `" name="` + name + `"`)
+		if q.ReadOnly {
+			b.WriteString(" disabled")
+		}
+		b.WriteString(attrs + ">\n")
+		selected := def != ""
+		for _, opt := range q.Options {
+			selected = selected || opt.Selected
+		}
+		if !selected || !q.Required {
+			b.WriteString(`<option value=""></option>` + "\n")
+		}
+		for _, opt := range q.Options {
+			b.WriteString(`<option value="` + html.EscapeString(opt.Value) + `"`)
+			if opt.Selected || def != "" && opt.Value == def {
+				b.WriteString(" selected")
+			}
+			b.WriteString(">" + html.EscapeString(optionLabel(opt)) + "</option>\n")
+		}
+		b.WriteString("</select>\n")
+
+	case q.Type == types.ResponseTypeTextarea:
+		b.WriteString(`<label for="` + id + `">` + labelHTML + "</label>\n")
+		b.WriteString(`<textarea id="` + id + `" name="` + name + `"`)
+		if q.ReadOnly {
+			b.WriteString(" readonly")
+		}
+		b.WriteString(attrs + ">" + html.EscapeString(def) + "</textarea>\n")
+
+	default:
+		inputType := map[types.ResponseType]string{
+			types.ResponseTypeNumber: "number",
+			types.ResponseTypeDate:   "date",
+			types.ResponseTypeEmail:  "email",
+		}[q.Type]
+		if inputType == "" {
+			inputType = "text"
+		}
+		b.WriteString(`<label for="` + id + `">` + labelHTML + "</label>\n")
+		b.WriteString(`<input type="` + inputType + `" id="` + id + `" name="` + name + `"`)
+		if def != "" {
+			b.WriteString(` value="` + html.EscapeString(def) + `"`)
+		}
+		if inputType == "number" {
+			b.WriteString(` step="any"`)
+		}
+		if q.ReadOnly {
+			b.WriteString(" readonly")
+		}
+		b.WriteString(attrs + ">\n")
+	}
+
+	if q.Description != "" {
+		b.WriteString(`<p id="` + describedBy + `" class="pdfer-description">` + html.EscapeString(q.Description) + "</p>\n")
+	}
+	b.WriteString("</div>\n")
+}
+
+// controlAttributes returns the required, validation and description attributes
+// of a single control, each with a leading space
+func controlAttributes(q *types.Question, describedBy string) string {
+	var b strings.Builder
+	if q.Required {
+		b.WriteString(` required aria-required="true"`)
+	}
+	if describedBy != "" {
+		b.WriteString(` aria-describedby="` + describedBy + `"`)
+	}
+	v := q.Validation
+	if v == nil {
+		return b.String()
+	}
+	textual := q.Type != types.ResponseTypeNumber && q.Type != types.ResponseTypeDate &&
+		q.Type != types.ResponseTypeSelect && q.Type != types.ResponseTypeCheckbox
+	if textual && v.MinLength != nil {
+		b.WriteString(` minlength="` + strconv.Itoa(*v.MinLength) + `"`)
+	}
+	if textual && v.MaxLength != nil {
+		b.WriteString(` maxlength="` + strconv.Itoa(*v.MaxLength) + `"`)
+	}
+	if q.Type == types.ResponseTypeNumber && v.MinValue != nil {
+		b.WriteString(` min="` + strconv.FormatFloat(*v.MinValue, 'f', -1, 64) + `"`)
+	}
+	if q.Type == types.ResponseTypeNumber && v.MaxValue != nil {
+		b.WriteString(` max="` + strconv.FormatFloat(*v.MaxValue, 'f', -1, 64) + `"`)
+	}
+	// The pattern attribute applies to text inputs only, and must compile
+	if textual && q.Type != types.ResponseTypeTextarea && v.Pattern != "" {
+		if _, err := regexp.Compile(v.Pattern); err == nil {
+			b.WriteString(` pattern="` + html.EscapeString(v.Pattern) + `"`)
+		}
+	}
+	if v.ErrorMessage != "" {
+		b.WriteString(` title="` + html.EscapeString(v.ErrorMessage) + `"`)
+	}
+	return b.String()
+}
+
+// defaultValue returns a question's default value as text, or "" for none
+func defaultValue(q *types.Question) string {
+	if q.Default == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", q.Default)
+}
+
+// optionLabel returns the display text of an option
+func optionLabel(opt types.Option) string {
+	if opt.Label != "" {
+		return opt.Label
+	}
+	return opt.Value
+}
@@ -0,0 +1,108 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+func intPtr(v int) *int { return &v }
+
+func floatPtr(v float64) *float64 { return &v }
+
+// testSchema returns a schema with two sections and one question of each kind
+func testSchema() *types.FormSchema {
+	return &types.FormSchema{
+		Metadata: types.FormMetadata{Title: "Intake <v2>", FormType: "XFA"},
+		Questions: []types.Question{
+			{ID: "name", Name: "form1.Applicant[0].Name[0]", Label: "Full name", Type: types.ResponseTypeText, Required: true,
+				Description: "As on your passport",
+				Validation:  &types.ValidationRules{MaxLength: intPtr(40), Pattern: "[A-Za-z ]+", ErrorMessage: "Letters only"}},
+			{ID: "age", Name: "form1.Applicant[0].Age[0]", Type: types.ResponseTypeNumber,
+				Validation: &types.ValidationRules{MinValue: floatPtr(18), MaxValue: floatPtr(120)}},
+			{ID: "born", Name: "form1.Applicant[0].Born[0]", Label: "Date of birth", Type: types.ResponseTypeDate},
+			{ID: "plan", Name: "form1.Plan[0].Choice[0]", Label: "Plan", Type: types.ResponseTypeRadio, Required: true,
+				Options: []types.Option{{Value: "basic", Label: "Basic"}, {Value: "plus", Label: "Plus"}}, Default: "plus"},
+			{ID: "country", Name: "form1.Plan[0].Country[0]", Label: "Country", Type: types.ResponseTypeSelect,
+				Options: []types.Option{{Value: "NO", Label: "Norway"}, {Value: "SE", Label: "Sweden", Selected: true}}},
+			{ID: "agree", Name: "form1.Plan[0].Agree[0]", Label: "I agree", Type: types.ResponseTypeCheckbox, Required: true},
+			{ID: "notes", Name: "form1.Plan[0].Notes[0]", Type: types.ResponseTypeTextarea, ReadOnly: true, Default: "a & b"},
+			{ID: "sign", Name: "form1.Plan[0].Signature[0]", Type: types.ResponseTypeSignature},
+			{ID: "print", Name: "form1.Plan[0].Print[0]", Type: types.ResponseTypeButton},
+		},
+	}
+}
+
+func TestToHTML(t *testing.T) {
+	out, err := ToHTML(testSchema(), HTMLOptions{Action: "/submit?form=1&v=2"})
+	if err != nil {
+		t.Fatalf("ToHTML failed: %v", err)
+	}
+
+	for _, tag := range []string{"fieldset", "div", "select", "form"} {
+		if open, closed := strings.Count(out, "<"+tag+">")+strings.Count(out, "<"+tag+" "), strings.Count(out, "</"+tag+">"); open != closed {
+			t.Errorf("%d <%s> elements opened and %d closed", open, tag, closed)
+		}
+	}
+
+	for _, want := range []string{
+		`<form class="pdfer-form" action="/submit?form=1&amp;v=2" method="post">`,
+		"<h1>Intake &lt;v2&gt;</h1>",
+		"<legend>Applicant</legend>",
+		"<legend>Plan</legend>",
+		`<label for="name">Full name <span class="pdfer-required" aria-hidden="true">*</span></label>`,
+		`<input type="text" id="name" name="form1.Applicant[0].Name[0]" required aria-required="true" aria-describedby="name-desc" maxlength="40" pattern="[A-Za-z ]+" title="Letters only">`,
+		`<p id="name-desc" class="pdfer-description">As on your passport</p>`,
+		`<label for="age">Age</label>`,
+		`<input type="number" id="age" name="form1.Applicant[0].Age[0]" step="any" min="18" max="120">`,
+		`<input type="date" id="born"`,
+		`<input type="radio" id="plan-1" name="form1.Plan[0].Choice[0]" value="plus" checked required>`,
+		`<label for="plan-0">Basic</label>`,
+		`<option value="SE" selected>Sweden</option>`,
+		`<input type="checkbox" id="agree" name="form1.Plan[0].Agree[0]" value="Yes" required aria-required="true">`,
+		`<textarea id="notes" name="form1.Plan[0].Notes[0]" readonly>a &amp; b</textarea>`,
+		`<button type="submit">Submit</button>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Output missing %s\n%s", want, out)
+		}
+	}
+	if strings.Count(out, `<fieldset class="pdfer-section">`) != 2 {
+		t.Errorf("Expected 2 sections:\n%s", out)
+	}
+	if strings.Contains(out, "Signature") || strings.Contains(out, "Print") {
+		t.Errorf("Buttons and signatures should be left out:\n%s", out)
+	}
+}
+
+func TestToHTML_SectionsAndIDs(t *testing.T) {
+	s := &types.FormSchema{Questions: []types.Question{
+		{ID: "a b", Name: "first", Type: types.ResponseTypeText, Properties: map[string]interface{}{"section": "Contact"}},
+		{ID: "a b", Name: "second", Type: types.ResponseTypeEmail, Properties: map[string]interface{}{"section": "Contact"}},
+		{ID: "c", Name: "third", Type: types.ResponseTypeText, Hidden: true},
+	}}
+	out, err := ToHTML(s, HTMLOptions{IDPrefix: "f1-", SubmitLabel: "Send"})
+	if err != nil {
+		t.Fatalf("ToHTML failed: %v", err)
+	}
+	for _, want := range []string{
+		"<legend>Contact</legend>",
+		`id="f1-a_b"`,
+		`<input type="email" id="f1-a_b-2" name="second">`,
+		`<div class="pdfer-field" hidden>`,
+		`<button type="submit">Send</button>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Output missing %s\n%s", want, out)
+		}
+	}
+	// The section closes before the question without one
+	if i, j := strings.Index(out, "</fieldset>"), strings.Index(out, `name="third"`); i < 0 || i > j {
+		t.Errorf("Section should close before an unsectioned question:\n%s", out)
+	}
+
+	if _, err := ToHTML(nil, HTMLOptions{}); err == nil {
+		t.Error("Expected an error for a nil schema")
+	}
+}
@@ -0,0 +1,349 @@
+package forms
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/benedoc-inc/pdfer/core/manipulate"
+	"github.com/benedoc-inc/pdfer/forms/acroform"
+	"github.com/benedoc-inc/pdfer/forms/xfa"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// Template is a form PDF parsed and indexed once for many fills. For AcroForms
+// the cross-reference data, field tree and field locations are kept; for XFA
+// forms, the decompressed datasets stream. A Template is not modified by Fill
+// and can be filled from multiple goroutines.
+type Template struct {
+	pdfBytes    []byte
+	password    []byte
+	hash        string
+	formType    FormType
+	acroForm    *acroform.Template
+	xfa         *xfa.Template
+	xfaTemplate []byte // XFA template stream, parsed into the schema on first use
+
+	schemaOnce sync.Once
+	schema     *types.FormSchema
+	schemaErr  error
+}
+
+// TemplateHash returns the hex SHA-256 of a form PDF's content, the key a Cache
+// stores its template under
+func TemplateHash(pdfBytes []byte) string {
+	sum := sha256.Sum256(pdfBytes)
+	return hex.EncodeToString(sum[:])
+}
+
+// NewTemplate prepares a form PDF for repeated fills, detecting the form type
+// like Detect. pdfBytes must not be modified while the template is in use.
+func NewTemplate(pdfBytes []byte, password []byte, verbose bool) (*Template, error) {
+	t := &Template{pdfBytes: pdfBytes, password: password, hash: TemplateHash(pdfBytes)}
+
+	// Try AcroForm first
+	if af, err := acroform.PrepareTemplate(pdfBytes, password, verbose); err == nil && len(af.AcroForm().Fields) > 0 {
+		t.formType, t.acroForm = FormTypeAcroForm, af
+		return t, nil
+	}
+
+	// Try XFA
+	streams, err := xfa.ExtractAllXFAStreams(pdfBytes, nil, xfa.DebugOptions{}, verbose)
+	if err == nil && streams.Template != nil && len(streams.Template.Data) > 0 {
+		x, err := xfa.PrepareTemplate(pdfBytes, nil, xfa.DebugOptions{}, verbose)
+		if err != nil {
+			return nil, err
+		}
+		t.formType, t.xfa, t.xfaTemplate = FormTypeXFA, x, streams.Template.Data
+		return t, nil
+	}
+
+	return nil, types.NewPDFError(types.ErrCodeNoForms, "no forms detected in PDF")
+}
+
+// Type returns the form type of the template
+func (t *Template) Type() FormType {
+	return t.formType
+}
+
+// Hash returns the content hash of the template's PDF, as TemplateHash
+func (t *Template) Hash() string {
+	return t.hash
+}
+
+// Schema returns the form schema of the template, like Form.Schema. It is built
+// on first use and shared by later calls, which must not modify it.
+func (t *Template) Schema() (*types.FormSchema, error) {
+	t.schemaOnce.Do(func() {
+		if t.formType == FormTypeXFA {
+			t.schema, t.schemaErr = xfa.ParseXFAForm(string(t.xfaTemplate), false)
+			if t.schemaErr != nil {
+				t.schemaErr = types.WrapError(types.ErrCodeInvalidForm, "failed to parse XFA form", t.schemaErr)
+			}
+			return
+		}
+		t.schema = t.acroForm.AcroForm().ToFormSchema()
+	})
+	return t.schema, t.schemaErr
+}
+
+// Fill fills a copy of the template like FillWithOptions. opts.Password is not
+// used: the template keeps the password it was prepared with.
+func (t *Template) Fill(data types.FormData, opts FillOptions) ([]byte, error) {
+	if opts.Incremental && (opts.AttachData || opts.Provenance != nil) {
+		return nil, fmt.Errorf("attaching data and recording provenance rewrite the file and cannot be combined with an incremental fill")
+	}
+
+	var filled []byte
+	var report *acroform.FillReport
+	var err error
+	if t.formType == FormTypeXFA {
+		xfaData := data
+		if len(opts.ClearFields) > 0 {
+			xfaData = make(types.FormData, len(data)+len(opts.ClearFields))
+			for _, name := range opts.ClearFields {
+				xfaData[name] = nil
+			}
+			for name, value := range data {
+				xfaData[name] = value
+			}
+		}
+		switch {
+		case !opts.Incremental:
+			filled, err = t.xfa.FillWithLock(xfaData, opts.Lock, opts.Verbose)
+		case opts.Lock != types.LockNone:
+			err = fmt.Errorf("locking XFA fields is not supported with an incremental fill")
+		default:
+			filled, err = t.xfa.FillIncremental(xfaData, opts.Verbose)
+		}
+	} else {
+		filled, report, err = t.acroForm.Fill(data, acroform.FillOptions{
+			Verbose:     opts.Verbose,
+			Lock:        opts.Lock,
+			ClearFields: opts.ClearFields,
+			Incremental: opts.Incremental,
+			Appearances: opts.Appearances,
+		})
+	}
+	if err != nil || (!opts.AttachData && opts.Provenance == nil) {
+		return filled, err
+	}
+
+	m, err := manipulate.NewPDFManipulator(filled, t.password, opts.Verbose)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Provenance != nil {
+		if err := stampProvenance(m, data, *opts.Provenance); err != nil {
+			return nil, fmt.Errorf("failed to record provenance: %w", err)
+		}
+	}
+	if !opts.AttachData {
+		return m.Rebuild()
+	}
+	dataJSON, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode form data: %w", err)
+	}
+	if err := m.AddAttachment(manipulate.Attachment{
+		Name:         DataAttachmentName,
+		Data:         dataJSON,
+		MIMEType:     "application/json",
+		Description:  "Submitted form data",
+		Relationship: manipulate.RelationshipData,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to attach form data: %w", err)
+	}
+	if report != nil {
+		reportJSON, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode fill report: %w", err)
+		}
+		if err := m.AddAttachment(manipulate.Attachment{
+			Name:         ReportAttachmentName,
+			Data:         reportJSON,
+			MIMEType:     "application/json",
+			Description:  "Form fill report",
+			Relationship: manipulate.RelationshipData,
+		}); err != nil {
+			return nil, fmt.Errorf("failed to attach fill report: %w", err)
+		}
+	}
+	return m.Rebuild()
+}
+
+// Cache stores prepared templates by the content hash of their PDF, so that
+// services filling the same template repeatedly reuse its object index, field
+// index and schema. TemplateCache is an in-memory implementation; services can
+// provide their own, such as one with an eviction policy. Implementations must
+// be safe for concurrent use.
+type Cache interface {
+	// Get returns the template stored under hash, or nil
+	Get(hash string) *Template
+	// Put stores a template under hash, replacing any stored before
+	Put(hash string, t *Template)
+	// Remove drops the template stored under hash
+	Remove(hash string)
+}
+
+// LoadTemplate returns the template of a form PDF from cache, preparing and
+// storing it under its content hash if the cache doesn't hold it
+func LoadTemplate(cache Cache, pdfBytes []byte, password []byte, verbose bool) (*Template, error) {
+	hash := TemplateHash(pdfBytes)
+	if t := cache.Get(hash); t != nil {
+		return t, nil
+	}
+	t, err := NewTemplate(pdfBytes, password, verbose)
+	if err != nil {
+		return nil, err
+	}
+	cache.Put(hash, t)
+	return t, nil
+}
+
+// TemplateCache holds prepared templates by key, such as a file path or content
+// hash, so that services filling the same forms repeatedly parse each one once.
+// It implements Cache and is safe for concurrent use.
+type TemplateCache struct {
+	mu           sync.Mutex
+	templates    map[string]*templateEntry
+	onInvalidate []func(key string, t *Template)
+}
+
+// templateEntry is a cached template, prepared once even when several
+// goroutines load it at the same time
+type templateEntry struct {
+	ready    chan struct{} // Closed once the template is prepared
+	template *Template
+	err      error
+}
+
+// NewTemplateCache creates an empty template cache
+func NewTemplateCache() *TemplateCache {
+	return &TemplateCache{templates: make(map[string]*templateEntry)}
+}
+
+// Load returns the template cached under key, preparing it from pdfBytes if it
+// isn't cached yet. A template that fails to prepare is not cached.
+func (c *TemplateCache) Load(key string, pdfBytes []byte, password []byte, verbose bool) (*Template, error) {
+	c.mu.Lock()
+	entry, ok := c.templates[key]
+	if !ok {
+		entry = &templateEntry{ready: make(chan struct{})}
+		c.templates[key] = entry
+	}
+	c.mu.Unlock()
+
+	if ok {
+		<-entry.ready
+		return entry.template, entry.err
+	}
+
+	entry.template, entry.err = NewTemplate(pdfBytes, password, verbose)
+	if entry.err != nil {
+		c.mu.Lock()
+		if c.templates[key] == entry {
+			delete(c.templates, key)
+		}
+		c.mu.Unlock()
+	}
+	close(entry.ready)
+	return entry.template, entry.err
+}
+
+// LoadContent returns the template of a form PDF cached under its content hash,
+// preparing it like Load if it isn't cached yet
+func (c *TemplateCache) LoadContent(pdfBytes []byte, password []byte, verbose bool) (*Template, error) {
+	return c.Load(TemplateHash(pdfBytes), pdfBytes, password, verbose)
+}
+
+// Get returns the template cached under key, or nil. A template still being
+// prepared by Load is waited for.
+func (c *TemplateCache) Get(key string) *Template {
+	c.mu.Lock()
+	entry, ok := c.templates[key]
+	c.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	<-entry.ready
+	return entry.template
+}
+
+// Fill fills the template cached under key
+func (c *TemplateCache) Fill(key string, data types.FormData, opts FillOptions) ([]byte, error) {
+	t := c.Get(key)
+	if t == nil {
+		return nil, fmt.Errorf("template %q is not cached", key)
+	}
+	return t.Fill(data, opts)
+}
+
+// Put caches a prepared template under key, replacing any cached before
+func (c *TemplateCache) Put(key string, t *Template) {
+	entry := &templateEntry{ready: make(chan struct{}), template: t}
+	close(entry.ready)
+
+	c.mu.Lock()
+	old := c.templates[key]
+	c.templates[key] = entry
+	c.mu.Unlock()
+	c.invalidate(key, old, t)
+}
+
+// Remove drops the template cached under key
+func (c *TemplateCache) Remove(key string) {
+	c.mu.Lock()
+	entry := c.templates[key]
+	delete(c.templates, key)
+	c.mu.Unlock()
+	c.invalidate(key, entry, nil)
+}
+
+// Clear drops every cached template
+func (c *TemplateCache) Clear() {
+	c.mu.Lock()
+	templates := c.templates
+	c.templates = make(map[string]*templateEntry)
+	c.mu.Unlock()
+	for key, entry := range templates {
+		c.invalidate(key, entry, nil)
+	}
+}
+
+// OnInvalidate registers fn to be called with the key and template of each
+// entry that Put, Remove or Clear drops, so that services can drop data they
+// derived from it. fn is not called with the cache locked.
+func (c *TemplateCache) OnInvalidate(fn func(key string, t *Template)) {
+	c.mu.Lock()
+	c.onInvalidate = append(c.onInvalidate, fn)
+	c.mu.Unlock()
+}
+
+// invalidate calls the invalidation hooks for a dropped entry, once it is
+// prepared. Entries that failed to prepare, or that hold the template
+// replacing them, are skipped.
+func (c *TemplateCache) invalidate(key string, entry *templateEntry, replacement *Template) {
+	if entry == nil {
+		return
+	}
+	<-entry.ready
+	if entry.template == nil || entry.template == replacement {
+		return
+	}
+	c.mu.Lock()
+	hooks := c.onInvalidate
+	c.mu.Unlock()
+	for _, fn := range hooks {
+		fn(key, entry.template)
+	}
+}
+
+// Len returns the number of cached templates
+func (c *TemplateCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.templates)
+}
@@ -0,0 +1,216 @@
+package forms
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/forms/acroform"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// buildTemplateTestForm creates a one-page AcroForm with a "name" text field
+func buildTemplateTestForm(t *testing.T) []byte {
+	t.Helper()
+	builder := write.NewSimplePDFBuilder()
+	page := builder.AddPage(write.PageSizeLetter)
+	formBuilder := acroform.NewFormBuilder(builder)
+	formBuilder.AddTextField("name", []float64{72, 700, 300, 720}, 0)
+	if _, err := formBuilder.BuildForm(); err != nil {
+		t.Fatalf("Failed to build form: %v", err)
+	}
+	builder.FinalizePage(page)
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to generate PDF: %v", err)
+	}
+	return pdfBytes
+}
+
+func TestTemplateCache_ConcurrentFills(t *testing.T) {
+	pdfBytes := buildTemplateTestForm(t)
+
+	cache := NewTemplateCache()
+	const fills = 8
+	results := make([][]byte, fills)
+	errs := make([]error, fills)
+	var wg sync.WaitGroup
+	for i := 0; i < fills; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tmpl, err := cache.Load("application", pdfBytes, nil, false)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			results[i], errs[i] = tmpl.Fill(types.FormData{"name": fmt.Sprintf("Applicant %d", i)}, FillOptions{})
+		}(i)
+	}
+	wg.Wait()
+
+	if cache.Len() != 1 {
+		t.Errorf("Expected 1 cached template, got %d", cache.Len())
+	}
+	if tmpl := cache.Get("application"); tmpl == nil || tmpl.Type() != FormTypeAcroForm {
+		t.Fatalf("Expected a cached AcroForm template, got %v", tmpl)
+	}
+	for i := 0; i < fills; i++ {
+		if errs[i] != nil {
+			t.Fatalf("Fill %d failed: %v", i, errs[i])
+		}
+		form, err := Extract(results[i], nil, false)
+		if err != nil {
+			t.Fatalf("Failed to extract fill %d: %v", i, err)
+		}
+		if got, want := fmt.Sprint(form.GetValues()["name"]), fmt.Sprintf("Applicant %d", i); got != want {
+			t.Errorf("Fill %d: name = %q, want %q", i, got, want)
+		}
+	}
+
+	// Fills through the cache match direct fills
+	cached, err := cache.Fill("application", types.FormData{"name": "Jane Doe"}, FillOptions{})
+	if err != nil {
+		t.Fatalf("Cache fill failed: %v", err)
+	}
+	direct, err := FillWithOptions(pdfBytes, types.FormData{"name": "Jane Doe"}, FillOptions{})
+	if err != nil {
+		t.Fatalf("FillWithOptions failed: %v", err)
+	}
+	if string(cached) != string(direct) {
+		t.Error("Cached fill differs from a direct fill")
+	}
+
+	cache.Remove("application")
+	if _, err := cache.Fill("application", types.FormData{}, FillOptions{}); err == nil {
+		t.Error("Expected error filling a removed template")
+	}
+}
+
+func TestTemplateCache_LoadFailureNotCached(t *testing.T) {
+	cache := NewTemplateCache()
+	if _, err := cache.Load("broken", []byte("not a pdf"), nil, false); err == nil {
+		t.Fatal("Expected error loading an invalid PDF")
+	}
+	if cache.Len() != 0 {
+		t.Errorf("Failed template was cached")
+	}
+}
+
+func TestTemplateCache_ContentHash(t *testing.T) {
+	pdfBytes := buildTemplateTestForm(t)
+	cache := NewTemplateCache()
+
+	tmpl, err := cache.LoadContent(pdfBytes, nil, false)
+	if err != nil {
+		t.Fatalf("LoadContent failed: %v", err)
+	}
+	if tmpl.Hash() != TemplateHash(pdfBytes) || cache.Get(TemplateHash(pdfBytes)) != tmpl {
+		t.Errorf("Template not cached under its content hash %s", TemplateHash(pdfBytes))
+	}
+	if again, err := LoadTemplate(cache, append([]byte(nil), pdfBytes...), nil, false); err != nil || again != tmpl {
+		t.Errorf("Same content should load the cached template, got %p (%v), want %p", again, err, tmpl)
+	}
+
+	schema, err := tmpl.Schema()
+	if err != nil {
+		t.Fatalf("Schema failed: %v", err)
+	}
+	if len(schema.Questions) != 1 || schema.Questions[0].Name != "name" {
+		t.Errorf("Schema questions = %+v, want the name field", schema.Questions)
+	}
+	if again, _ := tmpl.Schema(); again != schema {
+		t.Error("Schema should be built once and reused")
+	}
+}
+
+// mapCache is a Cache backed by a plain map, as a service might provide
+type mapCache struct {
+	mu        sync.Mutex
+	templates map[string]*Template
+	puts      int
+}
+
+func (c *mapCache) Get(hash string) *Template {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.templates[hash]
+}
+
+func (c *mapCache) Put(hash string, t *Template) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.templates[hash] = t
+	c.puts++
+}
+
+func (c *mapCache) Remove(hash string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.templates, hash)
+}
+
+func TestLoadTemplate_CustomCache(t *testing.T) {
+	pdfBytes := buildTemplateTestForm(t)
+	cache := &mapCache{templates: make(map[string]*Template)}
+
+	first, err := LoadTemplate(cache, pdfBytes, nil, false)
+	if err != nil {
+		t.Fatalf("LoadTemplate failed: %v", err)
+	}
+	second, err := LoadTemplate(cache, pdfBytes, nil, false)
+	if err != nil || second != first || cache.puts != 1 {
+		t.Errorf("Expected the template to be prepared once, got %d puts (%v)", cache.puts, err)
+	}
+
+	cache.Remove(first.Hash())
+	if third, err := LoadTemplate(cache, pdfBytes, nil, false); err != nil || third == first {
+		t.Errorf("Expected a removed template to be prepared again (%v)", err)
+	}
+	if _, err := LoadTemplate(cache, []byte("not a pdf"), nil, false); err == nil || cache.puts != 2 {
+		t.Errorf("Expected an invalid PDF to fail without being cached, got %d puts (%v)", cache.puts, err)
+	}
+}
+
+func TestTemplateCache_InvalidationHooks(t *testing.T) {
+	pdfBytes := buildTemplateTestForm(t)
+	cache := NewTemplateCache()
+	var dropped []string
+	cache.OnInvalidate(func(key string, tmpl *Template) {
+		if tmpl == nil {
+			t.Errorf("Hook called for %q without a template", key)
+		}
+		dropped = append(dropped, key)
+	})
+
+	first, err := cache.Load("a", pdfBytes, nil, false)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	cache.Put("a", first)
+	if len(dropped) != 0 {
+		t.Errorf("Putting the cached template again should not invalidate it, dropped %v", dropped)
+	}
+
+	replacement, err := NewTemplate(pdfBytes, nil, false)
+	if err != nil {
+		t.Fatalf("NewTemplate failed: %v", err)
+	}
+	cache.Put("a", replacement)
+	if cache.Get("a") != replacement || fmt.Sprint(dropped) != "[a]" {
+		t.Errorf("Replacing a template should invalidate the old one, dropped %v", dropped)
+	}
+
+	cache.Put("b", first)
+	cache.Remove("a")
+	cache.Remove("missing")
+	if fmt.Sprint(dropped) != "[a a]" {
+		t.Errorf("Remove should invalidate only cached templates, dropped %v", dropped)
+	}
+
+	cache.Clear()
+	if cache.Len() != 0 || fmt.Sprint(dropped) != "[a a b]" {
+		t.Errorf("Clear should drop and invalidate every template, dropped %v, %d left", dropped, cache.Len())
+	}
+}
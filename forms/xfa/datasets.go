@@ -0,0 +1,622 @@
+package xfa
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// Datasets is the datasets packet of an XFA form parsed into a DOM, for
+// reading and changing form data by SOM expression, e.g.
+// "form1.items.item[2].qty", rather than by patching the XML text. Names
+// match elements by local name, an index selects among siblings of the same
+// name (0 when left out), and expressions are relative to the <xfa:data>
+// element; a "$data." or "xfa.datasets.data." prefix is allowed.
+type Datasets struct {
+	prolog string // Processing instructions and comments before the root element
+	epilog string // Processing instructions and comments after it
+	root   *dataNode
+	data   *dataNode // The <xfa:data> element, nil until a value is set
+	top    *dataNode // The <xfa:datasets> element data is created in
+}
+
+// dataNode is an element of the datasets DOM, or a text, CDATA or comment
+// node in it
+type dataNode struct {
+	name        string // Qualified name as written, e.g. "xfa:data"; empty for other nodes
+	kind        textKind
+	attrs       []xml.Attr
+	value       string // Text of a leaf element, or of a text, CDATA or comment node
+	children    []*dataNode
+	selfClosing bool // An empty element is written as <name/>, not <name></name>
+}
+
+// textKind tells how a node without a name is written
+type textKind int
+
+const (
+	plainText textKind = iota
+	cdataText
+	commentText
+)
+
+// somStep is one name of a SOM expression, with its index
+type somStep struct {
+	name  string
+	index int
+}
+
+var datasetsEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+var datasetsAttrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "\n", "&#xA;", "\t", "&#x9;", "\r", "&#xD;")
+
+// ParseDatasets parses an XFA datasets packet, as held in the datasets stream
+// of an XFA form. A whole XDP document is accepted as well; its datasets
+// packet is then the one addressed. Comments and CDATA sections are kept for
+// Bytes; processing instructions inside elements are dropped.
+func ParseDatasets(data []byte) (*Datasets, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+
+	ds := &Datasets{}
+	var prolog, epilog strings.Builder
+	var stack []*dataNode
+	for {
+		start := decoder.InputOffset()
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid datasets XML at offset %d: %v", decoder.InputOffset(), err)
+		}
+		end := decoder.InputOffset()
+
+		switch t := token.(type) {
+		case xml.ProcInst:
+			if len(stack) > 0 {
+				break
+			}
+			outside := &prolog
+			if ds.root != nil {
+				outside = &epilog
+			}
+			fmt.Fprintf(outside, "<?%s %s?>", t.Target, strings.TrimSpace(string(t.Inst)))
+		case xml.Comment:
+			comment := &dataNode{kind: commentText, value: string(t)}
+			switch {
+			case len(stack) > 0:
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, comment)
+			case ds.root == nil:
+				comment.write(&prolog)
+			default:
+				comment.write(&epilog)
+			}
+		case xml.StartElement:
+			node := &dataNode{name: qualifiedName(t.Name), attrs: append([]xml.Attr(nil), t.Attr...)}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			} else if ds.root == nil {
+				ds.root = node
+			} else {
+				return nil, fmt.Errorf("datasets XML has more than one root element")
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("unexpected end element </%s>", qualifiedName(t.Name))
+			}
+			// The end of a self-closing element takes no input
+			stack[len(stack)-1].selfClosing = start == end
+			stack[len(stack)-1].normalize()
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				// The decoder gives CDATA sections as text; tell them by their markup
+				text := &dataNode{value: string(t)}
+				if bytes.HasPrefix(data[start:], []byte("<![CDATA[")) {
+					text.kind = cdataText
+				}
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, text)
+			} else if ds.root == nil {
+				prolog.Write(t)
+			} else {
+				epilog.Write(t)
+			}
+		}
+	}
+	if ds.root == nil {
+		return nil, fmt.Errorf("datasets XML has no root element")
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("datasets XML ends inside <%s>", stack[len(stack)-1].name)
+	}
+
+	ds.prolog = prolog.String()
+	ds.epilog = epilog.String()
+	ds.top = ds.root.find("datasets")
+	if ds.top == nil {
+		ds.top = ds.root
+	}
+	ds.data = ds.top.child("data", 0)
+	return ds, nil
+}
+
+// normalize turns the text and CDATA nodes of a leaf element into its value. A
+// leaf element with CDATA sections or comments keeps its nodes as well, to be
+// written as they were until its value is set.
+func (n *dataNode) normalize() {
+	hasElements := false
+	for _, c := range n.children {
+		if c.name != "" {
+			hasElements = true
+			break
+		}
+	}
+	if !hasElements {
+		var value strings.Builder
+		verbatim := false
+		for _, c := range n.children {
+			if c.kind != plainText {
+				verbatim = true
+			}
+			if c.kind != commentText {
+				value.WriteString(c.value)
+			}
+		}
+		n.value = value.String()
+		if !verbatim {
+			n.children = nil
+		}
+	}
+}
+
+// isGroup reports whether the element has child elements
+func (n *dataNode) isGroup() bool {
+	for _, c := range n.children {
+		if c.name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// child returns the index-th child element of the given local name
+func (n *dataNode) child(name string, index int) *dataNode {
+	for _, c := range n.children {
+		if c.name != "" && localName(c.name) == name {
+			if index == 0 {
+				return c
+			}
+			index--
+		}
+	}
+	return nil
+}
+
+// count returns the number of child elements of the given local name, and the
+// position in children after the last of them (-1 when there are none)
+func (n *dataNode) count(name string) (count, after int) {
+	after = -1
+	for i, c := range n.children {
+		if c.name != "" && localName(c.name) == name {
+			count++
+			after = i + 1
+		}
+	}
+	return count, after
+}
+
+// find returns the first element of the given local name, n included, in
+// document order
+func (n *dataNode) find(name string) *dataNode {
+	if n.name != "" && localName(n.name) == name {
+		return n
+	}
+	for _, c := range n.children {
+		if found := c.find(name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// insert adds a child at position i, or at the end when i is negative
+func (n *dataNode) insert(i int, child *dataNode) {
+	if i < 0 || i >= len(n.children) {
+		n.children = append(n.children, child)
+		return
+	}
+	n.children = append(n.children, nil)
+	copy(n.children[i+1:], n.children[i:])
+	n.children[i] = child
+}
+
+// blank returns a copy of the element and its descendants with their values
+// cleared, as a new instance of a repeating group
+func (n *dataNode) blank() *dataNode {
+	c := &dataNode{name: n.name, attrs: append([]xml.Attr(nil), n.attrs...), selfClosing: true}
+	for _, child := range n.children {
+		if child.name != "" {
+			c.children = append(c.children, child.blank())
+		}
+	}
+	return c
+}
+
+// parseSOM splits a SOM expression into its names and indexes
+func parseSOM(expr string) ([]somStep, error) {
+	parts := strings.Split(strings.TrimSpace(expr), ".")
+	switch {
+	case len(parts) > 1 && parts[0] == "$data":
+		parts = parts[1:]
+	case len(parts) > 3 && parts[0] == "xfa" && parts[1] == "datasets" && parts[2] == "data":
+		parts = parts[3:]
+	}
+
+	steps := make([]somStep, len(parts))
+	for i, part := range parts {
+		name, index := part, 0
+		if open := strings.IndexByte(part, '['); open != -1 {
+			if !strings.HasSuffix(part, "]") {
+				return nil, fmt.Errorf("invalid SOM expression %q: unterminated index", expr)
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(part[open+1 : len(part)-1]))
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid SOM expression %q: bad index in %q", expr, part)
+			}
+			name, index = part[:open], n
+		}
+		if name == "" {
+			return nil, fmt.Errorf("invalid SOM expression %q: empty name", expr)
+		}
+		steps[i] = somStep{name: name, index: index}
+	}
+	return steps, nil
+}
+
+// resolve returns the element a SOM expression addresses, or nil
+func (d *Datasets) resolve(steps []somStep) *dataNode {
+	node := d.data
+	for _, step := range steps {
+		if node == nil {
+			return nil
+		}
+		node = node.child(step.name, step.index)
+	}
+	return node
+}
+
+// Get returns the value of the data element a SOM expression addresses
+func (d *Datasets) Get(som string) (string, error) {
+	steps, err := parseSOM(som)
+	if err != nil {
+		return "", err
+	}
+	node := d.resolve(steps)
+	if node == nil {
+		return "", types.NewPDFErrorf(types.ErrCodeFieldNotFound, "no data element %q", som)
+	}
+	if node.isGroup() {
+		return "", fmt.Errorf("data element %q is a group, not a value", som)
+	}
+	return node.value, nil
+}
+
+// Set sets the value of the data element a SOM expression addresses. Missing
+// elements along the way are created, each as the next instance of its name,
+// so an index may be at most the number of existing instances.
+func (d *Datasets) Set(som, value string) error {
+	steps, err := parseSOM(som)
+	if err != nil {
+		return err
+	}
+	if d.data == nil {
+		d.data = &dataNode{name: qualify(d.top.name, "data")}
+		d.top.insert(-1, d.data)
+	}
+
+	node := d.data
+	for _, step := range steps {
+		next := node.child(step.name, step.index)
+		if next == nil {
+			count, after := node.count(step.name)
+			if step.index != count {
+				return fmt.Errorf("cannot set %q: %s has %d instances", som, step.name, count)
+			}
+			if !node.isGroup() && strings.TrimSpace(node.value) != "" {
+				return fmt.Errorf("cannot set %q: %s has a value", som, localName(node.name))
+			}
+			next = &dataNode{name: step.name}
+			node.value = ""
+			node.insert(after, next)
+		}
+		node = next
+	}
+	if node.isGroup() {
+		return fmt.Errorf("data element %q is a group, not a value", som)
+	}
+	node.setValue(value)
+	return nil
+}
+
+// setValue replaces the content of a leaf element with text
+func (n *dataNode) setValue(value string) {
+	n.value = value
+	n.children = nil
+	n.selfClosing = false
+}
+
+// Count returns the number of instances of the repeating element a SOM
+// expression names; the index of its last name is ignored
+func (d *Datasets) Count(som string) (int, error) {
+	steps, err := parseSOM(som)
+	if err != nil {
+		return 0, err
+	}
+	last := steps[len(steps)-1]
+	parent := d.resolve(steps[:len(steps)-1])
+	if parent == nil {
+		return 0, nil
+	}
+	count, _ := parent.count(last.name)
+	return count, nil
+}
+
+// Append adds an instance of a repeating group after its last one and returns
+// the index of the new instance. It has the elements of the last instance
+// with empty values, ready to be filled with Set. The index of the last name
+// of the SOM expression is ignored.
+func (d *Datasets) Append(som string) (int, error) {
+	steps, err := parseSOM(som)
+	if err != nil {
+		return 0, err
+	}
+	last := steps[len(steps)-1]
+	parent := d.resolve(steps[:len(steps)-1])
+	if parent == nil {
+		return 0, types.NewPDFErrorf(types.ErrCodeFieldNotFound, "no data element for the parent of %q", som)
+	}
+	count, after := parent.count(last.name)
+	if count == 0 {
+		return 0, types.NewPDFErrorf(types.ErrCodeFieldNotFound, "no instance of %q to append to", som)
+	}
+	parent.insert(after, parent.child(last.name, count-1).blank())
+	return count, nil
+}
+
+// Remove removes the data element a SOM expression addresses, e.g. one
+// instance of a repeating group
+func (d *Datasets) Remove(som string) error {
+	steps, err := parseSOM(som)
+	if err != nil {
+		return err
+	}
+	last := steps[len(steps)-1]
+	parent := d.resolve(steps[:len(steps)-1])
+	var node *dataNode
+	if parent != nil {
+		node = parent.child(last.name, last.index)
+	}
+	if node == nil {
+		return types.NewPDFErrorf(types.ErrCodeFieldNotFound, "no data element %q", som)
+	}
+	for i, c := range parent.children {
+		if c == node {
+			// The element goes with the whitespace that indents it
+			from := i
+			if i > 0 && parent.children[i-1].name == "" && parent.children[i-1].kind == plainText && strings.TrimSpace(parent.children[i-1].value) == "" {
+				from--
+			}
+			parent.children = append(parent.children[:from], parent.children[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// fillFields sets the values of <field name="..."> elements, as form data
+// keyed by field name is stored: the content of the first <value> element of
+// the first field of each name is replaced, or a <value> element added, and a
+// nil value clears it. With dataOnly, only fields inside <data> elements are
+// filled, unless the XML has no <data> element.
+func (d *Datasets) fillFields(formData types.FormData, dataOnly, verbose bool) {
+	all := make(map[string]*dataNode)
+	inData := make(map[string]*dataNode)
+	sawData := false
+	var walk func(n *dataNode, underData bool)
+	walk = func(n *dataNode, underData bool) {
+		if n.name == "" {
+			return
+		}
+		switch localName(n.name) {
+		case "data":
+			sawData, underData = true, true
+		case "field":
+			if name := n.attr("name"); name != "" {
+				if _, ok := all[name]; !ok {
+					all[name] = n
+				}
+				if _, ok := inData[name]; !ok && underData {
+					inData[name] = n
+				}
+			}
+		}
+		for _, c := range n.children {
+			walk(c, underData)
+		}
+	}
+	walk(d.root, false)
+
+	fields := all
+	if dataOnly && sawData {
+		fields = inData
+	}
+	for name, value := range formData {
+		field, ok := fields[name]
+		if !ok {
+			if verbose {
+				log.Printf("Warning: Field '%s' not found in XFA XML", name)
+			}
+			continue
+		}
+		valueStr := ""
+		if value != nil {
+			valueStr = fmt.Sprintf("%v", value)
+		}
+		field.fieldValue().setValue(valueStr)
+		if verbose {
+			log.Printf("Updated field '%s' = '%s'", name, valueStr)
+		}
+	}
+}
+
+// attr returns the value of the attribute of the given local name
+func (n *dataNode) attr(name string) string {
+	for _, attr := range n.attrs {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// fieldValue returns the first <value> element of a <field> element, outside
+// the fields nested in it, adding one at its end when it has none
+func (n *dataNode) fieldValue() *dataNode {
+	var find func(parent *dataNode) *dataNode
+	find = func(parent *dataNode) *dataNode {
+		for _, c := range parent.children {
+			switch {
+			case c.name == "" || localName(c.name) == "field":
+			case localName(c.name) == "value":
+				return c
+			default:
+				if found := find(c); found != nil {
+					return found
+				}
+			}
+		}
+		return nil
+	}
+	if value := find(n); value != nil {
+		return value
+	}
+
+	// Text of the field is kept before the new element
+	if !n.isGroup() && len(n.children) == 0 && n.value != "" {
+		n.children = []*dataNode{{value: n.value}}
+	}
+	n.value = ""
+	n.selfClosing = false
+	value := &dataNode{name: "value"}
+	n.insert(-1, value)
+	return value
+}
+
+// clone returns a deep copy of the DOM
+func (d *Datasets) clone() *Datasets {
+	c := &Datasets{prolog: d.prolog, epilog: d.epilog}
+	var copyNode func(n *dataNode) *dataNode
+	copyNode = func(n *dataNode) *dataNode {
+		cp := *n
+		cp.attrs = append([]xml.Attr(nil), n.attrs...)
+		cp.children = nil
+		for _, child := range n.children {
+			cp.children = append(cp.children, copyNode(child))
+		}
+		switch n {
+		case d.data:
+			c.data = &cp
+		case d.top:
+			c.top = &cp
+		}
+		return &cp
+	}
+	c.root = copyNode(d.root)
+	return c
+}
+
+// Bytes serializes the datasets packet. The output depends only on the DOM:
+// attributes keep their order, whitespace, comments and CDATA sections are
+// written where they were, empty elements are self-closing as they were
+// parsed, and added elements are written without whitespace between them, so
+// parsing and serializing again gives the same bytes.
+func (d *Datasets) Bytes() []byte {
+	var b strings.Builder
+	b.WriteString(d.prolog)
+	d.root.write(&b)
+	b.WriteString(d.epilog)
+	return []byte(b.String())
+}
+
+func (n *dataNode) write(b *strings.Builder) {
+	if n.name == "" {
+		switch n.kind {
+		case cdataText:
+			b.WriteString("<![CDATA[")
+			b.WriteString(n.value)
+			b.WriteString("]]>")
+		case commentText:
+			b.WriteString("<!--")
+			b.WriteString(n.value)
+			b.WriteString("-->")
+		default:
+			datasetsEscaper.WriteString(b, n.value)
+		}
+		return
+	}
+	b.WriteString("<")
+	b.WriteString(n.name)
+	for _, attr := range n.attrs {
+		fmt.Fprintf(b, ` %s="`, qualifiedName(attr.Name))
+		datasetsAttrEscaper.WriteString(b, attr.Value)
+		b.WriteString(`"`)
+	}
+	if n.value == "" && len(n.children) == 0 && n.selfClosing {
+		b.WriteString("/>")
+		return
+	}
+	b.WriteString(">")
+	if len(n.children) == 0 {
+		datasetsEscaper.WriteString(b, n.value)
+	}
+	for _, c := range n.children {
+		c.write(b)
+	}
+	b.WriteString("</")
+	b.WriteString(n.name)
+	b.WriteString(">")
+}
+
+// qualifiedName returns a name as written, with its namespace prefix
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// localName strips the namespace prefix of a qualified name
+func localName(name string) string {
+	if i := strings.IndexByte(name, ':'); i != -1 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// qualify gives local the namespace prefix of a sibling's qualified name
+func qualify(sibling, local string) string {
+	if i := strings.IndexByte(sibling, ':'); i != -1 {
+		return sibling[:i+1] + local
+	}
+	return local
+}
@@ -0,0 +1,180 @@
+package xfa
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+const testDatasetsXML = `<?xml version="1.0" encoding="UTF-8"?>
+<xfa:datasets xmlns:xfa="http://www.xfa.org/schema/xfa-data/1.0/">
+  <xfa:data>
+    <form1>
+      <name>Ada &amp; Co</name>
+      <items>
+        <item><desc>Widget</desc><qty>2</qty></item>
+        <item xfa:dataNode="dataGroup"><desc>Gadget</desc><qty>5</qty></item>
+      </items>
+    </form1>
+  </xfa:data>
+</xfa:datasets>`
+
+func TestDatasets(t *testing.T) {
+	ds, err := ParseDatasets([]byte(testDatasetsXML))
+	if err != nil {
+		t.Fatalf("ParseDatasets failed: %v", err)
+	}
+
+	for som, want := range map[string]string{
+		"form1.name":                   "Ada & Co",
+		"form1.items.item[1].desc":     "Gadget",
+		"$data.form1.items.item.qty":   "2",
+		"xfa.datasets.data.form1.name": "Ada & Co",
+	} {
+		if got, err := ds.Get(som); err != nil || got != want {
+			t.Errorf("Get(%q) = %q, %v; want %q", som, got, err, want)
+		}
+	}
+	if _, err := ds.Get("form1.items.item[2].desc"); !errors.Is(err, types.ErrFieldNotFound) {
+		t.Errorf("Expected not found error for a missing instance, got %v", err)
+	}
+	if _, err := ds.Get("form1.items"); err == nil {
+		t.Error("Expected an error getting the value of a group")
+	}
+
+	index, err := ds.Append("form1.items.item")
+	if err != nil || index != 2 {
+		t.Fatalf("Append = %d, %v; want 2", index, err)
+	}
+	if err := ds.Set(fmt.Sprintf("form1.items.item[%d].desc", index), "Gizmo <XL>"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if got, _ := ds.Get("form1.items.item[2].qty"); got != "" {
+		t.Errorf("Expected an empty value in the appended instance, got %q", got)
+	}
+	if n, _ := ds.Count("form1.items.item"); n != 3 {
+		t.Errorf("Expected 3 items, got %d", n)
+	}
+	if err := ds.Set("form1.address.city", "Paris"); err != nil {
+		t.Fatalf("Set creating elements failed: %v", err)
+	}
+	if err := ds.Set("form1.items.item[5].qty", "1"); err == nil {
+		t.Error("Expected an error setting an instance past the next one")
+	}
+	if err := ds.Remove("form1.items.item[0]"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+
+	out := ds.Bytes()
+	// The packet keeps its whitespace; added elements come without any
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<xfa:datasets xmlns:xfa="http://www.xfa.org/schema/xfa-data/1.0/">
+  <xfa:data>
+    <form1>
+      <name>Ada &amp; Co</name>
+      <items>
+        <item xfa:dataNode="dataGroup"><desc>Gadget</desc><qty>5</qty></item>` +
+		`<item xfa:dataNode="dataGroup"><desc>Gizmo &lt;XL&gt;</desc><qty/></item>
+      </items>
+    <address><city>Paris</city></address></form1>
+  </xfa:data>
+</xfa:datasets>`
+	if string(out) != want {
+		t.Errorf("Unexpected serialization:\n%s\nwant:\n%s", out, want)
+	}
+
+	reparsed, err := ParseDatasets(out)
+	if err != nil {
+		t.Fatalf("ParseDatasets of the output failed: %v", err)
+	}
+	if again := reparsed.Bytes(); string(again) != string(out) {
+		t.Errorf("Serialization is not stable:\n%s\n%s", out, again)
+	}
+}
+
+func TestDatasets_CommentsAndCDATA(t *testing.T) {
+	packet := `<?xml version="1.0" encoding="UTF-8"?><!-- exported by Designer -->` +
+		`<xfa:datasets xmlns:xfa="http://www.xfa.org/schema/xfa-data/1.0/"><xfa:data><form1>` +
+		`<!-- contact details --><name><![CDATA[Ada & <Co>]]></name>` +
+		`<note>See <![CDATA[<attached>]]> list<!-- unchecked --></note><city>Paris</city>` +
+		`</form1></xfa:data></xfa:datasets><!-- end -->`
+	ds, err := ParseDatasets([]byte(packet))
+	if err != nil {
+		t.Fatalf("ParseDatasets failed: %v", err)
+	}
+	if got := string(ds.Bytes()); got != packet {
+		t.Errorf("Comments and CDATA not kept:\n%s\nwant:\n%s", got, packet)
+	}
+
+	for som, want := range map[string]string{
+		"form1.name": "Ada & <Co>",
+		"form1.note": "See <attached> list",
+		"form1.city": "Paris",
+	} {
+		if got, err := ds.Get(som); err != nil || got != want {
+			t.Errorf("Get(%q) = %q, %v; want %q", som, got, err, want)
+		}
+	}
+
+	// A value set replaces the sections of its element, and only those
+	if err := ds.Set("form1.name", "Grace"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	want := strings.Replace(packet, "<name><![CDATA[Ada & <Co>]]></name>", "<name>Grace</name>", 1)
+	if got := string(ds.Bytes()); got != want {
+		t.Errorf("Unexpected serialization after Set:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDatasets_EmptyPacket(t *testing.T) {
+	ds, err := ParseDatasets([]byte(`<xfa:datasets xmlns:xfa="http://www.xfa.org/schema/xfa-data/1.0/"/>`))
+	if err != nil {
+		t.Fatalf("ParseDatasets failed: %v", err)
+	}
+	if err := ds.Set("form1.name", "Ada"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if got := string(ds.Bytes()); !strings.Contains(got, `<xfa:data><form1><name>Ada</name></form1></xfa:data>`) {
+		t.Errorf("Expected data created under xfa:data, got %s", got)
+	}
+}
+
+func TestUpdateDatasetsInPDF(t *testing.T) {
+	w := write.NewPDFWriter()
+	templateNum := w.AddStreamObject(write.Dictionary{}, []byte(`<template><subform name="form1"><field name="name"/></subform></template>`), true)
+	datasetsNum := w.AddStreamObject(write.Dictionary{}, []byte(testDatasetsXML), true)
+	pagesNum := w.AddObject([]byte("<</Type/Pages/Kids[]/Count 0>>"))
+	acroFormNum := w.AddObject([]byte(fmt.Sprintf("<</Fields[]/XFA[(template) %d 0 R(datasets) %d 0 R]>>", templateNum, datasetsNum)))
+	w.SetRoot(w.AddObject([]byte(fmt.Sprintf("<</Type/Catalog/Pages %d 0 R/AcroForm %d 0 R>>", pagesNum, acroFormNum))))
+	pdfBytes, err := w.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to write PDF: %v", err)
+	}
+
+	ds, err := ExtractDatasets(pdfBytes, nil, false)
+	if err != nil {
+		t.Fatalf("ExtractDatasets failed: %v", err)
+	}
+	if err := ds.Set("form1.name", "Grace"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	updated, err := UpdateDatasetsInPDF(pdfBytes, ds, nil, false)
+	if err != nil {
+		t.Fatalf("UpdateDatasetsInPDF failed: %v", err)
+	}
+
+	ds, err = ExtractDatasets(updated, nil, false)
+	if err != nil {
+		t.Fatalf("ExtractDatasets of the updated PDF failed: %v", err)
+	}
+	if got, _ := ds.Get("form1.name"); got != "Grace" {
+		t.Errorf("Expected updated name 'Grace', got %q", got)
+	}
+	if got, _ := ds.Get("form1.items.item[1].qty"); got != "5" {
+		t.Errorf("Expected other values kept, got qty %q", got)
+	}
+}
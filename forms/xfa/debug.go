@@ -0,0 +1,32 @@
+package xfa
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+// DebugOptions sends hex and ASCII dumps of the objects decrypted while
+// locating the XFA array of an encrypted AcroForm to a writer. The dumps are
+// written with or without verbose mode, which logs only offsets and lengths so
+// that it stays usable on large files. The zero value writes no dumps.
+type DebugOptions struct {
+	Output io.Writer // Where the dumps go; nil drops them
+	Limit  int       // Most bytes of each object to dump, 0 for all
+}
+
+// dump writes a labeled hex and ASCII dump of data to the output, truncated to
+// the limit
+func (d DebugOptions) dump(label string, data []byte) {
+	if d.Output == nil {
+		return
+	}
+	shown := data
+	if d.Limit > 0 && len(shown) > d.Limit {
+		shown = shown[:d.Limit]
+	}
+	fmt.Fprintf(d.Output, "=== %s: %d bytes ===\n%s", label, len(data), hex.Dump(shown))
+	if len(shown) < len(data) {
+		fmt.Fprintf(d.Output, "... %d more bytes\n", len(data)-len(shown))
+	}
+}
@@ -0,0 +1,47 @@
+package xfa
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDebugOptions_Dump(t *testing.T) {
+	// Dropped without an output
+	DebugOptions{}.dump("ignored", []byte("<< /XFA 5 0 R >>"))
+
+	var buf bytes.Buffer
+	DebugOptions{Output: &buf, Limit: 16}.dump("decrypted AcroForm", []byte("<< /Fields [] /XFA 5 0 R >>"))
+	out := buf.String()
+	if !strings.Contains(out, "=== decrypted AcroForm: 27 bytes ===") {
+		t.Errorf("Missing label: %q", out)
+	}
+	if !strings.Contains(out, "|<< /Fields [] /X|") {
+		t.Errorf("Missing ASCII column of the first 16 bytes: %q", out)
+	}
+	if strings.Contains(out, "5 0 R") || !strings.Contains(out, "... 11 more bytes") {
+		t.Errorf("Dump should stop at the limit: %q", out)
+	}
+}
+
+func TestExtractAllXFAStreams_Debug(t *testing.T) {
+	pdfBytes := buildDatasetsTestPDF(t)
+
+	// Dumps go to the writer of the call that asks for them, without verbose mode
+	var buf bytes.Buffer
+	streams, err := ExtractAllXFAStreams(pdfBytes, nil, DebugOptions{Output: &buf}, false)
+	if err != nil || streams.Datasets == nil {
+		t.Fatalf("ExtractAllXFAStreams failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "=== AcroForm dictionary:") {
+		t.Errorf("Missing dump of the AcroForm dictionary: %q", buf.String())
+	}
+
+	buf.Reset()
+	if _, err := PrepareTemplate(pdfBytes, nil, DebugOptions{}, false); err != nil {
+		t.Fatalf("PrepareTemplate failed: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Another call's options should not get dumps: %q", buf.String())
+	}
+}
@@ -0,0 +1,91 @@
+package xfa
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+var (
+	xfaFieldTagPattern   = regexp.MustCompile(`<field\b[^>]*>`)
+	xfaNameAttrPattern   = regexp.MustCompile(`\bname="([^"]*)"`)
+	xfaAccessAttrPattern = regexp.MustCompile(`\baccess="[^"]*"`)
+)
+
+// SetXFAFieldAccess sets the access attribute ("open", "readOnly", "protected" or
+// "nonInteractive") of the named <field> elements of an XFA template, or of all
+// fields when names is nil
+func SetXFAFieldAccess(templateXML string, names []string, access string) string {
+	var nameSet map[string]bool
+	if names != nil {
+		nameSet = make(map[string]bool, len(names))
+		for _, name := range names {
+			nameSet[name] = true
+		}
+	}
+
+	return xfaFieldTagPattern.ReplaceAllStringFunc(templateXML, func(tag string) string {
+		if nameSet != nil {
+			m := xfaNameAttrPattern.FindStringSubmatch(tag)
+			if m == nil || !nameSet[m[1]] {
+				return tag
+			}
+		}
+		attr := fmt.Sprintf(`access="%s"`, access)
+		if xfaAccessAttrPattern.MatchString(tag) {
+			return xfaAccessAttrPattern.ReplaceAllString(tag, attr)
+		}
+		return "<field " + attr + tag[len("<field"):]
+	})
+}
+
+// UpdateXFAInPDFWithLock updates XFA field values like UpdateXFAInPDF and then
+// makes the filled fields, or all fields, read-only in the template
+func UpdateXFAInPDFWithLock(pdfBytes []byte, formData types.FormData, encryptInfo *types.PDFEncryption, lock types.FieldLock, verbose bool) ([]byte, error) {
+	result, err := UpdateXFAInPDF(pdfBytes, formData, encryptInfo, verbose)
+	if err != nil || lock == types.LockNone {
+		return result, err
+	}
+	return lockXFAFields(result, formData, encryptInfo, lock, verbose)
+}
+
+// lockXFAFields makes the fields named in formData, or all fields, read-only in
+// the template stream of a filled PDF
+func lockXFAFields(result []byte, formData types.FormData, encryptInfo *types.PDFEncryption, lock types.FieldLock, verbose bool) ([]byte, error) {
+	streams, err := ExtractAllXFAStreams(result, encryptInfo, DebugOptions{}, verbose)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract XFA streams: %v", err)
+	}
+	if streams.Template == nil {
+		return nil, fmt.Errorf("template stream not found in XFA")
+	}
+
+	templateXML, wasCompressed, err := DecompressStream(streams.Template.Data)
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing template: %v", err)
+	}
+	wasCompressed = wasCompressed || streams.Template.Compressed
+
+	var names []string
+	if lock == types.LockFilled {
+		names = make([]string, 0, len(formData))
+		for name := range formData {
+			names = append(names, name)
+		}
+	}
+	updatedTemplate := []byte(SetXFAFieldAccess(string(templateXML), names, "readOnly"))
+
+	if wasCompressed {
+		updatedTemplate, err = CompressStream(updatedTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("error compressing template: %v", err)
+		}
+	}
+
+	if verbose {
+		log.Printf("Locking XFA fields (%s) in template stream %d", lock, streams.Template.ObjectNumber)
+	}
+	return ReplaceStreamInPDF(result, streams.Template.ObjectNumber, updatedTemplate, verbose)
+}
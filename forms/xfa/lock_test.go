@@ -0,0 +1,30 @@
+package xfa
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetXFAFieldAccess(t *testing.T) {
+	template := `<subform name="form1">` +
+		`<field name="name" w="50mm"><ui><textEdit/></ui></field>` +
+		`<field name="city" access="protected"/>` +
+		`<field name="zip"></field>` +
+		`</subform>`
+
+	got := SetXFAFieldAccess(template, []string{"name", "city"}, "readOnly")
+	for _, want := range []string{
+		`<field access="readOnly" name="name" w="50mm">`,
+		`<field name="city" access="readOnly"/>`,
+		`<field name="zip">`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %s in:\n%s", want, got)
+		}
+	}
+
+	got = SetXFAFieldAccess(template, nil, "readOnly")
+	if n := strings.Count(got, `access="readOnly"`); n != 3 {
+		t.Errorf("Expected all 3 fields read-only, got %d:\n%s", n, got)
+	}
+}
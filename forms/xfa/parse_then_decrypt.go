@@ -12,7 +12,7 @@ import (
 
 // parseObjectStructure parses a PDF object structure first, then decrypts encrypted parts
 // This follows PyPDF's approach: parse structure, then decrypt values
-func parseObjectStructure(pdfBytes []byte, objNum, genNum int, objOffset int64, encryptInfo *types.PDFEncryption, verbose bool) ([]byte, error) {
+func parseObjectStructure(pdfBytes []byte, objNum, genNum int, objOffset int64, encryptInfo *types.PDFEncryption, debug DebugOptions, verbose bool) ([]byte, error) {
 	// Step 1: Seek to object location (like PyPDF line 423)
 	if objOffset < 0 || int(objOffset) >= len(pdfBytes) {
 		return nil, fmt.Errorf("invalid object offset: %d", objOffset)
@@ -168,7 +168,7 @@ func parseObjectStructure(pdfBytes []byte, objNum, genNum int, objOffset int64,
 				log.Printf("Direct decryption failed, trying chunked decryption: %v", err)
 			}
 			// Try decrypting in chunks (for alignment) - uses existing function from xfa_utils.go
-			decryptedDict, err = decryptInChunks(dictContent, objNum, genNum, encryptInfo, verbose)
+			decryptedDict, err = decryptInChunks(dictContent, objNum, genNum, encryptInfo, debug, verbose)
 			if err != nil {
 				return nil, fmt.Errorf("failed to decrypt dictionary: %v", err)
 			}
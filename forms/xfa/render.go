@@ -0,0 +1,1265 @@
+package xfa
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/forms/acroform"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// Defaults of the XFA template grammar
+const (
+	xfaDefaultTypeface = "Courier"
+	xfaDefaultFontSize = 10.0
+	xfaDefaultEdge     = 0.5 // edge thickness in points
+	xfaLineSpacing     = 1.2 // line height relative to the font size
+	xfaCheckSize       = 10.0
+	xfaPageMargin      = 18.0 // content area inset of a page area without one
+)
+
+// RenderOptions controls how Render lays out an XFA form
+type RenderOptions struct {
+	// Flatten draws field values as page content instead of AcroForm widgets
+	Flatten bool
+	// PageSize is the size of page areas without a medium; the zero value is Letter
+	PageSize write.PageSize
+}
+
+// Render lays out the XFA template of a PDF, filled with the data of its
+// datasets, as a static PDF for viewers without XFA support. Page areas and
+// their content areas make the pages. Subforms are placed by position or
+// flowed top to bottom (or left to right in rows), repeat for each data
+// instance and continue in the next content area when one is full. Fields
+// become AcroForm widgets, with their captions and borders drawn on the page,
+// or text with opts.Flatten.
+//
+// Scripts are not run and picture clauses are not applied. Images, arcs and
+// rich text formatting are left out, and text is shown in the standard font
+// nearest to the template's typeface.
+func Render(pdfBytes []byte, encryptInfo *types.PDFEncryption, opts RenderOptions, verbose bool) ([]byte, error) {
+	streams, err := ExtractAllXFAStreams(pdfBytes, encryptInfo, DebugOptions{}, verbose)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract XFA streams: %w", err)
+	}
+	if streams.Template == nil {
+		return nil, fmt.Errorf("XFA form has no template")
+	}
+	var datasets []byte
+	if streams.Datasets != nil {
+		datasets = streams.Datasets.Data
+	}
+	return renderTemplate(streams.Template.Data, datasets, opts, verbose)
+}
+
+// renderTemplate lays out a template packet filled with a datasets packet
+func renderTemplate(templateXML, datasetsXML []byte, opts RenderOptions, verbose bool) ([]byte, error) {
+	doc, err := parseXFANode(templateXML)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse XFA template: %w", err)
+	}
+	template := doc.find("template")
+	if template == nil {
+		return nil, fmt.Errorf("no template element in XFA template")
+	}
+	root := template.child("subform")
+	if root == nil {
+		return nil, fmt.Errorf("XFA template has no root subform")
+	}
+
+	var data *xfaNode
+	if len(datasetsXML) > 0 {
+		datasets, err := parseXFANode(datasetsXML)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse XFA datasets: %w", err)
+		}
+		if dataNode := datasets.find("data"); dataNode != nil && len(dataNode.children) > 0 {
+			data = dataNode.children[0]
+		}
+	}
+
+	if opts.PageSize.Width <= 0 || opts.PageSize.Height <= 0 {
+		opts.PageSize = write.PageSizeLetter
+	}
+	l := &xfaLayout{opts: opts, data: data, used: make(map[*xfaNode]int)}
+	l.collectPageAreas(root.child("pageSet"))
+	if len(l.pageAreas) == 0 {
+		l.pageAreas = []*xfaNode{{tag: "pageArea"}}
+	}
+
+	l.newPage("")
+	width := l.contentArea().w
+	if root.attr("layout") == "position" {
+		items, _, h := l.place(root, newDataScope(data), width)
+		l.paginate([]xfaBlock{{h: h, items: items}})
+	} else {
+		l.paginate(l.flow(root, newDataScope(data), 0, width))
+	}
+	if verbose {
+		log.Printf("Rendered XFA form: %d pages, %d fields", len(l.pages), l.fields)
+	}
+	return l.write()
+}
+
+// xfaNode is an element of an XFA packet
+type xfaNode struct {
+	tag      string // local name
+	attrs    map[string]string
+	children []*xfaNode
+	text     string // character data directly inside the element
+}
+
+// parseXFANode parses an XFA packet into its root element
+func parseXFANode(data []byte) (*xfaNode, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	var stack []*xfaNode
+	var root *xfaNode
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &xfaNode{tag: t.Name.Local, attrs: make(map[string]string, len(t.Attr))}
+			for _, a := range t.Attr {
+				n.attrs[a.Name.Local] = a.Value
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			} else if root == nil {
+				root = n
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		}
+	}
+	if root == nil {
+		return nil, fmt.Errorf("no root element")
+	}
+	return root, nil
+}
+
+func (n *xfaNode) attr(name string) string {
+	if n == nil {
+		return ""
+	}
+	return n.attrs[name]
+}
+
+// child returns the first child element with the tag
+func (n *xfaNode) child(tag string) *xfaNode {
+	if n == nil {
+		return nil
+	}
+	for _, c := range n.children {
+		if c.tag == tag {
+			return c
+		}
+	}
+	return nil
+}
+
+// find returns the first element with the tag, depth first from n itself
+func (n *xfaNode) find(tag string) *xfaNode {
+	if n == nil {
+		return nil
+	}
+	if n.tag == tag {
+		return n
+	}
+	for _, c := range n.children {
+		if found := c.find(tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// textContent returns the character data of n and its descendants, with
+// paragraphs of rich text on lines of their own
+func (n *xfaNode) textContent() string {
+	if n == nil {
+		return ""
+	}
+	var b strings.Builder
+	var walk func(*xfaNode)
+	walk = func(n *xfaNode) {
+		if n.tag == "p" && b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(n.text)
+		for _, c := range n.children {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.TrimSpace(b.String())
+}
+
+// measure converts an XFA measurement such as "8.5in", "20mm" or "12pt" to
+// points; a number without a unit is in inches
+func measure(value string, def float64) float64 {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return def
+	}
+	units := map[string]float64{"in": 72, "pt": 1, "mm": 72 / 25.4, "cm": 72 / 2.54, "mp": 0.001}
+	scale := 72.0
+	for suffix, s := range units {
+		if strings.HasSuffix(value, suffix) {
+			value, scale = strings.TrimSuffix(value, suffix), s
+			break
+		}
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return def
+	}
+	return v * scale
+}
+
+// occurrences returns the min, max (-1 for unbounded) and initial occurrences
+// of a subform or page area, each defaulting to def
+func occurrences(n *xfaNode, def int) (min, max, initial int) {
+	occur := n.child("occur")
+	atoi := func(s string, d int) int {
+		if v, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+			return v
+		}
+		return d
+	}
+	min = atoi(occur.attr("min"), def)
+	max = atoi(occur.attr("max"), def)
+	initial = atoi(occur.attr("initial"), min)
+	return min, max, initial
+}
+
+// dataScope is the data group that the fields and subforms of a container
+// bind to, with how many data nodes of each name are already bound
+type dataScope struct {
+	node *xfaNode
+	used map[string]int
+}
+
+func newDataScope(node *xfaNode) *dataScope {
+	return &dataScope{node: node, used: make(map[string]int)}
+}
+
+// remaining returns the unbound data nodes with the name
+func (s *dataScope) remaining(name string) []*xfaNode {
+	var nodes []*xfaNode
+	if s.node == nil || name == "" {
+		return nodes
+	}
+	for _, c := range s.node.children {
+		if c.tag == name {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes[min(s.used[name], len(nodes)):]
+}
+
+// take binds the next data node with the name
+func (s *dataScope) take(name string) *xfaNode {
+	nodes := s.remaining(name)
+	if len(nodes) == 0 {
+		return nil
+	}
+	s.used[name]++
+	return nodes[0]
+}
+
+// xfaItem is a draw or field placed relative to the top left of its block
+type xfaItem struct {
+	node       *xfaNode
+	data       *xfaNode // Bound data value, nil if unbound
+	x, y, w, h float64
+}
+
+// xfaBlock is flowed content that is not split across content areas, or a
+// break to a new page when pageBreak is set
+type xfaBlock struct {
+	x, h      float64
+	items     []xfaItem
+	pageBreak bool
+	target    string // Page area to break to, by name or #id; "" for the next
+}
+
+// xfaArea is a content area, in page coordinates from the top left
+type xfaArea struct {
+	x, y, w, h float64
+}
+
+// xfaPage is a laid out page
+type xfaPage struct {
+	area  *xfaNode
+	size  write.PageSize
+	items []xfaItem // In page coordinates from the top left
+}
+
+// xfaLayout lays out a template onto pages
+type xfaLayout struct {
+	opts      RenderOptions
+	data      *xfaNode
+	pageAreas []*xfaNode
+	used      map[*xfaNode]int // pages made from each page area
+
+	pages   []*xfaPage
+	areas   []xfaArea // content areas of the current page
+	area    int       // current content area
+	cursor  float64   // space used in the current content area
+	started bool      // whether the current page has body content
+	fields  int       // fields placed, for the AcroForm
+}
+
+// collectPageAreas lists the page areas of a page set and its nested sets in
+// document order
+func (l *xfaLayout) collectPageAreas(pageSet *xfaNode) {
+	if pageSet == nil {
+		return
+	}
+	for _, c := range pageSet.children {
+		switch c.tag {
+		case "pageArea":
+			l.pageAreas = append(l.pageAreas, c)
+		case "pageSet":
+			l.collectPageAreas(c)
+		}
+	}
+}
+
+// nextPageArea picks the page area of a new page: the target, by name or
+// #id, or else the current one while its occurrences allow, then the next
+func (l *xfaLayout) nextPageArea(target string) *xfaNode {
+	if target != "" {
+		name := strings.TrimPrefix(target, "#")
+		for _, area := range l.pageAreas {
+			if area.attr("name") == name || area.attr("id") == name {
+				return area
+			}
+		}
+	}
+	start := 0
+	if len(l.pages) > 0 {
+		for i, area := range l.pageAreas {
+			if area == l.pages[len(l.pages)-1].area {
+				start = i
+			}
+		}
+	}
+	for i := start; i < len(l.pageAreas); i++ {
+		if _, max, _ := occurrences(l.pageAreas[i], -1); max < 0 || l.used[l.pageAreas[i]] < max {
+			return l.pageAreas[i]
+		}
+	}
+	return l.pageAreas[len(l.pageAreas)-1]
+}
+
+// newPage starts a page with the content of its page area
+func (l *xfaLayout) newPage(target string) {
+	area := l.nextPageArea(target)
+	l.used[area]++
+	page := &xfaPage{area: area, size: l.pageSize(area)}
+	l.pages = append(l.pages, page)
+
+	l.areas = l.areas[:0]
+	for _, c := range area.children {
+		if c.tag == "contentArea" {
+			l.areas = append(l.areas, xfaArea{
+				x: measure(c.attr("x"), 0),
+				y: measure(c.attr("y"), 0),
+				w: measure(c.attr("w"), page.size.Width),
+				h: measure(c.attr("h"), page.size.Height),
+			})
+		}
+	}
+	if len(l.areas) == 0 {
+		l.areas = append(l.areas, xfaArea{
+			x: xfaPageMargin, y: xfaPageMargin,
+			w: page.size.Width - 2*xfaPageMargin, h: page.size.Height - 2*xfaPageMargin,
+		})
+	}
+	l.area, l.cursor, l.started = 0, 0, false
+
+	// Page areas hold positioned content repeated on each of their pages
+	items, _, _ := l.place(area, newDataScope(nil), page.size.Width)
+	page.items = append(page.items, items...)
+}
+
+// pageSize returns the size of a page area's medium
+func (l *xfaLayout) pageSize(area *xfaNode) write.PageSize {
+	medium := area.child("medium")
+	size := l.opts.PageSize
+	switch strings.ToLower(medium.attr("stock")) {
+	case "letter":
+		size = write.PageSizeLetter
+	case "legal":
+		size = write.PageSizeLegal
+	case "a4":
+		size = write.PageSizeA4
+	case "a3":
+		size = write.PageSizeA3
+	case "a5":
+		size = write.PageSizeA5
+	}
+	size.Width = measure(medium.attr("short"), size.Width)
+	size.Height = measure(medium.attr("long"), size.Height)
+	if medium.attr("orientation") == "landscape" {
+		size.Width, size.Height = size.Height, size.Width
+	}
+	return size
+}
+
+func (l *xfaLayout) contentArea() xfaArea {
+	return l.areas[l.area]
+}
+
+// paginate places blocks in the content areas, moving on to the next
+// content area or page when a block doesn't fit
+func (l *xfaLayout) paginate(blocks []xfaBlock) {
+	for _, block := range blocks {
+		if block.pageBreak {
+			if l.started || block.target != "" && block.target != l.pages[len(l.pages)-1].area.attr("name") {
+				l.newPage(block.target)
+			}
+			continue
+		}
+		if l.cursor > 0 && l.cursor+block.h > l.contentArea().h {
+			if l.area+1 < len(l.areas) {
+				l.area++
+				l.cursor = 0
+			} else {
+				l.newPage("")
+			}
+		}
+		area := l.contentArea()
+		page := l.pages[len(l.pages)-1]
+		for _, item := range block.items {
+			item.x += area.x + block.x
+			item.y += area.y + l.cursor
+			page.items = append(page.items, item)
+		}
+		l.cursor += block.h
+		l.started = true
+	}
+}
+
+// contentChildren returns the children of a container, with the subforms of
+// subform sets in place of the sets
+func contentChildren(n *xfaNode) []*xfaNode {
+	var children []*xfaNode
+	for _, c := range n.children {
+		if c.tag == "subformSet" {
+			children = append(children, contentChildren(c)...)
+		} else {
+			children = append(children, c)
+		}
+	}
+	return children
+}
+
+// hidden reports whether a node takes no space in the layout
+func hidden(n *xfaNode) bool {
+	presence := n.attr("presence")
+	return presence == "hidden" || presence == "inactive"
+}
+
+// instances returns the data scopes of the occurrences of a subform: one per
+// data group of its name, within its occurrence limits. Unnamed subforms and
+// subforms that don't bind share the data scope of their parent.
+func (l *xfaLayout) instances(sf *xfaNode, scope *dataScope) []*dataScope {
+	min, max, initial := occurrences(sf, 1)
+	name := sf.attr("name")
+	match := sf.child("bind").attr("match")
+	var groups []*xfaNode
+	switch {
+	case match == "none" || name == "" && match != "dataRef":
+	case match == "dataRef":
+		if group := l.resolveRef(sf.child("bind").attr("ref"), scope); group != nil {
+			groups = []*xfaNode{group}
+		}
+	default:
+		groups = scope.remaining(name)
+	}
+
+	n := len(groups)
+	if n == 0 {
+		n = initial
+	}
+	if n < min {
+		n = min
+	}
+	if max >= 0 && n > max {
+		n = max
+	}
+
+	scopes := make([]*dataScope, n)
+	for i := range scopes {
+		switch {
+		case match == "none" || name == "" && match != "dataRef":
+			scopes[i] = scope
+		case i < len(groups):
+			if match != "dataRef" {
+				scope.take(name)
+			}
+			scopes[i] = newDataScope(groups[i])
+		default:
+			scopes[i] = newDataScope(nil)
+		}
+	}
+	return scopes
+}
+
+// bindValue returns the data value a field or exclusion group binds to
+func (l *xfaLayout) bindValue(n *xfaNode, scope *dataScope) *xfaNode {
+	bind := n.child("bind")
+	switch bind.attr("match") {
+	case "none":
+		return nil
+	case "global":
+		return l.data.find(n.attr("name"))
+	case "dataRef":
+		return l.resolveRef(bind.attr("ref"), scope)
+	}
+	return scope.take(n.attr("name"))
+}
+
+// resolveRef resolves a data reference such as "$.address.city" from the
+// current data group or "$record.items.item[2]" from the data root
+func (l *xfaLayout) resolveRef(ref string, scope *dataScope) *xfaNode {
+	node := l.data
+	switch {
+	case strings.HasPrefix(ref, "$record"), strings.HasPrefix(ref, "$data"):
+		ref = ref[strings.IndexByte(ref+".", '.'):]
+	case strings.HasPrefix(ref, "$"):
+		node = scope.node
+		ref = ref[1:]
+	default:
+		node = scope.node
+	}
+	for _, part := range strings.Split(strings.Trim(ref, "."), ".") {
+		if part == "" || node == nil {
+			continue
+		}
+		index := 0
+		if open := strings.IndexByte(part, '['); open != -1 && strings.HasSuffix(part, "]") {
+			index, _ = strconv.Atoi(part[open+1 : len(part)-1])
+			part = part[:open]
+		}
+		var next *xfaNode
+		for _, c := range node.children {
+			if c.tag == part {
+				if index == 0 {
+					next = c
+					break
+				}
+				index--
+			}
+		}
+		node = next
+	}
+	return node
+}
+
+// breakBlock returns the page break a subform asks for before or after it
+func breakBlock(sf *xfaNode, before bool) (xfaBlock, bool) {
+	tag, attr, target := "breakAfter", "after", "afterTarget"
+	if before {
+		tag, attr, target = "breakBefore", "before", "beforeTarget"
+	}
+	if b := sf.child(tag); b != nil && (b.attr("targetType") == "pageArea" || b.attr("targetType") == "") {
+		return xfaBlock{pageBreak: true, target: b.attr("target")}, true
+	}
+	if b := sf.child("break"); b != nil && (b.attr(attr) == "pageArea" || b.attr(attr) == "pageEven" || b.attr(attr) == "pageOdd") {
+		return xfaBlock{pageBreak: true, target: b.attr(target)}, true
+	}
+	return xfaBlock{}, false
+}
+
+// insets returns the margin insets of a node: left, top, right, bottom
+func insets(n *xfaNode) (left, top, right, bottom float64) {
+	margin := n.child("margin")
+	return measure(margin.attr("leftInset"), 0), measure(margin.attr("topInset"), 0),
+		measure(margin.attr("rightInset"), 0), measure(margin.attr("bottomInset"), 0)
+}
+
+// flow lays out the content of a top-to-bottom subform as blocks that may go
+// to different content areas. Nested top-to-bottom subforms without a fixed
+// height flow as well; other content makes a block each, or a block per row
+// in a left-to-right flow.
+func (l *xfaLayout) flow(sf *xfaNode, scope *dataScope, x, width float64) []xfaBlock {
+	var blocks []xfaBlock
+	left, _, right, _ := insets(sf)
+	x += left
+	width -= left + right
+	rows := strings.HasPrefix(sf.attr("layout"), "lr-") || strings.HasPrefix(sf.attr("layout"), "rl-")
+
+	var row *xfaBlock
+	rowWidth := 0.0
+	add := func(items []xfaItem, w, h float64) {
+		if !rows {
+			blocks = append(blocks, xfaBlock{x: x, h: h, items: items})
+			return
+		}
+		if row != nil && rowWidth+w > width && rowWidth > 0 {
+			blocks = append(blocks, *row)
+			row = nil
+		}
+		if row == nil {
+			row = &xfaBlock{x: x}
+			rowWidth = 0
+		}
+		for _, item := range items {
+			item.x += rowWidth
+			row.items = append(row.items, item)
+		}
+		rowWidth += w
+		row.h = math.Max(row.h, h)
+	}
+
+	for _, c := range contentChildren(sf) {
+		if hidden(c) {
+			continue
+		}
+		switch c.tag {
+		case "subform", "area", "exclGroup":
+			scopes := []*dataScope{scope}
+			if c.tag == "subform" {
+				scopes = l.instances(c, scope)
+			}
+			for _, s := range scopes {
+				if b, ok := breakBlock(c, true); ok {
+					if row != nil {
+						blocks, row = append(blocks, *row), nil
+					}
+					blocks = append(blocks, b)
+				}
+				if c.tag == "subform" && !rows && c.attr("layout") == "tb" && c.attr("h") == "" {
+					blocks = append(blocks, l.flow(c, s, x, width)...)
+				} else {
+					items, w, h := l.place(c, s, width)
+					add(items, w, h)
+				}
+				if b, ok := breakBlock(c, false); ok {
+					if row != nil {
+						blocks, row = append(blocks, *row), nil
+					}
+					blocks = append(blocks, b)
+				}
+			}
+		case "field", "draw":
+			item := l.item(c, scope)
+			add([]xfaItem{item}, item.w, item.h)
+		}
+	}
+	if row != nil {
+		blocks = append(blocks, *row)
+	}
+	return blocks
+}
+
+// place lays out a container as a whole and returns its items relative to
+// its top left corner and its size. width is the space available to
+// left-to-right flows of a container without a fixed width.
+func (l *xfaLayout) place(sf *xfaNode, scope *dataScope, width float64) ([]xfaItem, float64, float64) {
+	left, top, right, bottom := insets(sf)
+	layout := sf.attr("layout")
+	if layout == "" || sf.tag == "pageArea" || sf.tag == "area" || sf.tag == "exclGroup" {
+		layout = "position"
+	}
+	avail := measure(sf.attr("w"), width) - left - right
+
+	var items []xfaItem
+	cursor, rowX, rowH := top, left, 0.0
+	extentW, extentH := 0.0, 0.0
+	put := func(childItems []xfaItem, node *xfaNode, w, h float64) {
+		var x, y float64
+		switch {
+		case layout == "position":
+			x, y = measure(node.attr("x"), 0)+left, measure(node.attr("y"), 0)+top
+			x, y = anchor(node.attr("anchorType"), x, y, w, h)
+		case strings.HasPrefix(layout, "lr-") || strings.HasPrefix(layout, "rl-") || layout == "row":
+			if layout != "row" && rowX > left && rowX-left+w > avail {
+				cursor += rowH
+				rowX, rowH = left, 0
+			}
+			x, y = rowX, cursor
+			rowX += w
+			rowH = math.Max(rowH, h)
+		default: // tb, table
+			x, y = left, cursor
+			cursor += h
+		}
+		for _, item := range childItems {
+			item.x += x
+			item.y += y
+			items = append(items, item)
+		}
+		extentW = math.Max(extentW, x+w)
+		extentH = math.Max(extentH, y+h)
+	}
+
+	for _, c := range contentChildren(sf) {
+		if hidden(c) {
+			continue
+		}
+		switch c.tag {
+		case "subform", "area", "exclGroup":
+			scopes := []*dataScope{scope}
+			var groupValue *xfaNode
+			if c.tag == "subform" {
+				scopes = l.instances(c, scope)
+			} else if c.tag == "exclGroup" {
+				groupValue = l.bindValue(c, scope)
+			}
+			for _, s := range scopes {
+				childItems, w, h := l.place(c, s, avail)
+				if c.tag == "exclGroup" {
+					for i := range childItems {
+						if childItems[i].node.tag == "field" {
+							childItems[i].data = groupValue
+						}
+					}
+				}
+				put(childItems, c, w, h)
+			}
+		case "field", "draw":
+			if sf.tag == "exclGroup" {
+				item := l.item(c, newDataScope(nil))
+				put([]xfaItem{item}, c, item.w, item.h)
+				continue
+			}
+			item := l.item(c, scope)
+			put([]xfaItem{item}, c, item.w, item.h)
+		}
+	}
+	return items, measure(sf.attr("w"), extentW+right), measure(sf.attr("h"), extentH+bottom)
+}
+
+// anchor moves the position of a node from its anchor point to its top left
+func anchor(anchorType string, x, y, w, h float64) (float64, float64) {
+	switch {
+	case strings.HasSuffix(anchorType, "Center"):
+		x -= w / 2
+	case strings.HasSuffix(anchorType, "Right"):
+		x -= w
+	}
+	switch {
+	case strings.HasPrefix(anchorType, "middle"):
+		y -= h / 2
+	case strings.HasPrefix(anchorType, "bottom"):
+		y -= h
+	}
+	return x, y
+}
+
+// item binds a draw or field and sizes it, growing to fit its text where the
+// template gives no width or height
+func (l *xfaLayout) item(n *xfaNode, scope *dataScope) xfaItem {
+	item := xfaItem{node: n}
+	if n.tag == "field" {
+		item.data = l.bindValue(n, scope)
+		l.fields++
+	}
+	f := fontOf(n, nil)
+	left, top, right, bottom := insets(n)
+	text := item.text()
+	item.w = measure(n.attr("w"), 0)
+	if item.w == 0 {
+		item.w = measure(n.attr("minW"), 0)
+		if caption := captionText(n); caption != "" {
+			item.w += write.StandardTextWidth(caption, fontOf(n.child("caption"), n).name, f.size) + f.size/2
+		}
+		item.w = math.Max(item.w, write.StandardTextWidth(text, f.name, f.size)+left+right)
+	}
+	item.h = measure(n.attr("h"), 0)
+	if item.h == 0 {
+		lines := 1
+		if n.tag == "draw" {
+			lines = max(1, len(wrapText(text, f, item.w-left-right)))
+		}
+		item.h = math.Max(measure(n.attr("minH"), 0), float64(lines)*f.size*xfaLineSpacing+top+bottom)
+	}
+	return item
+}
+
+// xfaFont is the standard font and size text is shown with
+type xfaFont struct {
+	name string
+	size float64
+}
+
+// fontOf returns the font of a draw, field or caption; a caption without a
+// font of its own uses its field's
+func fontOf(n, fallback *xfaNode) xfaFont {
+	font := n.child("font")
+	if font == nil && fallback != nil {
+		font = fallback.child("font")
+	}
+	typeface := strings.ToLower(font.attr("typeface"))
+	if typeface == "" {
+		typeface = strings.ToLower(xfaDefaultTypeface)
+	}
+	bold := font.attr("weight") == "bold"
+	italic := font.attr("posture") == "italic"
+
+	var name string
+	switch {
+	case strings.Contains(typeface, "courier") || strings.Contains(typeface, "mono"):
+		name = "Courier" + styleSuffix(bold, italic, "Oblique", "")
+	case strings.Contains(typeface, "times") || strings.Contains(typeface, "roman") ||
+		strings.Contains(typeface, "georgia") || strings.Contains(typeface, "minion") ||
+		strings.Contains(typeface, "serif") && !strings.Contains(typeface, "sans"):
+		name = "Times" + styleSuffix(bold, italic, "Italic", "-Roman")
+	case strings.Contains(typeface, "symbol"):
+		name = "Symbol"
+	default:
+		name = "Helvetica" + styleSuffix(bold, italic, "Oblique", "")
+	}
+	return xfaFont{name: name, size: measure(font.attr("size"), xfaDefaultFontSize)}
+}
+
+// styleSuffix returns the suffix of a standard font style, such as
+// "-BoldOblique", or regular for the regular style
+func styleSuffix(bold, italic bool, slanted, regular string) string {
+	switch {
+	case bold && italic:
+		return "-Bold" + slanted
+	case bold:
+		return "-Bold"
+	case italic:
+		return "-" + slanted
+	}
+	return regular
+}
+
+// wrapText breaks text into lines that fit width, at spaces and line breaks
+func wrapText(text string, f xfaFont, width float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if line != "" && width > 0 && write.StandardTextWidth(candidate, f.name, f.size) > width {
+				lines = append(lines, line)
+				candidate = word
+			}
+			line = candidate
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 1 && lines[0] == "" {
+		return nil
+	}
+	return lines
+}
+
+// uiKind returns the widget type of a field: textEdit, checkButton,
+// choiceList, button, signature and so on
+func uiKind(field *xfaNode) string {
+	if ui := field.child("ui"); ui != nil && len(ui.children) > 0 {
+		for _, c := range ui.children {
+			if c.tag != "picture" && c.tag != "extras" {
+				return c.tag
+			}
+		}
+	}
+	return "textEdit"
+}
+
+// captionText returns the caption of a field
+func captionText(field *xfaNode) string {
+	caption := field.child("caption")
+	if caption == nil || hidden(caption) || caption.attr("presence") == "invisible" {
+		return ""
+	}
+	return caption.child("value").textContent()
+}
+
+// items returns the display items of a field and the values saved for them
+func items(field *xfaNode) (display, save []string) {
+	for _, list := range field.children {
+		if list.tag != "items" {
+			continue
+		}
+		var values []string
+		for _, c := range list.children {
+			values = append(values, c.textContent())
+		}
+		if list.attr("save") == "1" {
+			save = values
+		} else if display == nil {
+			display = values
+		}
+	}
+	if save == nil {
+		save = display
+	}
+	if display == nil {
+		display = save
+	}
+	return display, save
+}
+
+// text returns the value of a draw or field: the bound data, or else the
+// template default. Choice lists show the display text of a saved value.
+func (item xfaItem) text() string {
+	var value string
+	if item.data != nil {
+		value = item.data.textContent()
+	} else if v := item.node.child("value"); v != nil {
+		for _, c := range v.children {
+			if c.tag != "rectangle" && c.tag != "line" && c.tag != "arc" && c.tag != "image" {
+				value = c.textContent()
+				break
+			}
+		}
+	}
+	if item.node.tag == "field" && uiKind(item.node) == "choiceList" {
+		display, save := items(item.node)
+		for i, s := range save {
+			if s == value && i < len(display) {
+				return display[i]
+			}
+		}
+	}
+	return value
+}
+
+// checked reports whether a check button is on: its value is the first of
+// its items, "1" by default
+func (item xfaItem) checked() bool {
+	on := "1"
+	if _, save := items(item.node); len(save) > 0 {
+		on = save[0]
+	}
+	return item.text() == on
+}
+
+// write draws the pages and returns the PDF
+func (l *xfaLayout) write() ([]byte, error) {
+	builder := write.NewSimplePDFBuilder()
+	var fields *acroform.FieldBuilder
+	if !l.opts.Flatten && l.fields > 0 {
+		fields = acroform.NewFieldBuilder(builder.Writer())
+	}
+	p := &xfaPainter{fields: fields, names: make(map[string]int)}
+	for i, page := range l.pages {
+		p.page, p.index, p.height = builder.AddPage(page.size), i, page.size.Height
+		p.fonts = make(map[string]string)
+		for _, item := range page.items {
+			p.paint(item)
+		}
+		builder.FinalizePage(p.page)
+	}
+	if fields != nil && p.widgets > 0 {
+		builder.SetAcroForm(fields)
+	}
+	return builder.Bytes()
+}
+
+// xfaPainter draws laid out items on a page
+type xfaPainter struct {
+	page    *write.PageBuilder
+	index   int               // page index, for widgets
+	height  float64           // page height, to flip y
+	fonts   map[string]string // standard font -> resource name on the page
+	fields  *acroform.FieldBuilder
+	names   map[string]int // widget names used
+	widgets int
+}
+
+func (p *xfaPainter) font(name string) string {
+	if res, ok := p.fonts[name]; ok {
+		return res
+	}
+	res := p.page.AddStandardFont(name)
+	p.fonts[name] = res
+	return res
+}
+
+// paint draws a draw or field
+func (p *xfaPainter) paint(item xfaItem) {
+	n := item.node
+	if n.attr("presence") == "invisible" {
+		return
+	}
+	left, top, right, bottom := insets(n)
+	x, y, w, h := item.x+left, item.y+top, item.w-left-right, item.h-top-bottom
+	f := fontOf(n, nil)
+
+	if n.tag == "draw" {
+		p.border(n.child("border"), item.x, item.y, item.w, item.h)
+		value := n.child("value")
+		switch {
+		case value.child("rectangle") != nil:
+			p.border(value.child("rectangle"), x, y, w, h)
+		case value.child("line") != nil:
+			p.line(value.child("line"), x, y, w, h)
+		default:
+			p.text(wrapText(item.text(), f, w), f, n.child("para"), x, y, w, h)
+		}
+		return
+	}
+
+	// Split the field between its caption and its content
+	if caption := n.child("caption"); captionText(n) != "" {
+		cf := fontOf(caption, n)
+		placement := caption.attr("placement")
+		reserve := measure(caption.attr("reserve"), -1)
+		if reserve < 0 {
+			if placement == "top" || placement == "bottom" {
+				reserve = cf.size * xfaLineSpacing
+			} else {
+				reserve = write.StandardTextWidth(captionText(n), cf.name, cf.size) + cf.size/2
+			}
+		}
+		cx, cy, cw, ch := x, y, w, h
+		switch placement {
+		case "right":
+			cx, cw = x+w-reserve, reserve
+			w -= reserve
+		case "top":
+			ch = reserve
+			y, h = y+reserve, h-reserve
+		case "bottom":
+			cy, ch = y+h-reserve, reserve
+			h -= reserve
+		default:
+			cw = reserve
+			x, w = x+reserve, w-reserve
+		}
+		cl, ct, cr, cb := insets(caption)
+		p.text(wrapText(captionText(n), cf, cw-cl-cr), cf, caption.child("para"), cx+cl, cy+ct, cw-cl-cr, ch-ct-cb)
+	}
+
+	p.border(n.child("border"), item.x, item.y, item.w, item.h)
+	kind := uiKind(n)
+	ui := n.child("ui").child(kind)
+	if kind == "checkButton" {
+		size := math.Min(measure(ui.attr("size"), xfaCheckSize), math.Min(w, h))
+		x, y, w, h = x+(w-size)/2, y+(h-size)/2, size, size
+		if ui.child("border") == nil {
+			p.box(x, y, w, h, xfaDefaultEdge)
+		}
+	}
+	p.border(ui.child("border"), x, y, w, h)
+	if w <= 0 || h <= 0 {
+		return
+	}
+
+	// Buttons run scripts and signatures need signing; both are drawn only
+	if kind == "button" || kind == "signature" || kind == "imageEdit" {
+		return
+	}
+	if p.fields == nil {
+		switch kind {
+		case "checkButton":
+			if item.checked() {
+				size := h * 0.8
+				p.page.Content().BeginText().SetFont(p.font("ZapfDingbats"), size).
+					SetTextPosition(x+(w-size*0.846)/2, p.height-(y+h/2+size*0.35)).ShowText("4").EndText()
+			}
+		default:
+			lines := []string{item.text()}
+			if ui.attr("multiLine") == "1" {
+				lines = wrapText(item.text(), f, w-2)
+			}
+			p.text(lines, f, n.child("para"), x+1, y, w-2, h)
+		}
+		return
+	}
+	p.widget(item, kind, ui, []float64{x, p.height - (y + h), x + w, p.height - y})
+}
+
+// widget adds the AcroForm field of a field item
+func (p *xfaPainter) widget(item xfaItem, kind string, ui *xfaNode, rect []float64) {
+	n := item.node
+	name := n.attr("name")
+	if name == "" {
+		name = "field"
+	}
+	if used := p.names[name]; used > 0 {
+		p.names[name]++
+		name = fmt.Sprintf("%s[%d]", name, used)
+	} else {
+		p.names[name] = 1
+	}
+	p.widgets++
+
+	var fd *acroform.FieldDef
+	switch kind {
+	case "checkButton":
+		fd = p.fields.AddCheckbox(name, rect, p.index).SetValue(item.checked())
+	case "choiceList":
+		display, _ := items(n)
+		fd = p.fields.AddChoiceField(name, rect, p.index, display)
+		if ui.attr("open") != "always" && ui.attr("open") != "multiSelect" {
+			fd.Flags |= acroform.FlagCombo
+		}
+		if value := item.text(); value != "" {
+			fd.SetValue(value)
+		}
+	default:
+		fd = p.fields.AddTextField(name, rect, p.index)
+		if ui.attr("multiLine") == "1" {
+			fd.Flags |= acroform.FlagMultiline
+		}
+		if maxChars, err := strconv.Atoi(n.child("value").child("text").attr("maxChars")); err == nil && maxChars > 0 {
+			fd.SetMaxLength(maxChars)
+		}
+		if value := item.text(); value != "" {
+			fd.SetValue(value)
+		}
+	}
+	access := n.attr("access")
+	fd.SetReadOnly(access == "readOnly" || access == "protected" || access == "nonInteractive")
+	fd.SetRequired(n.child("validate").attr("nullTest") == "error")
+}
+
+// text draws lines of text in a box, aligned by a para element
+func (p *xfaPainter) text(lines []string, f xfaFont, para *xfaNode, x, y, w, h float64) {
+	if len(lines) == 0 {
+		return
+	}
+	lineHeight := f.size * xfaLineSpacing
+	top := y
+	switch para.attr("vAlign") {
+	case "middle":
+		top = y + (h-lineHeight*float64(len(lines)))/2
+	case "bottom":
+		top = y + h - lineHeight*float64(len(lines))
+	}
+	res := p.font(f.name)
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		lx := x
+		switch para.attr("hAlign") {
+		case "center":
+			lx = x + (w-write.StandardTextWidth(line, f.name, f.size))/2
+		case "right":
+			lx = x + w - write.StandardTextWidth(line, f.name, f.size)
+		}
+		baseline := top + float64(i)*lineHeight + f.size*0.8 + (lineHeight-f.size)/2
+		cs := p.page.Content().BeginText().SetFont(res, f.size).SetTextPosition(lx, p.height-baseline)
+		cs.ShowTextWithOptions(line, nil)
+		cs.EndText()
+	}
+}
+
+// edges returns the visible edges of a border or rectangle in the order
+// top, right, bottom, left; a single edge applies to all four sides
+func edges(border *xfaNode) []*xfaNode {
+	var list []*xfaNode
+	for _, c := range border.children {
+		if c.tag == "edge" {
+			list = append(list, c)
+		}
+	}
+	if len(list) == 0 {
+		list = []*xfaNode{{tag: "edge"}}
+	}
+	for len(list) < 4 {
+		list = append(list, list[len(list)-1])
+	}
+	return list[:4]
+}
+
+// setColor sets the stroke or fill color from a color element's "r,g,b" value
+func (p *xfaPainter) setColor(color *xfaNode, stroke bool) {
+	r, g, b := 0.0, 0.0, 0.0
+	if parts := strings.Split(color.attr("value"), ","); len(parts) == 3 {
+		rgb := make([]float64, 3)
+		for i, part := range parts {
+			v, _ := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			rgb[i] = v / 255
+		}
+		r, g, b = rgb[0], rgb[1], rgb[2]
+	}
+	if stroke {
+		p.page.Content().SetStrokeColorRGB(r, g, b)
+	} else {
+		p.page.Content().SetFillColorRGB(r, g, b)
+	}
+}
+
+// border draws the fill and edges of a border or rectangle element
+func (p *xfaPainter) border(border *xfaNode, x, y, w, h float64) {
+	if border == nil || hidden(border) || border.attr("presence") == "invisible" {
+		return
+	}
+	cs := p.page.Content()
+	if fill := border.child("fill"); fill != nil && fill.child("color") != nil && !hidden(fill) {
+		cs.SaveState()
+		p.setColor(fill.child("color"), false)
+		cs.Rectangle(x, p.height-(y+h), w, h).Fill()
+		cs.RestoreState()
+	}
+	sides := [4][4]float64{
+		{x, y, x + w, y},         // top
+		{x + w, y, x + w, y + h}, // right
+		{x, y + h, x + w, y + h}, // bottom
+		{x, y, x, y + h},         // left
+	}
+	for i, edge := range edges(border) {
+		if hidden(edge) || edge.attr("presence") == "invisible" {
+			continue
+		}
+		cs.SaveState()
+		cs.SetLineWidth(measure(edge.attr("thickness"), xfaDefaultEdge))
+		p.setColor(edge.child("color"), true)
+		s := sides[i]
+		cs.MoveTo(s[0], p.height-s[1]).LineTo(s[2], p.height-s[3]).Stroke()
+		cs.RestoreState()
+	}
+}
+
+// box strokes a plain rectangle
+func (p *xfaPainter) box(x, y, w, h, thickness float64) {
+	cs := p.page.Content()
+	cs.SaveState().SetLineWidth(thickness).Rectangle(x, p.height-(y+h), w, h).Stroke().RestoreState()
+}
+
+// line draws a line element across its box: from the top left to the bottom
+// right, or from the bottom left to the top right when its slope is "/"
+func (p *xfaPainter) line(line *xfaNode, x, y, w, h float64) {
+	edge := line.child("edge")
+	if hidden(line) || hidden(edge) {
+		return
+	}
+	y0, y1 := y, y+h
+	if line.attr("slope") == "/" {
+		y0, y1 = y+h, y
+	}
+	cs := p.page.Content()
+	cs.SaveState()
+	cs.SetLineWidth(measure(edge.attr("thickness"), xfaDefaultEdge))
+	p.setColor(edge.child("color"), true)
+	cs.MoveTo(x, p.height-y0).LineTo(x+w, p.height-y1).Stroke()
+	cs.RestoreState()
+}
@@ -0,0 +1,179 @@
+package xfa
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/forms/acroform"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+const renderTestTemplate = `<template xmlns="http://www.xfa.org/schema/xfa-template/3.3/">
+ <subform name="form1" layout="tb">
+  <pageSet>
+   <pageArea name="Page1" id="P1">
+    <contentArea x="0.5in" y="1in" w="7.5in" h="9in"/>
+    <medium stock="letter" short="8.5in" long="11in"/>
+    <draw name="header" x="0.5in" y="0.25in" w="7.5in" h="0.5in">
+     <value><text>Order Form</text></value>
+     <font typeface="Arial" size="14pt" weight="bold"/>
+    </draw>
+   </pageArea>
+   <pageArea name="Terms">
+    <contentArea x="0.5in" y="0.5in" w="7.5in" h="10in"/>
+    <medium stock="letter" short="8.5in" long="11in"/>
+   </pageArea>
+  </pageSet>
+  <subform name="customer" layout="position" w="7.5in" h="1in">
+   <field name="name" x="0" y="0" w="4in" h="0.3in">
+    <ui><textEdit/></ui>
+    <caption reserve="1in"><value><text>Name</text></value></caption>
+    <border><edge/></border>
+    <validate nullTest="error"/>
+   </field>
+   <field name="rush" x="4.5in" y="0" w="1in" h="0.3in">
+    <ui><checkButton/></ui>
+    <items><integer>1</integer><integer>0</integer></items>
+   </field>
+   <field name="country" x="0" y="0.5in" w="3in" h="0.3in">
+    <ui><choiceList/></ui>
+    <items><text>Germany</text><text>France</text></items>
+    <items save="1"><text>DE</text><text>FR</text></items>
+   </field>
+  </subform>
+  <subform name="item" layout="lr-tb" w="7.5in">
+   <occur min="0" max="-1"/>
+   <field name="desc" w="5in" h="20pt"><ui><textEdit/></ui></field>
+   <field name="qty" w="1in" h="20pt" access="readOnly"><ui><numericEdit/></ui></field>
+  </subform>
+  <subform name="terms" layout="tb">
+   <breakBefore targetType="pageArea" target="Terms"/>
+   <draw name="text" w="7.5in"><value><text>Goods remain our property until paid in full.</text></value></draw>
+  </subform>
+ </subform>
+</template>`
+
+// renderTestDatasets fills the order form with items order lines
+func renderTestDatasets(items int) string {
+	var b strings.Builder
+	b.WriteString(`<xfa:datasets xmlns:xfa="http://www.xfa.org/schema/xfa-data/1.0/"><xfa:data><form1>`)
+	b.WriteString(`<customer><name>Jane Doe</name><rush>1</rush><country>FR</country></customer>`)
+	for i := 1; i <= items; i++ {
+		fmt.Fprintf(&b, "<item><desc>Bolt %d</desc><qty>%d</qty></item>", i, i*10)
+	}
+	b.WriteString(`</form1></xfa:data></xfa:datasets>`)
+	return b.String()
+}
+
+// renderTestPDF builds an XFA form PDF with the order form
+func renderTestPDF(t *testing.T, items int) []byte {
+	pdfBytes, err := BuildPDFFromXFAStreams(&XFAStreams{
+		Template: &XFAStreamInfo{Data: []byte(renderTestTemplate)},
+		Datasets: &XFAStreamInfo{Data: []byte(renderTestDatasets(items))},
+	}, false)
+	if err != nil {
+		t.Fatalf("Failed to build XFA PDF: %v", err)
+	}
+	return pdfBytes
+}
+
+func TestRender(t *testing.T) {
+	out, err := Render(renderTestPDF(t, 3), nil, RenderOptions{}, false)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	pdf, err := parse.Open(out)
+	if err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+	if n, _ := pdf.PageCount(); n != 2 {
+		t.Errorf("Expected the order and the terms page, got %d pages", n)
+	}
+
+	form, err := acroform.ExtractAcroForm(out, nil, false)
+	if err != nil {
+		t.Fatalf("ExtractAcroForm failed: %v", err)
+	}
+	fields := make(map[string]*acroform.Field)
+	for _, field := range form.Fields {
+		fields[field.T] = field
+	}
+	if len(fields) != 9 {
+		t.Errorf("Expected 9 widgets, got %d", len(fields))
+	}
+	for name, want := range map[string]string{"name": "Jane Doe", "country": "France", "desc[2]": "Bolt 3", "qty[1]": "20"} {
+		if field := fields[name]; field == nil || fmt.Sprint(field.V) != want {
+			t.Errorf("Field %s = %+v, want %q", name, field, want)
+		}
+	}
+	if field := fields["name"]; field == nil || field.Ff&acroform.FlagRequired == 0 {
+		t.Error("Expected name to be required")
+	}
+	if field := fields["qty"]; field == nil || field.Ff&acroform.FlagReadOnly == 0 {
+		t.Error("Expected qty to be read-only")
+	}
+	if field := fields["country"]; field == nil || field.Ff&acroform.FlagCombo == 0 {
+		t.Error("Expected country to be a combo box")
+	}
+	if field := fields["rush"]; field == nil || field.FT != "Btn" {
+		t.Errorf("Expected rush to be a check box, got %+v", field)
+	}
+
+	pages, err := extract.ExtractPages(out, pdf, false)
+	if err != nil {
+		t.Fatalf("ExtractPages failed: %v", err)
+	}
+	if text := pageText(pages[0].Text); !strings.Contains(text, "Order Form") || !strings.Contains(text, "Name") || strings.Contains(text, "Jane Doe") {
+		t.Errorf("Expected the header and caption but not the value on page 1, got %q", text)
+	}
+	if text := pageText(pages[1].Text); !strings.Contains(text, "Goods remain") || strings.Contains(text, "Order Form") {
+		t.Errorf("Expected the terms on page 2 without the header, got %q", text)
+	}
+}
+
+func TestRender_Flatten(t *testing.T) {
+	// 40 lines of 20pt don't fit in the 9in content area of the first page
+	out, err := Render(renderTestPDF(t, 40), nil, RenderOptions{Flatten: true}, false)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	pdf, err := parse.Open(out)
+	if err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+	if n, _ := pdf.PageCount(); n != 3 {
+		t.Fatalf("Expected 3 pages, got %d", n)
+	}
+	if form, err := acroform.ExtractAcroForm(out, nil, false); err == nil && len(form.Fields) > 0 {
+		t.Errorf("Expected no widgets in a flattened form, got %d", len(form.Fields))
+	}
+
+	pages, err := extract.ExtractPages(out, pdf, false)
+	if err != nil {
+		t.Fatalf("ExtractPages failed: %v", err)
+	}
+	first, second := pageText(pages[0].Text), pageText(pages[1].Text)
+	for _, want := range []string{"Jane Doe", "France", "Bolt 1", "Bolt 28"} {
+		if !strings.Contains(first, want) {
+			t.Errorf("Page 1 lacks %q: %q", want, first)
+		}
+	}
+	if strings.Contains(first, "Bolt 29") || !strings.Contains(second, "Bolt 29") || !strings.Contains(second, "Bolt 40") {
+		t.Errorf("Expected the order lines to continue on page 2 from Bolt 29, got %q", second)
+	}
+	if !strings.Contains(second, "Order Form") {
+		t.Error("Expected the page area header to repeat on page 2")
+	}
+}
+
+// pageText joins the text of a page
+func pageText(elements []types.TextElement) string {
+	var parts []string
+	for _, e := range elements {
+		parts = append(parts, e.Text)
+	}
+	return strings.Join(parts, " ")
+}
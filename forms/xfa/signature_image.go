@@ -0,0 +1,270 @@
+package xfa
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benedoc-inc/pdfer/core/manipulate"
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// maxWidgetParentDepth bounds the walk up a widget's /Parent chain
+const maxWidgetParentDepth = 32
+
+var (
+	xfaFieldValuePattern = regexp.MustCompile(`(?s)<value\b[^>]*?(?:/>|>.*?</value>)`)
+	xfaSOMIndexPattern   = regexp.MustCompile(`\[\d+\]`)
+	widgetAnnotsPattern  = regexp.MustCompile(`/Annots\s*(\[[^\]]*\]|\d+\s+\d+\s+R)`)
+	widgetRefPattern     = regexp.MustCompile(`(\d+)\s+\d+\s+R`)
+	widgetSubtypePattern = regexp.MustCompile(`/Subtype\s*/Widget\b`)
+	widgetNamePattern    = regexp.MustCompile(`/T\s*\(((?:\\.|[^\\)])*)\)`)
+	widgetParentPattern  = regexp.MustCompile(`/Parent\s+(\d+)\s+\d+\s+R`)
+	widgetRectPattern    = regexp.MustCompile(`/Rect\s*\[([^\]]*)\]`)
+)
+
+// SignatureImageOptions configures PlaceSignatureImage
+type SignatureImageOptions struct {
+	Timestamp       time.Time // Time drawn below the image; the zero time draws none
+	TimestampFormat string    // Go layout for the timestamp (default: "2006-01-02 15:04:05 MST")
+}
+
+// PlaceSignatureImage places a scanned signature image, JPEG or PNG, in an XFA
+// signature or image field. name is the field name, or a dotted path ending in
+// it such as "form1.Page1.Signature1"; SOM indexes like "[0]" are ignored.
+//
+// An image field (imageEdit) gets the image as its value in the template, which
+// XFA viewers show. For either kind, the image is also drawn on the page in the
+// area of each of the field's widget annotations, scaled to fit while keeping
+// its aspect ratio, so that viewers which render the PDF pages of a static form
+// show it too. The timestamp, if set, is drawn on a line below the image. A
+// signature field without widgets has nowhere to show the image and fails.
+// Encrypted documents are not supported.
+func PlaceSignatureImage(pdfBytes []byte, name string, img []byte, opts SignatureImageOptions, verbose bool) ([]byte, error) {
+	contentType := http.DetectContentType(img)
+	if contentType != "image/png" && contentType != "image/jpeg" {
+		return nil, fmt.Errorf("signature image must be JPEG or PNG, got %s", contentType)
+	}
+
+	pdf, err := parse.Open(pdfBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PDF: %v", err)
+	}
+	if pdf.IsEncrypted() {
+		return nil, fmt.Errorf("placing signature images in encrypted documents is not supported")
+	}
+
+	streams, err := ExtractAllXFAStreams(pdfBytes, nil, DebugOptions{}, verbose)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract XFA streams: %v", err)
+	}
+	if streams.Template == nil {
+		return nil, fmt.Errorf("template stream not found in XFA")
+	}
+	templateXML, wasCompressed, err := DecompressStream(streams.Template.Data)
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing template: %v", err)
+	}
+	wasCompressed = wasCompressed || streams.Template.Compressed
+
+	fieldName := name
+	if i := strings.LastIndex(fieldName, "."); i >= 0 {
+		fieldName = fieldName[i+1:]
+	}
+	fieldName = xfaSOMIndexPattern.ReplaceAllString(fieldName, "")
+	start, end, ok := findXFAField(string(templateXML), fieldName)
+	if !ok {
+		return nil, fmt.Errorf("field %q not found in XFA template", name)
+	}
+	field := string(templateXML[start:end])
+	isSignature := strings.Contains(field, "<signature")
+	if !isSignature && !strings.Contains(field, "<imageEdit") {
+		return nil, fmt.Errorf("field %q is not a signature or image field", name)
+	}
+
+	result := pdfBytes
+	if !isSignature {
+		value := fmt.Sprintf(`<value><image contentType="%s" transferEncoding="base64">%s</image></value>`,
+			contentType, base64.StdEncoding.EncodeToString(img))
+		updated := string(templateXML[:start]) + setXFAFieldValue(field, value) + string(templateXML[end:])
+
+		updatedTemplate := []byte(updated)
+		if wasCompressed {
+			updatedTemplate, err = CompressStream(updatedTemplate)
+			if err != nil {
+				return nil, fmt.Errorf("error compressing template: %v", err)
+			}
+		}
+		result, err = ReplaceStreamInPDF(pdfBytes, streams.Template.ObjectNumber, updatedTemplate, verbose)
+		if err != nil {
+			return nil, fmt.Errorf("error replacing template: %v", err)
+		}
+		if verbose {
+			log.Printf("Set image value of XFA field '%s' (%s, %d bytes)", fieldName, contentType, len(img))
+		}
+	}
+
+	// Object numbers are unchanged by the template update, so the widgets found
+	// in the original document are where they were
+	placements, err := fieldWidgetPlacements(pdf, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(placements) == 0 {
+		if isSignature {
+			return nil, fmt.Errorf("signature field %q has no widget to draw the image in", name)
+		}
+		return result, nil
+	}
+
+	caption := ""
+	if !opts.Timestamp.IsZero() {
+		layout := opts.TimestampFormat
+		if layout == "" {
+			layout = "2006-01-02 15:04:05 MST"
+		}
+		caption = opts.Timestamp.Format(layout)
+	}
+	for i := range placements {
+		placements[i].Image = img
+		placements[i].Caption = caption
+	}
+	if verbose {
+		log.Printf("Drawing signature image in %d widget areas of field '%s'", len(placements), name)
+	}
+	return manipulate.PlaceImages(result, nil, placements, verbose)
+}
+
+// findXFAField returns the extent of the first <field> element of an XFA
+// template with the given name
+func findXFAField(templateXML, name string) (int, int, bool) {
+	for _, loc := range xfaFieldTagPattern.FindAllStringIndex(templateXML, -1) {
+		tag := templateXML[loc[0]:loc[1]]
+		if m := xfaNameAttrPattern.FindStringSubmatch(tag); m == nil || m[1] != name {
+			continue
+		}
+		if strings.HasSuffix(tag, "/>") {
+			return loc[0], loc[1], true
+		}
+		// Fields do not nest, so the field ends at the next closing tag
+		if end := strings.Index(templateXML[loc[1]:], "</field>"); end != -1 {
+			return loc[0], loc[1] + end + len("</field>"), true
+		}
+	}
+	return 0, 0, false
+}
+
+// setXFAFieldValue replaces the <value> element of a <field> element, or adds
+// one when it has none
+func setXFAFieldValue(field, value string) string {
+	if loc := xfaFieldValuePattern.FindStringIndex(field); loc != nil {
+		return field[:loc[0]] + value + field[loc[1]:]
+	}
+	if strings.HasSuffix(field, "/>") {
+		return strings.TrimSuffix(field, "/>") + ">" + value + "</field>"
+	}
+	return strings.TrimSuffix(field, "</field>") + value + "</field>"
+}
+
+// fieldWidgetPlacements returns the page areas of the widget annotations whose
+// field name matches name, ignoring SOM indexes. A widget matches when its fully
+// qualified name is name or ends with "." followed by name.
+func fieldWidgetPlacements(pdf *parse.PDF, name string) ([]manipulate.ImagePlacement, error) {
+	name = xfaSOMIndexPattern.ReplaceAllString(name, "")
+	var placements []manipulate.ImagePlacement
+
+	it := pdf.Pages()
+	for it.Next() {
+		page := it.Page()
+		m := widgetAnnotsPattern.FindStringSubmatch(page.Dict)
+		if m == nil {
+			continue
+		}
+		annots := m[1]
+		if !strings.HasPrefix(annots, "[") {
+			objNum, _ := strconv.Atoi(widgetRefPattern.FindStringSubmatch(annots)[1])
+			obj, err := pdf.GetObject(objNum)
+			if err != nil {
+				continue
+			}
+			annots = string(obj)
+		}
+
+		for _, ref := range widgetRefPattern.FindAllStringSubmatch(annots, -1) {
+			objNum, _ := strconv.Atoi(ref[1])
+			obj, err := pdf.GetObject(objNum)
+			if err != nil || !widgetSubtypePattern.Match(obj) {
+				continue
+			}
+			fullName := xfaSOMIndexPattern.ReplaceAllString(widgetFieldName(pdf, string(obj)), "")
+			if fullName != name && !strings.HasSuffix(fullName, "."+name) {
+				continue
+			}
+			rect := widgetRectPattern.FindStringSubmatch(string(obj))
+			if rect == nil {
+				continue
+			}
+			var values []float64
+			for _, f := range strings.Fields(rect[1]) {
+				if v, err := strconv.ParseFloat(f, 64); err == nil {
+					values = append(values, v)
+				}
+			}
+			if len(values) < 4 {
+				continue
+			}
+			placements = append(placements, manipulate.ImagePlacement{
+				Page: page.Number,
+				Rect: types.Rectangle{
+					LowerX: min(values[0], values[2]), LowerY: min(values[1], values[3]),
+					UpperX: max(values[0], values[2]), UpperY: max(values[1], values[3]),
+				},
+			})
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read pages: %v", err)
+	}
+	return placements, nil
+}
+
+// widgetFieldName returns the fully qualified field name of a widget, joining
+// the partial names up its /Parent chain
+func widgetFieldName(pdf *parse.PDF, widget string) string {
+	var parts []string
+	obj := widget
+	for depth := 0; depth < maxWidgetParentDepth; depth++ {
+		if m := widgetNamePattern.FindStringSubmatch(obj); m != nil {
+			parts = append([]string{unescapeLiteral(m[1])}, parts...)
+		}
+		parent := widgetParentPattern.FindStringSubmatch(obj)
+		if parent == nil {
+			break
+		}
+		objNum, _ := strconv.Atoi(parent[1])
+		parentObj, err := pdf.GetObject(objNum)
+		if err != nil {
+			break
+		}
+		obj = string(parentObj)
+	}
+	return strings.Join(parts, ".")
+}
+
+// unescapeLiteral removes the backslash escapes of a literal string body
+func unescapeLiteral(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
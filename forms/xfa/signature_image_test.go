@@ -0,0 +1,114 @@
+package xfa
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/core/write"
+)
+
+// buildSignatureTestPDF creates a static XFA form with the signature field
+// Signature1, which has a widget on the page, and the image field Photo
+func buildSignatureTestPDF(t *testing.T) []byte {
+	t.Helper()
+	template := `<template xmlns="http://www.xfa.org/schema/xfa-template/3.3/"><subform name="form1">` +
+		`<field name="Signature1" w="60mm" h="15mm"><ui><signature/></ui></field>` +
+		`<field name="Photo" w="30mm" h="30mm"><ui><imageEdit/></ui><value><image/></value></field>` +
+		`</subform></template>`
+
+	w := write.NewPDFWriter()
+	templateNum := w.AddStreamObject(write.Dictionary{}, []byte(template), true)
+	datasetsNum := w.AddStreamObject(write.Dictionary{}, []byte(`<xfa:datasets xmlns:xfa="http://www.xfa.org/schema/xfa-data/1.0/"><xfa:data/></xfa:datasets>`), true)
+	contentNum := w.AddStreamObject(write.Dictionary{}, []byte("BT /F1 12 Tf 72 700 Td (Sign here) Tj ET"), false)
+	pagesNum := w.NextObjectNumber()
+	pageNum, formNum, widgetNum := pagesNum+1, pagesNum+2, pagesNum+3
+	w.AddObject([]byte(fmt.Sprintf("<</Type/Pages/Kids[%d 0 R]/Count 1>>", pageNum)))
+	w.AddObject([]byte(fmt.Sprintf("<</Type/Page/Parent %d 0 R/MediaBox[0 0 612 792]/Contents %d 0 R"+
+		"/Resources<</Font<</F1<</Type/Font/Subtype/Type1/BaseFont/Helvetica>>>>>>/Annots[%d 0 R]>>", pagesNum, contentNum, widgetNum)))
+	w.AddObject([]byte(fmt.Sprintf("<</T(form1[0])/Kids[%d 0 R]>>", widgetNum)))
+	w.AddObject([]byte(fmt.Sprintf("<</Type/Annot/Subtype/Widget/FT/Sig/T(Signature1[0])/Parent %d 0 R/Rect[200 680 400 730]>>", formNum)))
+	acroFormNum := w.AddObject([]byte(fmt.Sprintf("<</Fields[%d 0 R]/XFA[(template) %d 0 R(datasets) %d 0 R]>>", formNum, templateNum, datasetsNum)))
+	w.SetRoot(w.AddObject([]byte(fmt.Sprintf("<</Type/Catalog/Pages %d 0 R/AcroForm %d 0 R>>", pagesNum, acroFormNum))))
+
+	pdfBytes, err := w.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to write PDF: %v", err)
+	}
+	return pdfBytes
+}
+
+func TestPlaceSignatureImage(t *testing.T) {
+	pdfBytes := buildSignatureTestPDF(t)
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewGray(image.Rect(0, 0, 100, 25))); err != nil {
+		t.Fatalf("Failed to encode image: %v", err)
+	}
+
+	signedAt := time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)
+	out, err := PlaceSignatureImage(pdfBytes, "form1.Signature1", img.Bytes(), SignatureImageOptions{Timestamp: signedAt}, false)
+	if err != nil {
+		t.Fatalf("PlaceSignatureImage failed: %v", err)
+	}
+	doc, err := extract.ExtractContent(out, nil, false)
+	if err != nil {
+		t.Fatalf("Failed to extract PDF: %v", err)
+	}
+	page := doc.Pages[0]
+	if len(page.Images) != 1 {
+		t.Fatalf("Expected the signature image on the page, got %d images", len(page.Images))
+	}
+	if ref := page.Images[0]; ref.X < 200 || ref.X+ref.Width > 400.01 || ref.Y < 680 || ref.Y+ref.Height > 730.01 {
+		t.Errorf("Image at %g,%g %gx%g outside the widget", ref.X, ref.Y, ref.Width, ref.Height)
+	}
+	found := false
+	for _, e := range page.Text {
+		found = found || e.Text == "2024-03-15 10:30:00 UTC"
+	}
+	if !found {
+		t.Errorf("Timestamp not drawn: %+v", page.Text)
+	}
+
+	// The image field takes the image as its template value; it has no widget
+	original := append([]byte(nil), pdfBytes...)
+	out, err = PlaceSignatureImage(pdfBytes, "Photo", img.Bytes(), SignatureImageOptions{}, false)
+	if err != nil {
+		t.Fatalf("PlaceSignatureImage on an image field failed: %v", err)
+	}
+	if !bytes.Equal(pdfBytes, original) {
+		t.Error("PlaceSignatureImage modified its input")
+	}
+	streams, err := ExtractAllXFAStreams(out, nil, DebugOptions{}, false)
+	if err != nil {
+		t.Fatalf("Failed to extract XFA: %v", err)
+	}
+	templateXML, _, _ := DecompressStream(streams.Template.Data)
+	if !strings.Contains(string(templateXML), `<value><image contentType="image/png" transferEncoding="base64">iVBOR`) {
+		t.Errorf("Image value not set in template:\n%s", templateXML)
+	}
+
+	if _, err := PlaceSignatureImage(pdfBytes, "Missing", img.Bytes(), SignatureImageOptions{}, false); err == nil {
+		t.Error("Expected an error for a missing field")
+	}
+	if _, err := PlaceSignatureImage(pdfBytes, "Signature1", []byte("GIF89a"), SignatureImageOptions{}, false); err == nil {
+		t.Error("Expected an error for an unsupported image format")
+	}
+}
+
+func TestSetXFAFieldValue(t *testing.T) {
+	value := `<value><image>x</image></value>`
+	for field, want := range map[string]string{
+		`<field name="a"><ui><imageEdit/></ui><value><image/></value></field>`: `<field name="a"><ui><imageEdit/></ui>` + value + `</field>`,
+		`<field name="a"><ui><imageEdit/></ui></field>`:                        `<field name="a"><ui><imageEdit/></ui>` + value + `</field>`,
+		`<field name="a"/>`: `<field name="a">` + value + `</field>`,
+	} {
+		if got := setXFAFieldValue(field, value); got != want {
+			t.Errorf("setXFAFieldValue(%s) = %s", field, got)
+		}
+	}
+}
@@ -216,7 +216,7 @@ func findObjectHeaderByRegex(pdfBytes []byte, objNum, genNum int) (int, error) {
 
 // ReadObjectFromXRef reads an object using xref offset (PyPDF approach)
 // Matches PyPDF's get_object method (lines 413-490)
-func ReadObjectFromXRef(pdfBytes []byte, objNum, genNum int, xrefOffset int64, encryptInfo *types.PDFEncryption, verbose bool) ([]byte, error) {
+func ReadObjectFromXRef(pdfBytes []byte, objNum, genNum int, xrefOffset int64, encryptInfo *types.PDFEncryption, debug DebugOptions, verbose bool) ([]byte, error) {
 	// Step 1: Create stream and seek to xref offset (PyPDF line 423)
 	stream := NewPDFStream(pdfBytes)
 
@@ -367,7 +367,7 @@ func ReadObjectFromXRef(pdfBytes []byte, objNum, genNum int, xrefOffset int64, e
 				// Decrypt full content
 				decrypted, err := encrypt.DecryptObject(objContent, objNum, generation, encryptInfo)
 				if err != nil {
-					decrypted, err = decryptInChunks(objContent, objNum, generation, encryptInfo, verbose)
+					decrypted, err = decryptInChunks(objContent, objNum, generation, encryptInfo, debug, verbose)
 					if err != nil {
 						return nil, fmt.Errorf("failed to decrypt object: %v", err)
 					}
@@ -411,7 +411,7 @@ func ReadObjectFromXRef(pdfBytes []byte, objNum, genNum int, xrefOffset int64, e
 		if encryptInfo != nil {
 			decrypted, err := encrypt.DecryptObject(objContent, objNum, generation, encryptInfo)
 			if err != nil {
-				decrypted, err = decryptInChunks(objContent, objNum, generation, encryptInfo, verbose)
+				decrypted, err = decryptInChunks(objContent, objNum, generation, encryptInfo, debug, verbose)
 				if err != nil {
 					return nil, fmt.Errorf("failed to decrypt object: %v", err)
 				}
@@ -437,7 +437,7 @@ func ReadObjectFromXRef(pdfBytes []byte, objNum, genNum int, xrefOffset int64, e
 	// Step 5: Decrypt the parsed dictionary's values (PyPDF lines 482-490)
 	// PyPDF decrypts the parsed object, not raw bytes
 	if encryptInfo != nil {
-		decryptedDict, err := decryptParsedDictionary(parsedDict, objNum, generation, encryptInfo, verbose)
+		decryptedDict, err := decryptParsedDictionary(parsedDict, objNum, generation, encryptInfo, debug, verbose)
 		if err != nil {
 			return nil, fmt.Errorf("failed to decrypt dictionary values: %v", err)
 		}
@@ -655,7 +655,7 @@ func readPDFObject(stream *PDFStream, pdfBytes []byte) ([]byte, error) {
 }
 
 // decryptParsedDictionary decrypts the values in a parsed dictionary (PyPDF's decrypt_object)
-func decryptParsedDictionary(entries []ParsedDictEntry, objNum, genNum int, encryptInfo *types.PDFEncryption, verbose bool) ([]ParsedDictEntry, error) {
+func decryptParsedDictionary(entries []ParsedDictEntry, objNum, genNum int, encryptInfo *types.PDFEncryption, debug DebugOptions, verbose bool) ([]ParsedDictEntry, error) {
 	result := make([]ParsedDictEntry, len(entries))
 
 	for i, entry := range entries {
@@ -665,7 +665,7 @@ func decryptParsedDictionary(entries []ParsedDictEntry, objNum, genNum int, encr
 		decrypted, err := encrypt.DecryptObject(entry.Value, objNum, genNum, encryptInfo)
 		if err != nil {
 			// Try chunked decryption for large values
-			decrypted, err = decryptInChunks(entry.Value, objNum, genNum, encryptInfo, verbose)
+			decrypted, err = decryptInChunks(entry.Value, objNum, genNum, encryptInfo, debug, verbose)
 			if err != nil {
 				if verbose {
 					log.Printf("Failed to decrypt value for key %s: %v", string(entry.Key), err)
@@ -114,8 +114,9 @@ type XFAStreams struct {
 	SourceSet     *XFAStreamInfo `json:"sourceSet,omitempty"`
 }
 
-// ExtractAllXFAStreams extracts all XFA streams from a PDF without using UniPDF
-func ExtractAllXFAStreams(pdfBytes []byte, encryptInfo *types.PDFEncryption, verbose bool) (*XFAStreams, error) {
+// ExtractAllXFAStreams extracts all XFA streams from a PDF without using UniPDF.
+// The objects decrypted on the way are dumped as debug says.
+func ExtractAllXFAStreams(pdfBytes []byte, encryptInfo *types.PDFEncryption, debug DebugOptions, verbose bool) (*XFAStreams, error) {
 	if verbose {
 		log.Printf("Extracting all XFA streams from PDF (no UniPDF)")
 	}
@@ -126,6 +127,13 @@ func ExtractAllXFAStreams(pdfBytes []byte, encryptInfo *types.PDFEncryption, ver
 	// Find AcroForm reference
 	acroFormPattern := regexp.MustCompile(`/AcroForm\s+(\d+)\s+(\d+)\s+R`)
 	acroFormMatch := acroFormPattern.FindStringSubmatchIndex(pdfStr)
+	if acroFormMatch == nil {
+		// A catalog stored in an object stream is compressed; search it instead
+		if catalog, err := parse.GetCatalog(pdfBytes, encryptInfo, verbose); err == nil && strings.Contains(string(catalog), "/AcroForm") {
+			pdfStr = string(catalog)
+			acroFormMatch = acroFormPattern.FindStringSubmatchIndex(pdfStr)
+		}
+	}
 
 	var acroFormObjNum int
 	var err error
@@ -157,18 +165,21 @@ func ExtractAllXFAStreams(pdfBytes []byte, encryptInfo *types.PDFEncryption, ver
 		// Use new GetObject function that handles both direct objects and object streams
 		// This is the equivalent of PyPDF's get_object() method
 		decryptedContent, err := parse.GetObject(pdfBytes, acroFormObjNum, encryptInfo, verbose)
+		if err == nil && encryptInfo != nil {
+			debug.dump("decrypted AcroForm", decryptedContent)
+		}
 		if err != nil {
 			if verbose {
 				log.Printf("GetObject failed for AcroForm %d: %v, trying fallback", acroFormObjNum, err)
 			}
 			// Fallback to old method
-			decryptedContent, err = findAndDecryptAcroForm(pdfBytes, acroFormObjNum, encryptInfo, verbose)
+			decryptedContent, err = findAndDecryptAcroForm(pdfBytes, acroFormObjNum, encryptInfo, debug, verbose)
 			if err != nil {
 				return nil, err
 			}
 		}
 
-		xfaArrayContent, err = findXFAArrayContent(decryptedContent, verbose)
+		xfaArrayContent, err = findXFAArrayContent(decryptedContent, debug, verbose)
 		if err != nil {
 			return nil, err
 		}
@@ -233,12 +244,15 @@ func ExtractAllXFAStreams(pdfBytes []byte, encryptInfo *types.PDFEncryption, ver
 
 		// Use GetObject which properly handles both direct objects and objects in streams
 		objData, err := parse.GetObject(pdfBytes, objNum, encryptInfo, verbose)
+		if err == nil && encryptInfo != nil {
+			debug.dump(fmt.Sprintf("%s stream object %d decrypted", streamName, objNum), objData)
+		}
 		if err != nil {
 			if verbose {
 				log.Printf("Failed to get object %d for stream %s: %v, trying fallback", objNum, streamName, err)
 			}
 			// Fallback to old method
-			objData, _, err = extractStreamFromPDF(pdfBytes, objNum, encryptInfo, verbose)
+			objData, _, err = extractStreamFromPDF(pdfBytes, objNum, encryptInfo, debug, verbose)
 			if err != nil {
 				if verbose {
 					log.Printf("Fallback also failed for %s (object %d): %v", streamName, objNum, err)
@@ -265,6 +279,12 @@ func ExtractAllXFAStreams(pdfBytes []byte, encryptInfo *types.PDFEncryption, ver
 			decompressed = streamData // Use as-is if decompression fails
 		}
 
+		// extractStreamDataFromObject decodes FlateDecode streams, so the filter
+		// in the dictionary tells whether the stream was compressed
+		if streamIdx := bytes.Index(objData, []byte("stream")); streamIdx != -1 && bytes.Contains(objData[:streamIdx], []byte("/FlateDecode")) {
+			wasCompressed = true
+		}
+
 		streamInfo := &XFAStreamInfo{
 			Data:         decompressed,
 			ObjectNumber: objNum,
@@ -309,7 +329,7 @@ func ExtractAllXFAStreams(pdfBytes []byte, encryptInfo *types.PDFEncryption, ver
 // This is a convenience function that uses ExtractAllXFAStreams and returns only the datasets stream
 func FindXFADatasetsStream(pdfBytes []byte, encryptInfo *types.PDFEncryption, verbose bool) ([]byte, int, error) {
 	// Extract all XFA streams
-	streams, err := ExtractAllXFAStreams(pdfBytes, encryptInfo, verbose)
+	streams, err := ExtractAllXFAStreams(pdfBytes, encryptInfo, DebugOptions{}, verbose)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to extract XFA streams: %v", err)
 	}
@@ -427,38 +447,101 @@ func BuildPDFFromXFAStreams(streams *XFAStreams, verbose bool) ([]byte, error) {
 	return builder.BuildFromXFA(xfaStreams)
 }
 
-// UpdateXFAInPDF updates XFA field values in PDF bytes
+// UpdateXFAInPDF updates XFA field values in PDF bytes. There is no variant that
+// reads the PDF from an io.ReaderAt like parse.OpenReader: the updated file is
+// produced whole in memory, so the input is needed in memory as well.
 func UpdateXFAInPDF(pdfBytes []byte, formData types.FormData, encryptInfo *types.PDFEncryption, verbose bool) ([]byte, error) {
+	t, err := PrepareTemplate(pdfBytes, encryptInfo, DebugOptions{}, verbose)
+	if err != nil {
+		return nil, err
+	}
+	return t.Fill(formData, verbose)
+}
+
+// Template is an XFA form PDF with its datasets stream located, decompressed and
+// parsed once for repeated fills. Fill never modifies a Template, so one can be filled
+// from multiple goroutines.
+type Template struct {
+	pdfBytes       []byte
+	encryptInfo    *types.PDFEncryption
+	datasetsObjNum int
+	datasets       *Datasets
+	compressed     bool
+}
+
+// PrepareTemplate locates, decompresses and parses the XFA datasets stream of a
+// PDF for repeated fills with Template.Fill. pdfBytes must not be modified while the
+// template is in use. The objects decrypted on the way are dumped as debug says.
+func PrepareTemplate(pdfBytes []byte, encryptInfo *types.PDFEncryption, debug DebugOptions, verbose bool) (*Template, error) {
 	// Find XFA datasets stream
-	datasetsStream, streamObjNum, err := FindXFADatasetsStream(pdfBytes, encryptInfo, verbose)
+	streams, err := ExtractAllXFAStreams(pdfBytes, encryptInfo, debug, verbose)
 	if err != nil {
 		return nil, fmt.Errorf("error finding XFA datasets stream: %v", err)
 	}
+	if streams.Datasets == nil {
+		return nil, fmt.Errorf("error finding XFA datasets stream: datasets stream not found in XFA")
+	}
+	datasetsStream, streamObjNum := streams.Datasets.Data, streams.Datasets.ObjectNumber
 
 	if verbose {
 		log.Printf("Found XFA datasets stream at object %d", streamObjNum)
 		log.Printf("Stream size: %d bytes", len(datasetsStream))
 	}
 
-	// Decompress if needed and parse XML
+	// Decompress if needed
 	xfaXML, wasCompressed, err := DecompressStream(datasetsStream)
 	if err != nil {
 		return nil, fmt.Errorf("error decompressing stream: %v", err)
 	}
+	wasCompressed = wasCompressed || streams.Datasets.Compressed
+	datasets, err := ParseDatasets(xfaXML)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing datasets: %v", err)
+	}
 
 	if verbose {
 		log.Printf("Decompressed XFA XML: %d bytes (was compressed: %v)", len(xfaXML), wasCompressed)
 	}
 
-	// Update field values in XFA XML
-	updatedXML, err := UpdateXFAValues(string(xfaXML), formData, verbose)
-	if err != nil {
-		return nil, fmt.Errorf("error updating XFA values: %v", err)
-	}
+	return &Template{
+		pdfBytes:       pdfBytes,
+		encryptInfo:    encryptInfo,
+		datasetsObjNum: streamObjNum,
+		datasets:       datasets,
+		compressed:     wasCompressed,
+	}, nil
+}
+
+// Fill updates field values in a copy of the template, like UpdateXFAInPDF
+func (t *Template) Fill(formData types.FormData, verbose bool) ([]byte, error) {
+	return t.FillDatasets(t.filledDatasets(formData, verbose), verbose)
+}
 
+// filledDatasets returns a copy of the template's datasets DOM with the values
+// of the <field> elements inside <data> set from formData
+func (t *Template) filledDatasets(formData types.FormData, verbose bool) *Datasets {
+	ds := t.datasets.clone()
+	ds.fillFields(formData, true, verbose)
+	return ds
+}
+
+// Datasets returns the template's datasets packet as a DOM. Each call returns
+// a new DOM, which can be changed and written back with FillDatasets.
+func (t *Template) Datasets() (*Datasets, error) {
+	return t.datasets.clone(), nil
+}
+
+// FillDatasets replaces the datasets packet in a copy of the template with the
+// serialized DOM, e.g. after adding instances of repeating groups
+func (t *Template) FillDatasets(ds *Datasets, verbose bool) ([]byte, error) {
+	return t.replaceDatasets(ds.Bytes(), verbose)
+}
+
+// replaceDatasets writes a copy of the PDF with new datasets XML, compressed if
+// the original stream was
+func (t *Template) replaceDatasets(updatedStream []byte, verbose bool) ([]byte, error) {
 	// Re-compress if it was compressed
-	updatedStream := []byte(updatedXML)
-	if wasCompressed {
+	if t.compressed {
 		compressed, err := CompressStream(updatedStream)
 		if err != nil {
 			return nil, fmt.Errorf("error compressing stream: %v", err)
@@ -470,7 +553,7 @@ func UpdateXFAInPDF(pdfBytes []byte, formData types.FormData, encryptInfo *types
 	}
 
 	// Update PDF with new stream
-	updatedPDF, err := ReplaceStreamInPDF(pdfBytes, streamObjNum, updatedStream, verbose)
+	updatedPDF, err := ReplaceStreamInPDF(t.pdfBytes, t.datasetsObjNum, updatedStream, verbose)
 	if err != nil {
 		return nil, fmt.Errorf("error replacing stream: %v", err)
 	}
@@ -478,82 +561,113 @@ func UpdateXFAInPDF(pdfBytes []byte, formData types.FormData, encryptInfo *types
 	return updatedPDF, nil
 }
 
-// UpdateXFAValues updates field values in XFA XML
-func UpdateXFAValues(xfaXML string, formData types.FormData, verbose bool) (string, error) {
-	// Find data section
-	dataStart := strings.Index(xfaXML, "<data")
-	if dataStart == -1 {
-		// If no data section, update individual fields
-		return UpdateXFAFieldValues(xfaXML, formData, verbose)
-	}
+// FillIncremental updates field values like Fill but appends the new datasets
+// stream to the original file as an incremental update instead of rewriting the
+// file. The original bytes are kept as they are, so earlier revisions and
+// existing digital signatures stay intact.
+func (t *Template) FillIncremental(formData types.FormData, verbose bool) ([]byte, error) {
+	updatedXML := t.filledDatasets(formData, verbose).Bytes()
 
-	// Find end of data section
-	dataEnd := strings.LastIndex(xfaXML, "</data>")
-	if dataEnd == -1 {
-		return UpdateXFAFieldValues(xfaXML, formData, verbose)
+	genNum, dict, err := t.datasetsStreamDict()
+	if err != nil {
+		return nil, err
 	}
-
-	// Extract data section
-	dataSection := xfaXML[dataStart : dataEnd+7]
-
-	// Update field values in data section
-	updatedData, err := UpdateXFAFieldValues(dataSection, formData, verbose)
+	w, err := write.NewIncrementalWriter(t.pdfBytes, t.encryptInfo)
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("error starting incremental update: %v", err)
 	}
-
-	// Replace data section in full XML
-	result := xfaXML[:dataStart] + updatedData + xfaXML[dataEnd+7:]
-
-	return result, nil
+	w.SetStreamObject(t.datasetsObjNum, genNum, dict, updatedXML, t.compressed)
+	if verbose {
+		log.Printf("Appending datasets stream %d as an incremental update", t.datasetsObjNum)
+	}
+	return w.Bytes()
 }
 
-// UpdateXFAFieldValues updates individual field values in XFA XML
-func UpdateXFAFieldValues(xfaXML string, formData types.FormData, verbose bool) (string, error) {
-	resultXML := xfaXML
-
-	for fieldName, newValue := range formData {
-		// Find field in XFA XML
-		fieldPattern := fmt.Sprintf(`<field name="%s"`, fieldName)
-		fieldIndex := strings.Index(resultXML, fieldPattern)
-
-		if fieldIndex == -1 {
-			if verbose {
-				log.Printf("Warning: Field '%s' not found in XFA XML", fieldName)
-			}
-			continue
-		}
-
-		// Find the value element for this field
-		fieldEnd := strings.Index(resultXML[fieldIndex:], "</field>")
-		if fieldEnd == -1 {
-			continue
-		}
+// datasetsStreamDict returns the generation of the datasets stream object and
+// the entries of its dictionary other than those describing how the data is
+// stored, for the stream replacing it
+func (t *Template) datasetsStreamDict() (int, write.Dictionary, error) {
+	genNum, err := parse.ObjectGeneration(t.pdfBytes, t.datasetsObjNum)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error finding datasets stream %d: %v", t.datasetsObjNum, err)
+	}
+	obj, err := parse.GetObject(t.pdfBytes, t.datasetsObjNum, t.encryptInfo, false)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error reading datasets stream %d: %v", t.datasetsObjNum, err)
+	}
+	dictIdx, streamIdx := bytes.Index(obj, []byte("<<")), bytes.Index(obj, []byte("stream"))
+	if dictIdx == -1 || streamIdx < dictIdx {
+		return 0, nil, fmt.Errorf("datasets object %d is not a stream", t.datasetsObjNum)
+	}
+	dict, err := write.ParseDictionary(obj[dictIdx:streamIdx])
+	if err != nil {
+		return 0, nil, fmt.Errorf("error parsing datasets stream dictionary: %v", err)
+	}
+	for _, key := range []string{"Length", "Filter", "DecodeParms"} {
+		delete(dict, key)
+	}
+	return genNum, dict, nil
+}
 
-		fieldSection := resultXML[fieldIndex : fieldIndex+fieldEnd]
+// UpdateXFAInPDFIncremental updates XFA field values like UpdateXFAInPDF, as an
+// incremental update that keeps the original bytes and their signatures intact
+func UpdateXFAInPDFIncremental(pdfBytes []byte, formData types.FormData, encryptInfo *types.PDFEncryption, verbose bool) ([]byte, error) {
+	t, err := PrepareTemplate(pdfBytes, encryptInfo, DebugOptions{}, verbose)
+	if err != nil {
+		return nil, err
+	}
+	return t.FillIncremental(formData, verbose)
+}
 
-		// Check if value element exists
-		valueStart := strings.Index(fieldSection, "<value>")
-		valueEnd := strings.Index(fieldSection, "</value>")
+// FillWithLock fills the template like Fill and then makes the filled fields, or
+// all fields, read-only, like UpdateXFAInPDFWithLock
+func (t *Template) FillWithLock(formData types.FormData, lock types.FieldLock, verbose bool) ([]byte, error) {
+	result, err := t.Fill(formData, verbose)
+	if err != nil || lock == types.LockNone {
+		return result, err
+	}
+	return lockXFAFields(result, formData, t.encryptInfo, lock, verbose)
+}
 
-		valueStr := fmt.Sprintf("%v", newValue)
+// ExtractDatasets parses the datasets packet of an XFA form PDF into a DOM
+func ExtractDatasets(pdfBytes []byte, encryptInfo *types.PDFEncryption, verbose bool) (*Datasets, error) {
+	t, err := PrepareTemplate(pdfBytes, encryptInfo, DebugOptions{}, verbose)
+	if err != nil {
+		return nil, err
+	}
+	return t.Datasets()
+}
 
-		if valueStart != -1 && valueEnd != -1 {
-			// Replace existing value
-			beforeValue := resultXML[:fieldIndex+valueStart+7]
-			afterValue := resultXML[fieldIndex+valueEnd:]
-			resultXML = beforeValue + valueStr + afterValue
-		} else {
-			// Insert new value element before </field>
-			beforeFieldEnd := resultXML[:fieldIndex+fieldEnd]
-			afterFieldEnd := resultXML[fieldIndex+fieldEnd:]
-			resultXML = beforeFieldEnd + "<value>" + valueStr + "</value>" + afterFieldEnd
-		}
+// UpdateDatasetsInPDF replaces the datasets packet of an XFA form PDF with the
+// serialized DOM
+func UpdateDatasetsInPDF(pdfBytes []byte, ds *Datasets, encryptInfo *types.PDFEncryption, verbose bool) ([]byte, error) {
+	t, err := PrepareTemplate(pdfBytes, encryptInfo, DebugOptions{}, verbose)
+	if err != nil {
+		return nil, err
+	}
+	return t.FillDatasets(ds, verbose)
+}
 
-		if verbose {
-			log.Printf("Updated field '%s' = '%s'", fieldName, valueStr)
-		}
+// UpdateXFAValues updates field values in XFA XML. Only the fields inside the
+// <data> element are updated when the XML has one. The XML is parsed once into
+// a Datasets DOM and written back, however many fields are set.
+func UpdateXFAValues(xfaXML string, formData types.FormData, verbose bool) (string, error) {
+	ds, err := ParseDatasets([]byte(xfaXML))
+	if err != nil {
+		return "", err
 	}
+	ds.fillFields(formData, true, verbose)
+	return string(ds.Bytes()), nil
+}
 
-	return resultXML, nil
+// UpdateXFAFieldValues updates the values of <field> elements anywhere in XFA
+// XML: the content of each named field's <value> element is replaced, or a
+// <value> element added, and a nil value clears it
+func UpdateXFAFieldValues(xfaXML string, formData types.FormData, verbose bool) (string, error) {
+	ds, err := ParseDatasets([]byte(xfaXML))
+	if err != nil {
+		return "", err
+	}
+	ds.fillFields(formData, false, verbose)
+	return string(ds.Bytes()), nil
 }
@@ -3,11 +3,15 @@ package xfa
 import (
 	"bytes"
 	"compress/flate"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/benedoc-inc/pdfer/core/encrypt"
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/core/write"
 	"github.com/benedoc-inc/pdfer/types"
 )
 
@@ -235,3 +239,191 @@ func TestFindXFADatasetsStream_Integration(t *testing.T) {
 
 	t.Logf("Integration test: Found XFA stream, %d bytes, object number: %d", len(xfaData), objNum)
 }
+
+func TestUpdateXFAFieldValues_Clear(t *testing.T) {
+	xml := `<field name="name"><value>Jane</value></field>`
+	got, err := UpdateXFAFieldValues(xml, types.FormData{"name": nil}, false)
+	if err != nil {
+		t.Fatalf("UpdateXFAFieldValues failed: %v", err)
+	}
+	if want := `<field name="name"><value></value></field>`; got != want {
+		t.Errorf("UpdateXFAFieldValues = %q, want %q", got, want)
+	}
+}
+
+// buildDatasetsTestPDF creates an XFA form whose compressed datasets stream
+// holds testField with the value oldValue
+func buildDatasetsTestPDF(t *testing.T) []byte {
+	t.Helper()
+	w := write.NewPDFWriter()
+	templateNum := w.AddStreamObject(write.Dictionary{}, []byte(`<template><subform name="form1"><field name="testField"/></subform></template>`), true)
+	datasetsNum := w.AddStreamObject(write.Dictionary{}, []byte(`<xdp><data><field name="testField"><value>oldValue</value></field></data></xdp>`), true)
+	pagesNum := w.AddObject([]byte("<</Type/Pages/Kids[]/Count 0>>"))
+	acroFormNum := w.AddObject([]byte(fmt.Sprintf("<</Fields[]/XFA[(template) %d 0 R(datasets) %d 0 R]>>", templateNum, datasetsNum)))
+	w.SetRoot(w.AddObject([]byte(fmt.Sprintf("<</Type/Catalog/Pages %d 0 R/AcroForm %d 0 R>>", pagesNum, acroFormNum))))
+	pdfBytes, err := w.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to write PDF: %v", err)
+	}
+	return pdfBytes
+}
+
+func TestTemplate_FillIncremental(t *testing.T) {
+	pdfBytes := buildDatasetsTestPDF(t)
+	tmpl, err := PrepareTemplate(pdfBytes, nil, DebugOptions{}, false)
+	if err != nil {
+		t.Fatalf("PrepareTemplate failed: %v", err)
+	}
+
+	filled, err := tmpl.FillIncremental(types.FormData{"testField": "newValue"}, false)
+	if err != nil {
+		t.Fatalf("FillIncremental failed: %v", err)
+	}
+	if !bytes.HasPrefix(filled, pdfBytes) {
+		t.Fatal("Incremental fill should keep the original bytes as a prefix")
+	}
+	streams, err := ExtractAllXFAStreams(filled, nil, DebugOptions{}, false)
+	if err != nil || streams.Datasets == nil {
+		t.Fatalf("Failed to extract datasets after the update: %v", err)
+	}
+	if !bytes.Contains(streams.Datasets.Data, []byte("<value>newValue</value>")) {
+		t.Errorf("Datasets not updated: %s", streams.Datasets.Data)
+	}
+	if !streams.Datasets.Compressed {
+		t.Error("Updated datasets stream should stay compressed")
+	}
+
+	// A fill in place recompresses the stream it replaces, whose dictionary
+	// keeps its /FlateDecode filter
+	repeated := strings.Repeat("newValue ", 50)
+	filled, err = tmpl.Fill(types.FormData{"testField": repeated}, false)
+	if err != nil {
+		t.Fatalf("Fill failed: %v", err)
+	}
+	if bytes.Contains(filled, []byte(repeated)) {
+		t.Error("Datasets written uncompressed under a /FlateDecode filter")
+	}
+}
+
+func TestTemplate_FillIncremental_KeepsStreamObject(t *testing.T) {
+	// Replace the datasets stream with one of generation 1 whose dictionary has
+	// entries of its own
+	base := buildDatasetsTestPDF(t)
+	streams, err := ExtractAllXFAStreams(base, nil, DebugOptions{}, false)
+	if err != nil || streams.Datasets == nil {
+		t.Fatalf("Failed to extract datasets: %v", err)
+	}
+	objNum := streams.Datasets.ObjectNumber
+	w, err := write.NewIncrementalWriter(base, nil)
+	if err != nil {
+		t.Fatalf("NewIncrementalWriter failed: %v", err)
+	}
+	w.SetStreamObject(objNum, 1, write.Dictionary{"Type": "/EmbeddedFile", "Params": write.Raw("<</Size 82>>")}, streams.Datasets.Data, true)
+	pdfBytes, err := w.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to write PDF: %v", err)
+	}
+
+	tmpl, err := PrepareTemplate(pdfBytes, nil, DebugOptions{}, false)
+	if err != nil {
+		t.Fatalf("PrepareTemplate failed: %v", err)
+	}
+	filled, err := tmpl.FillIncremental(types.FormData{"testField": "newValue"}, false)
+	if err != nil {
+		t.Fatalf("FillIncremental failed: %v", err)
+	}
+
+	update := string(filled[len(pdfBytes):])
+	if gen, err := parse.ObjectGeneration(filled, objNum); err != nil || gen != 1 {
+		t.Errorf("ObjectGeneration = %d, %v; want 1", gen, err)
+	}
+	if !strings.Contains(update, fmt.Sprintf("%d 1 obj", objNum)) {
+		t.Errorf("Expected the datasets stream written with generation 1:\n%s", update)
+	}
+	for _, entry := range []string{"/Type /EmbeddedFile", "/Params <</Size 82>>", "/Filter /FlateDecode"} {
+		if !strings.Contains(update, entry) {
+			t.Errorf("Expected %s in the datasets stream dictionary:\n%s", entry, update)
+		}
+	}
+	if strings.Count(update, "/Filter") != 1 || strings.Count(update, "/Length") != 1 {
+		t.Errorf("Expected a single /Filter and /Length:\n%s", update)
+	}
+	if streams, err := ExtractAllXFAStreams(filled, nil, DebugOptions{}, false); err != nil || !bytes.Contains(streams.Datasets.Data, []byte("<value>newValue</value>")) {
+		t.Errorf("Datasets not updated: %v", err)
+	}
+}
+
+func TestUpdateXFAValues_Fields(t *testing.T) {
+	xml := `<xfa:datasets xmlns:xfa="http://www.xfa.org/schema/xfa-data/1.0/">` +
+		`<field name="outside"><value>keep</value></field>` +
+		`<xfa:data>` +
+		`<field name="a"><value>old</value></field>` +
+		`<field name="b"/>` +
+		`<field name="c"><value/></field>` +
+		`<field name="d"><value><text>rich</text></value></field>` +
+		`<field name="group"><field name="inner"><value>x</value></field></field>` +
+		`</xfa:data></xfa:datasets>`
+
+	got, err := UpdateXFAValues(xml, types.FormData{
+		"outside": "changed", "a": "Q&A <1>", "b": 2, "c": "set", "d": nil, "group": "g", "inner": "y", "missing": "z",
+	}, false)
+	if err != nil {
+		t.Fatalf("UpdateXFAValues failed: %v", err)
+	}
+	want := `<xfa:datasets xmlns:xfa="http://www.xfa.org/schema/xfa-data/1.0/">` +
+		`<field name="outside"><value>keep</value></field>` +
+		`<xfa:data>` +
+		`<field name="a"><value>Q&amp;A &lt;1&gt;</value></field>` +
+		`<field name="b"><value>2</value></field>` +
+		`<field name="c"><value>set</value></field>` +
+		`<field name="d"><value></value></field>` +
+		`<field name="group"><field name="inner"><value>y</value></field><value>g</value></field>` +
+		`</xfa:data></xfa:datasets>`
+	if got != want {
+		t.Errorf("UpdateXFAValues =\n%s\nwant\n%s", got, want)
+	}
+
+	// A field cut off by truncated XML has no value site and is left alone
+	truncated := `<data><field name="a"><value>old`
+	if got, err := UpdateXFAValues(truncated, types.FormData{"a": "v"}, false); err == nil && got != truncated {
+		t.Errorf("Truncated field updated: %q", got)
+	}
+}
+
+func TestTemplate_Fill_Datasets(t *testing.T) {
+	w := write.NewPDFWriter()
+	templateNum := w.AddStreamObject(write.Dictionary{}, []byte(`<template><subform name="form1"><field name="testField"/></subform></template>`), true)
+	datasetsNum := w.AddStreamObject(write.Dictionary{}, []byte(`<xdp><data><!-- imported -->`+
+		`<field name="testField"><value>oldValue</value></field><note><![CDATA[a < b]]></note></data></xdp>`), true)
+	pagesNum := w.AddObject([]byte("<</Type/Pages/Kids[]/Count 0>>"))
+	acroFormNum := w.AddObject([]byte(fmt.Sprintf("<</Fields[]/XFA[(template) %d 0 R(datasets) %d 0 R]>>", templateNum, datasetsNum)))
+	w.SetRoot(w.AddObject([]byte(fmt.Sprintf("<</Type/Catalog/Pages %d 0 R/AcroForm %d 0 R>>", pagesNum, acroFormNum))))
+	pdfBytes, err := w.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to write PDF: %v", err)
+	}
+	tmpl, err := PrepareTemplate(pdfBytes, nil, DebugOptions{}, false)
+	if err != nil {
+		t.Fatalf("PrepareTemplate failed: %v", err)
+	}
+
+	// Fills go through the datasets DOM, keeping what the field values don't touch
+	filled, err := tmpl.Fill(types.FormData{"testField": "newValue"}, false)
+	if err != nil {
+		t.Fatalf("Fill failed: %v", err)
+	}
+	ds, err := ExtractDatasets(filled, nil, false)
+	if err != nil {
+		t.Fatalf("ExtractDatasets failed: %v", err)
+	}
+	want := `<xdp><data><!-- imported --><field name="testField"><value>newValue</value></field><note><![CDATA[a < b]]></note></data></xdp>`
+	if got := string(ds.Bytes()); got != want {
+		t.Errorf("Filled datasets =\n%s\nwant\n%s", got, want)
+	}
+
+	// and leave the template's DOM as it was
+	ds, _ = tmpl.Datasets()
+	if !strings.Contains(string(ds.Bytes()), "<value>oldValue</value>") {
+		t.Errorf("Fill changed the template: %s", ds.Bytes())
+	}
+}
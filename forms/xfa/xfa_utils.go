@@ -175,7 +175,7 @@ func extractDictionaryKeys(dictContent []byte) string {
 }
 
 // findAndDecryptAcroForm finds and decrypts the AcroForm object, returning the decrypted content
-func findAndDecryptAcroForm(pdfBytes []byte, acroFormObjNum int, encryptInfo *types.PDFEncryption, verbose bool) ([]byte, error) {
+func findAndDecryptAcroForm(pdfBytes []byte, acroFormObjNum int, encryptInfo *types.PDFEncryption, debug DebugOptions, verbose bool) ([]byte, error) {
 	// Find the AcroForm object
 	objIndex, err := parse.FindObjectByNumber(pdfBytes, acroFormObjNum, encryptInfo, verbose)
 	if err != nil {
@@ -259,7 +259,7 @@ func findAndDecryptAcroForm(pdfBytes []byte, acroFormObjNum int, encryptInfo *ty
 
 			// Decrypt dictionary in chunks if needed (for alignment)
 			// Use extracted generation number
-			decryptedDict, err := decryptInChunks(encryptedDict, acroFormObjNum, genNum, encryptInfo, verbose)
+			decryptedDict, err := decryptInChunks(encryptedDict, acroFormObjNum, genNum, encryptInfo, debug, verbose)
 			if err != nil {
 				if verbose {
 					log.Printf("Failed to decrypt dictionary: %v", err)
@@ -302,69 +302,22 @@ func findAndDecryptAcroForm(pdfBytes []byte, acroFormObjNum int, encryptInfo *ty
 							log.Printf("Attempting to decrypt %d bytes starting from xref position (offset %d in content, absolute %d)",
 								decryptLen, decryptStart, objIndex)
 						}
-						decryptedData, err := decryptInChunks(encryptedContent, acroFormObjNum, genNum, encryptInfo, verbose)
+						decryptedData, err := decryptInChunks(encryptedContent, acroFormObjNum, genNum, encryptInfo, debug, verbose)
 						if err == nil && len(decryptedData) > 0 {
 							// If decryptInChunks succeeded, use the decrypted content
 							// It will have found valid PDF structure (<< or PDF keywords)
 							decryptedContent = decryptedData
+							debug.dump("decrypted AcroForm", decryptedContent)
 							if verbose {
 								log.Printf("Successfully decrypted AcroForm (no header/endobj): %d bytes", len(decryptedContent))
-
-								// Output full structure for debugging
-								log.Printf("=== DECRYPTED ACROFORM STRUCTURE ===")
-								log.Printf("Content length: %d bytes", len(decryptedContent))
-
-								// Hex dump of first 2000 bytes
-								previewLen := min(2000, len(decryptedContent))
-								log.Printf("First %d bytes (hex dump):", previewLen)
-								for i := 0; i < previewLen; i += 16 {
-									end := min(i+16, previewLen)
-									hexPart := fmt.Sprintf("%04x: ", i)
-									for j := i; j < end; j++ {
-										hexPart += fmt.Sprintf("%02x ", decryptedContent[j])
-									}
-									log.Printf("%s", hexPart)
-								}
-
-								// ASCII dump
-								log.Printf("First %d bytes (ASCII, non-printable as .):", previewLen)
-								asciiLine := ""
-								for i := 0; i < previewLen; i++ {
-									if i > 0 && i%80 == 0 {
-										log.Printf("%s", asciiLine)
-										asciiLine = ""
-									}
-									b := decryptedContent[i]
-									if b >= 32 && b < 127 {
-										asciiLine += string(b)
-									} else {
-										asciiLine += "."
-									}
-								}
-								if asciiLine != "" {
-									log.Printf("%s", asciiLine)
-								}
-
-								// Check for PDF markers
-								if bytes.Contains(decryptedContent, []byte("<<")) {
-									dictStart := bytes.Index(decryptedContent, []byte("<<"))
+								if dictStart := bytes.Index(decryptedContent, []byte("<<")); dictStart != -1 {
 									log.Printf("Found '<<' at offset %d", dictStart)
-									// Show context around dictionary start
-									ctxStart := max(0, dictStart-50)
-									ctxEnd := min(len(decryptedContent), dictStart+200)
-									log.Printf("Context around '<<' (offset %d-%d): %q", ctxStart, ctxEnd, string(decryptedContent[ctxStart:ctxEnd]))
 								}
-								if bytes.Contains(decryptedContent, []byte("/XFA")) {
-									xfaPos := bytes.Index(decryptedContent, []byte("/XFA"))
+								if xfaPos := bytes.Index(decryptedContent, []byte("/XFA")); xfaPos != -1 {
 									log.Printf("Found '/XFA' at offset %d", xfaPos)
 								} else {
 									log.Printf("'/XFA' NOT found in decrypted content")
-									// Check for XFA with different case/spacing
-									if bytes.Contains(decryptedContent, []byte("XFA")) || bytes.Contains(decryptedContent, []byte("xfa")) {
-										log.Printf("Found 'XFA' (case-insensitive) in decrypted content")
-									}
 								}
-								log.Printf("=== END DECRYPTED ACROFORM STRUCTURE ===")
 							}
 						} else {
 							if verbose {
@@ -379,7 +332,7 @@ func findAndDecryptAcroForm(pdfBytes []byte, acroFormObjNum int, encryptInfo *ty
 					// Has header but no endobj - decrypt up to end
 					endobjPos = len(objContent)
 					encryptedContent := objContent[dataStart:endobjPos]
-					decryptedData, err := decryptInChunks(encryptedContent, acroFormObjNum, genNum, encryptInfo, verbose)
+					decryptedData, err := decryptInChunks(encryptedContent, acroFormObjNum, genNum, encryptInfo, debug, verbose)
 					if err != nil {
 						if verbose {
 							log.Printf("Failed to decrypt object content: %v", err)
@@ -393,7 +346,7 @@ func findAndDecryptAcroForm(pdfBytes []byte, acroFormObjNum int, encryptInfo *ty
 			} else {
 				// Has endobj - decrypt normally
 				encryptedContent := objContent[dataStart:endobjPos]
-				decryptedData, err := decryptInChunks(encryptedContent, acroFormObjNum, genNum, encryptInfo, verbose)
+				decryptedData, err := decryptInChunks(encryptedContent, acroFormObjNum, genNum, encryptInfo, debug, verbose)
 				if err != nil {
 					if verbose {
 						log.Printf("Failed to decrypt object content: %v", err)
@@ -427,7 +380,7 @@ func findAndDecryptAcroForm(pdfBytes []byte, acroFormObjNum int, encryptInfo *ty
 
 // decryptInChunks decrypts data in chunks, trying different alignments to find the correct decryption
 // This handles cases where encrypted data might not start exactly at a 16-byte boundary
-func decryptInChunks(encryptedData []byte, objNum, genNum int, encryptInfo *types.PDFEncryption, verbose bool) ([]byte, error) {
+func decryptInChunks(encryptedData []byte, objNum, genNum int, encryptInfo *types.PDFEncryption, debug DebugOptions, verbose bool) ([]byte, error) {
 	if len(encryptedData) == 0 {
 		return encryptedData, nil
 	}
@@ -488,15 +441,8 @@ func decryptInChunks(encryptedData []byte, objNum, genNum int, encryptInfo *type
 					if verbose {
 						log.Printf("Found valid decryption at offset %d, decrypted length=%d (hasDict=%v, hasKeywords=%v, printable=%.1f%%)",
 							offset, len(decrypted), hasValidDict, hasPDFKeywords, printableRatio*100)
-						if len(decrypted) > 0 && len(decrypted) <= 200 {
-							log.Printf("Decrypted preview: %q", decrypted)
-						} else if len(decrypted) > 200 {
-							// Show a sample from the middle where dictionary might be
-							midStart := max(0, len(decrypted)/2-100)
-							midEnd := min(len(decrypted), midStart+200)
-							log.Printf("Decrypted preview (middle section): %q", decrypted[midStart:midEnd])
-						}
 					}
+					debug.dump(fmt.Sprintf("object %d decrypted at offset %d", objNum, offset), decrypted)
 					// Prepend any skipped bytes (non-encrypted header)
 					if offset > 0 {
 						result := make([]byte, offset+len(decrypted))
@@ -505,10 +451,12 @@ func decryptInChunks(encryptedData []byte, objNum, genNum int, encryptInfo *type
 						return result, nil
 					}
 					return decrypted, nil
-				} else if verbose && offset == 0 {
+				} else if offset == 0 {
 					// Log first attempt to see what we're getting
-					log.Printf("Decryption at offset %d succeeded but no PDF markers found, length=%d, preview: %q",
-						offset, len(decrypted), decrypted[:min(100, len(decrypted))])
+					if verbose {
+						log.Printf("Decryption at offset %d succeeded but no PDF markers found, length=%d", offset, len(decrypted))
+					}
+					debug.dump(fmt.Sprintf("object %d decrypted at offset %d", objNum, offset), decrypted)
 				}
 			}
 		}
@@ -530,7 +478,7 @@ func decryptInChunks(encryptedData []byte, objNum, genNum int, encryptInfo *type
 }
 
 // findXFAArrayContent extracts the XFA array content string from decrypted AcroForm content
-func findXFAArrayContent(decryptedContent []byte, verbose bool) (string, error) {
+func findXFAArrayContent(decryptedContent []byte, debug DebugOptions, verbose bool) (string, error) {
 	// UniPDF approach: the decrypted content might have padding or extra data
 	// Find where the actual PDF dictionary starts (look for "<<")
 	dictStart := bytes.Index(decryptedContent, []byte("<<"))
@@ -558,10 +506,8 @@ func findXFAArrayContent(decryptedContent []byte, verbose bool) (string, error)
 			dictContent := decryptedContent[dictStart : dictEnd+2]
 			if verbose {
 				log.Printf("Found dictionary start at offset %d, end at %d (length: %d bytes)", dictStart, dictEnd, len(dictContent))
-				// Show the actual dictionary content to verify it's valid
-				previewLen := min(200, len(dictContent))
-				log.Printf("Dictionary content preview: %q", dictContent[:previewLen])
 			}
+			debug.dump("AcroForm dictionary", dictContent)
 
 			// Search for /XFA within the dictionary
 			xfaPos = bytes.Index(dictContent, []byte("/XFA"))
@@ -616,14 +562,11 @@ func findXFAArrayContent(decryptedContent []byte, verbose bool) (string, error)
 	if xfaPos == -1 {
 		if verbose {
 			log.Printf("XFA not found in decrypted content (length: %d bytes)", len(decryptedContent))
-			// Show content around dictionary start if found
-			if dictStart != -1 {
-				previewStart := max(0, dictStart-50)
-				previewEnd := min(len(decryptedContent), dictStart+500)
-				log.Printf("Content around dictionary start (offset %d): %q", dictStart, decryptedContent[previewStart:previewEnd])
-			} else {
-				log.Printf("Decrypted content preview (first 500 bytes): %q", decryptedContent[:min(500, len(decryptedContent))])
-			}
+		}
+		if dictStart != -1 {
+			debug.dump(fmt.Sprintf("decrypted content from the dictionary at offset %d", dictStart), decryptedContent[dictStart:])
+		} else {
+			debug.dump("decrypted content", decryptedContent)
 		}
 		return "", fmt.Errorf("XFA entry not found in AcroForm")
 	}
@@ -701,7 +644,7 @@ func extractGenerationNumber(objContent []byte, objNum int) int {
 }
 
 // This follows the same approach as findAndDecryptAcroForm: parse structure first, then decrypt only encrypted portions
-func extractStreamFromPDF(pdfBytes []byte, streamObjNum int, encryptInfo *types.PDFEncryption, verbose bool) ([]byte, int, error) {
+func extractStreamFromPDF(pdfBytes []byte, streamObjNum int, encryptInfo *types.PDFEncryption, debug DebugOptions, verbose bool) ([]byte, int, error) {
 	// Find the stream object using incremental parser (finds non-encrypted markers)
 	streamObjIndex, err := parse.FindObjectByNumber(pdfBytes, streamObjNum, encryptInfo, verbose)
 	if err != nil {
@@ -796,6 +739,7 @@ func extractStreamFromPDF(pdfBytes []byte, streamObjNum int, encryptInfo *types.
 		if verbose {
 			log.Printf("Decrypted stream data: %d bytes -> %d bytes", len(objContent[streamDataStart:streamDataEnd]), len(streamContent))
 		}
+		debug.dump(fmt.Sprintf("stream object %d decrypted", streamObjNum), streamContent)
 	}
 
 	return streamContent, streamObjNum, nil
@@ -909,7 +853,10 @@ func ReplaceStreamInPDF(pdfBytes []byte, streamObjNum int, newStream []byte, ver
 	beforeLength := pdfBytes[:lengthStart]
 	betweenLengthAndStream := pdfBytes[lengthEnd:streamStart]
 
-	result := append(beforeLength, []byte(newLength)...)
+	// Build the result in a new slice; appending to beforeLength would overwrite pdfBytes
+	result := make([]byte, 0, len(beforeLength)+len(newLength)+len(betweenLengthAndStream)+len(newStream)+len(afterStream))
+	result = append(result, beforeLength...)
+	result = append(result, newLength...)
 	result = append(result, betweenLengthAndStream...)
 	result = append(result, newStream...)
 	result = append(result, afterStream...)
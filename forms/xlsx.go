@@ -0,0 +1,254 @@
+package forms
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxXLSXColumns bounds the columns read from a sheet, as Excel does
+const maxXLSXColumns = 16384
+
+// xlsxEpoch is day 0 of the 1900 date system, offset for Excel's 1900 leap day
+var xlsxEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+// xlsxText is rich or plain text: a <t> element or runs with one each
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.T)
+	}
+	return b.String()
+}
+
+type xlsxStyles struct {
+	NumFmts []struct {
+		ID   int    `xml:"numFmtId,attr"`
+		Code string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellXfs []struct {
+		NumFmtID int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Style  int      `xml:"s,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSXSheet returns the cells of a worksheet as text, row by row, with
+// empty cells for the columns a row skips
+func readXLSXSheet(data []byte, sheetName string) ([][]string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("not an XLSX workbook: %w", err)
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+	readXML := func(name string, v interface{}) error {
+		f, ok := files[name]
+		if !ok {
+			return fmt.Errorf("workbook has no %s", name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		if err := xml.NewDecoder(io.LimitReader(rc, 1<<30)).Decode(v); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+		return nil
+	}
+
+	var workbook xlsxWorkbook
+	if err := readXML("xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	var rels xlsxRelationships
+	if err := readXML("xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	rid := ""
+	for _, s := range workbook.Sheets {
+		if sheetName == "" || s.Name == sheetName {
+			rid = s.RID
+			break
+		}
+	}
+	if rid == "" {
+		if sheetName == "" {
+			return nil, fmt.Errorf("workbook has no sheets")
+		}
+		return nil, fmt.Errorf("workbook has no sheet %q", sheetName)
+	}
+	sheetPath := ""
+	for _, rel := range rels.Relationships {
+		if rel.ID == rid {
+			sheetPath = rel.Target
+			if strings.HasPrefix(sheetPath, "/") {
+				sheetPath = strings.TrimPrefix(sheetPath, "/")
+			} else {
+				sheetPath = path.Join("xl", sheetPath)
+			}
+		}
+	}
+	if sheetPath == "" {
+		return nil, fmt.Errorf("workbook has no part for sheet %s", rid)
+	}
+
+	// Shared strings and styles are optional parts
+	var shared xlsxSharedStrings
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := readXML("xl/sharedStrings.xml", &shared); err != nil {
+			return nil, err
+		}
+	}
+	var styles xlsxStyles
+	if _, ok := files["xl/styles.xml"]; ok {
+		if err := readXML("xl/styles.xml", &styles); err != nil {
+			return nil, err
+		}
+	}
+	dateStyles := make(map[int]bool)
+	customDates := make(map[int]bool)
+	for _, f := range styles.NumFmts {
+		customDates[f.ID] = isDateFormat(f.Code)
+	}
+	for i, xf := range styles.CellXfs {
+		id := xf.NumFmtID
+		dateStyles[i] = id >= 14 && id <= 22 || id >= 45 && id <= 47 || customDates[id]
+	}
+
+	var sheet xlsxSheet
+	if err := readXML(sheetPath, &sheet); err != nil {
+		return nil, err
+	}
+	records := make([][]string, 0, len(sheet.Rows))
+	for _, row := range sheet.Rows {
+		var record []string
+		for _, cell := range row.Cells {
+			col := len(record)
+			if cell.Ref != "" {
+				if col, err = xlsxColumn(cell.Ref); err != nil {
+					return nil, err
+				}
+			}
+			for len(record) <= col {
+				record = append(record, "")
+			}
+			value := cell.Value
+			switch cell.Type {
+			case "s":
+				i, err := strconv.Atoi(value)
+				if err != nil || i < 0 || i >= len(shared.Items) {
+					return nil, fmt.Errorf("cell %s: invalid shared string %q", cell.Ref, value)
+				}
+				value = shared.Items[i].String()
+			case "inlineStr":
+				value = cell.Inline.String()
+			case "b":
+				value = strconv.FormatBool(value == "1")
+			case "", "n":
+				if dateStyles[cell.Style] {
+					value = xlsxDate(value)
+				}
+			}
+			record[col] = value
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// xlsxColumn returns the 0-based column of a cell reference such as "AB12"
+func xlsxColumn(ref string) (int, error) {
+	col := 0
+	for i := 0; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		col = col*26 + int(ref[i]-'A'+1)
+	}
+	if col == 0 || col > maxXLSXColumns {
+		return 0, fmt.Errorf("invalid cell reference %q", ref)
+	}
+	return col - 1, nil
+}
+
+// isDateFormat reports whether a custom number format shows a date: it has day
+// or year codes outside quoted text and bracketed sections
+func isDateFormat(code string) bool {
+	quoted, bracketed := false, false
+	for _, r := range strings.ToLower(code) {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case quoted:
+		case r == '[':
+			bracketed = true
+		case r == ']':
+			bracketed = false
+		case bracketed:
+		case r == 'd' || r == 'y':
+			return true
+		}
+	}
+	return false
+}
+
+// xlsxDate formats a date serial number as YYYY-MM-DD, with the time when it
+// has one. Values that are not numbers are returned as they are.
+func xlsxDate(value string) string {
+	serial, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return value
+	}
+	days := math.Floor(serial)
+	t := xlsxEpoch.AddDate(0, 0, int(days)).Add(time.Duration(math.Round((serial-days)*86400)) * time.Second)
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,222 @@
+// Package objectstore fills and extracts PDFs held in object storage such as S3,
+// GCS or Azure Blob Storage. Objects are downloaded with concurrent ranged reads
+// and results uploaded in parts, so documents never pass through local files and
+// large transfers are split across parallel requests.
+//
+// The package has no SDK dependencies: Bucket and MultipartBucket are small
+// enough to implement over any client, usually a few lines each. With the AWS
+// SDK, ReadRange is a GetObject call with Range set to "bytes=offset-end", and
+// the multipart methods map to the S3 calls of the same name.
+//
+// The parser works on a complete buffer, so Fill and ExtractContent fetch the
+// whole object. ReaderAt reads only the ranges asked for, for callers that need
+// part of an object, such as its header or trailer.
+package objectstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/forms"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// DefaultPartSize is the size of ranged reads and upload parts. S3 requires
+// upload parts other than the last to be at least 5 MiB.
+const DefaultPartSize = 8 << 20
+
+// DefaultConcurrency is the number of ranged reads or part uploads in flight
+const DefaultConcurrency = 4
+
+// Bucket reads objects from an object store
+type Bucket interface {
+	// Size returns the length of an object in bytes
+	Size(ctx context.Context, key string) (int64, error)
+
+	// ReadRange returns length bytes of an object starting at offset
+	ReadRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+}
+
+// MultipartBucket writes objects in parts
+type MultipartBucket interface {
+	// CreateMultipartUpload starts an upload and returns its ID
+	CreateMultipartUpload(ctx context.Context, key string) (string, error)
+
+	// UploadPart uploads one part, numbered from 1, and returns its ETag
+	UploadPart(ctx context.Context, key, uploadID string, partNumber int, data []byte) (string, error)
+
+	// CompleteMultipartUpload assembles the uploaded parts into the object
+	CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []Part) error
+
+	// AbortMultipartUpload discards an upload and its parts
+	AbortMultipartUpload(ctx context.Context, key, uploadID string) error
+}
+
+// Part identifies an uploaded part
+type Part struct {
+	Number int
+	ETag   string
+}
+
+// Options controls how objects are transferred
+type Options struct {
+	PartSize    int64 // Size of ranged reads and upload parts (default: DefaultPartSize)
+	Concurrency int   // Transfers in flight (default: DefaultConcurrency)
+}
+
+// withDefaults fills in unset options
+func (o Options) withDefaults() Options {
+	if o.PartSize <= 0 {
+		o.PartSize = DefaultPartSize
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = DefaultConcurrency
+	}
+	return o
+}
+
+// Read downloads an object with concurrent ranged reads
+func Read(ctx context.Context, bucket Bucket, key string, opts Options) ([]byte, error) {
+	opts = opts.withDefaults()
+	size, err := bucket.Size(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get size of %s: %w", key, err)
+	}
+
+	data := make([]byte, size)
+	err = forEachPart(ctx, size, opts, func(ctx context.Context, _ int, offset, length int64) error {
+		return readRange(ctx, bucket, key, data[offset:offset+length], offset)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Write uploads data as a multipart upload, aborting the upload if any part fails
+func Write(ctx context.Context, bucket MultipartBucket, key string, data []byte, opts Options) error {
+	opts = opts.withDefaults()
+	uploadID, err := bucket.CreateMultipartUpload(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to start upload of %s: %w", key, err)
+	}
+
+	var parts []Part
+	var mu sync.Mutex
+	err = forEachPart(ctx, int64(len(data)), opts, func(ctx context.Context, number int, offset, length int64) error {
+		etag, err := bucket.UploadPart(ctx, key, uploadID, number, data[offset:offset+length])
+		if err != nil {
+			return fmt.Errorf("failed to upload part %d of %s: %w", number, key, err)
+		}
+		mu.Lock()
+		parts = append(parts, Part{Number: number, ETag: etag})
+		mu.Unlock()
+		return nil
+	})
+	if err == nil {
+		sort.Slice(parts, func(i, j int) bool { return parts[i].Number < parts[j].Number })
+		err = bucket.CompleteMultipartUpload(ctx, key, uploadID, parts)
+		if err != nil {
+			err = fmt.Errorf("failed to complete upload of %s: %w", key, err)
+		}
+	}
+	if err != nil {
+		// Use a fresh context: ctx may be the reason the upload failed
+		if abortErr := bucket.AbortMultipartUpload(context.Background(), key, uploadID); abortErr != nil {
+			return fmt.Errorf("%w (abort also failed: %v)", err, abortErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// Fill downloads a form, fills it and uploads the result
+func Fill(ctx context.Context, src Bucket, srcKey string, dst MultipartBucket, dstKey string, data types.FormData, fillOpts forms.FillOptions, opts Options) error {
+	pdfBytes, err := Read(ctx, src, srcKey, opts)
+	if err != nil {
+		return err
+	}
+	filled, err := forms.FillWithOptions(pdfBytes, data, fillOpts)
+	if err != nil {
+		return fmt.Errorf("failed to fill %s: %w", srcKey, err)
+	}
+	return Write(ctx, dst, dstKey, filled, opts)
+}
+
+// ExtractContent downloads a PDF and extracts its content
+func ExtractContent(ctx context.Context, src Bucket, key string, password []byte, verbose bool, opts Options) (*types.ContentDocument, error) {
+	pdfBytes, err := Read(ctx, src, key, opts)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := extract.ExtractContent(pdfBytes, password, verbose)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract %s: %w", key, err)
+	}
+	return doc, nil
+}
+
+// forEachPart calls fn for each part of a size-byte object, at most
+// opts.Concurrency at a time, and returns the first error. Parts are numbered
+// from 1. An empty object has a single empty part, as uploads need one.
+func forEachPart(ctx context.Context, size int64, opts Options, fn func(ctx context.Context, number int, offset, length int64) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		sem      = make(chan struct{}, opts.Concurrency)
+	)
+	for number, offset := 1, int64(0); offset < size || number == 1; number, offset = number+1, offset+opts.PartSize {
+		length := opts.PartSize
+		if offset+length > size {
+			length = size - offset
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(number int, offset, length int64) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := fn(ctx, number, offset, length); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(number, offset, length)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// readRange fills p from the object starting at offset
+func readRange(ctx context.Context, bucket Bucket, key string, p []byte, offset int64) error {
+	if len(p) == 0 {
+		return nil
+	}
+	body, err := bucket.ReadRange(ctx, key, offset, int64(len(p)))
+	if err != nil {
+		return fmt.Errorf("failed to read %s at %d: %w", key, offset, err)
+	}
+	defer body.Close()
+	if _, err := io.ReadFull(body, p); err != nil {
+		return fmt.Errorf("failed to read %s at %d: %w", key, offset, err)
+	}
+	return nil
+}
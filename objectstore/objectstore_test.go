@@ -0,0 +1,171 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+)
+
+// memoryBucket is an in-memory object store that records ranged reads
+type memoryBucket struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	uploads map[string]map[int][]byte
+	reads   [][2]int64
+	failAt  int // Part number whose upload fails
+	aborted int
+}
+
+func newMemoryBucket() *memoryBucket {
+	return &memoryBucket{objects: make(map[string][]byte), uploads: make(map[string]map[int][]byte)}
+}
+
+func (b *memoryBucket) Size(ctx context.Context, key string) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	data, ok := b.objects[key]
+	if !ok {
+		return 0, fmt.Errorf("no such key: %s", key)
+	}
+	return int64(len(data)), nil
+}
+
+func (b *memoryBucket) ReadRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.reads = append(b.reads, [2]int64{offset, length})
+	data := b.objects[key]
+	return io.NopCloser(bytes.NewReader(data[offset : offset+length])), nil
+}
+
+func (b *memoryBucket) CreateMultipartUpload(ctx context.Context, key string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := fmt.Sprintf("upload-%d", len(b.uploads)+1)
+	b.uploads[id] = make(map[int][]byte)
+	return id, nil
+}
+
+func (b *memoryBucket) UploadPart(ctx context.Context, key, uploadID string, partNumber int, data []byte) (string, error) {
+	if partNumber == b.failAt {
+		return "", errors.New("connection reset")
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.uploads[uploadID][partNumber] = append([]byte(nil), data...)
+	return fmt.Sprintf("etag-%d", partNumber), nil
+}
+
+func (b *memoryBucket) CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []Part) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var data []byte
+	for i, part := range parts {
+		if part.Number != i+1 || part.ETag != fmt.Sprintf("etag-%d", part.Number) {
+			return fmt.Errorf("unexpected part %+v at %d", part, i)
+		}
+		data = append(data, b.uploads[uploadID][part.Number]...)
+	}
+	b.objects[key] = data
+	delete(b.uploads, uploadID)
+	return nil
+}
+
+func (b *memoryBucket) AbortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.uploads, uploadID)
+	b.aborted++
+	return nil
+}
+
+func TestReadWrite(t *testing.T) {
+	bucket := newMemoryBucket()
+	data := bytes.Repeat([]byte("0123456789"), 105)
+	opts := Options{PartSize: 100, Concurrency: 3}
+
+	if err := Write(context.Background(), bucket, "out.bin", data, opts); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if !bytes.Equal(bucket.objects["out.bin"], data) {
+		t.Fatal("Uploaded object differs from the data")
+	}
+
+	got, err := Read(context.Background(), bucket, "out.bin", opts)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("Read returned different data")
+	}
+	if len(bucket.reads) != 11 {
+		t.Errorf("Expected 11 ranged reads, got %d", len(bucket.reads))
+	}
+	sort.Slice(bucket.reads, func(i, j int) bool { return bucket.reads[i][0] < bucket.reads[j][0] })
+	if last := bucket.reads[10]; last != [2]int64{1000, 50} {
+		t.Errorf("Last range = %v, want [1000 50]", last)
+	}
+}
+
+func TestWrite_AbortsOnFailure(t *testing.T) {
+	bucket := newMemoryBucket()
+	bucket.failAt = 2
+	err := Write(context.Background(), bucket, "out.bin", make([]byte, 500), Options{PartSize: 100})
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+	if bucket.aborted != 1 || len(bucket.uploads) != 0 {
+		t.Errorf("Upload not aborted: aborted=%d pending=%d", bucket.aborted, len(bucket.uploads))
+	}
+	if _, ok := bucket.objects["out.bin"]; ok {
+		t.Error("Object written despite the failure")
+	}
+}
+
+func TestReaderAt(t *testing.T) {
+	bucket := newMemoryBucket()
+	bucket.objects["doc.pdf"] = []byte("%PDF-1.7 ... %%EOF")
+
+	r, err := NewReaderAt(context.Background(), bucket, "doc.pdf")
+	if err != nil {
+		t.Fatalf("NewReaderAt failed: %v", err)
+	}
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, 0); err != nil || string(header) != "%PDF-1.7" {
+		t.Errorf("ReadAt(0) = %q, %v", header, err)
+	}
+	tail := make([]byte, 10)
+	n, err := r.ReadAt(tail, r.Size()-5)
+	if n != 5 || err != io.EOF || string(tail[:n]) != "%%EOF" {
+		t.Errorf("ReadAt(tail) = %d %q, %v", n, tail[:n], err)
+	}
+	if len(bucket.reads) != 2 || bucket.reads[1] != [2]int64{r.Size() - 5, 5} {
+		t.Errorf("Unexpected ranged reads %v", bucket.reads)
+	}
+}
+
+func TestExtractContent(t *testing.T) {
+	builder := write.NewSimplePDFBuilder()
+	builder.FinalizePage(builder.AddPage(write.PageSizeLetter))
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+	bucket := newMemoryBucket()
+	bucket.objects["in.pdf"] = pdfBytes
+
+	doc, err := ExtractContent(context.Background(), bucket, "in.pdf", nil, false, Options{PartSize: 256})
+	if err != nil {
+		t.Fatalf("ExtractContent failed: %v", err)
+	}
+	if len(doc.Pages) != 1 {
+		t.Errorf("Expected 1 page, got %d", len(doc.Pages))
+	}
+}
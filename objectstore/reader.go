@@ -0,0 +1,50 @@
+package objectstore
+
+import (
+	"context"
+	"io"
+)
+
+// ReaderAt reads an object with a ranged read per call, fetching only the bytes
+// asked for. It is safe for concurrent use.
+type ReaderAt struct {
+	ctx    context.Context
+	bucket Bucket
+	key    string
+	size   int64
+}
+
+// NewReaderAt returns a reader for an object. ctx applies to every read.
+func NewReaderAt(ctx context.Context, bucket Bucket, key string) (*ReaderAt, error) {
+	size, err := bucket.Size(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return &ReaderAt{ctx: ctx, bucket: bucket, key: key, size: size}, nil
+}
+
+// Size returns the length of the object
+func (r *ReaderAt) Size() int64 {
+	return r.size
+}
+
+// ReadAt implements io.ReaderAt
+func (r *ReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	n := len(p)
+	if remaining := r.size - off; int64(n) > remaining {
+		n = int(remaining)
+	}
+	if err := readRange(r.ctx, r.bucket, r.key, p[:n], off); err != nil {
+		return 0, err
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
@@ -18,7 +18,7 @@
 //	_, encInfo, _ := encryption.DecryptPDF(pdfBytes, password, false)
 //
 //	// Extract XFA
-//	streams, _ := xfa.ExtractAllXFAStreams(pdfBytes, encInfo, false)
+//	streams, _ := xfa.ExtractAllXFAStreams(pdfBytes, encInfo, xfa.DebugOptions{}, false)
 //
 // # Packages
 //
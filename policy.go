@@ -0,0 +1,221 @@
+package pdfer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/forms"
+)
+
+// Policy rule names, as reported in PolicyResult.Rule
+const (
+	RuleMaxFileSize      = "max_file_size"
+	RuleRequiredMetadata = "required_metadata"
+	RuleForbiddenFonts   = "forbidden_fonts"
+	RuleRequiredFields   = "required_fields"
+	RuleNoExternalLinks  = "no_external_links"
+)
+
+// Policy is a set of checks a document must pass. Rules left at their zero value
+// are not checked. Policies are usually loaded from JSON with ParsePolicy:
+//
+//	{
+//	  "max_file_size": 5242880,
+//	  "required_metadata": ["Title", "Author"],
+//	  "forbidden_fonts": ["Comic Sans MS"],
+//	  "required_fields": ["applicant_name"],
+//	  "no_external_links": true
+//	}
+type Policy struct {
+	MaxFileSize      int64    `json:"max_file_size,omitempty"`     // Maximum file size in bytes
+	RequiredMetadata []string `json:"required_metadata,omitempty"` // Info entries that must be non-empty, e.g. "Title"; other names are custom entries
+	ForbiddenFonts   []string `json:"forbidden_fonts,omitempty"`   // Font names that must not be used; "Arial" also matches "Arial-BoldMT" and subsets
+	RequiredFields   []string `json:"required_fields,omitempty"`   // Form fields that must be filled
+	NoExternalLinks  bool     `json:"no_external_links,omitempty"` // Disallow links to URIs and other files
+}
+
+// PolicyResult is the outcome of a single policy rule
+type PolicyResult struct {
+	Rule       string   `json:"rule"`
+	Passed     bool     `json:"passed"`
+	Message    string   `json:"message"`
+	Violations []string `json:"violations,omitempty"` // Offending items, such as missing fields or font names
+}
+
+// PolicyReport is the outcome of checking a document against a policy
+type PolicyReport struct {
+	Passed  bool           `json:"passed"`
+	Results []PolicyResult `json:"results"`
+}
+
+// Failed returns the results of the rules the document did not pass
+func (r *PolicyReport) Failed() []PolicyResult {
+	var failed []PolicyResult
+	for _, result := range r.Results {
+		if !result.Passed {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// ParsePolicy parses a policy from JSON, rejecting unknown rules so that typos
+// don't silently disable a check
+func ParsePolicy(data []byte) (*Policy, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var policy Policy
+	if err := decoder.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("invalid policy: %w", err)
+	}
+	if policy.MaxFileSize < 0 {
+		return nil, fmt.Errorf("invalid policy: negative max_file_size")
+	}
+	return &policy, nil
+}
+
+// CheckPolicy evaluates a document against a policy. Each configured rule gets
+// a result; the report passes when all of them do. Only the parts of the
+// document a rule needs are extracted.
+func CheckPolicy(pdfBytes []byte, password []byte, policy *Policy, verbose bool) (*PolicyReport, error) {
+	pdf, err := parse.OpenWithOptions(pdfBytes, parse.ParseOptions{
+		Password: password,
+		Verbose:  verbose,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PDF: %w", err)
+	}
+
+	report := &PolicyReport{Passed: true}
+	add := func(result PolicyResult) {
+		result.Passed = len(result.Violations) == 0
+		if result.Passed && result.Message == "" {
+			result.Message = "ok"
+		}
+		report.Passed = report.Passed && result.Passed
+		report.Results = append(report.Results, result)
+	}
+
+	if policy.MaxFileSize > 0 {
+		result := PolicyResult{Rule: RuleMaxFileSize}
+		if size := int64(len(pdfBytes)); size > policy.MaxFileSize {
+			result.Message = fmt.Sprintf("file is %d bytes, limit is %d", size, policy.MaxFileSize)
+			result.Violations = []string{fmt.Sprintf("%d bytes", size)}
+		}
+		add(result)
+	}
+
+	if len(policy.RequiredMetadata) > 0 {
+		result := PolicyResult{Rule: RuleRequiredMetadata}
+		metadata, err := extract.ExtractMetadata(pdfBytes, pdf, verbose)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract metadata: %w", err)
+		}
+		for _, name := range policy.RequiredMetadata {
+			if strings.TrimSpace(metadata.InfoEntry(name)) == "" {
+				result.Violations = append(result.Violations, name)
+			}
+		}
+		if len(result.Violations) > 0 {
+			result.Message = "missing metadata: " + strings.Join(result.Violations, ", ")
+		}
+		add(result)
+	}
+
+	if len(policy.ForbiddenFonts) > 0 {
+		result := PolicyResult{Rule: RuleForbiddenFonts}
+		pages, err := extract.ExtractPages(pdfBytes, pdf, verbose)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract pages: %w", err)
+		}
+		used := make(map[string]bool)
+		for _, page := range pages {
+			if page.Resources == nil {
+				continue
+			}
+			for _, font := range page.Resources.Fonts {
+				name := fontBaseName(font.Name)
+				if !used[name] && fontForbidden(name, policy.ForbiddenFonts) {
+					result.Violations = append(result.Violations, name)
+				}
+				used[name] = true
+			}
+		}
+		sort.Strings(result.Violations)
+		if len(result.Violations) > 0 {
+			result.Message = "forbidden fonts used: " + strings.Join(result.Violations, ", ")
+		}
+		add(result)
+	}
+
+	if len(policy.RequiredFields) > 0 {
+		result := PolicyResult{Rule: RuleRequiredFields}
+		var values map[string]interface{}
+		if form, err := forms.Extract(pdfBytes, password, verbose); err == nil {
+			values = form.GetValues()
+		} else if verbose {
+			fmt.Printf("Warning: no form found, required fields are unfilled: %v\n", err)
+		}
+		for _, name := range policy.RequiredFields {
+			if value, ok := values[name]; !ok || value == nil || strings.TrimSpace(fmt.Sprintf("%v", value)) == "" {
+				result.Violations = append(result.Violations, name)
+			}
+		}
+		if len(result.Violations) > 0 {
+			result.Message = "unfilled fields: " + strings.Join(result.Violations, ", ")
+		}
+		add(result)
+	}
+
+	if policy.NoExternalLinks {
+		result := PolicyResult{Rule: RuleNoExternalLinks}
+		links, err := extract.ExtractLinks(pdfBytes, pdf, verbose)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract links: %w", err)
+		}
+		for _, link := range links {
+			target := link.URI
+			if target == "" {
+				target = link.File
+			}
+			if target != "" {
+				result.Violations = append(result.Violations, fmt.Sprintf("page %d: %s", link.PageNumber, target))
+			}
+		}
+		if len(result.Violations) > 0 {
+			result.Message = fmt.Sprintf("%d external links", len(result.Violations))
+		}
+		add(result)
+	}
+
+	return report, nil
+}
+
+// fontBaseName strips the leading slash and any subset tag ("ABCDEF+") from a
+// BaseFont name
+func fontBaseName(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if i := strings.IndexByte(name, '+'); i == 6 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// fontForbidden reports whether a font name matches one of the forbidden names,
+// ignoring case, spaces ("Comic Sans MS" is embedded as "ComicSansMS") and style
+// suffixes such as "-Bold" or ",Italic"
+func fontForbidden(name string, forbidden []string) bool {
+	lower := strings.ToLower(strings.ReplaceAll(name, " ", ""))
+	for _, f := range forbidden {
+		f = strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(f, "/"), " ", ""))
+		if lower == f || strings.HasPrefix(lower, f+"-") || strings.HasPrefix(lower, f+",") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package pdfer
+
+import (
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+)
+
+func TestCheckPolicy(t *testing.T) {
+	builder := write.NewSimplePDFBuilder()
+	page := builder.AddPage(write.PageSizeLetter)
+	font := page.AddStandardFont("Helvetica-Bold")
+	page.Content().
+		BeginText().
+		SetFont(font, 12).
+		SetTextPosition(72, 720).
+		ShowText("Policy test").
+		EndText()
+	builder.FinalizePage(page)
+	builder.Writer().SetMetadataFields(map[string]string{"Title": "Policy Test"})
+
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+
+	policy, err := ParsePolicy([]byte(`{
+		"max_file_size": 10,
+		"required_metadata": ["Title", "Author"],
+		"forbidden_fonts": ["helvetica"],
+		"required_fields": ["name"],
+		"no_external_links": true
+	}`))
+	if err != nil {
+		t.Fatalf("ParsePolicy failed: %v", err)
+	}
+
+	report, err := CheckPolicy(pdfBytes, nil, policy, false)
+	if err != nil {
+		t.Fatalf("CheckPolicy failed: %v", err)
+	}
+	if report.Passed {
+		t.Fatal("Expected policy check to fail")
+	}
+
+	results := make(map[string]PolicyResult)
+	for _, result := range report.Results {
+		results[result.Rule] = result
+	}
+	if len(results) != 5 {
+		t.Fatalf("Expected 5 results, got %+v", report.Results)
+	}
+	if results[RuleMaxFileSize].Passed {
+		t.Error("Expected max file size to fail")
+	}
+	if r := results[RuleRequiredMetadata]; r.Passed || len(r.Violations) != 1 || r.Violations[0] != "Author" {
+		t.Errorf("Expected missing Author, got %+v", r)
+	}
+	if r := results[RuleForbiddenFonts]; r.Passed || len(r.Violations) != 1 || r.Violations[0] != "Helvetica-Bold" {
+		t.Errorf("Expected Helvetica-Bold to be forbidden, got %+v", r)
+	}
+	if r := results[RuleRequiredFields]; r.Passed || len(r.Violations) != 1 {
+		t.Errorf("Expected unfilled field without a form, got %+v", r)
+	}
+	if r := results[RuleNoExternalLinks]; !r.Passed {
+		t.Errorf("Expected no external links, got %+v", r)
+	}
+	if len(report.Failed()) != 4 {
+		t.Errorf("Expected 4 failed rules, got %d", len(report.Failed()))
+	}
+
+	report, err = CheckPolicy(pdfBytes, nil, &Policy{RequiredMetadata: []string{"Title"}}, false)
+	if err != nil {
+		t.Fatalf("CheckPolicy failed: %v", err)
+	}
+	if !report.Passed || len(report.Results) != 1 {
+		t.Errorf("Expected a single passing rule, got %+v", report)
+	}
+}
+
+func TestParsePolicy_UnknownRule(t *testing.T) {
+	if _, err := ParsePolicy([]byte(`{"max_filesize": 100}`)); err == nil {
+		t.Error("Expected error for unknown rule")
+	}
+}
+
+func TestFontForbidden(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"ComicSansMS", true},
+		{"ComicSansMS-Bold", true},
+		{"ComicSansMS,Italic", true},
+		{"ComicSansMSX", false},
+		{"Arial", false},
+	}
+	for _, tt := range tests {
+		if got := fontForbidden(fontBaseName("/ABCDEF+"+tt.name), []string{"Comic Sans MS"}); got != tt.want {
+			t.Errorf("fontForbidden(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
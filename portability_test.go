@@ -0,0 +1,54 @@
+package pdfer
+
+import (
+	"go/build"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestLibraryImports keeps the library packages buildable for js/wasm and other
+// sandboxed targets: they take and return byte slices, and must not start
+// processes. Tools under cmd and examples may, as may files built only for
+// other platforms, such as the Windows font registry lookup.
+func TestLibraryImports(t *testing.T) {
+	wasm := build.Default
+	wasm.GOOS, wasm.GOARCH = "js", "wasm"
+	forbidden := map[string]bool{"os/exec": true, "plugin": true}
+	skip := map[string]bool{".git": true, "cmd": true, "examples": true, "scripts": true, "tests": true}
+
+	err := filepath.WalkDir(".", func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if skip[d.Name()] && path != "." {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		if ok, err := wasm.MatchFile(filepath.Dir(path), d.Name()); err != nil || !ok {
+			return err
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ImportsOnly)
+		if err != nil {
+			return err
+		}
+		for _, imp := range file.Imports {
+			if name, _ := strconv.Unquote(imp.Path.Value); forbidden[name] {
+				t.Errorf("%s imports %s", path, name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to walk the module: %v", err)
+	}
+}
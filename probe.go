@@ -0,0 +1,198 @@
+package pdfer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/benedoc-inc/pdfer/core/encrypt"
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// XFA form kinds, as reported in Capabilities.XFA
+const (
+	XFANone    = "none"
+	XFAStatic  = "static"  // XFA with AcroForm widgets that viewers without XFA support can fill
+	XFADynamic = "dynamic" // XFA whose pages are laid out by the viewer
+)
+
+// Encryption algorithms, as reported in Capabilities.Encryption
+const (
+	EncryptionRC4_40  = "RC4-40"
+	EncryptionRC4_128 = "RC4-128"
+	EncryptionAES128  = "AES-128"
+	EncryptionAES256  = "AES-256"
+)
+
+// linearizedWindow is how far into the file the linearization dictionary,
+// the first object, is looked for
+const linearizedWindow = 1024
+
+var (
+	probeRefPattern      = regexp.MustCompile(`^\s*(\d+)\s+\d+\s+R`)
+	probeFieldsPattern   = regexp.MustCompile(`/Fields\s*\[\s*\d+\s+\d+\s+R`)
+	probeXFAPattern      = regexp.MustCompile(`/XFA\s*[\[\d]`)
+	probeSignedPattern   = regexp.MustCompile(`/ByteRange\s*\[`)
+	probeScriptPattern   = regexp.MustCompile(`/S\s*/JavaScript\b|/JS\b`)
+	probeAttachPattern   = regexp.MustCompile(`/Type\s*/EmbeddedFile\b|/Subtype\s*/FileAttachment\b`)
+	probePDFAPartPattern = regexp.MustCompile(`pdfaid:part(?:>|\s*=\s*["'])\s*(\d)`)
+	probePDFAConfPattern = regexp.MustCompile(`pdfaid:conformance(?:>|\s*=\s*["'])\s*([A-Za-z])`)
+)
+
+// Capabilities are the features of a PDF that decide how it can be processed
+type Capabilities struct {
+	Encrypted   bool   `json:"encrypted"`
+	Encryption  string `json:"encryption,omitempty"` // Algorithm, e.g. "AES-256", or the security handler of non-standard encryption
+	Locked      bool   `json:"locked,omitempty"`     // The password doesn't open the document; nothing else was probed
+	XFA         string `json:"xfa"`                  // XFANone, XFAStatic or XFADynamic
+	AcroForm    bool   `json:"acroform"`             // Has AcroForm fields
+	Signed      bool   `json:"signed"`               // Has a digital signature or document timestamp
+	Linearized  bool   `json:"linearized"`           // Optimized for fast web view
+	Tagged      bool   `json:"tagged"`               // Has a structure tree and is marked as tagged
+	PDFA        string `json:"pdfa,omitempty"`       // PDF/A conformance the XMP metadata claims, e.g. "PDF/A-2b"
+	JavaScript  bool   `json:"javascript"`           // Has JavaScript actions or document scripts
+	Attachments bool   `json:"attachments"`          // Has embedded files or file attachment annotations
+}
+
+// Probe reports in one call what a PDF contains: encryption, forms,
+// signatures, linearization, tagging, a PDF/A claim, JavaScript and
+// attachments. Only the catalog, its form and metadata and the object
+// dictionaries are read; no page content is extracted. An encrypted document
+// the password doesn't open is reported as Locked rather than as an error.
+func Probe(pdfBytes []byte, password []byte, verbose bool) (*Capabilities, error) {
+	caps := &Capabilities{XFA: XFANone}
+	if enc, err := encrypt.ParseEncryptionDictionary(pdfBytes, verbose); err == nil && enc != nil {
+		caps.Encrypted = true
+		caps.Encryption = encryptionAlgorithm(enc)
+	}
+
+	pdf, err := parse.OpenWithOptions(pdfBytes, parse.ParseOptions{
+		Password: password,
+		Verbose:  verbose,
+	})
+	if err != nil {
+		if caps.Encrypted && errors.Is(err, types.ErrWrongPassword) {
+			caps.Locked = true
+			return caps, nil
+		}
+		return nil, fmt.Errorf("failed to parse PDF: %w", err)
+	}
+
+	caps.Linearized = bytes.Contains(pdfBytes[:min(len(pdfBytes), linearizedWindow)], []byte("/Linearized"))
+
+	trailer := pdf.Trailer()
+	if trailer == nil || trailer.RootRef == "" {
+		return nil, fmt.Errorf("no document catalog")
+	}
+	catalog := probeObject(pdf, trailer.RootRef)
+	if catalog == nil {
+		return nil, fmt.Errorf("failed to read document catalog %s", trailer.RootRef)
+	}
+
+	if acroForm := probeEntry(pdf, catalog, "/AcroForm"); acroForm != nil {
+		caps.AcroForm = probeFieldsPattern.Match(acroForm)
+		if probeXFAPattern.Match(acroForm) {
+			caps.XFA = XFAStatic
+			if !caps.AcroForm || regexp.MustCompile(`/NeedsRendering\s+true`).Match(catalog) {
+				caps.XFA = XFADynamic
+			}
+		}
+	}
+	markInfo := probeEntry(pdf, catalog, "/MarkInfo")
+	caps.Tagged = bytes.Contains(catalog, []byte("/StructTreeRoot")) && regexp.MustCompile(`/Marked\s+true`).Match(markInfo)
+	caps.PDFA = pdfaClaim(probeEntry(pdf, catalog, "/Metadata"))
+
+	// JavaScript, attachments and signatures can be anywhere; their keys are
+	// found in the object dictionaries, leaving stream data out
+	for _, objNum := range pdf.Objects() {
+		obj, err := pdf.GetObject(objNum)
+		if err != nil {
+			continue
+		}
+		if i := bytes.Index(obj, []byte("stream")); i != -1 {
+			obj = obj[:i]
+		}
+		caps.JavaScript = caps.JavaScript || probeScriptPattern.Match(obj)
+		caps.Attachments = caps.Attachments || probeAttachPattern.Match(obj) || bytes.Contains(obj, []byte("/EmbeddedFiles"))
+		caps.Signed = caps.Signed || probeSignedPattern.Match(obj)
+	}
+	return caps, nil
+}
+
+// encryptionAlgorithm names the algorithm of an encryption dictionary
+func encryptionAlgorithm(enc *types.PDFEncryption) string {
+	switch {
+	case enc.Filter != "" && enc.Filter != "Standard":
+		return enc.Filter
+	case enc.V >= 5:
+		return EncryptionAES256
+	case enc.V == 4:
+		return EncryptionAES128
+	case enc.KeyLength > 40 || enc.V == 2:
+		return EncryptionRC4_128
+	}
+	return EncryptionRC4_40
+}
+
+// probeObject returns the object a reference points to, without its
+// "obj ... endobj" wrapper
+func probeObject(pdf *parse.PDF, ref string) []byte {
+	match := probeRefPattern.FindStringSubmatch(ref)
+	if match == nil {
+		return nil
+	}
+	objNum, _ := strconv.Atoi(match[1])
+	obj, err := pdf.GetObject(objNum)
+	if err != nil {
+		return nil
+	}
+	if i := bytes.Index(obj, []byte("obj")); i != -1 && i < 32 {
+		obj = obj[i+3:]
+	}
+	return bytes.TrimSpace(bytes.TrimSuffix(bytes.TrimSpace(obj), []byte("endobj")))
+}
+
+// probeEntry returns the value of a dictionary entry, read from its object
+// when it is a reference. An inline dictionary is returned with the rest of
+// the enclosing dictionary, which is enough to look for its keys.
+func probeEntry(pdf *parse.PDF, dict []byte, key string) []byte {
+	loc := regexp.MustCompile(regexp.QuoteMeta(key) + `\b`).FindIndex(dict)
+	if loc == nil {
+		return nil
+	}
+	value := dict[loc[1]:]
+	if probeRefPattern.Match(value) {
+		return probeObject(pdf, string(value))
+	}
+	return value
+}
+
+// pdfaClaim returns the PDF/A conformance an XMP metadata stream claims,
+// e.g. "PDF/A-2b", or "" for none
+func pdfaClaim(metadata []byte) string {
+	if i := bytes.Index(metadata, []byte("stream")); i != -1 {
+		data := metadata[i+len("stream"):]
+		if end := bytes.LastIndex(data, []byte("endstream")); end != -1 {
+			data = data[:end]
+		}
+		data = bytes.TrimLeft(data, "\r\n")
+		if bytes.Contains(metadata[:i], []byte("/FlateDecode")) {
+			if decoded, err := parse.DecodeFlateDecode(data); err == nil {
+				data = decoded
+			}
+		}
+		metadata = data
+	}
+	part := probePDFAPartPattern.FindSubmatch(metadata)
+	if part == nil {
+		return ""
+	}
+	claim := "PDF/A-" + string(part[1])
+	if conformance := probePDFAConfPattern.FindSubmatch(metadata); conformance != nil {
+		claim += string(bytes.ToLower(conformance[1]))
+	}
+	return claim
+}
@@ -0,0 +1,103 @@
+package pdfer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/manipulate"
+	"github.com/benedoc-inc/pdfer/core/write"
+)
+
+func TestProbe(t *testing.T) {
+	builder := write.NewSimplePDFBuilder()
+	page := builder.AddPage(write.PageSizeLetter)
+	builder.FinalizePage(page)
+	plain, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+
+	caps, err := Probe(plain, nil, false)
+	if err != nil {
+		t.Fatalf("Probe failed: %v", err)
+	}
+	if *caps != (Capabilities{XFA: XFANone}) {
+		t.Errorf("Expected no capabilities for a plain PDF, got %+v", caps)
+	}
+
+	builder = write.NewSimplePDFBuilder()
+	page = builder.AddPage(write.PageSizeLetter)
+	builder.FinalizePage(page)
+	w := builder.Writer()
+	xmp := `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` +
+		`<rdf:Description rdf:about="" xmlns:pdfaid="http://www.aiim.org/pdfa/ns/id/" pdfaid:part="2" pdfaid:conformance="B"/>` +
+		`</rdf:RDF></x:xmpmeta>`
+	metadata := w.AddStreamObject(write.Dictionary{"/Type": "/Metadata", "/Subtype": "/XML"}, []byte(xmp), true)
+	structTree := w.AddObject([]byte("<< /Type /StructTreeRoot /K [] >>"))
+	builder.SetCatalogEntry("/Metadata", fmt.Sprintf("%d 0 R", metadata))
+	builder.SetCatalogEntry("/StructTreeRoot", fmt.Sprintf("%d 0 R", structTree))
+	builder.SetCatalogEntry("/MarkInfo", "<< /Marked true >>")
+	builder.SetCatalogEntry("/OpenAction", "<< /S /JavaScript /JS (app.alert\\('hi'\\);) >>")
+	featured, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+	m, err := manipulate.NewPDFManipulator(featured, nil, false)
+	if err != nil {
+		t.Fatalf("Failed to open PDF: %v", err)
+	}
+	if err := m.AddAttachment(manipulate.Attachment{Name: "data.json", Data: []byte("{}")}); err != nil {
+		t.Fatalf("AddAttachment failed: %v", err)
+	}
+	if featured, err = m.Rebuild(); err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+
+	caps, err = Probe(featured, nil, false)
+	if err != nil {
+		t.Fatalf("Probe failed: %v", err)
+	}
+	if !caps.Tagged || !caps.JavaScript || !caps.Attachments {
+		t.Errorf("Expected tagged PDF with JavaScript and attachments, got %+v", caps)
+	}
+	if caps.PDFA != "PDF/A-2b" {
+		t.Errorf("Expected PDF/A-2b claim, got %q", caps.PDFA)
+	}
+	if caps.Encrypted || caps.Signed || caps.AcroForm || caps.XFA != XFANone {
+		t.Errorf("Expected no encryption, signature or forms, got %+v", caps)
+	}
+}
+
+func TestProbe_Encrypted(t *testing.T) {
+	builder := write.NewSimplePDFBuilder()
+	page := builder.AddPage(write.PageSizeLetter)
+	builder.FinalizePage(page)
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+	encrypted, err := manipulate.SetSecurity(pdfBytes, nil, manipulate.SecurityOptions{
+		UserPassword: []byte("secret"),
+		Permissions:  manipulate.PermAll,
+		Cipher:       manipulate.CipherAES128,
+	}, false)
+	if err != nil {
+		t.Fatalf("SetSecurity failed: %v", err)
+	}
+
+	caps, err := Probe(encrypted, []byte("wrong"), false)
+	if err != nil {
+		t.Fatalf("Probe failed: %v", err)
+	}
+	if !caps.Encrypted || !caps.Locked || caps.Encryption != EncryptionAES128 {
+		t.Errorf("Expected locked AES-128 document, got %+v", caps)
+	}
+
+	caps, err = Probe(encrypted, []byte("secret"), false)
+	if err != nil {
+		t.Fatalf("Probe failed: %v", err)
+	}
+	if !caps.Encrypted || caps.Locked || caps.Encryption != EncryptionAES128 {
+		t.Errorf("Expected unlocked AES-128 document, got %+v", caps)
+	}
+}
@@ -0,0 +1,249 @@
+package font
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// HasColorGlyphs reports whether the font carries bitmap color glyphs (sbix or CBDT)
+func (f *Font) HasColorGlyphs() bool {
+	ttf, err := ParseTTF(f.Data)
+	if err != nil {
+		return false
+	}
+	_, sbix := ttf.Tables["sbix"]
+	_, cbdt := ttf.Tables["CBDT"]
+	return sbix || cbdt
+}
+
+// ColorGlyph returns the PNG bitmap of the color glyph for r from the strike whose
+// size is closest to ppem (pixels per em), preferring larger strikes.
+// Only PNG data is returned; JPEG/TIFF sbix glyphs and uncompressed bitmaps are skipped.
+func (f *Font) ColorGlyph(r rune, ppem int) ([]byte, bool) {
+	gid, ok := f.GlyphIndex(r)
+	if !ok {
+		return nil, false
+	}
+	ttf, err := ParseTTF(f.Data)
+	if err != nil {
+		return nil, false
+	}
+	if sbix, ok := ttf.Tables["sbix"]; ok {
+		if png := sbixGlyph(sbix.Data, int(ttf.NumGlyphs), gid, ppem); png != nil {
+			return png, true
+		}
+	}
+	cblc, hasCBLC := ttf.Tables["CBLC"]
+	cbdt, hasCBDT := ttf.Tables["CBDT"]
+	if hasCBLC && hasCBDT {
+		if png := cbdtGlyph(cblc.Data, cbdt.Data, gid, ppem); png != nil {
+			return png, true
+		}
+	}
+	return nil, false
+}
+
+// closerStrike reports whether a strike of size candidate fits ppem better than best:
+// the smallest strike at least ppem, else the largest
+func closerStrike(candidate, best, ppem int) bool {
+	if best == 0 {
+		return true
+	}
+	if candidate >= ppem {
+		return best < ppem || candidate < best
+	}
+	return best < ppem && candidate > best
+}
+
+// sbixGlyph returns the PNG data of a glyph from the sbix table (Apple)
+func sbixGlyph(data []byte, numGlyphs int, gid uint16, ppem int) []byte {
+	if len(data) < 8 {
+		return nil
+	}
+	numStrikes := int(binary.BigEndian.Uint32(data[4:8]))
+	if 8+numStrikes*4 > len(data) {
+		return nil
+	}
+
+	var best []byte
+	bestPPEM := 0
+	for i := 0; i < numStrikes; i++ {
+		offset := int(binary.BigEndian.Uint32(data[8+i*4:]))
+		if offset+4 > len(data) {
+			continue
+		}
+		strike := data[offset:]
+		strikePPEM := int(binary.BigEndian.Uint16(strike[0:2]))
+		if png := sbixStrikeGlyph(strike, numGlyphs, gid, 0); png != nil && closerStrike(strikePPEM, bestPPEM, ppem) {
+			best, bestPPEM = png, strikePPEM
+		}
+	}
+	return best
+}
+
+func sbixStrikeGlyph(strike []byte, numGlyphs int, gid uint16, depth int) []byte {
+	if int(gid) >= numGlyphs || 4+(int(gid)+2)*4 > len(strike) || depth > 4 {
+		return nil
+	}
+	start := int(binary.BigEndian.Uint32(strike[4+int(gid)*4:]))
+	end := int(binary.BigEndian.Uint32(strike[4+(int(gid)+1)*4:]))
+	if end-start < 8 || end > len(strike) {
+		return nil
+	}
+	glyph := strike[start:end]
+	switch string(glyph[4:8]) {
+	case "png ":
+		return glyph[8:]
+	case "dupe":
+		if len(glyph) >= 10 {
+			return sbixStrikeGlyph(strike, numGlyphs, binary.BigEndian.Uint16(glyph[8:10]), depth+1)
+		}
+	}
+	return nil
+}
+
+// cbdtGlyph returns the PNG data of a glyph from the CBLC/CBDT tables (Google)
+func cbdtGlyph(cblc, cbdt []byte, gid uint16, ppem int) []byte {
+	if len(cblc) < 8 {
+		return nil
+	}
+	numSizes := int(binary.BigEndian.Uint32(cblc[4:8]))
+
+	var best []byte
+	bestPPEM := 0
+	for i := 0; i < numSizes; i++ {
+		rec := 8 + i*48
+		if rec+48 > len(cblc) {
+			break
+		}
+		size := cblc[rec:]
+		start := binary.BigEndian.Uint16(size[40:42])
+		end := binary.BigEndian.Uint16(size[42:44])
+		if gid < start || gid > end {
+			continue
+		}
+		sizePPEM := int(size[45]) // ppemY
+		arrayOffset := int(binary.BigEndian.Uint32(size[0:4]))
+		numSubtables := int(binary.BigEndian.Uint32(size[8:12]))
+		if png := cblcLookup(cblc, cbdt, arrayOffset, numSubtables, gid); png != nil && closerStrike(sizePPEM, bestPPEM, ppem) {
+			best, bestPPEM = png, sizePPEM
+		}
+	}
+	return best
+}
+
+// cblcLookup finds a glyph in one size's index subtables and decodes its CBDT image
+func cblcLookup(cblc, cbdt []byte, arrayOffset, numSubtables int, gid uint16) []byte {
+	for i := 0; i < numSubtables; i++ {
+		entry := arrayOffset + i*8
+		if entry+8 > len(cblc) {
+			return nil
+		}
+		first := binary.BigEndian.Uint16(cblc[entry:])
+		last := binary.BigEndian.Uint16(cblc[entry+2:])
+		if gid < first || gid > last {
+			continue
+		}
+		sub := arrayOffset + int(binary.BigEndian.Uint32(cblc[entry+4:]))
+		if sub+8 > len(cblc) {
+			return nil
+		}
+		indexFormat := binary.BigEndian.Uint16(cblc[sub:])
+		imageFormat := binary.BigEndian.Uint16(cblc[sub+2:])
+		imageData := int(binary.BigEndian.Uint32(cblc[sub+4:]))
+		body := cblc[sub+8:]
+		k := int(gid - first)
+
+		var start, end int
+		switch indexFormat {
+		case 1: // 32-bit offsets
+			if (k+2)*4 > len(body) {
+				return nil
+			}
+			start = int(binary.BigEndian.Uint32(body[k*4:]))
+			end = int(binary.BigEndian.Uint32(body[(k+1)*4:]))
+		case 3: // 16-bit offsets
+			if (k+2)*2 > len(body) {
+				return nil
+			}
+			start = int(binary.BigEndian.Uint16(body[k*2:]))
+			end = int(binary.BigEndian.Uint16(body[(k+1)*2:]))
+		case 2: // constant image size
+			if len(body) < 4 {
+				return nil
+			}
+			imageSize := int(binary.BigEndian.Uint32(body[0:4]))
+			start, end = k*imageSize, (k+1)*imageSize
+		case 4: // sparse glyph/offset pairs
+			if len(body) < 4 {
+				return nil
+			}
+			n := int(binary.BigEndian.Uint32(body[0:4]))
+			found := false
+			for j := 0; j < n && 4+(j+2)*4 <= len(body); j++ {
+				if binary.BigEndian.Uint16(body[4+j*4:]) == gid {
+					start = int(binary.BigEndian.Uint16(body[4+j*4+2:]))
+					end = int(binary.BigEndian.Uint16(body[4+(j+1)*4+2:]))
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil
+			}
+		case 5: // constant image size, sparse glyph list
+			if len(body) < 16 {
+				return nil
+			}
+			imageSize := int(binary.BigEndian.Uint32(body[0:4]))
+			n := int(binary.BigEndian.Uint32(body[12:16]))
+			found := false
+			for j := 0; j < n && 16+(j+1)*2 <= len(body); j++ {
+				if binary.BigEndian.Uint16(body[16+j*2:]) == gid {
+					start, end = j*imageSize, (j+1)*imageSize
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil
+			}
+		default:
+			return nil
+		}
+
+		start += imageData
+		end += imageData
+		if start >= end || end > len(cbdt) {
+			return nil
+		}
+		return cbdtImage(cbdt[start:end], imageFormat)
+	}
+	return nil
+}
+
+// cbdtImage extracts the PNG from a CBDT glyph record (formats 17, 18 and 19)
+func cbdtImage(data []byte, format uint16) []byte {
+	var offset int
+	switch format {
+	case 17:
+		offset = 5 // smallGlyphMetrics
+	case 18:
+		offset = 8 // bigGlyphMetrics
+	case 19:
+		offset = 0
+	default:
+		return nil
+	}
+	if offset+4 > len(data) {
+		return nil
+	}
+	length := int(binary.BigEndian.Uint32(data[offset:]))
+	png := data[offset+4:]
+	if length > len(png) || !bytes.HasPrefix(png, pngSignature) {
+		return nil
+	}
+	return png[:length]
+}
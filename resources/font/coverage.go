@@ -0,0 +1,106 @@
+package font
+
+import (
+	"fmt"
+	"sort"
+	"unicode"
+)
+
+// CoverageReport lists the characters of a set of texts a font has no glyph
+// for, which would show as .notdef boxes (tofu) in the output
+type CoverageReport struct {
+	Characters int            // Distinct characters checked
+	Missing    []MissingGlyph // Characters without a glyph, by code point
+}
+
+// MissingGlyph is a character a font has no glyph for
+type MissingGlyph struct {
+	Rune  rune
+	Count int   // Occurrences in the texts
+	Texts []int // Indexes of the texts it occurs in
+}
+
+// OK reports whether the font has a glyph for every character
+func (r *CoverageReport) OK() bool {
+	return len(r.Missing) == 0
+}
+
+// MissingRunes returns the characters without a glyph as a string, e.g. for
+// an error message
+func (r *CoverageReport) MissingRunes() string {
+	runes := make([]rune, len(r.Missing))
+	for i, m := range r.Missing {
+		runes[i] = m.Rune
+	}
+	return string(runes)
+}
+
+// Coverage checks a candidate font (TTF/OTF data) against the texts it is
+// meant to show, so missing glyphs surface when the font is chosen rather
+// than in the generated document. Control characters and invisible format
+// characters such as joiners, variation selectors and bidi controls are not
+// checked, as they are not drawn.
+func Coverage(fontData []byte, texts []string) (*CoverageReport, error) {
+	f, err := NewFont("", fontData)
+	if err != nil {
+		return nil, err
+	}
+	ttf, err := ParseTTF(fontData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse font: %w", err)
+	}
+	if _, ok := ttf.Tables["cmap"]; !ok {
+		return nil, fmt.Errorf("font has no cmap table")
+	}
+
+	report := &CoverageReport{}
+	checked := make(map[rune]bool)
+	missing := make(map[rune]*MissingGlyph)
+	for i, text := range texts {
+		for _, r := range text {
+			if unicode.IsControl(r) || isInvisibleFormat(r) {
+				continue
+			}
+			if !checked[r] {
+				checked[r] = true
+				report.Characters++
+				if !f.HasGlyph(r) {
+					missing[r] = &MissingGlyph{Rune: r}
+				}
+			}
+			m, ok := missing[r]
+			if !ok {
+				continue
+			}
+			m.Count++
+			if len(m.Texts) == 0 || m.Texts[len(m.Texts)-1] != i {
+				m.Texts = append(m.Texts, i)
+			}
+		}
+	}
+
+	report.Missing = make([]MissingGlyph, 0, len(missing))
+	for _, m := range missing {
+		report.Missing = append(report.Missing, *m)
+	}
+	sort.Slice(report.Missing, func(i, j int) bool { return report.Missing[i].Rune < report.Missing[j].Rune })
+	return report, nil
+}
+
+// isInvisibleFormat reports whether r is a format character that shapes or
+// orders text without a glyph of its own
+func isInvisibleFormat(r rune) bool {
+	switch {
+	case r == 0x00AD || r == 0xFEFF || r == 0x2060: // soft hyphen, BOM, word joiner
+		return true
+	case r == 0x200B || r == 0x200C || r == 0x200D: // zero width space, ZWNJ, ZWJ
+		return true
+	case r == 0x200E || r == 0x200F, r >= 0x202A && r <= 0x202E, r >= 0x2066 && r <= 0x2069: // bidi controls
+		return true
+	case r >= 0xFE00 && r <= 0xFE0F, r >= 0xE0100 && r <= 0xE01EF: // variation selectors
+		return true
+	case r >= 0xE0020 && r <= 0xE007F: // tags
+		return true
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package font
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCoverage(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "tests", "resources", "test_font.ttf"))
+	if err != nil {
+		t.Skipf("test font not available: %v", err)
+	}
+
+	// The Gothic test font covers the Gothic block and the space, not Latin
+	report, err := Coverage(data, []string{"\U00010330 \U00010331", "\U00010330 ab\n", "b\u200d"})
+	if err != nil {
+		t.Fatalf("Coverage failed: %v", err)
+	}
+	if report.OK() || report.Characters != 5 || report.MissingRunes() != "ab" {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+	if b := report.Missing[1]; b.Count != 2 || len(b.Texts) != 2 || b.Texts[0] != 1 || b.Texts[1] != 2 {
+		t.Errorf("Expected b twice in texts 1 and 2, got %+v", b)
+	}
+
+	if report, err = Coverage(data, []string{"\U00010330\U00010331"}); err != nil || !report.OK() {
+		t.Errorf("Coverage = %+v, %v; want full coverage", report, err)
+	}
+	if _, err := Coverage([]byte("not a font"), nil); err == nil {
+		t.Error("Expected an error for data that is not a font")
+	}
+}
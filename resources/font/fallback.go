@@ -0,0 +1,188 @@
+package font
+
+import (
+	"unicode"
+)
+
+// ScriptEmoji is the script name SetScriptFonts uses for emoji and pictographs
+const ScriptEmoji = "Emoji"
+
+// TextRun is a piece of text drawn with a single font
+type TextRun struct {
+	Text    string
+	Font    *Font
+	Missing []rune // Characters no font in the chain has a glyph for (drawn as .notdef)
+}
+
+// FallbackChain chooses, character by character, the first font that has a glyph,
+// so mixed-script strings and emoji render instead of showing .notdef boxes.
+// Fonts registered for a script (Unicode script names such as "Arabic", "Han" or
+// ScriptEmoji) are tried before the general list.
+type FallbackChain struct {
+	fonts   []*Font
+	scripts map[string][]*Font
+}
+
+// NewFallbackChain creates a chain that tries fonts in order; the first font is
+// used for characters no font covers
+func NewFallbackChain(fonts ...*Font) *FallbackChain {
+	return &FallbackChain{
+		fonts:   fonts,
+		scripts: make(map[string][]*Font),
+	}
+}
+
+// Add appends fonts to the end of the chain
+func (c *FallbackChain) Add(fonts ...*Font) {
+	c.fonts = append(c.fonts, fonts...)
+}
+
+// SetScriptFonts sets the fonts tried first for characters of a script
+func (c *FallbackChain) SetScriptFonts(script string, fonts ...*Font) {
+	c.scripts[script] = fonts
+}
+
+// Segment splits text into runs by the font that covers each character.
+// Spaces, punctuation and other characters common to all scripts stay in the
+// current run when its font has them; combining marks, variation selectors and
+// zero-width joiners always stay with the character they modify.
+func (c *FallbackChain) Segment(text string) []TextRun {
+	var runs []TextRun
+	var current *TextRun
+	start := 0
+
+	flush := func(end int) {
+		if current != nil && end > start {
+			current.Text = text[start:end]
+			runs = append(runs, *current)
+		}
+	}
+
+	for i, r := range text {
+		if current != nil && (isClusterExtender(r) || (isCommonScript(r) && current.Font != nil && current.Font.HasGlyph(r))) {
+			continue
+		}
+
+		f := c.fontFor(r)
+		missing := f == nil
+		if missing {
+			f = c.primary()
+		}
+		if current != nil && current.Font == f {
+			if missing {
+				current.Missing = append(current.Missing, r)
+			}
+			continue
+		}
+
+		flush(i)
+		current = &TextRun{Font: f}
+		if missing {
+			current.Missing = []rune{r}
+		}
+		start = i
+	}
+	flush(len(text))
+	return runs
+}
+
+// FontFor returns the font the chain uses for r, or nil if none has a glyph for it
+func (c *FallbackChain) FontFor(r rune) *Font {
+	return c.fontFor(r)
+}
+
+// TextWidth returns the advance width of text in points at the given size, using
+// the font each run is drawn with
+func (c *FallbackChain) TextWidth(text string, size float64) (float64, error) {
+	total := 0.0
+	for _, run := range c.Segment(text) {
+		if run.Font == nil {
+			continue
+		}
+		w, err := run.Font.TextWidth(run.Text, size)
+		if err != nil {
+			return 0, err
+		}
+		total += w
+	}
+	return total, nil
+}
+
+func (c *FallbackChain) fontFor(r rune) *Font {
+	if script := scriptOf(r); script != "" {
+		for _, f := range c.scripts[script] {
+			if f.HasGlyph(r) {
+				return f
+			}
+		}
+	}
+	for _, f := range c.fonts {
+		if f.HasGlyph(r) {
+			return f
+		}
+	}
+	return nil
+}
+
+func (c *FallbackChain) primary() *Font {
+	if len(c.fonts) > 0 {
+		return c.fonts[0]
+	}
+	for _, fonts := range c.scripts {
+		if len(fonts) > 0 {
+			return fonts[0]
+		}
+	}
+	return nil
+}
+
+// scriptOf returns the Unicode script name of r, ScriptEmoji for pictographs, or
+// "" for characters shared by all scripts
+func scriptOf(r rune) string {
+	if isEmoji(r) {
+		return ScriptEmoji
+	}
+	if isCommonScript(r) {
+		return ""
+	}
+	for name, table := range unicode.Scripts {
+		if unicode.Is(table, r) {
+			return name
+		}
+	}
+	return ""
+}
+
+func isCommonScript(r rune) bool {
+	return unicode.Is(unicode.Common, r) || unicode.Is(unicode.Inherited, r)
+}
+
+// isEmoji reports whether r is in one of the emoji and pictograph blocks
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF: // Mahjong through Symbols and Pictographs Extended-A
+		return true
+	case r >= 0x2600 && r <= 0x27BF: // Miscellaneous Symbols, Dingbats
+		return true
+	case r >= 0x2B00 && r <= 0x2BFF: // Miscellaneous Symbols and Arrows
+		return true
+	}
+	return false
+}
+
+// isClusterExtender reports whether r modifies the preceding character rather than
+// starting a new one: combining marks, variation selectors, joiners, emoji skin
+// tone modifiers and tag characters
+func isClusterExtender(r rune) bool {
+	switch {
+	case r == 0x200D || r == 0x200C: // ZWJ, ZWNJ
+		return true
+	case r >= 0xFE00 && r <= 0xFE0F, r >= 0xE0100 && r <= 0xE01EF: // variation selectors
+		return true
+	case r >= 0x1F3FB && r <= 0x1F3FF: // skin tone modifiers
+		return true
+	case r >= 0xE0020 && r <= 0xE007F: // tags
+		return true
+	}
+	return unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r)
+}
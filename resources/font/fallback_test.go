@@ -0,0 +1,246 @@
+package font
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/png"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// rebuildTestFont returns the test font with some tables replaced
+func rebuildTestFont(t *testing.T, replace map[string][]byte) *Font {
+	t.Helper()
+	data, err := os.ReadFile(substitutionTestFont(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ttf, err := ParseTTF(data)
+	if err != nil {
+		t.Fatalf("ParseTTF failed: %v", err)
+	}
+	tables := make(map[string][]byte)
+	for tag, table := range ttf.Tables {
+		tables[tag] = append([]byte(nil), table.Data...)
+	}
+	for tag, table := range replace {
+		tables[tag] = table
+	}
+	f, err := NewFont("Test", buildSFNT(tables))
+	if err != nil {
+		t.Fatalf("NewFont failed: %v", err)
+	}
+	return f
+}
+
+// cmapFormat12 builds a cmap table mapping each range of characters to consecutive
+// glyphs starting at the given glyph ID
+func cmapFormat12(groups ...[3]uint32) []byte {
+	out := []byte{0, 0, 0, 1, 0, 3, 0, 10, 0, 0, 0, 12}
+	out = append(out, 0, 12, 0, 0)
+	out = binary.BigEndian.AppendUint32(out, uint32(16+12*len(groups)))
+	out = binary.BigEndian.AppendUint32(out, 0)
+	out = binary.BigEndian.AppendUint32(out, uint32(len(groups)))
+	for _, g := range groups {
+		for _, v := range g {
+			out = binary.BigEndian.AppendUint32(out, v)
+		}
+	}
+	return out
+}
+
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestFallbackChain_Segment(t *testing.T) {
+	gothic := rebuildTestFont(t, nil)
+	latin := rebuildTestFont(t, map[string][]byte{"cmap": cmapFormat12(
+		[3]uint32{' ', ' ', 3},
+		[3]uint32{'A', 'Z', 8},
+		[3]uint32{0x0308, 0x0308, 6},
+	)})
+
+	chain := NewFallbackChain(latin, gothic)
+	runs := chain.Segment("AB \U00010330\U00010331 C̈ 中")
+
+	var texts []string
+	for _, run := range runs {
+		texts = append(texts, run.Text)
+	}
+	want := []string{"AB ", "\U00010330\U00010331 ", "C̈ 中"}
+	if !reflect.DeepEqual(texts, want) {
+		t.Fatalf("Segment = %q, want %q", texts, want)
+	}
+	if runs[0].Font != latin || runs[1].Font != gothic || runs[2].Font != latin {
+		t.Error("Runs use the wrong fonts")
+	}
+	if !reflect.DeepEqual(runs[2].Missing, []rune{0x4E2D}) {
+		t.Errorf("Expected U+4E2D reported missing, got %q", runs[2].Missing)
+	}
+
+	// Script fonts are preferred over the general chain
+	chain.SetScriptFonts("Latin", gothic, latin)
+	if f := chain.FontFor('A'); f != latin {
+		t.Error("Script font without the glyph was used")
+	}
+	alt := rebuildTestFont(t, map[string][]byte{"cmap": cmapFormat12([3]uint32{'A', 'Z', 8})})
+	chain.SetScriptFonts("Latin", alt)
+	if f := chain.FontFor('A'); f != alt {
+		t.Error("Script font not preferred")
+	}
+
+	width, err := NewFallbackChain(latin, gothic).TextWidth("A\U00010330", 10)
+	if err != nil {
+		t.Fatalf("TextWidth failed: %v", err)
+	}
+	a, _ := latin.TextWidth("A", 10)
+	g, _ := gothic.TextWidth("\U00010330", 10)
+	if width != a+g {
+		t.Errorf("TextWidth = %v, want %v", width, a+g)
+	}
+}
+
+func TestColorGlyph_Sbix(t *testing.T) {
+	pngData := testPNG(t)
+	base := rebuildTestFont(t, nil)
+	ttf, _ := ParseTTF(base.Data)
+	gid, _ := base.GlyphIndex(0x10330)
+	numGlyphs := int(ttf.NumGlyphs)
+
+	dupe, _ := base.GlyphIndex(0x10331)
+
+	// Two strikes; U+10331 duplicates the bitmap of U+10330
+	strike := func(ppem uint16) []byte {
+		out := binary.BigEndian.AppendUint16(nil, ppem)
+		out = binary.BigEndian.AppendUint16(out, 72)
+		dataStart := 4 + (numGlyphs+1)*4
+		var glyphs []byte
+		for g := 0; g <= numGlyphs; g++ {
+			out = binary.BigEndian.AppendUint32(out, uint32(dataStart+len(glyphs)))
+			switch {
+			case g == int(gid):
+				glyphs = append(glyphs, 0, 0, 0, 0)
+				glyphs = append(glyphs, "png "...)
+				glyphs = append(glyphs, pngData...)
+				glyphs = append(glyphs, byte(ppem))
+			case g == int(dupe):
+				glyphs = append(glyphs, 0, 0, 0, 0)
+				glyphs = append(glyphs, "dupe"...)
+				glyphs = binary.BigEndian.AppendUint16(glyphs, gid)
+			}
+		}
+		return append(out, glyphs...)
+	}
+	small, large := strike(20), strike(160)
+	sbix := []byte{0, 1, 0, 1, 0, 0, 0, 2}
+	sbix = binary.BigEndian.AppendUint32(sbix, 16)
+	sbix = binary.BigEndian.AppendUint32(sbix, uint32(16+len(small)))
+	sbix = append(append(sbix, small...), large...)
+
+	f := rebuildTestFont(t, map[string][]byte{"sbix": sbix})
+	if !f.HasColorGlyphs() {
+		t.Fatal("Expected color glyphs")
+	}
+	for _, tt := range []struct {
+		ppem int
+		want byte
+	}{{16, 20}, {48, 160}, {400, 160}} {
+		data, ok := f.ColorGlyph(0x10330, tt.ppem)
+		if !ok || !bytes.HasPrefix(data, pngSignature) || data[len(data)-1] != tt.want {
+			t.Errorf("ColorGlyph(ppem %d) did not return the %d ppem strike", tt.ppem, tt.want)
+		}
+	}
+	if data, ok := f.ColorGlyph(0x10331, 20); !ok || data[len(data)-1] != 20 {
+		t.Error("dupe glyph not resolved")
+	}
+	if _, ok := f.ColorGlyph(0x10332, 20); ok {
+		t.Error("Expected no bitmap for a glyph without one")
+	}
+}
+
+func TestColorGlyph_CBDT(t *testing.T) {
+	pngData := testPNG(t)
+	base := rebuildTestFont(t, nil)
+	gid, _ := base.GlyphIndex(0x10330)
+
+	// CBDT: header then one format 17 image
+	cbdt := []byte{0, 3, 0, 0}
+	cbdt = append(cbdt, 2, 2, 0, 2, 2) // smallGlyphMetrics
+	cbdt = binary.BigEndian.AppendUint32(cbdt, uint32(len(pngData)))
+	cbdt = append(cbdt, pngData...)
+
+	// CBLC: one size covering gid with an index format 1 subtable
+	cblc := []byte{0, 3, 0, 0, 0, 0, 0, 1}
+	size := make([]byte, 48)
+	binary.BigEndian.PutUint32(size[0:], 56) // indexSubTableArrayOffset
+	binary.BigEndian.PutUint32(size[8:], 1)
+	binary.BigEndian.PutUint16(size[40:], gid)
+	binary.BigEndian.PutUint16(size[42:], gid)
+	size[44], size[45], size[46] = 109, 109, 32
+	cblc = append(cblc, size...)
+	cblc = binary.BigEndian.AppendUint16(cblc, gid)
+	cblc = binary.BigEndian.AppendUint16(cblc, gid)
+	cblc = binary.BigEndian.AppendUint32(cblc, 8)
+	cblc = append(cblc, 0, 1, 0, 17)              // index format 1, image format 17
+	cblc = binary.BigEndian.AppendUint32(cblc, 4) // image data offset in CBDT
+	cblc = binary.BigEndian.AppendUint32(cblc, 0) // glyph offsets
+	cblc = binary.BigEndian.AppendUint32(cblc, uint32(len(cbdt)-4))
+
+	f := rebuildTestFont(t, map[string][]byte{"CBLC": cblc, "CBDT": cbdt})
+	data, ok := f.ColorGlyph(0x10330, 64)
+	if !ok || !bytes.Equal(data, pngData) {
+		t.Fatalf("ColorGlyph did not return the CBDT bitmap")
+	}
+	if _, ok := f.ColorGlyph(0x10331, 64); ok {
+		t.Error("Expected no bitmap outside the indexed range")
+	}
+}
+
+func TestType0PDFObjects(t *testing.T) {
+	f := rebuildTestFont(t, nil)
+	f.AddString("\U00010330\U00010331")
+	if hex := f.GlyphHex("\U00010330"); len(hex) != 4 || hex == "0000" {
+		t.Errorf("Unexpected glyph hex %q", hex)
+	}
+
+	w := &recordingWriter{}
+	objs, err := f.ToType0PDFObjects(w)
+	if err != nil {
+		t.Fatalf("ToType0PDFObjects failed: %v", err)
+	}
+	fontDict := string(w.objects[objs.FontDictNum-1])
+	for _, want := range []string{"/Subtype /Type0", "/Encoding /Identity-H", "/DescendantFonts"} {
+		if !bytes.Contains([]byte(fontDict), []byte(want)) {
+			t.Errorf("Font dictionary missing %s: %s", want, fontDict)
+		}
+	}
+	if cmap := string(w.objects[objs.ToUnicodeNum-1]); !bytes.Contains([]byte(cmap), []byte("<D800DF30>")) {
+		t.Errorf("ToUnicode does not map to the UTF-16 of U+10330:\n%s", cmap)
+	}
+}
+
+// recordingWriter keeps objects in memory, numbered from 1
+type recordingWriter struct {
+	objects [][]byte
+}
+
+func (w *recordingWriter) AddObject(content []byte) int {
+	w.objects = append(w.objects, content)
+	return len(w.objects)
+}
+
+func (w *recordingWriter) AddStreamObject(dict map[string]interface{}, data []byte, compress bool) int {
+	return w.AddObject(data)
+}
+
+func (w *recordingWriter) NextObjectNumber() int {
+	return len(w.objects) + 1
+}
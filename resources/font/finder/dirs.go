@@ -0,0 +1,163 @@
+package finder
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// SystemFontDirs returns the directories the platform installs fonts in, user
+// directories included. On Linux and other Unix systems the directories listed in
+// the fontconfig configuration are added to the conventional defaults.
+func SystemFontDirs() []string {
+	home, _ := os.UserHomeDir()
+
+	var dirs []string
+	switch runtime.GOOS {
+	case "darwin":
+		dirs = []string{
+			"/System/Library/Fonts",
+			"/Library/Fonts",
+			"/Network/Library/Fonts",
+		}
+		if home != "" {
+			dirs = append(dirs, filepath.Join(home, "Library", "Fonts"))
+		}
+	case "windows":
+		windir := os.Getenv("WINDIR")
+		if windir == "" {
+			windir = `C:\Windows`
+		}
+		dirs = []string{filepath.Join(windir, "Fonts")}
+		if local := os.Getenv("LOCALAPPDATA"); local != "" {
+			dirs = append(dirs, filepath.Join(local, "Microsoft", "Windows", "Fonts"))
+		}
+	default:
+		dataHome := os.Getenv("XDG_DATA_HOME")
+		if dataHome == "" && home != "" {
+			dataHome = filepath.Join(home, ".local", "share")
+		}
+		dirs = []string{"/usr/share/fonts", "/usr/local/share/fonts"}
+		if dataHome != "" {
+			dirs = append(dirs, filepath.Join(dataHome, "fonts"))
+		}
+		if home != "" {
+			dirs = append(dirs, filepath.Join(home, ".fonts"))
+		}
+		dirs = append(dirs, fontconfigDirs("/etc/fonts/fonts.conf", home, dataHome)...)
+	}
+	return dedupe(dirs)
+}
+
+// fontconfigFile holds the parts of a fontconfig configuration that name font directories
+type fontconfigFile struct {
+	Dirs []struct {
+		Prefix string `xml:"prefix,attr"`
+		Path   string `xml:",chardata"`
+	} `xml:"dir"`
+	Includes []struct {
+		Prefix string `xml:"prefix,attr"`
+		Path   string `xml:",chardata"`
+	} `xml:"include"`
+}
+
+// fontconfigDirs returns the <dir> entries of a fontconfig file and the files it
+// <include>s. Included directories are read in name order, as fontconfig does.
+func fontconfigDirs(confPath, home, dataHome string) []string {
+	var dirs []string
+	visited := make(map[string]bool)
+
+	var read func(path string)
+	read = func(path string) {
+		if visited[path] {
+			return
+		}
+		visited[path] = true
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return
+		}
+		if info.IsDir() {
+			entries, err := os.ReadDir(path)
+			if err != nil {
+				return
+			}
+			for _, entry := range entries {
+				if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".conf") {
+					read(filepath.Join(path, entry.Name()))
+				}
+			}
+			return
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return
+		}
+		var conf fontconfigFile
+		if err := xml.Unmarshal(data, &conf); err != nil {
+			return
+		}
+		for _, dir := range conf.Dirs {
+			if p := fontconfigPath(dir.Path, dir.Prefix, filepath.Dir(path), home, dataHome); p != "" {
+				dirs = append(dirs, p)
+			}
+		}
+		for _, include := range conf.Includes {
+			if p := fontconfigPath(include.Path, include.Prefix, filepath.Dir(path), home, ""); p != "" {
+				read(p)
+			}
+		}
+	}
+	read(confPath)
+	return dirs
+}
+
+// fontconfigPath resolves a fontconfig path: "~" is the home directory, prefix
+// "xdg" is relative to the XDG data (or config) directory and other relative paths
+// to the directory of the configuration file
+func fontconfigPath(path, prefix, confDir, home, dataHome string) string {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return ""
+	}
+	switch {
+	case prefix == "xdg":
+		base := dataHome
+		if base == "" {
+			base = os.Getenv("XDG_CONFIG_HOME")
+			if base == "" && home != "" {
+				base = filepath.Join(home, ".config")
+			}
+		}
+		if base == "" {
+			return ""
+		}
+		return filepath.Join(base, path)
+	case path == "~" || strings.HasPrefix(path, "~/"):
+		if home == "" {
+			return ""
+		}
+		return filepath.Join(home, path[1:])
+	case !filepath.IsAbs(path):
+		return filepath.Join(confDir, path)
+	}
+	return path
+}
+
+// dedupe removes repeated paths, keeping the first occurrence
+func dedupe(paths []string) []string {
+	seen := make(map[string]bool)
+	out := paths[:0]
+	for _, p := range paths {
+		p = filepath.Clean(p)
+		if !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,265 @@
+// Package finder locates installed system fonts by family, weight and style
+package finder
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/benedoc-inc/pdfer/resources/font"
+)
+
+// Common OpenType weight classes
+const (
+	WeightThin     = 100
+	WeightLight    = 300
+	WeightRegular  = 400
+	WeightMedium   = 500
+	WeightSemiBold = 600
+	WeightBold     = 700
+	WeightBlack    = 900
+)
+
+const (
+	defaultWeight    = WeightRegular
+	maxFontFileBytes = 64 << 20
+
+	// OS/2 table: usWeightClass and fsSelection offsets and style bits
+	os2WeightOffset = 4
+	os2FsSelOffset  = 62
+	fsSelItalic     = 1 << 0
+	fsSelOblique    = 1 << 9
+
+	// head table: macStyle offset and bits, used when OS/2 is missing
+	headMacStyleOffset = 44
+	macStyleBold       = 1 << 0
+	macStyleItalic     = 1 << 1
+)
+
+// ErrNotFound is returned when no installed font matches a family
+var ErrNotFound = errors.New("font not found")
+
+// Face describes one installed font file
+type Face struct {
+	Path           string `json:"path"`
+	Family         string `json:"family"`
+	FullName       string `json:"full_name,omitempty"`
+	PostScriptName string `json:"postscript_name,omitempty"`
+	Weight         int    `json:"weight"` // OpenType weight class (400 regular, 700 bold)
+	Italic         bool   `json:"italic"`
+}
+
+// Finder indexes the TrueType/OpenType fonts in a set of directories.
+// Directories are scanned on first use; font collections (.ttc) are skipped
+// because the font package embeds single fonts only.
+type Finder struct {
+	dirs  []string
+	files []string
+
+	once  sync.Once
+	faces []Face
+}
+
+// New creates a Finder over the platform's font directories and, on Windows,
+// the fonts listed in the registry
+func New() *Finder {
+	return &Finder{dirs: SystemFontDirs(), files: registryFonts()}
+}
+
+// NewWithDirs creates a Finder over the given directories only
+func NewWithDirs(dirs ...string) *Finder {
+	return &Finder{dirs: dirs}
+}
+
+var (
+	defaultFinder     *Finder
+	defaultFinderOnce sync.Once
+)
+
+// Find returns the best installed match for a family using a shared system Finder
+func Find(family string, weight int, italic bool) (Face, error) {
+	defaultFinderOnce.Do(func() { defaultFinder = New() })
+	return defaultFinder.Find(family, weight, italic)
+}
+
+// Faces returns every font found, in path order
+func (f *Finder) Faces() []Face {
+	f.once.Do(f.scan)
+	return append([]Face(nil), f.faces...)
+}
+
+// Find returns the face of the family closest to the requested weight and style.
+// Family names are compared ignoring case, spaces and hyphens; a weight of 0
+// means regular. Style is matched before weight, so a bold italic request prefers
+// a regular italic over a bold upright face.
+func (f *Finder) Find(family string, weight int, italic bool) (Face, error) {
+	f.once.Do(f.scan)
+	if weight <= 0 {
+		weight = defaultWeight
+	}
+
+	want := normalizeFamily(family)
+	best, bestScore := -1, 0
+	for i, face := range f.faces {
+		if normalizeFamily(face.Family) != want {
+			continue
+		}
+		score := weightScore(face.Weight, weight)
+		if face.Italic != italic {
+			score += 10000
+		}
+		if best == -1 || score < bestScore {
+			best, bestScore = i, score
+		}
+	}
+	if best == -1 {
+		return Face{}, ErrNotFound
+	}
+	return f.faces[best], nil
+}
+
+// Load finds a face and reads it as an embeddable font
+func (f *Finder) Load(family string, weight int, italic bool) (*font.Font, error) {
+	face, err := f.Find(family, weight, italic)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(face.Path)
+	if err != nil {
+		return nil, err
+	}
+	name := face.PostScriptName
+	if name == "" {
+		name = face.Family
+	}
+	return font.NewFont(name, data)
+}
+
+// scan walks the directories and registry entries, indexing each font file once
+func (f *Finder) scan() {
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if seen[path] || !isFontFile(path) {
+			return
+		}
+		seen[path] = true
+		if face, ok := readFace(path); ok {
+			f.faces = append(f.faces, face)
+		}
+	}
+
+	for _, dir := range f.dirs {
+		filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				// Unreadable or missing directories are skipped
+				if d != nil && d.IsDir() && path != dir {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.IsDir() {
+				add(path)
+			}
+			return nil
+		})
+	}
+	for _, path := range f.files {
+		add(path)
+	}
+}
+
+// isFontFile reports whether a path has a single-font TrueType/OpenType extension
+func isFontFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ttf", ".otf":
+		return true
+	}
+	return false
+}
+
+// readFace reads the names, weight and style of a font file
+func readFace(path string) (Face, bool) {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > maxFontFileBytes {
+		return Face{}, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Face{}, false
+	}
+	ttf, err := font.ParseTTF(data)
+	if err != nil || ttf.FamilyName == "" {
+		return Face{}, false
+	}
+
+	face := Face{
+		Path:           path,
+		Family:         ttf.FamilyName,
+		FullName:       ttf.FullName,
+		PostScriptName: ttf.PostScriptName,
+		Weight:         defaultWeight,
+		Italic:         ttf.ItalicAngle != 0,
+	}
+
+	if os2, ok := ttf.Tables["OS/2"]; ok && len(os2.Data) >= os2FsSelOffset+2 {
+		if w := int(uint16(os2.Data[os2WeightOffset])<<8 | uint16(os2.Data[os2WeightOffset+1])); w > 0 {
+			face.Weight = w
+		}
+		fsSelection := uint16(os2.Data[os2FsSelOffset])<<8 | uint16(os2.Data[os2FsSelOffset+1])
+		face.Italic = fsSelection&(fsSelItalic|fsSelOblique) != 0
+	} else if head, ok := ttf.Tables["head"]; ok && len(head.Data) >= headMacStyleOffset+2 {
+		macStyle := uint16(head.Data[headMacStyleOffset])<<8 | uint16(head.Data[headMacStyleOffset+1])
+		if macStyle&macStyleBold != 0 {
+			face.Weight = WeightBold
+		}
+		face.Italic = macStyle&macStyleItalic != 0
+	}
+	return face, true
+}
+
+// normalizeFamily lowercases a family name and drops spaces, hyphens and underscores
+func normalizeFamily(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' || r == '_' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(name))
+}
+
+// weightScore ranks how well an available weight fits the desired one, lower
+// being better, following the CSS font matching fallback order: weights between
+// 400 and 500 look up to 500, then lighter, then heavier; lighter requests look
+// lighter first and bolder requests bolder first
+func weightScore(available, desired int) int {
+	distance := abs(available - desired)
+	switch {
+	case desired >= WeightRegular && desired <= WeightMedium:
+		if available >= desired && available <= WeightMedium {
+			return distance
+		}
+		if available < desired {
+			return 1000 + distance
+		}
+		return 2000 + distance
+	case desired < WeightRegular:
+		if available <= desired {
+			return distance
+		}
+		return 1000 + distance
+	default:
+		if available >= desired {
+			return distance
+		}
+		return 1000 + distance
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,130 @@
+package finder
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func testFontDir(t *testing.T) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "..", "..", "tests", "resources", "test_font.ttf"))
+	if err != nil {
+		t.Skip("test font not found")
+	}
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"sub/test.ttf", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestFinder_Find(t *testing.T) {
+	f := NewWithDirs(testFontDir(t), filepath.Join(t.TempDir(), "missing"))
+
+	faces := f.Faces()
+	if len(faces) != 1 {
+		t.Fatalf("Expected 1 face (non-font files skipped), got %+v", faces)
+	}
+	if faces[0].Family != "Noto Sans Gothic" || faces[0].Weight != WeightRegular || faces[0].Italic {
+		t.Errorf("Unexpected face %+v", faces[0])
+	}
+
+	// Closest weight and style of the family still match
+	face, err := f.Find("noto-sans gothic", WeightBold, true)
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if face.Path != faces[0].Path {
+		t.Errorf("Find returned %s", face.Path)
+	}
+
+	if _, err := f.Find("Helvetica", 0, false); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	loaded, err := f.Load("Noto Sans Gothic", 0, false)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.Name != "NotoSansGothic-Regular" {
+		t.Errorf("Loaded font name %q", loaded.Name)
+	}
+}
+
+func TestFinder_PrefersStyleThenWeight(t *testing.T) {
+	f := NewWithDirs()
+	f.once.Do(func() {})
+	f.faces = []Face{
+		{Path: "regular", Family: "Sans", Weight: 400},
+		{Path: "bold", Family: "Sans", Weight: 700},
+		{Path: "italic", Family: "Sans", Weight: 400, Italic: true},
+		{Path: "semibold", Family: "Sans", Weight: 600},
+	}
+
+	tests := []struct {
+		weight int
+		italic bool
+		want   string
+	}{
+		{0, false, "regular"},
+		{700, false, "bold"},
+		{700, true, "italic"},
+		{650, false, "bold"},
+		{500, false, "regular"},
+	}
+	for _, tt := range tests {
+		face, err := f.Find("Sans", tt.weight, tt.italic)
+		if err != nil || face.Path != tt.want {
+			t.Errorf("Find(%d, %v) = %s, %v; want %s", tt.weight, tt.italic, face.Path, err, tt.want)
+		}
+	}
+}
+
+func TestFontconfigDirs(t *testing.T) {
+	dir := t.TempDir()
+	confD := filepath.Join(dir, "conf.d")
+	if err := os.MkdirAll(confD, 0755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(path, content string) {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(dir, "fonts.conf"), `<?xml version="1.0"?>
+<fontconfig>
+	<dir>/usr/share/fonts</dir>
+	<dir prefix="xdg">fonts</dir>
+	<dir>~/.fonts</dir>
+	<include ignore_missing="yes">conf.d</include>
+	<include ignore_missing="yes">missing.conf</include>
+</fontconfig>`)
+	write(filepath.Join(confD, "10-extra.conf"), `<fontconfig><dir>/opt/fonts</dir></fontconfig>`)
+	write(filepath.Join(confD, "README"), `<fontconfig><dir>/ignored</dir></fontconfig>`)
+
+	got := fontconfigDirs(filepath.Join(dir, "fonts.conf"), "/home/user", "/home/user/.local/share")
+	want := []string{"/usr/share/fonts", "/home/user/.local/share/fonts", "/home/user/.fonts", "/opt/fonts"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fontconfigDirs = %v, want %v", got, want)
+	}
+}
+
+func TestParseRegQuery(t *testing.T) {
+	output := "\r\nHKEY_LOCAL_MACHINE\\SOFTWARE\\Microsoft\\Windows NT\\CurrentVersion\\Fonts\r\n" +
+		"    Arial (TrueType)    REG_SZ    arial.ttf\r\n" +
+		"    Custom (TrueType)    REG_SZ    D:\\Fonts\\custom.ttf\r\n" +
+		"    Empty    REG_SZ    \r\n"
+
+	got := parseRegQuery(output, "Fonts")
+	want := []string{filepath.Join("Fonts", "arial.ttf"), `D:\Fonts\custom.ttf`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseRegQuery = %q, want %q", got, want)
+	}
+}
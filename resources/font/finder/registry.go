@@ -0,0 +1,28 @@
+package finder
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// parseRegQuery extracts font file paths from `reg query` output of the Windows
+// Fonts key, e.g. "    Arial (TrueType)    REG_SZ    arial.ttf". Bare file names
+// are relative to fontsDir.
+func parseRegQuery(output, fontsDir string) []string {
+	var files []string
+	for _, line := range strings.Split(output, "\n") {
+		i := strings.Index(line, "REG_SZ")
+		if i == -1 {
+			continue
+		}
+		file := strings.TrimSpace(line[i+len("REG_SZ"):])
+		if file == "" {
+			continue
+		}
+		if !strings.ContainsAny(file, `\/`) {
+			file = filepath.Join(fontsDir, file)
+		}
+		files = append(files, file)
+	}
+	return files
+}
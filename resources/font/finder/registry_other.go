@@ -0,0 +1,8 @@
+//go:build !windows
+
+package finder
+
+// registryFonts returns nothing outside Windows, which has no font registry
+func registryFonts() []string {
+	return nil
+}
@@ -0,0 +1,35 @@
+//go:build windows
+
+package finder
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// fontRegistryKeys list installed fonts for the machine and the current user
+var fontRegistryKeys = []string{
+	`HKLM\SOFTWARE\Microsoft\Windows NT\CurrentVersion\Fonts`,
+	`HKCU\SOFTWARE\Microsoft\Windows NT\CurrentVersion\Fonts`,
+}
+
+// registryFonts returns the font files registered under the Windows Fonts keys.
+// Fonts installed outside the Fonts directories are only found this way.
+func registryFonts() []string {
+	windir := os.Getenv("WINDIR")
+	if windir == "" {
+		windir = `C:\Windows`
+	}
+	fontsDir := filepath.Join(windir, "Fonts")
+
+	var files []string
+	for _, key := range fontRegistryKeys {
+		out, err := exec.Command("reg", "query", key).Output()
+		if err != nil {
+			continue
+		}
+		files = append(files, parseRegQuery(string(out), fontsDir)...)
+	}
+	return files
+}
@@ -17,6 +17,9 @@ type Font struct {
 	Data    []byte // Raw font file (TTF/OTF)
 	Subset  []rune // Characters to include in subset
 	FontID  string // Unique identifier for this font instance
+
+	glyphMap map[rune]uint16 // cmap, loaded on first glyph lookup
+	metrics  *fontMetrics    // advance widths and line metrics, loaded on first measurement
 }
 
 // TTF represents a parsed TrueType font
@@ -552,6 +555,38 @@ func (f *Font) GetWidths() ([]int, error) {
 	return widths, nil
 }
 
+// GlyphIndex returns the glyph ID the font maps r to
+func (f *Font) GlyphIndex(r rune) (uint16, bool) {
+	if f.glyphMap == nil {
+		f.glyphMap = make(map[rune]uint16)
+		if ttf, err := ParseTTF(f.Data); err == nil {
+			if cmap, ok := ttf.Tables["cmap"]; ok {
+				if glyphMap, err := parseCmap(cmap.Data); err == nil {
+					f.glyphMap = glyphMap
+				}
+			}
+		}
+	}
+	gid, ok := f.glyphMap[r]
+	return gid, ok && gid != 0
+}
+
+// HasGlyph reports whether the font has a glyph for r
+func (f *Font) HasGlyph(r rune) bool {
+	_, ok := f.GlyphIndex(r)
+	return ok
+}
+
+// TextWidth returns the advance width of s in points at the given font size.
+// Characters the font has no glyph for use the .notdef width.
+func (f *Font) TextWidth(s string, size float64) (float64, error) {
+	m, err := f.MeasureString(s, size)
+	if err != nil {
+		return 0, err
+	}
+	return m.Width, nil
+}
+
 // Read reads font data from a reader
 func Read(r io.Reader) ([]byte, error) {
 	var buf bytes.Buffer
@@ -0,0 +1,79 @@
+package font
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Embedding is the embedding permission a font's licence grants, the fsType
+// field of its OS/2 table. The zero value is installable embedding, which
+// puts no restriction on embedding.
+type Embedding uint16
+
+// Embedding permission bits (OpenType OS/2 fsType)
+const (
+	EmbeddingRestricted   Embedding = 0x0002 // The font must not be embedded
+	EmbeddingPreviewPrint Embedding = 0x0004 // Documents may embed the font but be opened read-only
+	EmbeddingEditable     Embedding = 0x0008 // Documents may embed the font and be edited
+	EmbeddingNoSubsetting Embedding = 0x0100 // Only the full font may be embedded
+	EmbeddingBitmapOnly   Embedding = 0x0200 // Only bitmaps may be embedded, not outlines
+)
+
+// usageMask covers the bits of fsType that select the usage permission
+const usageMask = 0x000E
+
+// Restricted reports whether the licence forbids embedding the font. The
+// usage bits are exclusive; when a font sets several, the least restrictive
+// one applies.
+func (e Embedding) Restricted() bool {
+	return e&usageMask == EmbeddingRestricted
+}
+
+// Embeddable reports whether the font's outlines may be embedded in a document
+// at all. The writer always embeds outlines of the full font, so subsetting
+// restrictions don't matter to it.
+func (e Embedding) Embeddable() bool {
+	return !e.Restricted() && e&EmbeddingBitmapOnly == 0
+}
+
+func (e Embedding) String() string {
+	var usage string
+	switch {
+	case e&EmbeddingEditable != 0:
+		usage = "editable"
+	case e&EmbeddingPreviewPrint != 0:
+		usage = "preview & print"
+	case e&EmbeddingRestricted != 0:
+		usage = "restricted"
+	default:
+		usage = "installable"
+	}
+	parts := []string{usage}
+	if e&EmbeddingNoSubsetting != 0 {
+		parts = append(parts, "no subsetting")
+	}
+	if e&EmbeddingBitmapOnly != 0 {
+		parts = append(parts, "bitmap only")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Embedding returns the embedding permission of the font. Fonts without an
+// OS/2 table, such as many older Mac fonts, are installable.
+func (f *Font) Embedding() (Embedding, error) {
+	ttf, err := ParseTTF(f.Data)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse font: %w", err)
+	}
+	return ttf.Embedding(), nil
+}
+
+// Embedding returns the embedding permission from the OS/2 table
+func (ttf *TTF) Embedding() Embedding {
+	os2, ok := ttf.Tables["OS/2"]
+	if !ok || len(os2.Data) < 10 {
+		return 0
+	}
+	return Embedding(binary.BigEndian.Uint16(os2.Data[8:10]))
+}
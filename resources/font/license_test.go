@@ -0,0 +1,51 @@
+package font
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEmbedding(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "tests", "resources", "test_font.ttf"))
+	if err != nil {
+		t.Skipf("test font not available: %v", err)
+	}
+	ttf, err := ParseTTF(data)
+	if err != nil {
+		t.Fatalf("ParseTTF failed: %v", err)
+	}
+	os2, ok := ttf.Tables["OS/2"]
+	if !ok {
+		t.Skip("test font has no OS/2 table")
+	}
+
+	for _, tt := range []struct {
+		fsType     uint16
+		embeddable bool
+		name       string
+	}{
+		{0x0000, true, "installable"},
+		{0x0002, false, "restricted"},
+		{0x0004, true, "preview & print"},
+		{0x0006, true, "preview & print"}, // the least restrictive usage bit applies
+		{0x0108, true, "editable, no subsetting"},
+		{0x0204, false, "preview & print, bitmap only"},
+	} {
+		patched := append([]byte(nil), data...)
+		binary.BigEndian.PutUint16(patched[os2.Offset+8:], tt.fsType)
+		f, err := NewFont("Test", patched)
+		if err != nil {
+			t.Fatalf("NewFont failed: %v", err)
+		}
+		embedding, err := f.Embedding()
+		if err != nil {
+			t.Fatalf("Embedding failed: %v", err)
+		}
+		if embedding.Embeddable() != tt.embeddable || embedding.String() != tt.name {
+			t.Errorf("fsType %#04x: Embeddable() = %v, String() = %q; want %v, %q",
+				tt.fsType, embedding.Embeddable(), embedding, tt.embeddable, tt.name)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package font
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// TextMetrics is the extent of a string set in a font, in points
+type TextMetrics struct {
+	Width      float64 // Advance width
+	Ascent     float64 // Height above the baseline
+	Descent    float64 // Depth below the baseline (negative)
+	LineHeight float64 // Baseline-to-baseline distance: ascent, descent and line gap
+}
+
+// fontMetrics holds the tables MeasureString needs, parsed once per font
+type fontMetrics struct {
+	unitsPerEm float64
+	advances   []int
+	ascent     int
+	descent    int
+	lineGap    int
+}
+
+// MeasureString returns the advance width and vertical extent of text in points at
+// the given font size. Characters the font has no glyph for use the .notdef width.
+func (f *Font) MeasureString(text string, size float64) (TextMetrics, error) {
+	m, err := f.loadMetrics()
+	if err != nil {
+		return TextMetrics{}, err
+	}
+
+	units := 0
+	for _, r := range text {
+		gid, _ := f.GlyphIndex(r)
+		if int(gid) < len(m.advances) {
+			units += m.advances[gid]
+		} else {
+			units += int(m.unitsPerEm)
+		}
+	}
+
+	scale := size / m.unitsPerEm
+	return TextMetrics{
+		Width:      float64(units) * scale,
+		Ascent:     float64(m.ascent) * scale,
+		Descent:    float64(m.descent) * scale,
+		LineHeight: float64(m.ascent-m.descent+m.lineGap) * scale,
+	}, nil
+}
+
+func (f *Font) loadMetrics() (*fontMetrics, error) {
+	if f.metrics != nil {
+		return f.metrics, nil
+	}
+	ttf, err := ParseTTF(f.Data)
+	if err != nil {
+		return nil, err
+	}
+	if ttf.UnitsPerEm == 0 {
+		return nil, fmt.Errorf("font has no units per em")
+	}
+	advances, _, err := horizontalMetrics(ttf)
+	if err != nil {
+		return nil, err
+	}
+	f.metrics = &fontMetrics{
+		unitsPerEm: float64(ttf.UnitsPerEm),
+		advances:   advances,
+		ascent:     int(ttf.Ascent),
+		descent:    int(ttf.Descent),
+		lineGap:    int(int16(binary.BigEndian.Uint16(ttf.Tables["hhea"].Data[8:10]))),
+	}
+	return f.metrics, nil
+}
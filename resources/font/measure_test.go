@@ -0,0 +1,40 @@
+package font
+
+import "testing"
+
+func TestMeasureString(t *testing.T) {
+	f := rebuildTestFont(t, nil)
+	ttf, err := ParseTTF(f.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	advances, _, err := horizontalMetrics(ttf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := f.GlyphIndex(0x10330)
+	b, _ := f.GlyphIndex(0x10331)
+
+	m, err := f.MeasureString("\U00010330\U00010331", 20)
+	if err != nil {
+		t.Fatalf("MeasureString failed: %v", err)
+	}
+	scale := 20 / float64(ttf.UnitsPerEm)
+	if want := float64(advances[a]+advances[b]) * scale; m.Width != want {
+		t.Errorf("Width = %v, want %v", m.Width, want)
+	}
+	if m.Ascent != float64(ttf.Ascent)*scale || m.Descent != float64(ttf.Descent)*scale {
+		t.Errorf("Unexpected vertical metrics %+v", m)
+	}
+	if m.LineHeight < m.Ascent-m.Descent {
+		t.Errorf("LineHeight %v is less than ascent minus descent", m.LineHeight)
+	}
+
+	if width, _ := f.TextWidth("\U00010330\U00010331", 20); width != m.Width {
+		t.Errorf("TextWidth = %v, want %v", width, m.Width)
+	}
+	empty, _ := f.MeasureString("", 20)
+	if empty.Width != 0 || empty.LineHeight != m.LineHeight {
+		t.Errorf("Unexpected metrics for empty string %+v", empty)
+	}
+}
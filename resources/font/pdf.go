@@ -22,6 +22,10 @@ type FontObjects struct {
 // ToPDFObjects creates PDF objects for an embedded font
 // Returns the font dictionary, font descriptor, font file stream, and ToUnicode CMap
 func (f *Font) ToPDFObjects(writer PDFWriter) (*FontObjects, error) {
+	if f.Subtype == "CIDFontType2" {
+		return f.ToType0PDFObjects(writer)
+	}
+
 	ttf, err := ParseTTF(f.Data)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse font: %w", err)
@@ -93,6 +97,138 @@ func (f *Font) ToPDFObjects(writer PDFWriter) (*FontObjects, error) {
 	}, nil
 }
 
+// ToType0PDFObjects creates PDF objects for the font as a Type0 font with Identity-H
+// encoding, so any character the font has a glyph for can be shown. Text must be
+// written as glyph IDs, see GlyphHex.
+func (f *Font) ToType0PDFObjects(writer PDFWriter) (*FontObjects, error) {
+	ttf, err := ParseTTF(f.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse font: %w", err)
+	}
+	if ttf.UnitsPerEm == 0 {
+		return nil, fmt.Errorf("font has no units per em")
+	}
+
+	if f.FontID == "" {
+		hash := md5.Sum([]byte(ttf.FontName))
+		f.FontID = hex.EncodeToString(hash[:6])
+	}
+	subsetPrefix := f.FontID + "+"
+	baseFont := "/" + escapeName(subsetPrefix+ttf.FontName)
+
+	glyphs, err := f.GetSubsetGlyphs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subset glyphs: %w", err)
+	}
+	widths, err := f.GetWidths()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get widths: %w", err)
+	}
+
+	fontFileData, err := f.CreateSubsetFont()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create subset font: %w", err)
+	}
+	fontFileNum := writer.AddStreamObject(map[string]interface{}{
+		"/Length1": len(fontFileData),
+	}, fontFileData, true)
+	fontDescriptorNum := writer.AddObject(f.createFontDescriptor(ttf, fontFileNum))
+
+	// Widths in glyph space (1/1000 em), one entry per glyph ID
+	var w bytes.Buffer
+	w.WriteString("[")
+	for i, gid := range glyphs {
+		if i > 0 {
+			w.WriteString(" ")
+		}
+		w.WriteString(fmt.Sprintf("%d [%d]", gid, widths[i]*1000/int(ttf.UnitsPerEm)))
+	}
+	w.WriteString("]")
+
+	cidFont := fmt.Sprintf("<<\n/Type /Font\n/Subtype /CIDFontType2\n/BaseFont %s\n"+
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >>\n"+
+		"/FontDescriptor %d 0 R\n/DW 1000\n/W %s\n/CIDToGIDMap /Identity\n>>",
+		baseFont, fontDescriptorNum, w.String())
+	cidFontNum := writer.AddObject([]byte(cidFont))
+
+	toUnicodeNum := writer.AddStreamObject(map[string]interface{}{}, f.createIdentityToUnicodeCMap(glyphs), false)
+
+	fontDict := fmt.Sprintf("<<\n/Type /Font\n/Subtype /Type0\n/BaseFont %s\n/Encoding /Identity-H\n"+
+		"/DescendantFonts [%d 0 R]\n/ToUnicode %d 0 R\n>>", baseFont, cidFontNum, toUnicodeNum)
+	fontDictNum := writer.AddObject([]byte(fontDict))
+
+	return &FontObjects{
+		FontDictNum:       fontDictNum,
+		FontDescriptorNum: fontDescriptorNum,
+		FontFileNum:       fontFileNum,
+		ToUnicodeNum:      toUnicodeNum,
+		ResourceName:      fmt.Sprintf("F%d", writer.NextObjectNumber()),
+		SubsetPrefix:      subsetPrefix,
+	}, nil
+}
+
+// GlyphHex encodes text as the hex string of 2-byte glyph IDs shown by a Type0
+// Identity-H font (for ShowTextHex). Characters without a glyph map to .notdef.
+func (f *Font) GlyphHex(text string) string {
+	var buf bytes.Buffer
+	for _, r := range text {
+		gid, _ := f.GlyphIndex(r)
+		buf.WriteString(fmt.Sprintf("%04X", gid))
+	}
+	return buf.String()
+}
+
+// createIdentityToUnicodeCMap maps 2-byte glyph IDs back to Unicode for text extraction
+func (f *Font) createIdentityToUnicodeCMap(glyphs []uint16) []byte {
+	glyphToUnicode := make(map[uint16]rune)
+	for _, r := range f.Subset {
+		if gid, ok := f.GlyphIndex(r); ok {
+			if _, seen := glyphToUnicode[gid]; !seen {
+				glyphToUnicode[gid] = r
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("/CIDInit /ProcSet findresource begin\n")
+	buf.WriteString("12 dict begin\n")
+	buf.WriteString("begincmap\n")
+	buf.WriteString("/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	buf.WriteString("/CMapName /Adobe-Identity-UCS def\n")
+	buf.WriteString("/CMapType 2 def\n")
+	buf.WriteString("1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+
+	var entries []string
+	for _, gid := range glyphs {
+		if r, ok := glyphToUnicode[gid]; ok {
+			var utf16 bytes.Buffer
+			for _, unit := range encodeUTF16(string(r)) {
+				utf16.WriteString(fmt.Sprintf("%02X", unit))
+			}
+			entries = append(entries, fmt.Sprintf("<%04X> <%s>\n", gid, utf16.String()))
+		}
+	}
+	// bfchar blocks hold at most 100 entries
+	for len(entries) > 0 {
+		n := len(entries)
+		if n > 100 {
+			n = 100
+		}
+		buf.WriteString(fmt.Sprintf("%d beginbfchar\n", n))
+		for _, entry := range entries[:n] {
+			buf.WriteString(entry)
+		}
+		buf.WriteString("endbfchar\n")
+		entries = entries[n:]
+	}
+
+	buf.WriteString("endcmap\n")
+	buf.WriteString("CMapName currentdict /CMap defineresource pop\n")
+	buf.WriteString("end\n")
+	buf.WriteString("end\n")
+	return buf.Bytes()
+}
+
 // PDFWriter interface for creating PDF objects
 type PDFWriter interface {
 	AddObject(content []byte) int
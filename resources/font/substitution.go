@@ -0,0 +1,190 @@
+// Package font provides font substitution for fonts a document references but does not embed
+package font
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ErrNoSubstitute is returned when no replacement is registered for a font
+var ErrNoSubstitute = errors.New("no substitute font registered")
+
+// Substitution records a replacement font used in place of a font that was not embedded
+type Substitution struct {
+	Requested   string `json:"requested"`   // Font name referenced by the document
+	Replacement string `json:"replacement"` // Name of the font used instead
+	Path        string `json:"path"`        // Font file of the replacement, or "memory:<name>" for fonts registered with RegisterData
+	Context     string `json:"context"`     // Where the substitution was made, e.g. "render" or "appearance"
+}
+
+// SubstitutionRegistry maps font names and families to replacement font files.
+// Names are matched ignoring case, spaces, hyphens and subset prefixes, so a
+// registration for "Arial" covers "ABCDEF+Arial-BoldMT" unless "Arial Bold" is
+// registered separately. It is safe for concurrent use.
+type SubstitutionRegistry struct {
+	mu       sync.Mutex
+	fonts    map[string]string // normalized name -> font file path
+	fallback string
+	data     map[string][]byte // font file path -> contents
+	used     []Substitution
+	seen     map[[2]string]bool // (requested, context) pairs already reported
+}
+
+// NewSubstitutionRegistry creates an empty registry
+func NewSubstitutionRegistry() *SubstitutionRegistry {
+	return &SubstitutionRegistry{
+		fonts: make(map[string]string),
+		data:  make(map[string][]byte),
+		seen:  make(map[[2]string]bool),
+	}
+}
+
+// Register maps a font name (e.g. "Arial-BoldMT") or family (e.g. "Arial") to a TTF/OTF file
+func (r *SubstitutionRegistry) Register(name, path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fonts[normalizeFontName(name)] = path
+}
+
+// RegisterData maps a font name or family to TTF/OTF data held in memory, for
+// environments without a file system such as js/wasm
+func (r *SubstitutionRegistry) RegisterData(name string, data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	path := memoryFontPath(name)
+	r.fonts[normalizeFontName(name)] = path
+	r.data[path] = data
+}
+
+// SetFallbackData sets TTF/OTF data held in memory as the fallback font
+func (r *SubstitutionRegistry) SetFallbackData(name string, data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fallback = memoryFontPath(name)
+	r.data[r.fallback] = data
+}
+
+// memoryFontPath is the path under which fonts registered from memory are kept
+func memoryFontPath(name string) string {
+	return "memory:" + name
+}
+
+// SetFallback sets the font file used for fonts with no registered replacement
+func (r *SubstitutionRegistry) SetFallback(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fallback = path
+}
+
+// Lookup returns the replacement font file for a font name: an exact name match,
+// then the name without a PostScript "MT"/"PS" suffix, then its family, then the fallback
+func (r *SubstitutionRegistry) Lookup(fontName string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lookup(fontName)
+}
+
+func (r *SubstitutionRegistry) lookup(fontName string) (string, bool) {
+	name := normalizeFontName(fontName)
+	candidates := []string{name}
+	for _, suffix := range []string{"psmt", "mt", "ps"} {
+		if trimmed := strings.TrimSuffix(name, suffix); trimmed != name && trimmed != "" {
+			candidates = append(candidates, trimmed)
+			break
+		}
+	}
+	candidates = append(candidates, fontFamily(fontName))
+
+	for _, candidate := range candidates {
+		if path, ok := r.fonts[candidate]; ok {
+			return path, true
+		}
+	}
+	if r.fallback != "" {
+		return r.fallback, true
+	}
+	return "", false
+}
+
+// Substitute loads the replacement for fontName and records the substitution under
+// context for Report. Each call returns a new Font so subsets stay independent.
+func (r *SubstitutionRegistry) Substitute(fontName, context string) (*Font, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	path, ok := r.lookup(fontName)
+	if !ok {
+		return nil, ErrNoSubstitute
+	}
+
+	data, ok := r.data[path]
+	if !ok {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+		r.data[path] = data
+	}
+
+	replacement := strings.TrimSuffix(filepath.Base(strings.TrimPrefix(path, "memory:")), filepath.Ext(path))
+	if ttf, err := ParseTTF(data); err == nil && ttf.PostScriptName != "" {
+		replacement = ttf.PostScriptName
+	}
+	f, err := NewFont(replacement, data)
+	if err != nil {
+		return nil, err
+	}
+
+	requested := baseFontName(fontName)
+	key := [2]string{requested, context}
+	if !r.seen[key] {
+		r.seen[key] = true
+		r.used = append(r.used, Substitution{
+			Requested:   requested,
+			Replacement: replacement,
+			Path:        path,
+			Context:     context,
+		})
+	}
+	return f, nil
+}
+
+// Report returns the substitutions made so far, once per font and context, in the order made
+func (r *SubstitutionRegistry) Report() []Substitution {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Substitution(nil), r.used...)
+}
+
+// baseFontName strips the leading slash and a subset prefix ("ABCDEF+") from a font name
+func baseFontName(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if i := strings.IndexByte(name, '+'); i == 6 && strings.ToUpper(name[:6]) == name[:6] {
+		name = name[7:]
+	}
+	return name
+}
+
+// fontFamily returns the normalized family of a font name: the part before a
+// style separator, as in "Arial-BoldMT" or "Arial,Bold"
+func fontFamily(name string) string {
+	name = baseFontName(name)
+	if i := strings.IndexAny(name, "-,"); i > 0 {
+		name = name[:i]
+	}
+	return normalizeFontName(name)
+}
+
+// normalizeFontName lowercases a font name and drops its subset prefix and separators
+func normalizeFontName(name string) string {
+	name = strings.ToLower(baseFontName(name))
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' || r == '_' || r == ',' {
+			return -1
+		}
+		return r
+	}, name)
+}
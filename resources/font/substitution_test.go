@@ -0,0 +1,132 @@
+package font
+
+import (
+	"os"
+	"testing"
+)
+
+func substitutionTestFont(t *testing.T) string {
+	t.Helper()
+	for _, path := range []string{
+		"tests/resources/test_font.ttf",
+		"../tests/resources/test_font.ttf",
+		"../../tests/resources/test_font.ttf",
+	} {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	t.Skip("test font not found")
+	return ""
+}
+
+func TestSubstitutionRegistry_Lookup(t *testing.T) {
+	r := NewSubstitutionRegistry()
+	r.Register("Arial", "/fonts/arial.ttf")
+	r.Register("Arial Bold", "/fonts/arialbd.ttf")
+	r.Register("Times-Roman", "/fonts/times.ttf")
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Arial", "/fonts/arial.ttf"},
+		{"/ABCDEF+ArialMT", "/fonts/arial.ttf"},
+		{"Arial-BoldMT", "/fonts/arialbd.ttf"},
+		{"Arial,Bold", "/fonts/arialbd.ttf"},
+		{"Arial-Italic", "/fonts/arial.ttf"},
+		{"times roman", "/fonts/times.ttf"},
+	}
+	for _, tt := range tests {
+		if got, ok := r.Lookup(tt.name); !ok || got != tt.want {
+			t.Errorf("Lookup(%q) = %q, %v; want %q", tt.name, got, ok, tt.want)
+		}
+	}
+
+	if _, ok := r.Lookup("Courier"); ok {
+		t.Error("Lookup(Courier) matched without a fallback")
+	}
+	r.SetFallback("/fonts/default.ttf")
+	if got, ok := r.Lookup("Courier"); !ok || got != "/fonts/default.ttf" {
+		t.Errorf("Lookup(Courier) = %q, %v; want fallback", got, ok)
+	}
+}
+
+func TestSubstitutionRegistry_Substitute(t *testing.T) {
+	path := substitutionTestFont(t)
+
+	r := NewSubstitutionRegistry()
+	if _, err := r.Substitute("Helvetica", "render"); err != ErrNoSubstitute {
+		t.Fatalf("Expected ErrNoSubstitute, got %v", err)
+	}
+
+	r.Register("Helvetica", path)
+	for _, context := range []string{"render", "render", "appearance"} {
+		f, err := r.Substitute("/Helvetica-Bold", context)
+		if err != nil {
+			t.Fatalf("Substitute failed: %v", err)
+		}
+		if f.Name == "" || len(f.Data) == 0 {
+			t.Fatalf("Substitute returned an empty font: %+v", f)
+		}
+	}
+
+	report := r.Report()
+	if len(report) != 2 {
+		t.Fatalf("Expected one report entry per context, got %+v", report)
+	}
+	if report[0].Requested != "Helvetica-Bold" || report[0].Path != path || report[0].Context != "render" {
+		t.Errorf("Unexpected substitution %+v", report[0])
+	}
+	if report[1].Context != "appearance" {
+		t.Errorf("Expected appearance substitution, got %+v", report[1])
+	}
+}
+
+func TestSubstitutionRegistry_RegisterData(t *testing.T) {
+	data, err := os.ReadFile(substitutionTestFont(t))
+	if err != nil {
+		t.Fatalf("Failed to read test font: %v", err)
+	}
+
+	r := NewSubstitutionRegistry()
+	r.RegisterData("Helvetica", data)
+	f, err := r.Substitute("Helvetica-Bold", "render")
+	if err != nil {
+		t.Fatalf("Substitute failed: %v", err)
+	}
+	if len(f.Data) == 0 {
+		t.Fatal("Substitute returned an empty font")
+	}
+	if got := r.Report()[0].Path; got != "memory:Helvetica" {
+		t.Errorf("Path = %q, want memory:Helvetica", got)
+	}
+
+	r.SetFallbackData("Default", data)
+	if _, err := r.Substitute("Courier", "render"); err != nil {
+		t.Errorf("Fallback substitution failed: %v", err)
+	}
+}
+
+func TestFontTextWidth(t *testing.T) {
+	data, err := os.ReadFile(substitutionTestFont(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFont("Test", data)
+	if err != nil {
+		t.Fatalf("NewFont failed: %v", err)
+	}
+
+	one, err := f.TextWidth("M", 10)
+	if err != nil {
+		t.Fatalf("TextWidth failed: %v", err)
+	}
+	if one <= 0 {
+		t.Fatalf("Expected positive width, got %v", one)
+	}
+	three, _ := f.TextWidth("MMM", 20)
+	if diff := three - one*6; diff > 0.001 || diff < -0.001 {
+		t.Errorf("Width does not scale with length and size: %v vs %v", three, one*6)
+	}
+}
@@ -0,0 +1,1438 @@
+package font
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// VariationAxis describes one design axis of a variable font (from the fvar table)
+type VariationAxis struct {
+	Tag     string  `json:"tag"`  // e.g. "wght", "wdth", "slnt"
+	Name    string  `json:"name"` // Display name from the name table
+	Min     float64 `json:"min"`
+	Default float64 `json:"default"`
+	Max     float64 `json:"max"`
+	Hidden  bool    `json:"hidden,omitempty"`
+}
+
+// NamedInstance is a predefined set of axis values, such as "Bold" or "Condensed Light"
+type NamedInstance struct {
+	Name           string             `json:"name"`
+	PostScriptName string             `json:"postscript_name,omitempty"`
+	Coordinates    map[string]float64 `json:"coordinates"`
+}
+
+// Tables that describe variations or are invalidated by instancing; they are
+// dropped from static instances
+var variationTables = map[string]bool{
+	"fvar": true, "gvar": true, "avar": true, "cvar": true, "STAT": true,
+	"HVAR": true, "VVAR": true, "MVAR": true,
+	"DSIG": true, "hdmx": true, "LTSH": true, "VDMX": true,
+}
+
+// IsVariable reports whether the font has variation axes
+func (f *Font) IsVariable() bool {
+	ttf, err := ParseTTF(f.Data)
+	if err != nil {
+		return false
+	}
+	_, ok := ttf.Tables["fvar"]
+	return ok
+}
+
+// VariationAxes returns the axes of a variable font, or nil for a static font
+func (f *Font) VariationAxes() ([]VariationAxis, error) {
+	ttf, err := ParseTTF(f.Data)
+	if err != nil {
+		return nil, err
+	}
+	axes, _, err := parseFvar(ttf)
+	return axes, err
+}
+
+// NamedInstances returns the named instances of a variable font
+func (f *Font) NamedInstances() ([]NamedInstance, error) {
+	ttf, err := ParseTTF(f.Data)
+	if err != nil {
+		return nil, err
+	}
+	_, instances, err := parseFvar(ttf)
+	return instances, err
+}
+
+// NamedInstance returns a static font for the named instance with the given
+// subfamily name (e.g. "Bold") or PostScript name, compared ignoring case
+func (f *Font) NamedInstance(name string) (*Font, error) {
+	instances, err := f.NamedInstances()
+	if err != nil {
+		return nil, err
+	}
+	for _, instance := range instances {
+		if strings.EqualFold(instance.Name, name) || (instance.PostScriptName != "" && strings.EqualFold(instance.PostScriptName, name)) {
+			return f.Instance(instance.Coordinates)
+		}
+	}
+	return nil, fmt.Errorf("named instance %q not found", name)
+}
+
+// Instance returns a static TrueType font for the given axis values, keyed by axis
+// tag. Axes left out use their default; values are clamped to the axis range.
+// Glyph outlines and advance widths are interpolated from the gvar table and the
+// instance gets its own names, so it can be embedded and subset like any static
+// font. Fonts with CFF2 outlines are not supported.
+func (f *Font) Instance(coords map[string]float64) (*Font, error) {
+	ttf, err := ParseTTF(f.Data)
+	if err != nil {
+		return nil, err
+	}
+	axes, instances, err := parseFvar(ttf)
+	if err != nil {
+		return nil, err
+	}
+	if axes == nil {
+		return nil, fmt.Errorf("font is not a variable font")
+	}
+	if _, ok := ttf.Tables["glyf"]; !ok {
+		return nil, fmt.Errorf("only TrueType-outline variable fonts are supported")
+	}
+	for tag := range coords {
+		if axisIndex(axes, tag) == -1 {
+			return nil, fmt.Errorf("font has no %q axis", tag)
+		}
+	}
+
+	values := make([]float64, len(axes))
+	for i, axis := range axes {
+		v, ok := coords[axis.Tag]
+		if !ok {
+			v = axis.Default
+		}
+		values[i] = math.Max(axis.Min, math.Min(axis.Max, v))
+	}
+	normalized := normalizeCoords(axes, values)
+	if avar, ok := ttf.Tables["avar"]; ok {
+		normalized = applyAvar(avar.Data, normalized)
+	}
+
+	inst := &instancer{ttf: ttf, coords: normalized}
+	if err := inst.run(); err != nil {
+		return nil, err
+	}
+
+	subfamily, psName := instanceNames(ttf, axes, instances, values)
+	tables := make(map[string][]byte)
+	for tag, table := range ttf.Tables {
+		if !variationTables[tag] {
+			tables[tag] = append([]byte(nil), table.Data...)
+		}
+	}
+	tables["glyf"] = inst.glyf
+	tables["loca"] = inst.loca
+	tables["hmtx"] = inst.hmtx
+	inst.updateHead(tables["head"])
+	inst.updateHhea(tables["hhea"])
+	if os2, ok := tables["OS/2"]; ok {
+		updateOS2(os2, axes, values)
+	}
+	if name, ok := tables["name"]; ok {
+		family := familyName(ttf)
+		tables["name"] = renameInstance(name, family, subfamily, psName)
+	}
+
+	instance, err := NewFont(f.Name, buildSFNT(tables))
+	if err != nil {
+		return nil, err
+	}
+	instance.Subset = append(instance.Subset, f.Subset...)
+	return instance, nil
+}
+
+// parseFvar reads the axes and named instances; both are nil for a static font
+func parseFvar(ttf *TTF) ([]VariationAxis, []NamedInstance, error) {
+	fvar, ok := ttf.Tables["fvar"]
+	if !ok {
+		return nil, nil, nil
+	}
+	data := fvar.Data
+	if len(data) < 16 {
+		return nil, nil, fmt.Errorf("fvar table too short")
+	}
+	axesOffset := int(binary.BigEndian.Uint16(data[4:6]))
+	axisCount := int(binary.BigEndian.Uint16(data[8:10]))
+	axisSize := int(binary.BigEndian.Uint16(data[10:12]))
+	instanceCount := int(binary.BigEndian.Uint16(data[12:14]))
+	instanceSize := int(binary.BigEndian.Uint16(data[14:16]))
+	if axisSize < 20 || axesOffset+axisCount*axisSize+instanceCount*instanceSize > len(data) {
+		return nil, nil, fmt.Errorf("invalid fvar table")
+	}
+
+	axes := make([]VariationAxis, axisCount)
+	for i := range axes {
+		rec := data[axesOffset+i*axisSize:]
+		axes[i] = VariationAxis{
+			Tag:     string(rec[0:4]),
+			Min:     fixedToFloat(rec[4:8]),
+			Default: fixedToFloat(rec[8:12]),
+			Max:     fixedToFloat(rec[12:16]),
+			Hidden:  binary.BigEndian.Uint16(rec[16:18])&0x0001 != 0,
+			Name:    nameString(ttf, binary.BigEndian.Uint16(rec[18:20])),
+		}
+	}
+
+	instancesOffset := axesOffset + axisCount*axisSize
+	instances := make([]NamedInstance, 0, instanceCount)
+	for i := 0; i < instanceCount; i++ {
+		rec := data[instancesOffset+i*instanceSize:]
+		instance := NamedInstance{
+			Name:        nameString(ttf, binary.BigEndian.Uint16(rec[0:2])),
+			Coordinates: make(map[string]float64, axisCount),
+		}
+		for a, axis := range axes {
+			instance.Coordinates[axis.Tag] = fixedToFloat(rec[4+a*4 : 8+a*4])
+		}
+		if instanceSize >= 6+axisCount*4 {
+			instance.PostScriptName = nameString(ttf, binary.BigEndian.Uint16(rec[4+axisCount*4:6+axisCount*4]))
+		}
+		instances = append(instances, instance)
+	}
+	return axes, instances, nil
+}
+
+// normalizeCoords maps user axis values to the -1..1 range used by variation data
+func normalizeCoords(axes []VariationAxis, values []float64) []float64 {
+	normalized := make([]float64, len(axes))
+	for i, axis := range axes {
+		v := values[i]
+		switch {
+		case v < axis.Default && axis.Default > axis.Min:
+			normalized[i] = (v - axis.Default) / (axis.Default - axis.Min)
+		case v > axis.Default && axis.Max > axis.Default:
+			normalized[i] = (v - axis.Default) / (axis.Max - axis.Default)
+		}
+	}
+	return normalized
+}
+
+// applyAvar remaps normalized coordinates through the avar segment maps
+func applyAvar(data []byte, coords []float64) []float64 {
+	if len(data) < 8 {
+		return coords
+	}
+	out := append([]float64(nil), coords...)
+	offset := 8
+	for axis := 0; axis < int(binary.BigEndian.Uint16(data[6:8])) && axis < len(coords); axis++ {
+		if offset+2 > len(data) {
+			break
+		}
+		count := int(binary.BigEndian.Uint16(data[offset : offset+2]))
+		offset += 2
+		if offset+count*4 > len(data) {
+			break
+		}
+		from := make([]float64, count)
+		to := make([]float64, count)
+		for i := 0; i < count; i++ {
+			from[i] = f2dot14(data[offset+i*4:])
+			to[i] = f2dot14(data[offset+i*4+2:])
+		}
+		offset += count * 4
+
+		v := coords[axis]
+		for i := 1; i < count; i++ {
+			if v <= from[i] {
+				if from[i] == from[i-1] {
+					out[axis] = to[i]
+				} else {
+					out[axis] = to[i-1] + (v-from[i-1])*(to[i]-to[i-1])/(from[i]-from[i-1])
+				}
+				break
+			}
+		}
+	}
+	return out
+}
+
+// instancer applies glyph variations at one set of normalized coordinates and
+// rebuilds the glyf, loca and hmtx tables
+type instancer struct {
+	ttf    *TTF
+	coords []float64
+
+	outlines []*glyphOutline
+	advances []int
+	bounds   []*glyphBounds
+
+	glyf, loca, hmtx []byte
+}
+
+// glyphOutline is a decoded glyf entry. Simple glyphs have points; composite
+// glyphs have components, each carrying an offset that variations can move.
+type glyphOutline struct {
+	contours     int16
+	endPts       []int
+	instructions []byte
+	onCurve      []byte // flag bits kept per point: on-curve and overlap
+	xs, ys       []float64
+
+	components []glyphComponent
+	tail       []byte // composite instructions (numInstr + bytes)
+}
+
+type glyphComponent struct {
+	flags     uint16
+	glyph     uint16
+	dx, dy    float64 // offset, or point numbers when args are not XY values
+	transform []byte  // raw scale / 2x2 data
+}
+
+type glyphBounds struct {
+	xMin, yMin, xMax, yMax float64
+}
+
+const (
+	compArgsAreWords  = 0x0001
+	compArgsAreXY     = 0x0002
+	compHaveScale     = 0x0008
+	compMoreComponent = 0x0020
+	compHaveXYScale   = 0x0040
+	compHaveTwoByTwo  = 0x0080
+	compHaveInstr     = 0x0100
+)
+
+func (inst *instancer) run() error {
+	ttf := inst.ttf
+	numGlyphs := int(ttf.NumGlyphs)
+
+	offsets, err := locaOffsets(ttf)
+	if err != nil {
+		return err
+	}
+	advances, lsbs, err := horizontalMetrics(ttf)
+	if err != nil {
+		return err
+	}
+
+	glyf := ttf.Tables["glyf"].Data
+	inst.outlines = make([]*glyphOutline, numGlyphs)
+	inst.advances = make([]int, numGlyphs)
+	inst.bounds = make([]*glyphBounds, numGlyphs)
+
+	var gvar *gvarTable
+	if table, ok := ttf.Tables["gvar"]; ok {
+		if gvar, err = parseGvar(table.Data, len(inst.coords)); err != nil {
+			return err
+		}
+	}
+
+	for gid := 0; gid < numGlyphs; gid++ {
+		start, end := offsets[gid], offsets[gid+1]
+		if start > end || end > len(glyf) {
+			return fmt.Errorf("invalid loca entry for glyph %d", gid)
+		}
+		outline, err := decodeGlyph(glyf[start:end])
+		if err != nil {
+			return fmt.Errorf("glyph %d: %w", gid, err)
+		}
+
+		// Phantom points carry the horizontal origin and advance through the variation
+		xMin := 0.0
+		if b := outline.pointBounds(); b != nil {
+			xMin = b.xMin
+		} else if len(glyf[start:end]) >= 10 {
+			xMin = float64(int16(binary.BigEndian.Uint16(glyf[start+2 : start+4])))
+		}
+		left := xMin - float64(lsbs[gid])
+		phantomX := []float64{left, left + float64(advances[gid]), 0, 0}
+		phantomY := []float64{0, 0, 0, 0}
+
+		if gvar != nil {
+			if err := gvar.apply(gid, inst.coords, outline, phantomX, phantomY); err != nil {
+				return fmt.Errorf("glyph %d variations: %w", gid, err)
+			}
+		}
+
+		// Move the origin back to zero so hmtx and the outline agree
+		shift := math.Round(phantomX[0])
+		outline.translate(-shift)
+		inst.advances[gid] = max(0, int(math.Round(phantomX[1])-shift))
+		inst.outlines[gid] = outline
+	}
+
+	return inst.encode()
+}
+
+// encode writes the varied outlines as glyf (long loca) and hmtx data
+func (inst *instancer) encode() error {
+	numGlyphs := len(inst.outlines)
+	glyf := make([]byte, 0, len(inst.ttf.Tables["glyf"].Data))
+	loca := make([]byte, 4*(numGlyphs+1))
+	hmtx := make([]byte, 4*numGlyphs)
+
+	for gid, outline := range inst.outlines {
+		binary.BigEndian.PutUint32(loca[gid*4:], uint32(len(glyf)))
+		b := inst.glyphBounds(gid, 0)
+		glyf = append(glyf, outline.encode(b)...)
+		for len(glyf)%4 != 0 {
+			glyf = append(glyf, 0)
+		}
+
+		lsb := 0
+		if b != nil {
+			lsb = int(math.Round(b.xMin))
+		}
+		binary.BigEndian.PutUint16(hmtx[gid*4:], uint16(inst.advances[gid]))
+		binary.BigEndian.PutUint16(hmtx[gid*4+2:], uint16(int16(lsb)))
+	}
+	binary.BigEndian.PutUint32(loca[numGlyphs*4:], uint32(len(glyf)))
+
+	inst.glyf, inst.loca, inst.hmtx = glyf, loca, hmtx
+	return nil
+}
+
+// glyphBounds returns the rounded bounding box of a glyph, resolving components; nil if empty
+func (inst *instancer) glyphBounds(gid, depth int) *glyphBounds {
+	if gid >= len(inst.outlines) || depth > 16 {
+		return nil
+	}
+	if inst.bounds[gid] != nil {
+		return inst.bounds[gid]
+	}
+	outline := inst.outlines[gid]
+	b := outline.pointBounds()
+	for _, c := range outline.components {
+		cb := inst.glyphBounds(int(c.glyph), depth+1)
+		if cb == nil {
+			continue
+		}
+		dx, dy := 0.0, 0.0
+		if c.flags&compArgsAreXY != 0 {
+			dx, dy = math.Round(c.dx), math.Round(c.dy)
+		}
+		a, bb, cc, d := c.matrix()
+		for _, corner := range [][2]float64{{cb.xMin, cb.yMin}, {cb.xMin, cb.yMax}, {cb.xMax, cb.yMin}, {cb.xMax, cb.yMax}} {
+			x := a*corner[0] + cc*corner[1] + dx
+			y := bb*corner[0] + d*corner[1] + dy
+			b = b.extend(x, y)
+		}
+	}
+	if b != nil {
+		b = &glyphBounds{math.Floor(b.xMin), math.Floor(b.yMin), math.Ceil(b.xMax), math.Ceil(b.yMax)}
+	}
+	inst.bounds[gid] = b
+	return b
+}
+
+func (b *glyphBounds) extend(x, y float64) *glyphBounds {
+	if b == nil {
+		return &glyphBounds{x, y, x, y}
+	}
+	return &glyphBounds{math.Min(b.xMin, x), math.Min(b.yMin, y), math.Max(b.xMax, x), math.Max(b.yMax, y)}
+}
+
+// updateHead switches to long loca offsets, sets the font bounding box and clears the checksum adjustment
+func (inst *instancer) updateHead(head []byte) {
+	var all *glyphBounds
+	for gid := range inst.outlines {
+		if b := inst.glyphBounds(gid, 0); b != nil {
+			all = all.extend(b.xMin, b.yMin).extend(b.xMax, b.yMax)
+		}
+	}
+	if all != nil {
+		binary.BigEndian.PutUint16(head[36:], uint16(int16(all.xMin)))
+		binary.BigEndian.PutUint16(head[38:], uint16(int16(all.yMin)))
+		binary.BigEndian.PutUint16(head[40:], uint16(int16(all.xMax)))
+		binary.BigEndian.PutUint16(head[42:], uint16(int16(all.yMax)))
+	}
+	binary.BigEndian.PutUint32(head[8:], 0)
+	binary.BigEndian.PutUint16(head[50:], 1)
+}
+
+// updateHhea records one metric per glyph and recomputes the extents
+func (inst *instancer) updateHhea(hhea []byte) {
+	maxAdvance, minLSB, minRSB, maxExtent := 0, math.MaxInt16, math.MaxInt16, math.MinInt16
+	for gid, advance := range inst.advances {
+		maxAdvance = max(maxAdvance, advance)
+		if b := inst.glyphBounds(gid, 0); b != nil {
+			minLSB = min(minLSB, int(b.xMin))
+			minRSB = min(minRSB, advance-int(b.xMax))
+			maxExtent = max(maxExtent, int(b.xMax))
+		}
+	}
+	binary.BigEndian.PutUint16(hhea[10:], uint16(maxAdvance))
+	if maxExtent != math.MinInt16 {
+		binary.BigEndian.PutUint16(hhea[12:], uint16(int16(minLSB)))
+		binary.BigEndian.PutUint16(hhea[14:], uint16(int16(minRSB)))
+		binary.BigEndian.PutUint16(hhea[16:], uint16(int16(maxExtent)))
+	}
+	binary.BigEndian.PutUint16(hhea[34:], uint16(len(inst.advances)))
+}
+
+// locaOffsets returns the numGlyphs+1 glyph offsets into glyf
+func locaOffsets(ttf *TTF) ([]int, error) {
+	loca, ok := ttf.Tables["loca"]
+	if !ok {
+		return nil, fmt.Errorf("missing loca table")
+	}
+	numGlyphs := int(ttf.NumGlyphs)
+	long := binary.BigEndian.Uint16(ttf.Tables["head"].Data[50:52]) == 1
+	offsets := make([]int, numGlyphs+1)
+	for i := range offsets {
+		if long {
+			if i*4+4 > len(loca.Data) {
+				return nil, fmt.Errorf("loca table too short")
+			}
+			offsets[i] = int(binary.BigEndian.Uint32(loca.Data[i*4:]))
+		} else {
+			if i*2+2 > len(loca.Data) {
+				return nil, fmt.Errorf("loca table too short")
+			}
+			offsets[i] = int(binary.BigEndian.Uint16(loca.Data[i*2:])) * 2
+		}
+	}
+	return offsets, nil
+}
+
+// horizontalMetrics expands hmtx into one advance and left side bearing per glyph
+func horizontalMetrics(ttf *TTF) ([]int, []int, error) {
+	hmtx, ok := ttf.Tables["hmtx"]
+	if !ok {
+		return nil, nil, fmt.Errorf("missing hmtx table")
+	}
+	numGlyphs := int(ttf.NumGlyphs)
+	numMetrics := int(binary.BigEndian.Uint16(ttf.Tables["hhea"].Data[34:36]))
+	if numMetrics == 0 || numMetrics > numGlyphs || numMetrics*4+(numGlyphs-numMetrics)*2 > len(hmtx.Data) {
+		return nil, nil, fmt.Errorf("invalid hmtx table")
+	}
+
+	advances := make([]int, numGlyphs)
+	lsbs := make([]int, numGlyphs)
+	for gid := 0; gid < numGlyphs; gid++ {
+		if gid < numMetrics {
+			advances[gid] = int(binary.BigEndian.Uint16(hmtx.Data[gid*4:]))
+			lsbs[gid] = int(int16(binary.BigEndian.Uint16(hmtx.Data[gid*4+2:])))
+		} else {
+			advances[gid] = advances[numMetrics-1]
+			lsbs[gid] = int(int16(binary.BigEndian.Uint16(hmtx.Data[numMetrics*4+(gid-numMetrics)*2:])))
+		}
+	}
+	return advances, lsbs, nil
+}
+
+// decodeGlyph parses a glyf entry; empty data is a glyph without outline
+func decodeGlyph(data []byte) (*glyphOutline, error) {
+	outline := &glyphOutline{}
+	if len(data) == 0 {
+		return outline, nil
+	}
+	if len(data) < 10 {
+		return nil, fmt.Errorf("glyph header too short")
+	}
+	outline.contours = int16(binary.BigEndian.Uint16(data[0:2]))
+	if outline.contours < 0 {
+		return outline, outline.decodeComposite(data[10:])
+	}
+	return outline, outline.decodeSimple(data[10:])
+}
+
+func (g *glyphOutline) decodeSimple(data []byte) error {
+	n := int(g.contours)
+	if len(data) < n*2+2 {
+		return fmt.Errorf("simple glyph too short")
+	}
+	g.endPts = make([]int, n)
+	for i := range g.endPts {
+		g.endPts[i] = int(binary.BigEndian.Uint16(data[i*2:]))
+	}
+	pos := n * 2
+	instrLen := int(binary.BigEndian.Uint16(data[pos:]))
+	pos += 2
+	if pos+instrLen > len(data) {
+		return fmt.Errorf("glyph instructions truncated")
+	}
+	g.instructions = data[pos : pos+instrLen]
+	pos += instrLen
+
+	numPoints := 0
+	if n > 0 {
+		numPoints = g.endPts[n-1] + 1
+	}
+	flags := make([]byte, 0, numPoints)
+	for len(flags) < numPoints {
+		if pos >= len(data) {
+			return fmt.Errorf("glyph flags truncated")
+		}
+		flag := data[pos]
+		pos++
+		flags = append(flags, flag)
+		if flag&0x08 != 0 {
+			if pos >= len(data) {
+				return fmt.Errorf("glyph flags truncated")
+			}
+			for r := int(data[pos]); r > 0 && len(flags) < numPoints; r-- {
+				flags = append(flags, flag)
+			}
+			pos++
+		}
+	}
+
+	readCoords := func(short, same byte) ([]float64, error) {
+		coords := make([]float64, numPoints)
+		v := 0
+		for i, flag := range flags {
+			switch {
+			case flag&short != 0:
+				if pos >= len(data) {
+					return nil, fmt.Errorf("glyph coordinates truncated")
+				}
+				if flag&same != 0 {
+					v += int(data[pos])
+				} else {
+					v -= int(data[pos])
+				}
+				pos++
+			case flag&same == 0:
+				if pos+2 > len(data) {
+					return nil, fmt.Errorf("glyph coordinates truncated")
+				}
+				v += int(int16(binary.BigEndian.Uint16(data[pos:])))
+				pos += 2
+			}
+			coords[i] = float64(v)
+		}
+		return coords, nil
+	}
+	var err error
+	if g.xs, err = readCoords(0x02, 0x10); err != nil {
+		return err
+	}
+	if g.ys, err = readCoords(0x04, 0x20); err != nil {
+		return err
+	}
+	g.onCurve = make([]byte, numPoints)
+	for i, flag := range flags {
+		g.onCurve[i] = flag & 0x41
+	}
+	return nil
+}
+
+func (g *glyphOutline) decodeComposite(data []byte) error {
+	pos := 0
+	haveInstructions := false
+	for {
+		if pos+4 > len(data) {
+			return fmt.Errorf("composite glyph truncated")
+		}
+		c := glyphComponent{
+			flags: binary.BigEndian.Uint16(data[pos:]),
+			glyph: binary.BigEndian.Uint16(data[pos+2:]),
+		}
+		pos += 4
+
+		argSize := 2
+		if c.flags&compArgsAreWords != 0 {
+			argSize = 4
+		}
+		if pos+argSize > len(data) {
+			return fmt.Errorf("composite glyph truncated")
+		}
+		switch {
+		case c.flags&compArgsAreWords != 0 && c.flags&compArgsAreXY != 0:
+			c.dx = float64(int16(binary.BigEndian.Uint16(data[pos:])))
+			c.dy = float64(int16(binary.BigEndian.Uint16(data[pos+2:])))
+		case c.flags&compArgsAreWords != 0:
+			c.dx = float64(binary.BigEndian.Uint16(data[pos:]))
+			c.dy = float64(binary.BigEndian.Uint16(data[pos+2:]))
+		case c.flags&compArgsAreXY != 0:
+			c.dx = float64(int8(data[pos]))
+			c.dy = float64(int8(data[pos+1]))
+		default:
+			c.dx = float64(data[pos])
+			c.dy = float64(data[pos+1])
+		}
+		pos += argSize
+
+		transformSize := 0
+		switch {
+		case c.flags&compHaveScale != 0:
+			transformSize = 2
+		case c.flags&compHaveXYScale != 0:
+			transformSize = 4
+		case c.flags&compHaveTwoByTwo != 0:
+			transformSize = 8
+		}
+		if pos+transformSize > len(data) {
+			return fmt.Errorf("composite glyph truncated")
+		}
+		c.transform = data[pos : pos+transformSize]
+		pos += transformSize
+
+		g.components = append(g.components, c)
+		haveInstructions = haveInstructions || c.flags&compHaveInstr != 0
+		if c.flags&compMoreComponent == 0 {
+			break
+		}
+	}
+	if haveInstructions && pos+2 <= len(data) {
+		n := int(binary.BigEndian.Uint16(data[pos:]))
+		if pos+2+n <= len(data) {
+			g.tail = data[pos : pos+2+n]
+		}
+	}
+	return nil
+}
+
+// matrix returns the component's 2x2 transform as xscale, scale01, scale10, yscale
+func (c glyphComponent) matrix() (float64, float64, float64, float64) {
+	t := c.transform
+	switch len(t) {
+	case 2:
+		s := f2dot14(t)
+		return s, 0, 0, s
+	case 4:
+		return f2dot14(t), 0, 0, f2dot14(t[2:])
+	case 8:
+		return f2dot14(t), f2dot14(t[2:]), f2dot14(t[4:]), f2dot14(t[6:])
+	}
+	return 1, 0, 0, 1
+}
+
+// numPoints is the number of points variations address, excluding phantom points
+func (g *glyphOutline) numPoints() int {
+	if g.contours < 0 {
+		return len(g.components)
+	}
+	return len(g.xs)
+}
+
+// addDelta moves point i; for composites the point is the component offset
+func (g *glyphOutline) addDelta(i int, dx, dy float64) {
+	if g.contours < 0 {
+		if g.components[i].flags&compArgsAreXY != 0 {
+			g.components[i].dx += dx
+			g.components[i].dy += dy
+		}
+		return
+	}
+	g.xs[i] += dx
+	g.ys[i] += dy
+}
+
+func (g *glyphOutline) translate(dx float64) {
+	if dx == 0 {
+		return
+	}
+	for i := 0; i < g.numPoints(); i++ {
+		g.addDelta(i, dx, 0)
+	}
+}
+
+// pointBounds returns the bounds of a simple glyph's points, nil for none
+func (g *glyphOutline) pointBounds() *glyphBounds {
+	var b *glyphBounds
+	for i := range g.xs {
+		b = b.extend(math.Round(g.xs[i]), math.Round(g.ys[i]))
+	}
+	return b
+}
+
+// encode serializes the outline with the given bounds; empty glyphs encode to nothing
+func (g *glyphOutline) encode(b *glyphBounds) []byte {
+	if g.contours == 0 && len(g.components) == 0 {
+		return nil
+	}
+	if b == nil {
+		b = &glyphBounds{}
+	}
+	out := make([]byte, 10)
+	binary.BigEndian.PutUint16(out[0:], uint16(g.contours))
+	binary.BigEndian.PutUint16(out[2:], uint16(int16(b.xMin)))
+	binary.BigEndian.PutUint16(out[4:], uint16(int16(b.yMin)))
+	binary.BigEndian.PutUint16(out[6:], uint16(int16(b.xMax)))
+	binary.BigEndian.PutUint16(out[8:], uint16(int16(b.yMax)))
+
+	if g.contours < 0 {
+		for i, c := range g.components {
+			flags := c.flags
+			if i < len(g.components)-1 {
+				flags |= compMoreComponent
+			} else {
+				flags &^= compMoreComponent
+			}
+			if flags&compArgsAreXY != 0 {
+				flags |= compArgsAreWords
+			}
+			out = binary.BigEndian.AppendUint16(out, flags)
+			out = binary.BigEndian.AppendUint16(out, c.glyph)
+			switch {
+			case flags&compArgsAreXY != 0:
+				out = binary.BigEndian.AppendUint16(out, uint16(int16(math.Round(c.dx))))
+				out = binary.BigEndian.AppendUint16(out, uint16(int16(math.Round(c.dy))))
+			case flags&compArgsAreWords != 0:
+				out = binary.BigEndian.AppendUint16(out, uint16(c.dx))
+				out = binary.BigEndian.AppendUint16(out, uint16(c.dy))
+			default:
+				out = append(out, byte(c.dx), byte(c.dy))
+			}
+			out = append(out, c.transform...)
+		}
+		return append(out, g.tail...)
+	}
+
+	for _, end := range g.endPts {
+		out = binary.BigEndian.AppendUint16(out, uint16(end))
+	}
+	out = binary.BigEndian.AppendUint16(out, uint16(len(g.instructions)))
+	out = append(out, g.instructions...)
+
+	var flags, xs, ys []byte
+	px, py := 0, 0
+	for i := range g.xs {
+		flag := g.onCurve[i]
+		x, y := int(math.Round(g.xs[i])), int(math.Round(g.ys[i]))
+		flag, xs = appendCoord(flag, xs, x-px, 0x02, 0x10)
+		flag, ys = appendCoord(flag, ys, y-py, 0x04, 0x20)
+		flags = append(flags, flag)
+		px, py = x, y
+	}
+	out = append(out, flags...)
+	out = append(out, xs...)
+	return append(out, ys...)
+}
+
+// appendCoord encodes one coordinate delta and sets its short/same flag bits
+func appendCoord(flag byte, buf []byte, delta int, short, same byte) (byte, []byte) {
+	switch {
+	case delta == 0:
+		return flag | same, buf
+	case delta > -256 && delta < 256:
+		flag |= short
+		if delta > 0 {
+			flag |= same
+		} else {
+			delta = -delta
+		}
+		return flag, append(buf, byte(delta))
+	}
+	return flag, binary.BigEndian.AppendUint16(buf, uint16(int16(delta)))
+}
+
+// gvarTable gives access to per-glyph variation data
+type gvarTable struct {
+	data         []byte
+	axisCount    int
+	sharedTuples [][]float64
+	offsets      []int // glyphCount+1 offsets into data
+}
+
+func parseGvar(data []byte, axisCount int) (*gvarTable, error) {
+	if len(data) < 20 {
+		return nil, fmt.Errorf("gvar table too short")
+	}
+	if int(binary.BigEndian.Uint16(data[4:6])) != axisCount {
+		return nil, fmt.Errorf("gvar axis count does not match fvar")
+	}
+	sharedCount := int(binary.BigEndian.Uint16(data[6:8]))
+	sharedOffset := int(binary.BigEndian.Uint32(data[8:12]))
+	glyphCount := int(binary.BigEndian.Uint16(data[12:14]))
+	long := binary.BigEndian.Uint16(data[14:16])&1 != 0
+	dataOffset := int(binary.BigEndian.Uint32(data[16:20]))
+
+	g := &gvarTable{data: data, axisCount: axisCount, offsets: make([]int, glyphCount+1)}
+	for i := range g.offsets {
+		if long {
+			if 20+i*4+4 > len(data) {
+				return nil, fmt.Errorf("gvar offsets truncated")
+			}
+			g.offsets[i] = dataOffset + int(binary.BigEndian.Uint32(data[20+i*4:]))
+		} else {
+			if 20+i*2+2 > len(data) {
+				return nil, fmt.Errorf("gvar offsets truncated")
+			}
+			g.offsets[i] = dataOffset + int(binary.BigEndian.Uint16(data[20+i*2:]))*2
+		}
+	}
+	if sharedOffset+sharedCount*axisCount*2 > len(data) {
+		return nil, fmt.Errorf("gvar shared tuples truncated")
+	}
+	for i := 0; i < sharedCount; i++ {
+		g.sharedTuples = append(g.sharedTuples, readTuple(data[sharedOffset+i*axisCount*2:], axisCount))
+	}
+	return g, nil
+}
+
+// apply adds the variation deltas of a glyph at coords to its points and phantom points
+func (g *gvarTable) apply(gid int, coords []float64, outline *glyphOutline, phantomX, phantomY []float64) error {
+	if gid+1 >= len(g.offsets) {
+		return nil
+	}
+	start, end := g.offsets[gid], g.offsets[gid+1]
+	if start >= end {
+		return nil
+	}
+	if end > len(g.data) {
+		return fmt.Errorf("variation data out of range")
+	}
+	data := g.data[start:end]
+	if len(data) < 4 {
+		return fmt.Errorf("variation data too short")
+	}
+
+	tupleCount := binary.BigEndian.Uint16(data[0:2])
+	serialized := int(binary.BigEndian.Uint16(data[2:4]))
+	if serialized > len(data) {
+		return fmt.Errorf("variation data offset out of range")
+	}
+	numPoints := outline.numPoints()
+	total := numPoints + 4
+
+	pos := serialized
+	var sharedPoints []int
+	if tupleCount&0x8000 != 0 {
+		var n int
+		var err error
+		if sharedPoints, n, err = unpackPoints(data[pos:], total); err != nil {
+			return err
+		}
+		pos += n
+	}
+
+	header := 4
+	for t := 0; t < int(tupleCount&0x0FFF); t++ {
+		if header+4 > len(data) {
+			return fmt.Errorf("tuple header truncated")
+		}
+		size := int(binary.BigEndian.Uint16(data[header:]))
+		index := binary.BigEndian.Uint16(data[header+2:])
+		header += 4
+
+		var peak, startTuple, endTuple []float64
+		if index&0x8000 != 0 {
+			if header+g.axisCount*2 > len(data) {
+				return fmt.Errorf("tuple header truncated")
+			}
+			peak = readTuple(data[header:], g.axisCount)
+			header += g.axisCount * 2
+		} else {
+			if int(index&0x0FFF) >= len(g.sharedTuples) {
+				return fmt.Errorf("shared tuple index out of range")
+			}
+			peak = g.sharedTuples[index&0x0FFF]
+		}
+		if index&0x4000 != 0 {
+			if header+g.axisCount*4 > len(data) {
+				return fmt.Errorf("tuple header truncated")
+			}
+			startTuple = readTuple(data[header:], g.axisCount)
+			endTuple = readTuple(data[header+g.axisCount*2:], g.axisCount)
+			header += g.axisCount * 4
+		}
+
+		if pos+size > len(data) {
+			return fmt.Errorf("tuple data truncated")
+		}
+		tuple := data[pos : pos+size]
+		pos += size
+
+		scalar := tupleScalar(coords, peak, startTuple, endTuple)
+		if scalar == 0 {
+			continue
+		}
+
+		points := sharedPoints
+		off := 0
+		if index&0x2000 != 0 {
+			var err error
+			if points, off, err = unpackPoints(tuple, total); err != nil {
+				return err
+			}
+		}
+		count := total
+		if points != nil {
+			count = len(points)
+		}
+		xd, n, err := unpackDeltas(tuple[off:], count)
+		if err != nil {
+			return err
+		}
+		yd, _, err := unpackDeltas(tuple[off+n:], count)
+		if err != nil {
+			return err
+		}
+
+		dx := make([]float64, total)
+		dy := make([]float64, total)
+		touched := make([]bool, total)
+		for i := 0; i < count; i++ {
+			p := i
+			if points != nil {
+				p = points[i]
+			}
+			if p < total {
+				dx[p], dy[p] = float64(xd[i])*scalar, float64(yd[i])*scalar
+				touched[p] = true
+			}
+		}
+		if points != nil && outline.contours > 0 {
+			interpolateUntouched(outline, dx, dy, touched)
+		}
+
+		for i := 0; i < numPoints; i++ {
+			outline.addDelta(i, dx[i], dy[i])
+		}
+		for i := 0; i < 4; i++ {
+			phantomX[i] += dx[numPoints+i]
+			phantomY[i] += dy[numPoints+i]
+		}
+	}
+	return nil
+}
+
+// tupleScalar returns how much a tuple's deltas apply at coords
+func tupleScalar(coords, peak, start, end []float64) float64 {
+	scalar := 1.0
+	for i, p := range peak {
+		if i >= len(coords) {
+			break
+		}
+		v := coords[i]
+		switch {
+		case p == 0 || v == p:
+			continue
+		case start != nil:
+			s, e := start[i], end[i]
+			if s > p || p > e || (s < 0 && e > 0) {
+				continue
+			}
+			if v < s || v > e {
+				return 0
+			}
+			if v < p {
+				scalar *= (v - s) / (p - s)
+			} else {
+				scalar *= (e - v) / (e - p)
+			}
+		case v == 0 || (v < 0) != (p < 0) || math.Abs(v) > math.Abs(p):
+			return 0
+		default:
+			scalar *= v / p
+		}
+	}
+	return scalar
+}
+
+// interpolateUntouched infers deltas for points a tuple does not list, contour by
+// contour, from the nearest listed points on either side (IUP)
+func interpolateUntouched(outline *glyphOutline, dx, dy []float64, touched []bool) {
+	start := 0
+	for _, end := range outline.endPts {
+		if end >= len(outline.xs) || end < start {
+			break
+		}
+		var refs []int
+		for i := start; i <= end; i++ {
+			if touched[i] {
+				refs = append(refs, i)
+			}
+		}
+		if len(refs) > 0 && len(refs) < end-start+1 {
+			for r, ref := range refs {
+				next := refs[(r+1)%len(refs)]
+				// Walk the untouched points from ref to next, wrapping around the contour
+				for i := ref + 1; ; i++ {
+					if i > end {
+						i = start
+					}
+					if i == next {
+						break
+					}
+					dx[i] = iupDelta(outline.xs[i], outline.xs[ref], outline.xs[next], dx[ref], dx[next])
+					dy[i] = iupDelta(outline.ys[i], outline.ys[ref], outline.ys[next], dy[ref], dy[next])
+				}
+			}
+		}
+		start = end + 1
+	}
+}
+
+// iupDelta interpolates one coordinate's delta between two reference points
+func iupDelta(v, a, b, da, db float64) float64 {
+	if a > b {
+		a, b, da, db = b, a, db, da
+	}
+	switch {
+	case a == b:
+		if da == db {
+			return da
+		}
+		return 0
+	case v <= a:
+		return da
+	case v >= b:
+		return db
+	}
+	return da + (v-a)*(db-da)/(b-a)
+}
+
+// unpackPoints reads packed point numbers; nil means all points
+func unpackPoints(data []byte, total int) ([]int, int, error) {
+	if len(data) < 1 {
+		return nil, 0, fmt.Errorf("point numbers truncated")
+	}
+	count := int(data[0])
+	pos := 1
+	if count&0x80 != 0 {
+		if len(data) < 2 {
+			return nil, 0, fmt.Errorf("point numbers truncated")
+		}
+		count = (count&0x7F)<<8 | int(data[1])
+		pos = 2
+	}
+	if count == 0 {
+		return nil, pos, nil
+	}
+
+	points := make([]int, 0, count)
+	last := 0
+	for len(points) < count {
+		if pos >= len(data) {
+			return nil, 0, fmt.Errorf("point numbers truncated")
+		}
+		control := data[pos]
+		pos++
+		for run := int(control&0x7F) + 1; run > 0 && len(points) < count; run-- {
+			if control&0x80 != 0 {
+				if pos+2 > len(data) {
+					return nil, 0, fmt.Errorf("point numbers truncated")
+				}
+				last += int(binary.BigEndian.Uint16(data[pos:]))
+				pos += 2
+			} else {
+				if pos >= len(data) {
+					return nil, 0, fmt.Errorf("point numbers truncated")
+				}
+				last += int(data[pos])
+				pos++
+			}
+			points = append(points, last)
+		}
+	}
+	return points, pos, nil
+}
+
+// unpackDeltas reads count packed deltas
+func unpackDeltas(data []byte, count int) ([]int, int, error) {
+	deltas := make([]int, 0, count)
+	pos := 0
+	for len(deltas) < count {
+		if pos >= len(data) {
+			return nil, 0, fmt.Errorf("deltas truncated")
+		}
+		control := data[pos]
+		pos++
+		for run := int(control&0x3F) + 1; run > 0 && len(deltas) < count; run-- {
+			switch {
+			case control&0x80 != 0:
+				deltas = append(deltas, 0)
+			case control&0x40 != 0:
+				if pos+2 > len(data) {
+					return nil, 0, fmt.Errorf("deltas truncated")
+				}
+				deltas = append(deltas, int(int16(binary.BigEndian.Uint16(data[pos:]))))
+				pos += 2
+			default:
+				if pos >= len(data) {
+					return nil, 0, fmt.Errorf("deltas truncated")
+				}
+				deltas = append(deltas, int(int8(data[pos])))
+				pos++
+			}
+		}
+	}
+	return deltas, pos, nil
+}
+
+// updateOS2 sets the weight and width classes from the wght and wdth axes
+func updateOS2(os2 []byte, axes []VariationAxis, values []float64) {
+	if len(os2) < 8 {
+		return
+	}
+	if i := axisIndex(axes, "wght"); i != -1 {
+		binary.BigEndian.PutUint16(os2[4:], uint16(math.Round(math.Max(1, math.Min(1000, values[i])))))
+	}
+	if i := axisIndex(axes, "wdth"); i != -1 {
+		// usWidthClass 1-9 correspond to these percentages of normal width
+		widths := []float64{50, 62.5, 75, 87.5, 100, 112.5, 125, 150, 200}
+		best := 0
+		for c, w := range widths {
+			if math.Abs(values[i]-w) < math.Abs(values[i]-widths[best]) {
+				best = c
+			}
+		}
+		binary.BigEndian.PutUint16(os2[6:], uint16(best+1))
+	}
+}
+
+// instanceNames returns the subfamily and PostScript names of an instance: those of
+// the matching named instance, or names built from the axis values
+func instanceNames(ttf *TTF, axes []VariationAxis, instances []NamedInstance, values []float64) (string, string) {
+	family := familyName(ttf)
+	psFamily := strings.ReplaceAll(family, " ", "")
+
+	for _, instance := range instances {
+		match := true
+		for i, axis := range axes {
+			if instance.Coordinates[axis.Tag] != values[i] {
+				match = false
+				break
+			}
+		}
+		if match && instance.Name != "" {
+			ps := instance.PostScriptName
+			if ps == "" {
+				ps = psFamily + "-" + strings.ReplaceAll(instance.Name, " ", "")
+			}
+			return instance.Name, sanitizePostScriptName(ps)
+		}
+	}
+
+	var parts []string
+	for i, axis := range axes {
+		if values[i] != axis.Default {
+			parts = append(parts, axis.Tag+strconv.FormatFloat(values[i], 'f', -1, 64))
+		}
+	}
+	if len(parts) == 0 {
+		parts = []string{"Regular"}
+	}
+	subfamily := strings.Join(parts, " ")
+	return subfamily, sanitizePostScriptName(psFamily + "-" + strings.Join(parts, "_"))
+}
+
+// sanitizePostScriptName keeps printable ASCII allowed in PostScript names, up to 63 characters
+func sanitizePostScriptName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || strings.ContainsRune("[](){}<>/%", r) {
+			return -1
+		}
+		return r
+	}, name)
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	return name
+}
+
+// familyName returns the typographic family name, falling back to the legacy family
+func familyName(ttf *TTF) string {
+	if name := nameString(ttf, 16); name != "" {
+		return name
+	}
+	return ttf.FamilyName
+}
+
+// nameString returns a name table string by ID, preferring Unicode platforms
+func nameString(ttf *TTF, id uint16) string {
+	for _, rec := range nameRecords(ttf.Tables["name"]) {
+		if rec.nameID == id && (rec.platformID == 3 || rec.platformID == 0) {
+			return decodeUTF16(rec.value)
+		}
+	}
+	for _, rec := range nameRecords(ttf.Tables["name"]) {
+		if rec.nameID == id && rec.platformID == 1 {
+			return string(rec.value)
+		}
+	}
+	return ""
+}
+
+type nameRecord struct {
+	platformID, encodingID, languageID, nameID uint16
+	value                                      []byte
+}
+
+func nameRecords(table *Table) []nameRecord {
+	if table == nil || len(table.Data) < 6 {
+		return nil
+	}
+	data := table.Data
+	count := int(binary.BigEndian.Uint16(data[2:4]))
+	storage := int(binary.BigEndian.Uint16(data[4:6]))
+
+	var records []nameRecord
+	for i := 0; i < count && 6+i*12+12 <= len(data); i++ {
+		rec := data[6+i*12:]
+		length := int(binary.BigEndian.Uint16(rec[8:10]))
+		offset := storage + int(binary.BigEndian.Uint16(rec[10:12]))
+		if offset+length > len(data) {
+			continue
+		}
+		records = append(records, nameRecord{
+			platformID: binary.BigEndian.Uint16(rec[0:2]),
+			encodingID: binary.BigEndian.Uint16(rec[2:4]),
+			languageID: binary.BigEndian.Uint16(rec[4:6]),
+			nameID:     binary.BigEndian.Uint16(rec[6:8]),
+			value:      data[offset : offset+length],
+		})
+	}
+	return records
+}
+
+// renameInstance rewrites the subfamily, unique, full and PostScript names of an instance
+func renameInstance(data []byte, family, subfamily, psName string) []byte {
+	replaced := map[uint16]string{
+		2: subfamily,
+		3: psName,
+		4: family + " " + subfamily,
+		6: psName,
+	}
+
+	var records []nameRecord
+	for _, rec := range nameRecords(&Table{Data: data}) {
+		// Variation-specific names no longer apply to a static instance
+		if _, ok := replaced[rec.nameID]; ok || rec.nameID == 25 {
+			continue
+		}
+		records = append(records, rec)
+	}
+	for id, value := range replaced {
+		records = append(records, nameRecord{platformID: 3, encodingID: 1, languageID: 0x0409, nameID: id, value: encodeUTF16(value)})
+	}
+	return buildNameTable(records)
+}
+
+// buildNameTable writes a format 0 name table with records in the required order
+func buildNameTable(records []nameRecord) []byte {
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if a.platformID != b.platformID {
+			return a.platformID < b.platformID
+		}
+		if a.encodingID != b.encodingID {
+			return a.encodingID < b.encodingID
+		}
+		if a.languageID != b.languageID {
+			return a.languageID < b.languageID
+		}
+		return a.nameID < b.nameID
+	})
+
+	storageOffset := 6 + len(records)*12
+	out := make([]byte, storageOffset)
+	binary.BigEndian.PutUint16(out[2:], uint16(len(records)))
+	binary.BigEndian.PutUint16(out[4:], uint16(storageOffset))
+	var storage []byte
+	for i, rec := range records {
+		entry := out[6+i*12:]
+		binary.BigEndian.PutUint16(entry[0:], rec.platformID)
+		binary.BigEndian.PutUint16(entry[2:], rec.encodingID)
+		binary.BigEndian.PutUint16(entry[4:], rec.languageID)
+		binary.BigEndian.PutUint16(entry[6:], rec.nameID)
+		binary.BigEndian.PutUint16(entry[8:], uint16(len(rec.value)))
+		binary.BigEndian.PutUint16(entry[10:], uint16(len(storage)))
+		storage = append(storage, rec.value...)
+	}
+	return append(out, storage...)
+}
+
+// buildSFNT assembles tables into a TrueType font file with valid checksums
+func buildSFNT(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	numTables := len(tags)
+	searchRange, entrySelector := 1, 0
+	for searchRange*2 <= numTables {
+		searchRange *= 2
+		entrySelector++
+	}
+	searchRange *= 16
+
+	out := make([]byte, 12+16*numTables)
+	binary.BigEndian.PutUint32(out[0:], 0x00010000)
+	binary.BigEndian.PutUint16(out[4:], uint16(numTables))
+	binary.BigEndian.PutUint16(out[6:], uint16(searchRange))
+	binary.BigEndian.PutUint16(out[8:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(out[10:], uint16(numTables*16-searchRange))
+
+	headOffset := -1
+	for i, tag := range tags {
+		data := tables[tag]
+		if tag == "head" && len(data) >= 12 {
+			binary.BigEndian.PutUint32(data[8:], 0)
+			headOffset = len(out)
+		}
+		entry := out[12+i*16:]
+		copy(entry[0:4], tag)
+		binary.BigEndian.PutUint32(entry[4:], tableChecksum(data))
+		binary.BigEndian.PutUint32(entry[8:], uint32(len(out)))
+		binary.BigEndian.PutUint32(entry[12:], uint32(len(data)))
+		out = append(out, data...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+	}
+	if headOffset != -1 {
+		binary.BigEndian.PutUint32(out[headOffset+8:], 0xB1B0AFBA-tableChecksum(out))
+	}
+	return out
+}
+
+func tableChecksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
+
+func axisIndex(axes []VariationAxis, tag string) int {
+	for i, axis := range axes {
+		if axis.Tag == tag {
+			return i
+		}
+	}
+	return -1
+}
+
+func readTuple(data []byte, n int) []float64 {
+	tuple := make([]float64, n)
+	for i := range tuple {
+		tuple[i] = f2dot14(data[i*2:])
+	}
+	return tuple
+}
+
+func fixedToFloat(b []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(b))) / 65536
+}
+
+func f2dot14(b []byte) float64 {
+	return float64(int16(binary.BigEndian.Uint16(b))) / 16384
+}
+
+func decodeUTF16(b []byte) string {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = binary.BigEndian.Uint16(b[i*2:])
+	}
+	return string(utf16.Decode(units))
+}
+
+func encodeUTF16(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	out := make([]byte, len(units)*2)
+	for i, u := range units {
+		binary.BigEndian.PutUint16(out[i*2:], u)
+	}
+	return out
+}
@@ -0,0 +1,295 @@
+package font
+
+import (
+	"encoding/binary"
+	"os"
+	"testing"
+)
+
+// variableTestFont turns the static test font into a one-axis (wght 100-900) variable
+// font: at wght 900 every point of AHSA (U+10330) moves 20 units right and its advance
+// grows by 40; only the first point of GIBA (U+10332) has a delta, so the rest of its
+// contour follows by interpolation. Named instances are "Regular" (400) and "Bold" (700).
+func variableTestFont(t *testing.T) (*Font, map[string]uint16) {
+	t.Helper()
+	data, err := os.ReadFile(substitutionTestFont(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ttf, err := ParseTTF(data)
+	if err != nil {
+		t.Fatalf("ParseTTF failed: %v", err)
+	}
+	glyphMap, err := parseCmap(ttf.Tables["cmap"].Data)
+	if err != nil {
+		t.Fatalf("parseCmap failed: %v", err)
+	}
+	gids := map[string]uint16{"ahsa": glyphMap[0x10330], "giba": glyphMap[0x10332]}
+
+	tables := make(map[string][]byte)
+	for tag, table := range ttf.Tables {
+		tables[tag] = append([]byte(nil), table.Data...)
+	}
+
+	// Name records for the axis and the Bold instance
+	records := nameRecords(ttf.Tables["name"])
+	records = append(records,
+		nameRecord{platformID: 3, encodingID: 1, languageID: 0x0409, nameID: 256, value: encodeUTF16("Weight")},
+		nameRecord{platformID: 3, encodingID: 1, languageID: 0x0409, nameID: 257, value: encodeUTF16("Bold")},
+		nameRecord{platformID: 3, encodingID: 1, languageID: 0x0409, nameID: 258, value: encodeUTF16("Regular")},
+	)
+	tables["name"] = buildNameTable(records)
+
+	fvar := []byte{0, 1, 0, 0, 0, 16, 0, 2, 0, 1, 0, 20, 0, 2, 0, 8}
+	fvar = append(fvar, "wght"...)
+	for _, v := range []uint32{100 << 16, 400 << 16, 900 << 16} {
+		fvar = binary.BigEndian.AppendUint32(fvar, v)
+	}
+	fvar = append(fvar, 0, 0, 1, 0) // flags, axisNameID 256
+	fvar = append(fvar, 1, 2, 0, 0)
+	fvar = binary.BigEndian.AppendUint32(fvar, 400<<16)
+	fvar = append(fvar, 1, 1, 0, 0)
+	fvar = binary.BigEndian.AppendUint32(fvar, 700<<16)
+	tables["fvar"] = fvar
+
+	numGlyphs := int(ttf.NumGlyphs)
+	offsets, err := locaOffsets(ttf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	glyf := ttf.Tables["glyf"].Data
+	pointCount := func(gid uint16) int {
+		outline, err := decodeGlyph(glyf[offsets[gid]:offsets[gid+1]])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return outline.numPoints() + 4
+	}
+
+	// AHSA: all points move +20 in x, the advance phantom point +40
+	ahsaPoints := pointCount(gids["ahsa"])
+	xDeltas := make([]int, ahsaPoints)
+	for i := 0; i < ahsaPoints-4; i++ {
+		xDeltas[i] = 20
+	}
+	xDeltas[ahsaPoints-3] = 40
+	ahsaData := []byte{0}
+	ahsaData = append(ahsaData, packTestDeltas(xDeltas)...)
+	ahsaData = append(ahsaData, packTestDeltas(make([]int, ahsaPoints))...)
+
+	// GIBA: only point 0 moves +30
+	gibaData := []byte{1, 0, 0}
+	gibaData = append(gibaData, packTestDeltas([]int{30})...)
+	gibaData = append(gibaData, packTestDeltas([]int{0})...)
+
+	glyphData := func(serialized []byte) []byte {
+		out := []byte{0, 1, 0, 10}
+		out = binary.BigEndian.AppendUint16(out, uint16(len(serialized)))
+		out = append(out, 0xA0, 0, 0x40, 0) // embedded peak, private points; peak wght=1.0
+		return append(out, serialized...)
+	}
+
+	header := []byte{0, 1, 0, 0, 0, 1, 0, 0}
+	header = binary.BigEndian.AppendUint32(header, uint32(20+(numGlyphs+1)*4))
+	header = binary.BigEndian.AppendUint16(header, uint16(numGlyphs))
+	header = binary.BigEndian.AppendUint16(header, 1)
+	header = binary.BigEndian.AppendUint32(header, uint32(20+(numGlyphs+1)*4))
+	var variations []byte
+	for gid := 0; gid <= numGlyphs; gid++ {
+		header = binary.BigEndian.AppendUint32(header, uint32(len(variations)))
+		switch {
+		case gid == numGlyphs:
+		case uint16(gid) == gids["ahsa"]:
+			variations = append(variations, glyphData(ahsaData)...)
+		case uint16(gid) == gids["giba"]:
+			variations = append(variations, glyphData(gibaData)...)
+		}
+	}
+	tables["gvar"] = append(header, variations...)
+
+	f, err := NewFont("Test", buildSFNT(tables))
+	if err != nil {
+		t.Fatalf("NewFont failed: %v", err)
+	}
+	return f, gids
+}
+
+// packTestDeltas packs deltas as runs of 16-bit words
+func packTestDeltas(deltas []int) []byte {
+	var out []byte
+	for len(deltas) > 0 {
+		n := min(len(deltas), 64)
+		out = append(out, 0x40|byte(n-1))
+		for _, d := range deltas[:n] {
+			out = binary.BigEndian.AppendUint16(out, uint16(int16(d)))
+		}
+		deltas = deltas[n:]
+	}
+	return out
+}
+
+func glyphMetrics(t *testing.T, f *Font, gid uint16) (int, *glyphOutline) {
+	t.Helper()
+	ttf, err := ParseTTF(f.Data)
+	if err != nil {
+		t.Fatalf("ParseTTF failed: %v", err)
+	}
+	advances, _, err := horizontalMetrics(ttf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offsets, err := locaOffsets(ttf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outline, err := decodeGlyph(ttf.Tables["glyf"].Data[offsets[gid]:offsets[gid+1]])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return advances[gid], outline
+}
+
+func TestVariableFontAxesAndInstances(t *testing.T) {
+	f, _ := variableTestFont(t)
+	if !f.IsVariable() {
+		t.Fatal("Expected a variable font")
+	}
+
+	axes, err := f.VariationAxes()
+	if err != nil {
+		t.Fatalf("VariationAxes failed: %v", err)
+	}
+	if len(axes) != 1 || axes[0].Tag != "wght" || axes[0].Name != "Weight" || axes[0].Min != 100 || axes[0].Default != 400 || axes[0].Max != 900 {
+		t.Errorf("Unexpected axes %+v", axes)
+	}
+
+	instances, err := f.NamedInstances()
+	if err != nil {
+		t.Fatalf("NamedInstances failed: %v", err)
+	}
+	if len(instances) != 2 || instances[1].Name != "Bold" || instances[1].Coordinates["wght"] != 700 {
+		t.Errorf("Unexpected instances %+v", instances)
+	}
+}
+
+func TestVariableFontInstance(t *testing.T) {
+	f, gids := variableTestFont(t)
+	baseAdvance, base := glyphMetrics(t, f, gids["ahsa"])
+	_, baseGiba := glyphMetrics(t, f, gids["giba"])
+
+	// The default instance reproduces the original outlines
+	regular, err := f.Instance(nil)
+	if err != nil {
+		t.Fatalf("Instance failed: %v", err)
+	}
+	if regular.IsVariable() {
+		t.Error("Instance still has variation tables")
+	}
+	advance, outline := glyphMetrics(t, regular, gids["ahsa"])
+	if advance != baseAdvance || len(outline.xs) != len(base.xs) {
+		t.Fatalf("Default instance changed AHSA: advance %d vs %d", advance, baseAdvance)
+	}
+	for i := range base.xs {
+		if outline.xs[i] != base.xs[i] || outline.ys[i] != base.ys[i] {
+			t.Fatalf("Default instance moved point %d", i)
+		}
+	}
+
+	// Halfway to the peak applies half the deltas
+	medium, err := f.Instance(map[string]float64{"wght": 650})
+	if err != nil {
+		t.Fatalf("Instance failed: %v", err)
+	}
+	advance, outline = glyphMetrics(t, medium, gids["ahsa"])
+	if advance != baseAdvance+20 {
+		t.Errorf("Expected advance %d, got %d", baseAdvance+20, advance)
+	}
+	for i := range base.xs {
+		if outline.xs[i] != base.xs[i]+10 || outline.ys[i] != base.ys[i] {
+			t.Fatalf("Point %d at (%v,%v), expected (%v,%v)", i, outline.xs[i], outline.ys[i], base.xs[i]+10, base.ys[i])
+		}
+	}
+	width, err := medium.TextWidth("\U00010330", 1000)
+	if err != nil {
+		t.Fatalf("TextWidth failed: %v", err)
+	}
+	ttf, _ := ParseTTF(medium.Data)
+	if want := float64(advance) * 1000 / float64(ttf.UnitsPerEm); width != want {
+		t.Errorf("TextWidth = %v, want %v", width, want)
+	}
+
+	// Untouched points of GIBA follow the single moved point of their contour
+	_, outlineGiba := glyphMetrics(t, medium, gids["giba"])
+	for i := 0; i <= baseGiba.endPts[0]; i++ {
+		if outlineGiba.xs[i] != baseGiba.xs[i]+15 {
+			t.Errorf("GIBA point %d at %v, expected %v", i, outlineGiba.xs[i], baseGiba.xs[i]+15)
+		}
+	}
+
+	if _, err := f.Instance(map[string]float64{"wdth": 100}); err == nil {
+		t.Error("Expected error for unknown axis")
+	}
+}
+
+func TestVariableFontNamedInstance(t *testing.T) {
+	f, _ := variableTestFont(t)
+	f.AddString("Hi")
+
+	bold, err := f.NamedInstance("bold")
+	if err != nil {
+		t.Fatalf("NamedInstance failed: %v", err)
+	}
+	ttf, err := ParseTTF(bold.Data)
+	if err != nil {
+		t.Fatalf("Instance does not parse: %v", err)
+	}
+	if ttf.PostScriptName != "NotoSansGothic-Bold" || ttf.FullName != "Noto Sans Gothic Bold" {
+		t.Errorf("Unexpected names %q / %q", ttf.PostScriptName, ttf.FullName)
+	}
+	if weight := binary.BigEndian.Uint16(ttf.Tables["OS/2"].Data[4:6]); weight != 700 {
+		t.Errorf("Expected usWeightClass 700, got %d", weight)
+	}
+	if len(bold.Subset) != 2 {
+		t.Errorf("Instance did not keep the subset: %q", string(bold.Subset))
+	}
+	if _, err := f.NamedInstance("Black"); err == nil {
+		t.Error("Expected error for unknown instance")
+	}
+
+	custom, err := f.Instance(map[string]float64{"wght": 550})
+	if err != nil {
+		t.Fatalf("Instance failed: %v", err)
+	}
+	if ttf, _ := ParseTTF(custom.Data); ttf.PostScriptName != "NotoSansGothic-wght550" {
+		t.Errorf("Unexpected PostScript name %q", ttf.PostScriptName)
+	}
+
+	static, err := NewFont("Static", createMinimalTTF())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := static.Instance(nil); err == nil {
+		t.Error("Expected error for a static font")
+	}
+}
+
+func TestInterpolateUntouched(t *testing.T) {
+	// Square contour with the left and right edges touched
+	outline := &glyphOutline{
+		contours: 1,
+		endPts:   []int{4},
+		xs:       []float64{0, 50, 100, 100, 0},
+		ys:       []float64{0, 0, 0, 100, 100},
+	}
+	dx := []float64{10, 0, 30, 0, 0}
+	dy := make([]float64, 5)
+	touched := []bool{true, false, true, false, false}
+	interpolateUntouched(outline, dx, dy, touched)
+
+	want := []float64{10, 20, 30, 30, 10}
+	for i := range want {
+		if dx[i] != want[i] {
+			t.Errorf("dx[%d] = %v, want %v", i, dx[i], want[i])
+		}
+	}
+}
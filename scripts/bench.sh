@@ -0,0 +1,37 @@
+#!/bin/bash
+# Run the benchmark suite and optionally compare against a saved baseline.
+#
+# Usage:
+#   scripts/bench.sh                    # run and write bench_output.txt
+#   scripts/bench.sh baseline.txt       # run, then compare with baseline.txt
+#
+# Environment:
+#   BENCH      benchmark regex (default: .)
+#   COUNT      runs per benchmark (default: 6, enough for benchstat)
+#   BENCHTIME  -benchtime value (default: 1s)
+#
+# Comparison uses benchstat when available:
+#   go install golang.org/x/perf/cmd/benchstat@latest
+
+set -e
+
+cd "$(dirname "$0")/.."
+
+BENCH="${BENCH:-.}"
+COUNT="${COUNT:-6}"
+BENCHTIME="${BENCHTIME:-1s}"
+OUTPUT="bench_output.txt"
+
+echo "Running benchmarks (bench=$BENCH count=$COUNT benchtime=$BENCHTIME)..."
+go test ./... -run '^$' -bench "$BENCH" -benchmem -count "$COUNT" -benchtime "$BENCHTIME" | tee "$OUTPUT"
+echo "✓ Results written to $OUTPUT"
+
+if [ -n "$1" ]; then
+    if command -v benchstat >/dev/null 2>&1; then
+        echo ""
+        benchstat "$1" "$OUTPUT"
+    else
+        echo "benchstat not found; install with: go install golang.org/x/perf/cmd/benchstat@latest"
+        exit 1
+    fi
+fi
@@ -0,0 +1,83 @@
+#!/usr/bin/env python3
+"""Generate core/write/nfc_tables.go, the Unicode data used for NFC and NFKC normalization.
+
+Usage:
+    python3 scripts/gen_nfc_tables.py > core/write/nfc_tables.go && gofmt -w core/write/nfc_tables.go
+
+The tables come from the unicodedata module of the Python running the script.
+"""
+import sys
+import unicodedata
+
+HANGUL_S_BASE, HANGUL_S_COUNT = 0xAC00, 11172
+
+
+def main():
+    decomp = {}
+    compat = {}
+    excluded = []
+    ccc_ranges = []
+
+    for cp in range(sys.maxunicode + 1):
+        ch = chr(cp)
+        d = unicodedata.decomposition(ch)
+        if d.startswith("<"):
+            compat[cp] = [int(p, 16) for p in d.split()[1:]]
+        if d and not d.startswith("<") and not (HANGUL_S_BASE <= cp < HANGUL_S_BASE + HANGUL_S_COUNT):
+            parts = [int(p, 16) for p in d.split()]
+            decomp[cp] = parts
+            # Pairs that NFC does not recompose: composition exclusions and
+            # decompositions starting with a non-starter
+            if len(parts) == 2 and unicodedata.normalize("NFC", "".join(map(chr, parts))) != ch:
+                excluded.append(cp)
+
+        c = unicodedata.combining(ch)
+        if c:
+            if ccc_ranges and ccc_ranges[-1][1] == cp - 1 and ccc_ranges[-1][2] == c:
+                ccc_ranges[-1][1] = cp
+            else:
+                ccc_ranges.append([cp, cp, c])
+
+    out = sys.stdout
+    out.write("// Code generated by scripts/gen_nfc_tables.py; DO NOT EDIT.\n\n")
+    out.write("package write\n\n")
+    out.write("// unicodeDataVersion is the Unicode version the tables were generated from\n")
+    out.write('const unicodeDataVersion = "%s"\n\n' % unicodedata.unidata_version)
+
+    out.write("// canonicalDecompositions maps characters to their one-step canonical decomposition\n")
+    out.write("var canonicalDecompositions = map[rune][]rune{\n")
+    items = sorted(decomp.items())
+    for i in range(0, len(items), 4):
+        out.write("\t" + " ".join(
+            "0x%04X: {%s}," % (cp, ", ".join("0x%04X" % p for p in parts))
+            for cp, parts in items[i:i + 4]) + "\n")
+    out.write("}\n\n")
+
+    out.write("// compatibilityDecompositions maps characters to their one-step compatibility\n")
+    out.write("// decomposition, such as U+FB01 (fi ligature) to \"fi\"\n")
+    out.write("var compatibilityDecompositions = map[rune][]rune{\n")
+    items = sorted(compat.items())
+    for i in range(0, len(items), 4):
+        out.write("\t" + " ".join(
+            "0x%04X: {%s}," % (cp, ", ".join("0x%04X" % p for p in parts))
+            for cp, parts in items[i:i + 4]) + "\n")
+    out.write("}\n\n")
+
+    out.write("// compositionExclusions lists characters with a two-character decomposition that\n")
+    out.write("// NFC never recomposes\n")
+    out.write("var compositionExclusions = map[rune]bool{\n")
+    for i in range(0, len(excluded), 8):
+        out.write("\t" + " ".join("0x%04X: true," % cp for cp in excluded[i:i + 8]) + "\n")
+    out.write("}\n\n")
+
+    out.write("// combiningClassRanges holds the non-zero canonical combining classes as\n")
+    out.write("// sorted {first, last, class} ranges\n")
+    out.write("var combiningClassRanges = [][3]rune{\n")
+    for i in range(0, len(ccc_ranges), 4):
+        out.write("\t" + " ".join(
+            "{0x%04X, 0x%04X, %d}," % tuple(r) for r in ccc_ranges[i:i + 4]) + "\n")
+    out.write("}\n")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,86 @@
+package pdfer
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"strings"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/content/render"
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/forms"
+)
+
+// SummarySnippetLength is the maximum number of runes in Summary.Snippet
+const SummarySnippetLength = 280
+
+// Summary is a lightweight overview of a PDF suitable for listings and previews.
+type Summary struct {
+	Title      string `json:"title,omitempty"`
+	Author     string `json:"author,omitempty"`
+	PDFVersion string `json:"pdf_version,omitempty"`
+	PageCount  int    `json:"page_count"`
+	Encrypted  bool   `json:"encrypted"`
+	FormType   string `json:"form_type"`           // "acroform", "xfa", or "unknown"
+	Snippet    string `json:"snippet,omitempty"`   // Leading text of the first page
+	Thumbnail  []byte `json:"thumbnail,omitempty"` // PNG preview of the first page
+}
+
+// Summarize returns title, page count, a first-page text snippet, the form type and a
+// first-page thumbnail. Only the catalog, Info dictionary, the path to the first page
+// and that page's resources are read; the rest of the document is not extracted.
+func Summarize(pdfBytes []byte, password []byte, verbose bool) (*Summary, error) {
+	pdf, err := parse.OpenWithOptions(pdfBytes, parse.ParseOptions{
+		Password: password,
+		Verbose:  verbose,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PDF: %w", err)
+	}
+
+	summary := &Summary{
+		PDFVersion: pdf.Version(),
+		Encrypted:  pdf.IsEncrypted(),
+		FormType:   string(forms.DetectType(pdf)),
+	}
+
+	if metadata, err := extract.ExtractMetadata(pdfBytes, pdf, verbose); err == nil {
+		summary.Title = metadata.Title
+		summary.Author = metadata.Author
+	}
+
+	count, err := extract.PageCount(pdf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count pages: %w", err)
+	}
+	summary.PageCount = count
+	if count == 0 {
+		return summary, nil
+	}
+
+	page, err := extract.ExtractPage(pdfBytes, pdf, 1, verbose)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract first page: %w", err)
+	}
+
+	var text []string
+	for _, t := range page.Text {
+		if s := strings.TrimSpace(t.Text); s != "" {
+			text = append(text, s)
+		}
+	}
+	snippet := []rune(strings.Join(text, " "))
+	if len(snippet) > SummarySnippetLength {
+		snippet = snippet[:SummarySnippetLength]
+	}
+	summary.Snippet = string(snippet)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, render.RenderPage(page, render.DefaultRenderOptions())); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	summary.Thumbnail = buf.Bytes()
+
+	return summary, nil
+}
@@ -0,0 +1,53 @@
+package pdfer
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/write"
+)
+
+func TestSummarize(t *testing.T) {
+	builder := write.NewSimplePDFBuilder()
+	for i := 1; i <= 3; i++ {
+		page := builder.AddPage(write.PageSizeLetter)
+		font := page.AddStandardFont("Helvetica")
+		page.Content().
+			BeginText().
+			SetFont(font, 12).
+			SetTextPosition(72, 720).
+			ShowText(fmt.Sprintf("Page %d body", i)).
+			EndText()
+		builder.FinalizePage(page)
+	}
+	builder.Writer().SetMetadataFields(map[string]string{"Title": "Quarterly Report", "Author": "Jane Doe"})
+
+	pdfBytes, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+
+	summary, err := Summarize(pdfBytes, nil, false)
+	if err != nil {
+		t.Fatalf("Summarize failed: %v", err)
+	}
+
+	if summary.PageCount != 3 {
+		t.Errorf("Expected 3 pages, got %d", summary.PageCount)
+	}
+	if summary.Title != "Quarterly Report" {
+		t.Errorf("Expected title 'Quarterly Report', got %q", summary.Title)
+	}
+	if !strings.Contains(summary.Snippet, "Page 1 body") || strings.Contains(summary.Snippet, "Page 2") {
+		t.Errorf("Expected snippet from first page only, got %q", summary.Snippet)
+	}
+	if summary.FormType != "unknown" {
+		t.Errorf("Expected form type 'unknown', got %q", summary.FormType)
+	}
+	if _, err := png.Decode(bytes.NewReader(summary.Thumbnail)); err != nil {
+		t.Errorf("Thumbnail is not a valid PNG: %v", err)
+	}
+}
@@ -0,0 +1,365 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/core/compare"
+	"github.com/benedoc-inc/pdfer/core/encrypt"
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/forms/acroform"
+	"github.com/benedoc-inc/pdfer/forms/xfa"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// Benchmark corpora are built once and shared across benchmarks.
+// Run with: go test ./tests -run '^$' -bench . -benchmem
+// or scripts/bench.sh to record a baseline and compare against it.
+var (
+	benchCorpusOnce sync.Once
+	benchTextPDF    []byte // 20 pages of text and simple graphics
+	benchImagePDF   []byte // 4 pages, each with a full-page raster image (scan-like)
+	benchFormPDF    []byte // single page with 25 AcroForm text fields
+	benchXFAPDF     []byte // XFA form with benchXFAFields fields in its datasets
+)
+
+// benchXFAFields is the field count of the large XFA form
+const benchXFAFields = 2500
+
+func loadBenchCorpus(b *testing.B) {
+	b.Helper()
+	benchCorpusOnce.Do(func() {
+		benchTextPDF = buildBenchTextPDF(20)
+		benchImagePDF = buildBenchImagePDF(4, 850, 1100)
+		benchFormPDF = buildBenchFormPDF(25)
+		benchXFAPDF = buildBenchXFAPDF(benchXFAFields)
+	})
+	if benchTextPDF == nil || benchImagePDF == nil || benchFormPDF == nil || benchXFAPDF == nil {
+		b.Fatal("failed to build benchmark corpus")
+	}
+}
+
+func buildBenchTextPDF(pages int) []byte {
+	builder := write.NewSimplePDFBuilder()
+	for p := 1; p <= pages; p++ {
+		page := builder.AddPage(write.PageSizeLetter)
+		font := page.AddStandardFont("Helvetica")
+		content := page.Content()
+		content.BeginText().SetFont(font, 10)
+		for line := 0; line < 50; line++ {
+			content.SetTextPosition(72, 740-float64(line)*13)
+			content.ShowText(fmt.Sprintf("Page %d line %d: The quick brown fox jumps over the lazy dog.", p, line))
+		}
+		content.EndText()
+		content.SetStrokeColorRGB(0, 0, 0.5).Rectangle(60, 60, 492, 690).Stroke()
+		builder.FinalizePage(page)
+	}
+	data, err := builder.Bytes()
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func buildBenchImagePDF(pages, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := uint8((x*7 + y*13 + (x*y)%31) % 256)
+			img.SetRGBA(x, y, color.RGBA{R: v, G: v, B: v, A: 0xFF})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil
+	}
+
+	builder := write.NewSimplePDFBuilder()
+	for p := 0; p < pages; p++ {
+		info, err := builder.Writer().AddImage(buf.Bytes(), "")
+		if err != nil {
+			return nil
+		}
+		page := builder.AddPage(write.PageSizeLetter)
+		name := page.AddImage(info)
+		page.Content().DrawImageAt(name, 0, 0, 612, 792)
+		builder.FinalizePage(page)
+	}
+	data, err := builder.Bytes()
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func buildBenchFormPDF(fields int) []byte {
+	w := write.NewPDFWriter()
+	pageNum := w.AddObject([]byte("<</Type/Page/MediaBox[0 0 612 792]>>"))
+	pagesNum := w.AddObject([]byte(fmt.Sprintf("<</Type/Pages/Kids[%d 0 R]/Count 1>>", pageNum)))
+	w.SetObject(pageNum, []byte(fmt.Sprintf("<</Type/Page/Parent %d 0 R/MediaBox[0 0 612 792]>>", pagesNum)))
+
+	fb := acroform.NewFieldBuilder(w)
+	for i := 0; i < fields; i++ {
+		y := 740 - float64(i)*26
+		fb.AddTextField(fmt.Sprintf("field%d", i), []float64{72, y, 300, y + 20}, 0)
+	}
+	acroFormNum, err := fb.Build()
+	if err != nil {
+		return nil
+	}
+	w.SetRoot(w.AddObject([]byte(fmt.Sprintf("<</Type/Catalog/Pages %d 0 R/AcroForm %d 0 R>>", pagesNum, acroFormNum))))
+
+	data, err := w.Bytes()
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// buildBenchXFADatasets returns datasets XML with the given number of fields,
+// named field0 onwards
+func buildBenchXFADatasets(fields int) string {
+	var b strings.Builder
+	b.WriteString(`<xfa:datasets xmlns:xfa="http://www.xfa.org/schema/xfa-data/1.0/"><xfa:data>`)
+	for i := 0; i < fields; i++ {
+		fmt.Fprintf(&b, `<field name="field%d"><value>initial value %d</value></field>`, i, i)
+	}
+	b.WriteString(`</xfa:data></xfa:datasets>`)
+	return b.String()
+}
+
+func buildBenchXFAPDF(fields int) []byte {
+	var template strings.Builder
+	template.WriteString(`<template xmlns="http://www.xfa.org/schema/xfa-template/3.3/"><subform name="form1">`)
+	for i := 0; i < fields; i++ {
+		fmt.Fprintf(&template, `<field name="field%d" w="60mm" h="9mm"><ui><textEdit/></ui></field>`, i)
+	}
+	template.WriteString(`</subform></template>`)
+
+	w := write.NewPDFWriter()
+	templateNum := w.AddStreamObject(write.Dictionary{}, []byte(template.String()), true)
+	datasetsNum := w.AddStreamObject(write.Dictionary{}, []byte(buildBenchXFADatasets(fields)), true)
+	pagesNum := w.AddObject([]byte("<</Type/Pages/Kids[]/Count 0>>"))
+	acroFormNum := w.AddObject([]byte(fmt.Sprintf("<</Fields[]/XFA[(template) %d 0 R(datasets) %d 0 R]>>", templateNum, datasetsNum)))
+	w.SetRoot(w.AddObject([]byte(fmt.Sprintf("<</Type/Catalog/Pages %d 0 R/AcroForm %d 0 R>>", pagesNum, acroFormNum))))
+
+	data, err := w.Bytes()
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// benchXFAData returns values for the given number of fields, named field0 onwards
+func benchXFAData(fields int) types.FormData {
+	data := types.FormData{}
+	for i := 0; i < fields; i++ {
+		data[fmt.Sprintf("field%d", i)] = fmt.Sprintf("filled value %d", i)
+	}
+	return data
+}
+
+// loadEStar returns the encrypted XFA sample and its encryption info, skipping if absent
+func loadEStar(b *testing.B) ([]byte, *types.PDFEncryption) {
+	b.Helper()
+	path := getTestResourcePath("estar.pdf")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		b.Skipf("Test PDF not found at %s", path)
+	}
+	_, encInfo, err := encrypt.DecryptPDF(data, []byte(""), false)
+	if err != nil {
+		b.Skipf("Failed to decrypt %s: %v", path, err)
+	}
+	return data, encInfo
+}
+
+func BenchmarkOpen(b *testing.B) {
+	loadBenchCorpus(b)
+	corpora := []struct {
+		name string
+		data []byte
+	}{
+		{"text", benchTextPDF},
+		{"images", benchImagePDF},
+		{"form", benchFormPDF},
+	}
+	for _, c := range corpora {
+		b.Run(c.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(c.data)))
+			for i := 0; i < b.N; i++ {
+				if _, err := parse.Open(c.data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+
+	b.Run("encrypted_xfa", func(b *testing.B) {
+		data, _ := loadEStar(b)
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			if _, err := parse.OpenWithOptions(data, parse.ParseOptions{Password: []byte("")}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkExtractContent(b *testing.B) {
+	loadBenchCorpus(b)
+	corpora := []struct {
+		name string
+		data []byte
+	}{
+		{"text", benchTextPDF},
+		{"images", benchImagePDF},
+	}
+	for _, c := range corpora {
+		b.Run(c.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(c.data)))
+			for i := 0; i < b.N; i++ {
+				if _, err := extract.ExtractContent(c.data, nil, false); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkFill(b *testing.B) {
+	loadBenchCorpus(b)
+
+	b.Run("acroform", func(b *testing.B) {
+		data := types.FormData{}
+		for i := 0; i < 25; i++ {
+			data[fmt.Sprintf("field%d", i)] = fmt.Sprintf("value %d", i)
+		}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := acroform.FillFormFields(benchFormPDF, data, nil, false); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("xfa_2500_fields", func(b *testing.B) {
+		data := benchXFAData(benchXFAFields)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := xfa.UpdateXFAInPDF(benchXFAPDF, data, nil, false); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("xfa_2500_fields_template", func(b *testing.B) {
+		tmpl, err := xfa.PrepareTemplate(benchXFAPDF, nil, xfa.DebugOptions{}, false)
+		if err != nil {
+			b.Fatal(err)
+		}
+		data := benchXFAData(benchXFAFields)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := tmpl.Fill(data, false); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("encrypted_xfa", func(b *testing.B) {
+		pdfBytes, encInfo := loadEStar(b)
+		data := types.FormData{"ApplicantName": "Benchmark"}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := xfa.UpdateXFAInPDF(pdfBytes, data, encInfo, false); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkUpdateXFAValues measures the datasets update alone, every field set
+func BenchmarkUpdateXFAValues(b *testing.B) {
+	for _, fields := range []int{2000, 5000} {
+		b.Run(fmt.Sprintf("%d_fields", fields), func(b *testing.B) {
+			datasets := buildBenchXFADatasets(fields)
+			data := benchXFAData(fields)
+			b.ReportAllocs()
+			b.SetBytes(int64(len(datasets)))
+			for i := 0; i < b.N; i++ {
+				if _, err := xfa.UpdateXFAValues(datasets, data, false); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkEncrypt(b *testing.B) {
+	loadBenchCorpus(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		builder := write.NewSimplePDFBuilder()
+		page := builder.AddPage(write.PageSizeLetter)
+		font := page.AddStandardFont("Helvetica")
+		page.Content().BeginText().SetFont(font, 12).SetTextPosition(72, 720).ShowText("Encrypted").EndText()
+		builder.FinalizePage(page)
+		if _, err := builder.Writer().SetupEncryptionWithPasswords([]byte("user"), []byte("owner"), -4, true); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := builder.Bytes(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecrypt(b *testing.B) {
+	data, _ := loadEStar(b)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		if _, _, err := encrypt.DecryptPDF(data, []byte(""), false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCompare(b *testing.B) {
+	loadBenchCorpus(b)
+	modified := buildBenchTextPDF(21)
+	if modified == nil {
+		b.Fatal("failed to build modified corpus")
+	}
+
+	b.Run("identical", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := compare.ComparePDFs(benchTextPDF, benchTextPDF, nil, nil, false); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("changed", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := compare.ComparePDFs(benchTextPDF, modified, nil, nil, false); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
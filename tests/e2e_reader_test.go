@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/content/extract"
+	"github.com/benedoc-inc/pdfer/core/manipulate"
+	"github.com/benedoc-inc/pdfer/core/write"
+	"github.com/benedoc-inc/pdfer/forms/acroform"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// TestE2E_OpenReader_Encrypted extracts content and form fields of an encrypted
+// PDF read through an io.ReaderAt
+func TestE2E_OpenReader_Encrypted(t *testing.T) {
+	builder := write.NewSimplePDFBuilder()
+	page := builder.AddPage(write.PageSizeLetter)
+	fontName := page.AddStandardFont("Helvetica")
+	content := page.Content()
+	content.BeginText()
+	content.SetFont(fontName, 12)
+	content.SetTextPosition(72, 720)
+	content.ShowText("Invoice 2024-117")
+	content.EndText()
+	builder.FinalizePage(page)
+	fieldNum := builder.Writer().AddObject([]byte("<</FT/Tx/T(name)/V(Ada)/Rect[72 600 272 620]>>"))
+	acroFormNum := builder.Writer().AddObject([]byte(fmt.Sprintf("<</Fields[%d 0 R]>>", fieldNum)))
+	builder.SetCatalogEntry("AcroForm", fmt.Sprintf("%d 0 R", acroFormNum))
+	plain, err := builder.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to write PDF: %v", err)
+	}
+
+	pdfBytes, err := manipulate.SetSecurity(plain, nil, manipulate.SecurityOptions{
+		UserPassword: []byte("secret"),
+		Permissions:  manipulate.PermAll,
+		Cipher:       manipulate.CipherAES128,
+	}, false)
+	if err != nil {
+		t.Fatalf("SetSecurity failed: %v", err)
+	}
+	r := bytes.NewReader(pdfBytes)
+	size := int64(len(pdfBytes))
+
+	doc, err := extract.ExtractContentFromReader(r, size, extract.ExtractOptions{Password: []byte("secret")})
+	if err != nil {
+		t.Fatalf("ExtractContentFromReader failed: %v", err)
+	}
+	var text strings.Builder
+	for _, page := range doc.Pages {
+		for _, elem := range page.Text {
+			text.WriteString(elem.Text)
+		}
+	}
+	if len(doc.Pages) != 1 || !strings.Contains(text.String(), "Invoice 2024-117") {
+		t.Errorf("Expected the page text, got %d pages with %q", len(doc.Pages), text.String())
+	}
+
+	form, err := acroform.ExtractAcroFormFromReader(r, size, []byte("secret"), false)
+	if err != nil {
+		t.Fatalf("ExtractAcroFormFromReader failed: %v", err)
+	}
+	if len(form.Fields) != 1 || form.Fields[0].T != "name" || form.Fields[0].V != "Ada" {
+		t.Errorf("Unexpected fields %+v", form.Fields)
+	}
+
+	if _, err := acroform.ExtractAcroFormFromReader(r, size, []byte("wrong"), false); !errors.Is(err, types.ErrWrongPassword) {
+		t.Errorf("Expected a wrong password error, got %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/benedoc-inc/pdfer/core/encrypt"
+	"github.com/benedoc-inc/pdfer/core/manipulate"
+	"github.com/benedoc-inc/pdfer/core/parse"
+	"github.com/benedoc-inc/pdfer/forms/acroform"
+	"github.com/benedoc-inc/pdfer/forms/xfa"
+	"github.com/benedoc-inc/pdfer/types"
+)
+
+// protectForFill encrypts a form with an empty user password, like eSTAR
+// documents, and returns it decrypted with the original's encryption
+func protectForFill(t *testing.T, pdfBytes []byte) ([]byte, *types.PDFEncryption) {
+	t.Helper()
+	encrypted, err := manipulate.SetSecurity(pdfBytes, nil, manipulate.SecurityOptions{
+		OwnerPassword: []byte("owner"),
+		Permissions:   manipulate.PermPrint | manipulate.PermFillForms,
+		Cipher:        manipulate.CipherAES128,
+	}, false)
+	if err != nil {
+		t.Fatalf("SetSecurity failed: %v", err)
+	}
+	_, encInfo, err := encrypt.DecryptPDF(encrypted, []byte(""), false)
+	if err != nil {
+		t.Fatalf("DecryptPDF failed: %v", err)
+	}
+	plain, err := manipulate.RemoveSecurity(encrypted, []byte(""), false)
+	if err != nil {
+		t.Fatalf("RemoveSecurity failed: %v", err)
+	}
+	return plain, encInfo
+}
+
+func TestE2E_ReencryptXFAFill(t *testing.T) {
+	plain, original := protectForFill(t, buildBenchXFAPDF(3))
+
+	filled, err := xfa.UpdateXFAInPDF(plain, types.FormData{"field1": "re-encrypted value"}, nil, false)
+	if err != nil {
+		t.Fatalf("UpdateXFAInPDF failed: %v", err)
+	}
+	out, err := manipulate.EncryptPDF(filled, original, nil, false)
+	if err != nil {
+		t.Fatalf("EncryptPDF failed: %v", err)
+	}
+
+	for _, password := range []string{"", "owner"} {
+		_, encInfo, err := encrypt.DecryptPDF(out, []byte(password), false)
+		if err != nil {
+			t.Fatalf("Original password %q does not open the output: %v", password, err)
+		}
+		if encInfo.V != original.V || encInfo.P != original.P {
+			t.Errorf("V = %d, P = %d; want the original's V%d, P%d", encInfo.V, encInfo.P, original.V, original.P)
+		}
+		streams, err := xfa.ExtractAllXFAStreams(out, encInfo, xfa.DebugOptions{}, false)
+		if err != nil || streams.Datasets == nil {
+			t.Fatalf("Failed to extract datasets: %v", err)
+		}
+		datasets, _, err := xfa.DecompressStream(streams.Datasets.Data)
+		if err != nil {
+			t.Fatalf("DecompressStream failed: %v", err)
+		}
+		if !bytes.Contains(datasets, []byte("<value>re-encrypted value</value>")) {
+			t.Errorf("Filled value missing from datasets: %s", datasets)
+		}
+	}
+}
+
+func TestE2E_FillEncryptedXFADebugDump(t *testing.T) {
+	encrypted, err := manipulate.SetSecurity(buildBenchXFAPDF(3), nil, manipulate.SecurityOptions{
+		OwnerPassword: []byte("owner"),
+		Cipher:        manipulate.CipherAES128,
+	}, false)
+	if err != nil {
+		t.Fatalf("SetSecurity failed: %v", err)
+	}
+	_, encInfo, err := encrypt.DecryptPDF(encrypted, []byte(""), false)
+	if err != nil {
+		t.Fatalf("DecryptPDF failed: %v", err)
+	}
+
+	// The objects decrypted on the way to the XFA streams are dumped without verbose mode
+	var buf bytes.Buffer
+	tmpl, err := xfa.PrepareTemplate(encrypted, encInfo, xfa.DebugOptions{Output: &buf, Limit: 64}, false)
+	if err != nil {
+		t.Fatalf("PrepareTemplate failed: %v", err)
+	}
+	if _, err := tmpl.Fill(types.FormData{"field1": "dumped"}, false); err != nil {
+		t.Fatalf("Fill failed: %v", err)
+	}
+	for _, label := range []string{"=== decrypted AcroForm:", "datasets stream object"} {
+		if !strings.Contains(buf.String(), label) {
+			t.Errorf("Missing %q dump: %q", label, buf.String())
+		}
+	}
+}
+
+func TestE2E_ReencryptAcroFormFill(t *testing.T) {
+	plain, original := protectForFill(t, buildBenchFormPDF(3))
+
+	filled, err := acroform.FillFormFieldsWithStreams(plain, types.FormData{"field1": "re-encrypted value"}, nil, false)
+	if err != nil {
+		t.Fatalf("FillFormFieldsWithStreams failed: %v", err)
+	}
+	out, err := manipulate.EncryptPDF(filled, original, &manipulate.SecurityOptions{
+		UserPassword:  []byte("user"),
+		OwnerPassword: []byte("new owner"),
+		Permissions:   manipulate.PermPrint,
+	}, false)
+	if err != nil {
+		t.Fatalf("EncryptPDF failed: %v", err)
+	}
+	if bytes.Contains(out, []byte("re-encrypted value")) {
+		t.Error("Filled value written in the clear")
+	}
+	if _, err := parse.OpenWithOptions(out, parse.ParseOptions{Password: []byte("")}); err == nil {
+		t.Error("Expected the original empty password to fail")
+	}
+
+	pdf, err := parse.OpenWithOptions(out, parse.ParseOptions{Password: []byte("user")})
+	if err != nil {
+		t.Fatalf("Failed to open with the new password: %v", err)
+	}
+	if pdf.Encryption().V != original.V {
+		t.Errorf("V = %d, want the original's cipher V%d", pdf.Encryption().V, original.V)
+	}
+	found := false
+	for _, objNum := range pdf.Objects() {
+		obj, err := pdf.GetObject(objNum)
+		if err == nil && strings.Contains(string(obj), "(re-encrypted value)") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Filled value not found after decryption")
+	}
+}
@@ -642,13 +642,10 @@ func TestE2E_MetadataRoundTrip(t *testing.T) {
 		t.Error("Custom fields should be extracted")
 	}
 
-	// Note: Custom field extraction depends on how the extractor parses the Info dict
-	// For now, we just verify that custom fields were written (check PDF bytes)
-	if !strings.Contains(string(pdfBytes), "CustomField1") || !strings.Contains(string(pdfBytes), "CustomValue1") {
-		t.Error("PDF should contain custom field 1")
-	}
-	if !strings.Contains(string(pdfBytes), "CustomField2") || !strings.Contains(string(pdfBytes), "CustomValue2") {
-		t.Error("PDF should contain custom field 2")
+	for key, value := range map[string]string{"CustomField1": "CustomValue1", "CustomField2": "CustomValue2"} {
+		if got := extractedMetadata.Custom[key]; got != value {
+			t.Errorf("Custom field %s = %q, want %q", key, got, value)
+		}
 	}
 
 	t.Logf("Metadata round-trip successful:")
@@ -105,7 +105,7 @@ func TestRebuildPDFWithUpdatedXFA(t *testing.T) {
 	}
 
 	// Extract XFA
-	streams, err := xfa.ExtractAllXFAStreams(pdfBytes, encryptInfo, false)
+	streams, err := xfa.ExtractAllXFAStreams(pdfBytes, encryptInfo, xfa.DebugOptions{}, false)
 	if err != nil {
 		t.Fatalf("Failed to extract XFA: %v", err)
 	}
@@ -131,7 +131,7 @@ func TestRebuildPDFWithUpdatedXFA(t *testing.T) {
 
 	// Verify the rebuilt PDF has the modification
 	// Extract XFA from rebuilt PDF
-	rebuiltStreams, err := xfa.ExtractAllXFAStreams(rebuiltPDF, nil, false) // No encryption for rebuilt
+	rebuiltStreams, err := xfa.ExtractAllXFAStreams(rebuiltPDF, nil, xfa.DebugOptions{}, false) // No encryption for rebuilt
 	if err != nil {
 		t.Logf("Could not extract from rebuilt PDF: %v", err)
 		// This is expected since the rebuilt PDF doesn't preserve encryption properly yet
@@ -178,7 +178,7 @@ func TestBuildPDFFromExtractedXFA(t *testing.T) {
 	}
 
 	// Extract XFA
-	streams, err := xfa.ExtractAllXFAStreams(pdfBytes, encryptInfo, false)
+	streams, err := xfa.ExtractAllXFAStreams(pdfBytes, encryptInfo, xfa.DebugOptions{}, false)
 	if err != nil {
 		t.Fatalf("Failed to extract XFA: %v", err)
 	}
@@ -215,7 +215,7 @@ func TestBuildPDFFromExtractedXFA(t *testing.T) {
 	}
 
 	// Try to extract XFA from the new PDF
-	newStreams, err := xfa.ExtractAllXFAStreams(newPDF, nil, false)
+	newStreams, err := xfa.ExtractAllXFAStreams(newPDF, nil, xfa.DebugOptions{}, false)
 	if err != nil {
 		t.Logf("Could not extract XFA from new PDF: %v", err)
 	} else {
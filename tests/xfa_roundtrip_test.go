@@ -65,7 +65,7 @@ func TestXFARoundTrip(t *testing.T) {
 	}
 
 	// Extract all XFA streams
-	streams, err := xfa.ExtractAllXFAStreams(pdfBytes, encryptInfo, true)
+	streams, err := xfa.ExtractAllXFAStreams(pdfBytes, encryptInfo, xfa.DebugOptions{}, true)
 	if err != nil {
 		t.Fatalf("Failed to extract XFA streams: %v", err)
 	}
@@ -365,7 +365,7 @@ func TestXFARoundTrip(t *testing.T) {
 	}
 
 	// Extract XFA from rebuilt PDF to verify (non-fatal for now since rebuild needs work)
-	rebuiltStreams, err := xfa.ExtractAllXFAStreams(rebuiltPDF, encryptInfo, false)
+	rebuiltStreams, err := xfa.ExtractAllXFAStreams(rebuiltPDF, encryptInfo, xfa.DebugOptions{}, false)
 	if err != nil {
 		t.Logf("Could not extract XFA from rebuilt PDF (rebuild needs work): %v", err)
 		t.Log("Core extraction test PASSED - rebuild verification skipped")
@@ -0,0 +1,54 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ContentFormat identifies JSON written by MarshalContent
+const ContentFormat = "pdfer-content"
+
+// ContentFormatVersion is the version of the format written by MarshalContent.
+// It changes only when a field is removed or changes meaning; new fields are
+// added without a version change and ignored by older readers.
+const ContentFormatVersion = 1
+
+// contentEnvelope records the format and version alongside the document
+type contentEnvelope struct {
+	Format   string           `json:"format"`
+	Version  int              `json:"version"`
+	Document *ContentDocument `json:"document"`
+}
+
+// MarshalContent serializes extracted content in a versioned JSON format so it
+// can be stored and compared later without re-processing the PDF. The same
+// document always serializes to the same bytes.
+func MarshalContent(doc *ContentDocument) ([]byte, error) {
+	data, err := json.Marshal(contentEnvelope{
+		Format:   ContentFormat,
+		Version:  ContentFormatVersion,
+		Document: doc,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal content: %w", err)
+	}
+	return data, nil
+}
+
+// UnmarshalContent parses content serialized with MarshalContent
+func UnmarshalContent(data []byte) (*ContentDocument, error) {
+	var envelope contentEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("invalid content: %w", err)
+	}
+	if envelope.Format != ContentFormat {
+		return nil, fmt.Errorf("not pdfer content (format %q)", envelope.Format)
+	}
+	if envelope.Version < 1 || envelope.Version > ContentFormatVersion {
+		return nil, fmt.Errorf("unsupported content version %d", envelope.Version)
+	}
+	if envelope.Document == nil {
+		return nil, fmt.Errorf("invalid content: missing document")
+	}
+	return envelope.Document, nil
+}
@@ -0,0 +1,66 @@
+package types
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMarshalContent_RoundTrip(t *testing.T) {
+	doc := &ContentDocument{
+		Metadata: &DocumentMetadata{Title: "Report", PageCount: 1},
+		Pages: []Page{{
+			PageNumber: 1,
+			Width:      612,
+			Height:     792,
+			Text:       []TextElement{{Text: "Hello", X: 72, Y: 720, FontSize: 12}},
+			Annotations: []Annotation{{
+				Type:     AnnotationTypeText,
+				Contents: "Check this",
+				Rect:     &Rectangle{LowerX: 10, LowerY: 10, UpperX: 30, UpperY: 30},
+			}},
+			Resources: &PageResources{Fonts: map[string]FontInfo{"F2": {ID: "/F2"}, "F1": {ID: "/F1"}}},
+		}},
+		Images: []Image{{ID: "/Im1", Width: 2, Height: 2, Data: []byte{1, 2, 3}}},
+	}
+
+	data, err := MarshalContent(doc)
+	if err != nil {
+		t.Fatalf("MarshalContent failed: %v", err)
+	}
+	if !strings.HasPrefix(string(data), `{"format":"pdfer-content","version":1,`) {
+		t.Errorf("Unexpected envelope: %.60s", data)
+	}
+	again, _ := MarshalContent(doc)
+	if !bytes.Equal(data, again) {
+		t.Error("Serialization is not stable")
+	}
+
+	restored, err := UnmarshalContent(data)
+	if err != nil {
+		t.Fatalf("UnmarshalContent failed: %v", err)
+	}
+	if restored.Metadata.Title != "Report" || restored.Pages[0].Text[0].Text != "Hello" {
+		t.Errorf("Content not restored: %+v", restored)
+	}
+	if a := restored.Pages[0].Annotations[0]; a.Contents != "Check this" || a.Rect.UpperX != 30 {
+		t.Errorf("Annotation not restored: %+v", a)
+	}
+	if !bytes.Equal(restored.Images[0].Data, []byte{1, 2, 3}) {
+		t.Errorf("Image data not restored: %v", restored.Images[0].Data)
+	}
+}
+
+func TestUnmarshalContent_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"not json":       `{`,
+		"no envelope":    `{"pages":[]}`,
+		"future version": `{"format":"pdfer-content","version":99,"document":{"pages":[]}}`,
+		"no document":    `{"format":"pdfer-content","version":1}`,
+	}
+	for name, data := range tests {
+		if _, err := UnmarshalContent([]byte(data)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -9,6 +9,9 @@ type ContentDocument struct {
 	Annotations []Annotation      `json:"annotations,omitempty"`
 	Images      []Image           `json:"images,omitempty"`
 	Fonts       []FontInfo        `json:"fonts,omitempty"`
+	Attachments []Attachment      `json:"attachments,omitempty"`
+	Signatures  []Signature       `json:"signatures,omitempty"`
+	Threads     []ArticleThread   `json:"threads,omitempty"`
 }
 
 // DocumentMetadata contains document-level metadata
@@ -21,11 +24,128 @@ type DocumentMetadata struct {
 	Producer     string                 `json:"producer,omitempty"`
 	CreationDate string                 `json:"creation_date,omitempty"`
 	ModDate      string                 `json:"mod_date,omitempty"`
+	Trapped      string                 `json:"trapped,omitempty"` // TrappedTrue, TrappedFalse or TrappedUnknown
 	PDFVersion   string                 `json:"pdf_version,omitempty"`
 	PageCount    int                    `json:"page_count"`
 	Encrypted    bool                   `json:"encrypted"`
 	Custom       map[string]string      `json:"custom,omitempty"`
 	XMP          map[string]interface{} `json:"xmp,omitempty"`
+	Extensions   []Extension            `json:"extensions,omitempty"`
+	Requirements []Requirement          `json:"requirements,omitempty"`
+}
+
+// Values of the /Trapped Info entry, which prepress workflows use to record
+// whether a document has been trapped
+const (
+	TrappedTrue    = "True"
+	TrappedFalse   = "False"
+	TrappedUnknown = "Unknown"
+)
+
+// InfoEntry returns an Info dictionary entry by key, e.g. "Title" or
+// "Trapped", falling back to the custom entries for keys that aren't standard
+func (m *DocumentMetadata) InfoEntry(key string) string {
+	switch key {
+	case "Title":
+		return m.Title
+	case "Author":
+		return m.Author
+	case "Subject":
+		return m.Subject
+	case "Keywords":
+		return m.Keywords
+	case "Creator":
+		return m.Creator
+	case "Producer":
+		return m.Producer
+	case "CreationDate":
+		return m.CreationDate
+	case "ModDate":
+		return m.ModDate
+	case "Trapped":
+		return m.Trapped
+	}
+	return m.Custom[key]
+}
+
+// SetInfoEntry sets an Info dictionary entry by key, storing keys that aren't
+// standard in Custom. An empty value removes a custom entry.
+func (m *DocumentMetadata) SetInfoEntry(key, value string) {
+	switch key {
+	case "Title":
+		m.Title = value
+	case "Author":
+		m.Author = value
+	case "Subject":
+		m.Subject = value
+	case "Keywords":
+		m.Keywords = value
+	case "Creator":
+		m.Creator = value
+	case "Producer":
+		m.Producer = value
+	case "CreationDate":
+		m.CreationDate = value
+	case "ModDate":
+		m.ModDate = value
+	case "Trapped":
+		m.Trapped = value
+	default:
+		if value == "" {
+			delete(m.Custom, key)
+			return
+		}
+		if m.Custom == nil {
+			m.Custom = make(map[string]string)
+		}
+		m.Custom[key] = value
+	}
+}
+
+// IsStandardInfoKey reports whether key is an Info dictionary entry defined by
+// the PDF specification rather than a custom one
+func IsStandardInfoKey(key string) bool {
+	switch key {
+	case "Title", "Author", "Subject", "Keywords", "Creator", "Producer", "CreationDate", "ModDate", "Trapped":
+		return true
+	}
+	return false
+}
+
+// Extension is a developer extension declared in the catalog's /Extensions
+// dictionary, e.g. Adobe's extensions to ISO 32000 under the ADBE prefix
+type Extension struct {
+	Prefix         string `json:"prefix"`             // Registered developer prefix, e.g. "ADBE"
+	BaseVersion    string `json:"base_version"`       // PDF version the extension builds on, e.g. "1.7"
+	ExtensionLevel int    `json:"extension_level"`    // Level within the base version
+	URL            string `json:"url,omitempty"`      // Documentation of the extension (PDF 2.0)
+	Revision       string `json:"revision,omitempty"` // /ExtensionRevision (PDF 2.0)
+}
+
+// adobeExtensionLevels maps the levels of Adobe's extensions to PDF 1.7 to
+// the Acrobat versions that introduced them
+var adobeExtensionLevels = map[int]string{
+	3:  "9.0",
+	5:  "9.1",
+	8:  "10.0",
+	11: "11.0",
+}
+
+// AcrobatVersion returns the Acrobat version needed for an Adobe extension
+// level to PDF 1.7, or "" for other extensions and unknown levels
+func (e Extension) AcrobatVersion() string {
+	if e.Prefix != "ADBE" || e.BaseVersion != "1.7" {
+		return ""
+	}
+	return adobeExtensionLevels[e.ExtensionLevel]
+}
+
+// Requirement is an entry of the catalog's /Requirements array: a feature a
+// conforming reader must support to process the document properly
+type Requirement struct {
+	Type    string `json:"type"`              // /S, e.g. "EnableJavaScripts" or "DigSig"
+	Version string `json:"version,omitempty"` // /V, the minimum version of the feature (PDF 2.0)
+	Penalty int    `json:"penalty"`           // /Penalty, 0-100; 100 when the document cannot be used without the feature
 }
 
 // Page represents a single page with all its content
@@ -73,6 +193,13 @@ type TextElement struct {
 	BoundingBox *Rectangle `json:"bounding_box,omitempty"`
 }
 
+// TextMatch is a match of a text search on a page
+type TextMatch struct {
+	PageNumber int         `json:"page_number"` // 1-based page number
+	Text       string      `json:"text"`        // Matched text
+	Rects      []Rectangle `json:"rects"`       // Area of the match in each text element it spans
+}
+
 // Graphic represents a graphics element (path, shape, etc.)
 type Graphic struct {
 	Type        GraphicType `json:"type"` // "path", "rectangle", "circle", "line", etc.
@@ -199,6 +326,8 @@ type Annotation struct {
 	Contents   string                 `json:"contents,omitempty"`
 	Title      string                 `json:"title,omitempty"`
 	Subject    string                 `json:"subject,omitempty"`
+	Name       string                 `json:"name,omitempty"`     // Unique name (/NM)
+	Modified   string                 `json:"modified,omitempty"` // Modification date (/M) as written in the PDF
 	Color      *Color                 `json:"color,omitempty"`
 	Border     *Border                `json:"border,omitempty"`
 	Actions    []Action               `json:"actions,omitempty"`
@@ -256,6 +385,51 @@ type Bookmark struct {
 	Children    []Bookmark `json:"children,omitempty"`
 }
 
+// ArticleThread is an article thread (/Threads): a sequence of beads, areas on
+// pages that are read in order, such as the columns of a story that continues
+// over several pages
+type ArticleThread struct {
+	Title  string        `json:"title,omitempty"`  // Title from the thread information dictionary
+	Author string        `json:"author,omitempty"` // Author from the thread information dictionary
+	Beads  []ArticleBead `json:"beads"`
+}
+
+// ArticleBead is one area of an article thread
+type ArticleBead struct {
+	PageNumber int        `json:"page_number"` // Page the bead is on (1-based)
+	Rect       *Rectangle `json:"rect"`        // Area of the page, in default user space
+}
+
+// Attachment represents a file embedded in the document's EmbeddedFiles name
+// tree. The file data is not kept; its SHA-256 hash identifies the content.
+type Attachment struct {
+	Name         string `json:"name"`                   // Name in the EmbeddedFiles name tree
+	FileName     string `json:"file_name,omitempty"`    // File name from the file specification
+	Description  string `json:"description,omitempty"`  // Description (/Desc)
+	MIMEType     string `json:"mime_type,omitempty"`    // Media type (/Subtype)
+	Size         int    `json:"size"`                   // Decoded size in bytes
+	SHA256       string `json:"sha256"`                 // Hex SHA-256 of the decoded data
+	ModDate      string `json:"mod_date,omitempty"`     // Modification date as written in the PDF
+	Relationship string `json:"relationship,omitempty"` // Relationship to the document (/AFRelationship), e.g. "Data"
+}
+
+// Signature represents a signed signature field. Intact and the hashes describe
+// the signature's byte range; the CMS signature itself is not verified.
+type Signature struct {
+	FieldName      string `json:"field_name"`              // Fully qualified field name
+	Signer         string `json:"signer,omitempty"`        // Signer name (/Name)
+	Reason         string `json:"reason,omitempty"`        // Reason for signing (/Reason)
+	Location       string `json:"location,omitempty"`      // Signing location (/Location)
+	SigningTime    string `json:"signing_time,omitempty"`  // Signing time (/M) as written in the PDF
+	SubFilter      string `json:"sub_filter,omitempty"`    // Signature encoding, e.g. "adbe.pkcs7.detached"
+	ByteRange      []int  `json:"byte_range,omitempty"`    // Offset and length pairs of the signed bytes
+	ContentsHash   string `json:"contents_hash,omitempty"` // Hex SHA-256 of the signature value (/Contents)
+	SignedHash     string `json:"signed_hash,omitempty"`   // Hex SHA-256 of the bytes the byte range covers
+	Intact         bool   `json:"intact"`                  // The byte range covers the file except exactly the /Contents string
+	CoversDocument bool   `json:"covers_document"`         // The byte range extends to the end of the file
+	DocMDP         int    `json:"docmdp,omitempty"`        // Changes a certification signature permits (/DocMDP /P): 1 none, 2 form filling and signing, 3 also annotations; 0 for approval signatures
+}
+
 // PageResources represents resources used on a page
 type PageResources struct {
 	Fonts       map[string]FontInfo    `json:"fonts,omitempty"`
@@ -275,3 +449,111 @@ type XObject struct {
 	Height  float64    `json:"height,omitempty"`
 	Matrix  [6]float64 `json:"matrix,omitempty"`
 }
+
+// PageStats holds content statistics for a page
+type PageStats struct {
+	PageNumber   int      `json:"page_number"`
+	TextElements int      `json:"text_elements"`
+	Words        int      `json:"words"`
+	Images       int      `json:"images"`          // Images drawn on the page
+	VectorOps    int      `json:"vector_ops"`      // Path construction and painting operators
+	Fonts        []string `json:"fonts,omitempty"` // Fonts used by text (base font names, sorted)
+	InkCoverage  float64  `json:"ink_coverage"`    // Estimated fraction of the page covered by content (0-1)
+	ContentBytes int      `json:"content_bytes"`   // Decoded content stream size in bytes
+}
+
+// ImageResolution is the effective resolution of an image where it is placed:
+// its pixels over its size on the page
+type ImageResolution struct {
+	PageNumber    int     `json:"page_number"`
+	ImageID       string  `json:"image_id"`     // Resource name (e.g., "/Im1")
+	PixelWidth    int     `json:"pixel_width"`  // Width in pixels
+	PixelHeight   int     `json:"pixel_height"` // Height in pixels
+	PlacedWidth   float64 `json:"placed_width"` // Width on the page in points
+	PlacedHeight  float64 `json:"placed_height"`
+	DPI           float64 `json:"dpi"` // Effective resolution, the lower of horizontal and vertical
+	LowResolution bool    `json:"low_resolution"`
+}
+
+// PagePrintQuality holds the print budgets measured for a page and the ones it exceeds
+type PagePrintQuality struct {
+	PageNumber   int      `json:"page_number"`
+	InkCoverage  float64  `json:"ink_coverage"`       // Estimated fraction of the page covered by content (0-1)
+	TotalInk     float64  `json:"total_ink"`          // Highest total of C, M, Y and K among vector colors, in percent
+	VectorOps    int      `json:"vector_ops"`         // Path construction and painting operators
+	ContentBytes int      `json:"content_bytes"`      // Decoded content stream size in bytes
+	Exceeded     []string `json:"exceeded,omitempty"` // Budgets exceeded, e.g. "total_ink 400% > 300%"
+}
+
+// PrintQualityReport is the outcome of a print quality analysis
+type PrintQualityReport struct {
+	Passed              bool               `json:"passed"` // No low-resolution images and no page over budget
+	Images              []ImageResolution  `json:"images"`
+	Pages               []PagePrintQuality `json:"pages"`
+	LowResolutionImages int                `json:"low_resolution_images"`
+	PagesOverBudget     int                `json:"pages_over_budget"`
+}
+
+// Accessibility checks, as reported in AccessibilityIssue.Check
+const (
+	AccessibilityUntaggedDocument = "untagged_document" // No structure tree, or not marked as tagged
+	AccessibilityUntaggedContent  = "untagged_content"  // Page content outside any structure element or artifact
+	AccessibilityMissingLanguage  = "missing_language"  // No document language (/Lang)
+	AccessibilityMissingAltText   = "missing_alt_text"  // Figure without alternate text
+	AccessibilityMissingTooltip   = "missing_tooltip"   // Form field without a tooltip (/TU)
+	AccessibilityTabOrder         = "tab_order"         // Page with annotations whose tab order is not the structure order
+)
+
+// AccessibilityIssue is a problem found by an accessibility analysis
+type AccessibilityIssue struct {
+	Check      string `json:"check"`                 // One of the Accessibility* checks
+	PageNumber int    `json:"page_number,omitempty"` // Page of the issue, 0 for document-level issues
+	Object     int    `json:"object,omitempty"`      // Object number of the structure element, field or page
+	Field      string `json:"field,omitempty"`       // Full name of a form field
+	Count      int    `json:"count,omitempty"`       // Untagged content operators on the page
+	Message    string `json:"message"`
+}
+
+// AccessibilityReport is the outcome of an accessibility analysis, a starting
+// point for PDF/UA remediation rather than a conformance verdict
+type AccessibilityReport struct {
+	Passed      bool                 `json:"passed"`             // No issues found
+	Tagged      bool                 `json:"tagged"`             // Has a structure tree and /MarkInfo /Marked true
+	Language    string               `json:"language,omitempty"` // Document language (/Lang)
+	Figures     int                  `json:"figures"`            // Figure structure elements
+	FormFields  int                  `json:"form_fields"`        // Terminal form fields
+	Issues      []AccessibilityIssue `json:"issues"`
+	IssueCounts map[string]int       `json:"issue_counts,omitempty"` // Number of issues by check
+}
+
+// TermOccurrence is where a word occurs in a document
+type TermOccurrence struct {
+	PageNumber int       `json:"page_number"`
+	Rect       Rectangle `json:"rect"` // Area of the word, or of its first part when it spans text elements
+}
+
+// DictionaryTerm is a word of a document that is missing from a dictionary
+type DictionaryTerm struct {
+	Word        string           `json:"word"`
+	Count       int              `json:"count"`
+	Occurrences []TermOccurrence `json:"occurrences"`
+}
+
+// DictionaryReport is the outcome of a dictionary audit
+type DictionaryReport struct {
+	Passed       bool             `json:"passed"`        // Every word is in the dictionary
+	WordsChecked int              `json:"words_checked"` // Words checked, counting repeats
+	UnknownWords int              `json:"unknown_words"` // Words missing from the dictionary, counting repeats
+	Unknown      []DictionaryTerm `json:"unknown"`       // Missing words, most frequent first
+}
+
+// Link represents a Link annotation and its target
+type Link struct {
+	PageNumber  int        `json:"page_number"`
+	Rect        *Rectangle `json:"rect,omitempty"`
+	Action      string     `json:"action,omitempty"`      // Action type: "URI", "GoTo", "GoToR", "Launch", "Named", ...
+	URI         string     `json:"uri,omitempty"`         // Target URL of a URI action
+	Destination string     `json:"destination,omitempty"` // Named destination, or the raw explicit destination
+	TargetPage  int        `json:"target_page,omitempty"` // Page an internal destination points to (0 if unresolved)
+	File        string     `json:"file,omitempty"`        // Target file of a GoToR or Launch action
+}
@@ -15,12 +15,24 @@ type PDFEncryption struct {
 	OE              []byte // Encrypted owner encryption key (V5+, AES-256)
 	P               int32  // Permissions
 	EncryptMetadata bool
+	StmF            string // Crypt filter for streams (V4+); "Identity" leaves them in the clear
+	StrF            string // Crypt filter for strings (V4+); "Identity" leaves them in the clear
+	EFF             string // Crypt filter for embedded file streams (V4+); StmF when empty
 	EncryptKey      []byte // Master encryption key
 }
 
 // FormData represents the data to fill into the form
 type FormData map[string]interface{}
 
+// FieldLock selects the fields a fill makes read-only
+type FieldLock string
+
+const (
+	LockNone   FieldLock = ""       // Leave fields editable
+	LockFilled FieldLock = "filled" // Make the filled fields read-only
+	LockAll    FieldLock = "all"    // Make every field read-only
+)
+
 // FormField represents a field in an XFA form
 type FormField struct {
 	ID           string                 `json:"id"`
@@ -2,6 +2,7 @@ package types
 
 import (
 	"fmt"
+	"sync"
 	"time"
 )
 
@@ -71,8 +72,11 @@ func NewWarningWithCode(level WarningLevel, code, message string) *Warning {
 	}
 }
 
-// WarningCollector collects warnings during PDF processing
+// WarningCollector collects warnings during PDF processing. It is safe for
+// concurrent use, so one collector can be shared by a parsed document read from
+// several goroutines.
 type WarningCollector struct {
+	mu       sync.Mutex
 	warnings []*Warning
 	enabled  bool
 }
@@ -87,6 +91,8 @@ func NewWarningCollector(enabled bool) *WarningCollector {
 
 // Add adds a warning to the collector
 func (wc *WarningCollector) Add(warning *Warning) {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
 	if wc.enabled && warning != nil {
 		wc.warnings = append(wc.warnings, warning)
 	}
@@ -109,26 +115,36 @@ func (wc *WarningCollector) AddWarningWithCode(level WarningLevel, code, message
 
 // Warnings returns all collected warnings
 func (wc *WarningCollector) Warnings() []*Warning {
-	return wc.warnings
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+	return append([]*Warning(nil), wc.warnings...)
 }
 
 // Count returns the number of warnings collected
 func (wc *WarningCollector) Count() int {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
 	return len(wc.warnings)
 }
 
 // HasWarnings returns true if any warnings have been collected
 func (wc *WarningCollector) HasWarnings() bool {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
 	return len(wc.warnings) > 0
 }
 
 // Clear clears all warnings
 func (wc *WarningCollector) Clear() {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
 	wc.warnings = wc.warnings[:0]
 }
 
 // FilterByLevel returns warnings filtered by level
 func (wc *WarningCollector) FilterByLevel(level WarningLevel) []*Warning {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
 	result := make([]*Warning, 0)
 	for _, w := range wc.warnings {
 		if w.Level == level {
@@ -140,6 +156,8 @@ func (wc *WarningCollector) FilterByLevel(level WarningLevel) []*Warning {
 
 // GetByCode returns warnings filtered by code
 func (wc *WarningCollector) GetByCode(code string) []*Warning {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
 	result := make([]*Warning, 0)
 	for _, w := range wc.warnings {
 		if w.Code == code {
@@ -151,15 +169,21 @@ func (wc *WarningCollector) GetByCode(code string) []*Warning {
 
 // Enable enables warning collection
 func (wc *WarningCollector) Enable() {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
 	wc.enabled = true
 }
 
 // Disable disables warning collection
 func (wc *WarningCollector) Disable() {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
 	wc.enabled = false
 }
 
 // IsEnabled returns whether warning collection is enabled
 func (wc *WarningCollector) IsEnabled() bool {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
 	return wc.enabled
 }